@@ -0,0 +1,155 @@
+// Package handlers contains HTTP handlers for the API.
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/services/user"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// AccountHandler handles HTTP requests for deleting accounts and exporting their data.
+type AccountHandler struct {
+	deletionService *user.AccountDeletionService
+	exportService   *user.DataExportService
+	writeTimeout    time.Duration
+	logger          *utils.Logger
+}
+
+// NewAccountHandler creates a new account handler. A data archive download is given up on when the client
+// doesn't take any of it for writeTimeout.
+func NewAccountHandler(deletionService *user.AccountDeletionService, exportService *user.DataExportService, writeTimeout time.Duration, logger *utils.Logger) *AccountHandler {
+	return &AccountHandler{
+		deletionService: deletionService,
+		exportService:   exportService,
+		writeTimeout:    writeTimeout,
+		logger:          logger.Named("account_handler"),
+	}
+}
+
+// DeleteMyAccount handles requests to delete the current user's account. The account is purged once the
+// grace period ends, unless the user signs in again before then.
+func (h *AccountHandler) DeleteMyAccount(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	// Accounts without a password send no body
+	var req models.UserDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	deletion, err := h.deletionService.RequestDeletion(r.Context(), userID, req.Password)
+	if err != nil {
+		status := models.MapErrorToHTTPStatus(err)
+		if status == http.StatusInternalServerError {
+			h.logger.Error("Failed to delete account", err, "userId", userID)
+			utils.RespondWithError(w, status, "Failed to delete account")
+			return
+		}
+		utils.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusAccepted, map[string]any{
+		"success":  true,
+		"message":  "Account scheduled for deletion",
+		"deletion": deletion,
+	})
+}
+
+// AdminDeleteUser handles requests to delete a user right away, without a grace period (admin only).
+func (h *AccountHandler) AdminDeleteUser(w http.ResponseWriter, r *http.Request) {
+	targetID, err := bson.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	if err := h.deletionService.PurgeAccount(r.Context(), targetID); err != nil {
+		status := models.MapErrorToHTTPStatus(err)
+		if status == http.StatusInternalServerError {
+			h.logger.Error("Failed to delete user", err, "targetID", targetID.Hex())
+			utils.RespondWithError(w, status, "Failed to delete user")
+			return
+		}
+		utils.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"success": true,
+		"message": "User deleted successfully",
+	})
+}
+
+// CreateDataExport handles requests for a link downloading the current user's data archive.
+func (h *AccountHandler) CreateDataExport(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	link, err := h.exportService.CreateLink(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to create data export link", err, "userId", userID)
+		utils.RespondWithError(w, models.MapErrorToHTTPStatus(err), "Failed to create data export link")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, link)
+}
+
+// DownloadDataExport handles downloads of a user's data archive through a link from CreateDataExport. The
+// archive is streamed straight from the database; a download that breaks off leaves it incomplete.
+func (h *AccountHandler) DownloadDataExport(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+	if err := h.exportService.RedeemLink(r.Context(), userID, r.URL.Query().Get("token")); err != nil {
+		utils.RespondWithError(w, models.MapErrorToHTTPStatus(err), err.Error())
+		return
+	}
+
+	archive := &archiveResponse{w: w, rc: http.NewResponseController(w), timeout: h.writeTimeout}
+	if err := h.exportService.WriteArchive(r.Context(), userID, archive); err != nil {
+		if archive.started {
+			h.logger.Debug("Data export interrupted", "userId", userID, "error", err)
+			return
+		}
+		status := models.MapErrorToHTTPStatus(err)
+		if status == http.StatusInternalServerError {
+			h.logger.Error("Failed to export data", err, "userId", userID)
+			utils.RespondWithError(w, status, "Failed to export data")
+			return
+		}
+		utils.RespondWithError(w, status, err.Error())
+	}
+}
+
+// archiveResponse writes a data archive download. It starts the response on the first write, so errors
+// before then can still be answered with an error response, and waits for the client at most the timeout
+// on every write.
+type archiveResponse struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	timeout time.Duration
+	started bool
+}
+
+// Write implements io.Writer.
+func (a *archiveResponse) Write(p []byte) (int, error) {
+	if !a.started {
+		a.started = true
+		a.w.Header().Set("Content-Type", "application/json")
+		a.w.Header().Set("Content-Disposition", `attachment; filename="listenify-data.json"`)
+		a.w.Header().Set("Cache-Control", "no-store")
+		a.w.WriteHeader(http.StatusOK)
+	}
+	if err := a.rc.SetWriteDeadline(time.Now().Add(a.timeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return 0, err
+	}
+	return a.w.Write(p)
+}
@@ -0,0 +1,108 @@
+// Package handlers contains HTTP handlers for the API.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/services/room"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// AnalyticsHandler handles HTTP requests related to the analytics export of rooms.
+type AnalyticsHandler struct {
+	exporter *room.AnalyticsExporter
+	logger   *utils.Logger
+}
+
+// NewAnalyticsHandler creates a new analytics handler.
+func NewAnalyticsHandler(exporter *room.AnalyticsExporter, logger *utils.Logger) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		exporter: exporter,
+		logger:   logger.Named("analytics_handler"),
+	}
+}
+
+// GetConfig handles requests for a room's analytics export configuration (room owner only).
+func (h *AnalyticsHandler) GetConfig(w http.ResponseWriter, r *http.Request, roomID bson.ObjectID) {
+	userID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	config, err := h.exporter.GetConfig(r.Context(), roomID, userID)
+	if err != nil {
+		h.respondWithAnalyticsError(w, err, "Failed to get analytics export configuration", roomID)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, config)
+}
+
+// UpdateConfig handles requests to change a room's analytics export configuration (room owner only).
+func (h *AnalyticsHandler) UpdateConfig(w http.ResponseWriter, r *http.Request, roomID bson.ObjectID, config *models.RoomAnalytics) {
+	userID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	updated, err := h.exporter.UpdateConfig(r.Context(), roomID, userID, config)
+	if err != nil {
+		h.respondWithAnalyticsError(w, err, "Failed to update analytics export configuration", roomID)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, updated)
+}
+
+// Export handles requests to download a room's analytics events of one day as newline-delimited JSON
+// (room owner only). The "date" query parameter selects the UTC day, formatted YYYY-MM-DD, and defaults to today.
+func (h *AnalyticsHandler) Export(w http.ResponseWriter, r *http.Request, roomID bson.ObjectID) {
+	userID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	day := time.Now().UTC()
+	if date := r.URL.Query().Get("date"); date != "" {
+		day, err = time.Parse(time.DateOnly, date)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid date, expected YYYY-MM-DD")
+			return
+		}
+	}
+
+	events, err := h.exporter.Export(r.Context(), roomID, userID, day)
+	if err != nil {
+		h.respondWithAnalyticsError(w, err, "Failed to export analytics events", roomID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="analytics-`+roomID.Hex()+"-"+day.Format(time.DateOnly)+`.ndjson"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(events)
+}
+
+// respondWithAnalyticsError maps analytics export errors to HTTP responses.
+func (h *AnalyticsHandler) respondWithAnalyticsError(w http.ResponseWriter, err error, message string, roomID bson.ObjectID) {
+	if errors.Is(err, room.ErrNotAuthorized) {
+		utils.RespondWithError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	status := models.MapErrorToHTTPStatus(err)
+	if status == http.StatusInternalServerError {
+		h.logger.Error(message, err, "roomID", roomID.Hex())
+		utils.RespondWithError(w, status, message)
+		return
+	}
+
+	utils.RespondWithError(w, status, err.Error())
+}
@@ -0,0 +1,74 @@
+// Package handlers contains HTTP handlers for the API.
+package handlers
+
+import (
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/services/system"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// maxArchivesListed caps the number of archives listed in one request.
+const maxArchivesListed = 500
+
+// ArchiveHandler handles HTTP requests related to archived history.
+type ArchiveHandler struct {
+	archiveSvc *system.HistoryArchiveService
+	logger     *utils.Logger
+}
+
+// NewArchiveHandler creates a new archive handler.
+func NewArchiveHandler(archiveSvc *system.HistoryArchiveService, logger *utils.Logger) *ArchiveHandler {
+	return &ArchiveHandler{
+		archiveSvc: archiveSvc,
+		logger:     logger.Named("archive_handler"),
+	}
+}
+
+// ListArchives handles requests to list the history archives (admin only).
+// The optional "collection" query parameter limits the list to one history collection.
+func (h *ArchiveHandler) ListArchives(w http.ResponseWriter, r *http.Request) {
+	collection := r.URL.Query().Get("collection")
+	limit := GetLimit(r, maxArchivesListed)
+
+	archives, err := h.archiveSvc.ListArchives(r.Context(), collection, limit)
+	if err != nil {
+		h.logger.Error("Failed to list history archives", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to list history archives")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"archives": archives,
+	})
+}
+
+// RestoreArchive handles requests to restore an archive into its history collection (admin only).
+func (h *ArchiveHandler) RestoreArchive(w http.ResponseWriter, r *http.Request, archiveID bson.ObjectID) {
+	restored, err := h.archiveSvc.RestoreArchive(r.Context(), archiveID)
+	if err != nil {
+		h.logger.Error("Failed to restore history archive", err, "archiveId", archiveID.Hex())
+		utils.RespondWithError(w, models.MapErrorToHTTPStatus(err), "Failed to restore history archive")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"restored": restored,
+	})
+}
+
+// ReleaseArchive handles requests to remove the restored records of an archive again (admin only).
+func (h *ArchiveHandler) ReleaseArchive(w http.ResponseWriter, r *http.Request, archiveID bson.ObjectID) {
+	released, err := h.archiveSvc.ReleaseArchive(r.Context(), archiveID)
+	if err != nil {
+		h.logger.Error("Failed to release history archive", err, "archiveId", archiveID.Hex())
+		utils.RespondWithError(w, models.MapErrorToHTTPStatus(err), "Failed to release history archive")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"released": released,
+	})
+}
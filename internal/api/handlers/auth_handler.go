@@ -4,26 +4,37 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
+	"github.com/go-chi/chi/v5"
 	"norelock.dev/listenify/backend/internal/auth"
 	"norelock.dev/listenify/backend/internal/models"
 	"norelock.dev/listenify/backend/internal/services/user"
 	"norelock.dev/listenify/backend/internal/utils"
 )
 
+// oauthStateCookie is the cookie keeping the state of an OAuth sign-in in the browser that started it.
+const oauthStateCookie = "oauth_state"
+
 // AuthHandler handles authentication-related requests.
 type AuthHandler struct {
-	userManager  *user.Manager
-	authProvider auth.Provider
-	logger       *utils.Logger
+	userManager    *user.Manager
+	guestService   *user.GuestService
+	oauthService   *user.OAuthService
+	captchaService *user.CaptchaService
+	authProvider   auth.Provider
+	logger         *utils.Logger
 }
 
 // NewAuthHandler creates a new auth handler.
-func NewAuthHandler(userManager *user.Manager, authProvider auth.Provider, logger *utils.Logger) *AuthHandler {
+func NewAuthHandler(userManager *user.Manager, guestService *user.GuestService, oauthService *user.OAuthService, captchaService *user.CaptchaService, authProvider auth.Provider, logger *utils.Logger) *AuthHandler {
 	return &AuthHandler{
-		userManager:  userManager,
-		authProvider: authProvider,
-		logger:       logger.Named("auth_handler"),
+		userManager:    userManager,
+		guestService:   guestService,
+		oauthService:   oauthService,
+		captchaService: captchaService,
+		authProvider:   authProvider,
+		logger:         logger.Named("auth_handler"),
 	}
 }
 
@@ -55,14 +66,32 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Register user
-	user, token, err := h.userManager.Register(r.Context(), req)
+	// Require a captcha from risky signups
+	if err := h.captchaService.Check(r.Context(), user.CaptchaRequest{
+		Action:   user.CaptchaActionSignup,
+		Token:    req.CaptchaToken,
+		RemoteIP: utils.GetRequestIP(r),
+	}); err != nil {
+		utils.RespondWithError(w, models.MapErrorToHTTPStatus(err), err.Error())
+		return
+	}
+
+	// Register user, taking over the guest session they registered from
+	register := h.userManager.Register
+	if req.GuestToken != "" {
+		register = h.guestService.Register
+	}
+	user, token, err := register(r.Context(), req)
 	if err != nil {
 		switch err {
 		case models.ErrEmailAlreadyExists:
 			utils.RespondWithError(w, http.StatusConflict, "Email already in use")
 		case models.ErrUsernameAlreadyExists:
 			utils.RespondWithError(w, http.StatusConflict, "Username already in use")
+		case models.ErrInvalidToken, models.ErrNotGuest:
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid guest token")
+		case models.ErrGuestAlreadyLinked:
+			utils.RespondWithError(w, http.StatusConflict, "Guest session already registered")
 		default:
 			h.logger.Error("Failed to register user", err, "email", req.Email)
 			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to register user")
@@ -77,6 +106,31 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// CaptchaConfig returns what clients need to render the captcha and which actions may require it.
+func (h *AuthHandler) CaptchaConfig(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithJSON(w, http.StatusOK, h.captchaService.Config())
+}
+
+// Guest starts a guest session for listening without an account.
+func (h *AuthHandler) Guest(w http.ResponseWriter, r *http.Request) {
+	guest, token, err := h.guestService.CreateGuest(r.Context())
+	if err != nil {
+		switch err {
+		case models.ErrGuestsDisabled:
+			utils.RespondWithError(w, http.StatusForbidden, "Guest access is disabled")
+		default:
+			h.logger.Error("Failed to create guest", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to start guest session")
+		}
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, AuthResponse{
+		User:  guest.ToPersonalUser(),
+		Token: token,
+	})
+}
+
 // Login handles user login.
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
@@ -102,6 +156,8 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 			utils.RespondWithError(w, http.StatusUnauthorized, "Invalid email or password")
 		case models.ErrAccountDisabled:
 			utils.RespondWithError(w, http.StatusForbidden, "Account is disabled")
+		case models.ErrPasswordResetRequired:
+			utils.RespondWithError(w, http.StatusForbidden, "Password reset required")
 		default:
 			h.logger.Error("Failed to login user", err, "email", req.Email)
 			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to login")
@@ -116,6 +172,96 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// OAuthStart redirects the user to a provider's consent page to sign in with their account there.
+// The sign-in's state is kept in a cookie, so only this browser can complete it.
+func (h *AuthHandler) OAuthStart(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+
+	url, state, err := h.oauthService.Start(r.Context(), provider)
+	if err != nil {
+		switch err {
+		case auth.ErrUnknownOAuthProvider:
+			utils.RespondWithError(w, http.StatusNotFound, "Sign-in provider not available")
+		default:
+			h.logger.Error("Failed to start OAuth sign-in", err, "provider", provider)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to start sign-in")
+		}
+		return
+	}
+
+	// The cookie is only sent to this provider's routes, and along with the provider's redirect back
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     strings.TrimSuffix(r.URL.Path, "/start"),
+		MaxAge:   int(h.oauthService.StateTTL().Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// OAuthCallback completes signing in with a provider once the user consented.
+func (h *AuthHandler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	query := r.URL.Query()
+
+	// The provider reports a denied consent instead of sending a code
+	if query.Get("error") != "" {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Sign-in was cancelled")
+		return
+	}
+
+	var browserState string
+	if cookie, err := r.Cookie(oauthStateCookie); err == nil {
+		browserState = cookie.Value
+	}
+
+	// The state is used up either way
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Path:     strings.TrimSuffix(r.URL.Path, "/callback"),
+		MaxAge:   -1,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	user, token, err := h.oauthService.Complete(r.Context(), provider, query.Get("code"), query.Get("state"), browserState)
+	if err != nil {
+		switch err {
+		case auth.ErrUnknownOAuthProvider:
+			utils.RespondWithError(w, http.StatusNotFound, "Sign-in provider not available")
+		case auth.ErrOAuthExchange:
+			utils.RespondWithError(w, http.StatusUnauthorized, "Failed to sign in with provider")
+		case models.ErrInvalidOAuthState:
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid or expired sign-in")
+		case models.ErrEmailNotVerified:
+			utils.RespondWithError(w, http.StatusForbidden, "A verified email address is required to sign in")
+		case models.ErrEmailAlreadyExists:
+			utils.RespondWithError(w, http.StatusConflict, "Email already in use")
+		case models.ErrOAuthAlreadyLinked:
+			utils.RespondWithError(w, http.StatusConflict, "Another account with this provider is already linked")
+		case models.ErrOAuthEmailUnverified:
+			utils.RespondWithError(w, http.StatusConflict, "An account with this email address exists, sign in with its password and verify the address to link this provider")
+		case models.ErrAccountDisabled:
+			utils.RespondWithError(w, http.StatusForbidden, "Account is disabled")
+		case models.ErrPasswordResetRequired:
+			utils.RespondWithError(w, http.StatusForbidden, "Password reset required")
+		default:
+			h.logger.Error("Failed to complete OAuth sign-in", err, "provider", provider)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to sign in")
+		}
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, AuthResponse{
+		User:  user.ToPersonalUser(),
+		Token: token,
+	})
+}
+
 // Logout handles user logout.
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context (set by auth middleware)
@@ -0,0 +1,168 @@
+// Package handlers contains HTTP handlers for the API.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/services/room"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// CalendarHandler handles HTTP requests related to scheduled room events and their ICS feeds.
+type CalendarHandler struct {
+	calendarSvc *room.CalendarService
+	logger      *utils.Logger
+}
+
+// NewCalendarHandler creates a new calendar handler.
+func NewCalendarHandler(calendarSvc *room.CalendarService, logger *utils.Logger) *CalendarHandler {
+	return &CalendarHandler{
+		calendarSvc: calendarSvc,
+		logger:      logger.Named("calendar_handler"),
+	}
+}
+
+// ListEvents handles requests to list a room's upcoming events.
+func (h *CalendarHandler) ListEvents(w http.ResponseWriter, r *http.Request, roomID bson.ObjectID) {
+	userID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	events, err := h.calendarSvc.GetEvents(r.Context(), roomID, userID)
+	if err != nil {
+		h.respondWithEventError(w, err, "Failed to get room events", roomID)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, events)
+}
+
+// CreateEvent handles requests to schedule a room event.
+func (h *CalendarHandler) CreateEvent(w http.ResponseWriter, r *http.Request, roomID bson.ObjectID, event *models.RoomEvent) {
+	userID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	created, err := h.calendarSvc.AddEvent(r.Context(), roomID, userID, event)
+	if err != nil {
+		h.respondWithEventError(w, err, "Failed to schedule room event", roomID)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, created)
+}
+
+// DeleteEvent handles requests to cancel a room event.
+func (h *CalendarHandler) DeleteEvent(w http.ResponseWriter, r *http.Request, roomID bson.ObjectID) {
+	userID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	eventID, err := bson.ObjectIDFromHex(chi.URLParam(r, "eventId"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid event ID format")
+		return
+	}
+
+	if err := h.calendarSvc.RemoveEvent(r.Context(), roomID, eventID, userID); err != nil {
+		h.respondWithEventError(w, err, "Failed to cancel room event", roomID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RoomFeed handles requests for a public room's ICS feed.
+func (h *CalendarHandler) RoomFeed(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	feed, err := h.calendarSvc.RoomFeed(r.Context(), slug)
+	if err != nil {
+		if errors.Is(err, models.ErrRoomNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Room not found")
+			return
+		}
+		h.logger.Error("Failed to render room calendar", err, "slug", slug)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to render calendar")
+		return
+	}
+
+	writeCalendar(w, feed)
+}
+
+// UserFeed handles requests for a user's ICS feed of their favorited rooms.
+// The feed is authorized by the token from GetFeedURL rather than a session, so calendar apps can subscribe.
+func (h *CalendarHandler) UserFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := bson.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil || !h.calendarSvc.VerifyUserFeedToken(userID, r.URL.Query().Get("token")) {
+		utils.RespondWithError(w, http.StatusNotFound, "Calendar not found")
+		return
+	}
+
+	feed, err := h.calendarSvc.UserFeed(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Calendar not found")
+			return
+		}
+		h.logger.Error("Failed to render user calendar", err, "userID", userID.Hex())
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to render calendar")
+		return
+	}
+
+	writeCalendar(w, feed)
+}
+
+// GetFeedURL handles requests for the current user's private calendar subscription URL.
+func (h *CalendarHandler) GetFeedURL(w http.ResponseWriter, r *http.Request) {
+	userID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	feedURL := url.URL{
+		Path:     "/users/" + userID.Hex() + "/events.ics",
+		RawQuery: url.Values{"token": {h.calendarSvc.UserFeedToken(userID)}}.Encode(),
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{
+		"url": feedURL.String(),
+	})
+}
+
+// respondWithEventError maps room event errors to HTTP responses.
+func (h *CalendarHandler) respondWithEventError(w http.ResponseWriter, err error, message string, roomID bson.ObjectID) {
+	if errors.Is(err, room.ErrNotAuthorized) {
+		utils.RespondWithError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	status := models.MapErrorToHTTPStatus(err)
+	if status == http.StatusInternalServerError {
+		h.logger.Error(message, err, "roomID", roomID.Hex())
+		utils.RespondWithError(w, status, message)
+		return
+	}
+
+	utils.RespondWithError(w, status, err.Error())
+}
+
+// writeCalendar writes an ICS feed response.
+func writeCalendar(w http.ResponseWriter, feed []byte) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="events.ics"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(feed)
+}
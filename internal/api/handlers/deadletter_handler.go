@@ -0,0 +1,123 @@
+// Package handlers contains HTTP handlers for the API.
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// maxDeadLettersListed caps the number of dead-lettered events listed in one request.
+const maxDeadLettersListed = 100
+
+// DeadLetterHandler handles HTTP requests related to events that failed to be handled.
+type DeadLetterHandler struct {
+	pubSub *managers.PubSubManager
+	logger *utils.Logger
+}
+
+// NewDeadLetterHandler creates a new dead letter handler.
+func NewDeadLetterHandler(pubSub *managers.PubSubManager, logger *utils.Logger) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		pubSub: pubSub,
+		logger: logger.Named("dead_letter_handler"),
+	}
+}
+
+// ListDeadLetters handles requests to list the dead-lettered events, the most recently failed first (admin only).
+// The "offset" and "limit" query parameters page through them.
+func (h *DeadLetterHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	limit := GetLimit(r, maxDeadLettersListed)
+	if limit == 0 {
+		limit = maxDeadLettersListed
+	}
+	offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	letters, total, err := h.pubSub.ListDeadLetters(r.Context(), offset, int64(limit))
+	if err != nil {
+		h.logger.Error("Failed to list dead-lettered events", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to list dead-lettered events")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"deadLetters": letters,
+		"total":       total,
+		"offset":      offset,
+		"limit":       limit,
+	})
+}
+
+// GetDeadLetter handles requests for a dead-lettered event (admin only).
+func (h *DeadLetterHandler) GetDeadLetter(w http.ResponseWriter, r *http.Request, id bson.ObjectID) {
+	letter, err := h.pubSub.GetDeadLetter(r.Context(), id)
+	if err != nil {
+		h.respondWithDeadLetterError(w, err, "Failed to get dead-lettered event", id)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, letter)
+}
+
+// ReplayDeadLetter handles requests to hand a dead-lettered event to its handler again (admin only).
+// If the handler fails again the event is kept, and returned with the new error.
+func (h *DeadLetterHandler) ReplayDeadLetter(w http.ResponseWriter, r *http.Request, id bson.ObjectID) {
+	letter, err := h.pubSub.ReplayDeadLetter(r.Context(), id)
+	if err != nil {
+		h.respondWithDeadLetterError(w, err, "Failed to replay dead-lettered event", id)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"replayed":   letter == nil,
+		"deadLetter": letter,
+	})
+}
+
+// ReplayDeadLetters handles requests to replay every dead-lettered event, oldest first (admin only).
+// The optional "handler" query parameter limits the replay to the events of one handler.
+func (h *DeadLetterHandler) ReplayDeadLetters(w http.ResponseWriter, r *http.Request) {
+	handler := r.URL.Query().Get("handler")
+
+	replayed, failed, err := h.pubSub.ReplayDeadLetters(r.Context(), handler)
+	if err != nil {
+		h.logger.Error("Failed to replay dead-lettered events", err, "handler", handler)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to replay dead-lettered events")
+		return
+	}
+
+	h.logger.Info("Replayed dead-lettered events", "handler", handler, "replayed", replayed, "failed", failed)
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"replayed": replayed,
+		"failed":   failed,
+	})
+}
+
+// DiscardDeadLetter handles requests to drop a dead-lettered event without replaying it (admin only).
+func (h *DeadLetterHandler) DiscardDeadLetter(w http.ResponseWriter, r *http.Request, id bson.ObjectID) {
+	if err := h.pubSub.DiscardDeadLetter(r.Context(), id); err != nil {
+		h.respondWithDeadLetterError(w, err, "Failed to discard dead-lettered event", id)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondWithDeadLetterError maps dead letter errors to HTTP responses.
+func (h *DeadLetterHandler) respondWithDeadLetterError(w http.ResponseWriter, err error, message string, id bson.ObjectID) {
+	status := models.MapErrorToHTTPStatus(err)
+	if status == http.StatusInternalServerError {
+		h.logger.Error(message, err, "id", id.Hex())
+		utils.RespondWithError(w, status, message)
+		return
+	}
+
+	utils.RespondWithError(w, status, err.Error())
+}
@@ -0,0 +1,174 @@
+// Package handlers contains HTTP handlers for the API.
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/services/developer"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// maxWebhookDeliveriesListed caps the number of delivery attempts listed in one request.
+const maxWebhookDeliveriesListed = 100
+
+// DeveloperHandler handles HTTP requests related to developer applications and their webhook deliveries.
+type DeveloperHandler struct {
+	appSvc *developer.AppService
+	logger *utils.Logger
+}
+
+// NewDeveloperHandler creates a new developer handler.
+func NewDeveloperHandler(appSvc *developer.AppService, logger *utils.Logger) *DeveloperHandler {
+	return &DeveloperHandler{
+		appSvc: appSvc,
+		logger: logger.Named("developer_handler"),
+	}
+}
+
+// CreateApp handles requests to register a developer application. The response holds its signing secret, shown only once.
+func (h *DeveloperHandler) CreateApp(w http.ResponseWriter, r *http.Request, request *models.DeveloperAppRequest) {
+	userID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	app, err := h.appSvc.CreateApp(r.Context(), userID, request)
+	if err != nil {
+		h.respondWithAppError(w, err, "Failed to register developer application", userID)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, app)
+}
+
+// ListApps handles requests to list the current user's developer applications.
+func (h *DeveloperHandler) ListApps(w http.ResponseWriter, r *http.Request) {
+	userID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	apps, err := h.appSvc.ListApps(r.Context(), userID)
+	if err != nil {
+		h.respondWithAppError(w, err, "Failed to list developer applications", userID)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, apps)
+}
+
+// GetApp handles requests for one of the current user's developer applications.
+func (h *DeveloperHandler) GetApp(w http.ResponseWriter, r *http.Request, appID bson.ObjectID) {
+	userID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	app, err := h.appSvc.GetApp(r.Context(), appID, userID)
+	if err != nil {
+		h.respondWithAppError(w, err, "Failed to get developer application", appID)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, app)
+}
+
+// UpdateApp handles requests to change one of the current user's developer applications.
+func (h *DeveloperHandler) UpdateApp(w http.ResponseWriter, r *http.Request, appID bson.ObjectID, request *models.DeveloperAppRequest) {
+	userID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	app, err := h.appSvc.UpdateApp(r.Context(), appID, userID, request)
+	if err != nil {
+		h.respondWithAppError(w, err, "Failed to update developer application", appID)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, app)
+}
+
+// DeleteApp handles requests to delete one of the current user's developer applications.
+func (h *DeveloperHandler) DeleteApp(w http.ResponseWriter, r *http.Request, appID bson.ObjectID) {
+	userID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	if err := h.appSvc.DeleteApp(r.Context(), appID, userID); err != nil {
+		h.respondWithAppError(w, err, "Failed to delete developer application", appID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RotateSecret handles requests to replace the signing secret of one of the current user's developer applications.
+func (h *DeveloperHandler) RotateSecret(w http.ResponseWriter, r *http.Request, appID bson.ObjectID) {
+	userID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	app, err := h.appSvc.RotateSecret(r.Context(), appID, userID)
+	if err != nil {
+		h.respondWithAppError(w, err, "Failed to rotate developer application secret", appID)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, app)
+}
+
+// ListDeliveries handles requests for the delivery log of one of the current user's developer applications,
+// most recent first. The "offset" and "limit" query parameters page through it.
+func (h *DeveloperHandler) ListDeliveries(w http.ResponseWriter, r *http.Request, appID bson.ObjectID) {
+	userID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	limit := GetLimit(r, maxWebhookDeliveriesListed)
+	if limit == 0 {
+		limit = maxWebhookDeliveriesListed
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	deliveries, total, err := h.appSvc.ListDeliveries(r.Context(), appID, userID, offset, limit)
+	if err != nil {
+		h.respondWithAppError(w, err, "Failed to list webhook deliveries", appID)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"deliveries": deliveries,
+		"total":      total,
+		"offset":     offset,
+		"limit":      limit,
+	})
+}
+
+// respondWithAppError maps developer application errors to HTTP responses.
+func (h *DeveloperHandler) respondWithAppError(w http.ResponseWriter, err error, message string, id bson.ObjectID) {
+	status := models.MapErrorToHTTPStatus(err)
+	if status == http.StatusInternalServerError {
+		h.logger.Error(message, err, "id", id.Hex())
+		utils.RespondWithError(w, status, message)
+		return
+	}
+
+	utils.RespondWithError(w, status, err.Error())
+}
@@ -0,0 +1,121 @@
+// Package handlers contains HTTP handlers for the API.
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/services/user"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// historyExportFlushEvery is the number of plays written between flushes of an export to the client.
+const historyExportFlushEvery = 50
+
+// historyExportLine is a line of a history export: a play and the range token resuming after it.
+type historyExportLine struct {
+	Token string              `json:"token"`
+	Play  *models.PlayHistory `json:"play"`
+}
+
+// historyExportEnd is the last line of a history export download. An export that isn't complete is
+// resumed with the token.
+type historyExportEnd struct {
+	Complete bool   `json:"complete"`
+	Token    string `json:"token,omitempty"`
+}
+
+// HistoryExportHandler handles HTTP requests related to play history exports.
+type HistoryExportHandler struct {
+	exporter     *user.HistoryExportService
+	writeTimeout time.Duration
+	logger       *utils.Logger
+}
+
+// NewHistoryExportHandler creates a new history export handler. An export is given up on when the client
+// doesn't take any of it for writeTimeout.
+func NewHistoryExportHandler(exporter *user.HistoryExportService, writeTimeout time.Duration, logger *utils.Logger) *HistoryExportHandler {
+	return &HistoryExportHandler{
+		exporter:     exporter,
+		writeTimeout: writeTimeout,
+		logger:       logger.Named("history_export_handler"),
+	}
+}
+
+// ExportMyPlays handles requests to download the current user's play history as DJ, as NDJSON streamed
+// straight from the database. The range query parameter takes the token of the last play received to
+// resume an interrupted download. The last line says whether the export is complete.
+func (h *HistoryExportHandler) ExportMyPlays(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	token := r.URL.Query().Get("range")
+
+	rc := http.NewResponseController(w)
+	encoder := json.NewEncoder(w)
+	started := false
+	written := 0
+
+	// Every write waits for the client at most the write timeout, so a stalled download holds its
+	// database cursor and export slot no longer than that
+	extendDeadline := func() error {
+		err := rc.SetWriteDeadline(time.Now().Add(h.writeTimeout))
+		if errors.Is(err, http.ErrNotSupported) {
+			return nil
+		}
+		return err
+	}
+	start := func() {
+		started = true
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="play-history.ndjson"`)
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+	}
+
+	complete, err := h.exporter.ExportPlays(r.Context(), userID, token, func(play *models.PlayHistory, next string) error {
+		if !started {
+			start()
+		}
+		if err := extendDeadline(); err != nil {
+			return err
+		}
+		if err := encoder.Encode(historyExportLine{Token: next, Play: play}); err != nil {
+			return err
+		}
+
+		token = next
+		written++
+		if written%historyExportFlushEvery == 0 {
+			return rc.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		if started {
+			// The download broke off, the client resumes with the last token it received
+			h.logger.Debug("History export interrupted", "userId", userID, "written", written, "error", err)
+			return
+		}
+		status := models.MapErrorToHTTPStatus(err)
+		if status == http.StatusInternalServerError {
+			h.logger.Error("Failed to export history", err, "userId", userID)
+			utils.RespondWithError(w, status, "Failed to export history")
+			return
+		}
+		utils.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	if !started {
+		start()
+	}
+	end := historyExportEnd{Complete: complete}
+	if !complete {
+		end.Token = token
+	}
+	if err := extendDeadline(); err == nil {
+		_ = encoder.Encode(end)
+	}
+}
@@ -0,0 +1,79 @@
+// Package handlers contains HTTP handlers for the API.
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/services/room"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// HistoryImportHandler handles HTTP requests related to bulk play history imports.
+type HistoryImportHandler struct {
+	importer *room.HistoryImporter
+	maxSize  int64
+	logger   *utils.Logger
+}
+
+// NewHistoryImportHandler creates a new history import handler. Imports larger than maxSize bytes are rejected.
+func NewHistoryImportHandler(importer *room.HistoryImporter, maxSize int64, logger *utils.Logger) *HistoryImportHandler {
+	return &HistoryImportHandler{
+		importer: importer,
+		maxSize:  maxSize,
+		logger:   logger.Named("history_import_handler"),
+	}
+}
+
+// ImportHistory handles requests to import a room's play history from another platform (admin only).
+// The body holds one play per line as NDJSON. The import runs in the background.
+func (h *HistoryImportHandler) ImportHistory(w http.ResponseWriter, r *http.Request, roomID bson.ObjectID) {
+	adminID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.RespondWithError(w, http.StatusRequestEntityTooLarge, "The import is too large, split it into several imports")
+			return
+		}
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	job, err := h.importer.StartImport(r.Context(), roomID, adminID, data)
+	if err != nil {
+		status := models.MapErrorToHTTPStatus(err)
+		if status == http.StatusInternalServerError {
+			h.logger.Error("Failed to start history import", err, "roomId", roomID.Hex())
+			utils.RespondWithError(w, status, "Failed to start history import")
+			return
+		}
+		utils.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusAccepted, job)
+}
+
+// GetImportJob handles requests for the progress of a play history import (admin only).
+func (h *HistoryImportHandler) GetImportJob(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "jobId")
+
+	job, err := h.importer.GetImportJob(r.Context(), jobID)
+	if err != nil {
+		h.logger.Error("Failed to get history import job", err, "jobId", jobID)
+		utils.RespondWithError(w, models.MapErrorToHTTPStatus(err), "Failed to get history import job")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, job)
+}
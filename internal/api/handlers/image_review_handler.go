@@ -0,0 +1,160 @@
+// Package handlers contains HTTP handlers for the API.
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/services/user"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// maxImageReviewsListed caps the number of image reviews listed in one request.
+const maxImageReviewsListed = 100
+
+// ImageReviewHandler handles HTTP requests related to reviewing changed avatars and playlist covers.
+type ImageReviewHandler struct {
+	reviewSvc *user.ImageReviewService
+	logger    *utils.Logger
+}
+
+// NewImageReviewHandler creates a new image review handler.
+func NewImageReviewHandler(reviewSvc *user.ImageReviewService, logger *utils.Logger) *ImageReviewHandler {
+	return &ImageReviewHandler{
+		reviewSvc: reviewSvc,
+		logger:    logger.Named("image_review_handler"),
+	}
+}
+
+// ListReviews handles requests to list changed images, most recently changed first (admin only).
+// The "kind", "status" and "ownerId" query parameters filter them, "offset" and "limit" page through them.
+func (h *ImageReviewHandler) ListReviews(w http.ResponseWriter, r *http.Request) {
+	limit := GetLimit(r, maxImageReviewsListed)
+	if limit == 0 {
+		limit = maxImageReviewsListed
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	filter := models.ImageReviewFilter{
+		Kind:    models.ImageKind(r.URL.Query().Get("kind")),
+		OwnerID: GetIDFromQuery(r, "ownerId"),
+		Status:  models.ImageReviewStatus(r.URL.Query().Get("status")),
+	}
+	switch filter.Kind {
+	case "", models.ImageKindAvatar, models.ImageKindPlaylistCover:
+	default:
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid image kind")
+		return
+	}
+	switch filter.Status {
+	case "", models.ImageReviewPending, models.ImageReviewApproved, models.ImageReviewRemoved:
+	default:
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid review status")
+		return
+	}
+
+	reviews, total, err := h.reviewSvc.ListReviews(r.Context(), filter, offset, limit)
+	if err != nil {
+		h.logger.Error("Failed to list image reviews", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to list image reviews")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"reviews": reviews,
+		"total":   total,
+		"offset":  offset,
+		"limit":   limit,
+	})
+}
+
+// GetReview handles requests for an image review (admin only).
+func (h *ImageReviewHandler) GetReview(w http.ResponseWriter, r *http.Request, id bson.ObjectID) {
+	review, err := h.reviewSvc.GetReview(r.Context(), id)
+	if err != nil {
+		h.respondWithImageReviewError(w, err, "Failed to get image review", id)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, review)
+}
+
+// ResolveReviews handles requests to approve or remove several changed images at once (admin only).
+// Reviews the decision couldn't be applied to are listed with the reason instead of failing the request.
+func (h *ImageReviewHandler) ResolveReviews(w http.ResponseWriter, r *http.Request, decision *models.ImageReviewDecision) {
+	adminID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	results, err := h.reviewSvc.ResolveReviews(r.Context(), adminID, decision)
+	if err != nil {
+		h.respondWithImageReviewError(w, err, "Failed to resolve image reviews", bson.ObjectID{})
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, results)
+}
+
+// ListBlockedImages handles requests to list the known-bad images (admin only).
+func (h *ImageReviewHandler) ListBlockedImages(w http.ResponseWriter, r *http.Request) {
+	images, err := h.reviewSvc.ListBlockedImages(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to list blocked images", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to list blocked images")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, images)
+}
+
+// BlockImage handles requests to add an image to the known-bad images by its hash or URL (admin only).
+func (h *ImageReviewHandler) BlockImage(w http.ResponseWriter, r *http.Request, request *models.BlockedImageRequest) {
+	adminID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	image, err := h.reviewSvc.BlockImage(r.Context(), adminID, request)
+	if err != nil {
+		h.respondWithImageReviewError(w, err, "Failed to block image", bson.ObjectID{})
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, image)
+}
+
+// UnblockImage handles requests to remove an image from the known-bad images (admin only).
+func (h *ImageReviewHandler) UnblockImage(w http.ResponseWriter, r *http.Request, id bson.ObjectID) {
+	adminID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	if err := h.reviewSvc.UnblockImage(r.Context(), id, adminID); err != nil {
+		h.respondWithImageReviewError(w, err, "Failed to unblock image", id)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondWithImageReviewError maps image review errors to HTTP responses.
+func (h *ImageReviewHandler) respondWithImageReviewError(w http.ResponseWriter, err error, message string, id bson.ObjectID) {
+	status := models.MapErrorToHTTPStatus(err)
+	if status == http.StatusInternalServerError {
+		h.logger.Error(message, err, "id", id.Hex())
+		utils.RespondWithError(w, status, message)
+		return
+	}
+
+	utils.RespondWithError(w, status, err.Error())
+}
@@ -0,0 +1,42 @@
+// Package handlers contains HTTP handlers for the API.
+package handlers
+
+import (
+	"net/http"
+
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// LogHandler handles HTTP requests for searching recent server logs.
+type LogHandler struct {
+	traceLogs *utils.TraceLogBuffer
+	logger    *utils.Logger
+}
+
+// NewLogHandler creates a new log handler. A nil buffer disables log search.
+func NewLogHandler(traceLogs *utils.TraceLogBuffer, logger *utils.Logger) *LogHandler {
+	return &LogHandler{
+		traceLogs: traceLogs,
+		logger:    logger.Named("log_handler"),
+	}
+}
+
+// Search handles requests for the log lines of a request by its trace ID (admin only).
+// Only recent lines logged by this server instance are searched.
+func (h *LogHandler) Search(w http.ResponseWriter, r *http.Request) {
+	if h.traceLogs == nil {
+		utils.RespondWithError(w, http.StatusServiceUnavailable, "Log search is disabled")
+		return
+	}
+
+	traceID := r.URL.Query().Get("traceId")
+	if traceID == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "traceId parameter is required")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"traceId": traceID,
+		"entries": h.traceLogs.Search(traceID),
+	})
+}
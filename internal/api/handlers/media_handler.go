@@ -3,6 +3,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -10,20 +11,25 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"norelock.dev/listenify/backend/internal/models"
 	"norelock.dev/listenify/backend/internal/services/media"
+	"norelock.dev/listenify/backend/internal/services/user"
 	"norelock.dev/listenify/backend/internal/utils"
 )
 
 // MediaHandler handles HTTP requests related to media operations.
 type MediaHandler struct {
-	mediaResolver *media.Resolver
-	logger        *utils.Logger
+	mediaResolver  *media.Resolver
+	previewService *media.PreviewService
+	userManager    *user.Manager
+	logger         *utils.Logger
 }
 
 // NewMediaHandler creates a new media handler.
-func NewMediaHandler(mediaResolver *media.Resolver, logger *utils.Logger) *MediaHandler {
+func NewMediaHandler(mediaResolver *media.Resolver, previewService *media.PreviewService, userManager *user.Manager, logger *utils.Logger) *MediaHandler {
 	return &MediaHandler{
-		mediaResolver: mediaResolver,
-		logger:        logger.Named("media_handler"),
+		mediaResolver:  mediaResolver,
+		previewService: previewService,
+		userManager:    userManager,
+		logger:         logger.Named("media_handler"),
 	}
 }
 
@@ -60,9 +66,28 @@ func (h *MediaHandler) Search(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Age-restricted results are only shown to users who attested being adults
+	userID, _ := r.Context().Value("userID").(string)
+	if !h.isAdult(r, userID) {
+		response = media.WithoutAgeRestricted(response)
+	}
+
 	utils.RespondWithJSON(w, http.StatusOK, response)
 }
 
+// isAdult checks whether the requesting user attested being an adult.
+func (h *MediaHandler) isAdult(r *http.Request, userID string) bool {
+	if userID == "" {
+		return false
+	}
+	adult, err := h.userManager.IsAdult(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to check user age", err, "userID", userID)
+		return false
+	}
+	return adult
+}
+
 // Resolve handles requests to resolve a media item.
 func (h *MediaHandler) Resolve(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
@@ -90,6 +115,10 @@ func (h *MediaHandler) Resolve(w http.ResponseWriter, r *http.Request) {
 	// Resolve media
 	media, err := h.mediaResolver.Resolve(r.Context(), source, sourceID, userID)
 	if err != nil {
+		if errors.Is(err, models.ErrMediaTakenDown) {
+			utils.RespondWithError(w, http.StatusUnavailableForLegalReasons, err.Error())
+			return
+		}
 		h.logger.Error("Failed to resolve media", err, "source", source, "sourceID", sourceID)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to resolve media")
 		return
@@ -121,6 +150,44 @@ func (h *MediaHandler) Proxy(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, streamURL, http.StatusFound)
 }
 
+// Preview handles requests to play the first seconds of a search result before adding it to a playlist.
+// Previews are only served for providers whose terms permit it.
+func (h *MediaHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	id := chi.URLParam(r, "id")
+
+	if provider == "" || id == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Provider and ID are required")
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(string)
+	preview, err := h.previewService.Preview(r.Context(), userID, provider, id)
+	if err != nil {
+		status := models.MapErrorToHTTPStatus(err)
+		if status == http.StatusInternalServerError {
+			h.logger.Error("Failed to get preview", err, "provider", provider, "id", id)
+			utils.RespondWithError(w, status, "Failed to get preview")
+			return
+		}
+		utils.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", preview.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(preview.Content)))
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	if preview.Cached {
+		w.Header().Set("X-Cache", "HIT")
+	} else {
+		w.Header().Set("X-Cache", "MISS")
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(preview.Content); err != nil {
+		h.logger.Debug("Failed to write preview", "provider", provider, "id", id, "error", err)
+	}
+}
+
 // GetMedia handles requests to get a media item by ID.
 func (h *MediaHandler) GetMedia(w http.ResponseWriter, r *http.Request) {
 	// Get media ID from URL parameter
@@ -169,6 +236,10 @@ func (h *MediaHandler) AddMedia(w http.ResponseWriter, r *http.Request) {
 	// Resolve media
 	media, err := h.mediaResolver.Resolve(r.Context(), req.Type, req.SourceID, userID)
 	if err != nil {
+		if errors.Is(err, models.ErrMediaTakenDown) {
+			utils.RespondWithError(w, http.StatusUnavailableForLegalReasons, err.Error())
+			return
+		}
 		h.logger.Error("Failed to add media", err, "source", req.Type, "sourceID", req.SourceID)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to add media")
 		return
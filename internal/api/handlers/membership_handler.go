@@ -0,0 +1,39 @@
+// Package handlers contains HTTP handlers for the API.
+package handlers
+
+import (
+	"net/http"
+
+	"norelock.dev/listenify/backend/internal/services/room"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// MembershipHandler handles HTTP requests related to the reconciliation of room memberships.
+type MembershipHandler struct {
+	reconciler *room.MembershipReconciler
+	logger     *utils.Logger
+}
+
+// NewMembershipHandler creates a new membership handler.
+func NewMembershipHandler(reconciler *room.MembershipReconciler, logger *utils.Logger) *MembershipHandler {
+	return &MembershipHandler{
+		reconciler: reconciler,
+		logger:     logger.Named("membership_handler"),
+	}
+}
+
+// GetReport handles requests for the drift found by the last reconciliation of room memberships (admin only).
+func (h *MembershipHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.reconciler.GetReport(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to get membership report", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get membership report")
+		return
+	}
+	if report == nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Room memberships have not been reconciled yet")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, report)
+}
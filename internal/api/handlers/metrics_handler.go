@@ -0,0 +1,115 @@
+// Package handlers contains HTTP handlers for the API.
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"norelock.dev/listenify/backend/internal/services/room"
+	"norelock.dev/listenify/backend/internal/services/system"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// defaultMetricsHistoryWindow is the range returned when no start time is given.
+	defaultMetricsHistoryWindow = 24 * time.Hour
+
+	// maxMetricsHistoryPoints caps the number of snapshots returned in one request.
+	maxMetricsHistoryPoints = 10000
+
+	// defaultSlowestTransitionRooms is the number of rooms listed when no limit is given.
+	defaultSlowestTransitionRooms = 20
+
+	// maxSlowestTransitionRooms caps the number of rooms listed in one request.
+	maxSlowestTransitionRooms = 500
+)
+
+// MetricsHandler handles HTTP requests related to recorded metrics.
+type MetricsHandler struct {
+	historySvc  *system.MetricsHistoryService
+	transitions *room.TransitionMonitor
+	logger      *utils.Logger
+}
+
+// NewMetricsHandler creates a new metrics handler.
+func NewMetricsHandler(historySvc *system.MetricsHistoryService, transitions *room.TransitionMonitor, logger *utils.Logger) *MetricsHandler {
+	return &MetricsHandler{
+		historySvc:  historySvc,
+		transitions: transitions,
+		logger:      logger.Named("metrics_handler"),
+	}
+}
+
+// GetHistory handles requests to fetch a range of the metrics history (admin only).
+// The range is given by the RFC 3339 "from" and "to" query parameters and defaults to the last 24 hours.
+func (h *MetricsHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	to := time.Now()
+	if toStr := query.Get("to"); toStr != "" {
+		parsed, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid to parameter")
+			return
+		}
+		to = parsed
+	}
+
+	from := to.Add(-defaultMetricsHistoryWindow)
+	if fromStr := query.Get("from"); fromStr != "" {
+		parsed, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid from parameter")
+			return
+		}
+		from = parsed
+	}
+
+	if !from.Before(to) {
+		utils.RespondWithError(w, http.StatusBadRequest, "from must be before to")
+		return
+	}
+
+	limit := maxMetricsHistoryPoints
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxMetricsHistoryPoints {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid limit parameter")
+			return
+		}
+		limit = parsed
+	}
+
+	snapshots, err := h.historySvc.GetRange(r.Context(), from, to, limit)
+	if err != nil {
+		h.logger.Error("Failed to get metrics history", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get metrics history")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"from":      from,
+		"to":        to,
+		"snapshots": snapshots,
+	})
+}
+
+// GetSlowestTransitions handles requests to list the rooms whose recent track changes on this node were
+// slowest, with the time spent in each stage and the late and missed track changes (admin only).
+// The "limit" query parameter caps the number of rooms listed.
+func (h *MetricsHandler) GetSlowestTransitions(w http.ResponseWriter, r *http.Request) {
+	limit := defaultSlowestTransitionRooms
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxSlowestTransitionRooms {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid limit parameter")
+			return
+		}
+		limit = parsed
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"rooms": h.transitions.Slowest(limit),
+	})
+}
@@ -0,0 +1,201 @@
+// Package handlers contains HTTP handlers for the API.
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/services/user"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// RecoveryHandler handles HTTP requests for account recovery support tools.
+type RecoveryHandler struct {
+	recoveryService *user.RecoveryService
+	captchaService  *user.CaptchaService
+	logger          *utils.Logger
+}
+
+// NewRecoveryHandler creates a new recovery handler.
+func NewRecoveryHandler(recoveryService *user.RecoveryService, captchaService *user.CaptchaService, logger *utils.Logger) *RecoveryHandler {
+	return &RecoveryHandler{
+		recoveryService: recoveryService,
+		captchaService:  captchaService,
+		logger:          logger.Named("recovery_handler"),
+	}
+}
+
+// ResetEmailRequest represents a request to change a user's email address.
+type ResetEmailRequest struct {
+	// Email is the new email address, which the user has to verify.
+	Email string `json:"email" validate:"required,email"`
+}
+
+// MergeUsersRequest represents a request to merge a duplicate account into another account.
+type MergeUsersRequest struct {
+	// SourceID is the duplicate account, which is deactivated after the merge.
+	SourceID string `json:"sourceId" validate:"required"`
+
+	// TargetID is the account that receives the duplicate's data.
+	TargetID string `json:"targetId" validate:"required"`
+
+	// DryRun reports what would be merged without merging.
+	DryRun bool `json:"dryRun"`
+}
+
+// VerifyEmailRequest represents a request to verify a pending email address.
+type VerifyEmailRequest struct {
+	// Token is the verification token sent to the new address.
+	Token string `json:"token" validate:"required"`
+}
+
+// AdminResetEmail handles requests to change a user's email address (admin only).
+func (h *RecoveryHandler) AdminResetEmail(w http.ResponseWriter, r *http.Request) {
+	adminIDStr := r.Context().Value("userID").(string)
+	targetIDStr := chi.URLParam(r, "id")
+
+	var req ResetEmailRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	expiresAt, err := h.recoveryService.ResetEmail(r.Context(), targetIDStr, req.Email, adminIDStr)
+	if err != nil {
+		h.logger.Error("Failed to reset email", err, "targetID", targetIDStr)
+		utils.RespondWithError(w, models.MapErrorToHTTPStatus(err), "Failed to reset email")
+		return
+	}
+
+	// The verification token only goes to the new address
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"pendingEmail": req.Email,
+		"expiresAt":    expiresAt,
+	})
+}
+
+// AdminForcePasswordReset handles requests to force a user to reset their password (admin only).
+func (h *RecoveryHandler) AdminForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	adminIDStr := r.Context().Value("userID").(string)
+	targetIDStr := chi.URLParam(r, "id")
+
+	token, err := h.recoveryService.ForcePasswordReset(r.Context(), targetIDStr, adminIDStr)
+	if err != nil {
+		h.logger.Error("Failed to force password reset", err, "targetID", targetIDStr)
+		utils.RespondWithError(w, models.MapErrorToHTTPStatus(err), "Failed to force password reset")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"resetToken": token,
+	})
+}
+
+// AdminMergeUsers handles requests to merge a duplicate account into another account (admin only).
+// A dry run returns the merge report, otherwise the merge runs in the background.
+func (h *RecoveryHandler) AdminMergeUsers(w http.ResponseWriter, r *http.Request) {
+	adminIDStr := r.Context().Value("userID").(string)
+
+	var req MergeUsersRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	if req.DryRun {
+		report, err := h.recoveryService.PlanMerge(r.Context(), req.SourceID, req.TargetID)
+		if err != nil {
+			h.logger.Error("Failed to plan account merge", err, "sourceID", req.SourceID, "targetID", req.TargetID)
+			utils.RespondWithError(w, models.MapErrorToHTTPStatus(err), "Failed to plan account merge")
+			return
+		}
+		utils.RespondWithJSON(w, http.StatusOK, report)
+		return
+	}
+
+	job, err := h.recoveryService.StartMerge(r.Context(), req.SourceID, req.TargetID, adminIDStr)
+	if err != nil {
+		h.logger.Error("Failed to start account merge", err, "sourceID", req.SourceID, "targetID", req.TargetID)
+		utils.RespondWithError(w, models.MapErrorToHTTPStatus(err), "Failed to start account merge")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusAccepted, job)
+}
+
+// AdminGetMergeJob handles requests to get the status of an account merge (admin only).
+func (h *RecoveryHandler) AdminGetMergeJob(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "jobId")
+
+	job, err := h.recoveryService.GetMergeJob(r.Context(), jobID)
+	if err != nil {
+		h.logger.Error("Failed to get account merge job", err, "jobID", jobID)
+		utils.RespondWithError(w, models.MapErrorToHTTPStatus(err), "Failed to get account merge job")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, job)
+}
+
+// VerifyEmail handles requests to verify an email address set by support.
+func (h *RecoveryHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	if err := h.recoveryService.ConfirmEmail(r.Context(), req.Token); err != nil {
+		h.logger.Debug("Failed to verify email", "error", err)
+		utils.RespondWithError(w, models.MapErrorToHTTPStatus(err), "Failed to verify email")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"success": true,
+		"message": "Email verified successfully",
+	})
+}
+
+// ResetPassword handles requests to set a new password after a forced password reset.
+func (h *RecoveryHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.UserPasswordResetConfirmRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	// Require a captcha from risky resets
+	if err := h.captchaService.Check(r.Context(), user.CaptchaRequest{
+		Action:   user.CaptchaActionPasswordReset,
+		Token:    req.CaptchaToken,
+		RemoteIP: utils.GetRequestIP(r),
+	}); err != nil {
+		utils.RespondWithError(w, models.MapErrorToHTTPStatus(err), err.Error())
+		return
+	}
+
+	if err := h.recoveryService.ResetPassword(r.Context(), req); err != nil {
+		h.logger.Debug("Failed to reset password", "error", err)
+		utils.RespondWithError(w, models.MapErrorToHTTPStatus(err), "Failed to reset password")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"success": true,
+		"message": "Password reset successfully",
+	})
+}
+
+// decode reads and validates a request body, responding with an error if it is invalid.
+func (h *RecoveryHandler) decode(w http.ResponseWriter, r *http.Request, req any) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return false
+	}
+
+	if err := utils.Validate(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return false
+	}
+
+	return true
+}
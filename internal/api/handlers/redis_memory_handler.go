@@ -0,0 +1,46 @@
+// Package handlers contains HTTP handlers for the API.
+package handlers
+
+import (
+	"net/http"
+
+	"norelock.dev/listenify/backend/internal/services/system"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// RedisMemoryHandler handles HTTP requests related to Redis memory usage.
+type RedisMemoryHandler struct {
+	redisMemory *system.RedisMemoryService
+	logger      *utils.Logger
+}
+
+// NewRedisMemoryHandler creates a new Redis memory handler.
+func NewRedisMemoryHandler(redisMemory *system.RedisMemoryService, logger *utils.Logger) *RedisMemoryHandler {
+	return &RedisMemoryHandler{
+		redisMemory: redisMemory,
+		logger:      logger.Named("redis_memory_handler"),
+	}
+}
+
+// GetReport handles requests for the Redis memory usage by key family (admin only).
+// It returns the last measurement, or measures memory now when the "live" query parameter is "true".
+func (h *RedisMemoryHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	var report *system.RedisMemoryReport
+	var err error
+	if r.URL.Query().Get("live") == "true" {
+		report, err = h.redisMemory.Measure(r.Context())
+	} else {
+		report, err = h.redisMemory.GetReport(r.Context())
+	}
+	if err != nil {
+		h.logger.Error("Failed to get Redis memory report", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get Redis memory report")
+		return
+	}
+	if report == nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Redis memory has not been measured yet")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,156 @@
+// Package handlers contains HTTP handlers for the API.
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/services/room"
+	"norelock.dev/listenify/backend/internal/services/user"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// maxRoomReportsListed caps the number of room reports listed in one request.
+const maxRoomReportsListed = 100
+
+// ReportHandler handles HTTP requests related to reports of whole rooms and their triage.
+type ReportHandler struct {
+	reportSvc      *room.RoomReportService
+	captchaService *user.CaptchaService
+	logger         *utils.Logger
+}
+
+// NewReportHandler creates a new report handler.
+func NewReportHandler(reportSvc *room.RoomReportService, captchaService *user.CaptchaService, logger *utils.Logger) *ReportHandler {
+	return &ReportHandler{
+		reportSvc:      reportSvc,
+		captchaService: captchaService,
+		logger:         logger.Named("report_handler"),
+	}
+}
+
+// ReportRoom handles requests to report a room to the platform admins.
+func (h *ReportHandler) ReportRoom(w http.ResponseWriter, r *http.Request, roomID bson.ObjectID, request *models.RoomReportRequest) {
+	userID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	// Require a captcha from risky reporters
+	if err := h.captchaService.Check(r.Context(), user.CaptchaRequest{
+		Action:   user.CaptchaActionReport,
+		Token:    request.CaptchaToken,
+		RemoteIP: utils.GetRequestIP(r),
+		UserID:   userID.Hex(),
+	}); err != nil {
+		utils.RespondWithError(w, models.MapErrorToHTTPStatus(err), err.Error())
+		return
+	}
+
+	report, err := h.reportSvc.ReportRoom(r.Context(), roomID, userID, request)
+	if err != nil {
+		h.respondWithReportError(w, err, "Failed to report room", roomID)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, report)
+}
+
+// ListReports handles requests to list room reports, oldest first (admin only).
+// The "status" and "roomId" query parameters filter them, "offset" and "limit" page through them.
+func (h *ReportHandler) ListReports(w http.ResponseWriter, r *http.Request) {
+	limit := GetLimit(r, maxRoomReportsListed)
+	if limit == 0 {
+		limit = maxRoomReportsListed
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	filter := models.RoomReportFilter{
+		RoomID: GetIDFromQuery(r, "roomId"),
+		Status: models.RoomReportStatus(r.URL.Query().Get("status")),
+	}
+	switch filter.Status {
+	case "", models.RoomReportPending, models.RoomReportActioned, models.RoomReportDismissed:
+	default:
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid report status")
+		return
+	}
+
+	reports, total, err := h.reportSvc.ListReports(r.Context(), filter, offset, limit)
+	if err != nil {
+		h.logger.Error("Failed to list room reports", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to list room reports")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"reports": reports,
+		"total":   total,
+		"offset":  offset,
+		"limit":   limit,
+	})
+}
+
+// GetReport handles requests for a room report (admin only).
+func (h *ReportHandler) GetReport(w http.ResponseWriter, r *http.Request, id bson.ObjectID) {
+	report, err := h.reportSvc.GetReport(r.Context(), id)
+	if err != nil {
+		h.respondWithReportError(w, err, "Failed to get room report", id)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, report)
+}
+
+// ResolveReport handles requests to decide on a room report and act on the room (admin only).
+// The room's other pending reports are resolved along with it.
+func (h *ReportHandler) ResolveReport(w http.ResponseWriter, r *http.Request, id bson.ObjectID, resolution *models.RoomReportResolution) {
+	adminID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	report, err := h.reportSvc.ResolveReport(r.Context(), id, adminID, resolution)
+	if err != nil {
+		h.respondWithReportError(w, err, "Failed to resolve room report", id)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, report)
+}
+
+// RestoreRoom handles requests to lift every enforcement action from a room (admin only).
+func (h *ReportHandler) RestoreRoom(w http.ResponseWriter, r *http.Request, roomID bson.ObjectID) {
+	adminID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	restored, err := h.reportSvc.RestoreRoom(r.Context(), roomID, adminID)
+	if err != nil {
+		h.respondWithReportError(w, err, "Failed to restore room", roomID)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, restored)
+}
+
+// respondWithReportError maps room report errors to HTTP responses.
+func (h *ReportHandler) respondWithReportError(w http.ResponseWriter, err error, message string, id bson.ObjectID) {
+	status := models.MapErrorToHTTPStatus(err)
+	if status == http.StatusInternalServerError {
+		h.logger.Error(message, err, "id", id.Hex())
+		utils.RespondWithError(w, status, message)
+		return
+	}
+
+	utils.RespondWithError(w, status, err.Error())
+}
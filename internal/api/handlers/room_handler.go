@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"slices"
 	"strconv"
+	"strings"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"norelock.dev/listenify/backend/internal/models"
@@ -35,6 +36,7 @@ func (h *RoomHandler) List(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
+	rooms = h.visibleRooms(r, rooms)
 
 	if len(rooms) == 0 {
 		utils.RespondWithError(w, http.StatusNotFound, "No active rooms found")
@@ -53,6 +55,7 @@ func (h *RoomHandler) ListPopular(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
+	rooms = h.visibleRooms(r, rooms)
 
 	if len(rooms) == 0 {
 		utils.RespondWithError(w, http.StatusNotFound, "No popular rooms found")
@@ -72,6 +75,7 @@ func (h *RoomHandler) Search(w http.ResponseWriter, r *http.Request) {
 	// Get query, skip, and sort parameters from the request
 	query := r.URL.Query().Get("query")
 	sort := r.URL.Query().Get("sort")
+	language := strings.ToLower(r.URL.Query().Get("language"))
 	skip, err := strconv.Atoi(r.URL.Query().Get("skip"))
 	if err != nil {
 		skip = 0
@@ -79,16 +83,20 @@ func (h *RoomHandler) Search(w http.ResponseWriter, r *http.Request) {
 	skip = max(0, skip)
 
 	rooms, total, err := h.mgr.SearchRooms(r.Context(), models.RoomSearchCriteria{
-		Query:  query,
-		SortBy: sort,
-		Limit:  limit,
-		Page:   skip,
+		Query:    query,
+		Language: language,
+		SortBy:   sort,
+		Limit:    limit,
+		Page:     skip,
 	})
 	if err != nil {
 		h.logger.Error("Failed to search rooms", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
+	visible := h.visibleRooms(r, rooms)
+	total -= int64(len(rooms) - len(visible))
+	rooms = visible
 
 	if len(rooms) == 0 {
 		utils.RespondWithError(w, http.StatusNotFound, "No rooms found")
@@ -101,6 +109,12 @@ func (h *RoomHandler) Search(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// visibleRooms leaves the rooms minors can't see out of a room listing shown to the requesting user.
+func (h *RoomHandler) visibleRooms(r *http.Request, rooms []*models.Room) []*models.Room {
+	userID, _ := r.Context().Value("userID").(string)
+	return h.mgr.VisibleRooms(r.Context(), userID, rooms)
+}
+
 func (h *RoomHandler) Get(w http.ResponseWriter, r *http.Request, roomID bson.ObjectID) {
 	room, err := h.mgr.GetRoom(r.Context(), roomID)
 	if err != nil {
@@ -171,6 +185,7 @@ type RoomCreateRequest struct {
 	Description string              `json:"description"`
 	Slug        string              `json:"slug"`
 	Settings    models.RoomSettings `json:"settings"`
+	Expiry      *models.RoomExpiry  `json:"expiry,omitempty"`
 }
 
 func (h *RoomHandler) Create(w http.ResponseWriter, r *http.Request, data *RoomCreateRequest) {
@@ -197,10 +212,19 @@ func (h *RoomHandler) Create(w http.ResponseWriter, r *http.Request, data *RoomC
 		Moderators:  []bson.ObjectID{userID},
 		BannedUsers: []bson.ObjectID{},
 		IsActive:    true,
+		Expiry:      data.Expiry,
 	}
 
 	createdRoom, err := h.mgr.CreateRoom(r.Context(), room)
 	if err != nil {
+		if errors.Is(err, models.ErrTrustLevelTooLow) {
+			utils.RespondWithError(w, http.StatusForbidden, "Your trust level is too low to create rooms")
+			return
+		}
+		if errors.Is(err, models.ErrInvalidRoomExpiry) || errors.Is(err, models.ErrInvalidVoteSettings) || errors.Is(err, models.ErrInvalidChatAppearance) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		h.logger.Error("Failed to create room", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
 		return
@@ -249,7 +273,7 @@ func (h *RoomHandler) Update(w http.ResponseWriter, r *http.Request, id bson.Obj
 	}
 
 	// Check if user is creator or moderator
-	if room.CreatedBy != userID && !slices.Contains(room.Moderators, userID) {
+	if !slices.Contains(room.Staff(), userID) {
 		utils.RespondWithError(w, http.StatusForbidden, "You are not allowed to update this room")
 		return
 	}
@@ -262,6 +286,10 @@ func (h *RoomHandler) Update(w http.ResponseWriter, r *http.Request, id bson.Obj
 
 	updatedRoom, err := h.mgr.UpdateRoom(r.Context(), room)
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidVoteSettings) || errors.Is(err, models.ErrInvalidChatAppearance) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		h.logger.Error("Failed to update room", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
 		return
@@ -379,3 +407,35 @@ func (h *RoomHandler) PostFavorite(w http.ResponseWriter, r *http.Request, id bs
 func (h *RoomHandler) DeleteFavorite(w http.ResponseWriter, r *http.Request, id bson.ObjectID) {
 
 }
+
+// RoomLanguageRequest sets the language of a room. An empty language goes back to detecting it from the chat.
+type RoomLanguageRequest struct {
+	Language string `json:"language"`
+}
+
+// PutLanguage sets the language of a room, overriding the one detected from its chat. Only the room's owner can set it.
+func (h *RoomHandler) PutLanguage(w http.ResponseWriter, r *http.Request, id bson.ObjectID, data *RoomLanguageRequest) {
+	userID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	updatedRoom, err := h.mgr.SetLanguage(r.Context(), id, userID, data.Language)
+	if err != nil {
+		if errors.Is(err, room.ErrNotAuthorized) {
+			utils.RespondWithError(w, http.StatusForbidden, "You are not allowed to set the language of this room")
+			return
+		}
+		status := models.MapErrorToHTTPStatus(err)
+		if status == http.StatusInternalServerError {
+			h.logger.Error("Failed to set room language", err, "roomId", id.Hex())
+			utils.RespondWithError(w, status, "Internal server error")
+			return
+		}
+		utils.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, updatedRoom)
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+
+	"norelock.dev/listenify/backend/internal/services/system"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// SnapshotHandler handles HTTP requests for the platform state snapshot read by the analytics pipeline.
+type SnapshotHandler struct {
+	snapshotSvc *system.SnapshotService
+	logger      *utils.Logger
+}
+
+// NewSnapshotHandler creates a new snapshot handler.
+func NewSnapshotHandler(snapshotSvc *system.SnapshotService, logger *utils.Logger) *SnapshotHandler {
+	return &SnapshotHandler{
+		snapshotSvc: snapshotSvc,
+		logger:      logger.Named("snapshot_handler"),
+	}
+}
+
+// GetSnapshot handles requests for the current state of the platform's active rooms, their queues and
+// listener counts as one document (internal callers with a service token only).
+func (h *SnapshotHandler) GetSnapshot(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := h.snapshotSvc.GetSnapshot(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to get platform state snapshot", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get snapshot")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.RespondWithJSON(w, http.StatusOK, snapshot)
+}
@@ -0,0 +1,146 @@
+// Package handlers contains HTTP handlers for the API.
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/services/system"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// maxIncidentsListed caps the number of incidents listed in one request.
+const maxIncidentsListed = 100
+
+// StatusHandler handles HTTP requests related to the public status page and its incidents.
+type StatusHandler struct {
+	statusSvc *system.StatusService
+	logger    *utils.Logger
+}
+
+// NewStatusHandler creates a new status handler.
+func NewStatusHandler(statusSvc *system.StatusService, logger *utils.Logger) *StatusHandler {
+	return &StatusHandler{
+		statusSvc: statusSvc,
+		logger:    logger.Named("status_handler"),
+	}
+}
+
+// GetStatus handles requests for the status page feed: component health, active incidents and recent
+// uptime. It is public and cached briefly, so external status pages can poll it.
+func (h *StatusHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	page, err := h.statusSvc.GetStatus(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to get status page", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get status")
+		return
+	}
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.statusSvc.CacheTTL().Seconds())))
+	utils.RespondWithJSON(w, http.StatusOK, page)
+}
+
+// CreateIncident handles requests to open an incident on the status page (admin only).
+func (h *StatusHandler) CreateIncident(w http.ResponseWriter, r *http.Request, request *models.IncidentRequest) {
+	adminID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	incident, err := h.statusSvc.CreateIncident(r.Context(), adminID, request)
+	if err != nil {
+		h.respondWithIncidentError(w, err, "Failed to create incident", bson.ObjectID{})
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, incident)
+}
+
+// ListIncidents handles requests to list incidents, latest started first (admin only).
+// The "active" query parameter limits it to unresolved incidents, "since" to incidents active since an
+// RFC 3339 time, and "offset" and "limit" page through it.
+func (h *StatusHandler) ListIncidents(w http.ResponseWriter, r *http.Request) {
+	limit := GetLimit(r, maxIncidentsListed)
+	if limit == 0 {
+		limit = maxIncidentsListed
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	query := r.URL.Query()
+	filter := models.IncidentFilter{
+		Active: query.Get("active") == "true",
+	}
+	if since := query.Get("since"); since != "" {
+		filter.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid since time")
+			return
+		}
+	}
+
+	incidents, total, err := h.statusSvc.ListIncidents(r.Context(), filter, offset, limit)
+	if err != nil {
+		h.logger.Error("Failed to list incidents", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to list incidents")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"incidents": incidents,
+		"total":     total,
+		"offset":    offset,
+		"limit":     limit,
+	})
+}
+
+// GetIncident handles requests for an incident (admin only).
+func (h *StatusHandler) GetIncident(w http.ResponseWriter, r *http.Request, id bson.ObjectID) {
+	incident, err := h.statusSvc.GetIncident(r.Context(), id)
+	if err != nil {
+		h.respondWithIncidentError(w, err, "Failed to get incident", id)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, incident)
+}
+
+// UpdateIncident handles requests to update or resolve an incident (admin only).
+func (h *StatusHandler) UpdateIncident(w http.ResponseWriter, r *http.Request, id bson.ObjectID, request *models.IncidentRequest) {
+	incident, err := h.statusSvc.UpdateIncident(r.Context(), id, request)
+	if err != nil {
+		h.respondWithIncidentError(w, err, "Failed to update incident", id)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, incident)
+}
+
+// DeleteIncident handles requests to delete an incident (admin only).
+func (h *StatusHandler) DeleteIncident(w http.ResponseWriter, r *http.Request, id bson.ObjectID) {
+	if err := h.statusSvc.DeleteIncident(r.Context(), id); err != nil {
+		h.respondWithIncidentError(w, err, "Failed to delete incident", id)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondWithIncidentError maps incident errors to HTTP responses.
+func (h *StatusHandler) respondWithIncidentError(w http.ResponseWriter, err error, message string, id bson.ObjectID) {
+	status := models.MapErrorToHTTPStatus(err)
+	if status == http.StatusInternalServerError {
+		h.logger.Error(message, err, "id", id.Hex())
+		utils.RespondWithError(w, status, message)
+		return
+	}
+
+	utils.RespondWithError(w, status, err.Error())
+}
@@ -0,0 +1,109 @@
+// Package handlers contains HTTP handlers for the API.
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/services/media"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// maxTakedownsListed caps the number of takedowns listed in one request.
+const maxTakedownsListed = 100
+
+// TakedownHandler handles HTTP requests related to media takedowns after DMCA and other content complaints.
+type TakedownHandler struct {
+	takedownSvc *media.TakedownService
+	logger      *utils.Logger
+}
+
+// NewTakedownHandler creates a new takedown handler.
+func NewTakedownHandler(takedownSvc *media.TakedownService, logger *utils.Logger) *TakedownHandler {
+	return &TakedownHandler{
+		takedownSvc: takedownSvc,
+		logger:      logger.Named("takedown_handler"),
+	}
+}
+
+// TakeDown handles requests to take a media source down after a content complaint (admin only).
+func (h *TakedownHandler) TakeDown(w http.ResponseWriter, r *http.Request, request *models.MediaTakedownRequest) {
+	adminID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	takedown, err := h.takedownSvc.TakeDown(r.Context(), adminID, request)
+	if err != nil {
+		h.respondWithTakedownError(w, err, "Failed to take media down", request.MediaID)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, takedown)
+}
+
+// ListTakedowns handles requests to list the takedown registry, newest first (admin only).
+// The "type", "sourceId" and "reason" query parameters filter it, "offset" and "limit" page through it.
+func (h *TakedownHandler) ListTakedowns(w http.ResponseWriter, r *http.Request) {
+	limit := GetLimit(r, maxTakedownsListed)
+	if limit == 0 {
+		limit = maxTakedownsListed
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	query := r.URL.Query()
+	filter := models.MediaTakedownFilter{
+		Type:     query.Get("type"),
+		SourceID: query.Get("sourceId"),
+		Reason:   models.TakedownReason(query.Get("reason")),
+	}
+	switch filter.Reason {
+	case "", models.TakedownDMCA, models.TakedownCopyright, models.TakedownTrademark, models.TakedownOther:
+	default:
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid takedown reason")
+		return
+	}
+
+	takedowns, total, err := h.takedownSvc.ListTakedowns(r.Context(), filter, offset, limit)
+	if err != nil {
+		h.logger.Error("Failed to list media takedowns", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to list media takedowns")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"takedowns": takedowns,
+		"total":     total,
+		"offset":    offset,
+		"limit":     limit,
+	})
+}
+
+// GetTakedown handles requests for a takedown (admin only).
+func (h *TakedownHandler) GetTakedown(w http.ResponseWriter, r *http.Request, id bson.ObjectID) {
+	takedown, err := h.takedownSvc.GetTakedown(r.Context(), id)
+	if err != nil {
+		h.respondWithTakedownError(w, err, "Failed to get media takedown", id)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, takedown)
+}
+
+// respondWithTakedownError maps takedown errors to HTTP responses.
+func (h *TakedownHandler) respondWithTakedownError(w http.ResponseWriter, err error, message string, id bson.ObjectID) {
+	status := models.MapErrorToHTTPStatus(err)
+	if status == http.StatusInternalServerError {
+		h.logger.Error(message, err, "id", id.Hex())
+		utils.RespondWithError(w, status, message)
+		return
+	}
+
+	utils.RespondWithError(w, status, err.Error())
+}
@@ -0,0 +1,119 @@
+// Package handlers contains HTTP handlers for the API.
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/services/notification"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// TemplateHandler handles HTTP requests related to the templates of outbound emails and long-form notifications.
+type TemplateHandler struct {
+	templateSvc *notification.TemplateService
+	logger      *utils.Logger
+}
+
+// NewTemplateHandler creates a new template handler.
+func NewTemplateHandler(templateSvc *notification.TemplateService, logger *utils.Logger) *TemplateHandler {
+	return &TemplateHandler{
+		templateSvc: templateSvc,
+		logger:      logger.Named("template_handler"),
+	}
+}
+
+// ListTemplates handles requests to list the stored versions of message templates (admin only).
+// The "name" and "locale" query parameters filter them, and "active=true" lists only the versions being sent.
+func (h *TemplateHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	templates, err := h.templateSvc.ListTemplates(r.Context(), models.MessageTemplateFilter{
+		Name:       query.Get("name"),
+		Locale:     query.Get("locale"),
+		ActiveOnly: query.Get("active") == "true",
+	})
+	if err != nil {
+		h.respondWithTemplateError(w, err, "Failed to list message templates", "")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"templates": templates,
+	})
+}
+
+// CreateVersion handles requests to store a new version of a message template (admin only).
+func (h *TemplateHandler) CreateVersion(w http.ResponseWriter, r *http.Request, request *models.MessageTemplateRequest) {
+	adminID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	template, err := h.templateSvc.CreateVersion(r.Context(), name, adminID, request)
+	if err != nil {
+		h.respondWithTemplateError(w, err, "Failed to create message template version", name)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, template)
+}
+
+// ActivateVersion handles requests to make a version of a message template the one sent in its locale (admin only).
+// The "locale" query parameter is the locale of the version.
+func (h *TemplateHandler) ActivateVersion(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil || version <= 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid template version")
+		return
+	}
+
+	template, err := h.templateSvc.ActivateVersion(r.Context(), name, r.URL.Query().Get("locale"), version)
+	if err != nil {
+		h.respondWithTemplateError(w, err, "Failed to activate message template version", name)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, template)
+}
+
+// Preview handles requests to render a message template with sample data (admin only).
+func (h *TemplateHandler) Preview(w http.ResponseWriter, r *http.Request, preview *models.MessageTemplatePreview) {
+	name := chi.URLParam(r, "name")
+	message, err := h.templateSvc.Preview(r.Context(), name, preview)
+	if err != nil {
+		h.respondWithTemplateError(w, err, "Failed to preview message template", name)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, message)
+}
+
+// TestSend handles requests to email a message template rendered with sample data to an address (admin only).
+func (h *TemplateHandler) TestSend(w http.ResponseWriter, r *http.Request, preview *models.MessageTemplatePreview) {
+	name := chi.URLParam(r, "name")
+	message, err := h.templateSvc.TestSend(r.Context(), name, preview)
+	if err != nil {
+		h.respondWithTemplateError(w, err, "Failed to send test message", name)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, message)
+}
+
+// respondWithTemplateError maps message template errors to HTTP responses.
+func (h *TemplateHandler) respondWithTemplateError(w http.ResponseWriter, err error, message, name string) {
+	status := models.MapErrorToHTTPStatus(err)
+	if status == http.StatusInternalServerError {
+		h.logger.Error(message, err, "name", name)
+		utils.RespondWithError(w, status, message)
+		return
+	}
+
+	utils.RespondWithError(w, status, err.Error())
+}
@@ -16,14 +16,18 @@ import (
 type UserHandler struct {
 	userManager   *user.Manager
 	socialService *user.SocialService
+	trustService  *user.TrustService
+	statsService  *user.StatsService
 	logger        *utils.Logger
 }
 
 // NewUserHandler creates a new user handler.
-func NewUserHandler(userManager *user.Manager, logger *utils.Logger) *UserHandler {
+func NewUserHandler(userManager *user.Manager, socialService *user.SocialService, trustService *user.TrustService, statsService *user.StatsService, logger *utils.Logger) *UserHandler {
 	return &UserHandler{
 		userManager:   userManager,
-		socialService: user.NewSocialService(userManager, logger),
+		socialService: socialService,
+		trustService:  trustService,
+		statsService:  statsService,
 		logger:        logger.Named("user_handler"),
 	}
 }
@@ -80,25 +84,6 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	utils.RespondWithJSON(w, http.StatusOK, personalUser)
 }
 
-// DeleteUser handles requests to delete a user's account.
-func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
-	// Get user ID from context
-	userIDStr := r.Context().Value("userID").(string)
-
-	// Delete user
-	err := h.userManager.DeleteAccount(r.Context(), userIDStr)
-	if err != nil {
-		h.logger.Error("Failed to delete user", err, "id", userIDStr)
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete user")
-		return
-	}
-
-	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
-		"success": true,
-		"message": "User account deleted successfully",
-	})
-}
-
 // SearchUsers handles requests to search for users.
 func (h *UserHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
@@ -269,29 +254,6 @@ func (h *UserHandler) DeactivateUser(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// AdminDeleteUser handles requests to delete a user (admin only).
-func (h *UserHandler) AdminDeleteUser(w http.ResponseWriter, r *http.Request) {
-	// Get target user ID from URL parameter
-	targetIDStr := chi.URLParam(r, "id")
-	if targetIDStr == "" {
-		utils.RespondWithError(w, http.StatusBadRequest, "Target user ID is required")
-		return
-	}
-
-	// Delete user
-	err := h.userManager.DeleteAccount(r.Context(), targetIDStr)
-	if err != nil {
-		h.logger.Error("Failed to delete user", err, "targetID", targetIDStr)
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete user")
-		return
-	}
-
-	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
-		"success": true,
-		"message": "User deleted successfully",
-	})
-}
-
 // GetFollowing handles requests to get the list of users that the current user follows.
 func (h *UserHandler) GetFollowing(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context
@@ -466,3 +428,150 @@ func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 
 	utils.RespondWithJSON(w, http.StatusOK, users)
 }
+
+// GetMyTrust handles requests to get the current user's trust level.
+func (h *UserHandler) GetMyTrust(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userIDStr := r.Context().Value("userID").(string)
+
+	status, err := h.trustService.GetTrustStatus(r.Context(), userIDStr)
+	if err != nil {
+		h.logger.Error("Failed to get trust status", err, "userID", userIDStr)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get trust status")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, status)
+}
+
+// AttestAge handles requests to attest the current user's date of birth.
+func (h *UserHandler) AttestAge(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userIDStr := r.Context().Value("userID").(string)
+
+	// Parse request body
+	var req models.AgeAttestationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if err := utils.Validate(req); err != nil {
+		utils.RespondWithValidationError(w, err)
+		return
+	}
+
+	attestation, err := h.userManager.AttestAge(r.Context(), userIDStr, req.BirthDate)
+	if err != nil {
+		status := models.MapErrorToHTTPStatus(err)
+		if status == http.StatusInternalServerError {
+			h.logger.Error("Failed to attest age", err, "userID", userIDStr)
+			utils.RespondWithError(w, status, "Failed to attest age")
+			return
+		}
+		utils.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, attestation)
+}
+
+// GetUserTrust handles requests to get a user's trust level (admin only).
+func (h *UserHandler) GetUserTrust(w http.ResponseWriter, r *http.Request) {
+	// Get target user ID from URL parameter
+	targetIDStr := chi.URLParam(r, "id")
+	if targetIDStr == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Target user ID is required")
+		return
+	}
+
+	status, err := h.trustService.GetTrustStatus(r.Context(), targetIDStr)
+	if err != nil {
+		h.logger.Error("Failed to get trust status", err, "targetID", targetIDStr)
+		utils.RespondWithError(w, models.MapErrorToHTTPStatus(err), "Failed to get trust status")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, status)
+}
+
+// SetUserTrustRequest represents a request to override a user's trust level.
+type SetUserTrustRequest struct {
+	// Level is the trust level to pin the user to, or null to clear the override.
+	Level *models.TrustLevel `json:"level"`
+}
+
+// SetUserTrust handles requests to override a user's trust level (admin only).
+func (h *UserHandler) SetUserTrust(w http.ResponseWriter, r *http.Request) {
+	// Get admin user ID from context
+	adminIDStr := r.Context().Value("userID").(string)
+
+	// Get target user ID from URL parameter
+	targetIDStr := chi.URLParam(r, "id")
+	if targetIDStr == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Target user ID is required")
+		return
+	}
+
+	// Parse request body
+	var req SetUserTrustRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	err := h.trustService.SetTrustOverride(r.Context(), targetIDStr, req.Level, adminIDStr)
+	if err != nil {
+		h.logger.Error("Failed to set trust override", err, "targetID", targetIDStr)
+		utils.RespondWithError(w, models.MapErrorToHTTPStatus(err), "Failed to set trust level")
+		return
+	}
+
+	status, err := h.trustService.GetTrustStatus(r.Context(), targetIDStr)
+	if err != nil {
+		h.logger.Error("Failed to get trust status", err, "targetID", targetIDStr)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get trust status")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, status)
+}
+
+// GetMyHistory handles requests to get the current user's DJ history.
+func (h *UserHandler) GetMyHistory(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userIDStr := r.Context().Value("userID").(string)
+
+	// Parse query parameters
+	pageStr := r.URL.Query().Get("page")
+	page := 1 // Default page
+	if pageStr != "" {
+		var err error
+		page, err = strconv.Atoi(pageStr)
+		if err != nil || page < 1 {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid page parameter")
+			return
+		}
+	}
+
+	limitStr := r.URL.Query().Get("limit")
+	limit := 50 // Default limit
+	if limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > 200 {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid limit parameter")
+			return
+		}
+	}
+
+	// Get history
+	history, err := h.statsService.GetDJHistory(r.Context(), userIDStr, (page-1)*limit, limit)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get history")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, history)
+}
@@ -0,0 +1,156 @@
+// Package handlers contains HTTP handlers for the API.
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/services/room"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// maxClaimsListed caps the number of verification claims listed in one request.
+const maxClaimsListed = 100
+
+// VerificationHandler handles HTTP requests related to artists' and labels' verification claims and their review.
+type VerificationHandler struct {
+	verificationSvc *room.VerificationService
+	logger          *utils.Logger
+}
+
+// NewVerificationHandler creates a new verification handler.
+func NewVerificationHandler(verificationSvc *room.VerificationService, logger *utils.Logger) *VerificationHandler {
+	return &VerificationHandler{
+		verificationSvc: verificationSvc,
+		logger:          logger.Named("verification_handler"),
+	}
+}
+
+// SubmitClaim handles requests to claim a room or media item as an artist or label.
+func (h *VerificationHandler) SubmitClaim(w http.ResponseWriter, r *http.Request, request *models.VerificationClaimRequest) {
+	userID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	claim, err := h.verificationSvc.SubmitClaim(r.Context(), userID, request)
+	if err != nil {
+		h.respondWithClaimError(w, err, "Failed to submit verification claim", request.TargetID)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, claim)
+}
+
+// ListMyClaims handles requests for the current user's own verification claims.
+func (h *VerificationHandler) ListMyClaims(w http.ResponseWriter, r *http.Request) {
+	userID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	h.listClaims(w, r, models.VerificationClaimFilter{ClaimantID: userID})
+}
+
+// ListClaims handles requests to list verification claims, oldest first (admin only).
+// The "status" and "targetId" query parameters filter them, "offset" and "limit" page through them.
+func (h *VerificationHandler) ListClaims(w http.ResponseWriter, r *http.Request) {
+	h.listClaims(w, r, models.VerificationClaimFilter{TargetID: GetIDFromQuery(r, "targetId")})
+}
+
+// listClaims lists the verification claims matching a filter, narrowed by the "status" query parameter.
+func (h *VerificationHandler) listClaims(w http.ResponseWriter, r *http.Request, filter models.VerificationClaimFilter) {
+	limit := GetLimit(r, maxClaimsListed)
+	if limit == 0 {
+		limit = maxClaimsListed
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	filter.Status = models.ClaimStatus(r.URL.Query().Get("status"))
+	switch filter.Status {
+	case "", models.ClaimPending, models.ClaimApproved, models.ClaimRejected:
+	default:
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid claim status")
+		return
+	}
+
+	claims, total, err := h.verificationSvc.ListClaims(r.Context(), filter, offset, limit)
+	if err != nil {
+		h.logger.Error("Failed to list verification claims", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to list verification claims")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"claims": claims,
+		"total":  total,
+		"offset": offset,
+		"limit":  limit,
+	})
+}
+
+// GetClaim handles requests for a verification claim (admin only).
+func (h *VerificationHandler) GetClaim(w http.ResponseWriter, r *http.Request, id bson.ObjectID) {
+	claim, err := h.verificationSvc.GetClaim(r.Context(), id)
+	if err != nil {
+		h.respondWithClaimError(w, err, "Failed to get verification claim", id)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, claim)
+}
+
+// ReviewClaim handles requests to approve or reject a verification claim (admin only).
+func (h *VerificationHandler) ReviewClaim(w http.ResponseWriter, r *http.Request, id bson.ObjectID, review *models.VerificationClaimReview) {
+	adminID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	claim, err := h.verificationSvc.ReviewClaim(r.Context(), id, adminID, review)
+	if err != nil {
+		h.respondWithClaimError(w, err, "Failed to review verification claim", id)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, claim)
+}
+
+// RevokeBadge handles requests to take the verified badge away from a room, user or media item (admin only).
+// The "target" URL parameter is the kind of document: room, user or media.
+func (h *VerificationHandler) RevokeBadge(w http.ResponseWriter, r *http.Request, id bson.ObjectID) {
+	adminID, err := bson.ObjectIDFromHex(r.Context().Value("userID").(string))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	target := models.ClaimTarget(chi.URLParam(r, "target"))
+	if err := h.verificationSvc.RevokeBadge(r.Context(), target, id, adminID); err != nil {
+		h.respondWithClaimError(w, err, "Failed to revoke verified badge", id)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondWithClaimError maps verification errors to HTTP responses.
+func (h *VerificationHandler) respondWithClaimError(w http.ResponseWriter, err error, message string, id bson.ObjectID) {
+	status := models.MapErrorToHTTPStatus(err)
+	if status == http.StatusInternalServerError {
+		h.logger.Error(message, err, "id", id.Hex())
+		utils.RespondWithError(w, status, message)
+		return
+	}
+
+	utils.RespondWithError(w, status, err.Error())
+}
@@ -3,25 +3,37 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
 	"norelock.dev/listenify/backend/internal/auth"
 	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/models"
 	"norelock.dev/listenify/backend/internal/utils"
 )
 
+// APIKeyHeader is the header carrying a personal API key.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator resolves personal API keys to their owners.
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string, scope models.APIKeyScope) (*models.User, *models.APIKey, error)
+}
+
 // AuthMiddleware handles authentication for protected routes.
 type AuthMiddleware struct {
 	authProvider auth.Provider
 	sessionMgr   managers.SessionManager
+	apiKeys      APIKeyAuthenticator
 	logger       *utils.Logger
 }
 
 // NewAuthMiddleware creates a new auth middleware.
-func NewAuthMiddleware(authProvider auth.Provider, sessionMgr managers.SessionManager, logger *utils.Logger) *AuthMiddleware {
+func NewAuthMiddleware(authProvider auth.Provider, sessionMgr managers.SessionManager, apiKeys APIKeyAuthenticator, logger *utils.Logger) *AuthMiddleware {
 	return &AuthMiddleware{
 		authProvider: authProvider,
 		sessionMgr:   sessionMgr,
+		apiKeys:      apiKeys,
 		logger:       logger.Named("auth_middleware"),
 	}
 }
@@ -74,6 +86,48 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 	})
 }
 
+// RequireAuthOrAPIKey is a middleware that accepts either a session token or a personal API key granting the scope.
+// Requests without an API key fall back to RequireAuth.
+func (m *AuthMiddleware) RequireAuthOrAPIKey(scope models.APIKeyScope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		requireAuth := m.RequireAuth(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(APIKeyHeader)
+			if key == "" {
+				requireAuth.ServeHTTP(w, r)
+				return
+			}
+
+			user, apiKey, err := m.apiKeys.Authenticate(r.Context(), key, scope)
+			if err != nil {
+				switch {
+				case errors.Is(err, models.ErrInvalidAPIKey):
+					utils.RespondWithError(w, http.StatusUnauthorized, "Invalid API key")
+				case errors.Is(err, models.ErrPasswordResetRequired):
+					utils.RespondWithError(w, http.StatusForbidden, "Password reset required")
+				case errors.Is(err, models.ErrAPIKeyScope):
+					utils.RespondWithError(w, http.StatusForbidden, "API key does not grant the "+string(scope)+" scope")
+				case errors.Is(err, models.ErrTooManyRequests):
+					utils.RespondWithError(w, http.StatusTooManyRequests, "API key rate limit exceeded")
+				default:
+					m.logger.Error("Failed to authenticate API key", err)
+					utils.RespondWithError(w, http.StatusInternalServerError, "Failed to authenticate API key")
+				}
+				return
+			}
+
+			// Add user ID and roles to context, API keys never carry the owner's roles
+			ctx := context.WithValue(r.Context(), "userID", user.ID.Hex())
+			ctx = context.WithValue(ctx, "username", user.Username)
+			ctx = context.WithValue(ctx, "roles", []string{})
+			ctx = context.WithValue(ctx, "apiKeyID", apiKey.ID.Hex())
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
 // RequireRole is a middleware that requires a specific role.
 func (m *AuthMiddleware) RequireRole(role string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -20,6 +20,15 @@ func NewLoggerMiddleware(logger *utils.Logger) *LoggerMiddleware {
 	}
 }
 
+// Trace is a middleware that gives each HTTP request a trace ID, returned to the client in a header.
+func (m *LoggerMiddleware) Trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceID := utils.NewTraceID()
+		w.Header().Set(utils.TraceIDHeader, traceID)
+		next.ServeHTTP(w, r.WithContext(utils.WithTraceID(r.Context(), traceID)))
+	})
+}
+
 // Logger is a middleware that logs HTTP requests.
 func (m *LoggerMiddleware) Logger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -38,7 +47,7 @@ func (m *LoggerMiddleware) Logger(next http.Handler) http.Handler {
 		duration := time.Since(start)
 
 		// Log the request
-		m.logger.Info("HTTP request",
+		m.logger.WithTrace(r.Context()).Info("HTTP request",
 			"method", r.Method,
 			"path", r.URL.Path,
 			"status", rw.statusCode,
@@ -69,4 +78,9 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 // Header calls the underlying ResponseWriter's Header method.
 func (rw *responseWriter) Header() http.Header {
 	return rw.ResponseWriter.Header()
+}
+
+// Unwrap returns the underlying ResponseWriter, so http.ResponseController can flush streamed responses.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
 }
\ No newline at end of file
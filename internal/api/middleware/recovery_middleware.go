@@ -31,7 +31,7 @@ func (m *RecoveryMiddleware) Recovery(next http.Handler) http.Handler {
 				// Convert the recovered value to an error
 				recoveryErr := fmt.Errorf("panic: %v", err)
 
-				m.logger.Error("Panic recovered", recoveryErr,
+				m.logger.WithTrace(r.Context()).Error("Panic recovered", recoveryErr,
 					"stack", string(stack),
 					"method", r.Method,
 					"path", r.URL.Path,
@@ -58,7 +58,7 @@ func (m *RecoveryMiddleware) RecoveryWithCustomHandler(next http.Handler, handle
 				// Convert the recovered value to an error
 				recoveryErr := fmt.Errorf("panic: %v", err)
 
-				m.logger.Error("Panic recovered", recoveryErr,
+				m.logger.WithTrace(r.Context()).Error("Panic recovered", recoveryErr,
 					"stack", string(stack),
 					"method", r.Method,
 					"path", r.URL.Path,
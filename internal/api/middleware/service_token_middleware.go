@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// ServiceTokenHeader is the header carrying the service token of an internal caller.
+const ServiceTokenHeader = "X-Service-Token"
+
+// ServiceTokenAuthenticator checks the service tokens of internal callers, counting their requests.
+type ServiceTokenAuthenticator interface {
+	AuthenticateServiceToken(ctx context.Context, token string) error
+}
+
+// RequireServiceToken is a middleware that only lets internal callers with a valid service token through.
+// They are never given a user or roles.
+func RequireServiceToken(authenticator ServiceTokenAuthenticator, logger *utils.Logger) func(http.Handler) http.Handler {
+	logger = logger.Named("service_token_middleware")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Header.Get(ServiceTokenHeader)
+			if token == "" {
+				utils.RespondWithError(w, http.StatusUnauthorized, "Service token required")
+				return
+			}
+
+			if err := authenticator.AuthenticateServiceToken(r.Context(), token); err != nil {
+				switch {
+				case errors.Is(err, models.ErrInvalidToken):
+					utils.RespondWithError(w, http.StatusUnauthorized, "Invalid service token")
+				case errors.Is(err, models.ErrTooManyRequests):
+					utils.RespondWithError(w, http.StatusTooManyRequests, "Service token rate limit exceeded")
+				default:
+					logger.Error("Failed to authenticate service token", err)
+					utils.RespondWithError(w, http.StatusInternalServerError, "Failed to authenticate service token")
+				}
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -9,7 +9,10 @@ import (
 	"norelock.dev/listenify/backend/internal/auth"
 	"norelock.dev/listenify/backend/internal/config"
 	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/services/developer"
 	"norelock.dev/listenify/backend/internal/services/media"
+	"norelock.dev/listenify/backend/internal/services/notification"
 	"norelock.dev/listenify/backend/internal/services/playlist"
 	"norelock.dev/listenify/backend/internal/services/room"
 	"norelock.dev/listenify/backend/internal/services/system"
@@ -28,10 +31,40 @@ func NewRouter(
 	authProvider auth.Provider,
 	sessionMgr managers.SessionManager,
 	userManager *user.Manager,
+	guestService *user.GuestService,
+	oauthService *user.OAuthService,
+	trustService *user.TrustService,
+	socialService *user.SocialService,
+	statsService *user.StatsService,
+	apiKeyService *user.APIKeyService,
+	recoveryService *user.RecoveryService,
+	pubSubManager *managers.PubSubManager,
 	playlistManager *playlist.Manager,
 	roomManager *room.Manager,
+	calendarService *room.CalendarService,
+	reportService *room.RoomReportService,
+	verificationService *room.VerificationService,
+	takedownService *media.TakedownService,
+	imageReviewService *user.ImageReviewService,
+	membershipReconciler *room.MembershipReconciler,
+	analyticsExporter *room.AnalyticsExporter,
+	developerAppService *developer.AppService,
+	historyImporter *room.HistoryImporter,
+	historyExporter *user.HistoryExportService,
+	captchaService *user.CaptchaService,
+	deletionService *user.AccountDeletionService,
+	dataExporter *user.DataExportService,
 	mediaResolver *media.Resolver,
+	previewService *media.PreviewService,
 	healthService *system.HealthService,
+	statusService *system.StatusService,
+	snapshotService *system.SnapshotService,
+	metricsHistory *system.MetricsHistoryService,
+	transitionMonitor *room.TransitionMonitor,
+	historyArchive *system.HistoryArchiveService,
+	redisMemory *system.RedisMemoryService,
+	templateService *notification.TemplateService,
+	traceLogs *utils.TraceLogBuffer,
 	cfg *config.Config,
 	logger *utils.Logger,
 ) *Router {
@@ -42,22 +75,42 @@ func NewRouter(
 	recoveryMiddleware := appMiddleware.NewRecoveryMiddleware(apiLogger)
 	loggerMiddleware := appMiddleware.NewLoggerMiddleware(apiLogger)
 	corsMiddleware := appMiddleware.NewCORSMiddleware(appMiddleware.DefaultCORSConfig(), apiLogger)
-	authMiddleware := appMiddleware.NewAuthMiddleware(authProvider, sessionMgr, apiLogger)
+	authMiddleware := appMiddleware.NewAuthMiddleware(authProvider, sessionMgr, apiKeyService, apiLogger)
 
 	// Create handlers
-	authHandler := handlers.NewAuthHandler(userManager, authProvider, apiLogger)
-	userHandler := handlers.NewUserHandler(userManager, apiLogger)
-	mediaHandler := handlers.NewMediaHandler(mediaResolver, apiLogger)
+	authHandler := handlers.NewAuthHandler(userManager, guestService, oauthService, captchaService, authProvider, apiLogger)
+	userHandler := handlers.NewUserHandler(userManager, socialService, trustService, statsService, apiLogger)
+	mediaHandler := handlers.NewMediaHandler(mediaResolver, previewService, userManager, apiLogger)
 	playlistHandler := handlers.NewPlaylistHandler(playlistManager, apiLogger)
 	roomHandler := handlers.NewRoomHandler(roomManager, apiLogger)
+	calendarHandler := handlers.NewCalendarHandler(calendarService, apiLogger)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsExporter, apiLogger)
+	recoveryHandler := handlers.NewRecoveryHandler(recoveryService, captchaService, apiLogger)
 	healthHandler := handlers.NewHealthHandler(apiLogger, healthService, cfg)
+	statusHandler := handlers.NewStatusHandler(statusService, apiLogger)
+	snapshotHandler := handlers.NewSnapshotHandler(snapshotService, apiLogger)
+	metricsHandler := handlers.NewMetricsHandler(metricsHistory, transitionMonitor, apiLogger)
+	logHandler := handlers.NewLogHandler(traceLogs, apiLogger)
+	archiveHandler := handlers.NewArchiveHandler(historyArchive, apiLogger)
+	redisMemoryHandler := handlers.NewRedisMemoryHandler(redisMemory, apiLogger)
+	deadLetterHandler := handlers.NewDeadLetterHandler(pubSubManager, apiLogger)
+	reportHandler := handlers.NewReportHandler(reportService, captchaService, apiLogger)
+	verificationHandler := handlers.NewVerificationHandler(verificationService, apiLogger)
+	takedownHandler := handlers.NewTakedownHandler(takedownService, apiLogger)
+	imageReviewHandler := handlers.NewImageReviewHandler(imageReviewService, apiLogger)
+	templateHandler := handlers.NewTemplateHandler(templateService, apiLogger)
+	membershipHandler := handlers.NewMembershipHandler(membershipReconciler, apiLogger)
+	developerHandler := handlers.NewDeveloperHandler(developerAppService, apiLogger)
+	historyImportHandler := handlers.NewHistoryImportHandler(historyImporter, cfg.Room.HistoryImportMaxSize, apiLogger)
+	historyExportHandler := handlers.NewHistoryExportHandler(historyExporter, cfg.Room.HistoryExportWriteTimeout, apiLogger)
+	accountHandler := handlers.NewAccountHandler(deletionService, dataExporter, cfg.Room.HistoryExportWriteTimeout, apiLogger)
 
 	// Apply global middleware
+	r.Use(loggerMiddleware.Trace)
 	r.Use(recoveryMiddleware.Recovery)
 	r.Use(loggerMiddleware.Logger)
 	r.Use(corsMiddleware.CORS)
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
 	r.Use(middleware.Heartbeat("/ping"))
 
 	// Public routes
@@ -65,12 +118,54 @@ func NewRouter(
 		// Health check
 		r.Get("/health", healthHandler.Check)
 
+		// Status page feed, polled by the external status page
+		r.Get("/status", statusHandler.GetStatus)
+
 		// Auth routes
 		r.Route("/auth", func(r chi.Router) {
 			r.Post("/register", authHandler.Register)
+			r.Get("/captcha", authHandler.CaptchaConfig)
+			r.Post("/guest", authHandler.Guest)
 			r.Post("/login", authHandler.Login)
 			r.Post("/refresh", authHandler.Refresh)
 			r.Post("/logout", authHandler.Logout)
+			r.Get("/oauth/{provider}/start", authHandler.OAuthStart)
+			r.Get("/oauth/{provider}/callback", authHandler.OAuthCallback)
+			r.Post("/verify-email", recoveryHandler.VerifyEmail)
+			r.Post("/reset-password", recoveryHandler.ResetPassword)
+		})
+
+		// Calendar feeds, fetched by calendar apps without a session
+		r.Get("/rooms/{slug}/events.ics", calendarHandler.RoomFeed)
+		r.Get("/users/{id}/events.ics", calendarHandler.UserFeed)
+
+		// Data archive downloads, through single-use links
+		r.Get("/users/{id}/export.json", accountHandler.DownloadDataExport)
+	})
+
+	// Platform state snapshot, read by the analytics pipeline with a service token
+	if snapshotService.Enabled() {
+		r.With(appMiddleware.RequireServiceToken(snapshotService, apiLogger)).Get("/internal/snapshot", snapshotHandler.GetSnapshot)
+	}
+
+	// Routes readable with a personal API key as well as a session
+	r.With(authMiddleware.RequireAuthOrAPIKey(models.APIKeyScopeReadHistory)).Get("/users/me/history", userHandler.GetMyHistory)
+	r.With(authMiddleware.RequireAuthOrAPIKey(models.APIKeyScopeReadHistory)).Get("/users/me/history/export", historyExportHandler.ExportMyPlays)
+
+	// Playlist routes
+	r.Route("/playlists", func(r chi.Router) {
+		readPlaylists := authMiddleware.RequireAuthOrAPIKey(models.APIKeyScopeReadPlaylists)
+		r.With(readPlaylists).Get("/", playlistHandler.GetPlaylists)
+		r.With(readPlaylists).Get("/{id}", playlistHandler.GetPlaylist)
+
+		r.Group(func(r chi.Router) {
+			r.Use(authMiddleware.RequireAuth)
+			r.Post("/", playlistHandler.CreatePlaylist)
+			r.Put("/{id}", playlistHandler.UpdatePlaylist)
+			r.Delete("/{id}", playlistHandler.DeletePlaylist)
+			r.Post("/{id}/items", playlistHandler.AddPlaylistItem)
+			r.Delete("/{id}/items/{itemId}", playlistHandler.RemovePlaylistItem)
+			r.Post("/import", playlistHandler.ImportPlaylist)
 		})
 	})
 
@@ -83,9 +178,13 @@ func NewRouter(
 			r.Get("/me", authHandler.Me)
 			r.Get("/{id}", userHandler.GetUser)
 			r.Put("/me", userHandler.UpdateUser)
-			r.Delete("/me", userHandler.DeleteUser)
+			r.Delete("/me", accountHandler.DeleteMyAccount)
+			r.Post("/me/export", accountHandler.CreateDataExport)
 			r.Get("/search", userHandler.SearchUsers)
 			r.Get("/online", userHandler.GetOnlineUsers)
+			r.Get("/me/trust", userHandler.GetMyTrust)
+			r.Put("/me/age", userHandler.AttestAge)
+			r.Get("/me/calendar", calendarHandler.GetFeedURL)
 
 			// User social routes
 			r.Route("/me/social", func(r chi.Router) {
@@ -101,18 +200,7 @@ func NewRouter(
 			r.Get("/search", mediaHandler.Search)
 			r.Get("/resolve", mediaHandler.Resolve)
 			r.Get("/proxy/{provider}/{id}", mediaHandler.Proxy)
-		})
-
-		// Playlist routes
-		r.Route("/playlists", func(r chi.Router) {
-			r.Get("/", playlistHandler.GetPlaylists)
-			r.Post("/", playlistHandler.CreatePlaylist)
-			r.Get("/{id}", playlistHandler.GetPlaylist)
-			r.Put("/{id}", playlistHandler.UpdatePlaylist)
-			r.Delete("/{id}", playlistHandler.DeletePlaylist)
-			r.Post("/{id}/items", playlistHandler.AddPlaylistItem)
-			r.Delete("/{id}/items/{itemId}", playlistHandler.RemovePlaylistItem)
-			r.Post("/import", playlistHandler.ImportPlaylist)
+			r.Get("/preview/{provider}/{id}", mediaHandler.Preview)
 		})
 
 		// Room routes
@@ -131,6 +219,31 @@ func NewRouter(
 			r.Post("/{id}/queue/leave", WithID(roomHandler.PostQueueLeave))
 			r.Post("/{id}/favorite", WithID(roomHandler.PostFavorite))
 			r.Delete("/{id}/favorite", WithID(roomHandler.DeleteFavorite))
+			r.Get("/{id}/events", WithID(calendarHandler.ListEvents))
+			r.Post("/{id}/events", WithIDAndBody(calendarHandler.CreateEvent))
+			r.Delete("/{id}/events/{eventId}", WithID(calendarHandler.DeleteEvent))
+			r.Get("/{id}/analytics", WithID(analyticsHandler.GetConfig))
+			r.Put("/{id}/analytics", WithIDAndBody(analyticsHandler.UpdateConfig))
+			r.Get("/{id}/analytics/export", WithID(analyticsHandler.Export))
+			r.Post("/{id}/report", WithIDAndBody(reportHandler.ReportRoom))
+			r.Put("/{id}/language", WithIDAndBody(roomHandler.PutLanguage))
+		})
+
+		// Artists' and labels' claims of rooms and media
+		r.Route("/verification/claims", func(r chi.Router) {
+			r.Get("/", verificationHandler.ListMyClaims)
+			r.Post("/", WithBody(verificationHandler.SubmitClaim))
+		})
+
+		// Developer applications receiving platform events
+		r.Route("/developer/apps", func(r chi.Router) {
+			r.Get("/", developerHandler.ListApps)
+			r.Post("/", WithBody(developerHandler.CreateApp))
+			r.Get("/{id}", WithID(developerHandler.GetApp))
+			r.Put("/{id}", WithIDAndBody(developerHandler.UpdateApp))
+			r.Delete("/{id}", WithID(developerHandler.DeleteApp))
+			r.Post("/{id}/secret", WithID(developerHandler.RotateSecret))
+			r.Get("/{id}/deliveries", WithID(developerHandler.ListDeliveries))
 		})
 	})
 
@@ -145,7 +258,81 @@ func NewRouter(
 			r.Get("/users", userHandler.GetAllUsers)
 			r.Put("/users/{id}/activate", userHandler.ActivateUser)
 			r.Put("/users/{id}/deactivate", userHandler.DeactivateUser)
-			r.Delete("/users/{id}", userHandler.AdminDeleteUser)
+			r.Delete("/users/{id}", accountHandler.AdminDeleteUser)
+			r.Get("/users/{id}/trust", userHandler.GetUserTrust)
+			r.Put("/users/{id}/trust", userHandler.SetUserTrust)
+
+			// Account recovery
+			r.Put("/users/{id}/email", recoveryHandler.AdminResetEmail)
+			r.Post("/users/{id}/password-reset", recoveryHandler.AdminForcePasswordReset)
+			r.Post("/users/merge", recoveryHandler.AdminMergeUsers)
+			r.Get("/users/merge/{jobId}", recoveryHandler.AdminGetMergeJob)
+
+			// Capacity planning
+			r.Get("/metrics/history", metricsHandler.GetHistory)
+			r.Get("/metrics/transitions", metricsHandler.GetSlowestTransitions)
+			r.Get("/redis/memory", redisMemoryHandler.GetReport)
+
+			// Incidents shown on the status page
+			r.Get("/incidents", statusHandler.ListIncidents)
+			r.Post("/incidents", WithBody(statusHandler.CreateIncident))
+			r.Get("/incidents/{id}", WithID(statusHandler.GetIncident))
+			r.Put("/incidents/{id}", WithIDAndBody(statusHandler.UpdateIncident))
+			r.Delete("/incidents/{id}", WithID(statusHandler.DeleteIncident))
+
+			// Archived history
+			r.Get("/archives", archiveHandler.ListArchives)
+			r.Post("/archives/{id}/restore", WithID(archiveHandler.RestoreArchive))
+			r.Delete("/archives/{id}/restore", WithID(archiveHandler.ReleaseArchive))
+
+			// Play history imported from other platforms
+			r.Post("/rooms/{id}/history/import", WithID(historyImportHandler.ImportHistory))
+			r.Get("/history/imports/{jobId}", historyImportHandler.GetImportJob)
+
+			// Events that failed to be handled
+			r.Get("/deadletters", deadLetterHandler.ListDeadLetters)
+			r.Post("/deadletters/replay", deadLetterHandler.ReplayDeadLetters)
+			r.Get("/deadletters/{id}", WithID(deadLetterHandler.GetDeadLetter))
+			r.Post("/deadletters/{id}/replay", WithID(deadLetterHandler.ReplayDeadLetter))
+			r.Delete("/deadletters/{id}", WithID(deadLetterHandler.DiscardDeadLetter))
+
+			// Reports of whole rooms and the actions taken on them
+			r.Get("/reports/rooms", reportHandler.ListReports)
+			r.Get("/reports/rooms/{id}", WithID(reportHandler.GetReport))
+			r.Post("/reports/rooms/{id}/resolve", WithIDAndBody(reportHandler.ResolveReport))
+			r.Post("/rooms/{id}/restore", WithID(reportHandler.RestoreRoom))
+
+			// Verification claims and the verified badges given for them
+			r.Get("/verification/claims", verificationHandler.ListClaims)
+			r.Get("/verification/claims/{id}", WithID(verificationHandler.GetClaim))
+			r.Post("/verification/claims/{id}/review", WithIDAndBody(verificationHandler.ReviewClaim))
+			r.Delete("/verification/{target}/{id}", WithID(verificationHandler.RevokeBadge))
+
+			// Media taken down after DMCA and other content complaints
+			r.Get("/takedowns", takedownHandler.ListTakedowns)
+			r.Get("/takedowns/{id}", WithID(takedownHandler.GetTakedown))
+			r.Post("/takedowns", WithBody(takedownHandler.TakeDown))
+
+			// Changed avatars and playlist covers, and the known-bad images removed automatically
+			r.Get("/images/reviews", imageReviewHandler.ListReviews)
+			r.Post("/images/reviews/resolve", WithBody(imageReviewHandler.ResolveReviews))
+			r.Get("/images/reviews/{id}", WithID(imageReviewHandler.GetReview))
+			r.Get("/images/blocked", imageReviewHandler.ListBlockedImages)
+			r.Post("/images/blocked", WithBody(imageReviewHandler.BlockImage))
+			r.Delete("/images/blocked/{id}", WithID(imageReviewHandler.UnblockImage))
+
+			// Templates of outbound emails and long-form notifications
+			r.Get("/templates", templateHandler.ListTemplates)
+			r.Post("/templates/{name}/versions", WithBody(templateHandler.CreateVersion))
+			r.Post("/templates/{name}/versions/{version}/activate", templateHandler.ActivateVersion)
+			r.Post("/templates/{name}/preview", WithBody(templateHandler.Preview))
+			r.Post("/templates/{name}/test-send", WithBody(templateHandler.TestSend))
+
+			// Drift between the sources of room membership
+			r.Get("/rooms/memberships", membershipHandler.GetReport)
+
+			// Tracing a reported error to its backend call
+			r.Get("/logs", logHandler.Search)
 		})
 	})
 
@@ -0,0 +1,235 @@
+// Package auth provides authentication and authorization functionality.
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/endpoints"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// OAuth errors
+var (
+	ErrUnknownOAuthProvider = errors.New("unknown OAuth provider")
+	ErrOAuthExchange        = errors.New("failed to complete OAuth sign-in")
+)
+
+// OAuth providers users can sign in with.
+const (
+	OAuthGoogle  = "google"
+	OAuthDiscord = "discord"
+)
+
+// oauthRequestTimeout bounds the requests made to a provider while signing a user in.
+const oauthRequestTimeout = 10 * time.Second
+
+// OAuthClientConfig contains the credentials of the application with an OAuth provider.
+type OAuthClientConfig struct {
+	// ClientID is the application's client ID with the provider.
+	ClientID string
+
+	// ClientSecret is the application's client secret with the provider.
+	ClientSecret string
+
+	// RedirectURL is the callback URL registered with the provider.
+	RedirectURL string
+}
+
+// OAuthIdentity is who the provider says a signed-in user is.
+type OAuthIdentity struct {
+	// Provider is the provider the user signed in with.
+	Provider string
+
+	// Subject is the user's stable ID with the provider.
+	Subject string
+
+	// Email is the user's email address with the provider, if shared.
+	Email string
+
+	// EmailVerified indicates whether the provider verified the user owns the email address.
+	EmailVerified bool
+
+	// Name is the user's display name with the provider.
+	Name string
+}
+
+// oauthClient is a provider's OAuth configuration and how to read the identity of its users.
+type oauthClient struct {
+	config      *oauth2.Config
+	userInfoURL string
+	identity    func(data []byte) (*OAuthIdentity, error)
+}
+
+// OAuthProvider signs users in with third-party accounts. It runs the authorization code flow with
+// each configured provider and reads the identity of the user, leaving JWT issuance to the Provider.
+type OAuthProvider struct {
+	clients    map[string]*oauthClient
+	httpClient *http.Client
+	logger     *utils.Logger
+}
+
+// NewOAuthProvider creates a new OAuth provider for the providers with credentials.
+// Providers without a client ID are left out.
+func NewOAuthProvider(configs map[string]OAuthClientConfig, logger *utils.Logger) *OAuthProvider {
+	p := &OAuthProvider{
+		clients:    make(map[string]*oauthClient),
+		httpClient: &http.Client{Timeout: oauthRequestTimeout},
+		logger:     logger.Named("oauth_provider"),
+	}
+
+	for name, config := range configs {
+		if config.ClientID == "" {
+			continue
+		}
+
+		client, ok := newOAuthClient(name, config)
+		if !ok {
+			p.logger.Warn("Ignoring unsupported OAuth provider", "provider", name)
+			continue
+		}
+		p.clients[name] = client
+	}
+
+	return p
+}
+
+// newOAuthClient sets up a supported provider with the application's credentials.
+func newOAuthClient(name string, config OAuthClientConfig) (*oauthClient, bool) {
+	oauthConfig := &oauth2.Config{
+		ClientID:     config.ClientID,
+		ClientSecret: config.ClientSecret,
+		RedirectURL:  config.RedirectURL,
+	}
+
+	switch name {
+	case OAuthGoogle:
+		oauthConfig.Endpoint = endpoints.Google
+		oauthConfig.Scopes = []string{"openid", "email", "profile"}
+		return &oauthClient{
+			config:      oauthConfig,
+			userInfoURL: "https://openidconnect.googleapis.com/v1/userinfo",
+			identity:    googleIdentity,
+		}, true
+
+	case OAuthDiscord:
+		oauthConfig.Endpoint = endpoints.Discord
+		oauthConfig.Scopes = []string{"identify", "email"}
+		return &oauthClient{
+			config:      oauthConfig,
+			userInfoURL: "https://discord.com/api/users/@me",
+			identity:    discordIdentity,
+		}, true
+
+	default:
+		return nil, false
+	}
+}
+
+// Providers lists the providers users can sign in with.
+func (p *OAuthProvider) Providers() []string {
+	providers := make([]string, 0, len(p.clients))
+	for name := range p.clients {
+		providers = append(providers, name)
+	}
+	slices.Sort(providers)
+	return providers
+}
+
+// AuthCodeURL gets the URL of a provider's consent page. The state comes back with the callback.
+func (p *OAuthProvider) AuthCodeURL(provider, state string) (string, error) {
+	client, ok := p.clients[provider]
+	if !ok {
+		return "", ErrUnknownOAuthProvider
+	}
+	return client.config.AuthCodeURL(state), nil
+}
+
+// Exchange trades the code a provider sent to the callback for the identity of the user who signed in.
+func (p *OAuthProvider) Exchange(ctx context.Context, provider, code string) (*OAuthIdentity, error) {
+	client, ok := p.clients[provider]
+	if !ok {
+		return nil, ErrUnknownOAuthProvider
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.httpClient)
+	token, err := client.config.Exchange(ctx, code)
+	if err != nil {
+		p.logger.Warn("Failed to exchange OAuth code", "provider", provider, "error", err)
+		return nil, ErrOAuthExchange
+	}
+
+	response, err := client.config.Client(ctx, token).Get(client.userInfoURL)
+	if err != nil {
+		p.logger.Error("Failed to get OAuth user info", err, "provider", provider)
+		return nil, ErrOAuthExchange
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil || response.StatusCode != http.StatusOK {
+		p.logger.Error("Failed to read OAuth user info", err, "provider", provider, "status", response.StatusCode)
+		return nil, ErrOAuthExchange
+	}
+
+	identity, err := client.identity(data)
+	if err != nil || identity.Subject == "" {
+		p.logger.Error("Failed to decode OAuth user info", err, "provider", provider)
+		return nil, ErrOAuthExchange
+	}
+	identity.Provider = provider
+
+	return identity, nil
+}
+
+// googleIdentity reads a user's identity from Google's OpenID Connect user info.
+func googleIdentity(data []byte) (*OAuthIdentity, error) {
+	var info struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal Google user info: %w", err)
+	}
+
+	return &OAuthIdentity{
+		Subject:       info.Sub,
+		Email:         info.Email,
+		EmailVerified: info.EmailVerified,
+		Name:          info.Name,
+	}, nil
+}
+
+// discordIdentity reads a user's identity from Discord's current user.
+func discordIdentity(data []byte) (*OAuthIdentity, error) {
+	var info struct {
+		ID         string `json:"id"`
+		Username   string `json:"username"`
+		GlobalName string `json:"global_name"`
+		Email      string `json:"email"`
+		Verified   bool   `json:"verified"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal Discord user info: %w", err)
+	}
+
+	name := info.GlobalName
+	if name == "" {
+		name = info.Username
+	}
+	return &OAuthIdentity{
+		Subject:       info.ID,
+		Email:         info.Email,
+		EmailVerified: info.Verified,
+		Name:          name,
+	}, nil
+}
@@ -4,12 +4,14 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/spf13/viper"
+	"norelock.dev/listenify/backend/internal/models"
 )
 
 // Config represents the application configuration
@@ -29,7 +31,7 @@ type Config struct {
 		WriteTimeout time.Duration `mapstructure:"write_timeout"`
 		// IdleTimeout is the maximum amount of time to wait for the next request
 		IdleTimeout time.Duration `mapstructure:"idle_timeout"`
-		// TrustedProxies is the list of trusted proxy IP addresses
+		// TrustedProxies are the IP addresses or CIDR networks of the reverse proxies whose X-Forwarded-For header is trusted
 		TrustedProxies []string `mapstructure:"trusted_proxies"`
 		// UseHTTPS indicates whether to enable HTTPS
 		UseHTTPS bool `mapstructure:"use_https"`
@@ -37,10 +39,32 @@ type Config struct {
 		CertFile string `mapstructure:"cert_file"`
 		// KeyFile is the path to the TLS key file
 		KeyFile string `mapstructure:"key_file"`
+		// ShutdownAcceptTimeout is how long shutdown waits for HTTP requests after it stops accepting new ones
+		ShutdownAcceptTimeout time.Duration `mapstructure:"shutdown_accept_timeout"`
+		// ShutdownNotifyTimeout is how long shutdown waits for RPC requests in flight before disconnecting clients
+		ShutdownNotifyTimeout time.Duration `mapstructure:"shutdown_notify_timeout"`
+		// ShutdownFlushTimeout is how long shutdown flushes pending webhooks, analytics and maintenance
+		ShutdownFlushTimeout time.Duration `mapstructure:"shutdown_flush_timeout"`
+		// ShutdownPersistTimeout is how long shutdown stores the state of the rooms served by the instance
+		ShutdownPersistTimeout time.Duration `mapstructure:"shutdown_persist_timeout"`
+		// ShutdownCloseTimeout is how long shutdown waits for the database clients to close
+		ShutdownCloseTimeout time.Duration `mapstructure:"shutdown_close_timeout"`
+		// NodeID identifies the instance among the WebSocket servers sharing Redis. Empty generates one at startup
+		NodeID string `mapstructure:"node_id"`
+		// NodeHeartbeat is how often the instance refreshes its connection registry in Redis
+		NodeHeartbeat time.Duration `mapstructure:"node_heartbeat"`
+		// Region is the deployment region of the instance, shared by the instances serving the same clients
+		Region string `mapstructure:"region"`
+		// RegionEndpoint is the WebSocket URL clients use to connect to the instances of the region
+		RegionEndpoint string `mapstructure:"region_endpoint"`
 	} `mapstructure:"server"`
 
 	// Database configuration
 	Database struct {
+		// UseInMemory replaces MongoDB and Redis with in-memory stores for local development and tests, so
+		// the server runs without either. Data is lost on restart and instances can't form a cluster.
+		UseInMemory bool `mapstructure:"use_in_memory"`
+
 		// MongoDB configuration
 		MongoDB struct {
 			// URI is the MongoDB connection URI
@@ -55,6 +79,8 @@ type Config struct {
 			MinPoolSize uint64 `mapstructure:"min_pool_size"`
 			// MaxIdleTime is the maximum idle time for a connection
 			MaxIdleTime time.Duration `mapstructure:"max_idle_time"`
+			// MigrationTimeout bounds migrating the database and building its indexes at startup
+			MigrationTimeout time.Duration `mapstructure:"migration_timeout"`
 		} `mapstructure:"mongodb"`
 
 		// Redis configuration
@@ -100,6 +126,36 @@ type Config struct {
 		PasswordResetExpiry time.Duration `mapstructure:"password_reset_expiry"`
 		// AllowedOrigins is the list of allowed CORS origins
 		AllowedOrigins []string `mapstructure:"allowed_origins"`
+		// MaxAPIKeys is the maximum number of personal API keys per user
+		MaxAPIKeys int `mapstructure:"max_api_keys"`
+		// APIKeyRateLimit is the default number of requests per minute allowed for a personal API key
+		APIKeyRateLimit int `mapstructure:"api_key_rate_limit"`
+		// GuestsEnabled allows listening as a guest without registering
+		GuestsEnabled bool `mapstructure:"guests_enabled"`
+		// GuestHistoryTransfer allows guests to keep their listening time and history when they register
+		GuestHistoryTransfer bool `mapstructure:"guest_history_transfer"`
+		// OAuthProviders are the application's credentials with the providers users can sign in with, by provider
+		OAuthProviders map[string]OAuthClient `mapstructure:"oauth_providers"`
+		// OAuthStateExpiry is how long users have to complete signing in with a provider
+		OAuthStateExpiry time.Duration `mapstructure:"oauth_state_expiry"`
+		// UsernameMinLength is the minimum username length
+		UsernameMinLength int `mapstructure:"username_min_length"`
+		// UsernameMaxLength is the maximum username length, at most 30
+		UsernameMaxLength int `mapstructure:"username_max_length"`
+		// UsernameReserved are the usernames nobody can take, regardless of case
+		UsernameReserved []string `mapstructure:"username_reserved"`
+		// UsernameBlockedTerms are the terms usernames can't contain when the profanity filter is enabled, the chat's blocked terms when empty
+		UsernameBlockedTerms []string `mapstructure:"username_blocked_terms"`
+		// UsernameRenameCooldown is how long users wait between renames
+		UsernameRenameCooldown time.Duration `mapstructure:"username_rename_cooldown"`
+		// UsernameHistorySize is the number of previous usernames kept on each user
+		UsernameHistorySize int `mapstructure:"username_history_size"`
+		// DeletionGracePeriod is how long a deleted account can be restored by signing in before its data is purged
+		DeletionGracePeriod time.Duration `mapstructure:"deletion_grace_period"`
+		// DeletionBatchSize is the number of accounts purged per maintenance run
+		DeletionBatchSize int `mapstructure:"deletion_batch_size"`
+		// DataExportLinkExpiry is how long a personal data export download link stays valid
+		DataExportLinkExpiry time.Duration `mapstructure:"data_export_link_expiry"`
 	} `mapstructure:"auth"`
 
 	// Media configuration
@@ -114,6 +170,46 @@ type Config struct {
 		MaxDuration int `mapstructure:"max_duration"`
 		// CacheExpiry is the expiry time for media cache
 		CacheExpiry time.Duration `mapstructure:"cache_expiry"`
+		// LoudnessTarget is the loudness in LUFS normalization hints bring tracks to
+		LoudnessTarget float64 `mapstructure:"loudness_target"`
+		// MaxNormalizationGain is the largest gain in dB normalization hints suggest in either direction
+		MaxNormalizationGain float64 `mapstructure:"max_normalization_gain"`
+		// LoudnessAnalyzerURL is the analysis service measuring loudness the providers don't expose, empty disables analysis
+		LoudnessAnalyzerURL string `mapstructure:"loudness_analyzer_url"`
+		// LoudnessAnalysisInterval is how often media without loudness data is analyzed
+		LoudnessAnalysisInterval time.Duration `mapstructure:"loudness_analysis_interval"`
+		// LoudnessAnalysisBatch is the number of media items analyzed per run
+		LoudnessAnalysisBatch int `mapstructure:"loudness_analysis_batch"`
+		// LyricsProviderURL is the LRCLIB compatible track lookup endpoint lyrics come from, empty disables lyrics
+		LyricsProviderURL string `mapstructure:"lyrics_provider_url"`
+		// LyricsCacheTTL is how long the lyrics of a media item, or the lack of them, are cached
+		LyricsCacheTTL time.Duration `mapstructure:"lyrics_cache_ttl"`
+		// LyricsLookupInterval is how often media never looked up is checked for lyrics
+		LyricsLookupInterval time.Duration `mapstructure:"lyrics_lookup_interval"`
+		// LyricsLookupBatch is the number of media items checked for lyrics per run
+		LyricsLookupBatch int `mapstructure:"lyrics_lookup_batch"`
+		// SearchCacheTTL is how long media search results are served from the cache, 0 disables the cache
+		SearchCacheTTL time.Duration `mapstructure:"search_cache_ttl"`
+		// SearchBudgets are the search costs and daily budgets of the providers, by provider
+		SearchBudgets map[string]SearchBudget `mapstructure:"search_budgets"`
+		// SearchBudgetTimezone is the time zone in which the daily search budgets reset
+		SearchBudgetTimezone string `mapstructure:"search_budget_timezone"`
+		// PreviewsEnabled turns on previewing search results through the preview proxy
+		PreviewsEnabled bool `mapstructure:"previews_enabled"`
+		// PreviewProviders are the providers whose terms of service permit proxied previews
+		PreviewProviders []string `mapstructure:"preview_providers"`
+		// PreviewDuration is how much of the start of a track a preview plays
+		PreviewDuration time.Duration `mapstructure:"preview_duration"`
+		// PreviewBitrate is the bitrate in kbps previews are sized for
+		PreviewBitrate int `mapstructure:"preview_bitrate"`
+		// PreviewCacheTTL is how long a fetched preview is served from the cache
+		PreviewCacheTTL time.Duration `mapstructure:"preview_cache_ttl"`
+		// PreviewCacheSize is the most megabytes of previews cached per node
+		PreviewCacheSize int `mapstructure:"preview_cache_size"`
+		// PreviewRateLimit is the number of previews a user may fetch per PreviewRateWindow, 0 for no limit
+		PreviewRateLimit int `mapstructure:"preview_rate_limit"`
+		// PreviewRateWindow is the window previews are counted in
+		PreviewRateWindow time.Duration `mapstructure:"preview_rate_window"`
 	} `mapstructure:"media"`
 
 	// Room configuration
@@ -124,14 +220,148 @@ type Config struct {
 		MaxUsersPerRoom int `mapstructure:"max_users_per_room"`
 		// MaxDJQueueSize is the maximum size of the DJ queue
 		MaxDJQueueSize int `mapstructure:"max_dj_queue_size"`
+		// SetPlanMaxTracks is the number of tracks DJs can plan to play next, 0 disables set planning
+		SetPlanMaxTracks int `mapstructure:"set_plan_max_tracks"`
+		// SetPlanTTL is how long planned tracks are kept after the plan was last changed
+		SetPlanTTL time.Duration `mapstructure:"set_plan_ttl"`
 		// RoomInactiveTimeout is the time after which an inactive room is closed
 		RoomInactiveTimeout time.Duration `mapstructure:"room_inactive_timeout"`
 		// DefaultRoomTheme is the default room theme
 		DefaultRoomTheme string `mapstructure:"default_room_theme"`
 		// AvailableThemes is the list of available room themes
 		AvailableThemes []string `mapstructure:"available_themes"`
+		// GeoIPDatabase is the path to the CSV GeoIP database used for listener geo attribution
+		GeoIPDatabase string `mapstructure:"geoip_database"`
+		// LargeRoomThreshold is the number of users above which room state is summarized
+		LargeRoomThreshold int `mapstructure:"large_room_threshold"`
+		// LargeRoomRosterSize is the number of users listed in a summarized room state
+		LargeRoomRosterSize int `mapstructure:"large_room_roster_size"`
+		// LargeRoomEventInterval is the minimum interval between broadcasts of the same event in large rooms
+		LargeRoomEventInterval time.Duration `mapstructure:"large_room_event_interval"`
+		// JoinRosterPageSize is the number of users per roster chunk sent after a progressive join
+		JoinRosterPageSize int `mapstructure:"join_roster_page_size"`
+		// JoinChatBacklog is the number of recent chat messages sent to users joining a room, unless the room sets its own
+		JoinChatBacklog int `mapstructure:"join_chat_backlog"`
+		// MaxPinnedMessages is the maximum number of chat messages that can be pinned in a room
+		MaxPinnedMessages int `mapstructure:"max_pinned_messages"`
+		// ModerationUndoWindow is how long moderators can undo their last kick, mute, ban or message deletion
+		ModerationUndoWindow time.Duration `mapstructure:"moderation_undo_window"`
+		// CalendarCacheTTL is how long generated ICS event feeds are cached
+		CalendarCacheTTL time.Duration `mapstructure:"calendar_cache_ttl"`
+		// EventReminderBefore is how long before a scheduled room event starts its room is reminded
+		EventReminderBefore time.Duration `mapstructure:"event_reminder_before"`
+		// EventCheckInterval is how often scheduled room events are checked for reminders and starts
+		EventCheckInterval time.Duration `mapstructure:"event_check_interval"`
+		// RotationReportCacheTTL is how long generated DJ rotation reports are cached
+		RotationReportCacheTTL time.Duration `mapstructure:"rotation_report_cache_ttl"`
+		// PopupMaxLifetime is the longest lifetime a pop-up room can be created with
+		PopupMaxLifetime time.Duration `mapstructure:"popup_max_lifetime"`
+		// PopupReminderBefore is how long before a pop-up room expires its users are reminded
+		PopupReminderBefore time.Duration `mapstructure:"popup_reminder_before"`
+		// PopupCheckInterval is how often pop-up rooms are checked for expiry
+		PopupCheckInterval time.Duration `mapstructure:"popup_check_interval"`
+		// LobbyCacheFresh is how long lobby listings and room searches are served from the cache before being refreshed
+		LobbyCacheFresh time.Duration `mapstructure:"lobby_cache_fresh"`
+		// LobbyCacheStale is how long past LobbyCacheFresh a listing is still served while it is refreshed
+		LobbyCacheStale time.Duration `mapstructure:"lobby_cache_stale"`
+		// StateCacheTTL is how long room states are kept in memory for frequent reads, 0 disables the cache
+		StateCacheTTL time.Duration `mapstructure:"state_cache_ttl"`
+		// AnalyticsFlushInterval is how often room analytics events are delivered to owners' webhooks, 0 disables delivery
+		AnalyticsFlushInterval time.Duration `mapstructure:"analytics_flush_interval"`
+		// AnalyticsRetention is how long daily room analytics files are kept for download
+		AnalyticsRetention time.Duration `mapstructure:"analytics_retention"`
+		// HeatInterval is how often the heat score of rooms used in discovery is recomputed, 0 disables it
+		HeatInterval time.Duration `mapstructure:"heat_interval"`
+		// LanguageInterval is how often the chat language of rooms is detected, 0 disables it
+		LanguageInterval time.Duration `mapstructure:"language_interval"`
+		// LanguageMinMessages is the number of recent chat messages a room needs before its language is detected
+		LanguageMinMessages int `mapstructure:"language_min_messages"`
+		// MembershipReconcileInterval is how often room memberships are reconciled across their sources, 0 disables it
+		MembershipReconcileInterval time.Duration `mapstructure:"membership_reconcile_interval"`
+		// MembershipReconcileWorkers is the number of rooms whose memberships are reconciled in parallel
+		MembershipReconcileWorkers int `mapstructure:"membership_reconcile_workers"`
+		// MembershipGrace is how long a member without a live connection is kept before being removed
+		MembershipGrace time.Duration `mapstructure:"membership_grace"`
+		// StateHeartbeatInterval is how often the Redis state of rooms with live connections is kept from expiring, 0 disables it
+		StateHeartbeatInterval time.Duration `mapstructure:"state_heartbeat_interval"`
+		// PlaybackSyncInterval is how often rooms with live connections are told where their current media is by the server's clock, 0 disables it
+		PlaybackSyncInterval time.Duration `mapstructure:"playback_sync_interval"`
+		// AdmissionInterval is how often this node's load is evaluated for load-aware room admission, 0 disables it
+		AdmissionInterval time.Duration `mapstructure:"admission_interval"`
+		// AdmissionMaxConnections is the number of connections to this node above which room joins are constrained, 0 ignores connections
+		AdmissionMaxConnections int `mapstructure:"admission_max_connections"`
+		// AdmissionMaxBroadcastLatency is the average room broadcast latency above which room joins are constrained, 0 ignores latency
+		AdmissionMaxBroadcastLatency time.Duration `mapstructure:"admission_max_broadcast_latency"`
+		// AdmissionCapacityFactor is the share of room capacities open to joins while constrained
+		AdmissionCapacityFactor float64 `mapstructure:"admission_capacity_factor"`
+		// AdmissionQueueTimeout is how long a queued join keeps its place without the user trying again
+		AdmissionQueueTimeout time.Duration `mapstructure:"admission_queue_timeout"`
+		// HistoryImportMaxSize is the largest play history import accepted in one request, in bytes
+		HistoryImportMaxSize int64 `mapstructure:"history_import_max_size"`
+		// HistoryImportMaxAge is how long ago the oldest play in a history import may have started, 0 accepts any age
+		HistoryImportMaxAge time.Duration `mapstructure:"history_import_max_age"`
+		// HistoryExportMaxConcurrent is the number of history exports a user may download at once
+		HistoryExportMaxConcurrent int `mapstructure:"history_export_max_concurrent"`
+		// HistoryExportBatchSize is the number of plays read from the database at a time while exporting
+		HistoryExportBatchSize int `mapstructure:"history_export_batch_size"`
+		// HistoryExportWriteTimeout is how long a history export waits for the client to take more of it before giving up
+		HistoryExportWriteTimeout time.Duration `mapstructure:"history_export_write_timeout"`
+		// HistoryExportMaxDuration is the longest a single history export download may run; clients resume past it
+		HistoryExportMaxDuration time.Duration `mapstructure:"history_export_max_duration"`
+		// ToxicityClassifier is the classifier scoring chat messages for automated moderation: "wordlist", "http" or empty to disable scoring
+		ToxicityClassifier string `mapstructure:"toxicity_classifier"`
+		// ToxicityClassifierURL is the classification service used by the http classifier
+		ToxicityClassifierURL string `mapstructure:"toxicity_classifier_url"`
+		// ToxicityClassifierKey is the API key sent to the classification service
+		ToxicityClassifierKey string `mapstructure:"toxicity_classifier_key"`
+		// ToxicityBlockedTerms are the terms scored by the wordlist classifier
+		ToxicityBlockedTerms []string `mapstructure:"toxicity_blocked_terms"`
+		// ToxicityFlagThreshold is the toxicity score from which chat messages are flagged to their room's moderation queue
+		ToxicityFlagThreshold float64 `mapstructure:"toxicity_flag_threshold"`
+		// ToxicityMaskThreshold is the toxicity score from which flagged chat messages are masked until reviewed
+		ToxicityMaskThreshold float64 `mapstructure:"toxicity_mask_threshold"`
+		// ToxicityWorkers is the number of chat messages scored at once
+		ToxicityWorkers int `mapstructure:"toxicity_workers"`
+		// TransitionSlowAfter is how long a track change may take to run before it is logged with its stage timings
+		TransitionSlowAfter time.Duration `mapstructure:"transition_slow_after"`
+		// TransitionLateAfter is how long after a track's expected end its room may move on before the track change counts as late
+		TransitionLateAfter time.Duration `mapstructure:"transition_late_after"`
+		// TransitionMissedAfter is how long after a track's expected end a room that hasn't moved on counts as a missed track change
+		TransitionMissedAfter time.Duration `mapstructure:"transition_missed_after"`
 	} `mapstructure:"room"`
 
+	// Trust level configuration
+	Trust struct {
+		// Basic contains the requirements for the basic trust level
+		Basic TrustThreshold `mapstructure:"basic"`
+		// Member contains the requirements for the member trust level
+		Member TrustThreshold `mapstructure:"member"`
+		// Regular contains the requirements for the regular trust level
+		Regular TrustThreshold `mapstructure:"regular"`
+		// PostLinksLevel is the minimum trust level required to post links in chat
+		PostLinksLevel string `mapstructure:"post_links_level"`
+		// CreateRoomLevel is the minimum trust level required to create rooms
+		CreateRoomLevel string `mapstructure:"create_room_level"`
+		// LongTrackLevel is the minimum trust level required to play long tracks
+		LongTrackLevel string `mapstructure:"long_track_level"`
+		// LongTrackDuration is the track length in seconds above which a track counts as long
+		LongTrackDuration int `mapstructure:"long_track_duration"`
+	} `mapstructure:"trust"`
+
+	// Restricted experience of minors, the users who attested being under 18
+	Minors struct {
+		// Enabled turns the restricted experience on for minors
+		Enabled bool `mapstructure:"enabled"`
+		// FilterChat refuses chat messages from minors containing blocked terms
+		FilterChat bool `mapstructure:"filter_chat"`
+		// BlockedTerms are the terms minors can't post in chat, the chat's blocked terms when empty
+		BlockedTerms []string `mapstructure:"blocked_terms"`
+		// DisableLinks refuses links in chat messages from minors, whatever their trust level
+		DisableLinks bool `mapstructure:"disable_links"`
+		// HideRestrictedRooms leaves the rooms open to age-restricted media out of minors' room listings and searches
+		HideRestrictedRooms bool `mapstructure:"hide_restricted_rooms"`
+	} `mapstructure:"minors"`
+
 	// WebSocket configuration
 	WebSocket struct {
 		// MaxMessageSize is the maximum message size
@@ -144,6 +374,26 @@ type Config struct {
 		PingPeriod time.Duration `mapstructure:"ping_period"`
 		// MaxConnections is the maximum number of concurrent WebSocket connections
 		MaxConnections int `mapstructure:"max_connections"`
+		// BroadcastShards is the number of workers room broadcasts are spread across, each room on one of them
+		BroadcastShards int `mapstructure:"broadcast_shards"`
+		// BroadcastBacklog is the number of messages a room can have waiting for delivery before the oldest are dropped
+		BroadcastBacklog int `mapstructure:"broadcast_backlog"`
+		// ResumeWindow is how long after disconnecting a client can resume its session. Zero disables resuming
+		ResumeWindow time.Duration `mapstructure:"resume_window"`
+		// ResumeBuffer is the number of room notifications kept for a disconnected client before the oldest are dropped
+		ResumeBuffer int `mapstructure:"resume_buffer"`
+		// AdmissionRate is the number of connections admitted per second, so reconnect storms are spread out. Zero admits without limit
+		AdmissionRate int `mapstructure:"admission_rate"`
+		// AdmissionBurst is the number of connections admitted at once before the rate applies
+		AdmissionBurst int `mapstructure:"admission_burst"`
+		// AdmissionQueue is the number of connections that can wait for their turn before connections are turned away
+		AdmissionQueue int `mapstructure:"admission_queue"`
+		// AdmissionQueueTimeout is the longest a connection waits for its turn before it is turned away
+		AdmissionQueueTimeout time.Duration `mapstructure:"admission_queue_timeout"`
+		// ReconnectJitter is the spread of the random delay added to the reconnect hints sent to disconnected clients
+		ReconnectJitter time.Duration `mapstructure:"reconnect_jitter"`
+		// RejoinStateTTL is how long clients rejoining a room after reconnecting share its join state. Zero disables sharing
+		RejoinStateTTL time.Duration `mapstructure:"rejoin_state_ttl"`
 	} `mapstructure:"websocket"`
 
 	// Logging configuration
@@ -156,8 +406,146 @@ type Config struct {
 		OutputPaths []string `mapstructure:"output_paths"`
 		// ErrorOutputPaths is the list of output paths for error logs
 		ErrorOutputPaths []string `mapstructure:"error_output_paths"`
+		// TraceBufferSize is the number of recent traced log lines kept for searching by trace ID
+		TraceBufferSize int `mapstructure:"trace_buffer_size"`
 	} `mapstructure:"logging"`
 
+	// System monitoring configuration
+	System struct {
+		// MetricsHistoryInterval is how often operational gauges are recorded into the metrics history
+		MetricsHistoryInterval time.Duration `mapstructure:"metrics_history_interval"`
+		// MetricsHistoryRetention is how long metrics history is kept
+		MetricsHistoryRetention time.Duration `mapstructure:"metrics_history_retention"`
+		// QuietHours lists daily "HH:MM-HH:MM" windows per maintenance task class during which those tasks don't run
+		QuietHours map[string][]string `mapstructure:"quiet_hours"`
+		// QuietHoursTimezone is the IANA time zone the quiet hours are given in
+		QuietHoursTimezone string `mapstructure:"quiet_hours_timezone"`
+		// HistoryArchiveDir is where old play, user and session history is archived, empty to delete it instead
+		HistoryArchiveDir string `mapstructure:"history_archive_dir"`
+		// HistoryArchiveAfter is how long history stays in MongoDB before it is archived
+		HistoryArchiveAfter time.Duration `mapstructure:"history_archive_after"`
+		// HistoryArchiveBatch is the number of history records per archive file
+		HistoryArchiveBatch int `mapstructure:"history_archive_batch"`
+		// DeadLetterMaxEntries is the maximum number of failed events kept for replay, 0 to only log them
+		DeadLetterMaxEntries int64 `mapstructure:"dead_letter_max_entries"`
+		// DeadLetterRetention is how long failed events are kept for replay
+		DeadLetterRetention time.Duration `mapstructure:"dead_letter_retention"`
+		// RedisMemoryInterval is how often Redis memory is measured and kept under its budget, 0 disables it
+		RedisMemoryInterval time.Duration `mapstructure:"redis_memory_interval"`
+		// RedisMemoryTrimThreshold is the share of Redis maxmemory above which caches are trimmed
+		RedisMemoryTrimThreshold float64 `mapstructure:"redis_memory_trim_threshold"`
+		// RedisMemorySampleSize is the number of keys per key family whose memory usage is measured
+		RedisMemorySampleSize int `mapstructure:"redis_memory_sample_size"`
+		// RedisVoteMediaPerRoom is the number of media per room whose votes are kept in Redis, 0 for no limit
+		RedisVoteMediaPerRoom int `mapstructure:"redis_vote_media_per_room"`
+		// StatusIncidentAfterFailures is the number of consecutive failed health checks opening an incident, 0 disables it
+		StatusIncidentAfterFailures int `mapstructure:"status_incident_after_failures"`
+		// StatusUptimeDays is how many days of health checks are kept for the status page uptime
+		StatusUptimeDays int `mapstructure:"status_uptime_days"`
+		// StatusCacheTTL is how long the status page feed is cached
+		StatusCacheTTL time.Duration `mapstructure:"status_cache_ttl"`
+		// ImageReviewInterval is how often changed avatars and playlist covers are matched against the blocked images, 0 disables it
+		ImageReviewInterval time.Duration `mapstructure:"image_review_interval"`
+		// ImageReviewBatch is the most changed images matched per check
+		ImageReviewBatch int `mapstructure:"image_review_batch"`
+		// ImageMatchDistance is the most bits an image's perceptual hash may differ from a blocked image's to match it
+		ImageMatchDistance int `mapstructure:"image_match_distance"`
+		// ImageMaxSize is the largest image in bytes downloaded to hash it
+		ImageMaxSize int64 `mapstructure:"image_max_size"`
+		// SnapshotTokens are the service tokens internal callers read the platform state snapshot with, empty disables the snapshot
+		SnapshotTokens []string `mapstructure:"snapshot_tokens"`
+		// SnapshotRateLimit is the number of snapshots a service token may request per minute
+		SnapshotRateLimit int `mapstructure:"snapshot_rate_limit"`
+		// SnapshotCacheTTL is how long a generated platform state snapshot is served before another is generated
+		SnapshotCacheTTL time.Duration `mapstructure:"snapshot_cache_ttl"`
+	} `mapstructure:"system"`
+
+	// Developer application configuration
+	Developer struct {
+		// MaxApps is the maximum number of developer applications per user, 0 for no limit
+		MaxApps int `mapstructure:"max_apps"`
+		// WebhookPollInterval is how often due webhook deliveries are attempted, 0 disables delivery
+		WebhookPollInterval time.Duration `mapstructure:"webhook_poll_interval"`
+		// WebhookTimeout bounds one webhook delivery attempt
+		WebhookTimeout time.Duration `mapstructure:"webhook_timeout"`
+		// WebhookMaxAttempts is how many times an event is attempted before it is given up on
+		WebhookMaxAttempts int `mapstructure:"webhook_max_attempts"`
+		// WebhookRetryBackoff is the wait before the first retry, doubled for each further one
+		WebhookRetryBackoff time.Duration `mapstructure:"webhook_retry_backoff"`
+		// WebhookMaxBackoff caps the wait between attempts
+		WebhookMaxBackoff time.Duration `mapstructure:"webhook_max_backoff"`
+		// WebhookWorkers is the number of deliveries attempted at once per instance
+		WebhookWorkers int `mapstructure:"webhook_workers"`
+		// DeliveryLogRetention is how long webhook delivery attempts are logged for
+		DeliveryLogRetention time.Duration `mapstructure:"delivery_log_retention"`
+	} `mapstructure:"developer"`
+
+	// Scrobbling of listened plays to Last.fm compatible services
+	Scrobbling struct {
+		// APIURL is the scrobbling service's API endpoint
+		APIURL string `mapstructure:"api_url"`
+		// AuthURL is the page users authorize the application on
+		AuthURL string `mapstructure:"auth_url"`
+		// APIKey is the application's API key on the scrobbling service, empty disables scrobbling
+		APIKey string `mapstructure:"api_key"`
+		// APISecret is the application's shared secret, used to sign requests
+		APISecret string `mapstructure:"api_secret"`
+		// CallbackURL is where the scrobbling service sends users back to after they authorized the application
+		CallbackURL string `mapstructure:"callback_url"`
+		// PollInterval is how often due scrobbles are submitted, 0 disables submitting them
+		PollInterval time.Duration `mapstructure:"poll_interval"`
+		// Timeout bounds one submission attempt
+		Timeout time.Duration `mapstructure:"timeout"`
+		// MaxAttempts is how many times a scrobble is submitted before it is given up on
+		MaxAttempts int `mapstructure:"max_attempts"`
+		// RetryBackoff is the wait before the first retry, doubled for each further one
+		RetryBackoff time.Duration `mapstructure:"retry_backoff"`
+		// MaxBackoff caps the wait between attempts
+		MaxBackoff time.Duration `mapstructure:"max_backoff"`
+		// Workers is the number of scrobbles submitted at once per instance
+		Workers int `mapstructure:"workers"`
+		// LogRetention is how long users' scrobble logs are kept for
+		LogRetention time.Duration `mapstructure:"log_retention"`
+	} `mapstructure:"scrobbling"`
+
+	// Captcha verification of sensitive actions
+	Captcha struct {
+		// Provider is the captcha provider: "hcaptcha", "turnstile" or empty to disable captchas
+		Provider string `mapstructure:"provider"`
+		// SiteKey is the application's public site key, shown to clients rendering the captcha
+		SiteKey string `mapstructure:"site_key"`
+		// Secret is the application's secret key tokens are verified with
+		Secret string `mapstructure:"secret"`
+		// VerifyURL overrides the provider's verification endpoint
+		VerifyURL string `mapstructure:"verify_url"`
+		// Timeout bounds verifying a token with the provider
+		Timeout time.Duration `mapstructure:"timeout"`
+		// AttemptWindow is the window over which attempts from an IP address are counted to score risk
+		AttemptWindow time.Duration `mapstructure:"attempt_window"`
+		// Actions configures when each action requires a captcha, by action: "signup", "password_reset" and "report"
+		Actions map[string]CaptchaAction `mapstructure:"actions"`
+	} `mapstructure:"captcha"`
+
+	// Outbound email and message template configuration
+	Email struct {
+		// SMTPHost is the SMTP server emails are sent through, empty to only log them
+		SMTPHost string `mapstructure:"smtp_host"`
+		// SMTPPort is the SMTP server port
+		SMTPPort int `mapstructure:"smtp_port"`
+		// SMTPUsername is the SMTP username, empty to send without authenticating
+		SMTPUsername string `mapstructure:"smtp_username"`
+		// SMTPPassword is the SMTP password
+		SMTPPassword string `mapstructure:"smtp_password"`
+		// From is the sender address of outbound emails
+		From string `mapstructure:"from"`
+		// TemplatesDir is where the message templates shipped with the application are, one directory per locale
+		TemplatesDir string `mapstructure:"templates_dir"`
+		// TemplateCacheTTL is how long parsed message templates are cached, 0 disables the cache
+		TemplateCacheTTL time.Duration `mapstructure:"template_cache_ttl"`
+		// DefaultLocale is the locale messages fall back to when no template exists in the recipient's
+		DefaultLocale string `mapstructure:"default_locale"`
+	} `mapstructure:"email"`
+
 	// Feature flags
 	Features struct {
 		// EnableRegistration determines whether new user registration is enabled
@@ -175,6 +563,44 @@ type Config struct {
 	} `mapstructure:"features"`
 }
 
+// OAuthClient contains the application's credentials with an OAuth provider.
+type OAuthClient struct {
+	// ClientID is the application's client ID, empty to disable signing in with the provider
+	ClientID string `mapstructure:"client_id"`
+	// ClientSecret is the application's client secret
+	ClientSecret string `mapstructure:"client_secret"`
+	// RedirectURL is the callback URL registered with the provider
+	RedirectURL string `mapstructure:"redirect_url"`
+}
+
+// CaptchaAction contains when an action requires a captcha.
+type CaptchaAction struct {
+	// Mode is "always", "risky" to only require a captcha when the attempt looks risky, or "off"
+	Mode string `mapstructure:"mode"`
+	// RiskyAfter is the number of attempts from an IP address within the attempt window after which attempts are risky
+	RiskyAfter int `mapstructure:"risky_after"`
+	// FailOpen lets the action through without a captcha while the provider can't be reached
+	FailOpen bool `mapstructure:"fail_open"`
+}
+
+// SearchBudget contains what searching a media provider costs and how much may be spent per day.
+type SearchBudget struct {
+	// Cost is the quota cost of one search
+	Cost int `mapstructure:"cost"`
+	// Daily is the quota that may be spent on searches per day, 0 for no limit
+	Daily int `mapstructure:"daily"`
+}
+
+// TrustThreshold contains the requirements a user must meet to reach a trust level.
+type TrustThreshold struct {
+	// MinAccountAge is the minimum age of the account
+	MinAccountAge time.Duration `mapstructure:"min_account_age"`
+	// MinListeningTime is the minimum time spent listening in rooms
+	MinListeningTime time.Duration `mapstructure:"min_listening_time"`
+	// MaxStrikes is the maximum number of moderation strikes on the user's record
+	MaxStrikes int `mapstructure:"max_strikes"`
+}
+
 // LoadConfig loads the configuration from file and environment variables.
 // It looks for a configuration file in the following locations:
 // 1. Path specified in the CONFIG_FILE environment variable
@@ -257,14 +683,25 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("server.write_timeout", "15s")
 	v.SetDefault("server.idle_timeout", "60s")
 	v.SetDefault("server.use_https", false)
+	v.SetDefault("server.shutdown_accept_timeout", "10s")
+	v.SetDefault("server.shutdown_notify_timeout", "10s")
+	v.SetDefault("server.shutdown_flush_timeout", "10s")
+	v.SetDefault("server.shutdown_persist_timeout", "5s")
+	v.SetDefault("server.shutdown_close_timeout", "5s")
+	v.SetDefault("server.node_id", "")
+	v.SetDefault("server.node_heartbeat", "15s")
+	v.SetDefault("server.region", "default")
+	v.SetDefault("server.region_endpoint", "")
 
 	// Database defaults
+	v.SetDefault("database.use_in_memory", false)
 	v.SetDefault("database.mongodb.uri", "mongodb://localhost:27017")
 	v.SetDefault("database.mongodb.database", "musicroom")
 	v.SetDefault("database.mongodb.timeout", "10s")
 	v.SetDefault("database.mongodb.max_pool_size", 100)
 	v.SetDefault("database.mongodb.min_pool_size", 10)
 	v.SetDefault("database.mongodb.max_idle_time", "60s")
+	v.SetDefault("database.mongodb.migration_timeout", "10m")
 
 	v.SetDefault("database.redis.addresses", []string{"localhost:6379"})
 	v.SetDefault("database.redis.database", 0)
@@ -283,19 +720,130 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("auth.password_max_length", 72)
 	v.SetDefault("auth.password_reset_expiry", "1h")
 	v.SetDefault("auth.allowed_origins", []string{"*"})
+	v.SetDefault("auth.max_api_keys", 10)
+	v.SetDefault("auth.api_key_rate_limit", 60)
+	v.SetDefault("auth.guests_enabled", true)
+	v.SetDefault("auth.guest_history_transfer", true)
+	v.SetDefault("auth.oauth_providers", map[string]any{})
+	v.SetDefault("auth.oauth_state_expiry", "10m")
+	v.SetDefault("auth.username_min_length", 3)
+	v.SetDefault("auth.username_max_length", 20)
+	v.SetDefault("auth.username_reserved", []string{"admin", "administrator", "moderator", "mod", "system", "listenify", "support", "staff", "root", "guest", "deleted-user"})
+	v.SetDefault("auth.username_blocked_terms", []string{})
+	v.SetDefault("auth.username_rename_cooldown", "720h") // 30 days
+	v.SetDefault("auth.username_history_size", 10)
+	v.SetDefault("auth.deletion_grace_period", "720h")
+	v.SetDefault("auth.deletion_batch_size", 50)
+	v.SetDefault("auth.data_export_link_expiry", "15m")
 
 	// Media defaults
 	v.SetDefault("media.allowed_sources", []string{"youtube", "soundcloud"})
 	v.SetDefault("media.max_duration", 600) // 10 minutes
 	v.SetDefault("media.cache_expiry", "24h")
+	v.SetDefault("media.loudness_target", -14.0)
+	v.SetDefault("media.max_normalization_gain", 12.0)
+	v.SetDefault("media.loudness_analyzer_url", "")
+	v.SetDefault("media.loudness_analysis_interval", "1h")
+	v.SetDefault("media.loudness_analysis_batch", 100)
+	v.SetDefault("media.lyrics_provider_url", "")
+	v.SetDefault("media.lyrics_cache_ttl", "168h")
+	v.SetDefault("media.lyrics_lookup_interval", "1h")
+	v.SetDefault("media.lyrics_lookup_batch", 50)
+	v.SetDefault("media.search_cache_ttl", "10m")
+	v.SetDefault("media.search_budgets", map[string]any{
+		"youtube":    map[string]any{"cost": 100, "daily": 9000},
+		"soundcloud": map[string]any{"cost": 1, "daily": 0},
+	})
+	v.SetDefault("media.search_budget_timezone", "America/Los_Angeles")
+	v.SetDefault("media.previews_enabled", false)
+	v.SetDefault("media.preview_providers", []string{})
+	v.SetDefault("media.preview_duration", "15s")
+	v.SetDefault("media.preview_bitrate", 128)
+	v.SetDefault("media.preview_cache_ttl", "1h")
+	v.SetDefault("media.preview_cache_size", 64)
+	v.SetDefault("media.preview_rate_limit", 30)
+	v.SetDefault("media.preview_rate_window", "1m")
 
 	// Room defaults
 	v.SetDefault("room.max_rooms", 100)
 	v.SetDefault("room.max_users_per_room", 200)
 	v.SetDefault("room.max_dj_queue_size", 50)
+	v.SetDefault("room.set_plan_max_tracks", 10)
+	v.SetDefault("room.set_plan_ttl", "12h")
 	v.SetDefault("room.room_inactive_timeout", "6h")
 	v.SetDefault("room.default_room_theme", "default")
 	v.SetDefault("room.available_themes", []string{"default", "dark", "light", "neon", "vintage"})
+	v.SetDefault("room.geoip_database", "")
+	v.SetDefault("room.large_room_threshold", 500)
+	v.SetDefault("room.large_room_roster_size", 50)
+	v.SetDefault("room.large_room_event_interval", "5s")
+	v.SetDefault("room.join_roster_page_size", 500)
+	v.SetDefault("room.join_chat_backlog", 50)
+	v.SetDefault("room.max_pinned_messages", 3)
+	v.SetDefault("room.moderation_undo_window", "30s")
+	v.SetDefault("room.calendar_cache_ttl", "5m")
+	v.SetDefault("room.event_reminder_before", "10m")
+	v.SetDefault("room.event_check_interval", "1m")
+	v.SetDefault("room.rotation_report_cache_ttl", "5m")
+	v.SetDefault("room.popup_max_lifetime", "72h")
+	v.SetDefault("room.popup_reminder_before", "10m")
+	v.SetDefault("room.popup_check_interval", "1m")
+	v.SetDefault("room.lobby_cache_fresh", "15s")
+	v.SetDefault("room.lobby_cache_stale", "2m")
+	v.SetDefault("room.state_cache_ttl", "2s")
+	v.SetDefault("room.analytics_flush_interval", "1m")
+	v.SetDefault("room.analytics_retention", "168h")
+	v.SetDefault("room.heat_interval", "1m")
+	v.SetDefault("room.language_interval", "15m")
+	v.SetDefault("room.language_min_messages", 20)
+	v.SetDefault("room.membership_reconcile_interval", "5m")
+	v.SetDefault("room.membership_reconcile_workers", 8)
+	v.SetDefault("room.membership_grace", "10m")
+	v.SetDefault("room.state_heartbeat_interval", "10m")
+	v.SetDefault("room.playback_sync_interval", "15s")
+	v.SetDefault("room.admission_interval", "5s")
+	v.SetDefault("room.admission_max_connections", 20000)
+	v.SetDefault("room.admission_max_broadcast_latency", "250ms")
+	v.SetDefault("room.admission_capacity_factor", 0.75)
+	v.SetDefault("room.admission_queue_timeout", "2m")
+	v.SetDefault("room.history_import_max_size", 64<<20)
+	v.SetDefault("room.history_import_max_age", "4320h")
+	v.SetDefault("room.history_export_max_concurrent", 2)
+	v.SetDefault("room.history_export_batch_size", 200)
+	v.SetDefault("room.history_export_write_timeout", "30s")
+	v.SetDefault("room.history_export_max_duration", "30m")
+	v.SetDefault("room.toxicity_classifier", "")
+	v.SetDefault("room.toxicity_classifier_url", "")
+	v.SetDefault("room.toxicity_classifier_key", "")
+	v.SetDefault("room.toxicity_blocked_terms", []string{})
+	v.SetDefault("room.toxicity_flag_threshold", 0.6)
+	v.SetDefault("room.toxicity_mask_threshold", 0.85)
+	v.SetDefault("room.toxicity_workers", 2)
+	v.SetDefault("room.transition_slow_after", "500ms")
+	v.SetDefault("room.transition_late_after", "5s")
+	v.SetDefault("room.transition_missed_after", "1m")
+
+	// Trust defaults
+	v.SetDefault("trust.basic.min_account_age", "24h")
+	v.SetDefault("trust.basic.min_listening_time", "30m")
+	v.SetDefault("trust.basic.max_strikes", 2)
+	v.SetDefault("trust.member.min_account_age", "168h")
+	v.SetDefault("trust.member.min_listening_time", "10h")
+	v.SetDefault("trust.member.max_strikes", 1)
+	v.SetDefault("trust.regular.min_account_age", "720h")
+	v.SetDefault("trust.regular.min_listening_time", "50h")
+	v.SetDefault("trust.regular.max_strikes", 0)
+	v.SetDefault("trust.post_links_level", "basic")
+	v.SetDefault("trust.create_room_level", "member")
+	v.SetDefault("trust.long_track_level", "member")
+	v.SetDefault("trust.long_track_duration", 600)
+
+	// Minors defaults
+	v.SetDefault("minors.enabled", true)
+	v.SetDefault("minors.filter_chat", true)
+	v.SetDefault("minors.blocked_terms", []string{})
+	v.SetDefault("minors.disable_links", true)
+	v.SetDefault("minors.hide_restricted_rooms", true)
 
 	// WebSocket defaults
 	v.SetDefault("websocket.max_message_size", 4096)
@@ -303,12 +851,95 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("websocket.pong_wait", "60s")
 	v.SetDefault("websocket.ping_period", "54s")
 	v.SetDefault("websocket.max_connections", 10000)
+	v.SetDefault("websocket.broadcast_shards", 8)
+	v.SetDefault("websocket.broadcast_backlog", 512)
+	v.SetDefault("websocket.resume_window", "2m")
+	v.SetDefault("websocket.resume_buffer", 200)
+	v.SetDefault("websocket.admission_rate", 200)
+	v.SetDefault("websocket.admission_burst", 400)
+	v.SetDefault("websocket.admission_queue", 2000)
+	v.SetDefault("websocket.admission_queue_timeout", "10s")
+	v.SetDefault("websocket.reconnect_jitter", "10s")
+	v.SetDefault("websocket.rejoin_state_ttl", "3s")
 
 	// Logging defaults
 	v.SetDefault("logging.level", "info")
 	v.SetDefault("logging.format", "json")
 	v.SetDefault("logging.output_paths", []string{"stdout"})
 	v.SetDefault("logging.error_output_paths", []string{"stderr"})
+	v.SetDefault("logging.trace_buffer_size", 10000)
+
+	// System defaults
+	v.SetDefault("system.metrics_history_interval", "1m")
+	v.SetDefault("system.metrics_history_retention", "720h")
+	v.SetDefault("system.quiet_hours", map[string][]string{})
+	v.SetDefault("system.quiet_hours_timezone", "UTC")
+	v.SetDefault("system.history_archive_dir", "")
+	v.SetDefault("system.history_archive_after", "720h")
+	v.SetDefault("system.history_archive_batch", 5000)
+	v.SetDefault("system.dead_letter_max_entries", 10000)
+	v.SetDefault("system.dead_letter_retention", "336h")
+	v.SetDefault("system.redis_memory_interval", "5m")
+	v.SetDefault("system.redis_memory_trim_threshold", 0.85)
+	v.SetDefault("system.redis_memory_sample_size", 100)
+	v.SetDefault("system.redis_vote_media_per_room", 50)
+	v.SetDefault("system.status_incident_after_failures", 3)
+	v.SetDefault("system.status_uptime_days", 90)
+	v.SetDefault("system.status_cache_ttl", "30s")
+	v.SetDefault("system.image_review_interval", "1m")
+	v.SetDefault("system.image_review_batch", 50)
+	v.SetDefault("system.image_match_distance", 10)
+	v.SetDefault("system.image_max_size", 10485760)
+	v.SetDefault("system.snapshot_tokens", []string{})
+	v.SetDefault("system.snapshot_rate_limit", 12)
+	v.SetDefault("system.snapshot_cache_ttl", "5s")
+
+	// Developer defaults
+	v.SetDefault("developer.max_apps", 5)
+	v.SetDefault("developer.webhook_poll_interval", "5s")
+	v.SetDefault("developer.webhook_timeout", "10s")
+	v.SetDefault("developer.webhook_max_attempts", 8)
+	v.SetDefault("developer.webhook_retry_backoff", "30s")
+	v.SetDefault("developer.webhook_max_backoff", "1h")
+	v.SetDefault("developer.webhook_workers", 8)
+	v.SetDefault("developer.delivery_log_retention", "168h")
+
+	// Scrobbling defaults
+	v.SetDefault("scrobbling.api_url", "https://ws.audioscrobbler.com/2.0/")
+	v.SetDefault("scrobbling.auth_url", "https://www.last.fm/api/auth/")
+	v.SetDefault("scrobbling.api_key", "")
+	v.SetDefault("scrobbling.api_secret", "")
+	v.SetDefault("scrobbling.callback_url", "")
+	v.SetDefault("scrobbling.poll_interval", "5s")
+	v.SetDefault("scrobbling.timeout", "10s")
+	v.SetDefault("scrobbling.max_attempts", 6)
+	v.SetDefault("scrobbling.retry_backoff", "1m")
+	v.SetDefault("scrobbling.max_backoff", "1h")
+	v.SetDefault("scrobbling.workers", 4)
+	v.SetDefault("scrobbling.log_retention", "2160h")
+
+	// Captcha defaults
+	v.SetDefault("captcha.provider", "")
+	v.SetDefault("captcha.site_key", "")
+	v.SetDefault("captcha.secret", "")
+	v.SetDefault("captcha.verify_url", "")
+	v.SetDefault("captcha.timeout", "5s")
+	v.SetDefault("captcha.attempt_window", "1h")
+	v.SetDefault("captcha.actions", map[string]any{
+		"signup":         map[string]any{"mode": "risky", "risky_after": 3, "fail_open": true},
+		"password_reset": map[string]any{"mode": "risky", "risky_after": 3, "fail_open": true},
+		"report":         map[string]any{"mode": "risky", "risky_after": 5, "fail_open": false},
+	})
+
+	// Email defaults
+	v.SetDefault("email.smtp_host", "")
+	v.SetDefault("email.smtp_port", 587)
+	v.SetDefault("email.smtp_username", "")
+	v.SetDefault("email.smtp_password", "")
+	v.SetDefault("email.from", "Listenify <no-reply@listenify.local>")
+	v.SetDefault("email.templates_dir", "./templates")
+	v.SetDefault("email.template_cache_ttl", "5m")
+	v.SetDefault("email.default_locale", "en")
 
 	// Feature flags defaults
 	v.SetDefault("features.enable_registration", true)
@@ -325,12 +956,55 @@ func validateConfig(config *Config) error {
 	if config.Server.Port <= 0 || config.Server.Port > 65535 {
 		return errors.New("server port must be between 1 and 65535")
 	}
+	if config.Server.ShutdownAcceptTimeout <= 0 || config.Server.ShutdownNotifyTimeout <= 0 || config.Server.ShutdownFlushTimeout <= 0 ||
+		config.Server.ShutdownPersistTimeout <= 0 || config.Server.ShutdownCloseTimeout <= 0 {
+		return errors.New("server shutdown timeouts must be positive")
+	}
+	if config.Server.NodeHeartbeat <= 0 {
+		return errors.New("server node heartbeat must be positive")
+	}
+	if config.Server.Region == "" {
+		return errors.New("server region must not be empty")
+	}
+	for _, proxy := range config.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("invalid trusted proxy: %s", proxy)
+		}
+	}
+
+	// Validate database configuration
+	if !config.Database.UseInMemory && config.Database.MongoDB.MigrationTimeout <= 0 {
+		return errors.New("MongoDB migration timeout must be positive")
+	}
 
 	// Validate JWT Secret
 	if config.Auth.JWTSecret == "" {
 		return errors.New("JWT secret must be set")
 	}
 
+	// Validate OAuth configuration
+	if config.Auth.OAuthStateExpiry <= 0 {
+		return errors.New("OAuth state expiry must be positive")
+	}
+	for provider, client := range config.Auth.OAuthProviders {
+		if client.ClientID != "" && (client.ClientSecret == "" || client.RedirectURL == "") {
+			return fmt.Errorf("OAuth provider %s requires a client secret and redirect URL", provider)
+		}
+	}
+
+	// Validate username policy
+	if config.Auth.UsernameMinLength < 1 || config.Auth.UsernameMaxLength < config.Auth.UsernameMinLength || config.Auth.UsernameMaxLength > 30 {
+		return errors.New("username lengths must be between 1 and 30, the minimum not above the maximum")
+	}
+	if config.Auth.UsernameRenameCooldown < 0 || config.Auth.UsernameHistorySize < 0 {
+		return errors.New("username rename cooldown and history size must not be negative")
+	}
+
+	// Validate account deletion and data export
+	if config.Auth.DeletionGracePeriod < 0 || config.Auth.DeletionBatchSize < 1 || config.Auth.DataExportLinkExpiry <= 0 {
+		return errors.New("deletion grace period must not be negative, deletion batch size and data export link expiry must be positive")
+	}
+
 	// Check if HTTPS is enabled but certificates are not configured
 	if config.Server.UseHTTPS {
 		if config.Server.CertFile == "" || config.Server.KeyFile == "" {
@@ -348,15 +1022,32 @@ func validateConfig(config *Config) error {
 	}
 
 	// Validate MongoDB configuration
-	if config.Database.MongoDB.URI == "" {
+	if !config.Database.UseInMemory && config.Database.MongoDB.URI == "" {
 		return errors.New("MongoDB URI must be set")
 	}
 
 	// Validate Redis configuration
-	if len(config.Database.Redis.Addresses) == 0 {
+	if !config.Database.UseInMemory && len(config.Database.Redis.Addresses) == 0 {
 		return errors.New("at least one Redis address must be provided")
 	}
 
+	// Validate WebSocket configuration
+	if config.WebSocket.BroadcastShards <= 0 || config.WebSocket.BroadcastBacklog <= 0 {
+		return errors.New("WebSocket broadcast shards and backlog must be positive")
+	}
+	if config.WebSocket.ResumeWindow < 0 || config.WebSocket.ResumeBuffer <= 0 {
+		return errors.New("WebSocket resume window can't be negative and resume buffer must be positive")
+	}
+	if config.WebSocket.AdmissionRate < 0 || config.WebSocket.AdmissionQueue < 0 || config.WebSocket.AdmissionQueueTimeout < 0 {
+		return errors.New("WebSocket admission rate, queue and queue timeout can't be negative")
+	}
+	if config.WebSocket.AdmissionRate > 0 && config.WebSocket.AdmissionBurst <= 0 {
+		return errors.New("WebSocket admission burst must be positive when the admission rate is set")
+	}
+	if config.WebSocket.ReconnectJitter < 0 || config.WebSocket.RejoinStateTTL < 0 {
+		return errors.New("WebSocket reconnect jitter and rejoin state TTL can't be negative")
+	}
+
 	// Validate media configuration
 	if config.Features.EnableSoundCloud && config.Media.SoundCloudAPIKey == "" {
 		return errors.New("SoundCloud API key must be set when SoundCloud integration is enabled")
@@ -366,6 +1057,115 @@ func validateConfig(config *Config) error {
 		return errors.New("at least one allowed media source must be provided")
 	}
 
+	if config.Media.LyricsProviderURL != "" && (config.Media.LyricsLookupInterval <= 0 || config.Media.LyricsLookupBatch <= 0) {
+		return errors.New("lyrics lookup interval and batch size must be positive when a lyrics provider is set")
+	}
+
+	if config.Media.PreviewsEnabled && (config.Media.PreviewDuration < time.Second || config.Media.PreviewBitrate <= 0 || config.Media.PreviewCacheSize <= 0) {
+		return errors.New("preview duration, bitrate and cache size must be positive when previews are enabled")
+	}
+
+	// Validate email configuration
+	if config.Email.SMTPHost != "" {
+		if config.Email.SMTPPort <= 0 || config.Email.SMTPPort > 65535 {
+			return errors.New("SMTP port must be between 1 and 65535")
+		}
+		if config.Email.From == "" {
+			return errors.New("email sender address must be set when an SMTP server is set")
+		}
+	}
+	if config.Email.DefaultLocale == "" {
+		return errors.New("email default locale must be set")
+	}
+
+	// Validate scrobbling configuration
+	if config.Scrobbling.APIKey != "" && (config.Scrobbling.APISecret == "" || config.Scrobbling.APIURL == "" || config.Scrobbling.AuthURL == "") {
+		return errors.New("scrobbling API secret, API URL and auth URL must be set when a scrobbling API key is set")
+	}
+
+	// Validate captcha configuration
+	switch config.Captcha.Provider {
+	case "":
+	case "hcaptcha", "turnstile":
+		if config.Captcha.Secret == "" || config.Captcha.SiteKey == "" {
+			return errors.New("captcha site key and secret must be set when a captcha provider is set")
+		}
+		if config.Captcha.Timeout <= 0 || config.Captcha.AttemptWindow <= 0 {
+			return errors.New("captcha timeout and attempt window must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown captcha provider: %s", config.Captcha.Provider)
+	}
+	for action, policy := range config.Captcha.Actions {
+		switch action {
+		case "signup", "password_reset", "report":
+		default:
+			return fmt.Errorf("unknown captcha action: %s", action)
+		}
+		switch policy.Mode {
+		case "always", "risky", "off":
+		default:
+			return fmt.Errorf("unknown captcha mode for %s: %s", action, policy.Mode)
+		}
+	}
+
+	// Validate chat moderation configuration
+	switch config.Room.ToxicityClassifier {
+	case "", "wordlist":
+	case "http":
+		if config.Room.ToxicityClassifierURL == "" {
+			return errors.New("toxicity classifier URL must be set when the http toxicity classifier is used")
+		}
+	default:
+		return fmt.Errorf("unknown toxicity classifier: %s", config.Room.ToxicityClassifier)
+	}
+
+	// Validate track change monitoring configuration
+	if config.Room.TransitionLateAfter <= 0 || config.Room.TransitionMissedAfter < config.Room.TransitionLateAfter {
+		return errors.New("track changes must count as late after a positive delay, and as missed no sooner")
+	}
+
+	// Validate history export configuration
+	if config.Room.HistoryExportMaxConcurrent < 1 || config.Room.HistoryExportBatchSize < 1 {
+		return errors.New("history exports must allow at least one export per user and read at least one play at a time")
+	}
+	if config.Room.HistoryExportWriteTimeout <= 0 || config.Room.HistoryExportMaxDuration <= 0 {
+		return errors.New("history export write timeout and maximum duration must be positive")
+	}
+
+	// Validate Redis memory budget configuration
+	if t := config.System.RedisMemoryTrimThreshold; t <= 0 || t > 1 {
+		return errors.New("Redis memory trim threshold must be above 0 and at most 1")
+	}
+
+	// Validate status page configuration
+	if config.System.StatusUptimeDays < 1 || config.System.StatusIncidentAfterFailures < 0 {
+		return errors.New("status page uptime must cover at least a day, and incidents can't open after fewer than 0 failures")
+	}
+
+	// Validate image review configuration
+	if config.System.ImageReviewBatch < 1 || config.System.ImageMaxSize < 1 ||
+		config.System.ImageMatchDistance < 0 || config.System.ImageMatchDistance > 64 {
+		return errors.New("image reviews must match at least one image of at least one byte per check, within a distance of 0 to 64 bits")
+	}
+
+	// Validate platform state snapshot configuration
+	if config.System.SnapshotRateLimit < 1 || config.System.SnapshotCacheTTL < 0 {
+		return errors.New("snapshot rate limit must be at least 1, and the snapshot cache TTL can't be negative")
+	}
+	for _, token := range config.System.SnapshotTokens {
+		if len(token) < 32 {
+			return errors.New("snapshot service tokens must be at least 32 characters long")
+		}
+	}
+
+	// Validate trust configuration
+	for _, level := range []string{config.Trust.PostLinksLevel, config.Trust.CreateRoomLevel, config.Trust.LongTrackLevel} {
+		if _, err := models.ParseTrustLevel(level); err != nil {
+			return fmt.Errorf("invalid trust configuration: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -426,10 +1226,20 @@ server:
   use_https: false
   cert_file: ""
   key_file: ""
-  trusted_proxies: []
+  trusted_proxies: [] # Reverse proxies whose X-Forwarded-For is trusted, e.g. ["10.0.0.0/8"]
+  shutdown_accept_timeout: "10s" # Wait for HTTP requests once new ones are turned away
+  shutdown_notify_timeout: "10s" # Wait for RPC requests in flight before disconnecting clients
+  shutdown_flush_timeout: "10s" # Flush pending webhooks, analytics and maintenance
+  shutdown_persist_timeout: "5s" # Store the state of the rooms served by the instance
+  shutdown_close_timeout: "5s" # Close the database clients
+  node_id: "" # Identifies the instance among the WebSocket servers, generated when empty
+  node_heartbeat: "15s" # Refresh the instance's connection registry in Redis
+  region: "default" # Deployment region of the instance, shared by the instances serving the same clients
+  region_endpoint: "" # WebSocket URL clients use to connect to the region, e.g. wss://eu.example.com/ws
 
 # Database configuration
 database:
+  use_in_memory: false # Use in-memory stores instead of MongoDB and Redis (development only)
   mongodb:
     uri: "mongodb://localhost:27017"
     database: "musicroom"
@@ -437,6 +1247,7 @@ database:
     max_pool_size: 100
     min_pool_size: 10
     max_idle_time: "60s"
+    migration_timeout: "10m" # Time allowed for migrating the database and building indexes at startup
   redis:
     addresses: ["localhost:6379"]
     password: ""
@@ -458,6 +1269,21 @@ auth:
   password_max_length: 72
   password_reset_expiry: "1h"
   allowed_origins: ["*"]
+  max_api_keys: 10
+  api_key_rate_limit: 60 # Requests per minute for each personal API key
+  guests_enabled: true # Allow listening without registering
+  guest_history_transfer: true # Guests may keep their listening time when they register
+  oauth_providers: {} # Client credentials per provider (google, discord); set in secrets file
+  oauth_state_expiry: "10m" # Time allowed to complete signing in with a provider
+  username_min_length: 3
+  username_max_length: 20 # At most 30
+  username_reserved: ["admin", "administrator", "moderator", "mod", "system", "listenify", "support", "staff", "root", "guest", "deleted-user"]
+  username_blocked_terms: [] # Terms usernames can't contain with the profanity filter on; the chat's blocked terms when empty
+  username_rename_cooldown: "720h" # 30 days between renames
+  username_history_size: 10 # Previous usernames kept on each user
+  deletion_grace_period: "720h" # How long a deleted account can be restored by signing in before its data is purged
+  deletion_batch_size: 50 # Accounts purged per maintenance run
+  data_export_link_expiry: "15m" # How long a personal data export download link stays valid
 
 # Media configuration
 media:
@@ -466,15 +1292,119 @@ media:
   allowed_sources: ["youtube", "soundcloud"]
   max_duration: 600 # 10 minutes
   cache_expiry: "24h"
+  loudness_target: -14 # LUFS
+  max_normalization_gain: 12 # dB
+  loudness_analyzer_url: "" # Analysis service for loudness the providers don't expose; empty disables analysis
+  loudness_analysis_interval: "1h"
+  loudness_analysis_batch: 100
+  lyrics_provider_url: "" # LRCLIB compatible lookup endpoint, e.g. https://lrclib.net/api/get; empty disables lyrics
+  lyrics_cache_ttl: "168h" # How long lyrics, or their absence, are cached per media item
+  lyrics_lookup_interval: "1h"
+  lyrics_lookup_batch: 50
+  search_cache_ttl: "10m" # How long search results are served from the cache; 0 disables it
+  search_budgets: # Quota cost per search and daily quota spent on searches per provider; daily 0 means no limit
+    youtube:
+      cost: 100
+      daily: 9000 # Leaves room in the default 10000 unit quota for lookups
+    soundcloud:
+      cost: 1
+      daily: 0
+  search_budget_timezone: "America/Los_Angeles" # YouTube quotas reset at midnight Pacific time
+  previews_enabled: false # Preview search results through the preview proxy
+  preview_providers: [] # Only providers whose terms of service permit proxied previews
+  preview_duration: "15s"
+  preview_bitrate: 128 # kbps, previews are cut by bytes
+  preview_cache_ttl: "1h"
+  preview_cache_size: 64 # MB of previews cached per node
+  preview_rate_limit: 30 # Previews per user per window; 0 means no limit
+  preview_rate_window: "1m"
 
 # Room configuration
 room:
   max_rooms: 100
   max_users_per_room: 200
   max_dj_queue_size: 50
+  set_plan_max_tracks: 10 # Tracks DJs can plan to play next, 0 disables set planning
+  set_plan_ttl: "12h" # How long planned tracks are kept after the plan was last changed
   room_inactive_timeout: "6h"
   default_room_theme: "default"
   available_themes: ["default", "dark", "light", "neon", "vintage"]
+  geoip_database: "" # CSV of network,ISO country code pairs; empty disables listener geo
+  large_room_threshold: 500 # Rooms with more users get summarized state broadcasts
+  large_room_roster_size: 50 # Users listed in a summarized room state
+  large_room_event_interval: "5s" # Minimum interval between broadcasts of the same event in large rooms
+  join_roster_page_size: 500 # Users per roster chunk sent after joining a large room
+  join_chat_backlog: 50 # Recent chat messages sent to users joining a room, unless the room sets its own
+  max_pinned_messages: 3
+  moderation_undo_window: "30s" # How long moderators can undo their last kick, mute, ban or message deletion
+  calendar_cache_ttl: "5m" # How long generated ICS event feeds are cached
+  event_reminder_before: "10m" # How long before a scheduled room event starts its room is reminded
+  event_check_interval: "1m" # How often scheduled room events are checked for reminders and starts; 0 disables them
+  rotation_report_cache_ttl: "5m" # How long generated DJ rotation reports are cached
+  popup_max_lifetime: "72h" # Longest lifetime a pop-up room can be created with
+  popup_reminder_before: "10m" # How long before a pop-up room expires its users are reminded
+  popup_check_interval: "1m"
+  lobby_cache_fresh: "15s" # How long lobby listings are served from the cache before being refreshed; 0 disables caching
+  lobby_cache_stale: "2m" # How long past that a stale listing is still served while it is refreshed
+  state_cache_ttl: "2s" # How long room states are kept in memory for frequent reads; 0 disables the cache
+  analytics_flush_interval: "1m" # How often room analytics events are delivered to owners' webhooks; 0 disables delivery
+  analytics_retention: "168h" # How long daily room analytics files are kept for download
+  heat_interval: "1m" # How often the heat score of rooms used in discovery is recomputed; 0 disables it
+  language_interval: "15m" # How often the chat language of rooms is detected; 0 disables it
+  language_min_messages: 20 # Recent chat messages a room needs before its language is detected
+  membership_reconcile_interval: "5m" # How often room memberships are reconciled across Redis, MongoDB and live connections; 0 disables it
+  membership_reconcile_workers: 8 # Rooms reconciled in parallel
+  membership_grace: "10m" # How long a member without a live connection is kept before being removed
+  state_heartbeat_interval: "10m" # How often the Redis state of rooms with live connections is kept from expiring; 0 disables it
+  playback_sync_interval: "15s" # How often rooms with live connections are told where their current media is by the server's clock; 0 disables it
+  admission_interval: "5s" # How often this node's load is evaluated for load-aware room admission; 0 disables it
+  admission_max_connections: 20000 # Connections to this node above which room capacities are reduced and joins queued; 0 ignores connections
+  admission_max_broadcast_latency: "250ms" # Average room broadcast latency above which room capacities are reduced and joins queued; 0 ignores latency
+  admission_capacity_factor: 0.75 # Share of room capacities open to joins while constrained
+  admission_queue_timeout: "2m" # How long a queued join keeps its place without the user trying again
+  history_import_max_size: 67108864 # Largest play history import accepted in one request, in bytes
+  history_import_max_age: "4320h" # Oldest play accepted in a history import; keep within the play history retention
+  history_export_max_concurrent: 2 # History exports a user may download at once
+  history_export_batch_size: 200 # Plays read from the database at a time while exporting
+  history_export_write_timeout: "30s" # How long an export waits for a stalled client before giving up
+  history_export_max_duration: "30m" # Longest single export download; clients resume with the last range token
+  toxicity_classifier: "" # Chat toxicity classifier: wordlist, http, or empty to disable scoring
+  toxicity_classifier_url: "" # Classification service used by the http classifier
+  toxicity_classifier_key: "" # Must be set in environment or secrets file
+  toxicity_blocked_terms: [] # Terms scored by the wordlist classifier
+  toxicity_flag_threshold: 0.6 # Score from which messages are flagged to the room's moderation queue
+  toxicity_mask_threshold: 0.85 # Score from which flagged messages are masked until reviewed
+  toxicity_workers: 2 # Chat messages scored at once
+  transition_slow_after: "500ms" # Track changes taking longer to run are logged with their stage timings
+  transition_late_after: "5s" # Track changes this long after the track's expected end count as late
+  transition_missed_after: "1m" # Rooms still on a track this long after its expected end count as a missed track change
+
+# Trust level configuration
+trust:
+  basic:
+    min_account_age: "24h"
+    min_listening_time: "30m"
+    max_strikes: 2
+  member:
+    min_account_age: "168h"
+    min_listening_time: "10h"
+    max_strikes: 1
+  regular:
+    min_account_age: "720h"
+    min_listening_time: "50h"
+    max_strikes: 0
+  post_links_level: "basic"
+  create_room_level: "member"
+  long_track_level: "member"
+  long_track_duration: 600 # Tracks longer than this many seconds count as long
+
+# Restricted experience of users who attested being under 18
+minors:
+  enabled: true
+  filter_chat: true
+  blocked_terms: [] # Terms minors can't post in chat; the chat's blocked terms when empty
+  disable_links: true
+  hide_restricted_rooms: true
 
 # WebSocket configuration
 websocket:
@@ -483,6 +1413,16 @@ websocket:
   pong_wait: "60s"
   ping_period: "54s"
   max_connections: 10000
+  broadcast_shards: 8 # Workers room broadcasts are spread across, so a busy room only holds up its own shard
+  broadcast_backlog: 512 # Messages a room can have waiting for delivery before the oldest are dropped
+  resume_window: "2m" # How long a disconnected client can resume its session, 0 disables resuming
+  resume_buffer: 200 # Room notifications kept for a disconnected client to replay when it resumes
+  admission_rate: 200 # Connections admitted per second, so reconnect storms are spread out, 0 admits without limit
+  admission_burst: 400 # Connections admitted at once before the rate applies
+  admission_queue: 2000 # Connections that can wait for their turn before connections are turned away
+  admission_queue_timeout: "10s" # Longest a connection waits for its turn before it is turned away
+  reconnect_jitter: "10s" # Spread of the random delay added to the reconnect hints of disconnected clients
+  rejoin_state_ttl: "3s" # How long clients rejoining a room after reconnecting share its join state, 0 disables sharing
 
 # Logging configuration
 logging:
@@ -490,6 +1430,7 @@ logging:
   format: "json"
   output_paths: ["stdout"]
   error_output_paths: ["stderr"]
+  trace_buffer_size: 10000 # Recent traced log lines kept for the admin log search
 
 # Feature flags
 features:
@@ -499,6 +1440,82 @@ features:
   enable_avatars: true
   enable_soundcloud: true
   enable_profanity_filter: true
+
+# System monitoring
+system:
+  metrics_history_interval: "1m" # How often operational gauges are recorded
+  metrics_history_retention: "720h" # 30 days
+  quiet_hours: {} # Per task class (cleanup, optimization, backfill), e.g. optimization: ["17:00-23:00"]
+  quiet_hours_timezone: "UTC"
+  history_archive_dir: "" # Archive old history here (e.g. a mounted bucket) instead of deleting it
+  history_archive_after: "720h" # 30 days
+  history_archive_batch: 5000 # Records per archive file
+  dead_letter_max_entries: 10000 # Failed events kept for replay, 0 to only log them
+  dead_letter_retention: "336h" # 14 days
+  redis_memory_interval: "5m" # How often Redis memory is measured and caches trimmed; 0 disables it
+  redis_memory_trim_threshold: 0.85 # Share of Redis maxmemory above which caches are trimmed
+  redis_memory_sample_size: 100 # Keys per key family whose memory usage is measured
+  redis_vote_media_per_room: 50 # Media per room whose votes are kept, 0 for no limit
+  status_incident_after_failures: 3 # Consecutive failed health checks opening an incident; 0 disables it
+  status_uptime_days: 90 # Days of health checks kept for the status page uptime
+  status_cache_ttl: "30s" # How long the status page feed is cached
+  image_review_interval: "1m" # How often changed avatars and playlist covers are matched against the blocked images; 0 disables it
+  image_review_batch: 50 # Most changed images matched per check
+  image_match_distance: 10 # Most bits of 64 a perceptual hash may differ from a blocked image's to match it
+  image_max_size: 10485760 # Largest image in bytes downloaded to hash it
+  snapshot_tokens: [] # Service tokens the analytics pipeline reads the platform state snapshot with; empty disables it
+  snapshot_rate_limit: 12 # Snapshots a service token may request per minute
+  snapshot_cache_ttl: "5s" # How long a generated snapshot is served before another is generated
+
+# Developer applications and their platform event webhooks
+developer:
+  max_apps: 5 # Developer applications per user, 0 for no limit
+  webhook_poll_interval: "5s" # How often due webhook deliveries are attempted; 0 disables delivery
+  webhook_timeout: "10s"
+  webhook_max_attempts: 8 # Attempts per event before it is given up on
+  webhook_retry_backoff: "30s" # Wait before the first retry, doubled for each further one
+  webhook_max_backoff: "1h"
+  webhook_workers: 8 # Deliveries attempted at once per instance
+  delivery_log_retention: "168h" # 7 days
+
+# Scrobbling of listened plays to users' Last.fm (or compatible) accounts
+scrobbling:
+  api_url: "https://ws.audioscrobbler.com/2.0/"
+  auth_url: "https://www.last.fm/api/auth/" # Page users authorize the application on
+  api_key: "" # Empty disables scrobbling
+  api_secret: "" # Shared secret requests are signed with
+  callback_url: "" # Where users are sent back to with their authorization token
+  poll_interval: "5s" # How often due scrobbles are submitted; 0 disables submitting them
+  timeout: "10s"
+  max_attempts: 6 # Attempts per scrobble before it is given up on
+  retry_backoff: "1m" # Wait before the first retry, doubled for each further one
+  max_backoff: "1h"
+  workers: 4 # Scrobbles submitted at once per instance
+  log_retention: "2160h" # 90 days
+
+# Captcha verification of signups, password resets and reports
+captcha:
+  provider: "" # "hcaptcha", "turnstile" or empty to disable captchas
+  site_key: "" # Public site key clients render the captcha with
+  secret: "" # Secret key tokens are verified with; set in secrets file
+  verify_url: "" # Overrides the provider's verification endpoint
+  timeout: "5s"
+  attempt_window: "1h" # Window over which attempts from an IP address are counted
+  actions: # Mode "always", "risky" or "off"; risky attempts are new accounts or more than risky_after attempts per IP in the window
+    signup: { mode: "risky", risky_after: 3, fail_open: true } # fail_open lets the action through while the provider is down
+    password_reset: { mode: "risky", risky_after: 3, fail_open: true }
+    report: { mode: "risky", risky_after: 5, fail_open: false }
+
+# Outbound email and the templates of emails and long-form notifications
+email:
+  smtp_host: "" # SMTP server emails are sent through; empty only logs them
+  smtp_port: 587
+  smtp_username: "" # Empty sends without authenticating
+  smtp_password: ""
+  from: "Listenify <no-reply@listenify.local>"
+  templates_dir: "./templates" # Shipped templates, <locale>/<name>.subject.tmpl, .txt.tmpl and .html.tmpl
+  template_cache_ttl: "5m" # How long parsed templates are cached; 0 disables the cache
+  default_locale: "en" # Locale messages fall back to
 `
 		if err := os.WriteFile(defaultConfigPath, []byte(defaultConfig), 0644); err != nil {
 			return fmt.Errorf("failed to write default config file: %w", err)
@@ -578,6 +1595,11 @@ features:
 # Authentication configuration
 auth:
   jwt_secret: "replace_with_a_secure_random_string"
+  oauth_providers:
+    google:
+      client_id: "your_google_client_id"
+      client_secret: "your_google_client_secret"
+      redirect_url: "https://example.com/auth/oauth/google/callback"
 
 # Media configuration
 media:
@@ -289,6 +289,9 @@ func CreateDefaultConfig() *Config {
 	config.Room.RoomInactiveTimeout = 6 * time.Hour
 	config.Room.DefaultRoomTheme = "default"
 	config.Room.AvailableThemes = []string{"default", "dark", "light", "neon", "vintage"}
+	config.Room.TransitionSlowAfter = 500 * time.Millisecond
+	config.Room.TransitionLateAfter = 5 * time.Second
+	config.Room.TransitionMissedAfter = time.Minute
 
 	// Set default WebSocket configuration
 	config.WebSocket.MaxMessageSize = 4096
@@ -296,6 +299,15 @@ func CreateDefaultConfig() *Config {
 	config.WebSocket.PongWait = 60 * time.Second
 	config.WebSocket.PingPeriod = 54 * time.Second
 	config.WebSocket.MaxConnections = 10000
+	config.WebSocket.BroadcastShards = 8
+	config.WebSocket.BroadcastBacklog = 512
+
+	// Set default email configuration
+	config.Email.SMTPPort = 587
+	config.Email.From = "Listenify <no-reply@listenify.local>"
+	config.Email.TemplatesDir = "./templates"
+	config.Email.TemplateCacheTTL = 5 * time.Minute
+	config.Email.DefaultLocale = "en"
 
 	// Set default logging configuration
 	config.Logging.Level = "info"
@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// achievementRepository is the in-memory implementation of repositories.AchievementRepository.
+type achievementRepository struct {
+	achievements *Collection
+	unlocked     *Collection
+	logger       *utils.Logger
+}
+
+// NewAchievementRepository creates a new in-memory AchievementRepository.
+func NewAchievementRepository(db *Database, logger *utils.Logger) repositories.AchievementRepository {
+	achievements := db.Collection("achievements")
+	achievements.EnsureUniqueIndex("key")
+
+	unlocked := db.Collection("user_achievements")
+	unlocked.EnsureUniqueIndex("userId", "key")
+
+	return &achievementRepository{
+		achievements: achievements,
+		unlocked:     unlocked,
+		logger:       logger.Named("memory_achievement_repository"),
+	}
+}
+
+// CreateAchievement creates an achievement definition. Keys are unique among achievements.
+func (r *achievementRepository) CreateAchievement(ctx context.Context, achievement *models.Achievement) error {
+	if achievement.ID.IsZero() {
+		achievement.ID = bson.NewObjectID()
+	}
+	achievement.CreateNow()
+
+	if err := r.achievements.InsertOne(achievement); err != nil {
+		if isDuplicateKey(err) {
+			return models.ErrAchievementExists
+		}
+		r.logger.Error("Failed to create achievement", err, "key", achievement.Key)
+		return models.NewInternalError(err, "Failed to create achievement")
+	}
+	return nil
+}
+
+// FindAchievements finds every achievement definition, by metric and threshold.
+func (r *achievementRepository) FindAchievements(ctx context.Context) ([]*models.Achievement, error) {
+	sort := bson.D{{Key: "metric", Value: 1}, {Key: "threshold", Value: 1}}
+
+	achievements, err := findMany[models.Achievement](r.achievements, bson.M{}, pageOptions(sort, 0, 0))
+	if err != nil {
+		r.logger.Error("Failed to find achievements", err)
+		return nil, models.NewInternalError(err, "Failed to find achievements")
+	}
+	if achievements == nil {
+		achievements = []*models.Achievement{}
+	}
+	return achievements, nil
+}
+
+// UnlockAchievement records a user unlocking an achievement. An achievement can only be unlocked once per user.
+func (r *achievementRepository) UnlockAchievement(ctx context.Context, unlocked *models.UserAchievement) error {
+	if unlocked.ID.IsZero() {
+		unlocked.ID = bson.NewObjectID()
+	}
+
+	if err := r.unlocked.InsertOne(unlocked); err != nil {
+		if isDuplicateKey(err) {
+			return models.ErrAchievementUnlocked
+		}
+		r.logger.Error("Failed to unlock achievement", err, "userId", unlocked.UserID.Hex(), "key", unlocked.Key)
+		return models.NewInternalError(err, "Failed to unlock achievement")
+	}
+	return nil
+}
+
+// FindUserAchievements finds the achievements a user unlocked, oldest first.
+func (r *achievementRepository) FindUserAchievements(ctx context.Context, userID bson.ObjectID) ([]*models.UserAchievement, error) {
+	sort := bson.D{{Key: "unlockedAt", Value: 1}}
+
+	unlocked, err := findMany[models.UserAchievement](r.unlocked, bson.M{"userId": userID}, pageOptions(sort, 0, 0))
+	if err != nil {
+		r.logger.Error("Failed to find user achievements", err, "userId", userID.Hex())
+		return nil, models.NewInternalError(err, "Failed to find user achievements")
+	}
+	if unlocked == nil {
+		unlocked = []*models.UserAchievement{}
+	}
+	return unlocked, nil
+}
+
+// DeleteUserAchievements deletes the achievements a user unlocked, and returns how many it deleted.
+func (r *achievementRepository) DeleteUserAchievements(ctx context.Context, userID bson.ObjectID) (int64, error) {
+	deleted, err := r.unlocked.DeleteMany(bson.M{"userId": userID})
+	if err != nil {
+		return 0, models.NewInternalError(err, "Failed to delete user achievements")
+	}
+	return deleted, nil
+}
+
+// Ensure achievementRepository implements the interface
+var _ repositories.AchievementRepository = (*achievementRepository)(nil)
@@ -0,0 +1,248 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// chatRepository is the in-memory implementation of repositories.ChatRepository.
+type chatRepository struct {
+	messages *Collection
+	flags    *Collection
+	logger   *utils.Logger
+}
+
+// NewChatRepository creates a new in-memory ChatRepository.
+func NewChatRepository(db *Database, logger *utils.Logger) repositories.ChatRepository {
+	return &chatRepository{
+		messages: db.Collection("chat_messages"),
+		flags:    db.Collection("chat_flags"),
+		logger:   logger.Named("memory_chat_repository"),
+	}
+}
+
+// SaveMessage saves a chat message.
+func (r *chatRepository) SaveMessage(ctx context.Context, message *models.ChatMessage) error {
+	if message.ID.IsZero() {
+		message.ID = bson.NewObjectID()
+	}
+	if message.CreatedAt.IsZero() {
+		message.CreatedAt = time.Now()
+	}
+
+	if err := r.messages.InsertOne(message); err != nil {
+		r.logger.Error("Failed to save chat message", err, "roomId", message.RoomID.Hex())
+		return models.NewInternalError(err, "Failed to save chat message")
+	}
+	return nil
+}
+
+// FindMessageByID finds a chat message by its ID.
+func (r *chatRepository) FindMessageByID(ctx context.Context, id bson.ObjectID) (*models.ChatMessage, error) {
+	message, err := findOne[models.ChatMessage](r.messages, bson.M{"_id": id}, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, models.ErrMessageNotFound
+		}
+		return nil, models.NewInternalError(err, "Failed to find chat message")
+	}
+	return message, nil
+}
+
+// FindMessagesByRoom finds chat messages posted in one of a room's chat channels, the general channel if it is empty,
+// most recent first.
+func (r *chatRepository) FindMessagesByRoom(ctx context.Context, roomID bson.ObjectID, channel string, limit int, before bson.ObjectID) ([]*models.ChatMessage, error) {
+	if limit <= 0 {
+		limit = 50 // Default limit
+	}
+
+	filter := bson.M{"roomId": roomID, "channel": chatChannelFilter(channel)}
+
+	if !before.IsZero() {
+		beforeMsg, err := r.FindMessageByID(ctx, before)
+		if err != nil && !errors.Is(err, models.ErrMessageNotFound) {
+			return nil, err
+		}
+		if beforeMsg != nil {
+			filter["createdAt"] = bson.M{"$lt": beforeMsg.CreatedAt}
+		}
+	}
+
+	messages, err := findMany[models.ChatMessage](r.messages, filter, pageOptions(bson.D{{Key: "createdAt", Value: -1}}, 0, limit))
+	if err != nil {
+		r.logger.Error("Failed to find chat messages", err, "roomId", roomID.Hex())
+		return nil, models.NewInternalError(err, "Failed to find chat messages")
+	}
+	return messages, nil
+}
+
+// DeleteMessage marks a chat message as deleted.
+func (r *chatRepository) DeleteMessage(ctx context.Context, id bson.ObjectID) error {
+	matched, err := r.messages.UpdateByID(id, bson.M{"$set": bson.M{"isDeleted": true, "deletedAt": time.Now()}})
+	if err != nil {
+		return models.NewInternalError(err, "Failed to delete chat message")
+	}
+	if matched == 0 {
+		return models.ErrMessageNotFound
+	}
+	return nil
+}
+
+// RestoreMessage restores a deleted chat message.
+func (r *chatRepository) RestoreMessage(ctx context.Context, id bson.ObjectID) error {
+	matched, err := r.messages.UpdateByID(id, bson.M{"$set": bson.M{"isDeleted": false}, "$unset": bson.M{"deletedBy": "", "deletedAt": ""}})
+	if err != nil {
+		return models.NewInternalError(err, "Failed to restore chat message")
+	}
+	if matched == 0 {
+		return models.ErrMessageNotFound
+	}
+	return nil
+}
+
+// UpdateMessage updates a chat message.
+func (r *chatRepository) UpdateMessage(ctx context.Context, message *models.ChatMessage) error {
+	message.IsEdited = true
+	message.EditedAt = time.Now()
+
+	matched, err := r.messages.ReplaceOne(bson.M{"_id": message.ID}, message)
+	if err != nil {
+		return models.NewInternalError(err, "Failed to update chat message")
+	}
+	if matched == 0 {
+		return models.ErrMessageNotFound
+	}
+	return nil
+}
+
+// DeleteMessagesByUser marks all messages from a user in a room as deleted.
+func (r *chatRepository) DeleteMessagesByUser(ctx context.Context, roomID, userID bson.ObjectID) (int64, error) {
+	matched, err := r.messages.UpdateMany(roomAndUserIDs(roomID, userID), bson.M{"$set": bson.M{"isDeleted": true, "deletedAt": time.Now()}})
+	if err != nil {
+		return 0, models.NewInternalError(err, "Failed to delete user's chat messages")
+	}
+	return matched, nil
+}
+
+// AnonymizeUser detaches a deleted user's messages and mentions from their account.
+func (r *chatRepository) AnonymizeUser(ctx context.Context, userID bson.ObjectID) (int64, error) {
+	matched, err := r.messages.UpdateMany(bson.M{"userId": userID}, bson.M{"$set": bson.M{"userId": bson.NilObjectID}})
+	if err != nil {
+		return 0, models.NewInternalError(err, "Failed to anonymize user's chat messages")
+	}
+	if _, err := r.messages.UpdateMany(bson.M{"mentions": userID}, bson.M{"$pull": bson.M{"mentions": userID}}); err != nil {
+		return matched, models.NewInternalError(err, "Failed to anonymize user's chat messages")
+	}
+	return matched, nil
+}
+
+// DeleteMessagesByRoom marks all messages in a room as deleted.
+func (r *chatRepository) DeleteMessagesByRoom(ctx context.Context, roomID, deletedBy bson.ObjectID) (int64, error) {
+	matched, err := r.messages.UpdateMany(bson.M{"roomId": roomID, "isDeleted": false}, bson.M{"$set": bson.M{"isDeleted": true, "deletedBy": deletedBy, "deletedAt": time.Now()}})
+	if err != nil {
+		return 0, models.NewInternalError(err, "Failed to delete room's chat messages")
+	}
+	return matched, nil
+}
+
+// SetMessageMasked masks a chat message, or shows a masked message again.
+func (r *chatRepository) SetMessageMasked(ctx context.Context, id bson.ObjectID, masked bool) error {
+	matched, err := r.messages.UpdateByID(id, bson.M{"$set": bson.M{"isMasked": masked}})
+	if err != nil {
+		return models.NewInternalError(err, "Failed to mask chat message")
+	}
+	if matched == 0 {
+		return models.ErrMessageNotFound
+	}
+	return nil
+}
+
+// CreateFlag adds a flagged chat message to its room's moderation queue.
+func (r *chatRepository) CreateFlag(ctx context.Context, flag *models.ChatFlag) error {
+	if flag.ID.IsZero() {
+		flag.ID = bson.NewObjectID()
+	}
+	flag.CreateNow()
+
+	if err := r.flags.InsertOne(flag); err != nil {
+		r.logger.Error("Failed to create chat flag", err, "roomId", flag.RoomID.Hex(), "messageId", flag.MessageID.Hex())
+		return models.NewInternalError(err, "Failed to create chat flag")
+	}
+	return nil
+}
+
+// FindFlagByID finds a chat flag by its ID.
+func (r *chatRepository) FindFlagByID(ctx context.Context, id bson.ObjectID) (*models.ChatFlag, error) {
+	flag, err := findOne[models.ChatFlag](r.flags, bson.M{"_id": id}, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, models.ErrChatFlagNotFound
+		}
+		return nil, models.NewInternalError(err, "Failed to find chat flag")
+	}
+	return flag, nil
+}
+
+// FindFlagsByRoom finds a room's chat flags, oldest first, along with the total number matching.
+// An empty status matches every flag.
+func (r *chatRepository) FindFlagsByRoom(ctx context.Context, roomID bson.ObjectID, status models.ChatFlagStatus, skip, limit int) ([]*models.ChatFlag, int64, error) {
+	query := bson.M{"roomId": roomID}
+	if status != "" {
+		query["status"] = status
+	}
+
+	total, err := r.flags.CountDocuments(query)
+	if err != nil {
+		return nil, 0, models.NewInternalError(err, "Failed to count chat flags")
+	}
+
+	flags, err := findMany[models.ChatFlag](r.flags, query, pageOptions(bson.D{{Key: "createdAt", Value: 1}}, skip, limit))
+	if err != nil {
+		r.logger.Error("Failed to find chat flags", err, "roomId", roomID.Hex())
+		return nil, 0, models.NewInternalError(err, "Failed to find chat flags")
+	}
+	if flags == nil {
+		flags = []*models.ChatFlag{}
+	}
+	return flags, total, nil
+}
+
+// ReviewFlag records a moderator's review of a pending chat flag and returns the reviewed flag.
+func (r *chatRepository) ReviewFlag(ctx context.Context, id bson.ObjectID, status models.ChatFlagStatus, reviewedBy bson.ObjectID) (*models.ChatFlag, error) {
+	now := time.Now()
+	matched, err := r.flags.UpdateOne(bson.M{"_id": id, "status": models.ChatFlagPending}, bson.M{"$set": bson.M{
+		"status":     status,
+		"reviewedBy": reviewedBy,
+		"reviewedAt": now,
+		"updatedAt":  now,
+	}})
+	if err != nil {
+		return nil, models.NewInternalError(err, "Failed to review chat flag")
+	}
+
+	flag, err := r.FindFlagByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if matched == 0 {
+		return nil, models.ErrChatFlagReviewed
+	}
+	return flag, nil
+}
+
+// chatChannelFilter matches the messages of a chat channel. Messages in the general channel are stored without one.
+func chatChannelFilter(channel string) any {
+	if channel == "" || channel == models.DefaultChatChannel {
+		return bson.M{"$exists": false}
+	}
+	return channel
+}
+
+// Ensure chatRepository implements the interface
+var _ repositories.ChatRepository = (*chatRepository)(nil)
@@ -0,0 +1,450 @@
+// Package memory provides in-memory repository implementations for tests and local development.
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	// ErrNoDocuments is returned when a query matches no documents.
+	ErrNoDocuments = errors.New("no documents in result")
+
+	// ErrDuplicateKey is returned when an insert or replace violates a unique index.
+	ErrDuplicateKey = errors.New("duplicate key error")
+)
+
+// Collection is a minimal in-memory document collection.
+// Documents are stored as BSON so callers never share memory with the store,
+// and queries support the subset of MongoDB filter and update operators used by the repositories.
+type Collection struct {
+	name   string
+	docs   []bson.M
+	unique [][]string
+	mutex  sync.RWMutex
+}
+
+// Database is a set of named in-memory collections, the in-memory counterpart of a MongoDB database.
+type Database struct {
+	collections map[string]*Collection
+	mutex       sync.Mutex
+}
+
+// NewDatabase creates a new empty in-memory database.
+func NewDatabase() *Database {
+	return &Database{
+		collections: make(map[string]*Collection),
+	}
+}
+
+// Collection returns the collection with the given name, creating it if needed.
+func (db *Database) Collection(name string) *Collection {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	c, ok := db.collections[name]
+	if !ok {
+		c = &Collection{
+			name: name,
+			docs: make([]bson.M, 0),
+		}
+		db.collections[name] = c
+	}
+	return c
+}
+
+// EnsureUniqueIndex adds a unique index over the given fields if it does not exist yet.
+func (c *Collection) EnsureUniqueIndex(fields ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, existing := range c.unique {
+		if slices.Equal(existing, fields) {
+			return
+		}
+	}
+	c.unique = append(c.unique, fields)
+}
+
+// InsertOne inserts a document into the collection.
+func (c *Collection) InsertOne(doc any) error {
+	d, err := toDocument(doc)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := c.checkUnique(d, -1); err != nil {
+		return err
+	}
+
+	c.docs = append(c.docs, d)
+	return nil
+}
+
+// FindOne decodes the first document matching the filter into dest.
+func (c *Collection) FindOne(filter any, sortSpec any, dest any) error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	matched, err := c.match(filter)
+	if err != nil {
+		return err
+	}
+
+	if len(matched) == 0 {
+		return ErrNoDocuments
+	}
+
+	sortDocuments(matched, sortSpec)
+	return fromDocument(matched[0], dest)
+}
+
+// Find decodes all documents matching the filter into dest, which must be a pointer to a slice.
+func (c *Collection) Find(filter any, opts options.Lister[options.FindOptions], dest any) error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	matched, err := c.match(filter)
+	if err != nil {
+		return err
+	}
+
+	findOpts := &options.FindOptions{}
+	if opts != nil && !reflect.ValueOf(opts).IsNil() {
+		for _, set := range opts.List() {
+			if err := set(findOpts); err != nil {
+				return err
+			}
+		}
+	}
+
+	sortDocuments(matched, findOpts.Sort)
+
+	if findOpts.Skip != nil && *findOpts.Skip > 0 {
+		skip := min(int(*findOpts.Skip), len(matched))
+		matched = matched[skip:]
+	}
+	if findOpts.Limit != nil && *findOpts.Limit > 0 && int(*findOpts.Limit) < len(matched) {
+		matched = matched[:*findOpts.Limit]
+	}
+
+	return decodeAll(matched, dest)
+}
+
+// CountDocuments counts the documents matching the filter.
+func (c *Collection) CountDocuments(filter any) (int64, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	matched, err := c.match(filter)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(matched)), nil
+}
+
+// ReplaceOne replaces the first document matching the filter and returns the number of matched documents.
+func (c *Collection) ReplaceOne(filter any, doc any) (int64, error) {
+	d, err := toDocument(doc)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	f, err := toDocument(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, existing := range c.docs {
+		if matchDocument(existing, f) {
+			if _, ok := d["_id"]; !ok {
+				d["_id"] = existing["_id"]
+			}
+			if err := c.checkUnique(d, i); err != nil {
+				return 0, err
+			}
+			c.docs[i] = d
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// UpdateOne applies an update to the first document matching the filter and returns the number of matched documents.
+func (c *Collection) UpdateOne(filter any, update any) (int64, error) {
+	return c.update(filter, update, false)
+}
+
+// UpdateMany applies an update to all documents matching the filter and returns the number of matched documents.
+func (c *Collection) UpdateMany(filter any, update any) (int64, error) {
+	return c.update(filter, update, true)
+}
+
+// UpdateByID applies an update to the document with the given ID and returns the number of matched documents.
+func (c *Collection) UpdateByID(id bson.ObjectID, update any) (int64, error) {
+	return c.update(bson.M{"_id": id}, update, false)
+}
+
+// DeleteOne deletes the first document matching the filter and returns the number of deleted documents.
+func (c *Collection) DeleteOne(filter any) (int64, error) {
+	return c.delete(filter, false)
+}
+
+// DeleteMany deletes all documents matching the filter and returns the number of deleted documents.
+func (c *Collection) DeleteMany(filter any) (int64, error) {
+	return c.delete(filter, true)
+}
+
+// update applies an update document to matching documents.
+func (c *Collection) update(filter any, update any, many bool) (int64, error) {
+	f, err := toDocument(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	u, err := toDocument(update)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var matched int64
+	for i, existing := range c.docs {
+		if !matchDocument(existing, f) {
+			continue
+		}
+
+		updated, err := cloneDocument(existing)
+		if err != nil {
+			return matched, err
+		}
+		resolved, err := resolvePositional(updated, f, u)
+		if err != nil {
+			return matched, err
+		}
+		if err := applyUpdate(updated, resolved); err != nil {
+			return matched, err
+		}
+		if err := c.checkUnique(updated, i); err != nil {
+			return matched, err
+		}
+
+		c.docs[i] = updated
+		matched++
+		if !many {
+			break
+		}
+	}
+	return matched, nil
+}
+
+// delete removes matching documents.
+func (c *Collection) delete(filter any, many bool) (int64, error) {
+	f, err := toDocument(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var deleted int64
+	kept := c.docs[:0]
+	for _, existing := range c.docs {
+		if (many || deleted == 0) && matchDocument(existing, f) {
+			deleted++
+			continue
+		}
+		kept = append(kept, existing)
+	}
+	c.docs = kept
+	return deleted, nil
+}
+
+// match returns the documents matching the filter.
+func (c *Collection) match(filter any) ([]bson.M, error) {
+	f, err := toDocument(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]bson.M, 0)
+	for _, d := range c.docs {
+		if matchDocument(d, f) {
+			matched = append(matched, d)
+		}
+	}
+	return matched, nil
+}
+
+// checkUnique verifies that a document does not violate the _id or any unique index.
+// skip is the index of the document being replaced, or -1 for inserts.
+func (c *Collection) checkUnique(d bson.M, skip int) error {
+	indexes := append([][]string{{"_id"}}, c.unique...)
+	for i, existing := range c.docs {
+		if i == skip {
+			continue
+		}
+		for _, fields := range indexes {
+			if sameKey(existing, d, fields) {
+				return fmt.Errorf("%w: collection %s index %s", ErrDuplicateKey, c.name, strings.Join(fields, "_"))
+			}
+		}
+	}
+	return nil
+}
+
+// sameKey checks if two documents have equal, non-missing values for all the given fields.
+func sameKey(a, b bson.M, fields []string) bool {
+	for _, field := range fields {
+		av, aok := lookupPath(a, field)
+		bv, bok := lookupPath(b, field)
+		if !aok || !bok || !valuesEqual(av, bv) {
+			return false
+		}
+	}
+	return true
+}
+
+// toDocument converts a value into a normalized document.
+func toDocument(v any) (bson.M, error) {
+	if v == nil {
+		return bson.M{}, nil
+	}
+
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var d bson.M
+	dec := bson.NewDecoder(bson.NewDocumentReader(bytes.NewReader(data)))
+	dec.DefaultDocumentM()
+	if err := dec.Decode(&d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// normalizeValue converts a Go value into the representation used by stored documents.
+func normalizeValue(v any) any {
+	d, err := toDocument(bson.M{"v": v})
+	if err != nil {
+		return v
+	}
+	return d["v"]
+}
+
+// cloneDocument returns a deep copy of a document.
+func cloneDocument(d bson.M) (bson.M, error) {
+	return toDocument(d)
+}
+
+// fromDocument decodes a document into dest.
+func fromDocument(d bson.M, dest any) error {
+	data, err := bson.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, dest)
+}
+
+// decodeAll decodes documents into dest, which must be a pointer to a slice.
+func decodeAll(docs []bson.M, dest any) error {
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Pointer || slice.Elem().Kind() != reflect.Slice {
+		return errors.New("dest must be a pointer to a slice")
+	}
+
+	elemType := slice.Elem().Type().Elem()
+	result := reflect.MakeSlice(slice.Elem().Type(), 0, len(docs))
+	for _, d := range docs {
+		var elem reflect.Value
+		if elemType.Kind() == reflect.Pointer {
+			elem = reflect.New(elemType.Elem())
+			if err := fromDocument(d, elem.Interface()); err != nil {
+				return err
+			}
+		} else {
+			ptr := reflect.New(elemType)
+			if err := fromDocument(d, ptr.Interface()); err != nil {
+				return err
+			}
+			elem = ptr.Elem()
+		}
+		result = reflect.Append(result, elem)
+	}
+
+	slice.Elem().Set(result)
+	return nil
+}
+
+// sortDocuments sorts documents in place according to a sort specification.
+// Text score sorts are ignored since the in-memory store does not rank text matches.
+func sortDocuments(docs []bson.M, spec any) {
+	if spec == nil {
+		return
+	}
+
+	type sortKey struct {
+		field string
+		dir   int
+	}
+
+	var keys []sortKey
+	switch s := spec.(type) {
+	case bson.D:
+		for _, e := range s {
+			if dir, ok := toNumber(e.Value); ok {
+				keys = append(keys, sortKey{e.Key, int(dir)})
+			}
+		}
+	case bson.M:
+		fields := make([]string, 0, len(s))
+		for field := range s {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			if dir, ok := toNumber(s[field]); ok {
+				keys = append(keys, sortKey{field, int(dir)})
+			}
+		}
+	}
+
+	if len(keys) == 0 {
+		return
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, key := range keys {
+			a, _ := lookupPath(docs[i], key.field)
+			b, _ := lookupPath(docs[j], key.field)
+			cmp := compareValues(a, b)
+			if cmp != 0 {
+				if key.dir < 0 {
+					return cmp > 0
+				}
+				return cmp < 0
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"errors"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// findOne finds a single document and decodes it into a new T.
+func findOne[T any](c *Collection, filter any, sortSpec any) (*T, error) {
+	var result T
+	if err := c.FindOne(filter, sortSpec, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// findMany finds all matching documents and decodes them into a slice of T.
+func findMany[T any](c *Collection, filter any, opts options.Lister[options.FindOptions]) ([]*T, error) {
+	var results []*T
+	if err := c.Find(filter, opts, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// pageOptions builds find options with a sort order, skip and limit.
+func pageOptions(sortSpec bson.D, skip, limit int) *options.FindOptionsBuilder {
+	opts := options.Find().SetSort(sortSpec)
+	if skip > 0 {
+		opts.SetSkip(int64(skip))
+	}
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	return opts
+}
+
+// caseInsensitive builds a filter condition matching a string exactly, ignoring case.
+func caseInsensitive(value string) bson.M {
+	return bson.M{"$regex": "^" + regexp.QuoteMeta(value) + "$", "$options": "i"}
+}
+
+// isNotFound checks if an error indicates that no document matched.
+func isNotFound(err error) bool {
+	return errors.Is(err, ErrNoDocuments)
+}
+
+// isDuplicateKey checks if an error indicates a unique index violation.
+func isDuplicateKey(err error) bool {
+	return errors.Is(err, ErrDuplicateKey)
+}
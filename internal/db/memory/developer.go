@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// developerRepository is the in-memory implementation of repositories.DeveloperRepository.
+type developerRepository struct {
+	apps       *Collection
+	deliveries *Collection
+	logger     *utils.Logger
+}
+
+// NewDeveloperRepository creates a new in-memory DeveloperRepository.
+func NewDeveloperRepository(db *Database, logger *utils.Logger) repositories.DeveloperRepository {
+	return &developerRepository{
+		apps:       db.Collection("developer_apps"),
+		deliveries: db.Collection("webhook_deliveries"),
+		logger:     logger.Named("memory_developer_repository"),
+	}
+}
+
+// CreateApp creates a new developer application.
+func (r *developerRepository) CreateApp(ctx context.Context, app *models.DeveloperApp) error {
+	if app.ID.IsZero() {
+		app.ID = bson.NewObjectID()
+	}
+	app.CreateNow()
+
+	if err := r.apps.InsertOne(app); err != nil {
+		r.logger.Error("Failed to create developer application", err, "ownerId", app.OwnerID.Hex())
+		return models.NewInternalError(err, "Failed to create developer application")
+	}
+	return nil
+}
+
+// FindAppByID finds a developer application by its ID.
+func (r *developerRepository) FindAppByID(ctx context.Context, id bson.ObjectID) (*models.DeveloperApp, error) {
+	app, err := findOne[models.DeveloperApp](r.apps, bson.M{"_id": id}, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, models.ErrDeveloperAppNotFound
+		}
+		return nil, models.NewInternalError(err, "Failed to find developer application")
+	}
+	return app, nil
+}
+
+// FindAppsByOwner finds the developer applications a user registered, oldest first.
+func (r *developerRepository) FindAppsByOwner(ctx context.Context, ownerID bson.ObjectID) ([]*models.DeveloperApp, error) {
+	return r.findApps(bson.M{"ownerId": ownerID})
+}
+
+// FindAppsByEvent finds the active developer applications subscribed to an event.
+func (r *developerRepository) FindAppsByEvent(ctx context.Context, event models.WebhookEvent) ([]*models.DeveloperApp, error) {
+	return r.findApps(bson.M{"events": event, "active": true})
+}
+
+// findApps finds the developer applications matching a query, oldest first.
+func (r *developerRepository) findApps(query bson.M) ([]*models.DeveloperApp, error) {
+	apps, err := findMany[models.DeveloperApp](r.apps, query, pageOptions(bson.D{{Key: "createdAt", Value: 1}}, 0, 0))
+	if err != nil {
+		r.logger.Error("Failed to find developer applications", err)
+		return nil, models.NewInternalError(err, "Failed to find developer applications")
+	}
+	if apps == nil {
+		apps = []*models.DeveloperApp{}
+	}
+	return apps, nil
+}
+
+// UpdateApp updates a developer application.
+func (r *developerRepository) UpdateApp(ctx context.Context, app *models.DeveloperApp) error {
+	app.UpdateNow()
+
+	matched, err := r.apps.ReplaceOne(bson.M{"_id": app.ID}, app)
+	if err != nil {
+		r.logger.Error("Failed to update developer application", err, "id", app.ID.Hex())
+		return models.NewInternalError(err, "Failed to update developer application")
+	}
+	if matched == 0 {
+		return models.ErrDeveloperAppNotFound
+	}
+	return nil
+}
+
+// DeleteApp deletes a developer application along with its delivery log.
+func (r *developerRepository) DeleteApp(ctx context.Context, id bson.ObjectID) error {
+	deleted, err := r.apps.DeleteOne(bson.M{"_id": id})
+	if err != nil {
+		return models.NewInternalError(err, "Failed to delete developer application")
+	}
+	if deleted == 0 {
+		return models.ErrDeveloperAppNotFound
+	}
+
+	if _, err := r.deliveries.DeleteMany(bson.M{"appId": id}); err != nil {
+		r.logger.Error("Failed to delete webhook deliveries of developer application", err, "id", id.Hex())
+	}
+	return nil
+}
+
+// CreateDelivery adds a delivery attempt to an application's delivery log.
+func (r *developerRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if delivery.ID.IsZero() {
+		delivery.ID = bson.NewObjectID()
+	}
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now()
+	}
+
+	if err := r.deliveries.InsertOne(delivery); err != nil {
+		r.logger.Error("Failed to create webhook delivery", err, "appId", delivery.AppID.Hex())
+		return models.NewInternalError(err, "Failed to create webhook delivery")
+	}
+	return nil
+}
+
+// FindDeliveries finds an application's delivery attempts, most recent first, along with the total number.
+func (r *developerRepository) FindDeliveries(ctx context.Context, appID bson.ObjectID, skip, limit int) ([]*models.WebhookDelivery, int64, error) {
+	query := bson.M{"appId": appID}
+
+	total, err := r.deliveries.CountDocuments(query)
+	if err != nil {
+		return nil, 0, models.NewInternalError(err, "Failed to count webhook deliveries")
+	}
+
+	deliveries, err := findMany[models.WebhookDelivery](r.deliveries, query, pageOptions(bson.D{{Key: "createdAt", Value: -1}}, skip, limit))
+	if err != nil {
+		r.logger.Error("Failed to find webhook deliveries", err, "appId", appID.Hex())
+		return nil, 0, models.NewInternalError(err, "Failed to find webhook deliveries")
+	}
+	if deliveries == nil {
+		deliveries = []*models.WebhookDelivery{}
+	}
+	return deliveries, total, nil
+}
+
+// DeleteDeliveriesBefore deletes the delivery attempts made before a time, and returns how many it deleted.
+func (r *developerRepository) DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error) {
+	deleted, err := r.deliveries.DeleteMany(bson.M{"createdAt": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, models.NewInternalError(err, "Failed to delete webhook deliveries")
+	}
+	return deleted, nil
+}
+
+// Ensure developerRepository implements the interface
+var _ repositories.DeveloperRepository = (*developerRepository)(nil)
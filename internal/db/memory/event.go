@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// eventRepository is the in-memory implementation of repositories.EventRepository.
+type eventRepository struct {
+	events *Collection
+	logger *utils.Logger
+}
+
+// NewEventRepository creates a new in-memory EventRepository.
+func NewEventRepository(db *Database, logger *utils.Logger) repositories.EventRepository {
+	return &eventRepository{
+		events: db.Collection("room_events"),
+		logger: logger.Named("memory_event_repository"),
+	}
+}
+
+// CreateEvent schedules a new room event.
+func (r *eventRepository) CreateEvent(ctx context.Context, event *models.RoomEvent) error {
+	if event.ID.IsZero() {
+		event.ID = bson.NewObjectID()
+	}
+
+	if err := r.events.InsertOne(event); err != nil {
+		r.logger.Error("Failed to create room event", err, "roomId", event.RoomID.Hex())
+		return models.NewInternalError(err, "Failed to create room event")
+	}
+	return nil
+}
+
+// FindRoomEvents finds the events of the given rooms ending after a time, soonest first.
+func (r *eventRepository) FindRoomEvents(ctx context.Context, roomIDs []bson.ObjectID, endAfter time.Time) ([]*models.RoomEvent, error) {
+	return r.findEvents(bson.M{
+		"roomId":  bson.M{"$in": roomIDs},
+		"endTime": bson.M{"$gt": endAfter},
+	})
+}
+
+// CountRoomEvents counts the events of a room ending after a time.
+func (r *eventRepository) CountRoomEvents(ctx context.Context, roomID bson.ObjectID, endAfter time.Time) (int64, error) {
+	count, err := r.events.CountDocuments(bson.M{"roomId": roomID, "endTime": bson.M{"$gt": endAfter}})
+	if err != nil {
+		return 0, models.NewInternalError(err, "Failed to count room events")
+	}
+	return count, nil
+}
+
+// FindEventsStartingBefore finds the events of every room starting before a time and ending after
+// another, soonest first.
+func (r *eventRepository) FindEventsStartingBefore(ctx context.Context, startBefore, endAfter time.Time) ([]*models.RoomEvent, error) {
+	return r.findEvents(bson.M{
+		"startTime": bson.M{"$lte": startBefore},
+		"endTime":   bson.M{"$gt": endAfter},
+	})
+}
+
+// DeleteEvent cancels a room event.
+func (r *eventRepository) DeleteEvent(ctx context.Context, roomID, eventID bson.ObjectID) error {
+	deleted, err := r.events.DeleteOne(bson.M{"_id": eventID, "roomId": roomID})
+	if err != nil {
+		return models.NewInternalError(err, "Failed to delete room event")
+	}
+	if deleted == 0 {
+		return models.ErrRoomEventNotFound
+	}
+	return nil
+}
+
+// findEvents finds the events matching a filter, soonest first.
+func (r *eventRepository) findEvents(filter bson.M) ([]*models.RoomEvent, error) {
+	events, err := findMany[models.RoomEvent](r.events, filter, pageOptions(bson.D{{Key: "startTime", Value: 1}}, 0, 0))
+	if err != nil {
+		r.logger.Error("Failed to find room events", err)
+		return nil, models.NewInternalError(err, "Failed to find room events")
+	}
+	if events == nil {
+		events = []*models.RoomEvent{}
+	}
+	return events, nil
+}
+
+// Ensure eventRepository implements the interface
+var _ repositories.EventRepository = (*eventRepository)(nil)
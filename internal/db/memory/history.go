@@ -0,0 +1,739 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// historyRepository is the in-memory implementation of repositories.HistoryRepository.
+type historyRepository struct {
+	history           *Collection
+	playHistory       *Collection
+	userHistory       *Collection
+	roomHistory       *Collection
+	djHistory         *Collection
+	sessionHistory    *Collection
+	moderationHistory *Collection
+	logger            *utils.Logger
+}
+
+// NewHistoryRepository creates a new in-memory HistoryRepository.
+func NewHistoryRepository(db *Database, logger *utils.Logger) repositories.HistoryRepository {
+	return &historyRepository{
+		history:           db.Collection("history"),
+		playHistory:       db.Collection("play_history"),
+		userHistory:       db.Collection("user_history"),
+		roomHistory:       db.Collection("room_history"),
+		djHistory:         db.Collection("dj_history"),
+		sessionHistory:    db.Collection("session_history"),
+		moderationHistory: db.Collection("moderation_history"),
+		logger:            logger.Named("memory_history_repository"),
+	}
+}
+
+// CreateHistory creates a new generic history record.
+func (r *historyRepository) CreateHistory(ctx context.Context, history *models.History) error {
+	if history.ID.IsZero() {
+		history.ID = bson.NewObjectID()
+	}
+	if history.Timestamp.IsZero() {
+		history.Timestamp = time.Now()
+	}
+	if history.TraceID == "" {
+		history.TraceID = utils.TraceIDFromContext(ctx)
+	}
+
+	return r.insert(r.history, history, "Failed to create history record")
+}
+
+// FindHistoryByID finds a history record by its ID.
+func (r *historyRepository) FindHistoryByID(ctx context.Context, id bson.ObjectID) (*models.History, error) {
+	return findHistoryRecord[models.History](r, r.history, id, "history record not found")
+}
+
+// FindHistoryByType finds history records by type.
+func (r *historyRepository) FindHistoryByType(ctx context.Context, historyType string, skip, limit int) ([]*models.History, error) {
+	return findHistoryRecords[models.History](r, r.history, bson.M{"type": historyType}, "timestamp", skip, limit)
+}
+
+// FindHistoryByReference finds history records by reference ID.
+func (r *historyRepository) FindHistoryByReference(ctx context.Context, referenceID bson.ObjectID, skip, limit int) ([]*models.History, error) {
+	return findHistoryRecords[models.History](r, r.history, bson.M{"referenceId": referenceID}, "timestamp", skip, limit)
+}
+
+// CreatePlayHistory creates a new play history record.
+func (r *historyRepository) CreatePlayHistory(ctx context.Context, playHistory *models.PlayHistory) error {
+	if playHistory.ID.IsZero() {
+		playHistory.ID = bson.NewObjectID()
+	}
+	if playHistory.StartTime.IsZero() {
+		playHistory.StartTime = time.Now()
+	}
+	if !playHistory.EndTime.IsZero() {
+		playHistory.Duration = int(playHistory.EndTime.Sub(playHistory.StartTime).Seconds())
+	}
+	if playHistory.Votes.Voters == nil {
+		playHistory.Votes.Voters = make(map[string]string)
+	}
+
+	if err := r.insert(r.playHistory, playHistory, "Failed to create play history"); err != nil {
+		return err
+	}
+
+	r.createGenericHistory(ctx, "media", playHistory.MediaID, playHistory.StartTime, map[string]any{
+		"playHistoryId": playHistory.ID,
+		"roomId":        playHistory.RoomID,
+		"djId":          playHistory.DjID,
+	})
+	return nil
+}
+
+// FindPlayHistoryByID finds a play history record by its ID.
+func (r *historyRepository) FindPlayHistoryByID(ctx context.Context, id bson.ObjectID) (*models.PlayHistory, error) {
+	playHistory, err := findOne[models.PlayHistory](r.playHistory, bson.M{"_id": id}, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, models.ErrPlayHistoryNotFound
+		}
+		return nil, models.NewInternalError(err, "Failed to find play history")
+	}
+	return playHistory, nil
+}
+
+// UpdatePlayHistory updates how a play ended. Votes are recorded separately and left alone.
+func (r *historyRepository) UpdatePlayHistory(ctx context.Context, playHistory *models.PlayHistory) error {
+	update := bson.M{"$set": bson.M{
+		"endTime":     playHistory.EndTime,
+		"duration":    playHistory.Duration,
+		"skipped":     playHistory.Skipped,
+		"skipReason":  playHistory.SkipReason,
+		"skippedBy":   playHistory.SkippedBy,
+		"interrupted": playHistory.Interrupted,
+		"truncated":   playHistory.Truncated,
+
+		// Votes are counted in Redis while the media plays and kept on the play once it ends
+		"votes.woots":     playHistory.Votes.Woots,
+		"votes.mehs":      playHistory.Votes.Mehs,
+		"votes.grabs":     playHistory.Votes.Grabs,
+		"votes.reactions": playHistory.Votes.Reactions,
+	}}
+
+	matched, err := r.playHistory.UpdateByID(playHistory.ID, update)
+	if err != nil {
+		return models.NewInternalError(err, "Failed to update play history")
+	}
+	if matched == 0 {
+		return models.ErrPlayHistoryNotFound
+	}
+	return nil
+}
+
+// UpsertImportedPlay creates or replaces a play imported from another platform, keyed by its room and external ID.
+// It returns whether the play was created.
+func (r *historyRepository) UpsertImportedPlay(ctx context.Context, playHistory *models.PlayHistory) (bool, error) {
+	if playHistory.Votes.Voters == nil {
+		playHistory.Votes.Voters = make(map[string]string)
+	}
+
+	filter := bson.M{"roomId": playHistory.RoomID, "externalId": playHistory.ExternalID}
+	existing, err := findOne[models.PlayHistory](r.playHistory, filter, nil)
+	if err != nil && !isNotFound(err) {
+		return false, models.NewInternalError(err, "Failed to import play history")
+	}
+	if existing == nil {
+		playHistory.ID = bson.NewObjectID()
+		return true, r.insert(r.playHistory, playHistory, "Failed to import play history")
+	}
+
+	playHistory.ID = existing.ID
+	if _, err := r.playHistory.ReplaceOne(bson.M{"_id": existing.ID}, playHistory); err != nil {
+		return false, models.NewInternalError(err, "Failed to import play history")
+	}
+	return false, nil
+}
+
+// FindPlayHistoryByRoom finds play history records for a room.
+func (r *historyRepository) FindPlayHistoryByRoom(ctx context.Context, roomID bson.ObjectID, skip, limit int) ([]*models.PlayHistory, error) {
+	return findHistoryRecords[models.PlayHistory](r, r.playHistory, bson.M{"roomId": roomID}, "startTime", skip, limit)
+}
+
+// FindPlayHistoryByDJ finds play history records for a DJ.
+func (r *historyRepository) FindPlayHistoryByDJ(ctx context.Context, djID bson.ObjectID, skip, limit int) ([]*models.PlayHistory, error) {
+	return findHistoryRecords[models.PlayHistory](r, r.playHistory, bson.M{"djId": djID}, "startTime", skip, limit)
+}
+
+// StreamPlayHistoryByDJ passes a DJ's play history records with IDs between after and before to fn, in ID
+// order. An error from fn stops the stream and is returned as is.
+func (r *historyRepository) StreamPlayHistoryByDJ(ctx context.Context, djID, after, before bson.ObjectID, batchSize int, fn func(*models.PlayHistory) error) error {
+	filter := bson.M{"djId": djID, "_id": bson.M{"$gt": after, "$lt": before}}
+	plays, err := findMany[models.PlayHistory](r.playHistory, filter, pageOptions(bson.D{{Key: "_id", Value: 1}}, 0, 0))
+	if err != nil {
+		return models.NewInternalError(err, "Failed to find play history")
+	}
+	for _, play := range plays {
+		if err := fn(play); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindPlayHistoryByMedia finds play history records for a media item.
+func (r *historyRepository) FindPlayHistoryByMedia(ctx context.Context, mediaID bson.ObjectID, skip, limit int) ([]*models.PlayHistory, error) {
+	return findHistoryRecords[models.PlayHistory](r, r.playHistory, bson.M{"mediaId": mediaID}, "startTime", skip, limit)
+}
+
+// GetPlayHistorySummary gets a summary of play history for a room.
+func (r *historyRepository) GetPlayHistorySummary(ctx context.Context, roomID bson.ObjectID) (*models.HistorySummary, error) {
+	plays, err := r.FindPlayHistoryByRoom(ctx, roomID, 0, 0)
+	if err != nil {
+		return nil, models.NewInternalError(err, "Failed to generate history summary")
+	}
+
+	tracks := make(map[bson.ObjectID]bool)
+	djs := make(map[bson.ObjectID]bool)
+	var totalPlayTime int64
+	var totalWoots, totalMehs, totalGrabs int
+	for _, play := range plays {
+		tracks[playedSong(play)] = true
+		djs[play.DjID] = true
+		totalPlayTime += int64(play.Duration)
+		totalWoots += play.Votes.Woots
+		totalMehs += play.Votes.Mehs
+		totalGrabs += play.Votes.Grabs
+	}
+
+	summary := &models.HistorySummary{
+		TotalPlays:        len(plays),
+		TotalUniqueTracks: len(tracks),
+		TotalDJs:          len(djs),
+		TotalPlayTime:     totalPlayTime,
+		LastUpdated:       time.Now(),
+	}
+
+	if len(plays) > 0 {
+		summary.AverageVotes.Woots = float64(totalWoots) / float64(len(plays))
+		summary.AverageVotes.Mehs = float64(totalMehs) / float64(len(plays))
+		summary.AverageVotes.Grabs = float64(totalGrabs) / float64(len(plays))
+	}
+
+	summary.TopTracks, err = r.GetTopTracks(ctx, roomID, 10)
+	if err != nil {
+		summary.TopTracks = []models.TopTrackSummary{}
+	}
+
+	summary.TopDJs, err = r.GetTopDJs(ctx, roomID, 10)
+	if err != nil {
+		summary.TopDJs = []models.TopDJSummary{}
+	}
+
+	return summary, nil
+}
+
+// CreateUserHistory creates a new user history record.
+func (r *historyRepository) CreateUserHistory(ctx context.Context, userHistory *models.UserHistory) error {
+	if userHistory.ID.IsZero() {
+		userHistory.ID = bson.NewObjectID()
+	}
+	if userHistory.Timestamp.IsZero() {
+		userHistory.Timestamp = time.Now()
+	}
+
+	if err := r.insert(r.userHistory, userHistory, "Failed to create user history"); err != nil {
+		return err
+	}
+
+	r.createGenericHistory(ctx, "user", userHistory.UserID, userHistory.Timestamp, map[string]any{
+		"userHistoryId": userHistory.ID,
+		"type":          userHistory.Type,
+	})
+	return nil
+}
+
+// FindUserHistoryByType finds user history records by type.
+func (r *historyRepository) FindUserHistoryByType(ctx context.Context, userID bson.ObjectID, historyType string, skip, limit int) ([]*models.UserHistory, error) {
+	filter := bson.M{"userId": userID}
+	if historyType != "" {
+		filter["type"] = historyType
+	}
+	return findHistoryRecords[models.UserHistory](r, r.userHistory, filter, "timestamp", skip, limit)
+}
+
+// FindUserHistoryByTimeRange finds user history records within a time range.
+func (r *historyRepository) FindUserHistoryByTimeRange(ctx context.Context, userID bson.ObjectID, startTime, endTime time.Time, skip, limit int) ([]*models.UserHistory, error) {
+	filter := bson.M{
+		"userId":    userID,
+		"timestamp": bson.M{"$gte": startTime, "$lte": endTime},
+	}
+	return findHistoryRecords[models.UserHistory](r, r.userHistory, filter, "timestamp", skip, limit)
+}
+
+// CreateRoomHistory creates a new room history record.
+func (r *historyRepository) CreateRoomHistory(ctx context.Context, roomHistory *models.RoomHistory) error {
+	if roomHistory.ID.IsZero() {
+		roomHistory.ID = bson.NewObjectID()
+	}
+	if roomHistory.Timestamp.IsZero() {
+		roomHistory.Timestamp = time.Now()
+	}
+
+	if err := r.insert(r.roomHistory, roomHistory, "Failed to create room history"); err != nil {
+		return err
+	}
+
+	r.createGenericHistory(ctx, "room", roomHistory.RoomID, roomHistory.Timestamp, map[string]any{
+		"roomHistoryId": roomHistory.ID,
+		"type":          roomHistory.Type,
+	})
+	return nil
+}
+
+// FindRoomHistoryByID finds a room history record by its ID.
+func (r *historyRepository) FindRoomHistoryByID(ctx context.Context, id bson.ObjectID) (*models.RoomHistory, error) {
+	return findHistoryRecord[models.RoomHistory](r, r.roomHistory, id, "room history not found")
+}
+
+// FindRoomHistoryByRoom finds room history records for a room.
+func (r *historyRepository) FindRoomHistoryByRoom(ctx context.Context, roomID bson.ObjectID, skip, limit int) ([]*models.RoomHistory, error) {
+	return findHistoryRecords[models.RoomHistory](r, r.roomHistory, bson.M{"roomId": roomID}, "timestamp", skip, limit)
+}
+
+// FindRoomHistoryByType finds room history records by type.
+func (r *historyRepository) FindRoomHistoryByType(ctx context.Context, roomID bson.ObjectID, historyType string, skip, limit int) ([]*models.RoomHistory, error) {
+	filter := bson.M{"roomId": roomID, "type": historyType}
+	return findHistoryRecords[models.RoomHistory](r, r.roomHistory, filter, "timestamp", skip, limit)
+}
+
+// CreateDJHistory creates a new DJ history record.
+func (r *historyRepository) CreateDJHistory(ctx context.Context, djHistory *models.DJHistory) error {
+	if djHistory.ID.IsZero() {
+		djHistory.ID = bson.NewObjectID()
+	}
+	if djHistory.StartTime.IsZero() {
+		djHistory.StartTime = time.Now()
+	}
+
+	if err := r.insert(r.djHistory, djHistory, "Failed to create DJ history"); err != nil {
+		return err
+	}
+
+	r.createGenericHistory(ctx, "dj", djHistory.UserID, djHistory.StartTime, map[string]any{
+		"djHistoryId": djHistory.ID,
+		"roomId":      djHistory.RoomID,
+	})
+	return nil
+}
+
+// FindDJHistoryByUser finds DJ history records for a user.
+func (r *historyRepository) FindDJHistoryByUser(ctx context.Context, userID bson.ObjectID, skip, limit int) ([]*models.DJHistory, error) {
+	return findHistoryRecords[models.DJHistory](r, r.djHistory, bson.M{"userId": userID}, "startTime", skip, limit)
+}
+
+// FindDJHistoryByRoom finds DJ history records for a room.
+func (r *historyRepository) FindDJHistoryByRoom(ctx context.Context, roomID bson.ObjectID, skip, limit int) ([]*models.DJHistory, error) {
+	return findHistoryRecords[models.DJHistory](r, r.djHistory, bson.M{"roomId": roomID}, "startTime", skip, limit)
+}
+
+// UpdateDJHistoryEndTime updates the end time for a DJ history record.
+func (r *historyRepository) UpdateDJHistoryEndTime(ctx context.Context, id bson.ObjectID, endTime time.Time, leaveReason string) error {
+	djHistory, err := findHistoryRecord[models.DJHistory](r, r.djHistory, id, "DJ history not found")
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"endTime":     endTime,
+		"duration":    int(endTime.Sub(djHistory.StartTime).Seconds()),
+		"leaveReason": leaveReason,
+	}}
+
+	if _, err := r.djHistory.UpdateByID(id, update); err != nil {
+		return models.NewInternalError(err, "Failed to update DJ history")
+	}
+	return nil
+}
+
+// CreateSessionHistory creates a new session history record.
+func (r *historyRepository) CreateSessionHistory(ctx context.Context, sessionHistory *models.SessionHistory) error {
+	if sessionHistory.ID.IsZero() {
+		sessionHistory.ID = bson.NewObjectID()
+	}
+	if sessionHistory.StartTime.IsZero() {
+		sessionHistory.StartTime = time.Now()
+	}
+
+	if err := r.insert(r.sessionHistory, sessionHistory, "Failed to create session history"); err != nil {
+		return err
+	}
+
+	r.createGenericHistory(ctx, "user", sessionHistory.UserID, sessionHistory.StartTime, map[string]any{
+		"sessionHistoryId": sessionHistory.ID,
+		"type":             "session",
+	})
+	return nil
+}
+
+// UpdateSessionHistoryEndTime updates the end time for a session history record.
+func (r *historyRepository) UpdateSessionHistoryEndTime(ctx context.Context, id bson.ObjectID, endTime time.Time) error {
+	sessionHistory, err := findHistoryRecord[models.SessionHistory](r, r.sessionHistory, id, "session history not found")
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"endTime":  endTime,
+		"duration": int(endTime.Sub(sessionHistory.StartTime).Seconds()),
+	}}
+
+	if _, err := r.sessionHistory.UpdateByID(id, update); err != nil {
+		return models.NewInternalError(err, "Failed to update session history")
+	}
+	return nil
+}
+
+// FindSessionHistoryByUser finds session history records for a user.
+func (r *historyRepository) FindSessionHistoryByUser(ctx context.Context, userID bson.ObjectID, skip, limit int) ([]*models.SessionHistory, error) {
+	return findHistoryRecords[models.SessionHistory](r, r.sessionHistory, bson.M{"userId": userID}, "startTime", skip, limit)
+}
+
+// CreateModerationHistory creates a new moderation history record.
+func (r *historyRepository) CreateModerationHistory(ctx context.Context, moderationHistory *models.ModerationHistory) error {
+	if moderationHistory.ID.IsZero() {
+		moderationHistory.ID = bson.NewObjectID()
+	}
+	if moderationHistory.Timestamp.IsZero() {
+		moderationHistory.Timestamp = time.Now()
+	}
+	if moderationHistory.TraceID == "" {
+		moderationHistory.TraceID = utils.TraceIDFromContext(ctx)
+	}
+
+	if err := r.insert(r.moderationHistory, moderationHistory, "Failed to create moderation history"); err != nil {
+		return err
+	}
+
+	r.createGenericHistory(ctx, "moderation", moderationHistory.RoomID, moderationHistory.Timestamp, map[string]any{
+		"moderationHistoryId": moderationHistory.ID,
+		"action":              moderationHistory.Action,
+		"moderatorId":         moderationHistory.ModeratorID,
+		"targetUserId":        moderationHistory.TargetUserID,
+	})
+	return nil
+}
+
+// FindModerationHistoryByRoom finds moderation history records for a room.
+func (r *historyRepository) FindModerationHistoryByRoom(ctx context.Context, roomID bson.ObjectID, skip, limit int) ([]*models.ModerationHistory, error) {
+	return findHistoryRecords[models.ModerationHistory](r, r.moderationHistory, bson.M{"roomId": roomID}, "timestamp", skip, limit)
+}
+
+// FindModerationHistoryByModerator finds moderation history records for a moderator.
+func (r *historyRepository) FindModerationHistoryByModerator(ctx context.Context, moderatorID bson.ObjectID, skip, limit int) ([]*models.ModerationHistory, error) {
+	return findHistoryRecords[models.ModerationHistory](r, r.moderationHistory, bson.M{"moderatorId": moderatorID}, "timestamp", skip, limit)
+}
+
+// FindModerationHistoryByUser finds moderation history records targeting a user.
+func (r *historyRepository) FindModerationHistoryByUser(ctx context.Context, userID bson.ObjectID, skip, limit int) ([]*models.ModerationHistory, error) {
+	return findHistoryRecords[models.ModerationHistory](r, r.moderationHistory, bson.M{"targetUserId": userID}, "timestamp", skip, limit)
+}
+
+// GetTopTracks gets the most played tracks in a room, counting every provider's copy of a song together.
+func (r *historyRepository) GetTopTracks(ctx context.Context, roomID bson.ObjectID, limit int) ([]models.TopTrackSummary, error) {
+	plays, err := r.FindPlayHistoryByRoom(ctx, roomID, 0, 0)
+	if err != nil {
+		return nil, models.NewInternalError(err, "Failed to calculate top tracks")
+	}
+
+	// Plays are sorted newest first, so the first play of each track carries its latest metadata
+	tracks := make(map[bson.ObjectID]*models.TopTrackSummary)
+	audience := make(map[bson.ObjectID]int)
+	topTracks := make([]models.TopTrackSummary, 0)
+	for _, play := range plays {
+		song := playedSong(play)
+		track, ok := tracks[song]
+		if !ok {
+			track = &models.TopTrackSummary{
+				MediaID:    song,
+				Type:       play.Media.Type,
+				SourceID:   play.Media.SourceID,
+				Title:      play.Media.Title,
+				Artist:     play.Media.Artist,
+				LastPlayed: play.StartTime,
+			}
+			tracks[song] = track
+		}
+
+		track.PlayCount++
+		track.WootCount += play.Votes.Woots
+		track.MehCount += play.Votes.Mehs
+		track.GrabCount += play.Votes.Grabs
+		if play.Skipped {
+			track.SkipCount++
+		}
+		audience[song] += play.UserCount
+	}
+
+	for mediaID, track := range tracks {
+		track.AverageAudience = float64(audience[mediaID]) / float64(track.PlayCount)
+		topTracks = append(topTracks, *track)
+	}
+
+	utils.SortSlice(topTracks, func(i, j int) bool {
+		return topTracks[i].PlayCount > topTracks[j].PlayCount
+	})
+
+	if limit > 0 && len(topTracks) > limit {
+		topTracks = topTracks[:limit]
+	}
+
+	return topTracks, nil
+}
+
+// GetTopDJs gets the most active DJs in a room.
+func (r *historyRepository) GetTopDJs(ctx context.Context, roomID bson.ObjectID, limit int) ([]models.TopDJSummary, error) {
+	plays, err := r.FindPlayHistoryByRoom(ctx, roomID, 0, 0)
+	if err != nil {
+		return nil, models.NewInternalError(err, "Failed to calculate top DJs")
+	}
+
+	djs := make(map[bson.ObjectID]*models.TopDJSummary)
+	for _, play := range plays {
+		dj, ok := djs[play.DjID]
+		if !ok {
+			dj = &models.TopDJSummary{
+				UserID:     play.DjID,
+				Username:   play.DJ.Username,
+				LastDJTime: play.StartTime,
+			}
+			djs[play.DjID] = dj
+		}
+
+		dj.PlayCount++
+		dj.WootCount += play.Votes.Woots
+		dj.MehCount += play.Votes.Mehs
+		dj.GrabCount += play.Votes.Grabs
+		if play.Skipped {
+			dj.SkipCount++
+		}
+	}
+
+	sessions, err := r.FindDJHistoryByRoom(ctx, roomID, 0, 0)
+	if err != nil {
+		r.logger.Error("Failed to get DJ times", err, "roomId", roomID.Hex())
+		// Continue with play stats only
+		sessions = nil
+	}
+
+	for _, session := range sessions {
+		if dj, ok := djs[session.UserID]; ok {
+			dj.TotalDJTime += int64(session.Duration)
+			if session.EndTime.After(dj.LastDJTime) {
+				dj.LastDJTime = session.EndTime
+			}
+		}
+	}
+
+	topDJs := make([]models.TopDJSummary, 0, len(djs))
+	for _, dj := range djs {
+		topDJs = append(topDJs, *dj)
+	}
+
+	utils.SortSlice(topDJs, func(i, j int) bool {
+		return topDJs[i].PlayCount > topDJs[j].PlayCount
+	})
+
+	if limit > 0 && len(topDJs) > limit {
+		topDJs = topDJs[:limit]
+	}
+
+	return topDJs, nil
+}
+
+// GetRotationStats breaks down the DJ turns and plays of a room that started within a time window per DJ.
+// The DJs are sorted by the number of turns they received, most first.
+func (r *historyRepository) GetRotationStats(ctx context.Context, roomID bson.ObjectID, since, until time.Time) ([]models.DJRotationStats, error) {
+	filter := bson.M{"roomId": roomID, "startTime": bson.M{"$gte": since, "$lt": until}}
+
+	turns, err := findMany[models.DJHistory](r.djHistory, filter, nil)
+	if err != nil {
+		return nil, models.NewInternalError(err, "Failed to calculate DJ rotation")
+	}
+	plays, err := findMany[models.PlayHistory](r.playHistory, filter, pageOptions(bson.D{{Key: "startTime", Value: 1}}, 0, 0))
+	if err != nil {
+		return nil, models.NewInternalError(err, "Failed to calculate DJ rotation")
+	}
+
+	djs := make(map[bson.ObjectID]*models.DJRotationStats)
+	dj := func(userID bson.ObjectID) *models.DJRotationStats {
+		stats, ok := djs[userID]
+		if !ok {
+			stats = &models.DJRotationStats{UserID: userID}
+			djs[userID] = stats
+		}
+		return stats
+	}
+
+	for _, turn := range turns {
+		stats := dj(turn.UserID)
+		stats.Turns++
+		stats.AverageWait += float64(turn.WaitTime)
+		stats.LongestWait = max(stats.LongestWait, turn.WaitTime)
+	}
+	for _, play := range plays {
+		stats := dj(play.DjID)
+		stats.Username = play.DJ.Username
+		stats.Plays++
+		if play.Skipped {
+			stats.Skips++
+		}
+	}
+
+	rotation := make([]models.DJRotationStats, 0, len(djs))
+	for _, stats := range djs {
+		if stats.Turns > 0 {
+			stats.AverageWait /= float64(stats.Turns)
+		}
+		rotation = append(rotation, *stats)
+	}
+
+	utils.SortSlice(rotation, func(i, j int) bool {
+		if rotation[i].Turns != rotation[j].Turns {
+			return rotation[i].Turns > rotation[j].Turns
+		}
+		return rotation[i].Plays > rotation[j].Plays
+	})
+
+	return rotation, nil
+}
+
+// userRecordCollections lists the collections holding a user's own history, with the field that references the user.
+func (r *historyRepository) userRecordCollections() map[*Collection]string {
+	return map[*Collection]string{
+		r.playHistory:    "djId",
+		r.userHistory:    "userId",
+		r.djHistory:      "userId",
+		r.sessionHistory: "userId",
+	}
+}
+
+// CountUserRecords counts the play, user, DJ and session history records belonging to a user.
+func (r *historyRepository) CountUserRecords(ctx context.Context, userID bson.ObjectID) (int64, error) {
+	var total int64
+	for collection, field := range r.userRecordCollections() {
+		count, err := collection.CountDocuments(bson.M{field: userID})
+		if err != nil {
+			return total, models.NewInternalError(err, "Failed to count user history")
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// ReassignUser moves a user's play, user, DJ and session history records to another user.
+func (r *historyRepository) ReassignUser(ctx context.Context, fromID, toID bson.ObjectID) (int64, error) {
+	var total int64
+	for collection, field := range r.userRecordCollections() {
+		matched, err := collection.UpdateMany(bson.M{field: fromID}, bson.M{"$set": bson.M{field: toID}})
+		if err != nil {
+			return total, models.NewInternalError(err, "Failed to reassign user history")
+		}
+		total += matched
+	}
+	return total, nil
+}
+
+// AnonymizeUser detaches a deleted user's play, DJ and room history from their account and deletes the user and
+// session history about them.
+func (r *historyRepository) AnonymizeUser(ctx context.Context, userID bson.ObjectID) (int64, error) {
+	anonymous := models.PublicUser{BaseUser: models.BaseUser{Username: models.DeletedUsername}}
+	updates := []struct {
+		collection *Collection
+		filter     bson.M
+		update     bson.M
+	}{
+		{r.playHistory, bson.M{"djId": userID}, bson.M{"$set": bson.M{"djId": bson.NilObjectID, "dj": anonymous}}},
+		{r.playHistory, bson.M{"skippedBy": userID}, bson.M{"$unset": bson.M{"skippedBy": ""}}},
+		{r.djHistory, bson.M{"userId": userID}, bson.M{"$set": bson.M{"userId": bson.NilObjectID}}},
+		{r.roomHistory, bson.M{"userId": userID}, bson.M{"$unset": bson.M{"userId": ""}}},
+	}
+
+	var total int64
+	for _, u := range updates {
+		matched, err := u.collection.UpdateMany(u.filter, u.update)
+		if err != nil {
+			return total, models.NewInternalError(err, "Failed to anonymize user history")
+		}
+		total += matched
+	}
+	for _, collection := range []*Collection{r.userHistory, r.sessionHistory} {
+		deleted, err := collection.DeleteMany(bson.M{"userId": userID})
+		if err != nil {
+			return total, models.NewInternalError(err, "Failed to anonymize user history")
+		}
+		total += deleted
+	}
+	return total, nil
+}
+
+// insert inserts a history record into a collection.
+func (r *historyRepository) insert(c *Collection, record any, message string) error {
+	if err := c.InsertOne(record); err != nil {
+		r.logger.Error(message, err)
+		return models.NewInternalError(err, message)
+	}
+	return nil
+}
+
+// createGenericHistory records a generic history entry alongside a specific one.
+func (r *historyRepository) createGenericHistory(ctx context.Context, historyType string, referenceID bson.ObjectID, timestamp time.Time, metadata map[string]any) {
+	history := &models.History{
+		Type:        historyType,
+		ReferenceID: referenceID,
+		Timestamp:   timestamp,
+		Metadata:    metadata,
+	}
+
+	if err := r.CreateHistory(ctx, history); err != nil {
+		r.logger.Error("Failed to create generic history", err, "type", historyType)
+		// Continue anyway, the specific history was recorded
+	}
+}
+
+// findHistoryRecord finds a single history record by ID, returning notFound if it does not exist.
+func findHistoryRecord[T any](r *historyRepository, c *Collection, id bson.ObjectID, notFound string) (*T, error) {
+	record, err := findOne[T](c, bson.M{"_id": id}, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, errors.New(notFound)
+		}
+		r.logger.Error("Failed to find history record", err, "id", id.Hex())
+		return nil, models.NewInternalError(err, "Failed to find history record")
+	}
+	return record, nil
+}
+
+// findHistoryRecords finds history records, newest first by the given time field.
+func findHistoryRecords[T any](r *historyRepository, c *Collection, filter bson.M, timeField string, skip, limit int) ([]*T, error) {
+	records, err := findMany[T](c, filter, pageOptions(bson.D{{Key: timeField, Value: -1}}, skip, limit))
+	if err != nil {
+		r.logger.Error("Failed to find history records", err, "filter", filter)
+		return nil, models.NewInternalError(err, "Failed to find history records")
+	}
+	return records, nil
+}
+
+// Ensure historyRepository implements the interface
+var _ repositories.HistoryRepository = (*historyRepository)(nil)
+
+// playedSong returns the song a play counts towards, falling back to the media for plays recorded without a song ID.
+func playedSong(play *models.PlayHistory) bson.ObjectID {
+	if !play.SongID.IsZero() {
+		return play.SongID
+	}
+	return play.MediaID
+}
@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// imageReviewRepository is the in-memory implementation of repositories.ImageReviewRepository.
+type imageReviewRepository struct {
+	reviews *Collection
+	blocked *Collection
+	logger  *utils.Logger
+}
+
+// NewImageReviewRepository creates a new in-memory ImageReviewRepository.
+func NewImageReviewRepository(db *Database, logger *utils.Logger) repositories.ImageReviewRepository {
+	blocked := db.Collection("blocked_images")
+	blocked.EnsureUniqueIndex("hash")
+
+	return &imageReviewRepository{
+		reviews: db.Collection("image_reviews"),
+		blocked: blocked,
+		logger:  logger.Named("memory_image_review_repository"),
+	}
+}
+
+// CreateImageReview queues a changed image for review.
+func (r *imageReviewRepository) CreateImageReview(ctx context.Context, review *models.ImageReview) error {
+	if review.ID.IsZero() {
+		review.ID = bson.NewObjectID()
+	}
+	review.CreateNow()
+
+	if err := r.reviews.InsertOne(review); err != nil {
+		r.logger.Error("Failed to create image review", err, "kind", review.Kind, "targetId", review.TargetID.Hex())
+		return models.NewInternalError(err, "Failed to create image review")
+	}
+	return nil
+}
+
+// FindImageReviewByID finds an image review by its ID.
+func (r *imageReviewRepository) FindImageReviewByID(ctx context.Context, id bson.ObjectID) (*models.ImageReview, error) {
+	review, err := findOne[models.ImageReview](r.reviews, bson.M{"_id": id}, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, models.ErrImageReviewNotFound
+		}
+		return nil, models.NewInternalError(err, "Failed to find image review")
+	}
+	return review, nil
+}
+
+// FindImageReviews finds image reviews, most recently changed first, along with the total number matching.
+func (r *imageReviewRepository) FindImageReviews(ctx context.Context, filter models.ImageReviewFilter, skip, limit int) ([]*models.ImageReview, int64, error) {
+	query := imageReviewQuery(filter)
+
+	total, err := r.reviews.CountDocuments(query)
+	if err != nil {
+		return nil, 0, models.NewInternalError(err, "Failed to count image reviews")
+	}
+
+	reviews, err := findMany[models.ImageReview](r.reviews, query, pageOptions(bson.D{{Key: "createdAt", Value: -1}}, skip, limit))
+	if err != nil {
+		r.logger.Error("Failed to find image reviews", err)
+		return nil, 0, models.NewInternalError(err, "Failed to find image reviews")
+	}
+	if reviews == nil {
+		reviews = []*models.ImageReview{}
+	}
+	return reviews, total, nil
+}
+
+// FindUncheckedImageReviews finds the pending reviews whose image wasn't matched against the blocked images yet, oldest first.
+func (r *imageReviewRepository) FindUncheckedImageReviews(ctx context.Context, limit int) ([]*models.ImageReview, error) {
+	query := bson.M{"status": models.ImageReviewPending, "checked": false}
+
+	reviews, err := findMany[models.ImageReview](r.reviews, query, pageOptions(bson.D{{Key: "createdAt", Value: 1}}, 0, limit))
+	if err != nil {
+		r.logger.Error("Failed to find unchecked image reviews", err)
+		return nil, models.NewInternalError(err, "Failed to find image reviews")
+	}
+	if reviews == nil {
+		reviews = []*models.ImageReview{}
+	}
+	return reviews, nil
+}
+
+// SetImageReviewHash marks a review's image as checked, with its perceptual hash if it could be computed.
+func (r *imageReviewRepository) SetImageReviewHash(ctx context.Context, id bson.ObjectID, hash string) error {
+	set := bson.M{"checked": true, "updatedAt": time.Now()}
+	if hash != "" {
+		set["hash"] = hash
+	}
+
+	if _, err := r.reviews.UpdateByID(id, bson.M{"$set": set}); err != nil {
+		return models.NewInternalError(err, "Failed to update image review")
+	}
+	return nil
+}
+
+// ResolveImageReview records the outcome of a pending image review and returns the resolved review.
+// The matched image is zero unless a blocked image removed the image.
+func (r *imageReviewRepository) ResolveImageReview(ctx context.Context, id bson.ObjectID, status models.ImageReviewStatus, reason string, reviewedBy, matchedImageID bson.ObjectID) (*models.ImageReview, error) {
+	now := time.Now()
+	set := bson.M{
+		"status":     status,
+		"reason":     reason,
+		"reviewedAt": now,
+		"updatedAt":  now,
+	}
+	if !reviewedBy.IsZero() {
+		set["reviewedBy"] = reviewedBy
+	}
+	if !matchedImageID.IsZero() {
+		set["matchedImageId"] = matchedImageID
+	}
+
+	matched, err := r.reviews.UpdateOne(bson.M{"_id": id, "status": models.ImageReviewPending}, bson.M{"$set": set})
+	if err != nil {
+		return nil, models.NewInternalError(err, "Failed to resolve image review")
+	}
+
+	review, err := r.FindImageReviewByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if matched == 0 {
+		return nil, models.ErrImageReviewed
+	}
+	return review, nil
+}
+
+// DeleteOwnerImageReviews deletes the reviews of a user's images, and returns how many it deleted.
+func (r *imageReviewRepository) DeleteOwnerImageReviews(ctx context.Context, ownerID bson.ObjectID) (int64, error) {
+	deleted, err := r.reviews.DeleteMany(bson.M{"ownerId": ownerID})
+	if err != nil {
+		return 0, models.NewInternalError(err, "Failed to delete image reviews")
+	}
+	return deleted, nil
+}
+
+// CreateBlockedImage adds an image to the blocked images. An image can only be blocked once.
+func (r *imageReviewRepository) CreateBlockedImage(ctx context.Context, image *models.BlockedImage) error {
+	if image.ID.IsZero() {
+		image.ID = bson.NewObjectID()
+	}
+	image.CreateNow()
+
+	if err := r.blocked.InsertOne(image); err != nil {
+		if isDuplicateKey(err) {
+			return models.ErrImageAlreadyBlocked
+		}
+		r.logger.Error("Failed to create blocked image", err, "hash", image.Hash)
+		return models.NewInternalError(err, "Failed to create blocked image")
+	}
+	return nil
+}
+
+// FindBlockedImages finds every blocked image, most recently blocked first.
+func (r *imageReviewRepository) FindBlockedImages(ctx context.Context) ([]*models.BlockedImage, error) {
+	images, err := findMany[models.BlockedImage](r.blocked, bson.M{}, pageOptions(bson.D{{Key: "createdAt", Value: -1}}, 0, 0))
+	if err != nil {
+		r.logger.Error("Failed to find blocked images", err)
+		return nil, models.NewInternalError(err, "Failed to find blocked images")
+	}
+	if images == nil {
+		images = []*models.BlockedImage{}
+	}
+	return images, nil
+}
+
+// DeleteBlockedImage removes an image from the blocked images.
+func (r *imageReviewRepository) DeleteBlockedImage(ctx context.Context, id bson.ObjectID) error {
+	deleted, err := r.blocked.DeleteOne(bson.M{"_id": id})
+	if err != nil {
+		return models.NewInternalError(err, "Failed to delete blocked image")
+	}
+	if deleted == 0 {
+		return models.ErrBlockedImageNotFound
+	}
+	return nil
+}
+
+// imageReviewQuery builds the query for an image review filter.
+func imageReviewQuery(filter models.ImageReviewFilter) bson.M {
+	query := bson.M{}
+	if filter.Kind != "" {
+		query["kind"] = filter.Kind
+	}
+	if !filter.OwnerID.IsZero() {
+		query["ownerId"] = filter.OwnerID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	return query
+}
+
+// Ensure imageReviewRepository implements the interface
+var _ repositories.ImageReviewRepository = (*imageReviewRepository)(nil)
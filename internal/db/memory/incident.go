@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// incidentRepository is the in-memory implementation of repositories.IncidentRepository.
+type incidentRepository struct {
+	incidents *Collection
+	logger    *utils.Logger
+}
+
+// NewIncidentRepository creates a new in-memory IncidentRepository.
+func NewIncidentRepository(db *Database, logger *utils.Logger) repositories.IncidentRepository {
+	return &incidentRepository{
+		incidents: db.Collection("incidents"),
+		logger:    logger.Named("memory_incident_repository"),
+	}
+}
+
+// CreateIncident records a new incident.
+func (r *incidentRepository) CreateIncident(ctx context.Context, incident *models.Incident) error {
+	if incident.ID.IsZero() {
+		incident.ID = bson.NewObjectID()
+	}
+	incident.CreateNow()
+
+	if err := r.incidents.InsertOne(incident); err != nil {
+		r.logger.Error("Failed to create incident", err, "title", incident.Title)
+		return models.NewInternalError(err, "Failed to create incident")
+	}
+	return nil
+}
+
+// FindIncidentByID finds an incident by its ID.
+func (r *incidentRepository) FindIncidentByID(ctx context.Context, id bson.ObjectID) (*models.Incident, error) {
+	return r.findOne(bson.M{"_id": id})
+}
+
+// FindActiveAutomaticIncident finds the unresolved incident opened automatically for a component.
+func (r *incidentRepository) FindActiveAutomaticIncident(ctx context.Context, component string) (*models.Incident, error) {
+	return r.findOne(bson.M{
+		"automatic":  true,
+		"components": component,
+		"status":     bson.M{"$ne": models.IncidentResolved},
+	})
+}
+
+// findOne finds the incident matching a query.
+func (r *incidentRepository) findOne(query bson.M) (*models.Incident, error) {
+	incident, err := findOne[models.Incident](r.incidents, query, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, models.ErrIncidentNotFound
+		}
+		return nil, models.NewInternalError(err, "Failed to find incident")
+	}
+	return incident, nil
+}
+
+// FindIncidents finds incidents, latest started first, along with the total number matching.
+func (r *incidentRepository) FindIncidents(ctx context.Context, filter models.IncidentFilter, skip, limit int) ([]*models.Incident, int64, error) {
+	query := incidentQuery(filter)
+
+	total, err := r.incidents.CountDocuments(query)
+	if err != nil {
+		return nil, 0, models.NewInternalError(err, "Failed to count incidents")
+	}
+
+	incidents, err := findMany[models.Incident](r.incidents, query, pageOptions(bson.D{{Key: "startedAt", Value: -1}}, skip, limit))
+	if err != nil {
+		r.logger.Error("Failed to find incidents", err)
+		return nil, 0, models.NewInternalError(err, "Failed to find incidents")
+	}
+	if incidents == nil {
+		incidents = []*models.Incident{}
+	}
+	return incidents, total, nil
+}
+
+// UpdateIncident stores a modified incident.
+func (r *incidentRepository) UpdateIncident(ctx context.Context, incident *models.Incident) error {
+	incident.UpdateNow()
+
+	matched, err := r.incidents.ReplaceOne(bson.M{"_id": incident.ID}, incident)
+	if err != nil {
+		r.logger.Error("Failed to update incident", err, "id", incident.ID.Hex())
+		return models.NewInternalError(err, "Failed to update incident")
+	}
+	if matched == 0 {
+		return models.ErrIncidentNotFound
+	}
+	return nil
+}
+
+// DeleteIncident deletes an incident.
+func (r *incidentRepository) DeleteIncident(ctx context.Context, id bson.ObjectID) error {
+	deleted, err := r.incidents.DeleteOne(bson.M{"_id": id})
+	if err != nil {
+		r.logger.Error("Failed to delete incident", err, "id", id.Hex())
+		return models.NewInternalError(err, "Failed to delete incident")
+	}
+	if deleted == 0 {
+		return models.ErrIncidentNotFound
+	}
+	return nil
+}
+
+// incidentQuery builds the query for an incident filter.
+func incidentQuery(filter models.IncidentFilter) bson.M {
+	query := bson.M{}
+	if filter.Active {
+		query["status"] = bson.M{"$ne": models.IncidentResolved}
+	}
+	if !filter.Since.IsZero() {
+		query["$or"] = bson.A{
+			bson.M{"resolvedAt": bson.M{"$exists": false}},
+			bson.M{"resolvedAt": bson.M{"$gte": filter.Since}},
+		}
+	}
+	return query
+}
+
+// Ensure incidentRepository implements the interface
+var _ repositories.IncidentRepository = (*incidentRepository)(nil)
@@ -0,0 +1,439 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// mediaRepository is the in-memory implementation of repositories.MediaRepository.
+type mediaRepository struct {
+	media       *Collection
+	playHistory *Collection
+	tags        *Collection
+	logger      *utils.Logger
+}
+
+// NewMediaRepository creates a new in-memory MediaRepository.
+func NewMediaRepository(db *Database, logger *utils.Logger) repositories.MediaRepository {
+	media := db.Collection("media")
+	media.EnsureUniqueIndex("type", "sourceId")
+	tags := db.Collection("media_tags")
+	tags.EnsureUniqueIndex("mediaId", "userId")
+
+	return &mediaRepository{
+		media:       media,
+		playHistory: db.Collection("play_history"),
+		tags:        tags,
+		logger:      logger.Named("memory_media_repository"),
+	}
+}
+
+// Create creates a new media item, returning the existing one if it was already stored.
+func (r *mediaRepository) Create(ctx context.Context, media *models.Media) error {
+	if media.ID.IsZero() {
+		media.ID = bson.NewObjectID()
+	}
+
+	now := time.Now()
+	media.TimeCreate(now)
+
+	if media.Stats.LastUpdated.IsZero() {
+		media.Stats.LastUpdated = now
+	}
+
+	if err := r.media.InsertOne(media); err != nil {
+		if isDuplicateKey(err) {
+			existing, findErr := r.FindBySourceID(ctx, media.Type, media.SourceID)
+			if findErr == nil {
+				*media = *existing
+				return nil
+			}
+			return models.ErrMediaAlreadyExists
+		}
+		r.logger.Error("Failed to create media", err, "type", media.Type, "sourceId", media.SourceID)
+		return models.NewInternalError(err, "Failed to create media")
+	}
+
+	return nil
+}
+
+// FindByID finds a media item by its ID.
+func (r *mediaRepository) FindByID(ctx context.Context, id bson.ObjectID) (*models.Media, error) {
+	return r.findOne(bson.M{"_id": id})
+}
+
+// FindBySourceID finds a media item by its source type and ID.
+func (r *mediaRepository) FindBySourceID(ctx context.Context, sourceType, sourceID string) (*models.Media, error) {
+	return r.findOne(bson.M{"type": sourceType, "sourceId": sourceID})
+}
+
+// FindMany finds multiple media items based on query filters.
+func (r *mediaRepository) FindMany(ctx context.Context, filter bson.M, opts options.Lister[options.FindOptions]) ([]*models.Media, error) {
+	mediaItems, err := findMany[models.Media](r.media, filter, opts)
+	if err != nil {
+		r.logger.Error("Failed to find media items", err, "filter", filter)
+		return nil, models.NewInternalError(err, "Failed to find media items")
+	}
+	return mediaItems, nil
+}
+
+// Update updates an existing media item.
+func (r *mediaRepository) Update(ctx context.Context, media *models.Media) error {
+	media.UpdateNow()
+
+	matched, err := r.media.ReplaceOne(bson.M{"_id": media.ID}, media)
+	if err != nil {
+		r.logger.Error("Failed to update media", err, "id", media.ID.Hex())
+		return models.NewInternalError(err, "Failed to update media")
+	}
+	if matched == 0 {
+		return models.ErrMediaNotFound
+	}
+	return nil
+}
+
+// Delete deletes a media item by its ID.
+func (r *mediaRepository) Delete(ctx context.Context, id bson.ObjectID) error {
+	deleted, err := r.media.DeleteOne(bson.M{"_id": id})
+	if err != nil {
+		return models.NewInternalError(err, "Failed to delete media")
+	}
+	if deleted == 0 {
+		return models.ErrMediaNotFound
+	}
+	return nil
+}
+
+// Search searches for media items by text query.
+// Text queries match media containing the query as a substring instead of using a text index.
+func (r *mediaRepository) Search(ctx context.Context, query string, sourceType string, skip, limit int) ([]*models.Media, int64, error) {
+	filter := bson.M{}
+
+	if query != "" {
+		filter["$text"] = bson.M{"$search": query}
+	}
+	if sourceType != "" && sourceType != "all" {
+		filter["type"] = sourceType
+	}
+
+	total, err := r.media.CountDocuments(filter)
+	if err != nil {
+		return nil, 0, models.NewInternalError(err, "Failed to count media items")
+	}
+
+	opts := pageOptions(bson.D{{Key: "stats.playCount", Value: -1}}, skip, limit)
+	mediaItems, err := r.FindMany(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return mediaItems, total, nil
+}
+
+// FindPopular finds the most popular media items.
+func (r *mediaRepository) FindPopular(ctx context.Context, limit int) ([]*models.Media, error) {
+	return r.FindMany(ctx, bson.M{}, pageOptions(bson.D{{Key: "stats.playCount", Value: -1}}, 0, limit))
+}
+
+// FindRecent finds recently added media items.
+func (r *mediaRepository) FindRecent(ctx context.Context, limit int) ([]*models.Media, error) {
+	return r.FindMany(ctx, bson.M{}, pageOptions(bson.D{{Key: "createdAt", Value: -1}}, 0, limit))
+}
+
+// FindByArtist finds media items by artist name, ignoring case.
+func (r *mediaRepository) FindByArtist(ctx context.Context, artist string, skip, limit int) ([]*models.Media, error) {
+	opts := pageOptions(bson.D{{Key: "stats.playCount", Value: -1}}, skip, limit)
+	return r.FindMany(ctx, bson.M{"artist": caseInsensitive(artist)}, opts)
+}
+
+// UpdateStats updates the statistics of a media item.
+func (r *mediaRepository) UpdateStats(ctx context.Context, id bson.ObjectID, updates bson.M) error {
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"stats.lastUpdated": now, "updatedAt": now}}
+
+	if len(updates) > 0 {
+		incs := bson.M{}
+		for field, value := range updates {
+			incs["stats."+field] = value
+		}
+		update["$inc"] = incs
+	}
+
+	matched, err := r.media.UpdateByID(id, update)
+	if err != nil {
+		r.logger.Error("Failed to update media stats", err, "id", id.Hex())
+		return models.NewInternalError(err, "Failed to update media stats")
+	}
+	if matched == 0 {
+		return models.ErrMediaNotFound
+	}
+	return nil
+}
+
+// RecordPlay records a play history event.
+func (r *mediaRepository) RecordPlay(ctx context.Context, playHistory *models.PlayHistory) error {
+	if playHistory.ID.IsZero() {
+		playHistory.ID = bson.NewObjectID()
+	}
+
+	if err := r.playHistory.InsertOne(playHistory); err != nil {
+		r.logger.Error("Failed to record play history", err, "mediaId", playHistory.MediaID.Hex())
+		return models.NewInternalError(err, "Failed to record play history")
+	}
+
+	err := r.UpdateStats(ctx, playHistory.MediaID, bson.M{
+		"playCount": 1,
+		"wootCount": playHistory.Votes.Woots,
+		"mehCount":  playHistory.Votes.Mehs,
+		"grabCount": playHistory.Votes.Grabs,
+	})
+	if err != nil {
+		r.logger.Error("Failed to update media stats after play", err, "mediaId", playHistory.MediaID.Hex())
+		// Continue anyway, the play history was recorded
+	}
+
+	_, err = r.media.UpdateByID(playHistory.MediaID, bson.M{"$set": bson.M{
+		"stats.lastPlayed": playHistory.EndTime,
+		"updatedAt":        time.Now(),
+	}})
+	if err != nil {
+		r.logger.Error("Failed to update LastPlayed timestamp", err, "mediaId", playHistory.MediaID.Hex())
+		// Continue anyway, the play history was recorded
+	}
+
+	return nil
+}
+
+// FindPlayHistory finds play history records based on filters.
+func (r *mediaRepository) FindPlayHistory(ctx context.Context, filter bson.M, opts options.Lister[options.FindOptions]) ([]*models.PlayHistory, error) {
+	playHistory, err := findMany[models.PlayHistory](r.playHistory, filter, opts)
+	if err != nil {
+		r.logger.Error("Failed to find play history", err, "filter", filter)
+		return nil, models.NewInternalError(err, "Failed to find play history")
+	}
+	return playHistory, nil
+}
+
+// SetPlaysSong sets the song every play of a media item counts towards. It returns the number of plays updated.
+func (r *mediaRepository) SetPlaysSong(ctx context.Context, mediaID, songID bson.ObjectID) (int64, error) {
+	updated, err := r.playHistory.UpdateMany(bson.M{
+		"mediaId": mediaID,
+		"songId":  bson.M{"$ne": songID},
+	}, bson.M{"$set": bson.M{"songId": songID}})
+	if err != nil {
+		r.logger.Error("Failed to set the song of plays", err, "mediaId", mediaID.Hex())
+		return 0, models.NewInternalError(err, "Failed to set the song of plays")
+	}
+	return updated, nil
+}
+
+// RecordVote records a vote for a media item on its latest play in a room.
+func (r *mediaRepository) RecordVote(ctx context.Context, mediaID, userID bson.ObjectID, roomID bson.ObjectID, voteType string) error {
+	playHistory, err := r.latestPlay(mediaID, roomID)
+	if err != nil {
+		return err
+	}
+
+	userIDStr := userID.Hex()
+	if playHistory.Votes.Voters == nil {
+		playHistory.Votes.Voters = make(map[string]string)
+	}
+
+	previousVote, hasVoted := playHistory.Votes.Voters[userIDStr]
+	if hasVoted {
+		adjustVote(&playHistory.Votes, previousVote, -1)
+	}
+
+	playHistory.Votes.Voters[userIDStr] = voteType
+	adjustVote(&playHistory.Votes, voteType, 1)
+
+	if _, err := r.playHistory.UpdateByID(playHistory.ID, bson.M{"$set": bson.M{"votes": playHistory.Votes}}); err != nil {
+		r.logger.Error("Failed to update play history vote", err, "historyId", playHistory.ID.Hex())
+		return models.NewInternalError(err, "Failed to record vote")
+	}
+
+	// Only new votes and vote changes affect media stats
+	updates := bson.M{}
+	if !hasVoted || previousVote != voteType {
+		if field := voteStatField(voteType); field != "" {
+			updates[field] = 1
+		}
+		if field := voteStatField(previousVote); hasVoted && field != "" {
+			updates[field] = -1
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := r.UpdateStats(ctx, mediaID, updates); err != nil {
+			r.logger.Error("Failed to update media stats after vote", err, "mediaId", mediaID.Hex())
+			// Continue anyway, the vote was recorded
+		}
+	}
+
+	return nil
+}
+
+// GetMediaVotes gets the current votes for a media item in a room.
+func (r *mediaRepository) GetMediaVotes(ctx context.Context, mediaID, roomID bson.ObjectID) (*models.MediaVotes, error) {
+	playHistory, err := r.latestPlay(mediaID, roomID)
+	if err != nil {
+		return nil, err
+	}
+	return &playHistory.Votes, nil
+}
+
+// SaveTag creates or replaces a user's tag of a media item.
+func (r *mediaRepository) SaveTag(ctx context.Context, tag *models.MediaTag) error {
+	filter := bson.M{"mediaId": tag.MediaID, "userId": tag.UserID}
+	existing, err := findOne[models.MediaTag](r.tags, filter, nil)
+	if err != nil && !isNotFound(err) {
+		return models.NewInternalError(err, "Failed to save media tag")
+	}
+
+	now := time.Now()
+	if existing == nil {
+		tag.ID = bson.NewObjectID()
+		tag.TimeCreate(now)
+		if err := r.tags.InsertOne(tag); err != nil {
+			r.logger.Error("Failed to save media tag", err, "mediaId", tag.MediaID.Hex(), "userId", tag.UserID.Hex())
+			return models.NewInternalError(err, "Failed to save media tag")
+		}
+		return nil
+	}
+
+	tag.ID = existing.ID
+	tag.CreatedAt = existing.CreatedAt
+	tag.TimeUpdate(now)
+	if _, err := r.tags.ReplaceOne(bson.M{"_id": existing.ID}, tag); err != nil {
+		r.logger.Error("Failed to save media tag", err, "mediaId", tag.MediaID.Hex(), "userId", tag.UserID.Hex())
+		return models.NewInternalError(err, "Failed to save media tag")
+	}
+	return nil
+}
+
+// FindTags finds the tags of a media item.
+func (r *mediaRepository) FindTags(ctx context.Context, mediaID bson.ObjectID) ([]*models.MediaTag, error) {
+	tags, err := findMany[models.MediaTag](r.tags, bson.M{"mediaId": mediaID}, nil)
+	if err != nil {
+		r.logger.Error("Failed to find media tags", err, "mediaId", mediaID.Hex())
+		return nil, models.NewInternalError(err, "Failed to find media tags")
+	}
+	return tags, nil
+}
+
+// SetVibe sets the energy and mood profile of a media item.
+func (r *mediaRepository) SetVibe(ctx context.Context, mediaID bson.ObjectID, vibe *models.MediaVibe) error {
+	matched, err := r.media.UpdateByID(mediaID, bson.M{"$set": bson.M{"vibe": vibe, "updatedAt": time.Now()}})
+	if err != nil {
+		r.logger.Error("Failed to set media vibe", err, "mediaId", mediaID.Hex())
+		return models.NewInternalError(err, "Failed to set media vibe")
+	}
+	if matched == 0 {
+		return models.ErrMediaNotFound
+	}
+	return nil
+}
+
+// SetVerifiedBadge sets a media item's verified badge, or takes it away when badge is nil.
+func (r *mediaRepository) SetVerifiedBadge(ctx context.Context, mediaID bson.ObjectID, badge *models.VerifiedBadge) error {
+	matched, err := r.media.UpdateByID(mediaID, bson.M{"$set": bson.M{"verified": badge, "updatedAt": time.Now()}})
+	if err != nil {
+		r.logger.Error("Failed to set media verified badge", err, "mediaId", mediaID.Hex())
+		return models.NewInternalError(err, "Failed to set media verified badge")
+	}
+	if matched == 0 {
+		return models.ErrMediaNotFound
+	}
+	return nil
+}
+
+// SetTakedown marks a media item as taken down by a takedown.
+func (r *mediaRepository) SetTakedown(ctx context.Context, mediaID, takedownID bson.ObjectID) error {
+	matched, err := r.media.UpdateByID(mediaID, bson.M{"$set": bson.M{"takedownId": takedownID, "updatedAt": time.Now()}})
+	if err != nil {
+		r.logger.Error("Failed to set media takedown", err, "mediaId", mediaID.Hex())
+		return models.NewInternalError(err, "Failed to set media takedown")
+	}
+	if matched == 0 {
+		return models.ErrMediaNotFound
+	}
+	return nil
+}
+
+// SetLyricsChecked records whether the lyrics provider has lyrics for a media item.
+func (r *mediaRepository) SetLyricsChecked(ctx context.Context, mediaID bson.ObjectID, hasLyrics bool) error {
+	now := time.Now()
+	matched, err := r.media.UpdateByID(mediaID, bson.M{"$set": bson.M{
+		"metadata.hasLyrics": hasLyrics, "metadata.lyricsCheckedAt": now, "updatedAt": now,
+	}})
+	if err != nil {
+		r.logger.Error("Failed to set media lyrics status", err, "mediaId", mediaID.Hex())
+		return models.NewInternalError(err, "Failed to set media lyrics status")
+	}
+	if matched == 0 {
+		return models.ErrMediaNotFound
+	}
+	return nil
+}
+
+// findOne finds a single media item matching the filter.
+func (r *mediaRepository) findOne(filter bson.M) (*models.Media, error) {
+	media, err := findOne[models.Media](r.media, filter, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, models.ErrMediaNotFound
+		}
+		r.logger.Error("Failed to find media", err, "filter", filter)
+		return nil, models.NewInternalError(err, "Failed to find media")
+	}
+	return media, nil
+}
+
+// latestPlay finds the most recent play of a media item in a room.
+func (r *mediaRepository) latestPlay(mediaID, roomID bson.ObjectID) (*models.PlayHistory, error) {
+	filter := bson.M{"mediaId": mediaID, "roomId": roomID}
+
+	playHistory, err := findOne[models.PlayHistory](r.playHistory, filter, bson.D{{Key: "startTime", Value: -1}})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, models.NewMediaError(errors.New("no active play for this media"), "No active play found for this media", 404)
+		}
+		return nil, models.NewInternalError(err, "Failed to find play history")
+	}
+	return playHistory, nil
+}
+
+// adjustVote adds delta to the counter matching a vote type.
+func adjustVote(votes *models.MediaVotes, voteType string, delta int) {
+	switch voteType {
+	case "woot":
+		votes.Woots += delta
+	case "meh":
+		votes.Mehs += delta
+	case "grab":
+		votes.Grabs += delta
+	}
+}
+
+// voteStatField returns the media stats field counting a vote type.
+func voteStatField(voteType string) string {
+	switch voteType {
+	case "woot":
+		return "wootCount"
+	case "meh":
+		return "mehCount"
+	case "grab":
+		return "grabCount"
+	}
+	return ""
+}
+
+// Ensure mediaRepository implements the interface
+var _ repositories.MediaRepository = (*mediaRepository)(nil)
@@ -0,0 +1,622 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	lom "github.com/samber/lo/mutable"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// playlistRepository is the in-memory implementation of repositories.PlaylistRepository.
+type playlistRepository struct {
+	playlists *Collection
+	changes   *Collection
+	logger    *utils.Logger
+}
+
+// NewPlaylistRepository creates a new in-memory PlaylistRepository.
+func NewPlaylistRepository(db *Database, logger *utils.Logger) repositories.PlaylistRepository {
+	return &playlistRepository{
+		playlists: db.Collection("playlists"),
+		changes:   db.Collection("playlist_changes"),
+		logger:    logger.Named("memory_playlist_repository"),
+	}
+}
+
+// Create creates a new playlist.
+func (r *playlistRepository) Create(ctx context.Context, playlist *models.Playlist) error {
+	if playlist.ID.IsZero() {
+		playlist.ID = bson.NewObjectID()
+	}
+
+	now := time.Now()
+	playlist.TimeCreate(now)
+
+	if playlist.Items == nil {
+		playlist.Items = []models.PlaylistItem{}
+	}
+	if playlist.Stats.LastCalculated.IsZero() {
+		playlist.Stats.LastCalculated = now
+	}
+
+	if err := r.playlists.InsertOne(playlist); err != nil {
+		r.logger.Error("Failed to create playlist", err, "userId", playlist.Owner.Hex(), "name", playlist.Name)
+		return models.NewInternalError(err, "Failed to create playlist")
+	}
+
+	if playlist.IsActive {
+		r.deactivateOtherPlaylists(playlist.Owner, playlist.ID)
+	}
+	return nil
+}
+
+// FindByID finds a playlist by its ID.
+func (r *playlistRepository) FindByID(ctx context.Context, id bson.ObjectID) (*models.Playlist, error) {
+	return r.findOne(bson.M{"_id": id}, nil)
+}
+
+// FindMany finds multiple playlists based on query filters.
+func (r *playlistRepository) FindMany(ctx context.Context, filter bson.M, opts options.Lister[options.FindOptions]) ([]*models.Playlist, error) {
+	playlists, err := findMany[models.Playlist](r.playlists, filter, opts)
+	if err != nil {
+		r.logger.Error("Failed to find playlists", err, "filter", filter)
+		return nil, models.NewInternalError(err, "Failed to find playlists")
+	}
+	return playlists, nil
+}
+
+// Update updates an existing playlist. It fails with models.ErrPlaylistChanged if the playlist's items
+// were changed since it was read.
+func (r *playlistRepository) Update(ctx context.Context, playlist *models.Playlist) error {
+	playlist.UpdateNow()
+
+	if err := r.replace(playlist, nil, "Failed to update playlist"); err != nil {
+		return err
+	}
+
+	if playlist.IsActive {
+		r.deactivateOtherPlaylists(playlist.Owner, playlist.ID)
+	}
+	return nil
+}
+
+// Delete deletes a playlist by its ID.
+func (r *playlistRepository) Delete(ctx context.Context, id bson.ObjectID) error {
+	playlist, err := r.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := r.playlists.DeleteOne(bson.M{"_id": id})
+	if err != nil {
+		return models.NewInternalError(err, "Failed to delete playlist")
+	}
+	if deleted == 0 {
+		return models.ErrPlaylistNotFound
+	}
+
+	if _, err := r.changes.DeleteMany(bson.M{"playlistId": id}); err != nil {
+		r.logger.Error("Failed to delete playlist changes", err, "id", id.Hex())
+	}
+
+	// If this was the active playlist, set the most recently updated one as active
+	if playlist.IsActive {
+		next, err := r.findOne(bson.M{"owner": playlist.Owner}, bson.D{{Key: "updatedAt", Value: -1}})
+		if err == nil {
+			r.setActive(next.ID)
+		}
+	}
+	return nil
+}
+
+// FindUserPlaylists finds all playlists for a specific user.
+func (r *playlistRepository) FindUserPlaylists(ctx context.Context, userID bson.ObjectID) ([]*models.Playlist, error) {
+	opts := pageOptions(bson.D{{Key: "isActive", Value: -1}, {Key: "updatedAt", Value: -1}}, 0, 0)
+	return r.FindMany(ctx, bson.M{"owner": userID}, opts)
+}
+
+// GetActivePlaylist gets the user's active playlist, activating the most recent one if none is active.
+func (r *playlistRepository) GetActivePlaylist(ctx context.Context, userID bson.ObjectID) (*models.Playlist, error) {
+	playlist, err := r.findOne(bson.M{"owner": userID, "isActive": true}, nil)
+	if err == nil {
+		return playlist, nil
+	}
+	if err != models.ErrPlaylistNotFound {
+		return nil, err
+	}
+
+	playlist, err = r.findOne(bson.M{"owner": userID}, bson.D{{Key: "updatedAt", Value: -1}})
+	if err != nil {
+		return nil, err
+	}
+
+	playlist.IsActive = true
+	playlist.UpdateNow()
+	r.setActive(playlist.ID)
+
+	return playlist, nil
+}
+
+// SetActivePlaylist sets a playlist as the user's active playlist.
+func (r *playlistRepository) SetActivePlaylist(ctx context.Context, userID, playlistID bson.ObjectID) error {
+	if _, err := r.findOne(bson.M{"_id": playlistID, "owner": userID}, nil); err != nil {
+		return err
+	}
+
+	r.deactivateOtherPlaylists(userID, playlistID)
+
+	if _, err := r.playlists.UpdateByID(playlistID, bson.M{"$set": bson.M{"isActive": true, "updatedAt": time.Now()}}); err != nil {
+		return models.NewInternalError(err, "Failed to set active playlist")
+	}
+	return nil
+}
+
+// CountUserPlaylists counts the number of playlists owned by a user.
+func (r *playlistRepository) CountUserPlaylists(ctx context.Context, userID bson.ObjectID) (int64, error) {
+	count, err := r.playlists.CountDocuments(bson.M{"owner": userID})
+	if err != nil {
+		return 0, models.NewInternalError(err, "Failed to count playlists")
+	}
+	return count, nil
+}
+
+// AddItem adds a media item to a playlist.
+func (r *playlistRepository) AddItem(ctx context.Context, playlistID, mediaID bson.ObjectID, position int) error {
+	playlist, err := r.FindByID(ctx, playlistID)
+	if err != nil {
+		return err
+	}
+
+	itemCount := len(playlist.Items)
+	if position < 0 || position > itemCount {
+		position = itemCount // Append to end if position is invalid
+	}
+
+	newItem := models.PlaylistItem{
+		ID:      bson.NewObjectID(),
+		MediaID: mediaID,
+		AddedAt: time.Now(),
+	}
+
+	playlist.Items = slices.Insert(playlist.Items, position, newItem)
+	renumberItems(playlist)
+
+	playlist.Stats.TotalItems = len(playlist.Items)
+	playlist.UpdateNow()
+
+	op := &models.PlaylistOp{Type: models.PlaylistOpAdd, Items: []models.PlaylistItem{newItem}, Position: position}
+	return r.replace(playlist, op, "Failed to add item to playlist")
+}
+
+// RemoveItem removes an item from a playlist.
+func (r *playlistRepository) RemoveItem(ctx context.Context, playlistID, itemID bson.ObjectID) error {
+	playlist, err := r.FindByID(ctx, playlistID)
+	if err != nil {
+		return err
+	}
+
+	itemIndex := findItem(playlist, itemID)
+	if itemIndex == -1 {
+		return models.ErrPlaylistItemNotFound
+	}
+
+	playlist.Items = slices.Delete(playlist.Items, itemIndex, itemIndex+1)
+	renumberItems(playlist)
+
+	playlist.Stats.TotalItems = len(playlist.Items)
+	playlist.UpdateNow()
+
+	op := &models.PlaylistOp{Type: models.PlaylistOpRemove, ItemIDs: []bson.ObjectID{itemID}}
+	return r.replace(playlist, op, "Failed to remove item from playlist")
+}
+
+// AddItems appends media items to the end of a playlist.
+func (r *playlistRepository) AddItems(ctx context.Context, playlistID bson.ObjectID, mediaIDs []bson.ObjectID) error {
+	if len(mediaIDs) == 0 {
+		return nil
+	}
+
+	playlist, err := r.FindByID(ctx, playlistID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	items := make([]models.PlaylistItem, 0, len(mediaIDs))
+	for _, mediaID := range mediaIDs {
+		items = append(items, models.PlaylistItem{
+			ID:      bson.NewObjectID(),
+			MediaID: mediaID,
+			AddedAt: now,
+		})
+	}
+	playlist.Items = append(playlist.Items, items...)
+	renumberItems(playlist)
+
+	playlist.Stats.TotalItems = len(playlist.Items)
+	playlist.UpdateNow()
+
+	op := &models.PlaylistOp{Type: models.PlaylistOpAdd, Items: items, Position: -1}
+	return r.replace(playlist, op, "Failed to add items to playlist")
+}
+
+// RemoveItems removes items from a playlist. Nothing is removed unless the playlist holds every item.
+func (r *playlistRepository) RemoveItems(ctx context.Context, playlistID bson.ObjectID, itemIDs []bson.ObjectID) error {
+	if len(itemIDs) == 0 {
+		return nil
+	}
+
+	playlist, err := r.FindByID(ctx, playlistID)
+	if err != nil {
+		return err
+	}
+
+	for _, itemID := range itemIDs {
+		if findItem(playlist, itemID) == -1 {
+			return models.ErrPlaylistItemNotFound
+		}
+	}
+
+	playlist.Items = slices.DeleteFunc(playlist.Items, func(item models.PlaylistItem) bool {
+		return slices.Contains(itemIDs, item.ID)
+	})
+	renumberItems(playlist)
+
+	playlist.Stats.TotalItems = len(playlist.Items)
+	playlist.UpdateNow()
+
+	op := &models.PlaylistOp{Type: models.PlaylistOpRemove, ItemIDs: itemIDs}
+	return r.replace(playlist, op, "Failed to remove items from playlist")
+}
+
+// ReorderItems puts the items of a playlist in the given order. The order must list every item of the
+// playlist exactly once.
+func (r *playlistRepository) ReorderItems(ctx context.Context, playlistID bson.ObjectID, itemIDs []bson.ObjectID) error {
+	playlist, err := r.FindByID(ctx, playlistID)
+	if err != nil {
+		return err
+	}
+
+	if len(itemIDs) != len(playlist.Items) {
+		return models.ErrInvalidPlaylistOrder
+	}
+
+	items := make([]models.PlaylistItem, 0, len(itemIDs))
+	seen := make(map[bson.ObjectID]struct{}, len(itemIDs))
+	for _, itemID := range itemIDs {
+		itemIndex := findItem(playlist, itemID)
+		if _, ok := seen[itemID]; ok || itemIndex == -1 {
+			return models.ErrInvalidPlaylistOrder
+		}
+		seen[itemID] = struct{}{}
+		items = append(items, playlist.Items[itemIndex])
+	}
+	playlist.Items = items
+	renumberItems(playlist)
+
+	playlist.UpdateNow()
+
+	op := &models.PlaylistOp{Type: models.PlaylistOpReorder, ItemIDs: itemIDs}
+	return r.replace(playlist, op, "Failed to reorder playlist")
+}
+
+// MoveItem moves an item to a new position in a playlist.
+func (r *playlistRepository) MoveItem(ctx context.Context, playlistID, itemID bson.ObjectID, newPosition int) error {
+	playlist, err := r.FindByID(ctx, playlistID)
+	if err != nil {
+		return err
+	}
+
+	itemIndex := findItem(playlist, itemID)
+	if itemIndex == -1 {
+		return models.ErrPlaylistItemNotFound
+	}
+
+	itemCount := len(playlist.Items)
+	if newPosition < 0 || newPosition >= itemCount {
+		newPosition = itemCount - 1 // Move to end if position is invalid
+	}
+	if itemIndex == newPosition {
+		return nil
+	}
+
+	item := playlist.Items[itemIndex]
+	playlist.Items = slices.Delete(playlist.Items, itemIndex, itemIndex+1)
+	playlist.Items = slices.Insert(playlist.Items, newPosition, item)
+	renumberItems(playlist)
+
+	playlist.UpdateNow()
+
+	op := &models.PlaylistOp{Type: models.PlaylistOpMove, ItemID: itemID, Position: newPosition}
+	return r.replace(playlist, op, "Failed to move item")
+}
+
+// RelinkItem points a playlist item at another media item, keeping everything else about the item.
+func (r *playlistRepository) RelinkItem(ctx context.Context, playlistID, itemID, mediaID bson.ObjectID) error {
+	playlist, err := r.FindByID(ctx, playlistID)
+	if err != nil {
+		return err
+	}
+
+	itemIndex := findItem(playlist, itemID)
+	if itemIndex == -1 {
+		return models.ErrPlaylistItemNotFound
+	}
+
+	item := &playlist.Items[itemIndex]
+	if item.MediaID == mediaID {
+		return nil
+	}
+	item.RelinkedFrom = item.MediaID
+	item.MediaID = mediaID
+
+	playlist.UpdateNow()
+
+	op := &models.PlaylistOp{Type: models.PlaylistOpUpdate, Items: []models.PlaylistItem{*item}}
+	return r.replace(playlist, op, "Failed to relink item")
+}
+
+// FlagTakenDownItems flags the items of a playlist holding media that was taken down, and returns the
+// IDs of the items flagged.
+func (r *playlistRepository) FlagTakenDownItems(ctx context.Context, playlistID, mediaID, takedownID bson.ObjectID) ([]bson.ObjectID, error) {
+	playlist, err := r.FindByID(ctx, playlistID)
+	if err != nil {
+		return nil, err
+	}
+
+	var itemIDs []bson.ObjectID
+	var flagged []models.PlaylistItem
+	for i := range playlist.Items {
+		item := &playlist.Items[i]
+		if item.MediaID == mediaID && item.TakedownID.IsZero() {
+			item.TakedownID = takedownID
+			itemIDs = append(itemIDs, item.ID)
+			flagged = append(flagged, *item)
+		}
+	}
+	if len(itemIDs) == 0 {
+		return nil, nil
+	}
+
+	playlist.UpdateNow()
+
+	op := &models.PlaylistOp{Type: models.PlaylistOpUpdate, Items: flagged}
+	if err := r.replace(playlist, op, "Failed to flag taken down items"); err != nil {
+		return nil, err
+	}
+	return itemIDs, nil
+}
+
+// ShufflePlaylist randomizes the order of items in a playlist.
+func (r *playlistRepository) ShufflePlaylist(ctx context.Context, playlistID bson.ObjectID) error {
+	playlist, err := r.FindByID(ctx, playlistID)
+	if err != nil {
+		return err
+	}
+
+	lom.Shuffle(playlist.Items)
+	renumberItems(playlist)
+
+	playlist.UpdateNow()
+
+	itemIDs := make([]bson.ObjectID, 0, len(playlist.Items))
+	for _, item := range playlist.Items {
+		itemIDs = append(itemIDs, item.ID)
+	}
+
+	op := &models.PlaylistOp{Type: models.PlaylistOpReorder, ItemIDs: itemIDs}
+	return r.replace(playlist, op, "Failed to shuffle playlist")
+}
+
+// SearchPlaylists searches for playlists based on criteria.
+// Text queries match playlists containing the query as a substring instead of using a text index.
+func (r *playlistRepository) SearchPlaylists(ctx context.Context, criteria models.PlaylistSearchCriteria) ([]*models.Playlist, int64, error) {
+	filter := bson.M{}
+
+	if !criteria.IncludePrivate {
+		filter["isPrivate"] = false
+	}
+	if !criteria.OwnerID.IsZero() {
+		filter["owner"] = criteria.OwnerID
+	}
+	if len(criteria.Tags) > 0 {
+		filter["tags"] = bson.M{"$all": criteria.Tags}
+	}
+	if criteria.Query != "" {
+		filter["$text"] = bson.M{"$search": criteria.Query}
+	}
+
+	total, err := r.playlists.CountDocuments(filter)
+	if err != nil {
+		return nil, 0, models.NewInternalError(err, "Failed to count playlists")
+	}
+
+	if criteria.Page < 1 {
+		criteria.Page = 1
+	}
+	if criteria.Limit < 1 || criteria.Limit > 100 {
+		criteria.Limit = 20
+	}
+
+	var sortField string
+	direction := -1
+	switch criteria.SortBy {
+	case "name":
+		sortField, direction = "name", 1
+	case "created":
+		sortField = "createdAt"
+	case "items":
+		sortField = "stats.totalItems"
+	case "plays":
+		sortField = "stats.totalPlays"
+	case "followers":
+		sortField = "stats.followers"
+	default:
+		sortField = "updatedAt"
+	}
+
+	if criteria.SortDirection == "asc" && criteria.SortBy != "" {
+		direction = -direction
+	}
+
+	opts := pageOptions(bson.D{{Key: sortField, Value: direction}}, (criteria.Page-1)*criteria.Limit, criteria.Limit)
+	playlists, err := r.FindMany(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return playlists, total, nil
+}
+
+// FindPublicPlaylists finds public playlists.
+func (r *playlistRepository) FindPublicPlaylists(ctx context.Context, skip, limit int) ([]*models.Playlist, error) {
+	opts := pageOptions(bson.D{{Key: "stats.followers", Value: -1}, {Key: "updatedAt", Value: -1}}, skip, limit)
+	return r.FindMany(ctx, bson.M{"isPrivate": false}, opts)
+}
+
+// RecordPlaylistPlay records a play from a playlist.
+func (r *playlistRepository) RecordPlaylistPlay(ctx context.Context, playlistID, mediaID bson.ObjectID) error {
+	playlist, err := r.FindByID(ctx, playlistID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	playlist.Stats.TotalPlays++
+	playlist.LastPlayed = now
+	playlist.UpdatedAt = now
+
+	for i, item := range playlist.Items {
+		if item.MediaID == mediaID {
+			playlist.Items[i].PlayCount++
+			playlist.Items[i].LastPlayed = now
+			break
+		}
+	}
+
+	return r.replace(playlist, nil, "Failed to record playlist play")
+}
+
+// UpdatePlaylistStats recalculates and updates a playlist's statistics.
+func (r *playlistRepository) UpdatePlaylistStats(ctx context.Context, playlistID bson.ObjectID) error {
+	playlist, err := r.FindByID(ctx, playlistID)
+	if err != nil {
+		return err
+	}
+
+	playlist.Stats.TotalItems = len(playlist.Items)
+	playlist.Stats.LastCalculated = time.Now()
+
+	if len(playlist.Items) > 0 {
+		totalPlays := 0
+		for _, item := range playlist.Items {
+			totalPlays += item.PlayCount
+		}
+		playlist.Stats.TotalPlays = totalPlays
+	}
+
+	return r.replace(playlist, nil, "Failed to update playlist stats")
+}
+
+// FindChanges finds the changes recorded in a playlist's change log after a revision, up to another one,
+// oldest first.
+func (r *playlistRepository) FindChanges(ctx context.Context, playlistID bson.ObjectID, after, upTo int64) ([]*models.PlaylistChange, error) {
+	filter := bson.M{"playlistId": playlistID, "revision": bson.M{"$gt": after, "$lte": upTo}}
+	changes, err := findMany[models.PlaylistChange](r.changes, filter, pageOptions(bson.D{{Key: "revision", Value: 1}}, 0, 0))
+	if err != nil {
+		r.logger.Error("Failed to find playlist changes", err, "playlistId", playlistID.Hex(), "after", after)
+		return nil, models.NewInternalError(err, "Failed to find playlist changes")
+	}
+	return changes, nil
+}
+
+// findOne finds a single playlist matching the filter.
+func (r *playlistRepository) findOne(filter bson.M, sortSpec any) (*models.Playlist, error) {
+	playlist, err := findOne[models.Playlist](r.playlists, filter, sortSpec)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, models.ErrPlaylistNotFound
+		}
+		r.logger.Error("Failed to find playlist", err, "filter", filter)
+		return nil, models.NewInternalError(err, "Failed to find playlist")
+	}
+	return playlist, nil
+}
+
+// replace stores a modified playlist, unless its items were changed since it was read. Passing an op
+// moves the playlist to its next revision and records the op as the change made.
+func (r *playlistRepository) replace(playlist *models.Playlist, op *models.PlaylistOp, message string) error {
+	filter := bson.M{"_id": playlist.ID, "revision": playlist.Revision}
+	if op != nil {
+		playlist.Revision++
+	}
+
+	matched, err := r.playlists.ReplaceOne(filter, playlist)
+	if err != nil {
+		r.logger.Error(message, err, "playlistId", playlist.ID.Hex())
+		return models.NewInternalError(err, message)
+	}
+	if matched == 0 {
+		if _, err := r.findOne(bson.M{"_id": playlist.ID}, nil); err != nil {
+			return err
+		}
+		return models.ErrPlaylistChanged
+	}
+
+	if op != nil {
+		change := &models.PlaylistChange{
+			ID:         bson.NewObjectID(),
+			PlaylistID: playlist.ID,
+			Revision:   playlist.Revision,
+			PlaylistOp: *op,
+			CreatedAt:  time.Now(),
+		}
+		if err := r.changes.InsertOne(change); err != nil {
+			r.logger.Error("Failed to record playlist change", err, "playlistId", playlist.ID.Hex(), "revision", playlist.Revision)
+		}
+	}
+	return nil
+}
+
+// setActive marks a playlist as active without touching the user's other playlists.
+func (r *playlistRepository) setActive(playlistID bson.ObjectID) {
+	if _, err := r.playlists.UpdateByID(playlistID, bson.M{"$set": bson.M{"isActive": true, "updatedAt": time.Now()}}); err != nil {
+		r.logger.Error("Failed to set active playlist", err, "id", playlistID.Hex())
+	}
+}
+
+// deactivateOtherPlaylists deactivates all other playlists for a user.
+func (r *playlistRepository) deactivateOtherPlaylists(userID, activePlaylistID bson.ObjectID) {
+	filter := bson.M{
+		"owner":    userID,
+		"_id":      bson.M{"$ne": activePlaylistID},
+		"isActive": true,
+	}
+
+	if _, err := r.playlists.UpdateMany(filter, bson.M{"$set": bson.M{"isActive": false, "updatedAt": time.Now()}}); err != nil {
+		r.logger.Error("Failed to deactivate other playlists", err, "userId", userID.Hex())
+	}
+}
+
+// findItem returns the index of an item in a playlist, or -1 if it is not present.
+func findItem(playlist *models.Playlist, itemID bson.ObjectID) int {
+	return slices.IndexFunc(playlist.Items, func(item models.PlaylistItem) bool {
+		return item.ID == itemID
+	})
+}
+
+// renumberItems sets the order of each playlist item to its index.
+func renumberItems(playlist *models.Playlist) {
+	for i := range playlist.Items {
+		playlist.Items[i].Order = i
+	}
+}
+
+// Ensure playlistRepository implements the interface
+var _ repositories.PlaylistRepository = (*playlistRepository)(nil)
@@ -0,0 +1,647 @@
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// matchDocument checks if a document matches a normalized filter.
+func matchDocument(d bson.M, filter bson.M) bool {
+	for key, cond := range filter {
+		switch key {
+		case "$and":
+			for _, sub := range asArray(cond) {
+				if f, ok := sub.(bson.M); !ok || !matchDocument(d, f) {
+					return false
+				}
+			}
+		case "$or":
+			matched := false
+			for _, sub := range asArray(cond) {
+				if f, ok := sub.(bson.M); ok && matchDocument(d, f) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return false
+			}
+		case "$nor":
+			for _, sub := range asArray(cond) {
+				if f, ok := sub.(bson.M); ok && matchDocument(d, f) {
+					return false
+				}
+			}
+		case "$text":
+			search, _ := cond.(bson.M)["$search"].(string)
+			if !matchText(d, search) {
+				return false
+			}
+		default:
+			values, exists := lookupValues(d, key)
+			if !matchCondition(values, exists, cond) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchCondition checks if the values found at a path satisfy a filter condition.
+func matchCondition(values []any, exists bool, cond any) bool {
+	ops, ok := cond.(bson.M)
+	if !ok || !isOperatorDocument(ops) {
+		return matchEquals(values, cond)
+	}
+
+	for op, arg := range ops {
+		switch op {
+		case "$eq":
+			if !matchEquals(values, arg) {
+				return false
+			}
+		case "$ne":
+			if matchEquals(values, arg) {
+				return false
+			}
+		case "$gt", "$gte", "$lt", "$lte":
+			if !matchCompare(values, op, arg) {
+				return false
+			}
+		case "$in":
+			found := false
+			for _, candidate := range asArray(arg) {
+				if matchEquals(values, candidate) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		case "$nin":
+			for _, candidate := range asArray(arg) {
+				if matchEquals(values, candidate) {
+					return false
+				}
+			}
+		case "$all":
+			for _, candidate := range asArray(arg) {
+				if !matchEquals(values, candidate) {
+					return false
+				}
+			}
+		case "$exists":
+			want, _ := arg.(bool)
+			if exists != want {
+				return false
+			}
+		case "$size":
+			size, _ := toNumber(arg)
+			if len(values) != 1 || len(asArray(values[0])) != int(size) {
+				return false
+			}
+		case "$regex":
+			if !matchRegex(values, arg, ops["$options"]) {
+				return false
+			}
+		case "$options":
+			// Handled together with $regex
+		case "$elemMatch":
+			if !matchElem(values, arg) {
+				return false
+			}
+		case "$not":
+			if matchCondition(values, exists, arg) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchEquals checks if any value, or any element of an array value, equals the target.
+func matchEquals(values []any, target any) bool {
+	if len(values) == 0 {
+		return target == nil
+	}
+	for _, v := range values {
+		if valuesEqual(v, target) {
+			return true
+		}
+		for _, elem := range asArray(v) {
+			if valuesEqual(elem, target) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchCompare checks if any value satisfies an ordering comparison.
+func matchCompare(values []any, op string, target any) bool {
+	for _, v := range values {
+		candidates := []any{v}
+		if arr, ok := v.(bson.A); ok {
+			candidates = arr
+		}
+		for _, c := range candidates {
+			if !orderable(c, target) {
+				continue
+			}
+			cmp := compareValues(c, target)
+			switch op {
+			case "$gt":
+				if cmp > 0 {
+					return true
+				}
+			case "$gte":
+				if cmp >= 0 {
+					return true
+				}
+			case "$lt":
+				if cmp < 0 {
+					return true
+				}
+			case "$lte":
+				if cmp <= 0 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// matchRegex checks if any string value matches a regular expression.
+func matchRegex(values []any, pattern any, opts any) bool {
+	var expr string
+	flags, _ := opts.(string)
+	switch p := pattern.(type) {
+	case string:
+		expr = p
+	case bson.Regex:
+		expr = p.Pattern
+		flags += p.Options
+	default:
+		return false
+	}
+
+	if strings.Contains(flags, "i") {
+		expr = "(?i)" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return false
+	}
+
+	for _, v := range values {
+		candidates := []any{v}
+		if arr, ok := v.(bson.A); ok {
+			candidates = arr
+		}
+		for _, c := range candidates {
+			if s, ok := c.(string); ok && re.MatchString(s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchElem checks if any array element matches a sub-filter or condition.
+func matchElem(values []any, cond any) bool {
+	f, _ := cond.(bson.M)
+	for _, v := range values {
+		for _, elem := range asArray(v) {
+			if sub, ok := elem.(bson.M); ok && !isOperatorDocument(f) {
+				if matchDocument(sub, f) {
+					return true
+				}
+				continue
+			}
+			if matchCondition([]any{elem}, true, cond) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchText checks if any search term appears in a string field of the document.
+func matchText(d bson.M, search string) bool {
+	terms := strings.Fields(strings.ToLower(search))
+	if len(terms) == 0 {
+		return true
+	}
+
+	var text strings.Builder
+	collectText(d, &text)
+	content := strings.ToLower(text.String())
+
+	for _, term := range terms {
+		if strings.Contains(content, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectText appends all string values of a document to a builder.
+func collectText(v any, sb *strings.Builder) {
+	switch t := v.(type) {
+	case string:
+		sb.WriteString(t)
+		sb.WriteByte(' ')
+	case bson.M:
+		for _, child := range t {
+			collectText(child, sb)
+		}
+	case bson.A:
+		for _, child := range t {
+			collectText(child, sb)
+		}
+	}
+}
+
+// lookupPath returns the value at a dotted path.
+func lookupPath(d bson.M, path string) (any, bool) {
+	var current any = d
+	for part := range strings.SplitSeq(path, ".") {
+		m, ok := current.(bson.M)
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// lookupValues returns all values at a dotted path, traversing arrays of documents.
+func lookupValues(d bson.M, path string) ([]any, bool) {
+	current := []any{d}
+	for part := range strings.SplitSeq(path, ".") {
+		var next []any
+		for _, v := range current {
+			switch t := v.(type) {
+			case bson.M:
+				if child, ok := t[part]; ok {
+					next = append(next, child)
+				}
+			case bson.A:
+				for _, elem := range t {
+					if m, ok := elem.(bson.M); ok {
+						if child, ok := m[part]; ok {
+							next = append(next, child)
+						}
+					}
+				}
+			}
+		}
+		if len(next) == 0 {
+			return nil, false
+		}
+		current = next
+	}
+	return current, true
+}
+
+// setPath sets the value at a dotted path, creating intermediate documents.
+// Documents in arrays are addressed by their index, like the positional operator resolves to.
+func setPath(d bson.M, path string, value any) error {
+	parts := strings.Split(path, ".")
+	current := d
+	for i := 0; i < len(parts)-1; i++ {
+		part := parts[i]
+		child, ok := current[part]
+		if arr, isArray := child.(bson.A); isArray && i+1 < len(parts)-1 {
+			index, err := strconv.Atoi(parts[i+1])
+			if err != nil || index < 0 || index >= len(arr) {
+				return fmt.Errorf("cannot set %s: %s has no element %s", path, part, parts[i+1])
+			}
+			child = arr[index]
+			i++
+		}
+		if !ok || child == nil {
+			next := bson.M{}
+			current[part] = next
+			current = next
+			continue
+		}
+		next, ok := child.(bson.M)
+		if !ok {
+			return fmt.Errorf("cannot set %s: %s is not a document", path, part)
+		}
+		current = next
+	}
+	current[parts[len(parts)-1]] = value
+	return nil
+}
+
+// unsetPath removes the value at a dotted path.
+func unsetPath(d bson.M, path string) {
+	parts := strings.Split(path, ".")
+	current := d
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := current[part].(bson.M)
+		if !ok {
+			return
+		}
+		current = next
+	}
+	delete(current, parts[len(parts)-1])
+}
+
+// applyUpdate applies a normalized update document to a document.
+func applyUpdate(d bson.M, update bson.M) error {
+	for op, arg := range update {
+		fields, ok := arg.(bson.M)
+		if !ok {
+			return fmt.Errorf("invalid argument for %s", op)
+		}
+
+		for path, value := range fields {
+			current, exists := lookupPath(d, path)
+
+			var err error
+			switch op {
+			case "$set":
+				err = setPath(d, path, value)
+			case "$unset":
+				unsetPath(d, path)
+			case "$inc":
+				a, _ := toNumber(current)
+				b, _ := toNumber(value)
+				err = setPath(d, path, addNumbers(current, value, a+b))
+			case "$max":
+				if !exists || compareValues(value, current) > 0 {
+					err = setPath(d, path, value)
+				}
+			case "$min":
+				if !exists || compareValues(value, current) < 0 {
+					err = setPath(d, path, value)
+				}
+			case "$addToSet":
+				arr := asArray(current)
+				for _, item := range eachValues(value) {
+					if !containsValue(arr, item) {
+						arr = append(arr, item)
+					}
+				}
+				err = setPath(d, path, arr)
+			case "$push":
+				arr := asArray(current)
+				arr = append(arr, eachValues(value)...)
+				err = setPath(d, path, arr)
+			case "$pull":
+				if !exists {
+					continue
+				}
+				kept := bson.A{}
+				for _, item := range asArray(current) {
+					if !matchPull(item, value) {
+						kept = append(kept, item)
+					}
+				}
+				err = setPath(d, path, kept)
+			default:
+				return fmt.Errorf("unsupported update operator %s", op)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matchPull checks if an array element matches a $pull condition, a filter on its fields for documents.
+func matchPull(item any, cond any) bool {
+	if sub, ok := item.(bson.M); ok {
+		if f, ok := cond.(bson.M); ok && !isOperatorDocument(f) {
+			return matchDocument(sub, f)
+		}
+	}
+	return matchCondition([]any{item}, true, cond)
+}
+
+// resolvePositional replaces the positional operator in the paths of an update with the index of the
+// array element the filter matched.
+func resolvePositional(d bson.M, filter bson.M, update bson.M) (bson.M, error) {
+	resolved := bson.M{}
+	for op, arg := range update {
+		fields, ok := arg.(bson.M)
+		if !ok {
+			resolved[op] = arg
+			continue
+		}
+
+		paths := bson.M{}
+		for path, value := range fields {
+			parts := strings.Split(path, ".")
+			if at := slices.Index(parts, "$"); at > 0 {
+				index, ok := matchedIndex(d, filter, strings.Join(parts[:at], "."))
+				if !ok {
+					return nil, fmt.Errorf("cannot set %s: the filter matched no element of %s", path, parts[at-1])
+				}
+				parts[at] = strconv.Itoa(index)
+			}
+			paths[strings.Join(parts, ".")] = value
+		}
+		resolved[op] = paths
+	}
+	return resolved, nil
+}
+
+// matchedIndex returns the index of the first document in the array at a path whose fields match the
+// filter's conditions on that array.
+func matchedIndex(d bson.M, filter bson.M, array string) (int, bool) {
+	conditions := bson.M{}
+	for key, cond := range filter {
+		if field, found := strings.CutPrefix(key, array+"."); found {
+			conditions[field] = cond
+		}
+	}
+	if len(conditions) == 0 {
+		return 0, false
+	}
+
+	current, _ := lookupPath(d, array)
+	for i, elem := range asArray(current) {
+		if sub, ok := elem.(bson.M); ok && matchDocument(sub, conditions) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// eachValues expands a {$each: [...]} modifier into its values.
+func eachValues(v any) []any {
+	if m, ok := v.(bson.M); ok {
+		if each, ok := m["$each"]; ok {
+			return asArray(each)
+		}
+	}
+	return []any{v}
+}
+
+// containsValue checks if an array contains a value.
+func containsValue(arr bson.A, v any) bool {
+	for _, item := range arr {
+		if valuesEqual(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// isOperatorDocument checks if all keys of a document are query operators.
+func isOperatorDocument(m bson.M) bool {
+	if len(m) == 0 {
+		return false
+	}
+	for key := range m {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+// asArray returns the elements of an array value, or nil if it is not an array.
+func asArray(v any) bson.A {
+	switch t := v.(type) {
+	case bson.A:
+		return t
+	case []any:
+		return bson.A(t)
+	}
+	return nil
+}
+
+// valuesEqual checks if two normalized values are equal.
+func valuesEqual(a, b any) bool {
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			return x == y
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// orderable checks if two values can be ordered against each other.
+func orderable(a, b any) bool {
+	return typeRank(a) == typeRank(b)
+}
+
+// compareValues orders two normalized values, returning -1, 0 or 1.
+func compareValues(a, b any) int {
+	ra, rb := typeRank(a), typeRank(b)
+	if ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+
+	switch x := a.(type) {
+	case string:
+		return strings.Compare(x, b.(string))
+	case bson.DateTime:
+		return compareOrdered(int64(x), int64(b.(bson.DateTime)))
+	case bson.ObjectID:
+		y := b.(bson.ObjectID)
+		return bytes.Compare(x[:], y[:])
+	case bool:
+		y := b.(bool)
+		if x == y {
+			return 0
+		}
+		if !x {
+			return -1
+		}
+		return 1
+	}
+
+	if x, ok := toNumber(a); ok {
+		y, _ := toNumber(b)
+		return compareOrdered(x, y)
+	}
+	return 0
+}
+
+// compareOrdered compares two ordered values.
+func compareOrdered[T int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// typeRank returns the sort rank of a value's type, following MongoDB's comparison order.
+func typeRank(v any) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case int, int32, int64, float64:
+		return 1
+	case string:
+		return 2
+	case bson.M:
+		return 3
+	case bson.A:
+		return 4
+	case bson.ObjectID:
+		return 5
+	case bool:
+		return 6
+	case bson.DateTime:
+		return 7
+	}
+	return 8
+}
+
+// toNumber converts a numeric value to float64.
+func toNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// addNumbers returns sum in the widest numeric type of a and b.
+func addNumbers(a, b any, sum float64) any {
+	_, aFloat := a.(float64)
+	_, bFloat := b.(float64)
+	if aFloat || bFloat {
+		return sum
+	}
+	_, a64 := a.(int64)
+	_, b64 := b.(int64)
+	if a64 || b64 {
+		return int64(sum)
+	}
+	return int32(sum)
+}
@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// relationshipRepository is the in-memory implementation of repositories.RelationshipRepository.
+type relationshipRepository struct {
+	users    *Collection
+	userRepo *userRepository
+	logger   *utils.Logger
+}
+
+// NewRelationshipRepository creates a new in-memory RelationshipRepository.
+func NewRelationshipRepository(db *Database, logger *utils.Logger) repositories.RelationshipRepository {
+	users := db.Collection("users")
+	return &relationshipRepository{
+		users:    users,
+		userRepo: &userRepository{users: users, logger: logger.Named("memory_user_repository")},
+		logger:   logger.Named("memory_relationship_repository"),
+	}
+}
+
+// FindFollowing finds users that the given user is following.
+func (r *relationshipRepository) FindFollowing(ctx context.Context, userID bson.ObjectID, skip, limit int) ([]*models.User, error) {
+	user, err := r.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(user.Connections.Following) == 0 {
+		return []*models.User{}, nil
+	}
+
+	opts := pageOptions(bson.D{{Key: "username", Value: 1}}, skip, limit)
+	return r.userRepo.FindMany(ctx, bson.M{"_id": bson.M{"$in": user.Connections.Following}}, opts)
+}
+
+// FindFollowers finds users that follow the given user.
+func (r *relationshipRepository) FindFollowers(ctx context.Context, userID bson.ObjectID, skip, limit int) ([]*models.User, error) {
+	opts := pageOptions(bson.D{{Key: "username", Value: 1}}, skip, limit)
+	return r.userRepo.FindMany(ctx, bson.M{"connections.following": userID}, opts)
+}
+
+// IsFollowing checks if a user is following another user.
+func (r *relationshipRepository) IsFollowing(ctx context.Context, userID, targetID bson.ObjectID) (bool, error) {
+	count, err := r.users.CountDocuments(bson.M{"_id": userID, "connections.following": targetID})
+	if err != nil {
+		return false, models.NewInternalError(err, "Failed to check following status")
+	}
+	return count > 0, nil
+}
+
+// Follow makes a user follow another user.
+func (r *relationshipRepository) Follow(ctx context.Context, userID, targetID bson.ObjectID) error {
+	isFollowing, err := r.IsFollowing(ctx, userID, targetID)
+	if err != nil {
+		return err
+	}
+	if isFollowing {
+		return nil
+	}
+
+	now := time.Now()
+	err = r.userRepo.updateByID(userID, bson.M{
+		"$addToSet": bson.M{"connections.following": targetID},
+		"$set":      bson.M{"updatedAt": now},
+	}, "Failed to follow user")
+	if err != nil {
+		return err
+	}
+
+	_, err = r.users.UpdateByID(targetID, bson.M{
+		"$addToSet": bson.M{"connections.followers": userID},
+		"$set":      bson.M{"updatedAt": now},
+	})
+	if err != nil {
+		return models.NewInternalError(err, "Failed to update follower list")
+	}
+
+	// Mutual follows become friends
+	isFollower, err := r.IsFollowing(ctx, targetID, userID)
+	if err != nil {
+		return err
+	}
+	if isFollower {
+		if _, err := r.users.UpdateByID(userID, bson.M{"$addToSet": bson.M{"connections.friends": targetID}}); err != nil {
+			return models.NewInternalError(err, "Failed to update friends lists")
+		}
+		if _, err := r.users.UpdateByID(targetID, bson.M{"$addToSet": bson.M{"connections.friends": userID}}); err != nil {
+			return models.NewInternalError(err, "Failed to update friends lists")
+		}
+	}
+
+	return nil
+}
+
+// Unfollow makes a user unfollow another user.
+func (r *relationshipRepository) Unfollow(ctx context.Context, userID, targetID bson.ObjectID) error {
+	now := time.Now()
+	err := r.userRepo.updateByID(userID, bson.M{
+		"$pull": bson.M{"connections.following": targetID, "connections.friends": targetID},
+		"$set":  bson.M{"updatedAt": now},
+	}, "Failed to unfollow user")
+	if err != nil {
+		return err
+	}
+
+	_, err = r.users.UpdateByID(targetID, bson.M{
+		"$pull": bson.M{"connections.followers": userID, "connections.friends": userID},
+		"$set":  bson.M{"updatedAt": now},
+	})
+	if err != nil {
+		return models.NewInternalError(err, "Failed to update follower list")
+	}
+	return nil
+}
+
+// FindFollowerIDs finds the IDs of every user following the given user.
+func (r *relationshipRepository) FindFollowerIDs(ctx context.Context, userID bson.ObjectID) ([]bson.ObjectID, error) {
+	followers, err := r.userRepo.FindMany(ctx, bson.M{"connections.following": userID}, options.Find())
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]bson.ObjectID, len(followers))
+	for i, follower := range followers {
+		ids[i] = follower.ID
+	}
+	return ids, nil
+}
+
+var _ repositories.RelationshipRepository = (*relationshipRepository)(nil)
@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// reportRepository is the in-memory implementation of repositories.ReportRepository.
+type reportRepository struct {
+	roomReports *Collection
+	logger      *utils.Logger
+}
+
+// NewReportRepository creates a new in-memory ReportRepository.
+func NewReportRepository(db *Database, logger *utils.Logger) repositories.ReportRepository {
+	return &reportRepository{
+		roomReports: db.Collection("room_reports"),
+		logger:      logger.Named("memory_report_repository"),
+	}
+}
+
+// CreateRoomReport creates a new room report.
+func (r *reportRepository) CreateRoomReport(ctx context.Context, report *models.RoomReport) error {
+	if report.ID.IsZero() {
+		report.ID = bson.NewObjectID()
+	}
+	report.CreateNow()
+
+	if err := r.roomReports.InsertOne(report); err != nil {
+		r.logger.Error("Failed to create room report", err, "roomId", report.RoomID.Hex())
+		return models.NewInternalError(err, "Failed to create room report")
+	}
+	return nil
+}
+
+// FindRoomReportByID finds a room report by its ID.
+func (r *reportRepository) FindRoomReportByID(ctx context.Context, id bson.ObjectID) (*models.RoomReport, error) {
+	report, err := findOne[models.RoomReport](r.roomReports, bson.M{"_id": id}, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, models.ErrRoomReportNotFound
+		}
+		return nil, models.NewInternalError(err, "Failed to find room report")
+	}
+	return report, nil
+}
+
+// FindRoomReports finds room reports, oldest first, along with the total number matching.
+func (r *reportRepository) FindRoomReports(ctx context.Context, filter models.RoomReportFilter, skip, limit int) ([]*models.RoomReport, int64, error) {
+	query := roomReportQuery(filter)
+
+	total, err := r.roomReports.CountDocuments(query)
+	if err != nil {
+		return nil, 0, models.NewInternalError(err, "Failed to count room reports")
+	}
+
+	reports, err := findMany[models.RoomReport](r.roomReports, query, pageOptions(bson.D{{Key: "createdAt", Value: 1}}, skip, limit))
+	if err != nil {
+		r.logger.Error("Failed to find room reports", err)
+		return nil, 0, models.NewInternalError(err, "Failed to find room reports")
+	}
+	if reports == nil {
+		reports = []*models.RoomReport{}
+	}
+	return reports, total, nil
+}
+
+// ResolveRoomReports resolves every pending report of a room, and returns the reports it resolved.
+func (r *reportRepository) ResolveRoomReports(ctx context.Context, roomID bson.ObjectID, status models.RoomReportStatus, action models.RoomEnforcement, note string, resolvedBy bson.ObjectID) ([]*models.RoomReport, error) {
+	query := roomReportQuery(models.RoomReportFilter{RoomID: roomID, Status: models.RoomReportPending})
+
+	reports, err := findMany[models.RoomReport](r.roomReports, query, nil)
+	if err != nil {
+		r.logger.Error("Failed to find pending room reports", err, "roomId", roomID.Hex())
+		return nil, models.NewInternalError(err, "Failed to find room reports")
+	}
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{
+		"status":     status,
+		"action":     action,
+		"note":       note,
+		"resolvedBy": resolvedBy,
+		"resolvedAt": now,
+		"updatedAt":  now,
+	}}
+
+	for _, report := range reports {
+		if _, err := r.roomReports.UpdateByID(report.ID, update); err != nil {
+			r.logger.Error("Failed to resolve room report", err, "id", report.ID.Hex())
+			return nil, models.NewInternalError(err, "Failed to resolve room reports")
+		}
+		report.Status = status
+		report.Action = action
+		report.Note = note
+		report.ResolvedBy = resolvedBy
+		report.ResolvedAt = now
+		report.UpdatedAt = now
+	}
+	return reports, nil
+}
+
+// roomReportQuery builds the query for a room report filter.
+func roomReportQuery(filter models.RoomReportFilter) bson.M {
+	query := bson.M{}
+	if !filter.RoomID.IsZero() {
+		query["roomId"] = filter.RoomID
+	}
+	if !filter.ReporterID.IsZero() {
+		query["reporterId"] = filter.ReporterID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	return query
+}
+
+// Ensure reportRepository implements the interface
+var _ repositories.ReportRepository = (*reportRepository)(nil)
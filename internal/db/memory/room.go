@@ -0,0 +1,811 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// roomRepository is the in-memory implementation of repositories.RoomRepository.
+type roomRepository struct {
+	rooms     *Collection
+	roomUsers *Collection
+	logger    *utils.Logger
+}
+
+// NewRoomRepository creates a new in-memory RoomRepository.
+func NewRoomRepository(db *Database, logger *utils.Logger) repositories.RoomRepository {
+	rooms := db.Collection("rooms")
+	rooms.EnsureUniqueIndex("slug")
+
+	roomUsers := db.Collection("room_users")
+	roomUsers.EnsureUniqueIndex("roomId", "userId")
+
+	return &roomRepository{
+		rooms:     rooms,
+		roomUsers: roomUsers,
+		logger:    logger.Named("memory_room_repository"),
+	}
+}
+
+// Create creates a new room.
+func (r *roomRepository) Create(ctx context.Context, room *models.Room) error {
+	if room.ID.IsZero() {
+		room.ID = bson.NewObjectID()
+	}
+
+	if room.Slug == "" {
+		room.Slug = utils.SlugifyString(room.Name)
+	}
+
+	now := time.Now()
+	room.TimeCreate(now)
+	room.LastActivity = now
+
+	if room.Stats.LastStatsReset.IsZero() {
+		room.Stats.LastStatsReset = now
+	}
+
+	if err := r.rooms.InsertOne(room); err != nil {
+		if isDuplicateKey(err) {
+			if strings.Contains(err.Error(), "slug") {
+				// Try with a different slug
+				randomString, err := utils.GenerateRandomString(4)
+				if err != nil {
+					r.logger.Error("Failed to generate random string", err)
+					return models.NewInternalError(err, "Failed to generate slug")
+				}
+				room.Slug = utils.SlugifyString(room.Name) + "-" + randomString
+				return r.Create(ctx, room)
+			}
+			return models.ErrRoomAlreadyExists
+		}
+		r.logger.Error("Failed to create room", err, "name", room.Name)
+		return models.NewInternalError(err, "Failed to create room")
+	}
+
+	return nil
+}
+
+// FindByID finds a room by its ID.
+func (r *roomRepository) FindByID(ctx context.Context, id bson.ObjectID) (*models.Room, error) {
+	return r.findOne(bson.M{"_id": id})
+}
+
+// FindBySlug finds a room by its slug, ignoring case.
+func (r *roomRepository) FindBySlug(ctx context.Context, slug string) (*models.Room, error) {
+	return r.findOne(bson.M{"slug": caseInsensitive(slug)})
+}
+
+// FindMany finds multiple rooms based on query filters.
+func (r *roomRepository) FindMany(ctx context.Context, filter bson.M, opts options.Lister[options.FindOptions]) ([]*models.Room, error) {
+	rooms, err := findMany[models.Room](r.rooms, filter, opts)
+	if err != nil {
+		r.logger.Error("Failed to find rooms", err, "filter", filter)
+		return nil, models.NewInternalError(err, "Failed to find rooms")
+	}
+	return rooms, nil
+}
+
+// Update updates an existing room.
+func (r *roomRepository) Update(ctx context.Context, room *models.Room) error {
+	room.UpdateNow()
+
+	matched, err := r.rooms.ReplaceOne(bson.M{"_id": room.ID}, room)
+	if err != nil {
+		if isDuplicateKey(err) {
+			if strings.Contains(err.Error(), "slug") {
+				return models.NewRoomError(err, "Room slug already exists", 409)
+			}
+			return models.ErrRoomAlreadyExists
+		}
+		r.logger.Error("Failed to update room", err, "id", room.ID.Hex())
+		return models.NewInternalError(err, "Failed to update room")
+	}
+	if matched == 0 {
+		return models.ErrRoomNotFound
+	}
+	return nil
+}
+
+// Delete deletes a room by its ID.
+func (r *roomRepository) Delete(ctx context.Context, id bson.ObjectID) error {
+	deleted, err := r.rooms.DeleteOne(bson.M{"_id": id})
+	if err != nil {
+		return models.NewInternalError(err, "Failed to delete room")
+	}
+	if deleted == 0 {
+		return models.ErrRoomNotFound
+	}
+
+	if _, err := r.roomUsers.DeleteMany(bson.M{"roomId": id}); err != nil {
+		r.logger.Error("Failed to delete room users", err, "roomId", id.Hex())
+		// Continue anyway, the room was already deleted
+	}
+	return nil
+}
+
+// CountRooms counts the number of rooms that match the given filter.
+func (r *roomRepository) CountRooms(ctx context.Context, filter bson.M) (int64, error) {
+	count, err := r.rooms.CountDocuments(filter)
+	if err != nil {
+		return 0, models.NewInternalError(err, "Failed to count rooms")
+	}
+	return count, nil
+}
+
+// SetActive sets a room's active status.
+func (r *roomRepository) SetActive(ctx context.Context, id bson.ObjectID, active bool) error {
+	now := time.Now()
+	return r.updateByID(id, bson.M{"$set": bson.M{
+		"isActive":     active,
+		"updatedAt":    now,
+		"lastActivity": now,
+	}}, "Failed to set room active status")
+}
+
+// SetVerifiedBadge sets a room's verified badge, or takes it away when badge is nil.
+func (r *roomRepository) SetVerifiedBadge(ctx context.Context, id bson.ObjectID, badge *models.VerifiedBadge) error {
+	return r.updateByID(id, bson.M{"$set": bson.M{"verified": badge, "updatedAt": time.Now()}}, "Failed to set room verified badge")
+}
+
+// SetLanguage sets a room's language, or clears it when language is nil.
+func (r *roomRepository) SetLanguage(ctx context.Context, id bson.ObjectID, language *models.RoomLanguage) error {
+	return r.updateByID(id, bson.M{"$set": bson.M{"language": language, "updatedAt": time.Now()}}, "Failed to set room language")
+}
+
+// UpdateLastActivity updates a room's last activity time.
+func (r *roomRepository) UpdateLastActivity(ctx context.Context, id bson.ObjectID) error {
+	now := time.Now()
+	return r.updateByID(id, bson.M{"$set": bson.M{
+		"lastActivity": now,
+		"updatedAt":    now,
+	}}, "Failed to update room last activity")
+}
+
+// AddUserToRoom adds a user to a room.
+func (r *roomRepository) AddUserToRoom(ctx context.Context, roomUser *models.RoomUser) error {
+	if roomUser.ID.IsZero() {
+		roomUser.ID = bson.NewObjectID()
+	}
+
+	now := time.Now()
+	roomUser.JoinedAt = now
+	roomUser.LastActive = now
+
+	room, err := r.FindByID(ctx, roomUser.RoomID)
+	if err != nil {
+		return err
+	}
+
+	if !room.IsActive {
+		return models.ErrRoomInactive
+	}
+
+	isBanned, err := r.IsUserBanned(ctx, roomUser.RoomID, roomUser.UserID)
+	if err != nil {
+		return err
+	}
+	if isBanned {
+		return models.ErrUserBanned
+	}
+
+	count, err := r.roomUsers.CountDocuments(bson.M{"roomId": roomUser.RoomID})
+	if err != nil {
+		return models.NewInternalError(err, "Failed to count room users")
+	}
+	if count >= int64(room.Settings.Capacity) {
+		return models.ErrRoomFull
+	}
+
+	// User already in room, update last active time
+	matched, err := r.roomUsers.UpdateOne(roomAndUserIDs(roomUser.RoomID, roomUser.UserID), bson.M{"$set": bson.M{"lastActive": now}})
+	if err != nil {
+		return models.NewInternalError(err, "Failed to update room user")
+	}
+	if matched > 0 {
+		return models.ErrUserAlreadyInRoom
+	}
+
+	if err := r.roomUsers.InsertOne(roomUser); err != nil {
+		if isDuplicateKey(err) {
+			return models.ErrUserAlreadyInRoom
+		}
+		r.logger.Error("Failed to add user to room", err, "roomId", roomUser.RoomID.Hex(), "userId", roomUser.UserID.Hex())
+		return models.NewInternalError(err, "Failed to add user to room")
+	}
+
+	_, err = r.rooms.UpdateByID(roomUser.RoomID, bson.M{
+		"$inc": bson.M{"stats.totalUsers": 1},
+		"$set": bson.M{"lastActivity": now, "updatedAt": now},
+		"$max": bson.M{"stats.peakUsers": count + 1},
+	})
+	if err != nil {
+		r.logger.Error("Failed to update room stats", err, "roomId", roomUser.RoomID.Hex())
+		// Continue anyway, the user was added to the room
+	}
+
+	return nil
+}
+
+// RemoveUserFromRoom removes a user from a room.
+func (r *roomRepository) RemoveUserFromRoom(ctx context.Context, roomID, userID bson.ObjectID) error {
+	deleted, err := r.roomUsers.DeleteOne(roomAndUserIDs(roomID, userID))
+	if err != nil {
+		return models.NewInternalError(err, "Failed to remove user from room")
+	}
+	if deleted == 0 {
+		return models.ErrUserNotInRoom
+	}
+
+	room, err := r.FindByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	if room.CurrentDJ == userID {
+		r.clearCurrentDJ(roomID)
+	}
+	return nil
+}
+
+// RemoveUserFromRooms removes a user's membership records from every room, and returns how many it removed.
+func (r *roomRepository) RemoveUserFromRooms(ctx context.Context, userID bson.ObjectID) (int64, error) {
+	deleted, err := r.roomUsers.DeleteMany(bson.M{"userId": userID})
+	if err != nil {
+		return 0, models.NewInternalError(err, "Failed to remove user from rooms")
+	}
+	return deleted, nil
+}
+
+// FindRoomUsers finds all users in a room.
+func (r *roomRepository) FindRoomUsers(ctx context.Context, roomID bson.ObjectID) ([]*models.RoomUser, error) {
+	roomUsers, err := findMany[models.RoomUser](r.roomUsers, bson.M{"roomId": roomID}, nil)
+	if err != nil {
+		r.logger.Error("Failed to find room users", err, "roomId", roomID.Hex())
+		return nil, models.NewInternalError(err, "Failed to find room users")
+	}
+	return roomUsers, nil
+}
+
+// FindUserRoom finds the room a user is currently in.
+func (r *roomRepository) FindUserRoom(ctx context.Context, userID bson.ObjectID) (*models.RoomUser, error) {
+	roomUser, err := findOne[models.RoomUser](r.roomUsers, bson.M{"userId": userID}, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, models.ErrUserNotInRoom
+		}
+		return nil, models.NewInternalError(err, "Failed to find user's room")
+	}
+	return roomUser, nil
+}
+
+// UpdateRoomUser updates a room user's information.
+func (r *roomRepository) UpdateRoomUser(ctx context.Context, roomUser *models.RoomUser) error {
+	roomUser.LastActive = time.Now()
+
+	matched, err := r.roomUsers.ReplaceOne(roomAndUserIDs(roomUser.RoomID, roomUser.UserID), roomUser)
+	if err != nil {
+		return models.NewInternalError(err, "Failed to update room user")
+	}
+	if matched == 0 {
+		return models.ErrUserNotInRoom
+	}
+	return nil
+}
+
+// FindRoomUser finds a user's record in a room.
+func (r *roomRepository) FindRoomUser(ctx context.Context, roomID, userID bson.ObjectID) (*models.RoomUser, error) {
+	roomUser, err := findOne[models.RoomUser](r.roomUsers, roomAndUserIDs(roomID, userID), nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, models.ErrUserNotInRoom
+		}
+		return nil, models.NewInternalError(err, "Failed to find room user")
+	}
+	return roomUser, nil
+}
+
+// SetChatAppearance sets how a user's name shows in a room's chat, or resets it with nil.
+func (r *roomRepository) SetChatAppearance(ctx context.Context, roomID, userID bson.ObjectID, appearance *models.ChatAppearance) error {
+	update := bson.M{"$unset": bson.M{"chatAppearance": ""}}
+	if appearance != nil {
+		update = bson.M{"$set": bson.M{"chatAppearance": appearance}}
+	}
+
+	matched, err := r.roomUsers.UpdateOne(roomAndUserIDs(roomID, userID), update)
+	if err != nil {
+		return models.NewInternalError(err, "Failed to set chat appearance")
+	}
+	if matched == 0 {
+		return models.ErrUserNotInRoom
+	}
+	return nil
+}
+
+// UpdateDJQueue updates the DJ queue for a room.
+func (r *roomRepository) UpdateDJQueue(ctx context.Context, roomID bson.ObjectID, queueEntries []models.QueueEntry) error {
+	stored := make([]models.StoredQueueEntry, len(queueEntries))
+	userIDs := make([]bson.ObjectID, len(queueEntries))
+	for i, entry := range queueEntries {
+		stored[i] = entry.Stored()
+		userIDs[i] = entry.User.ID
+	}
+
+	now := time.Now()
+	err := r.updateByID(roomID, bson.M{"$set": bson.M{
+		"djQueue":      stored,
+		"lastActivity": now,
+		"updatedAt":    now,
+	}}, "Failed to update DJ queue")
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range queueEntries {
+		_, err := r.roomUsers.UpdateOne(roomAndUserIDs(roomID, entry.User.ID), bson.M{"$set": bson.M{
+			"position": entry.Position,
+			"isDJ":     true,
+		}})
+		if err != nil {
+			r.logger.Error("Failed to update DJ position", err, "roomId", roomID.Hex(), "userId", entry.User.ID.Hex())
+			// Continue with other updates
+		}
+	}
+
+	// Users who left the queue are no longer DJs
+	_, err = r.roomUsers.UpdateMany(bson.M{"roomId": roomID, "isDJ": true, "userId": bson.M{"$nin": userIDs}},
+		bson.M{"$set": bson.M{"isDJ": false}})
+	if err != nil {
+		r.logger.Error("Failed to clear DJs who left the queue", err, "roomId", roomID.Hex())
+	}
+	return nil
+}
+
+// SetCurrentDJ sets the current DJ for a room.
+func (r *roomRepository) SetCurrentDJ(ctx context.Context, roomID, userID bson.ObjectID) error {
+	if !userID.IsZero() {
+		count, err := r.roomUsers.CountDocuments(roomAndUserIDs(roomID, userID))
+		if err != nil {
+			return models.NewInternalError(err, "Failed to find room user")
+		}
+		if count == 0 {
+			return models.ErrUserNotInRoom
+		}
+	}
+
+	now := time.Now()
+	set := bson.M{
+		"currentDJ":    userID,
+		"updatedAt":    now,
+		"lastActivity": now,
+	}
+
+	// If setting to nil, also clear current media
+	if userID.IsZero() {
+		set["currentMedia"] = bson.NilObjectID
+	}
+
+	return r.updateByID(roomID, bson.M{"$set": set}, "Failed to set current DJ")
+}
+
+// SetCurrentMedia sets the current media for a room.
+func (r *roomRepository) SetCurrentMedia(ctx context.Context, roomID, mediaID bson.ObjectID) error {
+	now := time.Now()
+	set := bson.M{
+		"updatedAt":    now,
+		"lastActivity": now,
+	}
+	update := bson.M{"$set": set}
+
+	if mediaID.IsZero() {
+		update["$unset"] = bson.M{"currentMedia": ""}
+	} else {
+		set["currentMedia"] = mediaID
+	}
+
+	return r.updateByID(roomID, update, "Failed to set current media")
+}
+
+// SetInFlightPlay stores the track playing in a room, or clears it when play is nil.
+func (r *roomRepository) SetInFlightPlay(ctx context.Context, roomID bson.ObjectID, play *models.InFlightPlay) error {
+	update := bson.M{"$unset": bson.M{"inFlightPlay": ""}}
+	if play != nil {
+		update = bson.M{"$set": bson.M{"inFlightPlay": play}}
+	}
+	return r.updateByID(roomID, update, "Failed to set in-flight play")
+}
+
+// TakeInFlightPlay clears the track playing in a room if it is still the given play, reporting whether it was.
+func (r *roomRepository) TakeInFlightPlay(ctx context.Context, roomID, playID bson.ObjectID) (bool, error) {
+	matched, err := r.rooms.UpdateOne(bson.M{"_id": roomID, "inFlightPlay.id": playID}, bson.M{"$unset": bson.M{"inFlightPlay": ""}})
+	if err != nil {
+		return false, models.NewInternalError(err, "Failed to take in-flight play")
+	}
+	return matched > 0, nil
+}
+
+// AddModerator adds a moderator to a room.
+func (r *roomRepository) AddModerator(ctx context.Context, roomID, userID bson.ObjectID) error {
+	err := r.updateByID(roomID, bson.M{
+		"$addToSet": bson.M{"moderators": userID},
+		"$set":      bson.M{"updatedAt": time.Now()},
+	}, "Failed to add moderator")
+	if err != nil {
+		return err
+	}
+
+	r.setRoomUserRole(roomID, userID, "moderator")
+	return nil
+}
+
+// RemoveModerator removes a moderator from a room.
+func (r *roomRepository) RemoveModerator(ctx context.Context, roomID, userID bson.ObjectID) error {
+	room, err := r.FindByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	if room.CreatedBy == userID {
+		return models.NewRoomError(errors.New("cannot remove room creator as moderator"), "Cannot remove room creator as moderator", 403)
+	}
+
+	err = r.updateByID(roomID, bson.M{
+		"$pull": bson.M{"moderators": userID},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	}, "Failed to remove moderator")
+	if err != nil {
+		return err
+	}
+
+	r.setRoomUserRole(roomID, userID, "user")
+	return nil
+}
+
+// BanUser bans a user from a room.
+func (r *roomRepository) BanUser(ctx context.Context, roomID, userID bson.ObjectID) error {
+	room, err := r.FindByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	if room.CreatedBy == userID {
+		return models.NewRoomError(errors.New("cannot ban room creator"), "Cannot ban room creator", 403)
+	}
+
+	err = r.updateByID(roomID, bson.M{
+		"$addToSet": bson.M{"bannedUsers": userID},
+		"$set":      bson.M{"updatedAt": time.Now()},
+	}, "Failed to ban user")
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.roomUsers.DeleteOne(roomAndUserIDs(roomID, userID)); err != nil {
+		r.logger.Error("Failed to remove banned user from room", err, "roomId", roomID.Hex(), "userId", userID.Hex())
+		// Continue anyway, the user was banned
+	}
+
+	if room.CurrentDJ == userID {
+		r.clearCurrentDJ(roomID)
+	}
+	return nil
+}
+
+// UnbanUser unbans a user from a room.
+func (r *roomRepository) UnbanUser(ctx context.Context, roomID, userID bson.ObjectID) error {
+	return r.updateByID(roomID, bson.M{
+		"$pull": bson.M{"bannedUsers": userID},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	}, "Failed to unban user")
+}
+
+// IsUserBanned checks if a user is banned from a room.
+func (r *roomRepository) IsUserBanned(ctx context.Context, roomID, userID bson.ObjectID) (bool, error) {
+	count, err := r.rooms.CountDocuments(bson.M{"_id": roomID, "bannedUsers": userID})
+	if err != nil {
+		return false, models.NewInternalError(err, "Failed to check ban status")
+	}
+	return count > 0, nil
+}
+
+// SetRole assigns a role to a user in a room, replacing the role they had.
+func (r *roomRepository) SetRole(ctx context.Context, roomID bson.ObjectID, assignment models.RoomRoleAssignment) error {
+	moderators := "$pull"
+	if assignment.Role == models.RoomRoleCoHost {
+		moderators = "$addToSet"
+	}
+
+	err := r.updateByID(roomID, bson.M{
+		"$pull": bson.M{"roles": bson.M{"userId": assignment.UserID}},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	}, "Failed to set room role")
+	if err != nil {
+		return err
+	}
+
+	err = r.updateByID(roomID, bson.M{
+		"$push":    bson.M{"roles": assignment},
+		moderators: bson.M{"moderators": assignment.UserID},
+	}, "Failed to set room role")
+	if err != nil {
+		return err
+	}
+
+	r.setRoomUserRole(roomID, assignment.UserID, assignment.Role)
+	return nil
+}
+
+// RemoveRole removes a user's role in a room, leaving them a regular user.
+func (r *roomRepository) RemoveRole(ctx context.Context, roomID, userID bson.ObjectID) error {
+	err := r.updateByID(roomID, bson.M{
+		"$pull": bson.M{
+			"roles":      bson.M{"userId": userID},
+			"moderators": userID,
+		},
+		"$set": bson.M{"updatedAt": time.Now()},
+	}, "Failed to remove room role")
+	if err != nil {
+		return err
+	}
+
+	r.setRoomUserRole(roomID, userID, models.RoomRoleUser)
+	return nil
+}
+
+// RemoveUserRoles removes a user's roles and moderator rights from every room, and returns how many rooms
+// it changed.
+func (r *roomRepository) RemoveUserRoles(ctx context.Context, userID bson.ObjectID) (int64, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"roles.userId": userID},
+		bson.M{"moderators": userID},
+	}}
+	update := bson.M{
+		"$pull": bson.M{
+			"roles":      bson.M{"userId": userID},
+			"moderators": userID,
+		},
+		"$set": bson.M{"updatedAt": time.Now()},
+	}
+	modified, err := r.rooms.UpdateMany(filter, update)
+	if err != nil {
+		r.logger.Error("Failed to remove user roles", err, "userId", userID.Hex())
+		return 0, models.NewInternalError(err, "Failed to remove user roles")
+	}
+	return modified, nil
+}
+
+// TransferOwnership hands a room over from its owner to another user, who gives up their role for the
+// owner's. It fails with models.ErrRoomNotFound if the room isn't owned by fromID.
+func (r *roomRepository) TransferOwnership(ctx context.Context, roomID, fromID, toID bson.ObjectID) error {
+	filter := bson.M{"_id": roomID, "createdBy": fromID}
+	update := bson.M{
+		"$pull":     bson.M{"roles": bson.M{"userId": toID}},
+		"$addToSet": bson.M{"moderators": toID},
+		"$set":      bson.M{"createdBy": toID, "updatedAt": time.Now()},
+	}
+	matched, err := r.rooms.UpdateOne(filter, update)
+	if err != nil {
+		r.logger.Error("Failed to transfer room ownership", err, "roomId", roomID.Hex(), "userId", toID.Hex())
+		return models.NewInternalError(err, "Failed to transfer room ownership")
+	}
+	if matched == 0 {
+		return models.ErrRoomNotFound
+	}
+
+	r.setRoomUserRole(roomID, toID, models.RoomRoleOwner)
+	return nil
+}
+
+// FindRoles finds the roles assigned in a room.
+func (r *roomRepository) FindRoles(ctx context.Context, roomID bson.ObjectID) ([]models.RoomRoleAssignment, error) {
+	room, err := r.FindByID(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	return room.Roles, nil
+}
+
+// SetRolePermissions sets the permissions of a room's roles, or restores the defaults when permissions is empty.
+func (r *roomRepository) SetRolePermissions(ctx context.Context, roomID bson.ObjectID, permissions map[string][]string) error {
+	update := bson.M{"$set": bson.M{"rolePermissions": permissions, "updatedAt": time.Now()}}
+	if len(permissions) == 0 {
+		update = bson.M{
+			"$unset": bson.M{"rolePermissions": ""},
+			"$set":   bson.M{"updatedAt": time.Now()},
+		}
+	}
+	return r.updateByID(roomID, update, "Failed to set room role permissions")
+}
+
+// SetQueueLocked locks or unlocks a room's DJ queue.
+func (r *roomRepository) SetQueueLocked(ctx context.Context, roomID bson.ObjectID, locked bool) error {
+	return r.updateByID(roomID, bson.M{"$set": bson.M{"queueLocked": locked, "updatedAt": time.Now()}}, "Failed to set room queue lock")
+}
+
+// SetQueueOptions sets the options of a room's DJ queue.
+func (r *roomRepository) SetQueueOptions(ctx context.Context, roomID bson.ObjectID, options models.QueueOptions) error {
+	return r.updateByID(roomID, bson.M{"$set": bson.M{
+		"queueLocked":         options.Locked,
+		"queueCycleDisabled":  !options.Cycle,
+		"maxConsecutivePlays": options.MaxConsecutivePlays,
+		"updatedAt":           time.Now(),
+	}}, "Failed to set room queue options")
+}
+
+// SearchRooms searches for rooms based on criteria.
+// Text queries match rooms containing the query as a substring instead of using a text index.
+func (r *roomRepository) SearchRooms(ctx context.Context, criteria models.RoomSearchCriteria) ([]*models.Room, int64, error) {
+	filter := bson.M{"delisted": bson.M{"$ne": true}}
+
+	if criteria.OnlyActive {
+		filter["isActive"] = true
+	}
+	if !criteria.IncludePrivate {
+		filter["settings.private"] = false
+	}
+
+	activeUsers := bson.M{}
+	if criteria.MinUsers > 0 {
+		activeUsers["$gte"] = criteria.MinUsers
+	}
+	if criteria.MaxUsers > 0 {
+		activeUsers["$lte"] = criteria.MaxUsers
+	}
+	if len(activeUsers) > 0 {
+		filter["stats.activeUsers"] = activeUsers
+	}
+
+	if len(criteria.Tags) > 0 {
+		filter["tags"] = bson.M{"$all": criteria.Tags}
+	}
+	if criteria.Language != "" {
+		filter["language.code"] = criteria.Language
+	}
+	if criteria.Query != "" {
+		filter["$text"] = bson.M{"$search": criteria.Query}
+	}
+
+	total, err := r.CountRooms(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if criteria.Page < 1 {
+		criteria.Page = 1
+	}
+	if criteria.Limit < 1 || criteria.Limit > 100 {
+		criteria.Limit = 20
+	}
+
+	var sortField string
+	direction := -1
+	switch criteria.SortBy {
+	case "name":
+		sortField, direction = "name", 1
+	case "created":
+		sortField = "createdAt"
+	case "active":
+		sortField = "lastActivity"
+	case "users":
+		sortField = "stats.activeUsers"
+	case "popularity":
+		sortField = "stats.aggregateRating"
+	case "heat":
+		sortField = "stats.heat"
+	default:
+		sortField = "lastActivity"
+	}
+
+	if criteria.SortDirection == "asc" && criteria.SortBy != "" {
+		direction = -direction
+	}
+
+	opts := pageOptions(bson.D{{Key: sortField, Value: direction}}, (criteria.Page-1)*criteria.Limit, criteria.Limit)
+	rooms, err := r.FindMany(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return rooms, total, nil
+}
+
+// FindPopularRooms finds the most popular active rooms.
+func (r *roomRepository) FindPopularRooms(ctx context.Context, limit int) ([]*models.Room, error) {
+	opts := pageOptions(bson.D{{Key: "stats.heat", Value: -1}, {Key: "stats.activeUsers", Value: -1}}, 0, limit)
+	return r.FindMany(ctx, bson.M{"isActive": true, "delisted": bson.M{"$ne": true}, "expiry": bson.M{"$exists": false}}, opts)
+}
+
+// FindRecentRooms finds recently active rooms that are listed in discovery.
+func (r *roomRepository) FindRecentRooms(ctx context.Context, limit int) ([]*models.Room, error) {
+	opts := pageOptions(bson.D{{Key: "lastActivity", Value: -1}}, 0, limit)
+	return r.FindMany(ctx, bson.M{"isActive": true, "delisted": bson.M{"$ne": true}}, opts)
+}
+
+// UpdateHeat sets the heat of rooms. Rooms that no longer exist are skipped.
+func (r *roomRepository) UpdateHeat(ctx context.Context, heat map[bson.ObjectID]float64) error {
+	for roomID, value := range heat {
+		if _, err := r.rooms.UpdateByID(roomID, bson.M{"$set": bson.M{"stats.heat": value}}); err != nil {
+			r.logger.Error("Failed to update room heat", err, "id", roomID.Hex())
+			return models.NewInternalError(err, "Failed to update room heat")
+		}
+	}
+	return nil
+}
+
+// UpdateDetectedLanguages sets the languages detected from the chat of rooms.
+// Rooms whose owner set their language, and rooms that no longer exist, are left alone.
+func (r *roomRepository) UpdateDetectedLanguages(ctx context.Context, languages map[bson.ObjectID]models.RoomLanguage) error {
+	for roomID, language := range languages {
+		filter := bson.M{"_id": roomID, "language.manual": bson.M{"$ne": true}}
+		if _, err := r.rooms.UpdateOne(filter, bson.M{"$set": bson.M{"language": language}}); err != nil {
+			r.logger.Error("Failed to update room language", err, "id", roomID.Hex())
+			return models.NewInternalError(err, "Failed to update room languages")
+		}
+	}
+	return nil
+}
+
+// findOne finds a single room matching the filter.
+func (r *roomRepository) findOne(filter bson.M) (*models.Room, error) {
+	room, err := findOne[models.Room](r.rooms, filter, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, models.ErrRoomNotFound
+		}
+		r.logger.Error("Failed to find room", err, "filter", filter)
+		return nil, models.NewInternalError(err, "Failed to find room")
+	}
+	return room, nil
+}
+
+// updateByID applies an update to a room and maps a missing room to ErrRoomNotFound.
+func (r *roomRepository) updateByID(id bson.ObjectID, update bson.M, message string) error {
+	matched, err := r.rooms.UpdateByID(id, update)
+	if err != nil {
+		r.logger.Error(message, err, "id", id.Hex())
+		return models.NewInternalError(err, message)
+	}
+	if matched == 0 {
+		return models.ErrRoomNotFound
+	}
+	return nil
+}
+
+// clearCurrentDJ clears the current DJ and media of a room.
+func (r *roomRepository) clearCurrentDJ(roomID bson.ObjectID) {
+	_, err := r.rooms.UpdateByID(roomID, bson.M{"$set": bson.M{
+		"currentDJ":    bson.NilObjectID,
+		"currentMedia": bson.NilObjectID,
+		"updatedAt":    time.Now(),
+	}})
+	if err != nil {
+		r.logger.Error("Failed to clear current DJ", err, "roomId", roomID.Hex())
+	}
+}
+
+// setRoomUserRole updates the role of a user in a room, if present.
+func (r *roomRepository) setRoomUserRole(roomID, userID bson.ObjectID, role string) {
+	_, err := r.roomUsers.UpdateOne(roomAndUserIDs(roomID, userID), bson.M{"$set": bson.M{
+		"role":       role,
+		"lastActive": time.Now(),
+	}})
+	if err != nil {
+		r.logger.Error("Failed to update user role", err, "roomId", roomID.Hex(), "userId", userID.Hex())
+	}
+}
+
+// roomAndUserIDs builds a filter matching a user within a room.
+func roomAndUserIDs(roomID, userID bson.ObjectID) bson.M {
+	return bson.M{"roomId": roomID, "userId": userID}
+}
+
+// Ensure roomRepository implements the interface
+var _ repositories.RoomRepository = (*roomRepository)(nil)
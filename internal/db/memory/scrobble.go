@@ -0,0 +1,189 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// scrobbleRepository is the in-memory implementation of repositories.ScrobbleRepository.
+type scrobbleRepository struct {
+	accounts  *Collection
+	scrobbles *Collection
+	logger    *utils.Logger
+}
+
+// NewScrobbleRepository creates a new in-memory ScrobbleRepository.
+func NewScrobbleRepository(db *Database, logger *utils.Logger) repositories.ScrobbleRepository {
+	accounts := db.Collection("scrobble_accounts")
+	accounts.EnsureUniqueIndex("userId")
+
+	return &scrobbleRepository{
+		accounts:  accounts,
+		scrobbles: db.Collection("scrobbles"),
+		logger:    logger.Named("memory_scrobble_repository"),
+	}
+}
+
+// SaveAccount links a scrobbling account to its user, replacing the account they linked before.
+func (r *scrobbleRepository) SaveAccount(ctx context.Context, account *models.ScrobbleAccount) error {
+	existing, err := r.FindAccount(ctx, account.UserID)
+	if err != nil && !errors.Is(err, models.ErrScrobbleAccountNotFound) {
+		return err
+	}
+	if existing != nil {
+		account.ID = existing.ID
+		account.CreatedAt = existing.CreatedAt
+		account.UpdateNow()
+		_, err = r.accounts.ReplaceOne(bson.M{"userId": account.UserID}, account)
+	} else {
+		account.ID = bson.NewObjectID()
+		account.CreateNow()
+		err = r.accounts.InsertOne(account)
+	}
+	if err != nil {
+		r.logger.Error("Failed to save scrobbling account", err, "userId", account.UserID.Hex())
+		return models.NewInternalError(err, "Failed to save scrobbling account")
+	}
+	return nil
+}
+
+// FindAccount finds the scrobbling account a user linked.
+func (r *scrobbleRepository) FindAccount(ctx context.Context, userID bson.ObjectID) (*models.ScrobbleAccount, error) {
+	account, err := findOne[models.ScrobbleAccount](r.accounts, bson.M{"userId": userID}, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, models.ErrScrobbleAccountNotFound
+		}
+		return nil, models.NewInternalError(err, "Failed to find scrobbling account")
+	}
+	return account, nil
+}
+
+// FindEnabledAccounts finds the scrobbling accounts of the given users that have scrobbling enabled.
+func (r *scrobbleRepository) FindEnabledAccounts(ctx context.Context, userIDs []bson.ObjectID) ([]*models.ScrobbleAccount, error) {
+	accounts, err := findMany[models.ScrobbleAccount](r.accounts, bson.M{"userId": bson.M{"$in": userIDs}, "enabled": true}, nil)
+	if err != nil {
+		r.logger.Error("Failed to find scrobbling accounts", err)
+		return nil, models.NewInternalError(err, "Failed to find scrobbling accounts")
+	}
+	if accounts == nil {
+		accounts = []*models.ScrobbleAccount{}
+	}
+	return accounts, nil
+}
+
+// SetAccountEnabled enables or disables scrobbling a user's plays.
+func (r *scrobbleRepository) SetAccountEnabled(ctx context.Context, userID bson.ObjectID, enabled bool) error {
+	matched, err := r.accounts.UpdateOne(bson.M{"userId": userID}, bson.M{"$set": bson.M{"enabled": enabled, "updatedAt": time.Now()}})
+	if err != nil {
+		return models.NewInternalError(err, "Failed to update scrobbling account")
+	}
+	if matched == 0 {
+		return models.ErrScrobbleAccountNotFound
+	}
+	return nil
+}
+
+// DeleteAccount unlinks a user's scrobbling account. Their scrobble log is kept.
+func (r *scrobbleRepository) DeleteAccount(ctx context.Context, userID bson.ObjectID) error {
+	deleted, err := r.accounts.DeleteOne(bson.M{"userId": userID})
+	if err != nil {
+		return models.NewInternalError(err, "Failed to delete scrobbling account")
+	}
+	if deleted == 0 {
+		return models.ErrScrobbleAccountNotFound
+	}
+	return nil
+}
+
+// CreateScrobble adds a scrobble to its user's scrobble log.
+func (r *scrobbleRepository) CreateScrobble(ctx context.Context, scrobble *models.Scrobble) error {
+	if scrobble.ID.IsZero() {
+		scrobble.ID = bson.NewObjectID()
+	}
+	scrobble.CreateNow()
+
+	if err := r.scrobbles.InsertOne(scrobble); err != nil {
+		r.logger.Error("Failed to create scrobble", err, "userId", scrobble.UserID.Hex(), "playId", scrobble.PlayID.Hex())
+		return models.NewInternalError(err, "Failed to create scrobble")
+	}
+	return nil
+}
+
+// FindScrobbleByID finds a scrobble by its ID.
+func (r *scrobbleRepository) FindScrobbleByID(ctx context.Context, id bson.ObjectID) (*models.Scrobble, error) {
+	scrobble, err := findOne[models.Scrobble](r.scrobbles, bson.M{"_id": id}, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, models.ErrScrobbleNotFound
+		}
+		return nil, models.NewInternalError(err, "Failed to find scrobble")
+	}
+	return scrobble, nil
+}
+
+// UpdateScrobble records the outcome of submitting a scrobble.
+func (r *scrobbleRepository) UpdateScrobble(ctx context.Context, scrobble *models.Scrobble) error {
+	scrobble.UpdateNow()
+	matched, err := r.scrobbles.UpdateByID(scrobble.ID, bson.M{"$set": bson.M{
+		"status":      scrobble.Status,
+		"attempts":    scrobble.Attempts,
+		"error":       scrobble.Error,
+		"submittedAt": scrobble.SubmittedAt,
+		"updatedAt":   scrobble.UpdatedAt,
+	}})
+	if err != nil {
+		return models.NewInternalError(err, "Failed to update scrobble")
+	}
+	if matched == 0 {
+		return models.ErrScrobbleNotFound
+	}
+	return nil
+}
+
+// FindScrobbles finds a user's scrobbles, most recently played first, along with the total number.
+func (r *scrobbleRepository) FindScrobbles(ctx context.Context, userID bson.ObjectID, skip, limit int) ([]*models.Scrobble, int64, error) {
+	query := bson.M{"userId": userID}
+
+	total, err := r.scrobbles.CountDocuments(query)
+	if err != nil {
+		return nil, 0, models.NewInternalError(err, "Failed to count scrobbles")
+	}
+
+	scrobbles, err := findMany[models.Scrobble](r.scrobbles, query, pageOptions(bson.D{{Key: "playedAt", Value: -1}}, skip, limit))
+	if err != nil {
+		r.logger.Error("Failed to find scrobbles", err, "userId", userID.Hex())
+		return nil, 0, models.NewInternalError(err, "Failed to find scrobbles")
+	}
+	if scrobbles == nil {
+		scrobbles = []*models.Scrobble{}
+	}
+	return scrobbles, total, nil
+}
+
+// DeleteScrobblesBefore deletes the scrobbles logged before a time, and returns how many it deleted.
+func (r *scrobbleRepository) DeleteScrobblesBefore(ctx context.Context, before time.Time) (int64, error) {
+	deleted, err := r.scrobbles.DeleteMany(bson.M{"createdAt": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, models.NewInternalError(err, "Failed to delete scrobbles")
+	}
+	return deleted, nil
+}
+
+// DeleteUserScrobbles deletes a user's scrobble log, and returns how many scrobbles it deleted.
+func (r *scrobbleRepository) DeleteUserScrobbles(ctx context.Context, userID bson.ObjectID) (int64, error) {
+	deleted, err := r.scrobbles.DeleteMany(bson.M{"userId": userID})
+	if err != nil {
+		return 0, models.NewInternalError(err, "Failed to delete scrobbles")
+	}
+	return deleted, nil
+}
+
+// Ensure scrobbleRepository implements the interface
+var _ repositories.ScrobbleRepository = (*scrobbleRepository)(nil)
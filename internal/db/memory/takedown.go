@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// takedownRepository is the in-memory implementation of repositories.TakedownRepository.
+type takedownRepository struct {
+	takedowns *Collection
+	logger    *utils.Logger
+}
+
+// NewTakedownRepository creates a new in-memory TakedownRepository.
+func NewTakedownRepository(db *Database, logger *utils.Logger) repositories.TakedownRepository {
+	takedowns := db.Collection("media_takedowns")
+	takedowns.EnsureUniqueIndex("type", "sourceId")
+
+	return &takedownRepository{
+		takedowns: takedowns,
+		logger:    logger.Named("memory_takedown_repository"),
+	}
+}
+
+// CreateTakedown records a new media takedown. A source can only be taken down once.
+func (r *takedownRepository) CreateTakedown(ctx context.Context, takedown *models.MediaTakedown) error {
+	if takedown.ID.IsZero() {
+		takedown.ID = bson.NewObjectID()
+	}
+	takedown.CreateNow()
+
+	if err := r.takedowns.InsertOne(takedown); err != nil {
+		if isDuplicateKey(err) {
+			return models.ErrAlreadyTakenDown
+		}
+		r.logger.Error("Failed to create media takedown", err, "type", takedown.Type, "sourceId", takedown.SourceID)
+		return models.NewInternalError(err, "Failed to create media takedown")
+	}
+	return nil
+}
+
+// FindTakedownByID finds a media takedown by its ID.
+func (r *takedownRepository) FindTakedownByID(ctx context.Context, id bson.ObjectID) (*models.MediaTakedown, error) {
+	return r.findOne(bson.M{"_id": id})
+}
+
+// FindTakedownBySource finds the takedown of a media source.
+func (r *takedownRepository) FindTakedownBySource(ctx context.Context, sourceType, sourceID string) (*models.MediaTakedown, error) {
+	return r.findOne(bson.M{"type": sourceType, "sourceId": sourceID})
+}
+
+// findOne finds the media takedown matching a query.
+func (r *takedownRepository) findOne(query bson.M) (*models.MediaTakedown, error) {
+	takedown, err := findOne[models.MediaTakedown](r.takedowns, query, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, models.ErrTakedownNotFound
+		}
+		return nil, models.NewInternalError(err, "Failed to find media takedown")
+	}
+	return takedown, nil
+}
+
+// FindTakedowns finds media takedowns, newest first, along with the total number matching.
+func (r *takedownRepository) FindTakedowns(ctx context.Context, filter models.MediaTakedownFilter, skip, limit int) ([]*models.MediaTakedown, int64, error) {
+	query := mediaTakedownQuery(filter)
+
+	total, err := r.takedowns.CountDocuments(query)
+	if err != nil {
+		return nil, 0, models.NewInternalError(err, "Failed to count media takedowns")
+	}
+
+	takedowns, err := findMany[models.MediaTakedown](r.takedowns, query, pageOptions(bson.D{{Key: "createdAt", Value: -1}}, skip, limit))
+	if err != nil {
+		r.logger.Error("Failed to find media takedowns", err)
+		return nil, 0, models.NewInternalError(err, "Failed to find media takedowns")
+	}
+	if takedowns == nil {
+		takedowns = []*models.MediaTakedown{}
+	}
+	return takedowns, total, nil
+}
+
+// SetTakedownCounts records how many playlists and playlist items a takedown flagged.
+func (r *takedownRepository) SetTakedownCounts(ctx context.Context, id bson.ObjectID, playlists, items int) error {
+	matched, err := r.takedowns.UpdateByID(id, bson.M{"$set": bson.M{"playlists": playlists, "items": items, "updatedAt": time.Now()}})
+	if err != nil {
+		r.logger.Error("Failed to set media takedown counts", err, "id", id.Hex())
+		return models.NewInternalError(err, "Failed to set media takedown counts")
+	}
+	if matched == 0 {
+		return models.ErrTakedownNotFound
+	}
+	return nil
+}
+
+// mediaTakedownQuery builds the query for a media takedown filter.
+func mediaTakedownQuery(filter models.MediaTakedownFilter) bson.M {
+	query := bson.M{}
+	if filter.Type != "" {
+		query["type"] = filter.Type
+	}
+	if filter.SourceID != "" {
+		query["sourceId"] = filter.SourceID
+	}
+	if filter.Reason != "" {
+		query["reason"] = filter.Reason
+	}
+	return query
+}
+
+// Ensure takedownRepository implements the interface
+var _ repositories.TakedownRepository = (*takedownRepository)(nil)
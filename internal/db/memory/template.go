@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// templateRepository is the in-memory implementation of repositories.TemplateRepository.
+type templateRepository struct {
+	templates *Collection
+	logger    *utils.Logger
+}
+
+// NewTemplateRepository creates a new in-memory TemplateRepository.
+func NewTemplateRepository(db *Database, logger *utils.Logger) repositories.TemplateRepository {
+	templates := db.Collection("message_templates")
+	templates.EnsureUniqueIndex("name", "locale", "version")
+
+	return &templateRepository{
+		templates: templates,
+		logger:    logger.Named("memory_template_repository"),
+	}
+}
+
+// CreateVersion creates the next version of a message template in its locale.
+func (r *templateRepository) CreateVersion(ctx context.Context, template *models.MessageTemplate) error {
+	for {
+		latest, err := findOne[models.MessageTemplate](r.templates,
+			bson.M{"name": template.Name, "locale": template.Locale}, bson.D{{Key: "version", Value: -1}})
+		if err != nil && !isNotFound(err) {
+			return models.NewInternalError(err, "Failed to find message template")
+		}
+
+		template.ID = bson.NewObjectID()
+		template.Version = 1
+		if latest != nil {
+			template.Version = latest.Version + 1
+		}
+		template.CreateNow()
+
+		err = r.templates.InsertOne(template)
+		if err == nil {
+			return nil
+		}
+		if !isDuplicateKey(err) {
+			r.logger.Error("Failed to create message template version", err, "name", template.Name, "locale", template.Locale)
+			return models.NewInternalError(err, "Failed to create message template version")
+		}
+	}
+}
+
+// FindActive finds the version of a message template sent in a locale.
+func (r *templateRepository) FindActive(ctx context.Context, name, locale string) (*models.MessageTemplate, error) {
+	return r.findOne(bson.M{"name": name, "locale": locale, "active": true})
+}
+
+// FindVersion finds a version of a message template in a locale.
+func (r *templateRepository) FindVersion(ctx context.Context, name, locale string, version int) (*models.MessageTemplate, error) {
+	return r.findOne(bson.M{"name": name, "locale": locale, "version": version})
+}
+
+// findOne finds the message template matching a query.
+func (r *templateRepository) findOne(query bson.M) (*models.MessageTemplate, error) {
+	template, err := findOne[models.MessageTemplate](r.templates, query, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, models.ErrTemplateNotFound
+		}
+		return nil, models.NewInternalError(err, "Failed to find message template")
+	}
+	return template, nil
+}
+
+// FindTemplates finds message templates by name and locale, newest version first.
+func (r *templateRepository) FindTemplates(ctx context.Context, filter models.MessageTemplateFilter) ([]*models.MessageTemplate, error) {
+	sort := bson.D{
+		{Key: "name", Value: 1},
+		{Key: "locale", Value: 1},
+		{Key: "version", Value: -1},
+	}
+
+	templates, err := findMany[models.MessageTemplate](r.templates, messageTemplateQuery(filter), pageOptions(sort, 0, 0))
+	if err != nil {
+		r.logger.Error("Failed to find message templates", err)
+		return nil, models.NewInternalError(err, "Failed to find message templates")
+	}
+	if templates == nil {
+		templates = []*models.MessageTemplate{}
+	}
+	return templates, nil
+}
+
+// ActivateVersion makes a version of a message template the one sent in its locale, and returns it.
+func (r *templateRepository) ActivateVersion(ctx context.Context, name, locale string, version int) (*models.MessageTemplate, error) {
+	now := time.Now()
+	matched, err := r.templates.UpdateOne(bson.M{"name": name, "locale": locale, "version": version},
+		bson.M{"$set": bson.M{"active": true, "updatedAt": now}})
+	if err != nil {
+		return nil, models.NewInternalError(err, "Failed to activate message template version")
+	}
+	if matched == 0 {
+		return nil, models.ErrTemplateNotFound
+	}
+
+	_, err = r.templates.UpdateMany(bson.M{"name": name, "locale": locale, "version": bson.M{"$ne": version}, "active": true},
+		bson.M{"$set": bson.M{"active": false, "updatedAt": now}})
+	if err != nil {
+		return nil, models.NewInternalError(err, "Failed to deactivate message template versions")
+	}
+
+	return r.FindVersion(ctx, name, locale, version)
+}
+
+// messageTemplateQuery builds the query for a message template filter.
+func messageTemplateQuery(filter models.MessageTemplateFilter) bson.M {
+	query := bson.M{}
+	if filter.Name != "" {
+		query["name"] = filter.Name
+	}
+	if filter.Locale != "" {
+		query["locale"] = filter.Locale
+	}
+	if filter.ActiveOnly {
+		query["active"] = true
+	}
+	return query
+}
+
+// Ensure templateRepository implements the interface
+var _ repositories.TemplateRepository = (*templateRepository)(nil)
@@ -0,0 +1,510 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// userRepository is the in-memory implementation of repositories.UserRepository.
+type userRepository struct {
+	users  *Collection
+	logger *utils.Logger
+}
+
+// NewUserRepository creates a new in-memory UserRepository.
+func NewUserRepository(db *Database, logger *utils.Logger) repositories.UserRepository {
+	users := db.Collection("users")
+	users.EnsureUniqueIndex("email")
+	users.EnsureUniqueIndex("username")
+
+	return &userRepository{
+		users:  users,
+		logger: logger.Named("memory_user_repository"),
+	}
+}
+
+// Create creates a new user.
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	if user.ID.IsZero() {
+		user.ID = bson.NewObjectID()
+	}
+
+	now := time.Now()
+	user.TimeCreate(now)
+	if user.Profile.JoinDate.IsZero() {
+		user.Profile.JoinDate = now
+	}
+
+	// Usernames are unique regardless of case
+	if _, err := r.FindByUsername(ctx, user.Username); err == nil {
+		return models.ErrUsernameAlreadyExists
+	}
+
+	if err := r.users.InsertOne(user); err != nil {
+		return r.mapWriteError(err, "Failed to create user")
+	}
+	return nil
+}
+
+// FindByID finds a user by their ID.
+func (r *userRepository) FindByID(ctx context.Context, id bson.ObjectID) (*models.User, error) {
+	return r.findOne(bson.M{"_id": id})
+}
+
+// FindByEmail finds a user by their email address.
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findOne(bson.M{"email": email})
+}
+
+// FindByOAuthIdentity finds the user a third-party account is linked to.
+func (r *userRepository) FindByOAuthIdentity(ctx context.Context, provider, subject string) (*models.User, error) {
+	return r.findOne(bson.M{"oauthIdentities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}}})
+}
+
+// FindByUsername finds a user by their username, ignoring case.
+func (r *userRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.findOne(bson.M{"username": caseInsensitive(username)})
+}
+
+// FindMany finds multiple users based on query filters.
+func (r *userRepository) FindMany(ctx context.Context, filter bson.M, opts options.Lister[options.FindOptions]) ([]*models.User, error) {
+	users, err := findMany[models.User](r.users, filter, opts)
+	if err != nil {
+		r.logger.Error("Failed to find users", err, "filter", filter)
+		return nil, models.NewInternalError(err, "Failed to find users")
+	}
+	return users, nil
+}
+
+// Update updates an existing user.
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	user.UpdateNow()
+
+	matched, err := r.users.ReplaceOne(bson.M{"_id": user.ID}, user)
+	if err != nil {
+		return r.mapWriteError(err, "Failed to update user")
+	}
+	if matched == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+// UpdateLastLogin updates a user's last login time.
+func (r *userRepository) UpdateLastLogin(ctx context.Context, id bson.ObjectID) error {
+	now := time.Now()
+	return r.updateByID(id, bson.M{"$set": bson.M{"lastLogin": now, "updatedAt": now}}, "Failed to update last login")
+}
+
+// Delete deletes a user by their ID.
+func (r *userRepository) Delete(ctx context.Context, id bson.ObjectID) error {
+	deleted, err := r.users.DeleteOne(bson.M{"_id": id})
+	if err != nil {
+		return models.NewInternalError(err, "Failed to delete user")
+	}
+	if deleted == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+// CountUsers counts the number of users that match the given filter.
+func (r *userRepository) CountUsers(ctx context.Context, filter bson.M) (int64, error) {
+	count, err := r.users.CountDocuments(filter)
+	if err != nil {
+		return 0, models.NewInternalError(err, "Failed to count users")
+	}
+	return count, nil
+}
+
+// UpdateAvatar updates a user's avatar configuration.
+func (r *userRepository) UpdateAvatar(ctx context.Context, userID bson.ObjectID, avatar models.AvatarConfig) error {
+	return r.updateByID(userID, bson.M{"$set": bson.M{"avatarConfig": avatar, "updatedAt": time.Now()}}, "Failed to update avatar")
+}
+
+// UpdateSettings updates a user's settings.
+func (r *userRepository) UpdateSettings(ctx context.Context, userID bson.ObjectID, settings models.UserSettings) error {
+	return r.updateByID(userID, bson.M{"$set": bson.M{"settings": settings, "updatedAt": time.Now()}}, "Failed to update settings")
+}
+
+// AddBadge adds a badge to a user.
+func (r *userRepository) AddBadge(ctx context.Context, userID bson.ObjectID, badge string) error {
+	return r.updateByID(userID, bson.M{
+		"$addToSet": bson.M{"badges": badge},
+		"$set":      bson.M{"updatedAt": time.Now()},
+	}, "Failed to add badge")
+}
+
+// RemoveBadge removes a badge from a user.
+func (r *userRepository) RemoveBadge(ctx context.Context, userID bson.ObjectID, badge string) error {
+	return r.updateByID(userID, bson.M{
+		"$pull": bson.M{"badges": badge},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	}, "Failed to remove badge")
+}
+
+// UpdateStats updates a user's statistics.
+func (r *userRepository) UpdateStats(ctx context.Context, userID bson.ObjectID, updates bson.M) error {
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"stats.lastUpdated": now, "updatedAt": now}}
+
+	if len(updates) > 0 {
+		incs := bson.M{}
+		for key, value := range updates {
+			incs["stats."+key] = value
+		}
+		update["$inc"] = incs
+	}
+
+	return r.updateByID(userID, update, "Failed to update stats")
+}
+
+// AddAudienceTime adds the same listening time in seconds to several users.
+func (r *userRepository) AddAudienceTime(ctx context.Context, userIDs []bson.ObjectID, seconds int64) error {
+	now := time.Now()
+	_, err := r.users.UpdateMany(bson.M{"_id": bson.M{"$in": userIDs}}, bson.M{
+		"$inc": bson.M{"stats.audienceTime": seconds},
+		"$set": bson.M{"stats.lastUpdated": now, "updatedAt": now},
+	})
+	if err != nil {
+		r.logger.Error("Failed to add audience time", err, "users", len(userIDs))
+		return models.NewInternalError(err, "Failed to add audience time")
+	}
+	return nil
+}
+
+// SetLevel raises a user's level from one level to another, unless their level changed meanwhile.
+func (r *userRepository) SetLevel(ctx context.Context, userID bson.ObjectID, from, to int) (bool, error) {
+	now := time.Now()
+	matched, err := r.users.UpdateOne(bson.M{"_id": userID, "stats.level": from}, bson.M{"$set": bson.M{
+		"stats.level":       to,
+		"stats.lastUpdated": now,
+		"updatedAt":         now,
+	}})
+	if err != nil {
+		r.logger.Error("Failed to set level", err, "id", userID.Hex(), "level", to)
+		return false, models.NewInternalError(err, "Failed to set level")
+	}
+	return matched > 0, nil
+}
+
+// SetActive sets a user's active status.
+func (r *userRepository) SetActive(ctx context.Context, userID bson.ObjectID, active bool) error {
+	return r.updateByID(userID, bson.M{"$set": bson.M{"isActive": active, "updatedAt": time.Now()}}, "Failed to set active status")
+}
+
+// SetVerified sets a user's verified status.
+func (r *userRepository) SetVerified(ctx context.Context, userID bson.ObjectID, verified bool) error {
+	return r.updateByID(userID, bson.M{"$set": bson.M{"isVerified": verified, "updatedAt": time.Now()}}, "Failed to set verified status")
+}
+
+// SetVerifiedBadge sets a user's verified artist or label badge, or takes it away when badge is nil.
+func (r *userRepository) SetVerifiedBadge(ctx context.Context, userID bson.ObjectID, badge *models.VerifiedBadge) error {
+	return r.updateByID(userID, bson.M{"$set": bson.M{"verified": badge, "updatedAt": time.Now()}}, "Failed to set verified badge")
+}
+
+// SetTrustOverride pins a user's trust level as set by an admin, or clears the override when level is nil.
+func (r *userRepository) SetTrustOverride(ctx context.Context, userID bson.ObjectID, level *models.TrustLevel, adminID bson.ObjectID) error {
+	if level == nil {
+		return r.updateByID(userID, bson.M{
+			"$unset": bson.M{"trust.override": "", "trust.overriddenBy": ""},
+			"$set":   bson.M{"updatedAt": time.Now()},
+		}, "Failed to set trust override")
+	}
+	return r.updateByID(userID, bson.M{"$set": bson.M{
+		"trust.override": level, "trust.overriddenBy": adminID, "updatedAt": time.Now(),
+	}}, "Failed to set trust override")
+}
+
+// AddStrike records a moderation action against a user at the given time.
+func (r *userRepository) AddStrike(ctx context.Context, userID bson.ObjectID, at time.Time) error {
+	return r.updateByID(userID, bson.M{
+		"$inc": bson.M{"trust.strikes": 1},
+		"$set": bson.M{"trust.lastStrike": at, "updatedAt": time.Now()},
+	}, "Failed to add strike")
+}
+
+// SetAgeAttestation records a user's attestation of their date of birth, unless they already attested it.
+func (r *userRepository) SetAgeAttestation(ctx context.Context, userID bson.ObjectID, attestation models.AgeAttestation) error {
+	filter := bson.M{"_id": userID, "ageAttestation": bson.M{"$exists": false}}
+	matched, err := r.users.UpdateOne(filter, bson.M{"$set": bson.M{"ageAttestation": attestation, "updatedAt": time.Now()}})
+	if err != nil {
+		r.logger.Error("Failed to set age attestation", err, "id", userID.Hex())
+		return models.NewInternalError(err, "Failed to set age attestation")
+	}
+	if matched == 0 {
+		if _, err := r.findOne(bson.M{"_id": userID}); err != nil {
+			return err
+		}
+		return models.ErrAgeAlreadyAttested
+	}
+	return nil
+}
+
+// LinkOAuthIdentity links a third-party account to a user, unless they have one with the same provider linked.
+func (r *userRepository) LinkOAuthIdentity(ctx context.Context, userID bson.ObjectID, identity models.OAuthIdentity) error {
+	filter := bson.M{"_id": userID, "oauthIdentities.provider": bson.M{"$ne": identity.Provider}}
+	update := bson.M{
+		"$push": bson.M{"oauthIdentities": identity},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	}
+	matched, err := r.users.UpdateOne(filter, update)
+	if err != nil {
+		r.logger.Error("Failed to link OAuth identity", err, "id", userID.Hex(), "provider", identity.Provider)
+		return models.NewInternalError(err, "Failed to link OAuth identity")
+	}
+	if matched == 0 {
+		if _, err := r.findOne(bson.M{"_id": userID}); err != nil {
+			return err
+		}
+		return models.ErrOAuthAlreadyLinked
+	}
+	return nil
+}
+
+// AddAPIKey adds a personal API key to a user.
+func (r *userRepository) AddAPIKey(ctx context.Context, userID bson.ObjectID, key models.APIKey) error {
+	return r.updateByID(userID, bson.M{
+		"$push": bson.M{"apiKeys": key},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	}, "Failed to add API key")
+}
+
+// RemoveAPIKey removes one of a user's API keys.
+func (r *userRepository) RemoveAPIKey(ctx context.Context, userID, keyID bson.ObjectID) error {
+	filter := bson.M{"_id": userID, "apiKeys._id": keyID}
+	update := bson.M{
+		"$pull": bson.M{"apiKeys": bson.M{"_id": keyID}},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	}
+	matched, err := r.users.UpdateOne(filter, update)
+	if err != nil {
+		r.logger.Error("Failed to remove API key", err, "id", userID.Hex(), "keyId", keyID.Hex())
+		return models.NewInternalError(err, "Failed to remove API key")
+	}
+	if matched == 0 {
+		if _, err := r.findOne(bson.M{"_id": userID}); err != nil {
+			return err
+		}
+		return models.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// TouchAPIKey records when one of a user's API keys was last used.
+func (r *userRepository) TouchAPIKey(ctx context.Context, userID, keyID bson.ObjectID, at time.Time) error {
+	filter := bson.M{"_id": userID, "apiKeys._id": keyID}
+	matched, err := r.users.UpdateOne(filter, bson.M{"$set": bson.M{"apiKeys.$.lastUsedAt": at}})
+	if err != nil {
+		r.logger.Error("Failed to record API key usage", err, "id", userID.Hex(), "keyId", keyID.Hex())
+		return models.NewInternalError(err, "Failed to record API key usage")
+	}
+	if matched == 0 {
+		return models.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// RenameUser renames a user still named as the change's username, recording it in their username history.
+func (r *userRepository) RenameUser(ctx context.Context, userID bson.ObjectID, username string, change models.UsernameChange, historySize int) error {
+	user, err := r.findOne(bson.M{"_id": userID})
+	if err != nil {
+		return err
+	}
+	if user.Username != change.Username {
+		return models.ErrUsernameChanged
+	}
+
+	// Usernames are unique regardless of case
+	if other, err := r.FindByUsername(ctx, username); err == nil && other.ID != userID {
+		return models.ErrUsernameAlreadyExists
+	}
+
+	user.Username = username
+	user.UsernameHistory = append(user.UsernameHistory, change)
+	user.UsernameHistory = user.UsernameHistory[max(len(user.UsernameHistory)-historySize, 0):]
+	user.UpdateNow()
+
+	matched, err := r.users.ReplaceOne(bson.M{"_id": userID, "username": change.Username}, user)
+	if err != nil {
+		return r.mapWriteError(err, "Failed to rename user")
+	}
+	if matched == 0 {
+		return models.ErrUsernameChanged
+	}
+	return nil
+}
+
+// FindInactive finds users who haven't logged in for the specified duration.
+func (r *userRepository) FindInactive(ctx context.Context, duration time.Duration, limit int) ([]*models.User, error) {
+	filter := bson.M{
+		"lastLogin": bson.M{"$lt": time.Now().Add(-duration)},
+		"isActive":  true,
+	}
+	return r.FindMany(ctx, filter, pageOptions(bson.D{{Key: "lastLogin", Value: 1}}, 0, limit))
+}
+
+// SetDeletion schedules a user's account for deletion, or cancels its deletion when deletion is nil.
+func (r *userRepository) SetDeletion(ctx context.Context, userID bson.ObjectID, deletion *models.AccountDeletion) error {
+	if deletion == nil {
+		return r.updateByID(userID, bson.M{"$unset": bson.M{"deletion": ""}, "$set": bson.M{"updatedAt": time.Now()}}, "Failed to set account deletion")
+	}
+	return r.updateByID(userID, bson.M{"$set": bson.M{"deletion": deletion, "updatedAt": time.Now()}}, "Failed to set account deletion")
+}
+
+// FindDueDeletions finds users whose account deletion is due by the given time, the longest due first.
+func (r *userRepository) FindDueDeletions(ctx context.Context, before time.Time, limit int) ([]*models.User, error) {
+	filter := bson.M{"deletion.purgeAt": bson.M{"$lte": before}}
+	return r.FindMany(ctx, filter, pageOptions(bson.D{{Key: "deletion.purgeAt", Value: 1}}, 0, limit))
+}
+
+// ClaimGuest marks an unlinked guest as being registered, so only one registration can link it. Claims
+// made before staleBefore are taken over. It fails with models.ErrGuestAlreadyLinked if the guest is
+// linked, closed or claimed.
+func (r *userRepository) ClaimGuest(ctx context.Context, guestID bson.ObjectID, staleBefore time.Time) error {
+	filter := bson.M{
+		"_id":            guestID,
+		"isActive":       true,
+		"guest":          bson.M{"$exists": true},
+		"guest.linkedTo": bson.M{"$exists": false},
+		"$or": bson.A{
+			bson.M{"guest.claimedAt": bson.M{"$exists": false}},
+			bson.M{"guest.claimedAt": bson.M{"$lt": staleBefore}},
+		},
+	}
+	matched, err := r.users.UpdateOne(filter, bson.M{"$set": bson.M{"guest.claimedAt": time.Now(), "updatedAt": time.Now()}})
+	if err != nil {
+		r.logger.Error("Failed to claim guest", err, "id", guestID.Hex())
+		return models.NewInternalError(err, "Failed to claim guest")
+	}
+	if matched == 0 {
+		return models.ErrGuestAlreadyLinked
+	}
+	return nil
+}
+
+// ReleaseGuest drops the claim on a guest whose registration failed, so it can register again.
+func (r *userRepository) ReleaseGuest(ctx context.Context, guestID bson.ObjectID) error {
+	filter := bson.M{"_id": guestID, "guest.linkedTo": bson.M{"$exists": false}}
+	if _, err := r.users.UpdateOne(filter, bson.M{"$unset": bson.M{"guest.claimedAt": ""}, "$set": bson.M{"updatedAt": time.Now()}}); err != nil {
+		r.logger.Error("Failed to release guest", err, "id", guestID.Hex())
+		return models.NewInternalError(err, "Failed to release guest")
+	}
+	return nil
+}
+
+// LinkGuest links a claimed guest to the account it registered and closes it.
+func (r *userRepository) LinkGuest(ctx context.Context, guestID, userID bson.ObjectID, at time.Time) error {
+	return r.updateByID(guestID, bson.M{
+		"$set":   bson.M{"guest.linkedTo": userID, "guest.linkedAt": at, "isActive": false, "updatedAt": time.Now()},
+		"$unset": bson.M{"guest.claimedAt": ""},
+	}, "Failed to link guest")
+}
+
+// RemoveConnections removes a user from every other user's following, followers, friends and blocked lists.
+func (r *userRepository) RemoveConnections(ctx context.Context, userID bson.ObjectID) error {
+	update := bson.M{"$pull": bson.M{
+		"connections.following": userID,
+		"connections.followers": userID,
+		"connections.friends":   userID,
+		"connections.blocked":   userID,
+	}}
+	if _, err := r.users.UpdateMany(bson.M{}, update); err != nil {
+		r.logger.Error("Failed to remove user connections", err, "id", userID.Hex())
+		return models.NewInternalError(err, "Failed to remove user connections")
+	}
+	return nil
+}
+
+// ReplaceConnection replaces a user with another in every other user's following, followers and friends lists.
+func (r *userRepository) ReplaceConnection(ctx context.Context, fromID, toID bson.ObjectID) error {
+	for _, field := range []string{"connections.following", "connections.followers", "connections.friends"} {
+		filter := bson.M{"_id": bson.M{"$ne": toID}, field: fromID}
+		if _, err := r.users.UpdateMany(filter, bson.M{"$addToSet": bson.M{field: toID}, "$set": bson.M{"updatedAt": time.Now()}}); err != nil {
+			r.logger.Error("Failed to replace user connections", err, "id", fromID.Hex())
+			return models.NewInternalError(err, "Failed to replace user connections")
+		}
+		if _, err := r.users.UpdateMany(bson.M{field: fromID}, bson.M{"$pull": bson.M{field: fromID}}); err != nil {
+			r.logger.Error("Failed to replace user connections", err, "id", fromID.Hex())
+			return models.NewInternalError(err, "Failed to replace user connections")
+		}
+	}
+	return nil
+}
+
+// AddConnections adds the users and rooms in connections to a user's own connections.
+func (r *userRepository) AddConnections(ctx context.Context, userID bson.ObjectID, connections models.UserConnections) error {
+	lists := map[string][]bson.ObjectID{
+		"connections.following": connections.Following,
+		"connections.followers": connections.Followers,
+		"connections.friends":   connections.Friends,
+		"connections.blocked":   connections.Blocked,
+		"connections.favorites": connections.Favorites,
+	}
+	added := bson.M{}
+	for field, ids := range lists {
+		if len(ids) > 0 {
+			added[field] = bson.M{"$each": ids}
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	return r.updateByID(userID, bson.M{"$addToSet": added, "$set": bson.M{"updatedAt": time.Now()}}, "Failed to add user connections")
+}
+
+// ClearConnections empties a user's own connections.
+func (r *userRepository) ClearConnections(ctx context.Context, userID bson.ObjectID) error {
+	return r.updateByID(userID, bson.M{"$set": bson.M{"connections": models.UserConnections{}, "updatedAt": time.Now()}}, "Failed to clear user connections")
+}
+
+// findOne finds a single user matching the filter.
+func (r *userRepository) findOne(filter bson.M) (*models.User, error) {
+	user, err := findOne[models.User](r.users, filter, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, models.ErrUserNotFound
+		}
+		r.logger.Error("Failed to find user", err, "filter", filter)
+		return nil, models.NewInternalError(err, "Failed to find user")
+	}
+	return user, nil
+}
+
+// updateByID applies an update to a user and maps a missing user to ErrUserNotFound.
+func (r *userRepository) updateByID(id bson.ObjectID, update bson.M, message string) error {
+	matched, err := r.users.UpdateByID(id, update)
+	if err != nil {
+		r.logger.Error(message, err, "id", id.Hex())
+		return models.NewInternalError(err, message)
+	}
+	if matched == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+// mapWriteError maps unique index violations to user errors.
+func (r *userRepository) mapWriteError(err error, message string) error {
+	if isDuplicateKey(err) {
+		if strings.Contains(err.Error(), "email") {
+			return models.ErrEmailAlreadyExists
+		}
+		if strings.Contains(err.Error(), "username") {
+			return models.ErrUsernameAlreadyExists
+		}
+		return models.ErrUserAlreadyExists
+	}
+	r.logger.Error(message, err)
+	return models.NewInternalError(err, message)
+}
+
+// Ensure userRepository implements the interface
+var _ repositories.UserRepository = (*userRepository)(nil)
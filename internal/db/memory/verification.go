@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// verificationRepository is the in-memory implementation of repositories.VerificationRepository.
+type verificationRepository struct {
+	claims *Collection
+	logger *utils.Logger
+}
+
+// NewVerificationRepository creates a new in-memory VerificationRepository.
+func NewVerificationRepository(db *Database, logger *utils.Logger) repositories.VerificationRepository {
+	return &verificationRepository{
+		claims: db.Collection("verification_claims"),
+		logger: logger.Named("memory_verification_repository"),
+	}
+}
+
+// CreateClaim creates a new verification claim.
+func (r *verificationRepository) CreateClaim(ctx context.Context, claim *models.VerificationClaim) error {
+	if claim.ID.IsZero() {
+		claim.ID = bson.NewObjectID()
+	}
+	claim.CreateNow()
+
+	if err := r.claims.InsertOne(claim); err != nil {
+		r.logger.Error("Failed to create verification claim", err, "targetId", claim.TargetID.Hex())
+		return models.NewInternalError(err, "Failed to create verification claim")
+	}
+	return nil
+}
+
+// FindClaimByID finds a verification claim by its ID.
+func (r *verificationRepository) FindClaimByID(ctx context.Context, id bson.ObjectID) (*models.VerificationClaim, error) {
+	claim, err := findOne[models.VerificationClaim](r.claims, bson.M{"_id": id}, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, models.ErrClaimNotFound
+		}
+		return nil, models.NewInternalError(err, "Failed to find verification claim")
+	}
+	return claim, nil
+}
+
+// FindClaims finds verification claims, oldest first, along with the total number matching.
+func (r *verificationRepository) FindClaims(ctx context.Context, filter models.VerificationClaimFilter, skip, limit int) ([]*models.VerificationClaim, int64, error) {
+	query := verificationClaimQuery(filter)
+
+	total, err := r.claims.CountDocuments(query)
+	if err != nil {
+		return nil, 0, models.NewInternalError(err, "Failed to count verification claims")
+	}
+
+	claims, err := findMany[models.VerificationClaim](r.claims, query, pageOptions(bson.D{{Key: "createdAt", Value: 1}}, skip, limit))
+	if err != nil {
+		r.logger.Error("Failed to find verification claims", err)
+		return nil, 0, models.NewInternalError(err, "Failed to find verification claims")
+	}
+	if claims == nil {
+		claims = []*models.VerificationClaim{}
+	}
+	return claims, total, nil
+}
+
+// ReviewClaim records an admin's decision on a pending verification claim, and returns the reviewed claim.
+func (r *verificationRepository) ReviewClaim(ctx context.Context, id bson.ObjectID, status models.ClaimStatus, note string, reviewedBy bson.ObjectID) (*models.VerificationClaim, error) {
+	now := time.Now()
+	matched, err := r.claims.UpdateOne(bson.M{"_id": id, "status": models.ClaimPending}, bson.M{"$set": bson.M{
+		"status":     status,
+		"note":       note,
+		"reviewedBy": reviewedBy,
+		"reviewedAt": now,
+		"updatedAt":  now,
+	}})
+	if err != nil {
+		return nil, models.NewInternalError(err, "Failed to review verification claim")
+	}
+
+	claim, err := r.FindClaimByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if matched == 0 {
+		return nil, models.ErrClaimReviewed
+	}
+	return claim, nil
+}
+
+// DeleteClaimantClaims deletes the verification claims a user made, and returns how many it deleted.
+func (r *verificationRepository) DeleteClaimantClaims(ctx context.Context, claimantID bson.ObjectID) (int64, error) {
+	deleted, err := r.claims.DeleteMany(bson.M{"claimantId": claimantID})
+	if err != nil {
+		return 0, models.NewInternalError(err, "Failed to delete verification claims")
+	}
+	return deleted, nil
+}
+
+// verificationClaimQuery builds the query for a verification claim filter.
+func verificationClaimQuery(filter models.VerificationClaimFilter) bson.M {
+	query := bson.M{}
+	if !filter.ClaimantID.IsZero() {
+		query["claimantId"] = filter.ClaimantID
+	}
+	if !filter.TargetID.IsZero() {
+		query["targetId"] = filter.TargetID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	return query
+}
+
+// Ensure verificationRepository implements the interface
+var _ repositories.VerificationRepository = (*verificationRepository)(nil)
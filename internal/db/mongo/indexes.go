@@ -14,22 +14,37 @@ import (
 
 // Collection name constants for use throughout the application
 const (
-	UsersCollection          = "users"
-	RoomsCollection          = "rooms"
-	RoomUsersCollection      = "room_users"
-	MediaCollection          = "media"
-	PlaylistsCollection      = "playlists"
-	ChatCollection           = "chat_messages"
-	ChatEmoteCollection      = "chat_emotes"
-	ChatCommandCollection    = "chat_commands"
-	ChatModerationCollection = "chat_moderation"
-	HistoryCollection        = "history"
-	PlayHistoryCollection    = "play_history"
-	UserHistoryCollection    = "user_history"
-	RoomHistoryCollection    = "room_history"
-	DJHistoryCollection      = "dj_history"
-	SessionHistoryCollection = "session_history"
-	ModHistoryCollection     = "moderation_history"
+	UsersCollection            = "users"
+	RoomsCollection            = "rooms"
+	RoomUsersCollection        = "room_users"
+	MediaCollection            = "media"
+	MediaTagsCollection        = "media_tags"
+	PlaylistsCollection        = "playlists"
+	PlaylistChangesCollection  = "playlist_changes"
+	ChatCollection             = "chat_messages"
+	ChatEmoteCollection        = "chat_emotes"
+	ChatCommandCollection      = "chat_commands"
+	ChatModerationCollection   = "chat_moderation"
+	ChatFlagsCollection        = "chat_flags"
+	HistoryCollection          = "history"
+	PlayHistoryCollection      = "play_history"
+	UserHistoryCollection      = "user_history"
+	RoomHistoryCollection      = "room_history"
+	DJHistoryCollection        = "dj_history"
+	SessionHistoryCollection   = "session_history"
+	ModHistoryCollection       = "moderation_history"
+	DeveloperAppsCollection    = "developer_apps"
+	WebhookLogCollection       = "webhook_deliveries"
+	MessageTemplatesCollection = "message_templates"
+	MediaTakedownsCollection   = "media_takedowns"
+	IncidentsCollection        = "incidents"
+	ImageReviewsCollection     = "image_reviews"
+	BlockedImagesCollection    = "blocked_images"
+	AchievementsCollection     = "achievements"
+	UserAchievementsCollection = "user_achievements"
+	ScrobbleAccountsCollection = "scrobble_accounts"
+	ScrobblesCollection        = "scrobbles"
+	RoomEventsCollection       = "room_events"
 )
 
 // IndexCreator defines a function type for index creation
@@ -38,12 +53,24 @@ type IndexCreator func(context.Context, *Client) error
 // Index creators for different collections
 var (
 	indexCreators = map[string]IndexCreator{
-		UsersCollection:     ensureUserIndexes,
-		RoomsCollection:     ensureRoomIndexes,
-		MediaCollection:     ensureMediaIndexes,
-		PlaylistsCollection: ensurePlaylistIndexes,
-		ChatCollection:      ensureChatIndexes,
-		HistoryCollection:   ensureHistoryIndexes,
+		UsersCollection:            ensureUserIndexes,
+		RoomsCollection:            ensureRoomIndexes,
+		MediaCollection:            ensureMediaIndexes,
+		PlaylistsCollection:        ensurePlaylistIndexes,
+		PlaylistChangesCollection:  ensurePlaylistChangeIndexes,
+		ChatCollection:             ensureChatIndexes,
+		HistoryCollection:          ensureHistoryIndexes,
+		DeveloperAppsCollection:    ensureDeveloperIndexes,
+		MessageTemplatesCollection: ensureMessageTemplateIndexes,
+		MediaTakedownsCollection:   ensureMediaTakedownIndexes,
+		IncidentsCollection:        ensureIncidentIndexes,
+		ImageReviewsCollection:     ensureImageReviewIndexes,
+		BlockedImagesCollection:    ensureBlockedImageIndexes,
+		AchievementsCollection:     ensureAchievementIndexes,
+		UserAchievementsCollection: ensureUserAchievementIndexes,
+		ScrobbleAccountsCollection: ensureScrobbleAccountIndexes,
+		ScrobblesCollection:        ensureScrobbleIndexes,
+		RoomEventsCollection:       ensureRoomEventIndexes,
 	}
 )
 
@@ -159,6 +186,19 @@ func ensureUserIndexes(ctx context.Context, client *Client) error {
 			Keys:    bson.D{{Key: "roles", Value: 1}},
 			Options: options.Index(),
 		},
+		// OAuth identity index (for signing in with third-party accounts)
+		{
+			Keys: bson.D{
+				{Key: "oauthIdentities.provider", Value: 1},
+				{Key: "oauthIdentities.subject", Value: 1},
+			},
+			Options: options.Index(),
+		},
+		// Account deletion index (for purging accounts once their grace period ends)
+		{
+			Keys:    bson.D{{Key: "deletion.purgeAt", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
 	}
 
 	return createIndexes(ctx, collection, indexes, logger, UsersCollection)
@@ -209,6 +249,11 @@ func ensureRoomIndexes(ctx context.Context, client *Client) error {
 			Keys:    bson.D{{Key: "tags", Value: 1}},
 			Options: options.Index(),
 		},
+		// Language index for the lobby's language filter
+		{
+			Keys:    bson.D{{Key: "language.code", Value: 1}},
+			Options: options.Index(),
+		},
 	}
 
 	// Indexes for RoomUsers collection
@@ -285,9 +330,39 @@ func ensureMediaIndexes(ctx context.Context, client *Client) error {
 			Keys:    bson.D{{Key: "stats.playCount", Value: -1}},
 			Options: options.Index(),
 		},
+		// Fingerprint index for linking copies of a song
+		{
+			Keys:    bson.D{{Key: "fingerprint", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		// ISRC index for linking copies of a recording
+		{
+			Keys:    bson.D{{Key: "metadata.isrc", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		// Canonical index for finding every copy of a song
+		{
+			Keys:    bson.D{{Key: "canonicalId", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	}
+
+	if err := createIndexes(ctx, collection, indexes, logger, MediaCollection); err != nil {
+		return err
 	}
 
-	return createIndexes(ctx, collection, indexes, logger, MediaCollection)
+	// One tag per user and media item
+	tagIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "mediaId", Value: 1},
+				{Key: "userId", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	return createIndexes(ctx, client.Collection(MediaTagsCollection), tagIndexes, logger, MediaTagsCollection)
 }
 
 // ensurePlaylistIndexes creates indexes for the playlists collection
@@ -335,12 +410,37 @@ func ensurePlaylistIndexes(ctx context.Context, client *Client) error {
 	return createIndexes(ctx, collection, indexes, logger, PlaylistsCollection)
 }
 
+// ensurePlaylistChangeIndexes creates indexes for the playlist change log collection
+func ensurePlaylistChangeIndexes(ctx context.Context, client *Client) error {
+	collection := client.Collection(PlaylistChangesCollection)
+	logger := client.Logger().With("operation", "ensurePlaylistChangeIndexes")
+
+	indexes := []mongo.IndexModel{
+		// Playlist + Revision index, a revision is reached by one change only
+		{
+			Keys: bson.D{
+				{Key: "playlistId", Value: 1},
+				{Key: "revision", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		// TTL index, clients offline for longer reload their playlists whole
+		{
+			Keys:    bson.D{{Key: "createdAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(3600 * 24 * 30), // 30 days
+		},
+	}
+
+	return createIndexes(ctx, collection, indexes, logger, PlaylistChangesCollection)
+}
+
 // ensureChatIndexes creates indexes for chat-related collections
 func ensureChatIndexes(ctx context.Context, client *Client) error {
 	chatCollection := client.Collection(ChatCollection)
 	emoteCollection := client.Collection(ChatEmoteCollection)
 	commandCollection := client.Collection(ChatCommandCollection)
 	moderationCollection := client.Collection(ChatModerationCollection)
+	flagsCollection := client.Collection(ChatFlagsCollection)
 	logger := client.Logger().With("operation", "ensureChatIndexes")
 
 	// Indexes for main chat messages collection
@@ -460,6 +560,24 @@ func ensureChatIndexes(ctx context.Context, client *Client) error {
 		},
 	}
 
+	// Indexes for the chat flags collection, the rooms' moderation queues
+	flagIndexes := []mongo.IndexModel{
+		// Room + Status + CreatedAt index for listing a room's queue in order
+		{
+			Keys: bson.D{
+				{Key: "roomId", Value: 1},
+				{Key: "status", Value: 1},
+				{Key: "createdAt", Value: 1},
+			},
+			Options: options.Index(),
+		},
+		// TTL index, flags outlive their messages so late reviews still have the content
+		{
+			Keys:    bson.D{{Key: "createdAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(3600 * 24 * 90), // 90 days
+		},
+	}
+
 	// Create all indexes
 	if err := createIndexes(ctx, chatCollection, chatIndexes, logger, ChatCollection); err != nil {
 		return err
@@ -473,7 +591,11 @@ func ensureChatIndexes(ctx context.Context, client *Client) error {
 		return err
 	}
 
-	return createIndexes(ctx, moderationCollection, moderationIndexes, logger, ChatModerationCollection)
+	if err := createIndexes(ctx, moderationCollection, moderationIndexes, logger, ChatModerationCollection); err != nil {
+		return err
+	}
+
+	return createIndexes(ctx, flagsCollection, flagIndexes, logger, ChatFlagsCollection)
 }
 
 // ensureHistoryIndexes creates indexes for all history-related collections
@@ -527,27 +649,22 @@ func ensureHistoryIndexes(ctx context.Context, client *Client) error {
 
 	// Play history collection indexes
 	playHistoryIndexes := []mongo.IndexModel{
-		// Room index
-		{
-			Keys:    bson.D{{Key: "roomId", Value: 1}},
-			Options: options.Index(),
-		},
 		// Media index
 		{
 			Keys:    bson.D{{Key: "mediaId", Value: 1}},
 			Options: options.Index(),
 		},
-		// DJ index
+		// Song index
 		{
-			Keys:    bson.D{{Key: "djId", Value: 1}},
-			Options: options.Index(),
+			Keys:    bson.D{{Key: "songId", Value: 1}},
+			Options: options.Index().SetSparse(true),
 		},
 		// Start time index
 		{
 			Keys:    bson.D{{Key: "startTime", Value: -1}},
 			Options: options.Index(),
 		},
-		// Room + Start time index
+		// Room + Start time index, which also serves queries by room alone
 		{
 			Keys: bson.D{
 				{Key: "roomId", Value: 1},
@@ -555,7 +672,15 @@ func ensureHistoryIndexes(ctx context.Context, client *Client) error {
 			},
 			Options: options.Index(),
 		},
-		// DJ + Start time index
+		// DJ + ID index for streaming a DJ's history exports
+		{
+			Keys: bson.D{
+				{Key: "djId", Value: 1},
+				{Key: "_id", Value: 1},
+			},
+			Options: options.Index(),
+		},
+		// DJ + Start time index, which also serves queries by DJ alone
 		{
 			Keys: bson.D{
 				{Key: "djId", Value: 1},
@@ -563,6 +688,14 @@ func ensureHistoryIndexes(ctx context.Context, client *Client) error {
 			},
 			Options: options.Index(),
 		},
+		// Room + External ID index (unique, imported plays only)
+		{
+			Keys: bson.D{
+				{Key: "roomId", Value: 1},
+				{Key: "externalId", Value: 1},
+			},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"externalId": bson.M{"$exists": true}}),
+		},
 		// TTL index
 		{
 			Keys:    bson.D{{Key: "startTime", Value: 1}},
@@ -744,3 +877,229 @@ func ensureHistoryIndexes(ctx context.Context, client *Client) error {
 
 	return nil
 }
+
+// ensureDeveloperIndexes creates indexes for developer applications and their webhook delivery log
+func ensureDeveloperIndexes(ctx context.Context, client *Client) error {
+	appsCollection := client.Collection(DeveloperAppsCollection)
+	deliveriesCollection := client.Collection(WebhookLogCollection)
+	logger := client.Logger().With("operation", "ensureDeveloperIndexes")
+
+	appIndexes := []mongo.IndexModel{
+		// Owner index
+		{
+			Keys:    bson.D{{Key: "ownerId", Value: 1}},
+			Options: options.Index(),
+		},
+		// Subscribed events index
+		{
+			Keys: bson.D{
+				{Key: "events", Value: 1},
+				{Key: "active", Value: 1},
+			},
+			Options: options.Index(),
+		},
+	}
+	if err := createIndexes(ctx, appsCollection, appIndexes, logger, DeveloperAppsCollection); err != nil {
+		return err
+	}
+
+	deliveryIndexes := []mongo.IndexModel{
+		// App + CreatedAt index
+		{
+			Keys: bson.D{
+				{Key: "appId", Value: 1},
+				{Key: "createdAt", Value: -1},
+			},
+			Options: options.Index(),
+		},
+		// CreatedAt index, for pruning
+		{
+			Keys:    bson.D{{Key: "createdAt", Value: 1}},
+			Options: options.Index(),
+		},
+	}
+	return createIndexes(ctx, deliveriesCollection, deliveryIndexes, logger, WebhookLogCollection)
+}
+
+// ensureMessageTemplateIndexes creates indexes for the message templates collection
+func ensureMessageTemplateIndexes(ctx context.Context, client *Client) error {
+	collection := client.Collection(MessageTemplatesCollection)
+	logger := client.Logger().With("operation", "ensureMessageTemplateIndexes")
+
+	indexes := []mongo.IndexModel{
+		// Name + Locale + Version index (unique)
+		{
+			Keys: bson.D{
+				{Key: "name", Value: 1},
+				{Key: "locale", Value: 1},
+				{Key: "version", Value: -1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	return createIndexes(ctx, collection, indexes, logger, MessageTemplatesCollection)
+}
+
+// ensureMediaTakedownIndexes creates indexes for the media takedowns collection
+func ensureMediaTakedownIndexes(ctx context.Context, client *Client) error {
+	collection := client.Collection(MediaTakedownsCollection)
+	logger := client.Logger().With("operation", "ensureMediaTakedownIndexes")
+
+	indexes := []mongo.IndexModel{
+		// Type + SourceID index (unique)
+		{
+			Keys:    bson.D{{Key: "type", Value: 1}, {Key: "sourceId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		// Reason + CreatedAt index
+		{
+			Keys: bson.D{{Key: "reason", Value: 1}, {Key: "createdAt", Value: -1}},
+		},
+	}
+	return createIndexes(ctx, collection, indexes, logger, MediaTakedownsCollection)
+}
+
+// ensureIncidentIndexes creates indexes for the status page incidents collection
+func ensureIncidentIndexes(ctx context.Context, client *Client) error {
+	collection := client.Collection(IncidentsCollection)
+	logger := client.Logger().With("operation", "ensureIncidentIndexes")
+
+	indexes := []mongo.IndexModel{
+		// Status + StartedAt index
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "startedAt", Value: -1}},
+		},
+		// Automatic + Components + Status index
+		{
+			Keys: bson.D{{Key: "automatic", Value: 1}, {Key: "components", Value: 1}, {Key: "status", Value: 1}},
+		},
+		// ResolvedAt index
+		{
+			Keys: bson.D{{Key: "resolvedAt", Value: -1}},
+		},
+	}
+	return createIndexes(ctx, collection, indexes, logger, IncidentsCollection)
+}
+
+// ensureImageReviewIndexes creates indexes for the image reviews collection
+func ensureImageReviewIndexes(ctx context.Context, client *Client) error {
+	collection := client.Collection(ImageReviewsCollection)
+	logger := client.Logger().With("operation", "ensureImageReviewIndexes")
+
+	indexes := []mongo.IndexModel{
+		// Status + CreatedAt index
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "createdAt", Value: -1}},
+		},
+		// Status + Checked + CreatedAt index
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "checked", Value: 1}, {Key: "createdAt", Value: 1}},
+		},
+		// OwnerID + CreatedAt index
+		{
+			Keys: bson.D{{Key: "ownerId", Value: 1}, {Key: "createdAt", Value: -1}},
+		},
+	}
+	return createIndexes(ctx, collection, indexes, logger, ImageReviewsCollection)
+}
+
+// ensureBlockedImageIndexes creates indexes for the blocked images collection
+func ensureBlockedImageIndexes(ctx context.Context, client *Client) error {
+	collection := client.Collection(BlockedImagesCollection)
+	logger := client.Logger().With("operation", "ensureBlockedImageIndexes")
+
+	indexes := []mongo.IndexModel{
+		// Hash index (unique)
+		{
+			Keys:    bson.D{{Key: "hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	return createIndexes(ctx, collection, indexes, logger, BlockedImagesCollection)
+}
+
+// ensureAchievementIndexes creates indexes for the achievements collection
+func ensureAchievementIndexes(ctx context.Context, client *Client) error {
+	collection := client.Collection(AchievementsCollection)
+	logger := client.Logger().With("operation", "ensureAchievementIndexes")
+
+	indexes := []mongo.IndexModel{
+		// Key index (unique)
+		{
+			Keys:    bson.D{{Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	return createIndexes(ctx, collection, indexes, logger, AchievementsCollection)
+}
+
+// ensureUserAchievementIndexes creates indexes for the user achievements collection
+func ensureUserAchievementIndexes(ctx context.Context, client *Client) error {
+	collection := client.Collection(UserAchievementsCollection)
+	logger := client.Logger().With("operation", "ensureUserAchievementIndexes")
+
+	indexes := []mongo.IndexModel{
+		// UserID + Key index (unique)
+		{
+			Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	return createIndexes(ctx, collection, indexes, logger, UserAchievementsCollection)
+}
+
+// ensureScrobbleAccountIndexes creates indexes for the scrobble accounts collection
+func ensureScrobbleAccountIndexes(ctx context.Context, client *Client) error {
+	collection := client.Collection(ScrobbleAccountsCollection)
+	logger := client.Logger().With("operation", "ensureScrobbleAccountIndexes")
+
+	indexes := []mongo.IndexModel{
+		// UserID index (unique)
+		{
+			Keys:    bson.D{{Key: "userId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	return createIndexes(ctx, collection, indexes, logger, ScrobbleAccountsCollection)
+}
+
+// ensureScrobbleIndexes creates indexes for the scrobbles collection
+func ensureScrobbleIndexes(ctx context.Context, client *Client) error {
+	collection := client.Collection(ScrobblesCollection)
+	logger := client.Logger().With("operation", "ensureScrobbleIndexes")
+
+	indexes := []mongo.IndexModel{
+		// UserID + PlayedAt index for a user's scrobble log
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}, {Key: "playedAt", Value: -1}},
+		},
+		// CreatedAt index for pruning the scrobble log
+		{
+			Keys: bson.D{{Key: "createdAt", Value: 1}},
+		},
+	}
+	return createIndexes(ctx, collection, indexes, logger, ScrobblesCollection)
+}
+
+// ensureRoomEventIndexes creates indexes for the room_events collection
+func ensureRoomEventIndexes(ctx context.Context, client *Client) error {
+	collection := client.Collection(RoomEventsCollection)
+	logger := client.Logger().With("operation", "ensureRoomEventIndexes")
+
+	indexes := []mongo.IndexModel{
+		// StartTime index for the events about to start across every room
+		{
+			Keys: bson.D{{Key: "startTime", Value: 1}},
+		},
+		// RoomID + StartTime index for a room's upcoming events
+		{
+			Keys: bson.D{{Key: "roomId", Value: 1}, {Key: "startTime", Value: 1}},
+		},
+		// TTL index, finished events stay in feeds for 30 days
+		{
+			Keys:    bson.D{{Key: "endTime", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(3600 * 24 * 30), // 30 days
+		},
+	}
+	return createIndexes(ctx, collection, indexes, logger, RoomEventsCollection)
+}
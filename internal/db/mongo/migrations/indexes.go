@@ -0,0 +1,66 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	mongodriver "go.mongodb.org/mongo-driver/v2/mongo"
+	"norelock.dev/listenify/backend/internal/db/mongo"
+)
+
+// requiredIndex is an index the application can't run correctly without: a unique index that keeps
+// duplicates out, or one that keeps a frequent query from scanning a whole collection.
+type requiredIndex struct {
+	// collection is the collection the index is on.
+	collection string
+
+	// name is the index name, as MongoDB derives it from the keys.
+	name string
+}
+
+// requiredIndexes are the indexes checked to exist once the database is migrated. The indexes themselves
+// are declared with the rest of each collection's indexes in the mongo package.
+var requiredIndexes = []requiredIndex{
+	{collection: mongo.UsersCollection, name: "email_1"},
+	{collection: mongo.UsersCollection, name: "username_1"},
+	{collection: mongo.RoomsCollection, name: "slug_1"},
+	{collection: mongo.RoomUsersCollection, name: "roomId_1_userId_1"},
+	{collection: mongo.MediaCollection, name: "type_1_sourceId_1"},
+	{collection: mongo.PlaylistsCollection, name: "name_text_description_text"},
+	{collection: mongo.PlayHistoryCollection, name: "roomId_1_startTime_-1"},
+	{collection: mongo.PlayHistoryCollection, name: "djId_1_startTime_-1"},
+	{collection: mongo.MediaTakedownsCollection, name: "type_1_sourceId_1"},
+	{collection: mongo.UserAchievementsCollection, name: "userId_1_key_1"},
+	{collection: mongo.ScrobbleAccountsCollection, name: "userId_1"},
+	{collection: mongo.RoomEventsCollection, name: "startTime_1"},
+}
+
+// verifyIndexes checks that every required index exists.
+func verifyIndexes(ctx context.Context, db *mongodriver.Database) error {
+	existing := make(map[string]map[string]bool)
+	var missing []string
+	for _, index := range requiredIndexes {
+		names, ok := existing[index.collection]
+		if !ok {
+			specs, err := db.Collection(index.collection).Indexes().ListSpecifications(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to list indexes of %s: %w", index.collection, err)
+			}
+			names = make(map[string]bool, len(specs))
+			for _, spec := range specs {
+				names[spec.Name] = true
+			}
+			existing[index.collection] = names
+		}
+
+		if !names[index.name] {
+			missing = append(missing, index.collection+"."+index.name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("required indexes are missing: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
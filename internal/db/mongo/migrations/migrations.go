@@ -0,0 +1,197 @@
+// Package migrations bootstraps the MongoDB indexes and runs the versioned schema migrations at startup.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	mongodriver "go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/db/mongo"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// migrationsCollection records the versions of the migrations applied to the database.
+	migrationsCollection = "schema_migrations"
+
+	// lockCollection holds the lock that keeps instances starting together from migrating at once.
+	lockCollection = "schema_migrations_lock"
+
+	// lockID is the ID of the migration lock document.
+	lockID = "migrations"
+
+	// lockRetryInterval is how often a held migration lock is retried.
+	lockRetryInterval = time.Second
+)
+
+// Migration is a versioned change to the database schema or data. Migrations are applied once, in
+// version order, and must not be changed after they are released; later changes get a new version.
+type Migration struct {
+	// Version orders the migrations. Versions are unique and only grow.
+	Version int
+
+	// Description says what the migration changes.
+	Description string
+
+	// Up applies the migration. It should tolerate a previous attempt that failed halfway.
+	Up func(ctx context.Context, db *mongodriver.Database) error
+}
+
+// appliedMigration records a migration applied to the database.
+type appliedMigration struct {
+	Version     int           `bson:"_id"`
+	Description string        `bson:"description"`
+	AppliedAt   time.Time     `bson:"appliedAt"`
+	Duration    time.Duration `bson:"duration"`
+}
+
+// Runner migrates the database: it applies the pending migrations, creates the indexes declared for each
+// collection and checks that the indexes the application requires exist. Instances starting at the same
+// time take turns through a lock, so each migration is applied once.
+type Runner struct {
+	client     *mongo.Client
+	migrations []Migration
+	lockTTL    time.Duration
+	logger     *utils.Logger
+}
+
+// NewRunner creates a new migration runner. The lock an instance migrates under expires after the
+// timeout, so an instance that died while migrating doesn't hold back the others forever.
+func NewRunner(client *mongo.Client, timeout time.Duration, logger *utils.Logger) *Runner {
+	return &Runner{
+		client:     client,
+		migrations: migrations,
+		lockTTL:    timeout,
+		logger:     logger.Named("migrations"),
+	}
+}
+
+// Run migrates the database, waiting for another instance migrating it to finish first.
+func (r *Runner) Run(ctx context.Context) error {
+	if err := validate(r.migrations); err != nil {
+		return err
+	}
+
+	owner, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.unlock(context.WithoutCancel(ctx), owner)
+
+	if err := r.migrate(ctx); err != nil {
+		return err
+	}
+
+	if err := mongo.EnsureIndexes(ctx, r.client); err != nil {
+		return err
+	}
+
+	return verifyIndexes(ctx, r.client.Database())
+}
+
+// migrate applies the migrations that weren't applied yet, in version order.
+func (r *Runner) migrate(ctx context.Context) error {
+	collection := r.client.Collection(migrationsCollection)
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("failed to load applied migrations: %w", err)
+	}
+	var records []appliedMigration
+	if err := cursor.All(ctx, &records); err != nil {
+		return fmt.Errorf("failed to decode applied migrations: %w", err)
+	}
+	applied := make(map[int]bool, len(records))
+	for _, record := range records {
+		applied[record.Version] = true
+	}
+
+	pending := 0
+	for _, migration := range r.migrations {
+		if applied[migration.Version] {
+			continue
+		}
+		pending++
+
+		r.logger.Info("Applying migration", "version", migration.Version, "description", migration.Description)
+		start := time.Now()
+		if err := migration.Up(ctx, r.client.Database()); err != nil {
+			r.logger.Error("Migration failed", err, "version", migration.Version)
+			return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Description, err)
+		}
+
+		record := appliedMigration{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedAt:   time.Now(),
+			Duration:    time.Since(start),
+		}
+		if _, err := collection.InsertOne(ctx, record); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+		}
+		r.logger.Info("Migration applied", "version", migration.Version, "duration", record.Duration)
+	}
+
+	if pending == 0 {
+		r.logger.Info("Database schema is up to date", "version", r.migrations[len(r.migrations)-1].Version)
+	}
+	return nil
+}
+
+// lock takes the migration lock, waiting while another instance holds it, and returns the owner it
+// was taken as. A lock past its expiry is taken over.
+func (r *Runner) lock(ctx context.Context) (string, error) {
+	collection := r.client.Collection(lockCollection)
+	owner := bson.NewObjectID().Hex()
+
+	for {
+		now := time.Now()
+		filter := bson.M{"_id": lockID, "expiresAt": bson.M{"$lt": now}}
+		update := bson.M{"$set": bson.M{"owner": owner, "lockedAt": now, "expiresAt": now.Add(r.lockTTL)}}
+
+		// Upserting while the lock is held collides with the holder's document
+		_, err := collection.UpdateOne(ctx, filter, update, options.UpdateOne().SetUpsert(true))
+		if err == nil {
+			return owner, nil
+		}
+		if !mongodriver.IsDuplicateKeyError(err) {
+			return "", fmt.Errorf("failed to take migration lock: %w", err)
+		}
+
+		r.logger.Info("Waiting for another instance to finish migrating")
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("timed out waiting for migration lock: %w", ctx.Err())
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// unlock releases the migration lock if it is still held by the owner.
+func (r *Runner) unlock(ctx context.Context, owner string) {
+	_, err := r.client.Collection(lockCollection).DeleteOne(ctx, bson.M{"_id": lockID, "owner": owner})
+	if err != nil {
+		r.logger.Error("Failed to release migration lock", err)
+	}
+}
+
+// validate checks that the migrations are in version order without repeated versions.
+func validate(migrations []Migration) error {
+	if len(migrations) == 0 {
+		return errors.New("no migrations declared")
+	}
+	if !slices.IsSortedFunc(migrations, func(a, b Migration) int { return a.Version - b.Version }) {
+		return errors.New("migrations are not in version order")
+	}
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return fmt.Errorf("migration version %d is declared twice", migrations[i].Version)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	mongodriver "go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/db/mongo"
+)
+
+// Server error codes of indexes and collections that don't exist.
+const (
+	errCodeNamespaceNotFound = 26
+	errCodeIndexNotFound     = 27
+)
+
+// migrations are the database migrations, in version order. Append new migrations with the next version.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "Drop the play history room and DJ indexes covered by their start time compound indexes",
+		Up: func(ctx context.Context, db *mongodriver.Database) error {
+			collection := db.Collection(mongo.PlayHistoryCollection)
+			if err := dropIndexIfExists(ctx, collection, "roomId_1"); err != nil {
+				return err
+			}
+			return dropIndexIfExists(ctx, collection, "djId_1")
+		},
+	},
+	{
+		Version:     2,
+		Description: "Move the events embedded in rooms to the room events collection",
+		Up:          moveRoomEvents,
+	},
+}
+
+// moveRoomEvents moves the events embedded in each room to their own documents, keyed by their own ID
+// so a room moved before a failed attempt is moved again without duplicates.
+func moveRoomEvents(ctx context.Context, db *mongodriver.Database) error {
+	rooms := db.Collection(mongo.RoomsCollection)
+	events := db.Collection(mongo.RoomEventsCollection)
+
+	cursor, err := rooms.Find(ctx, bson.M{"events": bson.M{"$exists": true}}, options.Find().SetProjection(bson.M{"events": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var room struct {
+			ID     bson.ObjectID `bson:"_id"`
+			Events []bson.M      `bson:"events"`
+		}
+		if err := cursor.Decode(&room); err != nil {
+			return err
+		}
+
+		for _, event := range room.Events {
+			event["roomId"] = room.ID
+			_, err := events.ReplaceOne(ctx, bson.M{"_id": event["_id"]}, event, options.Replace().SetUpsert(true))
+			if err != nil {
+				return fmt.Errorf("failed to move events of room %s: %w", room.ID.Hex(), err)
+			}
+		}
+
+		if _, err := rooms.UpdateByID(ctx, room.ID, bson.M{"$unset": bson.M{"events": ""}}); err != nil {
+			return fmt.Errorf("failed to move events of room %s: %w", room.ID.Hex(), err)
+		}
+	}
+
+	return cursor.Err()
+}
+
+// dropIndexIfExists drops an index by name, ignoring that it or its collection doesn't exist.
+func dropIndexIfExists(ctx context.Context, collection *mongodriver.Collection, name string) error {
+	err := collection.Indexes().DropOne(ctx, name)
+
+	var serverErr mongodriver.ServerError
+	if errors.As(err, &serverErr) && (serverErr.HasErrorCode(errCodeIndexNotFound) || serverErr.HasErrorCode(errCodeNamespaceNotFound)) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,131 @@
+// Package repositories contains MongoDB repository implementations.
+package repositories
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// Collection names
+const (
+	achievementsCollection     = "achievements"
+	userAchievementsCollection = "user_achievements"
+)
+
+// AchievementRepository defines the interface for achievement definition and unlocked achievement data access operations.
+type AchievementRepository interface {
+	CreateAchievement(ctx context.Context, achievement *models.Achievement) error
+	FindAchievements(ctx context.Context) ([]*models.Achievement, error)
+	UnlockAchievement(ctx context.Context, unlocked *models.UserAchievement) error
+	FindUserAchievements(ctx context.Context, userID bson.ObjectID) ([]*models.UserAchievement, error)
+	DeleteUserAchievements(ctx context.Context, userID bson.ObjectID) (int64, error)
+}
+
+// achievementRepository is the MongoDB implementation of AchievementRepository.
+type achievementRepository struct {
+	achievementsCollection *mongo.Collection
+	unlockedCollection     *mongo.Collection
+	logger                 *utils.Logger
+}
+
+// NewAchievementRepository creates a new instance of AchievementRepository.
+func NewAchievementRepository(db *mongo.Database, logger *utils.Logger) AchievementRepository {
+	return &achievementRepository{
+		achievementsCollection: db.Collection(achievementsCollection),
+		unlockedCollection:     db.Collection(userAchievementsCollection),
+		logger:                 logger.Named("achievement_repository"),
+	}
+}
+
+// CreateAchievement creates an achievement definition. Keys are unique among achievements.
+func (r *achievementRepository) CreateAchievement(ctx context.Context, achievement *models.Achievement) error {
+	if achievement.ID.IsZero() {
+		achievement.ID = bson.NewObjectID()
+	}
+	achievement.CreateNow()
+
+	_, err := r.achievementsCollection.InsertOne(ctx, achievement)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrAchievementExists
+		}
+		r.logger.Error("Failed to create achievement", err, "key", achievement.Key)
+		return models.NewInternalError(err, "Failed to create achievement")
+	}
+
+	return nil
+}
+
+// FindAchievements finds every achievement definition, by metric and threshold.
+func (r *achievementRepository) FindAchievements(ctx context.Context) ([]*models.Achievement, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "metric", Value: 1}, {Key: "threshold", Value: 1}})
+
+	cursor, err := r.achievementsCollection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		r.logger.Error("Failed to find achievements", err)
+		return nil, models.NewInternalError(err, "Failed to find achievements")
+	}
+	defer cursor.Close(ctx)
+
+	achievements := []*models.Achievement{}
+	if err = cursor.All(ctx, &achievements); err != nil {
+		r.logger.Error("Failed to decode achievements", err)
+		return nil, models.NewInternalError(err, "Failed to decode achievements")
+	}
+
+	return achievements, nil
+}
+
+// UnlockAchievement records a user unlocking an achievement. An achievement can only be unlocked once per user.
+func (r *achievementRepository) UnlockAchievement(ctx context.Context, unlocked *models.UserAchievement) error {
+	if unlocked.ID.IsZero() {
+		unlocked.ID = bson.NewObjectID()
+	}
+
+	_, err := r.unlockedCollection.InsertOne(ctx, unlocked)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrAchievementUnlocked
+		}
+		r.logger.Error("Failed to unlock achievement", err, "userId", unlocked.UserID.Hex(), "key", unlocked.Key)
+		return models.NewInternalError(err, "Failed to unlock achievement")
+	}
+
+	return nil
+}
+
+// FindUserAchievements finds the achievements a user unlocked, oldest first.
+func (r *achievementRepository) FindUserAchievements(ctx context.Context, userID bson.ObjectID) ([]*models.UserAchievement, error) {
+	opts := options.Find().SetSort(bson.M{"unlockedAt": 1})
+
+	cursor, err := r.unlockedCollection.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		r.logger.Error("Failed to find user achievements", err, "userId", userID.Hex())
+		return nil, models.NewInternalError(err, "Failed to find user achievements")
+	}
+	defer cursor.Close(ctx)
+
+	unlocked := []*models.UserAchievement{}
+	if err = cursor.All(ctx, &unlocked); err != nil {
+		r.logger.Error("Failed to decode user achievements", err, "userId", userID.Hex())
+		return nil, models.NewInternalError(err, "Failed to decode user achievements")
+	}
+
+	return unlocked, nil
+}
+
+// DeleteUserAchievements deletes the achievements a user unlocked, and returns how many it deleted.
+func (r *achievementRepository) DeleteUserAchievements(ctx context.Context, userID bson.ObjectID) (int64, error) {
+	result, err := r.unlockedCollection.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		r.logger.Error("Failed to delete user achievements", err, "userId", userID.Hex())
+		return 0, models.NewInternalError(err, "Failed to delete user achievements")
+	}
+
+	return result.DeletedCount, nil
+}
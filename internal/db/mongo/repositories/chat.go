@@ -13,9 +13,10 @@ import (
 	"norelock.dev/listenify/backend/internal/utils"
 )
 
-// Collection name
+// Collection names
 const (
 	chatMessagesCollection = "chat_messages"
+	chatFlagsCollection    = "chat_flags"
 )
 
 // ChatRepository defines the interface for chat message data access operations.
@@ -23,25 +24,39 @@ type ChatRepository interface {
 	// Message operations
 	SaveMessage(ctx context.Context, message *models.ChatMessage) error
 	FindMessageByID(ctx context.Context, id bson.ObjectID) (*models.ChatMessage, error)
-	FindMessagesByRoom(ctx context.Context, roomID bson.ObjectID, limit int, before bson.ObjectID) ([]*models.ChatMessage, error)
+	FindMessagesByRoom(ctx context.Context, roomID bson.ObjectID, channel string, limit int, before bson.ObjectID) ([]*models.ChatMessage, error)
 	DeleteMessage(ctx context.Context, id bson.ObjectID) error
+	RestoreMessage(ctx context.Context, id bson.ObjectID) error
 	UpdateMessage(ctx context.Context, message *models.ChatMessage) error
 
 	// Moderation operations
 	DeleteMessagesByUser(ctx context.Context, roomID, userID bson.ObjectID) (int64, error)
+	DeleteMessagesByRoom(ctx context.Context, roomID, deletedBy bson.ObjectID) (int64, error)
+	SetMessageMasked(ctx context.Context, id bson.ObjectID, masked bool) error
+
+	// Account operations
+	AnonymizeUser(ctx context.Context, userID bson.ObjectID) (int64, error)
+
+	// Flag operations
+	CreateFlag(ctx context.Context, flag *models.ChatFlag) error
+	FindFlagByID(ctx context.Context, id bson.ObjectID) (*models.ChatFlag, error)
+	FindFlagsByRoom(ctx context.Context, roomID bson.ObjectID, status models.ChatFlagStatus, skip, limit int) ([]*models.ChatFlag, int64, error)
+	ReviewFlag(ctx context.Context, id bson.ObjectID, status models.ChatFlagStatus, reviewedBy bson.ObjectID) (*models.ChatFlag, error)
 }
 
 // chatRepository is the MongoDB implementation of ChatRepository.
 type chatRepository struct {
-	collection *mongo.Collection
-	logger     *utils.Logger
+	collection      *mongo.Collection
+	flagsCollection *mongo.Collection
+	logger          *utils.Logger
 }
 
 // NewChatRepository creates a new instance of ChatRepository.
 func NewChatRepository(db *mongo.Database, logger *utils.Logger) ChatRepository {
 	return &chatRepository{
-		collection: db.Collection(chatMessagesCollection),
-		logger:     logger.Named("chat_repository"),
+		collection:      db.Collection(chatMessagesCollection),
+		flagsCollection: db.Collection(chatFlagsCollection),
+		logger:          logger.Named("chat_repository"),
 	}
 }
 
@@ -80,13 +95,13 @@ func (r *chatRepository) FindMessageByID(ctx context.Context, id bson.ObjectID)
 	return &message, nil
 }
 
-// FindMessagesByRoom finds chat messages for a room.
-func (r *chatRepository) FindMessagesByRoom(ctx context.Context, roomID bson.ObjectID, limit int, before bson.ObjectID) ([]*models.ChatMessage, error) {
+// FindMessagesByRoom finds chat messages posted in one of a room's chat channels, the general channel if it is empty.
+func (r *chatRepository) FindMessagesByRoom(ctx context.Context, roomID bson.ObjectID, channel string, limit int, before bson.ObjectID) ([]*models.ChatMessage, error) {
 	if limit <= 0 {
 		limit = 50 // Default limit
 	}
 
-	filter := bson.M{"roomId": roomID}
+	filter := bson.M{"roomId": roomID, "channel": chatChannelFilter(channel)}
 
 	// If before ID is provided, only get messages before that ID
 	if !before.IsZero() {
@@ -146,6 +161,29 @@ func (r *chatRepository) DeleteMessage(ctx context.Context, id bson.ObjectID) er
 	return nil
 }
 
+// RestoreMessage restores a deleted chat message.
+func (r *chatRepository) RestoreMessage(ctx context.Context, id bson.ObjectID) error {
+	result, err := r.collection.UpdateByID(
+		ctx,
+		id,
+		bson.D{
+			cmdSet(bson.M{"isDeleted": false}),
+			cmdUnset(bson.M{"deletedBy": "", "deletedAt": ""}),
+		},
+	)
+
+	if err != nil {
+		r.logger.Error("Failed to restore chat message", err, "id", id.Hex())
+		return models.NewInternalError(err, "Failed to restore chat message")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrMessageNotFound
+	}
+
+	return nil
+}
+
 // UpdateMessage updates a chat message.
 func (r *chatRepository) UpdateMessage(ctx context.Context, message *models.ChatMessage) error {
 	message.IsEdited = true
@@ -184,3 +222,161 @@ func (r *chatRepository) DeleteMessagesByUser(ctx context.Context, roomID, userI
 
 	return result.ModifiedCount, nil
 }
+
+// AnonymizeUser detaches a deleted user's messages and mentions from their account. The messages stay in their
+// rooms' chat without pointing at anyone.
+func (r *chatRepository) AnonymizeUser(ctx context.Context, userID bson.ObjectID) (int64, error) {
+	result, err := r.collection.UpdateMany(ctx, bson.M{"userId": userID}, bson.D{cmdSet(bson.M{"userId": bson.NilObjectID})})
+	if err != nil {
+		r.logger.Error("Failed to anonymize user's chat messages", err, "userId", userID.Hex())
+		return 0, models.NewInternalError(err, "Failed to anonymize user's chat messages")
+	}
+
+	if _, err := r.collection.UpdateMany(ctx, bson.M{"mentions": userID}, bson.D{cmdPull(bson.M{"mentions": userID})}); err != nil {
+		r.logger.Error("Failed to remove user's chat mentions", err, "userId", userID.Hex())
+		return result.ModifiedCount, models.NewInternalError(err, "Failed to anonymize user's chat messages")
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// DeleteMessagesByRoom deletes all messages in a room.
+func (r *chatRepository) DeleteMessagesByRoom(ctx context.Context, roomID, deletedBy bson.ObjectID) (int64, error) {
+	result, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"roomId": roomID, "isDeleted": false},
+		bson.D{
+			cmdSet(bson.M{
+				"isDeleted": true,
+				"deletedBy": deletedBy,
+				"deletedAt": time.Now(),
+			}),
+		},
+	)
+
+	if err != nil {
+		r.logger.Error("Failed to delete room's chat messages", err, "roomId", roomID.Hex())
+		return 0, models.NewInternalError(err, "Failed to delete room's chat messages")
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// SetMessageMasked masks a chat message, or shows a masked message again.
+func (r *chatRepository) SetMessageMasked(ctx context.Context, id bson.ObjectID, masked bool) error {
+	result, err := r.collection.UpdateByID(ctx, id, bson.D{cmdSet(bson.M{"isMasked": masked})})
+	if err != nil {
+		r.logger.Error("Failed to mask chat message", err, "id", id.Hex())
+		return models.NewInternalError(err, "Failed to mask chat message")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrMessageNotFound
+	}
+
+	return nil
+}
+
+// CreateFlag adds a flagged chat message to its room's moderation queue.
+func (r *chatRepository) CreateFlag(ctx context.Context, flag *models.ChatFlag) error {
+	if flag.ID.IsZero() {
+		flag.ID = bson.NewObjectID()
+	}
+	flag.CreateNow()
+
+	_, err := r.flagsCollection.InsertOne(ctx, flag)
+	if err != nil {
+		r.logger.Error("Failed to create chat flag", err, "roomId", flag.RoomID.Hex(), "messageId", flag.MessageID.Hex())
+		return models.NewInternalError(err, "Failed to create chat flag")
+	}
+
+	return nil
+}
+
+// FindFlagByID finds a chat flag by its ID.
+func (r *chatRepository) FindFlagByID(ctx context.Context, id bson.ObjectID) (*models.ChatFlag, error) {
+	var flag models.ChatFlag
+
+	err := r.flagsCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&flag)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, models.ErrChatFlagNotFound
+		}
+		r.logger.Error("Failed to find chat flag by ID", err, "id", id.Hex())
+		return nil, models.NewInternalError(err, "Failed to find chat flag")
+	}
+
+	return &flag, nil
+}
+
+// FindFlagsByRoom finds a room's chat flags, oldest first so moderators work through them in order,
+// along with the total number matching. An empty status matches every flag.
+func (r *chatRepository) FindFlagsByRoom(ctx context.Context, roomID bson.ObjectID, status models.ChatFlagStatus, skip, limit int) ([]*models.ChatFlag, int64, error) {
+	query := bson.M{"roomId": roomID}
+	if status != "" {
+		query["status"] = status
+	}
+
+	total, err := r.flagsCollection.CountDocuments(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to count chat flags", err, "roomId", roomID.Hex())
+		return nil, 0, models.NewInternalError(err, "Failed to count chat flags")
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"createdAt": 1}).
+		SetSkip(int64(skip)).
+		SetLimit(int64(limit))
+
+	cursor, err := r.flagsCollection.Find(ctx, query, opts)
+	if err != nil {
+		r.logger.Error("Failed to find chat flags", err, "roomId", roomID.Hex())
+		return nil, 0, models.NewInternalError(err, "Failed to find chat flags")
+	}
+	defer cursor.Close(ctx)
+
+	flags := []*models.ChatFlag{}
+	if err = cursor.All(ctx, &flags); err != nil {
+		r.logger.Error("Failed to decode chat flags", err)
+		return nil, 0, models.NewInternalError(err, "Failed to decode chat flags")
+	}
+
+	return flags, total, nil
+}
+
+// ReviewFlag records a moderator's review of a pending chat flag and returns the reviewed flag.
+func (r *chatRepository) ReviewFlag(ctx context.Context, id bson.ObjectID, status models.ChatFlagStatus, reviewedBy bson.ObjectID) (*models.ChatFlag, error) {
+	now := time.Now()
+	update := bson.D{cmdSet(bson.M{
+		"status":     status,
+		"reviewedBy": reviewedBy,
+		"reviewedAt": now,
+		"updatedAt":  now,
+	})}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var flag models.ChatFlag
+	err := r.flagsCollection.FindOneAndUpdate(ctx, bson.M{"_id": id, "status": models.ChatFlagPending}, update, opts).Decode(&flag)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// Tell a missing flag apart from one another moderator got to first
+			if _, findErr := r.FindFlagByID(ctx, id); findErr != nil {
+				return nil, findErr
+			}
+			return nil, models.ErrChatFlagReviewed
+		}
+		r.logger.Error("Failed to review chat flag", err, "id", id.Hex())
+		return nil, models.NewInternalError(err, "Failed to review chat flag")
+	}
+
+	return &flag, nil
+}
+
+// chatChannelFilter matches the messages of a chat channel. Messages in the general channel are stored without one.
+func chatChannelFilter(channel string) any {
+	if channel == "" || channel == models.DefaultChatChannel {
+		return bson.M{"$exists": false}
+	}
+	return channel
+}
@@ -0,0 +1,204 @@
+// Package repositories contains MongoDB repository implementations.
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// Collection names
+const (
+	developerAppsCollection     = "developer_apps"
+	webhookDeliveriesCollection = "webhook_deliveries"
+)
+
+// DeveloperRepository defines the interface for developer application and webhook delivery data access operations.
+type DeveloperRepository interface {
+	CreateApp(ctx context.Context, app *models.DeveloperApp) error
+	FindAppByID(ctx context.Context, id bson.ObjectID) (*models.DeveloperApp, error)
+	FindAppsByOwner(ctx context.Context, ownerID bson.ObjectID) ([]*models.DeveloperApp, error)
+	FindAppsByEvent(ctx context.Context, event models.WebhookEvent) ([]*models.DeveloperApp, error)
+	UpdateApp(ctx context.Context, app *models.DeveloperApp) error
+	DeleteApp(ctx context.Context, id bson.ObjectID) error
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	FindDeliveries(ctx context.Context, appID bson.ObjectID, skip, limit int) ([]*models.WebhookDelivery, int64, error)
+	DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// developerRepository is the MongoDB implementation of DeveloperRepository.
+type developerRepository struct {
+	appsCollection       *mongo.Collection
+	deliveriesCollection *mongo.Collection
+	logger               *utils.Logger
+}
+
+// NewDeveloperRepository creates a new instance of DeveloperRepository.
+func NewDeveloperRepository(db *mongo.Database, logger *utils.Logger) DeveloperRepository {
+	return &developerRepository{
+		appsCollection:       db.Collection(developerAppsCollection),
+		deliveriesCollection: db.Collection(webhookDeliveriesCollection),
+		logger:               logger.Named("developer_repository"),
+	}
+}
+
+// CreateApp creates a new developer application.
+func (r *developerRepository) CreateApp(ctx context.Context, app *models.DeveloperApp) error {
+	if app.ID.IsZero() {
+		app.ID = bson.NewObjectID()
+	}
+	app.CreateNow()
+
+	_, err := r.appsCollection.InsertOne(ctx, app)
+	if err != nil {
+		r.logger.Error("Failed to create developer application", err, "ownerId", app.OwnerID.Hex())
+		return models.NewInternalError(err, "Failed to create developer application")
+	}
+
+	return nil
+}
+
+// FindAppByID finds a developer application by its ID.
+func (r *developerRepository) FindAppByID(ctx context.Context, id bson.ObjectID) (*models.DeveloperApp, error) {
+	var app models.DeveloperApp
+
+	err := r.appsCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&app)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, models.ErrDeveloperAppNotFound
+		}
+		r.logger.Error("Failed to find developer application by ID", err, "id", id.Hex())
+		return nil, models.NewInternalError(err, "Failed to find developer application")
+	}
+
+	return &app, nil
+}
+
+// FindAppsByOwner finds the developer applications a user registered, oldest first.
+func (r *developerRepository) FindAppsByOwner(ctx context.Context, ownerID bson.ObjectID) ([]*models.DeveloperApp, error) {
+	return r.findApps(ctx, bson.M{"ownerId": ownerID})
+}
+
+// FindAppsByEvent finds the active developer applications subscribed to an event.
+func (r *developerRepository) FindAppsByEvent(ctx context.Context, event models.WebhookEvent) ([]*models.DeveloperApp, error) {
+	return r.findApps(ctx, bson.M{"events": event, "active": true})
+}
+
+// findApps finds the developer applications matching a query, oldest first.
+func (r *developerRepository) findApps(ctx context.Context, query bson.M) ([]*models.DeveloperApp, error) {
+	cursor, err := r.appsCollection.Find(ctx, query, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		r.logger.Error("Failed to find developer applications", err)
+		return nil, models.NewInternalError(err, "Failed to find developer applications")
+	}
+	defer cursor.Close(ctx)
+
+	apps := []*models.DeveloperApp{}
+	if err = cursor.All(ctx, &apps); err != nil {
+		r.logger.Error("Failed to decode developer applications", err)
+		return nil, models.NewInternalError(err, "Failed to decode developer applications")
+	}
+
+	return apps, nil
+}
+
+// UpdateApp updates a developer application.
+func (r *developerRepository) UpdateApp(ctx context.Context, app *models.DeveloperApp) error {
+	app.UpdateNow()
+
+	result, err := r.appsCollection.ReplaceOne(ctx, bson.M{"_id": app.ID}, app)
+	if err != nil {
+		r.logger.Error("Failed to update developer application", err, "id", app.ID.Hex())
+		return models.NewInternalError(err, "Failed to update developer application")
+	}
+	if result.MatchedCount == 0 {
+		return models.ErrDeveloperAppNotFound
+	}
+
+	return nil
+}
+
+// DeleteApp deletes a developer application along with its delivery log.
+func (r *developerRepository) DeleteApp(ctx context.Context, id bson.ObjectID) error {
+	result, err := r.appsCollection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		r.logger.Error("Failed to delete developer application", err, "id", id.Hex())
+		return models.NewInternalError(err, "Failed to delete developer application")
+	}
+	if result.DeletedCount == 0 {
+		return models.ErrDeveloperAppNotFound
+	}
+
+	if _, err := r.deliveriesCollection.DeleteMany(ctx, bson.M{"appId": id}); err != nil {
+		r.logger.Error("Failed to delete webhook deliveries of developer application", err, "id", id.Hex())
+		// Continue anyway, the log is pruned when it expires
+	}
+
+	return nil
+}
+
+// CreateDelivery adds a delivery attempt to an application's delivery log.
+func (r *developerRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if delivery.ID.IsZero() {
+		delivery.ID = bson.NewObjectID()
+	}
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now()
+	}
+
+	_, err := r.deliveriesCollection.InsertOne(ctx, delivery)
+	if err != nil {
+		r.logger.Error("Failed to create webhook delivery", err, "appId", delivery.AppID.Hex())
+		return models.NewInternalError(err, "Failed to create webhook delivery")
+	}
+
+	return nil
+}
+
+// FindDeliveries finds an application's delivery attempts, most recent first, along with the total number.
+func (r *developerRepository) FindDeliveries(ctx context.Context, appID bson.ObjectID, skip, limit int) ([]*models.WebhookDelivery, int64, error) {
+	query := bson.M{"appId": appID}
+
+	total, err := r.deliveriesCollection.CountDocuments(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to count webhook deliveries", err, "appId", appID.Hex())
+		return nil, 0, models.NewInternalError(err, "Failed to count webhook deliveries")
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"createdAt": -1}).
+		SetSkip(int64(skip)).
+		SetLimit(int64(limit))
+
+	cursor, err := r.deliveriesCollection.Find(ctx, query, opts)
+	if err != nil {
+		r.logger.Error("Failed to find webhook deliveries", err, "appId", appID.Hex())
+		return nil, 0, models.NewInternalError(err, "Failed to find webhook deliveries")
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []*models.WebhookDelivery{}
+	if err = cursor.All(ctx, &deliveries); err != nil {
+		r.logger.Error("Failed to decode webhook deliveries", err)
+		return nil, 0, models.NewInternalError(err, "Failed to decode webhook deliveries")
+	}
+
+	return deliveries, total, nil
+}
+
+// DeleteDeliveriesBefore deletes the delivery attempts made before a time, and returns how many it deleted.
+func (r *developerRepository) DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.deliveriesCollection.DeleteMany(ctx, bson.M{"createdAt": bson.M{"$lt": before}})
+	if err != nil {
+		r.logger.Error("Failed to delete old webhook deliveries", err)
+		return 0, models.NewInternalError(err, "Failed to delete webhook deliveries")
+	}
+
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,118 @@
+// Package repositories contains MongoDB repository implementations.
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// Collection names
+const (
+	roomEventsCollection = "room_events"
+)
+
+// EventRepository defines the interface for scheduled room event data access operations.
+type EventRepository interface {
+	CreateEvent(ctx context.Context, event *models.RoomEvent) error
+	FindRoomEvents(ctx context.Context, roomIDs []bson.ObjectID, endAfter time.Time) ([]*models.RoomEvent, error)
+	CountRoomEvents(ctx context.Context, roomID bson.ObjectID, endAfter time.Time) (int64, error)
+	FindEventsStartingBefore(ctx context.Context, startBefore, endAfter time.Time) ([]*models.RoomEvent, error)
+	DeleteEvent(ctx context.Context, roomID, eventID bson.ObjectID) error
+}
+
+// eventRepository is the MongoDB implementation of EventRepository.
+type eventRepository struct {
+	eventsCollection *mongo.Collection
+	logger           *utils.Logger
+}
+
+// NewEventRepository creates a new instance of EventRepository.
+func NewEventRepository(db *mongo.Database, logger *utils.Logger) EventRepository {
+	return &eventRepository{
+		eventsCollection: db.Collection(roomEventsCollection),
+		logger:           logger.Named("event_repository"),
+	}
+}
+
+// CreateEvent schedules a new room event.
+func (r *eventRepository) CreateEvent(ctx context.Context, event *models.RoomEvent) error {
+	if event.ID.IsZero() {
+		event.ID = bson.NewObjectID()
+	}
+
+	_, err := r.eventsCollection.InsertOne(ctx, event)
+	if err != nil {
+		r.logger.Error("Failed to create room event", err, "roomId", event.RoomID.Hex())
+		return models.NewInternalError(err, "Failed to create room event")
+	}
+
+	return nil
+}
+
+// FindRoomEvents finds the events of the given rooms ending after a time, soonest first.
+func (r *eventRepository) FindRoomEvents(ctx context.Context, roomIDs []bson.ObjectID, endAfter time.Time) ([]*models.RoomEvent, error) {
+	filter := bson.M{
+		"roomId":  bson.M{"$in": roomIDs},
+		"endTime": bson.M{"$gt": endAfter},
+	}
+	return r.findEvents(ctx, filter)
+}
+
+// CountRoomEvents counts the events of a room ending after a time.
+func (r *eventRepository) CountRoomEvents(ctx context.Context, roomID bson.ObjectID, endAfter time.Time) (int64, error) {
+	count, err := r.eventsCollection.CountDocuments(ctx, bson.M{"roomId": roomID, "endTime": bson.M{"$gt": endAfter}})
+	if err != nil {
+		r.logger.Error("Failed to count room events", err, "roomId", roomID.Hex())
+		return 0, models.NewInternalError(err, "Failed to count room events")
+	}
+
+	return count, nil
+}
+
+// FindEventsStartingBefore finds the events of every room starting before a time and ending after
+// another, soonest first.
+func (r *eventRepository) FindEventsStartingBefore(ctx context.Context, startBefore, endAfter time.Time) ([]*models.RoomEvent, error) {
+	filter := bson.M{
+		"startTime": bson.M{"$lte": startBefore},
+		"endTime":   bson.M{"$gt": endAfter},
+	}
+	return r.findEvents(ctx, filter)
+}
+
+// DeleteEvent cancels a room event.
+func (r *eventRepository) DeleteEvent(ctx context.Context, roomID, eventID bson.ObjectID) error {
+	result, err := r.eventsCollection.DeleteOne(ctx, bson.M{"_id": eventID, "roomId": roomID})
+	if err != nil {
+		r.logger.Error("Failed to delete room event", err, "roomId", roomID.Hex(), "eventId", eventID.Hex())
+		return models.NewInternalError(err, "Failed to delete room event")
+	}
+	if result.DeletedCount == 0 {
+		return models.ErrRoomEventNotFound
+	}
+
+	return nil
+}
+
+// findEvents finds the events matching a filter, soonest first.
+func (r *eventRepository) findEvents(ctx context.Context, filter bson.M) ([]*models.RoomEvent, error) {
+	cursor, err := r.eventsCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "startTime", Value: 1}}))
+	if err != nil {
+		r.logger.Error("Failed to find room events", err)
+		return nil, models.NewInternalError(err, "Failed to find room events")
+	}
+	defer cursor.Close(ctx)
+
+	events := []*models.RoomEvent{}
+	if err = cursor.All(ctx, &events); err != nil {
+		r.logger.Error("Failed to decode room events", err)
+		return nil, models.NewInternalError(err, "Failed to decode room events")
+	}
+
+	return events, nil
+}
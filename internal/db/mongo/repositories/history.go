@@ -24,6 +24,9 @@ const (
 	histModerationHistoryCollection = "moderation_history"
 )
 
+// playedSong groups plays by song, falling back to the media for plays recorded without a song ID.
+var playedSong = bson.M{"$ifNull": bson.A{"$songId", "$mediaId"}}
+
 // HistoryRepository defines the interface for history data access operations.
 type HistoryRepository interface {
 	// Generic history operations
@@ -35,10 +38,13 @@ type HistoryRepository interface {
 	// Play history operations
 	CreatePlayHistory(ctx context.Context, playHistory *models.PlayHistory) error
 	FindPlayHistoryByID(ctx context.Context, id bson.ObjectID) (*models.PlayHistory, error)
+	UpdatePlayHistory(ctx context.Context, playHistory *models.PlayHistory) error
 	FindPlayHistoryByRoom(ctx context.Context, roomID bson.ObjectID, skip, limit int) ([]*models.PlayHistory, error)
 	FindPlayHistoryByDJ(ctx context.Context, djID bson.ObjectID, skip, limit int) ([]*models.PlayHistory, error)
 	FindPlayHistoryByMedia(ctx context.Context, mediaID bson.ObjectID, skip, limit int) ([]*models.PlayHistory, error)
 	GetPlayHistorySummary(ctx context.Context, roomID bson.ObjectID) (*models.HistorySummary, error)
+	UpsertImportedPlay(ctx context.Context, playHistory *models.PlayHistory) (bool, error)
+	StreamPlayHistoryByDJ(ctx context.Context, djID, after, before bson.ObjectID, batchSize int, fn func(*models.PlayHistory) error) error
 
 	// User history operations
 	CreateUserHistory(ctx context.Context, userHistory *models.UserHistory) error
@@ -71,6 +77,12 @@ type HistoryRepository interface {
 	// Statistics operations
 	GetTopTracks(ctx context.Context, roomID bson.ObjectID, limit int) ([]models.TopTrackSummary, error)
 	GetTopDJs(ctx context.Context, roomID bson.ObjectID, limit int) ([]models.TopDJSummary, error)
+	GetRotationStats(ctx context.Context, roomID bson.ObjectID, since, until time.Time) ([]models.DJRotationStats, error)
+
+	// Account operations
+	CountUserRecords(ctx context.Context, userID bson.ObjectID) (int64, error)
+	ReassignUser(ctx context.Context, fromID, toID bson.ObjectID) (int64, error)
+	AnonymizeUser(ctx context.Context, userID bson.ObjectID) (int64, error)
 }
 
 // historyRepository is the MongoDB implementation of HistoryRepository.
@@ -109,6 +121,10 @@ func (r *historyRepository) CreateHistory(ctx context.Context, history *models.H
 		history.Timestamp = time.Now()
 	}
 
+	if history.TraceID == "" {
+		history.TraceID = utils.TraceIDFromContext(ctx)
+	}
+
 	_, err := r.historyCollection.InsertOne(ctx, history)
 	if err != nil {
 		r.logger.Error("Failed to create history record", err, "type", history.Type)
@@ -234,7 +250,7 @@ func (r *historyRepository) FindPlayHistoryByID(ctx context.Context, id bson.Obj
 	err := r.playHistoryCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&playHistory)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("play history not found")
+			return nil, models.ErrPlayHistoryNotFound
 		}
 		r.logger.Error("Failed to find play history by ID", err, "id", id.Hex())
 		return nil, models.NewInternalError(err, "Failed to find play history")
@@ -243,6 +259,59 @@ func (r *historyRepository) FindPlayHistoryByID(ctx context.Context, id bson.Obj
 	return &playHistory, nil
 }
 
+// UpdatePlayHistory updates how a play ended. Votes are recorded separately and left alone.
+func (r *historyRepository) UpdatePlayHistory(ctx context.Context, playHistory *models.PlayHistory) error {
+	update := bson.D{cmdSet(bson.M{
+		"endTime":     playHistory.EndTime,
+		"duration":    playHistory.Duration,
+		"skipped":     playHistory.Skipped,
+		"skipReason":  playHistory.SkipReason,
+		"skippedBy":   playHistory.SkippedBy,
+		"interrupted": playHistory.Interrupted,
+		"truncated":   playHistory.Truncated,
+
+		// Votes are counted in Redis while the media plays and kept on the play once it ends
+		"votes.woots":     playHistory.Votes.Woots,
+		"votes.mehs":      playHistory.Votes.Mehs,
+		"votes.grabs":     playHistory.Votes.Grabs,
+		"votes.reactions": playHistory.Votes.Reactions,
+	})}
+
+	result, err := r.playHistoryCollection.UpdateByID(ctx, playHistory.ID, update)
+	if err != nil {
+		r.logger.Error("Failed to update play history", err, "id", playHistory.ID.Hex())
+		return models.NewInternalError(err, "Failed to update play history")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrPlayHistoryNotFound
+	}
+
+	return nil
+}
+
+// UpsertImportedPlay creates or replaces a play imported from another platform, keyed by its room and external ID.
+// It returns whether the play was created.
+func (r *historyRepository) UpsertImportedPlay(ctx context.Context, playHistory *models.PlayHistory) (bool, error) {
+	if playHistory.Votes.Voters == nil {
+		playHistory.Votes.Voters = make(map[string]string)
+	}
+
+	// A replaced play keeps its ID, a created one gets a new ID from the upsert
+	playHistory.ID = bson.ObjectID{}
+	filter := bson.M{"roomId": playHistory.RoomID, "externalId": playHistory.ExternalID}
+	result, err := r.playHistoryCollection.ReplaceOne(ctx, filter, playHistory, options.Replace().SetUpsert(true))
+	if err != nil {
+		r.logger.Error("Failed to upsert imported play", err, "roomId", playHistory.RoomID.Hex(), "externalId", playHistory.ExternalID)
+		return false, models.NewInternalError(err, "Failed to import play history")
+	}
+
+	if id, ok := result.UpsertedID.(bson.ObjectID); ok {
+		playHistory.ID = id
+	}
+	return result.UpsertedCount > 0, nil
+}
+
 // FindPlayHistoryByRoom finds play history records for a room.
 func (r *historyRepository) FindPlayHistoryByRoom(ctx context.Context, roomID bson.ObjectID, skip, limit int) ([]*models.PlayHistory, error) {
 	opts := options.Find().
@@ -289,6 +358,40 @@ func (r *historyRepository) FindPlayHistoryByDJ(ctx context.Context, djID bson.O
 	return playHistories, nil
 }
 
+// StreamPlayHistoryByDJ passes a DJ's play history records with IDs between after and before to fn, in ID
+// order, one at a time. Records are read from the cursor batchSize at a time as fn takes them, so a slow
+// fn holds back reading instead of records piling up in memory. An error from fn stops the stream and is
+// returned as is.
+func (r *historyRepository) StreamPlayHistoryByDJ(ctx context.Context, djID, after, before bson.ObjectID, batchSize int, fn func(*models.PlayHistory) error) error {
+	filter := bson.M{"djId": djID, "_id": bson.M{"$gt": after, "$lt": before}}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetBatchSize(int32(batchSize))
+
+	cursor, err := r.playHistoryCollection.Find(ctx, filter, opts)
+	if err != nil {
+		r.logger.Error("Failed to stream play history by DJ", err, "djId", djID.Hex())
+		return models.NewInternalError(err, "Failed to find play history")
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var playHistory models.PlayHistory
+		if err := cursor.Decode(&playHistory); err != nil {
+			r.logger.Error("Failed to decode play history record", err)
+			return models.NewInternalError(err, "Failed to decode play history")
+		}
+		if err := fn(&playHistory); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return models.NewInternalError(err, "Failed to read play history")
+	}
+
+	return nil
+}
+
 // FindPlayHistoryByMedia finds play history records for a media item.
 func (r *historyRepository) FindPlayHistoryByMedia(ctx context.Context, mediaID bson.ObjectID, skip, limit int) ([]*models.PlayHistory, error) {
 	opts := options.Find().
@@ -325,7 +428,7 @@ func (r *historyRepository) GetPlayHistorySummary(ctx context.Context, roomID bs
 	uniqueTracksPipeline := mongo.Pipeline{
 		{cmdMatch(bson.M{"roomId": roomID})},
 		{cmdGroup(bson.M{
-			"_id":   "$mediaId",
+			"_id":   playedSong,
 			"count": bson.M{"$sum": 1},
 		})},
 		{cmdCount("count")},
@@ -903,6 +1006,10 @@ func (r *historyRepository) CreateModerationHistory(ctx context.Context, moderat
 		moderationHistory.Timestamp = time.Now()
 	}
 
+	if moderationHistory.TraceID == "" {
+		moderationHistory.TraceID = utils.TraceIDFromContext(ctx)
+	}
+
 	_, err := r.moderationHistoryCollection.InsertOne(ctx, moderationHistory)
 	if err != nil {
 		r.logger.Error("Failed to create moderation history", err, "moderatorId", moderationHistory.ModeratorID.Hex(), "targetUserId", moderationHistory.TargetUserID.Hex())
@@ -1000,12 +1107,12 @@ func (r *historyRepository) FindModerationHistoryByUser(ctx context.Context, use
 	return moderationHistories, nil
 }
 
-// GetTopTracks gets the most played tracks in a room.
+// GetTopTracks gets the most played tracks in a room, counting every provider's copy of a song together.
 func (r *historyRepository) GetTopTracks(ctx context.Context, roomID bson.ObjectID, limit int) ([]models.TopTrackSummary, error) {
 	pipeline := mongo.Pipeline{
 		{cmdMatch(bson.M{"roomId": roomID})},
 		{cmdGroup(bson.M{
-			"_id":         "$mediaId",
+			"_id":         playedSong,
 			"playCount":   bson.M{"$sum": 1},
 			"wootCount":   bson.M{"$sum": "$votes.woots"},
 			"mehCount":    bson.M{"$sum": "$votes.mehs"},
@@ -1177,3 +1284,184 @@ func (r *historyRepository) GetTopDJs(ctx context.Context, roomID bson.ObjectID,
 
 	return topDJs, nil
 }
+
+// GetRotationStats breaks down the DJ turns and plays of a room that started within a time window per DJ.
+// The DJs are sorted by the number of turns they received, most first.
+func (r *historyRepository) GetRotationStats(ctx context.Context, roomID bson.ObjectID, since, until time.Time) ([]models.DJRotationStats, error) {
+	window := bson.M{"$gte": since, "$lt": until}
+
+	turnsPipeline := mongo.Pipeline{
+		{cmdMatch(bson.M{"roomId": roomID, "startTime": window})},
+		{cmdGroup(bson.M{
+			"_id":         "$userId",
+			"turns":       bson.M{"$sum": 1},
+			"averageWait": bson.M{"$avg": "$waitTime"},
+			"longestWait": bson.M{"$max": "$waitTime"},
+		})},
+	}
+
+	turnsCursor, err := r.djHistoryCollection.Aggregate(ctx, turnsPipeline)
+	if err != nil {
+		r.logger.Error("Failed to aggregate DJ turns", err, "roomId", roomID.Hex())
+		return nil, models.NewInternalError(err, "Failed to calculate DJ rotation")
+	}
+	defer turnsCursor.Close(ctx)
+
+	var turnStats []struct {
+		ID          bson.ObjectID `bson:"_id"`
+		Turns       int           `bson:"turns"`
+		AverageWait float64       `bson:"averageWait"`
+		LongestWait int           `bson:"longestWait"`
+	}
+	if err = turnsCursor.All(ctx, &turnStats); err != nil {
+		r.logger.Error("Failed to decode DJ turns", err, "roomId", roomID.Hex())
+		return nil, models.NewInternalError(err, "Failed to calculate DJ rotation")
+	}
+
+	playsPipeline := mongo.Pipeline{
+		{cmdMatch(bson.M{"roomId": roomID, "startTime": window})},
+		{cmdSort(bson.D{{Key: "startTime", Value: 1}})},
+		{cmdGroup(bson.M{
+			"_id":      "$djId",
+			"plays":    bson.M{"$sum": 1},
+			"skips":    bson.M{"$sum": bson.M{"$cond": []any{bson.M{"$eq": []any{"$skipped", true}}, 1, 0}}},
+			"username": bson.M{"$last": "$dj.username"},
+		})},
+	}
+
+	playsCursor, err := r.playHistoryCollection.Aggregate(ctx, playsPipeline)
+	if err != nil {
+		r.logger.Error("Failed to aggregate DJ plays", err, "roomId", roomID.Hex())
+		return nil, models.NewInternalError(err, "Failed to calculate DJ rotation")
+	}
+	defer playsCursor.Close(ctx)
+
+	var playStats []struct {
+		ID       bson.ObjectID `bson:"_id"`
+		Plays    int           `bson:"plays"`
+		Skips    int           `bson:"skips"`
+		Username string        `bson:"username"`
+	}
+	if err = playsCursor.All(ctx, &playStats); err != nil {
+		r.logger.Error("Failed to decode DJ plays", err, "roomId", roomID.Hex())
+		return nil, models.NewInternalError(err, "Failed to calculate DJ rotation")
+	}
+
+	// The turns and the plays come from two collections, so they are combined here
+	djs := make(map[bson.ObjectID]*models.DJRotationStats)
+	for _, stat := range turnStats {
+		djs[stat.ID] = &models.DJRotationStats{
+			UserID:      stat.ID,
+			Turns:       stat.Turns,
+			AverageWait: stat.AverageWait,
+			LongestWait: stat.LongestWait,
+		}
+	}
+	for _, stat := range playStats {
+		dj, ok := djs[stat.ID]
+		if !ok {
+			dj = &models.DJRotationStats{UserID: stat.ID}
+			djs[stat.ID] = dj
+		}
+		dj.Username = stat.Username
+		dj.Plays = stat.Plays
+		dj.Skips = stat.Skips
+	}
+
+	return sortRotationStats(djs), nil
+}
+
+// sortRotationStats lists DJ rotation stats by the number of turns received, most first, then by plays.
+func sortRotationStats(djs map[bson.ObjectID]*models.DJRotationStats) []models.DJRotationStats {
+	stats := make([]models.DJRotationStats, 0, len(djs))
+	for _, dj := range djs {
+		stats = append(stats, *dj)
+	}
+
+	utils.SortSlice(stats, func(i, j int) bool {
+		if stats[i].Turns != stats[j].Turns {
+			return stats[i].Turns > stats[j].Turns
+		}
+		return stats[i].Plays > stats[j].Plays
+	})
+
+	return stats
+}
+
+// userRecordCollections lists the collections holding a user's own history, with the field that references the user.
+// Moderation history is left out so moderation records keep pointing at the account they were taken against.
+func (r *historyRepository) userRecordCollections() map[*mongo.Collection]string {
+	return map[*mongo.Collection]string{
+		r.playHistoryCollection:    "djId",
+		r.userHistoryCollection:    "userId",
+		r.djHistoryCollection:      "userId",
+		r.sessionHistoryCollection: "userId",
+	}
+}
+
+// CountUserRecords counts the play, user, DJ and session history records belonging to a user.
+func (r *historyRepository) CountUserRecords(ctx context.Context, userID bson.ObjectID) (int64, error) {
+	var total int64
+	for collection, field := range r.userRecordCollections() {
+		count, err := collection.CountDocuments(ctx, bson.M{field: userID})
+		if err != nil {
+			r.logger.Error("Failed to count user history records", err, "collection", collection.Name(), "userId", userID.Hex())
+			return total, models.NewInternalError(err, "Failed to count user history")
+		}
+		total += count
+	}
+
+	return total, nil
+}
+
+// ReassignUser moves a user's play, user, DJ and session history records to another user.
+func (r *historyRepository) ReassignUser(ctx context.Context, fromID, toID bson.ObjectID) (int64, error) {
+	var total int64
+	for collection, field := range r.userRecordCollections() {
+		result, err := collection.UpdateMany(ctx, bson.M{field: fromID}, bson.D{cmdSet(bson.M{field: toID})})
+		if err != nil {
+			r.logger.Error("Failed to reassign user history records", err, "collection", collection.Name(), "fromId", fromID.Hex(), "toId", toID.Hex())
+			return total, models.NewInternalError(err, "Failed to reassign user history")
+		}
+		total += result.ModifiedCount
+	}
+
+	return total, nil
+}
+
+// AnonymizeUser detaches a deleted user's play, DJ and room history from their account, so rooms keep their history
+// without it pointing at anyone, and deletes the user and session history about them. Moderation history is left out.
+func (r *historyRepository) AnonymizeUser(ctx context.Context, userID bson.ObjectID) (int64, error) {
+	anonymous := models.PublicUser{BaseUser: models.BaseUser{Username: models.DeletedUsername}}
+	updates := []struct {
+		collection *mongo.Collection
+		filter     bson.M
+		update     bson.D
+	}{
+		{r.playHistoryCollection, bson.M{"djId": userID}, bson.D{cmdSet(bson.M{"djId": bson.NilObjectID, "dj": anonymous})}},
+		{r.playHistoryCollection, bson.M{"skippedBy": userID}, bson.D{cmdUnset(bson.M{"skippedBy": ""})}},
+		{r.djHistoryCollection, bson.M{"userId": userID}, bson.D{cmdSet(bson.M{"userId": bson.NilObjectID})}},
+		{r.roomHistoryCollection, bson.M{"userId": userID}, bson.D{cmdUnset(bson.M{"userId": ""})}},
+	}
+
+	var total int64
+	for _, u := range updates {
+		result, err := u.collection.UpdateMany(ctx, u.filter, u.update)
+		if err != nil {
+			r.logger.Error("Failed to anonymize user history records", err, "collection", u.collection.Name(), "userId", userID.Hex())
+			return total, models.NewInternalError(err, "Failed to anonymize user history")
+		}
+		total += result.ModifiedCount
+	}
+
+	for _, collection := range []*mongo.Collection{r.userHistoryCollection, r.sessionHistoryCollection} {
+		result, err := collection.DeleteMany(ctx, bson.M{"userId": userID})
+		if err != nil {
+			r.logger.Error("Failed to delete user history records", err, "collection", collection.Name(), "userId", userID.Hex())
+			return total, models.NewInternalError(err, "Failed to anonymize user history")
+		}
+		total += result.DeletedCount
+	}
+
+	return total, nil
+}
@@ -0,0 +1,264 @@
+// Package repositories contains MongoDB repository implementations.
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// Collection names
+const (
+	imageReviewsCollection  = "image_reviews"
+	blockedImagesCollection = "blocked_images"
+)
+
+// ImageReviewRepository defines the interface for image review and blocked image data access operations.
+type ImageReviewRepository interface {
+	CreateImageReview(ctx context.Context, review *models.ImageReview) error
+	FindImageReviewByID(ctx context.Context, id bson.ObjectID) (*models.ImageReview, error)
+	FindImageReviews(ctx context.Context, filter models.ImageReviewFilter, skip, limit int) ([]*models.ImageReview, int64, error)
+	FindUncheckedImageReviews(ctx context.Context, limit int) ([]*models.ImageReview, error)
+	SetImageReviewHash(ctx context.Context, id bson.ObjectID, hash string) error
+	ResolveImageReview(ctx context.Context, id bson.ObjectID, status models.ImageReviewStatus, reason string, reviewedBy, matchedImageID bson.ObjectID) (*models.ImageReview, error)
+	DeleteOwnerImageReviews(ctx context.Context, ownerID bson.ObjectID) (int64, error)
+	CreateBlockedImage(ctx context.Context, image *models.BlockedImage) error
+	FindBlockedImages(ctx context.Context) ([]*models.BlockedImage, error)
+	DeleteBlockedImage(ctx context.Context, id bson.ObjectID) error
+}
+
+// imageReviewRepository is the MongoDB implementation of ImageReviewRepository.
+type imageReviewRepository struct {
+	reviewsCollection *mongo.Collection
+	blockedCollection *mongo.Collection
+	logger            *utils.Logger
+}
+
+// NewImageReviewRepository creates a new instance of ImageReviewRepository.
+func NewImageReviewRepository(db *mongo.Database, logger *utils.Logger) ImageReviewRepository {
+	return &imageReviewRepository{
+		reviewsCollection: db.Collection(imageReviewsCollection),
+		blockedCollection: db.Collection(blockedImagesCollection),
+		logger:            logger.Named("image_review_repository"),
+	}
+}
+
+// CreateImageReview queues a changed image for review.
+func (r *imageReviewRepository) CreateImageReview(ctx context.Context, review *models.ImageReview) error {
+	if review.ID.IsZero() {
+		review.ID = bson.NewObjectID()
+	}
+	review.CreateNow()
+
+	_, err := r.reviewsCollection.InsertOne(ctx, review)
+	if err != nil {
+		r.logger.Error("Failed to create image review", err, "kind", review.Kind, "targetId", review.TargetID.Hex())
+		return models.NewInternalError(err, "Failed to create image review")
+	}
+
+	return nil
+}
+
+// FindImageReviewByID finds an image review by its ID.
+func (r *imageReviewRepository) FindImageReviewByID(ctx context.Context, id bson.ObjectID) (*models.ImageReview, error) {
+	var review models.ImageReview
+
+	err := r.reviewsCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&review)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, models.ErrImageReviewNotFound
+		}
+		r.logger.Error("Failed to find image review by ID", err, "id", id.Hex())
+		return nil, models.NewInternalError(err, "Failed to find image review")
+	}
+
+	return &review, nil
+}
+
+// FindImageReviews finds image reviews, most recently changed first, along with the total number matching.
+func (r *imageReviewRepository) FindImageReviews(ctx context.Context, filter models.ImageReviewFilter, skip, limit int) ([]*models.ImageReview, int64, error) {
+	query := imageReviewQuery(filter)
+
+	total, err := r.reviewsCollection.CountDocuments(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to count image reviews", err)
+		return nil, 0, models.NewInternalError(err, "Failed to count image reviews")
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"createdAt": -1}).
+		SetSkip(int64(skip)).
+		SetLimit(int64(limit))
+
+	cursor, err := r.reviewsCollection.Find(ctx, query, opts)
+	if err != nil {
+		r.logger.Error("Failed to find image reviews", err)
+		return nil, 0, models.NewInternalError(err, "Failed to find image reviews")
+	}
+	defer cursor.Close(ctx)
+
+	reviews := []*models.ImageReview{}
+	if err = cursor.All(ctx, &reviews); err != nil {
+		r.logger.Error("Failed to decode image reviews", err)
+		return nil, 0, models.NewInternalError(err, "Failed to decode image reviews")
+	}
+
+	return reviews, total, nil
+}
+
+// FindUncheckedImageReviews finds the pending reviews whose image wasn't matched against the blocked images yet, oldest first.
+func (r *imageReviewRepository) FindUncheckedImageReviews(ctx context.Context, limit int) ([]*models.ImageReview, error) {
+	opts := options.Find().
+		SetSort(bson.M{"createdAt": 1}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.reviewsCollection.Find(ctx, bson.M{"status": models.ImageReviewPending, "checked": false}, opts)
+	if err != nil {
+		r.logger.Error("Failed to find unchecked image reviews", err)
+		return nil, models.NewInternalError(err, "Failed to find image reviews")
+	}
+	defer cursor.Close(ctx)
+
+	reviews := []*models.ImageReview{}
+	if err = cursor.All(ctx, &reviews); err != nil {
+		r.logger.Error("Failed to decode image reviews", err)
+		return nil, models.NewInternalError(err, "Failed to decode image reviews")
+	}
+
+	return reviews, nil
+}
+
+// SetImageReviewHash marks a review's image as checked, with its perceptual hash if it could be computed.
+func (r *imageReviewRepository) SetImageReviewHash(ctx context.Context, id bson.ObjectID, hash string) error {
+	set := bson.M{"checked": true, "updatedAt": time.Now()}
+	if hash != "" {
+		set["hash"] = hash
+	}
+
+	_, err := r.reviewsCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.D{cmdSet(set)})
+	if err != nil {
+		r.logger.Error("Failed to set image review hash", err, "id", id.Hex())
+		return models.NewInternalError(err, "Failed to update image review")
+	}
+
+	return nil
+}
+
+// ResolveImageReview records the outcome of a pending image review and returns the resolved review.
+// The matched image is zero unless a blocked image removed the image.
+func (r *imageReviewRepository) ResolveImageReview(ctx context.Context, id bson.ObjectID, status models.ImageReviewStatus, reason string, reviewedBy, matchedImageID bson.ObjectID) (*models.ImageReview, error) {
+	now := time.Now()
+	set := bson.M{
+		"status":     status,
+		"reason":     reason,
+		"reviewedAt": now,
+		"updatedAt":  now,
+	}
+	if !reviewedBy.IsZero() {
+		set["reviewedBy"] = reviewedBy
+	}
+	if !matchedImageID.IsZero() {
+		set["matchedImageId"] = matchedImageID
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var review models.ImageReview
+	err := r.reviewsCollection.FindOneAndUpdate(ctx, bson.M{"_id": id, "status": models.ImageReviewPending}, bson.D{cmdSet(set)}, opts).Decode(&review)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// Tell a missing review apart from one another admin got to first
+			if _, findErr := r.FindImageReviewByID(ctx, id); findErr != nil {
+				return nil, findErr
+			}
+			return nil, models.ErrImageReviewed
+		}
+		r.logger.Error("Failed to resolve image review", err, "id", id.Hex())
+		return nil, models.NewInternalError(err, "Failed to resolve image review")
+	}
+
+	return &review, nil
+}
+
+// DeleteOwnerImageReviews deletes the reviews of a user's images, and returns how many it deleted.
+func (r *imageReviewRepository) DeleteOwnerImageReviews(ctx context.Context, ownerID bson.ObjectID) (int64, error) {
+	result, err := r.reviewsCollection.DeleteMany(ctx, bson.M{"ownerId": ownerID})
+	if err != nil {
+		r.logger.Error("Failed to delete image reviews", err, "ownerId", ownerID.Hex())
+		return 0, models.NewInternalError(err, "Failed to delete image reviews")
+	}
+
+	return result.DeletedCount, nil
+}
+
+// CreateBlockedImage adds an image to the blocked images. An image can only be blocked once.
+func (r *imageReviewRepository) CreateBlockedImage(ctx context.Context, image *models.BlockedImage) error {
+	if image.ID.IsZero() {
+		image.ID = bson.NewObjectID()
+	}
+	image.CreateNow()
+
+	_, err := r.blockedCollection.InsertOne(ctx, image)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrImageAlreadyBlocked
+		}
+		r.logger.Error("Failed to create blocked image", err, "hash", image.Hash)
+		return models.NewInternalError(err, "Failed to create blocked image")
+	}
+
+	return nil
+}
+
+// FindBlockedImages finds every blocked image, most recently blocked first.
+func (r *imageReviewRepository) FindBlockedImages(ctx context.Context) ([]*models.BlockedImage, error) {
+	cursor, err := r.blockedCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"createdAt": -1}))
+	if err != nil {
+		r.logger.Error("Failed to find blocked images", err)
+		return nil, models.NewInternalError(err, "Failed to find blocked images")
+	}
+	defer cursor.Close(ctx)
+
+	images := []*models.BlockedImage{}
+	if err = cursor.All(ctx, &images); err != nil {
+		r.logger.Error("Failed to decode blocked images", err)
+		return nil, models.NewInternalError(err, "Failed to decode blocked images")
+	}
+
+	return images, nil
+}
+
+// DeleteBlockedImage removes an image from the blocked images.
+func (r *imageReviewRepository) DeleteBlockedImage(ctx context.Context, id bson.ObjectID) error {
+	result, err := r.blockedCollection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		r.logger.Error("Failed to delete blocked image", err, "id", id.Hex())
+		return models.NewInternalError(err, "Failed to delete blocked image")
+	}
+	if result.DeletedCount == 0 {
+		return models.ErrBlockedImageNotFound
+	}
+
+	return nil
+}
+
+// imageReviewQuery builds the query for an image review filter.
+func imageReviewQuery(filter models.ImageReviewFilter) bson.M {
+	query := bson.M{}
+	if filter.Kind != "" {
+		query["kind"] = filter.Kind
+	}
+	if !filter.OwnerID.IsZero() {
+		query["ownerId"] = filter.OwnerID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	return query
+}
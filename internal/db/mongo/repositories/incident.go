@@ -0,0 +1,166 @@
+// Package repositories contains MongoDB repository implementations.
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// Collection names
+const (
+	incidentsCollection = "incidents"
+)
+
+// IncidentRepository defines the interface for status page incident data access operations.
+type IncidentRepository interface {
+	CreateIncident(ctx context.Context, incident *models.Incident) error
+	FindIncidentByID(ctx context.Context, id bson.ObjectID) (*models.Incident, error)
+	FindIncidents(ctx context.Context, filter models.IncidentFilter, skip, limit int) ([]*models.Incident, int64, error)
+	FindActiveAutomaticIncident(ctx context.Context, component string) (*models.Incident, error)
+	UpdateIncident(ctx context.Context, incident *models.Incident) error
+	DeleteIncident(ctx context.Context, id bson.ObjectID) error
+}
+
+// incidentRepository is the MongoDB implementation of IncidentRepository.
+type incidentRepository struct {
+	incidentsCollection *mongo.Collection
+	logger              *utils.Logger
+}
+
+// NewIncidentRepository creates a new instance of IncidentRepository.
+func NewIncidentRepository(db *mongo.Database, logger *utils.Logger) IncidentRepository {
+	return &incidentRepository{
+		incidentsCollection: db.Collection(incidentsCollection),
+		logger:              logger.Named("incident_repository"),
+	}
+}
+
+// CreateIncident records a new incident.
+func (r *incidentRepository) CreateIncident(ctx context.Context, incident *models.Incident) error {
+	if incident.ID.IsZero() {
+		incident.ID = bson.NewObjectID()
+	}
+	incident.CreateNow()
+
+	_, err := r.incidentsCollection.InsertOne(ctx, incident)
+	if err != nil {
+		r.logger.Error("Failed to create incident", err, "title", incident.Title)
+		return models.NewInternalError(err, "Failed to create incident")
+	}
+
+	return nil
+}
+
+// FindIncidentByID finds an incident by its ID.
+func (r *incidentRepository) FindIncidentByID(ctx context.Context, id bson.ObjectID) (*models.Incident, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+// FindActiveAutomaticIncident finds the unresolved incident opened automatically for a component.
+func (r *incidentRepository) FindActiveAutomaticIncident(ctx context.Context, component string) (*models.Incident, error) {
+	return r.findOne(ctx, bson.M{
+		"automatic":  true,
+		"components": component,
+		"status":     bson.M{"$ne": models.IncidentResolved},
+	})
+}
+
+// findOne finds the incident matching a query.
+func (r *incidentRepository) findOne(ctx context.Context, query bson.M) (*models.Incident, error) {
+	var incident models.Incident
+
+	err := r.incidentsCollection.FindOne(ctx, query).Decode(&incident)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, models.ErrIncidentNotFound
+		}
+		r.logger.Error("Failed to find incident", err)
+		return nil, models.NewInternalError(err, "Failed to find incident")
+	}
+
+	return &incident, nil
+}
+
+// FindIncidents finds incidents, latest started first, along with the total number matching.
+func (r *incidentRepository) FindIncidents(ctx context.Context, filter models.IncidentFilter, skip, limit int) ([]*models.Incident, int64, error) {
+	query := incidentQuery(filter)
+
+	total, err := r.incidentsCollection.CountDocuments(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to count incidents", err)
+		return nil, 0, models.NewInternalError(err, "Failed to count incidents")
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"startedAt": -1}).
+		SetSkip(int64(skip)).
+		SetLimit(int64(limit))
+
+	cursor, err := r.incidentsCollection.Find(ctx, query, opts)
+	if err != nil {
+		r.logger.Error("Failed to find incidents", err)
+		return nil, 0, models.NewInternalError(err, "Failed to find incidents")
+	}
+	defer cursor.Close(ctx)
+
+	incidents := []*models.Incident{}
+	if err = cursor.All(ctx, &incidents); err != nil {
+		r.logger.Error("Failed to decode incidents", err)
+		return nil, 0, models.NewInternalError(err, "Failed to decode incidents")
+	}
+
+	return incidents, total, nil
+}
+
+// UpdateIncident stores a modified incident.
+func (r *incidentRepository) UpdateIncident(ctx context.Context, incident *models.Incident) error {
+	incident.UpdateNow()
+
+	result, err := r.incidentsCollection.ReplaceOne(ctx, bson.M{"_id": incident.ID}, incident)
+	if err != nil {
+		r.logger.Error("Failed to update incident", err, "id", incident.ID.Hex())
+		return models.NewInternalError(err, "Failed to update incident")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrIncidentNotFound
+	}
+
+	return nil
+}
+
+// DeleteIncident deletes an incident.
+func (r *incidentRepository) DeleteIncident(ctx context.Context, id bson.ObjectID) error {
+	result, err := r.incidentsCollection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		r.logger.Error("Failed to delete incident", err, "id", id.Hex())
+		return models.NewInternalError(err, "Failed to delete incident")
+	}
+
+	if result.DeletedCount == 0 {
+		return models.ErrIncidentNotFound
+	}
+
+	return nil
+}
+
+// incidentQuery builds the query for an incident filter.
+func incidentQuery(filter models.IncidentFilter) bson.M {
+	query := bson.M{}
+	if filter.Active {
+		query["status"] = bson.M{"$ne": models.IncidentResolved}
+	}
+	if !filter.Since.IsZero() {
+		query["$or"] = bson.A{
+			bson.M{"resolvedAt": bson.M{"$exists": false}},
+			bson.M{"resolvedAt": bson.M{"$gte": filter.Since}},
+		}
+	}
+	return query
+}
@@ -17,6 +17,7 @@ import (
 const (
 	mediaCollection       = "media"
 	playHistoryCollection = "play_history"
+	mediaTagsCollection   = "media_tags"
 )
 
 // MediaRepository defines the interface for media data access operations.
@@ -39,16 +40,28 @@ type MediaRepository interface {
 	UpdateStats(ctx context.Context, id bson.ObjectID, updates bson.M) error
 	RecordPlay(ctx context.Context, playHistory *models.PlayHistory) error
 	FindPlayHistory(ctx context.Context, filter bson.M, opts options.Lister[options.FindOptions]) ([]*models.PlayHistory, error)
+	SetPlaysSong(ctx context.Context, mediaID, songID bson.ObjectID) (int64, error)
 
 	// Media vote operations
 	RecordVote(ctx context.Context, mediaID, userID bson.ObjectID, roomID bson.ObjectID, voteType string) error
 	GetMediaVotes(ctx context.Context, mediaID, roomID bson.ObjectID) (*models.MediaVotes, error)
+
+	// Media tag operations
+	SaveTag(ctx context.Context, tag *models.MediaTag) error
+	FindTags(ctx context.Context, mediaID bson.ObjectID) ([]*models.MediaTag, error)
+	SetVibe(ctx context.Context, mediaID bson.ObjectID, vibe *models.MediaVibe) error
+	SetVerifiedBadge(ctx context.Context, mediaID bson.ObjectID, badge *models.VerifiedBadge) error
+	SetTakedown(ctx context.Context, mediaID, takedownID bson.ObjectID) error
+
+	// Media lyrics operations
+	SetLyricsChecked(ctx context.Context, mediaID bson.ObjectID, hasLyrics bool) error
 }
 
 // mediaRepository is the MongoDB implementation of MediaRepository.
 type mediaRepository struct {
 	mediaCollection       *mongo.Collection
 	playHistoryCollection *mongo.Collection
+	tagsCollection        *mongo.Collection
 	logger                *utils.Logger
 }
 
@@ -57,6 +70,7 @@ func NewMediaRepository(db *mongo.Database, logger *utils.Logger) MediaRepositor
 	return &mediaRepository{
 		mediaCollection:       db.Collection(mediaCollection),
 		playHistoryCollection: db.Collection(playHistoryCollection),
+		tagsCollection:        db.Collection(mediaTagsCollection),
 		logger:                logger.Named("media_repository"),
 	}
 }
@@ -359,6 +373,21 @@ func (r *mediaRepository) FindPlayHistory(ctx context.Context, filter bson.M, op
 	return playHistory, nil
 }
 
+// SetPlaysSong sets the song every play of a media item counts towards, after the media was linked to
+// another provider's copy of the song. It returns the number of plays updated.
+func (r *mediaRepository) SetPlaysSong(ctx context.Context, mediaID, songID bson.ObjectID) (int64, error) {
+	result, err := r.playHistoryCollection.UpdateMany(ctx, bson.M{
+		"mediaId": mediaID,
+		"songId":  bson.M{"$ne": songID},
+	}, bson.D{cmdSet(bson.M{"songId": songID})})
+	if err != nil {
+		r.logger.Error("Failed to set the song of plays", err, "mediaId", mediaID.Hex())
+		return 0, models.NewInternalError(err, "Failed to set the song of plays")
+	}
+
+	return result.ModifiedCount, nil
+}
+
 // RecordVote records a vote for a media item.
 func (r *mediaRepository) RecordVote(ctx context.Context, mediaID, userID bson.ObjectID, roomID bson.ObjectID, voteType string) error {
 	// Find the latest play history record for this media in this room
@@ -498,3 +527,110 @@ func (r *mediaRepository) GetMediaVotes(ctx context.Context, mediaID, roomID bso
 
 	return &playHistory.Votes, nil
 }
+
+// SaveTag creates or replaces a user's tag of a media item.
+func (r *mediaRepository) SaveTag(ctx context.Context, tag *models.MediaTag) error {
+	now := time.Now()
+	tag.TimeUpdate(now)
+
+	filter := bson.M{"mediaId": tag.MediaID, "userId": tag.UserID}
+	update := bson.D{
+		cmdSet(bson.M{"energy": tag.Energy, "moods": tag.Moods, "updatedAt": now}),
+		{Key: "$setOnInsert", Value: bson.M{"createdAt": now}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	if err := r.tagsCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(tag); err != nil {
+		r.logger.Error("Failed to save media tag", err, "mediaId", tag.MediaID.Hex(), "userId", tag.UserID.Hex())
+		return models.NewInternalError(err, "Failed to save media tag")
+	}
+
+	return nil
+}
+
+// FindTags finds the tags of a media item.
+func (r *mediaRepository) FindTags(ctx context.Context, mediaID bson.ObjectID) ([]*models.MediaTag, error) {
+	cursor, err := r.tagsCollection.Find(ctx, bson.M{"mediaId": mediaID})
+	if err != nil {
+		r.logger.Error("Failed to find media tags", err, "mediaId", mediaID.Hex())
+		return nil, models.NewInternalError(err, "Failed to find media tags")
+	}
+	defer cursor.Close(ctx)
+
+	var tags []*models.MediaTag
+	if err = cursor.All(ctx, &tags); err != nil {
+		r.logger.Error("Failed to decode media tags", err)
+		return nil, models.NewInternalError(err, "Failed to decode media tags")
+	}
+
+	return tags, nil
+}
+
+// SetVibe sets the energy and mood profile of a media item.
+func (r *mediaRepository) SetVibe(ctx context.Context, mediaID bson.ObjectID, vibe *models.MediaVibe) error {
+	result, err := r.mediaCollection.UpdateByID(ctx, mediaID, bson.D{
+		cmdSet(bson.M{"vibe": vibe, "updatedAt": time.Now()}),
+	})
+	if err != nil {
+		r.logger.Error("Failed to set media vibe", err, "mediaId", mediaID.Hex())
+		return models.NewInternalError(err, "Failed to set media vibe")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrMediaNotFound
+	}
+
+	return nil
+}
+
+// SetVerifiedBadge sets a media item's verified badge, or takes it away when badge is nil.
+func (r *mediaRepository) SetVerifiedBadge(ctx context.Context, mediaID bson.ObjectID, badge *models.VerifiedBadge) error {
+	result, err := r.mediaCollection.UpdateByID(ctx, mediaID, bson.D{
+		cmdSet(bson.M{"verified": badge, "updatedAt": time.Now()}),
+	})
+	if err != nil {
+		r.logger.Error("Failed to set media verified badge", err, "mediaId", mediaID.Hex())
+		return models.NewInternalError(err, "Failed to set media verified badge")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrMediaNotFound
+	}
+
+	return nil
+}
+
+// SetTakedown marks a media item as taken down by a takedown.
+func (r *mediaRepository) SetTakedown(ctx context.Context, mediaID, takedownID bson.ObjectID) error {
+	result, err := r.mediaCollection.UpdateByID(ctx, mediaID, bson.D{
+		cmdSet(bson.M{"takedownId": takedownID, "updatedAt": time.Now()}),
+	})
+	if err != nil {
+		r.logger.Error("Failed to set media takedown", err, "mediaId", mediaID.Hex())
+		return models.NewInternalError(err, "Failed to set media takedown")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrMediaNotFound
+	}
+
+	return nil
+}
+
+// SetLyricsChecked records whether the lyrics provider has lyrics for a media item.
+func (r *mediaRepository) SetLyricsChecked(ctx context.Context, mediaID bson.ObjectID, hasLyrics bool) error {
+	now := time.Now()
+	result, err := r.mediaCollection.UpdateByID(ctx, mediaID, bson.D{
+		cmdSet(bson.M{"metadata.hasLyrics": hasLyrics, "metadata.lyricsCheckedAt": now, "updatedAt": now}),
+	})
+	if err != nil {
+		r.logger.Error("Failed to set media lyrics status", err, "mediaId", mediaID.Hex())
+		return models.NewInternalError(err, "Failed to set media lyrics status")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrMediaNotFound
+	}
+
+	return nil
+}
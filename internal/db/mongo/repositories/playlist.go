@@ -17,9 +17,10 @@ import (
 	lom "github.com/samber/lo/mutable"
 )
 
-// Collection name
+// Collection names
 const (
-	playlistCollection = "playlists"
+	playlistCollection       = "playlists"
+	playlistChangeCollection = "playlist_changes"
 )
 
 // PlaylistRepository defines the interface for playlist data access operations.
@@ -39,8 +40,13 @@ type PlaylistRepository interface {
 
 	// Playlist item operations
 	AddItem(ctx context.Context, playlistID, mediaID bson.ObjectID, position int) error
+	AddItems(ctx context.Context, playlistID bson.ObjectID, mediaIDs []bson.ObjectID) error
 	RemoveItem(ctx context.Context, playlistID, itemID bson.ObjectID) error
+	RemoveItems(ctx context.Context, playlistID bson.ObjectID, itemIDs []bson.ObjectID) error
 	MoveItem(ctx context.Context, playlistID, itemID bson.ObjectID, newPosition int) error
+	ReorderItems(ctx context.Context, playlistID bson.ObjectID, itemIDs []bson.ObjectID) error
+	RelinkItem(ctx context.Context, playlistID, itemID, mediaID bson.ObjectID) error
+	FlagTakenDownItems(ctx context.Context, playlistID, mediaID, takedownID bson.ObjectID) ([]bson.ObjectID, error)
 	ShufflePlaylist(ctx context.Context, playlistID bson.ObjectID) error
 
 	// Playlist search
@@ -50,11 +56,15 @@ type PlaylistRepository interface {
 	// Playlist stats
 	RecordPlaylistPlay(ctx context.Context, playlistID, mediaID bson.ObjectID) error
 	UpdatePlaylistStats(ctx context.Context, playlistID bson.ObjectID) error
+
+	// Playlist sync
+	FindChanges(ctx context.Context, playlistID bson.ObjectID, after, upTo int64) ([]*models.PlaylistChange, error)
 }
 
 // playlistRepository is the MongoDB implementation of PlaylistRepository.
 type playlistRepository struct {
 	collection *mongo.Collection
+	changes    *mongo.Collection
 	logger     *utils.Logger
 }
 
@@ -62,6 +72,7 @@ type playlistRepository struct {
 func NewPlaylistRepository(db *mongo.Database, logger *utils.Logger) PlaylistRepository {
 	return &playlistRepository{
 		collection: db.Collection(playlistCollection),
+		changes:    db.Collection(playlistChangeCollection),
 		logger:     logger.Named("playlist_repository"),
 	}
 }
@@ -156,24 +167,19 @@ func (r *playlistRepository) FindMany(ctx context.Context, filter bson.M, opts o
 	return playlists, nil
 }
 
-// Update updates an existing playlist.
+// Update updates an existing playlist. It fails with models.ErrPlaylistChanged if the playlist's items
+// were changed since it was read.
 func (r *playlistRepository) Update(ctx context.Context, playlist *models.Playlist) error {
 	wasActive := playlist.IsActive
 	playlist.UpdateNow()
 
-	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": playlist.ID}, playlist)
-	if err != nil {
-		r.logger.Error("Failed to update playlist", err, "id", playlist.ID.Hex())
-		return models.NewInternalError(err, "Failed to update playlist")
-	}
-
-	if result.MatchedCount == 0 {
-		return models.ErrPlaylistNotFound
+	if err := r.replace(ctx, playlist, nil, "Failed to update playlist"); err != nil {
+		return err
 	}
 
 	// If playlist is now active, deactivate all other playlists for this user
 	if playlist.IsActive && !wasActive {
-		err = r.deactivateOtherPlaylists(ctx, playlist.Owner, playlist.ID)
+		err := r.deactivateOtherPlaylists(ctx, playlist.Owner, playlist.ID)
 		if err != nil {
 			r.logger.Error("Failed to deactivate other playlists", err, "userId", playlist.Owner.Hex())
 			// Continue anyway, the playlist was updated
@@ -201,6 +207,11 @@ func (r *playlistRepository) Delete(ctx context.Context, id bson.ObjectID) error
 		return models.ErrPlaylistNotFound
 	}
 
+	if _, err := r.changes.DeleteMany(ctx, bson.M{"playlistId": id}); err != nil {
+		r.logger.Error("Failed to delete playlist changes", err, "id", id.Hex())
+		// Continue anyway, the changes expire on their own
+	}
+
 	// If this was the active playlist, try to set another playlist as active
 	if playlist.IsActive {
 		// Find another playlist from this user
@@ -403,17 +414,8 @@ func (r *playlistRepository) AddItem(ctx context.Context, playlistID, mediaID bs
 	// This would require a media lookup, which we'll skip for now
 
 	// Update the playlist
-	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": playlistID}, playlist)
-	if err != nil {
-		r.logger.Error("Failed to add item to playlist", err, "playlistId", playlistID.Hex(), "mediaId", mediaID.Hex())
-		return models.NewInternalError(err, "Failed to add item to playlist")
-	}
-
-	if result.MatchedCount == 0 {
-		return models.ErrPlaylistNotFound
-	}
-
-	return nil
+	op := &models.PlaylistOp{Type: models.PlaylistOpAdd, Items: []models.PlaylistItem{newItem}, Position: position}
+	return r.replace(ctx, playlist, op, "Failed to add item to playlist")
 }
 
 // RemoveItem removes an item from a playlist.
@@ -450,19 +452,236 @@ func (r *playlistRepository) RemoveItem(ctx context.Context, playlistID, itemID
 	playlist.UpdateNow()
 
 	// Update the playlist
-	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": playlistID}, playlist)
+	op := &models.PlaylistOp{Type: models.PlaylistOpRemove, ItemIDs: []bson.ObjectID{itemID}}
+	return r.replace(ctx, playlist, op, "Failed to remove item from playlist")
+}
+
+// AddItems appends media items to the end of a playlist in a single update.
+func (r *playlistRepository) AddItems(ctx context.Context, playlistID bson.ObjectID, mediaIDs []bson.ObjectID) error {
+	if len(mediaIDs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	items := make([]models.PlaylistItem, 0, len(mediaIDs))
+	newItems := make(bson.A, 0, len(mediaIDs))
+	for _, mediaID := range mediaIDs {
+		item := models.PlaylistItem{
+			ID:      bson.NewObjectID(),
+			MediaID: mediaID,
+			AddedAt: now,
+		}
+		items = append(items, item)
+		newItems = append(newItems, item)
+	}
+
+	// The new items are numbered after the existing ones by the database, so concurrent adds can't clash
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"items": bson.M{"$concatArrays": bson.A{
+				bson.M{"$ifNull": bson.A{"$items", bson.A{}}},
+				bson.M{"$literal": newItems},
+			}},
+		}}},
+		renumberItemsStage(now),
+		nextRevisionStage(),
+	}
+
+	op := models.PlaylistOp{Type: models.PlaylistOpAdd, Items: items, Position: -1}
+	matched, err := r.updateRevision(ctx, playlistID, bson.M{"_id": playlistID}, pipeline, op, options.FindOneAndUpdate())
 	if err != nil {
-		r.logger.Error("Failed to remove item from playlist", err, "playlistId", playlistID.Hex(), "itemId", itemID.Hex())
-		return models.NewInternalError(err, "Failed to remove item from playlist")
+		r.logger.Error("Failed to add items to playlist", err, "playlistId", playlistID.Hex(), "count", len(mediaIDs))
+		return models.NewInternalError(err, "Failed to add items to playlist")
 	}
 
-	if result.MatchedCount == 0 {
+	if !matched {
 		return models.ErrPlaylistNotFound
 	}
 
 	return nil
 }
 
+// RemoveItems removes items from a playlist in a single update. Nothing is removed unless the playlist
+// holds every item.
+func (r *playlistRepository) RemoveItems(ctx context.Context, playlistID bson.ObjectID, itemIDs []bson.ObjectID) error {
+	if len(itemIDs) == 0 {
+		return nil
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"items": bson.M{"$filter": bson.M{
+				"input": "$items",
+				"cond":  bson.M{"$not": bson.A{bson.M{"$in": bson.A{"$$this._id", itemIDs}}}},
+			}},
+		}}},
+		renumberItemsStage(time.Now()),
+		nextRevisionStage(),
+	}
+
+	filter := bson.M{"_id": playlistID, "items._id": bson.M{"$all": itemIDs}}
+	op := models.PlaylistOp{Type: models.PlaylistOpRemove, ItemIDs: itemIDs}
+	matched, err := r.updateRevision(ctx, playlistID, filter, pipeline, op, options.FindOneAndUpdate())
+	if err != nil {
+		r.logger.Error("Failed to remove items from playlist", err, "playlistId", playlistID.Hex(), "count", len(itemIDs))
+		return models.NewInternalError(err, "Failed to remove items from playlist")
+	}
+
+	if !matched {
+		if _, err := r.FindByID(ctx, playlistID); err != nil {
+			return err
+		}
+		return models.ErrPlaylistItemNotFound
+	}
+
+	return nil
+}
+
+// ReorderItems puts the items of a playlist in the given order in a single update. The order must list
+// every item of the playlist exactly once.
+func (r *playlistRepository) ReorderItems(ctx context.Context, playlistID bson.ObjectID, itemIDs []bson.ObjectID) error {
+	if !uniqueItemIDs(itemIDs) {
+		return models.ErrInvalidPlaylistOrder
+	}
+
+	// With no duplicates, an order as long as the playlist naming only its items names each of them once
+	filter := bson.M{"_id": playlistID, "items": bson.M{"$size": len(itemIDs)}}
+	if len(itemIDs) > 0 {
+		filter["items._id"] = bson.M{"$all": itemIDs}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"items": bson.M{"$map": bson.M{
+				"input": itemIDs,
+				"as":    "id",
+				"in": bson.M{"$arrayElemAt": bson.A{
+					"$items",
+					bson.M{"$indexOfArray": bson.A{"$items._id", "$$id"}},
+				}},
+			}},
+		}}},
+		renumberItemsStage(time.Now()),
+		nextRevisionStage(),
+	}
+
+	op := models.PlaylistOp{Type: models.PlaylistOpReorder, ItemIDs: itemIDs}
+	matched, err := r.updateRevision(ctx, playlistID, filter, pipeline, op, options.FindOneAndUpdate())
+	if err != nil {
+		r.logger.Error("Failed to reorder playlist", err, "playlistId", playlistID.Hex())
+		return models.NewInternalError(err, "Failed to reorder playlist")
+	}
+
+	if !matched {
+		if _, err := r.FindByID(ctx, playlistID); err != nil {
+			return err
+		}
+		return models.ErrInvalidPlaylistOrder
+	}
+
+	return nil
+}
+
+// RelinkItem points a playlist item at another media item. The item keeps its place, play count and
+// everything else, so its history isn't lost the way it would be by removing and re-adding it.
+func (r *playlistRepository) RelinkItem(ctx context.Context, playlistID, itemID, mediaID bson.ObjectID) error {
+	playlist, err := r.FindByID(ctx, playlistID)
+	if err != nil {
+		return err
+	}
+
+	var item *models.PlaylistItem
+	for i := range playlist.Items {
+		if playlist.Items[i].ID == itemID {
+			item = &playlist.Items[i]
+			break
+		}
+	}
+	if item == nil {
+		return models.ErrPlaylistItemNotFound
+	}
+	if item.MediaID == mediaID {
+		return nil
+	}
+
+	update := bson.D{
+		cmdSet(bson.M{
+			"items.$.mediaId":      mediaID,
+			"items.$.relinkedFrom": item.MediaID,
+			"updatedAt":            time.Now(),
+		}),
+		cmdInc(bson.M{"revision": 1}),
+	}
+
+	relinked := *item
+	relinked.RelinkedFrom = item.MediaID
+	relinked.MediaID = mediaID
+	op := models.PlaylistOp{Type: models.PlaylistOpUpdate, Items: []models.PlaylistItem{relinked}}
+
+	// The item must still hold the media it was read with, so concurrent re-links don't overwrite each other
+	filter := bson.M{
+		"_id":   playlistID,
+		"items": bson.M{"$elemMatch": bson.M{"_id": itemID, "mediaId": item.MediaID}},
+	}
+	matched, err := r.updateRevision(ctx, playlistID, filter, update, op, options.FindOneAndUpdate())
+	if err != nil {
+		r.logger.Error("Failed to relink playlist item", err, "playlistId", playlistID.Hex(), "itemId", itemID.Hex())
+		return models.NewInternalError(err, "Failed to relink item")
+	}
+
+	if !matched {
+		return models.ErrPlaylistItemNotFound
+	}
+
+	return nil
+}
+
+// FlagTakenDownItems flags the items of a playlist holding media that was taken down, and returns the
+// IDs of the items flagged. The items stay in the playlist, so their owner can see what was taken down.
+func (r *playlistRepository) FlagTakenDownItems(ctx context.Context, playlistID, mediaID, takedownID bson.ObjectID) ([]bson.ObjectID, error) {
+	playlist, err := r.FindByID(ctx, playlistID)
+	if err != nil {
+		return nil, err
+	}
+
+	var itemIDs []bson.ObjectID
+	var flagged []models.PlaylistItem
+	for _, item := range playlist.Items {
+		if item.MediaID == mediaID && item.TakedownID.IsZero() {
+			itemIDs = append(itemIDs, item.ID)
+			item.TakedownID = takedownID
+			flagged = append(flagged, item)
+		}
+	}
+	if len(itemIDs) == 0 {
+		return nil, nil
+	}
+
+	update := bson.D{
+		cmdSet(bson.M{
+			"items.$[item].takedownId": takedownID,
+			"updatedAt":                time.Now(),
+		}),
+		cmdInc(bson.M{"revision": 1}),
+	}
+	opts := options.FindOneAndUpdate().SetArrayFilters([]any{
+		bson.M{"item._id": bson.M{"$in": itemIDs}},
+	})
+
+	op := models.PlaylistOp{Type: models.PlaylistOpUpdate, Items: flagged}
+	matched, err := r.updateRevision(ctx, playlistID, bson.M{"_id": playlistID}, update, op, opts)
+	if err != nil {
+		r.logger.Error("Failed to flag taken down playlist items", err, "playlistId", playlistID.Hex(), "mediaId", mediaID.Hex())
+		return nil, models.NewInternalError(err, "Failed to flag taken down items")
+	}
+
+	if !matched {
+		return nil, models.ErrPlaylistNotFound
+	}
+
+	return itemIDs, nil
+}
+
 // MoveItem moves an item to a new position in a playlist.
 func (r *playlistRepository) MoveItem(ctx context.Context, playlistID, itemID bson.ObjectID, newPosition int) error {
 	// Get current playlist
@@ -512,17 +731,8 @@ func (r *playlistRepository) MoveItem(ctx context.Context, playlistID, itemID bs
 	playlist.UpdateNow()
 
 	// Update the playlist
-	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": playlistID}, playlist)
-	if err != nil {
-		r.logger.Error("Failed to move item in playlist", err, "playlistId", playlistID.Hex(), "itemId", itemID.Hex())
-		return models.NewInternalError(err, "Failed to move item")
-	}
-
-	if result.MatchedCount == 0 {
-		return models.ErrPlaylistNotFound
-	}
-
-	return nil
+	op := &models.PlaylistOp{Type: models.PlaylistOpMove, ItemID: itemID, Position: newPosition}
+	return r.replace(ctx, playlist, op, "Failed to move item")
 }
 
 // ShufflePlaylist randomizes the order of items in a playlist.
@@ -544,17 +754,8 @@ func (r *playlistRepository) ShufflePlaylist(ctx context.Context, playlistID bso
 	playlist.UpdateNow()
 
 	// Update the playlist
-	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": playlistID}, playlist)
-	if err != nil {
-		r.logger.Error("Failed to shuffle playlist", err, "playlistId", playlistID.Hex())
-		return models.NewInternalError(err, "Failed to shuffle playlist")
-	}
-
-	if result.MatchedCount == 0 {
-		return models.ErrPlaylistNotFound
-	}
-
-	return nil
+	op := &models.PlaylistOp{Type: models.PlaylistOpReorder, ItemIDs: playlistItemIDs(playlist)}
+	return r.replace(ctx, playlist, op, "Failed to shuffle playlist")
 }
 
 // SearchPlaylists searches for playlists based on criteria.
@@ -714,8 +915,8 @@ func (r *playlistRepository) RecordPlaylistPlay(ctx context.Context, playlistID,
 		}
 	}
 
-	// Update the playlist
-	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": playlistID}, playlist)
+	// Update the playlist, unless its items were changed meanwhile
+	_, err = r.collection.ReplaceOne(ctx, revisionFilter(playlistID, playlist.Revision), playlist)
 	if err != nil {
 		r.logger.Error("Failed to update playlist item play count", err, "playlistId", playlistID.Hex(), "mediaId", mediaID.Hex())
 		// Continue anyway, the playlist stats were updated
@@ -748,15 +949,144 @@ func (r *playlistRepository) UpdatePlaylistStats(ctx context.Context, playlistID
 	}
 
 	// Update the playlist
-	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": playlistID}, playlist)
+	return r.replace(ctx, playlist, nil, "Failed to update playlist stats")
+}
+
+// FindChanges finds the changes recorded in a playlist's change log after a revision, up to another one,
+// oldest first. Changes expire, so some may be missing.
+func (r *playlistRepository) FindChanges(ctx context.Context, playlistID bson.ObjectID, after, upTo int64) ([]*models.PlaylistChange, error) {
+	filter := bson.M{"playlistId": playlistID, "revision": bson.M{"$gt": after, "$lte": upTo}}
+	opts := options.Find().SetSort(bson.D{{Key: "revision", Value: 1}})
+
+	cursor, err := r.changes.Find(ctx, filter, opts)
+	if err != nil {
+		r.logger.Error("Failed to find playlist changes", err, "playlistId", playlistID.Hex(), "after", after)
+		return nil, models.NewInternalError(err, "Failed to find playlist changes")
+	}
+	defer cursor.Close(ctx)
+
+	var changes []*models.PlaylistChange
+	if err = cursor.All(ctx, &changes); err != nil {
+		r.logger.Error("Failed to decode playlist changes", err)
+		return nil, models.NewInternalError(err, "Failed to decode playlist changes")
+	}
+
+	return changes, nil
+}
+
+// replace stores a playlist read with FindByID, unless its items were changed since it was read. Passing
+// an op moves the playlist to its next revision and records the op as the change made.
+func (r *playlistRepository) replace(ctx context.Context, playlist *models.Playlist, op *models.PlaylistOp, message string) error {
+	filter := revisionFilter(playlist.ID, playlist.Revision)
+	if op != nil {
+		playlist.Revision++
+	}
+
+	result, err := r.collection.ReplaceOne(ctx, filter, playlist)
 	if err != nil {
-		r.logger.Error("Failed to update playlist stats", err, "playlistId", playlistID.Hex())
-		return models.NewInternalError(err, "Failed to update playlist stats")
+		r.logger.Error(message, err, "playlistId", playlist.ID.Hex())
+		return models.NewInternalError(err, message)
 	}
 
 	if result.MatchedCount == 0 {
-		return models.ErrPlaylistNotFound
+		if _, err := r.FindByID(ctx, playlist.ID); err != nil {
+			return err
+		}
+		return models.ErrPlaylistChanged
 	}
 
+	if op != nil {
+		r.recordChange(ctx, playlist.ID, playlist.Revision, *op)
+	}
 	return nil
 }
+
+// updateRevision applies an update moving a playlist to its next revision, and records the op as the
+// change made. It reports whether the filter matched the playlist.
+func (r *playlistRepository) updateRevision(ctx context.Context, playlistID bson.ObjectID, filter bson.M, update any, op models.PlaylistOp, opts *options.FindOneAndUpdateOptionsBuilder) (bool, error) {
+	opts.SetReturnDocument(options.After).SetProjection(bson.M{"revision": 1})
+
+	var updated struct {
+		Revision int64 `bson:"revision"`
+	}
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	r.recordChange(ctx, playlistID, updated.Revision, op)
+	return true, nil
+}
+
+// recordChange adds a change to a playlist's change log. The playlist was changed already, so failing
+// to record it only leaves a gap, which makes syncing clients reset their copy.
+func (r *playlistRepository) recordChange(ctx context.Context, playlistID bson.ObjectID, revision int64, op models.PlaylistOp) {
+	change := &models.PlaylistChange{
+		ID:         bson.NewObjectID(),
+		PlaylistID: playlistID,
+		Revision:   revision,
+		PlaylistOp: op,
+		CreatedAt:  time.Now(),
+	}
+
+	if _, err := r.changes.InsertOne(ctx, change); err != nil {
+		r.logger.Error("Failed to record playlist change", err, "playlistId", playlistID.Hex(), "revision", revision)
+	}
+}
+
+// revisionFilter matches a playlist still at the revision it was read at. Playlists stored before
+// revisions were counted have none, and are at revision zero.
+func revisionFilter(playlistID bson.ObjectID, revision int64) bson.M {
+	if revision == 0 {
+		return bson.M{"_id": playlistID, "revision": bson.M{"$in": bson.A{0, nil}}}
+	}
+	return bson.M{"_id": playlistID, "revision": revision}
+}
+
+// nextRevisionStage is an update pipeline stage moving a playlist to its next revision.
+func nextRevisionStage() bson.D {
+	return bson.D{{Key: "$set", Value: bson.M{
+		"revision": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$revision", 0}}, 1}},
+	}}}
+}
+
+// playlistItemIDs lists the IDs of a playlist's items in order.
+func playlistItemIDs(playlist *models.Playlist) []bson.ObjectID {
+	itemIDs := make([]bson.ObjectID, 0, len(playlist.Items))
+	for _, item := range playlist.Items {
+		itemIDs = append(itemIDs, item.ID)
+	}
+	return itemIDs
+}
+
+// renumberItemsStage is an update pipeline stage setting the order of each playlist item to its index,
+// and the playlist's item count to match.
+func renumberItemsStage(now time.Time) bson.D {
+	return bson.D{{Key: "$set", Value: bson.M{
+		"items": bson.M{"$map": bson.M{
+			"input": bson.M{"$range": bson.A{0, bson.M{"$size": "$items"}}},
+			"as":    "i",
+			"in": bson.M{"$mergeObjects": bson.A{
+				bson.M{"$arrayElemAt": bson.A{"$items", "$$i"}},
+				bson.M{"order": "$$i"},
+			}},
+		}},
+		"stats.totalItems": bson.M{"$size": "$items"},
+		"updatedAt":        now,
+	}}}
+}
+
+// uniqueItemIDs checks that no item ID is listed twice.
+func uniqueItemIDs(itemIDs []bson.ObjectID) bool {
+	seen := make(map[bson.ObjectID]struct{}, len(itemIDs))
+	for _, id := range itemIDs {
+		if _, ok := seen[id]; ok {
+			return false
+		}
+		seen[id] = struct{}{}
+	}
+	return true
+}
@@ -0,0 +1,220 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// RelationshipRepository defines the interface for data access operations on the follow graph between users.
+// Follows are kept in the connections of both users, and users following each other are friends.
+type RelationshipRepository interface {
+	// FindFollowing finds users that the given user is following.
+	FindFollowing(ctx context.Context, userID bson.ObjectID, skip, limit int) ([]*models.User, error)
+
+	// FindFollowers finds users that follow the given user.
+	FindFollowers(ctx context.Context, userID bson.ObjectID, skip, limit int) ([]*models.User, error)
+
+	// FindFollowerIDs finds the IDs of every user following the given user.
+	FindFollowerIDs(ctx context.Context, userID bson.ObjectID) ([]bson.ObjectID, error)
+
+	// IsFollowing checks if a user is following another user.
+	IsFollowing(ctx context.Context, userID, targetID bson.ObjectID) (bool, error)
+
+	// Follow makes a user follow another user.
+	Follow(ctx context.Context, userID, targetID bson.ObjectID) error
+
+	// Unfollow makes a user unfollow another user.
+	Unfollow(ctx context.Context, userID, targetID bson.ObjectID) error
+}
+
+// relationshipRepository is the MongoDB implementation of RelationshipRepository.
+type relationshipRepository struct {
+	collection *mongo.Collection
+	userRepo   *userRepository
+	logger     *utils.Logger
+}
+
+// NewRelationshipRepository creates a new instance of RelationshipRepository.
+func NewRelationshipRepository(db *mongo.Database, logger *utils.Logger) RelationshipRepository {
+	collection := db.Collection(userCollection)
+	return &relationshipRepository{
+		collection: collection,
+		userRepo:   &userRepository{collection: collection, logger: logger.Named("user_repository")},
+		logger:     logger.Named("relationship_repository"),
+	}
+}
+
+// FindFollowing finds users that the given user is following.
+func (r *relationshipRepository) FindFollowing(ctx context.Context, userID bson.ObjectID, skip, limit int) ([]*models.User, error) {
+	// First get the user to retrieve their following list
+	user, err := r.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(user.Connections.Following) == 0 {
+		return []*models.User{}, nil
+	}
+
+	// Query users that are in the following list
+	opts := options.Find().
+		SetSkip(int64(skip)).
+		SetLimit(int64(limit)).
+		SetSort(bson.M{"username": 1})
+
+	return r.userRepo.FindMany(ctx, bson.M{"_id": bson.M{"$in": user.Connections.Following}}, opts)
+}
+
+// FindFollowers finds users that follow the given user.
+func (r *relationshipRepository) FindFollowers(ctx context.Context, userID bson.ObjectID, skip, limit int) ([]*models.User, error) {
+	opts := options.Find().
+		SetSkip(int64(skip)).
+		SetLimit(int64(limit)).
+		SetSort(bson.M{"username": 1})
+
+	return r.userRepo.FindMany(ctx, bson.M{"connections.following": userID}, opts)
+}
+
+// IsFollowing checks if a user is following another user.
+func (r *relationshipRepository) IsFollowing(ctx context.Context, userID, targetID bson.ObjectID) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{
+		"_id":                   userID,
+		"connections.following": targetID,
+	})
+
+	if err != nil {
+		r.logger.Error("Failed to check if user is following", err, "userID", userID.Hex(), "targetID", targetID.Hex())
+		return false, models.NewInternalError(err, "Failed to check following status")
+	}
+
+	return count > 0, nil
+}
+
+// Follow makes a user follow another user.
+func (r *relationshipRepository) Follow(ctx context.Context, userID, targetID bson.ObjectID) error {
+	// Check if already following
+	isFollowing, err := r.IsFollowing(ctx, userID, targetID)
+	if err != nil {
+		return err
+	}
+
+	if isFollowing {
+		return nil // Already following, no error
+	}
+
+	// Update the user's following list
+	result, err := r.collection.UpdateByID(ctx,
+		userID,
+		bson.D{
+			cmdAddToSet(bson.M{"connections.following": targetID}),
+			cmdSet(bson.M{"updatedAt": time.Now()}),
+		},
+	)
+
+	if err != nil {
+		r.logger.Error("Failed to follow user", err, "userID", userID.Hex(), "targetID", targetID.Hex())
+		return models.NewInternalError(err, "Failed to follow user")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrUserNotFound
+	}
+
+	// Check if mutual follow and update friends list for both users
+	isFollower, err := r.IsFollowing(ctx, targetID, userID)
+	if err != nil {
+		return err
+	}
+
+	if isFollower {
+		// Mutual follow, add to friends lists
+		_, err = r.collection.UpdateMany(ctx,
+			bson.M{"_id": bson.M{"$in": []bson.ObjectID{userID, targetID}}},
+			bson.M{
+				"$addToSet": bson.M{"connections.friends": bson.M{"$cond": bson.A{
+					bson.D{{Key: "$eq", Value: []any{"$_id", userID}}},
+					targetID,
+					userID,
+				}}},
+			},
+		)
+
+		if err != nil {
+			r.logger.Error("Failed to update friends lists", err, "userID", userID.Hex(), "targetID", targetID.Hex())
+			return models.NewInternalError(err, "Failed to update friends lists")
+		}
+	}
+
+	// Update the target user's followers list
+	_, err = r.collection.UpdateByID(ctx,
+		targetID,
+		bson.D{
+			cmdAddToSet(bson.M{"connections.followers": userID}),
+			cmdSet(bson.M{"updatedAt": time.Now()}),
+		},
+	)
+
+	if err != nil {
+		r.logger.Error("Failed to update follower list", err, "targetID", targetID.Hex(), "userID", userID.Hex())
+		return models.NewInternalError(err, "Failed to update follower list")
+	}
+
+	return nil
+}
+
+// Unfollow makes a user unfollow another user.
+func (r *relationshipRepository) Unfollow(ctx context.Context, userID, targetID bson.ObjectID) error {
+	// Update the user's following list
+	result, err := r.collection.UpdateByID(ctx,
+		userID,
+		bson.D{
+			cmdPull(bson.M{"connections.following": targetID, "connections.friends": targetID}),
+			cmdSet(bson.M{"updatedAt": time.Now()}),
+		},
+	)
+
+	if err != nil {
+		r.logger.Error("Failed to unfollow user", err, "userID", userID.Hex(), "targetID", targetID.Hex())
+		return models.NewInternalError(err, "Failed to unfollow user")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrUserNotFound
+	}
+
+	// Update the target user's followers list and friends list
+	_, err = r.collection.UpdateByID(ctx,
+		targetID,
+		bson.D{
+			cmdPull(bson.M{"connections.followers": userID, "connections.friends": userID}),
+			cmdSet(bson.M{"updatedAt": time.Now()}),
+		},
+	)
+
+	if err != nil {
+		r.logger.Error("Failed to update follower list", err, "targetID", targetID.Hex(), "userID", userID.Hex())
+		return models.NewInternalError(err, "Failed to update follower list")
+	}
+
+	return nil
+}
+
+// FindFollowerIDs finds the IDs of every user following the given user.
+func (r *relationshipRepository) FindFollowerIDs(ctx context.Context, userID bson.ObjectID) ([]bson.ObjectID, error) {
+	followers, err := r.userRepo.FindMany(ctx, bson.M{"connections.following": userID}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]bson.ObjectID, len(followers))
+	for i, follower := range followers {
+		ids[i] = follower.ID
+	}
+	return ids, nil
+}
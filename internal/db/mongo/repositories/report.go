@@ -0,0 +1,173 @@
+// Package repositories contains MongoDB repository implementations.
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// Collection names
+const (
+	roomReportsCollection = "room_reports"
+)
+
+// ReportRepository defines the interface for room report data access operations.
+type ReportRepository interface {
+	CreateRoomReport(ctx context.Context, report *models.RoomReport) error
+	FindRoomReportByID(ctx context.Context, id bson.ObjectID) (*models.RoomReport, error)
+	FindRoomReports(ctx context.Context, filter models.RoomReportFilter, skip, limit int) ([]*models.RoomReport, int64, error)
+	ResolveRoomReports(ctx context.Context, roomID bson.ObjectID, status models.RoomReportStatus, action models.RoomEnforcement, note string, resolvedBy bson.ObjectID) ([]*models.RoomReport, error)
+}
+
+// reportRepository is the MongoDB implementation of ReportRepository.
+type reportRepository struct {
+	roomReportsCollection *mongo.Collection
+	logger                *utils.Logger
+}
+
+// NewReportRepository creates a new instance of ReportRepository.
+func NewReportRepository(db *mongo.Database, logger *utils.Logger) ReportRepository {
+	return &reportRepository{
+		roomReportsCollection: db.Collection(roomReportsCollection),
+		logger:                logger.Named("report_repository"),
+	}
+}
+
+// CreateRoomReport creates a new room report.
+func (r *reportRepository) CreateRoomReport(ctx context.Context, report *models.RoomReport) error {
+	if report.ID.IsZero() {
+		report.ID = bson.NewObjectID()
+	}
+	report.CreateNow()
+
+	_, err := r.roomReportsCollection.InsertOne(ctx, report)
+	if err != nil {
+		r.logger.Error("Failed to create room report", err, "roomId", report.RoomID.Hex())
+		return models.NewInternalError(err, "Failed to create room report")
+	}
+
+	return nil
+}
+
+// FindRoomReportByID finds a room report by its ID.
+func (r *reportRepository) FindRoomReportByID(ctx context.Context, id bson.ObjectID) (*models.RoomReport, error) {
+	var report models.RoomReport
+
+	err := r.roomReportsCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&report)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, models.ErrRoomReportNotFound
+		}
+		r.logger.Error("Failed to find room report by ID", err, "id", id.Hex())
+		return nil, models.NewInternalError(err, "Failed to find room report")
+	}
+
+	return &report, nil
+}
+
+// FindRoomReports finds room reports, oldest first so triage works through them in order, along with the total number matching.
+func (r *reportRepository) FindRoomReports(ctx context.Context, filter models.RoomReportFilter, skip, limit int) ([]*models.RoomReport, int64, error) {
+	query := roomReportQuery(filter)
+
+	total, err := r.roomReportsCollection.CountDocuments(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to count room reports", err)
+		return nil, 0, models.NewInternalError(err, "Failed to count room reports")
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"createdAt": 1}).
+		SetSkip(int64(skip)).
+		SetLimit(int64(limit))
+
+	cursor, err := r.roomReportsCollection.Find(ctx, query, opts)
+	if err != nil {
+		r.logger.Error("Failed to find room reports", err)
+		return nil, 0, models.NewInternalError(err, "Failed to find room reports")
+	}
+	defer cursor.Close(ctx)
+
+	reports := []*models.RoomReport{}
+	if err = cursor.All(ctx, &reports); err != nil {
+		r.logger.Error("Failed to decode room reports", err)
+		return nil, 0, models.NewInternalError(err, "Failed to decode room reports")
+	}
+
+	return reports, total, nil
+}
+
+// ResolveRoomReports resolves every pending report of a room, and returns the reports it resolved.
+func (r *reportRepository) ResolveRoomReports(ctx context.Context, roomID bson.ObjectID, status models.RoomReportStatus, action models.RoomEnforcement, note string, resolvedBy bson.ObjectID) ([]*models.RoomReport, error) {
+	query := roomReportQuery(models.RoomReportFilter{RoomID: roomID, Status: models.RoomReportPending})
+
+	cursor, err := r.roomReportsCollection.Find(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to find pending room reports", err, "roomId", roomID.Hex())
+		return nil, models.NewInternalError(err, "Failed to find room reports")
+	}
+	defer cursor.Close(ctx)
+
+	var reports []*models.RoomReport
+	if err = cursor.All(ctx, &reports); err != nil {
+		r.logger.Error("Failed to decode room reports", err)
+		return nil, models.NewInternalError(err, "Failed to decode room reports")
+	}
+	if len(reports) == 0 {
+		return reports, nil
+	}
+
+	ids := make([]bson.ObjectID, len(reports))
+	for i, report := range reports {
+		ids[i] = report.ID
+	}
+
+	now := time.Now()
+	update := bson.D{cmdSet(bson.M{
+		"status":     status,
+		"action":     action,
+		"note":       note,
+		"resolvedBy": resolvedBy,
+		"resolvedAt": now,
+		"updatedAt":  now,
+	})}
+
+	// Reports filed in the meantime stay pending for another look
+	_, err = r.roomReportsCollection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}, "status": models.RoomReportPending}, update)
+	if err != nil {
+		r.logger.Error("Failed to resolve room reports", err, "roomId", roomID.Hex())
+		return nil, models.NewInternalError(err, "Failed to resolve room reports")
+	}
+
+	for _, report := range reports {
+		report.Status = status
+		report.Action = action
+		report.Note = note
+		report.ResolvedBy = resolvedBy
+		report.ResolvedAt = now
+		report.UpdatedAt = now
+	}
+
+	return reports, nil
+}
+
+// roomReportQuery builds the query for a room report filter.
+func roomReportQuery(filter models.RoomReportFilter) bson.M {
+	query := bson.M{}
+	if !filter.RoomID.IsZero() {
+		query["roomId"] = filter.RoomID
+	}
+	if !filter.ReporterID.IsZero() {
+		query["reporterId"] = filter.ReporterID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	return query
+}
@@ -34,18 +34,25 @@ type RoomRepository interface {
 	// Room status operations
 	SetActive(ctx context.Context, id bson.ObjectID, active bool) error
 	UpdateLastActivity(ctx context.Context, id bson.ObjectID) error
+	SetVerifiedBadge(ctx context.Context, id bson.ObjectID, badge *models.VerifiedBadge) error
+	SetLanguage(ctx context.Context, id bson.ObjectID, language *models.RoomLanguage) error
 
 	// Room user operations
 	AddUserToRoom(ctx context.Context, roomUser *models.RoomUser) error
 	RemoveUserFromRoom(ctx context.Context, roomID, userID bson.ObjectID) error
+	RemoveUserFromRooms(ctx context.Context, userID bson.ObjectID) (int64, error)
 	FindRoomUsers(ctx context.Context, roomID bson.ObjectID) ([]*models.RoomUser, error)
 	FindUserRoom(ctx context.Context, userID bson.ObjectID) (*models.RoomUser, error)
 	UpdateRoomUser(ctx context.Context, roomUser *models.RoomUser) error
+	FindRoomUser(ctx context.Context, roomID, userID bson.ObjectID) (*models.RoomUser, error)
+	SetChatAppearance(ctx context.Context, roomID, userID bson.ObjectID, appearance *models.ChatAppearance) error
 
 	// DJ queue operations
 	UpdateDJQueue(ctx context.Context, roomID bson.ObjectID, queueEntries []models.QueueEntry) error
 	SetCurrentDJ(ctx context.Context, roomID, userID bson.ObjectID) error
 	SetCurrentMedia(ctx context.Context, roomID, mediaID bson.ObjectID) error
+	SetInFlightPlay(ctx context.Context, roomID bson.ObjectID, play *models.InFlightPlay) error
+	TakeInFlightPlay(ctx context.Context, roomID, playID bson.ObjectID) (bool, error)
 
 	// Moderation operations
 	AddModerator(ctx context.Context, roomID, userID bson.ObjectID) error
@@ -54,10 +61,22 @@ type RoomRepository interface {
 	UnbanUser(ctx context.Context, roomID, userID bson.ObjectID) error
 	IsUserBanned(ctx context.Context, roomID, userID bson.ObjectID) (bool, error)
 
+	// Role operations
+	SetRole(ctx context.Context, roomID bson.ObjectID, assignment models.RoomRoleAssignment) error
+	RemoveRole(ctx context.Context, roomID, userID bson.ObjectID) error
+	RemoveUserRoles(ctx context.Context, userID bson.ObjectID) (int64, error)
+	TransferOwnership(ctx context.Context, roomID, fromID, toID bson.ObjectID) error
+	FindRoles(ctx context.Context, roomID bson.ObjectID) ([]models.RoomRoleAssignment, error)
+	SetRolePermissions(ctx context.Context, roomID bson.ObjectID, permissions map[string][]string) error
+	SetQueueLocked(ctx context.Context, roomID bson.ObjectID, locked bool) error
+	SetQueueOptions(ctx context.Context, roomID bson.ObjectID, options models.QueueOptions) error
+
 	// Room search and discovery
 	SearchRooms(ctx context.Context, criteria models.RoomSearchCriteria) ([]*models.Room, int64, error)
 	FindPopularRooms(ctx context.Context, limit int) ([]*models.Room, error)
 	FindRecentRooms(ctx context.Context, limit int) ([]*models.Room, error)
+	UpdateHeat(ctx context.Context, heat map[bson.ObjectID]float64) error
+	UpdateDetectedLanguages(ctx context.Context, languages map[bson.ObjectID]models.RoomLanguage) error
 }
 
 // roomRepository is the MongoDB implementation of RoomRepository.
@@ -258,6 +277,40 @@ func (r *roomRepository) SetActive(ctx context.Context, id bson.ObjectID, active
 	return nil
 }
 
+// SetVerifiedBadge sets a room's verified badge, or takes it away when badge is nil.
+func (r *roomRepository) SetVerifiedBadge(ctx context.Context, id bson.ObjectID, badge *models.VerifiedBadge) error {
+	result, err := r.roomCollection.UpdateByID(ctx, id, bson.D{
+		cmdSet(bson.M{"verified": badge, "updatedAt": time.Now()}),
+	})
+	if err != nil {
+		r.logger.Error("Failed to set room verified badge", err, "id", id.Hex())
+		return models.NewInternalError(err, "Failed to set room verified badge")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrRoomNotFound
+	}
+
+	return nil
+}
+
+// SetLanguage sets a room's language, or clears it when language is nil.
+func (r *roomRepository) SetLanguage(ctx context.Context, id bson.ObjectID, language *models.RoomLanguage) error {
+	result, err := r.roomCollection.UpdateByID(ctx, id, bson.D{
+		cmdSet(bson.M{"language": language, "updatedAt": time.Now()}),
+	})
+	if err != nil {
+		r.logger.Error("Failed to set room language", err, "id", id.Hex())
+		return models.NewInternalError(err, "Failed to set room language")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrRoomNotFound
+	}
+
+	return nil
+}
+
 // UpdateLastActivity updates a room's last activity time.
 func (r *roomRepository) UpdateLastActivity(ctx context.Context, id bson.ObjectID) error {
 	now := time.Now()
@@ -415,6 +468,17 @@ func (r *roomRepository) RemoveUserFromRoom(ctx context.Context, roomID, userID
 	return nil
 }
 
+// RemoveUserFromRooms removes a user's membership records from every room, and returns how many it removed.
+func (r *roomRepository) RemoveUserFromRooms(ctx context.Context, userID bson.ObjectID) (int64, error) {
+	result, err := r.roomUsersCollection.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		r.logger.Error("Failed to remove user from rooms", err, "userId", userID.Hex())
+		return 0, models.NewInternalError(err, "Failed to remove user from rooms")
+	}
+
+	return result.DeletedCount, nil
+}
+
 // FindRoomUsers finds all users in a room.
 func (r *roomRepository) FindRoomUsers(ctx context.Context, roomID bson.ObjectID) ([]*models.RoomUser, error) {
 	cursor, err := r.roomUsersCollection.Find(ctx, bson.M{"roomId": roomID})
@@ -467,14 +531,56 @@ func (r *roomRepository) UpdateRoomUser(ctx context.Context, roomUser *models.Ro
 	return nil
 }
 
+// FindRoomUser finds a user's record in a room.
+func (r *roomRepository) FindRoomUser(ctx context.Context, roomID, userID bson.ObjectID) (*models.RoomUser, error) {
+	var roomUser models.RoomUser
+
+	err := r.roomUsersCollection.FindOne(ctx, roomAndUserIDs(roomID, userID)).Decode(&roomUser)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, models.ErrUserNotInRoom
+		}
+		r.logger.Error("Failed to find room user", err, "roomId", roomID.Hex(), "userId", userID.Hex())
+		return nil, models.NewInternalError(err, "Failed to find room user")
+	}
+
+	return &roomUser, nil
+}
+
+// SetChatAppearance sets how a user's name shows in a room's chat, or resets it with nil.
+func (r *roomRepository) SetChatAppearance(ctx context.Context, roomID, userID bson.ObjectID, appearance *models.ChatAppearance) error {
+	update := bson.D{cmdUnset(bson.M{"chatAppearance": ""})}
+	if appearance != nil {
+		update = bson.D{cmdSet(bson.M{"chatAppearance": appearance})}
+	}
+
+	result, err := r.roomUsersCollection.UpdateOne(ctx, roomAndUserIDs(roomID, userID), update)
+	if err != nil {
+		r.logger.Error("Failed to set chat appearance", err, "roomId", roomID.Hex(), "userId", userID.Hex())
+		return models.NewInternalError(err, "Failed to set chat appearance")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrUserNotInRoom
+	}
+
+	return nil
+}
+
 // UpdateDJQueue updates the DJ queue for a room.
 func (r *roomRepository) UpdateDJQueue(ctx context.Context, roomID bson.ObjectID, queueEntries []models.QueueEntry) error {
-	// We'll store the DJ queue in Redis for real-time access, but also update the room state
-	// to reflect the current queue state for persistence.
+	// The DJ queue lives in Redis for real-time access, the copy stored with the room
+	// is what it is rebuilt from when Redis loses it.
+	stored := make([]models.StoredQueueEntry, len(queueEntries))
+	userIDs := make([]bson.ObjectID, len(queueEntries))
+	for i, entry := range queueEntries {
+		stored[i] = entry.Stored()
+		userIDs[i] = entry.User.ID
+	}
 
-	// Update room's lastActivity
 	update := bson.D{
 		cmdSet(bson.M{
+			"djQueue":      stored,
 			"lastActivity": time.Now(),
 			"updatedAt":    time.Now(),
 		}),
@@ -509,6 +615,18 @@ func (r *roomRepository) UpdateDJQueue(ctx context.Context, roomID bson.ObjectID
 		}
 	}
 
+	// Users who left the queue are no longer DJs
+	_, err = r.roomUsersCollection.UpdateMany(
+		ctx,
+		bson.M{"roomId": roomID, "isDJ": true, "userId": bson.M{"$nin": userIDs}},
+		bson.D{
+			cmdSet(bson.M{"isDJ": false}),
+		},
+	)
+	if err != nil {
+		r.logger.Error("Failed to clear DJs who left the queue", err, "roomId", roomID.Hex())
+	}
+
 	return nil
 }
 
@@ -590,6 +708,41 @@ func (r *roomRepository) SetCurrentMedia(ctx context.Context, roomID, mediaID bs
 	return nil
 }
 
+// SetInFlightPlay stores the track playing in a room, or clears it when play is nil.
+func (r *roomRepository) SetInFlightPlay(ctx context.Context, roomID bson.ObjectID, play *models.InFlightPlay) error {
+	update := bson.D{cmdUnset(bson.M{"inFlightPlay": ""})}
+	if play != nil {
+		update = bson.D{cmdSet(bson.M{"inFlightPlay": play})}
+	}
+
+	result, err := r.roomCollection.UpdateByID(ctx, roomID, update)
+	if err != nil {
+		r.logger.Error("Failed to set in-flight play", err, "roomId", roomID.Hex())
+		return models.NewInternalError(err, "Failed to set in-flight play")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrRoomNotFound
+	}
+
+	return nil
+}
+
+// TakeInFlightPlay clears the track playing in a room if it is still the given play, reporting whether
+// it was. Only one caller takes a play, so it is recovered once.
+func (r *roomRepository) TakeInFlightPlay(ctx context.Context, roomID, playID bson.ObjectID) (bool, error) {
+	result, err := r.roomCollection.UpdateOne(ctx,
+		bson.M{"_id": roomID, "inFlightPlay.id": playID},
+		bson.D{cmdUnset(bson.M{"inFlightPlay": ""})},
+	)
+	if err != nil {
+		r.logger.Error("Failed to take in-flight play", err, "roomId", roomID.Hex(), "playId", playID.Hex())
+		return false, models.NewInternalError(err, "Failed to take in-flight play")
+	}
+
+	return result.ModifiedCount > 0, nil
+}
+
 // AddModerator adds a moderator to a room.
 func (r *roomRepository) AddModerator(ctx context.Context, roomID, userID bson.ObjectID) error {
 	update := bson.D{
@@ -771,9 +924,217 @@ func (r *roomRepository) IsUserBanned(ctx context.Context, roomID, userID bson.O
 	return count > 0, nil
 }
 
+// SetRole assigns a role to a user in a room, replacing the role they had. Co-hosts are kept
+// in the room's moderators too, for the clients and code that only know about moderators.
+func (r *roomRepository) SetRole(ctx context.Context, roomID bson.ObjectID, assignment models.RoomRoleAssignment) error {
+	moderators := cmdPull(bson.M{"moderators": assignment.UserID})
+	if assignment.Role == models.RoomRoleCoHost {
+		moderators = cmdAddToSet(bson.M{"moderators": assignment.UserID})
+	}
+
+	result, err := r.roomCollection.UpdateByID(ctx, roomID, bson.D{
+		cmdPull(bson.M{"roles": bson.M{"userId": assignment.UserID}}),
+		moderators,
+		cmdSet(bson.M{"updatedAt": time.Now()}),
+	})
+	if err != nil {
+		r.logger.Error("Failed to set room role", err, "roomId", roomID.Hex(), "userId", assignment.UserID.Hex())
+		return models.NewInternalError(err, "Failed to set room role")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrRoomNotFound
+	}
+
+	_, err = r.roomCollection.UpdateByID(ctx, roomID, bson.D{
+		{Key: "$push", Value: bson.M{"roles": assignment}},
+	})
+	if err != nil {
+		r.logger.Error("Failed to set room role", err, "roomId", roomID.Hex(), "userId", assignment.UserID.Hex())
+		return models.NewInternalError(err, "Failed to set room role")
+	}
+
+	r.setRoomUserRole(ctx, roomID, assignment.UserID, assignment.Role)
+	return nil
+}
+
+// RemoveRole removes a user's role in a room, leaving them a regular user.
+func (r *roomRepository) RemoveRole(ctx context.Context, roomID, userID bson.ObjectID) error {
+	result, err := r.roomCollection.UpdateByID(ctx, roomID, bson.D{
+		cmdPull(bson.M{
+			"roles":      bson.M{"userId": userID},
+			"moderators": userID,
+		}),
+		cmdSet(bson.M{"updatedAt": time.Now()}),
+	})
+	if err != nil {
+		r.logger.Error("Failed to remove room role", err, "roomId", roomID.Hex(), "userId", userID.Hex())
+		return models.NewInternalError(err, "Failed to remove room role")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrRoomNotFound
+	}
+
+	r.setRoomUserRole(ctx, roomID, userID, models.RoomRoleUser)
+	return nil
+}
+
+// RemoveUserRoles removes a user's roles and moderator rights from every room, and returns how many rooms
+// it changed. Each list is pulled from separately, as pulling from a list left unset fails.
+func (r *roomRepository) RemoveUserRoles(ctx context.Context, userID bson.ObjectID) (int64, error) {
+	pulls := []struct {
+		filter bson.M
+		update bson.M
+	}{
+		{bson.M{"roles.userId": userID}, bson.M{"roles": bson.M{"userId": userID}}},
+		{bson.M{"moderators": userID}, bson.M{"moderators": userID}},
+	}
+
+	var modified int64
+	for _, pull := range pulls {
+		result, err := r.roomCollection.UpdateMany(ctx, pull.filter, bson.D{
+			cmdPull(pull.update),
+			cmdSet(bson.M{"updatedAt": time.Now()}),
+		})
+		if err != nil {
+			r.logger.Error("Failed to remove user roles", err, "userId", userID.Hex())
+			return modified, models.NewInternalError(err, "Failed to remove user roles")
+		}
+		modified += result.ModifiedCount
+	}
+
+	return modified, nil
+}
+
+// TransferOwnership hands a room over from its owner to another user, who gives up their role for the
+// owner's. It fails with models.ErrRoomNotFound if the room isn't owned by fromID.
+func (r *roomRepository) TransferOwnership(ctx context.Context, roomID, fromID, toID bson.ObjectID) error {
+	filter := bson.M{
+		"_id":       roomID,
+		"createdBy": fromID,
+	}
+	update := bson.D{
+		cmdPull(bson.M{"roles": bson.M{"userId": toID}}),
+		cmdAddToSet(bson.M{"moderators": toID}),
+		cmdSet(bson.M{
+			"createdBy": toID,
+			"updatedAt": time.Now(),
+		}),
+	}
+
+	result, err := r.roomCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		r.logger.Error("Failed to transfer room ownership", err, "roomId", roomID.Hex(), "userId", toID.Hex())
+		return models.NewInternalError(err, "Failed to transfer room ownership")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrRoomNotFound
+	}
+
+	r.setRoomUserRole(ctx, roomID, toID, models.RoomRoleOwner)
+	return nil
+}
+
+// FindRoles finds the roles assigned in a room.
+func (r *roomRepository) FindRoles(ctx context.Context, roomID bson.ObjectID) ([]models.RoomRoleAssignment, error) {
+	var room models.Room
+	err := r.roomCollection.FindOne(ctx, bson.M{"_id": roomID},
+		options.FindOne().SetProjection(bson.M{"roles": 1})).Decode(&room)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, models.ErrRoomNotFound
+		}
+		r.logger.Error("Failed to find room roles", err, "roomId", roomID.Hex())
+		return nil, models.NewInternalError(err, "Failed to find room roles")
+	}
+
+	return room.Roles, nil
+}
+
+// SetRolePermissions sets the permissions of a room's roles, or restores the defaults when permissions is empty.
+func (r *roomRepository) SetRolePermissions(ctx context.Context, roomID bson.ObjectID, permissions map[string][]string) error {
+	update := bson.D{
+		cmdSet(bson.M{"rolePermissions": permissions, "updatedAt": time.Now()}),
+	}
+	if len(permissions) == 0 {
+		update = bson.D{
+			cmdUnset(bson.M{"rolePermissions": ""}),
+			cmdSet(bson.M{"updatedAt": time.Now()}),
+		}
+	}
+
+	result, err := r.roomCollection.UpdateByID(ctx, roomID, update)
+	if err != nil {
+		r.logger.Error("Failed to set room role permissions", err, "roomId", roomID.Hex())
+		return models.NewInternalError(err, "Failed to set room role permissions")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrRoomNotFound
+	}
+
+	return nil
+}
+
+// SetQueueLocked locks or unlocks a room's DJ queue.
+func (r *roomRepository) SetQueueLocked(ctx context.Context, roomID bson.ObjectID, locked bool) error {
+	result, err := r.roomCollection.UpdateByID(ctx, roomID, bson.D{
+		cmdSet(bson.M{"queueLocked": locked, "updatedAt": time.Now()}),
+	})
+	if err != nil {
+		r.logger.Error("Failed to set room queue lock", err, "roomId", roomID.Hex())
+		return models.NewInternalError(err, "Failed to set room queue lock")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrRoomNotFound
+	}
+
+	return nil
+}
+
+// SetQueueOptions sets the options of a room's DJ queue.
+func (r *roomRepository) SetQueueOptions(ctx context.Context, roomID bson.ObjectID, options models.QueueOptions) error {
+	result, err := r.roomCollection.UpdateByID(ctx, roomID, bson.D{
+		cmdSet(bson.M{
+			"queueLocked":         options.Locked,
+			"queueCycleDisabled":  !options.Cycle,
+			"maxConsecutivePlays": options.MaxConsecutivePlays,
+			"updatedAt":           time.Now(),
+		}),
+	})
+	if err != nil {
+		r.logger.Error("Failed to set room queue options", err, "roomId", roomID.Hex())
+		return models.NewInternalError(err, "Failed to set room queue options")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrRoomNotFound
+	}
+
+	return nil
+}
+
+// setRoomUserRole updates a user's role in a room's user list. The role lives on the room,
+// so failures are only logged.
+func (r *roomRepository) setRoomUserRole(ctx context.Context, roomID, userID bson.ObjectID, role string) {
+	_, err := r.roomUsersCollection.UpdateOne(ctx, roomAndUserIDs(roomID, userID), bson.D{
+		cmdSet(bson.M{
+			"role":       role,
+			"lastActive": time.Now(),
+		}),
+	})
+	if err != nil {
+		r.logger.Error("Failed to update user role", err, "roomId", roomID.Hex(), "userId", userID.Hex())
+	}
+}
+
 // SearchRooms searches for rooms based on criteria.
 func (r *roomRepository) SearchRooms(ctx context.Context, criteria models.RoomSearchCriteria) ([]*models.Room, int64, error) {
-	filter := bson.M{}
+	// Delisted rooms never show up in discovery
+	filter := bson.M{"delisted": bson.M{"$ne": true}}
 
 	// Apply active filter
 	if criteria.OnlyActive {
@@ -802,6 +1163,11 @@ func (r *roomRepository) SearchRooms(ctx context.Context, criteria models.RoomSe
 		filter["tags"] = bson.M{"$all": criteria.Tags}
 	}
 
+	// Apply language filter
+	if criteria.Language != "" {
+		filter["language.code"] = criteria.Language
+	}
+
 	// Apply text search if query provided
 	if criteria.Query != "" {
 		filter["$text"] = bson.M{"$search": criteria.Query}
@@ -842,6 +1208,8 @@ func (r *roomRepository) SearchRooms(ctx context.Context, criteria models.RoomSe
 		sort["stats.activeUsers"] = -1
 	case "popularity":
 		sort["stats.aggregateRating"] = -1
+	case "heat":
+		sort["stats.heat"] = -1
 	default:
 		// Default sort by activity
 		if len(sort) == 0 {
@@ -884,21 +1252,26 @@ func (r *roomRepository) SearchRooms(ctx context.Context, criteria models.RoomSe
 
 // FindPopularRooms finds the most popular active rooms.
 func (r *roomRepository) FindPopularRooms(ctx context.Context, limit int) ([]*models.Room, error) {
+	// Pop-up rooms don't build up a standing in the directory
 	filter := bson.M{
 		"isActive": true,
+		"delisted": bson.M{"$ne": true},
+		"expiry":   bson.M{"$exists": false},
 	}
 
+	// Heat favors lively rooms over big stagnant ones, size only breaks ties
 	opts := options.Find().
 		SetLimit(int64(limit)).
-		SetSort(bson.M{"stats.activeUsers": -1})
+		SetSort(bson.D{{Key: "stats.heat", Value: -1}, {Key: "stats.activeUsers", Value: -1}})
 
 	return r.FindMany(ctx, filter, opts)
 }
 
-// FindRecentRooms finds recently active rooms.
+// FindRecentRooms finds recently active rooms that are listed in discovery.
 func (r *roomRepository) FindRecentRooms(ctx context.Context, limit int) ([]*models.Room, error) {
 	filter := bson.M{
 		"isActive": true,
+		"delisted": bson.M{"$ne": true},
 	}
 
 	opts := options.Find().
@@ -908,6 +1281,49 @@ func (r *roomRepository) FindRecentRooms(ctx context.Context, limit int) ([]*mod
 	return r.FindMany(ctx, filter, opts)
 }
 
+// UpdateHeat sets the heat of rooms.
+func (r *roomRepository) UpdateHeat(ctx context.Context, heat map[bson.ObjectID]float64) error {
+	if len(heat) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(heat))
+	for roomID, value := range heat {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": roomID}).
+			SetUpdate(bson.D{cmdSet(bson.M{"stats.heat": value})}))
+	}
+
+	if _, err := r.roomCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		r.logger.Error("Failed to update room heat", err, "rooms", len(heat))
+		return models.NewInternalError(err, "Failed to update room heat")
+	}
+
+	return nil
+}
+
+// UpdateDetectedLanguages sets the languages detected from the chat of rooms.
+// Rooms whose owner set their language are left alone.
+func (r *roomRepository) UpdateDetectedLanguages(ctx context.Context, languages map[bson.ObjectID]models.RoomLanguage) error {
+	if len(languages) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(languages))
+	for roomID, language := range languages {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": roomID, "language.manual": bson.M{"$ne": true}}).
+			SetUpdate(bson.D{cmdSet(bson.M{"language": language})}))
+	}
+
+	if _, err := r.roomCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		r.logger.Error("Failed to update room languages", err, "rooms", len(languages))
+		return models.NewInternalError(err, "Failed to update room languages")
+	}
+
+	return nil
+}
+
 func roomAndUserIDs(roomID, userID bson.ObjectID) bson.D {
 	return bson.D{
 		{Key: "roomId", Value: roomID},
@@ -0,0 +1,246 @@
+// Package repositories contains MongoDB repository implementations.
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// Collection names
+const (
+	scrobbleAccountsCollection = "scrobble_accounts"
+	scrobblesCollection        = "scrobbles"
+)
+
+// ScrobbleRepository defines the interface for scrobbling account and scrobble log data access operations.
+type ScrobbleRepository interface {
+	SaveAccount(ctx context.Context, account *models.ScrobbleAccount) error
+	FindAccount(ctx context.Context, userID bson.ObjectID) (*models.ScrobbleAccount, error)
+	FindEnabledAccounts(ctx context.Context, userIDs []bson.ObjectID) ([]*models.ScrobbleAccount, error)
+	SetAccountEnabled(ctx context.Context, userID bson.ObjectID, enabled bool) error
+	DeleteAccount(ctx context.Context, userID bson.ObjectID) error
+	CreateScrobble(ctx context.Context, scrobble *models.Scrobble) error
+	FindScrobbleByID(ctx context.Context, id bson.ObjectID) (*models.Scrobble, error)
+	UpdateScrobble(ctx context.Context, scrobble *models.Scrobble) error
+	FindScrobbles(ctx context.Context, userID bson.ObjectID, skip, limit int) ([]*models.Scrobble, int64, error)
+	DeleteScrobblesBefore(ctx context.Context, before time.Time) (int64, error)
+	DeleteUserScrobbles(ctx context.Context, userID bson.ObjectID) (int64, error)
+}
+
+// scrobbleRepository is the MongoDB implementation of ScrobbleRepository.
+type scrobbleRepository struct {
+	accountsCollection  *mongo.Collection
+	scrobblesCollection *mongo.Collection
+	logger              *utils.Logger
+}
+
+// NewScrobbleRepository creates a new instance of ScrobbleRepository.
+func NewScrobbleRepository(db *mongo.Database, logger *utils.Logger) ScrobbleRepository {
+	return &scrobbleRepository{
+		accountsCollection:  db.Collection(scrobbleAccountsCollection),
+		scrobblesCollection: db.Collection(scrobblesCollection),
+		logger:              logger.Named("scrobble_repository"),
+	}
+}
+
+// SaveAccount links a scrobbling account to its user, replacing the account they linked before.
+func (r *scrobbleRepository) SaveAccount(ctx context.Context, account *models.ScrobbleAccount) error {
+	existing, err := r.FindAccount(ctx, account.UserID)
+	if err != nil && !errors.Is(err, models.ErrScrobbleAccountNotFound) {
+		return err
+	}
+	if existing != nil {
+		account.ID = existing.ID
+		account.CreatedAt = existing.CreatedAt
+		account.UpdateNow()
+	} else {
+		account.ID = bson.NewObjectID()
+		account.CreateNow()
+	}
+
+	filter := bson.M{"userId": account.UserID}
+	_, err = r.accountsCollection.ReplaceOne(ctx, filter, account, options.Replace().SetUpsert(true))
+	if err != nil {
+		r.logger.Error("Failed to save scrobbling account", err, "userId", account.UserID.Hex())
+		return models.NewInternalError(err, "Failed to save scrobbling account")
+	}
+
+	return nil
+}
+
+// FindAccount finds the scrobbling account a user linked.
+func (r *scrobbleRepository) FindAccount(ctx context.Context, userID bson.ObjectID) (*models.ScrobbleAccount, error) {
+	var account models.ScrobbleAccount
+	err := r.accountsCollection.FindOne(ctx, bson.M{"userId": userID}).Decode(&account)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, models.ErrScrobbleAccountNotFound
+		}
+		r.logger.Error("Failed to find scrobbling account", err, "userId", userID.Hex())
+		return nil, models.NewInternalError(err, "Failed to find scrobbling account")
+	}
+
+	return &account, nil
+}
+
+// FindEnabledAccounts finds the scrobbling accounts of the given users that have scrobbling enabled.
+func (r *scrobbleRepository) FindEnabledAccounts(ctx context.Context, userIDs []bson.ObjectID) ([]*models.ScrobbleAccount, error) {
+	cursor, err := r.accountsCollection.Find(ctx, bson.M{"userId": bson.M{"$in": userIDs}, "enabled": true})
+	if err != nil {
+		r.logger.Error("Failed to find scrobbling accounts", err)
+		return nil, models.NewInternalError(err, "Failed to find scrobbling accounts")
+	}
+	defer cursor.Close(ctx)
+
+	accounts := []*models.ScrobbleAccount{}
+	if err = cursor.All(ctx, &accounts); err != nil {
+		r.logger.Error("Failed to decode scrobbling accounts", err)
+		return nil, models.NewInternalError(err, "Failed to decode scrobbling accounts")
+	}
+
+	return accounts, nil
+}
+
+// SetAccountEnabled enables or disables scrobbling a user's plays.
+func (r *scrobbleRepository) SetAccountEnabled(ctx context.Context, userID bson.ObjectID, enabled bool) error {
+	update := bson.M{"$set": bson.M{"enabled": enabled, "updatedAt": time.Now()}}
+
+	result, err := r.accountsCollection.UpdateOne(ctx, bson.M{"userId": userID}, update)
+	if err != nil {
+		r.logger.Error("Failed to update scrobbling account", err, "userId", userID.Hex())
+		return models.NewInternalError(err, "Failed to update scrobbling account")
+	}
+	if result.MatchedCount == 0 {
+		return models.ErrScrobbleAccountNotFound
+	}
+
+	return nil
+}
+
+// DeleteAccount unlinks a user's scrobbling account. Their scrobble log is kept.
+func (r *scrobbleRepository) DeleteAccount(ctx context.Context, userID bson.ObjectID) error {
+	result, err := r.accountsCollection.DeleteOne(ctx, bson.M{"userId": userID})
+	if err != nil {
+		r.logger.Error("Failed to delete scrobbling account", err, "userId", userID.Hex())
+		return models.NewInternalError(err, "Failed to delete scrobbling account")
+	}
+	if result.DeletedCount == 0 {
+		return models.ErrScrobbleAccountNotFound
+	}
+
+	return nil
+}
+
+// CreateScrobble adds a scrobble to its user's scrobble log.
+func (r *scrobbleRepository) CreateScrobble(ctx context.Context, scrobble *models.Scrobble) error {
+	if scrobble.ID.IsZero() {
+		scrobble.ID = bson.NewObjectID()
+	}
+	scrobble.CreateNow()
+
+	_, err := r.scrobblesCollection.InsertOne(ctx, scrobble)
+	if err != nil {
+		r.logger.Error("Failed to create scrobble", err, "userId", scrobble.UserID.Hex(), "playId", scrobble.PlayID.Hex())
+		return models.NewInternalError(err, "Failed to create scrobble")
+	}
+
+	return nil
+}
+
+// FindScrobbleByID finds a scrobble by its ID.
+func (r *scrobbleRepository) FindScrobbleByID(ctx context.Context, id bson.ObjectID) (*models.Scrobble, error) {
+	var scrobble models.Scrobble
+	err := r.scrobblesCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&scrobble)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, models.ErrScrobbleNotFound
+		}
+		r.logger.Error("Failed to find scrobble", err, "id", id.Hex())
+		return nil, models.NewInternalError(err, "Failed to find scrobble")
+	}
+
+	return &scrobble, nil
+}
+
+// UpdateScrobble records the outcome of submitting a scrobble.
+func (r *scrobbleRepository) UpdateScrobble(ctx context.Context, scrobble *models.Scrobble) error {
+	scrobble.UpdateNow()
+	update := bson.M{"$set": bson.M{
+		"status":      scrobble.Status,
+		"attempts":    scrobble.Attempts,
+		"error":       scrobble.Error,
+		"submittedAt": scrobble.SubmittedAt,
+		"updatedAt":   scrobble.UpdatedAt,
+	}}
+
+	result, err := r.scrobblesCollection.UpdateByID(ctx, scrobble.ID, update)
+	if err != nil {
+		r.logger.Error("Failed to update scrobble", err, "id", scrobble.ID.Hex())
+		return models.NewInternalError(err, "Failed to update scrobble")
+	}
+	if result.MatchedCount == 0 {
+		return models.ErrScrobbleNotFound
+	}
+
+	return nil
+}
+
+// FindScrobbles finds a user's scrobbles, most recently played first, along with the total number.
+func (r *scrobbleRepository) FindScrobbles(ctx context.Context, userID bson.ObjectID, skip, limit int) ([]*models.Scrobble, int64, error) {
+	query := bson.M{"userId": userID}
+
+	total, err := r.scrobblesCollection.CountDocuments(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to count scrobbles", err, "userId", userID.Hex())
+		return nil, 0, models.NewInternalError(err, "Failed to count scrobbles")
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"playedAt": -1}).
+		SetSkip(int64(skip)).
+		SetLimit(int64(limit))
+
+	cursor, err := r.scrobblesCollection.Find(ctx, query, opts)
+	if err != nil {
+		r.logger.Error("Failed to find scrobbles", err, "userId", userID.Hex())
+		return nil, 0, models.NewInternalError(err, "Failed to find scrobbles")
+	}
+	defer cursor.Close(ctx)
+
+	scrobbles := []*models.Scrobble{}
+	if err = cursor.All(ctx, &scrobbles); err != nil {
+		r.logger.Error("Failed to decode scrobbles", err, "userId", userID.Hex())
+		return nil, 0, models.NewInternalError(err, "Failed to decode scrobbles")
+	}
+
+	return scrobbles, total, nil
+}
+
+// DeleteScrobblesBefore deletes the scrobbles logged before a time, and returns how many it deleted.
+func (r *scrobbleRepository) DeleteScrobblesBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.scrobblesCollection.DeleteMany(ctx, bson.M{"createdAt": bson.M{"$lt": before}})
+	if err != nil {
+		r.logger.Error("Failed to delete old scrobbles", err)
+		return 0, models.NewInternalError(err, "Failed to delete scrobbles")
+	}
+
+	return result.DeletedCount, nil
+}
+
+// DeleteUserScrobbles deletes a user's scrobble log, and returns how many scrobbles it deleted.
+func (r *scrobbleRepository) DeleteUserScrobbles(ctx context.Context, userID bson.ObjectID) (int64, error) {
+	result, err := r.scrobblesCollection.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		r.logger.Error("Failed to delete user's scrobbles", err, "userId", userID.Hex())
+		return 0, models.NewInternalError(err, "Failed to delete scrobbles")
+	}
+
+	return result.DeletedCount, nil
+}
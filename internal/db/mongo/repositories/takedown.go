@@ -0,0 +1,150 @@
+// Package repositories contains MongoDB repository implementations.
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// Collection names
+const (
+	mediaTakedownsCollection = "media_takedowns"
+)
+
+// TakedownRepository defines the interface for media takedown data access operations.
+type TakedownRepository interface {
+	CreateTakedown(ctx context.Context, takedown *models.MediaTakedown) error
+	FindTakedownByID(ctx context.Context, id bson.ObjectID) (*models.MediaTakedown, error)
+	FindTakedownBySource(ctx context.Context, sourceType, sourceID string) (*models.MediaTakedown, error)
+	FindTakedowns(ctx context.Context, filter models.MediaTakedownFilter, skip, limit int) ([]*models.MediaTakedown, int64, error)
+	SetTakedownCounts(ctx context.Context, id bson.ObjectID, playlists, items int) error
+}
+
+// takedownRepository is the MongoDB implementation of TakedownRepository.
+type takedownRepository struct {
+	takedownsCollection *mongo.Collection
+	logger              *utils.Logger
+}
+
+// NewTakedownRepository creates a new instance of TakedownRepository.
+func NewTakedownRepository(db *mongo.Database, logger *utils.Logger) TakedownRepository {
+	return &takedownRepository{
+		takedownsCollection: db.Collection(mediaTakedownsCollection),
+		logger:              logger.Named("takedown_repository"),
+	}
+}
+
+// CreateTakedown records a new media takedown. A source can only be taken down once.
+func (r *takedownRepository) CreateTakedown(ctx context.Context, takedown *models.MediaTakedown) error {
+	if takedown.ID.IsZero() {
+		takedown.ID = bson.NewObjectID()
+	}
+	takedown.CreateNow()
+
+	_, err := r.takedownsCollection.InsertOne(ctx, takedown)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrAlreadyTakenDown
+		}
+		r.logger.Error("Failed to create media takedown", err, "type", takedown.Type, "sourceId", takedown.SourceID)
+		return models.NewInternalError(err, "Failed to create media takedown")
+	}
+
+	return nil
+}
+
+// FindTakedownByID finds a media takedown by its ID.
+func (r *takedownRepository) FindTakedownByID(ctx context.Context, id bson.ObjectID) (*models.MediaTakedown, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+// FindTakedownBySource finds the takedown of a media source.
+func (r *takedownRepository) FindTakedownBySource(ctx context.Context, sourceType, sourceID string) (*models.MediaTakedown, error) {
+	return r.findOne(ctx, bson.M{"type": sourceType, "sourceId": sourceID})
+}
+
+// findOne finds the media takedown matching a query.
+func (r *takedownRepository) findOne(ctx context.Context, query bson.M) (*models.MediaTakedown, error) {
+	var takedown models.MediaTakedown
+
+	err := r.takedownsCollection.FindOne(ctx, query).Decode(&takedown)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, models.ErrTakedownNotFound
+		}
+		r.logger.Error("Failed to find media takedown", err)
+		return nil, models.NewInternalError(err, "Failed to find media takedown")
+	}
+
+	return &takedown, nil
+}
+
+// FindTakedowns finds media takedowns, newest first, along with the total number matching.
+func (r *takedownRepository) FindTakedowns(ctx context.Context, filter models.MediaTakedownFilter, skip, limit int) ([]*models.MediaTakedown, int64, error) {
+	query := mediaTakedownQuery(filter)
+
+	total, err := r.takedownsCollection.CountDocuments(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to count media takedowns", err)
+		return nil, 0, models.NewInternalError(err, "Failed to count media takedowns")
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"createdAt": -1}).
+		SetSkip(int64(skip)).
+		SetLimit(int64(limit))
+
+	cursor, err := r.takedownsCollection.Find(ctx, query, opts)
+	if err != nil {
+		r.logger.Error("Failed to find media takedowns", err)
+		return nil, 0, models.NewInternalError(err, "Failed to find media takedowns")
+	}
+	defer cursor.Close(ctx)
+
+	takedowns := []*models.MediaTakedown{}
+	if err = cursor.All(ctx, &takedowns); err != nil {
+		r.logger.Error("Failed to decode media takedowns", err)
+		return nil, 0, models.NewInternalError(err, "Failed to decode media takedowns")
+	}
+
+	return takedowns, total, nil
+}
+
+// SetTakedownCounts records how many playlists and playlist items a takedown flagged.
+func (r *takedownRepository) SetTakedownCounts(ctx context.Context, id bson.ObjectID, playlists, items int) error {
+	result, err := r.takedownsCollection.UpdateByID(ctx, id, bson.D{
+		cmdSet(bson.M{"playlists": playlists, "items": items, "updatedAt": time.Now()}),
+	})
+	if err != nil {
+		r.logger.Error("Failed to set media takedown counts", err, "id", id.Hex())
+		return models.NewInternalError(err, "Failed to set media takedown counts")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrTakedownNotFound
+	}
+
+	return nil
+}
+
+// mediaTakedownQuery builds the query for a media takedown filter.
+func mediaTakedownQuery(filter models.MediaTakedownFilter) bson.M {
+	query := bson.M{}
+	if filter.Type != "" {
+		query["type"] = filter.Type
+	}
+	if filter.SourceID != "" {
+		query["sourceId"] = filter.SourceID
+	}
+	if filter.Reason != "" {
+		query["reason"] = filter.Reason
+	}
+	return query
+}
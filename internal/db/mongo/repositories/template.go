@@ -0,0 +1,186 @@
+// Package repositories contains MongoDB repository implementations.
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// Collection names
+const (
+	messageTemplatesCollection = "message_templates"
+)
+
+// maxTemplateVersionAttempts is how many times creating a template version is attempted when another
+// version of the same template in the same locale was created at the same time.
+const maxTemplateVersionAttempts = 3
+
+// TemplateRepository defines the interface for message template data access operations.
+type TemplateRepository interface {
+	CreateVersion(ctx context.Context, template *models.MessageTemplate) error
+	FindActive(ctx context.Context, name, locale string) (*models.MessageTemplate, error)
+	FindVersion(ctx context.Context, name, locale string, version int) (*models.MessageTemplate, error)
+	FindTemplates(ctx context.Context, filter models.MessageTemplateFilter) ([]*models.MessageTemplate, error)
+	ActivateVersion(ctx context.Context, name, locale string, version int) (*models.MessageTemplate, error)
+}
+
+// templateRepository is the MongoDB implementation of TemplateRepository.
+type templateRepository struct {
+	templatesCollection *mongo.Collection
+	logger              *utils.Logger
+}
+
+// NewTemplateRepository creates a new instance of TemplateRepository.
+func NewTemplateRepository(db *mongo.Database, logger *utils.Logger) TemplateRepository {
+	return &templateRepository{
+		templatesCollection: db.Collection(messageTemplatesCollection),
+		logger:              logger.Named("template_repository"),
+	}
+}
+
+// CreateVersion creates the next version of a message template in its locale. The version is numbered
+// here, and the unique index on name, locale and version keeps two versions from getting the same number.
+func (r *templateRepository) CreateVersion(ctx context.Context, template *models.MessageTemplate) error {
+	for attempt := 1; ; attempt++ {
+		latest, err := r.latestVersion(ctx, template.Name, template.Locale)
+		if err != nil {
+			return err
+		}
+
+		template.ID = bson.NewObjectID()
+		template.Version = latest + 1
+		template.CreateNow()
+
+		_, err = r.templatesCollection.InsertOne(ctx, template)
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) || attempt == maxTemplateVersionAttempts {
+			r.logger.Error("Failed to create message template version", err, "name", template.Name, "locale", template.Locale)
+			return models.NewInternalError(err, "Failed to create message template version")
+		}
+	}
+}
+
+// latestVersion gets the number of the latest version of a message template in a locale, 0 when it has none.
+func (r *templateRepository) latestVersion(ctx context.Context, name, locale string) (int, error) {
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "version", Value: -1}}).
+		SetProjection(bson.M{"version": 1})
+
+	var latest models.MessageTemplate
+	err := r.templatesCollection.FindOne(ctx, bson.M{"name": name, "locale": locale}, opts).Decode(&latest)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, nil
+		}
+		r.logger.Error("Failed to find latest message template version", err, "name", name, "locale", locale)
+		return 0, models.NewInternalError(err, "Failed to find message template")
+	}
+
+	return latest.Version, nil
+}
+
+// FindActive finds the version of a message template sent in a locale.
+func (r *templateRepository) FindActive(ctx context.Context, name, locale string) (*models.MessageTemplate, error) {
+	return r.findOne(ctx, bson.M{"name": name, "locale": locale, "active": true})
+}
+
+// FindVersion finds a version of a message template in a locale.
+func (r *templateRepository) FindVersion(ctx context.Context, name, locale string, version int) (*models.MessageTemplate, error) {
+	return r.findOne(ctx, bson.M{"name": name, "locale": locale, "version": version})
+}
+
+// findOne finds the message template matching a query.
+func (r *templateRepository) findOne(ctx context.Context, query bson.M) (*models.MessageTemplate, error) {
+	var template models.MessageTemplate
+
+	err := r.templatesCollection.FindOne(ctx, query).Decode(&template)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, models.ErrTemplateNotFound
+		}
+		r.logger.Error("Failed to find message template", err, "name", query["name"], "locale", query["locale"])
+		return nil, models.NewInternalError(err, "Failed to find message template")
+	}
+
+	return &template, nil
+}
+
+// FindTemplates finds message templates by name and locale, newest version first.
+func (r *templateRepository) FindTemplates(ctx context.Context, filter models.MessageTemplateFilter) ([]*models.MessageTemplate, error) {
+	opts := options.Find().SetSort(bson.D{
+		{Key: "name", Value: 1},
+		{Key: "locale", Value: 1},
+		{Key: "version", Value: -1},
+	})
+
+	cursor, err := r.templatesCollection.Find(ctx, messageTemplateQuery(filter), opts)
+	if err != nil {
+		r.logger.Error("Failed to find message templates", err)
+		return nil, models.NewInternalError(err, "Failed to find message templates")
+	}
+	defer cursor.Close(ctx)
+
+	templates := []*models.MessageTemplate{}
+	if err = cursor.All(ctx, &templates); err != nil {
+		r.logger.Error("Failed to decode message templates", err)
+		return nil, models.NewInternalError(err, "Failed to decode message templates")
+	}
+
+	return templates, nil
+}
+
+// ActivateVersion makes a version of a message template the one sent in its locale, and returns it.
+func (r *templateRepository) ActivateVersion(ctx context.Context, name, locale string, version int) (*models.MessageTemplate, error) {
+	now := time.Now()
+
+	// The new version is activated before the others are deactivated, so the locale is never without one
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var template models.MessageTemplate
+	err := r.templatesCollection.FindOneAndUpdate(ctx,
+		bson.M{"name": name, "locale": locale, "version": version},
+		bson.D{cmdSet(bson.M{"active": true, "updatedAt": now})},
+		opts,
+	).Decode(&template)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, models.ErrTemplateNotFound
+		}
+		r.logger.Error("Failed to activate message template version", err, "name", name, "locale", locale, "version", version)
+		return nil, models.NewInternalError(err, "Failed to activate message template version")
+	}
+
+	_, err = r.templatesCollection.UpdateMany(ctx,
+		bson.M{"name": name, "locale": locale, "version": bson.M{"$ne": version}, "active": true},
+		bson.D{cmdSet(bson.M{"active": false, "updatedAt": now})},
+	)
+	if err != nil {
+		r.logger.Error("Failed to deactivate message template versions", err, "name", name, "locale", locale)
+		return nil, models.NewInternalError(err, "Failed to deactivate message template versions")
+	}
+
+	return &template, nil
+}
+
+// messageTemplateQuery builds the query for a message template filter.
+func messageTemplateQuery(filter models.MessageTemplateFilter) bson.M {
+	query := bson.M{}
+	if filter.Name != "" {
+		query["name"] = filter.Name
+	}
+	if filter.Locale != "" {
+		query["locale"] = filter.Locale
+	}
+	if filter.ActiveOnly {
+		query["active"] = true
+	}
+	return query
+}
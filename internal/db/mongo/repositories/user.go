@@ -33,6 +33,9 @@ type UserRepository interface {
 	// FindByUsername finds a user by their username.
 	FindByUsername(ctx context.Context, username string) (*models.User, error)
 
+	// FindByOAuthIdentity finds the user a third-party account is linked to.
+	FindByOAuthIdentity(ctx context.Context, provider, subject string) (*models.User, error)
+
 	// FindMany finds multiple users based on query filters.
 	FindMany(ctx context.Context, filter bson.M, options options.Lister[options.FindOptions]) ([]*models.User, error)
 
@@ -48,21 +51,6 @@ type UserRepository interface {
 	// CountUsers counts the number of users that match the given filter.
 	CountUsers(ctx context.Context, filter bson.M) (int64, error)
 
-	// FindFollowing finds users that the given user is following.
-	FindFollowing(ctx context.Context, userID bson.ObjectID, skip, limit int) ([]*models.User, error)
-
-	// FindFollowers finds users that follow the given user.
-	FindFollowers(ctx context.Context, userID bson.ObjectID, skip, limit int) ([]*models.User, error)
-
-	// IsFollowing checks if a user is following another user.
-	IsFollowing(ctx context.Context, userID, targetID bson.ObjectID) (bool, error)
-
-	// Follow makes a user follow another user.
-	Follow(ctx context.Context, userID, targetID bson.ObjectID) error
-
-	// Unfollow makes a user unfollow another user.
-	Unfollow(ctx context.Context, userID, targetID bson.ObjectID) error
-
 	// UpdateAvatar updates a user's avatar configuration.
 	UpdateAvatar(ctx context.Context, userID bson.ObjectID, avatar models.AvatarConfig) error
 
@@ -78,14 +66,84 @@ type UserRepository interface {
 	// UpdateStats updates a user's statistics.
 	UpdateStats(ctx context.Context, userID bson.ObjectID, updates bson.M) error
 
+	// AddAudienceTime adds the same listening time in seconds to several users.
+	AddAudienceTime(ctx context.Context, userIDs []bson.ObjectID, seconds int64) error
+
+	// SetLevel raises a user's level from one level to another, unless their level changed meanwhile.
+	// It reports whether the level was set, so a level-up is only counted once.
+	SetLevel(ctx context.Context, userID bson.ObjectID, from, to int) (bool, error)
+
 	// SetActive sets a user's active status.
 	SetActive(ctx context.Context, userID bson.ObjectID, active bool) error
 
 	// SetVerified sets a user's verified status.
 	SetVerified(ctx context.Context, userID bson.ObjectID, verified bool) error
 
+	// SetVerifiedBadge sets a user's verified artist or label badge, or takes it away when badge is nil.
+	SetVerifiedBadge(ctx context.Context, userID bson.ObjectID, badge *models.VerifiedBadge) error
+
+	// SetTrustOverride pins a user's trust level as set by an admin, or clears the override when level is nil.
+	SetTrustOverride(ctx context.Context, userID bson.ObjectID, level *models.TrustLevel, adminID bson.ObjectID) error
+
+	// AddStrike records a moderation action against a user at the given time.
+	AddStrike(ctx context.Context, userID bson.ObjectID, at time.Time) error
+
+	// SetAgeAttestation records a user's attestation of their date of birth. It fails with
+	// models.ErrAgeAlreadyAttested if the user already attested it.
+	SetAgeAttestation(ctx context.Context, userID bson.ObjectID, attestation models.AgeAttestation) error
+
+	// LinkOAuthIdentity links a third-party account to a user. It fails with models.ErrOAuthAlreadyLinked
+	// if the user already has an account with the same provider linked.
+	LinkOAuthIdentity(ctx context.Context, userID bson.ObjectID, identity models.OAuthIdentity) error
+
+	// AddAPIKey adds a personal API key to a user.
+	AddAPIKey(ctx context.Context, userID bson.ObjectID, key models.APIKey) error
+
+	// RemoveAPIKey removes one of a user's API keys. It fails with models.ErrAPIKeyNotFound if the user
+	// has no such key.
+	RemoveAPIKey(ctx context.Context, userID, keyID bson.ObjectID) error
+
+	// TouchAPIKey records when one of a user's API keys was last used.
+	TouchAPIKey(ctx context.Context, userID, keyID bson.ObjectID, at time.Time) error
+
+	// RenameUser renames a user still named as the change's username, recording the change in their
+	// username history and keeping the latest historySize changes. It fails with models.ErrUsernameChanged
+	// if the user was renamed meanwhile.
+	RenameUser(ctx context.Context, userID bson.ObjectID, username string, change models.UsernameChange, historySize int) error
+
 	// FindInactive finds users who haven't logged in for the specified duration.
 	FindInactive(ctx context.Context, duration time.Duration, limit int) ([]*models.User, error)
+
+	// SetDeletion schedules a user's account for deletion, or cancels its deletion when deletion is nil.
+	SetDeletion(ctx context.Context, userID bson.ObjectID, deletion *models.AccountDeletion) error
+
+	// FindDueDeletions finds users whose account deletion is due by the given time, the longest due first.
+	FindDueDeletions(ctx context.Context, before time.Time, limit int) ([]*models.User, error)
+
+	// ClaimGuest marks an unlinked guest as being registered, so only one registration can link it. Claims
+	// made before staleBefore are taken over. It fails with models.ErrGuestAlreadyLinked if the guest is
+	// linked, closed or claimed.
+	ClaimGuest(ctx context.Context, guestID bson.ObjectID, staleBefore time.Time) error
+
+	// ReleaseGuest drops the claim on a guest whose registration failed, so it can register again.
+	ReleaseGuest(ctx context.Context, guestID bson.ObjectID) error
+
+	// LinkGuest links a claimed guest to the account it registered and closes it.
+	LinkGuest(ctx context.Context, guestID, userID bson.ObjectID, at time.Time) error
+
+	// RemoveConnections removes a user from every other user's following, followers, friends and blocked lists.
+	RemoveConnections(ctx context.Context, userID bson.ObjectID) error
+
+	// ReplaceConnection replaces a user with another in every other user's following, followers and friends
+	// lists. The replacement isn't added to its own lists.
+	ReplaceConnection(ctx context.Context, fromID, toID bson.ObjectID) error
+
+	// AddConnections adds the users and rooms in connections to a user's own connections, skipping those
+	// already there.
+	AddConnections(ctx context.Context, userID bson.ObjectID, connections models.UserConnections) error
+
+	// ClearConnections empties a user's own connections.
+	ClearConnections(ctx context.Context, userID bson.ObjectID) error
 }
 
 // userRepository is the MongoDB implementation of UserRepository.
@@ -166,6 +224,23 @@ func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models
 	return &user, nil
 }
 
+// FindByOAuthIdentity finds the user a third-party account is linked to.
+func (r *userRepository) FindByOAuthIdentity(ctx context.Context, provider, subject string) (*models.User, error) {
+	var user models.User
+
+	filter := bson.M{"oauthIdentities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}}}
+	err := r.collection.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, models.ErrUserNotFound
+		}
+		r.logger.Error("Failed to find user by OAuth identity", err, "provider", provider)
+		return nil, models.NewInternalError(err, "Failed to find user")
+	}
+
+	return &user, nil
+}
+
 // FindByUsername finds a user by their username.
 func (r *userRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
@@ -283,161 +358,6 @@ func (r *userRepository) CountUsers(ctx context.Context, filter bson.M) (int64,
 	return count, nil
 }
 
-// FindFollowing finds users that the given user is following.
-func (r *userRepository) FindFollowing(ctx context.Context, userID bson.ObjectID, skip, limit int) ([]*models.User, error) {
-	// First get the user to retrieve their following list
-	user, err := r.FindByID(ctx, userID)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(user.Connections.Following) == 0 {
-		return []*models.User{}, nil
-	}
-
-	// Query users that are in the following list
-	opts := options.Find().
-		SetSkip(int64(skip)).
-		SetLimit(int64(limit)).
-		SetSort(bson.M{"username": 1})
-
-	return r.FindMany(ctx, bson.M{"_id": bson.M{"$in": user.Connections.Following}}, opts)
-}
-
-// FindFollowers finds users that follow the given user.
-func (r *userRepository) FindFollowers(ctx context.Context, userID bson.ObjectID, skip, limit int) ([]*models.User, error) {
-	opts := options.Find().
-		SetSkip(int64(skip)).
-		SetLimit(int64(limit)).
-		SetSort(bson.M{"username": 1})
-
-	return r.FindMany(ctx, bson.M{"connections.following": userID}, opts)
-}
-
-// IsFollowing checks if a user is following another user.
-func (r *userRepository) IsFollowing(ctx context.Context, userID, targetID bson.ObjectID) (bool, error) {
-	count, err := r.collection.CountDocuments(ctx, bson.M{
-		"_id":                   userID,
-		"connections.following": targetID,
-	})
-
-	if err != nil {
-		r.logger.Error("Failed to check if user is following", err, "userID", userID.Hex(), "targetID", targetID.Hex())
-		return false, models.NewInternalError(err, "Failed to check following status")
-	}
-
-	return count > 0, nil
-}
-
-// Follow makes a user follow another user.
-func (r *userRepository) Follow(ctx context.Context, userID, targetID bson.ObjectID) error {
-	// Check if already following
-	isFollowing, err := r.IsFollowing(ctx, userID, targetID)
-	if err != nil {
-		return err
-	}
-
-	if isFollowing {
-		return nil // Already following, no error
-	}
-
-	// Update the user's following list
-	result, err := r.collection.UpdateByID(ctx,
-		userID,
-		bson.D{
-			cmdAddToSet(bson.M{"connections.following": targetID}),
-			cmdSet(bson.M{"updatedAt": time.Now()}),
-		},
-	)
-
-	if err != nil {
-		r.logger.Error("Failed to follow user", err, "userID", userID.Hex(), "targetID", targetID.Hex())
-		return models.NewInternalError(err, "Failed to follow user")
-	}
-
-	if result.MatchedCount == 0 {
-		return models.ErrUserNotFound
-	}
-
-	// Check if mutual follow and update friends list for both users
-	isFollower, err := r.IsFollowing(ctx, targetID, userID)
-	if err != nil {
-		return err
-	}
-
-	if isFollower {
-		// Mutual follow, add to friends lists
-		_, err = r.collection.UpdateMany(ctx,
-			bson.M{"_id": bson.M{"$in": []bson.ObjectID{userID, targetID}}},
-			bson.M{
-				"$addToSet": bson.M{"connections.friends": bson.M{"$cond": bson.A{
-					bson.D{{Key: "$eq", Value: []any{"$_id", userID}}},
-					targetID,
-					userID,
-				}}},
-			},
-		)
-
-		if err != nil {
-			r.logger.Error("Failed to update friends lists", err, "userID", userID.Hex(), "targetID", targetID.Hex())
-			return models.NewInternalError(err, "Failed to update friends lists")
-		}
-	}
-
-	// Update the target user's followers list
-	_, err = r.collection.UpdateByID(ctx,
-		targetID,
-		bson.D{
-			cmdAddToSet(bson.M{"connections.followers": userID}),
-			cmdSet(bson.M{"updatedAt": time.Now()}),
-		},
-	)
-
-	if err != nil {
-		r.logger.Error("Failed to update follower list", err, "targetID", targetID.Hex(), "userID", userID.Hex())
-		return models.NewInternalError(err, "Failed to update follower list")
-	}
-
-	return nil
-}
-
-// Unfollow makes a user unfollow another user.
-func (r *userRepository) Unfollow(ctx context.Context, userID, targetID bson.ObjectID) error {
-	// Update the user's following list
-	result, err := r.collection.UpdateByID(ctx,
-		userID,
-		bson.D{
-			cmdPull(bson.M{"connections.following": targetID, "connections.friends": targetID}),
-			cmdSet(bson.M{"updatedAt": time.Now()}),
-		},
-	)
-
-	if err != nil {
-		r.logger.Error("Failed to unfollow user", err, "userID", userID.Hex(), "targetID", targetID.Hex())
-		return models.NewInternalError(err, "Failed to unfollow user")
-	}
-
-	if result.MatchedCount == 0 {
-		return models.ErrUserNotFound
-	}
-
-	// Update the target user's followers list and friends list
-	_, err = r.collection.UpdateByID(ctx,
-		targetID,
-		bson.D{
-			cmdPull(bson.M{"connections.followers": userID, "connections.friends": userID}),
-			cmdSet(bson.M{"updatedAt": time.Now()}),
-		},
-	)
-
-	if err != nil {
-		r.logger.Error("Failed to update follower list", err, "targetID", targetID.Hex(), "userID", userID.Hex())
-		return models.NewInternalError(err, "Failed to update follower list")
-	}
-
-	return nil
-}
-
 // UpdateAvatar updates a user's avatar configuration.
 func (r *userRepository) UpdateAvatar(ctx context.Context, userID bson.ObjectID, avatar models.AvatarConfig) error {
 	update := bson.D{
@@ -554,6 +474,45 @@ func (r *userRepository) UpdateStats(ctx context.Context, userID bson.ObjectID,
 	return nil
 }
 
+// AddAudienceTime adds the same listening time in seconds to several users.
+func (r *userRepository) AddAudienceTime(ctx context.Context, userIDs []bson.ObjectID, seconds int64) error {
+	now := time.Now()
+	update := bson.D{
+		cmdInc(bson.M{"stats.audienceTime": seconds}),
+		cmdSet(bson.M{"stats.lastUpdated": now, "updatedAt": now}),
+	}
+
+	if _, err := r.collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": userIDs}}, update); err != nil {
+		r.logger.Error("Failed to add audience time", err, "users", len(userIDs))
+		return models.NewInternalError(err, "Failed to add audience time")
+	}
+
+	return nil
+}
+
+// SetLevel raises a user's level from one level to another, unless their level changed meanwhile.
+func (r *userRepository) SetLevel(ctx context.Context, userID bson.ObjectID, from, to int) (bool, error) {
+	filter := bson.M{
+		"_id":         userID,
+		"stats.level": from,
+	}
+	update := bson.D{
+		cmdSet(bson.M{
+			"stats.level":       to,
+			"stats.lastUpdated": time.Now(),
+			"updatedAt":         time.Now(),
+		}),
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		r.logger.Error("Failed to set level", err, "userID", userID.Hex(), "level", to)
+		return false, models.NewInternalError(err, "Failed to set level")
+	}
+
+	return result.ModifiedCount > 0, nil
+}
+
 // SetActive sets a user's active status.
 func (r *userRepository) SetActive(ctx context.Context, userID bson.ObjectID, active bool) error {
 	update := bson.D{
@@ -598,6 +557,262 @@ func (r *userRepository) SetVerified(ctx context.Context, userID bson.ObjectID,
 	return nil
 }
 
+// SetVerifiedBadge sets a user's verified artist or label badge, or takes it away when badge is nil.
+func (r *userRepository) SetVerifiedBadge(ctx context.Context, userID bson.ObjectID, badge *models.VerifiedBadge) error {
+	update := bson.D{
+		cmdSet(bson.M{
+			"verified":  badge,
+			"updatedAt": time.Now(),
+		}),
+	}
+
+	result, err := r.collection.UpdateByID(ctx, userID, update)
+	if err != nil {
+		r.logger.Error("Failed to set verified badge", err, "userID", userID.Hex())
+		return models.NewInternalError(err, "Failed to set verified badge")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SetTrustOverride pins a user's trust level as set by an admin, or clears the override when level is nil.
+func (r *userRepository) SetTrustOverride(ctx context.Context, userID bson.ObjectID, level *models.TrustLevel, adminID bson.ObjectID) error {
+	update := bson.D{
+		cmdSet(bson.M{
+			"trust.override":     level,
+			"trust.overriddenBy": adminID,
+			"updatedAt":          time.Now(),
+		}),
+	}
+	if level == nil {
+		update = bson.D{
+			cmdUnset(bson.M{"trust.override": "", "trust.overriddenBy": ""}),
+			cmdSet(bson.M{"updatedAt": time.Now()}),
+		}
+	}
+
+	result, err := r.collection.UpdateByID(ctx, userID, update)
+	if err != nil {
+		r.logger.Error("Failed to set trust override", err, "userID", userID.Hex())
+		return models.NewInternalError(err, "Failed to set trust override")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// AddStrike records a moderation action against a user at the given time.
+func (r *userRepository) AddStrike(ctx context.Context, userID bson.ObjectID, at time.Time) error {
+	update := bson.D{
+		cmdInc(bson.M{"trust.strikes": 1}),
+		cmdSet(bson.M{
+			"trust.lastStrike": at,
+			"updatedAt":        time.Now(),
+		}),
+	}
+
+	result, err := r.collection.UpdateByID(ctx, userID, update)
+	if err != nil {
+		r.logger.Error("Failed to add strike", err, "userID", userID.Hex())
+		return models.NewInternalError(err, "Failed to add strike")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SetAgeAttestation records a user's attestation of their date of birth, unless they already attested it.
+func (r *userRepository) SetAgeAttestation(ctx context.Context, userID bson.ObjectID, attestation models.AgeAttestation) error {
+	filter := bson.M{
+		"_id":            userID,
+		"ageAttestation": bson.M{"$exists": false},
+	}
+	update := bson.D{
+		cmdSet(bson.M{
+			"ageAttestation": attestation,
+			"updatedAt":      time.Now(),
+		}),
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		r.logger.Error("Failed to set age attestation", err, "userID", userID.Hex())
+		return models.NewInternalError(err, "Failed to set age attestation")
+	}
+
+	if result.MatchedCount == 0 {
+		count, err := r.collection.CountDocuments(ctx, bson.M{"_id": userID})
+		if err != nil {
+			r.logger.Error("Failed to check user", err, "userID", userID.Hex())
+			return models.NewInternalError(err, "Failed to set age attestation")
+		}
+		if count == 0 {
+			return models.ErrUserNotFound
+		}
+		return models.ErrAgeAlreadyAttested
+	}
+
+	return nil
+}
+
+// LinkOAuthIdentity links a third-party account to a user, unless they have one with the same provider linked.
+func (r *userRepository) LinkOAuthIdentity(ctx context.Context, userID bson.ObjectID, identity models.OAuthIdentity) error {
+	filter := bson.M{
+		"_id":                      userID,
+		"oauthIdentities.provider": bson.M{"$ne": identity.Provider},
+	}
+	update := bson.D{
+		{Key: "$push", Value: bson.M{"oauthIdentities": identity}},
+		cmdSet(bson.M{"updatedAt": time.Now()}),
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		r.logger.Error("Failed to link OAuth identity", err, "userID", userID.Hex(), "provider", identity.Provider)
+		return models.NewInternalError(err, "Failed to link OAuth identity")
+	}
+
+	if result.MatchedCount == 0 {
+		count, err := r.collection.CountDocuments(ctx, bson.M{"_id": userID})
+		if err != nil {
+			r.logger.Error("Failed to check user", err, "userID", userID.Hex())
+			return models.NewInternalError(err, "Failed to link OAuth identity")
+		}
+		if count == 0 {
+			return models.ErrUserNotFound
+		}
+		return models.ErrOAuthAlreadyLinked
+	}
+
+	return nil
+}
+
+// AddAPIKey adds a personal API key to a user.
+func (r *userRepository) AddAPIKey(ctx context.Context, userID bson.ObjectID, key models.APIKey) error {
+	update := bson.D{
+		{Key: "$push", Value: bson.M{"apiKeys": key}},
+		cmdSet(bson.M{"updatedAt": time.Now()}),
+	}
+
+	result, err := r.collection.UpdateByID(ctx, userID, update)
+	if err != nil {
+		r.logger.Error("Failed to add API key", err, "userID", userID.Hex(), "keyID", key.ID.Hex())
+		return models.NewInternalError(err, "Failed to add API key")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// RemoveAPIKey removes one of a user's API keys.
+func (r *userRepository) RemoveAPIKey(ctx context.Context, userID, keyID bson.ObjectID) error {
+	filter := bson.M{
+		"_id":         userID,
+		"apiKeys._id": keyID,
+	}
+	update := bson.D{
+		cmdPull(bson.M{"apiKeys": bson.M{"_id": keyID}}),
+		cmdSet(bson.M{"updatedAt": time.Now()}),
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		r.logger.Error("Failed to remove API key", err, "userID", userID.Hex(), "keyID", keyID.Hex())
+		return models.NewInternalError(err, "Failed to remove API key")
+	}
+
+	if result.MatchedCount == 0 {
+		count, err := r.collection.CountDocuments(ctx, bson.M{"_id": userID})
+		if err != nil {
+			r.logger.Error("Failed to check user", err, "userID", userID.Hex())
+			return models.NewInternalError(err, "Failed to remove API key")
+		}
+		if count == 0 {
+			return models.ErrUserNotFound
+		}
+		return models.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// TouchAPIKey records when one of a user's API keys was last used.
+func (r *userRepository) TouchAPIKey(ctx context.Context, userID, keyID bson.ObjectID, at time.Time) error {
+	filter := bson.M{
+		"_id":         userID,
+		"apiKeys._id": keyID,
+	}
+	update := bson.D{
+		cmdSet(bson.M{"apiKeys.$.lastUsedAt": at}),
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		r.logger.Error("Failed to record API key usage", err, "userID", userID.Hex(), "keyID", keyID.Hex())
+		return models.NewInternalError(err, "Failed to record API key usage")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// RenameUser renames a user still named as the change's username, recording it in their username history.
+func (r *userRepository) RenameUser(ctx context.Context, userID bson.ObjectID, username string, change models.UsernameChange, historySize int) error {
+	filter := bson.M{
+		"_id":      userID,
+		"username": change.Username,
+	}
+	update := bson.D{
+		cmdSet(bson.M{
+			"username":  username,
+			"updatedAt": time.Now(),
+		}),
+		{Key: "$push", Value: bson.M{"usernameHistory": bson.M{
+			"$each":  []models.UsernameChange{change},
+			"$slice": -historySize,
+		}}},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrUsernameAlreadyExists
+		}
+		r.logger.Error("Failed to rename user", err, "userID", userID.Hex(), "username", username)
+		return models.NewInternalError(err, "Failed to rename user")
+	}
+
+	if result.MatchedCount == 0 {
+		count, err := r.collection.CountDocuments(ctx, bson.M{"_id": userID})
+		if err != nil {
+			r.logger.Error("Failed to check user", err, "userID", userID.Hex())
+			return models.NewInternalError(err, "Failed to rename user")
+		}
+		if count == 0 {
+			return models.ErrUserNotFound
+		}
+		return models.ErrUsernameChanged
+	}
+
+	return nil
+}
+
 // FindInactive finds users who haven't logged in for the specified duration.
 func (r *userRepository) FindInactive(ctx context.Context, duration time.Duration, limit int) ([]*models.User, error) {
 	cutoff := time.Now().Add(-duration)
@@ -613,3 +828,229 @@ func (r *userRepository) FindInactive(ctx context.Context, duration time.Duratio
 
 	return r.FindMany(ctx, filter, opts)
 }
+
+// SetDeletion schedules a user's account for deletion, or cancels its deletion when deletion is nil.
+func (r *userRepository) SetDeletion(ctx context.Context, userID bson.ObjectID, deletion *models.AccountDeletion) error {
+	update := bson.D{
+		cmdSet(bson.M{
+			"deletion":  deletion,
+			"updatedAt": time.Now(),
+		}),
+	}
+	if deletion == nil {
+		update = bson.D{
+			cmdUnset(bson.M{"deletion": ""}),
+			cmdSet(bson.M{"updatedAt": time.Now()}),
+		}
+	}
+
+	result, err := r.collection.UpdateByID(ctx, userID, update)
+	if err != nil {
+		r.logger.Error("Failed to set account deletion", err, "userID", userID.Hex())
+		return models.NewInternalError(err, "Failed to set account deletion")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// FindDueDeletions finds users whose account deletion is due by the given time, the longest due first.
+func (r *userRepository) FindDueDeletions(ctx context.Context, before time.Time, limit int) ([]*models.User, error) {
+	filter := bson.M{
+		"deletion.purgeAt": bson.M{"$lte": before},
+	}
+
+	opts := options.Find().
+		SetLimit(int64(limit)).
+		SetSort(bson.M{"deletion.purgeAt": 1})
+
+	return r.FindMany(ctx, filter, opts)
+}
+
+// ClaimGuest marks an unlinked guest as being registered, so only one registration can link it. Claims
+// made before staleBefore are taken over. It fails with models.ErrGuestAlreadyLinked if the guest is
+// linked, closed or claimed.
+func (r *userRepository) ClaimGuest(ctx context.Context, guestID bson.ObjectID, staleBefore time.Time) error {
+	filter := bson.M{
+		"_id":            guestID,
+		"isActive":       true,
+		"guest":          bson.M{"$exists": true},
+		"guest.linkedTo": bson.M{"$exists": false},
+		"$or": bson.A{
+			bson.M{"guest.claimedAt": bson.M{"$exists": false}},
+			bson.M{"guest.claimedAt": bson.M{"$lt": staleBefore}},
+		},
+	}
+	update := bson.D{
+		cmdSet(bson.M{
+			"guest.claimedAt": time.Now(),
+			"updatedAt":       time.Now(),
+		}),
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		r.logger.Error("Failed to claim guest", err, "guestID", guestID.Hex())
+		return models.NewInternalError(err, "Failed to claim guest")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrGuestAlreadyLinked
+	}
+
+	return nil
+}
+
+// ReleaseGuest drops the claim on a guest whose registration failed, so it can register again.
+func (r *userRepository) ReleaseGuest(ctx context.Context, guestID bson.ObjectID) error {
+	filter := bson.M{
+		"_id":            guestID,
+		"guest.linkedTo": bson.M{"$exists": false},
+	}
+	update := bson.D{
+		cmdUnset(bson.M{"guest.claimedAt": ""}),
+		cmdSet(bson.M{"updatedAt": time.Now()}),
+	}
+
+	if _, err := r.collection.UpdateOne(ctx, filter, update); err != nil {
+		r.logger.Error("Failed to release guest", err, "guestID", guestID.Hex())
+		return models.NewInternalError(err, "Failed to release guest")
+	}
+
+	return nil
+}
+
+// LinkGuest links a claimed guest to the account it registered and closes it.
+func (r *userRepository) LinkGuest(ctx context.Context, guestID, userID bson.ObjectID, at time.Time) error {
+	update := bson.D{
+		cmdSet(bson.M{
+			"guest.linkedTo": userID,
+			"guest.linkedAt": at,
+			"isActive":       false,
+			"updatedAt":      time.Now(),
+		}),
+		cmdUnset(bson.M{"guest.claimedAt": ""}),
+	}
+
+	result, err := r.collection.UpdateByID(ctx, guestID, update)
+	if err != nil {
+		r.logger.Error("Failed to link guest", err, "guestID", guestID.Hex())
+		return models.NewInternalError(err, "Failed to link guest")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// RemoveConnections removes a user from every other user's following, followers, friends and blocked lists.
+// Each list is pulled from separately, as pulling from a list left unset fails.
+func (r *userRepository) RemoveConnections(ctx context.Context, userID bson.ObjectID) error {
+	for _, field := range []string{"connections.following", "connections.followers", "connections.friends", "connections.blocked"} {
+		update := bson.D{
+			cmdPull(bson.M{field: userID}),
+		}
+
+		if _, err := r.collection.UpdateMany(ctx, bson.M{field: userID}, update); err != nil {
+			r.logger.Error("Failed to remove user connections", err, "userID", userID.Hex(), "field", field)
+			return models.NewInternalError(err, "Failed to remove user connections")
+		}
+	}
+
+	return nil
+}
+
+// ReplaceConnection replaces a user with another in every other user's following, followers and friends
+// lists. Each list is updated separately, adding the replacement before pulling the user so the filter
+// still finds the lists to add it to.
+func (r *userRepository) ReplaceConnection(ctx context.Context, fromID, toID bson.ObjectID) error {
+	for _, field := range []string{"connections.following", "connections.followers", "connections.friends"} {
+		filter := bson.M{
+			"_id": bson.M{"$ne": toID},
+			field: fromID,
+		}
+		update := bson.D{
+			cmdAddToSet(bson.M{field: toID}),
+			cmdSet(bson.M{"updatedAt": time.Now()}),
+		}
+		if _, err := r.collection.UpdateMany(ctx, filter, update); err != nil {
+			r.logger.Error("Failed to replace user connections", err, "userID", fromID.Hex(), "field", field)
+			return models.NewInternalError(err, "Failed to replace user connections")
+		}
+
+		if _, err := r.collection.UpdateMany(ctx, bson.M{field: fromID}, bson.D{cmdPull(bson.M{field: fromID})}); err != nil {
+			r.logger.Error("Failed to replace user connections", err, "userID", fromID.Hex(), "field", field)
+			return models.NewInternalError(err, "Failed to replace user connections")
+		}
+	}
+
+	return nil
+}
+
+// AddConnections adds the users and rooms in connections to a user's own connections.
+func (r *userRepository) AddConnections(ctx context.Context, userID bson.ObjectID, connections models.UserConnections) error {
+	added := bson.M{}
+	for field, ids := range connectionLists(connections) {
+		if len(ids) > 0 {
+			added[field] = bson.M{"$each": ids}
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+
+	update := bson.D{
+		cmdAddToSet(added),
+		cmdSet(bson.M{"updatedAt": time.Now()}),
+	}
+
+	result, err := r.collection.UpdateByID(ctx, userID, update)
+	if err != nil {
+		r.logger.Error("Failed to add user connections", err, "userID", userID.Hex())
+		return models.NewInternalError(err, "Failed to add user connections")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// ClearConnections empties a user's own connections.
+func (r *userRepository) ClearConnections(ctx context.Context, userID bson.ObjectID) error {
+	update := bson.D{
+		cmdSet(bson.M{
+			"connections": models.UserConnections{},
+			"updatedAt":   time.Now(),
+		}),
+	}
+
+	result, err := r.collection.UpdateByID(ctx, userID, update)
+	if err != nil {
+		r.logger.Error("Failed to clear user connections", err, "userID", userID.Hex())
+		return models.NewInternalError(err, "Failed to clear user connections")
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// connectionLists returns the lists of a user's connections by their field.
+func connectionLists(connections models.UserConnections) map[string][]bson.ObjectID {
+	return map[string][]bson.ObjectID{
+		"connections.following": connections.Following,
+		"connections.followers": connections.Followers,
+		"connections.friends":   connections.Friends,
+		"connections.blocked":   connections.Blocked,
+		"connections.favorites": connections.Favorites,
+	}
+}
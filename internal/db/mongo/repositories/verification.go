@@ -0,0 +1,160 @@
+// Package repositories contains MongoDB repository implementations.
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// Collection names
+const (
+	verificationClaimsCollection = "verification_claims"
+)
+
+// VerificationRepository defines the interface for verification claim data access operations.
+type VerificationRepository interface {
+	CreateClaim(ctx context.Context, claim *models.VerificationClaim) error
+	FindClaimByID(ctx context.Context, id bson.ObjectID) (*models.VerificationClaim, error)
+	FindClaims(ctx context.Context, filter models.VerificationClaimFilter, skip, limit int) ([]*models.VerificationClaim, int64, error)
+	ReviewClaim(ctx context.Context, id bson.ObjectID, status models.ClaimStatus, note string, reviewedBy bson.ObjectID) (*models.VerificationClaim, error)
+	DeleteClaimantClaims(ctx context.Context, claimantID bson.ObjectID) (int64, error)
+}
+
+// verificationRepository is the MongoDB implementation of VerificationRepository.
+type verificationRepository struct {
+	claimsCollection *mongo.Collection
+	logger           *utils.Logger
+}
+
+// NewVerificationRepository creates a new instance of VerificationRepository.
+func NewVerificationRepository(db *mongo.Database, logger *utils.Logger) VerificationRepository {
+	return &verificationRepository{
+		claimsCollection: db.Collection(verificationClaimsCollection),
+		logger:           logger.Named("verification_repository"),
+	}
+}
+
+// CreateClaim creates a new verification claim.
+func (r *verificationRepository) CreateClaim(ctx context.Context, claim *models.VerificationClaim) error {
+	if claim.ID.IsZero() {
+		claim.ID = bson.NewObjectID()
+	}
+	claim.CreateNow()
+
+	_, err := r.claimsCollection.InsertOne(ctx, claim)
+	if err != nil {
+		r.logger.Error("Failed to create verification claim", err, "targetId", claim.TargetID.Hex())
+		return models.NewInternalError(err, "Failed to create verification claim")
+	}
+
+	return nil
+}
+
+// FindClaimByID finds a verification claim by its ID.
+func (r *verificationRepository) FindClaimByID(ctx context.Context, id bson.ObjectID) (*models.VerificationClaim, error) {
+	var claim models.VerificationClaim
+
+	err := r.claimsCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&claim)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, models.ErrClaimNotFound
+		}
+		r.logger.Error("Failed to find verification claim by ID", err, "id", id.Hex())
+		return nil, models.NewInternalError(err, "Failed to find verification claim")
+	}
+
+	return &claim, nil
+}
+
+// FindClaims finds verification claims, oldest first so review works through them in order, along with the total number matching.
+func (r *verificationRepository) FindClaims(ctx context.Context, filter models.VerificationClaimFilter, skip, limit int) ([]*models.VerificationClaim, int64, error) {
+	query := verificationClaimQuery(filter)
+
+	total, err := r.claimsCollection.CountDocuments(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to count verification claims", err)
+		return nil, 0, models.NewInternalError(err, "Failed to count verification claims")
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"createdAt": 1}).
+		SetSkip(int64(skip)).
+		SetLimit(int64(limit))
+
+	cursor, err := r.claimsCollection.Find(ctx, query, opts)
+	if err != nil {
+		r.logger.Error("Failed to find verification claims", err)
+		return nil, 0, models.NewInternalError(err, "Failed to find verification claims")
+	}
+	defer cursor.Close(ctx)
+
+	claims := []*models.VerificationClaim{}
+	if err = cursor.All(ctx, &claims); err != nil {
+		r.logger.Error("Failed to decode verification claims", err)
+		return nil, 0, models.NewInternalError(err, "Failed to decode verification claims")
+	}
+
+	return claims, total, nil
+}
+
+// ReviewClaim records an admin's decision on a pending verification claim, and returns the reviewed claim.
+func (r *verificationRepository) ReviewClaim(ctx context.Context, id bson.ObjectID, status models.ClaimStatus, note string, reviewedBy bson.ObjectID) (*models.VerificationClaim, error) {
+	now := time.Now()
+	update := bson.D{cmdSet(bson.M{
+		"status":     status,
+		"note":       note,
+		"reviewedBy": reviewedBy,
+		"reviewedAt": now,
+		"updatedAt":  now,
+	})}
+
+	// Only pending claims are reviewed, so two admins can't both decide on one
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var claim models.VerificationClaim
+	err := r.claimsCollection.FindOneAndUpdate(ctx, bson.M{"_id": id, "status": models.ClaimPending}, update, opts).Decode(&claim)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			if _, findErr := r.FindClaimByID(ctx, id); findErr != nil {
+				return nil, findErr
+			}
+			return nil, models.ErrClaimReviewed
+		}
+		r.logger.Error("Failed to review verification claim", err, "id", id.Hex())
+		return nil, models.NewInternalError(err, "Failed to review verification claim")
+	}
+
+	return &claim, nil
+}
+
+// DeleteClaimantClaims deletes the verification claims a user made, and returns how many it deleted.
+func (r *verificationRepository) DeleteClaimantClaims(ctx context.Context, claimantID bson.ObjectID) (int64, error) {
+	result, err := r.claimsCollection.DeleteMany(ctx, bson.M{"claimantId": claimantID})
+	if err != nil {
+		r.logger.Error("Failed to delete verification claims", err, "claimantId", claimantID.Hex())
+		return 0, models.NewInternalError(err, "Failed to delete verification claims")
+	}
+
+	return result.DeletedCount, nil
+}
+
+// verificationClaimQuery builds the query for a verification claim filter.
+func verificationClaimQuery(filter models.VerificationClaimFilter) bson.M {
+	query := bson.M{}
+	if !filter.ClaimantID.IsZero() {
+		query["claimantId"] = filter.ClaimantID
+	}
+	if !filter.TargetID.IsZero() {
+		query["targetId"] = filter.TargetID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	return query
+}
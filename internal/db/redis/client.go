@@ -9,6 +9,7 @@ import (
 
 	"github.com/go-redis/redis/v8"
 	"norelock.dev/listenify/backend/internal/config"
+	"norelock.dev/listenify/backend/internal/db/redis/memory"
 	"norelock.dev/listenify/backend/internal/utils"
 )
 
@@ -60,6 +61,36 @@ func NewClient(cfg *config.Config, logger *utils.Logger) (*Client, error) {
 	}, nil
 }
 
+// NewMemoryClient creates a Redis client backed by an in-process server keeping its data in memory,
+// for local development and tests. Data is lost on shutdown.
+func NewMemoryClient(logger *utils.Logger) (*Client, error) {
+	// If no logger is provided, use the global logger
+	if logger == nil {
+		logger = utils.GetLogger()
+	}
+
+	server := memory.NewServer()
+	client := redis.NewClient(&redis.Options{
+		Addr:   "memory",
+		Dialer: server.Dial,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		logger.Error("Failed to start in-memory Redis", err)
+		return nil, err
+	}
+
+	logger.Info("Using in-memory Redis")
+
+	return &Client{
+		client: client,
+		logger: logger,
+	}, nil
+}
+
 // Close closes the Redis connection
 func (c *Client) Close() error {
 	err := c.client.Close()
@@ -260,6 +291,16 @@ func (c *Client) HSetObject(ctx context.Context, key, field string, value any) e
 	return c.HSet(ctx, key, field, string(data))
 }
 
+// HIncrBy increments a hash field by a specific amount
+func (c *Client) HIncrBy(ctx context.Context, key, field string, value int64) (int64, error) {
+	result, err := c.client.HIncrBy(ctx, key, field, value).Result()
+	if err != nil {
+		c.logger.Error("Failed to increment hash field", err, "key", key, "field", field, "value", value)
+		return 0, err
+	}
+	return result, nil
+}
+
 // HGet gets a hash field
 func (c *Client) HGet(ctx context.Context, key, field string) (string, error) {
 	value, err := c.client.HGet(ctx, key, field).Result()
@@ -483,6 +524,21 @@ func (c *Client) ZRangeWithScores(ctx context.Context, key string, start, stop i
 	return members, nil
 }
 
+// ZRangeByScore gets up to count members of a sorted set with scores between min and max, lowest first.
+// A count of zero or less returns every member in the range.
+func (c *Client) ZRangeByScore(ctx context.Context, key, min, max string, count int64) ([]string, error) {
+	by := &redis.ZRangeBy{Min: min, Max: max}
+	if count > 0 {
+		by.Count = count
+	}
+	members, err := c.client.ZRangeByScore(ctx, key, by).Result()
+	if err != nil {
+		c.logger.Error("Failed to get range by score from sorted set", err, "key", key)
+		return nil, err
+	}
+	return members, nil
+}
+
 // ZRank gets the rank of a member in a sorted set
 func (c *Client) ZRank(ctx context.Context, key, member string) (int64, error) {
 	rank, err := c.client.ZRank(ctx, key, member).Result()
@@ -0,0 +1,312 @@
+package managers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	r "github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+const (
+	// deadLettersKey is the hash holding dead-lettered events by ID
+	deadLettersKey = "pubsub:deadletters"
+
+	// deadLetterIndexKey orders dead-lettered events by when they last failed
+	deadLetterIndexKey = "pubsub:deadletters:index"
+)
+
+// FailableHandler is a message handler that reports whether it handled the message.
+// Messages it fails to handle are dead-lettered so they can be replayed.
+type FailableHandler func(channel string, payload []byte) error
+
+// DeadLetterPolicy controls how failed events are kept.
+type DeadLetterPolicy struct {
+	// MaxEntries is the maximum number of dead-lettered events kept, the oldest are dropped first.
+	// Zero disables dead-lettering, failed events are only logged.
+	MaxEntries int64
+
+	// Retention is how long dead-lettered events are kept. Zero keeps them until they are dropped for space.
+	Retention time.Duration
+}
+
+// DeadLetter is an event a handler failed to handle.
+type DeadLetter struct {
+	// ID identifies the dead-lettered event
+	ID bson.ObjectID `json:"id"`
+
+	// Handler is the name of the handler that failed
+	Handler string `json:"handler"`
+
+	// Channel is the channel the event was received on
+	Channel string `json:"channel"`
+
+	// Payload is the event as it was received
+	Payload string `json:"payload"`
+
+	// Error is the error of the last attempt
+	Error string `json:"error"`
+
+	// Attempts is the number of times the handler failed the event
+	Attempts int `json:"attempts"`
+
+	// FirstFailedAt is when the handler first failed the event
+	FirstFailedAt time.Time `json:"firstFailedAt"`
+
+	// LastFailedAt is when the handler last failed the event
+	LastFailedAt time.Time `json:"lastFailedAt"`
+}
+
+// AddFailableHandler adds a named message handler for a channel whose failures are dead-lettered.
+// The name identifies the handler when a dead-lettered event is replayed, so it must be unique and stable across nodes.
+func (m *PubSubManager) AddFailableHandler(channel, name string, handler FailableHandler) {
+	m.mutex.Lock()
+	m.failableHandlers[name] = handler
+	m.mutex.Unlock()
+
+	m.AddHandler(channel, func(channel string, payload []byte) {
+		if err := callFailable(handler, channel, payload); err != nil {
+			m.logger.Error("Message handler failed, dead-lettering event", err, "handler", name, "channel", channel)
+			m.deadLetter(m.ctx, name, channel, payload, err)
+		}
+	})
+}
+
+// callFailable calls a failable handler, turning a panic into an error.
+func callFailable(handler FailableHandler, channel string, payload []byte) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic in message handler: %v", rec)
+		}
+	}()
+
+	return handler(channel, payload)
+}
+
+// deadLetter stores an event a handler failed to handle.
+func (m *PubSubManager) deadLetter(ctx context.Context, handler, channel string, payload []byte, err error) {
+	if m.deadLetterPolicy.MaxEntries <= 0 {
+		return
+	}
+
+	now := time.Now()
+	letter := &DeadLetter{
+		ID:            bson.NewObjectID(),
+		Handler:       handler,
+		Channel:       channel,
+		Payload:       string(payload),
+		Error:         err.Error(),
+		Attempts:      1,
+		FirstFailedAt: now,
+		LastFailedAt:  now,
+	}
+
+	if err := m.saveDeadLetter(ctx, letter); err != nil {
+		m.logger.Error("Failed to store dead-lettered event", err, "handler", handler, "channel", channel)
+		return
+	}
+	if err := m.trimDeadLetters(ctx); err != nil {
+		m.logger.Error("Failed to trim dead-lettered events", err)
+	}
+}
+
+// saveDeadLetter stores a dead-lettered event, replacing it if it exists.
+func (m *PubSubManager) saveDeadLetter(ctx context.Context, letter *DeadLetter) error {
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+
+	pipe := m.client.TxPipeline()
+	pipe.HSet(ctx, deadLettersKey, letter.ID.Hex(), string(data))
+	pipe.ZAdd(ctx, deadLetterIndexKey, &r.Z{Score: float64(letter.LastFailedAt.Unix()), Member: letter.ID.Hex()})
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// trimDeadLetters drops dead-lettered events past the retention, and the oldest ones past the maximum number kept.
+func (m *PubSubManager) trimDeadLetters(ctx context.Context) error {
+	var expired []string
+	if m.deadLetterPolicy.Retention > 0 {
+		cutoff := time.Now().Add(-m.deadLetterPolicy.Retention).Unix()
+		ids, err := m.client.Client().ZRangeByScore(ctx, deadLetterIndexKey, &r.ZRangeBy{
+			Min: "-inf",
+			Max: strconv.FormatInt(cutoff, 10),
+		}).Result()
+		if err != nil {
+			return err
+		}
+		expired = append(expired, ids...)
+	}
+
+	overflow, err := m.client.ZRange(ctx, deadLetterIndexKey, 0, -m.deadLetterPolicy.MaxEntries-1)
+	if err != nil {
+		return err
+	}
+	expired = append(expired, overflow...)
+
+	if len(expired) == 0 {
+		return nil
+	}
+	return m.removeDeadLetters(ctx, expired...)
+}
+
+// removeDeadLetters removes dead-lettered events by ID.
+func (m *PubSubManager) removeDeadLetters(ctx context.Context, ids ...string) error {
+	members := make([]any, len(ids))
+	for i, id := range ids {
+		members[i] = id
+	}
+
+	pipe := m.client.TxPipeline()
+	pipe.HDel(ctx, deadLettersKey, ids...)
+	pipe.ZRem(ctx, deadLetterIndexKey, members...)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// ListDeadLetters lists dead-lettered events, the most recently failed first, along with the total number kept.
+func (m *PubSubManager) ListDeadLetters(ctx context.Context, offset, limit int64) ([]*DeadLetter, int64, error) {
+	total, err := m.client.ZCard(ctx, deadLetterIndexKey)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids, err := m.client.Client().ZRevRange(ctx, deadLetterIndexKey, offset, offset+limit-1).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(ids) == 0 {
+		return []*DeadLetter{}, total, nil
+	}
+
+	values, err := m.client.Client().HMGet(ctx, deadLettersKey, ids...).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	letters := make([]*DeadLetter, 0, len(values))
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var letter DeadLetter
+		if err := json.Unmarshal([]byte(data), &letter); err != nil {
+			m.logger.Error("Failed to unmarshal dead-lettered event", err)
+			continue
+		}
+		letters = append(letters, &letter)
+	}
+
+	return letters, total, nil
+}
+
+// GetDeadLetter gets a dead-lettered event by ID.
+func (m *PubSubManager) GetDeadLetter(ctx context.Context, id bson.ObjectID) (*DeadLetter, error) {
+	data, err := m.client.HGet(ctx, deadLettersKey, id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if data == "" {
+		return nil, models.ErrDeadLetterNotFound
+	}
+
+	var letter DeadLetter
+	if err := json.Unmarshal([]byte(data), &letter); err != nil {
+		return nil, err
+	}
+	return &letter, nil
+}
+
+// ReplayDeadLetter hands a dead-lettered event to its handler again on this node.
+// If the handler succeeds the event is removed and nil is returned. If it fails again
+// the event is kept with the new error, and returned.
+func (m *PubSubManager) ReplayDeadLetter(ctx context.Context, id bson.ObjectID) (*DeadLetter, error) {
+	letter, err := m.GetDeadLetter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mutex.RLock()
+	handler, ok := m.failableHandlers[letter.Handler]
+	m.mutex.RUnlock()
+	if !ok {
+		return nil, models.ErrDeadLetterNoHandler
+	}
+
+	if err := callFailable(handler, letter.Channel, []byte(letter.Payload)); err != nil {
+		letter.Error = err.Error()
+		letter.Attempts++
+		letter.LastFailedAt = time.Now()
+		if err := m.saveDeadLetter(ctx, letter); err != nil {
+			return nil, err
+		}
+
+		m.logger.Warn("Replayed event failed again", "id", id.Hex(), "handler", letter.Handler, "attempts", letter.Attempts, "error", letter.Error)
+		return letter, nil
+	}
+
+	if err := m.removeDeadLetters(ctx, id.Hex()); err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Replayed dead-lettered event", "id", id.Hex(), "handler", letter.Handler)
+	return nil, nil
+}
+
+// ReplayDeadLetters replays every dead-lettered event, oldest first, or only those of one handler if a name is given.
+// It returns how many events were handled and how many failed again.
+func (m *PubSubManager) ReplayDeadLetters(ctx context.Context, handler string) (int, int, error) {
+	ids, err := m.client.ZRange(ctx, deadLetterIndexKey, 0, -1)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	replayed, failed := 0, 0
+	for _, hex := range ids {
+		id, err := bson.ObjectIDFromHex(hex)
+		if err != nil {
+			continue
+		}
+
+		if handler != "" {
+			letter, err := m.GetDeadLetter(ctx, id)
+			if err != nil || letter.Handler != handler {
+				continue
+			}
+		}
+
+		letter, err := m.ReplayDeadLetter(ctx, id)
+		switch {
+		case errors.Is(err, models.ErrDeadLetterNotFound), errors.Is(err, models.ErrDeadLetterNoHandler):
+			continue
+		case err != nil:
+			return replayed, failed, err
+		case letter != nil:
+			failed++
+		default:
+			replayed++
+		}
+	}
+
+	return replayed, failed, nil
+}
+
+// DiscardDeadLetter removes a dead-lettered event without replaying it.
+func (m *PubSubManager) DiscardDeadLetter(ctx context.Context, id bson.ObjectID) error {
+	removed, err := m.client.Client().HDel(ctx, deadLettersKey, id.Hex()).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return models.ErrDeadLetterNotFound
+	}
+
+	return m.client.ZRem(ctx, deadLetterIndexKey, id.Hex())
+}
@@ -0,0 +1,86 @@
+// Package redis provides Redis database connectivity and operations.
+package managers
+
+import (
+	"context"
+	"time"
+
+	"norelock.dev/listenify/backend/internal/db/redis"
+)
+
+const (
+	// RoomGeoKeyPrefix is the prefix for room listener country keys
+	RoomGeoKeyPrefix = "room:geo"
+
+	// RoomGeoExpiry is how long listener country attribution is kept without activity
+	RoomGeoExpiry = 24 * time.Hour
+)
+
+// ListenerGeoManager tracks the coarse country of listeners in each room.
+// Countries are only ever read back in aggregate.
+type ListenerGeoManager struct {
+	client *redis.Client
+}
+
+// NewListenerGeoManager creates a new listener geo manager
+func NewListenerGeoManager(client *redis.Client) *ListenerGeoManager {
+	return &ListenerGeoManager{
+		client: client,
+	}
+}
+
+// TrackListener records the country of a listener in a room
+func (m *ListenerGeoManager) TrackListener(ctx context.Context, roomID, userID, country string) error {
+	logger := m.client.Logger()
+
+	key := formatRoomGeoKey(roomID)
+	if err := m.client.HSet(ctx, key, userID, country); err != nil {
+		logger.Error("Failed to track listener country", err, "roomId", roomID)
+		return err
+	}
+
+	if err := m.client.Expire(ctx, key, RoomGeoExpiry); err != nil {
+		logger.Error("Failed to set listener geo expiry", err, "roomId", roomID)
+		return err
+	}
+
+	return nil
+}
+
+// UntrackListener removes a listener from a room's geo attribution
+func (m *ListenerGeoManager) UntrackListener(ctx context.Context, roomID, userID string) error {
+	logger := m.client.Logger()
+
+	if err := m.client.HDel(ctx, formatRoomGeoKey(roomID), userID); err != nil {
+		logger.Error("Failed to untrack listener country", err, "roomId", roomID)
+		return err
+	}
+
+	return nil
+}
+
+// GetCountryCounts returns the number of listeners per country in a room.
+// Only listeners contained in activeUsers are counted.
+func (m *ListenerGeoManager) GetCountryCounts(ctx context.Context, roomID string, activeUsers map[string]bool) (map[string]int, error) {
+	logger := m.client.Logger()
+
+	entries, err := m.client.HGetAll(ctx, formatRoomGeoKey(roomID))
+	if err != nil {
+		logger.Error("Failed to get listener countries", err, "roomId", roomID)
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for userID, country := range entries {
+		if activeUsers[userID] {
+			counts[country]++
+		}
+	}
+
+	return counts, nil
+}
+
+// formatRoomGeoKey formats a room listener geo key
+func formatRoomGeoKey(roomID string) string {
+	return redis.FormatKey(RoomGeoKeyPrefix, roomID)
+}
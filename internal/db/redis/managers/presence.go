@@ -3,7 +3,10 @@ package managers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
+	"strconv"
 	"time"
 
 	r "github.com/go-redis/redis/v8"
@@ -18,11 +21,17 @@ const (
 	// OnlineUsersKey is the key for the set of online users
 	OnlineUsersKey = "online:users"
 
+	// UserRegionsKeyPrefix is the prefix for the keys of the regions users are connected in
+	UserRegionsKeyPrefix = PresenceKeyPrefix + ":regions"
+
 	// PresenceTTL is the expiration time for presence keys
 	PresenceTTL = 2 * time.Minute
 
 	// PresenceUpdateInterval is the recommended interval for updating presence
 	PresenceUpdateInterval = 1 * time.Minute
+
+	// presenceBulkBatch is the number of presence keys fetched per MGET in bulk lookups
+	presenceBulkBatch = 500
 )
 
 // PresenceInfo represents user presence information
@@ -47,6 +56,9 @@ type PresenceInfo struct {
 
 	// Data contains additional presence data
 	Data map[string]any `json:"data,omitempty"`
+
+	// Regions are the deployment regions the user is connected in, kept apart from the rest of the presence
+	Regions []string `json:"regions,omitempty"`
 }
 
 // PresenceManager handles Redis operations for user presence
@@ -182,6 +194,39 @@ func (m *PresenceManager) SetUserRoom(ctx context.Context, userID bson.ObjectID,
 	return nil
 }
 
+// SetUsername updates the username a user's presence shows, such as after they renamed
+func (m *PresenceManager) SetUsername(ctx context.Context, userID bson.ObjectID, username string) error {
+	logger := m.client.Logger()
+
+	userIDStr := userID.Hex()
+
+	// Get existing presence info
+	presenceKey := formatPresenceKey(userIDStr)
+	var presence PresenceInfo
+
+	err := m.client.GetObject(ctx, presenceKey, &presence)
+	if err != nil {
+		if err == r.Nil {
+			logger.Debug("No presence info for username update", "userId", userIDStr)
+			return nil
+		}
+		logger.Error("Failed to get presence info for username update", err, "userId", userIDStr)
+		return err
+	}
+
+	presence.Username = username
+
+	// Store updated presence info
+	err = m.client.SetObject(ctx, presenceKey, &presence, PresenceTTL)
+	if err != nil {
+		logger.Error("Failed to update username", err, "userId", userIDStr)
+		return err
+	}
+
+	logger.Debug("Updated presence username", "userId", userIDStr)
+	return nil
+}
+
 // GetPresence gets a user's presence information
 func (m *PresenceManager) GetPresence(ctx context.Context, userID bson.ObjectID) (*PresenceInfo, error) {
 	logger := m.client.Logger()
@@ -200,9 +245,93 @@ func (m *PresenceManager) GetPresence(ctx context.Context, userID bson.ObjectID)
 		return nil, err
 	}
 
+	presence.Regions, err = m.GetUserRegions(ctx, userID)
+	if err != nil {
+		logger.Warn("Failed to get user regions", "userId", userIDStr, "error", err)
+	}
+
 	return &presence, nil
 }
 
+// RecordUserRegion records that users are connected in a deployment region. Regions where a user
+// isn't recorded again within the presence TTL are forgotten.
+func (m *PresenceManager) RecordUserRegion(ctx context.Context, region string, userIDs []string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	now := float64(time.Now().Unix())
+	pipe := m.client.Pipeline()
+	for _, userID := range userIDs {
+		key := formatUserRegionsKey(userID)
+		pipe.ZAdd(ctx, key, &r.Z{Score: now, Member: region})
+		pipe.Expire(ctx, key, PresenceTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		m.client.Logger().Error("Failed to record user regions", err, "region", region, "count", len(userIDs))
+		return err
+	}
+	return nil
+}
+
+// GetUserRegions gets the deployment regions a user was connected in within the presence TTL.
+func (m *PresenceManager) GetUserRegions(ctx context.Context, userID bson.ObjectID) ([]string, error) {
+	since := time.Now().Add(-PresenceTTL).Unix()
+	return m.client.Client().ZRangeByScore(ctx, formatUserRegionsKey(userID.Hex()), &r.ZRangeBy{
+		Min: strconv.FormatInt(since, 10),
+		Max: "+inf",
+	}).Result()
+}
+
+// GetPresenceBulk gets the presence information of many users in a single round trip.
+// Users who aren't present are missing from the result.
+func (m *PresenceManager) GetPresenceBulk(ctx context.Context, userIDs []bson.ObjectID) (map[bson.ObjectID]*PresenceInfo, error) {
+	logger := m.client.Logger()
+
+	presences := make(map[bson.ObjectID]*PresenceInfo, len(userIDs))
+	if len(userIDs) == 0 {
+		return presences, nil
+	}
+
+	// Large lists are split over several MGETs sent in one pipeline
+	pipe := m.client.Pipeline()
+	cmds := make([]*r.SliceCmd, 0, (len(userIDs)+presenceBulkBatch-1)/presenceBulkBatch)
+	for batch := range slices.Chunk(userIDs, presenceBulkBatch) {
+		keys := make([]string, len(batch))
+		for i, userID := range batch {
+			keys[i] = formatPresenceKey(userID.Hex())
+		}
+		cmds = append(cmds, pipe.MGet(ctx, keys...))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != r.Nil {
+		logger.Error("Failed to get presence info in bulk", err, "count", len(userIDs))
+		return nil, err
+	}
+
+	for i, cmd := range cmds {
+		values, err := cmd.Result()
+		if err != nil {
+			logger.Error("Failed to get presence info in bulk", err, "count", len(userIDs))
+			return nil, err
+		}
+		for j, value := range values {
+			data, ok := value.(string)
+			if !ok || data == "" {
+				continue // User not present
+			}
+			userID := userIDs[i*presenceBulkBatch+j]
+			var presence PresenceInfo
+			if err := json.Unmarshal([]byte(data), &presence); err != nil {
+				logger.Error("Failed to decode presence info", err, "userId", userID.Hex())
+				continue
+			}
+			presences[userID] = &presence
+		}
+	}
+
+	return presences, nil
+}
+
 // GetUserStatus gets a user's current status
 func (m *PresenceManager) GetUserStatus(ctx context.Context, userID bson.ObjectID) (string, error) {
 	presence, err := m.GetPresence(ctx, userID)
@@ -432,3 +561,8 @@ func (m *PresenceManager) CleanupExpiredPresence(ctx context.Context) (int, erro
 func formatPresenceKey(userID string) string {
 	return redis.FormatKey(PresenceKeyPrefix, userID)
 }
+
+// formatUserRegionsKey formats a key for the regions a user is connected in
+func formatUserRegionsKey(userID string) string {
+	return redis.FormatKey(UserRegionsKeyPrefix, userID)
+}
@@ -33,19 +33,25 @@ type PubSubManager struct {
 	ctx        context.Context
 	cancelFunc context.CancelFunc
 	running    bool
+
+	// failableHandlers holds the handlers whose failures are dead-lettered by name, for replays
+	failableHandlers map[string]FailableHandler
+	deadLetterPolicy DeadLetterPolicy
 }
 
 // NewPubSubManager creates a new PubSub manager
-func NewPubSubManager(client *redis.Client) *PubSubManager {
+func NewPubSubManager(client *redis.Client, deadLetterPolicy DeadLetterPolicy) *PubSubManager {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &PubSubManager{
-		client:     client,
-		logger:     client.Logger(),
-		handlers:   make(map[string][]MessageHandler),
-		ctx:        ctx,
-		cancelFunc: cancel,
-		running:    false,
+		client:           client,
+		logger:           client.Logger(),
+		handlers:         make(map[string][]MessageHandler),
+		ctx:              ctx,
+		cancelFunc:       cancel,
+		running:          false,
+		failableHandlers: make(map[string]FailableHandler),
+		deadLetterPolicy: deadLetterPolicy,
 	}
 }
 
@@ -54,11 +60,15 @@ func (m *PubSubManager) Subscribe(channels ...string) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	// If already subscribed, close existing subscription
+	// If already subscribed, add the channels to the existing subscription.
+	// Replacing it would leave the running listener reading a closed channel.
 	if m.pubSub != nil {
-		if err := m.pubSub.Close(); err != nil {
-			m.logger.Error("Failed to close existing PubSub", err)
+		if err := m.pubSub.Subscribe(m.ctx, channels...); err != nil {
+			m.logger.Error("Failed to subscribe to channels", err, "channels", channels)
+			return err
 		}
+		m.logger.Info("Subscribed to channels", "channels", channels)
+		return nil
 	}
 
 	// Create new PubSub
@@ -158,6 +168,36 @@ func (m *PubSubManager) PublishToRoom(ctx context.Context, roomID, eventType str
 	return m.Publish(ctx, channel, message)
 }
 
+// PublishToChatShard publishes a message to a room channel for the clients in one of its chat shards
+// following one of its chat channels
+func (m *PubSubManager) PublishToChatShard(ctx context.Context, roomID string, shard int, chatChannel, eventType string, data any) error {
+	message := map[string]any{
+		"type":        eventType,
+		"roomId":      roomID,
+		"chatShard":   shard,
+		"chatChannel": chatChannel,
+		"data":        data,
+		"timestamp":   time.Now(),
+	}
+
+	channel := redis.FormatKey(RoomChannelPrefix, roomID)
+	return m.Publish(ctx, channel, message)
+}
+
+// PublishToChatChannel publishes a message to a room channel for the clients following one of its chat channels
+func (m *PubSubManager) PublishToChatChannel(ctx context.Context, roomID, chatChannel, eventType string, data any) error {
+	message := map[string]any{
+		"type":        eventType,
+		"roomId":      roomID,
+		"chatChannel": chatChannel,
+		"data":        data,
+		"timestamp":   time.Now(),
+	}
+
+	channel := redis.FormatKey(RoomChannelPrefix, roomID)
+	return m.Publish(ctx, channel, message)
+}
+
 // PublishToUser publishes a message to a user channel
 func (m *PubSubManager) PublishToUser(ctx context.Context, userID, eventType string, data any) error {
 	message := map[string]any{
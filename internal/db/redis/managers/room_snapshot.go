@@ -0,0 +1,85 @@
+package managers
+
+import (
+	"context"
+	"encoding/json"
+
+	r "github.com/go-redis/redis/v8"
+)
+
+// RoomStateSnapshot is the real-time state of a room as read at one point in time.
+type RoomStateSnapshot struct {
+	// State is the room's state, nil if the room has none in Redis
+	State *RoomState
+
+	// Users is the number of users in the room
+	Users int64
+
+	// Listeners is the number of listener-only users in the room's overflow
+	Listeners int64
+
+	// Queue is the room's DJ queue
+	Queue []QueueEntry
+}
+
+// SnapshotRooms reads the real-time state of several rooms in a single Redis transaction, so every room
+// is read at the same point in time without holding a lock in the application. Rooms without state in
+// Redis get a snapshot with a nil state. Lost states and queues aren't rebuilt, so reading a snapshot
+// never writes.
+func (m *RoomStateManager) SnapshotRooms(ctx context.Context, roomIDs []string) (map[string]*RoomStateSnapshot, error) {
+	if len(roomIDs) == 0 {
+		return map[string]*RoomStateSnapshot{}, nil
+	}
+
+	type roomCmds struct {
+		state     *r.StringCmd
+		users     *r.IntCmd
+		listeners *r.IntCmd
+		queue     *r.StringSliceCmd
+	}
+
+	pipe := m.client.TxPipeline()
+	cmds := make(map[string]roomCmds, len(roomIDs))
+	for _, roomID := range roomIDs {
+		cmds[roomID] = roomCmds{
+			state:     pipe.Get(ctx, formatRoomStateKey(roomID)),
+			users:     pipe.SCard(ctx, formatRoomUsersKey(roomID)),
+			listeners: pipe.ZCard(ctx, formatRoomListenersKey(roomID)),
+			queue:     pipe.LRange(ctx, formatRoomQueueKey(roomID), 0, -1),
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != r.Nil {
+		m.client.Logger().Error("Failed to snapshot room states", err, "rooms", len(roomIDs))
+		return nil, err
+	}
+
+	snapshots := make(map[string]*RoomStateSnapshot, len(roomIDs))
+	for roomID, cmd := range cmds {
+		snapshot := &RoomStateSnapshot{
+			Users:     cmd.users.Val(),
+			Listeners: cmd.listeners.Val(),
+			Queue:     make([]QueueEntry, 0, len(cmd.queue.Val())),
+		}
+
+		if data, err := cmd.state.Bytes(); err == nil {
+			var state RoomState
+			if err := json.Unmarshal(data, &state); err != nil {
+				m.client.Logger().Error("Failed to unmarshal room state for snapshot", err, "roomId", roomID)
+			} else {
+				snapshot.State = &state
+			}
+		}
+
+		for _, value := range cmd.queue.Val() {
+			var entry QueueEntry
+			if err := json.Unmarshal([]byte(value), &entry); err != nil {
+				m.client.Logger().Error("Failed to unmarshal queue entry for snapshot", err, "roomId", roomID)
+				continue
+			}
+			snapshot.Queue = append(snapshot.Queue, entry)
+		}
+
+		snapshots[roomID] = snapshot
+	}
+	return snapshots, nil
+}
@@ -4,12 +4,16 @@ package managers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
+	"sync"
 	"time"
 
 	r "github.com/go-redis/redis/v8"
 	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
 )
 
 const (
@@ -19,6 +23,18 @@ const (
 	// RoomUsersKeyPrefix is the prefix for room users keys
 	RoomUsersKeyPrefix = "room:users"
 
+	// RoomJoinsKeyPrefix is the prefix for the keys holding when users joined a room
+	RoomJoinsKeyPrefix = "room:joins"
+
+	// RoomListenersKeyPrefix is the prefix for room overflow listener keys
+	RoomListenersKeyPrefix = "room:listeners"
+
+	// RoomChatShardsKeyPrefix is the prefix for the keys holding the overflow chat shard of room users
+	RoomChatShardsKeyPrefix = "room:chat_shards"
+
+	// RoomChatShardSizesKeyPrefix is the prefix for the keys holding the number of users in each overflow chat shard
+	RoomChatShardSizesKeyPrefix = "room:chat_shard_sizes"
+
 	// RoomQueueKeyPrefix is the prefix for room DJ queue keys
 	RoomQueueKeyPrefix = "room:queue"
 
@@ -31,6 +47,9 @@ const (
 	// RoomHistoryKeyPrefix is the prefix for room history keys
 	RoomHistoryKeyPrefix = "room:history"
 
+	// RoomPinnedKeyPrefix is the prefix for the keys listing a room's pinned chat messages
+	RoomPinnedKeyPrefix = "room:pinned"
+
 	// Default expiration times
 	RoomStateExpiry     = 12 * time.Hour
 	RoomInactiveExpiry  = 7 * 24 * time.Hour // 7 days
@@ -83,20 +102,134 @@ type QueueEntry struct {
 
 	// PlayCount is the number of tracks the user has played
 	PlayCount int `json:"playCount"`
+
+	// User is the public profile of the user, if known
+	User *models.PublicUser `json:"user,omitempty"`
+
+	// JoinedAt is when the user joined the room
+	JoinedAt time.Time `json:"joinedAt,omitzero"`
+
+	// WaitingSince is when the user started waiting for their next turn
+	WaitingSince time.Time `json:"waitingSince,omitzero"`
+
+	// TurnPlays is the number of tracks the user played in their current or last turn
+	TurnPlays int `json:"turnPlays,omitempty"`
+}
+
+// HistoryEntry represents a play in a room's play history list
+type HistoryEntry struct {
+	// HistoryID is the ID of the play's record in the play history collection, if it has one
+	HistoryID string `json:"historyId,omitempty"`
+
+	// MediaID is the ID of the media that was played
+	MediaID string `json:"mediaId"`
+
+	// DJID is the ID of the user who played the media
+	DJID string `json:"djId"`
+
+	// Time is when the media started playing
+	Time time.Time `json:"time"`
+
+	// Duration is the duration of the media in seconds
+	Duration int `json:"duration"`
+
+	// EndTime is when the media stopped playing, zero while it is still playing
+	EndTime time.Time `json:"endTime,omitzero"`
+
+	// Play is a copy of the play's history record, used to restore the record if it is missing
+	Play *models.PlayHistory `json:"play,omitempty"`
+}
+
+// VoteRules limit when votes on a room's current media are accepted and which count towards skipping it
+type VoteRules struct {
+	// Grace is how long before the media starts and after it ends votes are still accepted
+	Grace time.Duration
+
+	// SkipVoteCutoff is the share of the media, from 0 to 1, after which users joining the room
+	// no longer count towards skipping it. Zero counts every user.
+	SkipVoteCutoff float64
+
+	// SkipThreshold is the share of the users in the room, from 0 to 1, whose mehs skip the media
+	// once exceeded. Zero never skips.
+	SkipThreshold float64
+}
+
+// RoomStateLoader rebuilds the state of a room whose state expired from the room's stored record.
+// It returns nil when there is no state to rebuild, because the room doesn't exist or isn't active.
+type RoomStateLoader func(ctx context.Context, roomID string) (*RoomState, error)
+
+// RoomQueueLoader loads the stored DJ queue of a room, to rebuild the queue after Redis lost it.
+type RoomQueueLoader func(ctx context.Context, roomID string) ([]QueueEntry, error)
+
+// RoomStateReader reads the real-time state of rooms. Read paths that run often use it, so it can be
+// served from a cache; writes go through the RoomStateManager.
+type RoomStateReader interface {
+	// GetRoomState gets a room's state, or nil if the room has none. The state must not be modified.
+	GetRoomState(ctx context.Context, roomID string) (*RoomState, error)
+}
+
+// roomStateRecovery holds the loaders rebuilding lost room states and queues, shared by every copy of the manager.
+type roomStateRecovery struct {
+	mu          sync.RWMutex
+	loader      RoomStateLoader
+	queueLoader RoomQueueLoader
+}
+
+// roomStateListeners holds the handlers notified of room state writes, shared by every copy of the manager.
+type roomStateListeners struct {
+	mu       sync.RWMutex
+	handlers []func(ctx context.Context, roomID string)
 }
 
 // RoomStateManager handles Redis operations for room state
 type RoomStateManager struct {
-	client *redis.Client
+	client    *redis.Client
+	recovery  *roomStateRecovery
+	listeners *roomStateListeners
 }
 
 // NewRoomStateManager creates a new room state manager
 func NewRoomStateManager(client *redis.Client) *RoomStateManager {
 	return &RoomStateManager{
-		client: client,
+		client:    client,
+		recovery:  &roomStateRecovery{},
+		listeners: &roomStateListeners{},
 	}
 }
 
+// OnStateChange adds a handler called after a room's state was written on this node.
+func (m *RoomStateManager) OnStateChange(handler func(ctx context.Context, roomID string)) {
+	m.listeners.mu.Lock()
+	defer m.listeners.mu.Unlock()
+	m.listeners.handlers = append(m.listeners.handlers, handler)
+}
+
+// stateChanged notifies the state change handlers of a write to a room's state.
+func (m *RoomStateManager) stateChanged(ctx context.Context, roomID string) {
+	m.listeners.mu.RLock()
+	handlers := m.listeners.handlers
+	m.listeners.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(ctx, roomID)
+	}
+}
+
+// SetStateLoader sets the loader rebuilding room states that expired while their rooms were still in use.
+// Without one, expired states are reported as missing.
+func (m *RoomStateManager) SetStateLoader(loader RoomStateLoader) {
+	m.recovery.mu.Lock()
+	defer m.recovery.mu.Unlock()
+	m.recovery.loader = loader
+}
+
+// SetQueueLoader sets the loader rebuilding the DJ queues Redis lost. Without one, lost queues stay empty.
+func (m *RoomStateManager) SetQueueLoader(loader RoomQueueLoader) {
+	m.recovery.mu.Lock()
+	defer m.recovery.mu.Unlock()
+	m.recovery.queueLoader = loader
+}
+
 // InitRoom initializes a room's state in Redis
 func (m *RoomStateManager) InitRoom(ctx context.Context, roomID string) error {
 	logger := m.client.Logger()
@@ -125,6 +258,7 @@ func (m *RoomStateManager) InitRoom(ctx context.Context, roomID string) error {
 			logger.Error("Failed to store room state in Redis", err, "roomId", roomID)
 			return err
 		}
+		m.stateChanged(ctx, roomID)
 
 		// Initialize empty users set
 		usersKey := formatRoomUsersKey(roomID)
@@ -154,6 +288,69 @@ func (m *RoomStateManager) InitRoom(ctx context.Context, roomID string) error {
 		logger.Info("Updated existing room state", "roomId", roomID)
 	}
 
+	return m.recoverQueue(ctx, roomID)
+}
+
+// recoverQueue rebuilds a room's DJ queue from its stored copy when the queue is missing from Redis.
+// Empty queues are missing too, so their stored copy, empty as well, is checked each time.
+func (m *RoomStateManager) recoverQueue(ctx context.Context, roomID string) error {
+	logger := m.client.Logger()
+
+	m.recovery.mu.RLock()
+	loader := m.recovery.queueLoader
+	m.recovery.mu.RUnlock()
+
+	if loader == nil {
+		return nil
+	}
+
+	queueKey := formatRoomQueueKey(roomID)
+	exists, err := m.client.Exists(ctx, queueKey)
+	if err != nil {
+		logger.Error("Failed to check if room queue exists", err, "roomId", roomID)
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	entries, err := loader(ctx, roomID)
+	if err != nil {
+		logger.Error("Failed to load stored room queue", err, "roomId", roomID)
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	values, err := queueValues(entries)
+	if err != nil {
+		logger.Error("Failed to marshal queue entries", err, "roomId", roomID)
+		return err
+	}
+
+	// Keep the queue of a concurrent join or rebuild if there is one
+	err = m.client.Client().Watch(ctx, func(tx *r.Tx) error {
+		count, err := tx.Exists(ctx, queueKey).Result()
+		if err != nil || count > 0 {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe r.Pipeliner) error {
+			pipe.RPush(ctx, queueKey, values...)
+			return nil
+		})
+		return err
+	}, queueKey)
+	if err == r.TxFailedErr {
+		return nil
+	}
+	if err != nil {
+		logger.Error("Failed to rebuild room queue", err, "roomId", roomID)
+		return err
+	}
+
+	logger.Warn("Room queue missing from Redis, rebuilt it from the stored room", "roomId", roomID, "entries", len(entries))
+	m.stateChanged(ctx, roomID)
 	return nil
 }
 
@@ -167,8 +364,7 @@ func (m *RoomStateManager) GetRoomState(ctx context.Context, roomID string) (*Ro
 	err := m.client.GetObject(ctx, stateKey, &state)
 	if err != nil {
 		if err == r.Nil {
-			logger.Debug("Room state not found", "roomId", roomID)
-			return nil, nil
+			return m.recoverRoomState(ctx, roomID)
 		}
 		logger.Error("Failed to get room state from Redis", err, "roomId", roomID)
 		return nil, err
@@ -177,6 +373,88 @@ func (m *RoomStateManager) GetRoomState(ctx context.Context, roomID string) (*Ro
 	return &state, nil
 }
 
+// recoverRoomState rebuilds a missing room state with the state loader, returning nil if there is nothing to rebuild.
+func (m *RoomStateManager) recoverRoomState(ctx context.Context, roomID string) (*RoomState, error) {
+	logger := m.client.Logger()
+
+	m.recovery.mu.RLock()
+	loader := m.recovery.loader
+	m.recovery.mu.RUnlock()
+
+	if loader == nil {
+		logger.Debug("Room state not found", "roomId", roomID)
+		return nil, nil
+	}
+
+	state, err := loader(ctx, roomID)
+	if err != nil {
+		logger.Error("Failed to rebuild expired room state", err, "roomId", roomID)
+		return nil, err
+	}
+	if state == nil {
+		logger.Debug("Room state not found", "roomId", roomID)
+		return nil, nil
+	}
+
+	// The users set doesn't expire, so the count survives the state
+	userCount, err := m.client.SCard(ctx, formatRoomUsersKey(roomID))
+	if err != nil {
+		logger.Error("Failed to get room user count", err, "roomId", roomID)
+		return nil, err
+	}
+	state.ActiveUsers = int(userCount)
+	state.LastActivity = time.Now()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the state of a concurrent rebuild or update if there is one
+	stored, err := m.client.Client().SetNX(ctx, formatRoomStateKey(roomID), data, RoomStateExpiry).Result()
+	if err != nil {
+		logger.Error("Failed to store rebuilt room state", err, "roomId", roomID)
+		return nil, err
+	}
+	if !stored {
+		var current RoomState
+		if err := m.client.GetObject(ctx, formatRoomStateKey(roomID), &current); err != nil {
+			logger.Error("Failed to get room state from Redis", err, "roomId", roomID)
+			return nil, err
+		}
+		return &current, nil
+	}
+
+	logger.Warn("Room state expired, rebuilt it from the stored room", "roomId", roomID)
+	return state, nil
+}
+
+// Heartbeat keeps the state of a room in use from expiring, rebuilding it if it already expired.
+// Inactive rooms keep their longer expiry.
+func (m *RoomStateManager) Heartbeat(ctx context.Context, roomID string) error {
+	state, err := m.GetRoomState(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if state == nil || !state.IsActive {
+		return nil
+	}
+
+	pipe := m.client.Pipeline()
+	pipe.Expire(ctx, formatRoomStateKey(roomID), RoomStateExpiry)
+	pipe.Expire(ctx, formatRoomListenersKey(roomID), RoomStateExpiry)
+	pipe.Expire(ctx, formatRoomChatShardsKey(roomID), RoomStateExpiry)
+	pipe.Expire(ctx, formatRoomChatShardSizesKey(roomID), RoomStateExpiry)
+	pipe.Expire(ctx, formatRoomHistoryKey(roomID), RoomStateExpiry)
+	pipe.Expire(ctx, formatRoomPinnedKey(roomID), RoomInactiveExpiry)
+	if _, err := pipe.Exec(ctx); err != nil {
+		m.client.Logger().Error("Failed to refresh room state expiry", err, "roomId", roomID)
+		return err
+	}
+
+	return nil
+}
+
 // UpdateRoomState updates a room's state in Redis
 func (m *RoomStateManager) UpdateRoomState(ctx context.Context, state *RoomState) error {
 	logger := m.client.Logger()
@@ -191,6 +469,7 @@ func (m *RoomStateManager) UpdateRoomState(ctx context.Context, state *RoomState
 		logger.Error("Failed to update room state in Redis", err, "roomId", state.RoomID)
 		return err
 	}
+	m.stateChanged(ctx, state.RoomID)
 
 	logger.Debug("Updated room state", "roomId", state.RoomID)
 	return nil
@@ -228,6 +507,7 @@ func (m *RoomStateManager) SetRoomActive(ctx context.Context, roomID string, isA
 		logger.Error("Failed to update room active status", err, "roomId", roomID)
 		return err
 	}
+	m.stateChanged(ctx, roomID)
 
 	logger.Info("Set room active status", "roomId", roomID, "isActive", isActive)
 	return nil
@@ -245,6 +525,13 @@ func (m *RoomStateManager) AddUserToRoom(ctx context.Context, roomID, userID str
 		return err
 	}
 
+	// Remember when the user joined, for the skip vote cutoff
+	err = m.client.HSet(ctx, formatRoomJoinsKey(roomID), userID, time.Now().Unix())
+	if err != nil {
+		logger.Error("Failed to record room join time", err, "roomId", roomID, "userId", userID)
+		return err
+	}
+
 	// Update active users count in room state
 	state, err := m.GetRoomState(ctx, roomID)
 	if err != nil {
@@ -293,6 +580,17 @@ func (m *RoomStateManager) RemoveUserFromRoom(ctx context.Context, roomID, userI
 		return err
 	}
 
+	err = m.client.HDel(ctx, formatRoomJoinsKey(roomID), userID)
+	if err != nil {
+		logger.Error("Failed to remove room join time", err, "roomId", roomID, "userId", userID)
+		return err
+	}
+
+	if err := m.releaseChatShard(ctx, roomID, userID); err != nil {
+		logger.Error("Failed to release chat shard", err, "roomId", roomID, "userId", userID)
+		return err
+	}
+
 	// Update active users count in room state
 	state, err := m.GetRoomState(ctx, roomID)
 	if err != nil {
@@ -323,6 +621,7 @@ func (m *RoomStateManager) RemoveUserFromRoom(ctx context.Context, roomID, userI
 			logger.Error("Failed to update empty room state", err, "roomId", roomID)
 			return err
 		}
+		m.stateChanged(ctx, roomID)
 
 		logger.Info("Room is now empty", "roomId", roomID)
 		return nil
@@ -365,6 +664,24 @@ func (m *RoomStateManager) GetRoomUsers(ctx context.Context, roomID string) ([]s
 	return users, nil
 }
 
+// GetRoomJoinTimes gets when the users in a room joined it. Users who joined before join times were
+// recorded are missing.
+func (m *RoomStateManager) GetRoomJoinTimes(ctx context.Context, roomID string) (map[string]time.Time, error) {
+	joins, err := m.client.HGetAll(ctx, formatRoomJoinsKey(roomID))
+	if err != nil {
+		m.client.Logger().Error("Failed to get room join times", err, "roomId", roomID)
+		return nil, err
+	}
+
+	joinTimes := make(map[string]time.Time, len(joins))
+	for userID, joined := range joins {
+		if joinedAt, err := strconv.ParseInt(joined, 10, 64); err == nil {
+			joinTimes[userID] = time.Unix(joinedAt, 0)
+		}
+	}
+	return joinTimes, nil
+}
+
 // IsUserInRoom checks if a user is in a room
 func (m *RoomStateManager) IsUserInRoom(ctx context.Context, roomID, userID string) (bool, error) {
 	logger := m.client.Logger()
@@ -380,6 +697,357 @@ func (m *RoomStateManager) IsUserInRoom(ctx context.Context, roomID, userID stri
 	return isMember, nil
 }
 
+// TransferUser hands a user's place in a room to another user: their membership or overflow place,
+// join time, chat shard, DJ queue entry, DJ turn and vote on the current media. The changes are written in one
+// transaction so the place is never lost in between. It returns false if the user wasn't in the room.
+func (m *RoomStateManager) TransferUser(ctx context.Context, roomID, fromUserID, toUserID string) (bool, error) {
+	logger := m.client.Logger()
+
+	state, err := m.GetRoomState(ctx, roomID)
+	if err != nil {
+		return false, err
+	}
+	if state == nil {
+		return false, nil
+	}
+
+	inRoom, err := m.IsUserInRoom(ctx, roomID, fromUserID)
+	if err != nil {
+		return false, err
+	}
+	listeners, err := m.client.ZRangeWithScores(ctx, formatRoomListenersKey(roomID), 0, -1)
+	if err != nil {
+		return false, err
+	}
+	listenerScore, isListener := 0.0, false
+	for _, listener := range listeners {
+		if listener.Member == fromUserID {
+			listenerScore, isListener = listener.Score, true
+			break
+		}
+	}
+	if !inRoom && !isListener {
+		return false, nil
+	}
+
+	joined, err := m.client.HGet(ctx, formatRoomJoinsKey(roomID), fromUserID)
+	if err != nil {
+		return false, err
+	}
+	shard, err := m.client.HGet(ctx, formatRoomChatShardsKey(roomID), fromUserID)
+	if err != nil {
+		return false, err
+	}
+
+	queueKey := formatRoomQueueKey(roomID)
+	queue, err := m.client.LRange(ctx, queueKey, 0, -1)
+	if err != nil {
+		return false, err
+	}
+
+	var vote string
+	var skipVote bool
+	votesKey := formatRoomVotesKey(roomID, state.CurrentMedia)
+	skipKey := fmt.Sprintf("%s:skip", votesKey)
+	if state.CurrentMedia != "" {
+		if vote, err = m.GetUserVote(ctx, roomID, fromUserID, state.CurrentMedia); err != nil {
+			return false, err
+		}
+		if skipVote, err = m.client.SIsMember(ctx, skipKey, fromUserID); err != nil {
+			return false, err
+		}
+	}
+
+	pipe := m.client.TxPipeline()
+
+	if inRoom {
+		usersKey := formatRoomUsersKey(roomID)
+		pipe.SRem(ctx, usersKey, fromUserID)
+		pipe.SAdd(ctx, usersKey, toUserID)
+	}
+	if isListener {
+		// Keep the overflow position
+		listenersKey := formatRoomListenersKey(roomID)
+		pipe.ZRem(ctx, listenersKey, fromUserID)
+		pipe.ZAdd(ctx, listenersKey, &r.Z{Score: listenerScore, Member: toUserID})
+	}
+	if joined != "" {
+		joinsKey := formatRoomJoinsKey(roomID)
+		pipe.HDel(ctx, joinsKey, fromUserID)
+		pipe.HSet(ctx, joinsKey, toUserID, joined)
+	}
+	if shard != "" {
+		shardsKey := formatRoomChatShardsKey(roomID)
+		pipe.HDel(ctx, shardsKey, fromUserID)
+		pipe.HSet(ctx, shardsKey, toUserID, shard)
+	}
+
+	for i, entryJson := range queue {
+		var entry QueueEntry
+		if err := json.Unmarshal([]byte(entryJson), &entry); err != nil || entry.UserID != fromUserID {
+			continue
+		}
+		entry.UserID = toUserID
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return false, err
+		}
+		pipe.LSet(ctx, queueKey, int64(i), string(data))
+	}
+
+	if vote != "" {
+		pipe.Del(ctx, fmt.Sprintf("%s:%s", votesKey, fromUserID))
+		pipe.Set(ctx, fmt.Sprintf("%s:%s", votesKey, toUserID), vote, time.Hour*24)
+	}
+	if skipVote {
+		pipe.SRem(ctx, skipKey, fromUserID)
+		pipe.SAdd(ctx, skipKey, toUserID)
+	}
+
+	if state.CurrentDJ == fromUserID {
+		state.CurrentDJ = toUserID
+		data, err := json.Marshal(state)
+		if err != nil {
+			return false, err
+		}
+		pipe.Set(ctx, formatRoomStateKey(roomID), string(data), RoomStateExpiry)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.Error("Failed to transfer user in room", err, "roomId", roomID, "fromUserId", fromUserID, "toUserId", toUserID)
+		return false, err
+	}
+	if state.CurrentDJ == toUserID {
+		m.stateChanged(ctx, roomID)
+	}
+
+	logger.Info("Transferred user in room", "roomId", roomID, "fromUserId", fromUserID, "toUserId", toUserID)
+	return true, nil
+}
+
+// AddListenerToRoom adds a listener-only user to a room's overflow, keeping the join order
+func (m *RoomStateManager) AddListenerToRoom(ctx context.Context, roomID, userID string) error {
+	logger := m.client.Logger()
+
+	listenersKey := formatRoomListenersKey(roomID)
+	err := m.client.ZAdd(ctx, listenersKey, float64(time.Now().UnixNano()), userID)
+	if err != nil {
+		logger.Error("Failed to add listener to room", err, "roomId", roomID, "userId", userID)
+		return err
+	}
+
+	// Keep the overflow around as long as the room state
+	if err := m.client.Expire(ctx, listenersKey, RoomStateExpiry); err != nil {
+		logger.Error("Failed to set room listeners expiry", err, "roomId", roomID)
+	}
+
+	logger.Info("Added listener to room", "roomId", roomID, "userId", userID)
+	return nil
+}
+
+// RemoveListenerFromRoom removes a listener-only user from a room's overflow
+func (m *RoomStateManager) RemoveListenerFromRoom(ctx context.Context, roomID, userID string) error {
+	logger := m.client.Logger()
+
+	err := m.client.ZRem(ctx, formatRoomListenersKey(roomID), userID)
+	if err != nil {
+		logger.Error("Failed to remove listener from room", err, "roomId", roomID, "userId", userID)
+		return err
+	}
+
+	return nil
+}
+
+// GetRoomListeners gets the listener-only users in a room, oldest first
+func (m *RoomStateManager) GetRoomListeners(ctx context.Context, roomID string) ([]string, error) {
+	return m.client.ZRange(ctx, formatRoomListenersKey(roomID), 0, -1)
+}
+
+// CountRoomListeners gets the number of listener-only users in a room
+func (m *RoomStateManager) CountRoomListeners(ctx context.Context, roomID string) (int, error) {
+	count, err := m.client.ZCard(ctx, formatRoomListenersKey(roomID))
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// IsListenerInRoom checks if a user is in a room's listener-only overflow
+func (m *RoomStateManager) IsListenerInRoom(ctx context.Context, roomID, userID string) (bool, error) {
+	rank, err := m.client.ZRank(ctx, formatRoomListenersKey(roomID), userID)
+	if err != nil {
+		return false, err
+	}
+	return rank >= 0, nil
+}
+
+// AssignChatShard puts a user who just joined a room in a chat shard, a parallel chat channel of the
+// room. Users stay in the main chat, shard 0, while it has fewer than threshold users, and new joiners
+// then go to the lowest shard with fewer than size users. Users already assigned keep their shard.
+// Assignment is soft: concurrent joins may leave a chat slightly over its size.
+func (m *RoomStateManager) AssignChatShard(ctx context.Context, roomID, userID string, threshold, size int) (int, error) {
+	shardsKey := formatRoomChatShardsKey(roomID)
+	assigned, err := m.client.HGet(ctx, shardsKey, userID)
+	if err != nil {
+		return 0, err
+	}
+	if assigned != "" {
+		shard, _ := strconv.Atoi(assigned)
+		return shard, nil
+	}
+
+	users, err := m.client.SCard(ctx, formatRoomUsersKey(roomID))
+	if err != nil {
+		return 0, err
+	}
+	sharded, err := m.client.HLen(ctx, shardsKey)
+	if err != nil {
+		return 0, err
+	}
+	// The user was already added to the room's users
+	if int(users-sharded)-1 < threshold {
+		return 0, nil
+	}
+
+	sizes, err := m.GetChatShardSizes(ctx, roomID)
+	if err != nil {
+		return 0, err
+	}
+	shard := 1
+	for sizes[shard] >= size {
+		shard++
+	}
+
+	sizesKey := formatRoomChatShardSizesKey(roomID)
+	pipe := m.client.TxPipeline()
+	pipe.HSet(ctx, shardsKey, userID, shard)
+	pipe.HIncrBy(ctx, sizesKey, strconv.Itoa(shard), 1)
+	pipe.Expire(ctx, shardsKey, RoomStateExpiry)
+	pipe.Expire(ctx, sizesKey, RoomStateExpiry)
+	if _, err := pipe.Exec(ctx); err != nil {
+		m.client.Logger().Error("Failed to assign chat shard", err, "roomId", roomID, "userId", userID)
+		return 0, err
+	}
+
+	return shard, nil
+}
+
+// releaseChatShard takes a user leaving a room out of their chat shard
+func (m *RoomStateManager) releaseChatShard(ctx context.Context, roomID, userID string) error {
+	shardsKey := formatRoomChatShardsKey(roomID)
+	shard, err := m.client.HGet(ctx, shardsKey, userID)
+	if err != nil || shard == "" {
+		return err
+	}
+
+	pipe := m.client.TxPipeline()
+	pipe.HDel(ctx, shardsKey, userID)
+	pipe.HIncrBy(ctx, formatRoomChatShardSizesKey(roomID), shard, -1)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// GetChatShard gets the chat shard of a user in a room, 0 for the main chat
+func (m *RoomStateManager) GetChatShard(ctx context.Context, roomID, userID string) (int, error) {
+	shard, err := m.client.HGet(ctx, formatRoomChatShardsKey(roomID), userID)
+	if err != nil || shard == "" {
+		return 0, err
+	}
+	return strconv.Atoi(shard)
+}
+
+// GetChatShardSizes gets the number of users in each overflow chat shard of a room
+func (m *RoomStateManager) GetChatShardSizes(ctx context.Context, roomID string) (map[int]int, error) {
+	values, err := m.client.HGetAll(ctx, formatRoomChatShardSizesKey(roomID))
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make(map[int]int, len(values))
+	for field, value := range values {
+		shard, err := strconv.Atoi(field)
+		if err != nil {
+			continue
+		}
+		if size, _ := strconv.Atoi(value); size > 0 {
+			sizes[shard] = size
+		}
+	}
+	return sizes, nil
+}
+
+// GetPinnedMessages gets the IDs of a room's pinned chat messages, oldest pin first
+func (m *RoomStateManager) GetPinnedMessages(ctx context.Context, roomID string) ([]string, error) {
+	ids, err := m.client.Client().LRange(ctx, formatRoomPinnedKey(roomID), 0, -1).Result()
+	if err != nil {
+		m.client.Logger().Error("Failed to get pinned messages", err, "roomId", roomID)
+		return nil, err
+	}
+	return ids, nil
+}
+
+// PinMessage adds a chat message to a room's pinned messages, unless it is already pinned. The limit is
+// checked and the message added at once, so concurrent pins on any node can't go past it. It reports
+// whether the message was pinned by this call.
+func (m *RoomStateManager) PinMessage(ctx context.Context, roomID, messageID string, limit int) (bool, error) {
+	const maxAttempts = 5
+	pinnedKey := formatRoomPinnedKey(roomID)
+
+	added := false
+	pin := func(tx *r.Tx) error {
+		ids, err := tx.LRange(ctx, pinnedKey, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+		if slices.Contains(ids, messageID) {
+			return nil
+		}
+		if len(ids) >= limit {
+			return models.ErrPinLimitReached
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe r.Pipeliner) error {
+			pipe.RPush(ctx, pinnedKey, messageID)
+			pipe.Expire(ctx, pinnedKey, RoomInactiveExpiry)
+			return nil
+		})
+		added = err == nil
+		return err
+	}
+
+	// Retry when another pin or unpin changed the list in between
+	var err error
+	for range maxAttempts {
+		err = m.client.Client().Watch(ctx, pin, pinnedKey)
+		if err != r.TxFailedErr {
+			break
+		}
+	}
+	if err != nil && !errors.Is(err, models.ErrPinLimitReached) {
+		m.client.Logger().Error("Failed to pin message", err, "roomId", roomID, "messageId", messageID)
+	}
+	return added, err
+}
+
+// UnpinMessage removes a chat message from a room's pinned messages, reporting whether it was pinned
+func (m *RoomStateManager) UnpinMessage(ctx context.Context, roomID, messageID string) (bool, error) {
+	removed, err := m.client.Client().LRem(ctx, formatRoomPinnedKey(roomID), 0, messageID).Result()
+	if err != nil {
+		m.client.Logger().Error("Failed to unpin message", err, "roomId", roomID, "messageId", messageID)
+		return false, err
+	}
+	return removed > 0, nil
+}
+
+// ClearPinnedMessages unpins all of a room's chat messages
+func (m *RoomStateManager) ClearPinnedMessages(ctx context.Context, roomID string) error {
+	if err := m.client.Client().Del(ctx, formatRoomPinnedKey(roomID)).Err(); err != nil {
+		m.client.Logger().Error("Failed to clear pinned messages", err, "roomId", roomID)
+		return err
+	}
+	return nil
+}
+
 // AddUserToQueue adds a user to the DJ queue
 func (m *RoomStateManager) AddUserToQueue(ctx context.Context, roomID, userID string) error {
 	logger := m.client.Logger()
@@ -674,7 +1342,12 @@ func (m *RoomStateManager) SetCurrentMedia(ctx context.Context, roomID string, m
 	}
 
 	// Add to history
-	err = m.AddToHistory(ctx, roomID, mediaID, state.CurrentDJ, duration)
+	err = m.AddToHistory(ctx, roomID, HistoryEntry{
+		MediaID:  mediaID,
+		DJID:     state.CurrentDJ,
+		Time:     now,
+		Duration: duration,
+	})
 	if err != nil {
 		logger.Error("Failed to add media to history", err, "roomId", roomID, "mediaId", mediaID)
 		// Continue anyway as this is not critical
@@ -727,27 +1400,51 @@ func (m *RoomStateManager) GetQueueEntries(ctx context.Context, roomID string) (
 	return queueEntries, nil
 }
 
-// AddToHistory adds a media item to the room's play history
-func (m *RoomStateManager) AddToHistory(ctx context.Context, roomID, mediaID, djID string, duration int) error {
+// SetQueueEntries replaces the entries of the room's DJ queue
+func (m *RoomStateManager) SetQueueEntries(ctx context.Context, roomID string, entries []QueueEntry) error {
 	logger := m.client.Logger()
 
-	// Create history entry
-	entry := struct {
-		MediaID  string    `json:"mediaId"`
-		DJID     string    `json:"djId"`
-		Time     time.Time `json:"time"`
-		Duration int       `json:"duration"`
-	}{
-		MediaID:  mediaID,
-		DJID:     djID,
-		Time:     time.Now(),
-		Duration: duration,
+	values, err := queueValues(entries)
+	if err != nil {
+		logger.Error("Failed to marshal queue entries", err, "roomId", roomID)
+		return err
+	}
+
+	queueKey := formatRoomQueueKey(roomID)
+	pipe := m.client.TxPipeline()
+	pipe.Del(ctx, queueKey)
+	if len(values) > 0 {
+		pipe.RPush(ctx, queueKey, values...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.Error("Failed to set queue entries", err, "roomId", roomID)
+		return err
+	}
+
+	return nil
+}
+
+// queueValues marshals queue entries into the values of a queue list.
+func queueValues(entries []QueueEntry) ([]any, error) {
+	values := make([]any, len(entries))
+	for i, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = string(data)
 	}
+	return values, nil
+}
+
+// AddToHistory adds a play to the start of the room's play history
+func (m *RoomStateManager) AddToHistory(ctx context.Context, roomID string, entry HistoryEntry) error {
+	logger := m.client.Logger()
 
 	// Convert to JSON
 	entryJson, err := json.Marshal(entry)
 	if err != nil {
-		logger.Error("Failed to marshal history entry", err, "roomId", roomID, "mediaId", mediaID)
+		logger.Error("Failed to marshal history entry", err, "roomId", roomID, "mediaId", entry.MediaID)
 		return err
 	}
 
@@ -755,7 +1452,7 @@ func (m *RoomStateManager) AddToHistory(ctx context.Context, roomID, mediaID, dj
 	historyKey := formatRoomHistoryKey(roomID)
 	err = m.client.LPush(ctx, historyKey, string(entryJson))
 	if err != nil {
-		logger.Error("Failed to add to history", err, "roomId", roomID, "mediaId", mediaID)
+		logger.Error("Failed to add to history", err, "roomId", roomID, "mediaId", entry.MediaID)
 		return err
 	}
 
@@ -773,12 +1470,12 @@ func (m *RoomStateManager) AddToHistory(ctx context.Context, roomID, mediaID, dj
 		// Continue anyway as this is not critical
 	}
 
-	logger.Debug("Added media to history", "roomId", roomID, "mediaId", mediaID, "djId", djID)
+	logger.Debug("Added media to history", "roomId", roomID, "mediaId", entry.MediaID, "djId", entry.DJID)
 	return nil
 }
 
-// GetHistory gets the room's play history
-func (m *RoomStateManager) GetHistory(ctx context.Context, roomID string, limit int) ([]map[string]any, error) {
+// GetHistoryEntries gets the room's play history, most recent play first
+func (m *RoomStateManager) GetHistoryEntries(ctx context.Context, roomID string, limit int) ([]HistoryEntry, error) {
 	logger := m.client.Logger()
 
 	if limit <= 0 || limit > RoomHistoryMaxItems {
@@ -794,11 +1491,10 @@ func (m *RoomStateManager) GetHistory(ctx context.Context, roomID string, limit
 	}
 
 	// Parse entries
-	history := make([]map[string]any, 0, len(entries))
+	history := make([]HistoryEntry, 0, len(entries))
 	for _, entryJson := range entries {
-		var entry map[string]any
-		err := json.Unmarshal([]byte(entryJson), &entry)
-		if err != nil {
+		var entry HistoryEntry
+		if err := json.Unmarshal([]byte(entryJson), &entry); err != nil {
 			logger.Error("Failed to unmarshal history entry", err, "roomId", roomID)
 			continue
 		}
@@ -809,37 +1505,87 @@ func (m *RoomStateManager) GetHistory(ctx context.Context, roomID string, limit
 	return history, nil
 }
 
-// RecordVote records a user's vote for the current media
-func (m *RoomStateManager) RecordVote(ctx context.Context, roomID, userID, mediaID, voteType string) error {
+// UpdateHistoryEntry replaces the entry with the same history ID in the room's play history
+func (m *RoomStateManager) UpdateHistoryEntry(ctx context.Context, roomID string, entry HistoryEntry) error {
 	logger := m.client.Logger()
 
-	// Validate vote type
-	if voteType != "woot" && voteType != "meh" && voteType != "grab" {
-		return fmt.Errorf("invalid vote type: %s", voteType)
-	}
-
-	// Check if room and media exist
-	state, err := m.GetRoomState(ctx, roomID)
+	historyKey := formatRoomHistoryKey(roomID)
+	entries, err := m.client.LRange(ctx, historyKey, 0, -1)
 	if err != nil {
+		logger.Error("Failed to get history", err, "roomId", roomID)
 		return err
 	}
 
-	if state == nil {
-		return fmt.Errorf("room not found: %s", roomID)
+	for i, entryJson := range entries {
+		var existing HistoryEntry
+		if err := json.Unmarshal([]byte(entryJson), &existing); err != nil || existing.HistoryID != entry.HistoryID {
+			continue
+		}
+
+		updatedJson, err := json.Marshal(entry)
+		if err != nil {
+			logger.Error("Failed to marshal history entry", err, "roomId", roomID, "historyId", entry.HistoryID)
+			return err
+		}
+
+		err = m.client.Client().LSet(ctx, historyKey, int64(i), string(updatedJson)).Err()
+		if err != nil {
+			logger.Error("Failed to update history entry", err, "roomId", roomID, "historyId", entry.HistoryID)
+			return err
+		}
+		return nil
 	}
 
-	if state.CurrentMedia != mediaID {
-		return fmt.Errorf("media is not currently playing: %s", mediaID)
+	return fmt.Errorf("history entry not found: %s", entry.HistoryID)
+}
+
+// GetHistory gets the room's play history
+func (m *RoomStateManager) GetHistory(ctx context.Context, roomID string, limit int) ([]map[string]any, error) {
+	logger := m.client.Logger()
+
+	if limit <= 0 || limit > RoomHistoryMaxItems {
+		limit = RoomHistoryMaxItems
 	}
 
-	// Check if user is in the room
-	inRoom, err := m.IsUserInRoom(ctx, roomID, userID)
+	// Get history entries
+	historyKey := formatRoomHistoryKey(roomID)
+	entries, err := m.client.LRange(ctx, historyKey, 0, int64(limit-1))
 	if err != nil {
-		return err
+		logger.Error("Failed to get history", err, "roomId", roomID)
+		return nil, err
 	}
 
-	if !inRoom {
-		return fmt.Errorf("user is not in the room: %s", userID)
+	// Parse entries
+	history := make([]map[string]any, 0, len(entries))
+	for _, entryJson := range entries {
+		var entry map[string]any
+		err := json.Unmarshal([]byte(entryJson), &entry)
+		if err != nil {
+			logger.Error("Failed to unmarshal history entry", err, "roomId", roomID)
+			continue
+		}
+
+		history = append(history, entry)
+	}
+
+	return history, nil
+}
+
+// RecordVote records a user's vote for the current media.
+// Votes are only accepted while the media plays, and mehs only count towards skipping it
+// if the user joined the room before the rules' skip vote cutoff. It returns whether the vote pushed
+// those mehs past the rules' skip threshold, meaning the media should be skipped.
+func (m *RoomStateManager) RecordVote(ctx context.Context, roomID, userID, mediaID, voteType string, rules VoteRules) (bool, error) {
+	logger := m.client.Logger()
+
+	// Validate vote type
+	if voteType != models.VoteWoot && voteType != models.VoteMeh && voteType != models.VoteGrab {
+		return false, fmt.Errorf("invalid vote type: %s", voteType)
+	}
+
+	state, err := m.checkVoter(ctx, roomID, userID, mediaID, rules)
+	if err != nil {
+		return false, err
 	}
 
 	// Record vote
@@ -850,12 +1596,20 @@ func (m *RoomStateManager) RecordVote(ctx context.Context, roomID, userID, media
 	previousVote, err := m.client.Get(ctx, voterKey)
 	if err != nil && err != r.Nil {
 		logger.Error("Failed to get previous vote", err, "roomId", roomID, "userId", userID, "mediaId", mediaID)
-		return err
+		return false, err
 	}
 
 	// If vote is the same, do nothing
 	if previousVote == voteType {
-		return nil
+		return false, nil
+	}
+
+	countsTowardSkip := false
+	if voteType == "meh" {
+		countsTowardSkip, err = m.joinedBeforeCutoff(ctx, state, userID, rules.SkipVoteCutoff)
+		if err != nil {
+			return false, err
+		}
 	}
 
 	// Pipeline commands for atomic updates
@@ -874,19 +1628,161 @@ func (m *RoomStateManager) RecordVote(ctx context.Context, roomID, userID, media
 	countKey := fmt.Sprintf("%s:%s:count", votesKey, voteType)
 	pipe.Incr(ctx, countKey)
 
+	// Keep track of the mehs that count towards skipping the media
+	skipKey := fmt.Sprintf("%s:skip", votesKey)
+	if previousVote == "meh" {
+		pipe.SRem(ctx, skipKey, userID)
+	}
+	if countsTowardSkip {
+		pipe.SAdd(ctx, skipKey, userID)
+		pipe.Expire(ctx, skipKey, time.Hour*24)
+	}
+
 	// Execute pipeline
 	_, err = pipe.Exec(ctx)
 	if err != nil {
 		logger.Error("Failed to record vote", err, "roomId", roomID, "userId", userID, "mediaId", mediaID, "voteType", voteType)
-		return err
+		return false, err
 	}
 
 	logger.Info("Recorded vote", "roomId", roomID, "userId", userID, "mediaId", mediaID, "voteType", voteType, "previousVote", previousVote)
-	return nil
+
+	if !countsTowardSkip || rules.SkipThreshold <= 0 {
+		return false, nil
+	}
+	return m.passedSkipThreshold(ctx, roomID, mediaID, rules.SkipThreshold)
 }
 
-// GetVotes gets the votes for a media item
-func (m *RoomStateManager) GetVotes(ctx context.Context, roomID, mediaID string) (map[string]int, error) {
+// passedSkipThreshold checks whether the mehs counting towards skipping a room's current media exceed the
+// threshold share of the users in the room. Only the first check to see them exceed it reports it, so
+// the media is skipped once.
+func (m *RoomStateManager) passedSkipThreshold(ctx context.Context, roomID, mediaID string, threshold float64) (bool, error) {
+	logger := m.client.Logger()
+	votesKey := formatRoomVotesKey(roomID, mediaID)
+
+	pipe := m.client.Pipeline()
+	mehs := pipe.SCard(ctx, fmt.Sprintf("%s:skip", votesKey))
+	users := pipe.SCard(ctx, formatRoomUsersKey(roomID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.Error("Failed to count skip votes", err, "roomId", roomID, "mediaId", mediaID)
+		return false, err
+	}
+
+	if float64(mehs.Val()) <= threshold*float64(users.Val()) {
+		return false, nil
+	}
+
+	claimed, err := m.client.Client().SetNX(ctx, fmt.Sprintf("%s:skipped", votesKey), 1, time.Hour*24).Result()
+	if err != nil {
+		logger.Error("Failed to claim vote skip", err, "roomId", roomID, "mediaId", mediaID)
+		return false, err
+	}
+	if claimed {
+		logger.Info("Skip votes passed the threshold", "roomId", roomID, "mediaId", mediaID, "mehs", mehs.Val(), "users", users.Val())
+	}
+	return claimed, nil
+}
+
+// ToggleReaction adds a user's reaction to the current media, or takes it back if they already added it.
+// Reactions follow the same rules as votes but don't replace the user's vote, nor each other.
+// It returns whether the user now has the reaction.
+func (m *RoomStateManager) ToggleReaction(ctx context.Context, roomID, userID, mediaID, reaction string, rules VoteRules) (bool, error) {
+	if _, err := m.checkVoter(ctx, roomID, userID, mediaID, rules); err != nil {
+		return false, err
+	}
+
+	reactorsKey := formatRoomReactorsKey(roomID, mediaID, reaction)
+	removed, err := m.client.Client().SRem(ctx, reactorsKey, userID).Result()
+	if err != nil {
+		return false, err
+	}
+	if removed > 0 {
+		return false, nil
+	}
+
+	pipe := m.client.Pipeline()
+	pipe.SAdd(ctx, reactorsKey, userID)
+	pipe.Expire(ctx, reactorsKey, time.Hour*24)
+	if _, err := pipe.Exec(ctx); err != nil {
+		m.client.Logger().Error("Failed to record reaction", err, "roomId", roomID, "userId", userID, "mediaId", mediaID, "reaction", reaction)
+		return false, err
+	}
+	return true, nil
+}
+
+// checkVoter checks that a user can vote or react on a media: the media is playing in the room, within
+// the rules' grace period, and the user is in the room. It returns the room's state.
+func (m *RoomStateManager) checkVoter(ctx context.Context, roomID, userID, mediaID string, rules VoteRules) (*RoomState, error) {
+	// Check if room and media exist
+	state, err := m.GetRoomState(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	if state == nil {
+		return nil, fmt.Errorf("room not found: %s", roomID)
+	}
+
+	if state.CurrentMedia != mediaID {
+		return nil, fmt.Errorf("media is not currently playing: %s", mediaID)
+	}
+
+	if !voteWindowOpen(state, rules.Grace, time.Now()) {
+		return nil, models.ErrVoteWindowClosed
+	}
+
+	// Check if user is in the room
+	inRoom, err := m.IsUserInRoom(ctx, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !inRoom {
+		return nil, fmt.Errorf("user is not in the room: %s", userID)
+	}
+
+	return state, nil
+}
+
+// joinedBeforeCutoff checks whether a user joined the room before a share of the current media had played.
+func (m *RoomStateManager) joinedBeforeCutoff(ctx context.Context, state *RoomState, userID string, cutoff float64) (bool, error) {
+	// Media of unknown duration has no cutoff
+	if cutoff <= 0 || !state.MediaEndTime.After(state.MediaStartTime) {
+		return true, nil
+	}
+
+	joined, err := m.client.HGet(ctx, formatRoomJoinsKey(state.RoomID), userID)
+	if err != nil {
+		return false, err
+	}
+	joinedAt, err := strconv.ParseInt(joined, 10, 64)
+	if err != nil {
+		return true, nil // Joined before join times were recorded
+	}
+
+	length := state.MediaEndTime.Sub(state.MediaStartTime)
+	deadline := state.MediaStartTime.Add(time.Duration(float64(length) * min(cutoff, 1)))
+	return !time.Unix(joinedAt, 0).After(deadline), nil
+}
+
+// voteWindowOpen checks whether votes on the current media are accepted at a time.
+func voteWindowOpen(state *RoomState, grace time.Duration, now time.Time) bool {
+	if state.MediaStartTime.IsZero() {
+		return false
+	}
+	if now.Before(state.MediaStartTime.Add(-grace)) {
+		return false
+	}
+
+	// Media of unknown duration plays until it is replaced
+	if state.MediaEndTime.After(state.MediaStartTime) && now.After(state.MediaEndTime.Add(grace)) {
+		return false
+	}
+	return true
+}
+
+// GetVotes gets the votes for a media item, along with the counts of the given reaction types
+func (m *RoomStateManager) GetVotes(ctx context.Context, roomID, mediaID string, reactions []string) (map[string]int, error) {
 	logger := m.client.Logger()
 
 	votesKey := formatRoomVotesKey(roomID, mediaID)
@@ -895,12 +1791,18 @@ func (m *RoomStateManager) GetVotes(ctx context.Context, roomID, mediaID string)
 	wootKey := fmt.Sprintf("%s:woot:count", votesKey)
 	mehKey := fmt.Sprintf("%s:meh:count", votesKey)
 	grabKey := fmt.Sprintf("%s:grab:count", votesKey)
+	skipKey := fmt.Sprintf("%s:skip", votesKey)
 
 	// Pipeline commands
 	pipe := m.client.Pipeline()
 	wootCmd := pipe.Get(ctx, wootKey)
 	mehCmd := pipe.Get(ctx, mehKey)
 	grabCmd := pipe.Get(ctx, grabKey)
+	skipCmd := pipe.SCard(ctx, skipKey)
+	reactionCmds := make([]*r.IntCmd, len(reactions))
+	for i, reaction := range reactions {
+		reactionCmds[i] = pipe.SCard(ctx, formatRoomReactorsKey(roomID, mediaID, reaction))
+	}
 
 	// Execute pipeline
 	_, err := pipe.Exec(ctx)
@@ -932,11 +1834,15 @@ func (m *RoomStateManager) GetVotes(ctx context.Context, roomID, mediaID string)
 		grabCount, _ = strconv.Atoi(grabStr)
 	}
 
-	// Create result map
+	// Create result map, skip is the number of mehs that count towards skipping the media
 	votes := map[string]int{
 		"woot": wootCount,
 		"meh":  mehCount,
 		"grab": grabCount,
+		"skip": int(skipCmd.Val()),
+	}
+	for i, reaction := range reactions {
+		votes[reaction] = int(reactionCmds[i].Val())
 	}
 
 	return votes, nil
@@ -963,6 +1869,11 @@ func (m *RoomStateManager) GetUserVote(ctx context.Context, roomID, userID, medi
 
 // Helper functions
 
+// formatRoomPinnedKey formats a key for a room's pinned chat messages
+func formatRoomPinnedKey(roomID string) string {
+	return redis.FormatKey(RoomPinnedKeyPrefix, roomID)
+}
+
 // formatRoomStateKey formats a key for room state
 func formatRoomStateKey(roomID string) string {
 	return redis.FormatKey(RoomStateKeyPrefix, roomID)
@@ -973,6 +1884,26 @@ func formatRoomUsersKey(roomID string) string {
 	return redis.FormatKey(RoomUsersKeyPrefix, roomID)
 }
 
+// formatRoomJoinsKey formats a key for room join times
+func formatRoomJoinsKey(roomID string) string {
+	return redis.FormatKey(RoomJoinsKeyPrefix, roomID)
+}
+
+// formatRoomListenersKey formats a key for room overflow listeners
+func formatRoomListenersKey(roomID string) string {
+	return redis.FormatKey(RoomListenersKeyPrefix, roomID)
+}
+
+// formatRoomChatShardsKey formats a key for the chat shards of room users
+func formatRoomChatShardsKey(roomID string) string {
+	return redis.FormatKey(RoomChatShardsKeyPrefix, roomID)
+}
+
+// formatRoomChatShardSizesKey formats a key for the sizes of room chat shards
+func formatRoomChatShardSizesKey(roomID string) string {
+	return redis.FormatKey(RoomChatShardSizesKeyPrefix, roomID)
+}
+
 // formatRoomQueueKey formats a key for room DJ queue
 func formatRoomQueueKey(roomID string) string {
 	return redis.FormatKey(RoomQueueKeyPrefix, roomID)
@@ -988,6 +1919,11 @@ func formatRoomVotesKey(roomID, mediaID string) string {
 	return redis.FormatKey(RoomVotesKeyPrefix, fmt.Sprintf("%s:%s", roomID, mediaID))
 }
 
+// formatRoomReactorsKey formats a key for the users who added a reaction to a media item
+func formatRoomReactorsKey(roomID, mediaID, reaction string) string {
+	return fmt.Sprintf("%s:%s:reactors", formatRoomVotesKey(roomID, mediaID), reaction)
+}
+
 // formatRoomHistoryKey formats a key for room history
 func formatRoomHistoryKey(roomID string) string {
 	return redis.FormatKey(RoomHistoryKeyPrefix, roomID)
@@ -0,0 +1,125 @@
+// Package redis provides Redis database connectivity and operations.
+package managers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxCachedRoomStates is the number of cached room states past which expired ones are swept out.
+const maxCachedRoomStates = 10000
+
+// roomStateChannel is the PubSub channel room state writes are announced on, so every node drops its cached copy.
+var roomStateChannel = FormatGlobalChannel("room_state_changed")
+
+// cachedRoomState is a room state held in memory until it expires.
+type cachedRoomState struct {
+	state   *RoomState
+	expires time.Time
+}
+
+// RoomStateCache is a RoomStateReader keeping room states in memory for a short time, so read paths
+// that run often don't fetch and decode the state from Redis every time. Writes through the
+// RoomStateManager drop the cached copy on every node, and the TTL bounds how stale a copy can get
+// if an announcement is missed.
+type RoomStateCache struct {
+	manager *RoomStateManager
+	pubSub  *PubSubManager
+	ttl     time.Duration
+	entries map[string]cachedRoomState
+	mutex   sync.RWMutex
+}
+
+// NewRoomStateCache creates a new room state cache over a room state manager. A zero TTL disables the cache.
+func NewRoomStateCache(manager *RoomStateManager, pubSub *PubSubManager, ttl time.Duration) *RoomStateCache {
+	c := &RoomStateCache{
+		manager: manager,
+		pubSub:  pubSub,
+		ttl:     ttl,
+		entries: make(map[string]cachedRoomState),
+	}
+	if ttl > 0 {
+		manager.OnStateChange(c.announce)
+	}
+	return c
+}
+
+// Start begins receiving the room state writes made on other nodes.
+func (c *RoomStateCache) Start() error {
+	if c.ttl <= 0 {
+		return nil
+	}
+
+	if err := c.pubSub.Subscribe(roomStateChannel); err != nil {
+		return fmt.Errorf("failed to subscribe to room state channel: %w", err)
+	}
+
+	c.pubSub.AddHandler(roomStateChannel, func(channel string, payload []byte) {
+		var roomID string
+		if err := json.Unmarshal(payload, &roomID); err != nil {
+			c.manager.client.Logger().Error("Failed to unmarshal room state change", err)
+			return
+		}
+		c.Invalidate(roomID)
+	})
+
+	return nil
+}
+
+// GetRoomState gets a room's state, from memory while the cached copy is fresh. Missing states aren't cached.
+func (c *RoomStateCache) GetRoomState(ctx context.Context, roomID string) (*RoomState, error) {
+	if c.ttl <= 0 {
+		return c.manager.GetRoomState(ctx, roomID)
+	}
+
+	c.mutex.RLock()
+	entry, ok := c.entries[roomID]
+	c.mutex.RUnlock()
+	if ok && time.Now().Before(entry.expires) {
+		state := *entry.state
+		return &state, nil
+	}
+
+	state, err := c.manager.GetRoomState(ctx, roomID)
+	if err != nil || state == nil {
+		return state, err
+	}
+
+	c.mutex.Lock()
+	if len(c.entries) >= maxCachedRoomStates {
+		c.sweep()
+	}
+	c.entries[roomID] = cachedRoomState{state: state, expires: time.Now().Add(c.ttl)}
+	c.mutex.Unlock()
+
+	cached := *state
+	return &cached, nil
+}
+
+// Invalidate drops the cached state of a room.
+func (c *RoomStateCache) Invalidate(roomID string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.entries, roomID)
+}
+
+// announce drops the cached state of a room written on this node and tells the other nodes to do the same.
+func (c *RoomStateCache) announce(ctx context.Context, roomID string) {
+	c.Invalidate(roomID)
+	if err := c.pubSub.Publish(ctx, roomStateChannel, roomID); err != nil {
+		c.manager.client.Logger().Warn("Failed to announce room state change", "roomId", roomID, "error", err)
+	}
+}
+
+// sweep drops the expired states. The caller must hold the write lock.
+func (c *RoomStateCache) sweep() {
+	now := time.Now()
+	for roomID, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, roomID)
+		}
+	}
+}
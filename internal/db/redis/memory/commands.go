@@ -0,0 +1,1434 @@
+package memory
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors replied for invalid arguments.
+const (
+	errNotInteger = "ERR value is not an integer or out of range"
+	errNotFloat   = "ERR value is not a valid float"
+	errSyntax     = "ERR syntax error"
+)
+
+// command is a data command the server runs, with the data locked.
+type command struct {
+	// arity is the number of arguments including the command name, or its negation for a minimum
+	arity int
+	run   func(s *Server, w *writer, args []string)
+}
+
+// accepts checks whether the command can be called with n arguments including its name.
+func (c *command) accepts(n int) bool {
+	if c.arity < 0 {
+		return n >= -c.arity
+	}
+	return n == c.arity
+}
+
+// commands are the data commands the server implements, by lowercase name. Connection, transaction and
+// pub/sub commands are handled by the client.
+var commands = map[string]*command{
+	// Server
+	"select":   {2, cmdOK},
+	"auth":     {-2, cmdOK},
+	"client":   {-2, cmdOK},
+	"echo":     {2, cmdEcho},
+	"info":     {-1, cmdInfo},
+	"dbsize":   {1, cmdDBSize},
+	"flushdb":  {-1, cmdFlush},
+	"flushall": {-1, cmdFlush},
+	"memory":   {-2, cmdMemory},
+
+	// Keys
+	"del":       {-2, cmdDel},
+	"unlink":    {-2, cmdDel},
+	"exists":    {-2, cmdExists},
+	"type":      {2, cmdType},
+	"keys":      {2, cmdKeys},
+	"scan":      {-2, cmdScan},
+	"expire":    {-3, cmdExpire(time.Second, false)},
+	"pexpire":   {-3, cmdExpire(time.Millisecond, false)},
+	"expireat":  {-3, cmdExpire(time.Second, true)},
+	"pexpireat": {-3, cmdExpire(time.Millisecond, true)},
+	"persist":   {2, cmdPersist},
+	"ttl":       {2, cmdTTL(time.Second)},
+	"pttl":      {2, cmdTTL(time.Millisecond)},
+
+	// Strings
+	"get":    {2, cmdGet},
+	"getdel": {2, cmdGetDel},
+	"mget":   {-2, cmdMGet},
+	"set":    {-3, cmdSet},
+	"setnx":  {3, cmdSetNX},
+	"setex":  {4, cmdSetEX},
+	"incr":   {2, cmdIncrBy(1, false)},
+	"decr":   {2, cmdIncrBy(-1, false)},
+	"incrby": {3, cmdIncrBy(1, true)},
+	"decrby": {3, cmdIncrBy(-1, true)},
+
+	// Hashes
+	"hset":    {-4, cmdHSet},
+	"hmset":   {-4, cmdHMSet},
+	"hsetnx":  {4, cmdHSetNX},
+	"hget":    {3, cmdHGet},
+	"hmget":   {-3, cmdHMGet},
+	"hgetall": {2, cmdHGetAll},
+	"hkeys":   {2, cmdHKeys},
+	"hvals":   {2, cmdHVals},
+	"hdel":    {-3, cmdHDel},
+	"hexists": {3, cmdHExists},
+	"hlen":    {2, cmdHLen},
+	"hincrby": {4, cmdHIncrBy},
+
+	// Lists
+	"lpush":  {-3, cmdPush(true, false)},
+	"rpush":  {-3, cmdPush(false, false)},
+	"lpushx": {-3, cmdPush(true, true)},
+	"rpushx": {-3, cmdPush(false, true)},
+	"lpop":   {-2, cmdPop(true)},
+	"rpop":   {-2, cmdPop(false)},
+	"lrange": {4, cmdLRange},
+	"llen":   {2, cmdLLen},
+	"lindex": {3, cmdLIndex},
+	"lset":   {4, cmdLSet},
+	"lrem":   {4, cmdLRem},
+	"ltrim":  {4, cmdLTrim},
+
+	// Sets
+	"sadd":      {-3, cmdSAdd},
+	"srem":      {-3, cmdSRem},
+	"scard":     {2, cmdSCard},
+	"sismember": {3, cmdSIsMember},
+	"smembers":  {2, cmdSMembers},
+
+	// Sorted sets
+	"zadd":             {-4, cmdZAdd},
+	"zincrby":          {4, cmdZIncrBy},
+	"zrem":             {-3, cmdZRem},
+	"zcard":            {2, cmdZCard},
+	"zscore":           {3, cmdZScore},
+	"zrank":            {3, cmdZRank(false)},
+	"zrevrank":         {3, cmdZRank(true)},
+	"zrange":           {-4, cmdZRange(false)},
+	"zrevrange":        {-4, cmdZRange(true)},
+	"zrangebyscore":    {-4, cmdZRangeByScore},
+	"zcount":           {4, cmdZCount},
+	"zremrangebyscore": {4, cmdZRemRangeByScore},
+	"zremrangebyrank":  {4, cmdZRemRangeByRank},
+}
+
+// cmdOK accepts a command it has nothing to do for, such as selecting the only database.
+func cmdOK(s *Server, w *writer, args []string) {
+	w.ok()
+}
+
+// cmdEcho replies with its argument.
+func cmdEcho(s *Server, w *writer, args []string) {
+	w.bulk(args[0])
+}
+
+// cmdInfo reports the memory used by the data. The server has no memory limit.
+func cmdInfo(s *Server, w *writer, args []string) {
+	var used int64
+	for key, e := range s.keys {
+		used += sizeOf(key, e.value)
+	}
+	w.bulk(fmt.Sprintf("# Memory\r\nused_memory:%d\r\nmaxmemory:0\r\nmaxmemory_policy:noeviction\r\n\r\n# Keyspace\r\ndb0:keys=%d\r\n", used, len(s.keys)))
+}
+
+// cmdDBSize counts the keys.
+func cmdDBSize(s *Server, w *writer, args []string) {
+	w.int(int64(len(s.liveKeys())))
+}
+
+// cmdFlush deletes every key.
+func cmdFlush(s *Server, w *writer, args []string) {
+	for key := range s.keys {
+		s.remove(key)
+	}
+	w.ok()
+}
+
+// cmdMemory estimates the memory used by a key with MEMORY USAGE.
+func cmdMemory(s *Server, w *writer, args []string) {
+	if strings.ToLower(args[0]) != "usage" || len(args) < 2 {
+		w.err("ERR unknown subcommand or wrong number of arguments for 'memory' command")
+		return
+	}
+	e := s.lookup(args[1])
+	if e == nil {
+		w.null()
+		return
+	}
+	w.int(sizeOf(args[1], e.value))
+}
+
+// cmdDel deletes keys, and replies how many existed.
+func cmdDel(s *Server, w *writer, args []string) {
+	var deleted int64
+	for _, key := range args {
+		if s.lookup(key) != nil && s.remove(key) {
+			deleted++
+		}
+	}
+	w.int(deleted)
+}
+
+// cmdExists counts the keys that exist, a key given twice counting twice.
+func cmdExists(s *Server, w *writer, args []string) {
+	var count int64
+	for _, key := range args {
+		if s.lookup(key) != nil {
+			count++
+		}
+	}
+	w.int(count)
+}
+
+// cmdType replies with the type of a key's value.
+func cmdType(s *Server, w *writer, args []string) {
+	e := s.lookup(args[0])
+	if e == nil {
+		w.simple("none")
+		return
+	}
+	w.simple(typeName(e.value))
+}
+
+// cmdKeys lists the keys matching a pattern.
+func cmdKeys(s *Server, w *writer, args []string) {
+	keys := []string{}
+	for _, key := range s.liveKeys() {
+		if matchGlob(args[0], key) {
+			keys = append(keys, key)
+		}
+	}
+	w.bulks(keys)
+}
+
+// cmdScan lists the keys a page at a time. The cursor is the position in the sorted keys, so keys
+// created or deleted while scanning may be skipped or listed twice, as Redis allows.
+func cmdScan(s *Server, w *writer, args []string) {
+	cursor, err := strconv.Atoi(args[0])
+	if err != nil || cursor < 0 {
+		w.err("ERR invalid cursor")
+		return
+	}
+
+	pattern, count, kind := "*", 10, ""
+	for i := 1; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			w.err(errSyntax)
+			return
+		}
+		switch strings.ToLower(args[i]) {
+		case "match":
+			pattern = args[i+1]
+		case "count":
+			count, err = strconv.Atoi(args[i+1])
+			if err != nil || count < 1 {
+				w.err(errSyntax)
+				return
+			}
+		case "type":
+			kind = strings.ToLower(args[i+1])
+		default:
+			w.err(errSyntax)
+			return
+		}
+	}
+
+	keys := s.liveKeys()
+	end := min(cursor+count, len(keys))
+	matched := []string{}
+	for _, key := range keys[min(cursor, len(keys)):end] {
+		if matchGlob(pattern, key) && (kind == "" || typeName(s.keys[key].value) == kind) {
+			matched = append(matched, key)
+		}
+	}
+	if end >= len(keys) {
+		end = 0
+	}
+
+	w.array(2)
+	w.bulk(strconv.Itoa(end))
+	w.bulks(matched)
+}
+
+// cmdExpire sets a key's expiry, relative or absolute, in the given unit.
+func cmdExpire(unit time.Duration, absolute bool) func(s *Server, w *writer, args []string) {
+	return func(s *Server, w *writer, args []string) {
+		n, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			w.err(errNotInteger)
+			return
+		}
+		e := s.lookup(args[0])
+		if e == nil {
+			w.int(0)
+			return
+		}
+
+		at := time.Now().Add(time.Duration(n) * unit)
+		if absolute {
+			at = time.UnixMilli(0).Add(time.Duration(n) * unit)
+		}
+		for _, option := range args[2:] {
+			switch strings.ToLower(option) {
+			case "nx":
+				if !e.expiresAt.IsZero() {
+					w.int(0)
+					return
+				}
+			case "xx":
+				if e.expiresAt.IsZero() {
+					w.int(0)
+					return
+				}
+			case "gt":
+				if e.expiresAt.IsZero() || !at.After(e.expiresAt) {
+					w.int(0)
+					return
+				}
+			case "lt":
+				if !e.expiresAt.IsZero() && !at.Before(e.expiresAt) {
+					w.int(0)
+					return
+				}
+			default:
+				w.err("ERR Unsupported option " + option)
+				return
+			}
+		}
+
+		if !at.After(time.Now()) {
+			s.remove(args[0])
+		} else {
+			e.expiresAt = at
+			s.touch(args[0])
+		}
+		w.int(1)
+	}
+}
+
+// cmdPersist drops a key's expiry.
+func cmdPersist(s *Server, w *writer, args []string) {
+	e := s.lookup(args[0])
+	if e == nil || e.expiresAt.IsZero() {
+		w.int(0)
+		return
+	}
+	e.expiresAt = time.Time{}
+	s.touch(args[0])
+	w.int(1)
+}
+
+// cmdTTL replies with the time left before a key expires in the given unit, -1 for keys that don't
+// expire and -2 for missing keys.
+func cmdTTL(unit time.Duration) func(s *Server, w *writer, args []string) {
+	return func(s *Server, w *writer, args []string) {
+		e := s.lookup(args[0])
+		switch {
+		case e == nil:
+			w.int(-2)
+		case e.expiresAt.IsZero():
+			w.int(-1)
+		default:
+			left := time.Until(e.expiresAt)
+			w.int(int64((left + unit - 1) / unit))
+		}
+	}
+}
+
+// cmdGet gets the value of a string key.
+func cmdGet(s *Server, w *writer, args []string) {
+	value, found, ok := s.getString(args[0])
+	switch {
+	case !ok:
+		w.wrongType()
+	case !found:
+		w.null()
+	default:
+		w.bulk(value)
+	}
+}
+
+// cmdGetDel gets the value of a string key and deletes it.
+func cmdGetDel(s *Server, w *writer, args []string) {
+	cmdGet(s, w, args)
+	if _, found, ok := s.getString(args[0]); found && ok {
+		s.remove(args[0])
+	}
+}
+
+// cmdMGet gets the values of string keys, nil for the missing ones and those of another type.
+func cmdMGet(s *Server, w *writer, args []string) {
+	w.array(len(args))
+	for _, key := range args {
+		if value, found, _ := s.getString(key); found {
+			w.bulk(value)
+		} else {
+			w.null()
+		}
+	}
+}
+
+// cmdSet sets the value of a string key, with the EX, PX, EXAT, PXAT, NX, XX, KEEPTTL and GET options.
+func cmdSet(s *Server, w *writer, args []string) {
+	key, value := args[0], args[1]
+	var (
+		expiresAt         time.Time
+		nx, xx, keep, get bool
+	)
+	for i := 2; i < len(args); i++ {
+		option := strings.ToLower(args[i])
+		switch option {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "keepttl":
+			keep = true
+		case "get":
+			get = true
+		case "ex", "px", "exat", "pxat":
+			if i+1 >= len(args) {
+				w.err(errSyntax)
+				return
+			}
+			i++
+			n, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil || n <= 0 {
+				w.err("ERR invalid expire time in 'set' command")
+				return
+			}
+			switch option {
+			case "ex":
+				expiresAt = time.Now().Add(time.Duration(n) * time.Second)
+			case "px":
+				expiresAt = time.Now().Add(time.Duration(n) * time.Millisecond)
+			case "exat":
+				expiresAt = time.Unix(n, 0)
+			case "pxat":
+				expiresAt = time.UnixMilli(n)
+			}
+		default:
+			w.err(errSyntax)
+			return
+		}
+	}
+	if nx && xx {
+		w.err(errSyntax)
+		return
+	}
+
+	old, found, ok := s.getString(key)
+	if get && !ok {
+		w.wrongType()
+		return
+	}
+	existed := s.lookup(key) != nil
+	if (nx && existed) || (xx && !existed) {
+		if get && found {
+			w.bulk(old)
+		} else {
+			w.null()
+		}
+		return
+	}
+
+	if keep && existed {
+		expiresAt = s.keys[key].expiresAt
+	}
+	s.keys[key] = &entry{value: value, expiresAt: expiresAt}
+	s.touch(key)
+
+	switch {
+	case get && found:
+		w.bulk(old)
+	case get:
+		w.null()
+	default:
+		w.ok()
+	}
+}
+
+// cmdSetNX sets the value of a key that doesn't exist.
+func cmdSetNX(s *Server, w *writer, args []string) {
+	if s.lookup(args[0]) != nil {
+		w.int(0)
+		return
+	}
+	s.store(args[0], args[1])
+	w.int(1)
+}
+
+// cmdSetEX sets the value of a string key expiring in a number of seconds.
+func cmdSetEX(s *Server, w *writer, args []string) {
+	cmdSet(s, w, []string{args[0], args[2], "ex", args[1]})
+}
+
+// cmdIncrBy adds to the integer value of a string key, by the sign times one or times its argument.
+func cmdIncrBy(sign int64, withArg bool) func(s *Server, w *writer, args []string) {
+	return func(s *Server, w *writer, args []string) {
+		by := sign
+		if withArg {
+			n, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				w.err(errNotInteger)
+				return
+			}
+			by = sign * n
+		}
+
+		value, found, ok := s.getString(args[0])
+		if !ok {
+			w.wrongType()
+			return
+		}
+		var current int64
+		if found {
+			var err error
+			current, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				w.err(errNotInteger)
+				return
+			}
+		}
+
+		current += by
+		if found {
+			s.keys[args[0]].value = strconv.FormatInt(current, 10)
+			s.touch(args[0])
+		} else {
+			s.store(args[0], strconv.FormatInt(current, 10))
+		}
+		w.int(current)
+	}
+}
+
+// cmdHSet sets fields of a hash, and replies how many were added.
+func cmdHSet(s *Server, w *writer, args []string) {
+	if len(args)%2 != 1 {
+		w.arityErr("hset")
+		return
+	}
+	hash, ok := s.getHash(args[0], true)
+	if !ok {
+		w.wrongType()
+		return
+	}
+
+	var added int64
+	for i := 1; i < len(args); i += 2 {
+		if _, exists := hash[args[i]]; !exists {
+			added++
+		}
+		hash[args[i]] = args[i+1]
+	}
+	s.touch(args[0])
+	w.int(added)
+}
+
+// cmdHMSet sets fields of a hash.
+func cmdHMSet(s *Server, w *writer, args []string) {
+	reply := &writer{}
+	cmdHSet(s, reply, args)
+	if strings.HasPrefix(reply.String(), ":") {
+		w.ok()
+		return
+	}
+	w.Write(reply.Bytes())
+}
+
+// cmdHSetNX sets a field of a hash that doesn't have it.
+func cmdHSetNX(s *Server, w *writer, args []string) {
+	hash, ok := s.getHash(args[0], true)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	if _, exists := hash[args[1]]; exists {
+		w.int(0)
+		return
+	}
+	hash[args[1]] = args[2]
+	s.touch(args[0])
+	w.int(1)
+}
+
+// cmdHGet gets a field of a hash.
+func cmdHGet(s *Server, w *writer, args []string) {
+	hash, ok := s.getHash(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	value, exists := hash[args[1]]
+	if !exists {
+		w.null()
+		return
+	}
+	w.bulk(value)
+}
+
+// cmdHMGet gets fields of a hash, nil for the missing ones.
+func cmdHMGet(s *Server, w *writer, args []string) {
+	hash, ok := s.getHash(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	w.array(len(args) - 1)
+	for _, field := range args[1:] {
+		if value, exists := hash[field]; exists {
+			w.bulk(value)
+		} else {
+			w.null()
+		}
+	}
+}
+
+// cmdHGetAll gets the fields of a hash and their values.
+func cmdHGetAll(s *Server, w *writer, args []string) {
+	hash, ok := s.getHash(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	fields := sortedKeys(hash)
+	w.array(2 * len(fields))
+	for _, field := range fields {
+		w.bulk(field)
+		w.bulk(hash[field])
+	}
+}
+
+// cmdHKeys gets the fields of a hash.
+func cmdHKeys(s *Server, w *writer, args []string) {
+	hash, ok := s.getHash(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	w.bulks(sortedKeys(hash))
+}
+
+// cmdHVals gets the values of a hash.
+func cmdHVals(s *Server, w *writer, args []string) {
+	hash, ok := s.getHash(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	fields := sortedKeys(hash)
+	w.array(len(fields))
+	for _, field := range fields {
+		w.bulk(hash[field])
+	}
+}
+
+// cmdHDel deletes fields of a hash, and replies how many existed.
+func cmdHDel(s *Server, w *writer, args []string) {
+	hash, ok := s.getHash(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	var deleted int64
+	for _, field := range args[1:] {
+		if _, exists := hash[field]; exists {
+			delete(hash, field)
+			deleted++
+		}
+	}
+	if deleted > 0 {
+		s.touch(args[0])
+		s.removeIfEmpty(args[0], len(hash))
+	}
+	w.int(deleted)
+}
+
+// cmdHExists checks whether a hash has a field.
+func cmdHExists(s *Server, w *writer, args []string) {
+	hash, ok := s.getHash(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	_, exists := hash[args[1]]
+	w.int(boolInt(exists))
+}
+
+// cmdHLen counts the fields of a hash.
+func cmdHLen(s *Server, w *writer, args []string) {
+	hash, ok := s.getHash(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	w.int(int64(len(hash)))
+}
+
+// cmdHIncrBy adds to the integer value of a field of a hash.
+func cmdHIncrBy(s *Server, w *writer, args []string) {
+	by, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		w.err(errNotInteger)
+		return
+	}
+	hash, ok := s.getHash(args[0], true)
+	if !ok {
+		w.wrongType()
+		return
+	}
+
+	var current int64
+	if value, exists := hash[args[1]]; exists {
+		current, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			s.removeIfEmpty(args[0], len(hash))
+			w.err("ERR hash value is not an integer")
+			return
+		}
+	}
+	current += by
+	hash[args[1]] = strconv.FormatInt(current, 10)
+	s.touch(args[0])
+	w.int(current)
+}
+
+// cmdPush adds values to the head or the tail of a list, only if it exists when asked to, and replies
+// with its length.
+func cmdPush(head, onlyExisting bool) func(s *Server, w *writer, args []string) {
+	return func(s *Server, w *writer, args []string) {
+		l, ok := s.getList(args[0], !onlyExisting)
+		if !ok {
+			w.wrongType()
+			return
+		}
+		if l == nil {
+			w.int(0)
+			return
+		}
+
+		for _, value := range args[1:] {
+			if head {
+				l.items = slices.Insert(l.items, 0, value)
+			} else {
+				l.items = append(l.items, value)
+			}
+		}
+		s.touch(args[0])
+		w.int(int64(len(l.items)))
+	}
+}
+
+// cmdPop removes values from the head or the tail of a list, one or as many as asked for.
+func cmdPop(head bool) func(s *Server, w *writer, args []string) {
+	return func(s *Server, w *writer, args []string) {
+		count, withCount := 1, len(args) > 1
+		if withCount {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 0 {
+				w.err(errNotInteger)
+				return
+			}
+			count = n
+		}
+
+		l, ok := s.getList(args[0], false)
+		if !ok {
+			w.wrongType()
+			return
+		}
+		if l == nil {
+			if withCount {
+				w.nullArray()
+			} else {
+				w.null()
+			}
+			return
+		}
+
+		count = min(count, len(l.items))
+		var popped []string
+		if head {
+			popped = slices.Clone(l.items[:count])
+			l.items = l.items[count:]
+		} else {
+			popped = slices.Clone(l.items[len(l.items)-count:])
+			slices.Reverse(popped)
+			l.items = l.items[:len(l.items)-count]
+		}
+		if count > 0 {
+			s.touch(args[0])
+		}
+		s.removeIfEmpty(args[0], len(l.items))
+
+		if withCount {
+			w.bulks(popped)
+		} else {
+			w.bulk(popped[0])
+		}
+	}
+}
+
+// cmdLRange gets a range of a list.
+func cmdLRange(s *Server, w *writer, args []string) {
+	start, err1 := strconv.Atoi(args[1])
+	stop, err2 := strconv.Atoi(args[2])
+	if err1 != nil || err2 != nil {
+		w.err(errNotInteger)
+		return
+	}
+	l, ok := s.getList(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	if l == nil {
+		w.array(0)
+		return
+	}
+
+	from, to, empty := normalizeRange(start, stop, len(l.items))
+	if empty {
+		w.array(0)
+		return
+	}
+	w.bulks(l.items[from:to])
+}
+
+// cmdLLen gets the length of a list.
+func cmdLLen(s *Server, w *writer, args []string) {
+	l, ok := s.getList(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	if l == nil {
+		w.int(0)
+		return
+	}
+	w.int(int64(len(l.items)))
+}
+
+// cmdLIndex gets an element of a list by its index.
+func cmdLIndex(s *Server, w *writer, args []string) {
+	index, err := strconv.Atoi(args[1])
+	if err != nil {
+		w.err(errNotInteger)
+		return
+	}
+	l, ok := s.getList(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	if l == nil {
+		w.null()
+		return
+	}
+	if index < 0 {
+		index += len(l.items)
+	}
+	if index < 0 || index >= len(l.items) {
+		w.null()
+		return
+	}
+	w.bulk(l.items[index])
+}
+
+// cmdLSet sets an element of a list by its index.
+func cmdLSet(s *Server, w *writer, args []string) {
+	index, err := strconv.Atoi(args[1])
+	if err != nil {
+		w.err(errNotInteger)
+		return
+	}
+	l, ok := s.getList(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	if l == nil {
+		w.err("ERR no such key")
+		return
+	}
+	if index < 0 {
+		index += len(l.items)
+	}
+	if index < 0 || index >= len(l.items) {
+		w.err("ERR index out of range")
+		return
+	}
+	l.items[index] = args[2]
+	s.touch(args[0])
+	w.ok()
+}
+
+// cmdLRem removes elements equal to a value from a list: the first ones for a positive count, the last
+// ones for a negative count and all of them for zero.
+func cmdLRem(s *Server, w *writer, args []string) {
+	count, err := strconv.Atoi(args[1])
+	if err != nil {
+		w.err(errNotInteger)
+		return
+	}
+	l, ok := s.getList(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	if l == nil {
+		w.int(0)
+		return
+	}
+
+	limit := count
+	if limit < 0 {
+		limit = -limit
+		slices.Reverse(l.items)
+	}
+	removed := 0
+	l.items = slices.DeleteFunc(l.items, func(item string) bool {
+		if item != args[2] || (limit > 0 && removed >= limit) {
+			return false
+		}
+		removed++
+		return true
+	})
+	if count < 0 {
+		slices.Reverse(l.items)
+	}
+
+	if removed > 0 {
+		s.touch(args[0])
+		s.removeIfEmpty(args[0], len(l.items))
+	}
+	w.int(int64(removed))
+}
+
+// cmdLTrim trims a list to a range.
+func cmdLTrim(s *Server, w *writer, args []string) {
+	start, err1 := strconv.Atoi(args[1])
+	stop, err2 := strconv.Atoi(args[2])
+	if err1 != nil || err2 != nil {
+		w.err(errNotInteger)
+		return
+	}
+	l, ok := s.getList(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	if l == nil {
+		w.ok()
+		return
+	}
+
+	from, to, empty := normalizeRange(start, stop, len(l.items))
+	if empty {
+		l.items = nil
+	} else {
+		l.items = slices.Clone(l.items[from:to])
+	}
+	s.touch(args[0])
+	s.removeIfEmpty(args[0], len(l.items))
+	w.ok()
+}
+
+// cmdSAdd adds members to a set, and replies how many were added.
+func cmdSAdd(s *Server, w *writer, args []string) {
+	set, ok := s.getSet(args[0], true)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	var added int64
+	for _, m := range args[1:] {
+		if _, exists := set[m]; !exists {
+			set[m] = struct{}{}
+			added++
+		}
+	}
+	s.touch(args[0])
+	w.int(added)
+}
+
+// cmdSRem removes members from a set, and replies how many were in it.
+func cmdSRem(s *Server, w *writer, args []string) {
+	set, ok := s.getSet(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	var removed int64
+	for _, m := range args[1:] {
+		if _, exists := set[m]; exists {
+			delete(set, m)
+			removed++
+		}
+	}
+	if removed > 0 {
+		s.touch(args[0])
+		s.removeIfEmpty(args[0], len(set))
+	}
+	w.int(removed)
+}
+
+// cmdSCard counts the members of a set.
+func cmdSCard(s *Server, w *writer, args []string) {
+	set, ok := s.getSet(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	w.int(int64(len(set)))
+}
+
+// cmdSIsMember checks whether a set has a member.
+func cmdSIsMember(s *Server, w *writer, args []string) {
+	set, ok := s.getSet(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	_, exists := set[args[1]]
+	w.int(boolInt(exists))
+}
+
+// cmdSMembers gets the members of a set.
+func cmdSMembers(s *Server, w *writer, args []string) {
+	set, ok := s.getSet(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	w.bulks(sortedKeys(set))
+}
+
+// cmdZAdd adds members to a sorted set or updates their scores, with the NX, XX, GT, LT, CH and INCR
+// options.
+func cmdZAdd(s *Server, w *writer, args []string) {
+	key := args[0]
+	var nx, xx, gt, lt, ch, incr bool
+	i := 1
+options:
+	for ; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "gt":
+			gt = true
+		case "lt":
+			lt = true
+		case "ch":
+			ch = true
+		case "incr":
+			incr = true
+		default:
+			break options
+		}
+	}
+	pairs := args[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 || (nx && (xx || gt || lt)) || (gt && lt) || (incr && len(pairs) != 2) {
+		w.err(errSyntax)
+		return
+	}
+
+	scores := make([]float64, len(pairs)/2)
+	for j := range scores {
+		score, ok := parseFloat(pairs[2*j])
+		if !ok {
+			w.err(errNotFloat)
+			return
+		}
+		scores[j] = score
+	}
+
+	z, ok := s.getSortedSet(key, !xx)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	if z == nil {
+		if incr {
+			w.null()
+		} else {
+			w.int(0)
+		}
+		return
+	}
+
+	var added, changed int64
+	var result float64
+	updated := true
+	for j, score := range scores {
+		name := pairs[2*j+1]
+		current, exists := z.scores[name]
+		if incr && exists {
+			score += current
+		}
+		if (nx && exists) || (xx && !exists) ||
+			(exists && gt && score <= current) || (exists && lt && score >= current) {
+			updated = false
+			continue
+		}
+
+		z.scores[name] = score
+		result = score
+		if !exists {
+			added++
+		} else if current != score {
+			changed++
+		}
+	}
+	s.touch(key)
+	s.removeIfEmpty(key, len(z.scores))
+
+	switch {
+	case incr && !updated:
+		w.null()
+	case incr:
+		w.bulk(formatFloat(result))
+	case ch:
+		w.int(added + changed)
+	default:
+		w.int(added)
+	}
+}
+
+// cmdZIncrBy adds to the score of a member of a sorted set.
+func cmdZIncrBy(s *Server, w *writer, args []string) {
+	by, ok := parseFloat(args[1])
+	if !ok {
+		w.err(errNotFloat)
+		return
+	}
+	z, ok := s.getSortedSet(args[0], true)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	z.scores[args[2]] += by
+	s.touch(args[0])
+	w.bulk(formatFloat(z.scores[args[2]]))
+}
+
+// cmdZRem removes members from a sorted set, and replies how many were in it.
+func cmdZRem(s *Server, w *writer, args []string) {
+	z, ok := s.getSortedSet(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	if z == nil {
+		w.int(0)
+		return
+	}
+	var removed int64
+	for _, name := range args[1:] {
+		if _, exists := z.scores[name]; exists {
+			delete(z.scores, name)
+			removed++
+		}
+	}
+	if removed > 0 {
+		s.touch(args[0])
+		s.removeIfEmpty(args[0], len(z.scores))
+	}
+	w.int(removed)
+}
+
+// cmdZCard counts the members of a sorted set.
+func cmdZCard(s *Server, w *writer, args []string) {
+	z, ok := s.getSortedSet(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	if z == nil {
+		w.int(0)
+		return
+	}
+	w.int(int64(len(z.scores)))
+}
+
+// cmdZScore gets the score of a member of a sorted set.
+func cmdZScore(s *Server, w *writer, args []string) {
+	z, ok := s.getSortedSet(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	if z == nil {
+		w.null()
+		return
+	}
+	score, exists := z.scores[args[1]]
+	if !exists {
+		w.null()
+		return
+	}
+	w.bulk(formatFloat(score))
+}
+
+// cmdZRank gets the rank of a member of a sorted set, from the lowest score or from the highest.
+func cmdZRank(reverse bool) func(s *Server, w *writer, args []string) {
+	return func(s *Server, w *writer, args []string) {
+		z, ok := s.getSortedSet(args[0], false)
+		if !ok {
+			w.wrongType()
+			return
+		}
+		if z == nil {
+			w.null()
+			return
+		}
+		members := z.sorted()
+		if reverse {
+			slices.Reverse(members)
+		}
+		rank := slices.IndexFunc(members, func(m member) bool { return m.name == args[1] })
+		if rank < 0 {
+			w.null()
+			return
+		}
+		w.int(int64(rank))
+	}
+}
+
+// cmdZRange gets a range of a sorted set by rank, from the lowest score or from the highest, with the
+// WITHSCORES option.
+func cmdZRange(reverse bool) func(s *Server, w *writer, args []string) {
+	return func(s *Server, w *writer, args []string) {
+		start, err1 := strconv.Atoi(args[1])
+		stop, err2 := strconv.Atoi(args[2])
+		if err1 != nil || err2 != nil {
+			w.err(errNotInteger)
+			return
+		}
+		withScores := false
+		for _, option := range args[3:] {
+			switch strings.ToLower(option) {
+			case "withscores":
+				withScores = true
+			case "rev":
+				reverse = !reverse
+			default:
+				w.err(errSyntax)
+				return
+			}
+		}
+
+		z, ok := s.getSortedSet(args[0], false)
+		if !ok {
+			w.wrongType()
+			return
+		}
+		var members []member
+		if z != nil {
+			members = z.sorted()
+		}
+		if reverse {
+			slices.Reverse(members)
+		}
+
+		from, to, empty := normalizeRange(start, stop, len(members))
+		if empty {
+			w.array(0)
+			return
+		}
+		writeMembers(w, members[from:to], withScores)
+	}
+}
+
+// cmdZRangeByScore gets the members of a sorted set within a score range, with the WITHSCORES and
+// LIMIT options.
+func cmdZRangeByScore(s *Server, w *writer, args []string) {
+	minBound, ok1 := parseScoreBound(args[1])
+	maxBound, ok2 := parseScoreBound(args[2])
+	if !ok1 || !ok2 {
+		w.err("ERR min or max is not a float")
+		return
+	}
+
+	withScores, offset, count := false, 0, -1
+	for i := 3; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "withscores":
+			withScores = true
+		case "limit":
+			if i+2 >= len(args) {
+				w.err(errSyntax)
+				return
+			}
+			var err1, err2 error
+			offset, err1 = strconv.Atoi(args[i+1])
+			count, err2 = strconv.Atoi(args[i+2])
+			if err1 != nil || err2 != nil {
+				w.err(errNotInteger)
+				return
+			}
+			i += 2
+		default:
+			w.err(errSyntax)
+			return
+		}
+	}
+
+	z, ok := s.getSortedSet(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	members := []member{}
+	if z != nil {
+		for _, m := range z.sorted() {
+			if minBound.below(m.score) && maxBound.above(m.score) {
+				members = append(members, m)
+			}
+		}
+	}
+
+	if offset < 0 || offset >= len(members) {
+		members = nil
+	} else {
+		members = members[offset:]
+		if count >= 0 && count < len(members) {
+			members = members[:count]
+		}
+	}
+	writeMembers(w, members, withScores)
+}
+
+// cmdZCount counts the members of a sorted set within a score range.
+func cmdZCount(s *Server, w *writer, args []string) {
+	minBound, ok1 := parseScoreBound(args[1])
+	maxBound, ok2 := parseScoreBound(args[2])
+	if !ok1 || !ok2 {
+		w.err("ERR min or max is not a float")
+		return
+	}
+	z, ok := s.getSortedSet(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+
+	var count int64
+	if z != nil {
+		for _, score := range z.scores {
+			if minBound.below(score) && maxBound.above(score) {
+				count++
+			}
+		}
+	}
+	w.int(count)
+}
+
+// cmdZRemRangeByScore removes the members of a sorted set within a score range.
+func cmdZRemRangeByScore(s *Server, w *writer, args []string) {
+	minBound, ok1 := parseScoreBound(args[1])
+	maxBound, ok2 := parseScoreBound(args[2])
+	if !ok1 || !ok2 {
+		w.err("ERR min or max is not a float")
+		return
+	}
+	z, ok := s.getSortedSet(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	if z == nil {
+		w.int(0)
+		return
+	}
+
+	var removed int64
+	for name, score := range z.scores {
+		if minBound.below(score) && maxBound.above(score) {
+			delete(z.scores, name)
+			removed++
+		}
+	}
+	if removed > 0 {
+		s.touch(args[0])
+		s.removeIfEmpty(args[0], len(z.scores))
+	}
+	w.int(removed)
+}
+
+// cmdZRemRangeByRank removes a range of a sorted set by rank.
+func cmdZRemRangeByRank(s *Server, w *writer, args []string) {
+	start, err1 := strconv.Atoi(args[1])
+	stop, err2 := strconv.Atoi(args[2])
+	if err1 != nil || err2 != nil {
+		w.err(errNotInteger)
+		return
+	}
+	z, ok := s.getSortedSet(args[0], false)
+	if !ok {
+		w.wrongType()
+		return
+	}
+	if z == nil {
+		w.int(0)
+		return
+	}
+
+	members := z.sorted()
+	from, to, empty := normalizeRange(start, stop, len(members))
+	if empty {
+		w.int(0)
+		return
+	}
+	for _, m := range members[from:to] {
+		delete(z.scores, m.name)
+	}
+	s.touch(args[0])
+	s.removeIfEmpty(args[0], len(z.scores))
+	w.int(int64(to - from))
+}
+
+// writeMembers writes members of a sorted set, followed by their scores when asked to.
+func writeMembers(w *writer, members []member, withScores bool) {
+	if withScores {
+		w.array(2 * len(members))
+	} else {
+		w.array(len(members))
+	}
+	for _, m := range members {
+		w.bulk(m.name)
+		if withScores {
+			w.bulk(formatFloat(m.score))
+		}
+	}
+}
+
+// sortedKeys returns the keys of a map, sorted so replies are stable.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// boolInt converts a boolean to the 1 or 0 Redis replies.
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,677 @@
+// Package memory provides an in-process Redis server keeping its data in memory, so the application can
+// run without a Redis server for local development and tests. It speaks the Redis protocol to the
+// regular Redis client over in-memory connections and implements the commands the application uses.
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// expireInterval is how often keys that expired without being accessed again are dropped.
+const expireInterval = time.Second
+
+// errServerClosed is returned when dialing a closed server.
+var errServerClosed = errors.New("memory redis server closed")
+
+// Server is an in-process Redis server. All commands run one at a time against a single database.
+type Server struct {
+	// mu guards the data, commands and transactions run with it held
+	mu         sync.Mutex
+	keys       map[string]*entry
+	watchers   map[string]map[*client]bool
+	lastExpire time.Time
+
+	// subMu guards the subscriptions and the connections
+	subMu    sync.Mutex
+	channels map[string]map[*client]bool
+	patterns map[string]map[*client]bool
+	clients  map[*client]bool
+	closed   bool
+}
+
+// NewServer creates a new in-process Redis server with an empty database.
+func NewServer() *Server {
+	return &Server{
+		keys:     make(map[string]*entry),
+		watchers: make(map[string]map[*client]bool),
+		channels: make(map[string]map[*client]bool),
+		patterns: make(map[string]map[*client]bool),
+		clients:  make(map[*client]bool),
+	}
+}
+
+// Dial opens a connection to the server. Its signature matches the dialer of the Redis client options.
+func (s *Server) Dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	clientConn, serverConn := net.Pipe()
+	c := newClient(s, serverConn)
+
+	s.subMu.Lock()
+	if s.closed {
+		s.subMu.Unlock()
+		return nil, errServerClosed
+	}
+	s.clients[c] = true
+	s.subMu.Unlock()
+
+	go c.serve()
+	return clientConn, nil
+}
+
+// Close closes every connection to the server. The data is kept.
+func (s *Server) Close() error {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	s.closed = true
+	for c := range s.clients {
+		// The client's goroutine drops it once its connection fails
+		c.conn.Close()
+	}
+	return nil
+}
+
+// run runs a command against the data, writing its reply.
+func (s *Server) run(spec *command, args []string, w *writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireKeys()
+	spec.run(s, w, args)
+}
+
+// publish sends a message to the clients subscribed to its channel, and returns how many received it.
+func (s *Server) publish(channel, message string) int {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	receivers := 0
+	for c := range s.channels[channel] {
+		w := &writer{}
+		w.array(3)
+		w.bulk("message")
+		w.bulk(channel)
+		w.bulk(message)
+		c.out.push(w.Bytes())
+		receivers++
+	}
+	for pattern, clients := range s.patterns {
+		if !matchGlob(pattern, channel) {
+			continue
+		}
+		for c := range clients {
+			w := &writer{}
+			w.array(4)
+			w.bulk("pmessage")
+			w.bulk(pattern)
+			w.bulk(channel)
+			w.bulk(message)
+			c.out.push(w.Bytes())
+			receivers++
+		}
+	}
+	return receivers
+}
+
+// subscribe subscribes a client to a channel, or to a pattern of channels.
+func (s *Server) subscribe(c *client, name string, pattern bool) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	subscriptions := s.channels
+	if pattern {
+		subscriptions = s.patterns
+	}
+	if subscriptions[name] == nil {
+		subscriptions[name] = make(map[*client]bool)
+	}
+	subscriptions[name][c] = true
+}
+
+// unsubscribe unsubscribes a client from a channel, or from a pattern of channels.
+func (s *Server) unsubscribe(c *client, name string, pattern bool) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	subscriptions := s.channels
+	if pattern {
+		subscriptions = s.patterns
+	}
+	delete(subscriptions[name], c)
+	if len(subscriptions[name]) == 0 {
+		delete(subscriptions, name)
+	}
+}
+
+// disconnect drops a closed client and its subscriptions.
+func (s *Server) disconnect(c *client) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	delete(s.clients, c)
+	for name := range c.channels {
+		delete(s.channels[name], c)
+		if len(s.channels[name]) == 0 {
+			delete(s.channels, name)
+		}
+	}
+	for name := range c.patterns {
+		delete(s.patterns[name], c)
+		if len(s.patterns[name]) == 0 {
+			delete(s.patterns, name)
+		}
+	}
+}
+
+// client is a connection to the server.
+type client struct {
+	server *Server
+	conn   net.Conn
+	out    *outbox
+
+	// multi is set between MULTI and EXEC, with the commands queued and whether one was rejected
+	multi  bool
+	queued [][]string
+	dirty  bool
+
+	// watched are the keys watched for the next transaction, which fails once one of them changes
+	watched   []string
+	casFailed bool
+
+	// channels and patterns are the client's subscriptions, only touched by its own goroutine
+	channels map[string]bool
+	patterns map[string]bool
+}
+
+// newClient creates a client serving a connection.
+func newClient(server *Server, conn net.Conn) *client {
+	c := &client{
+		server:   server,
+		conn:     conn,
+		channels: make(map[string]bool),
+		patterns: make(map[string]bool),
+	}
+	c.out = newOutbox(conn)
+	return c
+}
+
+// serve reads and runs the client's commands until its connection closes.
+func (c *client) serve() {
+	defer c.close()
+	go c.out.run()
+
+	reader := bufio.NewReader(c.conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrClosedPipe) {
+				w := &writer{}
+				w.err("ERR Protocol error: " + err.Error())
+				c.out.push(w.Bytes())
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		w := &writer{}
+		quit := c.handle(args, w)
+		c.out.push(w.Bytes())
+		if quit {
+			c.out.drain()
+			return
+		}
+	}
+}
+
+// close closes the client's connection and drops its subscriptions and watched keys.
+func (c *client) close() {
+	c.out.close()
+	c.conn.Close()
+	c.unwatch()
+	c.server.disconnect(c)
+}
+
+// subscribed checks whether the client is in subscribed mode.
+func (c *client) subscribed() bool {
+	return len(c.channels) > 0 || len(c.patterns) > 0
+}
+
+// handle runs a command sent by the client and reports whether the client quit.
+func (c *client) handle(args []string, w *writer) bool {
+	name := strings.ToLower(args[0])
+
+	if c.subscribed() {
+		switch name {
+		case "subscribe", "unsubscribe", "psubscribe", "punsubscribe", "ping", "quit":
+		default:
+			w.err(fmt.Sprintf("ERR Can't execute '%s': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context", name))
+			return false
+		}
+	}
+
+	switch name {
+	case "quit":
+		w.ok()
+		return true
+	case "multi":
+		if c.multi {
+			w.err("ERR MULTI calls can not be nested")
+			return false
+		}
+		c.multi = true
+		w.ok()
+	case "exec":
+		c.exec(w)
+	case "discard":
+		if !c.multi {
+			w.err("ERR DISCARD without MULTI")
+			return false
+		}
+		c.reset()
+		w.ok()
+	case "watch":
+		c.watch(args[1:], w)
+	case "unwatch":
+		c.unwatch()
+		w.ok()
+	case "subscribe", "psubscribe":
+		c.subscribe(args[1:], name == "psubscribe", w)
+	case "unsubscribe", "punsubscribe":
+		c.unsubscribe(args[1:], name == "punsubscribe", w)
+	case "ping":
+		if c.subscribed() {
+			w.array(2)
+			w.bulk("pong")
+			if len(args) > 1 {
+				w.bulk(args[1])
+			} else {
+				w.bulk("")
+			}
+			return false
+		}
+		if len(args) > 1 {
+			w.bulk(args[1])
+		} else {
+			w.simple("PONG")
+		}
+	case "publish":
+		if len(args) != 3 {
+			w.arityErr(name)
+			return false
+		}
+		if c.multi {
+			c.queued = append(c.queued, args)
+			w.simple("QUEUED")
+			return false
+		}
+		w.int(int64(c.server.publish(args[1], args[2])))
+	default:
+		spec, ok := commands[name]
+		if !ok {
+			c.dirty = c.multi
+			w.err(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+			return false
+		}
+		if !spec.accepts(len(args)) {
+			c.dirty = c.multi
+			w.arityErr(name)
+			return false
+		}
+		if c.multi {
+			c.queued = append(c.queued, args)
+			w.simple("QUEUED")
+			return false
+		}
+		c.server.run(spec, args[1:], w)
+	}
+	return false
+}
+
+// exec runs the queued commands of a transaction, unless it was rejected or a watched key changed.
+func (c *client) exec(w *writer) {
+	if !c.multi {
+		w.err("ERR EXEC without MULTI")
+		return
+	}
+	defer c.reset()
+	if c.dirty {
+		w.err("EXECABORT Transaction discarded because of previous errors.")
+		return
+	}
+
+	// Messages published in the transaction are sent once the data is unlocked
+	type message struct{ channel, payload string }
+	var messages []message
+	var published []*writer
+
+	s := c.server
+	s.mu.Lock()
+	s.expireKeys()
+	for _, key := range c.watched {
+		s.lookup(key)
+	}
+	if c.casFailed {
+		s.mu.Unlock()
+		w.nullArray()
+		return
+	}
+
+	replies := make([]*writer, len(c.queued))
+	for i, args := range c.queued {
+		replies[i] = &writer{}
+		if strings.ToLower(args[0]) == "publish" {
+			messages = append(messages, message{args[1], args[2]})
+			published = append(published, replies[i])
+			continue
+		}
+		commands[strings.ToLower(args[0])].run(s, replies[i], args[1:])
+	}
+	s.mu.Unlock()
+
+	for i, m := range messages {
+		published[i].int(int64(s.publish(m.channel, m.payload)))
+	}
+
+	w.array(len(replies))
+	for _, reply := range replies {
+		w.Write(reply.Bytes())
+	}
+}
+
+// reset ends a transaction and forgets the watched keys.
+func (c *client) reset() {
+	c.multi = false
+	c.queued = nil
+	c.dirty = false
+	c.unwatch()
+}
+
+// watch watches keys so the next transaction fails if any of them change.
+func (c *client) watch(keys []string, w *writer) {
+	if len(keys) == 0 {
+		w.arityErr("watch")
+		return
+	}
+	if c.multi {
+		w.err("ERR WATCH inside MULTI is not allowed")
+		return
+	}
+
+	s := c.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		if s.watchers[key] == nil {
+			s.watchers[key] = make(map[*client]bool)
+		}
+		if !s.watchers[key][c] {
+			s.watchers[key][c] = true
+			c.watched = append(c.watched, key)
+		}
+	}
+	w.ok()
+}
+
+// unwatch forgets the watched keys.
+func (c *client) unwatch() {
+	s := c.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range c.watched {
+		delete(s.watchers[key], c)
+		if len(s.watchers[key]) == 0 {
+			delete(s.watchers, key)
+		}
+	}
+	c.watched = nil
+	c.casFailed = false
+}
+
+// subscribe subscribes the client to channels or patterns, replying once for each.
+func (c *client) subscribe(names []string, pattern bool, w *writer) {
+	kind, subscriptions := "subscribe", c.channels
+	if pattern {
+		kind, subscriptions = "psubscribe", c.patterns
+	}
+	if len(names) == 0 {
+		w.arityErr(kind)
+		return
+	}
+
+	for _, name := range names {
+		if !subscriptions[name] {
+			subscriptions[name] = true
+			c.server.subscribe(c, name, pattern)
+		}
+		w.array(3)
+		w.bulk(kind)
+		w.bulk(name)
+		w.int(int64(len(c.channels) + len(c.patterns)))
+	}
+}
+
+// unsubscribe unsubscribes the client from channels or patterns, or from all of them when none are given.
+func (c *client) unsubscribe(names []string, pattern bool, w *writer) {
+	kind, subscriptions := "unsubscribe", c.channels
+	if pattern {
+		kind, subscriptions = "punsubscribe", c.patterns
+	}
+	if len(names) == 0 {
+		for name := range subscriptions {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		w.array(3)
+		w.bulk(kind)
+		w.null()
+		w.int(int64(len(c.channels) + len(c.patterns)))
+		return
+	}
+
+	for _, name := range names {
+		if subscriptions[name] {
+			delete(subscriptions, name)
+			c.server.unsubscribe(c, name, pattern)
+		}
+		w.array(3)
+		w.bulk(kind)
+		w.bulk(name)
+		w.int(int64(len(c.channels) + len(c.patterns)))
+	}
+}
+
+// outbox queues the replies and messages sent to a client. Writing them from their own goroutine keeps
+// the client from blocking the server while it is still sending a pipeline over the synchronous pipe.
+type outbox struct {
+	conn net.Conn
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending [][]byte
+	closed  bool
+	idle    bool
+}
+
+// newOutbox creates an outbox writing to a connection.
+func newOutbox(conn net.Conn) *outbox {
+	o := &outbox{conn: conn, idle: true}
+	o.cond = sync.NewCond(&o.mu)
+	return o
+}
+
+// push queues data to send.
+func (o *outbox) push(data []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return
+	}
+	o.pending = append(o.pending, data)
+	o.cond.Broadcast()
+}
+
+// run writes the queued data until the outbox is closed.
+func (o *outbox) run() {
+	for {
+		o.mu.Lock()
+		for len(o.pending) == 0 && !o.closed {
+			o.idle = true
+			o.cond.Broadcast()
+			o.cond.Wait()
+		}
+		if o.closed {
+			o.mu.Unlock()
+			return
+		}
+		pending := o.pending
+		o.pending = nil
+		o.idle = false
+		o.mu.Unlock()
+
+		if _, err := o.conn.Write(bytes.Join(pending, nil)); err != nil {
+			// The client's goroutine closes the client once its connection fails
+			o.conn.Close()
+			o.close()
+			return
+		}
+	}
+}
+
+// drain waits for the queued data to be written.
+func (o *outbox) drain() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for (len(o.pending) > 0 || !o.idle) && !o.closed {
+		o.cond.Wait()
+	}
+}
+
+// close stops the outbox, dropping the data not written yet.
+func (o *outbox) close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closed = true
+	o.cond.Broadcast()
+}
+
+// readCommand reads a command sent as an array of bulk strings, or as an inline command.
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+
+	count, err := strconv.Atoi(line[1:])
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("invalid multibulk length")
+	}
+
+	args := make([]string, count)
+	for i := range args {
+		line, err := readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, fmt.Errorf("expected '$', got '%.1s'", line)
+		}
+		length, err := strconv.Atoi(line[1:])
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("invalid bulk length")
+		}
+
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:length])
+	}
+	return args, nil
+}
+
+// readLine reads a line ended by CRLF, without it.
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// writer builds replies in the Redis protocol.
+type writer struct {
+	bytes.Buffer
+}
+
+// ok writes the OK status.
+func (w *writer) ok() {
+	w.simple("OK")
+}
+
+// simple writes a status reply.
+func (w *writer) simple(s string) {
+	w.WriteString("+" + s + "\r\n")
+}
+
+// err writes an error reply.
+func (w *writer) err(s string) {
+	w.WriteString("-" + s + "\r\n")
+}
+
+// arityErr writes the error of a command called with the wrong number of arguments.
+func (w *writer) arityErr(name string) {
+	w.err(fmt.Sprintf("ERR wrong number of arguments for '%s' command", name))
+}
+
+// wrongType writes the error of a command run against a key holding another type of value.
+func (w *writer) wrongType() {
+	w.err("WRONGTYPE Operation against a key holding the wrong kind of value")
+}
+
+// int writes an integer reply.
+func (w *writer) int(n int64) {
+	w.WriteString(":" + strconv.FormatInt(n, 10) + "\r\n")
+}
+
+// bulk writes a bulk string reply.
+func (w *writer) bulk(s string) {
+	w.WriteString("$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n")
+}
+
+// null writes a nil bulk string reply.
+func (w *writer) null() {
+	w.WriteString("$-1\r\n")
+}
+
+// array writes the header of an array reply of n elements.
+func (w *writer) array(n int) {
+	w.WriteString("*" + strconv.Itoa(n) + "\r\n")
+}
+
+// nullArray writes a nil array reply.
+func (w *writer) nullArray() {
+	w.WriteString("*-1\r\n")
+}
+
+// bulks writes an array reply of bulk strings.
+func (w *writer) bulks(values []string) {
+	w.array(len(values))
+	for _, value := range values {
+		w.bulk(value)
+	}
+}
@@ -0,0 +1,402 @@
+package memory
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// entry is a key's value along with when it expires, the zero time for keys that don't.
+type entry struct {
+	value     any
+	expiresAt time.Time
+}
+
+// expired checks whether the entry expired at a time.
+func (e *entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// list is the value of a list key.
+type list struct {
+	items []string
+}
+
+// sortedSet is the value of a sorted set key.
+type sortedSet struct {
+	scores map[string]float64
+}
+
+// member is a member of a sorted set with its score.
+type member struct {
+	name  string
+	score float64
+}
+
+// sorted returns the members of the sorted set by score, then by name.
+func (z *sortedSet) sorted() []member {
+	members := make([]member, 0, len(z.scores))
+	for name, score := range z.scores {
+		members = append(members, member{name, score})
+	}
+	slices.SortFunc(members, func(a, b member) int {
+		if c := cmp.Compare(a.score, b.score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.name, b.name)
+	})
+	return members
+}
+
+// lookup gets a key's entry, dropping it if it expired.
+func (s *Server) lookup(key string) *entry {
+	e, ok := s.keys[key]
+	if !ok {
+		return nil
+	}
+	if e.expired(time.Now()) {
+		s.remove(key)
+		return nil
+	}
+	return e
+}
+
+// touch records a change to a key, failing the transactions watching it.
+func (s *Server) touch(key string) {
+	for c := range s.watchers[key] {
+		c.casFailed = true
+	}
+}
+
+// remove deletes a key, reporting whether it existed.
+func (s *Server) remove(key string) bool {
+	if _, ok := s.keys[key]; !ok {
+		return false
+	}
+	delete(s.keys, key)
+	s.touch(key)
+	return true
+}
+
+// store sets a key's value, dropping its expiry.
+func (s *Server) store(key string, value any) {
+	s.keys[key] = &entry{value: value}
+	s.touch(key)
+}
+
+// removeIfEmpty deletes a collection key left without elements, as Redis does.
+func (s *Server) removeIfEmpty(key string, size int) {
+	if size == 0 {
+		s.remove(key)
+	}
+}
+
+// expireKeys drops the keys that expired, at most once per interval.
+func (s *Server) expireKeys() {
+	now := time.Now()
+	if now.Sub(s.lastExpire) < expireInterval {
+		return
+	}
+	s.lastExpire = now
+
+	for key, e := range s.keys {
+		if e.expired(now) {
+			s.remove(key)
+		}
+	}
+}
+
+// liveKeys returns the keys that haven't expired, sorted.
+func (s *Server) liveKeys() []string {
+	now := time.Now()
+	keys := make([]string, 0, len(s.keys))
+	for key, e := range s.keys {
+		if !e.expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// getString gets the value of a string key. Missing keys are not found, keys of another type fail.
+func (s *Server) getString(key string) (value string, found, ok bool) {
+	e := s.lookup(key)
+	if e == nil {
+		return "", false, true
+	}
+	value, ok = e.value.(string)
+	return value, ok, ok
+}
+
+// getHash gets the value of a hash key, creating it if asked to. Keys of another type fail.
+func (s *Server) getHash(key string, create bool) (map[string]string, bool) {
+	e := s.lookup(key)
+	if e == nil {
+		if !create {
+			return nil, true
+		}
+		hash := make(map[string]string)
+		s.keys[key] = &entry{value: hash}
+		return hash, true
+	}
+	hash, ok := e.value.(map[string]string)
+	return hash, ok
+}
+
+// getList gets the value of a list key, creating it if asked to. Keys of another type fail.
+func (s *Server) getList(key string, create bool) (*list, bool) {
+	e := s.lookup(key)
+	if e == nil {
+		if !create {
+			return nil, true
+		}
+		l := &list{}
+		s.keys[key] = &entry{value: l}
+		return l, true
+	}
+	l, ok := e.value.(*list)
+	return l, ok
+}
+
+// getSet gets the value of a set key, creating it if asked to. Keys of another type fail.
+func (s *Server) getSet(key string, create bool) (map[string]struct{}, bool) {
+	e := s.lookup(key)
+	if e == nil {
+		if !create {
+			return nil, true
+		}
+		set := make(map[string]struct{})
+		s.keys[key] = &entry{value: set}
+		return set, true
+	}
+	set, ok := e.value.(map[string]struct{})
+	return set, ok
+}
+
+// getSortedSet gets the value of a sorted set key, creating it if asked to. Keys of another type fail.
+func (s *Server) getSortedSet(key string, create bool) (*sortedSet, bool) {
+	e := s.lookup(key)
+	if e == nil {
+		if !create {
+			return nil, true
+		}
+		z := &sortedSet{scores: make(map[string]float64)}
+		s.keys[key] = &entry{value: z}
+		return z, true
+	}
+	z, ok := e.value.(*sortedSet)
+	return z, ok
+}
+
+// typeName returns the Redis type name of a value.
+func typeName(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case map[string]string:
+		return "hash"
+	case *list:
+		return "list"
+	case map[string]struct{}:
+		return "set"
+	case *sortedSet:
+		return "zset"
+	}
+	return "none"
+}
+
+// sizeOf estimates the memory used by a key and its value, in bytes.
+func sizeOf(key string, value any) int64 {
+	size := int64(len(key)) + 48
+	switch v := value.(type) {
+	case string:
+		size += int64(len(v))
+	case map[string]string:
+		for field, value := range v {
+			size += int64(len(field)+len(value)) + 16
+		}
+	case *list:
+		for _, item := range v.items {
+			size += int64(len(item)) + 16
+		}
+	case map[string]struct{}:
+		for item := range v {
+			size += int64(len(item)) + 16
+		}
+	case *sortedSet:
+		for name := range v.scores {
+			size += int64(len(name)) + 24
+		}
+	}
+	return size
+}
+
+// normalizeRange turns a start and stop index, negative ones counting from the end, into bounds of a
+// slice of n elements. It reports whether the range is empty.
+func normalizeRange(start, stop, n int) (int, int, bool) {
+	if start < 0 {
+		start = max(n+start, 0)
+	}
+	if stop < 0 {
+		stop = n + stop
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	if start > stop || start >= n {
+		return 0, 0, true
+	}
+	return start, stop + 1, false
+}
+
+// scoreBound is a bound of a score range, such as "(1.5" or "-inf".
+type scoreBound struct {
+	value     float64
+	exclusive bool
+}
+
+// parseScoreBound parses a bound of a score range.
+func parseScoreBound(s string) (scoreBound, bool) {
+	var bound scoreBound
+	if len(s) > 0 && s[0] == '(' {
+		bound.exclusive = true
+		s = s[1:]
+	}
+	value, ok := parseFloat(s)
+	bound.value = value
+	return bound, ok
+}
+
+// below checks whether a score is within the bound as a minimum.
+func (b scoreBound) below(score float64) bool {
+	if b.exclusive {
+		return b.value < score
+	}
+	return b.value <= score
+}
+
+// above checks whether a score is within the bound as a maximum.
+func (b scoreBound) above(score float64) bool {
+	if b.exclusive {
+		return score < b.value
+	}
+	return score <= b.value
+}
+
+// parseFloat parses a score, accepting infinities as Redis writes them.
+func parseFloat(s string) (float64, bool) {
+	switch s {
+	case "+inf", "inf":
+		return math.Inf(1), true
+	case "-inf":
+		return math.Inf(-1), true
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	return value, err == nil && !math.IsNaN(value)
+}
+
+// formatFloat formats a score as Redis replies it.
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// matchGlob checks whether a string matches a Redis glob pattern, with *, ?, [...] and \ escapes.
+func matchGlob(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchGlob(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			end := 1
+			for end < len(pattern) && pattern[end] != ']' {
+				if pattern[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(pattern) {
+				// An unterminated class matches itself literally
+				if s[0] != '[' {
+					return false
+				}
+				s = s[1:]
+				pattern = pattern[1:]
+				continue
+			}
+			if !matchClass(pattern[1:end], s[0]) {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[end+1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+// matchClass checks whether a byte is in a glob character class, such as "a-z" or "^0-9".
+func matchClass(class string, c byte) bool {
+	negate := len(class) > 0 && class[0] == '^'
+	if negate {
+		class = class[1:]
+	}
+
+	matched := false
+	for i := 0; i < len(class); i++ {
+		if class[i] == '\\' && i+1 < len(class) {
+			i++
+			matched = matched || class[i] == c
+			continue
+		}
+		if i+2 < len(class) && class[i+1] == '-' {
+			lo, hi := class[i], class[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			matched = matched || (lo <= c && c <= hi)
+			i += 2
+			continue
+		}
+		matched = matched || class[i] == c
+	}
+	return matched != negate
+}
@@ -0,0 +1,95 @@
+// Package models contains the data structures used throughout the application.
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// AchievementMetric is the user statistic an achievement is unlocked by.
+type AchievementMetric string
+
+const (
+	// AchievementMetricPlays counts the tracks a user played as DJ.
+	AchievementMetricPlays AchievementMetric = "plays"
+	// AchievementMetricWoots counts the woots a user's plays received.
+	AchievementMetricWoots AchievementMetric = "woots"
+	// AchievementMetricDJMinutes counts the minutes a user spent DJing.
+	AchievementMetricDJMinutes AchievementMetric = "djMinutes"
+	// AchievementMetricChatMessages counts the chat messages a user sent.
+	AchievementMetricChatMessages AchievementMetric = "chatMessages"
+	// AchievementMetricLevel is a user's level.
+	AchievementMetricLevel AchievementMetric = "level"
+)
+
+// Achievement is the definition of an achievement, unlocked once a user's statistic reaches its threshold.
+type Achievement struct {
+	// ID is the unique identifier for the achievement.
+	ID bson.ObjectID `json:"id" bson:"_id"`
+
+	// Key is the stable name of the achievement, unique among achievements.
+	Key string `json:"key" bson:"key"`
+
+	// Name is the achievement's display name.
+	Name string `json:"name" bson:"name"`
+
+	// Description explains how the achievement is unlocked.
+	Description string `json:"description" bson:"description"`
+
+	// Icon is the name of the achievement's icon in the client.
+	Icon string `json:"icon,omitempty" bson:"icon,omitempty"`
+
+	// Metric is the statistic the achievement is unlocked by.
+	Metric AchievementMetric `json:"metric" bson:"metric"`
+
+	// Threshold is the value of the statistic that unlocks the achievement.
+	Threshold int64 `json:"threshold" bson:"threshold"`
+
+	// ObjectTimes contains timestamps for this achievement.
+	ObjectTimes
+}
+
+// UserAchievement is an achievement a user unlocked.
+type UserAchievement struct {
+	// ID is the unique identifier for the unlocked achievement.
+	ID bson.ObjectID `json:"id" bson:"_id"`
+
+	// UserID is the user who unlocked the achievement.
+	UserID bson.ObjectID `json:"userId" bson:"userId"`
+
+	// Key is the key of the achievement unlocked.
+	Key string `json:"key" bson:"key"`
+
+	// UnlockedAt is when the achievement was unlocked.
+	UnlockedAt time.Time `json:"unlockedAt" bson:"unlockedAt"`
+}
+
+// AchievementProgress is a user's progress towards an achievement.
+type AchievementProgress struct {
+	*Achievement
+
+	// Progress is the user's current value of the achievement's statistic, capped at its threshold.
+	Progress int64 `json:"progress"`
+
+	// Unlocked is whether the user unlocked the achievement.
+	Unlocked bool `json:"unlocked"`
+
+	// UnlockedAt is when the user unlocked the achievement.
+	UnlockedAt time.Time `json:"unlockedAt,omitzero"`
+}
+
+// LevelUp is a user reaching a higher level.
+type LevelUp struct {
+	// UserID is the user who leveled up.
+	UserID bson.ObjectID `json:"userId"`
+
+	// RoomID is the room the user earned the experience in, zero if it wasn't earned in a room.
+	RoomID bson.ObjectID `json:"roomId,omitzero"`
+
+	// OldLevel is the user's level before.
+	OldLevel int `json:"oldLevel"`
+
+	// NewLevel is the user's level now.
+	NewLevel int `json:"newLevel"`
+}
@@ -0,0 +1,55 @@
+// Package models contains the data structures used throughout the application.
+package models
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// APIKeyScope is a permission granted to a personal API key.
+type APIKeyScope string
+
+const (
+	// APIKeyScopeReadPlaylists allows reading the owner's playlists.
+	APIKeyScopeReadPlaylists APIKeyScope = "playlists:read"
+	// APIKeyScopeReadHistory allows reading the owner's activity history.
+	APIKeyScopeReadHistory APIKeyScope = "history:read"
+)
+
+// APIKeyScopes lists every scope a personal API key can be granted.
+var APIKeyScopes = []APIKeyScope{APIKeyScopeReadPlaylists, APIKeyScopeReadHistory}
+
+// APIKey is a personal API key a user created for their own integrations.
+// Only a hash of the secret is stored; the key itself is shown once on creation.
+type APIKey struct {
+	// ID is the unique identifier for the key.
+	ID bson.ObjectID `json:"id" bson:"_id"`
+
+	// Name is the label the user gave the key.
+	Name string `json:"name" bson:"name"`
+
+	// Hint is the last characters of the key, to help users recognize it.
+	Hint string `json:"hint" bson:"hint"`
+
+	// Hash is the SHA-256 hash of the key's secret.
+	Hash string `json:"-" bson:"hash"`
+
+	// Scopes are the permissions granted to the key.
+	Scopes []APIKeyScope `json:"scopes" bson:"scopes"`
+
+	// RateLimit is the maximum number of requests per minute made with the key.
+	RateLimit int `json:"rateLimit" bson:"rateLimit"`
+
+	// CreatedAt is when the key was created.
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+
+	// LastUsedAt is when the key was last used, tracked at minute granularity.
+	LastUsedAt time.Time `json:"lastUsedAt,omitzero" bson:"lastUsedAt,omitempty"`
+}
+
+// HasScope checks whether the key has been granted a scope.
+func (k *APIKey) HasScope(scope APIKeyScope) bool {
+	return slices.Contains(k.Scopes, scope)
+}
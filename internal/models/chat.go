@@ -7,6 +7,19 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+// ChatModes restrict what can be posted in a room's chat, for crowd control during big events.
+// The room's owner and moderators aren't held to them.
+type ChatModes struct {
+	// LinksDisabled rejects messages containing links.
+	LinksDisabled bool `json:"linksDisabled" bson:"linksDisabled"`
+
+	// ImagesDisabled rejects messages linking to images, so they aren't embedded in the chat.
+	ImagesDisabled bool `json:"imagesDisabled" bson:"imagesDisabled"`
+
+	// EmojiOnly rejects messages that contain anything but emoji.
+	EmojiOnly bool `json:"emojiOnly" bson:"emojiOnly"`
+}
+
 // ChatMessage represents a chat message sent in a room.
 type ChatMessage struct {
 	// ID is the unique identifier for the message.
@@ -39,6 +52,9 @@ type ChatMessage struct {
 	// DeletedAt is the time the message was deleted.
 	DeletedAt time.Time `json:"deletedAt,omitzero" bson:"deletedAt,omitempty"`
 
+	// IsMasked indicates whether the message was hidden by moderation. Masked messages are sent without their content.
+	IsMasked bool `json:"isMasked,omitempty" bson:"isMasked,omitempty"`
+
 	// IsEdited indicates whether the message has been edited.
 	IsEdited bool `json:"isEdited" bson:"isEdited"`
 
@@ -51,10 +67,112 @@ type ChatMessage struct {
 	// UserRole is the role of the user at the time of sending.
 	UserRole string `json:"userRole" bson:"userRole"`
 
+	// ChatShard is the overflow chat shard of the room the message was posted in, 0 for the main chat.
+	ChatShard int `json:"chatShard,omitempty" bson:"chatShard,omitempty"`
+
+	// Broadcast indicates whether room staff posted the message to every chat shard of the room.
+	Broadcast bool `json:"broadcast,omitempty" bson:"broadcast,omitempty"`
+
+	// Channel is the named chat channel of the room the message was posted in, empty for the general channel.
+	Channel string `json:"channel,omitempty" bson:"channel,omitempty"`
+
+	// Appearance is how the user's name showed in the room's chat at the time of sending.
+	Appearance *ChatAppearance `json:"appearance,omitempty" bson:"appearance,omitempty"`
+
 	// Metadata contains additional information about the message.
 	Metadata map[string]any `json:"metadata,omitempty" bson:"metadata,omitempty"`
 }
 
+// DefaultChatChannel is the chat channel every room has, which messages are posted in unless they name another.
+const DefaultChatChannel = "general"
+
+// ChatChannel is a named channel of a room's chat, such as one only DJs or moderators post in.
+// Everyone in the room reads every channel.
+type ChatChannel struct {
+	// Name is the name of the channel, unique in the room.
+	Name string `json:"name" bson:"name"`
+
+	// Description is what the channel is for.
+	Description string `json:"description,omitempty" bson:"description,omitempty"`
+
+	// PostRole is the minimum room role required to post in the channel.
+	PostRole string `json:"postRole" bson:"postRole"`
+
+	// CreatedBy is the user who created the channel, zero for the general channel.
+	CreatedBy bson.ObjectID `json:"createdBy,omitzero" bson:"createdBy,omitempty"`
+
+	// CreatedAt is when the channel was created.
+	CreatedAt time.Time `json:"createdAt,omitzero" bson:"createdAt,omitempty"`
+}
+
+// ChatChannelRequest represents a chat channel created or changed by room staff.
+type ChatChannelRequest struct {
+	// Name is the name of the channel.
+	Name string `json:"name" validate:"required,min=2,max=20,alphanum,lowercase"`
+
+	// Description is what the channel is for.
+	Description string `json:"description" validate:"max=100"`
+
+	// PostRole is the minimum room role required to post in the channel, everyone when empty.
+	PostRole string `json:"postRole" validate:"omitempty,oneof=user vip resident_dj cohost moderator owner"`
+}
+
+// ChatFilter is a term or pattern moderators blocked in a room's chat. Messages matching it are refused,
+// except from room staff.
+type ChatFilter struct {
+	// ID is the unique identifier for the filter.
+	ID bson.ObjectID `json:"id" bson:"id"`
+
+	// Pattern is the blocked term, or the regular expression when Regex is set.
+	Pattern string `json:"pattern" bson:"pattern"`
+
+	// Regex tells whether the pattern is a regular expression. Terms match whole words and regular
+	// expressions match anywhere in the message, both ignoring case.
+	Regex bool `json:"regex" bson:"regex"`
+
+	// CreatedBy is the ID of the moderator who added the filter.
+	CreatedBy bson.ObjectID `json:"createdBy" bson:"createdBy"`
+
+	// CreatedAt is when the filter was added.
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// ChatFilterRequest represents the data needed to add a chat filter or test one.
+type ChatFilterRequest struct {
+	// Pattern is the blocked term, or the regular expression when Regex is set.
+	Pattern string `json:"pattern" validate:"required,max=200"`
+
+	// Regex tells whether the pattern is a regular expression.
+	Regex bool `json:"regex"`
+}
+
+// ChatFilterStats is a room's chat filter along with how often it matched, so moderators can tune noisy filters.
+type ChatFilterStats struct {
+	ChatFilter
+
+	// Matches is the number of messages the filter refused.
+	Matches int64 `json:"matches"`
+
+	// LastMatchAt is when the filter last refused a message.
+	LastMatchAt time.Time `json:"lastMatchAt,omitzero"`
+}
+
+// ChatFilterTest is the outcome of checking a sample message against a room's chat filters.
+type ChatFilterTest struct {
+	// Message is the sample message.
+	Message string `json:"message"`
+
+	// Blocked tells whether the message would be refused.
+	Blocked bool `json:"blocked"`
+
+	// Matches are the IDs of the filters matching the message. A filter tested before being added has no ID
+	// and matches as "candidate".
+	Matches []string `json:"matches"`
+
+	// TimedOut tells that checking the message took too long, in which case it would be let through.
+	TimedOut bool `json:"timedOut,omitempty"`
+}
+
 // ChatMessageRequest represents the data needed to send a chat message.
 type ChatMessageRequest struct {
 	// Type is the type of message.
@@ -120,11 +238,62 @@ type ChatCommand struct {
 	// MinimumRole is the minimum role required to use the command.
 	MinimumRole string `json:"minimumRole" bson:"minimumRole"`
 
+	// Permission is the room permission required to use a built-in command. When set, it is checked instead of the minimum role.
+	Permission string `json:"permission,omitempty" bson:"permission,omitempty"`
+
 	// Enabled indicates whether the command is enabled.
 	Enabled bool `json:"enabled" bson:"enabled"`
 
 	// CooldownSeconds is the cooldown between uses of the command.
 	CooldownSeconds int `json:"cooldownSeconds" bson:"cooldownSeconds"`
+
+	// Response is the text a room's custom command replies with. Built-in commands don't have one.
+	Response string `json:"response,omitempty" bson:"response,omitempty"`
+}
+
+// ChatCommandRequest represents a custom command defined by a room's owner.
+type ChatCommandRequest struct {
+	// Name is the name of the command, used as /name.
+	Name string `json:"name" validate:"required,min=2,max=20,alphanum,lowercase"`
+
+	// Description is a description of what the command does.
+	Description string `json:"description" validate:"max=100"`
+
+	// MinimumRole is the minimum role required to use the command.
+	MinimumRole string `json:"minimumRole" validate:"omitempty,oneof=user vip resident_dj cohost moderator owner"`
+
+	// CooldownSeconds is the cooldown between uses of the command.
+	CooldownSeconds int `json:"cooldownSeconds" validate:"min=0,max=3600"`
+
+	// Response is the text the command replies with.
+	Response string `json:"response" validate:"required,max=500"`
+}
+
+// Chat command response visibilities.
+const (
+	// CommandVisibilityPrivate responses are only returned to the user who ran the command.
+	CommandVisibilityPrivate = "private"
+
+	// CommandVisibilityRoom responses are sent to everyone in the room.
+	CommandVisibilityRoom = "room"
+)
+
+// ChatCommandResult is the structured response of a chat command.
+type ChatCommandResult struct {
+	// Command is the name of the command that ran.
+	Command string `json:"command" bson:"command"`
+
+	// Visibility is who sees the response, one of the CommandVisibility constants.
+	Visibility string `json:"visibility" bson:"visibility"`
+
+	// Content is the text of the response.
+	Content string `json:"content" bson:"content"`
+
+	// Emote marks a response that describes the user's own action, shown as an emote message.
+	Emote bool `json:"emote,omitempty" bson:"emote,omitempty"`
+
+	// Data holds command-specific details of the response.
+	Data map[string]any `json:"data,omitempty" bson:"data,omitempty"`
 }
 
 // ChatEmote represents an emote that can be used in chat.
@@ -228,3 +397,63 @@ type ChatModerationResponse struct {
 	// Message is a message about the moderation action.
 	Message string `json:"message,omitempty"`
 }
+
+// ChatFlagStatus is where a flagged chat message is in its room's moderation queue.
+type ChatFlagStatus string
+
+const (
+	// ChatFlagPending flags wait for a room moderator.
+	ChatFlagPending ChatFlagStatus = "pending"
+	// ChatFlagDismissed flags were reviewed and found to be harmless. A masked message is shown again.
+	ChatFlagDismissed ChatFlagStatus = "dismissed"
+	// ChatFlagRemoved flags were reviewed and their message deleted.
+	ChatFlagRemoved ChatFlagStatus = "removed"
+)
+
+// ChatFlag is a chat message flagged by automated moderation, queued for the room's moderators to review.
+type ChatFlag struct {
+	// ID is the unique identifier for the flag.
+	ID bson.ObjectID `json:"id" bson:"_id"`
+
+	// RoomID is the room the message was sent in.
+	RoomID bson.ObjectID `json:"roomId" bson:"roomId"`
+
+	// MessageID is the flagged message.
+	MessageID bson.ObjectID `json:"messageId" bson:"messageId"`
+
+	// UserID is the user who sent the message.
+	UserID bson.ObjectID `json:"userId" bson:"userId"`
+
+	// Content is the content of the message when it was flagged, kept for review once it is masked.
+	Content string `json:"content" bson:"content"`
+
+	// Score is the toxicity score of the message, from 0 to 1.
+	Score float64 `json:"score" bson:"score"`
+
+	// Categories are the scores of the kinds of toxicity the classifier detected, if it reports them.
+	Categories map[string]float64 `json:"categories,omitempty" bson:"categories,omitempty"`
+
+	// Classifier is the name of the classifier that scored the message.
+	Classifier string `json:"classifier" bson:"classifier"`
+
+	// Masked indicates whether the message was masked automatically.
+	Masked bool `json:"masked" bson:"masked"`
+
+	// Status is where the flag is in review.
+	Status ChatFlagStatus `json:"status" bson:"status"`
+
+	// ReviewedBy is the moderator who reviewed the flag.
+	ReviewedBy bson.ObjectID `json:"reviewedBy,omitzero" bson:"reviewedBy,omitempty"`
+
+	// ReviewedAt is when the flag was reviewed.
+	ReviewedAt time.Time `json:"reviewedAt,omitzero" bson:"reviewedAt,omitempty"`
+
+	// ObjectTimes contains timestamps for this flag.
+	ObjectTimes
+}
+
+// ChatFlagReview is a moderator's decision on a flagged chat message.
+type ChatFlagReview struct {
+	// Action is what to do with the message: "dismiss" keeps it, showing it again if it was masked, "remove" deletes it.
+	Action string `json:"action" validate:"required,oneof=dismiss remove"`
+}
@@ -0,0 +1,108 @@
+// Package models contains the data structures used throughout the application.
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// WebhookEvent is a platform event developer applications can subscribe to.
+type WebhookEvent string
+
+const (
+	// WebhookEventUserCreated is sent when a user registers.
+	WebhookEventUserCreated WebhookEvent = "user.created"
+	// WebhookEventRoomCreated is sent when a public room is created.
+	WebhookEventRoomCreated WebhookEvent = "room.created"
+	// WebhookEventPlayRecorded is sent when media starts playing in a public room.
+	WebhookEventPlayRecorded WebhookEvent = "play.recorded"
+)
+
+// WebhookEvents lists every event developer applications can subscribe to.
+var WebhookEvents = []WebhookEvent{WebhookEventUserCreated, WebhookEventRoomCreated, WebhookEventPlayRecorded}
+
+// DeveloperApp is a third-party application a user registered to receive platform events.
+// Its secret signs the deliveries and is only shown when it is generated.
+type DeveloperApp struct {
+	// ID is the unique identifier for the application.
+	ID bson.ObjectID `json:"id" bson:"_id"`
+
+	// OwnerID is the user who registered the application.
+	OwnerID bson.ObjectID `json:"ownerId" bson:"ownerId"`
+
+	// Name is the name of the application.
+	Name string `json:"name" bson:"name"`
+
+	// Description tells what the application does.
+	Description string `json:"description,omitempty" bson:"description,omitempty"`
+
+	// WebhookURL receives the events the application subscribed to.
+	WebhookURL string `json:"webhookUrl" bson:"webhookUrl"`
+
+	// Events are the events the application subscribed to.
+	Events []WebhookEvent `json:"events" bson:"events"`
+
+	// Active turns deliveries on. Events of inactive applications are dropped, not queued.
+	Active bool `json:"active" bson:"active"`
+
+	// Secret signs the deliveries.
+	Secret string `json:"-" bson:"secret"`
+
+	// ObjectTimes contains timestamps for this application.
+	ObjectTimes
+}
+
+// DeveloperAppRequest registers a developer application or changes it.
+type DeveloperAppRequest struct {
+	// Name is the name of the application.
+	Name string `json:"name" validate:"required,max=50"`
+
+	// Description tells what the application does.
+	Description string `json:"description" validate:"max=500"`
+
+	// WebhookURL receives the events, it must be an https URL.
+	WebhookURL string `json:"webhookUrl" validate:"required,url,max=2048"`
+
+	// Events are the events to subscribe to.
+	Events []WebhookEvent `json:"events" validate:"required,min=1,dive,oneof=user.created room.created play.recorded"`
+
+	// Active turns deliveries on or off. Applications are active when it is left out.
+	Active *bool `json:"active"`
+}
+
+// WebhookDelivery is one attempt to deliver an event to a developer application, kept in its delivery log.
+type WebhookDelivery struct {
+	// ID is the unique identifier for the attempt.
+	ID bson.ObjectID `json:"id" bson:"_id"`
+
+	// AppID is the application the event was delivered to.
+	AppID bson.ObjectID `json:"appId" bson:"appId"`
+
+	// EventID identifies the event, it is the same for every attempt to deliver it.
+	EventID string `json:"eventId" bson:"eventId"`
+
+	// Event is the event delivered.
+	Event WebhookEvent `json:"event" bson:"event"`
+
+	// Attempt counts the attempts to deliver the event, starting at 1.
+	Attempt int `json:"attempt" bson:"attempt"`
+
+	// Success is whether the application accepted the event.
+	Success bool `json:"success" bson:"success"`
+
+	// StatusCode is the status the webhook responded with, zero if it couldn't be reached.
+	StatusCode int `json:"statusCode,omitempty" bson:"statusCode,omitempty"`
+
+	// Error describes why the attempt failed.
+	Error string `json:"error,omitempty" bson:"error,omitempty"`
+
+	// DurationMs is how long the webhook took to respond.
+	DurationMs int64 `json:"durationMs" bson:"durationMs"`
+
+	// NextAttemptAt is when the event is delivered again after a failed attempt, zero if it is not.
+	NextAttemptAt time.Time `json:"nextAttemptAt,omitzero" bson:"nextAttemptAt,omitempty"`
+
+	// CreatedAt is when the attempt was made.
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
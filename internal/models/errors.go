@@ -25,23 +25,61 @@ var (
 	ErrUnauthorizedAction    = errors.New("unauthorized action")
 	ErrPasswordResetExpired  = errors.New("password reset token expired")
 	ErrInvalidID             = errors.New("invalid ID format")
+	ErrTrustLevelTooLow      = errors.New("trust level too low for this action")
+	ErrPasswordResetRequired = errors.New("password reset required")
+	ErrInvalidRecoveryToken  = errors.New("invalid or expired recovery token")
+	ErrMergeSameAccount      = errors.New("cannot merge an account into itself")
+	ErrMergeJobNotFound      = errors.New("account merge job not found")
+	ErrGuestsDisabled        = errors.New("guest access is disabled")
+	ErrNotGuest              = errors.New("token does not belong to a guest session")
+	ErrGuestAlreadyLinked    = errors.New("guest session is already linked to an account")
+	ErrAgeAlreadyAttested    = errors.New("date of birth was already attested")
+	ErrInvalidBirthDate      = errors.New("invalid date of birth")
+	ErrAdultsOnly            = errors.New("only users who attested being adults can do this")
+	ErrMinorRestricted       = errors.New("this is turned off for users under 18")
+	ErrInvalidOAuthState     = errors.New("invalid or expired OAuth sign-in")
+	ErrOAuthAlreadyLinked    = errors.New("an account with this provider is already linked")
+	ErrOAuthEmailUnverified  = errors.New("an account with this email address exists but the address isn't verified")
+	ErrRenameCooldown        = errors.New("username was changed too recently")
+	ErrUsernameChanged       = errors.New("username was changed meanwhile")
 
 	// Room errors
 	ErrRoomNotFound        = errors.New("room not found")
 	ErrRoomAlreadyExists   = errors.New("room already exists")
 	ErrRoomFull            = errors.New("room is full")
+	ErrJoinQueued          = errors.New("room joins are queued while the server is under load")
 	ErrRoomInactive        = errors.New("room is inactive")
 	ErrInvalidRoomPassword = errors.New("invalid room password")
 	ErrUserBanned          = errors.New("user is banned from this room")
 	ErrUserAlreadyInRoom   = errors.New("user is already in this room")
 	ErrMaxRoomsReached     = errors.New("maximum number of rooms reached")
+	ErrListenerOnly        = errors.New("listener-only users cannot participate in this room")
+	ErrRoomEventNotFound   = errors.New("room event not found")
+	ErrInvalidRoomEvent    = errors.New("invalid room event")
+	ErrInvalidRoomExpiry   = errors.New("invalid pop-up room expiry")
+	ErrInvalidVoteSettings = errors.New("invalid vote labels or reactions")
+	ErrInvalidAnalytics    = errors.New("invalid analytics export configuration")
+	ErrRoomQuarantined     = errors.New("room is quarantined")
+	ErrRoomReportNotFound  = errors.New("room report not found")
+	ErrRoomAlreadyReported = errors.New("you already reported this room")
+	ErrRoomReportResolved  = errors.New("room report is already resolved")
+	ErrInvalidRoomReport   = errors.New("invalid room report")
+	ErrInvalidLanguage     = errors.New("invalid language code")
+	ErrInvalidRoomRole     = errors.New("invalid room role or permission")
 
 	// DJ queue errors
-	ErrQueueFull          = errors.New("DJ queue is full")
-	ErrUserNotInQueue     = errors.New("user is not in the DJ queue")
-	ErrUserAlreadyInQueue = errors.New("user is already in the DJ queue")
-	ErrCannotSkipSelf     = errors.New("cannot skip yourself")
-	ErrNotCurrentDJ       = errors.New("user is not the current DJ")
+	ErrQueueFull           = errors.New("DJ queue is full")
+	ErrQueueLocked         = errors.New("DJ queue is locked")
+	ErrUserNotInQueue      = errors.New("user is not in the DJ queue")
+	ErrUserAlreadyInQueue  = errors.New("user is already in the DJ queue")
+	ErrCannotSkipSelf      = errors.New("cannot skip yourself")
+	ErrNotCurrentDJ        = errors.New("user is not the current DJ")
+	ErrNoActivePlaylist    = errors.New("user has no active playlist")
+	ErrNoPlayableItems     = errors.New("active playlist has no playable items")
+	ErrAllItemsTooLong     = errors.New("all playlist items exceed the room's maximum length")
+	ErrMediaNotInPlaylist  = errors.New("media is not in the active playlist")
+	ErrSetPlanTooLong      = errors.New("too many tracks planned")
+	ErrSetPlanningDisabled = errors.New("planning tracks ahead is disabled")
 
 	// Media errors
 	ErrMediaNotFound          = errors.New("media not found")
@@ -51,6 +89,18 @@ var (
 	ErrMediaRestricted        = errors.New("media is age-restricted or restricted in some regions")
 	ErrMediaSourceUnavailable = errors.New("media source is unavailable")
 	ErrMediaCantBeResolved    = errors.New("media URL could not be resolved")
+	ErrPreviewUnavailable     = errors.New("previews are unavailable for this media")
+	ErrLyricsNotFound         = errors.New("lyrics not found")
+	ErrVoteWindowClosed       = errors.New("votes are only accepted while the media is playing")
+	ErrReactionDisabled       = errors.New("reaction is not enabled in this room")
+
+	// History errors
+	ErrPlayHistoryNotFound = errors.New("play history not found")
+	ErrArchiveNotFound     = errors.New("history archive not found")
+	ErrArchiveRestored     = errors.New("history archive is already restored")
+	ErrArchiveNotRestored  = errors.New("history archive is not restored")
+	ErrImportJobNotFound   = errors.New("history import job not found")
+	ErrInvalidImport       = errors.New("invalid history import")
 
 	// Playlist errors
 	ErrPlaylistNotFound     = errors.New("playlist not found")
@@ -58,16 +108,33 @@ var (
 	ErrPlaylistEmpty        = errors.New("playlist is empty")
 	ErrPlaylistItemNotFound = errors.New("playlist item not found")
 	ErrPlaylistPrivate      = errors.New("playlist is private")
+	ErrInvalidPlaylistOrder = errors.New("playlist order must list each item exactly once")
+	ErrPlaylistChanged      = errors.New("playlist was changed meanwhile")
 
 	// Chat errors
 	ErrMessageNotFound        = errors.New("message not found")
 	ErrUserMuted              = errors.New("user is muted")
 	ErrChatDisabled           = errors.New("chat is disabled in this room")
+	ErrChatLinksDisabled      = errors.New("links are disabled in this room's chat")
+	ErrChatImagesDisabled     = errors.New("images are disabled in this room's chat")
+	ErrChatEmojiOnly          = errors.New("only emoji are allowed in this room's chat")
+	ErrChatFiltered           = errors.New("message contains terms that aren't allowed")
 	ErrMessageTooLong         = errors.New("message exceeds maximum length")
 	ErrMessageRateLimited     = errors.New("message rate limit exceeded")
 	ErrInvalidCommand         = errors.New("invalid chat command")
 	ErrCommandDisabled        = errors.New("command is disabled")
 	ErrInsufficientPermission = errors.New("insufficient permission for this command")
+	ErrPinLimitReached        = errors.New("pinned message limit reached")
+	ErrChatFlagNotFound       = errors.New("chat flag not found")
+	ErrChatFlagReviewed       = errors.New("chat flag was already reviewed")
+	ErrInvalidChatAppearance  = errors.New("invalid chat color or flair")
+	ErrNothingToUndo          = errors.New("no moderation action to undo")
+	ErrChatChannelNotFound    = errors.New("chat channel not found")
+	ErrChatChannelExists      = errors.New("chat channel already exists")
+	ErrInvalidChatChannel     = errors.New("invalid chat channel")
+	ErrChatChannelRestricted  = errors.New("your role can't post in this chat channel")
+	ErrChatFilterNotFound     = errors.New("chat filter not found")
+	ErrInvalidChatFilter      = errors.New("invalid chat filter")
 
 	// Validation errors
 	ErrInvalidInput         = errors.New("invalid input")
@@ -80,6 +147,64 @@ var (
 	ErrTokenExpired    = errors.New("token expired")
 	ErrSessionExpired  = errors.New("session expired")
 	ErrTooManyRequests = errors.New("too many requests")
+	ErrInvalidAPIKey   = errors.New("invalid API key")
+	ErrAPIKeyScope     = errors.New("API key does not grant this scope")
+	ErrAPIKeyNotFound  = errors.New("API key not found")
+	ErrTooManyAPIKeys  = errors.New("maximum number of API keys reached")
+
+	// Developer application errors
+	ErrDeveloperAppNotFound = errors.New("developer application not found")
+	ErrInvalidDeveloperApp  = errors.New("invalid developer application")
+	ErrTooManyDeveloperApps = errors.New("maximum number of developer applications reached")
+
+	// Verification errors
+	ErrClaimNotFound   = errors.New("verification claim not found")
+	ErrInvalidClaim    = errors.New("invalid verification claim")
+	ErrClaimPending    = errors.New("you already have a pending claim for this")
+	ErrClaimReviewed   = errors.New("verification claim was already reviewed")
+	ErrAlreadyVerified = errors.New("already verified")
+	ErrNotVerified     = errors.New("not verified")
+
+	// Message template errors
+	ErrTemplateNotFound = errors.New("message template not found")
+	ErrInvalidTemplate  = errors.New("invalid message template")
+
+	// Takedown errors
+	ErrMediaTakenDown   = errors.New("media was taken down after a content complaint")
+	ErrTakedownNotFound = errors.New("takedown not found")
+	ErrInvalidTakedown  = errors.New("invalid takedown")
+	ErrAlreadyTakenDown = errors.New("media is already taken down")
+
+	// Image review errors
+	ErrImageReviewNotFound  = errors.New("image review not found")
+	ErrImageReviewed        = errors.New("image was already reviewed")
+	ErrBlockedImageNotFound = errors.New("blocked image not found")
+	ErrImageAlreadyBlocked  = errors.New("image is already blocked")
+	ErrInvalidImage         = errors.New("invalid image")
+
+	// Achievement errors
+	ErrAchievementExists   = errors.New("achievement already exists")
+	ErrAchievementUnlocked = errors.New("achievement already unlocked")
+
+	// Scrobbling errors
+	ErrScrobblingDisabled       = errors.New("scrobbling is not available")
+	ErrScrobbleAccountNotFound  = errors.New("no scrobbling account linked")
+	ErrScrobbleNotFound         = errors.New("scrobble not found")
+	ErrInvalidScrobbleAuthToken = errors.New("invalid or expired scrobbling authorization")
+
+	// History export errors
+	ErrTooManyExports     = errors.New("too many exports in progress")
+	ErrInvalidExportToken = errors.New("invalid history export range token")
+	ErrDataExportNotFound = errors.New("data export link is invalid or expired")
+
+	// Captcha errors
+	ErrCaptchaRequired    = errors.New("captcha verification required")
+	ErrCaptchaFailed      = errors.New("captcha verification failed")
+	ErrCaptchaUnavailable = errors.New("captcha verification is unavailable, try again later")
+
+	// Status page errors
+	ErrIncidentNotFound = errors.New("incident not found")
+	ErrInvalidIncident  = errors.New("invalid incident")
 
 	// System errors
 	ErrInternalServer     = errors.New("internal server error")
@@ -88,6 +213,10 @@ var (
 	ErrCacheError         = errors.New("cache error")
 	ErrNetworkError       = errors.New("network error")
 	ErrFeatureDisabled    = errors.New("feature is disabled")
+
+	// Event errors
+	ErrDeadLetterNotFound  = errors.New("dead-lettered event not found")
+	ErrDeadLetterNoHandler = errors.New("the handler of the dead-lettered event is not registered on this node")
 )
 
 // DomainError represents an error that occurs in the application domain.
@@ -285,7 +414,31 @@ func MapErrorToHTTPStatus(err error) int {
 	switch {
 	case errors.Is(err, ErrUserNotFound),
 		errors.Is(err, ErrRoomNotFound),
+		errors.Is(err, ErrRoomEventNotFound),
+		errors.Is(err, ErrAPIKeyNotFound),
+		errors.Is(err, ErrScrobbleAccountNotFound),
+		errors.Is(err, ErrScrobbleNotFound),
+		errors.Is(err, ErrMergeJobNotFound),
 		errors.Is(err, ErrMediaNotFound),
+		errors.Is(err, ErrLyricsNotFound),
+		errors.Is(err, ErrMessageNotFound),
+		errors.Is(err, ErrPlayHistoryNotFound),
+		errors.Is(err, ErrArchiveNotFound),
+		errors.Is(err, ErrImportJobNotFound),
+		errors.Is(err, ErrDeadLetterNotFound),
+		errors.Is(err, ErrRoomReportNotFound),
+		errors.Is(err, ErrClaimNotFound),
+		errors.Is(err, ErrTemplateNotFound),
+		errors.Is(err, ErrTakedownNotFound),
+		errors.Is(err, ErrIncidentNotFound),
+		errors.Is(err, ErrImageReviewNotFound),
+		errors.Is(err, ErrBlockedImageNotFound),
+		errors.Is(err, ErrChatFlagNotFound),
+		errors.Is(err, ErrChatChannelNotFound),
+		errors.Is(err, ErrChatFilterNotFound),
+		errors.Is(err, ErrNothingToUndo),
+		errors.Is(err, ErrDeveloperAppNotFound),
+		errors.Is(err, ErrDataExportNotFound),
 		errors.Is(err, ErrPlaylistNotFound),
 		errors.Is(err, ErrPlaylistItemNotFound):
 		return http.StatusNotFound
@@ -293,6 +446,7 @@ func MapErrorToHTTPStatus(err error) int {
 	case errors.Is(err, ErrInvalidCredentials),
 		errors.Is(err, ErrInvalidToken),
 		errors.Is(err, ErrTokenExpired),
+		errors.Is(err, ErrInvalidAPIKey),
 		errors.Is(err, ErrSessionExpired),
 		errors.Is(err, ErrEmailNotVerified):
 		return http.StatusUnauthorized
@@ -301,14 +455,59 @@ func MapErrorToHTTPStatus(err error) int {
 		errors.Is(err, ErrUserNotInRoom),
 		errors.Is(err, ErrUnauthorizedAction),
 		errors.Is(err, ErrInsufficientPermission),
-		errors.Is(err, ErrUserBanned):
+		errors.Is(err, ErrUserBanned),
+		errors.Is(err, ErrListenerOnly),
+		errors.Is(err, ErrQueueLocked),
+		errors.Is(err, ErrRoomQuarantined),
+		errors.Is(err, ErrMediaRestricted),
+		errors.Is(err, ErrPreviewUnavailable),
+		errors.Is(err, ErrAdultsOnly),
+		errors.Is(err, ErrChatDisabled),
+		errors.Is(err, ErrUserMuted),
+		errors.Is(err, ErrChatLinksDisabled),
+		errors.Is(err, ErrChatImagesDisabled),
+		errors.Is(err, ErrChatEmojiOnly),
+		errors.Is(err, ErrChatFiltered),
+		errors.Is(err, ErrChatChannelRestricted),
+		errors.Is(err, ErrMinorRestricted),
+		errors.Is(err, ErrAPIKeyScope),
+		errors.Is(err, ErrPasswordResetRequired),
+		errors.Is(err, ErrGuestsDisabled),
+		errors.Is(err, ErrSetPlanningDisabled),
+		errors.Is(err, ErrTrustLevelTooLow),
+		errors.Is(err, ErrCaptchaRequired),
+		errors.Is(err, ErrCaptchaFailed):
 		return http.StatusForbidden
 
 	case errors.Is(err, ErrUserAlreadyExists),
 		errors.Is(err, ErrEmailAlreadyExists),
 		errors.Is(err, ErrUsernameAlreadyExists),
 		errors.Is(err, ErrUserAlreadyInRoom),
-		errors.Is(err, ErrUserAlreadyInQueue):
+		errors.Is(err, ErrUserAlreadyInQueue),
+		errors.Is(err, ErrArchiveRestored),
+		errors.Is(err, ErrArchiveNotRestored),
+		errors.Is(err, ErrVoteWindowClosed),
+		errors.Is(err, ErrGuestAlreadyLinked),
+		errors.Is(err, ErrAgeAlreadyAttested),
+		errors.Is(err, ErrOAuthAlreadyLinked),
+		errors.Is(err, ErrOAuthEmailUnverified),
+		errors.Is(err, ErrUsernameChanged),
+		errors.Is(err, ErrPlaylistChanged),
+		errors.Is(err, ErrDeadLetterNoHandler),
+		errors.Is(err, ErrRoomAlreadyReported),
+		errors.Is(err, ErrRoomReportResolved),
+		errors.Is(err, ErrClaimPending),
+		errors.Is(err, ErrClaimReviewed),
+		errors.Is(err, ErrAlreadyVerified),
+		errors.Is(err, ErrNotVerified),
+		errors.Is(err, ErrAlreadyTakenDown),
+		errors.Is(err, ErrImageReviewed),
+		errors.Is(err, ErrImageAlreadyBlocked),
+		errors.Is(err, ErrAchievementExists),
+		errors.Is(err, ErrAchievementUnlocked),
+		errors.Is(err, ErrPinLimitReached),
+		errors.Is(err, ErrChatChannelExists),
+		errors.Is(err, ErrChatFlagReviewed):
 		return http.StatusConflict
 
 	case errors.Is(err, ErrInvalidInput),
@@ -317,18 +516,60 @@ func MapErrorToHTTPStatus(err error) int {
 		errors.Is(err, ErrPasswordTooWeak),
 		errors.Is(err, ErrInvalidUsername),
 		errors.Is(err, ErrInvalidRoomPassword),
+		errors.Is(err, ErrInvalidRoomEvent),
+		errors.Is(err, ErrInvalidRoomExpiry),
+		errors.Is(err, ErrInvalidVoteSettings),
+		errors.Is(err, ErrInvalidChatAppearance),
+		errors.Is(err, ErrReactionDisabled),
+		errors.Is(err, ErrInvalidAnalytics),
+		errors.Is(err, ErrInvalidRoomReport),
+		errors.Is(err, ErrInvalidLanguage),
+		errors.Is(err, ErrInvalidRoomRole),
+		errors.Is(err, ErrInvalidClaim),
+		errors.Is(err, ErrInvalidTemplate),
+		errors.Is(err, ErrInvalidTakedown),
+		errors.Is(err, ErrInvalidImage),
+		errors.Is(err, ErrInvalidIncident),
+		errors.Is(err, ErrTooManyAPIKeys),
+		errors.Is(err, ErrInvalidDeveloperApp),
+		errors.Is(err, ErrTooManyDeveloperApps),
+		errors.Is(err, ErrInvalidRecoveryToken),
+		errors.Is(err, ErrMergeSameAccount),
+		errors.Is(err, ErrNotGuest),
+		errors.Is(err, ErrInvalidBirthDate),
+		errors.Is(err, ErrInvalidOAuthState),
+		errors.Is(err, ErrScrobblingDisabled),
+		errors.Is(err, ErrInvalidScrobbleAuthToken),
+		errors.Is(err, ErrInvalidExportToken),
 		errors.Is(err, ErrInvalidMediaType),
-		errors.Is(err, ErrInvalidCommand):
+		errors.Is(err, ErrInvalidCommand),
+		errors.Is(err, ErrInvalidChatChannel),
+		errors.Is(err, ErrInvalidChatFilter),
+		errors.Is(err, ErrNoActivePlaylist),
+		errors.Is(err, ErrPlaylistEmpty),
+		errors.Is(err, ErrNoPlayableItems),
+		errors.Is(err, ErrAllItemsTooLong),
+		errors.Is(err, ErrMediaNotInPlaylist),
+		errors.Is(err, ErrSetPlanTooLong),
+		errors.Is(err, ErrInvalidPlaylistOrder),
+		errors.Is(err, ErrInvalidImport):
 		return http.StatusBadRequest
 
 	case errors.Is(err, ErrTooManyRequests),
+		errors.Is(err, ErrTooManyExports),
+		errors.Is(err, ErrRenameCooldown),
 		errors.Is(err, ErrMessageRateLimited):
 		return http.StatusTooManyRequests
 
+	case errors.Is(err, ErrMediaTakenDown):
+		return http.StatusUnavailableForLegalReasons
+
 	case errors.Is(err, ErrRoomFull),
+		errors.Is(err, ErrJoinQueued),
 		errors.Is(err, ErrQueueFull),
 		errors.Is(err, ErrPlaylistFull),
-		errors.Is(err, ErrMaxRoomsReached):
+		errors.Is(err, ErrMaxRoomsReached),
+		errors.Is(err, ErrCaptchaUnavailable):
 		return http.StatusServiceUnavailable
 
 	default:
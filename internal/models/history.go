@@ -24,6 +24,9 @@ type History struct {
 
 	// Metadata contains additional information about the record.
 	Metadata map[string]any `json:"metadata,omitempty" bson:"metadata,omitempty"`
+
+	// TraceID is the trace ID of the request that created the record.
+	TraceID string `json:"traceId,omitempty" bson:"traceId,omitempty"`
 }
 
 // PlayHistory represents a record of a media item being played.
@@ -37,6 +40,10 @@ type PlayHistory struct {
 	// MediaID is the ID of the media that was played.
 	MediaID bson.ObjectID `json:"mediaId" bson:"mediaId"`
 
+	// SongID is the ID shared by every provider's copy of the song played, see Media.SongID.
+	// Plays recorded before the song was known to other providers may lack it.
+	SongID bson.ObjectID `json:"songId,omitzero" bson:"songId,omitempty"`
+
 	// DjID is the ID of the user who played the media.
 	DjID bson.ObjectID `json:"djId" bson:"djId"`
 
@@ -64,11 +71,65 @@ type PlayHistory struct {
 	// SkippedBy is the ID of the user who skipped the media, if applicable.
 	SkippedBy bson.ObjectID `json:"skippedBy,omitempty" bson:"skippedBy,omitempty"`
 
+	// Interrupted indicates whether the play was cut short by a server restart.
+	Interrupted bool `json:"interrupted,omitempty" bson:"interrupted,omitempty"`
+
+	// Truncated indicates whether the play was cut off at the room's maximum track length.
+	Truncated bool `json:"truncated,omitempty" bson:"truncated,omitempty"`
+
 	// Votes contains the voting information.
 	Votes MediaVotes `json:"votes" bson:"votes"`
 
 	// UserCount is the number of users in the room when the media was played.
 	UserCount int `json:"userCount" bson:"userCount"`
+
+	// ExternalID is the ID of the play on the platform it was imported from. Empty for plays in Listenify.
+	ExternalID string `json:"externalId,omitempty" bson:"externalId,omitempty"`
+}
+
+// PlayImportRecord is one historical play in a bulk play history import, one JSON object per line.
+type PlayImportRecord struct {
+	// ExternalID is the ID of the play on the platform it is imported from. Importing it again updates it.
+	ExternalID string `json:"externalId" validate:"required,max=200"`
+
+	// Source is the media's source type (e.g., "youtube", "soundcloud").
+	Source string `json:"source" validate:"required,oneof=youtube soundcloud"`
+
+	// SourceID is the ID of the media on its source platform.
+	SourceID string `json:"sourceId" validate:"required,max=200"`
+
+	// Title is the title of the media. Along with the duration it saves looking the media up on its source.
+	Title string `json:"title,omitempty" validate:"max=200"`
+
+	// Artist is the artist of the media.
+	Artist string `json:"artist,omitempty" validate:"max=200"`
+
+	// Duration is the duration of the media in seconds.
+	Duration int `json:"duration,omitempty" validate:"min=0,max=3600"`
+
+	// PlayedAt is when the media started playing.
+	PlayedAt time.Time `json:"playedAt"`
+
+	// EndedAt is when the media finished playing. Defaults to the media's duration after it started.
+	EndedAt time.Time `json:"endedAt,omitzero"`
+
+	// DJUsername is the username of the DJ. Plays of DJs without a Listenify account keep just the name.
+	DJUsername string `json:"djUsername,omitempty" validate:"max=50"`
+
+	// Woots is the number of woots the play received.
+	Woots int `json:"woots,omitempty" validate:"min=0"`
+
+	// Mehs is the number of mehs the play received.
+	Mehs int `json:"mehs,omitempty" validate:"min=0"`
+
+	// Grabs is the number of grabs the play received.
+	Grabs int `json:"grabs,omitempty" validate:"min=0"`
+
+	// Skipped indicates whether the media was skipped.
+	Skipped bool `json:"skipped,omitempty"`
+
+	// UserCount is the number of users in the room during the play.
+	UserCount int `json:"userCount,omitempty" validate:"min=0"`
 }
 
 // UserHistory represents a record of a user's activities.
@@ -175,6 +236,10 @@ type DJHistory struct {
 	// EndTime is when the user stopped DJing.
 	EndTime time.Time `json:"endTime,omitzero" bson:"endTime,omitempty"`
 
+	// WaitTime is how long the user waited in the DJ queue for this turn in seconds,
+	// since joining the queue or since their previous turn ended.
+	WaitTime int `json:"waitTime" bson:"waitTime"`
+
 	// Duration is the duration of the DJ session in seconds.
 	Duration int `json:"duration" bson:"duration"`
 
@@ -281,11 +346,17 @@ type ModerationHistory struct {
 	TargetUserID bson.ObjectID `json:"targetUserId" bson:"targetUserId"`
 
 	// Action is the type of moderation action.
-	Action string `json:"action" bson:"action" validate:"required,oneof=warn mute unmute kick ban unban delete"`
+	Action string `json:"action" bson:"action" validate:"required,oneof=warn mute unmute kick ban unban delete flag mask unmask undo"`
 
 	// Reason is the reason for the moderation action.
 	Reason string `json:"reason,omitempty" bson:"reason,omitempty"`
 
+	// Automated indicates whether the action was taken by automated moderation rather than a moderator.
+	Automated bool `json:"automated,omitempty" bson:"automated,omitempty"`
+
+	// Score is the toxicity score that led to an automated action.
+	Score float64 `json:"score,omitempty" bson:"score,omitempty"`
+
 	// Timestamp is when the action occurred.
 	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
 
@@ -298,8 +369,14 @@ type ModerationHistory struct {
 	// MessageID is the ID of the message that was moderated (for delete actions).
 	MessageID bson.ObjectID `json:"messageId,omitempty" bson:"messageId,omitempty"`
 
+	// TraceID is the trace ID of the request that took the action.
+	TraceID string `json:"traceId,omitempty" bson:"traceId,omitempty"`
+
 	// MessageContent is the content of the deleted message (if applicable).
 	MessageContent string `json:"-" bson:"messageContent,omitempty"`
+
+	// UndoneID is the ID of the moderation action an undo reversed (for undo actions).
+	UndoneID bson.ObjectID `json:"undoneId,omitempty" bson:"undoneId,omitempty"`
 }
 
 // SystemHistory represents a record of system events.
@@ -398,9 +475,10 @@ type HistorySummary struct {
 	LastUpdated time.Time `json:"lastUpdated"`
 }
 
-// TopTrackSummary represents a summary of a popular track.
+// TopTrackSummary represents a summary of a popular track. Plays of every provider's copy of a song
+// count towards the same track.
 type TopTrackSummary struct {
-	// MediaID is the ID of the media.
+	// MediaID is the ID of the song, see Media.SongID.
 	MediaID bson.ObjectID `json:"mediaId"`
 
 	// Type is the type of media (youtube, soundcloud, etc).
@@ -466,3 +544,99 @@ type TopDJSummary struct {
 	// LastDJTime is when the user last DJ'd.
 	LastDJTime time.Time `json:"lastDJTime"`
 }
+
+// DJRotationStats is how a DJ fared in a room's DJ rotation over a time window.
+type DJRotationStats struct {
+	// UserID is the ID of the DJ.
+	UserID bson.ObjectID `json:"userId"`
+
+	// Username is the username of the DJ.
+	Username string `json:"username"`
+
+	// Turns is the number of turns the DJ received.
+	Turns int `json:"turns"`
+
+	// AverageWait is the average time the DJ waited in the queue for a turn, in seconds.
+	AverageWait float64 `json:"averageWait"`
+
+	// LongestWait is the longest time the DJ waited in the queue for a turn, in seconds.
+	LongestWait int `json:"longestWait"`
+
+	// Plays is the number of tracks the DJ played.
+	Plays int `json:"plays"`
+
+	// Skips is the number of the DJ's tracks that were skipped.
+	Skips int `json:"skips"`
+}
+
+// RotationReport breaks down a room's DJ rotation per DJ over a time window, so moderators can tell
+// whether turns are shared fairly.
+type RotationReport struct {
+	// RoomID is the ID of the room.
+	RoomID bson.ObjectID `json:"roomId"`
+
+	// Since is the start of the window.
+	Since time.Time `json:"since"`
+
+	// Until is the end of the window.
+	Until time.Time `json:"until"`
+
+	// DJs are the DJs who had turns or played in the window, most turns first.
+	DJs []DJRotationStats `json:"djs"`
+
+	// GeneratedAt is when the report was computed. Reports are cached for a while.
+	GeneratedAt time.Time `json:"generatedAt"`
+}
+
+// Queue transition stages, the steps of a room's move from one track to the next.
+const (
+	// TransitionStageVoteClose is waiting for the queue and closing out the ending play with its votes.
+	TransitionStageVoteClose = "vote_close"
+
+	// TransitionStageAdvanceDecision is picking the next DJ, or the crowd pick to replay.
+	TransitionStageAdvanceDecision = "advance_decision"
+
+	// TransitionStageMediaResolve is resolving the track to play and starting it.
+	TransitionStageMediaResolve = "media_resolve"
+
+	// TransitionStageBroadcast is telling the room's clients about the change.
+	TransitionStageBroadcast = "broadcast"
+)
+
+// TransitionStages are the queue transition stages in the order they run.
+var TransitionStages = []string{
+	TransitionStageVoteClose,
+	TransitionStageAdvanceDecision,
+	TransitionStageMediaResolve,
+	TransitionStageBroadcast,
+}
+
+// QueueTransitionStats summarizes how a room's recent track changes went on a node.
+type QueueTransitionStats struct {
+	// RoomID is the ID of the room.
+	RoomID bson.ObjectID `json:"roomId"`
+
+	// Transitions is the number of track changes summarized.
+	Transitions int `json:"transitions"`
+
+	// P50 is the median time a track change took, in milliseconds.
+	P50 float64 `json:"p50Ms"`
+
+	// P95 is the 95th percentile of the time a track change took, in milliseconds.
+	P95 float64 `json:"p95Ms"`
+
+	// Max is the longest a track change took, in milliseconds.
+	Max float64 `json:"maxMs"`
+
+	// Stages is the average time spent in each transition stage, in milliseconds.
+	Stages map[string]float64 `json:"stagesMs"`
+
+	// Late is the number of track changes that came too long after the track's expected end.
+	Late int `json:"late"`
+
+	// Missed is the number of tracks the room didn't move on from in time.
+	Missed int `json:"missed"`
+
+	// LastTransition is when the room last changed tracks.
+	LastTransition time.Time `json:"lastTransition"`
+}
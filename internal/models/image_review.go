@@ -0,0 +1,142 @@
+// Package models contains the data structures used throughout the application.
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ImageKind is where a user-provided image is shown.
+type ImageKind string
+
+const (
+	// ImageKindAvatar is an uploaded profile avatar.
+	ImageKindAvatar ImageKind = "avatar"
+	// ImageKindPlaylistCover is a playlist's cover image.
+	ImageKindPlaylistCover ImageKind = "playlist_cover"
+)
+
+// ImageReviewStatus is where a changed image is in the admins' review.
+type ImageReviewStatus string
+
+const (
+	// ImageReviewPending images wait for an admin.
+	ImageReviewPending ImageReviewStatus = "pending"
+	// ImageReviewApproved images were found fine.
+	ImageReviewApproved ImageReviewStatus = "approved"
+	// ImageReviewRemoved images were taken off the avatar or playlist they were set on.
+	ImageReviewRemoved ImageReviewStatus = "removed"
+)
+
+// ImageReview is a changed avatar or playlist cover queued for review by platform admins.
+type ImageReview struct {
+	// ID is the unique identifier for the review.
+	ID bson.ObjectID `json:"id" bson:"_id"`
+
+	// Kind is where the image is shown.
+	Kind ImageKind `json:"kind" bson:"kind"`
+
+	// OwnerID is the user who set the image, and is told if it is removed.
+	OwnerID bson.ObjectID `json:"ownerId" bson:"ownerId"`
+
+	// TargetID is the user whose avatar or the playlist whose cover the image is.
+	TargetID bson.ObjectID `json:"targetId" bson:"targetId"`
+
+	// URL is the URL of the image.
+	URL string `json:"url" bson:"url"`
+
+	// Hash is the perceptual hash of the image in hexadecimal, empty until it was computed.
+	Hash string `json:"hash,omitempty" bson:"hash,omitempty"`
+
+	// Checked is whether the image was matched against the blocked images, even if it couldn't be hashed.
+	Checked bool `json:"checked" bson:"checked"`
+
+	// MatchedImageID is the blocked image the image matched, which removed it automatically.
+	MatchedImageID bson.ObjectID `json:"matchedImageId,omitzero" bson:"matchedImageId,omitempty"`
+
+	// Status is where the image is in review.
+	Status ImageReviewStatus `json:"status" bson:"status"`
+
+	// Reason is why the image was removed, shared with its owner.
+	Reason string `json:"reason,omitempty" bson:"reason,omitempty"`
+
+	// ReviewedBy is the admin who reviewed the image, zero when it was removed automatically.
+	ReviewedBy bson.ObjectID `json:"reviewedBy,omitzero" bson:"reviewedBy,omitempty"`
+
+	// ReviewedAt is when the image was reviewed.
+	ReviewedAt time.Time `json:"reviewedAt,omitzero" bson:"reviewedAt,omitempty"`
+
+	// ObjectTimes contains timestamps for this review.
+	ObjectTimes
+}
+
+// ImageReviewDecision is an admin's decision on one or more changed images.
+type ImageReviewDecision struct {
+	// ReviewIDs are the reviews decided on together.
+	ReviewIDs []bson.ObjectID `json:"reviewIds" validate:"required,min=1,max=100"`
+
+	// Status is the outcome, approved or removed.
+	Status ImageReviewStatus `json:"status" validate:"required,oneof=approved removed"`
+
+	// Reason explains a removal to the image's owner.
+	Reason string `json:"reason" validate:"max=500"`
+
+	// Block adds a removed image to the blocked images, so it is removed automatically wherever it is set again.
+	Block bool `json:"block"`
+}
+
+// ImageReviewResults is the outcome of a decision on several changed images.
+type ImageReviewResults struct {
+	// Resolved are the reviews the decision resolved.
+	Resolved []*ImageReview `json:"resolved"`
+
+	// Failed explains, by review ID, why the decision couldn't be applied to the other reviews.
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// ImageReviewFilter narrows a listing of image reviews. Zero fields match every review.
+type ImageReviewFilter struct {
+	// Kind limits the listing to avatars or playlist covers.
+	Kind ImageKind
+
+	// OwnerID limits the listing to one user's images.
+	OwnerID bson.ObjectID
+
+	// Status limits the listing to reviews in one status.
+	Status ImageReviewStatus
+}
+
+// BlockedImage is a known-bad image. Images whose perceptual hash is close to its hash are removed as
+// soon as they are set.
+type BlockedImage struct {
+	// ID is the unique identifier for the blocked image.
+	ID bson.ObjectID `json:"id" bson:"_id"`
+
+	// Hash is the perceptual hash of the image in hexadecimal.
+	Hash string `json:"hash" bson:"hash"`
+
+	// Note is the admins' description of the image.
+	Note string `json:"note,omitempty" bson:"note,omitempty"`
+
+	// ReviewID is the review the image was blocked from, if any.
+	ReviewID bson.ObjectID `json:"reviewId,omitzero" bson:"reviewId,omitempty"`
+
+	// AddedBy is the admin who blocked the image.
+	AddedBy bson.ObjectID `json:"addedBy" bson:"addedBy"`
+
+	// ObjectTimes contains timestamps for this blocked image.
+	ObjectTimes
+}
+
+// BlockedImageRequest is an admin's request to block an image, given by its perceptual hash or its URL.
+type BlockedImageRequest struct {
+	// Hash is the perceptual hash of the image in hexadecimal.
+	Hash string `json:"hash" validate:"omitempty,hexadecimal,len=16"`
+
+	// URL is the URL of the image, hashed when the hash isn't given.
+	URL string `json:"url" validate:"omitempty,url"`
+
+	// Note describes the image.
+	Note string `json:"note" validate:"max=500"`
+}
@@ -0,0 +1,108 @@
+// Package models contains the data structures used throughout the application.
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// IncidentStatus is how far along the handling of an incident is.
+type IncidentStatus string
+
+const (
+	// IncidentInvestigating is for incidents whose cause is not known yet.
+	IncidentInvestigating IncidentStatus = "investigating"
+	// IncidentIdentified is for incidents whose cause is known and being fixed.
+	IncidentIdentified IncidentStatus = "identified"
+	// IncidentMonitoring is for incidents fixed and being watched.
+	IncidentMonitoring IncidentStatus = "monitoring"
+	// IncidentResolved is for incidents that are over.
+	IncidentResolved IncidentStatus = "resolved"
+)
+
+// IncidentImpact is how badly an incident affects the platform.
+type IncidentImpact string
+
+const (
+	// IncidentMinor is for incidents degrading part of the platform.
+	IncidentMinor IncidentImpact = "minor"
+	// IncidentMajor is for incidents making part of the platform unavailable.
+	IncidentMajor IncidentImpact = "major"
+	// IncidentCritical is for incidents making the platform unavailable.
+	IncidentCritical IncidentImpact = "critical"
+)
+
+// Incident is an outage or degradation shown on the public status page. Incidents are managed by the
+// platform admins, or opened automatically when a component fails its health checks repeatedly.
+type Incident struct {
+	// ID is the unique identifier for the incident.
+	ID bson.ObjectID `json:"id" bson:"_id"`
+
+	// Title is the short summary shown on the status page.
+	Title string `json:"title" bson:"title"`
+
+	// Message is the latest update on the incident.
+	Message string `json:"message,omitempty" bson:"message,omitempty"`
+
+	// Status is how far along the handling of the incident is.
+	Status IncidentStatus `json:"status" bson:"status"`
+
+	// Impact is how badly the incident affects the platform.
+	Impact IncidentImpact `json:"impact" bson:"impact"`
+
+	// Components are the names of the health checked components affected.
+	Components []string `json:"components,omitempty" bson:"components,omitempty"`
+
+	// Automatic is whether the incident was opened by failing health checks rather than an admin.
+	// Automatic incidents are resolved once their component is healthy again.
+	Automatic bool `json:"automatic" bson:"automatic"`
+
+	// CreatedBy is the admin who opened the incident, zero for automatic incidents.
+	CreatedBy bson.ObjectID `json:"createdBy,omitzero" bson:"createdBy,omitempty"`
+
+	// StartedAt is when the incident started.
+	StartedAt time.Time `json:"startedAt" bson:"startedAt"`
+
+	// ResolvedAt is when the incident was resolved, zero while it is active.
+	ResolvedAt time.Time `json:"resolvedAt,omitzero" bson:"resolvedAt,omitempty"`
+
+	// ObjectTimes contains timestamps for this incident.
+	ObjectTimes
+}
+
+// IsActive checks if the incident is not resolved yet.
+func (i *Incident) IsActive() bool {
+	return i.Status != IncidentResolved
+}
+
+// IncidentRequest is an admin's new incident, or their update of one.
+type IncidentRequest struct {
+	// Title is the short summary shown on the status page.
+	Title string `json:"title" validate:"required,max=200"`
+
+	// Message is the latest update on the incident.
+	Message string `json:"message" validate:"max=2000"`
+
+	// Status is how far along the handling of the incident is. New incidents are investigating if not
+	// given, updated ones keep their status.
+	Status IncidentStatus `json:"status" validate:"omitempty,oneof=investigating identified monitoring resolved"`
+
+	// Impact is how badly the incident affects the platform.
+	Impact IncidentImpact `json:"impact" validate:"required,oneof=minor major critical"`
+
+	// Components are the names of the components affected.
+	Components []string `json:"components" validate:"max=20,dive,required,max=50"`
+
+	// StartedAt is when the incident started, now if not given.
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// IncidentFilter narrows a listing of incidents. Zero fields match every incident.
+type IncidentFilter struct {
+	// Active limits the listing to incidents not resolved yet.
+	Active bool
+
+	// Since limits the listing to incidents active at some point since then.
+	Since time.Time
+}
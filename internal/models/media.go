@@ -2,6 +2,7 @@
 package models
 
 import (
+	"math"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -39,10 +40,48 @@ type Media struct {
 	// AddedBy is the ID of the user who added the media.
 	AddedBy bson.ObjectID `json:"addedBy" bson:"addedBy"`
 
+	// Fingerprint identifies the song independently of its provider, derived from its title and artist.
+	Fingerprint string `json:"-" bson:"fingerprint,omitempty"`
+
+	// CanonicalID is the ID of the media item this one is a duplicate of from another provider, if any.
+	CanonicalID bson.ObjectID `json:"canonicalId,omitzero" bson:"canonicalId,omitempty"`
+
+	// Vibe is the energy and mood profile of the media aggregated from user tags, if it was tagged.
+	Vibe *MediaVibe `json:"vibe,omitempty" bson:"vibe,omitempty"`
+
+	// Verified marks the media as claimed by its artist or label. Only platform admins set it.
+	Verified *VerifiedBadge `json:"verified,omitempty" bson:"verified,omitempty"`
+
+	// TakedownID is the takedown that took the media down after a content complaint, if any. Only platform admins set it.
+	TakedownID bson.ObjectID `json:"takedownId,omitzero" bson:"takedownId,omitempty"`
+
 	// ObjectTimes contains timestamps for this media.
 	ObjectTimes
 }
 
+// SongID returns the ID shared by every provider's copy of the same song.
+func (m *Media) SongID() bson.ObjectID {
+	if !m.CanonicalID.IsZero() {
+		return m.CanonicalID
+	}
+	return m.ID
+}
+
+// CanonicalMedia is a song along with every provider's copy of it and their combined statistics.
+type CanonicalMedia struct {
+	// Media is the canonical media item for the song.
+	Media *Media `json:"media"`
+
+	// Variants are all media items for the song, including the canonical one.
+	Variants []*Media `json:"variants"`
+
+	// Stats are the statistics combined across all variants.
+	Stats MediaStats `json:"stats"`
+
+	// RecentPlays are the most recent plays of any variant.
+	RecentPlays []*PlayHistory `json:"recentPlays"`
+}
+
 // MediaMetadata contains additional information about a media item.
 type MediaMetadata struct {
 	// Views is the number of views on the original platform.
@@ -72,8 +111,189 @@ type MediaMetadata struct {
 	// ContentRating is the content rating of the media.
 	ContentRating string `json:"contentRating" bson:"contentRating"`
 
+	// ISRC is the International Standard Recording Code of the recording, if the provider exposes it.
+	ISRC string `json:"isrc,omitempty" bson:"isrc,omitempty"`
+
 	// Restricted indicates whether the media has content restrictions.
 	Restricted bool `json:"restricted" bson:"restricted"`
+
+	// AgeRestricted indicates whether the provider flagged the media as suitable for adults only.
+	AgeRestricted bool `json:"ageRestricted" bson:"ageRestricted"`
+
+	// Loudness is the measured loudness of the media, if known.
+	Loudness *MediaLoudness `json:"loudness,omitempty" bson:"loudness,omitempty"`
+
+	// HasLyrics indicates whether the lyrics provider has lyrics for the media.
+	HasLyrics bool `json:"hasLyrics" bson:"hasLyrics"`
+
+	// LyricsCheckedAt is when the lyrics provider was last asked for the media's lyrics, if ever.
+	LyricsCheckedAt *time.Time `json:"-" bson:"lyricsCheckedAt,omitempty"`
+}
+
+// Lyrics sources.
+const (
+	// LyricsSourceSynced marks lyrics with the time each line is sung at.
+	LyricsSourceSynced = "synced"
+
+	// LyricsSourcePlain marks lyrics without timing.
+	LyricsSourcePlain = "plain"
+)
+
+// MediaLyrics are the lyrics of a media item, for clients to show during playback.
+type MediaLyrics struct {
+	// MediaID is the ID of the media the lyrics belong to.
+	MediaID bson.ObjectID `json:"mediaId"`
+
+	// Synced indicates whether the lines have timestamps.
+	Synced bool `json:"synced"`
+
+	// Lines are the lines of the lyrics in order.
+	Lines []LyricsLine `json:"lines"`
+
+	// Instrumental indicates the media has no vocals, and so no lines.
+	Instrumental bool `json:"instrumental,omitempty"`
+
+	// Provider is the name of the provider the lyrics came from.
+	Provider string `json:"provider"`
+
+	// FetchedAt is when the lyrics were fetched from the provider.
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// LyricsLine is a line of lyrics.
+type LyricsLine struct {
+	// Time is when the line starts, in milliseconds from the start of the media. Zero for unsynced lyrics.
+	Time int64 `json:"time"`
+
+	// Text is the text of the line, empty for a pause.
+	Text string `json:"text"`
+}
+
+// Loudness measurement sources.
+const (
+	// LoudnessSourceProvider marks loudness data exposed by the media's provider.
+	LoudnessSourceProvider = "provider"
+
+	// LoudnessSourceAnalysis marks loudness data measured by the analysis worker.
+	LoudnessSourceAnalysis = "analysis"
+)
+
+// MediaLoudness describes how loud a media item is, so clients can normalize volume between tracks.
+type MediaLoudness struct {
+	// IntegratedLUFS is the integrated loudness of the media in LUFS.
+	IntegratedLUFS float64 `json:"integratedLufs" bson:"integratedLufs"`
+
+	// Peak is the true peak of the media as a linear sample value, where 1 is full scale, or 0 if unknown.
+	Peak float64 `json:"peak,omitempty" bson:"peak,omitempty"`
+
+	// Source is where the measurement came from.
+	Source string `json:"source" bson:"source"`
+
+	// MeasuredAt is when the loudness was measured.
+	MeasuredAt time.Time `json:"measuredAt" bson:"measuredAt"`
+}
+
+// SuggestedGain returns the gain in dB that brings the media to the target loudness.
+// The gain is limited to maxGain in either direction, and boosts are limited so the peak doesn't clip.
+func (l *MediaLoudness) SuggestedGain(targetLUFS, maxGain float64) float64 {
+	gain := targetLUFS - l.IntegratedLUFS
+	if l.Peak > 0 {
+		gain = min(gain, -20*math.Log10(l.Peak))
+	}
+	if maxGain > 0 {
+		gain = max(min(gain, maxGain), -maxGain)
+	}
+	return math.Round(gain*100) / 100
+}
+
+// Moods media can be tagged with.
+const (
+	MoodChill       = "chill"
+	MoodHappy       = "happy"
+	MoodUplifting   = "uplifting"
+	MoodGroovy      = "groovy"
+	MoodRomantic    = "romantic"
+	MoodMelancholic = "melancholic"
+	MoodDark        = "dark"
+	MoodAggressive  = "aggressive"
+)
+
+// MediaTag is how a user tagged the energy and mood of a media item.
+type MediaTag struct {
+	// ID is the unique identifier for the tag.
+	ID bson.ObjectID `json:"id" bson:"_id,omitempty"`
+
+	// MediaID is the ID of the tagged media.
+	MediaID bson.ObjectID `json:"mediaId" bson:"mediaId"`
+
+	// UserID is the ID of the user who tagged the media.
+	UserID bson.ObjectID `json:"userId" bson:"userId"`
+
+	// Energy is how energetic the user finds the media, from 1 (calm) to 5 (intense).
+	Energy int `json:"energy" bson:"energy"`
+
+	// Moods are the moods the user finds the media has.
+	Moods []string `json:"moods" bson:"moods"`
+
+	// ObjectTimes contains timestamps for this tag.
+	ObjectTimes
+}
+
+// MediaTagRequest represents the data needed to tag the energy and mood of a media item.
+type MediaTagRequest struct {
+	// Energy is how energetic the media is, from 1 (calm) to 5 (intense).
+	Energy int `json:"energy" validate:"required,min=1,max=5"`
+
+	// Moods are up to three moods of the media.
+	Moods []string `json:"moods" validate:"max=3,dive,oneof=chill happy uplifting groovy romantic melancholic dark aggressive"`
+}
+
+// MediaVibe is the energy and mood profile of a media item, aggregated from the tags of its users.
+type MediaVibe struct {
+	// Energy is the average energy the media was tagged with, from 1 (calm) to 5 (intense).
+	Energy float64 `json:"energy" bson:"energy"`
+
+	// Moods counts the users who tagged the media with each mood.
+	Moods map[string]int `json:"moods,omitempty" bson:"moods,omitempty"`
+
+	// Tags is the number of users who tagged the media.
+	Tags int `json:"tags" bson:"tags"`
+}
+
+// RoomVibe is the rolling energy and mood of a room, from the tagged media it played recently.
+type RoomVibe struct {
+	// Energy is the energy of the recent plays, from 1 (calm) to 5 (intense), weighted towards the latest ones.
+	Energy float64 `json:"energy"`
+
+	// Moods is the share of each mood in the recent plays, weighted towards the latest ones.
+	Moods map[string]float64 `json:"moods"`
+
+	// Plays is the number of recent plays of tagged media the vibe is made of.
+	Plays int `json:"plays"`
+}
+
+// VibeMatch is a media item suggested for how well it matches a room's vibe.
+type VibeMatch struct {
+	// Media is the suggested media.
+	Media *MediaInfo `json:"media"`
+
+	// Vibe is the energy and mood profile of the media.
+	Vibe *MediaVibe `json:"vibe"`
+
+	// Score is how well the media matches the room's vibe, from 0 to 1.
+	Score float64 `json:"score"`
+}
+
+// NormalizationHint is a suggested volume adjustment for the media playing in a room.
+type NormalizationHint struct {
+	// GainDB is the gain in dB clients should apply to the media.
+	GainDB float64 `json:"gainDb"`
+
+	// LoudnessLUFS is the integrated loudness of the media in LUFS.
+	LoudnessLUFS float64 `json:"loudnessLufs"`
+
+	// TargetLUFS is the loudness the gain brings the media to.
+	TargetLUFS float64 `json:"targetLufs"`
 }
 
 // MediaStats contains statistics for a media item within the app.
@@ -131,19 +351,59 @@ type MediaInfo struct {
 
 	// AddedBy is information about the user who added the media.
 	AddedBy *PublicUser `json:"addedBy,omitempty"`
+
+	// Verified is the media's verified badge, if its artist or label claimed it.
+	Verified *VerifiedBadge `json:"verified,omitempty"`
+
+	// AgeRestricted indicates whether the media is suitable for adults only.
+	AgeRestricted bool `json:"ageRestricted,omitempty"`
+
+	// HasLyrics indicates whether lyrics can be fetched with media.getLyrics.
+	HasLyrics bool `json:"hasLyrics"`
+
+	// Normalization is the suggested volume adjustment, set when the room has normalization hints enabled.
+	Normalization *NormalizationHint `json:"normalization,omitempty"`
+
+	// CrowdPick is set when the media plays as a crowd pick rather than a DJ's track.
+	CrowdPick *CrowdPick `json:"crowdPick,omitempty"`
+
+	// CanonicalID is the ID of the media item this one is a duplicate of from another provider, if any.
+	CanonicalID bson.ObjectID `json:"canonicalId,omitzero"`
+}
+
+// SongID returns the ID shared by every provider's copy of the same song.
+func (i *MediaInfo) SongID() bson.ObjectID {
+	if !i.CanonicalID.IsZero() {
+		return i.CanonicalID
+	}
+	return i.ID
+}
+
+// CrowdPick credits a track replayed between DJ turns because the room grabbed it.
+// The DJ of a crowd pick is the DJ who played the track it was picked from.
+type CrowdPick struct {
+	// PlayID is the ID of the play the track was picked from.
+	PlayID bson.ObjectID `json:"playId"`
+
+	// Grabs is the number of grabs the track got in that play.
+	Grabs int `json:"grabs"`
 }
 
 // ToMediaInfo converts a Media to a MediaInfo.
 func (m *Media) ToMediaInfo(addedByUser *User) *MediaInfo {
 	info := &MediaInfo{
-		ID:        m.ID,
-		Type:      m.Type,
-		SourceID:  m.SourceID,
-		Title:     m.Title,
-		Artist:    m.Artist,
-		Thumbnail: m.Thumbnail,
-		Duration:  m.Duration,
-		PlayCount: m.Stats.PlayCount,
+		ID:            m.ID,
+		Type:          m.Type,
+		SourceID:      m.SourceID,
+		Title:         m.Title,
+		Artist:        m.Artist,
+		Thumbnail:     m.Thumbnail,
+		Duration:      m.Duration,
+		PlayCount:     m.Stats.PlayCount,
+		Verified:      m.Verified,
+		AgeRestricted: m.Metadata.AgeRestricted,
+		HasLyrics:     m.Metadata.HasLyrics,
+		CanonicalID:   m.CanonicalID,
 	}
 
 	if addedByUser != nil {
@@ -182,6 +442,15 @@ type MediaSearchResponse struct {
 
 	// Query is the search query.
 	Query string `json:"query"`
+
+	// Partial is whether some providers were skipped, because their search budget is spent or they failed.
+	Partial bool `json:"partial,omitempty"`
+
+	// SkippedProviders are the providers that were skipped.
+	SkippedProviders []string `json:"skippedProviders,omitempty"`
+
+	// Cached is whether the response was served from the cache.
+	Cached bool `json:"cached,omitempty"`
 }
 
 // MediaSearchResult represents a single result from a media search.
@@ -218,6 +487,9 @@ type MediaSearchResult struct {
 
 	// Restricted indicates whether the media has content restrictions.
 	Restricted bool `json:"restricted"`
+
+	// AgeRestricted indicates whether the provider flagged the media as suitable for adults only.
+	AgeRestricted bool `json:"ageRestricted"`
 }
 
 // MediaHistoryEntry represents a record of a media item being played in a room.
@@ -284,6 +556,9 @@ type MediaVotes struct {
 
 	// Voters is a map of user IDs to their votes.
 	Voters map[string]string `json:"voters" bson:"voters"`
+
+	// Reactions is the number of each extra reaction received, for rooms that enable them.
+	Reactions map[string]int `json:"reactions,omitempty" bson:"reactions,omitempty"`
 }
 
 // MediaVoteRequest represents a request to vote on media.
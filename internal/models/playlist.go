@@ -44,6 +44,10 @@ type Playlist struct {
 
 	// LastPlayed is the time the playlist was last played from.
 	LastPlayed time.Time `json:"lastPlayed" bson:"lastPlayed"`
+
+	// Revision counts the changes made to the playlist's items. Each change is recorded in the playlist's
+	// change log at the revision it brought the playlist to.
+	Revision int64 `json:"revision" bson:"revision"`
 }
 
 // PlaylistItem represents a media item in a playlist.
@@ -66,6 +70,13 @@ type PlaylistItem struct {
 	// PlayCount is the number of times the item has been played from this playlist.
 	PlayCount int `json:"playCount" bson:"playCount"`
 
+	// RelinkedFrom is the media the item played before it was last re-linked to another source.
+	RelinkedFrom bson.ObjectID `json:"relinkedFrom,omitzero" bson:"relinkedFrom,omitempty"`
+
+	// TakedownID is the takedown that flagged the item's media, if any. Flagged items stay in the playlist
+	// for their owner to see, but are never played.
+	TakedownID bson.ObjectID `json:"takedownId,omitzero" bson:"takedownId,omitempty"`
+
 	// Media is the media item (populated when retrieving the playlist).
 	Media *MediaInfo `json:"media,omitempty" bson:"-"`
 }
@@ -88,6 +99,82 @@ type PlaylistStats struct {
 	LastCalculated time.Time `json:"lastCalculated" bson:"lastCalculated"`
 }
 
+// PlaylistOpType is the kind of change an op makes to a playlist's items.
+type PlaylistOpType string
+
+const (
+	// PlaylistOpAdd inserts items at a position, or appends them when the position is negative.
+	PlaylistOpAdd PlaylistOpType = "add"
+
+	// PlaylistOpRemove removes items.
+	PlaylistOpRemove PlaylistOpType = "remove"
+
+	// PlaylistOpMove moves an item to a position.
+	PlaylistOpMove PlaylistOpType = "move"
+
+	// PlaylistOpReorder puts every item in a new order, such as after a shuffle.
+	PlaylistOpReorder PlaylistOpType = "reorder"
+
+	// PlaylistOpUpdate replaces items in place, such as when they are re-linked or taken down.
+	PlaylistOpUpdate PlaylistOpType = "update"
+)
+
+// PlaylistOp is a change to a playlist's items, compact enough for clients to apply to their own copy.
+// Items are numbered by their position again after each op.
+type PlaylistOp struct {
+	// Type is the kind of change.
+	Type PlaylistOpType `json:"type" bson:"type"`
+
+	// Items are the items added, or the items updated.
+	Items []PlaylistItem `json:"items,omitempty" bson:"items,omitempty"`
+
+	// ItemIDs are the IDs of the items removed, or of every item in its new order.
+	ItemIDs []bson.ObjectID `json:"itemIds,omitempty" bson:"itemIds,omitempty"`
+
+	// ItemID is the ID of the item moved.
+	ItemID bson.ObjectID `json:"itemId,omitzero" bson:"itemId,omitempty"`
+
+	// Position is where the items were added or the item was moved to (zero-based).
+	Position int `json:"position" bson:"position"`
+}
+
+// PlaylistChange is an op recorded in a playlist's change log.
+type PlaylistChange struct {
+	// ID is the unique identifier for the change.
+	ID bson.ObjectID `json:"-" bson:"_id,omitempty"`
+
+	// PlaylistID is the ID of the playlist changed.
+	PlaylistID bson.ObjectID `json:"-" bson:"playlistId"`
+
+	// Revision is the revision the change brought the playlist to.
+	Revision int64 `json:"revision" bson:"revision"`
+
+	// PlaylistOp is the change made.
+	PlaylistOp `bson:",inline"`
+
+	// CreatedAt is the time the change was made.
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// PlaylistChanges brings a client's copy of a playlist's items up to date.
+type PlaylistChanges struct {
+	// PlaylistID is the ID of the playlist.
+	PlaylistID bson.ObjectID `json:"playlistId"`
+
+	// Revision is the playlist's current revision.
+	Revision int64 `json:"revision"`
+
+	// Reset tells the client its copy can't be brought up to date by changes, such as when it is too far
+	// behind. Items then holds every item of the playlist instead.
+	Reset bool `json:"reset"`
+
+	// Changes are the changes made since the client's revision, oldest first.
+	Changes []*PlaylistChange `json:"changes"`
+
+	// Items are the playlist's items, when the client's copy is reset.
+	Items []PlaylistItem `json:"items,omitempty"`
+}
+
 // PlaylistInfo represents a simplified playlist object for public display.
 type PlaylistInfo struct {
 	// ID is the unique identifier for the playlist.
@@ -122,6 +209,9 @@ type PlaylistInfo struct {
 
 	// UpdatedAt is the time the playlist was last updated.
 	UpdatedAt time.Time `json:"updatedAt"`
+
+	// Revision is the revision of the playlist's items, for clients syncing them.
+	Revision int64 `json:"revision"`
 }
 
 // ToPlaylistInfo converts a Playlist to a PlaylistInfo.
@@ -137,6 +227,7 @@ func (p *Playlist) ToPlaylistInfo(owner *User) PlaylistInfo {
 		CoverImage:    p.CoverImage,
 		CreatedAt:     p.CreatedAt,
 		UpdatedAt:     p.UpdatedAt,
+		Revision:      p.Revision,
 	}
 
 	if owner != nil {
@@ -0,0 +1,119 @@
+// Package models contains the data structures used throughout the application.
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// RoomReportReason is why a user reported a room.
+type RoomReportReason string
+
+const (
+	// RoomReportIllegalContent is for rooms playing or sharing illegal content.
+	RoomReportIllegalContent RoomReportReason = "illegal_content"
+	// RoomReportHateGroup is for rooms organizing or promoting hate.
+	RoomReportHateGroup RoomReportReason = "hate_group"
+	// RoomReportHarassment is for rooms used to harass people.
+	RoomReportHarassment RoomReportReason = "harassment"
+	// RoomReportSpam is for rooms made to spam or scam.
+	RoomReportSpam RoomReportReason = "spam"
+	// RoomReportOther is for anything else, explained in the description.
+	RoomReportOther RoomReportReason = "other"
+)
+
+// RoomReportStatus is where a room report is in the admins' triage.
+type RoomReportStatus string
+
+const (
+	// RoomReportPending reports wait for an admin.
+	RoomReportPending RoomReportStatus = "pending"
+	// RoomReportActioned reports led to an action against the room.
+	RoomReportActioned RoomReportStatus = "actioned"
+	// RoomReportDismissed reports were reviewed and found to need no action.
+	RoomReportDismissed RoomReportStatus = "dismissed"
+)
+
+// RoomEnforcement is an action platform admins take against a reported room.
+type RoomEnforcement string
+
+const (
+	// RoomEnforcementNone dismisses a report without acting on the room.
+	RoomEnforcementNone RoomEnforcement = "none"
+	// RoomEnforcementDelist hides the room from discovery. It stays open to anyone who has its link.
+	RoomEnforcementDelist RoomEnforcement = "delist"
+	// RoomEnforcementQuarantine hides the room from discovery and closes it to everyone but its owner and moderators.
+	RoomEnforcementQuarantine RoomEnforcement = "quarantine"
+	// RoomEnforcementClose deactivates the room, sending everyone in it away.
+	RoomEnforcementClose RoomEnforcement = "close"
+)
+
+// RoomReport is a user's report of a whole room, triaged by platform admins rather than the room's moderators.
+type RoomReport struct {
+	// ID is the unique identifier for the report.
+	ID bson.ObjectID `json:"id" bson:"_id"`
+
+	// RoomID is the reported room.
+	RoomID bson.ObjectID `json:"roomId" bson:"roomId"`
+
+	// ReporterID is the user who reported the room.
+	ReporterID bson.ObjectID `json:"reporterId" bson:"reporterId"`
+
+	// Reason is why the room was reported.
+	Reason RoomReportReason `json:"reason" bson:"reason"`
+
+	// Description is the reporter's account of the problem.
+	Description string `json:"description,omitempty" bson:"description,omitempty"`
+
+	// Status is where the report is in triage.
+	Status RoomReportStatus `json:"status" bson:"status"`
+
+	// Action is what was done to the room when the report was resolved.
+	Action RoomEnforcement `json:"action,omitempty" bson:"action,omitempty"`
+
+	// Note is the admin's explanation of the resolution, shared with the reporter.
+	Note string `json:"note,omitempty" bson:"note,omitempty"`
+
+	// ResolvedBy is the admin who resolved the report.
+	ResolvedBy bson.ObjectID `json:"resolvedBy,omitzero" bson:"resolvedBy,omitempty"`
+
+	// ResolvedAt is when the report was resolved.
+	ResolvedAt time.Time `json:"resolvedAt,omitzero" bson:"resolvedAt,omitempty"`
+
+	// ObjectTimes contains timestamps for this report.
+	ObjectTimes
+}
+
+// RoomReportRequest is a user's report of a room.
+type RoomReportRequest struct {
+	// Reason is why the room is reported.
+	Reason RoomReportReason `json:"reason" validate:"required,oneof=illegal_content hate_group harassment spam other"`
+
+	// Description is the reporter's account of the problem.
+	Description string `json:"description" validate:"max=2000"`
+
+	// CaptchaToken is the solved captcha, required when the captcha policy asks for one.
+	CaptchaToken string `json:"captchaToken,omitempty"`
+}
+
+// RoomReportResolution is an admin's decision on a room report.
+type RoomReportResolution struct {
+	// Action is what to do to the room.
+	Action RoomEnforcement `json:"action" validate:"required,oneof=none delist quarantine close"`
+
+	// Note explains the decision to the reporters.
+	Note string `json:"note" validate:"max=1000"`
+}
+
+// RoomReportFilter narrows a listing of room reports. Zero fields match every report.
+type RoomReportFilter struct {
+	// RoomID limits the listing to one room's reports.
+	RoomID bson.ObjectID
+
+	// ReporterID limits the listing to one user's reports.
+	ReporterID bson.ObjectID
+
+	// Status limits the listing to reports in one triage status.
+	Status RoomReportStatus
+}
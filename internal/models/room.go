@@ -2,6 +2,7 @@
 package models
 
 import (
+	"slices"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -30,12 +31,37 @@ type Room struct {
 	// Stats contains the room's statistics.
 	Stats RoomStats `json:"stats" bson:"stats"`
 
-	// Moderators is a list of users who have moderation privileges.
+	// Moderators is a list of users who have moderation privileges. It mirrors the room's co-hosts.
 	Moderators []bson.ObjectID `json:"moderators" bson:"moderators"`
 
+	// Roles are the roles the owner and co-hosts gave users in the room. Everyone else is a user.
+	Roles []RoomRoleAssignment `json:"roles,omitempty" bson:"roles,omitempty"`
+
+	// RolePermissions overrides the permissions of roles, by role. Roles left out have their default permissions.
+	RolePermissions map[string][]string `json:"rolePermissions,omitempty" bson:"rolePermissions,omitempty"`
+
 	// BannedUsers is a list of users who are banned from joining the room.
 	BannedUsers []bson.ObjectID `json:"bannedUsers" bson:"bannedUsers"`
 
+	// QueueLocked stops users without the queue lock permission from joining the DJ queue.
+	QueueLocked bool `json:"queueLocked" bson:"queueLocked,omitempty"`
+
+	// QueueCycleDisabled takes DJs out of the DJ queue once their turn ends, instead of putting them
+	// back at its end.
+	QueueCycleDisabled bool `json:"queueCycleDisabled" bson:"queueCycleDisabled,omitempty"`
+
+	// MaxConsecutivePlays is the number of tracks a DJ plays in a row before their turn passes.
+	// Zero plays one.
+	MaxConsecutivePlays int `json:"maxConsecutivePlays" bson:"maxConsecutivePlays,omitempty"`
+
+	// DJQueue is a copy of the room's DJ queue, written through from the real-time state so the
+	// queue can be rebuilt if the state is lost.
+	DJQueue []StoredQueueEntry `json:"-" bson:"djQueue,omitempty"`
+
+	// InFlightPlay is the track playing in the room, written through from the real-time state when it
+	// starts so it can be resumed or made up for if a server restart loses it.
+	InFlightPlay *InFlightPlay `json:"-" bson:"inFlightPlay,omitempty"`
+
 	// Tags are keywords that describe the room.
 	Tags []string `json:"tags" bson:"tags" validate:"dive,max=20"`
 
@@ -48,6 +74,37 @@ type Room struct {
 	// IsActive indicates whether the room is currently active.
 	IsActive bool `json:"isActive" bson:"isActive"`
 
+	// Delisted hides the room from discovery. Platform admins set it when acting on reports.
+	Delisted bool `json:"delisted,omitempty" bson:"delisted,omitempty"`
+
+	// Quarantined closes the room to everyone but its owner and moderators. Quarantined rooms are also delisted.
+	Quarantined bool `json:"quarantined,omitempty" bson:"quarantined,omitempty"`
+
+	// Verified marks the room as the official room of an artist or label. Only platform admins set it.
+	Verified *VerifiedBadge `json:"verified,omitempty" bson:"verified,omitempty"`
+
+	// Language is the language the room chats in, detected from its chat or set by its owner.
+	Language *RoomLanguage `json:"language,omitempty" bson:"language,omitempty"`
+
+	// Expiry makes the room a pop-up room that is deleted automatically. Nil for permanent rooms.
+	Expiry *RoomExpiry `json:"expiry,omitempty" bson:"expiry,omitempty"`
+
+	// Commands are the room's custom chat commands, defined by its owner.
+	Commands []ChatCommand `json:"commands,omitempty" bson:"commands,omitempty"`
+
+	// ChatModes are the content restrictions moderators put on the room's chat.
+	ChatModes ChatModes `json:"chatModes" bson:"chatModes"`
+
+	// ChatChannels are the named channels moderators added to the room's chat, besides the general channel.
+	ChatChannels []ChatChannel `json:"chatChannels,omitempty" bson:"chatChannels,omitempty"`
+
+	// ChatFilters are the terms and patterns moderators blocked in the room's chat. They are only shown to moderators.
+	ChatFilters []ChatFilter `json:"-" bson:"chatFilters,omitempty"`
+
+	// Analytics configures the owner's analytics event export. Nil until the owner opts in.
+	// It is kept out of the room's JSON, the owner manages it through its own endpoints.
+	Analytics *RoomAnalytics `json:"-" bson:"analytics,omitempty"`
+
 	// ObjectTimes contains timestamps for this room.
 	ObjectTimes
 
@@ -55,6 +112,85 @@ type Room struct {
 	LastActivity time.Time `json:"lastActivity" bson:"lastActivity"`
 }
 
+// RoomAnalytics configures the analytics event export of a room.
+type RoomAnalytics struct {
+	// Enabled turns the export on.
+	Enabled bool `json:"enabled" bson:"enabled"`
+
+	// WebhookURL receives the events in batches. Empty to only export daily files.
+	WebhookURL string `json:"webhookUrl,omitempty" bson:"webhookUrl,omitempty" validate:"omitempty,url,max=2048"`
+
+	// Events limits the export to these event types. Empty exports all of them.
+	Events []string `json:"events,omitempty" bson:"events,omitempty" validate:"dive,oneof=play join leave vote reaction"`
+
+	// Secret signs webhook deliveries and derives the pseudonymous user IDs of the events.
+	// It is generated when the export is first enabled.
+	Secret string `json:"secret,omitempty" bson:"secret,omitempty"`
+}
+
+// RoomExpiry configures when a pop-up room is deleted: a fixed time after creation, after being empty for a while, or both.
+type RoomExpiry struct {
+	// LifetimeHours is how many hours after creation the room is deleted. Zero means no fixed lifetime.
+	LifetimeHours int `json:"lifetimeHours" bson:"lifetimeHours" validate:"min=0"`
+
+	// EmptyMinutes is how many minutes the room may stay empty before it is deleted. Zero means never for being empty.
+	EmptyMinutes int `json:"emptyMinutes" bson:"emptyMinutes" validate:"min=0,max=1440"`
+
+	// ExpiresAt is when the room's lifetime ends.
+	ExpiresAt time.Time `json:"expiresAt,omitzero" bson:"expiresAt,omitempty"`
+
+	// EmptySince is when the room was last seen becoming empty.
+	EmptySince time.Time `json:"emptySince,omitzero" bson:"emptySince,omitempty"`
+
+	// RemindedFor is the deadline users were last reminded of, so each deadline is announced once.
+	RemindedFor time.Time `json:"-" bson:"remindedFor,omitempty"`
+}
+
+// Deadline returns when the room will be deleted if nothing changes, or the zero time if it has no deadline yet.
+func (e *RoomExpiry) Deadline() time.Time {
+	deadline := e.ExpiresAt
+	if e.EmptyMinutes > 0 && !e.EmptySince.IsZero() {
+		emptyDeadline := e.EmptySince.Add(time.Duration(e.EmptyMinutes) * time.Minute)
+		if deadline.IsZero() || emptyDeadline.Before(deadline) {
+			deadline = emptyDeadline
+		}
+	}
+	return deadline
+}
+
+// RoomEvent represents a scheduled event in a room, such as a themed set or a listening party.
+type RoomEvent struct {
+	// ID is the unique identifier for the event.
+	ID bson.ObjectID `json:"id" bson:"_id"`
+
+	// RoomID is the ID of the room the event is held in.
+	RoomID bson.ObjectID `json:"roomId" bson:"roomId"`
+
+	// Title is the display title of the event.
+	Title string `json:"title" bson:"title" validate:"required,min=2,max=100"`
+
+	// Description provides information about the event.
+	Description string `json:"description" bson:"description" validate:"max=1000"`
+
+	// Theme is the musical theme of the event, such as a genre or an era.
+	Theme string `json:"theme,omitempty" bson:"theme,omitempty" validate:"max=100"`
+
+	// Hosts are the IDs of the users lined up to DJ the event.
+	Hosts []bson.ObjectID `json:"hosts,omitempty" bson:"hosts,omitempty" validate:"max=10"`
+
+	// StartTime is when the event starts.
+	StartTime time.Time `json:"startTime" bson:"startTime" validate:"required"`
+
+	// EndTime is when the event ends.
+	EndTime time.Time `json:"endTime" bson:"endTime" validate:"required,gtfield=StartTime"`
+
+	// CreatedBy is the ID of the user who scheduled the event.
+	CreatedBy bson.ObjectID `json:"createdBy" bson:"createdBy"`
+
+	// CreatedAt is when the event was scheduled.
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
 // RoomSettings represents the configuration settings for a room.
 type RoomSettings struct {
 	// Private indicates whether the room is private.
@@ -63,6 +199,17 @@ type RoomSettings struct {
 	// Capacity is the maximum number of users allowed in the room.
 	Capacity int `json:"capacity" bson:"capacity" validate:"min=1,max=1000"`
 
+	// ListenerOverflow is the number of additional listener-only users admitted once the room is at capacity.
+	// Listener-only users cannot chat, join the DJ queue or vote, and are promoted as slots open.
+	ListenerOverflow int `json:"listenerOverflow" bson:"listenerOverflow" validate:"min=0,max=10000"`
+
+	// ChatShardThreshold is the number of users in the main chat beyond which new joiners are put in
+	// overflow chat shards, parallel chats sharing the room's audio and DJ queue. Zero disables shards.
+	ChatShardThreshold int `json:"chatShardThreshold" bson:"chatShardThreshold" validate:"min=0,max=10000"`
+
+	// ChatShardSize is the number of users each overflow chat shard holds. Zero uses the threshold.
+	ChatShardSize int `json:"chatShardSize" bson:"chatShardSize" validate:"min=0,max=10000"`
+
 	// WaitlistMax is the maximum number of users allowed in the DJ waitlist.
 	WaitlistMax int `json:"waitlistMax" bson:"waitlistMax" validate:"min=1,max=100"`
 
@@ -84,6 +231,17 @@ type RoomSettings struct {
 	// ChatDelay is the delay in seconds between chat messages for a user.
 	ChatDelay int `json:"chatDelay" bson:"chatDelay" validate:"min=0,max=60"`
 
+	// ChatBacklog is the number of recent chat messages sent to users joining the room. Zero uses the default.
+	ChatBacklog int `json:"chatBacklog" bson:"chatBacklog" validate:"min=0,max=200"`
+
+	// ChatColors are the hex colors, such as "#ff8800", users can pick for their name in the room's chat.
+	// None disables name colors.
+	ChatColors []string `json:"chatColors,omitempty" bson:"chatColors,omitempty" validate:"omitempty,max=24,unique,dive,hexcolor"`
+
+	// ChatFlairs are the flairs users can pick to show next to their name in the room's chat.
+	// None disables flairs.
+	ChatFlairs []string `json:"chatFlairs,omitempty" bson:"chatFlairs,omitempty" validate:"omitempty,max=24,unique,dive,min=1,max=24"`
+
 	// AutoSkipDisconnect indicates whether to skip disconnected DJs.
 	AutoSkipDisconnect bool `json:"autoSkipDisconnect" bson:"autoSkipDisconnect"`
 
@@ -93,6 +251,43 @@ type RoomSettings struct {
 	// GuestCanJoinQueue indicates whether guests can join the DJ queue.
 	GuestCanJoinQueue bool `json:"guestCanJoinQueue" bson:"guestCanJoinQueue"`
 
+	// VoteGracePeriod is how long in seconds before the current media starts and after it ends votes are still accepted.
+	// Zero uses the default grace period.
+	VoteGracePeriod int `json:"voteGracePeriod" bson:"voteGracePeriod" validate:"min=0,max=60"`
+
+	// SkipVoteCutoff is the percentage of the current media after which users joining the room
+	// no longer count towards skipping it. Their votes are still shown. Zero counts every user.
+	SkipVoteCutoff int `json:"skipVoteCutoff" bson:"skipVoteCutoff" validate:"min=0,max=100"`
+
+	// SkipThreshold is the percentage of the users in the room whose meh votes, once exceeded, skip
+	// the current media. Zero never skips on votes.
+	SkipThreshold int `json:"skipThreshold" bson:"skipThreshold" validate:"min=0,max=100"`
+
+	// CrowdPicks indicates whether the recent track the room grabbed most is replayed between DJ turns
+	// every CrowdPickInterval tracks.
+	CrowdPicks bool `json:"crowdPicks" bson:"crowdPicks"`
+
+	// CrowdPickInterval is the number of DJ tracks between crowd picks. Zero uses the default.
+	CrowdPickInterval int `json:"crowdPickInterval" bson:"crowdPickInterval" validate:"min=0,max=40"`
+
+	// ChatSensitivity is how readily automated moderation flags and masks chat messages:
+	// "off", "low", "medium" or "high". Empty uses medium.
+	ChatSensitivity string `json:"chatSensitivity,omitempty" bson:"chatSensitivity,omitempty" validate:"omitempty,oneof=off low medium high"`
+
+	// NormalizeVolume indicates whether now-playing media carries volume normalization hints.
+	NormalizeVolume bool `json:"normalizeVolume" bson:"normalizeVolume"`
+
+	// AllowAgeRestricted marks the room as 18+: age-restricted media can be played in it, and only users
+	// who attested being adults can join it.
+	AllowAgeRestricted bool `json:"allowAgeRestricted" bson:"allowAgeRestricted"`
+
+	// VoteLabels renames the woot, meh and grab votes shown in the room, by vote type. Votes are still
+	// cast and counted under their canonical types, so stats compare across rooms.
+	VoteLabels map[string]string `json:"voteLabels,omitempty" bson:"voteLabels,omitempty" validate:"omitempty,dive,keys,oneof=woot meh grab,endkeys,min=1,max=20"`
+
+	// Reactions are the extra reaction types enabled in the room, which users can add next to their vote.
+	Reactions []string `json:"reactions,omitempty" bson:"reactions,omitempty" validate:"omitempty,unique,dive,oneof=fire laugh"`
+
 	// PasswordProtected indicates whether a password is required to join.
 	PasswordProtected bool `json:"passwordProtected" bson:"passwordProtected"`
 
@@ -100,6 +295,22 @@ type RoomSettings struct {
 	Password string `json:"-" bson:"password,omitempty"`
 }
 
+// Vote types. Rooms can relabel them, but they are always cast and counted under these names.
+const (
+	VoteWoot = "woot"
+	VoteMeh  = "meh"
+	VoteGrab = "grab"
+)
+
+// Reaction types rooms can enable next to the votes. Unlike votes, a user can add several of them.
+const (
+	ReactionFire  = "fire"
+	ReactionLaugh = "laugh"
+)
+
+// ReactionTypes lists every reaction type rooms can enable.
+var ReactionTypes = []string{ReactionFire, ReactionLaugh}
+
 // RoomStats represents the statistics for a room.
 type RoomStats struct {
 	// TotalPlays is the total number of tracks played in the room.
@@ -129,10 +340,150 @@ type RoomStats struct {
 	// AggregateRating is the overall rating of the room.
 	AggregateRating float64 `json:"aggregateRating" bson:"aggregateRating"`
 
+	// Heat measures how lively the room is right now, from recent joins, retention, chat and votes.
+	Heat float64 `json:"heat" bson:"heat"`
+
 	// LastStatsReset is the time when the stats were last reset.
 	LastStatsReset time.Time `json:"lastStatsReset" bson:"lastStatsReset"`
 }
 
+// Room roles, from most to least privileged.
+const (
+	RoomRoleOwner      = "owner"
+	RoomRoleCoHost     = "cohost"
+	RoomRoleResidentDJ = "resident_dj"
+	RoomRoleVIP        = "vip"
+	RoomRoleUser       = "user"
+)
+
+// RoomRoleRanks orders the room roles. Users can only manage users whose role ranks below theirs.
+var RoomRoleRanks = map[string]int{
+	RoomRoleUser:       0,
+	RoomRoleVIP:        1,
+	RoomRoleResidentDJ: 2,
+	RoomRoleCoHost:     3,
+	RoomRoleOwner:      4,
+}
+
+// Room permissions, granted to roles.
+const (
+	// RoomPermissionSkip allows skipping the playing track of any DJ.
+	RoomPermissionSkip = "skip"
+
+	// RoomPermissionQueueLock allows locking the DJ queue, and joining it while locked.
+	RoomPermissionQueueLock = "queue_lock"
+
+	// RoomPermissionChatDelete allows deleting anyone's chat messages.
+	RoomPermissionChatDelete = "chat_delete"
+
+	// RoomPermissionBan allows banning users of lower roles from the room.
+	RoomPermissionBan = "ban"
+)
+
+// RoomPermissions are all the permissions roles can be granted. The owner always has every one.
+var RoomPermissions = []string{RoomPermissionSkip, RoomPermissionQueueLock, RoomPermissionChatDelete, RoomPermissionBan}
+
+// DefaultRoomRolePermissions are the permissions of the roles in rooms whose owner didn't change them.
+var DefaultRoomRolePermissions = map[string][]string{
+	RoomRoleCoHost:     {RoomPermissionSkip, RoomPermissionQueueLock, RoomPermissionChatDelete, RoomPermissionBan},
+	RoomRoleResidentDJ: {RoomPermissionSkip},
+	RoomRoleVIP:        {},
+	RoomRoleUser:       {},
+}
+
+// RoomRoleAssignment is the role a user was given in a room.
+type RoomRoleAssignment struct {
+	// UserID is the ID of the user.
+	UserID bson.ObjectID `json:"userId" bson:"userId"`
+
+	// Role is the user's role in the room. It is never the owner or user role.
+	Role string `json:"role" bson:"role"`
+
+	// AssignedBy is the ID of the user who gave the role.
+	AssignedBy bson.ObjectID `json:"assignedBy" bson:"assignedBy"`
+
+	// AssignedAt is when the role was given.
+	AssignedAt time.Time `json:"assignedAt" bson:"assignedAt"`
+}
+
+// RoomRoles are the roles given in a room and what each role is allowed to do.
+type RoomRoles struct {
+	// OwnerID is the ID of the room's owner.
+	OwnerID bson.ObjectID `json:"ownerId"`
+
+	// Assignments are the roles given to users.
+	Assignments []RoomRoleAssignment `json:"assignments"`
+
+	// Permissions are the permissions of each role, by role.
+	Permissions map[string][]string `json:"permissions"`
+}
+
+// RoleOf returns a user's role in the room. Moderators added before roles existed are co-hosts.
+func (r *Room) RoleOf(userID bson.ObjectID) string {
+	if r.CreatedBy == userID {
+		return RoomRoleOwner
+	}
+	for _, assignment := range r.Roles {
+		if assignment.UserID == userID {
+			return assignment.Role
+		}
+	}
+	if slices.Contains(r.Moderators, userID) {
+		return RoomRoleCoHost
+	}
+	return RoomRoleUser
+}
+
+// PermissionsOf returns the permissions of a role in the room.
+func (r *Room) PermissionsOf(role string) []string {
+	if role == RoomRoleOwner {
+		return RoomPermissions
+	}
+	if permissions, ok := r.RolePermissions[role]; ok {
+		return permissions
+	}
+	return DefaultRoomRolePermissions[role]
+}
+
+// PermissionMatrix returns the permissions of every role in the room, by role.
+func (r *Room) PermissionMatrix() map[string][]string {
+	matrix := make(map[string][]string, len(RoomRoleRanks))
+	for role := range RoomRoleRanks {
+		matrix[role] = slices.Clone(r.PermissionsOf(role))
+	}
+	return matrix
+}
+
+// QueueOptions returns the options of the room's DJ queue.
+func (r *Room) QueueOptions() QueueOptions {
+	return QueueOptions{
+		Locked:              r.QueueLocked,
+		Cycle:               !r.QueueCycleDisabled,
+		MaxConsecutivePlays: max(r.MaxConsecutivePlays, 1),
+	}
+}
+
+// Can checks whether a user's role in the room grants a permission.
+func (r *Room) Can(userID bson.ObjectID, permission string) bool {
+	return slices.Contains(r.PermissionsOf(r.RoleOf(userID)), permission)
+}
+
+// Staff returns the IDs of the owner and co-hosts of the room.
+func (r *Room) Staff() []bson.ObjectID {
+	staff := []bson.ObjectID{r.CreatedBy}
+	for _, userID := range r.Moderators {
+		if !slices.Contains(staff, userID) && r.RoleOf(userID) == RoomRoleCoHost {
+			staff = append(staff, userID)
+		}
+	}
+	for _, assignment := range r.Roles {
+		if assignment.Role == RoomRoleCoHost && !slices.Contains(staff, assignment.UserID) {
+			staff = append(staff, assignment.UserID)
+		}
+	}
+	return staff
+}
+
 // RoomUser represents a user's status within a room.
 type RoomUser struct {
 	// ID is a unique identifier for this room-user relationship.
@@ -167,6 +518,18 @@ type RoomUser struct {
 
 	// IsDJ indicates whether the user is currently a DJ.
 	IsDJ bool `json:"isDJ" bson:"isDJ"`
+
+	// ChatAppearance is how the user's name shows in the room's chat, if they customized it.
+	ChatAppearance *ChatAppearance `json:"chatAppearance,omitempty" bson:"chatAppearance,omitempty"`
+}
+
+// ChatAppearance is how a user's name shows in a room's chat, picked from what the room allows.
+type ChatAppearance struct {
+	// Color is the color of the user's name, one of the room's chat colors.
+	Color string `json:"color,omitempty" bson:"color,omitempty"`
+
+	// Flair is shown next to the user's name, one of the room's chat flairs.
+	Flair string `json:"flair,omitempty" bson:"flair,omitempty"`
 }
 
 // RoomState represents the real-time state of a room.
@@ -193,8 +556,38 @@ type RoomState struct {
 	ActiveUsers int `json:"activeUsers"`
 
 	// Users is the list of users currently in the room.
+	// In summary mode it only holds the top of the roster: staff, the current DJ and then other users.
 	Users []PublicUser `json:"users"`
 
+	// StateMode indicates whether the state lists every user or is summarized for an oversized room.
+	StateMode RoomStateMode `json:"stateMode"`
+
+	// OverflowListeners is the number of listener-only users admitted beyond the room's capacity.
+	OverflowListeners int `json:"overflowListeners"`
+
+	// ListenerOnly indicates whether the requesting user is in the room in listener-only mode.
+	ListenerOnly bool `json:"listenerOnly"`
+
+	// ChatShard is the overflow chat shard the requesting user chats in, 0 for the main chat.
+	ChatShard int `json:"chatShard"`
+
+	// Expiry is the pop-up room's expiry configuration. Nil for permanent rooms.
+	Expiry *RoomExpiry `json:"expiry,omitempty"`
+
+	// ExpiresAt is when the pop-up room will be deleted if nothing changes.
+	ExpiresAt time.Time `json:"expiresAt,omitzero"`
+
+	// PinnedMessages are the messages pinned by the room's moderators, oldest pin first.
+	// They are only included in the join payload.
+	PinnedMessages []ChatMessage `json:"pinnedMessages,omitempty"`
+
+	// ChatBacklog are the room's most recent chat messages, most recent first. They are only included in
+	// the join payload, and streamed after it in progressive joins.
+	ChatBacklog []ChatMessage `json:"chatBacklog,omitempty"`
+
+	// JoinStream identifies the chunks that complete a progressive join payload.
+	JoinStream string `json:"joinStream,omitempty"`
+
 	// MediaStartTime is the time when the current media started playing.
 	MediaStartTime time.Time `json:"mediaStartTime"`
 
@@ -208,6 +601,61 @@ type RoomState struct {
 	PlayHistory []PlayHistoryEntry `json:"playHistory"`
 }
 
+// PlaybackSync is where a room's current media is by the server's clock, for clients to correct the drift
+// of their own playback.
+type PlaybackSync struct {
+	// RoomID is the ID of the room.
+	RoomID string `json:"roomId"`
+
+	// MediaID is the ID of the current media, empty when nothing plays.
+	MediaID string `json:"mediaId,omitempty"`
+
+	// ServerTime is the server's time when the sync was taken.
+	ServerTime time.Time `json:"serverTime"`
+
+	// Elapsed is how far into the current media playback is, in milliseconds.
+	Elapsed int64 `json:"elapsedMs"`
+
+	// MediaStartTime is the time when the current media started playing.
+	MediaStartTime time.Time `json:"mediaStartTime,omitzero"`
+
+	// MediaEndTime is the expected time when the current media will end.
+	MediaEndTime time.Time `json:"mediaEndTime,omitzero"`
+}
+
+// NewPlaybackSync takes a playback sync of a room's current media at a time.
+func NewPlaybackSync(roomID, mediaID string, startTime, endTime, now time.Time) PlaybackSync {
+	playback := PlaybackSync{RoomID: roomID, ServerTime: now}
+	if mediaID == "" || startTime.IsZero() {
+		return playback
+	}
+
+	playback.MediaID = mediaID
+	playback.MediaStartTime = startTime
+	playback.MediaEndTime = endTime
+
+	elapsed := max(now.Sub(startTime), 0)
+	if !endTime.IsZero() {
+		elapsed = min(elapsed, endTime.Sub(startTime))
+	}
+	playback.Elapsed = elapsed.Milliseconds()
+	return playback
+}
+
+// RoomStateMode describes how much of a room's roster is included in its state.
+type RoomStateMode string
+
+const (
+	// RoomStateModeFull lists every user in the room.
+	RoomStateModeFull RoomStateMode = "full"
+
+	// RoomStateModeSummary lists only the top of the roster; clients should rely on the counts.
+	RoomStateModeSummary RoomStateMode = "summary"
+
+	// RoomStateModeProgressive lists no users; the roster, DJ queue and chat backlog follow in join chunks.
+	RoomStateModeProgressive RoomStateMode = "progressive"
+)
+
 // QueueEntry represents a user in the DJ queue.
 type QueueEntry struct {
 	// User is the user in the queue.
@@ -224,6 +672,79 @@ type QueueEntry struct {
 
 	// JoinedAt is the time the user joined the room.
 	JoinedAt time.Time `json:"joinedAt"`
+
+	// WaitingSince is when the user started waiting for their next turn: when they joined the queue
+	// or when their previous turn ended.
+	WaitingSince time.Time `json:"waitingSince"`
+
+	// PlannedCount is the number of tracks the user planned to play next. The tracks themselves are private.
+	PlannedCount int `json:"plannedCount"`
+
+	// TurnPlays is the number of tracks the user played in their current or last turn.
+	TurnPlays int `json:"turnPlays"`
+}
+
+// Stored returns the entry as it is persisted with the room.
+func (e QueueEntry) Stored() StoredQueueEntry {
+	return StoredQueueEntry{
+		UserID:       e.User.ID,
+		Position:     e.Position,
+		JoinTime:     e.JoinTime,
+		PlayCount:    e.PlayCount,
+		JoinedAt:     e.JoinedAt,
+		WaitingSince: e.WaitingSince,
+	}
+}
+
+// InFlightPlay is a track playing in a room, as persisted with the room.
+type InFlightPlay struct {
+	// ID identifies this play of the track, so it is recovered once.
+	ID bson.ObjectID `bson:"id"`
+
+	// DJ is the user playing the track.
+	DJ PublicUser `bson:"dj"`
+
+	// Media is the track being played.
+	Media MediaInfo `bson:"media"`
+
+	// StartTime is when the track started playing.
+	StartTime time.Time `bson:"startTime"`
+
+	// ExpectedEnd is when the track should end.
+	ExpectedEnd time.Time `bson:"expectedEnd"`
+}
+
+// QueueOptions are the options of a room's DJ queue.
+type QueueOptions struct {
+	// Locked stops users without the queue lock permission from joining the queue.
+	Locked bool `json:"locked"`
+
+	// Cycle puts DJs back at the end of the queue once their turn ends. Otherwise they leave the queue.
+	Cycle bool `json:"cycle"`
+
+	// MaxConsecutivePlays is the number of tracks a DJ plays in a row before their turn passes.
+	MaxConsecutivePlays int `json:"maxConsecutivePlays" validate:"min=1,max=10"`
+}
+
+// StoredQueueEntry is a user's place in a room's DJ queue, as persisted with the room.
+type StoredQueueEntry struct {
+	// UserID is the ID of the user in the queue.
+	UserID bson.ObjectID `json:"userId" bson:"userId"`
+
+	// Position is the user's position in the queue.
+	Position int `json:"position" bson:"position"`
+
+	// JoinTime is the time the user joined the queue.
+	JoinTime time.Time `json:"joinTime" bson:"joinTime"`
+
+	// PlayCount is the number of tracks the user has played since joining the queue.
+	PlayCount int `json:"playCount" bson:"playCount"`
+
+	// JoinedAt is the time the user joined the room.
+	JoinedAt time.Time `json:"joinedAt" bson:"joinedAt"`
+
+	// WaitingSince is when the user started waiting for their next turn.
+	WaitingSince time.Time `json:"waitingSince" bson:"waitingSince"`
 }
 
 // PlayHistoryEntry represents a previously played track.
@@ -245,6 +766,9 @@ type PlayHistoryEntry struct {
 
 	// Grabs is the number of users who added the media to their playlists.
 	Grabs int `json:"grabs"`
+
+	// Reactions is the number of each extra reaction the media received.
+	Reactions map[string]int `json:"reactions,omitempty"`
 }
 
 // RoomCreateRequest represents the data needed to create a new room.
@@ -260,6 +784,9 @@ type RoomCreateRequest struct {
 
 	// Tags are keywords that describe the room.
 	Tags []string `json:"tags" validate:"dive,max=20"`
+
+	// Expiry makes the room a pop-up room that is deleted automatically.
+	Expiry *RoomExpiry `json:"expiry,omitempty"`
 }
 
 // RoomUpdateRequest represents the data needed to update a room.
@@ -283,6 +810,58 @@ type RoomJoinRequest struct {
 	Password string `json:"password,omitempty"`
 }
 
+// JoinAvailability reports whether a user can join a room right now, and the capacity that applies.
+type JoinAvailability struct {
+	// CanJoin indicates whether joining the room would succeed right now.
+	CanJoin bool `json:"canJoin"`
+
+	// Reason is why the user can't join: room_full, queued, banned, closed, quarantined or adults_only. Empty when they can.
+	Reason string `json:"reason,omitempty"`
+
+	// Constrained indicates whether this server reduced room capacities because of its load.
+	Constrained bool `json:"constrained"`
+
+	// Users is the number of participants in the room.
+	Users int `json:"users"`
+
+	// Listeners is the number of overflow listeners in the room.
+	Listeners int `json:"listeners"`
+
+	// Capacity is the room's configured participant capacity.
+	Capacity int `json:"capacity"`
+
+	// EffectiveCapacity is the participant capacity open to joins, reduced while constrained.
+	EffectiveCapacity int `json:"effectiveCapacity"`
+
+	// ListenerOverflow is the room's configured overflow listener capacity.
+	ListenerOverflow int `json:"listenerOverflow"`
+
+	// EffectiveListenerOverflow is the overflow listener capacity open to joins, reduced while constrained.
+	EffectiveListenerOverflow int `json:"effectiveListenerOverflow"`
+
+	// QueuePosition is the user's place in the room's join queue while constrained, starting at 1.
+	QueuePosition int `json:"queuePosition,omitempty"`
+
+	// RetryAfter is how many seconds a queued user should wait before trying again.
+	RetryAfter int `json:"retryAfter,omitempty"`
+}
+
+// RoomLanguage is the language a room chats in.
+type RoomLanguage struct {
+	// Code is the ISO 639-1 code of the language.
+	Code string `json:"code" bson:"code"`
+
+	// Share is the share of the room's recent chat messages in the language, from 0 to 1.
+	// It is zero for languages set by the owner.
+	Share float64 `json:"share,omitempty" bson:"share,omitempty"`
+
+	// Manual marks a language set by the room's owner, which detection leaves alone.
+	Manual bool `json:"manual,omitempty" bson:"manual,omitempty"`
+
+	// UpdatedAt is when the language was last detected or set.
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
 // RoomSearchCriteria represents the criteria for searching rooms.
 type RoomSearchCriteria struct {
 	// Query is the search query.
@@ -291,6 +870,9 @@ type RoomSearchCriteria struct {
 	// Tags are the tags to filter by.
 	Tags []string `json:"tags"`
 
+	// Language is the ISO 639-1 code of the language rooms must chat in.
+	Language string `json:"language"`
+
 	// OnlyActive indicates whether to show only active rooms.
 	OnlyActive bool `json:"onlyActive"`
 
@@ -303,7 +885,7 @@ type RoomSearchCriteria struct {
 	// MaxUsers is the maximum number of users.
 	MaxUsers int `json:"maxUsers"`
 
-	// SortBy is the field to sort by.
+	// SortBy is the field to sort by: name, created, active, users, popularity or heat.
 	SortBy string `json:"sortBy"`
 
 	// SortDirection is the direction to sort (asc or desc).
@@ -0,0 +1,88 @@
+// Package models contains the data structures used throughout the application.
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ScrobbleStatus is where a scrobble is in its submission.
+type ScrobbleStatus string
+
+const (
+	// ScrobbleStatusPending scrobbles are waiting to be submitted.
+	ScrobbleStatusPending ScrobbleStatus = "pending"
+	// ScrobbleStatusSubmitted scrobbles were accepted by the scrobbling service.
+	ScrobbleStatusSubmitted ScrobbleStatus = "submitted"
+	// ScrobbleStatusIgnored scrobbles were received but not counted by the scrobbling service.
+	ScrobbleStatusIgnored ScrobbleStatus = "ignored"
+	// ScrobbleStatusFailed scrobbles were given up on.
+	ScrobbleStatusFailed ScrobbleStatus = "failed"
+)
+
+// ScrobbleAccount is a user's account on a Last.fm compatible scrobbling service.
+type ScrobbleAccount struct {
+	// ID is the unique identifier for the linked account.
+	ID bson.ObjectID `json:"id" bson:"_id"`
+
+	// UserID is the user who linked the account. A user links at most one account.
+	UserID bson.ObjectID `json:"userId" bson:"userId"`
+
+	// Username is the user's name on the scrobbling service.
+	Username string `json:"username" bson:"username"`
+
+	// SessionKey authorizes submitting scrobbles on the user's behalf. It never leaves the server.
+	SessionKey string `json:"-" bson:"sessionKey"`
+
+	// Enabled is whether the user's plays are scrobbled.
+	Enabled bool `json:"enabled" bson:"enabled"`
+
+	// ObjectTimes contains timestamps for this account.
+	ObjectTimes
+}
+
+// Scrobble is a play a user listened to, submitted to their scrobbling account.
+type Scrobble struct {
+	// ID is the unique identifier for the scrobble.
+	ID bson.ObjectID `json:"id" bson:"_id"`
+
+	// UserID is the user who listened to the play.
+	UserID bson.ObjectID `json:"userId" bson:"userId"`
+
+	// RoomID is the room the play was in.
+	RoomID bson.ObjectID `json:"roomId" bson:"roomId"`
+
+	// PlayID is the play history record of the play.
+	PlayID bson.ObjectID `json:"playId" bson:"playId"`
+
+	// MediaID is the media played.
+	MediaID bson.ObjectID `json:"mediaId" bson:"mediaId"`
+
+	// Artist is the artist submitted.
+	Artist string `json:"artist" bson:"artist"`
+
+	// Track is the track title submitted.
+	Track string `json:"track" bson:"track"`
+
+	// Duration is the length of the track in seconds.
+	Duration int `json:"duration" bson:"duration"`
+
+	// PlayedAt is when the track started playing.
+	PlayedAt time.Time `json:"playedAt" bson:"playedAt"`
+
+	// Status is where the scrobble is in its submission.
+	Status ScrobbleStatus `json:"status" bson:"status"`
+
+	// Attempts is how many times the scrobble was submitted.
+	Attempts int `json:"attempts" bson:"attempts"`
+
+	// Error is why the last attempt failed or was ignored.
+	Error string `json:"error,omitempty" bson:"error,omitempty"`
+
+	// SubmittedAt is when the scrobbling service accepted or ignored the scrobble.
+	SubmittedAt time.Time `json:"submittedAt,omitzero" bson:"submittedAt,omitempty"`
+
+	// ObjectTimes contains timestamps for this scrobble.
+	ObjectTimes
+}
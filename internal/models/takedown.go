@@ -0,0 +1,109 @@
+// Package models contains the data structures used throughout the application.
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// TakedownReason is the kind of complaint media was taken down for.
+type TakedownReason string
+
+const (
+	// TakedownDMCA is for DMCA notices.
+	TakedownDMCA TakedownReason = "dmca"
+	// TakedownCopyright is for other copyright complaints.
+	TakedownCopyright TakedownReason = "copyright"
+	// TakedownTrademark is for trademark complaints.
+	TakedownTrademark TakedownReason = "trademark"
+	// TakedownOther is for any other legal complaint.
+	TakedownOther TakedownReason = "other"
+)
+
+// MediaTakedown is a platform admin's takedown of a media source after a DMCA or other content complaint.
+// Taken down sources can't be resolved, added to playlists or played anymore, and the playlist items
+// holding them are flagged for their owners rather than silently removed.
+type MediaTakedown struct {
+	// ID is the unique identifier for the takedown.
+	ID bson.ObjectID `json:"id" bson:"_id"`
+
+	// MediaID is the stored media item taken down, zero when the source was never stored.
+	MediaID bson.ObjectID `json:"mediaId,omitzero" bson:"mediaId,omitempty"`
+
+	// Type is the source type of the media taken down.
+	Type string `json:"type" bson:"type"`
+
+	// SourceID is the ID of the media on its platform.
+	SourceID string `json:"sourceId" bson:"sourceId"`
+
+	// Title is the title of the media when it was taken down.
+	Title string `json:"title,omitempty" bson:"title,omitempty"`
+
+	// Reason is the kind of complaint the media was taken down for.
+	Reason TakedownReason `json:"reason" bson:"reason"`
+
+	// Complainant is who filed the complaint, such as the rights holder or their agent.
+	Complainant string `json:"complainant,omitempty" bson:"complainant,omitempty"`
+
+	// Details are the admin's notes on the complaint, such as its reference number.
+	Details string `json:"details,omitempty" bson:"details,omitempty"`
+
+	// TakenDownBy is the admin who took the media down.
+	TakenDownBy bson.ObjectID `json:"takenDownBy" bson:"takenDownBy"`
+
+	// Playlists is the number of playlists that held the media when it was taken down.
+	Playlists int `json:"playlists" bson:"playlists"`
+
+	// Items is the number of playlist items flagged by the takedown.
+	Items int `json:"items" bson:"items"`
+
+	// ObjectTimes contains timestamps for this takedown.
+	ObjectTimes
+}
+
+// MediaTakedownRequest is an admin's takedown of a media source, given by its stored media item or its URL.
+type MediaTakedownRequest struct {
+	// MediaID is the stored media item to take down.
+	MediaID bson.ObjectID `json:"mediaId"`
+
+	// URL is the media's URL on its platform, for sources that may not be stored yet.
+	URL string `json:"url" validate:"omitempty,url,max=2048"`
+
+	// Reason is the kind of complaint the media is taken down for.
+	Reason TakedownReason `json:"reason" validate:"required,oneof=dmca copyright trademark other"`
+
+	// Complainant is who filed the complaint.
+	Complainant string `json:"complainant" validate:"max=200"`
+
+	// Details are the admin's notes on the complaint.
+	Details string `json:"details" validate:"max=2000"`
+}
+
+// MediaTakedownFilter narrows a listing of takedowns. Zero fields match every takedown.
+type MediaTakedownFilter struct {
+	// Type limits the listing to one source type.
+	Type string
+
+	// SourceID limits the listing to the takedowns of one source.
+	SourceID string
+
+	// Reason limits the listing to one kind of complaint.
+	Reason TakedownReason
+}
+
+// TakenDownItems tells a playlist owner which items of a playlist were flagged by a takedown.
+type TakenDownItems struct {
+	// OwnerID is the owner of the playlist.
+	OwnerID bson.ObjectID `json:"ownerId"`
+
+	// PlaylistID is the playlist holding the items.
+	PlaylistID bson.ObjectID `json:"playlistId"`
+
+	// PlaylistName is the name of the playlist.
+	PlaylistName string `json:"playlistName"`
+
+	// ItemIDs are the flagged items.
+	ItemIDs []bson.ObjectID `json:"itemIds"`
+
+	// Takedown is the takedown that flagged them.
+	Takedown *MediaTakedown `json:"takedown"`
+}
@@ -0,0 +1,108 @@
+// Package models contains the data structures used throughout the application.
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// MessageTemplate is one version of an outbound email or long-form notification, in one locale.
+// The subject and text body are Go text templates and the HTML body is a Go HTML template, all
+// executed with the data of the message being sent.
+type MessageTemplate struct {
+	// ID is the unique identifier for the template version.
+	ID bson.ObjectID `json:"id" bson:"_id"`
+
+	// Name identifies the message, such as "email_verification".
+	Name string `json:"name" bson:"name"`
+
+	// Locale is the language the template is written in, such as "en" or "pt-BR".
+	Locale string `json:"locale" bson:"locale"`
+
+	// Version counts the versions of the template in its locale, starting at 1.
+	// Templates loaded from disk have version 0.
+	Version int `json:"version" bson:"version"`
+
+	// Subject is the template of the subject line.
+	Subject string `json:"subject" bson:"subject"`
+
+	// Text is the template of the plain text body.
+	Text string `json:"text" bson:"text"`
+
+	// HTML is the template of the HTML body, empty to send plain text only.
+	HTML string `json:"html,omitempty" bson:"html,omitempty"`
+
+	// Active marks the version sent in its locale. At most one version per locale is active.
+	Active bool `json:"active" bson:"active"`
+
+	// CreatedBy is the admin who wrote the version.
+	CreatedBy bson.ObjectID `json:"createdBy,omitzero" bson:"createdBy,omitempty"`
+
+	// ObjectTimes contains timestamps for this template version.
+	ObjectTimes
+}
+
+// MessageTemplateFilter narrows down the message templates listed.
+type MessageTemplateFilter struct {
+	// Name only lists the versions of one template.
+	Name string
+
+	// Locale only lists templates in one locale.
+	Locale string
+
+	// ActiveOnly only lists the versions being sent.
+	ActiveOnly bool
+}
+
+// MessageTemplateRequest is a new version of a message template.
+type MessageTemplateRequest struct {
+	// Locale is the language the version is written in.
+	Locale string `json:"locale" validate:"required,max=10"`
+
+	// Subject is the template of the subject line.
+	Subject string `json:"subject" validate:"required,max=500"`
+
+	// Text is the template of the plain text body.
+	Text string `json:"text" validate:"required,max=50000"`
+
+	// HTML is the template of the HTML body.
+	HTML string `json:"html" validate:"max=200000"`
+
+	// Activate makes the new version the one sent in its locale.
+	Activate bool `json:"activate"`
+}
+
+// MessageTemplatePreview asks for a message template rendered with sample data.
+type MessageTemplatePreview struct {
+	// Locale is the language the message is rendered in, falling back like a sent message would.
+	Locale string `json:"locale" validate:"max=10"`
+
+	// Version is the version rendered, 0 for the one being sent.
+	Version int `json:"version" validate:"min=0"`
+
+	// Data is the sample data the template is executed with.
+	Data map[string]any `json:"data"`
+
+	// To is where a test message is sent. Previews ignore it.
+	To string `json:"to" validate:"omitempty,email"`
+}
+
+// RenderedMessage is a message template executed with the data of one message.
+type RenderedMessage struct {
+	// Name is the template rendered.
+	Name string `json:"name"`
+
+	// Locale is the locale of the template rendered, which may be a fallback of the locale asked for.
+	Locale string `json:"locale"`
+
+	// Version is the version of the template rendered.
+	Version int `json:"version"`
+
+	// Subject is the subject line.
+	Subject string `json:"subject"`
+
+	// Text is the plain text body.
+	Text string `json:"text"`
+
+	// HTML is the HTML body, empty for plain text messages.
+	HTML string `json:"html,omitempty"`
+}
@@ -0,0 +1,79 @@
+// Package models contains the data structures used throughout the application.
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TrustLevel represents how far a user has progressed in the automatic trust system.
+// Higher levels unlock abilities that are restricted for new accounts.
+type TrustLevel int
+
+const (
+	// TrustLevelNew is the level of freshly registered accounts.
+	TrustLevelNew TrustLevel = iota
+	// TrustLevelBasic is the level of accounts that have spent some time on the platform.
+	TrustLevelBasic
+	// TrustLevelMember is the level of established accounts.
+	TrustLevelMember
+	// TrustLevelRegular is the level of long-standing accounts with a clean record.
+	TrustLevelRegular
+)
+
+// trustLevelNames maps trust levels to their names.
+var trustLevelNames = map[TrustLevel]string{
+	TrustLevelNew:     "new",
+	TrustLevelBasic:   "basic",
+	TrustLevelMember:  "member",
+	TrustLevelRegular: "regular",
+}
+
+// String returns the name of the trust level.
+func (l TrustLevel) String() string {
+	if name, ok := trustLevelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("TrustLevel(%d)", int(l))
+}
+
+// MarshalText encodes the trust level as its name.
+func (l TrustLevel) MarshalText() ([]byte, error) {
+	if _, ok := trustLevelNames[l]; !ok {
+		return nil, fmt.Errorf("invalid trust level %d", int(l))
+	}
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText decodes a trust level from its name.
+func (l *TrustLevel) UnmarshalText(text []byte) error {
+	level, err := ParseTrustLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// ParseTrustLevel parses a trust level name.
+func ParseTrustLevel(name string) (TrustLevel, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for level, levelName := range trustLevelNames {
+		if levelName == name {
+			return level, nil
+		}
+	}
+	return TrustLevelNew, fmt.Errorf("unknown trust level %q", name)
+}
+
+// TrustAbility represents a feature that is unlocked by a minimum trust level.
+type TrustAbility string
+
+const (
+	// TrustAbilityPostLinks allows posting links in chat.
+	TrustAbilityPostLinks TrustAbility = "post_links"
+	// TrustAbilityCreateRoom allows creating rooms.
+	TrustAbilityCreateRoom TrustAbility = "create_room"
+	// TrustAbilityQueueLongTracks allows playing tracks longer than the configured limit.
+	TrustAbilityQueueLongTracks TrustAbility = "queue_long_tracks"
+)
@@ -29,6 +29,9 @@ type BaseUser struct {
 
 	// Roles contains the user's roles.
 	Roles []string `json:"roles" bson:"roles"`
+
+	// Verified marks the user as a verified artist or label. Only platform admins set it.
+	Verified *VerifiedBadge `json:"verified,omitempty" bson:"verified,omitempty"`
 }
 
 // User represents a user in the application.
@@ -57,10 +60,63 @@ type User struct {
 	// LastLogin is the time of the user's last login.
 	LastLogin time.Time `json:"lastLogin" bson:"lastLogin"`
 
+	// Trust contains the user's trust record.
+	Trust UserTrust `json:"trust" bson:"trust"`
+
+	// APIKeys are the user's personal API keys.
+	APIKeys []APIKey `json:"-" bson:"apiKeys,omitempty"`
+
+	// Recovery contains pending account recovery actions started by support.
+	Recovery UserRecovery `json:"-" bson:"recovery,omitempty"`
+
+	// Guest is set on accounts created for guests listening without registering.
+	Guest *GuestAccount `json:"guest,omitempty" bson:"guest,omitempty"`
+
+	// AgeAttestation is the user's own statement of their date of birth, unset until they give it.
+	AgeAttestation *AgeAttestation `json:"ageAttestation,omitempty" bson:"ageAttestation,omitempty"`
+
+	// OAuthIdentities are the third-party accounts the user can sign in with.
+	OAuthIdentities []OAuthIdentity `json:"-" bson:"oauthIdentities,omitempty"`
+
+	// UsernameHistory are the user's previous usernames, oldest first.
+	UsernameHistory []UsernameChange `json:"-" bson:"usernameHistory,omitempty"`
+
+	// Deletion is set while the user's account is scheduled for deletion.
+	Deletion *AccountDeletion `json:"deletion,omitempty" bson:"deletion,omitempty"`
+
 	// ObjectTimes contains timestamps for this user.
 	ObjectTimes
 }
 
+// UsernameChange records a username a user renamed away from.
+type UsernameChange struct {
+	// Username is the username the user had.
+	Username string `json:"username" bson:"username"`
+
+	// ChangedAt is when the user renamed away from it.
+	ChangedAt time.Time `json:"changedAt" bson:"changedAt"`
+}
+
+// LastRenamed is when the user last changed their username, zero if they never did.
+func (u *User) LastRenamed() time.Time {
+	if len(u.UsernameHistory) == 0 {
+		return time.Time{}
+	}
+	return u.UsernameHistory[len(u.UsernameHistory)-1].ChangedAt
+}
+
+// DeletedUsername is shown in place of the username of a deleted account in the history and chat it leaves behind.
+const DeletedUsername = "deleted-user"
+
+// AccountDeletion records a user's request to delete their account. Signing in again before PurgeAt cancels it.
+type AccountDeletion struct {
+	// RequestedAt is when the user asked for their account to be deleted.
+	RequestedAt time.Time `json:"requestedAt" bson:"requestedAt"`
+
+	// PurgeAt is when the account and its personal data are removed.
+	PurgeAt time.Time `json:"purgeAt" bson:"purgeAt"`
+}
+
 // AvatarConfig represents the customization options for a user's avatar.
 type AvatarConfig struct {
 	// Type is the type of avatar (e.g., "default", "custom").
@@ -209,6 +265,99 @@ type UserSettings struct {
 
 	// LanguageFilter indicates whether to enable language filtering.
 	LanguageFilter bool `json:"languageFilter" bson:"languageFilter"`
+
+	// HideListenerGeo indicates whether to exclude the user from room listener geo statistics.
+	HideListenerGeo bool `json:"hideListenerGeo" bson:"hideListenerGeo"`
+}
+
+// UserTrust represents the inputs to a user's trust level that are not derived from stats.
+type UserTrust struct {
+	// Override is a trust level set by an admin that replaces the computed level.
+	Override *TrustLevel `json:"override,omitempty" bson:"override,omitempty"`
+
+	// OverriddenBy is the ID of the admin who set the override.
+	OverriddenBy bson.ObjectID `json:"overriddenBy,omitzero" bson:"overriddenBy,omitempty"`
+
+	// Strikes is the number of moderation actions taken against the user.
+	Strikes int `json:"strikes" bson:"strikes"`
+
+	// LastStrike is the time of the most recent moderation action against the user.
+	LastStrike time.Time `json:"lastStrike,omitzero" bson:"lastStrike,omitempty"`
+}
+
+// GuestAccount represents the guest side of an account created for a guest.
+type GuestAccount struct {
+	// LinkedTo is the ID of the account the guest registered, zero until they register.
+	LinkedTo bson.ObjectID `json:"linkedTo,omitzero" bson:"linkedTo,omitempty"`
+
+	// LinkedAt is the time the guest registered.
+	LinkedAt time.Time `json:"linkedAt,omitzero" bson:"linkedAt,omitempty"`
+
+	// ClaimedAt is the time a registration started linking the guest, zero when none is in progress.
+	ClaimedAt time.Time `json:"-" bson:"claimedAt,omitempty"`
+}
+
+// AdultAge is the age from which users may see and play age-restricted media.
+const AdultAge = 18
+
+// AgeAttestation is a user's statement of their date of birth. It can't be changed once given.
+type AgeAttestation struct {
+	// BirthDate is the date of birth the user attested, at midnight UTC.
+	BirthDate time.Time `json:"birthDate" bson:"birthDate"`
+
+	// AttestedAt is when the user attested it.
+	AttestedAt time.Time `json:"attestedAt" bson:"attestedAt"`
+}
+
+// IsAdult reports whether the user attested a date of birth making them at least AdultAge years old at the given time.
+// Users who never attested their age are not adults.
+func (u *User) IsAdult(now time.Time) bool {
+	if u.AgeAttestation == nil {
+		return false
+	}
+	return !u.AgeAttestation.BirthDate.AddDate(AdultAge, 0, 0).After(now)
+}
+
+// IsMinor reports whether the user attested a date of birth making them younger than AdultAge at the given time.
+// Unlike IsAdult, users who never attested their age are not minors either.
+func (u *User) IsMinor(now time.Time) bool {
+	return u.AgeAttestation != nil && !u.IsAdult(now)
+}
+
+// OAuthIdentity is a third-party account a user signs in with.
+type OAuthIdentity struct {
+	// Provider is the OAuth provider of the account, such as google or discord.
+	Provider string `json:"provider" bson:"provider"`
+
+	// Subject is the user's stable ID with the provider.
+	Subject string `json:"-" bson:"subject"`
+
+	// Email is the email address the provider gave when the account was linked.
+	Email string `json:"email,omitempty" bson:"email,omitempty"`
+
+	// LinkedAt is when the account was linked.
+	LinkedAt time.Time `json:"linkedAt" bson:"linkedAt"`
+}
+
+// UserRecovery represents account recovery actions waiting on the user.
+type UserRecovery struct {
+	// PendingEmail is the new email address waiting to be verified.
+	PendingEmail string `json:"-" bson:"pendingEmail,omitempty"`
+
+	// EmailTokenHash is the hash of the token that verifies the pending email.
+	EmailTokenHash string `json:"-" bson:"emailTokenHash,omitempty"`
+
+	// EmailTokenExpiry is when the email verification token expires.
+	EmailTokenExpiry time.Time `json:"-" bson:"emailTokenExpiry,omitempty"`
+
+	// PasswordResetRequired indicates whether the user must reset their password before logging in.
+	PasswordResetRequired bool `json:"-" bson:"passwordResetRequired,omitempty"`
+
+	// ResetTokenHash is the hash of the token that resets the user's password.
+	ResetTokenHash string `json:"-" bson:"resetTokenHash,omitempty"`
+
+	// ResetTokenExpiry is when the password reset token expires.
+	ResetTokenExpiry time.Time `json:"-" bson:"resetTokenExpiry,omitempty"`
 }
 
 // PublicUser represents a subset of user information that is safe to share publicly.
@@ -241,15 +390,31 @@ type PersonalUser struct {
 
 	// Connections contains the user's social connections.
 	Connections UserConnections `json:"connections"`
+
+	// AgeAttestation is the user's own statement of their date of birth, if given.
+	AgeAttestation *AgeAttestation `json:"ageAttestation,omitempty"`
+
+	// OAuthIdentities are the third-party accounts the user can sign in with.
+	OAuthIdentities []OAuthIdentity `json:"oauthIdentities,omitempty"`
+
+	// UsernameHistory are the user's previous usernames, oldest first.
+	UsernameHistory []UsernameChange `json:"usernameHistory,omitempty"`
+
+	// Deletion is set while the user's account is scheduled for deletion.
+	Deletion *AccountDeletion `json:"deletion,omitempty"`
 }
 
 // ToPersonalUser converts a User to a PersonalUser.
 func (u *User) ToPersonalUser() PersonalUser {
 	return PersonalUser{
-		BaseUser:    u.BaseUser,
-		Email:       u.Email,
-		Settings:    u.Settings,
-		Connections: u.Connections,
+		BaseUser:        u.BaseUser,
+		Email:           u.Email,
+		Settings:        u.Settings,
+		Connections:     u.Connections,
+		AgeAttestation:  u.AgeAttestation,
+		OAuthIdentities: u.OAuthIdentities,
+		UsernameHistory: u.UsernameHistory,
+		Deletion:        u.Deletion,
 	}
 }
 
@@ -263,6 +428,22 @@ type UserRegisterRequest struct {
 
 	// Password is the user's password.
 	Password string `json:"password" validate:"required,min=8,max=72,password"`
+
+	// GuestToken is the token of the guest session the user registers from, if any.
+	// The guest's place in their room and their vote on the playing track move to the new account.
+	GuestToken string `json:"guestToken,omitempty"`
+
+	// KeepGuestHistory asks to attribute the guest's listening time and history to the new account.
+	KeepGuestHistory bool `json:"keepGuestHistory,omitempty"`
+
+	// CaptchaToken is the solved captcha, required when the captcha policy asks for one.
+	CaptchaToken string `json:"captchaToken,omitempty"`
+}
+
+// AgeAttestationRequest represents a user's attestation of their date of birth.
+type AgeAttestationRequest struct {
+	// BirthDate is the user's date of birth, as YYYY-MM-DD.
+	BirthDate string `json:"birthDate" validate:"required,datetime=2006-01-02"`
 }
 
 // UserLoginRequest represents the data needed to log in a user.
@@ -298,6 +479,12 @@ type UserPasswordChangeRequest struct {
 	NewPassword string `json:"newPassword" validate:"required,min=8,max=72,password"`
 }
 
+// UserDeleteRequest represents a user's request to delete their account.
+type UserDeleteRequest struct {
+	// Password is the user's current password, confirming the request. Accounts without a password leave it empty.
+	Password string `json:"password"`
+}
+
 // UserPasswordResetRequest represents the data needed to request a password reset.
 type UserPasswordResetRequest struct {
 	// Email is the user's email address.
@@ -311,4 +498,7 @@ type UserPasswordResetConfirmRequest struct {
 
 	// Password is the user's new password.
 	Password string `json:"password" validate:"required,min=8,max=72,password"`
+
+	// CaptchaToken is the solved captcha, required when the captcha policy asks for one.
+	CaptchaToken string `json:"captchaToken,omitempty"`
 }
@@ -0,0 +1,142 @@
+// Package models contains the data structures used throughout the application.
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// VerificationKind is who a verified badge vouches for.
+type VerificationKind string
+
+const (
+	// VerificationArtist is for artists and bands.
+	VerificationArtist VerificationKind = "artist"
+	// VerificationLabel is for record labels.
+	VerificationLabel VerificationKind = "label"
+)
+
+// ClaimTarget is the kind of document a verification claim is for.
+type ClaimTarget string
+
+const (
+	// ClaimTargetRoom claims a room as the official room of an artist or label.
+	ClaimTargetRoom ClaimTarget = "room"
+	// ClaimTargetMedia claims a media item as the work of an artist or label.
+	ClaimTargetMedia ClaimTarget = "media"
+	// ClaimTargetUser is the claimant's own account, badged along with the room or media they claimed.
+	ClaimTargetUser ClaimTarget = "user"
+)
+
+// ClaimStatus is where a verification claim is in the admins' review.
+type ClaimStatus string
+
+const (
+	// ClaimPending claims wait for an admin.
+	ClaimPending ClaimStatus = "pending"
+	// ClaimApproved claims were accepted, and their targets verified.
+	ClaimApproved ClaimStatus = "approved"
+	// ClaimRejected claims were found not to hold up.
+	ClaimRejected ClaimStatus = "rejected"
+)
+
+// VerifiedBadge marks a room, user or media item as the official presence of an artist or label.
+// Only platform admins set it, by approving a verification claim.
+type VerifiedBadge struct {
+	// Kind is whether the badge vouches for an artist or a label.
+	Kind VerificationKind `json:"kind" bson:"kind"`
+
+	// Name is the artist or label the badge vouches for.
+	Name string `json:"name" bson:"name"`
+
+	// ClaimID is the approved claim the badge was given for.
+	ClaimID bson.ObjectID `json:"-" bson:"claimId,omitempty"`
+
+	// VerifiedAt is when the badge was given.
+	VerifiedAt time.Time `json:"verifiedAt" bson:"verifiedAt"`
+}
+
+// VerificationClaim is an artist's or label's claim that a room or media item is theirs, reviewed by platform admins.
+type VerificationClaim struct {
+	// ID is the unique identifier for the claim.
+	ID bson.ObjectID `json:"id" bson:"_id"`
+
+	// ClaimantID is the user who filed the claim.
+	ClaimantID bson.ObjectID `json:"claimantId" bson:"claimantId"`
+
+	// TargetType is the kind of document claimed.
+	TargetType ClaimTarget `json:"targetType" bson:"targetType"`
+
+	// TargetID is the claimed room or media item.
+	TargetID bson.ObjectID `json:"targetId" bson:"targetId"`
+
+	// Kind is whether the claimant is an artist or a label.
+	Kind VerificationKind `json:"kind" bson:"kind"`
+
+	// Name is the artist or label the claimant speaks for.
+	Name string `json:"name" bson:"name"`
+
+	// Evidence are links backing the claim, such as the artist's official site or social profiles.
+	Evidence []string `json:"evidence" bson:"evidence"`
+
+	// Message is the claimant's explanation for the admins.
+	Message string `json:"message,omitempty" bson:"message,omitempty"`
+
+	// Status is where the claim is in review.
+	Status ClaimStatus `json:"status" bson:"status"`
+
+	// Note is the admin's explanation of the decision, shared with the claimant.
+	Note string `json:"note,omitempty" bson:"note,omitempty"`
+
+	// ReviewedBy is the admin who reviewed the claim.
+	ReviewedBy bson.ObjectID `json:"reviewedBy,omitzero" bson:"reviewedBy,omitempty"`
+
+	// ReviewedAt is when the claim was reviewed.
+	ReviewedAt time.Time `json:"reviewedAt,omitzero" bson:"reviewedAt,omitempty"`
+
+	// ObjectTimes contains timestamps for this claim.
+	ObjectTimes
+}
+
+// VerificationClaimRequest is an artist's or label's claim of a room or media item.
+type VerificationClaimRequest struct {
+	// TargetType is the kind of document claimed.
+	TargetType ClaimTarget `json:"targetType" validate:"required,oneof=room media"`
+
+	// TargetID is the claimed room or media item.
+	TargetID bson.ObjectID `json:"targetId"`
+
+	// Kind is whether the claimant is an artist or a label.
+	Kind VerificationKind `json:"kind" validate:"required,oneof=artist label"`
+
+	// Name is the artist or label the claimant speaks for.
+	Name string `json:"name" validate:"required,min=1,max=100"`
+
+	// Evidence are links backing the claim.
+	Evidence []string `json:"evidence" validate:"required,min=1,max=5,dive,url,max=2048"`
+
+	// Message is the claimant's explanation for the admins.
+	Message string `json:"message" validate:"max=2000"`
+}
+
+// VerificationClaimReview is an admin's decision on a verification claim.
+type VerificationClaimReview struct {
+	// Approve verifies the claimed target and the claimant. Otherwise the claim is rejected.
+	Approve bool `json:"approve"`
+
+	// Note explains the decision to the claimant.
+	Note string `json:"note" validate:"max=1000"`
+}
+
+// VerificationClaimFilter narrows a listing of verification claims. Zero fields match every claim.
+type VerificationClaimFilter struct {
+	// ClaimantID limits the listing to one user's claims.
+	ClaimantID bson.ObjectID
+
+	// TargetID limits the listing to the claims of one room or media item.
+	TargetID bson.ObjectID
+
+	// Status limits the listing to claims in one review status.
+	Status ClaimStatus
+}
@@ -0,0 +1,138 @@
+// Package rpc provides WebSocket-based RPC functionality.
+package rpc
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// AdmissionPolicy controls how a server admits new connections, so clients reconnecting all at once,
+// such as after a deploy or a network blip, are spread out instead of hitting the databases together.
+type AdmissionPolicy struct {
+	// Rate is the number of connections admitted per second. Zero admits connections without limit.
+	Rate int
+
+	// Burst is the number of connections admitted at once before the rate applies.
+	Burst int
+
+	// QueueSize is the number of connections that can wait for their turn. Past it connections are turned away.
+	QueueSize int
+
+	// QueueTimeout is the longest a connection waits for its turn. Connections that would wait longer are turned away.
+	QueueTimeout time.Duration
+
+	// ReconnectJitter is the spread of the random delay added to the reconnect hints sent to clients,
+	// so clients disconnected together don't all come back together.
+	ReconnectJitter time.Duration
+}
+
+// admission is a token bucket admitting connections at the policy's rate. Connections over the rate
+// reserve a later token and wait for it, so they are admitted in the order they arrived.
+type admission struct {
+	policy AdmissionPolicy
+
+	mu      sync.Mutex
+	tokens  float64
+	updated time.Time
+	waiting int
+}
+
+// newAdmission creates the admission of connections for a policy, with a full bucket.
+func newAdmission(policy AdmissionPolicy) *admission {
+	return &admission{
+		policy:  policy,
+		tokens:  float64(max(policy.Burst, 1)),
+		updated: time.Now(),
+	}
+}
+
+// SetAdmissionPolicy sets how the server admits new connections and spreads out the reconnects of the
+// clients it disconnects.
+func (s *Server) SetAdmissionPolicy(policy AdmissionPolicy) {
+	s.admission = newAdmission(policy)
+}
+
+// admit waits for a connection's turn to be admitted. It returns false, with the reason to turn the
+// connection away with, when the queue is full or the connection would wait too long.
+func (s *Server) admit(ctx context.Context) (bool, CloseReason) {
+	if s.admission == nil || s.admission.policy.Rate <= 0 {
+		return true, CloseReason{}
+	}
+
+	wait, ok := s.admission.reserve()
+	if !ok {
+		s.logger.Debug("Connection turned away, too many connections waiting")
+		return false, NewCloseReason(CloseOverloaded, "Too many clients connecting, try again later")
+	}
+	if wait <= 0 {
+		return true, CloseReason{}
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		s.admission.done()
+		return true, CloseReason{}
+	case <-ctx.Done():
+		s.admission.cancel()
+		return false, NewCloseReason(CloseOverloaded, "Too many clients connecting, try again later")
+	}
+}
+
+// reserve takes a token for a connection, and returns how long the connection has to wait for it.
+// It returns false when the connection can't be queued.
+func (a *admission) reserve() (time.Duration, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	rate := float64(a.policy.Rate)
+	a.tokens = min(a.tokens+now.Sub(a.updated).Seconds()*rate, float64(max(a.policy.Burst, 1)))
+	a.updated = now
+
+	if a.tokens >= 1 {
+		a.tokens--
+		return 0, true
+	}
+
+	// Tokens owed to the connections waiting make the count negative
+	wait := time.Duration((1 - a.tokens) / rate * float64(time.Second))
+	if a.waiting >= a.policy.QueueSize || wait > a.policy.QueueTimeout {
+		return 0, false
+	}
+	a.tokens--
+	a.waiting++
+	return wait, true
+}
+
+// done counts a waiting connection as admitted.
+func (a *admission) done() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.waiting--
+}
+
+// cancel gives back the token of a waiting connection that went away.
+func (a *admission) cancel() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.waiting--
+	a.tokens++
+}
+
+// jitterReconnect adds a random delay to the reconnect hint of a close reason, so clients disconnected
+// together spread their reconnects. Clients told not to reconnect on their own are left alone.
+func (s *Server) jitterReconnect(reason CloseReason) CloseReason {
+	if reason.Reconnect != ReconnectNow && reason.Reconnect != ReconnectBackoff {
+		return reason
+	}
+	if s.admission == nil || s.admission.policy.ReconnectJitter < time.Second {
+		return reason
+	}
+	reason.RetryAfter += rand.IntN(int(s.admission.policy.ReconnectJitter.Seconds()) + 1)
+	return reason
+}
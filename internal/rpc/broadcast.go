@@ -0,0 +1,201 @@
+// Package rpc provides WebSocket-based RPC functionality.
+package rpc
+
+import (
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// BroadcastPolicy controls how room broadcasts are delivered to the clients of this node.
+type BroadcastPolicy struct {
+	// Shards is the number of workers room broadcasts are spread across. Each room always goes to the
+	// same worker, so its messages stay in order.
+	Shards int
+
+	// Backlog is the number of messages a room can have waiting for delivery. Past it the oldest are
+	// dropped, so a room its clients can't keep up with only holds up itself.
+	Backlog int
+}
+
+// BroadcastShardStats describes the room broadcasts a shard delivered and has waiting.
+type BroadcastShardStats struct {
+	// Shard is the index of the shard.
+	Shard int `json:"shard"`
+
+	// Rooms is the number of rooms with messages waiting.
+	Rooms int `json:"rooms"`
+
+	// Backlog is the number of messages waiting across the rooms.
+	Backlog int `json:"backlog"`
+
+	// Delivered is the number of messages delivered since the server started.
+	Delivered uint64 `json:"delivered"`
+
+	// Dropped is the number of messages dropped for rooms over their backlog since the server started.
+	Dropped uint64 `json:"dropped"`
+
+	// Latency is the moving average of how long messages wait before reaching every client.
+	// It is zero when the shard delivered nothing recently.
+	Latency time.Duration `json:"latency"`
+}
+
+// broadcastShard delivers the broadcasts of the rooms hashed to it. Each room has its own queue, and
+// the rooms with messages waiting take turns, one message each, so a busy room can't starve the others.
+type broadcastShard struct {
+	index   int
+	hub     *Hub
+	backlog int
+	logger  *utils.Logger
+
+	// queues are the messages waiting for each room, oldest first
+	queues map[string][]*roomMessage
+
+	// ready are the rooms with messages waiting, in the order they get their next turn
+	ready []string
+
+	// pending is the number of messages waiting across the rooms
+	pending int
+
+	// overflowing are the rooms that dropped messages since their queue last emptied
+	overflowing map[string]bool
+
+	mutex sync.Mutex
+	wake  chan struct{}
+
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+
+	// latency is a moving average of the delivery latency in nanoseconds, recorded only by the
+	// shard's worker, and lastDelivery is when it last delivered, in Unix nanoseconds
+	latency      atomic.Int64
+	lastDelivery atomic.Int64
+}
+
+// newBroadcastShard creates a shard of the hub's room broadcasts.
+func newBroadcastShard(index int, hub *Hub, backlog int, logger *utils.Logger) *broadcastShard {
+	return &broadcastShard{
+		index:       index,
+		hub:         hub,
+		backlog:     backlog,
+		logger:      logger,
+		queues:      make(map[string][]*roomMessage),
+		overflowing: make(map[string]bool),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// enqueue queues a message for delivery to a room, dropping the room's oldest message when its
+// backlog is full. It never blocks on delivery.
+func (s *broadcastShard) enqueue(rm *roomMessage) {
+	s.mutex.Lock()
+	queue, ok := s.queues[rm.room]
+	if !ok {
+		s.ready = append(s.ready, rm.room)
+	}
+	if s.backlog > 0 && len(queue) >= s.backlog {
+		queue = queue[1:]
+		s.pending--
+		s.dropped.Add(1)
+		if !s.overflowing[rm.room] {
+			s.overflowing[rm.room] = true
+			s.logger.Warn("Room broadcast backlog full, dropping oldest messages", "room", rm.room, "shard", s.index, "backlog", s.backlog)
+		}
+	}
+	s.queues[rm.room] = append(queue, rm)
+	s.pending++
+	s.mutex.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next takes the message of the room whose turn it is, if any is waiting.
+func (s *broadcastShard) next() (*roomMessage, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.ready) == 0 {
+		return nil, false
+	}
+
+	room := s.ready[0]
+	s.ready = s.ready[1:]
+
+	queue := s.queues[room]
+	rm := queue[0]
+	queue[0] = nil
+	if len(queue) == 1 {
+		delete(s.queues, room)
+		delete(s.overflowing, room)
+	} else {
+		s.queues[room] = queue[1:]
+		s.ready = append(s.ready, room)
+	}
+	s.pending--
+
+	return rm, true
+}
+
+// run delivers the queued messages, forever.
+func (s *broadcastShard) run() {
+	for range s.wake {
+		for {
+			rm, ok := s.next()
+			if !ok {
+				break
+			}
+
+			s.hub.broadcastToRoom(rm)
+			s.recordLatency(time.Since(rm.queuedAt))
+			s.delivered.Add(1)
+		}
+	}
+}
+
+// recordLatency folds the latency of a delivered message into the shard's moving average.
+func (s *broadcastShard) recordLatency(latency time.Duration) {
+	current := s.latency.Load()
+	next := int64(latency)
+	if current > 0 {
+		next = current + int64(broadcastLatencyWeight*float64(int64(latency)-current))
+	}
+	s.latency.Store(next)
+	s.lastDelivery.Store(time.Now().UnixNano())
+}
+
+// averageLatency gets the shard's moving average latency, zero when it delivered nothing recently.
+func (s *broadcastShard) averageLatency() time.Duration {
+	if time.Since(time.Unix(0, s.lastDelivery.Load())) > broadcastLatencyStale {
+		return 0
+	}
+	return time.Duration(s.latency.Load())
+}
+
+// stats describes the shard's deliveries and backlog.
+func (s *broadcastShard) stats() BroadcastShardStats {
+	s.mutex.Lock()
+	rooms, backlog := len(s.queues), s.pending
+	s.mutex.Unlock()
+
+	return BroadcastShardStats{
+		Shard:     s.index,
+		Rooms:     rooms,
+		Backlog:   backlog,
+		Delivered: s.delivered.Load(),
+		Dropped:   s.dropped.Load(),
+		Latency:   s.averageLatency(),
+	}
+}
+
+// shardIndex picks the shard a room's broadcasts go to.
+func shardIndex(room string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(room))
+	return int(h.Sum32() % uint32(shards))
+}
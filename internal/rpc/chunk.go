@@ -0,0 +1,83 @@
+// Package rpc provides WebSocket-based RPC functionality.
+package rpc
+
+import (
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// ChunkKindEnd marks the last chunk of a stream, which carries no items.
+const ChunkKindEnd = "end"
+
+// Chunk is one part of a response streamed to a client as notifications after the response itself.
+// Clients with the progressive-join capability get the core of a response first and the rest in chunks,
+// which they match to the response by stream ID and apply in sequence order.
+type Chunk struct {
+	// Stream identifies the response the chunk belongs to.
+	Stream string `json:"stream"`
+
+	// Seq is the position of the chunk in the stream, starting at zero.
+	Seq int `json:"seq"`
+
+	// Kind is what the chunk's items are, such as "roster" or "chat".
+	Kind string `json:"kind"`
+
+	// Items are the chunk's contents.
+	Items any `json:"items,omitempty"`
+
+	// Offset is the position of the chunk's first item among all items of its kind.
+	Offset int `json:"offset"`
+
+	// Total is the number of items of the chunk's kind across the whole stream.
+	Total int `json:"total"`
+
+	// Last indicates the stream is complete.
+	Last bool `json:"last"`
+}
+
+// ChunkStream sends the chunks of a streamed response to a client.
+type ChunkStream struct {
+	// ID identifies the stream, the response it follows carries it.
+	ID string
+
+	client *Client
+	method string
+	seq    int
+}
+
+// NewChunkStream creates a stream of chunks sent to the client as notifications of the given method.
+func (c *Client) NewChunkStream(method string) (*ChunkStream, error) {
+	id, err := utils.GenerateID("stream")
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChunkStream{
+		ID:     id,
+		client: c,
+		method: method,
+	}, nil
+}
+
+// Send sends a chunk of items.
+func (s *ChunkStream) Send(kind string, items any, offset, total int) {
+	s.client.SendNotification(s.method, &Chunk{
+		Stream: s.ID,
+		Seq:    s.seq,
+		Kind:   kind,
+		Items:  items,
+		Offset: offset,
+		Total:  total,
+	})
+	s.seq++
+}
+
+// End tells the client the stream is complete.
+func (s *ChunkStream) End() {
+	s.client.SendNotification(s.method, &Chunk{
+		Stream: s.ID,
+		Seq:    s.seq,
+		Kind:   ChunkKindEnd,
+		Last:   true,
+	})
+	s.seq++
+}
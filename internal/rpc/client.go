@@ -3,7 +3,11 @@ package rpc
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -24,15 +28,74 @@ type Client struct {
 	// server is the WebSocket server that created this client.
 	server *Server
 
-	// conn is the WebSocket connection.
+	// conn is the WebSocket connection, nil for clients connected over Server-Sent Events.
 	conn *websocket.Conn
 
+	// cancel ends the event stream of a client connected over Server-Sent Events.
+	cancel context.CancelFunc
+
+	// requestMutex serializes the HTTP requests of a client connected over Server-Sent Events,
+	// so they are handled one at a time like messages on a WebSocket.
+	requestMutex sync.Mutex
+
 	// send is a channel of outbound messages.
 	send chan []byte
 
 	// rooms is a map of room IDs that the client is in.
 	rooms map[string]bool
 
+	// topics are the topics followed in the rooms where the client chose a subset, by room ID.
+	topics      map[string][]Topic
+	topicsMutex sync.RWMutex
+
+	// chatShards are the overflow chat shards the client chats in, by room ID. Rooms where it is in
+	// the main chat are left out.
+	chatShards map[string]int
+
+	// chatChannels are the chat channels followed in the rooms where the client chose a subset, by room ID.
+	chatChannels map[string][]string
+
+	// protocol is the protocol version and capabilities negotiated on connect.
+	protocol *NegotiatedProtocol
+
+	// country is the country code resolved from the client's IP address on connect.
+	country string
+
+	// ip is the IP address the client connected from.
+	ip string
+
+	// rtt is a moving average of the round-trip time measured with pings in nanoseconds, zero until
+	// the first pong.
+	rtt atomic.Int64
+
+	// token is the token the client authenticated with, used to look its session up again once it expires.
+	token string
+
+	// sessionExpiresAt is when the client's session expires unless it was refreshed.
+	sessionExpiresAt time.Time
+
+	// instance identifies the client instance across reconnects, empty if the client didn't announce one.
+	instance string
+
+	// resumeToken lets the client resume its session after reconnecting, empty if it can't.
+	resumeToken string
+
+	// reconnected is set when the client connected with the resume token of a previous connection.
+	reconnected bool
+
+	// connectedAt is when the client connected.
+	connectedAt time.Time
+
+	// closeOnce makes sure the client is disconnected once.
+	closeOnce sync.Once
+
+	// closeReason is why the server disconnected the client, nil while it is connected.
+	closeReason atomic.Pointer[CloseReason]
+
+	// afterResponse holds functions run once the response to the current request is sent.
+	afterResponse      []func()
+	afterResponseMutex sync.Mutex
+
 	// logger is the client's logger.
 	logger *utils.Logger
 }
@@ -40,19 +103,21 @@ type Client struct {
 // readPump pumps messages from the WebSocket connection to the hub.
 func (c *Client) readPump() {
 	defer func() {
+		c.server.detach(c)
 		c.server.unregister <- c
 		c.conn.Close()
 	}()
 
 	c.conn.SetReadLimit(maxMessageSize)
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.conn.SetPongHandler(func(string) error {
+	c.conn.SetPongHandler(func(payload string) error {
 		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.recordRTT(payload)
 		return nil
 	})
 
 	for {
-		_, message, err := c.conn.ReadMessage()
+		messageType, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				c.logger.Error("Unexpected close error", err)
@@ -60,6 +125,12 @@ func (c *Client) readPump() {
 			break
 		}
 
+		// Binary frames are only valid with an encoding the server doesn't offer
+		if messageType != websocket.TextMessage {
+			c.Disconnect(NewCloseReason(CloseProtocolViolation, "Only text messages are accepted"))
+			break
+		}
+
 		message = bytes.TrimSpace(bytes.Replace(message, []byte{'\n'}, []byte{' '}, -1))
 		c.handleMessage(message)
 	}
@@ -73,6 +144,11 @@ func (c *Client) writePump() {
 		c.conn.Close()
 	}()
 
+	// Measure the round-trip time right away rather than after the first ping period
+	if err := c.ping(); err != nil {
+		return
+	}
+
 	for {
 		select {
 		case message, ok := <-c.send:
@@ -100,41 +176,154 @@ func (c *Client) writePump() {
 				return
 			}
 		case <-ticker.C:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			if !c.checkSession() {
+				return
+			}
+			if err := c.ping(); err != nil {
 				return
 			}
 		}
 	}
 }
 
-// handleMessage processes incoming messages.
+// ping sends a ping carrying the time it was sent, so the pong measures the round-trip time.
+func (c *Client) ping() error {
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.conn.WriteMessage(websocket.PingMessage, []byte(strconv.FormatInt(time.Now().UnixNano(), 10)))
+}
+
+// recordRTT adds the round-trip time of a pong to the client's moving average. Pongs that don't
+// carry the time of a ping are ignored.
+func (c *Client) recordRTT(payload string) {
+	sent, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil || sent <= 0 {
+		return
+	}
+	rtt := time.Since(time.Unix(0, sent))
+	if rtt <= 0 || rtt > pongWait {
+		return
+	}
+
+	previous := c.rtt.Load()
+	if previous == 0 {
+		c.rtt.Store(int64(rtt))
+		return
+	}
+	c.rtt.Store(int64(float64(previous)*(1-rttWeight) + float64(rtt)*rttWeight))
+}
+
+// RTT returns the moving average of the client's round-trip time, zero until it is measured or for
+// clients connected over Server-Sent Events.
+func (c *Client) RTT() time.Duration {
+	return time.Duration(c.rtt.Load())
+}
+
+// handleMessage processes incoming messages and sends the response back to the client.
 func (c *Client) handleMessage(message []byte) {
+	if !c.server.beginRequest() {
+		c.send <- c.errorResponse(nil, ErrServerError, "Server is shutting down")
+		return
+	}
+	defer c.server.requests.Done()
+
+	if response := c.process(message); response != nil {
+		c.send <- response
+	}
+	c.runAfterResponse()
+}
+
+// AfterResponse registers a function run in its own goroutine once the response to the request
+// being handled is queued, so notifications it sends reach the client after the response.
+func (c *Client) AfterResponse(fn func()) {
+	c.afterResponseMutex.Lock()
+	defer c.afterResponseMutex.Unlock()
+
+	c.afterResponse = append(c.afterResponse, fn)
+}
+
+// runAfterResponse starts the functions registered while handling the last request.
+func (c *Client) runAfterResponse() {
+	c.afterResponseMutex.Lock()
+	fns := c.afterResponse
+	c.afterResponse = nil
+	c.afterResponseMutex.Unlock()
+
+	// The request is still in flight, so the server waits for these too when it shuts down
+	for _, fn := range fns {
+		c.server.requests.Add(1)
+		go func() {
+			defer c.server.requests.Done()
+			fn()
+		}()
+	}
+}
+
+// process handles a JSON-RPC request or batch and returns the encoded response, or nil if there is none.
+func (c *Client) process(message []byte) []byte {
+	// Batch requests are JSON arrays
+	if len(message) > 0 && message[0] == '[' {
+		return c.processBatch(message)
+	}
+
 	// Parse the message as a JSON-RPC request
 	var request Request
 	if err := json.Unmarshal(message, &request); err != nil {
 		c.logger.Error("Failed to parse message", err, "message", string(message))
-		c.sendErrorResponse(request.ID, ErrParseError, "Invalid JSON")
-		return
+		return c.errorResponse(request.ID, ErrParseError, "Invalid JSON")
 	}
 
 	// Route the request to the appropriate handler
 	response := c.server.router.Route(c, &request)
+	if response == nil {
+		return nil
+	}
 
-	// Send the response
-	if response != nil {
-		responseJSON, err := json.Marshal(response)
-		if err != nil {
-			c.logger.Error("Failed to marshal response", err, "response", response)
-			c.sendErrorResponse(request.ID, ErrInternalError, "Failed to marshal response")
-			return
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		c.logger.Error("Failed to marshal response", err, "response", response)
+		return c.errorResponse(request.ID, ErrInternalError, "Failed to marshal response")
+	}
+	return responseJSON
+}
+
+// processBatch handles a JSON-RPC batch request.
+func (c *Client) processBatch(message []byte) []byte {
+	if !c.HasCapability(CapBatch) {
+		return c.errorResponse(nil, ErrInvalidRequest, "Batch requests require the batch capability")
+	}
+
+	var requests []Request
+	if err := json.Unmarshal(message, &requests); err != nil {
+		c.logger.Error("Failed to parse batch", err, "message", string(message))
+		return c.errorResponse(nil, ErrParseError, "Invalid JSON")
+	}
+
+	if len(requests) == 0 {
+		return c.errorResponse(nil, ErrInvalidRequest, "Empty batch")
+	}
+
+	responses := make([]*Response, 0, len(requests))
+	for i := range requests {
+		if response := c.server.router.Route(c, &requests[i]); response != nil {
+			responses = append(responses, response)
 		}
-		c.send <- responseJSON
 	}
+
+	// A batch of notifications produces no response
+	if len(responses) == 0 {
+		return nil
+	}
+
+	responseJSON, err := json.Marshal(responses)
+	if err != nil {
+		c.logger.Error("Failed to marshal batch response", err)
+		return c.errorResponse(nil, ErrInternalError, "Failed to marshal response")
+	}
+	return responseJSON
 }
 
-// sendErrorResponse sends an error response to the client.
-func (c *Client) sendErrorResponse(id any, code ErrorCode, message string) {
+// errorResponse encodes an error response to the client.
+func (c *Client) errorResponse(id any, code ErrorCode, message string) []byte {
 	response := &Response{
 		JSONRPC: "2.0",
 		ID:      id,
@@ -147,10 +336,10 @@ func (c *Client) sendErrorResponse(id any, code ErrorCode, message string) {
 	responseJSON, err := json.Marshal(response)
 	if err != nil {
 		c.logger.Error("Failed to marshal error response", err, "response", response)
-		return
+		return nil
 	}
 
-	c.send <- responseJSON
+	return responseJSON
 }
 
 // SendNotification sends a notification to the client.
@@ -167,12 +356,87 @@ func (c *Client) SendNotification(method string, params any) {
 		return
 	}
 
-	c.send <- notificationJSON
+	select {
+	case c.send <- notificationJSON:
+	default:
+		c.Disconnect(slowConsumer)
+	}
 }
 
-// JoinRoom adds the client to a room.
+// Disconnect closes the client's connection, telling it why and whether to reconnect.
+// WebSocket clients get the reason in the close frame, Server-Sent Events clients in a final
+// connection.closed notification.
+func (c *Client) Disconnect(reason CloseReason) {
+	c.closeOnce.Do(func() {
+		reason = c.server.jitterReconnect(reason)
+		c.logger.Info("Disconnecting client", "clientID", c.ID, "userID", c.UserID, "code", reason.Code, "reason", reason.Reason)
+		c.closeReason.Store(&reason)
+
+		if c.conn != nil {
+			if err := c.conn.WriteControl(websocket.CloseMessage, reason.frame(), time.Now().Add(writeWait)); err != nil {
+				c.logger.Debug("Failed to send close message", "clientID", c.ID, "error", err)
+			}
+			c.conn.Close()
+		}
+		if c.cancel != nil {
+			c.cancel()
+		}
+	})
+}
+
+// checkSession disconnects the client once its session expired, unless the session was refreshed since.
+// It returns whether the client is still connected.
+func (c *Client) checkSession() bool {
+	if c.sessionExpiresAt.IsZero() || time.Now().Before(c.sessionExpiresAt) {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	defer cancel()
+
+	session, err := c.server.sessionMgr.GetSession(ctx, c.token)
+	if err == nil && session != nil && time.Now().Before(session.ExpiresAt) {
+		c.sessionExpiresAt = session.ExpiresAt
+		return true
+	}
+
+	c.Disconnect(NewCloseReason(CloseAuthExpired, "Session expired"))
+	return false
+}
+
+// Protocol returns the protocol negotiated for the client.
+func (c *Client) Protocol() *NegotiatedProtocol {
+	return c.protocol
+}
+
+// HasCapability checks if a capability was negotiated for the client.
+func (c *Client) HasCapability(capability Capability) bool {
+	return c.protocol.Has(capability)
+}
+
+// Rejoining reports whether the client reconnected with the resume token of a previous connection
+// within the last rejoinWindow, whether or not its session could be resumed, so the rooms it joins
+// are most likely those it was in before.
+func (c *Client) Rejoining() bool {
+	return c.reconnected && time.Since(c.connectedAt) < rejoinWindow
+}
+
+// Country returns the country code resolved for the client, or an empty string if unknown.
+func (c *Client) Country() string {
+	return c.country
+}
+
+// IP returns the IP address the client connected from.
+func (c *Client) IP() string {
+	return c.ip
+}
+
+// JoinRoom adds the client to a room, following every topic of its events and every channel of its chat.
 func (c *Client) JoinRoom(roomID string) {
 	c.rooms[roomID] = true
+	c.SetRoomTopics(roomID, nil)
+	c.SetChatShard(roomID, 0)
+	c.SetChatChannels(roomID, nil)
 	c.server.AddClientToRoom(c, roomID)
 	c.logger.Debug("Client joined room", "clientID", c.ID, "roomID", roomID)
 }
@@ -180,10 +444,18 @@ func (c *Client) JoinRoom(roomID string) {
 // LeaveRoom removes the client from a room.
 func (c *Client) LeaveRoom(roomID string) {
 	delete(c.rooms, roomID)
+	c.SetRoomTopics(roomID, nil)
+	c.SetChatShard(roomID, 0)
+	c.SetChatChannels(roomID, nil)
 	c.server.RemoveClientFromRoom(c, roomID)
 	c.logger.Debug("Client left room", "clientID", c.ID, "roomID", roomID)
 }
 
+// NotifyRoom sends a notification to all clients in a room, on every node.
+func (c *Client) NotifyRoom(roomID, method string, params any) {
+	c.server.NotifyRoom(roomID, method, params)
+}
+
 // IsInRoom checks if the client is in a room.
 func (c *Client) IsInRoom(roomID string) bool {
 	return c.rooms[roomID]
@@ -0,0 +1,48 @@
+// Package rpc provides WebSocket-based RPC functionality.
+package rpc
+
+import "time"
+
+// ClockSample is one exchange of timestamps between a client and the server, as in NTP. The client
+// stamps its request when sending it and the response when receiving it, and the server stamps both
+// in between. With the four timestamps, the client can tell how far its clock is off the server's
+// regardless of network delay, as long as the delay is about the same each way.
+type ClockSample struct {
+	// ClientSent is the client's time when it sent the request.
+	ClientSent time.Time `json:"clientSent"`
+
+	// ServerReceived is the server's time when it received the request.
+	ServerReceived time.Time `json:"serverReceived"`
+
+	// ServerSent is the server's time when it sent the response.
+	ServerSent time.Time `json:"serverSent"`
+
+	// ClientReceived is the client's time when it received the response.
+	ClientReceived time.Time `json:"clientReceived,omitzero"`
+}
+
+// Offset is how far the server's clock is ahead of the client's. Adding it to a client time gives the
+// server time.
+func (s ClockSample) Offset() time.Duration {
+	return (s.ServerReceived.Sub(s.ClientSent) + s.ServerSent.Sub(s.ClientReceived)) / 2
+}
+
+// RoundTrip is the network delay of the exchange, leaving out the time the server took to respond.
+func (s ClockSample) RoundTrip() time.Duration {
+	return s.ClientReceived.Sub(s.ClientSent) - s.ServerSent.Sub(s.ServerReceived)
+}
+
+// BestOffset picks the offset of the sample with the shortest round trip out of several exchanges, the
+// one whose delays had the least room to be uneven. It returns zero without samples.
+func BestOffset(samples []ClockSample) time.Duration {
+	var best *ClockSample
+	for i := range samples {
+		if best == nil || samples[i].RoundTrip() < best.RoundTrip() {
+			best = &samples[i]
+		}
+	}
+	if best == nil {
+		return 0
+	}
+	return best.Offset()
+}
@@ -0,0 +1,141 @@
+// Package rpc provides WebSocket-based RPC functionality.
+package rpc
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Close codes sent when the server ends a connection. RFC 6455 leaves 4000-4999 to applications,
+// failures of the server itself use the standard internal error code.
+const (
+	// CloseUnsupportedProtocol is sent to clients whose protocol version is not supported.
+	CloseUnsupportedProtocol = 4001
+
+	// CloseAuthFailed is sent to connecting clients without a valid token or session.
+	CloseAuthFailed = 4002
+
+	// CloseAuthExpired is sent when a connected client's session expires.
+	CloseAuthExpired = 4003
+
+	// CloseBanned is sent to the clients of a user banned from the site.
+	CloseBanned = 4004
+
+	// CloseKicked is sent to the clients of a user kicked or banned from their room.
+	CloseKicked = 4005
+
+	// CloseDraining is sent when the server stops serving connections, so clients move to another node.
+	CloseDraining = 4006
+
+	// CloseProtocolViolation is sent to clients that don't follow the protocol.
+	CloseProtocolViolation = 4007
+
+	// CloseSlowConsumer is sent to clients that can't keep up with the messages sent to them.
+	CloseSlowConsumer = 4008
+
+	// CloseDuplicateSession is sent to a connection replaced by a newer one from the same client instance.
+	CloseDuplicateSession = 4009
+
+	// CloseOverloaded is sent to connecting clients turned away because too many clients are connecting.
+	CloseOverloaded = 4010
+
+	// CloseInternalError is sent when the server fails to serve a connection.
+	CloseInternalError = websocket.CloseInternalServerErr
+)
+
+// maxCloseReasonSize is the largest close reason that fits in a WebSocket close frame.
+const maxCloseReasonSize = 123
+
+// ReconnectHint tells a disconnected client whether and how to reconnect.
+type ReconnectHint string
+
+const (
+	// ReconnectNow means the client can reconnect right away, with some jitter to spread the load.
+	ReconnectNow ReconnectHint = "now"
+
+	// ReconnectBackoff means the client should reconnect with exponential backoff, starting at RetryAfter.
+	ReconnectBackoff ReconnectHint = "backoff"
+
+	// ReconnectReauth means the client has to sign in again before reconnecting.
+	ReconnectReauth ReconnectHint = "reauth"
+
+	// ReconnectNever means the client should not reconnect on its own.
+	ReconnectNever ReconnectHint = "never"
+)
+
+// CloseReason describes why the server ended a connection. It is sent as JSON in the reason of
+// the WebSocket close frame, or as the connection.closed notification over Server-Sent Events.
+type CloseReason struct {
+	// Code is the close code.
+	Code int `json:"code"`
+
+	// Reason is a stable machine-readable name for the close code.
+	Reason string `json:"reason"`
+
+	// Message is a human-readable explanation.
+	Message string `json:"message,omitempty"`
+
+	// Reconnect tells the client whether and how to reconnect.
+	Reconnect ReconnectHint `json:"reconnect"`
+
+	// RetryAfter is how many seconds the client should wait before reconnecting.
+	RetryAfter int `json:"retryAfter,omitempty"`
+}
+
+// ShutdownNotice tells clients that the node they are connected to is shutting down, ahead of their
+// disconnection, so they can move to another node while the connection still works.
+type ShutdownNotice struct {
+	// Message is a human-readable explanation.
+	Message string `json:"message,omitempty"`
+
+	// Reconnect tells the client how to reconnect.
+	Reconnect ReconnectHint `json:"reconnect"`
+
+	// RetryAfter is how many seconds the client should wait before reconnecting.
+	RetryAfter int `json:"retryAfter,omitempty"`
+
+	// Deadline is when the node disconnects the clients still connected, at the latest.
+	Deadline time.Time `json:"deadline,omitzero"`
+}
+
+// closeReasons are the defaults of each close code.
+var closeReasons = map[int]CloseReason{
+	CloseUnsupportedProtocol: {Reason: "unsupported_protocol", Reconnect: ReconnectNever},
+	CloseAuthFailed:          {Reason: "auth_failed", Reconnect: ReconnectReauth},
+	CloseAuthExpired:         {Reason: "auth_expired", Reconnect: ReconnectReauth},
+	CloseBanned:              {Reason: "banned", Reconnect: ReconnectNever},
+	CloseKicked:              {Reason: "kicked", Reconnect: ReconnectBackoff, RetryAfter: 30},
+	CloseDraining:            {Reason: "draining", Reconnect: ReconnectNow},
+	CloseProtocolViolation:   {Reason: "protocol_violation", Reconnect: ReconnectBackoff, RetryAfter: 5},
+	CloseSlowConsumer:        {Reason: "slow_consumer", Reconnect: ReconnectBackoff, RetryAfter: 5},
+	CloseDuplicateSession:    {Reason: "duplicate_session", Reconnect: ReconnectNever},
+	CloseOverloaded:          {Reason: "overloaded", Reconnect: ReconnectBackoff, RetryAfter: 5},
+	CloseInternalError:       {Reason: "internal_error", Reconnect: ReconnectBackoff, RetryAfter: 5},
+}
+
+// slowConsumer is the close reason of clients whose outbound buffer is full.
+var slowConsumer = NewCloseReason(CloseSlowConsumer, "Too many undelivered messages")
+
+// NewCloseReason creates the close reason for a close code with its default reconnect hint.
+func NewCloseReason(code int, message string) CloseReason {
+	reason, ok := closeReasons[code]
+	if !ok {
+		reason = CloseReason{Reason: "closed", Reconnect: ReconnectBackoff}
+	}
+	reason.Code = code
+	reason.Message = message
+	return reason
+}
+
+// frame encodes the close reason as a WebSocket close frame.
+// The message is left out if the reason doesn't fit in the frame.
+func (r CloseReason) frame() []byte {
+	payload, _ := json.Marshal(r) // Plain struct, never fails
+	if len(payload) > maxCloseReasonSize {
+		r.Message = ""
+		payload, _ = json.Marshal(r)
+	}
+	return websocket.FormatCloseMessage(r.Code, string(payload))
+}
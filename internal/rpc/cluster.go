@@ -0,0 +1,369 @@
+// Package rpc provides WebSocket-based RPC functionality.
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	r "github.com/go-redis/redis/v8"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// clusterKeyPrefix prefixes the keys of the connection registries of the nodes.
+	clusterKeyPrefix = "rpc_cluster"
+
+	// clusterNodesKey holds the nodes by the Unix time of their last heartbeat.
+	clusterNodesKey = clusterKeyPrefix + ":nodes"
+
+	// clusterMissedHeartbeats is how many heartbeats a node can miss before it is considered gone.
+	clusterMissedHeartbeats = 3
+)
+
+// clusterChannel is the PubSub channel notifications are fanned out to the other nodes on.
+var clusterChannel = managers.FormatGlobalChannel("rpc_notifications")
+
+// ClusterPolicy controls how a node takes part in the cluster of WebSocket servers.
+type ClusterPolicy struct {
+	// NodeID identifies the node. Empty generates one.
+	NodeID string
+
+	// Heartbeat is how often the node refreshes its connection registry in Redis.
+	Heartbeat time.Duration
+
+	// Region is the deployment region of the node. Empty is the default region.
+	Region string
+
+	// Endpoint is the WebSocket URL clients connect to the node's region at, advertised to clients
+	// choosing a region. Empty doesn't advertise one.
+	Endpoint string
+}
+
+// NodeInfo describes a node of the cluster, as last reported in its connection registry.
+type NodeInfo struct {
+	// ID identifies the node.
+	ID string `json:"id"`
+
+	// Region is the deployment region of the node.
+	Region string `json:"region"`
+
+	// Endpoint is the WebSocket URL advertised for the node's region, if any.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Connections is the number of clients connected to the node.
+	Connections int `json:"connections"`
+
+	// Users is the number of distinct users connected to the node.
+	Users int `json:"users"`
+
+	// SeenAt is when the node last refreshed its registry.
+	SeenAt time.Time `json:"seenAt"`
+}
+
+// clusterNotification is a notification fanned out to the clients connected to the other nodes.
+// It goes to a room's clients if it has a room ID, to a user's clients if it has a user ID, and to
+// every client otherwise. With a chat shard, it carries no message but moves the user's clients in
+// the room to that chat shard, and with a username it renames the user's clients.
+type clusterNotification struct {
+	Node      string          `json:"node"`
+	RoomID    string          `json:"roomId,omitempty"`
+	Topic     Topic           `json:"topic,omitempty"`
+	UserID    string          `json:"userId,omitempty"`
+	ChatShard *int            `json:"chatShard,omitempty"`
+	Username  *string         `json:"username,omitempty"`
+	Message   json.RawMessage `json:"message,omitempty"`
+}
+
+// roomEvent is an event published to a room's PubSub channel.
+type roomEvent struct {
+	Type        string `json:"type"`
+	RoomID      string `json:"roomId"`
+	ChatShard   *int   `json:"chatShard"`
+	ChatChannel string `json:"chatChannel"`
+}
+
+// Cluster lets WebSocket servers sharing Redis act as one. Notifications sent through the server reach
+// the clients connected to every node, the events services publish to a room's PubSub channel are
+// forwarded to the room's clients on every node following it, and each node keeps a registry of its
+// connections in Redis for the others and for operators to see.
+type Cluster struct {
+	server      *Server
+	pubSub      *managers.PubSubManager
+	redisClient *redis.Client
+	policy      ClusterPolicy
+	logger      *utils.Logger
+
+	// rooms are the room channels the node is subscribed to, by when it subscribed
+	rooms map[string]time.Time
+	mutex sync.Mutex
+}
+
+// NewCluster creates a cluster membership for a server and attaches it to the server.
+func NewCluster(
+	server *Server,
+	pubSub *managers.PubSubManager,
+	redisClient *redis.Client,
+	policy ClusterPolicy,
+	logger *utils.Logger,
+) *Cluster {
+	if policy.NodeID == "" {
+		policy.NodeID = generateNodeID()
+	}
+	if policy.Region == "" {
+		policy.Region = defaultRegion
+	}
+
+	c := &Cluster{
+		server:      server,
+		pubSub:      pubSub,
+		redisClient: redisClient,
+		policy:      policy,
+		logger:      logger.Named("rpc_cluster"),
+		rooms:       make(map[string]time.Time),
+	}
+	server.cluster = c
+	return c
+}
+
+// generateNodeID generates a node ID, falling back to the host name.
+func generateNodeID() string {
+	if id, err := utils.GenerateID("node"); err == nil {
+		return id
+	}
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("node_%s_%d", hostname, os.Getpid())
+}
+
+// NodeID returns the ID of this node.
+func (c *Cluster) NodeID() string {
+	return c.policy.NodeID
+}
+
+// Start begins receiving the notifications sent on the other nodes and refreshing this node's
+// connection registry, until the context is done.
+func (c *Cluster) Start(ctx context.Context) error {
+	if err := c.pubSub.Subscribe(clusterChannel); err != nil {
+		return fmt.Errorf("failed to subscribe to cluster channel: %w", err)
+	}
+	c.pubSub.AddHandler(clusterChannel, c.receive)
+
+	c.heartbeat(ctx)
+	go func() {
+		ticker := time.NewTicker(c.policy.Heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.heartbeat(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	c.logger.Info("Joined WebSocket cluster", "nodeId", c.policy.NodeID, "region", c.policy.Region)
+	return nil
+}
+
+// Leave removes this node's connection registry, once its clients are disconnected.
+func (c *Cluster) Leave(ctx context.Context) error {
+	pipe := c.redisClient.TxPipeline()
+	pipe.ZRem(ctx, clusterNodesKey, c.policy.NodeID)
+	pipe.Del(ctx, formatNodeClientsKey(c.policy.NodeID))
+	pipe.Del(ctx, formatNodeRegionKey(c.policy.NodeID))
+	pipe.Del(ctx, formatNodeRTTKey(c.policy.NodeID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to remove connection registry: %w", err)
+	}
+
+	c.logger.Info("Left WebSocket cluster", "nodeId", c.policy.NodeID)
+	return nil
+}
+
+// Nodes lists the nodes of the cluster that are still sending heartbeats, with their connections.
+func (c *Cluster) Nodes(ctx context.Context) ([]NodeInfo, error) {
+	since := time.Now().Add(-clusterMissedHeartbeats * c.policy.Heartbeat)
+	nodes, err := c.redisClient.Client().ZRangeByScoreWithScores(ctx, clusterNodesKey, &r.ZRangeBy{
+		Min: strconv.FormatInt(since.Unix(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := c.redisClient.Pipeline()
+	clients := make([]*r.StringStringMapCmd, len(nodes))
+	regions := make([]*r.StringCmd, len(nodes))
+	for i, node := range nodes {
+		clients[i] = pipe.HGetAll(ctx, formatNodeClientsKey(node.Member.(string)))
+		regions[i] = pipe.Get(ctx, formatNodeRegionKey(node.Member.(string)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != r.Nil {
+		return nil, err
+	}
+
+	infos := make([]NodeInfo, len(nodes))
+	for i, node := range nodes {
+		users := make(map[string]bool)
+		for _, userID := range clients[i].Val() {
+			users[userID] = true
+		}
+		// Nodes from before regions were registered are in the default region
+		region := nodeRegion{Region: defaultRegion}
+		if data := regions[i].Val(); data != "" {
+			if err := json.Unmarshal([]byte(data), &region); err != nil {
+				c.logger.Warn("Ignoring malformed node region", "nodeId", node.Member, "error", err)
+			}
+		}
+		infos[i] = NodeInfo{
+			ID:          node.Member.(string),
+			Region:      region.Region,
+			Endpoint:    region.Endpoint,
+			Connections: len(clients[i].Val()),
+			Users:       len(users),
+			SeenAt:      time.Unix(int64(node.Score), 0),
+		}
+	}
+	return infos, nil
+}
+
+// heartbeat rewrites this node's connection and region registries, records where its users are
+// connected, forgets the nodes that stopped sending heartbeats, and unsubscribes from the channels of rooms no client on this node follows anymore.
+func (c *Cluster) heartbeat(ctx context.Context) {
+	now := time.Now()
+	ttl := clusterMissedHeartbeats * c.policy.Heartbeat
+
+	connections := c.server.connections()
+	key := formatNodeClientsKey(c.policy.NodeID)
+
+	pipe := c.redisClient.TxPipeline()
+	pipe.Del(ctx, key)
+	if len(connections) > 0 {
+		pipe.HSet(ctx, key, connections)
+		pipe.Expire(ctx, key, ttl)
+	}
+	c.recordRegion(ctx, pipe, ttl)
+	pipe.ZAdd(ctx, clusterNodesKey, &r.Z{Score: float64(now.Unix()), Member: c.policy.NodeID})
+	pipe.ZRemRangeByScore(ctx, clusterNodesKey, "-inf", strconv.FormatInt(now.Add(-ttl).Unix(), 10))
+	if _, err := pipe.Exec(ctx); err != nil {
+		c.logger.Error("Failed to refresh connection registry", err, "nodeId", c.policy.NodeID)
+	}
+	c.recordUserRegions(ctx, connections)
+
+	c.unfollowRooms(now)
+}
+
+// publish fans a notification out to the other nodes.
+func (c *Cluster) publish(notification clusterNotification) {
+	notification.Node = c.policy.NodeID
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	defer cancel()
+
+	if err := c.pubSub.Publish(ctx, clusterChannel, notification); err != nil {
+		c.logger.Warn("Failed to fan out notification", "roomId", notification.RoomID, "userId", notification.UserID, "error", err)
+	}
+}
+
+// receive delivers a notification sent on another node to the clients connected to this one.
+func (c *Cluster) receive(channel string, payload []byte) {
+	var notification clusterNotification
+	if err := json.Unmarshal(payload, &notification); err != nil {
+		c.logger.Error("Failed to unmarshal cluster notification", err)
+		return
+	}
+	if notification.Node == c.policy.NodeID {
+		return
+	}
+
+	switch {
+	case notification.ChatShard != nil:
+		c.server.setLocalChatShard(notification.UserID, notification.RoomID, *notification.ChatShard)
+	case notification.Username != nil:
+		c.server.renameLocalUser(notification.UserID, *notification.Username)
+	case notification.RoomID != "":
+		c.server.hub.BroadcastTopicToRoom(notification.RoomID, notification.Topic, notification.Message)
+	case notification.UserID != "":
+		c.server.hub.BroadcastToUser(notification.UserID, notification.Message)
+	default:
+		c.server.hub.Broadcast(notification.Message)
+	}
+}
+
+// followRoom subscribes to a room's channel, so the events services publish to it reach the room's
+// clients on this node.
+func (c *Cluster) followRoom(roomID string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.rooms[roomID]; ok {
+		c.rooms[roomID] = time.Now()
+		return
+	}
+
+	channel := managers.FormatRoomChannel(roomID)
+	if err := c.pubSub.Subscribe(channel); err != nil {
+		c.logger.Error("Failed to follow room channel", err, "roomId", roomID)
+		return
+	}
+	c.pubSub.AddHandler(channel, c.forwardRoomEvent)
+	c.rooms[roomID] = time.Now()
+}
+
+// unfollowRooms unsubscribes from the channels of rooms no client on this node follows anymore.
+// Rooms followed since the last heartbeat are kept, their clients may not be in the hub yet.
+func (c *Cluster) unfollowRooms(now time.Time) {
+	followed := c.server.hub.GetRoomUsers()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for roomID, since := range c.rooms {
+		if _, ok := followed[roomID]; ok || now.Sub(since) < c.policy.Heartbeat {
+			continue
+		}
+
+		channel := managers.FormatRoomChannel(roomID)
+		if err := c.pubSub.Unsubscribe(channel); err != nil {
+			c.logger.Warn("Failed to unfollow room channel", "roomId", roomID, "error", err)
+			continue
+		}
+		c.pubSub.RemoveAllHandlers(channel)
+		delete(c.rooms, roomID)
+	}
+}
+
+// forwardRoomEvent sends an event published to a room's channel to the room's clients on this node.
+func (c *Cluster) forwardRoomEvent(channel string, payload []byte) {
+	var event roomEvent
+	if err := json.Unmarshal(payload, &event); err != nil || managers.FormatRoomChannel(event.RoomID) != channel {
+		c.logger.Warn("Ignoring malformed room event", "channel", channel)
+		return
+	}
+
+	// Messages posted in a chat shard only go to the clients in that shard, and those posted in a chat
+	// channel only to the clients following it
+	switch {
+	case event.ChatShard != nil:
+		c.server.notifyLocalChatShard(event.RoomID, *event.ChatShard, event.ChatChannel, EventRoomEvent, json.RawMessage(payload))
+		return
+	case event.ChatChannel != "":
+		c.server.notifyLocalChatChannel(event.RoomID, event.ChatChannel, EventRoomEvent, json.RawMessage(payload))
+		return
+	}
+
+	c.server.notifyLocalRoom(event.RoomID, roomEventTopic(event.Type), EventRoomEvent, json.RawMessage(payload))
+}
+
+// formatNodeClientsKey formats the key of a node's connection registry, the user IDs by client ID.
+func formatNodeClientsKey(nodeID string) string {
+	return fmt.Sprintf("%s:node:%s:clients", clusterKeyPrefix, nodeID)
+}
@@ -58,6 +58,9 @@ const (
 	// User already in room: The user is already in the room.
 	ErrUserAlreadyInRoom ErrorCode = -32104
 
+	// Chat restricted: The message breaks one of the room's chat modes.
+	ErrChatRestricted ErrorCode = -32105
+
 	// Media not found: The requested media does not exist.
 	ErrMediaNotFound ErrorCode = -32200
 
@@ -112,6 +115,8 @@ func (c ErrorCode) String() string {
 		return "User not in room"
 	case ErrUserAlreadyInRoom:
 		return "User already in room"
+	case ErrChatRestricted:
+		return "Chat restricted"
 	case ErrMediaNotFound:
 		return "Media not found"
 	case ErrMediaUnavailable:
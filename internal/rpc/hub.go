@@ -3,6 +3,7 @@ package rpc
 
 import (
 	"sync"
+	"time"
 
 	"norelock.dev/listenify/backend/internal/utils"
 )
@@ -21,8 +22,8 @@ type Hub struct {
 	// broadcast is a channel of messages to broadcast to all clients.
 	broadcast chan []byte
 
-	// roomBroadcast is a channel of messages to broadcast to a specific room.
-	roomBroadcast chan *roomMessage
+	// shards deliver the messages broadcast to rooms, each room always on the same shard.
+	shards []*broadcastShard
 
 	// userBroadcast is a channel of messages to broadcast to a specific user.
 	userBroadcast chan *userMessage
@@ -46,10 +47,25 @@ type Hub struct {
 	logger *utils.Logger
 }
 
+const (
+	// broadcastLatencyWeight is the weight of the latest room broadcast in the latency moving average.
+	broadcastLatencyWeight = 0.1
+
+	// broadcastLatencyStale is how long after the last room broadcast the latency average stops counting.
+	broadcastLatencyStale = time.Minute
+)
+
 // roomMessage represents a message to be broadcast to a room.
+// Messages with a topic only go to the clients following it in the room, sharded messages only to
+// the clients in their chat shard, and messages with a chat channel only to the clients following it.
 type roomMessage struct {
-	room    string
-	message []byte
+	room        string
+	topic       Topic
+	sharded     bool
+	chatShard   int
+	chatChannel string
+	message     []byte
+	queuedAt    time.Time
 }
 
 // userMessage represents a message to be broadcast to a user.
@@ -64,14 +80,13 @@ type roomOperation struct {
 	room   string
 }
 
-// NewHub creates a new hub.
-func NewHub(logger *utils.Logger) *Hub {
-	return &Hub{
+// NewHub creates a new hub, spreading room broadcasts across the policy's shards.
+func NewHub(policy BroadcastPolicy, logger *utils.Logger) *Hub {
+	h := &Hub{
 		clients:       make(map[*Client]bool),
 		rooms:         make(map[string]map[*Client]bool),
 		userClients:   make(map[string]map[*Client]bool),
 		broadcast:     make(chan []byte),
-		roomBroadcast: make(chan *roomMessage),
 		userBroadcast: make(chan *userMessage),
 		register:      make(chan *Client),
 		unregister:    make(chan *Client),
@@ -79,10 +94,20 @@ func NewHub(logger *utils.Logger) *Hub {
 		leave:         make(chan *roomOperation),
 		logger:        logger.Named("hub"),
 	}
+
+	h.shards = make([]*broadcastShard, max(policy.Shards, 1))
+	for i := range h.shards {
+		h.shards[i] = newBroadcastShard(i, h, policy.Backlog, h.logger)
+	}
+	return h
 }
 
-// Run starts the hub.
+// Run starts the hub and its broadcast shards.
 func (h *Hub) Run() {
+	for _, shard := range h.shards {
+		go shard.run()
+	}
+
 	for {
 		select {
 		case client := <-h.register:
@@ -94,9 +119,6 @@ func (h *Hub) Run() {
 		case message := <-h.broadcast:
 			h.broadcastMessage(message)
 
-		case rm := <-h.roomBroadcast:
-			h.broadcastToRoom(rm.room, rm.message)
-
 		case um := <-h.userBroadcast:
 			h.broadcastToUser(um.userID, um.message)
 
@@ -170,22 +192,32 @@ func (h *Hub) broadcastMessage(message []byte) {
 		select {
 		case client.send <- message:
 		default:
-			h.unregisterClient(client)
+			go client.Disconnect(slowConsumer)
 		}
 	}
 }
 
-// broadcastToRoom broadcasts a message to all clients in a room.
-func (h *Hub) broadcastToRoom(room string, message []byte) {
+// broadcastToRoom broadcasts a message to all clients in a room, only those following its topic if it has one,
+// those in its chat shard if it is sharded and those following its chat channel if it has one.
+func (h *Hub) broadcastToRoom(rm *roomMessage) {
 	h.mutex.RLock()
 	defer h.mutex.RUnlock()
 
-	if clients, ok := h.rooms[room]; ok {
+	if clients, ok := h.rooms[rm.room]; ok {
 		for client := range clients {
+			if rm.topic != "" && !client.FollowsTopic(rm.room, rm.topic) {
+				continue
+			}
+			if rm.sharded && client.ChatShard(rm.room) != rm.chatShard {
+				continue
+			}
+			if rm.chatChannel != "" && !client.FollowsChatChannel(rm.room, rm.chatChannel) {
+				continue
+			}
 			select {
-			case client.send <- message:
+			case client.send <- rm.message:
 			default:
-				h.unregisterClient(client)
+				go client.Disconnect(slowConsumer)
 			}
 		}
 	}
@@ -201,7 +233,7 @@ func (h *Hub) broadcastToUser(userID string, message []byte) {
 			select {
 			case client.send <- message:
 			default:
-				h.unregisterClient(client)
+				go client.Disconnect(slowConsumer)
 			}
 		}
 	}
@@ -245,6 +277,44 @@ func (h *Hub) removeClientFromRoom(client *Client, room string) {
 	h.logger.Debug("Client removed from room", "id", client.ID, "userID", client.UserID, "room", room)
 }
 
+// handOver moves a client's rooms to another client at once, so each message broadcast to the rooms
+// reaches exactly one of them, and returns the rooms moved. With moveQueued, the messages queued for the
+// first client and not yet taken are moved along, ahead of the ones broadcast afterwards.
+func (h *Hub) handOver(from, to *Client, moveQueued bool) []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	rooms := make([]string, 0, len(from.rooms))
+	for room := range from.rooms {
+		if clients, ok := h.rooms[room]; ok {
+			delete(clients, from)
+			clients[to] = true
+		} else {
+			h.rooms[room] = map[*Client]bool{to: true}
+		}
+		to.rooms[room] = true
+		delete(from.rooms, room)
+		rooms = append(rooms, room)
+	}
+
+	for moveQueued {
+		select {
+		case message := <-from.send:
+			select {
+			case to.send <- message:
+			default:
+				go to.Disconnect(slowConsumer)
+				moveQueued = false
+			}
+		default:
+			moveQueued = false
+		}
+	}
+
+	h.logger.Debug("Client rooms handed over", "from", from.ID, "to", to.ID, "rooms", len(rooms))
+	return rooms
+}
+
 // Broadcast sends a message to all connected clients.
 func (h *Hub) Broadcast(message []byte) {
 	h.broadcast <- message
@@ -252,7 +322,46 @@ func (h *Hub) Broadcast(message []byte) {
 
 // BroadcastToRoom sends a message to all clients in a room.
 func (h *Hub) BroadcastToRoom(room string, message []byte) {
-	h.roomBroadcast <- &roomMessage{room: room, message: message}
+	h.BroadcastTopicToRoom(room, "", message)
+}
+
+// BroadcastTopicToRoom sends a message about a topic to the clients in a room following it.
+// The message is queued on the room's shard, so it doesn't wait on the delivery to other rooms.
+func (h *Hub) BroadcastTopicToRoom(room string, topic Topic, message []byte) {
+	shard := h.shards[shardIndex(room, len(h.shards))]
+	shard.enqueue(&roomMessage{room: room, topic: topic, message: message, queuedAt: time.Now()})
+}
+
+// BroadcastToChatShard sends a chat message to the clients in a room's chat shard following the chat
+// and the message's chat channel.
+func (h *Hub) BroadcastToChatShard(room string, chatShard int, chatChannel string, message []byte) {
+	shard := h.shards[shardIndex(room, len(h.shards))]
+	shard.enqueue(&roomMessage{room: room, topic: TopicChat, sharded: true, chatShard: chatShard, chatChannel: chatChannel, message: message, queuedAt: time.Now()})
+}
+
+// BroadcastToChatChannel sends a chat message to the clients in a room following the chat and one of its chat channels.
+func (h *Hub) BroadcastToChatChannel(room string, chatChannel string, message []byte) {
+	shard := h.shards[shardIndex(room, len(h.shards))]
+	shard.enqueue(&roomMessage{room: room, topic: TopicChat, chatChannel: chatChannel, message: message, queuedAt: time.Now()})
+}
+
+// BroadcastLatency gets the moving average of how long room broadcasts take to reach every client,
+// on the slowest shard. It is zero when no room broadcast went out recently.
+func (h *Hub) BroadcastLatency() time.Duration {
+	var latency time.Duration
+	for _, shard := range h.shards {
+		latency = max(latency, shard.averageLatency())
+	}
+	return latency
+}
+
+// BroadcastShards describes the room broadcasts each shard delivered and has waiting.
+func (h *Hub) BroadcastShards() []BroadcastShardStats {
+	stats := make([]BroadcastShardStats, len(h.shards))
+	for i, shard := range h.shards {
+		stats[i] = shard.stats()
+	}
+	return stats
 }
 
 // BroadcastToUser sends a message to all clients of a user.
@@ -284,6 +393,62 @@ func (h *Hub) GetClientsInRoom(room string) []*Client {
 	return clients
 }
 
+// GetUserClients gets all clients of a user.
+func (h *Hub) GetUserClients(userID string) []*Client {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	clients := make([]*Client, 0, len(h.userClients[userID]))
+	for client := range h.userClients[userID] {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// RemoveUserFromRoom removes all clients of a user from a room, sending each of them a message.
+func (h *Hub) RemoveUserFromRoom(userID, room string, message []byte) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for client := range h.rooms[room] {
+		if client.UserID != userID {
+			continue
+		}
+
+		delete(h.rooms[room], client)
+		delete(client.rooms, room)
+
+		select {
+		case client.send <- message:
+		default:
+			go client.Disconnect(slowConsumer)
+		}
+	}
+
+	if len(h.rooms[room]) == 0 {
+		delete(h.rooms, room)
+	}
+}
+
+// GetRoomUsers gets the IDs of the users with clients in each room, by room.
+func (h *Hub) GetRoomUsers() map[string][]string {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	rooms := make(map[string][]string, len(h.rooms))
+	for room, clients := range h.rooms {
+		seen := make(map[string]bool, len(clients))
+		for client := range clients {
+			if client.UserID == "" || seen[client.UserID] {
+				continue
+			}
+			seen[client.UserID] = true
+			rooms[room] = append(rooms[room], client.UserID)
+		}
+	}
+	return rooms
+}
+
 // GetClientCount gets the number of connected clients.
 func (h *Hub) GetClientCount() int {
 	h.mutex.RLock()
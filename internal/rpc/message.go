@@ -33,6 +33,77 @@ type Response struct {
 	ID any `json:"id"`
 }
 
+// Notification methods sent to the clients of a room on every node.
+const (
+	// EventUserJoinedRoom tells a room's clients that a user joined it.
+	EventUserJoinedRoom = "room.userJoined"
+
+	// EventUserLeftRoom tells a room's clients that a user left it.
+	EventUserLeftRoom = "room.userLeft"
+
+	// EventRoomUsersChanged tells a large room's clients how many users joined and left it, in place of
+	// the join and leave notifications throttled during an interval.
+	EventRoomUsersChanged = "room.usersChanged"
+
+	// EventQueueUpdated tells a room's clients that its DJ queue changed.
+	EventQueueUpdated = "queue.updated"
+
+	// EventQueueLockChanged tells a room's clients that its DJ queue was locked or unlocked.
+	EventQueueLockChanged = "queue.lockChanged"
+
+	// EventQueueOptionsChanged tells a room's clients that the options of its DJ queue changed.
+	EventQueueOptionsChanged = "queue.optionsChanged"
+
+	// EventRolesChanged tells a room's clients that a user's role, or what the roles can do, changed.
+	EventRolesChanged = "room.rolesChanged"
+
+	// EventChatAppearanceChanged tells a room's clients that how a user's name shows in its chat changed.
+	EventChatAppearanceChanged = "room.chatAppearanceChanged"
+
+	// EventTrackSkipped tells a room's clients that its current media was skipped because the room voted it down.
+	EventTrackSkipped = "room.trackSkipped"
+
+	// EventTrackTruncated tells a room's clients that its current media was cut off at the room's maximum track length.
+	EventTrackTruncated = "room.trackTruncated"
+
+	// EventTrackPreload tells a room's clients which tracks the next DJs are expected to play, so they can buffer them ahead.
+	EventTrackPreload = "room.trackPreload"
+
+	// EventCrowdPick tells a room's clients that a track the room grabbed is replayed as a crowd pick.
+	EventCrowdPick = "room.crowdPick"
+
+	// EventRoomEventScheduled tells a room's clients that an event was scheduled in it.
+	EventRoomEventScheduled = "room.eventScheduled"
+
+	// EventRoomEventCancelled tells a room's clients that one of its scheduled events was cancelled.
+	EventRoomEventCancelled = "room.eventCancelled"
+
+	// EventUserRenamed tells a user's rooms, and the user's own clients, that the user changed their username.
+	EventUserRenamed = "user.renamed"
+
+	// EventPlaybackSync tells a room's clients where its current media is by the server's clock, so they can
+	// correct the drift of their playback.
+	EventPlaybackSync = "room.playbackSync"
+
+	// EventRoomEvent carries an event services published to a room's PubSub channel, such as a chat message.
+	EventRoomEvent = "room.event"
+)
+
+// Notification methods sent to the clients connected to a node.
+const (
+	// EventServerShutdown tells a node's clients that it is shutting down, and how to reconnect to another
+	// node before it disconnects them.
+	EventServerShutdown = "server.shutdown"
+)
+
+// Notification methods sent to a single connection.
+const (
+	// EventConnectionSession gives a client the token to resume its session with after reconnecting, and
+	// tells it whether the connection resumed a previous session.
+	EventConnectionSession = "connection.session"
+)
+
+// Notification represents a JSON-RPC 2.0 notification.
 type Notification struct {
 	// JSONRPC is the version of the JSON-RPC protocol. Must be "2.0".
 	JSONRPC string `json:"jsonrpc"`
@@ -54,6 +125,9 @@ type Error struct {
 
 	// Data is additional information about the error.
 	Data any `json:"data,omitempty"`
+
+	// TraceID identifies the failed call in the server logs.
+	TraceID string `json:"traceId,omitempty"`
 }
 
 // Error implements the error interface.
@@ -16,13 +16,17 @@ import (
 // ChatHandler handles chat-related RPC methods.
 type ChatHandler struct {
 	chatService room.ChatService
+	toxicity    *room.ToxicityModerator
+	moderation  *room.UndoableModeration
 	logger      *utils.Logger
 }
 
 // NewChatHandler creates a new ChatHandler.
-func NewChatHandler(chatService room.ChatService, logger *utils.Logger) *ChatHandler {
+func NewChatHandler(chatService room.ChatService, toxicity *room.ToxicityModerator, moderation *room.UndoableModeration, logger *utils.Logger) *ChatHandler {
 	return &ChatHandler{
 		chatService: chatService,
+		toxicity:    toxicity,
+		moderation:  moderation,
 		logger:      logger,
 	}
 }
@@ -31,8 +35,21 @@ func NewChatHandler(chatService room.ChatService, logger *utils.Logger) *ChatHan
 func (h *ChatHandler) RegisterMethods(hr rpc.HandlerRegistry) {
 	auth := hr.Wrap(rpc.AuthMiddleware)
 	rpc.Register(auth, "chat.sendMessage", h.SendMessage)
+	rpc.Register(auth, "chat.broadcast", h.Broadcast)
 	rpc.Register(auth, "chat.getMessages", h.GetMessages)
 	rpc.Register(auth, "chat.deleteMessage", h.DeleteMessage)
+	rpc.Register(auth, "chat.pinMessage", h.PinMessage)
+	rpc.Register(auth, "chat.unpinMessage", h.UnpinMessage)
+	rpc.Register(auth, "chat.getCommands", h.GetCommands)
+	rpc.Register(auth, "chat.setCommands", h.SetCommands)
+	rpc.Register(auth, "chat.setModes", h.SetModes)
+	rpc.Register(auth, "chat.getChannels", h.GetChannels)
+	rpc.Register(auth, "chat.createChannel", h.CreateChannel)
+	rpc.Register(auth, "chat.updateChannel", h.UpdateChannel)
+	rpc.Register(auth, "chat.deleteChannel", h.DeleteChannel)
+	rpc.Register(auth, "chat.getFlags", h.GetFlags)
+	rpc.Register(auth, "chat.reviewFlag", h.ReviewFlag)
+	rpc.Register(auth, "chat.getModerationLog", h.GetModerationLog)
 }
 
 // SendMessageParams represents the parameters for the sendMessage method.
@@ -40,6 +57,7 @@ type SendMessageParams struct {
 	RoomID  string `json:"roomId" validate:"required"`
 	Content string `json:"content" validate:"required,min=1,max=500"`
 	Type    string `json:"type,omitempty"`
+	Channel string `json:"channel,omitempty"`
 }
 
 // SendMessageResult represents the result of the sendMessage method.
@@ -87,6 +105,7 @@ func (h *ChatHandler) SendMessage(ctx context.Context, client *rpc.Client, p *Se
 		RoomID:    roomObjID,
 		Content:   p.Content,
 		Type:      messageType,
+		Channel:   p.Channel,
 		CreatedAt: time.Now(),
 	}
 
@@ -105,6 +124,45 @@ func (h *ChatHandler) SendMessage(ctx context.Context, client *rpc.Client, p *Se
 				Message: "You are not in this room",
 			}
 		}
+		if errors.Is(err, models.ErrListenerOnly) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrNotAuthorized,
+				Message: "Listener-only users cannot chat",
+			}
+		}
+		if errors.Is(err, models.ErrTrustLevelTooLow) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrNotAuthorized,
+				Message: "Your trust level is too low to post links",
+			}
+		}
+		if errors.Is(err, models.ErrChatDisabled) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrNotAuthorized,
+				Message: "Chat is disabled in this room",
+			}
+		}
+		if errors.Is(err, models.ErrUserMuted) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrNotAuthorized,
+				Message: "You are muted in this room",
+			}
+		}
+		if errors.Is(err, models.ErrMessageRateLimited) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrRateLimitExceeded,
+				Message: "You are sending messages too fast",
+			}
+		}
+		if rpcErr := chatModeError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		if rpcErr := chatChannelError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		if rpcErr := commandError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
 		h.logger.Error("Failed to send message", err, "roomId", p.RoomID, "userId", client.UserID)
 		return nil, &rpc.Error{
 			Code:    rpc.ErrInternalError,
@@ -120,9 +178,10 @@ func (h *ChatHandler) SendMessage(ctx context.Context, client *rpc.Client, p *Se
 
 // GetMessagesParams represents the parameters for the getMessages method.
 type GetMessagesParams struct {
-	RoomID string `json:"roomId" validate:"required"`
-	Limit  int    `json:"limit,omitempty"`
-	Before string `json:"before,omitempty"`
+	RoomID  string `json:"roomId" validate:"required"`
+	Channel string `json:"channel,omitempty"`
+	Limit   int    `json:"limit,omitempty"`
+	Before  string `json:"before,omitempty"`
 }
 
 // GetMessagesResult represents the result of the getMessages method.
@@ -148,7 +207,7 @@ func (h *ChatHandler) GetMessages(ctx context.Context, client *rpc.Client, p *Ge
 	}
 
 	// Get messages
-	messages, err := h.chatService.GetMessages(ctx, p.RoomID, limit, p.Before)
+	messages, err := h.chatService.GetMessages(ctx, p.RoomID, p.Channel, limit, p.Before)
 	if err != nil {
 		if errors.Is(err, models.ErrRoomNotFound) {
 			return nil, &rpc.Error{
@@ -162,6 +221,9 @@ func (h *ChatHandler) GetMessages(ctx context.Context, client *rpc.Client, p *Ge
 				Message: "You are not in this room",
 			}
 		}
+		if rpcErr := chatChannelError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
 		h.logger.Error("Failed to get messages", err, "roomId", p.RoomID, "userId", client.UserID)
 		return nil, &rpc.Error{
 			Code:    rpc.ErrInternalError,
@@ -197,8 +259,8 @@ func (h *ChatHandler) DeleteMessage(ctx context.Context, client *rpc.Client, p *
 		}
 	}
 
-	// Delete message
-	err := h.chatService.DeleteMessage(ctx, p.RoomID, p.MessageID, client.UserID)
+	// Delete message, moderators can undo deleting other users' messages
+	err := h.moderation.DeleteMessage(ctx, p.RoomID, p.MessageID, client.UserID)
 	if err != nil {
 		if errors.Is(err, models.ErrRoomNotFound) {
 			return nil, &rpc.Error{
@@ -206,7 +268,7 @@ func (h *ChatHandler) DeleteMessage(ctx context.Context, client *rpc.Client, p *
 				Message: "Room not found",
 			}
 		}
-		if errors.Is(err, room.ErrMessageNotFound) {
+		if errors.Is(err, room.ErrMessageNotFound) || errors.Is(err, models.ErrMessageNotFound) {
 			return nil, &rpc.Error{
 				Code:    rpc.ErrInvalidParams,
 				Message: "Message not found",
@@ -230,3 +292,732 @@ func (h *ChatHandler) DeleteMessage(ctx context.Context, client *rpc.Client, p *
 		Success: true,
 	}, nil
 }
+
+// PinMessageParams represents the parameters for the pinMessage and unpinMessage methods.
+type PinMessageParams struct {
+	RoomID    string `json:"roomId" validate:"required"`
+	MessageID string `json:"messageId" validate:"required"`
+}
+
+// PinMessageResult represents the result of the pinMessage method.
+type PinMessageResult struct {
+	Message models.ChatMessage `json:"message"`
+}
+
+// UnpinMessageResult represents the result of the unpinMessage method.
+type UnpinMessageResult struct {
+	Success bool `json:"success"`
+}
+
+// PinMessage handles pinning a chat message.
+func (h *ChatHandler) PinMessage(ctx context.Context, client *rpc.Client, p *PinMessageParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	// Pin message
+	message, err := h.chatService.PinMessage(ctx, p.RoomID, p.MessageID, client.UserID)
+	if err != nil {
+		if errors.Is(err, models.ErrPinLimitReached) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrInvalidParams,
+				Message: "Pinned message limit reached",
+			}
+		}
+		if rpcErr := pinError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to pin message", err, "roomId", p.RoomID, "messageId", p.MessageID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to pin message",
+		}
+	}
+
+	// Return pinned message
+	return PinMessageResult{
+		Message: message,
+	}, nil
+}
+
+// UnpinMessage handles unpinning a chat message.
+func (h *ChatHandler) UnpinMessage(ctx context.Context, client *rpc.Client, p *PinMessageParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	// Unpin message
+	err := h.chatService.UnpinMessage(ctx, p.RoomID, p.MessageID, client.UserID)
+	if err != nil {
+		if rpcErr := pinError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to unpin message", err, "roomId", p.RoomID, "messageId", p.MessageID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to unpin message",
+		}
+	}
+
+	// Return success
+	return UnpinMessageResult{
+		Success: true,
+	}, nil
+}
+
+// pinError maps the errors shared by pinMessage and unpinMessage, returning nil for unexpected errors.
+func pinError(err error) *rpc.Error {
+	switch {
+	case errors.Is(err, models.ErrInvalidID):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid ID",
+		}
+	case errors.Is(err, models.ErrRoomNotFound):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Room not found",
+		}
+	case errors.Is(err, models.ErrMessageNotFound):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Message not found",
+		}
+	case errors.Is(err, room.ErrNotAuthorized):
+		return &rpc.Error{
+			Code:    rpc.ErrNotAuthorized,
+			Message: "Only the room owner and moderators can pin messages",
+		}
+	}
+	return nil
+}
+
+// GetCommandsParams represents the parameters for the getCommands method.
+type GetCommandsParams struct {
+	RoomID string `json:"roomId" validate:"required"`
+}
+
+// CommandsResult represents the result of the getCommands and setCommands methods.
+type CommandsResult struct {
+	Commands []models.ChatCommand `json:"commands"`
+}
+
+// GetCommands handles listing the chat commands available in a room.
+func (h *ChatHandler) GetCommands(ctx context.Context, client *rpc.Client, p *GetCommandsParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	commands, err := h.chatService.GetCommands(ctx, p.RoomID)
+	if err != nil {
+		if rpcErr := commandError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to get chat commands", err, "roomId", p.RoomID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to get chat commands",
+		}
+	}
+
+	return CommandsResult{
+		Commands: commands,
+	}, nil
+}
+
+// SetCommandsParams represents the parameters for the setCommands method.
+type SetCommandsParams struct {
+	RoomID   string                      `json:"roomId" validate:"required"`
+	Commands []models.ChatCommandRequest `json:"commands"`
+}
+
+// SetCommands handles replacing a room's custom chat commands (room owner only).
+func (h *ChatHandler) SetCommands(ctx context.Context, client *rpc.Client, p *SetCommandsParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	commands, err := h.chatService.SetCustomCommands(ctx, p.RoomID, client.UserID, p.Commands)
+	if err != nil {
+		if errors.Is(err, room.ErrNotAuthorized) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrNotAuthorized,
+				Message: "Only the room owner can define custom commands",
+			}
+		}
+		if rpcErr := commandError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to set chat commands", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to set chat commands",
+		}
+	}
+
+	return CommandsResult{
+		Commands: commands,
+	}, nil
+}
+
+// BroadcastParams represents the parameters for the broadcast method.
+type BroadcastParams struct {
+	RoomID  string `json:"roomId" validate:"required"`
+	Content string `json:"content" validate:"required,min=1,max=500"`
+}
+
+// Broadcast handles posting a message to the main chat and every overflow chat shard of a room (room staff only).
+func (h *ChatHandler) Broadcast(ctx context.Context, client *rpc.Client, p *BroadcastParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	message, err := h.chatService.BroadcastMessage(ctx, p.RoomID, client.UserID, p.Content)
+	if err != nil {
+		if errors.Is(err, room.ErrNotAuthorized) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrNotAuthorized,
+				Message: "Only the room's owner and moderators can broadcast to every chat shard",
+			}
+		}
+		if rpcErr := commandError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to broadcast chat message", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to broadcast message",
+		}
+	}
+
+	return SendMessageResult{
+		Message: message,
+	}, nil
+}
+
+// SetModesParams represents the parameters for the setModes method.
+type SetModesParams struct {
+	RoomID    string           `json:"roomId" validate:"required"`
+	ChatModes models.ChatModes `json:"chatModes"`
+}
+
+// ModesResult represents the result of the setModes method.
+type ModesResult struct {
+	ChatModes models.ChatModes `json:"chatModes"`
+}
+
+// SetModes handles changing the content restrictions of a room's chat (room staff only).
+func (h *ChatHandler) SetModes(ctx context.Context, client *rpc.Client, p *SetModesParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	modes, err := h.chatService.SetChatModes(ctx, p.RoomID, client.UserID, p.ChatModes)
+	if err != nil {
+		if errors.Is(err, room.ErrNotAuthorized) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrNotAuthorized,
+				Message: "Only the room's owner and moderators can change chat modes",
+			}
+		}
+		if rpcErr := commandError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to set chat modes", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to set chat modes",
+		}
+	}
+
+	return ModesResult{
+		ChatModes: modes,
+	}, nil
+}
+
+// GetChannelsParams represents the parameters for the getChannels method.
+type GetChannelsParams struct {
+	RoomID string `json:"roomId" validate:"required"`
+}
+
+// ChannelsResult represents the result of the getChannels method.
+type ChannelsResult struct {
+	Channels []models.ChatChannel `json:"channels"`
+}
+
+// GetChannels handles listing the chat channels of a room.
+func (h *ChatHandler) GetChannels(ctx context.Context, client *rpc.Client, p *GetChannelsParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	channels, err := h.chatService.GetChatChannels(ctx, p.RoomID)
+	if err != nil {
+		if rpcErr := commandError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to get chat channels", err, "roomId", p.RoomID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to get chat channels",
+		}
+	}
+
+	return ChannelsResult{
+		Channels: channels,
+	}, nil
+}
+
+// ChannelParams represents the parameters for the createChannel and updateChannel methods.
+type ChannelParams struct {
+	RoomID  string                    `json:"roomId" validate:"required"`
+	Channel models.ChatChannelRequest `json:"channel"`
+}
+
+// ChannelResult represents the result of the createChannel and updateChannel methods.
+type ChannelResult struct {
+	Channel models.ChatChannel `json:"channel"`
+}
+
+// CreateChannel handles adding a named channel to a room's chat (room staff only).
+func (h *ChatHandler) CreateChannel(ctx context.Context, client *rpc.Client, p *ChannelParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	channel, err := h.chatService.CreateChatChannel(ctx, p.RoomID, client.UserID, p.Channel)
+	if err != nil {
+		if rpcErr := channelChangeError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to create chat channel", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to create chat channel",
+		}
+	}
+
+	return ChannelResult{
+		Channel: channel,
+	}, nil
+}
+
+// UpdateChannel handles changing the description and post role of a room's named chat channel (room staff only).
+func (h *ChatHandler) UpdateChannel(ctx context.Context, client *rpc.Client, p *ChannelParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	channel, err := h.chatService.UpdateChatChannel(ctx, p.RoomID, client.UserID, p.Channel)
+	if err != nil {
+		if rpcErr := channelChangeError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to update chat channel", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to update chat channel",
+		}
+	}
+
+	return ChannelResult{
+		Channel: channel,
+	}, nil
+}
+
+// DeleteChannelParams represents the parameters for the deleteChannel method.
+type DeleteChannelParams struct {
+	RoomID string `json:"roomId" validate:"required"`
+	Name   string `json:"name" validate:"required"`
+}
+
+// DeleteChannel handles removing a named channel from a room's chat (room staff only).
+func (h *ChatHandler) DeleteChannel(ctx context.Context, client *rpc.Client, p *DeleteChannelParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	if err := h.chatService.DeleteChatChannel(ctx, p.RoomID, client.UserID, p.Name); err != nil {
+		if rpcErr := channelChangeError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to delete chat channel", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to delete chat channel",
+		}
+	}
+
+	return DeleteMessageResult{
+		Success: true,
+	}, nil
+}
+
+// channelChangeError maps the errors of changing a room's chat channels, returning nil for unexpected errors.
+func channelChangeError(err error) *rpc.Error {
+	if errors.Is(err, room.ErrNotAuthorized) {
+		return &rpc.Error{
+			Code:    rpc.ErrNotAuthorized,
+			Message: "Only the room's owner and moderators can manage chat channels",
+		}
+	}
+	if rpcErr := chatChannelError(err); rpcErr != nil {
+		return rpcErr
+	}
+	return commandError(err)
+}
+
+// maxChatFlagsListed caps the number of flags or moderation history records listed in one request.
+const maxChatFlagsListed = 100
+
+// GetFlagsParams represents the parameters for the getFlags method.
+type GetFlagsParams struct {
+	RoomID string `json:"roomId" validate:"required"`
+	Status string `json:"status,omitempty" validate:"omitempty,oneof=pending dismissed removed"`
+	Offset int    `json:"offset,omitempty" validate:"min=0"`
+	Limit  int    `json:"limit,omitempty" validate:"min=0,max=100"`
+}
+
+// GetFlagsResult represents the result of the getFlags method.
+type GetFlagsResult struct {
+	Flags []*models.ChatFlag `json:"flags"`
+	Total int64              `json:"total"`
+}
+
+// GetFlags handles listing a room's queue of chat messages flagged by automated moderation (room staff only).
+func (h *ChatHandler) GetFlags(ctx context.Context, client *rpc.Client, p *GetFlagsParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	roomID, userID, rpcErr := parseRoomAndUser(p.RoomID, client.UserID)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	limit := p.Limit
+	if limit == 0 {
+		limit = maxChatFlagsListed
+	}
+
+	flags, total, err := h.toxicity.GetFlags(ctx, roomID, userID, models.ChatFlagStatus(p.Status), p.Offset, limit)
+	if err != nil {
+		if rpcErr := flagError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to get chat flags", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to get chat flags",
+		}
+	}
+
+	return GetFlagsResult{
+		Flags: flags,
+		Total: total,
+	}, nil
+}
+
+// ReviewFlagParams represents the parameters for the reviewFlag method.
+type ReviewFlagParams struct {
+	RoomID string `json:"roomId" validate:"required"`
+	FlagID string `json:"flagId" validate:"required"`
+	models.ChatFlagReview
+}
+
+// ReviewFlagResult represents the result of the reviewFlag method.
+type ReviewFlagResult struct {
+	Flag *models.ChatFlag `json:"flag"`
+}
+
+// ReviewFlag handles a moderator's decision on a flagged chat message (room staff only).
+func (h *ChatHandler) ReviewFlag(ctx context.Context, client *rpc.Client, p *ReviewFlagParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	roomID, userID, rpcErr := parseRoomAndUser(p.RoomID, client.UserID)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	flagID, err := bson.ObjectIDFromHex(p.FlagID)
+	if err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid flag ID",
+		}
+	}
+
+	flag, err := h.toxicity.ReviewFlag(ctx, roomID, flagID, userID, &p.ChatFlagReview)
+	if err != nil {
+		if rpcErr := flagError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to review chat flag", err, "roomId", p.RoomID, "flagId", p.FlagID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to review chat flag",
+		}
+	}
+
+	return ReviewFlagResult{
+		Flag: flag,
+	}, nil
+}
+
+// GetModerationLogParams represents the parameters for the getModerationLog method.
+type GetModerationLogParams struct {
+	RoomID string `json:"roomId" validate:"required"`
+	Offset int    `json:"offset,omitempty" validate:"min=0"`
+	Limit  int    `json:"limit,omitempty" validate:"min=0,max=100"`
+}
+
+// GetModerationLogResult represents the result of the getModerationLog method.
+type GetModerationLogResult struct {
+	Entries []*models.ModerationHistory `json:"entries"`
+}
+
+// GetModerationLog handles listing a room's moderation history, automated actions included (room staff only).
+func (h *ChatHandler) GetModerationLog(ctx context.Context, client *rpc.Client, p *GetModerationLogParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	roomID, userID, rpcErr := parseRoomAndUser(p.RoomID, client.UserID)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	limit := p.Limit
+	if limit == 0 {
+		limit = maxChatFlagsListed
+	}
+
+	entries, err := h.toxicity.GetModerationLog(ctx, roomID, userID, p.Offset, limit)
+	if err != nil {
+		if rpcErr := flagError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to get moderation log", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to get moderation log",
+		}
+	}
+	if entries == nil {
+		entries = []*models.ModerationHistory{}
+	}
+
+	return GetModerationLogResult{
+		Entries: entries,
+	}, nil
+}
+
+// parseRoomAndUser parses the room ID of a request and the ID of the client's user.
+func parseRoomAndUser(roomID, userID string) (bson.ObjectID, bson.ObjectID, *rpc.Error) {
+	roomObjID, err := bson.ObjectIDFromHex(roomID)
+	if err != nil {
+		return bson.ObjectID{}, bson.ObjectID{}, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid room ID",
+		}
+	}
+
+	userObjID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return bson.ObjectID{}, bson.ObjectID{}, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid user ID",
+		}
+	}
+
+	return roomObjID, userObjID, nil
+}
+
+// flagError maps the errors of the moderation queue methods, returning nil for unexpected errors.
+func flagError(err error) *rpc.Error {
+	switch {
+	case errors.Is(err, models.ErrRoomNotFound):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Room not found",
+		}
+	case errors.Is(err, models.ErrChatFlagNotFound):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Flag not found",
+		}
+	case errors.Is(err, models.ErrChatFlagReviewed),
+		errors.Is(err, models.ErrInvalidInput):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: err.Error(),
+		}
+	case errors.Is(err, room.ErrNotAuthorized):
+		return &rpc.Error{
+			Code:    rpc.ErrNotAuthorized,
+			Message: "Only the room's owner and moderators can review flagged messages",
+		}
+	}
+	return nil
+}
+
+// chatModeError maps messages rejected by a room's chat modes or the minor policy, returning nil for other errors.
+// The data names the mode, so clients can tell the user what to change.
+func chatModeError(err error) *rpc.Error {
+	var mode string
+	switch {
+	case errors.Is(err, models.ErrChatLinksDisabled):
+		mode = "linksDisabled"
+	case errors.Is(err, models.ErrChatImagesDisabled):
+		mode = "imagesDisabled"
+	case errors.Is(err, models.ErrChatEmojiOnly):
+		mode = "emojiOnly"
+	case errors.Is(err, models.ErrChatFiltered):
+		mode = "filtered"
+	case errors.Is(err, models.ErrMinorRestricted):
+		mode = "minor"
+	case errors.Is(err, models.ErrChatChannelRestricted):
+		mode = "channel"
+	default:
+		return nil
+	}
+
+	message := err.Error()
+	var domainErr *models.DomainError
+	if errors.As(err, &domainErr) {
+		message = domainErr.Message
+	}
+
+	return &rpc.Error{
+		Code:    rpc.ErrChatRestricted,
+		Message: message,
+		Data:    map[string]string{"mode": mode},
+	}
+}
+
+// chatChannelError maps chat channel errors, returning nil for unexpected errors.
+func chatChannelError(err error) *rpc.Error {
+	switch {
+	case errors.Is(err, models.ErrChatChannelNotFound):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Chat channel not found",
+		}
+	case errors.Is(err, models.ErrChatChannelExists):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "A chat channel with this name already exists",
+		}
+	case errors.Is(err, models.ErrInvalidChatChannel):
+		message := err.Error()
+		var domainErr *models.DomainError
+		if errors.As(err, &domainErr) {
+			message = domainErr.Message
+		}
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: message,
+		}
+	}
+	return nil
+}
+
+// commandError maps chat command errors, returning nil for unexpected errors.
+func commandError(err error) *rpc.Error {
+	switch {
+	case errors.Is(err, models.ErrInvalidID):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid ID",
+		}
+	case errors.Is(err, models.ErrRoomNotFound):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Room not found",
+		}
+	case errors.Is(err, models.ErrUserNotFound):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "User not found",
+		}
+	case errors.Is(err, models.ErrInvalidCommand),
+		errors.Is(err, models.ErrCommandDisabled):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: err.Error(),
+		}
+	case errors.Is(err, models.ErrInsufficientPermission):
+		return &rpc.Error{
+			Code:    rpc.ErrNotAuthorized,
+			Message: "You are not allowed to use this command",
+		}
+	}
+	return nil
+}
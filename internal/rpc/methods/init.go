@@ -20,21 +20,39 @@ func RegisterAllMethods(
 	authProvider auth.Provider,
 	sessionMgr managers.SessionManager,
 	userManager *user.Manager,
+	socialService *user.SocialService,
 	statsService *user.StatsService,
+	apiKeyService *user.APIKeyService,
+	scrobbleService *user.ScrobbleService,
+	deletionService *user.AccountDeletionService,
+	dataExporter *user.DataExportService,
 	playlistManager *playlist.Manager,
 	mediaResolver *media.Resolver,
+	lyricsService *media.LyricsService,
 	roomManager *room.Manager,
 	chatService room.ChatService,
+	toxicityModerator *room.ToxicityModerator,
+	undoableModeration *room.UndoableModeration,
 	queueManager *room.QueueManager,
+	vibeService *room.VibeService,
+	rotationReporter *room.RotationReporter,
+	reportService *room.RoomReportService,
+	captchaService *user.CaptchaService,
+	calendarService *room.CalendarService,
+	listenerGeoMgr *managers.ListenerGeoManager,
+	joinPolicy JoinPolicy,
 	logger *utils.Logger,
 ) {
 	// Create handlers
-	userHandler := NewUserHandler(*userManager, statsService, logger)
-	chatHandler := NewChatHandler(chatService, logger)
-	mediaHandler := NewMediaHandler(mediaResolver, logger)
+	userHandler := NewUserHandler(*userManager, socialService, statsService, apiKeyService, scrobbleService, deletionService, dataExporter, logger)
+	chatHandler := NewChatHandler(chatService, toxicityModerator, undoableModeration, logger)
+	moderationHandler := NewModerationHandler(chatService, undoableModeration, logger)
+	mediaHandler := NewMediaHandler(mediaResolver, lyricsService, playlistManager, userManager, logger)
 	playlistHandler := NewPlaylistHandler(playlistManager, userManager, logger)
 	queueHandler := NewQueueHandler(queueManager, logger)
-	roomHandler := NewRoomHandler(roomManager, logger)
+	vibeHandler := NewVibeHandler(vibeService, logger)
+	rotationHandler := NewRotationHandler(rotationReporter, logger)
+	roomHandler := NewRoomHandler(roomManager, userManager, chatService, queueManager, reportService, captchaService, calendarService, listenerGeoMgr, joinPolicy, logger)
 
 	hr := router.Wrap(rpc.RecoveryMiddleware(logger)).Wrap(rpc.LoggingMiddleware(logger))
 
@@ -43,9 +61,12 @@ func RegisterAllMethods(
 
 	userHandler.RegisterMethods(hr)
 	chatHandler.RegisterMethods(hr)
+	moderationHandler.RegisterMethods(hr)
 	mediaHandler.RegisterMethods(hr)
 	playlistHandler.RegisterMethods(hr)
 	queueHandler.RegisterMethods(hr)
+	vibeHandler.RegisterMethods(hr)
+	rotationHandler.RegisterMethods(hr)
 	roomHandler.RegisterMethods(hr)
 	logger.Info("Registered all RPC methods")
 }
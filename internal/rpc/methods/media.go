@@ -3,25 +3,34 @@ package methods
 
 import (
 	"context"
+	"errors"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"norelock.dev/listenify/backend/internal/models"
 	"norelock.dev/listenify/backend/internal/rpc"
 	"norelock.dev/listenify/backend/internal/services/media"
+	"norelock.dev/listenify/backend/internal/services/playlist"
+	"norelock.dev/listenify/backend/internal/services/user"
 	"norelock.dev/listenify/backend/internal/utils"
 )
 
 // MediaHandler handles media-related RPC methods.
 type MediaHandler struct {
-	mediaResolver *media.Resolver
-	logger        *utils.Logger
+	mediaResolver   *media.Resolver
+	lyricsService   *media.LyricsService
+	playlistManager *playlist.Manager
+	userManager     *user.Manager
+	logger          *utils.Logger
 }
 
-// NewMediaHandler creates a new MediaHandler.
-func NewMediaHandler(mediaResolver *media.Resolver, logger *utils.Logger) *MediaHandler {
+// NewMediaHandler creates a new MediaHandler. The lyrics service is nil when no lyrics provider is configured.
+func NewMediaHandler(mediaResolver *media.Resolver, lyricsService *media.LyricsService, playlistManager *playlist.Manager, userManager *user.Manager, logger *utils.Logger) *MediaHandler {
 	return &MediaHandler{
-		mediaResolver: mediaResolver,
-		logger:        logger,
+		mediaResolver:   mediaResolver,
+		lyricsService:   lyricsService,
+		playlistManager: playlistManager,
+		userManager:     userManager,
+		logger:          logger,
 	}
 }
 
@@ -31,6 +40,11 @@ func (h *MediaHandler) RegisterMethods(hr rpc.HandlerRegistry) {
 	rpc.Register(hr, "media.search", h.SearchMedia)
 	rpc.Register(auth, "media.getInfo", h.GetMediaInfo)
 	rpc.Register(auth, "media.getStreamURL", h.GetStreamURL)
+	rpc.Register(hr, "media.getCanonical", h.GetCanonical)
+	rpc.Register(hr, "media.parseUrl", h.ParseURL)
+	rpc.Register(auth, "media.getAlternatives", h.GetAlternatives)
+	rpc.Register(auth, "media.relinkItem", h.RelinkItem)
+	rpc.Register(auth, "media.getLyrics", h.GetLyrics)
 }
 
 // SearchMediaParams represents the parameters for the searchMedia method.
@@ -47,6 +61,9 @@ type SearchMediaResult struct {
 	TotalResults  int                        `json:"totalResults"`
 	Source        string                     `json:"source"`
 	Query         string                     `json:"query"`
+	// Partial is set when some providers were skipped, e.g. because their daily search budget is spent
+	Partial          bool     `json:"partial,omitempty"`
+	SkippedProviders []string `json:"skippedProviders,omitempty"`
 }
 
 // SearchMedia handles searching for media across different providers.
@@ -70,16 +87,36 @@ func (h *MediaHandler) SearchMedia(ctx context.Context, client *rpc.Client, p *S
 		}
 	}
 
+	// Age-restricted results are only shown to users who attested being adults
+	if !h.isAdult(ctx, client.UserID) {
+		response = media.WithoutAgeRestricted(response)
+	}
+
 	// Return search results
 	return SearchMediaResult{
-		Results:       response.Results,
-		NextPageToken: response.NextPageToken,
-		TotalResults:  response.TotalResults,
-		Source:        response.Source,
-		Query:         response.Query,
+		Results:          response.Results,
+		NextPageToken:    response.NextPageToken,
+		TotalResults:     response.TotalResults,
+		Source:           response.Source,
+		Query:            response.Query,
+		Partial:          response.Partial,
+		SkippedProviders: response.SkippedProviders,
 	}, nil
 }
 
+// isAdult checks whether a user attested being an adult. Anonymous clients are not.
+func (h *MediaHandler) isAdult(ctx context.Context, userID string) bool {
+	if userID == "" {
+		return false
+	}
+	adult, err := h.userManager.IsAdult(ctx, userID)
+	if err != nil {
+		h.logger.Error("Failed to check user age", err, "userID", userID)
+		return false
+	}
+	return adult
+}
+
 // GetMediaInfoParams represents the parameters for the getMediaInfo method.
 type GetMediaInfoParams struct {
 	Source   string `json:"source" validate:"required,oneof=youtube soundcloud"`
@@ -114,6 +151,9 @@ func (h *MediaHandler) GetMediaInfo(ctx context.Context, client *rpc.Client, p *
 	// Resolve media
 	mediaItem, err := h.mediaResolver.Resolve(ctx, p.Source, p.SourceID, userObjID)
 	if err != nil {
+		if errors.Is(err, models.ErrMediaTakenDown) {
+			return nil, rpc.NewError(rpc.ErrMediaUnavailable, "media was taken down after a content complaint", nil)
+		}
 		h.logger.Error("Failed to resolve media", err, "source", p.Source, "sourceId", p.SourceID)
 		return nil, &rpc.Error{
 			Code:    rpc.ErrInternalError,
@@ -164,3 +204,292 @@ func (h *MediaHandler) GetStreamURL(ctx context.Context, client *rpc.Client, p *
 		URL: url,
 	}, nil
 }
+
+// GetCanonicalParams represents the parameters for the getCanonical method.
+type GetCanonicalParams struct {
+	MediaID string `json:"mediaId" validate:"required"`
+}
+
+// GetCanonical handles retrieving the song a media item belongs to across all providers.
+func (h *MediaHandler) GetCanonical(ctx context.Context, client *rpc.Client, p *GetCanonicalParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	mediaID, err := bson.ObjectIDFromHex(p.MediaID)
+	if err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid media ID",
+		}
+	}
+
+	canonical, err := h.mediaResolver.GetCanonical(ctx, mediaID)
+	if err != nil {
+		if errors.Is(err, models.ErrMediaNotFound) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrInvalidParams,
+				Message: "Media not found",
+			}
+		}
+		h.logger.Error("Failed to get canonical media", err, "mediaId", p.MediaID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to get canonical media",
+		}
+	}
+
+	return canonical, nil
+}
+
+// ParseURLParams represents the parameters for the parseUrl method.
+type ParseURLParams struct {
+	URL string `json:"url" validate:"required"`
+}
+
+// ParseURL handles normalizing a pasted media URL into the provider and source ID it points at, so clients
+// can reject links that can't be added before resolving them.
+func (h *MediaHandler) ParseURL(ctx context.Context, client *rpc.Client, p *ParseURLParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	sourceURL, err := media.ParseSourceURL(p.URL)
+	if err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Unsupported media URL",
+			Data:    err.Error(),
+		}
+	}
+
+	return sourceURL, nil
+}
+
+// GetAlternativesParams represents the parameters for the getAlternatives method.
+type GetAlternativesParams struct {
+	MediaID string `json:"mediaId" validate:"required"`
+	Limit   int    `json:"limit" validate:"min=0,max=20"`
+}
+
+// GetAlternativesResult represents the result of the getAlternatives method.
+type GetAlternativesResult struct {
+	Alternatives []models.MediaSearchResult `json:"alternatives"`
+}
+
+// GetAlternatives handles suggesting other sources of the same song, to re-link playlist items whose source is gone.
+func (h *MediaHandler) GetAlternatives(ctx context.Context, client *rpc.Client, p *GetAlternativesParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	mediaID, err := bson.ObjectIDFromHex(p.MediaID)
+	if err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid media ID",
+		}
+	}
+
+	alternatives, err := h.mediaResolver.FindAlternatives(ctx, mediaID, p.Limit)
+	if err != nil {
+		if errors.Is(err, models.ErrMediaNotFound) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrInvalidParams,
+				Message: "Media not found",
+			}
+		}
+		h.logger.Error("Failed to find media alternatives", err, "mediaId", p.MediaID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to find media alternatives",
+		}
+	}
+
+	return GetAlternativesResult{
+		Alternatives: alternatives,
+	}, nil
+}
+
+// GetLyricsParams represents the parameters for the getLyrics method.
+type GetLyricsParams struct {
+	MediaID string `json:"mediaId" validate:"required"`
+}
+
+// GetLyrics handles retrieving the lyrics of a media item, with the time of each line when the provider has them.
+func (h *MediaHandler) GetLyrics(ctx context.Context, client *rpc.Client, p *GetLyricsParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	mediaID, err := bson.ObjectIDFromHex(p.MediaID)
+	if err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid media ID",
+		}
+	}
+
+	if h.lyricsService == nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrMediaNotFound,
+			Message: "Lyrics not found",
+		}
+	}
+
+	lyrics, err := h.lyricsService.GetLyrics(ctx, mediaID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrMediaNotFound):
+			return nil, &rpc.Error{
+				Code:    rpc.ErrMediaNotFound,
+				Message: "Media not found",
+			}
+		case errors.Is(err, models.ErrLyricsNotFound):
+			return nil, &rpc.Error{
+				Code:    rpc.ErrMediaNotFound,
+				Message: "Lyrics not found",
+			}
+		}
+		h.logger.Error("Failed to get lyrics", err, "mediaId", p.MediaID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to get lyrics",
+		}
+	}
+
+	return lyrics, nil
+}
+
+// RelinkItemParams represents the parameters for the relinkItem method.
+type RelinkItemParams struct {
+	PlaylistID string `json:"playlistId" validate:"required"`
+	ItemID     string `json:"itemId" validate:"required"`
+	Source     string `json:"source" validate:"required,oneof=youtube soundcloud"`
+	SourceID   string `json:"sourceId" validate:"required"`
+}
+
+// RelinkItemResult represents the result of the relinkItem method.
+type RelinkItemResult struct {
+	Item  models.PlaylistItem `json:"item"`
+	Media *models.Media       `json:"media"`
+}
+
+// RelinkItem handles pointing a playlist item at a different source, keeping its play statistics
+// and position instead of losing them to removing the item and adding the new source.
+func (h *MediaHandler) RelinkItem(ctx context.Context, client *rpc.Client, p *RelinkItemParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	// Convert IDs to ObjectIDs
+	playlistObjID, err := bson.ObjectIDFromHex(p.PlaylistID)
+	if err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid playlist ID",
+		}
+	}
+
+	itemObjID, err := bson.ObjectIDFromHex(p.ItemID)
+	if err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid item ID",
+		}
+	}
+
+	userObjID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid user ID",
+		}
+	}
+
+	// Get playlist
+	existing, err := h.playlistManager.GetPlaylist(ctx, playlistObjID)
+	if err != nil {
+		h.logger.Error("Failed to get playlist", err, "playlistId", p.PlaylistID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Playlist not found",
+		}
+	}
+
+	// Check if user is the owner
+	if existing.Owner != userObjID {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrNotAuthorized,
+			Message: "You do not have permission to modify this playlist",
+		}
+	}
+
+	// Resolve the new source
+	mediaItem, err := h.mediaResolver.Resolve(ctx, p.Source, p.SourceID, userObjID)
+	if err != nil {
+		if errors.Is(err, models.ErrMediaTakenDown) {
+			return nil, rpc.NewError(rpc.ErrMediaUnavailable, "media was taken down after a content complaint", nil)
+		}
+		h.logger.Error("Failed to resolve media", err, "source", p.Source, "sourceId", p.SourceID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to resolve media",
+		}
+	}
+
+	updated, err := h.playlistManager.RelinkPlaylistItem(ctx, playlistObjID, itemObjID, mediaItem.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrPlaylistItemNotFound) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrInvalidParams,
+				Message: "Playlist item not found",
+			}
+		}
+		h.logger.Error("Failed to relink playlist item", err, "playlistId", p.PlaylistID, "itemId", p.ItemID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to relink playlist item",
+		}
+	}
+
+	for _, item := range updated.Items {
+		if item.ID == itemObjID {
+			return RelinkItemResult{
+				Item:  item,
+				Media: mediaItem,
+			}, nil
+		}
+	}
+
+	// The item was removed right after being re-linked
+	return nil, &rpc.Error{
+		Code:    rpc.ErrInvalidParams,
+		Message: "Playlist item not found",
+	}
+}
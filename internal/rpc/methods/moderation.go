@@ -0,0 +1,460 @@
+// Package methods contains RPC method handlers for the application.
+package methods
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/rpc"
+	"norelock.dev/listenify/backend/internal/services/room"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// defaultMuteMinutes is how long users are muted for when no duration is given.
+const defaultMuteMinutes = 10
+
+// ModerationHandler handles RPC methods for moderating room users.
+type ModerationHandler struct {
+	chatService room.ChatService
+	moderation  *room.UndoableModeration
+	logger      *utils.Logger
+}
+
+// NewModerationHandler creates a new ModerationHandler.
+func NewModerationHandler(chatService room.ChatService, moderation *room.UndoableModeration, logger *utils.Logger) *ModerationHandler {
+	return &ModerationHandler{
+		chatService: chatService,
+		moderation:  moderation,
+		logger:      logger,
+	}
+}
+
+// RegisterMethods registers moderation-related RPC methods with the router.
+func (h *ModerationHandler) RegisterMethods(hr rpc.HandlerRegistry) {
+	auth := hr.Wrap(rpc.AuthMiddleware)
+	rpc.Register(auth, "moderation.kick", h.Kick)
+	rpc.Register(auth, "moderation.mute", h.Mute)
+	rpc.Register(auth, "moderation.unmute", h.Unmute)
+	rpc.Register(auth, "moderation.undoLast", h.UndoLast)
+	rpc.Register(auth, "moderation.getFilters", h.GetFilters)
+	rpc.Register(auth, "moderation.addFilter", h.AddFilter)
+	rpc.Register(auth, "moderation.removeFilter", h.RemoveFilter)
+	rpc.Register(auth, "moderation.testFilter", h.TestFilter)
+}
+
+// ModerateUserParams represents the parameters for the kick and unmute methods.
+type ModerateUserParams struct {
+	RoomID string `json:"roomId" validate:"required"`
+	UserID string `json:"userId" validate:"required"`
+	Reason string `json:"reason,omitempty" validate:"max=500"`
+}
+
+// MuteParams represents the parameters for the mute method.
+type MuteParams struct {
+	RoomID   string `json:"roomId" validate:"required"`
+	UserID   string `json:"userId" validate:"required"`
+	Duration int    `json:"duration,omitempty" validate:"min=0,max=1440"`
+	Reason   string `json:"reason,omitempty" validate:"max=500"`
+}
+
+// UndoLastParams represents the parameters for the undoLast method.
+type UndoLastParams struct {
+	RoomID string `json:"roomId" validate:"required"`
+}
+
+// ModerationResult represents the result of the kick, mute and undoLast methods.
+type ModerationResult struct {
+	Entry *models.ModerationHistory `json:"entry"`
+}
+
+// UnmuteResult represents the result of the unmute method.
+type UnmuteResult struct {
+	Success bool `json:"success"`
+}
+
+// GetFiltersParams represents the parameters for the getFilters method.
+type GetFiltersParams struct {
+	RoomID string `json:"roomId" validate:"required"`
+}
+
+// GetFiltersResult represents the result of the getFilters method.
+type GetFiltersResult struct {
+	Filters []models.ChatFilterStats `json:"filters"`
+}
+
+// AddFilterParams represents the parameters for the addFilter method.
+type AddFilterParams struct {
+	RoomID string                   `json:"roomId" validate:"required"`
+	Filter models.ChatFilterRequest `json:"filter"`
+}
+
+// AddFilterResult represents the result of the addFilter method.
+type AddFilterResult struct {
+	Filter models.ChatFilter `json:"filter"`
+}
+
+// RemoveFilterParams represents the parameters for the removeFilter method.
+type RemoveFilterParams struct {
+	RoomID   string `json:"roomId" validate:"required"`
+	FilterID string `json:"filterId" validate:"required"`
+}
+
+// RemoveFilterResult represents the result of the removeFilter method.
+type RemoveFilterResult struct {
+	Success bool `json:"success"`
+}
+
+// TestFilterParams represents the parameters for the testFilter method.
+type TestFilterParams struct {
+	RoomID   string   `json:"roomId" validate:"required"`
+	Messages []string `json:"messages" validate:"required,min=1,max=20,dive,max=500"`
+
+	// Filter is a filter to test along with the room's filters before adding it, if any.
+	Filter *models.ChatFilterRequest `json:"filter,omitempty"`
+}
+
+// TestFilterResult represents the result of the testFilter method.
+type TestFilterResult struct {
+	Results []models.ChatFilterTest `json:"results"`
+}
+
+// Kick handles removing a user from a room. The moderator can undo it for a short while.
+func (h *ModerationHandler) Kick(ctx context.Context, client *rpc.Client, p *ModerateUserParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	roomID, moderatorID, targetID, rpcErr := parseModerationIDs(p.RoomID, client.UserID, p.UserID)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	entry, err := h.moderation.KickUser(ctx, roomID, moderatorID, targetID, p.Reason)
+	if err != nil {
+		if rpcErr := moderationError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to kick user", err, "roomId", p.RoomID, "targetId", p.UserID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to kick user",
+		}
+	}
+
+	return ModerationResult{
+		Entry: entry,
+	}, nil
+}
+
+// Mute handles keeping a user from chatting in a room for a number of minutes. The moderator can undo
+// it for a short while.
+func (h *ModerationHandler) Mute(ctx context.Context, client *rpc.Client, p *MuteParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	roomID, moderatorID, targetID, rpcErr := parseModerationIDs(p.RoomID, client.UserID, p.UserID)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	minutes := p.Duration
+	if minutes == 0 {
+		minutes = defaultMuteMinutes
+	}
+
+	entry, err := h.moderation.MuteUser(ctx, roomID, moderatorID, targetID, time.Duration(minutes)*time.Minute, p.Reason)
+	if err != nil {
+		if rpcErr := moderationError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to mute user", err, "roomId", p.RoomID, "targetId", p.UserID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to mute user",
+		}
+	}
+
+	return ModerationResult{
+		Entry: entry,
+	}, nil
+}
+
+// Unmute handles letting a muted user chat in a room again.
+func (h *ModerationHandler) Unmute(ctx context.Context, client *rpc.Client, p *ModerateUserParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	roomID, moderatorID, targetID, rpcErr := parseModerationIDs(p.RoomID, client.UserID, p.UserID)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if err := h.moderation.UnmuteUser(ctx, roomID, moderatorID, targetID); err != nil {
+		if rpcErr := moderationError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to unmute user", err, "roomId", p.RoomID, "targetId", p.UserID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to unmute user",
+		}
+	}
+
+	return UnmuteResult{
+		Success: true,
+	}, nil
+}
+
+// UndoLast handles undoing the last kick, mute, ban or message deletion the client's user took in a room,
+// within the undo window.
+func (h *ModerationHandler) UndoLast(ctx context.Context, client *rpc.Client, p *UndoLastParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	roomID, moderatorID, rpcErr := parseRoomAndUser(p.RoomID, client.UserID)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	entry, err := h.moderation.UndoLast(ctx, roomID, moderatorID)
+	if err != nil {
+		if rpcErr := moderationError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to undo moderation action", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to undo moderation action",
+		}
+	}
+
+	return ModerationResult{
+		Entry: entry,
+	}, nil
+}
+
+// GetFilters handles listing the terms and patterns blocked in a room's chat, with how often each matched
+// (room owner and moderators only).
+func (h *ModerationHandler) GetFilters(ctx context.Context, client *rpc.Client, p *GetFiltersParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	filters, err := h.chatService.GetChatFilters(ctx, p.RoomID, client.UserID)
+	if err != nil {
+		if rpcErr := chatFilterError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to get chat filters", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to get chat filters",
+		}
+	}
+
+	return GetFiltersResult{
+		Filters: filters,
+	}, nil
+}
+
+// AddFilter handles blocking a term or regular expression in a room's chat (room owner and moderators only).
+func (h *ModerationHandler) AddFilter(ctx context.Context, client *rpc.Client, p *AddFilterParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	filter, err := h.chatService.AddChatFilter(ctx, p.RoomID, client.UserID, p.Filter)
+	if err != nil {
+		if rpcErr := chatFilterError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to add chat filter", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to add chat filter",
+		}
+	}
+
+	return AddFilterResult{
+		Filter: filter,
+	}, nil
+}
+
+// RemoveFilter handles unblocking a term or regular expression in a room's chat (room owner and moderators only).
+func (h *ModerationHandler) RemoveFilter(ctx context.Context, client *rpc.Client, p *RemoveFilterParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	if err := h.chatService.RemoveChatFilter(ctx, p.RoomID, client.UserID, p.FilterID); err != nil {
+		if rpcErr := chatFilterError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to remove chat filter", err, "roomId", p.RoomID, "filterId", p.FilterID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to remove chat filter",
+		}
+	}
+
+	return RemoveFilterResult{
+		Success: true,
+	}, nil
+}
+
+// TestFilter handles checking sample messages against a room's chat filters, and a filter about to be
+// added, without posting them (room owner and moderators only).
+func (h *ModerationHandler) TestFilter(ctx context.Context, client *rpc.Client, p *TestFilterParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	results, err := h.chatService.TestChatFilters(ctx, p.RoomID, client.UserID, p.Messages, p.Filter)
+	if err != nil {
+		if rpcErr := chatFilterError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to test chat filters", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to test chat filters",
+		}
+	}
+
+	return TestFilterResult{
+		Results: results,
+	}, nil
+}
+
+// parseModerationIDs parses the room and target user IDs of a request and the ID of the client's user.
+func parseModerationIDs(roomID, userID, targetID string) (bson.ObjectID, bson.ObjectID, bson.ObjectID, *rpc.Error) {
+	roomObjID, userObjID, rpcErr := parseRoomAndUser(roomID, userID)
+	if rpcErr != nil {
+		return bson.ObjectID{}, bson.ObjectID{}, bson.ObjectID{}, rpcErr
+	}
+
+	targetObjID, err := bson.ObjectIDFromHex(targetID)
+	if err != nil {
+		return bson.ObjectID{}, bson.ObjectID{}, bson.ObjectID{}, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid target user ID",
+		}
+	}
+
+	return roomObjID, userObjID, targetObjID, nil
+}
+
+// moderationError maps the errors of the moderation methods, returning nil for unexpected errors.
+func moderationError(err error) *rpc.Error {
+	switch {
+	case errors.Is(err, models.ErrRoomNotFound):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Room not found",
+		}
+	case errors.Is(err, models.ErrUserNotInRoom):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "User is not in this room",
+		}
+	case errors.Is(err, models.ErrMessageNotFound):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Message not found",
+		}
+	case errors.Is(err, models.ErrNothingToUndo):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Nothing to undo, actions can only be undone shortly after they are taken",
+		}
+	case errors.Is(err, models.ErrInvalidInput):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: err.Error(),
+		}
+	case errors.Is(err, room.ErrNotAuthorized):
+		return &rpc.Error{
+			Code:    rpc.ErrNotAuthorized,
+			Message: "You can only moderate users whose role is below yours",
+		}
+	}
+	return nil
+}
+
+// chatFilterError maps the errors of the chat filter methods, returning nil for unexpected errors.
+func chatFilterError(err error) *rpc.Error {
+	switch {
+	case errors.Is(err, models.ErrInvalidID):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid ID",
+		}
+	case errors.Is(err, models.ErrChatFilterNotFound):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Chat filter not found",
+		}
+	case errors.Is(err, models.ErrInvalidChatFilter):
+		message := err.Error()
+		var domainErr *models.DomainError
+		if errors.As(err, &domainErr) {
+			message = domainErr.Message
+		}
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: message,
+		}
+	case errors.Is(err, room.ErrNotAuthorized):
+		return &rpc.Error{
+			Code:    rpc.ErrNotAuthorized,
+			Message: "Only the room's owner and moderators can manage chat filters",
+		}
+	}
+	return moderationError(err)
+}
@@ -3,6 +3,7 @@ package methods
 
 import (
 	"context"
+	"errors"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"norelock.dev/listenify/backend/internal/models"
@@ -33,11 +34,16 @@ func (h *PlaylistHandler) RegisterMethods(hr rpc.HandlerRegistry) {
 	auth := hr.Wrap(rpc.AuthMiddleware)
 	rpc.Register(auth, "playlist.create", h.CreatePlaylist)
 	rpc.Register(hr, "playlist.get", h.GetPlaylist)
+	rpc.Register(hr, "playlist.getChangesSince", h.GetChangesSince)
 	rpc.Register(hr, "playlist.getUserPlaylists", h.GetUserPlaylists)
 	rpc.Register(auth, "playlist.update", h.UpdatePlaylist)
 	rpc.Register(auth, "playlist.delete", h.DeletePlaylist)
 	rpc.Register(auth, "playlist.addItem", h.AddPlaylistItem)
+	rpc.Register(auth, "playlist.addByUrl", h.AddPlaylistItemByURL)
 	rpc.Register(auth, "playlist.removeItem", h.RemovePlaylistItem)
+	rpc.Register(auth, "playlist.addItems", h.AddPlaylistItems)
+	rpc.Register(auth, "playlist.removeItems", h.RemovePlaylistItems)
+	rpc.Register(auth, "playlist.reorder", h.ReorderPlaylist)
 	rpc.Register(auth, "playlist.import", h.ImportPlaylist)
 	rpc.Register(auth, "playlist.setActive", h.SetActivePlaylist)
 	rpc.RegisterNoParams(auth, "playlist.getActive", h.GetActivePlaylist)
@@ -188,6 +194,64 @@ func (h *PlaylistHandler) GetPlaylist(ctx context.Context, client *rpc.Client, p
 	}, nil
 }
 
+// GetChangesSinceParams represents the parameters for the getChangesSince method.
+type GetChangesSinceParams struct {
+	PlaylistID string `json:"playlistId" validate:"required"`
+	Revision   int64  `json:"revision" validate:"min=0"`
+}
+
+// GetChangesSince handles syncing a client's copy of a playlist. It returns the changes made to the
+// playlist's items since the client's revision, or every item when the changes can't bring the copy
+// up to date.
+func (h *PlaylistHandler) GetChangesSince(ctx context.Context, client *rpc.Client, p *GetChangesSinceParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	// Convert playlist ID to ObjectID
+	playlistObjID, err := bson.ObjectIDFromHex(p.PlaylistID)
+	if err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid playlist ID",
+		}
+	}
+
+	// Get playlist
+	playlist, err := h.playlistManager.GetPlaylist(ctx, playlistObjID)
+	if err != nil {
+		h.logger.Error("Failed to get playlist", err, "playlistId", p.PlaylistID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Playlist not found",
+		}
+	}
+
+	// Check if playlist is private and user is not the owner
+	if playlist.IsPrivate && (client.UserID == "" || client.UserID != playlist.Owner.Hex()) {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrNotAuthorized,
+			Message: "You do not have permission to view this playlist",
+		}
+	}
+
+	changes, err := h.playlistManager.GetChangesSince(ctx, playlist, p.Revision)
+	if err != nil {
+		h.logger.Error("Failed to get playlist changes", err, "playlistId", p.PlaylistID, "revision", p.Revision)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to get playlist changes",
+		}
+	}
+
+	return changes, nil
+}
+
 // GetUserPlaylistsResult represents the result of the getUserPlaylists method.
 type GetUserPlaylistsResult struct {
 	Playlists []models.PlaylistInfo `json:"playlists"`
@@ -485,6 +549,9 @@ func (h *PlaylistHandler) AddPlaylistItem(ctx context.Context, client *rpc.Clien
 	// Add item to playlist
 	updatedPlaylist, err := h.playlistManager.AddPlaylistItem(ctx, playlistObjID, mediaObjID, position)
 	if err != nil {
+		if errors.Is(err, models.ErrMediaTakenDown) {
+			return nil, rpc.NewError(rpc.ErrMediaUnavailable, "media was taken down after a content complaint", nil)
+		}
 		h.logger.Error("Failed to add item to playlist", err, "playlistId", p.PlaylistID, "mediaId", p.MediaID)
 		return nil, &rpc.Error{
 			Code:    rpc.ErrInternalError,
@@ -506,6 +573,103 @@ func (h *PlaylistHandler) AddPlaylistItem(ctx context.Context, client *rpc.Clien
 	}, nil
 }
 
+// AddPlaylistItemByURLParams represents the parameters for the addPlaylistItemByURL method.
+type AddPlaylistItemByURLParams struct {
+	PlaylistID string `json:"playlistId" validate:"required"`
+	URL        string `json:"url" validate:"required,url"`
+	Position   *int   `json:"position,omitempty"`
+}
+
+// AddPlaylistItemByURLResult represents the result of the addPlaylistItemByURL method.
+type AddPlaylistItemByURLResult struct {
+	Playlist models.PlaylistInfo `json:"playlist"`
+	Media    *models.Media       `json:"media"`
+}
+
+// AddPlaylistItemByURL handles adding the media at a URL to a playlist, resolving it first if it isn't known yet.
+func (h *PlaylistHandler) AddPlaylistItemByURL(ctx context.Context, client *rpc.Client, p *AddPlaylistItemByURLParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	// Convert IDs to ObjectIDs
+	playlistObjID, err := bson.ObjectIDFromHex(p.PlaylistID)
+	if err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid playlist ID",
+		}
+	}
+
+	userObjID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid user ID",
+		}
+	}
+
+	// Get playlist
+	playlist, err := h.playlistManager.GetPlaylist(ctx, playlistObjID)
+	if err != nil {
+		h.logger.Error("Failed to get playlist", err, "playlistId", p.PlaylistID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Playlist not found",
+		}
+	}
+
+	// Check if user is the owner
+	if playlist.Owner != userObjID {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrNotAuthorized,
+			Message: "You do not have permission to modify this playlist",
+		}
+	}
+
+	// Set default position if not provided
+	position := -1 // Append to end
+	if p.Position != nil {
+		position = *p.Position
+	}
+
+	// Resolve the media and add it to the playlist
+	updatedPlaylist, media, err := h.playlistManager.AddPlaylistItemByURL(ctx, playlistObjID, userObjID, p.URL, position)
+	if err != nil {
+		if errors.Is(err, models.ErrMediaCantBeResolved) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrInvalidParams,
+				Message: "Unsupported media URL",
+			}
+		}
+		if errors.Is(err, models.ErrMediaTakenDown) {
+			return nil, rpc.NewError(rpc.ErrMediaUnavailable, "media was taken down after a content complaint", nil)
+		}
+		h.logger.Error("Failed to add media to playlist by URL", err, "playlistId", p.PlaylistID, "url", p.URL)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to add media to playlist",
+		}
+	}
+
+	// Get user for playlist info
+	user, err := h.userManager.GetUserByID(ctx, client.UserID)
+	if err != nil {
+		h.logger.Error("Failed to get user for playlist info", err, "userId", client.UserID)
+		// Continue anyway, we'll just return the playlist without owner info
+	}
+
+	return AddPlaylistItemByURLResult{
+		Playlist: updatedPlaylist.ToPlaylistInfo(user),
+		Media:    media,
+	}, nil
+}
+
 // RemovePlaylistItemParams represents the parameters for the removePlaylistItem method.
 type RemovePlaylistItemParams struct {
 	PlaylistID string `json:"playlistId" validate:"required"`
@@ -587,6 +751,218 @@ func (h *PlaylistHandler) RemovePlaylistItem(ctx context.Context, client *rpc.Cl
 	}, nil
 }
 
+// AddPlaylistItemsParams represents the parameters for the addPlaylistItems method.
+type AddPlaylistItemsParams struct {
+	PlaylistID string   `json:"playlistId" validate:"required"`
+	MediaIDs   []string `json:"mediaIds" validate:"required,min=1,max=500,dive,required"`
+}
+
+// RemovePlaylistItemsParams represents the parameters for the removePlaylistItems method.
+type RemovePlaylistItemsParams struct {
+	PlaylistID string   `json:"playlistId" validate:"required"`
+	ItemIDs    []string `json:"itemIds" validate:"required,min=1,max=500,dive,required"`
+}
+
+// ReorderPlaylistParams represents the parameters for the reorderPlaylist method.
+type ReorderPlaylistParams struct {
+	PlaylistID string   `json:"playlistId" validate:"required"`
+	ItemIDs    []string `json:"itemIds" validate:"required,min=1,dive,required"`
+}
+
+// PlaylistItemsResult represents the result of the addPlaylistItems, removePlaylistItems and
+// reorderPlaylist methods.
+type PlaylistItemsResult struct {
+	Playlist models.PlaylistInfo `json:"playlist"`
+}
+
+// AddPlaylistItems handles appending several media items to a playlist at once, such as when importing
+// a large playlist. None are added if any of the media is missing or was taken down.
+func (h *PlaylistHandler) AddPlaylistItems(ctx context.Context, client *rpc.Client, p *AddPlaylistItemsParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	mediaObjIDs, ok := parseObjectIDs(p.MediaIDs)
+	if !ok {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid media ID",
+		}
+	}
+
+	playlistObjID, rpcErr := h.ownedPlaylist(ctx, client, p.PlaylistID)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	updatedPlaylist, err := h.playlistManager.AddPlaylistItems(ctx, playlistObjID, mediaObjIDs)
+	if err != nil {
+		if errors.Is(err, models.ErrMediaTakenDown) {
+			return nil, rpc.NewError(rpc.ErrMediaUnavailable, "media was taken down after a content complaint", nil)
+		}
+		if errors.Is(err, models.ErrMediaNotFound) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrInvalidParams,
+				Message: "Media not found",
+			}
+		}
+		h.logger.Error("Failed to add items to playlist", err, "playlistId", p.PlaylistID, "count", len(p.MediaIDs))
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to add items to playlist",
+		}
+	}
+
+	return h.playlistItemsResult(ctx, client, updatedPlaylist), nil
+}
+
+// RemovePlaylistItems handles removing several items from a playlist at once. None are removed if any of
+// them isn't in the playlist.
+func (h *PlaylistHandler) RemovePlaylistItems(ctx context.Context, client *rpc.Client, p *RemovePlaylistItemsParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	itemObjIDs, ok := parseObjectIDs(p.ItemIDs)
+	if !ok {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid item ID",
+		}
+	}
+
+	playlistObjID, rpcErr := h.ownedPlaylist(ctx, client, p.PlaylistID)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	updatedPlaylist, err := h.playlistManager.RemovePlaylistItems(ctx, playlistObjID, itemObjIDs)
+	if err != nil {
+		if errors.Is(err, models.ErrPlaylistItemNotFound) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrInvalidParams,
+				Message: "Playlist item not found",
+			}
+		}
+		h.logger.Error("Failed to remove items from playlist", err, "playlistId", p.PlaylistID, "count", len(p.ItemIDs))
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to remove items from playlist",
+		}
+	}
+
+	return h.playlistItemsResult(ctx, client, updatedPlaylist), nil
+}
+
+// ReorderPlaylist handles putting the items of a playlist in a new order. The order must list every item
+// of the playlist exactly once.
+func (h *PlaylistHandler) ReorderPlaylist(ctx context.Context, client *rpc.Client, p *ReorderPlaylistParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	itemObjIDs, ok := parseObjectIDs(p.ItemIDs)
+	if !ok {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid item ID",
+		}
+	}
+
+	playlistObjID, rpcErr := h.ownedPlaylist(ctx, client, p.PlaylistID)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	updatedPlaylist, err := h.playlistManager.ReorderPlaylist(ctx, playlistObjID, itemObjIDs)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidPlaylistOrder) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrInvalidParams,
+				Message: "The order must list every item of the playlist exactly once",
+			}
+		}
+		h.logger.Error("Failed to reorder playlist", err, "playlistId", p.PlaylistID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to reorder playlist",
+		}
+	}
+
+	return h.playlistItemsResult(ctx, client, updatedPlaylist), nil
+}
+
+// ownedPlaylist parses a playlist ID and checks that the client's user owns the playlist.
+func (h *PlaylistHandler) ownedPlaylist(ctx context.Context, client *rpc.Client, playlistID string) (bson.ObjectID, *rpc.Error) {
+	playlistObjID, err := bson.ObjectIDFromHex(playlistID)
+	if err != nil {
+		return bson.ObjectID{}, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid playlist ID",
+		}
+	}
+
+	playlist, err := h.playlistManager.GetPlaylist(ctx, playlistObjID)
+	if err != nil {
+		h.logger.Error("Failed to get playlist", err, "playlistId", playlistID)
+		return bson.ObjectID{}, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Playlist not found",
+		}
+	}
+
+	if playlist.Owner.Hex() != client.UserID {
+		return bson.ObjectID{}, &rpc.Error{
+			Code:    rpc.ErrNotAuthorized,
+			Message: "You do not have permission to modify this playlist",
+		}
+	}
+
+	return playlistObjID, nil
+}
+
+// playlistItemsResult builds the result of the bulk item methods.
+func (h *PlaylistHandler) playlistItemsResult(ctx context.Context, client *rpc.Client, playlist *models.Playlist) PlaylistItemsResult {
+	// Get user for playlist info
+	user, err := h.userManager.GetUserByID(ctx, client.UserID)
+	if err != nil {
+		h.logger.Error("Failed to get user for playlist info", err, "userId", client.UserID)
+		// Continue anyway, we'll just return the playlist without owner info
+	}
+
+	return PlaylistItemsResult{
+		Playlist: playlist.ToPlaylistInfo(user),
+	}
+}
+
+// parseObjectIDs parses a list of hex IDs, reporting whether all of them were valid.
+func parseObjectIDs(ids []string) ([]bson.ObjectID, bool) {
+	objIDs := make([]bson.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objID, err := bson.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, false
+		}
+		objIDs = append(objIDs, objID)
+	}
+	return objIDs, true
+}
+
 // ImportPlaylistParams represents the parameters for the importPlaylist method.
 type ImportPlaylistParams struct {
 	Source    string `json:"source" validate:"required,oneof=youtube soundcloud"`
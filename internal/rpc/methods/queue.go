@@ -38,12 +38,16 @@ func (h *QueueHandler) RegisterMethods(hr rpc.HandlerRegistry) {
 	rpc.Register(auth, "queue.advance", h.AdvanceQueue)
 	rpc.Register(auth, "queue.playMedia", h.PlayMedia)
 	rpc.Register(auth, "queue.skip", h.SkipCurrentMedia)
+	rpc.Register(auth, "queue.setLocked", h.SetQueueLocked)
+	rpc.Register(auth, "queue.setOptions", h.SetQueueOptions)
 	rpc.Register(auth, "queue.clear", h.ClearQueue)
 	rpc.Register(auth, "queue.shuffle", h.ShuffleQueue)
 	rpc.Register(hr, "queue.getPosition", h.GetQueuePosition)
 	rpc.Register(hr, "queue.isInQueue", h.IsUserInQueue)
 	rpc.Register(hr, "queue.isCurrentDJ", h.IsUserCurrentDJ)
 	rpc.Register(hr, "queue.getHistory", h.GetPlayHistory)
+	rpc.Register(auth, "dj.setUpNext", h.SetUpNext)
+	rpc.Register(auth, "dj.getUpNext", h.GetUpNext)
 }
 
 // JoinQueue adds the current user to the DJ queue.
@@ -67,10 +71,23 @@ func (h *QueueHandler) JoinQueue(ctx context.Context, client *rpc.Client, p *Roo
 	// Add user to queue
 	roomState, err := h.queueManager.AddToQueue(ctx, roomID, userID)
 	if err != nil {
+		if errors.Is(err, models.ErrListenerOnly) {
+			return nil, rpc.NewError(rpc.ErrNotAuthorized, "listener-only users cannot join the queue", nil)
+		}
+		if errors.Is(err, models.ErrQueueLocked) {
+			return nil, rpc.NewError(rpc.ErrNotAuthorized, err.Error(), nil)
+		}
+		if errors.Is(err, models.ErrNoActivePlaylist) ||
+			errors.Is(err, models.ErrPlaylistEmpty) ||
+			errors.Is(err, models.ErrNoPlayableItems) ||
+			errors.Is(err, models.ErrAllItemsTooLong) {
+			return nil, rpc.NewError(rpc.ErrInvalidParams, err.Error(), nil)
+		}
 		h.logger.Error("Failed to add user to queue", err, "roomId", p.RoomID, "userId", client.UserID)
 		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
 	}
 
+	notifyQueueUpdated(client, p.RoomID, roomState)
 	return roomState, nil
 }
 
@@ -99,9 +116,90 @@ func (h *QueueHandler) LeaveQueue(ctx context.Context, client *rpc.Client, p *Ro
 		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
 	}
 
+	notifyQueueUpdated(client, p.RoomID, roomState)
 	return roomState, nil
 }
 
+// SetUpNextParams represents the parameters for the SetUpNext method.
+type SetUpNextParams struct {
+	RoomID   string   `json:"roomId"`
+	MediaIDs []string `json:"mediaIds"`
+}
+
+// SetUpNext replaces the tracks the current user plans to play next when their turn comes.
+// Only the user sees the tracks, the queue shows how many are planned.
+func (h *QueueHandler) SetUpNext(ctx context.Context, client *rpc.Client, p *SetUpNextParams) (any, error) {
+	// Validate parameters
+	if p.RoomID == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId is required", nil)
+	}
+
+	// Convert IDs to ObjectIDs
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
+	}
+
+	userID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
+	mediaIDs := make([]bson.ObjectID, len(p.MediaIDs))
+	for i, id := range p.MediaIDs {
+		if mediaIDs[i], err = bson.ObjectIDFromHex(id); err != nil {
+			return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid mediaId", id)
+		}
+	}
+
+	// Plan the tracks
+	planned, err := h.queueManager.SetUpNext(ctx, roomID, userID, mediaIDs)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotInRoom) ||
+			errors.Is(err, models.ErrListenerOnly) ||
+			errors.Is(err, models.ErrSetPlanningDisabled) {
+			return nil, rpc.NewError(rpc.ErrNotAuthorized, err.Error(), nil)
+		}
+		if errors.Is(err, models.ErrNoActivePlaylist) ||
+			errors.Is(err, models.ErrMediaNotInPlaylist) ||
+			errors.Is(err, models.ErrSetPlanTooLong) {
+			return nil, rpc.NewError(rpc.ErrInvalidParams, err.Error(), nil)
+		}
+		h.logger.Error("Failed to plan tracks", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
+	}
+
+	return map[string]any{"mediaIds": planned}, nil
+}
+
+// GetUpNext gets the tracks the current user plans to play next when their turn comes.
+func (h *QueueHandler) GetUpNext(ctx context.Context, client *rpc.Client, p *RoomIDParam) (any, error) {
+	// Validate parameters
+	if p.RoomID == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId is required", nil)
+	}
+
+	// Convert IDs to ObjectIDs
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
+	}
+
+	userID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
+	// Get planned tracks
+	planned, err := h.queueManager.GetUpNext(ctx, roomID, userID)
+	if err != nil {
+		h.logger.Error("Failed to get planned tracks", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
+	}
+
+	return map[string]any{"mediaIds": planned}, nil
+}
+
 // MoveInQueueParams represents the parameters for the MoveInQueue method.
 type MoveInQueueParams struct {
 	RoomID      string `json:"roomId"`
@@ -140,6 +238,7 @@ func (h *QueueHandler) MoveInQueue(ctx context.Context, client *rpc.Client, p *M
 		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
 	}
 
+	notifyQueueUpdated(client, p.RoomID, roomState)
 	return roomState, nil
 }
 
@@ -232,6 +331,9 @@ func (h *QueueHandler) AdvanceQueue(ctx context.Context, client *rpc.Client, p *
 		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
 	}
 
+	h.queueManager.AnnounceTransition(roomID, func() {
+		notifyQueueUpdated(client, p.RoomID, roomState)
+	})
 	return roomState, nil
 }
 
@@ -273,36 +375,164 @@ func (h *QueueHandler) PlayMedia(ctx context.Context, client *rpc.Client, p *Pla
 	// Play media
 	roomState, err := h.queueManager.PlayMedia(ctx, roomID, p.MediaInfo)
 	if err != nil {
+		if errors.Is(err, models.ErrTrustLevelTooLow) {
+			return nil, rpc.NewError(rpc.ErrNotAuthorized, "trust level too low to play long tracks", nil)
+		}
+		if errors.Is(err, models.ErrMediaRestricted) {
+			return nil, rpc.NewError(rpc.ErrMediaUnavailable, "age-restricted media can only be played in 18+ rooms", nil)
+		}
+		if errors.Is(err, models.ErrMediaTakenDown) {
+			return nil, rpc.NewError(rpc.ErrMediaUnavailable, "media was taken down after a content complaint", nil)
+		}
 		h.logger.Error("Failed to play media", err, "roomId", p.RoomID)
 		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
 	}
 
+	notifyQueueUpdated(client, p.RoomID, roomState)
 	return roomState, nil
 }
 
-// SkipCurrentMedia skips the currently playing media.
+// SkipCurrentMedia skips the currently playing media. The current DJ can skip their own track,
+// anyone else needs the skip permission in the room.
 func (h *QueueHandler) SkipCurrentMedia(ctx context.Context, client *rpc.Client, p *RoomIDParam) (any, error) {
 	// Validate parameters
 	if p.RoomID == "" {
 		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId is required", nil)
 	}
 
-	// Convert ID to ObjectID
+	// Convert IDs to ObjectIDs
 	roomID, err := bson.ObjectIDFromHex(p.RoomID)
 	if err != nil {
 		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
 	}
 
+	userID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
 	// Skip current media
-	roomState, err := h.queueManager.SkipCurrentMedia(ctx, roomID)
+	roomState, err := h.queueManager.SkipCurrentMediaAs(ctx, roomID, userID)
 	if err != nil {
+		if errors.Is(err, models.ErrRoomNotFound) {
+			return nil, rpc.ErrRoomNotFound.Error()
+		}
+		if errors.Is(err, room.ErrNotAuthorized) {
+			return nil, rpc.ErrNotAuthorized.Error()
+		}
 		h.logger.Error("Failed to skip current media", err, "roomId", p.RoomID)
 		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
 	}
 
+	h.queueManager.AnnounceTransition(roomID, func() {
+		notifyQueueUpdated(client, p.RoomID, roomState)
+	})
 	return roomState, nil
 }
 
+// SetQueueLockedParams represents the parameters for the SetQueueLocked method.
+type SetQueueLockedParams struct {
+	RoomID string `json:"roomId"`
+	Locked bool   `json:"locked"`
+}
+
+// SetQueueLocked locks or unlocks the DJ queue of a room. Users without the queue lock
+// permission can't join a locked queue.
+func (h *QueueHandler) SetQueueLocked(ctx context.Context, client *rpc.Client, p *SetQueueLockedParams) (any, error) {
+	// Validate parameters
+	if p.RoomID == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId is required", nil)
+	}
+
+	// Convert IDs to ObjectIDs
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
+	}
+
+	userID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
+	// Lock or unlock the queue
+	if _, err := h.queueManager.SetQueueLocked(ctx, roomID, userID, p.Locked); err != nil {
+		if errors.Is(err, models.ErrRoomNotFound) {
+			return nil, rpc.ErrRoomNotFound.Error()
+		}
+		if errors.Is(err, room.ErrNotAuthorized) {
+			return nil, rpc.ErrNotAuthorized.Error()
+		}
+		h.logger.Error("Failed to set queue lock", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
+	}
+
+	result := map[string]any{
+		"roomId":      p.RoomID,
+		"queueLocked": p.Locked,
+	}
+	client.NotifyRoom(p.RoomID, rpc.EventQueueLockChanged, result)
+	return result, nil
+}
+
+// SetQueueOptionsParams represents the parameters for the SetQueueOptions method.
+type SetQueueOptionsParams struct {
+	RoomID              string `json:"roomId"`
+	Locked              bool   `json:"locked"`
+	Cycle               bool   `json:"cycle"`
+	MaxConsecutivePlays int    `json:"maxConsecutivePlays"`
+}
+
+// SetQueueOptions sets the options of the DJ queue of a room: whether only users with the queue lock
+// permission can join it, whether DJs rejoin it at the end once their turn ends, and how many tracks
+// they play in a row.
+func (h *QueueHandler) SetQueueOptions(ctx context.Context, client *rpc.Client, p *SetQueueOptionsParams) (any, error) {
+	// Validate parameters
+	if p.RoomID == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId is required", nil)
+	}
+
+	options := models.QueueOptions{
+		Locked:              p.Locked,
+		Cycle:               p.Cycle,
+		MaxConsecutivePlays: max(p.MaxConsecutivePlays, 1),
+	}
+	if err := utils.Validate(options); err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "Invalid parameters", err.Error())
+	}
+
+	// Convert IDs to ObjectIDs
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
+	}
+
+	userID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
+	// Set the options
+	updated, err := h.queueManager.SetQueueOptions(ctx, roomID, userID, options)
+	if err != nil {
+		if errors.Is(err, models.ErrRoomNotFound) {
+			return nil, rpc.ErrRoomNotFound.Error()
+		}
+		if errors.Is(err, room.ErrNotAuthorized) {
+			return nil, rpc.ErrNotAuthorized.Error()
+		}
+		h.logger.Error("Failed to set queue options", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
+	}
+
+	result := map[string]any{
+		"roomId":  p.RoomID,
+		"options": updated.QueueOptions(),
+	}
+	client.NotifyRoom(p.RoomID, rpc.EventQueueOptionsChanged, result)
+	return result, nil
+}
+
 // ClearQueue clears the DJ queue for a room.
 func (h *QueueHandler) ClearQueue(ctx context.Context, client *rpc.Client, p *RoomIDParam) (any, error) {
 	// Validate parameters
@@ -323,6 +553,7 @@ func (h *QueueHandler) ClearQueue(ctx context.Context, client *rpc.Client, p *Ro
 		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
 	}
 
+	notifyQueueUpdated(client, p.RoomID, roomState)
 	return roomState, nil
 }
 
@@ -346,6 +577,7 @@ func (h *QueueHandler) ShuffleQueue(ctx context.Context, client *rpc.Client, p *
 		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
 	}
 
+	notifyQueueUpdated(client, p.RoomID, roomState)
 	return roomState, nil
 }
 
@@ -485,3 +717,16 @@ func (h *QueueHandler) GetPlayHistory(ctx context.Context, client *rpc.Client, p
 
 	return history, nil
 }
+
+// notifyQueueUpdated tells a room's clients on every node that its DJ queue changed.
+func notifyQueueUpdated(client *rpc.Client, roomID string, state *models.RoomState) {
+	if state == nil {
+		return
+	}
+	client.NotifyRoom(roomID, rpc.EventQueueUpdated, map[string]any{
+		"roomId":       roomID,
+		"djQueue":      state.DJQueue,
+		"currentDJ":    state.CurrentDJ,
+		"currentMedia": state.CurrentMedia,
+	})
+}
@@ -4,27 +4,73 @@ package methods
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
 
 	"slices"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
 	"norelock.dev/listenify/backend/internal/models"
 	"norelock.dev/listenify/backend/internal/rpc"
 	"norelock.dev/listenify/backend/internal/services/room"
+	"norelock.dev/listenify/backend/internal/services/user"
 	"norelock.dev/listenify/backend/internal/utils"
 )
 
+// minGeoBucketSize is the smallest number of listeners reported for a single country.
+// Smaller groups are folded into "other" so that individual listeners cannot be located.
+const minGeoBucketSize = 3
+
+// JoinPolicy controls what is sent to clients joining a room.
+type JoinPolicy struct {
+	// RosterPageSize is the number of users per roster chunk after a progressive join.
+	RosterPageSize int
+
+	// ChatBacklog is the number of recent chat messages sent in rooms that don't set their own.
+	ChatBacklog int
+}
+
 // RoomHandler handles room-related RPC methods.
 type RoomHandler struct {
-	roomManager room.RoomManager
-	logger      *utils.Logger
+	roomManager     room.RoomManager
+	userManager     *user.Manager
+	chatService     room.ChatService
+	queueManager    *room.QueueManager
+	reportService   *room.RoomReportService
+	captchaService  *user.CaptchaService
+	calendarService *room.CalendarService
+	listenerGeoMgr  *managers.ListenerGeoManager
+	joinPolicy      JoinPolicy
+	logger          *utils.Logger
 }
 
 // NewRoomHandler creates a new RoomHandler.
-func NewRoomHandler(roomManager room.RoomManager, logger *utils.Logger) *RoomHandler {
+func NewRoomHandler(
+	roomManager room.RoomManager,
+	userManager *user.Manager,
+	chatService room.ChatService,
+	queueManager *room.QueueManager,
+	reportService *room.RoomReportService,
+	captchaService *user.CaptchaService,
+	calendarService *room.CalendarService,
+	listenerGeoMgr *managers.ListenerGeoManager,
+	joinPolicy JoinPolicy,
+	logger *utils.Logger,
+) *RoomHandler {
 	return &RoomHandler{
-		roomManager: roomManager,
-		logger:      logger,
+		roomManager:     roomManager,
+		userManager:     userManager,
+		chatService:     chatService,
+		queueManager:    queueManager,
+		reportService:   reportService,
+		captchaService:  captchaService,
+		calendarService: calendarService,
+		listenerGeoMgr:  listenerGeoMgr,
+		joinPolicy:      joinPolicy,
+		logger:          logger,
 	}
 }
 
@@ -36,14 +82,30 @@ func (h *RoomHandler) RegisterMethods(hr rpc.HandlerRegistry) {
 	rpc.Register(hr, "room.getBySlug", h.GetRoomBySlug)
 	rpc.Register(auth, "room.update", h.UpdateRoom)
 	rpc.Register(auth, "room.delete", h.DeleteRoom)
+	rpc.Register(auth, "room.canJoin", h.CanJoin)
 	rpc.Register(auth, "room.join", h.JoinRoom)
 	rpc.Register(auth, "room.leave", h.LeaveRoom)
+	rpc.Register(auth, "room.updateSubscriptions", h.UpdateSubscriptions)
 	rpc.Register(hr, "room.getUsers", h.GetRoomUsers)
 	rpc.Register(hr, "room.isUserInRoom", h.IsUserInRoom)
 	rpc.Register(hr, "room.getState", h.GetRoomState)
+	rpc.Register(hr, "room.syncPlayback", h.SyncPlayback)
+	rpc.Register(auth, "room.vote", h.Vote)
+	rpc.Register(auth, "room.react", h.React)
+	rpc.Register(auth, "room.setLanguage", h.SetLanguage)
+	rpc.Register(auth, "room.setRole", h.SetRole)
+	rpc.Register(hr, "room.getRoles", h.GetRoles)
+	rpc.Register(auth, "room.setRolePermissions", h.SetRolePermissions)
+	rpc.Register(auth, "room.setChatAppearance", h.SetChatAppearance)
+	rpc.Register(auth, "room.resetChatAppearance", h.ResetChatAppearance)
 	rpc.Register(hr, "room.search", h.SearchRooms)
 	rpc.Register(hr, "room.getActive", h.GetActiveRooms)
 	rpc.Register(hr, "room.getPopular", h.GetPopularRooms)
+	rpc.Register(auth, "room.getListenerGeo", h.GetListenerGeo)
+	rpc.Register(auth, "room.report", h.ReportRoom)
+	rpc.Register(auth, "room.scheduleEvent", h.ScheduleEvent)
+	rpc.Register(auth, "room.cancelEvent", h.CancelEvent)
+	rpc.Register(auth, "room.getUpcomingEvents", h.GetUpcomingEvents)
 }
 
 // CreateRoomParams represents the parameters for the CreateRoom method.
@@ -52,6 +114,7 @@ type CreateRoomParams struct {
 	Description string              `json:"description"`
 	Slug        string              `json:"slug"`
 	Settings    models.RoomSettings `json:"settings"`
+	Expiry      *models.RoomExpiry  `json:"expiry,omitempty"`
 }
 
 // CreateRoom creates a new room.
@@ -80,11 +143,18 @@ func (h *RoomHandler) CreateRoom(ctx context.Context, client *rpc.Client, p *Cre
 		Moderators:  []bson.ObjectID{userID}, // Creator is automatically a moderator
 		BannedUsers: []bson.ObjectID{},
 		IsActive:    true,
+		Expiry:      p.Expiry,
 	}
 
 	// Create room
 	createdRoom, err := h.roomManager.CreateRoom(ctx, room)
 	if err != nil {
+		if errors.Is(err, models.ErrTrustLevelTooLow) {
+			return nil, rpc.NewError(rpc.ErrNotAuthorized, "trust level too low to create rooms", nil)
+		}
+		if errors.Is(err, models.ErrInvalidRoomExpiry) || errors.Is(err, models.ErrInvalidVoteSettings) || errors.Is(err, models.ErrInvalidChatAppearance) {
+			return nil, rpc.NewError(rpc.ErrInvalidParams, err.Error(), nil)
+		}
 		h.logger.Error("Failed to create room", err, "name", p.Name, "slug", p.Slug, "userId", client.UserID)
 		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
 	}
@@ -94,6 +164,8 @@ func (h *RoomHandler) CreateRoom(ctx context.Context, client *rpc.Client, p *Cre
 	if err != nil {
 		h.logger.Error("Failed to join room after creation", err, "roomId", createdRoom.ID.Hex(), "userId", client.UserID)
 		// Continue anyway, the room was created successfully
+	} else {
+		client.JoinRoom(createdRoom.ID.Hex())
 	}
 
 	return createdRoom, nil
@@ -194,7 +266,7 @@ func (h *RoomHandler) UpdateRoom(ctx context.Context, client *rpc.Client, p *Upd
 	}
 
 	// Check if user is creator or moderator
-	if room.CreatedBy != userID && !slices.Contains(room.Moderators, userID) {
+	if !slices.Contains(room.Staff(), userID) {
 		return nil, rpc.ErrNotAuthorized.Error()
 	}
 
@@ -207,6 +279,9 @@ func (h *RoomHandler) UpdateRoom(ctx context.Context, client *rpc.Client, p *Upd
 	// Update room
 	updatedRoom, err := h.roomManager.UpdateRoom(ctx, room)
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidVoteSettings) || errors.Is(err, models.ErrInvalidChatAppearance) {
+			return nil, rpc.NewError(rpc.ErrInvalidParams, err.Error(), nil)
+		}
 		h.logger.Error("Failed to update room", err, "roomId", p.RoomID)
 		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
 	}
@@ -257,12 +332,24 @@ func (h *RoomHandler) DeleteRoom(ctx context.Context, client *rpc.Client, p *Roo
 	return true, nil
 }
 
+// JoinRoomParams represents the parameters for the JoinRoom method.
+type JoinRoomParams struct {
+	RoomID string `json:"roomId"`
+
+	// Topics are the topics of the room's events to follow: chat, state and queue. None means all of them.
+	Topics []string `json:"topics"`
+}
+
 // JoinRoom joins a room.
-func (h *RoomHandler) JoinRoom(ctx context.Context, client *rpc.Client, p *RoomIDParam) (any, error) {
+func (h *RoomHandler) JoinRoom(ctx context.Context, client *rpc.Client, p *JoinRoomParams) (any, error) {
 	// Validate parameters
 	if p.RoomID == "" {
 		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId is required", nil)
 	}
+	topics, err := rpc.ParseTopics(p.Topics)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, err.Error(), nil)
+	}
 
 	// Convert IDs to ObjectIDs
 	roomID, err := bson.ObjectIDFromHex(p.RoomID)
@@ -281,29 +368,243 @@ func (h *RoomHandler) JoinRoom(ctx context.Context, client *rpc.Client, p *RoomI
 		if errors.Is(err, models.ErrRoomNotFound) {
 			return nil, rpc.ErrRoomNotFound.Error()
 		}
-		if errors.Is(err, errors.New("room is at capacity")) {
+		if errors.Is(err, models.ErrRoomFull) {
 			return nil, rpc.ErrRoomFull.Error()
 		}
+		var queued *room.JoinQueuedError
+		if errors.As(err, &queued) {
+			// The room isn't full, this server is busy, so the client should wait its turn and try again
+			return nil, rpc.NewError(rpc.ErrRoomFull, err.Error(), map[string]any{
+				"queued":        true,
+				"queuePosition": queued.Position,
+				"retryAfter":    int(queued.RetryAfter.Seconds()),
+			})
+		}
 		if errors.Is(err, errors.New("room is not active")) {
 			return nil, rpc.ErrRoomClosed.Error()
 		}
 		if errors.Is(err, errors.New("user is banned from this room")) {
 			return nil, rpc.NewError(rpc.ErrNotAuthorized, "user is banned from this room", nil)
 		}
+		if errors.Is(err, models.ErrRoomQuarantined) || errors.Is(err, models.ErrAdultsOnly) {
+			return nil, rpc.NewError(rpc.ErrNotAuthorized, err.Error(), nil)
+		}
 		h.logger.Error("Failed to join room", err, "roomId", p.RoomID, "userId", client.UserID)
 		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
 	}
 
-	// Get room state
-	state, err := h.roomManager.GetRoomState(ctx, roomID)
+	// Follow the room's events on this connection, and let the room know on every node
+	client.JoinRoom(p.RoomID)
+	client.SetRoomTopics(p.RoomID, topics)
+	client.NotifyRoom(p.RoomID, rpc.EventUserJoinedRoom, map[string]any{
+		"roomId":   p.RoomID,
+		"userId":   client.UserID,
+		"username": client.Username,
+	})
+
+	// Attribute the listener's country for aggregate room statistics
+	h.trackListenerGeo(ctx, client, p.RoomID)
+
+	// Get room state, clients that can take it progressively get only the core of large rooms. Clients
+	// rejoining after reconnecting share it, so a reconnect storm doesn't load each room once per client
+	progressive := client.HasCapability(rpc.CapProgressiveJoin)
+	getState := h.roomManager.GetJoinState
+	if client.Rejoining() {
+		getState = h.roomManager.GetRejoinState
+	}
+	state, err := getState(ctx, roomID, progressive)
 	if err != nil {
 		h.logger.Error("Failed to get room state after joining", err, "roomId", p.RoomID)
 		return true, nil // Return success anyway, the user joined the room
 	}
 
+	// Let the client render the limited mode if the user landed in the overflow
+	listenerOnly, err := h.roomManager.IsListenerOnly(ctx, roomID, userID)
+	if err != nil {
+		h.logger.Error("Failed to check listener-only status", err, "roomId", p.RoomID, "userId", client.UserID)
+	}
+	state.ListenerOnly = listenerOnly
+
+	// Deliver the chat of the user's shard to this connection
+	if !listenerOnly {
+		state.ChatShard = h.roomManager.ChatShardOf(ctx, roomID, userID)
+		client.SetChatShard(p.RoomID, state.ChatShard)
+	}
+
+	// Include pinned messages so the client can render the pinned banner right away
+	followsChat := client.FollowsTopic(p.RoomID, rpc.TopicChat)
+	if followsChat {
+		pinned, err := h.chatService.GetPinnedMessages(ctx, p.RoomID)
+		if err != nil {
+			h.logger.Error("Failed to get pinned messages", err, "roomId", p.RoomID)
+		}
+		state.PinnedMessages = pinned
+	}
+
+	// Include the chat backlog so joining users see the conversation, progressive joins stream it instead
+	backlog := h.chatBacklogDepth(state.Settings)
+	if !followsChat {
+		backlog = 0
+	}
+	if state.StateMode != models.RoomStateModeProgressive && backlog > 0 {
+		messages, err := h.chatService.GetBacklog(ctx, p.RoomID, backlog)
+		if err != nil {
+			h.logger.Error("Failed to get chat backlog", err, "roomId", p.RoomID)
+		}
+		state.ChatBacklog = chatShardMessages(messages, state.ChatShard)
+	}
+
+	// Stream the rest of the room once the core payload is out
+	if state.StateMode == models.RoomStateModeProgressive {
+		stream, err := client.NewChunkStream("room.joinChunk")
+		if err != nil {
+			h.logger.Error("Failed to start join stream", err, "roomId", p.RoomID)
+			return state, nil // The client can still list the room's users with room.getUsers
+		}
+		state.JoinStream = stream.ID
+
+		streamCtx := context.WithoutCancel(ctx)
+		client.AfterResponse(func() {
+			h.streamJoin(streamCtx, stream, roomID, backlog, state.ChatShard)
+		})
+	}
+
 	return state, nil
 }
 
+// UpdateSubscriptionsParams represents the parameters for the UpdateSubscriptions method.
+type UpdateSubscriptionsParams struct {
+	RoomID string `json:"roomId"`
+
+	// Topics are the topics of the room's events to follow: chat, state and queue. None means all of them.
+	Topics []string `json:"topics"`
+
+	// ChatChannels are the channels of the room's chat to follow. None means all of them.
+	ChatChannels []string `json:"chatChannels"`
+}
+
+// UpdateSubscriptions changes the topics of a joined room's events and the channels of its chat this
+// connection follows, so lightweight clients can skip what they don't show. It returns what is now followed.
+func (h *RoomHandler) UpdateSubscriptions(ctx context.Context, client *rpc.Client, p *UpdateSubscriptionsParams) (any, error) {
+	// Validate parameters
+	if p.RoomID == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId is required", nil)
+	}
+	topics, err := rpc.ParseTopics(p.Topics)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, err.Error(), nil)
+	}
+
+	if !client.IsInRoom(p.RoomID) {
+		return nil, rpc.NewError(rpc.ErrUserNotInRoom, "join the room before choosing its topics", nil)
+	}
+
+	// Only the room's chat channels can be followed
+	if len(p.ChatChannels) > 0 {
+		channels, err := h.chatService.GetChatChannels(ctx, p.RoomID)
+		if err != nil {
+			h.logger.Error("Failed to get chat channels", err, "roomId", p.RoomID)
+			return nil, rpc.NewError(rpc.ErrInternalError, "failed to get chat channels", nil)
+		}
+		for _, name := range p.ChatChannels {
+			if !slices.ContainsFunc(channels, func(c models.ChatChannel) bool { return c.Name == name }) {
+				return nil, rpc.NewError(rpc.ErrInvalidParams, fmt.Sprintf("unknown chat channel %q", name), nil)
+			}
+		}
+	}
+
+	client.SetRoomTopics(p.RoomID, topics)
+	client.SetChatChannels(p.RoomID, p.ChatChannels)
+	return map[string]any{
+		"roomId":       p.RoomID,
+		"topics":       client.RoomTopics(p.RoomID),
+		"chatChannels": client.ChatChannels(p.RoomID),
+	}, nil
+}
+
+// CanJoin reports whether the current user can join a room right now, and the capacity that applies.
+// While the server is under load it includes the user's place in the room's join queue.
+func (h *RoomHandler) CanJoin(ctx context.Context, client *rpc.Client, p *RoomIDParam) (any, error) {
+	// Validate parameters
+	if p.RoomID == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId is required", nil)
+	}
+
+	// Convert IDs to ObjectIDs
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
+	}
+
+	userID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
+	availability, err := h.roomManager.CanJoin(ctx, roomID, userID)
+	if err != nil {
+		if errors.Is(err, models.ErrRoomNotFound) {
+			return nil, rpc.ErrRoomNotFound.Error()
+		}
+		h.logger.Error("Failed to check whether user can join room", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
+	}
+
+	return availability, nil
+}
+
+// streamJoin sends what a progressive join payload left out: the DJ queue, the chat backlog and the roster in pages.
+// The queue and chat come first since the room is usable without the rest of the roster. The chat backlog
+// is the one of the user's chat shard.
+func (h *RoomHandler) streamJoin(ctx context.Context, stream *rpc.ChunkStream, roomID bson.ObjectID, backlog, chatShard int) {
+	defer stream.End()
+
+	queue, err := h.queueManager.GetQueue(ctx, roomID)
+	if err != nil {
+		h.logger.Error("Failed to get queue for join stream", err, "roomId", roomID.Hex())
+	} else {
+		stream.Send("queue", queue, 0, len(queue))
+	}
+
+	if backlog > 0 {
+		messages, err := h.chatService.GetBacklog(ctx, roomID.Hex(), backlog)
+		if err != nil {
+			h.logger.Error("Failed to get chat backlog for join stream", err, "roomId", roomID.Hex())
+		} else {
+			messages = chatShardMessages(messages, chatShard)
+			stream.Send("chat", messages, 0, len(messages))
+		}
+	}
+
+	pageSize := max(h.joinPolicy.RosterPageSize, 1)
+	for offset := 0; ; offset += pageSize {
+		users, total, err := h.roomManager.GetRosterPage(ctx, roomID, offset, pageSize)
+		if err != nil {
+			h.logger.Error("Failed to get roster page for join stream", err, "roomId", roomID.Hex(), "offset", offset)
+			return
+		}
+		stream.Send("roster", users, offset, total)
+		if offset+pageSize >= total {
+			return
+		}
+	}
+}
+
+// chatShardMessages keeps the messages posted in a chat shard, and those room staff broadcast to every shard.
+func chatShardMessages(messages []models.ChatMessage, shard int) []models.ChatMessage {
+	return slices.DeleteFunc(messages, func(message models.ChatMessage) bool {
+		return message.ChatShard != shard && !message.Broadcast
+	})
+}
+
+// chatBacklogDepth gets the number of recent chat messages sent to users joining a room.
+func (h *RoomHandler) chatBacklogDepth(settings models.RoomSettings) int {
+	if settings.ChatBacklog > 0 {
+		return settings.ChatBacklog
+	}
+	return h.joinPolicy.ChatBacklog
+}
+
 // LeaveRoom leaves a room.
 func (h *RoomHandler) LeaveRoom(ctx context.Context, client *rpc.Client, p *RoomIDParam) (any, error) {
 	// Validate parameters
@@ -331,6 +632,15 @@ func (h *RoomHandler) LeaveRoom(ctx context.Context, client *rpc.Client, p *Room
 		h.logger.Error("Failed to leave room", err, "roomId", p.RoomID, "userId", client.UserID)
 		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
 	}
+	client.LeaveRoom(p.RoomID)
+	client.NotifyRoom(p.RoomID, rpc.EventUserLeftRoom, map[string]any{
+		"roomId": p.RoomID,
+		"userId": client.UserID,
+	})
+
+	if err := h.listenerGeoMgr.UntrackListener(ctx, p.RoomID, client.UserID); err != nil {
+		h.logger.Error("Failed to untrack listener geo", err, "roomId", p.RoomID)
+	}
 
 	return true, nil
 }
@@ -401,6 +711,361 @@ func (h *RoomHandler) IsUserInRoom(ctx context.Context, client *rpc.Client, p *I
 	return inRoom, nil
 }
 
+// VoteParams represents the parameters for the Vote method.
+type VoteParams struct {
+	RoomID string `json:"roomId"`
+	Vote   string `json:"vote"`
+}
+
+// Vote votes on the media playing in a room.
+func (h *RoomHandler) Vote(ctx context.Context, client *rpc.Client, p *VoteParams) (any, error) {
+	// Validate parameters
+	if p.RoomID == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId is required", nil)
+	}
+	if p.Vote != models.VoteWoot && p.Vote != models.VoteMeh && p.Vote != models.VoteGrab {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "vote must be woot, meh or grab", nil)
+	}
+
+	// Convert IDs to ObjectIDs
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
+	}
+
+	userID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
+	// Record vote
+	votes, err := h.roomManager.Vote(ctx, roomID, userID, p.Vote)
+	if err != nil {
+		if errors.Is(err, models.ErrRoomNotFound) {
+			return nil, rpc.ErrRoomNotFound.Error()
+		}
+		if errors.Is(err, models.ErrVoteWindowClosed) {
+			return nil, rpc.NewError(rpc.ErrInvalidRequest, err.Error(), nil)
+		}
+		h.logger.Error("Failed to record vote", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
+	}
+
+	return votes, nil
+}
+
+// ReactParams represents the parameters for the React method.
+type ReactParams struct {
+	RoomID   string `json:"roomId"`
+	Reaction string `json:"reaction"`
+}
+
+// React adds a reaction to the media playing in a room, or takes it back if it was already added.
+func (h *RoomHandler) React(ctx context.Context, client *rpc.Client, p *ReactParams) (any, error) {
+	// Validate parameters
+	if p.RoomID == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId is required", nil)
+	}
+	if !slices.Contains(models.ReactionTypes, p.Reaction) {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "reaction must be fire or laugh", nil)
+	}
+
+	// Convert IDs to ObjectIDs
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
+	}
+
+	userID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
+	// Toggle reaction
+	votes, err := h.roomManager.React(ctx, roomID, userID, p.Reaction)
+	if err != nil {
+		if errors.Is(err, models.ErrRoomNotFound) {
+			return nil, rpc.ErrRoomNotFound.Error()
+		}
+		if errors.Is(err, models.ErrVoteWindowClosed) || errors.Is(err, models.ErrReactionDisabled) {
+			return nil, rpc.NewError(rpc.ErrInvalidRequest, err.Error(), nil)
+		}
+		h.logger.Error("Failed to record reaction", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
+	}
+
+	return votes, nil
+}
+
+// SetLanguageParams represents the parameters for the SetLanguage method.
+type SetLanguageParams struct {
+	RoomID   string `json:"roomId"`
+	Language string `json:"language"`
+}
+
+// SetLanguage sets the language of a room, overriding the one detected from its chat.
+// An empty language goes back to detecting it.
+func (h *RoomHandler) SetLanguage(ctx context.Context, client *rpc.Client, p *SetLanguageParams) (any, error) {
+	// Validate parameters
+	if p.RoomID == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId is required", nil)
+	}
+
+	// Convert IDs to ObjectIDs
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
+	}
+
+	userID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
+	// Set language
+	updatedRoom, err := h.roomManager.SetLanguage(ctx, roomID, userID, p.Language)
+	if err != nil {
+		if errors.Is(err, models.ErrRoomNotFound) {
+			return nil, rpc.ErrRoomNotFound.Error()
+		}
+		if errors.Is(err, room.ErrNotAuthorized) {
+			return nil, rpc.ErrNotAuthorized.Error()
+		}
+		if errors.Is(err, models.ErrInvalidLanguage) {
+			return nil, rpc.NewError(rpc.ErrInvalidParams, err.Error(), nil)
+		}
+		h.logger.Error("Failed to set room language", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
+	}
+
+	return updatedRoom, nil
+}
+
+// SetRoleParams represents the parameters for the SetRole method.
+type SetRoleParams struct {
+	RoomID string `json:"roomId"`
+	UserID string `json:"userId"`
+	Role   string `json:"role"`
+}
+
+// SetRole gives a user a role in a room, or takes it away with the user role.
+// Users can only give roles below their own, to users below them.
+func (h *RoomHandler) SetRole(ctx context.Context, client *rpc.Client, p *SetRoleParams) (any, error) {
+	// Validate parameters
+	if p.RoomID == "" || p.UserID == "" || p.Role == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId, userId and role are required", nil)
+	}
+
+	// Convert IDs to ObjectIDs
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
+	}
+
+	targetID, err := bson.ObjectIDFromHex(p.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
+	actorID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
+	// Set role
+	updatedRoom, err := h.roomManager.SetRole(ctx, roomID, actorID, targetID, p.Role)
+	if err != nil {
+		return nil, roleError(err, h.logger, "Failed to set room role", p.RoomID)
+	}
+
+	result := map[string]any{
+		"roomId": p.RoomID,
+		"userId": p.UserID,
+		"role":   updatedRoom.RoleOf(targetID),
+	}
+	client.NotifyRoom(p.RoomID, rpc.EventRolesChanged, result)
+	return result, nil
+}
+
+// GetRoles gets the roles given in a room and the permissions of each role.
+func (h *RoomHandler) GetRoles(ctx context.Context, client *rpc.Client, p *RoomIDParam) (any, error) {
+	// Validate parameters
+	if p.RoomID == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId is required", nil)
+	}
+
+	// Convert room ID to ObjectID
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
+	}
+
+	// Get roles
+	roles, err := h.roomManager.GetRoles(ctx, roomID)
+	if err != nil {
+		return nil, roleError(err, h.logger, "Failed to get room roles", p.RoomID)
+	}
+
+	return roles, nil
+}
+
+// SetRolePermissionsParams represents the parameters for the SetRolePermissions method.
+type SetRolePermissionsParams struct {
+	RoomID      string              `json:"roomId"`
+	Permissions map[string][]string `json:"permissions"`
+}
+
+// SetRolePermissions changes what the roles of a room are allowed to do. Only the owner can change them.
+// Roles left out keep their default permissions.
+func (h *RoomHandler) SetRolePermissions(ctx context.Context, client *rpc.Client, p *SetRolePermissionsParams) (any, error) {
+	// Validate parameters
+	if p.RoomID == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId is required", nil)
+	}
+
+	// Convert IDs to ObjectIDs
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
+	}
+
+	userID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
+	// Set permissions
+	updatedRoom, err := h.roomManager.SetRolePermissions(ctx, roomID, userID, p.Permissions)
+	if err != nil {
+		return nil, roleError(err, h.logger, "Failed to set room role permissions", p.RoomID)
+	}
+
+	permissions := updatedRoom.PermissionMatrix()
+	client.NotifyRoom(p.RoomID, rpc.EventRolesChanged, map[string]any{
+		"roomId":      p.RoomID,
+		"permissions": permissions,
+	})
+
+	return permissions, nil
+}
+
+// SetChatAppearanceParams represents the parameters for the SetChatAppearance method.
+type SetChatAppearanceParams struct {
+	RoomID string `json:"roomId"`
+	Color  string `json:"color"`
+	Flair  string `json:"flair"`
+}
+
+// SetChatAppearance sets the color and flair of the user's name in a room's chat, from the ones the room allows.
+// Leaving both empty resets them.
+func (h *RoomHandler) SetChatAppearance(ctx context.Context, client *rpc.Client, p *SetChatAppearanceParams) (any, error) {
+	// Validate parameters
+	if p.RoomID == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId is required", nil)
+	}
+
+	// Convert IDs to ObjectIDs
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
+	}
+
+	userID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
+	// Set appearance
+	appearance, err := h.roomManager.SetChatAppearance(ctx, roomID, userID, models.ChatAppearance{Color: p.Color, Flair: p.Flair})
+	if err != nil {
+		return nil, chatAppearanceError(err, h.logger, "Failed to set chat appearance", p.RoomID)
+	}
+
+	result := map[string]any{
+		"roomId":     p.RoomID,
+		"userId":     client.UserID,
+		"appearance": appearance,
+	}
+	client.NotifyRoom(p.RoomID, rpc.EventChatAppearanceChanged, result)
+	return result, nil
+}
+
+// ResetChatAppearanceParams represents the parameters for the ResetChatAppearance method.
+type ResetChatAppearanceParams struct {
+	RoomID string `json:"roomId"`
+	UserID string `json:"userId"`
+}
+
+// ResetChatAppearance resets the color and flair of a user's name in a room's chat. Moderators with the
+// chat delete permission reset those of users below them, for abusive customizations.
+func (h *RoomHandler) ResetChatAppearance(ctx context.Context, client *rpc.Client, p *ResetChatAppearanceParams) (any, error) {
+	// Validate parameters
+	if p.RoomID == "" || p.UserID == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId and userId are required", nil)
+	}
+
+	// Convert IDs to ObjectIDs
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
+	}
+
+	targetID, err := bson.ObjectIDFromHex(p.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
+	actorID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
+	// Reset appearance
+	if err := h.roomManager.ResetChatAppearance(ctx, roomID, actorID, targetID); err != nil {
+		return nil, chatAppearanceError(err, h.logger, "Failed to reset chat appearance", p.RoomID)
+	}
+
+	result := map[string]any{
+		"roomId":     p.RoomID,
+		"userId":     p.UserID,
+		"appearance": nil,
+	}
+	client.NotifyRoom(p.RoomID, rpc.EventChatAppearanceChanged, result)
+	return result, nil
+}
+
+// chatAppearanceError converts an error from customizing a user's name in a room's chat to an RPC error.
+func chatAppearanceError(err error, logger *utils.Logger, message, roomID string) error {
+	switch {
+	case errors.Is(err, models.ErrRoomNotFound):
+		return rpc.ErrRoomNotFound.Error()
+	case errors.Is(err, models.ErrUserNotInRoom):
+		return rpc.NewError(rpc.ErrUserNotInRoom, "join the room before customizing your chat name", nil)
+	case errors.Is(err, room.ErrNotAuthorized):
+		return rpc.ErrNotAuthorized.Error()
+	case errors.Is(err, models.ErrInvalidChatAppearance):
+		return rpc.NewError(rpc.ErrInvalidParams, err.Error(), nil)
+	default:
+		logger.Error(message, err, "roomId", roomID)
+		return rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
+	}
+}
+
+// roleError converts an error from managing the roles of a room to an RPC error.
+func roleError(err error, logger *utils.Logger, message, roomID string) error {
+	switch {
+	case errors.Is(err, models.ErrRoomNotFound):
+		return rpc.ErrRoomNotFound.Error()
+	case errors.Is(err, room.ErrNotAuthorized):
+		return rpc.ErrNotAuthorized.Error()
+	case errors.Is(err, models.ErrInvalidRoomRole):
+		return rpc.NewError(rpc.ErrInvalidParams, err.Error(), nil)
+	default:
+		logger.Error(message, err, "roomId", roomID)
+		return rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
+	}
+}
+
 // GetRoomState gets the current state of a room.
 func (h *RoomHandler) GetRoomState(ctx context.Context, client *rpc.Client, p *RoomIDParam) (any, error) {
 	// Validate parameters
@@ -427,12 +1092,74 @@ func (h *RoomHandler) GetRoomState(ctx context.Context, client *rpc.Client, p *R
 	return state, nil
 }
 
+// SyncPlaybackParams represents the parameters for the SyncPlayback method.
+type SyncPlaybackParams struct {
+	RoomID string `json:"roomId"`
+
+	// ClientTime is the client's time when it sent the request
+	ClientTime time.Time `json:"clientTime"`
+}
+
+// SyncPlaybackResult represents the result of the SyncPlayback method.
+type SyncPlaybackResult struct {
+	Playback models.PlaybackSync `json:"playback"`
+
+	// Clock holds the server's timestamps of the exchange, for the client to compute its clock offset
+	// once it stamps the response
+	Clock rpc.ClockSample `json:"clock"`
+}
+
+// SyncPlayback tells a client where a room's current media is by the server's clock, along with the
+// timestamps it needs to tell how far its own clock is off.
+func (h *RoomHandler) SyncPlayback(ctx context.Context, client *rpc.Client, p *SyncPlaybackParams) (any, error) {
+	received := time.Now()
+
+	// Validate parameters
+	if p.RoomID == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId is required", nil)
+	}
+
+	// Convert room ID to ObjectID
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
+	}
+
+	// Get room state
+	state, err := h.roomManager.GetRoomState(ctx, roomID)
+	if err != nil {
+		if errors.Is(err, models.ErrRoomNotFound) {
+			return nil, rpc.ErrRoomNotFound.Error()
+		}
+		h.logger.Error("Failed to get room state for playback sync", err, "roomId", p.RoomID)
+		return nil, rpc.NewError(rpc.ErrInternalError, "Failed to get room playback", nil)
+	}
+
+	var mediaID string
+	if state.CurrentMedia != nil {
+		mediaID = state.CurrentMedia.ID.Hex()
+	}
+
+	now := time.Now()
+	return SyncPlaybackResult{
+		Playback: models.NewPlaybackSync(p.RoomID, mediaID, state.MediaStartTime, state.MediaEndTime, now),
+		Clock: rpc.ClockSample{
+			ClientSent:     p.ClientTime,
+			ServerReceived: received,
+			ServerSent:     now,
+		},
+	}, nil
+}
+
 // SearchRoomsParams represents the parameters for the SearchRooms method.
 type SearchRoomsParams struct {
 	Query  string `json:"query"`
 	Skip   int    `json:"skip"`
 	Limit  int    `json:"limit"`
 	SortBy string `json:"sortBy"`
+
+	// Language is the ISO 639-1 code of the language rooms must chat in
+	Language string `json:"language"`
 }
 
 // SearchRooms searches for rooms based on criteria.
@@ -444,10 +1171,11 @@ func (h *RoomHandler) SearchRooms(ctx context.Context, client *rpc.Client, p *Se
 
 	// Create search criteria
 	criteria := models.RoomSearchCriteria{
-		Query:  p.Query,
-		Page:   p.Skip,
-		Limit:  p.Limit,
-		SortBy: p.SortBy,
+		Query:    p.Query,
+		Language: strings.ToLower(p.Language),
+		Page:     p.Skip,
+		Limit:    p.Limit,
+		SortBy:   p.SortBy,
 	}
 
 	// Search rooms
@@ -456,13 +1184,15 @@ func (h *RoomHandler) SearchRooms(ctx context.Context, client *rpc.Client, p *Se
 		h.logger.Error("Failed to search rooms", err, "query", p.Query)
 		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
 	}
+	visible := h.roomManager.VisibleRooms(ctx, client.UserID, rooms)
+	total -= int64(len(rooms) - len(visible))
 
 	// Create response
 	response := struct {
 		Rooms []*models.Room `json:"rooms"`
 		Total int64          `json:"total"`
 	}{
-		Rooms: rooms,
+		Rooms: visible,
 		Total: total,
 	}
 
@@ -488,7 +1218,7 @@ func (h *RoomHandler) GetActiveRooms(ctx context.Context, client *rpc.Client, p
 		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
 	}
 
-	return rooms, nil
+	return h.roomManager.VisibleRooms(ctx, client.UserID, rooms), nil
 }
 
 // GetPopularRoomsParams represents the parameters for the GetPopularRooms method.
@@ -510,5 +1240,189 @@ func (h *RoomHandler) GetPopularRooms(ctx context.Context, client *rpc.Client, p
 		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
 	}
 
-	return rooms, nil
+	return h.roomManager.VisibleRooms(ctx, client.UserID, rooms), nil
+}
+
+// ListenerGeoEntry represents the number of listeners from a single country.
+type ListenerGeoEntry struct {
+	Country   string `json:"country"`
+	Listeners int    `json:"listeners"`
+}
+
+// ListenerGeoResult represents the aggregated listener geo distribution of a room.
+type ListenerGeoResult struct {
+	RoomID     string             `json:"roomId"`
+	Listeners  int                `json:"listeners"`
+	Attributed int                `json:"attributed"`
+	Countries  []ListenerGeoEntry `json:"countries"`
+}
+
+// GetListenerGeo gets the country-level listener distribution of a room.
+// Only the room owner may request it, and only aggregated counts are returned.
+func (h *RoomHandler) GetListenerGeo(ctx context.Context, client *rpc.Client, p *RoomIDParam) (any, error) {
+	// Validate parameters
+	if p.RoomID == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId is required", nil)
+	}
+
+	// Convert IDs to ObjectIDs
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
+	}
+
+	userID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
+	// Get room
+	room, err := h.roomManager.GetRoom(ctx, roomID)
+	if err != nil {
+		if errors.Is(err, models.ErrRoomNotFound) {
+			return nil, rpc.ErrRoomNotFound.Error()
+		}
+		h.logger.Error("Failed to get room", err, "roomId", p.RoomID)
+		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
+	}
+
+	// Check if user is the owner
+	if room.CreatedBy != userID {
+		return nil, rpc.ErrNotAuthorized.Error()
+	}
+
+	// Only count listeners that are currently in the room
+	users, err := h.roomManager.GetRoomUsers(ctx, roomID)
+	if err != nil {
+		h.logger.Error("Failed to get room users", err, "roomId", p.RoomID)
+		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
+	}
+
+	activeUsers := make(map[string]bool, len(users))
+	for _, u := range users {
+		activeUsers[u.ID.Hex()] = true
+	}
+
+	counts, err := h.listenerGeoMgr.GetCountryCounts(ctx, p.RoomID, activeUsers)
+	if err != nil {
+		h.logger.Error("Failed to get listener geo", err, "roomId", p.RoomID)
+		return nil, rpc.NewError(rpc.ErrInternalError, err.Error(), nil)
+	}
+
+	result := &ListenerGeoResult{
+		RoomID:    p.RoomID,
+		Listeners: len(users),
+		Countries: []ListenerGeoEntry{},
+	}
+
+	other := 0
+	for country, count := range counts {
+		result.Attributed += count
+		if count < minGeoBucketSize {
+			other += count
+			continue
+		}
+		result.Countries = append(result.Countries, ListenerGeoEntry{Country: country, Listeners: count})
+	}
+
+	sort.Slice(result.Countries, func(i, j int) bool {
+		if result.Countries[i].Listeners != result.Countries[j].Listeners {
+			return result.Countries[i].Listeners > result.Countries[j].Listeners
+		}
+		return result.Countries[i].Country < result.Countries[j].Country
+	})
+
+	if other > 0 {
+		result.Countries = append(result.Countries, ListenerGeoEntry{Country: "other", Listeners: other})
+	}
+
+	return result, nil
+}
+
+// trackListenerGeo records the client's country for a room unless the user opted out.
+func (h *RoomHandler) trackListenerGeo(ctx context.Context, client *rpc.Client, roomID string) {
+	country := client.Country()
+	if country == "" {
+		return
+	}
+
+	u, err := h.userManager.GetUserByID(ctx, client.UserID)
+	if err != nil {
+		h.logger.Error("Failed to get user for listener geo", err, "userId", client.UserID)
+		return
+	}
+
+	// Honor the opt-out, dropping any attribution left from an earlier join
+	if u.Settings.HideListenerGeo {
+		if err := h.listenerGeoMgr.UntrackListener(ctx, roomID, client.UserID); err != nil {
+			h.logger.Error("Failed to untrack listener geo", err, "roomId", roomID)
+		}
+		return
+	}
+
+	if err := h.listenerGeoMgr.TrackListener(ctx, roomID, client.UserID, country); err != nil {
+		h.logger.Error("Failed to track listener geo", err, "roomId", roomID)
+	}
+}
+
+// ReportRoomParams represents the parameters for the ReportRoom method.
+type ReportRoomParams struct {
+	RoomID      string                  `json:"roomId"`
+	Reason      models.RoomReportReason `json:"reason"`
+	Description string                  `json:"description"`
+
+	// CaptchaToken is the captcha the client solved, required from risky reporters.
+	CaptchaToken string `json:"captchaToken"`
+}
+
+// ReportRoom reports a whole room to the platform admins. The room's own moderators don't see the report.
+func (h *RoomHandler) ReportRoom(ctx context.Context, client *rpc.Client, p *ReportRoomParams) (any, error) {
+	// Validate parameters
+	if p.RoomID == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId is required", nil)
+	}
+
+	// Convert IDs to ObjectIDs
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
+	}
+
+	userID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
+	// Require a captcha from risky reporters
+	if err := h.captchaService.Check(ctx, user.CaptchaRequest{
+		Action:   user.CaptchaActionReport,
+		Token:    p.CaptchaToken,
+		RemoteIP: client.IP(),
+		UserID:   client.UserID,
+	}); err != nil {
+		if errors.Is(err, models.ErrCaptchaUnavailable) {
+			return nil, rpc.NewError(rpc.ErrServerError, err.Error(), nil)
+		}
+		return nil, rpc.NewError(rpc.ErrNotAuthorized, err.Error(), nil)
+	}
+
+	report, err := h.reportService.ReportRoom(ctx, roomID, userID, &models.RoomReportRequest{
+		Reason:      p.Reason,
+		Description: p.Description,
+	})
+	if err != nil {
+		var domainErr *models.DomainError
+		switch {
+		case errors.Is(err, models.ErrRoomNotFound):
+			return nil, rpc.ErrRoomNotFound.Error()
+		case errors.As(err, &domainErr) && errors.Is(err, models.ErrInvalidRoomReport):
+			return nil, rpc.NewError(rpc.ErrInvalidParams, domainErr.Message, nil)
+		case errors.Is(err, models.ErrRoomAlreadyReported):
+			return nil, rpc.NewError(rpc.ErrInvalidRequest, err.Error(), nil)
+		}
+		h.logger.Error("Failed to report room", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, rpc.NewError(rpc.ErrInternalError, "Failed to report room", nil)
+	}
+
+	return report, nil
 }
@@ -0,0 +1,166 @@
+// Package methods contains RPC method handlers for the application.
+package methods
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/rpc"
+	"norelock.dev/listenify/backend/internal/services/room"
+)
+
+// ScheduleEventParams represents the parameters for the ScheduleEvent method.
+type ScheduleEventParams struct {
+	RoomID      string    `json:"roomId"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Theme       string    `json:"theme"`
+	StartTime   time.Time `json:"startTime"`
+	EndTime     time.Time `json:"endTime"`
+	Hosts       []string  `json:"hosts"`
+}
+
+// CancelEventParams represents the parameters for the CancelEvent method.
+type CancelEventParams struct {
+	RoomID  string `json:"roomId"`
+	EventID string `json:"eventId"`
+}
+
+// ScheduleEvent schedules an event in a room, with its theme and the hosts lined up to DJ it.
+// The room is reminded shortly before the event starts.
+func (h *RoomHandler) ScheduleEvent(ctx context.Context, client *rpc.Client, p *ScheduleEventParams) (any, error) {
+	// Validate parameters
+	if p.RoomID == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId is required", nil)
+	}
+
+	// Convert IDs to ObjectIDs
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
+	}
+
+	userID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
+	hosts := make([]bson.ObjectID, 0, len(p.Hosts))
+	for _, host := range p.Hosts {
+		hostID, err := bson.ObjectIDFromHex(host)
+		if err != nil {
+			return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid host ID", nil)
+		}
+		hosts = append(hosts, hostID)
+	}
+
+	event, err := h.calendarService.AddEvent(ctx, roomID, userID, &models.RoomEvent{
+		Title:       p.Title,
+		Description: p.Description,
+		Theme:       p.Theme,
+		StartTime:   p.StartTime,
+		EndTime:     p.EndTime,
+		Hosts:       hosts,
+	})
+	if err != nil {
+		if rpcErr := roomEventError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to schedule room event", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, rpc.NewError(rpc.ErrInternalError, "Failed to schedule room event", nil)
+	}
+
+	client.NotifyRoom(p.RoomID, rpc.EventRoomEventScheduled, event)
+	return event, nil
+}
+
+// CancelEvent cancels a scheduled room event.
+func (h *RoomHandler) CancelEvent(ctx context.Context, client *rpc.Client, p *CancelEventParams) (any, error) {
+	// Validate parameters
+	if p.RoomID == "" || p.EventID == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId and eventId are required", nil)
+	}
+
+	// Convert IDs to ObjectIDs
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
+	}
+
+	eventID, err := bson.ObjectIDFromHex(p.EventID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid eventId", nil)
+	}
+
+	userID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
+	if err := h.calendarService.RemoveEvent(ctx, roomID, eventID, userID); err != nil {
+		if rpcErr := roomEventError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to cancel room event", err, "roomId", p.RoomID, "eventId", p.EventID, "userId", client.UserID)
+		return nil, rpc.NewError(rpc.ErrInternalError, "Failed to cancel room event", nil)
+	}
+
+	client.NotifyRoom(p.RoomID, rpc.EventRoomEventCancelled, map[string]any{
+		"roomId":  p.RoomID,
+		"eventId": p.EventID,
+	})
+	return map[string]any{
+		"success": true,
+	}, nil
+}
+
+// GetUpcomingEvents gets a room's events that have not finished yet, soonest first.
+func (h *RoomHandler) GetUpcomingEvents(ctx context.Context, client *rpc.Client, p *RoomIDParam) (any, error) {
+	// Validate parameters
+	if p.RoomID == "" {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "roomId is required", nil)
+	}
+
+	// Convert room ID to ObjectID
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid roomId", nil)
+	}
+
+	userID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, rpc.NewError(rpc.ErrInvalidParams, "invalid userId", nil)
+	}
+
+	events, err := h.calendarService.GetEvents(ctx, roomID, userID)
+	if err != nil {
+		if rpcErr := roomEventError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to get room events", err, "roomId", p.RoomID)
+		return nil, rpc.NewError(rpc.ErrInternalError, "Failed to get room events", nil)
+	}
+
+	return events, nil
+}
+
+// roomEventError maps the errors of the room event methods, returning nil for unexpected errors.
+func roomEventError(err error) error {
+	var domainErr *models.DomainError
+	switch {
+	case errors.Is(err, models.ErrRoomNotFound):
+		return rpc.ErrRoomNotFound.Error()
+	case errors.Is(err, models.ErrRoomEventNotFound):
+		return rpc.NewError(rpc.ErrInvalidParams, "Room event not found", nil)
+	case errors.As(err, &domainErr) && errors.Is(err, models.ErrInvalidRoomEvent):
+		return rpc.NewError(rpc.ErrInvalidParams, domainErr.Message, nil)
+	case errors.Is(err, models.ErrInvalidRoomEvent):
+		return rpc.NewError(rpc.ErrInvalidParams, "Events need a title, and an end after their start that hasn't passed", nil)
+	case errors.Is(err, room.ErrNotAuthorized):
+		return rpc.ErrNotAuthorized.Error()
+	}
+	return nil
+}
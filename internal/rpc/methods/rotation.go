@@ -0,0 +1,114 @@
+// Package methods contains RPC method handlers for the application.
+package methods
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/rpc"
+	"norelock.dev/listenify/backend/internal/services/room"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// RotationHandler handles RPC methods for DJ rotation reports.
+type RotationHandler struct {
+	rotationReporter *room.RotationReporter
+	logger           *utils.Logger
+}
+
+// NewRotationHandler creates a new RotationHandler.
+func NewRotationHandler(rotationReporter *room.RotationReporter, logger *utils.Logger) *RotationHandler {
+	return &RotationHandler{
+		rotationReporter: rotationReporter,
+		logger:           logger,
+	}
+}
+
+// RegisterMethods registers rotation-related RPC methods with the router.
+func (h *RotationHandler) RegisterMethods(hr rpc.HandlerRegistry) {
+	auth := hr.Wrap(rpc.AuthMiddleware)
+	rpc.Register(auth, "room.getRotationReport", h.GetRotationReport)
+}
+
+// GetRotationReportParams represents the parameters for the getRotationReport method.
+type GetRotationReportParams struct {
+	RoomID string `json:"roomId" validate:"required"`
+	Hours  int    `json:"hours" validate:"min=0,max=720"`
+	Format string `json:"format" validate:"omitempty,oneof=json csv"`
+}
+
+// RotationReportCSVResult represents the result of the getRotationReport method in CSV format.
+type RotationReportCSVResult struct {
+	CSV      string `json:"csv"`
+	Filename string `json:"filename"`
+}
+
+// GetRotationReport handles getting the per-DJ rotation report of a room, as JSON or as CSV.
+func (h *RotationHandler) GetRotationReport(ctx context.Context, client *rpc.Client, p *GetRotationReportParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid room ID",
+		}
+	}
+
+	userID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid user ID",
+		}
+	}
+
+	report, err := h.rotationReporter.GetReport(ctx, roomID, userID, p.Hours)
+	if err != nil {
+		if errors.Is(err, models.ErrRoomNotFound) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrInvalidParams,
+				Message: "Room not found",
+			}
+		}
+		if errors.Is(err, room.ErrNotAuthorized) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrNotAuthorized,
+				Message: "Only the room's owner and moderators can view its rotation report",
+			}
+		}
+		h.logger.Error("Failed to get rotation report", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to get rotation report",
+		}
+	}
+
+	if p.Format != "csv" {
+		return report, nil
+	}
+
+	data, err := room.RotationReportCSV(report)
+	if err != nil {
+		h.logger.Error("Failed to render rotation report", err, "roomId", p.RoomID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to render rotation report",
+		}
+	}
+
+	return RotationReportCSVResult{
+		CSV:      string(data),
+		Filename: fmt.Sprintf("rotation-%s-%s.csv", p.RoomID, report.GeneratedAt.Format("20060102-1504")),
+	}, nil
+}
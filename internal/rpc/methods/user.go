@@ -13,17 +13,27 @@ import (
 
 // UserHandler handles user-related RPC methods.
 type UserHandler struct {
-	userManager  user.Manager
-	statsService *user.StatsService
-	logger       *utils.Logger
+	userManager   user.Manager
+	socialService *user.SocialService
+	statsService  *user.StatsService
+	apiKeyService *user.APIKeyService
+	scrobbler     *user.ScrobbleService
+	deletion      *user.AccountDeletionService
+	dataExporter  *user.DataExportService
+	logger        *utils.Logger
 }
 
 // NewUserHandler creates a new UserHandler.
-func NewUserHandler(userManager user.Manager, statsService *user.StatsService, logger *utils.Logger) *UserHandler {
+func NewUserHandler(userManager user.Manager, socialService *user.SocialService, statsService *user.StatsService, apiKeyService *user.APIKeyService, scrobbler *user.ScrobbleService, deletion *user.AccountDeletionService, dataExporter *user.DataExportService, logger *utils.Logger) *UserHandler {
 	return &UserHandler{
-		userManager:  userManager,
-		statsService: statsService,
-		logger:       logger,
+		userManager:   userManager,
+		socialService: socialService,
+		statsService:  statsService,
+		apiKeyService: apiKeyService,
+		scrobbler:     scrobbler,
+		deletion:      deletion,
+		dataExporter:  dataExporter,
+		logger:        logger,
 	}
 }
 
@@ -35,15 +45,41 @@ func (h *UserHandler) RegisterMethods(hr rpc.HandlerRegistry) {
 	rpc.RegisterNoParams(auth, "user.logout", h.Logout)
 	rpc.Register(hr, "user.getProfile", h.GetProfile)
 	rpc.Register(auth, "user.updateProfile", h.UpdateProfile)
+	rpc.Register(auth, "user.rename", h.Rename)
 	rpc.Register(auth, "user.changePassword", h.ChangePassword)
+	rpc.Register(auth, "user.attestAge", h.AttestAge)
 	rpc.RegisterNoParams(hr, "user.getOnlineUsers", h.GetOnlineUsers)
 	rpc.Register(hr, "user.searchUsers", h.SearchUsers)
 
+	// Social methods
+	rpc.Register(auth, "user.follow", h.Follow)
+	rpc.Register(auth, "user.unfollow", h.Unfollow)
+	rpc.Register(hr, "user.getFollowers", h.GetFollowers)
+	rpc.Register(hr, "user.getFollowing", h.GetFollowing)
+	rpc.RegisterNoParams(auth, "user.getFriendsOnline", h.GetFriendsOnline)
+
 	// Stats methods
 	rpc.Register(hr, "user.getStats", h.GetUserStats)
 	rpc.Register(hr, "user.getTopUsers", h.GetTopUsers)
 	rpc.Register(hr, "user.getRank", h.GetUserRank)
 	rpc.Register(hr, "user.getExperienceProgress", h.GetExperienceProgress)
+	rpc.Register(hr, "user.getAchievements", h.GetAchievements)
+
+	// API key methods
+	rpc.Register(auth, "user.createApiKey", h.CreateAPIKey)
+	rpc.RegisterNoParams(auth, "user.listApiKeys", h.ListAPIKeys)
+	rpc.Register(auth, "user.revokeApiKey", h.RevokeAPIKey)
+
+	// Scrobbling methods
+	rpc.RegisterNoParams(auth, "user.getScrobbleAuthUrl", h.GetScrobbleAuthURL)
+	rpc.Register(auth, "user.linkScrobbler", h.LinkScrobbler)
+	rpc.RegisterNoParams(auth, "user.unlinkScrobbler", h.UnlinkScrobbler)
+	rpc.Register(auth, "user.setScrobbling", h.SetScrobbling)
+	rpc.Register(auth, "user.getScrobbles", h.GetScrobbles)
+
+	// Account data methods
+	rpc.Register(auth, "user.deleteAccount", h.DeleteAccount)
+	rpc.RegisterNoParams(auth, "user.exportData", h.ExportData)
 }
 
 // GetUserStats handles retrieving a user's statistics.
@@ -258,6 +294,42 @@ func (h *UserHandler) GetExperienceProgress(ctx context.Context, client *rpc.Cli
 	}, nil
 }
 
+// GetAchievements handles retrieving a user's progress towards every achievement.
+func (h *UserHandler) GetAchievements(ctx context.Context, client *rpc.Client, p *UserIDParam) (any, error) {
+	// If no user ID is provided, use the authenticated user's ID
+	userID := p.UserID
+	if userID == "" {
+		userID = client.UserID
+		if userID == "" {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrAuthenticationRequired,
+				Message: "Authentication required",
+			}
+		}
+	}
+
+	achievements, err := h.statsService.GetAchievements(ctx, userID)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) || errors.Is(err, models.ErrInvalidID) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrInvalidParams,
+				Message: "User not found",
+			}
+		}
+		h.logger.Error("Failed to get achievements", err, "userID", userID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to get achievements",
+		}
+	}
+
+	return struct {
+		Achievements []*models.AchievementProgress `json:"achievements"`
+	}{
+		Achievements: achievements,
+	}, nil
+}
+
 // LoginParams represents the parameters for the login method.
 type LoginParams struct {
 	Email    string `json:"email" validate:"required,email"`
@@ -296,6 +368,12 @@ func (h *UserHandler) Login(ctx context.Context, client *rpc.Client, p *LoginPar
 				Message: "Invalid email or password",
 			}
 		}
+		if errors.Is(err, models.ErrPasswordResetRequired) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrNotAuthorized,
+				Message: "Password reset required",
+			}
+		}
 		h.logger.Error("Failed to login user", err, "email", p.Email)
 		return nil, &rpc.Error{
 			Code:    rpc.ErrInternalError,
@@ -316,7 +394,7 @@ func (h *UserHandler) Login(ctx context.Context, client *rpc.Client, p *LoginPar
 
 // RegisterParams represents the parameters for the register method.
 type RegisterParams struct {
-	Username string `json:"username" validate:"required,min=3,max=20"`
+	Username string `json:"username" validate:"required"`
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=8"`
 }
@@ -354,11 +432,8 @@ func (h *UserHandler) Register(ctx context.Context, client *rpc.Client, p *Regis
 				Message: "Email already in use",
 			}
 		}
-		if errors.Is(err, models.ErrUsernameAlreadyExists) {
-			return nil, &rpc.Error{
-				Code:    rpc.ErrInvalidParams,
-				Message: "Username already in use",
-			}
+		if rpcErr := usernameError(err); rpcErr != nil {
+			return nil, rpcErr
 		}
 		h.logger.Error("Failed to register user", err, "email", p.Email, "username", p.Username)
 		return nil, &rpc.Error{
@@ -478,17 +553,90 @@ func (h *UserHandler) UpdateProfile(ctx context.Context, client *rpc.Client, p *
 				Message: "User not found",
 			}
 		}
+		if rpcErr := usernameError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
 		h.logger.Error("Failed to update user profile", err, "userID", client.UserID)
 		return nil, &rpc.Error{
 			Code:    rpc.ErrInternalError,
 			Message: "Failed to update user profile",
 		}
 	}
+	client.Username = user.Username
 
 	// Return updated public user
 	return user.ToPublicUser(), nil
 }
 
+// RenameParams represents the parameters for the rename method.
+type RenameParams struct {
+	Username string `json:"username" validate:"required"`
+}
+
+// RenameResult represents the result of the rename method.
+type RenameResult struct {
+	User            models.PublicUser       `json:"user"`
+	UsernameHistory []models.UsernameChange `json:"usernameHistory"`
+}
+
+// Rename handles changing the client's username. The user's rooms are told of the new username.
+func (h *UserHandler) Rename(ctx context.Context, client *rpc.Client, p *RenameParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	user, err := h.userManager.RenameUser(ctx, client.UserID, p.Username)
+	if err != nil {
+		if rpcErr := usernameError(err); rpcErr != nil {
+			return nil, rpcErr
+		}
+		h.logger.Error("Failed to rename user", err, "userID", client.UserID, "username", p.Username)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to change username",
+		}
+	}
+	client.Username = user.Username
+
+	return RenameResult{
+		User:            user.ToPublicUser(),
+		UsernameHistory: user.UsernameHistory,
+	}, nil
+}
+
+// usernameError maps the errors of choosing a username, returning nil for other errors.
+func usernameError(err error) *rpc.Error {
+	var domainErr *models.DomainError
+	switch {
+	case errors.Is(err, models.ErrUsernameAlreadyExists):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Username already in use",
+		}
+	case errors.Is(err, models.ErrUsernameChanged):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Your username was changed meanwhile, try again",
+		}
+	case errors.As(err, &domainErr) && (errors.Is(err, models.ErrInvalidUsername) || errors.Is(err, models.ErrRenameCooldown)):
+		return &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: domainErr.Message,
+		}
+	case errors.As(err, &domainErr) && errors.Is(err, models.ErrUnauthorizedAction):
+		return &rpc.Error{
+			Code:    rpc.ErrNotAuthorized,
+			Message: domainErr.Message,
+		}
+	}
+	return nil
+}
+
 // ChangePasswordParams represents the parameters for the changePassword method.
 type ChangePasswordParams struct {
 	CurrentPassword string `json:"currentPassword" validate:"required"`
@@ -545,6 +693,40 @@ func (h *UserHandler) ChangePassword(ctx context.Context, client *rpc.Client, p
 	}, nil
 }
 
+// AttestAgeParams represents the parameters for the attestAge method.
+type AttestAgeParams struct {
+	BirthDate string `json:"birthDate" validate:"required,datetime=2006-01-02"`
+}
+
+// AttestAge handles attesting the authenticated user's date of birth.
+func (h *UserHandler) AttestAge(ctx context.Context, client *rpc.Client, p *AttestAgeParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	attestation, err := h.userManager.AttestAge(ctx, client.UserID, p.BirthDate)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidBirthDate) || errors.Is(err, models.ErrAgeAlreadyAttested) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrInvalidParams,
+				Message: err.Error(),
+			}
+		}
+		h.logger.Error("Failed to attest user age", err, "userID", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to attest age",
+		}
+	}
+
+	return attestation, nil
+}
+
 // GetOnlineUsersResult represents the result of the getOnlineUsers method.
 type GetOnlineUsersResult struct {
 	Users []models.PublicUser `json:"users"`
@@ -625,3 +807,368 @@ func (h *UserHandler) SearchUsers(ctx context.Context, client *rpc.Client, p *Se
 		Total: len(publicUsers),
 	}, nil
 }
+
+// FollowParams represents the parameters for the follow and unfollow methods.
+type FollowParams struct {
+	UserID string `json:"userId" validate:"required"`
+}
+
+// Follow handles following a user.
+func (h *UserHandler) Follow(ctx context.Context, client *rpc.Client, p *FollowParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+	if p.UserID == client.UserID {
+		return nil, &rpc.Error{Code: rpc.ErrInvalidParams, Message: "You cannot follow yourself"}
+	}
+
+	if err := h.socialService.FollowUser(ctx, client.UserID, p.UserID); err != nil {
+		return nil, h.socialError(err, "Failed to follow user", client.UserID)
+	}
+
+	return map[string]bool{"success": true}, nil
+}
+
+// Unfollow handles unfollowing a user.
+func (h *UserHandler) Unfollow(ctx context.Context, client *rpc.Client, p *FollowParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+	if p.UserID == client.UserID {
+		return nil, &rpc.Error{Code: rpc.ErrInvalidParams, Message: "You cannot unfollow yourself"}
+	}
+
+	if err := h.socialService.UnfollowUser(ctx, client.UserID, p.UserID); err != nil {
+		return nil, h.socialError(err, "Failed to unfollow user", client.UserID)
+	}
+
+	return map[string]bool{"success": true}, nil
+}
+
+// GetConnectionsParams represents the parameters for the getFollowers and getFollowing methods.
+type GetConnectionsParams struct {
+	UserID string `json:"userId,omitempty"`
+	Skip   int    `json:"skip,omitempty" validate:"min=0"`
+	Limit  int    `json:"limit,omitempty" validate:"min=0,max=100"`
+}
+
+// GetConnectionsResult represents the result of the getFollowers and getFollowing methods.
+type GetConnectionsResult struct {
+	Users []*models.PublicUser `json:"users"`
+	Skip  int                  `json:"skip"`
+	Limit int                  `json:"limit"`
+}
+
+// GetFollowers handles listing the followers of a user, the authenticated user if none is given.
+func (h *UserHandler) GetFollowers(ctx context.Context, client *rpc.Client, p *GetConnectionsParams) (any, error) {
+	userID, rpcErr := h.connectionsParams(client, p)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	users, err := h.socialService.GetFollowers(ctx, userID, p.Skip, p.Limit)
+	if err != nil {
+		return nil, h.socialError(err, "Failed to get followers", userID)
+	}
+
+	return GetConnectionsResult{Users: users, Skip: p.Skip, Limit: p.Limit}, nil
+}
+
+// GetFollowing handles listing the users a user follows, the authenticated user if none is given.
+func (h *UserHandler) GetFollowing(ctx context.Context, client *rpc.Client, p *GetConnectionsParams) (any, error) {
+	userID, rpcErr := h.connectionsParams(client, p)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	users, err := h.socialService.GetFollowing(ctx, userID, p.Skip, p.Limit)
+	if err != nil {
+		return nil, h.socialError(err, "Failed to get following", userID)
+	}
+
+	return GetConnectionsResult{Users: users, Skip: p.Skip, Limit: p.Limit}, nil
+}
+
+// connectionsParams validates the parameters of connection listings and resolves whose connections are listed.
+func (h *UserHandler) connectionsParams(client *rpc.Client, p *GetConnectionsParams) (string, *rpc.Error) {
+	if err := utils.Validate(p); err != nil {
+		return "", &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	if p.Limit == 0 {
+		p.Limit = 20
+	}
+
+	userID := p.UserID
+	if userID == "" {
+		userID = client.UserID
+		if userID == "" {
+			return "", &rpc.Error{
+				Code:    rpc.ErrAuthenticationRequired,
+				Message: "Authentication required",
+			}
+		}
+	}
+	return userID, nil
+}
+
+// GetFriendsOnlineResult represents the result of the getFriendsOnline method.
+type GetFriendsOnlineResult struct {
+	Friends []*user.FriendPresence `json:"friends"`
+}
+
+// GetFriendsOnline handles listing the authenticated user's friends who are online, and the rooms they are in.
+func (h *UserHandler) GetFriendsOnline(ctx context.Context, client *rpc.Client) (any, error) {
+	friends, err := h.socialService.GetFriendsOnline(ctx, client.UserID)
+	if err != nil {
+		return nil, h.socialError(err, "Failed to get friends online", client.UserID)
+	}
+
+	return GetFriendsOnlineResult{Friends: friends}, nil
+}
+
+// socialError maps social service errors to RPC errors.
+func (h *UserHandler) socialError(err error, message, userID string) *rpc.Error {
+	switch {
+	case errors.Is(err, models.ErrInvalidID):
+		return &rpc.Error{Code: rpc.ErrInvalidParams, Message: err.Error()}
+	case errors.Is(err, models.ErrUserNotFound):
+		return &rpc.Error{Code: rpc.ErrUserNotFound, Message: err.Error()}
+	default:
+		h.logger.Error(message, err, "userID", userID)
+		return &rpc.Error{Code: rpc.ErrInternalError, Message: message}
+	}
+}
+
+// CreateAPIKeyParams represents the parameters for the createApiKey method.
+type CreateAPIKeyParams struct {
+	Name      string               `json:"name" validate:"required,max=50"`
+	Scopes    []models.APIKeyScope `json:"scopes" validate:"required,min=1"`
+	RateLimit int                  `json:"rateLimit,omitempty" validate:"min=0"`
+}
+
+// CreateAPIKey handles creating a personal API key. The key is only ever returned here.
+func (h *UserHandler) CreateAPIKey(ctx context.Context, client *rpc.Client, p *CreateAPIKeyParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	key, err := h.apiKeyService.CreateAPIKey(ctx, client.UserID, p.Name, p.Scopes, p.RateLimit)
+	if err != nil {
+		return nil, h.apiKeyError(err, "Failed to create API key", client.UserID)
+	}
+
+	return key, nil
+}
+
+// ListAPIKeys handles listing the authenticated user's API keys.
+func (h *UserHandler) ListAPIKeys(ctx context.Context, client *rpc.Client) (any, error) {
+	keys, err := h.apiKeyService.ListAPIKeys(ctx, client.UserID)
+	if err != nil {
+		return nil, h.apiKeyError(err, "Failed to list API keys", client.UserID)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKeyParams represents the parameters for the revokeApiKey method.
+type RevokeAPIKeyParams struct {
+	KeyID string `json:"keyId" validate:"required"`
+}
+
+// RevokeAPIKey handles revoking one of the authenticated user's API keys.
+func (h *UserHandler) RevokeAPIKey(ctx context.Context, client *rpc.Client, p *RevokeAPIKeyParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(ctx, client.UserID, p.KeyID); err != nil {
+		return nil, h.apiKeyError(err, "Failed to revoke API key", client.UserID)
+	}
+
+	return map[string]bool{"success": true}, nil
+}
+
+// apiKeyError maps API key service errors to RPC errors.
+func (h *UserHandler) apiKeyError(err error, message, userID string) *rpc.Error {
+	var domainErr *models.DomainError
+	switch {
+	case errors.As(err, &domainErr) && errors.Is(err, models.ErrInvalidInput):
+		return &rpc.Error{Code: rpc.ErrInvalidParams, Message: domainErr.Message}
+	case errors.Is(err, models.ErrInvalidID),
+		errors.Is(err, models.ErrAPIKeyNotFound),
+		errors.Is(err, models.ErrTooManyAPIKeys):
+		return &rpc.Error{Code: rpc.ErrInvalidParams, Message: err.Error()}
+	default:
+		h.logger.Error(message, err, "userID", userID)
+		return &rpc.Error{Code: rpc.ErrInternalError, Message: message}
+	}
+}
+
+// GetScrobbleAuthURL handles getting the page the authenticated user authorizes scrobbling their plays on.
+func (h *UserHandler) GetScrobbleAuthURL(ctx context.Context, client *rpc.Client) (any, error) {
+	authURL, err := h.scrobbler.AuthURL()
+	if err != nil {
+		return nil, h.scrobbleError(err, "Failed to get scrobbling authorization URL", client.UserID)
+	}
+
+	return map[string]string{"url": authURL}, nil
+}
+
+// LinkScrobblerParams represents the parameters for the linkScrobbler method.
+type LinkScrobblerParams struct {
+	Token string `json:"token" validate:"required,max=200"`
+}
+
+// LinkScrobbler handles linking the scrobbling account the authenticated user authorized.
+func (h *UserHandler) LinkScrobbler(ctx context.Context, client *rpc.Client, p *LinkScrobblerParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	account, err := h.scrobbler.Link(ctx, client.UserID, p.Token)
+	if err != nil {
+		return nil, h.scrobbleError(err, "Failed to link scrobbling account", client.UserID)
+	}
+
+	return account, nil
+}
+
+// UnlinkScrobbler handles unlinking the authenticated user's scrobbling account.
+func (h *UserHandler) UnlinkScrobbler(ctx context.Context, client *rpc.Client) (any, error) {
+	if err := h.scrobbler.Unlink(ctx, client.UserID); err != nil {
+		return nil, h.scrobbleError(err, "Failed to unlink scrobbling account", client.UserID)
+	}
+
+	return map[string]bool{"success": true}, nil
+}
+
+// SetScrobblingParams represents the parameters for the setScrobbling method.
+type SetScrobblingParams struct {
+	Enabled bool `json:"enabled"`
+}
+
+// SetScrobbling handles enabling or disabling scrobbling the authenticated user's plays.
+func (h *UserHandler) SetScrobbling(ctx context.Context, client *rpc.Client, p *SetScrobblingParams) (any, error) {
+	account, err := h.scrobbler.SetEnabled(ctx, client.UserID, p.Enabled)
+	if err != nil {
+		return nil, h.scrobbleError(err, "Failed to update scrobbling", client.UserID)
+	}
+
+	return account, nil
+}
+
+// GetScrobblesParams represents the parameters for the getScrobbles method.
+type GetScrobblesParams struct {
+	Skip  int `json:"skip,omitempty" validate:"min=0"`
+	Limit int `json:"limit,omitempty" validate:"min=0,max=100"`
+}
+
+// GetScrobbles handles retrieving the authenticated user's scrobbling account and scrobble log.
+func (h *UserHandler) GetScrobbles(ctx context.Context, client *rpc.Client, p *GetScrobblesParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+	if p.Limit == 0 {
+		p.Limit = 20
+	}
+
+	log, err := h.scrobbler.GetScrobbles(ctx, client.UserID, p.Skip, p.Limit)
+	if err != nil {
+		return nil, h.scrobbleError(err, "Failed to get scrobbles", client.UserID)
+	}
+
+	return log, nil
+}
+
+// scrobbleError maps scrobbling service errors to RPC errors.
+func (h *UserHandler) scrobbleError(err error, message, userID string) *rpc.Error {
+	switch {
+	case errors.Is(err, models.ErrInvalidID),
+		errors.Is(err, models.ErrScrobblingDisabled),
+		errors.Is(err, models.ErrScrobbleAccountNotFound),
+		errors.Is(err, models.ErrInvalidScrobbleAuthToken):
+		return &rpc.Error{Code: rpc.ErrInvalidParams, Message: err.Error()}
+	default:
+		h.logger.Error(message, err, "userID", userID)
+		return &rpc.Error{Code: rpc.ErrInternalError, Message: message}
+	}
+}
+
+// DeleteAccountParams represents the parameters for the deleteAccount method.
+type DeleteAccountParams struct {
+	Password string `json:"password"`
+}
+
+// DeleteAccount handles deleting the authenticated user's account. The account is purged once the grace period
+// ends, unless the user signs in again before then, and the client is signed out.
+func (h *UserHandler) DeleteAccount(ctx context.Context, client *rpc.Client, p *DeleteAccountParams) (any, error) {
+	deletion, err := h.deletion.RequestDeletion(ctx, client.UserID, p.Password)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCredentials) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrNotAuthorized,
+				Message: "Password is incorrect",
+			}
+		}
+		h.logger.Error("Failed to delete account", err, "userID", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to delete account",
+		}
+	}
+
+	// The account's sessions are gone, so is this client's
+	client.UserID = ""
+	client.Username = ""
+
+	return deletion, nil
+}
+
+// ExportData handles creating a link downloading the authenticated user's data archive.
+func (h *UserHandler) ExportData(ctx context.Context, client *rpc.Client) (any, error) {
+	link, err := h.dataExporter.CreateLink(ctx, client.UserID)
+	if err != nil {
+		h.logger.Error("Failed to create data export link", err, "userID", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to create data export link",
+		}
+	}
+
+	return link, nil
+}
@@ -0,0 +1,174 @@
+// Package methods contains RPC method handlers for the application.
+package methods
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/rpc"
+	"norelock.dev/listenify/backend/internal/services/room"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// defaultVibeMatches is the number of vibe matches suggested when no limit is given.
+const defaultVibeMatches = 10
+
+// VibeHandler handles RPC methods for media energy tagging and room vibe matching.
+type VibeHandler struct {
+	vibeService *room.VibeService
+	logger      *utils.Logger
+}
+
+// NewVibeHandler creates a new VibeHandler.
+func NewVibeHandler(vibeService *room.VibeService, logger *utils.Logger) *VibeHandler {
+	return &VibeHandler{
+		vibeService: vibeService,
+		logger:      logger,
+	}
+}
+
+// RegisterMethods registers vibe-related RPC methods with the router.
+func (h *VibeHandler) RegisterMethods(hr rpc.HandlerRegistry) {
+	auth := hr.Wrap(rpc.AuthMiddleware)
+	rpc.Register(auth, "media.tag", h.TagMedia)
+	rpc.Register(hr, "discover.getRoomVibe", h.GetRoomVibe)
+	rpc.Register(auth, "discover.matchVibe", h.MatchVibe)
+}
+
+// TagMediaParams represents the parameters for the tag method.
+type TagMediaParams struct {
+	MediaID string `json:"mediaId" validate:"required"`
+	models.MediaTagRequest
+}
+
+// TagMedia handles tagging the energy and mood of a media item, replacing the user's previous tag.
+func (h *VibeHandler) TagMedia(ctx context.Context, client *rpc.Client, p *TagMediaParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+
+	mediaID, err := bson.ObjectIDFromHex(p.MediaID)
+	if err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid media ID",
+		}
+	}
+
+	userID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid user ID",
+		}
+	}
+
+	vibe, err := h.vibeService.TagMedia(ctx, mediaID, userID, &p.MediaTagRequest)
+	if err != nil {
+		if errors.Is(err, models.ErrMediaNotFound) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrInvalidParams,
+				Message: "Media not found",
+			}
+		}
+		h.logger.Error("Failed to tag media", err, "mediaId", p.MediaID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to tag media",
+		}
+	}
+
+	return vibe, nil
+}
+
+// GetRoomVibe handles getting the vibe of a room from its recent plays.
+func (h *VibeHandler) GetRoomVibe(ctx context.Context, client *rpc.Client, p *RoomIDParam) (any, error) {
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid room ID",
+		}
+	}
+
+	vibe, err := h.vibeService.GetRoomVibe(ctx, roomID)
+	if err != nil {
+		h.logger.Error("Failed to get room vibe", err, "roomId", p.RoomID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to get room vibe",
+		}
+	}
+
+	return vibe, nil
+}
+
+// MatchVibeParams represents the parameters for the matchVibe method.
+type MatchVibeParams struct {
+	RoomID string `json:"roomId" validate:"required"`
+	Limit  int    `json:"limit" validate:"min=0,max=50"`
+}
+
+// MatchVibeResult represents the result of the matchVibe method.
+type MatchVibeResult struct {
+	Vibe    *models.RoomVibe    `json:"vibe"`
+	Matches []*models.VibeMatch `json:"matches"`
+}
+
+// MatchVibe handles suggesting the items of the user's active playlist that best match a room's vibe.
+func (h *VibeHandler) MatchVibe(ctx context.Context, client *rpc.Client, p *MatchVibeParams) (any, error) {
+	// Validate parameters
+	if err := utils.Validate(p); err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid parameters",
+			Data:    err.Error(),
+		}
+	}
+	if p.Limit == 0 {
+		p.Limit = defaultVibeMatches
+	}
+
+	roomID, err := bson.ObjectIDFromHex(p.RoomID)
+	if err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid room ID",
+		}
+	}
+
+	userID, err := bson.ObjectIDFromHex(client.UserID)
+	if err != nil {
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInvalidParams,
+			Message: "Invalid user ID",
+		}
+	}
+
+	vibe, matches, err := h.vibeService.MatchVibe(ctx, roomID, userID, p.Limit)
+	if err != nil {
+		if errors.Is(err, models.ErrNoActivePlaylist) {
+			return nil, &rpc.Error{
+				Code:    rpc.ErrInvalidParams,
+				Message: err.Error(),
+			}
+		}
+		h.logger.Error("Failed to match room vibe", err, "roomId", p.RoomID, "userId", client.UserID)
+		return nil, &rpc.Error{
+			Code:    rpc.ErrInternalError,
+			Message: "Failed to match room vibe",
+		}
+	}
+
+	return MatchVibeResult{
+		Vibe:    vibe,
+		Matches: matches,
+	}, nil
+}
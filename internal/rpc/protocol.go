@@ -0,0 +1,167 @@
+// Package rpc provides WebSocket-based RPC functionality.
+package rpc
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ProtocolVersion is the current version of the WebSocket RPC protocol.
+	ProtocolVersion = 2
+
+	// MinProtocolVersion is the oldest protocol version the server still accepts.
+	MinProtocolVersion = 1
+)
+
+// Capability is an optional protocol feature a client can announce on connect.
+type Capability string
+
+const (
+	// CapDeltaState indicates the client can apply partial room state updates.
+	CapDeltaState Capability = "delta-state"
+
+	// CapMsgPack indicates the client can decode MessagePack-encoded frames.
+	CapMsgPack Capability = "msgpack"
+
+	// CapBatch indicates the client can send and receive JSON-RPC batch requests.
+	CapBatch Capability = "batch"
+
+	// CapResume indicates the client can resume a previous session after reconnecting.
+	CapResume Capability = "resume"
+
+	// CapProgressiveJoin indicates the client can render a room from a core join payload
+	// and receive the rest of the room in chunks afterwards.
+	CapProgressiveJoin Capability = "progressive-join"
+)
+
+// Transport is the way a client's messages travel between it and the server.
+type Transport string
+
+const (
+	// TransportWebSocket carries requests and notifications over a WebSocket connection.
+	TransportWebSocket Transport = "websocket"
+
+	// TransportSSE carries notifications over Server-Sent Events and requests over HTTP POST,
+	// for clients behind proxies that block WebSockets.
+	TransportSSE Transport = "sse"
+)
+
+// serverCapabilities lists the capabilities the server is able to honor.
+var serverCapabilities = map[Capability]bool{
+	CapBatch:           true,
+	CapResume:          true,
+	CapProgressiveJoin: true,
+}
+
+// Handshake contains the protocol information announced by a client on connect.
+type Handshake struct {
+	// Version is the protocol version requested by the client.
+	Version int
+
+	// Capabilities is the list of capabilities announced by the client.
+	Capabilities []Capability
+
+	// Transport is the transport the client connected over.
+	Transport Transport
+
+	// Instance identifies the client instance, such as a browser tab, across reconnects.
+	// A new connection from an instance replaces the one the server still holds for it.
+	Instance string
+
+	// ResumeToken is the token of the session the client resumes, given to it on its previous connection.
+	ResumeToken string
+}
+
+// NegotiatedProtocol is the protocol configuration selected for a connection.
+type NegotiatedProtocol struct {
+	// Version is the protocol version used for the connection.
+	Version int `json:"version"`
+
+	// ServerVersion is the latest protocol version supported by the server.
+	ServerVersion int `json:"serverVersion"`
+
+	// MinVersion is the oldest protocol version accepted by the server.
+	MinVersion int `json:"minVersion"`
+
+	// Capabilities is the list of capabilities enabled for the connection.
+	Capabilities []Capability `json:"capabilities"`
+
+	// Transport is the transport used for the connection.
+	Transport Transport `json:"transport"`
+}
+
+// ParseHandshake reads the protocol version and capabilities from the connection request.
+// Clients that do not announce a version are treated as speaking the minimum supported version.
+func ParseHandshake(r *http.Request) (*Handshake, error) {
+	query := r.URL.Query()
+
+	handshake := &Handshake{
+		Version:   MinProtocolVersion,
+		Transport: TransportWebSocket,
+	}
+
+	if raw := query.Get("protocol"); raw != "" {
+		version, err := strconv.Atoi(raw)
+		if err != nil || version < 0 {
+			return nil, fmt.Errorf("invalid protocol version %q", raw)
+		}
+		handshake.Version = version
+	}
+
+	handshake.Instance = query.Get("instance")
+	handshake.ResumeToken = query.Get("resumeToken")
+
+	if raw := query.Get("capabilities"); raw != "" {
+		for name := range strings.SplitSeq(raw, ",") {
+			name = strings.TrimSpace(strings.ToLower(name))
+			if name == "" {
+				continue
+			}
+			capability := Capability(name)
+			if !slices.Contains(handshake.Capabilities, capability) {
+				handshake.Capabilities = append(handshake.Capabilities, capability)
+			}
+		}
+	}
+
+	return handshake, nil
+}
+
+// Negotiate selects the protocol version and capabilities to use for a connection.
+// It returns an error describing the required upgrade if the client is too old.
+func Negotiate(handshake *Handshake) (*NegotiatedProtocol, error) {
+	if handshake.Version < MinProtocolVersion {
+		return nil, fmt.Errorf(
+			"protocol version %d is no longer supported; upgrade the client to protocol version %d or newer",
+			handshake.Version, MinProtocolVersion,
+		)
+	}
+
+	negotiated := &NegotiatedProtocol{
+		Version:       min(handshake.Version, ProtocolVersion),
+		ServerVersion: ProtocolVersion,
+		MinVersion:    MinProtocolVersion,
+		Capabilities:  make([]Capability, 0, len(handshake.Capabilities)),
+		Transport:     handshake.Transport,
+	}
+
+	for _, capability := range handshake.Capabilities {
+		if serverCapabilities[capability] {
+			negotiated.Capabilities = append(negotiated.Capabilities, capability)
+		}
+	}
+
+	return negotiated, nil
+}
+
+// Has checks if a capability was enabled for the connection.
+func (p *NegotiatedProtocol) Has(capability Capability) bool {
+	if p == nil {
+		return false
+	}
+	return slices.Contains(p.Capabilities, capability)
+}
@@ -0,0 +1,294 @@
+// Package rpc provides WebSocket-based RPC functionality.
+package rpc
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	r "github.com/go-redis/redis/v8"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// BootstrapPath is the path clients ask which regional endpoints they can connect to before connecting.
+	BootstrapPath = "/bootstrap"
+
+	// defaultRegion is the region of nodes deployed without one.
+	defaultRegion = "default"
+
+	// rttAllCountries is the field of a node's round-trip times averaging its clients from every country.
+	rttAllCountries = "*"
+
+	// RTTScopeCountry marks round-trip time hints measured on clients from the asking client's country.
+	RTTScopeCountry = "country"
+
+	// RTTScopeRegion marks round-trip time hints measured on every client of a region, for lack of
+	// clients from the asking client's country.
+	RTTScopeRegion = "region"
+)
+
+// nodeRegion is where a node is deployed, as stored in its region registry.
+type nodeRegion struct {
+	Region   string `json:"region"`
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// RegionInfo describes a deployment region and how close it is to a client.
+type RegionInfo struct {
+	// Region is the name of the region.
+	Region string `json:"region"`
+
+	// Endpoint is the WebSocket URL clients connect to the region at, empty when it isn't advertised.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Nodes is the number of nodes serving the region.
+	Nodes int `json:"nodes"`
+
+	// Connections is the number of clients connected to the region.
+	Connections int `json:"connections"`
+
+	// RTTHint is the average round-trip time in milliseconds measured on the region's clients, zero when
+	// none was measured yet.
+	RTTHint float64 `json:"rttHint,omitempty"`
+
+	// RTTScope is whether the hint was measured on clients from the asking client's country or on every
+	// client of the region.
+	RTTScope string `json:"rttScope,omitempty"`
+
+	// Current marks the region of the node that answered.
+	Current bool `json:"current,omitempty"`
+}
+
+// Bootstrap tells a client which regional endpoints it can connect to and which looks nearest.
+type Bootstrap struct {
+	// Region is the region of the node that answered.
+	Region string `json:"region"`
+
+	// NodeID identifies the node that answered.
+	NodeID string `json:"nodeId"`
+
+	// Country is the country resolved for the client, empty if unknown.
+	Country string `json:"country,omitempty"`
+
+	// Recommended is the region the client should connect to.
+	Recommended string `json:"recommended"`
+
+	// Regions are the regions with live nodes, the nearest first.
+	Regions []RegionInfo `json:"regions"`
+}
+
+// Region returns the deployment region of this node.
+func (c *Cluster) Region() string {
+	return c.policy.Region
+}
+
+// recordRegion adds this node's region and the round-trip times of its clients by country to a
+// heartbeat, expiring along with its connection registry.
+func (c *Cluster) recordRegion(ctx context.Context, pipe r.Pipeliner, ttl time.Duration) {
+	region, _ := json.Marshal(nodeRegion{Region: c.policy.Region, Endpoint: c.policy.Endpoint})
+	pipe.Set(ctx, formatNodeRegionKey(c.policy.NodeID), region, ttl)
+
+	key := formatNodeRTTKey(c.policy.NodeID)
+	pipe.Del(ctx, key)
+	if rtts := c.server.rttByCountry(); len(rtts) > 0 {
+		pipe.HSet(ctx, key, rtts)
+		pipe.Expire(ctx, key, ttl)
+	}
+}
+
+// recordUserRegions records the users connected to this node as present in its region, so every
+// region knows where a user is connected.
+func (c *Cluster) recordUserRegions(ctx context.Context, connections map[string]any) {
+	users := make([]string, 0, len(connections))
+	seen := make(map[string]bool, len(connections))
+	for _, userID := range connections {
+		if id, ok := userID.(string); ok && !seen[id] {
+			seen[id] = true
+			users = append(users, id)
+		}
+	}
+
+	if err := c.server.presenceMgr.RecordUserRegion(ctx, c.policy.Region, users); err != nil {
+		c.logger.Warn("Failed to record user regions", "region", c.policy.Region, "error", err)
+	}
+}
+
+// Regions lists the regions with live nodes, the nearest to clients from a country first. Regions are
+// ranked by the round-trip times measured on their clients from that country, and regions without such
+// clients come after them.
+func (c *Cluster) Regions(ctx context.Context, country string) ([]RegionInfo, error) {
+	nodes, err := c.Nodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := c.redisClient.Pipeline()
+	rtts := make([]*r.SliceCmd, len(nodes))
+	for i, node := range nodes {
+		rtts[i] = pipe.HMGet(ctx, formatNodeRTTKey(node.ID), country, rttAllCountries)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != r.Nil {
+		return nil, err
+	}
+
+	type rttSum struct {
+		country, all    float64
+		countries, alls int
+	}
+	regions := make(map[string]*RegionInfo)
+	sums := make(map[string]*rttSum)
+	for i, node := range nodes {
+		region := regions[node.Region]
+		if region == nil {
+			region = &RegionInfo{Region: node.Region, Current: node.Region == c.policy.Region}
+			regions[node.Region] = region
+			sums[node.Region] = &rttSum{}
+		}
+		region.Nodes++
+		region.Connections += node.Connections
+		if region.Endpoint == "" {
+			region.Endpoint = node.Endpoint
+		}
+
+		values := rtts[i].Val()
+		sum := sums[node.Region]
+		if ms, ok := parseRTT(values, 0); ok && country != "" {
+			sum.country += ms
+			sum.countries++
+		}
+		if ms, ok := parseRTT(values, 1); ok {
+			sum.all += ms
+			sum.alls++
+		}
+	}
+
+	infos := make([]RegionInfo, 0, len(regions))
+	for name, region := range regions {
+		sum := sums[name]
+		switch {
+		case sum.countries > 0:
+			region.RTTHint = sum.country / float64(sum.countries)
+			region.RTTScope = RTTScopeCountry
+		case sum.alls > 0:
+			region.RTTHint = sum.all / float64(sum.alls)
+			region.RTTScope = RTTScopeRegion
+		}
+		infos = append(infos, *region)
+	}
+
+	slices.SortFunc(infos, func(a, b RegionInfo) int {
+		if c := cmp.Compare(rttRank(a), rttRank(b)); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.RTTHint, b.RTTHint); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Region, b.Region)
+	})
+	return infos, nil
+}
+
+// rttRank ranks regions by how much their round-trip time hint says about the asking client.
+func rttRank(region RegionInfo) int {
+	switch region.RTTScope {
+	case RTTScopeCountry:
+		return 0
+	case RTTScopeRegion:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// parseRTT reads a round-trip time in milliseconds from the values of a node's round-trip times.
+func parseRTT(values []any, index int) (float64, bool) {
+	if index >= len(values) {
+		return 0, false
+	}
+	value, ok := values[index].(string)
+	if !ok {
+		return 0, false
+	}
+	ms, err := strconv.ParseFloat(value, 64)
+	return ms, err == nil && ms > 0
+}
+
+// rttByCountry averages the round-trip times of the WebSocket clients connected to this server by
+// country, in milliseconds, along with their average over every country.
+func (s *Server) rttByCountry() map[string]any {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sums := make(map[string]time.Duration)
+	counts := make(map[string]int)
+	for client := range s.clients {
+		rtt := client.RTT()
+		if rtt <= 0 {
+			continue
+		}
+		sums[rttAllCountries] += rtt
+		counts[rttAllCountries]++
+		if client.country != "" {
+			sums[client.country] += rtt
+			counts[client.country]++
+		}
+	}
+
+	rtts := make(map[string]any, len(sums))
+	for country, sum := range sums {
+		average := sum / time.Duration(counts[country])
+		rtts[country] = strconv.FormatFloat(float64(average.Microseconds())/1000, 'f', 1, 64)
+	}
+	return rtts
+}
+
+// HandleBootstrap tells a client which regional endpoints it can connect to, with round-trip time hints
+// measured on the clients already connected from its country, so it can connect to the nearest node.
+// Rooms are shared through Redis, so they stay the same whichever region a client connects to.
+func (s *Server) HandleBootstrap(w http.ResponseWriter, r *http.Request) {
+	if s.cluster == nil {
+		utils.RespondWithError(w, http.StatusServiceUnavailable, "Regions are unavailable")
+		return
+	}
+
+	var country string
+	if s.geoLocator != nil {
+		country = s.geoLocator.LookupCountry(utils.GetRequestIP(r))
+	}
+
+	regions, err := s.cluster.Regions(r.Context(), country)
+	if err != nil {
+		s.logger.Error("Failed to list regions", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to list regions")
+		return
+	}
+
+	bootstrap := Bootstrap{
+		Region:      s.cluster.Region(),
+		NodeID:      s.cluster.NodeID(),
+		Country:     country,
+		Recommended: s.cluster.Region(),
+		Regions:     regions,
+	}
+	if len(regions) > 0 && regions[0].RTTScope == RTTScopeCountry && regions[0].Endpoint != "" {
+		bootstrap.Recommended = regions[0].Region
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, bootstrap)
+}
+
+// formatNodeRegionKey formats the key of a node's region registry.
+func formatNodeRegionKey(nodeID string) string {
+	return fmt.Sprintf("%s:node:%s:region", clusterKeyPrefix, nodeID)
+}
+
+// formatNodeRTTKey formats the key of the round-trip times of a node's clients, in milliseconds by country.
+func formatNodeRTTKey(nodeID string) string {
+	return fmt.Sprintf("%s:node:%s:rtt", clusterKeyPrefix, nodeID)
+}
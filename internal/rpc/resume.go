@@ -0,0 +1,578 @@
+// Package rpc provides WebSocket-based RPC functionality.
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	r "github.com/go-redis/redis/v8"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// resumeKeyPrefix prefixes the keys of the sessions kept for disconnected clients.
+	resumeKeyPrefix = "rpc_resume"
+
+	// resumeTimeout is how long reading or writing a kept session in Redis may take.
+	resumeTimeout = 5 * time.Second
+)
+
+// ResumePolicy controls how disconnected clients can resume their session.
+type ResumePolicy struct {
+	// Window is how long after disconnecting a client can resume its session. Zero disables resuming.
+	Window time.Duration
+
+	// Buffer is the number of room notifications kept for a disconnected client. Past it the oldest are
+	// dropped, and the client is told it missed some.
+	Buffer int
+}
+
+// RoomAccess tells whether users may still follow the rooms of the sessions they resume.
+type RoomAccess interface {
+	CanFollowRoom(ctx context.Context, roomID, userID string) (bool, error)
+}
+
+// SessionInfo is sent to clients that negotiated the resume capability once they connect.
+type SessionInfo struct {
+	// ResumeToken is the token to resume the session with after reconnecting.
+	ResumeToken string `json:"resumeToken"`
+
+	// ResumeWindow is how many seconds after disconnecting the session can be resumed.
+	ResumeWindow int `json:"resumeWindow"`
+
+	// Resumed tells whether the connection resumed the session of the token it connected with.
+	// When it didn't, the client has to join its rooms again.
+	Resumed bool `json:"resumed"`
+
+	// Rooms are the rooms the resumed session follows again.
+	Rooms []string `json:"rooms,omitempty"`
+
+	// Replayed is the number of room notifications missed while disconnected, sent right after this one.
+	Replayed int `json:"replayed,omitempty"`
+
+	// Truncated tells that more notifications were missed than were kept, so the client should fetch
+	// the state of its rooms again.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// resumeState is what a disconnected client followed, kept in Redis until it resumes or its window ends.
+type resumeState struct {
+	UserID       string              `json:"userId"`
+	Rooms        []string            `json:"rooms"`
+	Topics       map[string][]Topic  `json:"topics,omitempty"`
+	ChatShards   map[string]int      `json:"chatShards,omitempty"`
+	ChatChannels map[string][]string `json:"chatChannels,omitempty"`
+}
+
+// recording is the session of a client that disconnected from this node. A stand-in client follows its
+// rooms in the hub and records their notifications in Redis, until the client resumes or its window ends.
+type recording struct {
+	token     string
+	standIn   *Client
+	deadline  time.Time
+	truncated bool
+	handoff   chan *Client
+	done      chan struct{}
+
+	// resumed is set once the session was handed over, read after done is closed
+	resumed bool
+}
+
+// Resumer keeps the sessions of disconnected clients, so they can reconnect to any node within a short
+// window and have their rooms, topics and chat shards restored and the room notifications they missed
+// replayed, without joining their rooms again. A client resuming on the node it disconnected from gets
+// every notification exactly once. Moving to another node, notifications sent while it moves may be missed.
+type Resumer struct {
+	server      *Server
+	redisClient *redis.Client
+	rooms       RoomAccess
+	policy      ResumePolicy
+	logger      *utils.Logger
+
+	// recordings are the sessions recorded on this node, by resume token
+	recordings map[string]*recording
+	mutex      sync.Mutex
+}
+
+// NewResumer creates the keeper of disconnected clients' sessions for a server and attaches it to the server.
+// Resumed sessions follow again only the rooms their user may still follow.
+func NewResumer(server *Server, redisClient *redis.Client, rooms RoomAccess, policy ResumePolicy, logger *utils.Logger) *Resumer {
+	resumer := &Resumer{
+		server:      server,
+		redisClient: redisClient,
+		rooms:       rooms,
+		policy:      policy,
+		logger:      logger.Named("rpc_resumer"),
+		recordings:  make(map[string]*recording),
+	}
+	server.resumer = resumer
+	return resumer
+}
+
+// negotiateResume withdraws the resume capability from a connection when sessions can't be resumed.
+func (s *Server) negotiateResume(protocol *NegotiatedProtocol) {
+	if s.resumer == nil || s.resumer.policy.Window <= 0 {
+		protocol.Capabilities = slices.DeleteFunc(protocol.Capabilities, func(capability Capability) bool {
+			return capability == CapResume
+		})
+	}
+}
+
+// attach gives a client that negotiated the resume capability its resume token, resuming the session of
+// the token it connected with first. The session notification, and the notifications replayed, are queued
+// before any live notification of the rooms resumed.
+func (s *Server) attach(client *Client, handshake *Handshake) {
+	if !client.HasCapability(CapResume) {
+		return
+	}
+
+	token, err := utils.GenerateID("resume")
+	if err != nil {
+		s.logger.Error("Failed to generate resume token", err, "clientID", client.ID)
+		return
+	}
+	client.resumeToken = token
+
+	info := &SessionInfo{
+		ResumeToken:  token,
+		ResumeWindow: int(s.resumer.policy.Window.Seconds()),
+	}
+	if handshake.ResumeToken == "" || !s.resumer.resume(client, handshake.ResumeToken, info) {
+		client.SendNotification(EventConnectionSession, info)
+	}
+}
+
+// detach keeps the session of a disconnecting client for it to resume, unless it was disconnected
+// for a reason it shouldn't come back from, such as being kicked.
+func (s *Server) detach(client *Client) {
+	if s.resumer == nil || client.resumeToken == "" {
+		return
+	}
+	if reason := client.closeReason.Load(); reason != nil && !reason.resumable() {
+		return
+	}
+	s.resumer.record(client)
+}
+
+// resumable checks whether a client disconnected for this reason can resume its session.
+func (r CloseReason) resumable() bool {
+	return (r.Reconnect == ReconnectNow || r.Reconnect == ReconnectBackoff) && r.Code != CloseKicked
+}
+
+// record keeps the session of a disconnecting client, and starts recording the notifications of its rooms.
+// Clients following no room have nothing to resume.
+func (rs *Resumer) record(client *Client) {
+	standIn := &Client{
+		ID:           client.ID,
+		UserID:       client.UserID,
+		Username:     client.Username,
+		server:       rs.server,
+		send:         make(chan []byte, 256),
+		rooms:        make(map[string]bool),
+		topics:       make(map[string][]Topic),
+		chatShards:   make(map[string]int),
+		chatChannels: make(map[string][]string),
+		protocol:     client.protocol,
+		logger:       client.logger,
+	}
+	standIn.copySubscriptions(client)
+
+	// The stand-in takes the client's place in its rooms at once, so no notification falls in between
+	rooms := rs.server.hub.handOver(client, standIn, false)
+	if len(rooms) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rs.policy.Window)
+	standIn.cancel = cancel
+	rec := &recording{
+		token:    client.resumeToken,
+		standIn:  standIn,
+		deadline: time.Now().Add(rs.policy.Window),
+		handoff:  make(chan *Client),
+		done:     make(chan struct{}),
+	}
+
+	topics, chatShards, chatChannels := standIn.subscriptions()
+	state := resumeState{UserID: client.UserID, Rooms: rooms, Topics: topics, ChatShards: chatShards, ChatChannels: chatChannels}
+	storeCtx, storeCancel := context.WithTimeout(ctx, resumeTimeout)
+	defer storeCancel()
+	if err := rs.redisClient.SetObject(storeCtx, formatResumeStateKey(rec.token), state, rs.policy.Window); err != nil {
+		rs.logger.Error("Failed to keep session for resuming", err, "clientID", client.ID, "userID", client.UserID)
+		rs.release(rec)
+		cancel()
+		return
+	}
+
+	rs.mutex.Lock()
+	rs.recordings[rec.token] = rec
+	rs.mutex.Unlock()
+
+	go rs.run(ctx, rec)
+	rs.logger.Debug("Kept session for resuming", "clientID", client.ID, "userID", client.UserID, "rooms", len(rooms))
+}
+
+// run records the notifications of a recorded session's rooms until its client resumes on this node,
+// resumes on another node, or its window ends. Stand-ins disconnected before, such as when the user is
+// kicked from one of the rooms, discard the session.
+func (rs *Resumer) run(ctx context.Context, rec *recording) {
+	defer close(rec.done)
+
+	for {
+		select {
+		case message := <-rec.standIn.send:
+			if !rs.append(ctx, rec, message) {
+				rs.forget(rec)
+				rs.release(rec)
+				return
+			}
+
+		case client := <-rec.handoff:
+			rs.handOff(rec, client)
+			return
+
+		case <-ctx.Done():
+			rs.forget(rec)
+			rs.release(rec)
+			if errors.Is(ctx.Err(), context.Canceled) {
+				rs.discard(rec.token)
+			}
+			return
+		}
+	}
+}
+
+// append records a notification of a session's rooms. It returns false once the session can't be resumed
+// from this node anymore, because it was resumed on another node or can't be recorded.
+func (rs *Resumer) append(ctx context.Context, rec *recording, message []byte) bool {
+	writeCtx, cancel := context.WithTimeout(ctx, resumeTimeout)
+	defer cancel()
+
+	eventsKey := formatResumeEventsKey(rec.token)
+	pipe := rs.redisClient.TxPipeline()
+	exists := pipe.Exists(writeCtx, formatResumeStateKey(rec.token))
+	length := pipe.RPush(writeCtx, eventsKey, message)
+	pipe.LTrim(writeCtx, eventsKey, int64(-rs.policy.Buffer), -1)
+	pipe.PExpireAt(writeCtx, eventsKey, rec.deadline)
+	if _, err := pipe.Exec(writeCtx); err != nil {
+		rs.logger.Error("Failed to record notification for resuming", err, "clientID", rec.standIn.ID)
+		rs.discard(rec.token)
+		return false
+	}
+
+	if exists.Val() == 0 {
+		// Resumed on another node, which already took the notifications recorded
+		rs.redisClient.Del(writeCtx, eventsKey)
+		return false
+	}
+
+	if length.Val() > int64(rs.policy.Buffer) && !rec.truncated {
+		rec.truncated = true
+		if err := rs.redisClient.Set(writeCtx, formatResumeTruncatedKey(rec.token), "1", time.Until(rec.deadline)); err != nil {
+			rs.logger.Error("Failed to mark session as truncated", err, "clientID", rec.standIn.ID)
+		}
+	}
+	return true
+}
+
+// resume restores the session of a resume token on a client connecting with it, if the session belongs to
+// the client's user and is still kept. It returns false if there was no session to resume.
+func (rs *Resumer) resume(client *Client, token string, info *SessionInfo) bool {
+	// A connection of the session still open on this node, not yet noticed to be gone, hands it over directly
+	if rs.takeOver(client, token, info) {
+		return true
+	}
+
+	// The session was recorded on this node, so its stand-in hands over exactly what the client missed
+	rs.mutex.Lock()
+	rec := rs.recordings[token]
+	if rec != nil && rec.standIn.UserID == client.UserID {
+		delete(rs.recordings, token)
+	} else {
+		rec = nil
+	}
+	rs.mutex.Unlock()
+	if rec != nil {
+		select {
+		case rec.handoff <- client:
+			<-rec.done
+			return rec.resumed
+		case <-rec.done:
+			// It stopped recording meanwhile, what it recorded is in Redis if the session is still kept
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resumeTimeout)
+	defer cancel()
+
+	// The session was recorded on another node, or this node restarted since
+	state, ok := rs.claim(ctx, client, token)
+	if !ok {
+		return false
+	}
+
+	info.Resumed = true
+	info.Rooms = rs.followable(ctx, client, state.Rooms)
+	events, truncated := rs.collect(ctx, token)
+	rs.replay(client, info, events, truncated)
+
+	for _, roomID := range info.Rooms {
+		if topics, ok := state.Topics[roomID]; ok {
+			client.SetRoomTopics(roomID, topics)
+		}
+		if shard, ok := state.ChatShards[roomID]; ok {
+			client.SetChatShard(roomID, shard)
+		}
+		if channels, ok := state.ChatChannels[roomID]; ok {
+			client.SetChatChannels(roomID, channels)
+		}
+		rs.server.AddClientToRoom(client, roomID)
+	}
+
+	rs.logger.Info("Client resumed session", "clientID", client.ID, "userID", client.UserID, "rooms", len(info.Rooms), "replayed", info.Replayed)
+	return true
+}
+
+// claim takes a kept session for a client resuming it, so the session is resumed only once. It returns
+// false if the session isn't kept anymore or belongs to another user.
+func (rs *Resumer) claim(ctx context.Context, client *Client, token string) (*resumeState, bool) {
+	data, err := rs.redisClient.Client().GetDel(ctx, formatResumeStateKey(token)).Result()
+	if err != nil {
+		if !errors.Is(err, r.Nil) {
+			rs.logger.Error("Failed to claim session", err, "clientID", client.ID)
+		}
+		return nil, false
+	}
+
+	var state resumeState
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		rs.logger.Warn("Ignoring malformed session", "clientID", client.ID, "error", err)
+		return nil, false
+	}
+	if state.UserID != client.UserID {
+		rs.logger.Warn("Refused to resume another user's session", "clientID", client.ID, "userID", client.UserID)
+		return nil, false
+	}
+	return &state, true
+}
+
+// takeOver moves a session from a connection still open on this node to a client resuming it, closing the
+// older connection. It returns false if no open connection holds the session.
+func (rs *Resumer) takeOver(client *Client, token string, info *SessionInfo) bool {
+	rs.server.mutex.Lock()
+	var previous *Client
+	for other := range rs.server.clients {
+		if other != client && other.UserID == client.UserID && other.resumeToken == token {
+			previous = other
+			break
+		}
+	}
+	rs.server.mutex.Unlock()
+	if previous == nil {
+		return false
+	}
+
+	client.copySubscriptions(previous)
+	info.Resumed = true
+	info.Rooms = rs.server.hub.handOver(previous, client, false)
+	client.SendNotification(EventConnectionSession, info)
+	previous.Disconnect(NewCloseReason(CloseDuplicateSession, "Resumed by a newer connection"))
+
+	rs.logger.Info("Client took over session", "clientID", client.ID, "previousClientID", previous.ID, "rooms", len(info.Rooms))
+	return true
+}
+
+// handOff hands a recorded session over to the client resuming it: the notifications recorded go out
+// first, then the ones the stand-in has yet to record, then the live ones. Sessions resumed on another
+// node meanwhile are released instead.
+func (rs *Resumer) handOff(rec *recording, client *Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), resumeTimeout)
+	defer cancel()
+	defer rec.standIn.cancel()
+
+	if _, ok := rs.claim(ctx, client, rec.token); !ok {
+		rs.release(rec)
+		return
+	}
+	rec.resumed = true
+
+	// The stand-in leaves the rooms the user may no longer follow, so only the others are handed over
+	rooms := rec.standIn.GetRooms()
+	followable := rs.followable(ctx, client, rooms)
+	for _, roomID := range rooms {
+		if !slices.Contains(followable, roomID) {
+			rs.leave(rec.standIn, roomID)
+		}
+	}
+
+	client.copySubscriptions(rec.standIn)
+	events, truncated := rs.collect(ctx, rec.token)
+
+	info := &SessionInfo{
+		ResumeToken:  client.resumeToken,
+		ResumeWindow: int(rs.policy.Window.Seconds()),
+		Resumed:      true,
+		Rooms:        followable,
+	}
+	rs.replay(client, info, events, truncated || rec.truncated)
+	rs.server.hub.handOver(rec.standIn, client, true)
+
+	rs.logger.Info("Client resumed session", "clientID", client.ID, "userID", client.UserID, "rooms", len(info.Rooms), "replayed", info.Replayed)
+}
+
+// collect takes the notifications recorded for a session, and whether some were dropped.
+func (rs *Resumer) collect(ctx context.Context, token string) ([][]byte, bool) {
+	eventsKey := formatResumeEventsKey(token)
+	truncatedKey := formatResumeTruncatedKey(token)
+
+	pipe := rs.redisClient.TxPipeline()
+	recorded := pipe.LRange(ctx, eventsKey, 0, -1)
+	truncated := pipe.Exists(ctx, truncatedKey)
+	pipe.Del(ctx, eventsKey, truncatedKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		rs.logger.Error("Failed to collect notifications for resuming", err, "token", token)
+		return nil, true
+	}
+
+	events := make([][]byte, len(recorded.Val()))
+	for i, event := range recorded.Val() {
+		events[i] = []byte(event)
+	}
+	return events, truncated.Val() > 0
+}
+
+// replay queues the session notification for a resuming client, then the notifications it missed.
+// Only as many are replayed as leave the client's queue room for the live ones, the oldest are dropped.
+func (rs *Resumer) replay(client *Client, info *SessionInfo, events [][]byte, truncated bool) {
+	if excess := len(events) - cap(client.send)/2; excess > 0 {
+		events = events[excess:]
+		truncated = true
+	}
+	info.Replayed = len(events)
+	info.Truncated = truncated
+
+	client.SendNotification(EventConnectionSession, info)
+	for _, event := range events {
+		select {
+		case client.send <- event:
+		default:
+			client.Disconnect(slowConsumer)
+			return
+		}
+	}
+}
+
+// followable keeps the rooms of a session its client's user may still follow: rooms the user wasn't removed
+// or banned from, and that didn't turn private meanwhile. The user joins the others again to follow them.
+func (rs *Resumer) followable(ctx context.Context, client *Client, rooms []string) []string {
+	followable := make([]string, 0, len(rooms))
+	for _, roomID := range rooms {
+		allowed, err := rs.rooms.CanFollowRoom(ctx, roomID, client.UserID)
+		if err != nil {
+			rs.logger.Error("Failed to check room of resumed session", err, "clientID", client.ID, "roomID", roomID)
+			continue
+		}
+		if !allowed {
+			rs.logger.Debug("Dropped room from resumed session", "clientID", client.ID, "userID", client.UserID, "roomID", roomID)
+			continue
+		}
+		followable = append(followable, roomID)
+	}
+	return followable
+}
+
+// leave takes a client out of a room at once, forgetting what it followed in the room.
+func (rs *Resumer) leave(client *Client, roomID string) {
+	client.SetRoomTopics(roomID, nil)
+	client.SetChatShard(roomID, 0)
+	client.SetChatChannels(roomID, nil)
+	rs.server.hub.removeClientFromRoom(client, roomID)
+}
+
+// forget stops tracking a recorded session on this node.
+func (rs *Resumer) forget(rec *recording) {
+	rs.mutex.Lock()
+	if rs.recordings[rec.token] == rec {
+		delete(rs.recordings, rec.token)
+	}
+	rs.mutex.Unlock()
+}
+
+// release takes a recorded session's stand-in out of its rooms.
+func (rs *Resumer) release(rec *recording) {
+	for _, roomID := range rec.standIn.GetRooms() {
+		rs.server.RemoveClientFromRoom(rec.standIn, roomID)
+	}
+}
+
+// discard deletes a kept session, so it can't be resumed.
+func (rs *Resumer) discard(token string) {
+	ctx, cancel := context.WithTimeout(context.Background(), resumeTimeout)
+	defer cancel()
+
+	pipe := rs.redisClient.Pipeline()
+	pipe.Del(ctx, formatResumeStateKey(token), formatResumeEventsKey(token), formatResumeTruncatedKey(token))
+	if _, err := pipe.Exec(ctx); err != nil {
+		rs.logger.Error("Failed to discard session", err, "token", token)
+	}
+}
+
+// copySubscriptions copies the topics, chat shards and chat channels another client follows in its rooms.
+func (c *Client) copySubscriptions(other *Client) {
+	topics, chatShards, chatChannels := other.subscriptions()
+
+	c.topicsMutex.Lock()
+	defer c.topicsMutex.Unlock()
+
+	for roomID, roomTopics := range topics {
+		c.topics[roomID] = roomTopics
+	}
+	for roomID, shard := range chatShards {
+		c.chatShards[roomID] = shard
+	}
+	for roomID, channels := range chatChannels {
+		c.chatChannels[roomID] = channels
+	}
+}
+
+// subscriptions copies the topics and chat channels followed in the rooms where the client chose a subset,
+// and the chat shards it chats in, by room ID.
+func (c *Client) subscriptions() (map[string][]Topic, map[string]int, map[string][]string) {
+	c.topicsMutex.RLock()
+	defer c.topicsMutex.RUnlock()
+
+	topics := make(map[string][]Topic, len(c.topics))
+	for roomID, roomTopics := range c.topics {
+		topics[roomID] = slices.Clone(roomTopics)
+	}
+	chatShards := make(map[string]int, len(c.chatShards))
+	for roomID, shard := range c.chatShards {
+		chatShards[roomID] = shard
+	}
+	chatChannels := make(map[string][]string, len(c.chatChannels))
+	for roomID, channels := range c.chatChannels {
+		chatChannels[roomID] = slices.Clone(channels)
+	}
+	return topics, chatShards, chatChannels
+}
+
+// formatResumeStateKey formats the key of what a disconnected client followed.
+func formatResumeStateKey(token string) string {
+	return fmt.Sprintf("%s:%s:state", resumeKeyPrefix, token)
+}
+
+// formatResumeEventsKey formats the key of the notifications recorded for a disconnected client.
+func formatResumeEventsKey(token string) string {
+	return fmt.Sprintf("%s:%s:events", resumeKeyPrefix, token)
+}
+
+// formatResumeTruncatedKey formats the key marking that notifications recorded for a disconnected client were dropped.
+func formatResumeTruncatedKey(token string) string {
+	return fmt.Sprintf("%s:%s:truncated", resumeKeyPrefix, token)
+}
@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"norelock.dev/listenify/backend/internal/utils"
 )
@@ -57,6 +59,9 @@ type Router struct {
 	// mutex is used to synchronize access to the handlers map.
 	mutex sync.RWMutex
 
+	// requestCount is the number of requests routed since startup.
+	requestCount atomic.Uint64
+
 	// logger is the router's logger.
 	logger *utils.Logger
 }
@@ -109,26 +114,39 @@ func (r *Router) Wrap(mw MiddlewareFunc) HandlerRegistry {
 
 // Route routes a request to the appropriate handler.
 func (r *Router) Route(client *Client, request *Request) *Response {
+	r.requestCount.Add(1)
+
 	r.mutex.RLock()
 	handler, ok := r.handlers[request.Method]
 	r.mutex.RUnlock()
 
+	// Every call gets its own trace ID, tying its log lines to the error the client sees
+	traceID := utils.NewTraceID()
+	logger := r.logger.With("traceId", traceID, "client", client.ID, "userID", client.UserID, "method", request.Method)
+
 	if !ok {
-		r.logger.Warn("Method not found", "method", request.Method)
-		return NewErrorResponse(request.ID, ErrMethodNotFound, fmt.Sprintf("Method '%s' not found", request.Method), nil)
+		logger.Warn("Method not found")
+		response := NewErrorResponse(request.ID, ErrMethodNotFound, fmt.Sprintf("Method '%s' not found", request.Method), nil)
+		response.Error.TraceID = traceID
+		return response
 	}
 
 	// Create context with client information
 	ctx := context.WithValue(context.Background(), "client", client)
 	ctx = context.WithValue(ctx, "userID", client.UserID)
 	ctx = context.WithValue(ctx, "username", client.Username)
+	ctx = utils.WithTraceID(ctx, traceID)
 
 	// Call the handler
+	start := time.Now()
 	result, err := handler(ctx, client, request.Params)
 	if err != nil {
-		r.logger.Error("Handler error", err, "method", request.Method)
-		return handleError(request.ID, err)
+		logger.Error("Handler error", err, "duration", time.Since(start).String())
+		response := handleError(request.ID, err)
+		response.Error.TraceID = traceID
+		return response
 	}
+	logger.Debug("RPC call", "duration", time.Since(start).String())
 
 	// If this is a notification, don't return a response
 	if request.IsNotification() {
@@ -138,6 +156,11 @@ func (r *Router) Route(client *Client, request *Request) *Response {
 	return NewResponse(request.ID, result)
 }
 
+// RequestCount returns the number of requests routed since startup.
+func (r *Router) RequestCount() uint64 {
+	return r.requestCount.Load()
+}
+
 // handleError converts an error to an appropriate error response.
 func handleError(id any, err error) *Response {
 	// Check if the error is an RPC error
@@ -173,12 +196,12 @@ func RoleMiddleware(role string) MiddlewareFunc {
 func LoggingMiddleware(logger *utils.Logger) MiddlewareFunc {
 	return func(next HandlerFunc) HandlerFunc {
 		return func(ctx context.Context, client *Client, params json.RawMessage) (any, error) {
-			logger.Debug("RPC request", "client", client.ID, "userID", client.UserID)
+			logger.WithTrace(ctx).Debug("RPC request", "client", client.ID, "userID", client.UserID)
 			result, err := next(ctx, client, params)
 			if err != nil {
-				logger.Error("RPC error", err, "client", client.ID, "userID", client.UserID)
+				logger.WithTrace(ctx).Error("RPC error", err, "client", client.ID, "userID", client.UserID)
 			} else {
-				logger.Debug("RPC response", "client", client.ID, "userID", client.UserID)
+				logger.WithTrace(ctx).Debug("RPC response", "client", client.ID, "userID", client.UserID)
 			}
 			return result, err
 		}
@@ -191,7 +214,7 @@ func RecoveryMiddleware(logger *utils.Logger) MiddlewareFunc {
 		return func(ctx context.Context, client *Client, params json.RawMessage) (any, error) {
 			defer func() {
 				if r := recover(); r != nil {
-					logger.Error("Panic recovered", fmt.Errorf("panic: %v", r), "client", client.ID, "userID", client.UserID)
+					logger.WithTrace(ctx).Error("Panic recovered", fmt.Errorf("panic: %v", r), "client", client.ID, "userID", client.UserID)
 				}
 			}()
 			return next(ctx, client, params)
@@ -3,7 +3,10 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,6 +28,15 @@ const (
 
 	// Maximum message size allowed from peer.
 	maxMessageSize = 512 * 1024 // 512KB
+
+	// Time kept before the shutdown deadline to send clients their close frames.
+	shutdownCloseMargin = time.Second
+
+	// Weight of the latest pong in the moving average of a client's round-trip time.
+	rttWeight = 0.2
+
+	// Time after reconnecting during which a client's room joins count as rejoins.
+	rejoinWindow = 30 * time.Second
 )
 
 var upgrader = websocket.Upgrader{
@@ -35,6 +47,11 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// GeoLocator resolves an IP address to a country code.
+type GeoLocator interface {
+	LookupCountry(ip string) string
+}
+
 // Server handles WebSocket connections and RPC requests.
 type Server struct {
 	hub          *Hub
@@ -42,11 +59,37 @@ type Server struct {
 	authProvider auth.Provider
 	sessionMgr   managers.SessionManager
 	presenceMgr  managers.PresenceManager
+	geoLocator   GeoLocator
 	logger       *utils.Logger
 	clients      map[*Client]bool
 	register     chan *Client
 	unregister   chan *Client
 	mutex        sync.Mutex
+
+	// sseClients are the clients connected over Server-Sent Events, by client ID
+	sseClients map[string]*Client
+
+	// realtimeMethods are notifications skipped on transports without low latency
+	realtimeMethods map[string]bool
+
+	// requests counts the requests in flight, and the work they left to run after their response
+	requests sync.WaitGroup
+
+	// draining is set once the server turns away new connections and requests
+	draining      bool
+	drainingMutex sync.RWMutex
+
+	// cluster fans notifications out to the other nodes, nil when the server runs alone
+	cluster *Cluster
+
+	// resumer keeps the sessions of disconnected clients for them to resume, nil when they can't
+	resumer *Resumer
+
+	// admission spreads out new connections and reconnects, nil when connections are admitted without limit
+	admission *admission
+
+	// throttle rate limits the notifications of large rooms, nil when they aren't throttled
+	throttle *throttle
 }
 
 // NewServer creates a new WebSocket server.
@@ -55,9 +98,11 @@ func NewServer(
 	authProvider auth.Provider,
 	sessionMgr managers.SessionManager,
 	presenceMgr managers.PresenceManager,
+	geoLocator GeoLocator,
+	broadcast BroadcastPolicy,
 	logger *utils.Logger,
 ) *Server {
-	hub := NewHub(logger)
+	hub := NewHub(broadcast, logger)
 	go hub.Run()
 
 	server := &Server{
@@ -66,10 +111,14 @@ func NewServer(
 		authProvider: authProvider,
 		sessionMgr:   sessionMgr,
 		presenceMgr:  presenceMgr,
+		geoLocator:   geoLocator,
 		logger:       logger.Named("rpc_server"),
 		clients:      make(map[*Client]bool),
 		register:     make(chan *Client),
 		unregister:   make(chan *Client),
+
+		sseClients:      make(map[string]*Client),
+		realtimeMethods: make(map[string]bool),
 	}
 
 	go server.run()
@@ -87,12 +136,15 @@ func (s *Server) run() {
 			s.mutex.Lock()
 			s.clients[client] = true
 			s.mutex.Unlock()
+			s.hub.registerClient(client)
 			s.logger.Debug("Client registered", "id", client.ID, "userID", client.UserID)
 
 		case client := <-s.unregister:
 			s.mutex.Lock()
 			if _, ok := s.clients[client]; ok {
 				delete(s.clients, client)
+				// Drop the client's room subscriptions before nothing can be sent to it anymore
+				s.hub.unregisterClient(client)
 				close(client.send)
 				s.logger.Debug("Client unregistered", "id", client.ID, "userID", client.UserID)
 			}
@@ -102,7 +154,14 @@ func (s *Server) run() {
 }
 
 // HandleWebSocket upgrades an HTTP connection to WebSocket and handles the connection.
+// Requests that aren't WebSocket upgrades, for example because a proxy dropped the upgrade headers,
+// get the list of transports instead so the client can fall back.
 func (s *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsWebSocketUpgrade(r) {
+		s.describeTransports(w)
+		return
+	}
+
 	// Upgrade connection to WebSocket
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -110,50 +169,50 @@ func (s *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get token from query parameters
-	token := r.URL.Query().Get("token")
-	if token == "" {
-		s.logger.Warn("No token provided")
-
-		err := conn.WriteMessage(websocket.TextMessage, []byte(`{"error": "No token provided"}`))
-		if err != nil {
-			s.logger.Error("Failed to send error message", err)
-		}
-
-		conn.Close()
+	// Send clients connecting while the server shuts down straight to another node
+	if s.isDraining() {
+		s.rejectConnection(conn, NewCloseReason(CloseDraining, "Server is shutting down"))
 		return
 	}
 
-	// Validate token
-	claims, err := s.authProvider.ValidateToken(token)
+	// Negotiate protocol version and capabilities
+	handshake, err := ParseHandshake(r)
 	if err != nil {
-		s.logger.Warn("Invalid token", "error", err)
+		s.logger.Warn("Invalid protocol handshake", "error", err)
+		s.rejectConnection(conn, NewCloseReason(CloseProtocolViolation, err.Error()))
+		return
+	}
 
-		err := conn.WriteMessage(websocket.TextMessage, []byte(`{"error": "Invalid token"}`))
-		if err != nil {
-			s.logger.Error("Failed to send error message", err)
-		}
+	protocol, err := Negotiate(handshake)
+	if err != nil {
+		s.logger.Warn("Unsupported protocol version", "version", handshake.Version, "minVersion", MinProtocolVersion)
+		s.rejectConnection(conn, NewCloseReason(CloseUnsupportedProtocol, err.Error()))
+		return
+	}
+	s.negotiateResume(protocol)
 
-		conn.Close()
+	// Wait for the connection's turn before touching the session store, so reconnect storms are spread out
+	if ok, reason := s.admit(r.Context()); !ok {
+		s.rejectConnection(conn, reason)
 		return
 	}
 
-	// Verify session
-	session, err := s.sessionMgr.GetSession(r.Context(), token)
-	if err != nil || session == nil {
-		s.logger.Warn("Invalid session", "error", err)
+	// Authenticate the user
+	claims, session, failure := s.authenticate(r)
+	if failure != nil {
+		s.logger.Warn("Connection not authenticated", "reason", failure.Message)
 
-		err := conn.WriteMessage(websocket.TextMessage, []byte(`{"error": "Invalid session"}`))
-		if err != nil {
+		message, _ := json.Marshal(map[string]string{"error": failure.Message})
+		if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
 			s.logger.Error("Failed to send error message", err)
 		}
 
-		conn.Close()
+		s.rejectConnection(conn, *failure)
 		return
 	}
 
 	// Create client
-	clientID, err := utils.GenerateID("client")
+	client, err := s.newClient(claims, session, bearerToken(r), handshake, protocol)
 	if err != nil {
 		s.logger.Error("Failed to generate client ID", err)
 
@@ -162,24 +221,23 @@ func (s *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 			s.logger.Error("Failed to send error message", err)
 		}
 
-		conn.Close()
+		s.rejectConnection(conn, NewCloseReason(CloseInternalError, "Failed to generate client ID"))
 		return
 	}
+	client.conn = conn
 
-	client := &Client{
-		ID:       clientID,
-		UserID:   claims.UserID,
-		Username: claims.Username,
-		server:   s,
-		conn:     conn,
-		send:     make(chan []byte, 256),
-		rooms:    make(map[string]bool),
-		logger:   s.logger.Named("client"),
-	}
+	// Resolve the coarse listener location; the IP address itself is not retained
+	s.locate(client, r)
 
 	// Register client
 	s.register <- client
 
+	// Let the client know which protocol behaviors were selected, then resume its previous session
+	// before the connection it replaces is closed
+	client.SendNotification("connection.negotiated", protocol)
+	s.attach(client, handshake)
+	s.replaceInstance(client)
+
 	// Update presence
 	// Note: Assuming the PresenceManager has a method to mark a user as online
 	// If this method doesn't exist, it needs to be implemented in the PresenceManager
@@ -190,27 +248,254 @@ func (s *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	go client.readPump()
 	go client.writePump()
 
-	s.logger.Info("WebSocket connection established", "clientID", client.ID, "userID", client.UserID)
+	s.logger.Info("WebSocket connection established", "clientID", client.ID, "userID", client.UserID, "protocol", protocol.Version)
 }
 
-// Broadcast sends a message to all connected clients.
+// authenticate checks the token and session of a connecting client.
+// The token is read from the token query parameter or a bearer Authorization header.
+// If the client can't be authenticated it returns the reason to close the connection with.
+func (s *Server) authenticate(r *http.Request) (*auth.Claims, *managers.SessionData, *CloseReason) {
+	fail := func(code int, message string) (*auth.Claims, *managers.SessionData, *CloseReason) {
+		reason := NewCloseReason(code, message)
+		return nil, nil, &reason
+	}
+
+	token := bearerToken(r)
+	if token == "" {
+		return fail(CloseAuthFailed, "No token provided")
+	}
+
+	claims, err := s.authProvider.ValidateToken(token)
+	if errors.Is(err, auth.ErrExpiredToken) {
+		return fail(CloseAuthExpired, "Token expired")
+	}
+	if err != nil {
+		return fail(CloseAuthFailed, "Invalid token")
+	}
+
+	session, err := s.sessionMgr.GetSession(r.Context(), token)
+	if err != nil || session == nil {
+		return fail(CloseAuthFailed, "Invalid session")
+	}
+
+	return claims, session, nil
+}
+
+// bearerToken reads the token from the token query parameter or a bearer Authorization header.
+func bearerToken(r *http.Request) string {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token
+	}
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// newClient creates a client for an authenticated user, without its connection.
+func (s *Server) newClient(claims *auth.Claims, session *managers.SessionData, token string, handshake *Handshake, protocol *NegotiatedProtocol) (*Client, error) {
+	clientID, err := utils.GenerateID("client")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		ID:               clientID,
+		UserID:           claims.UserID,
+		Username:         claims.Username,
+		server:           s,
+		send:             make(chan []byte, 256),
+		rooms:            make(map[string]bool),
+		topics:           make(map[string][]Topic),
+		chatShards:       make(map[string]int),
+		chatChannels:     make(map[string][]string),
+		protocol:         protocol,
+		token:            token,
+		sessionExpiresAt: session.ExpiresAt,
+		instance:         handshake.Instance,
+		reconnected:      handshake.ResumeToken != "",
+		connectedAt:      time.Now(),
+		logger:           s.logger.Named("client"),
+	}, nil
+}
+
+// replaceInstance disconnects the other connections of a new client's instance.
+// They are left over from before the client reconnected and would otherwise linger until they time out.
+func (s *Server) replaceInstance(client *Client) {
+	if client.instance == "" {
+		return
+	}
+
+	s.mutex.Lock()
+	var replaced []*Client
+	for other := range s.clients {
+		if other != client && other.UserID == client.UserID && other.instance == client.instance {
+			replaced = append(replaced, other)
+		}
+	}
+	s.mutex.Unlock()
+
+	for _, other := range replaced {
+		other.Disconnect(NewCloseReason(CloseDuplicateSession, "Replaced by a newer connection"))
+	}
+}
+
+// locate records the IP address of a connecting client and resolves its country.
+func (s *Server) locate(client *Client, r *http.Request) {
+	client.ip = utils.GetRequestIP(r)
+	if s.geoLocator != nil {
+		client.country = s.geoLocator.LookupCountry(client.ip)
+	}
+}
+
+// rejectConnection closes a freshly upgraded connection with a close reason.
+func (s *Server) rejectConnection(conn *websocket.Conn, reason CloseReason) {
+	reason = s.jitterReconnect(reason)
+	if err := conn.WriteControl(websocket.CloseMessage, reason.frame(), time.Now().Add(writeWait)); err != nil {
+		s.logger.Error("Failed to send close message", err)
+	}
+
+	conn.Close()
+}
+
+// Broadcast sends a message to all clients connected to this server.
 func (s *Server) Broadcast(message []byte) {
 	s.hub.Broadcast(message)
 }
 
-// BroadcastToRoom sends a message to all clients in a room.
+// BroadcastToRoom sends a message to all clients in a room connected to this server.
 func (s *Server) BroadcastToRoom(roomID string, message []byte) {
 	s.hub.BroadcastToRoom(roomID, message)
 }
 
-// BroadcastToUser sends a message to a specific user.
+// BroadcastToUser sends a message to the clients of a user connected to this server.
 func (s *Server) BroadcastToUser(userID string, message []byte) {
 	s.hub.BroadcastToUser(userID, message)
 }
 
-// AddClientToRoom adds a client to a room.
+// NotifyUser sends a notification to all clients of a user, on every node.
+func (s *Server) NotifyUser(userID, method string, params any) {
+	notificationJSON, err := s.marshalNotification(method, params)
+	if err != nil {
+		return
+	}
+
+	s.hub.BroadcastToUser(userID, notificationJSON)
+	if s.cluster != nil {
+		s.cluster.publish(clusterNotification{UserID: userID, Message: notificationJSON})
+	}
+}
+
+// NotifyRoom sends a notification to all clients in a room following its topic, on every node.
+// High-churn notifications to large rooms are throttled and may be coalesced with the ones after them.
+func (s *Server) NotifyRoom(roomID, method string, params any) {
+	if s.throttle != nil && s.throttle.hold(roomID, method, params) {
+		return
+	}
+	s.notifyRoom(roomID, method, params)
+}
+
+// notifyRoom sends a notification to all clients in a room following its topic, on every node, without throttling.
+func (s *Server) notifyRoom(roomID, method string, params any) {
+	notificationJSON, err := s.marshalNotification(method, params)
+	if err != nil {
+		return
+	}
+
+	topic := notificationTopic(method)
+	s.hub.BroadcastTopicToRoom(roomID, topic, notificationJSON)
+	if s.cluster != nil {
+		s.cluster.publish(clusterNotification{RoomID: roomID, Topic: topic, Message: notificationJSON})
+	}
+}
+
+// NotifyLocalRoom sends a notification to all clients in a room following its topic connected to this server.
+// It is meant for events every node learns of by itself, such as room settings changes.
+func (s *Server) NotifyLocalRoom(roomID, method string, params any) {
+	s.notifyLocalRoom(roomID, notificationTopic(method), method, params)
+}
+
+// notifyLocalRoom sends a notification about a topic to the clients in a room following it connected to this server.
+func (s *Server) notifyLocalRoom(roomID string, topic Topic, method string, params any) {
+	notificationJSON, err := s.marshalNotification(method, params)
+	if err != nil {
+		return
+	}
+
+	s.hub.BroadcastTopicToRoom(roomID, topic, notificationJSON)
+}
+
+// notifyLocalChatShard sends a notification to the clients in a room's chat shard following the chat
+// and a chat channel connected to this server.
+func (s *Server) notifyLocalChatShard(roomID string, shard int, chatChannel string, method string, params any) {
+	notificationJSON, err := s.marshalNotification(method, params)
+	if err != nil {
+		return
+	}
+
+	s.hub.BroadcastToChatShard(roomID, shard, chatChannel, notificationJSON)
+}
+
+// notifyLocalChatChannel sends a notification to the clients in a room following the chat and one of its
+// chat channels connected to this server.
+func (s *Server) notifyLocalChatChannel(roomID string, chatChannel string, method string, params any) {
+	notificationJSON, err := s.marshalNotification(method, params)
+	if err != nil {
+		return
+	}
+
+	s.hub.BroadcastToChatChannel(roomID, chatChannel, notificationJSON)
+}
+
+// SetChatShard moves the clients of a user in a room to another of its chat shards, on every node,
+// such as when they are promoted from the room's overflow.
+func (s *Server) SetChatShard(userID, roomID string, shard int) {
+	s.setLocalChatShard(userID, roomID, shard)
+	if s.cluster != nil {
+		s.cluster.publish(clusterNotification{RoomID: roomID, UserID: userID, ChatShard: &shard})
+	}
+}
+
+// setLocalChatShard moves the clients of a user in a room connected to this server to another of its chat shards.
+func (s *Server) setLocalChatShard(userID, roomID string, shard int) {
+	for _, client := range s.hub.GetClientsInRoom(roomID) {
+		if client.UserID == userID {
+			client.SetChatShard(roomID, shard)
+		}
+	}
+}
+
+// RenameUser shows a user's new username on their clients, connected to any node.
+func (s *Server) RenameUser(userID, username string) {
+	s.renameLocalUser(userID, username)
+	if s.cluster != nil {
+		s.cluster.publish(clusterNotification{UserID: userID, Username: &username})
+	}
+}
+
+// renameLocalUser shows a user's new username on their clients connected to this server.
+func (s *Server) renameLocalUser(userID, username string) {
+	for _, client := range s.hub.GetUserClients(userID) {
+		client.Username = username
+	}
+}
+
+// marshalNotification marshals a notification, logging the failure.
+func (s *Server) marshalNotification(method string, params any) ([]byte, error) {
+	notificationJSON, err := json.Marshal(&Notification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		s.logger.Error("Failed to marshal notification", err, "method", method)
+	}
+	return notificationJSON, err
+}
+
+// AddClientToRoom adds a client to a room, and has this server follow the room's events published by any node.
 func (s *Server) AddClientToRoom(client *Client, roomID string) {
 	s.hub.AddClientToRoom(client, roomID)
+	if s.cluster != nil {
+		s.cluster.followRoom(roomID)
+	}
 }
 
 // RemoveClientFromRoom removes a client from a room.
@@ -223,6 +508,39 @@ func (s *Server) GetClientsInRoom(roomID string) []*Client {
 	return s.hub.GetClientsInRoom(roomID)
 }
 
+// RoomSubscriptions lists the users following each room's events over connections to this server, by room ID.
+func (s *Server) RoomSubscriptions() map[string][]string {
+	return s.hub.GetRoomUsers()
+}
+
+// UnsubscribeUser stops the clients of a user connected to this server from following a room's events,
+// and tells them they are no longer in the room.
+func (s *Server) UnsubscribeUser(roomID, userID string) {
+	notification, err := json.Marshal(&Notification{
+		JSONRPC: "2.0",
+		Method:  "room.removed",
+		Params:  map[string]any{"roomId": roomID},
+	})
+	if err != nil {
+		s.logger.Error("Failed to marshal notification", err, "method", "room.removed")
+		return
+	}
+
+	s.hub.RemoveUserFromRoom(userID, roomID, notification)
+}
+
+// connections lists the user IDs of the clients connected to this server, by client ID.
+func (s *Server) connections() map[string]any {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	connections := make(map[string]any, len(s.clients))
+	for client := range s.clients {
+		connections[client.ID] = client.UserID
+	}
+	return connections
+}
+
 // GetClientCount gets the number of connected clients.
 func (s *Server) GetClientCount() int {
 	s.mutex.Lock()
@@ -230,17 +548,170 @@ func (s *Server) GetClientCount() int {
 	return len(s.clients)
 }
 
-// Shutdown gracefully shuts down the server.
+// BroadcastLatency gets the moving average of how long room broadcasts take to reach every client.
+func (s *Server) BroadcastLatency() time.Duration {
+	return s.hub.BroadcastLatency()
+}
+
+// BroadcastShards describes the room broadcasts each shard of this server delivered and has waiting.
+func (s *Server) BroadcastShards() []BroadcastShardStats {
+	return s.hub.BroadcastShards()
+}
+
+// BroadcastBacklog gets, per shard, the number of room broadcasts waiting for delivery and the total dropped.
+func (s *Server) BroadcastBacklog() (waiting []int, dropped []uint64) {
+	shards := s.hub.BroadcastShards()
+	waiting = make([]int, len(shards))
+	dropped = make([]uint64, len(shards))
+	for i, shard := range shards {
+		waiting[i] = shard.Backlog
+		dropped[i] = shard.Dropped
+	}
+	return waiting, dropped
+}
+
+// DisconnectUser disconnects the clients of a user, only those in a room if a room ID is given.
+func (s *Server) DisconnectUser(userID, roomID string, reason CloseReason) {
+	var clients []*Client
+	if roomID != "" {
+		clients = s.hub.GetClientsInRoom(roomID)
+	} else {
+		s.mutex.Lock()
+		for client := range s.clients {
+			clients = append(clients, client)
+		}
+		s.mutex.Unlock()
+	}
+
+	for _, client := range clients {
+		if client.UserID == userID {
+			client.Disconnect(reason)
+		}
+	}
+}
+
+// StopAccepting makes the server turn away new connections and requests, so the requests in flight
+// can finish before the clients are disconnected.
+func (s *Server) StopAccepting() {
+	s.drainingMutex.Lock()
+	defer s.drainingMutex.Unlock()
+
+	if !s.draining {
+		s.draining = true
+		s.logger.Info("RPC server stopped accepting connections and requests")
+	}
+}
+
+// isDraining checks whether the server turns away new connections and requests.
+func (s *Server) isDraining() bool {
+	s.drainingMutex.RLock()
+	defer s.drainingMutex.RUnlock()
+
+	return s.draining
+}
+
+// beginRequest counts a request as in flight. It returns false when the server turns away requests.
+// Every request begun must be ended with s.requests.Done().
+func (s *Server) beginRequest() bool {
+	s.drainingMutex.RLock()
+	defer s.drainingMutex.RUnlock()
+
+	if s.draining {
+		return false
+	}
+	s.requests.Add(1)
+	return true
+}
+
+// AnnounceShutdown tells the clients connected to the server that it is shutting down and disconnects them
+// by a deadline, so they can reconnect to another node first. Call it once the server stopped accepting,
+// so the clients moving aren't accepted again.
+func (s *Server) AnnounceShutdown(deadline time.Time) {
+	reason := NewCloseReason(CloseDraining, "Server is shutting down")
+	notice := ShutdownNotice{
+		Message:   reason.Message,
+		Reconnect: reason.Reconnect,
+		Deadline:  deadline,
+	}
+
+	s.mutex.Lock()
+	clients := make([]*Client, 0, len(s.clients))
+	for client := range s.clients {
+		clients = append(clients, client)
+	}
+	s.mutex.Unlock()
+
+	// Each client gets its own delay, so they don't all land on the other nodes at once
+	for _, client := range clients {
+		jittered := s.jitterReconnect(reason)
+		notice.RetryAfter = jittered.RetryAfter
+		client.SendNotification(EventServerShutdown, notice)
+	}
+	s.logger.Info("Announced shutdown to clients", "clients", len(clients), "deadline", deadline)
+}
+
+// Shutdown gracefully shuts down the server. It stops accepting requests, waits for the requests in flight
+// to finish, or for the context to nearly end, and then disconnects the clients with a close frame before
+// the context's deadline, sending them to the other nodes.
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("Shutting down RPC server")
+	s.StopAccepting()
+
+	// Keep time to close the connections before the deadline
+	drainCtx := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		margin := min(shutdownCloseMargin, time.Until(deadline)/4)
+		var cancel context.CancelFunc
+		drainCtx, cancel = context.WithDeadline(ctx, deadline.Add(-margin))
+		defer cancel()
+	}
+
+	// Let queue advances and other requests in flight finish before their clients are gone
+	drained := make(chan struct{})
+	go func() {
+		s.requests.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+		s.logger.Info("RPC requests drained")
+	case <-drainCtx.Done():
+		err = drainCtx.Err()
+		s.logger.Warn("RPC requests still in flight, disconnecting clients anyway")
+	}
 
-	// Close all client connections
+	// Close all client connections, sending clients to the other nodes. Slow connections are closed
+	// side by side, so they don't hold up the others past the deadline.
+	reason := NewCloseReason(CloseDraining, "Server is shutting down")
+	var closing sync.WaitGroup
 	s.mutex.Lock()
 	for client := range s.clients {
-		client.conn.Close()
+		closing.Add(1)
+		go func() {
+			defer closing.Done()
+			client.Disconnect(reason)
+		}()
 		delete(s.clients, client)
 	}
 	s.mutex.Unlock()
 
-	return nil
+	closed := make(chan struct{})
+	go func() {
+		closing.Wait()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+		s.logger.Info("RPC clients disconnected")
+	case <-ctx.Done():
+		s.logger.Warn("RPC clients still closing at the shutdown deadline")
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+
+	return err
 }
@@ -0,0 +1,271 @@
+// Package rpc provides WebSocket-based RPC functionality.
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// EventsPath is the path of the Server-Sent Events stream of the fallback transport.
+	EventsPath = "/events"
+
+	// RequestsPath is the path requests are posted to over the fallback transport.
+	RequestsPath = "/rpc"
+)
+
+// transportInfo describes a transport a client can connect over.
+type transportInfo struct {
+	Name     Transport `json:"name"`
+	URL      string    `json:"url,omitempty"`
+	Events   string    `json:"events,omitempty"`
+	Requests string    `json:"requests,omitempty"`
+}
+
+// Handler returns the HTTP handler serving the WebSocket transport, its fallback, and the regional
+// endpoints to connect to.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+EventsPath, s.HandleEvents)
+	mux.HandleFunc("POST "+RequestsPath, s.HandleRequest)
+	mux.HandleFunc("GET "+BootstrapPath, s.HandleBootstrap)
+	mux.HandleFunc("/", s.HandleWebSocket)
+	return mux
+}
+
+// AddRealtimeMethod marks a notification method as only worth delivering with low latency.
+// Clients connected over Server-Sent Events don't receive it and rely on the next full update instead.
+func (s *Server) AddRealtimeMethod(method string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.realtimeMethods[method] = true
+}
+
+// isRealtime checks whether a message is a notification reserved for low-latency transports.
+func (s *Server) isRealtime(message []byte) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.realtimeMethods) == 0 {
+		return false
+	}
+
+	var notification struct {
+		Method string `json:"method"`
+	}
+	if err := json.Unmarshal(message, &notification); err != nil {
+		return false
+	}
+	return s.realtimeMethods[notification.Method]
+}
+
+// describeTransports tells a client which transports it can connect over.
+func (s *Server) describeTransports(w http.ResponseWriter) {
+	utils.RespondWithJSON(w, http.StatusUpgradeRequired, map[string]any{
+		"error": "WebSocket upgrade required, or connect over a fallback transport",
+		"transports": []transportInfo{
+			{Name: TransportWebSocket, URL: "/"},
+			{Name: TransportSSE, Events: EventsPath, Requests: RequestsPath},
+		},
+	})
+}
+
+// HandleEvents opens a Server-Sent Events stream for a client that can't use WebSockets.
+// The client receives its ID in the connection.negotiated notification and posts its requests
+// to the requests path with it. Responses are returned to the requests, notifications arrive on the stream.
+func (s *Server) HandleEvents(w http.ResponseWriter, r *http.Request) {
+	if s.isDraining() {
+		utils.RespondWithError(w, http.StatusServiceUnavailable, "Server is shutting down")
+		return
+	}
+
+	// Negotiate protocol version and capabilities
+	handshake, err := ParseHandshake(r)
+	if err != nil {
+		s.logger.Warn("Invalid protocol handshake", "error", err)
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	handshake.Transport = TransportSSE
+
+	protocol, err := Negotiate(handshake)
+	if err != nil {
+		s.logger.Warn("Unsupported protocol version", "version", handshake.Version, "minVersion", MinProtocolVersion)
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.negotiateResume(protocol)
+
+	// Wait for the connection's turn before touching the session store, so reconnect storms are spread out
+	if ok, reason := s.admit(r.Context()); !ok {
+		reason = s.jitterReconnect(reason)
+		w.Header().Set("Retry-After", strconv.Itoa(reason.RetryAfter))
+		utils.RespondWithError(w, http.StatusServiceUnavailable, reason.Message)
+		return
+	}
+
+	// Authenticate the user
+	claims, session, failure := s.authenticate(r)
+	if failure != nil {
+		s.logger.Warn("Connection not authenticated", "reason", failure.Message)
+		utils.RespondWithError(w, http.StatusUnauthorized, failure.Message)
+		return
+	}
+
+	// Create client
+	client, err := s.newClient(claims, session, bearerToken(r), handshake, protocol)
+	if err != nil {
+		s.logger.Error("Failed to generate client ID", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to generate client ID")
+		return
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	client.cancel = cancel
+	s.locate(client, r)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Keep buffering proxies from holding back events
+	w.WriteHeader(http.StatusOK)
+
+	// Register client
+	s.register <- client
+	s.mutex.Lock()
+	s.sseClients[client.ID] = client
+	s.mutex.Unlock()
+
+	// Let the client know which protocol behaviors were selected, and its ID for posting requests,
+	// then resume its previous session before the connection it replaces is closed
+	client.SendNotification("connection.negotiated", struct {
+		*NegotiatedProtocol
+		ClientID string `json:"clientId"`
+	}{protocol, client.ID})
+	s.attach(client, handshake)
+	s.replaceInstance(client)
+
+	s.logger.Info("Event stream established", "clientID", client.ID, "userID", client.UserID, "protocol", protocol.Version)
+
+	client.eventPump(ctx, w)
+
+	s.detach(client)
+	s.mutex.Lock()
+	delete(s.sseClients, client.ID)
+	s.mutex.Unlock()
+	s.unregister <- client
+
+	s.logger.Info("Event stream closed", "clientID", client.ID, "userID", client.UserID)
+}
+
+// HandleRequest handles a JSON-RPC request or batch posted by a client connected over Server-Sent Events.
+func (s *Server) HandleRequest(w http.ResponseWriter, r *http.Request) {
+	claims, _, failure := s.authenticate(r)
+	if failure != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, failure.Message)
+		return
+	}
+
+	s.mutex.Lock()
+	client, ok := s.sseClients[r.URL.Query().Get("clientId")]
+	s.mutex.Unlock()
+	if !ok || client.UserID != claims.UserID {
+		utils.RespondWithError(w, http.StatusNotFound, "Unknown client, open an event stream first")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusRequestEntityTooLarge, "Request too large")
+		return
+	}
+
+	if !s.beginRequest() {
+		utils.RespondWithError(w, http.StatusServiceUnavailable, "Server is shutting down")
+		return
+	}
+	defer s.requests.Done()
+
+	client.requestMutex.Lock()
+	response := client.process(bytes.TrimSpace(body))
+	client.requestMutex.Unlock()
+
+	if response == nil {
+		w.WriteHeader(http.StatusNoContent)
+		client.runAfterResponse()
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(response); err != nil {
+		client.logger.Error("Failed to write response", err)
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	client.runAfterResponse()
+}
+
+// eventPump writes messages for a client to its event stream until the stream or the client is closed.
+func (c *Client) eventPump(ctx context.Context, w http.ResponseWriter) {
+	controller := http.NewResponseController(w)
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	write := func(event string) bool {
+		controller.SetWriteDeadline(time.Now().Add(writeWait))
+		if _, err := io.WriteString(w, event); err != nil {
+			return false
+		}
+		return controller.Flush() == nil
+	}
+
+	// Send the headers right away so the client knows the stream is open
+	if controller.Flush() != nil {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Tell the client why the server closed the stream
+			if reason := c.closeReason.Load(); reason != nil {
+				if event, err := json.Marshal(&Notification{JSONRPC: "2.0", Method: "connection.closed", Params: reason}); err == nil {
+					write(fmt.Sprintf("data: %s\n\n", event))
+				}
+			}
+			return
+
+		case message, ok := <-c.send:
+			if !ok {
+				// The server closed the channel.
+				return
+			}
+			if c.server.isRealtime(message) {
+				continue
+			}
+			if !write(fmt.Sprintf("data: %s\n\n", message)) {
+				return
+			}
+
+		case <-ticker.C:
+			if !c.checkSession() {
+				continue // The stream ends with the close notification
+			}
+			// Comments keep proxies from closing an idle stream
+			if !write(": ping\n\n") {
+				return
+			}
+		}
+	}
+}
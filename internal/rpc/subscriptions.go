@@ -0,0 +1,145 @@
+// Package rpc provides WebSocket-based RPC functionality.
+package rpc
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Topic is a kind of room event a connection can follow.
+type Topic string
+
+const (
+	// TopicChat covers chat messages and what happens to them.
+	TopicChat Topic = "chat"
+
+	// TopicState covers the room itself: its users, settings, votes and moderation.
+	TopicState Topic = "state"
+
+	// TopicQueue covers the DJ queue and what is playing.
+	TopicQueue Topic = "queue"
+)
+
+// AllTopics are the topics connections follow unless they chose a subset.
+var AllTopics = []Topic{TopicChat, TopicState, TopicQueue}
+
+// ParseTopics parses the names of room topics. No names means every topic.
+func ParseTopics(names []string) ([]Topic, error) {
+	if len(names) == 0 {
+		return slices.Clone(AllTopics), nil
+	}
+
+	topics := make([]Topic, 0, len(names))
+	for _, name := range names {
+		topic := Topic(name)
+		if !slices.Contains(AllTopics, topic) {
+			return nil, fmt.Errorf("unknown topic %q", name)
+		}
+		if !slices.Contains(topics, topic) {
+			topics = append(topics, topic)
+		}
+	}
+	return topics, nil
+}
+
+// notificationTopic gets the topic of a room notification method.
+func notificationTopic(method string) Topic {
+	switch {
+	case strings.HasPrefix(method, "chat."):
+		return TopicChat
+	case strings.HasPrefix(method, "queue."):
+		return TopicQueue
+	default:
+		return TopicState
+	}
+}
+
+// roomEventTopic gets the topic of an event services published to a room's PubSub channel.
+func roomEventTopic(eventType string) Topic {
+	if strings.HasPrefix(eventType, "chat_") {
+		return TopicChat
+	}
+	return TopicState
+}
+
+// SetRoomTopics sets the topics of a room's events the client follows. No topics means every topic.
+func (c *Client) SetRoomTopics(roomID string, topics []Topic) {
+	c.topicsMutex.Lock()
+	defer c.topicsMutex.Unlock()
+
+	if len(topics) == 0 || len(topics) == len(AllTopics) {
+		delete(c.topics, roomID)
+		return
+	}
+	c.topics[roomID] = slices.Clone(topics)
+}
+
+// RoomTopics gets the topics of a room's events the client follows.
+func (c *Client) RoomTopics(roomID string) []Topic {
+	c.topicsMutex.RLock()
+	defer c.topicsMutex.RUnlock()
+
+	if topics, ok := c.topics[roomID]; ok {
+		return slices.Clone(topics)
+	}
+	return slices.Clone(AllTopics)
+}
+
+// FollowsTopic checks whether the client follows a topic of a room's events.
+func (c *Client) FollowsTopic(roomID string, topic Topic) bool {
+	c.topicsMutex.RLock()
+	defer c.topicsMutex.RUnlock()
+
+	topics, ok := c.topics[roomID]
+	return !ok || slices.Contains(topics, topic)
+}
+
+// SetChatShard sets the overflow chat shard the client chats in in a room, 0 for the main chat.
+func (c *Client) SetChatShard(roomID string, shard int) {
+	c.topicsMutex.Lock()
+	defer c.topicsMutex.Unlock()
+
+	if shard == 0 {
+		delete(c.chatShards, roomID)
+		return
+	}
+	c.chatShards[roomID] = shard
+}
+
+// ChatShard gets the overflow chat shard the client chats in in a room, 0 for the main chat.
+func (c *Client) ChatShard(roomID string) int {
+	c.topicsMutex.RLock()
+	defer c.topicsMutex.RUnlock()
+
+	return c.chatShards[roomID]
+}
+
+// SetChatChannels sets the chat channels of a room the client follows. No channels means every channel.
+func (c *Client) SetChatChannels(roomID string, channels []string) {
+	c.topicsMutex.Lock()
+	defer c.topicsMutex.Unlock()
+
+	if len(channels) == 0 {
+		delete(c.chatChannels, roomID)
+		return
+	}
+	c.chatChannels[roomID] = slices.Clone(channels)
+}
+
+// ChatChannels gets the chat channels of a room the client follows, nil when it follows every channel.
+func (c *Client) ChatChannels(roomID string) []string {
+	c.topicsMutex.RLock()
+	defer c.topicsMutex.RUnlock()
+
+	return slices.Clone(c.chatChannels[roomID])
+}
+
+// FollowsChatChannel checks whether the client follows a chat channel of a room.
+func (c *Client) FollowsChatChannel(roomID string, channel string) bool {
+	c.topicsMutex.RLock()
+	defer c.topicsMutex.RUnlock()
+
+	channels, ok := c.chatChannels[roomID]
+	return !ok || slices.Contains(channels, channel)
+}
@@ -0,0 +1,187 @@
+// Package rpc provides WebSocket-based RPC functionality.
+package rpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+)
+
+// Time allowed to look up the size of a room before notifying it.
+const roomSizeTimeout = time.Second
+
+// throttledMethods are the high-churn notifications rate limited in large rooms, with the group each is
+// coalesced in. Playback notifications are never throttled so clients stay in sync.
+var throttledMethods = map[string]throttleGroup{
+	EventUserJoinedRoom: throttleMembers,
+	EventUserLeftRoom:   throttleMembers,
+	EventQueueUpdated:   throttleQueue,
+}
+
+// throttleGroup is a set of notifications coalesced together in a large room.
+type throttleGroup string
+
+const (
+	// throttleMembers coalesces users joining and leaving into net counts.
+	throttleMembers throttleGroup = "members"
+
+	// throttleQueue coalesces queue updates into the latest one.
+	throttleQueue throttleGroup = "queue"
+)
+
+// RoomSizer gets the state of a room, for the number of users in it across every node.
+type RoomSizer interface {
+	GetRoomState(ctx context.Context, roomID string) (*managers.RoomState, error)
+}
+
+// ThrottlePolicy controls how the notifications of rooms above a configurable size are rate limited.
+type ThrottlePolicy struct {
+	// Threshold is the number of users above which a room's notifications are throttled. Zero disables throttling.
+	Threshold int
+
+	// Interval is the minimum interval between notifications of the same group in a large room.
+	Interval time.Duration
+}
+
+// throttle holds back the high-churn notifications of large rooms, sending at most one per group and
+// interval. Each node throttles the notifications it sends itself.
+type throttle struct {
+	server *Server
+	policy ThrottlePolicy
+	sizer  RoomSizer
+
+	mu      sync.Mutex
+	entries map[string]*throttleEntry
+}
+
+// throttleEntry tracks the notifications of one group in one large room during an interval.
+type throttleEntry struct {
+	timer *time.Timer
+	held  bool
+
+	// joined and left count the users that joined and left the room while held back
+	joined int
+	left   int
+
+	// method and params are the latest notification held back for groups other than members
+	method string
+	params any
+}
+
+// SetThrottlePolicy sets how the server throttles the notifications of large rooms, sized with sizer.
+func (s *Server) SetThrottlePolicy(policy ThrottlePolicy, sizer RoomSizer) {
+	s.throttle = &throttle{
+		server:  s,
+		policy:  policy,
+		sizer:   sizer,
+		entries: make(map[string]*throttleEntry),
+	}
+}
+
+// hold checks whether a notification to a room is held back, coalescing it with the others of its group.
+// The first notification of a group goes out right away and opens an interval, the ones after it are
+// sent together when the interval ends.
+func (t *throttle) hold(roomID, method string, params any) bool {
+	group, ok := throttledMethods[method]
+	if !ok || t.policy.Threshold <= 0 || t.policy.Interval <= 0 {
+		return false
+	}
+	key := roomID + ":" + string(group)
+
+	// A room with an open interval was large a moment ago, only look up the size of the others
+	t.mu.Lock()
+	_, exists := t.entries[key]
+	t.mu.Unlock()
+	if !exists && !t.isLarge(roomID) {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, exists := t.entries[key]
+	if !exists {
+		t.entries[key] = &throttleEntry{timer: t.schedule(roomID, key, group)}
+		return false
+	}
+
+	entry.held = true
+	switch method {
+	case EventUserJoinedRoom:
+		entry.joined++
+	case EventUserLeftRoom:
+		entry.left++
+	default:
+		entry.method = method
+		entry.params = params
+	}
+	return true
+}
+
+// schedule ends the interval of a group in a room after the policy's interval.
+func (t *throttle) schedule(roomID, key string, group throttleGroup) *time.Timer {
+	return time.AfterFunc(t.policy.Interval, func() {
+		t.flush(roomID, key, group)
+	})
+}
+
+// flush sends what a group held back in a room during its interval and opens the next one. Groups that
+// held nothing back are dropped, so rooms that went quiet don't keep an entry.
+func (t *throttle) flush(roomID, key string, group throttleGroup) {
+	t.mu.Lock()
+	entry, exists := t.entries[key]
+	if !exists {
+		t.mu.Unlock()
+		return
+	}
+	if !entry.held {
+		delete(t.entries, key)
+		t.mu.Unlock()
+		return
+	}
+
+	method, params := entry.method, entry.params
+	joined, left := entry.joined, entry.left
+	t.entries[key] = &throttleEntry{timer: t.schedule(roomID, key, group)}
+	t.mu.Unlock()
+
+	if group == throttleMembers {
+		method = EventRoomUsersChanged
+		summary := map[string]any{
+			"roomId": roomID,
+			"joined": joined,
+			"left":   left,
+		}
+		if users, err := t.roomSize(roomID); err == nil {
+			summary["activeUsers"] = users
+		}
+		params = summary
+	}
+
+	t.server.notifyRoom(roomID, method, params)
+}
+
+// isLarge checks whether a room is above the policy's threshold. Rooms whose size can't be looked up
+// are notified as small rooms.
+func (t *throttle) isLarge(roomID string) bool {
+	users, err := t.roomSize(roomID)
+	if err != nil {
+		t.server.logger.Error("Failed to get room size for notification", err, "roomId", roomID)
+		return false
+	}
+	return users > t.policy.Threshold
+}
+
+// roomSize gets the number of users in a room.
+func (t *throttle) roomSize(roomID string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), roomSizeTimeout)
+	defer cancel()
+
+	state, err := t.sizer.GetRoomState(ctx, roomID)
+	if err != nil {
+		return 0, err
+	}
+	return state.ActiveUsers, nil
+}
@@ -0,0 +1,1435 @@
+// Package server wires the services of a Listenify node together, and serves its HTTP API and its
+// WebSocket RPC.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	mongodriver "go.mongodb.org/mongo-driver/v2/mongo"
+	"norelock.dev/listenify/backend/internal/api"
+	"norelock.dev/listenify/backend/internal/auth"
+	"norelock.dev/listenify/backend/internal/config"
+	"norelock.dev/listenify/backend/internal/db/memory"
+	"norelock.dev/listenify/backend/internal/db/mongo"
+	"norelock.dev/listenify/backend/internal/db/mongo/migrations"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/rpc"
+	"norelock.dev/listenify/backend/internal/rpc/methods"
+	"norelock.dev/listenify/backend/internal/services/developer"
+	"norelock.dev/listenify/backend/internal/services/geo"
+	"norelock.dev/listenify/backend/internal/services/media"
+	"norelock.dev/listenify/backend/internal/services/notification"
+	"norelock.dev/listenify/backend/internal/services/playlist"
+	"norelock.dev/listenify/backend/internal/services/room"
+	"norelock.dev/listenify/backend/internal/services/system"
+	"norelock.dev/listenify/backend/internal/services/user"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// CombinedAuthProvider combines JWT and password providers to implement the full auth.Provider interface
+type CombinedAuthProvider struct {
+	*auth.JWTProvider
+	*auth.PasswordProvider
+}
+
+// Server is a Listenify node: its services wired together, serving the HTTP API on the configured port
+// and the WebSocket RPC, along with its Server-Sent Events fallback, on the next one.
+type Server struct {
+	cfg    *config.Config
+	logger *utils.Logger
+
+	// ctx is the context of the background services, canceled once shutdown gets to flushing their work
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	redisClient *redis.Client
+	mongoClient *mongo.Client
+	roomRepo    repositories.RoomRepository
+	mediaRepo   repositories.MediaRepository
+
+	roomManager         *room.Manager
+	roomStateCache      *managers.RoomStateCache
+	queueManager        *room.QueueManager
+	historyRecorder     *room.HistoryRecorder
+	transitionMonitor   *room.TransitionMonitor
+	popupService        *room.PopupService
+	eventScheduler      *room.EventScheduler
+	analyticsExporter   *room.AnalyticsExporter
+	heatService         *room.HeatService
+	languageService     *room.LanguageService
+	admissionController *room.AdmissionController
+	stateHeartbeat      *room.StateHeartbeat
+	playbackSyncer      *room.PlaybackSyncer
+	toxicityModerator   *room.ToxicityModerator
+	settingsSync        *room.SettingsSync
+
+	webhookDispatcher  *developer.WebhookDispatcher
+	scrobbleService    *user.ScrobbleService
+	imageReviewService *user.ImageReviewService
+
+	maintenanceService    *system.MaintenanceService
+	healthService         *system.HealthService
+	metricsHistoryService *system.MetricsHistoryService
+	redisMemoryService    *system.RedisMemoryService
+
+	rpcServer  *rpc.Server
+	rpcCluster *rpc.Cluster
+	httpServer *http.Server
+	wsServer   *http.Server
+}
+
+// New wires the services of a node from its configuration, connecting to its databases, or keeping them
+// in memory when configured to. Its background services start with Start.
+func New(cfg *config.Config, logger *utils.Logger, traceLogs *utils.TraceLogBuffer) (_ *Server, err error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		if err != nil {
+			cancel()
+		}
+	}()
+
+	// Initialize Redis client, in process along with the in-memory repositories
+	var redisClient *redis.Client
+	if cfg.Database.UseInMemory {
+		redisClient, err = redis.NewMemoryClient(logger)
+	} else {
+		redisClient, err = redis.NewClient(cfg, logger)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	// Initialize repositories
+	var (
+		userRepo         repositories.UserRepository
+		relationshipRepo repositories.RelationshipRepository
+		roomRepo         repositories.RoomRepository
+		mediaRepo        repositories.MediaRepository
+		playlistRepo     repositories.PlaylistRepository
+		historyRepo      repositories.HistoryRepository
+		chatRepo         repositories.ChatRepository
+		reportRepo       repositories.ReportRepository
+		claimRepo        repositories.VerificationRepository
+		devRepo          repositories.DeveloperRepository
+		templateRepo     repositories.TemplateRepository
+		takedownRepo     repositories.TakedownRepository
+		incidentRepo     repositories.IncidentRepository
+		imageReviewRepo  repositories.ImageReviewRepository
+		achievementRepo  repositories.AchievementRepository
+		scrobbleRepo     repositories.ScrobbleRepository
+		eventRepo        repositories.EventRepository
+		mongoClient      *mongo.Client
+		mongoDriver      *mongodriver.Client
+		mongoDB          *mongodriver.Database
+	)
+
+	if cfg.Database.UseInMemory {
+		logger.Warn("Using in-memory repositories; data will be lost on shutdown")
+
+		memoryDB := memory.NewDatabase()
+		userRepo = memory.NewUserRepository(memoryDB, logger)
+		relationshipRepo = memory.NewRelationshipRepository(memoryDB, logger)
+		roomRepo = memory.NewRoomRepository(memoryDB, logger)
+		mediaRepo = memory.NewMediaRepository(memoryDB, logger)
+		playlistRepo = memory.NewPlaylistRepository(memoryDB, logger)
+		historyRepo = memory.NewHistoryRepository(memoryDB, logger)
+		chatRepo = memory.NewChatRepository(memoryDB, logger)
+		reportRepo = memory.NewReportRepository(memoryDB, logger)
+		claimRepo = memory.NewVerificationRepository(memoryDB, logger)
+		devRepo = memory.NewDeveloperRepository(memoryDB, logger)
+		templateRepo = memory.NewTemplateRepository(memoryDB, logger)
+		takedownRepo = memory.NewTakedownRepository(memoryDB, logger)
+		incidentRepo = memory.NewIncidentRepository(memoryDB, logger)
+		imageReviewRepo = memory.NewImageReviewRepository(memoryDB, logger)
+		achievementRepo = memory.NewAchievementRepository(memoryDB, logger)
+		scrobbleRepo = memory.NewScrobbleRepository(memoryDB, logger)
+		eventRepo = memory.NewEventRepository(memoryDB, logger)
+	} else {
+		// Initialize MongoDB client
+		mongoClient, err = mongo.NewClient(cfg, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
+		}
+
+		mongoDriver = mongoClient.Client()
+		mongoDB = mongoClient.Database()
+
+		// Migrate the database and build its indexes before anything reads or writes it
+		migrateCtx, cancelMigrate := context.WithTimeout(context.Background(), cfg.Database.MongoDB.MigrationTimeout)
+		err = migrations.NewRunner(mongoClient, cfg.Database.MongoDB.MigrationTimeout, logger).Run(migrateCtx)
+		cancelMigrate()
+		if err != nil {
+			return nil, fmt.Errorf("failed to migrate MongoDB: %w", err)
+		}
+
+		// Initialize MongoDB repositories
+		userRepo = repositories.NewUserRepository(mongoDB, logger)
+		relationshipRepo = repositories.NewRelationshipRepository(mongoDB, logger)
+		roomRepo = repositories.NewRoomRepository(mongoDB, logger)
+		mediaRepo = repositories.NewMediaRepository(mongoDB, logger)
+		playlistRepo = repositories.NewPlaylistRepository(mongoDB, logger)
+		historyRepo = repositories.NewHistoryRepository(mongoDB, logger)
+		chatRepo = repositories.NewChatRepository(mongoDB, logger)
+		reportRepo = repositories.NewReportRepository(mongoDB, logger)
+		claimRepo = repositories.NewVerificationRepository(mongoDB, logger)
+		devRepo = repositories.NewDeveloperRepository(mongoDB, logger)
+		templateRepo = repositories.NewTemplateRepository(mongoDB, logger)
+		takedownRepo = repositories.NewTakedownRepository(mongoDB, logger)
+		incidentRepo = repositories.NewIncidentRepository(mongoDB, logger)
+		imageReviewRepo = repositories.NewImageReviewRepository(mongoDB, logger)
+		achievementRepo = repositories.NewAchievementRepository(mongoDB, logger)
+		scrobbleRepo = repositories.NewScrobbleRepository(mongoDB, logger)
+		eventRepo = repositories.NewEventRepository(mongoDB, logger)
+	}
+
+	// Initialize Redis managers
+	sessionMgr := managers.NewSessionManager(redisClient, cfg.Auth.AccessTokenExpiry)
+	presenceMgr := managers.NewPresenceManager(redisClient)
+	roomStateMgr := managers.NewRoomStateManager(redisClient)
+	listenerGeoMgr := managers.NewListenerGeoManager(redisClient)
+
+	// Initialize PubSub manager
+	pubSubManager := managers.NewPubSubManager(redisClient, managers.DeadLetterPolicy{
+		MaxEntries: cfg.System.DeadLetterMaxEntries,
+		Retention:  cfg.System.DeadLetterRetention,
+	})
+
+	// Keep room states in memory for frequent reads, dropped on every node when they are written
+	roomStateCache := managers.NewRoomStateCache(roomStateMgr, pubSubManager, cfg.Room.StateCacheTTL)
+
+	// Initialize authentication provider
+	jwtConfig := auth.JWTConfig{
+		Secret:               cfg.Auth.JWTSecret,
+		Issuer:               "listenify",
+		Audience:             "listenify-users",
+		AccessTokenDuration:  cfg.Auth.AccessTokenExpiry,
+		RefreshTokenDuration: cfg.Auth.RefreshTokenExpiry,
+	}
+	jwtProvider := auth.NewJWTProvider(jwtConfig, logger)
+	passwordProvider := auth.NewPasswordProvider(logger)
+	authProvider := &CombinedAuthProvider{
+		JWTProvider:      jwtProvider,
+		PasswordProvider: passwordProvider,
+	}
+
+	// Initialize services
+	usernameBlockedTerms := []string{}
+	if cfg.Features.EnableProfanityFilter {
+		usernameBlockedTerms = cfg.Auth.UsernameBlockedTerms
+		if len(usernameBlockedTerms) == 0 {
+			usernameBlockedTerms = cfg.Room.ToxicityBlockedTerms
+		}
+	}
+	userManager := user.NewManager(userRepo, *sessionMgr, *presenceMgr, authProvider, user.UsernamePolicy{
+		MinLength:      cfg.Auth.UsernameMinLength,
+		MaxLength:      cfg.Auth.UsernameMaxLength,
+		Reserved:       cfg.Auth.UsernameReserved,
+		BlockedTerms:   usernameBlockedTerms,
+		RenameCooldown: cfg.Auth.UsernameRenameCooldown,
+		HistorySize:    cfg.Auth.UsernameHistorySize,
+	}, logger)
+
+	// Initialize trust service for gating features by account standing
+	trustService := user.NewTrustService(cfg, userManager, logger)
+
+	// Initialize captcha verification of signups, password resets and reports
+	captchaVerifier, err := user.NewCaptchaVerifier(cfg.Captcha.Provider, cfg.Captcha.Secret, cfg.Captcha.SiteKey, cfg.Captcha.VerifyURL, cfg.Captcha.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize captcha verifier: %w", err)
+	}
+	captchaActions := make(map[user.CaptchaAction]user.CaptchaActionPolicy, len(cfg.Captcha.Actions))
+	for name, action := range cfg.Captcha.Actions {
+		captchaActions[user.CaptchaAction(name)] = user.CaptchaActionPolicy{
+			Mode:       user.CaptchaMode(action.Mode),
+			RiskyAfter: action.RiskyAfter,
+			FailOpen:   action.FailOpen,
+		}
+	}
+	captchaService := user.NewCaptchaService(captchaVerifier, userManager, trustService, redisClient, user.CaptchaPolicy{
+		Provider:      cfg.Captcha.Provider,
+		SiteKey:       cfg.Captcha.SiteKey,
+		AttemptWindow: cfg.Captcha.AttemptWindow,
+		Actions:       captchaActions,
+	}, logger)
+
+	// Initialize social service for following users
+	socialService := user.NewSocialService(userManager, relationshipRepo, redisClient, logger)
+
+	// Initialize media services
+	providers := make(map[string]media.Provider)
+	youtubeProvider := media.NewYouTubeProvider(cfg.Media.YouTubeAPIKey, logger)
+	providers["youtube"] = youtubeProvider
+
+	if cfg.Features.EnableSoundCloud {
+		soundcloudProvider := media.NewSoundCloudProvider(cfg.Media.SoundCloudAPIKey, logger)
+		providers["soundcloud"] = soundcloudProvider
+	}
+
+	// Initialize media search service and use it to register providers with resolver
+	_ = media.NewSearchService(providers, logger)
+	mediaResolver := media.NewResolver(mediaRepo, takedownRepo, logger)
+
+	// Register providers with mediaResolver
+	for _, provider := range providers {
+		mediaResolver.RegisterProvider(provider)
+	}
+
+	// Route searches through the cache and the providers' search budgets
+	searchBudgetLocation, err := time.LoadLocation(cfg.Media.SearchBudgetTimezone)
+	if err != nil {
+		logger.Error("Invalid search budget time zone, using UTC", err, "timezone", cfg.Media.SearchBudgetTimezone)
+		searchBudgetLocation = time.UTC
+	}
+	searchBudgets := make(map[string]media.SearchBudget, len(cfg.Media.SearchBudgets))
+	for providerType, budget := range cfg.Media.SearchBudgets {
+		searchBudgets[providerType] = media.SearchBudget{Cost: budget.Cost, Daily: budget.Daily}
+	}
+	mediaResolver.SetSearchRouter(media.NewSearchRouter(redisClient, media.SearchRoutingPolicy{
+		CacheTTL: cfg.Media.SearchCacheTTL,
+		Budgets:  searchBudgets,
+		Location: searchBudgetLocation,
+	}, logger))
+
+	// Preview search results from the providers whose terms permit it
+	previewService := media.NewPreviewService(mediaResolver, redisClient, media.PreviewPolicy{
+		Enabled:    cfg.Media.PreviewsEnabled,
+		Providers:  cfg.Media.PreviewProviders,
+		Duration:   cfg.Media.PreviewDuration,
+		Bitrate:    cfg.Media.PreviewBitrate,
+		CacheTTL:   cfg.Media.PreviewCacheTTL,
+		CacheSize:  int64(cfg.Media.PreviewCacheSize) * 1024 * 1024,
+		RateLimit:  cfg.Media.PreviewRateLimit,
+		RateWindow: cfg.Media.PreviewRateWindow,
+	}, logger)
+
+	// Initialize playlist services
+	playlistManager := playlist.NewManager(playlistRepo, mediaRepo, mediaResolver, logger)
+
+	// Initialize room services, summarizing state for oversized rooms
+	largeRoomPolicy := room.LargeRoomPolicy{
+		Threshold:  cfg.Room.LargeRoomThreshold,
+		RosterSize: cfg.Room.LargeRoomRosterSize,
+	}
+	popupPolicy := room.PopupPolicy{
+		MaxLifetime:    cfg.Room.PopupMaxLifetime,
+		ReminderBefore: cfg.Room.PopupReminderBefore,
+		CheckInterval:  cfg.Room.PopupCheckInterval,
+	}
+	minorBlockedTerms := cfg.Minors.BlockedTerms
+	if len(minorBlockedTerms) == 0 {
+		minorBlockedTerms = cfg.Room.ToxicityBlockedTerms
+	}
+	minorPolicy := room.MinorPolicy{
+		Enabled:             cfg.Minors.Enabled,
+		FilterChat:          cfg.Minors.FilterChat,
+		BlockedTerms:        minorBlockedTerms,
+		DisableLinks:        cfg.Minors.DisableLinks,
+		HideRestrictedRooms: cfg.Minors.HideRestrictedRooms,
+	}
+	lobbyCache := room.NewLobbyCache(redisClient, room.LobbyCachePolicy{
+		FreshFor: cfg.Room.LobbyCacheFresh,
+		StaleFor: cfg.Room.LobbyCacheStale,
+	}, logger)
+	roomManager := room.NewManager(roomRepo, userRepo, *roomStateMgr, roomStateCache, *presenceMgr, trustService, largeRoomPolicy, popupPolicy, minorPolicy, lobbyCache, logger)
+	roomStateMgr.SetStateLoader(roomManager.RebuildRoomState)
+	roomStateMgr.SetQueueLoader(roomManager.LoadDJQueue)
+
+	// Initialize queue manager
+	normalizationPolicy := room.NormalizationPolicy{
+		TargetLUFS: cfg.Media.LoudnessTarget,
+		MaxGain:    cfg.Media.MaxNormalizationGain,
+	}
+	historyRecorder := room.NewHistoryRecorder(historyRepo, roomStateMgr, logger)
+	setPlanner := room.NewSetPlanner(redisClient, playlistManager, room.SetPlanPolicy{
+		MaxTracks: cfg.Room.SetPlanMaxTracks,
+		TTL:       cfg.Room.SetPlanTTL,
+	}, logger)
+	transitionMonitor := room.NewTransitionMonitor(roomManager, cfg.Room.TransitionSlowAfter, cfg.Room.TransitionLateAfter, cfg.Room.TransitionMissedAfter, logger)
+	queueManager := room.NewQueueManager(roomManager, playlistManager, mediaRepo, trustService, normalizationPolicy, historyRecorder, setPlanner, transitionMonitor, logger)
+	vibeService := room.NewVibeService(mediaRepo, playlistManager, historyRecorder, logger)
+	rotationReporter := room.NewRotationReporter(roomManager, historyRepo, redisClient, cfg.Room.RotationReportCacheTTL, logger)
+
+	// Import play history of communities moving from other platforms
+	historyImporter := room.NewHistoryImporter(historyRepo, mediaRepo, userRepo, roomRepo, mediaResolver, redisClient, room.HistoryImportPolicy{
+		MaxAge: cfg.Room.HistoryImportMaxAge,
+	}, logger)
+
+	// Stream play history exports, limiting how many each user downloads at once
+	historyExportService := user.NewHistoryExportService(historyRepo, redisClient, user.HistoryExportPolicy{
+		MaxConcurrent: cfg.Room.HistoryExportMaxConcurrent,
+		BatchSize:     cfg.Room.HistoryExportBatchSize,
+		MaxDuration:   cfg.Room.HistoryExportMaxDuration,
+	}, logger)
+
+	// Export users' data archives through single-use links, sharing the history export limits
+	dataExportService := user.NewDataExportService(userManager, playlistRepo, historyRepo, redisClient, user.HistoryExportPolicy{
+		MaxConcurrent: cfg.Room.HistoryExportMaxConcurrent,
+		BatchSize:     cfg.Room.HistoryExportBatchSize,
+		MaxDuration:   cfg.Room.HistoryExportMaxDuration,
+	}, cfg.Auth.DataExportLinkExpiry, logger)
+
+	// Move old history out of MongoDB into archive files
+	historyArchiveService := system.NewHistoryArchiveService(
+		mongoDB,
+		system.NewFileArchiveStore(cfg.System.HistoryArchiveDir),
+		system.HistoryArchiveConfig{
+			After:     cfg.System.HistoryArchiveAfter,
+			BatchSize: cfg.System.HistoryArchiveBatch,
+		},
+		logger,
+	)
+	// Delete accounts on their users' request after a grace period
+	accountDeletionService := user.NewAccountDeletionService(userManager, playlistRepo, historyRepo, chatRepo, scrobbleRepo, devRepo, achievementRepo, roomRepo, imageReviewRepo, claimRepo, historyArchiveService, user.AccountDeletionPolicy{
+		GracePeriod: cfg.Auth.DeletionGracePeriod,
+		BatchSize:   cfg.Auth.DeletionBatchSize,
+	}, logger)
+
+	// Propagate room settings changes to every node
+	settingsSync := room.NewSettingsSync(pubSubManager, logger)
+	roomManager.AddSettingsChangeHandler(settingsSync.Publish)
+
+	// Export room activity to owners who opted in to analytics
+	analyticsExporter := room.NewAnalyticsExporter(roomManager, redisClient, room.AnalyticsPolicy{
+		FlushInterval: cfg.Room.AnalyticsFlushInterval,
+		Retention:     cfg.Room.AnalyticsRetention,
+	}, logger)
+	roomManager.AddActivityHandler(analyticsExporter.Record)
+	historyRecorder.AddActivityHandler(analyticsExporter.Record)
+
+	// Deliver platform events to the webhooks of developer applications
+	webhookDispatcher := developer.NewWebhookDispatcher(devRepo, roomManager, redisClient, developer.WebhookPolicy{
+		PollInterval: cfg.Developer.WebhookPollInterval,
+		Timeout:      cfg.Developer.WebhookTimeout,
+		MaxAttempts:  cfg.Developer.WebhookMaxAttempts,
+		RetryBackoff: cfg.Developer.WebhookRetryBackoff,
+		MaxBackoff:   cfg.Developer.WebhookMaxBackoff,
+		Workers:      cfg.Developer.WebhookWorkers,
+	}, logger)
+	userManager.AddCreatedHandler(webhookDispatcher.UserCreated)
+	roomManager.AddCreatedHandler(webhookDispatcher.RoomCreated)
+	historyRecorder.AddActivityHandler(webhookDispatcher.PlayRecorded)
+	developerAppService := developer.NewAppService(devRepo, webhookDispatcher, cfg.Developer.MaxApps, logger)
+
+	// Tell followers when the users they follow start DJing
+	historyRecorder.AddActivityHandler(func(ctx context.Context, activity room.RoomActivity) {
+		if activity.Type == room.ActivityPlay && !activity.UserID.IsZero() {
+			socialService.DJStarted(ctx, activity.UserID, activity.RoomID)
+		}
+	})
+
+	// Initialize pop-up room expiry
+	popupService := room.NewPopupService(roomManager, pubSubManager, popupPolicy, logger)
+
+	// Initialize chat service
+	chatService := room.NewChatService(roomManager, chatRepo, userRepo, pubSubManager, redisClient, trustService, cfg.Room.MaxPinnedMessages, cfg.Features.EnableChatCommands, logger)
+	chatService.RegisterCommand(room.NewSkipCommand(queueManager))
+
+	// Score how lively rooms are for discovery
+	heatService := room.NewHeatService(roomRepo, roomStateMgr, redisClient, room.HeatPolicy{
+		Interval: cfg.Room.HeatInterval,
+	}, logger)
+	roomManager.AddActivityHandler(heatService.RecordActivity)
+	chatService.AddMessageHandler(heatService.RecordMessage)
+
+	// Detect the chat language of rooms so users can find rooms in their language
+	languageService := room.NewLanguageService(roomRepo, redisClient, room.LanguagePolicy{
+		Interval:    cfg.Room.LanguageInterval,
+		MinMessages: cfg.Room.LanguageMinMessages,
+	}, logger)
+	chatService.AddMessageHandler(languageService.RecordMessage)
+
+	// Score chat messages for automated moderation
+	var toxicityClassifier room.ToxicityClassifier
+	switch cfg.Room.ToxicityClassifier {
+	case "wordlist":
+		toxicityClassifier = room.NewWordListClassifier(cfg.Room.ToxicityBlockedTerms)
+	case "http":
+		toxicityClassifier = room.NewHTTPToxicityClassifier(cfg.Room.ToxicityClassifierURL, cfg.Room.ToxicityClassifierKey)
+	}
+	toxicityModerator := room.NewToxicityModerator(roomManager, chatService, chatRepo, historyRepo, pubSubManager, toxicityClassifier, room.ToxicityPolicy{
+		FlagThreshold: cfg.Room.ToxicityFlagThreshold,
+		MaskThreshold: cfg.Room.ToxicityMaskThreshold,
+		Workers:       cfg.Room.ToxicityWorkers,
+	}, logger)
+	chatService.AddMessageHandler(toxicityModerator.ScoreMessage)
+
+	// Let moderators kick, mute, ban and delete messages with a short window to undo
+	undoableModeration := room.NewUndoableModeration(roomManager, chatService, chatRepo, historyRepo, pubSubManager, redisClient, cfg.Room.ModerationUndoWindow, logger)
+	chatService.RegisterCommand(room.NewBanCommand(undoableModeration, userRepo))
+
+	// Initialize room reports, triaged by platform admins
+	reportService := room.NewRoomReportService(roomManager, reportRepo, pubSubManager, logger)
+
+	// Initialize artist and label verification, reviewed by platform admins
+	verificationService := room.NewVerificationService(roomManager, claimRepo, mediaRepo, userRepo, logger)
+
+	// Initialize media takedowns after content complaints, issued by platform admins
+	takedownService := media.NewTakedownService(takedownRepo, mediaRepo, playlistRepo, logger)
+
+	// Initialize outbound email, rendered from versioned and localized templates
+	var emailSender notification.Sender = notification.NewLogSender(logger)
+	if cfg.Email.SMTPHost != "" {
+		emailSender, err = notification.NewSMTPSender(cfg.Email.SMTPHost, cfg.Email.SMTPPort, cfg.Email.SMTPUsername, cfg.Email.SMTPPassword, cfg.Email.From)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize SMTP sender: %w", err)
+		}
+	}
+	templateService := notification.NewTemplateService(templateRepo, emailSender, cfg.Email.TemplatesDir, cfg.Email.DefaultLocale, cfg.Email.TemplateCacheTTL, logger)
+
+	// Initialize GeoIP database for listener geo attribution
+	geoDatabase, err := geo.NewDatabase(cfg.Room.GeoIPDatabase, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize experience, levels and achievements, fed by the user stats service
+	gamificationService := user.NewGamificationService(userManager, achievementRepo, logger)
+	if err := gamificationService.SeedAchievements(ctx); err != nil {
+		logger.Error("Failed to create default achievements", err)
+	}
+
+	// Initialize user stats service
+	statsService := user.NewStatsService(userManager, historyRepo, gamificationService, roomStateMgr, logger)
+	historyRecorder.AddEndHandler(statsService.TrackPlay)
+	historyRecorder.AddEndHandler(statsService.TrackListeners)
+	chatService.AddMessageHandler(statsService.TrackChatMessage)
+
+	// Scrobble the plays users listened to to their linked Last.fm compatible accounts
+	scrobbleService := user.NewScrobbleService(scrobbleRepo, roomStateMgr, redisClient, user.ScrobblePolicy{
+		APIURL:       cfg.Scrobbling.APIURL,
+		AuthURL:      cfg.Scrobbling.AuthURL,
+		APIKey:       cfg.Scrobbling.APIKey,
+		APISecret:    cfg.Scrobbling.APISecret,
+		CallbackURL:  cfg.Scrobbling.CallbackURL,
+		PollInterval: cfg.Scrobbling.PollInterval,
+		Timeout:      cfg.Scrobbling.Timeout,
+		MaxAttempts:  cfg.Scrobbling.MaxAttempts,
+		RetryBackoff: cfg.Scrobbling.RetryBackoff,
+		MaxBackoff:   cfg.Scrobbling.MaxBackoff,
+		Workers:      cfg.Scrobbling.Workers,
+	}, logger)
+	historyRecorder.AddEndHandler(scrobbleService.PlayEnded)
+
+	// Initialize personal API keys
+	apiKeyService := user.NewAPIKeyService(userManager, redisClient, cfg.Auth.MaxAPIKeys, cfg.Auth.APIKeyRateLimit, logger)
+
+	// Initialize the review of changed avatars and playlist covers, matched against known-bad images
+	imageReviewService := user.NewImageReviewService(userManager, imageReviewRepo, playlistRepo, user.ImageReviewPolicy{
+		CheckInterval: cfg.System.ImageReviewInterval,
+		CheckBatch:    cfg.System.ImageReviewBatch,
+		MatchDistance: cfg.System.ImageMatchDistance,
+		MaxImageSize:  cfg.System.ImageMaxSize,
+	}, logger)
+	userManager.AddAvatarChangedHandler(func(ctx context.Context, changed *models.User) {
+		if err := imageReviewService.Submit(ctx, models.ImageKindAvatar, changed.ID, changed.ID, changed.AvatarConfig.CustomImage); err != nil {
+			logger.Error("Failed to queue avatar for review", err, "userId", changed.ID.Hex())
+		}
+	})
+	playlistManager.AddCoverChangedHandler(func(ctx context.Context, changed *models.Playlist) {
+		if err := imageReviewService.Submit(ctx, models.ImageKindPlaylistCover, changed.Owner, changed.ID, changed.CoverImage); err != nil {
+			logger.Error("Failed to queue playlist cover for review", err, "playlistId", changed.ID.Hex())
+		}
+	})
+
+	// Initialize account recovery tools for support
+	recoveryService := user.NewRecoveryService(userManager, playlistRepo, historyRepo, redisClient, emailSender, cfg.Auth.PasswordResetExpiry, logger)
+
+	// Initialize guest sessions, which keep their place in their room when they register
+	guestService := user.NewGuestService(userManager, historyRepo, user.GuestPolicy{
+		Enabled:         cfg.Auth.GuestsEnabled,
+		HistoryTransfer: cfg.Auth.GuestHistoryTransfer,
+	}, logger)
+	guestService.AddLinkHandler(func(ctx context.Context, guest, registered *models.User) error {
+		return roomManager.TransferMember(ctx, guest.ID, registered.ID)
+	})
+
+	// Let users sign in with their Google or Discord accounts
+	oauthClients := make(map[string]auth.OAuthClientConfig, len(cfg.Auth.OAuthProviders))
+	for provider, client := range cfg.Auth.OAuthProviders {
+		oauthClients[provider] = auth.OAuthClientConfig{
+			ClientID:     client.ClientID,
+			ClientSecret: client.ClientSecret,
+			RedirectURL:  client.RedirectURL,
+		}
+	}
+	oauthProvider := auth.NewOAuthProvider(oauthClients, logger)
+	oauthService := user.NewOAuthService(userManager, oauthProvider, redisClient, cfg.Auth.OAuthStateExpiry, logger)
+
+	// Initialize system services
+	healthConfig := system.HealthServiceConfig{
+		Version:     "1.0.0",
+		Environment: cfg.Environment,
+	}
+	healthService := system.NewHealthService(mongoDriver, redisClient, logger, healthConfig)
+
+	// Feed the public status page, opening incidents for components failing their health checks
+	statusService := system.NewStatusService(healthService, incidentRepo, redisClient, system.StatusPolicy{
+		IncidentAfterFailures: cfg.System.StatusIncidentAfterFailures,
+		UptimeDays:            cfg.System.StatusUptimeDays,
+		CacheTTL:              cfg.System.StatusCacheTTL,
+	}, logger)
+	healthService.AddCheckHandler(statusService.RecordCheck)
+
+	// Serve the platform state snapshot read by the analytics pipeline
+	snapshotService := system.NewSnapshotService(roomRepo, roomStateMgr, redisClient, system.SnapshotPolicy{
+		Tokens:    cfg.System.SnapshotTokens,
+		RateLimit: cfg.System.SnapshotRateLimit,
+		CacheTTL:  cfg.System.SnapshotCacheTTL,
+	}, logger)
+
+	// Initialize maintenance service
+	maintenanceConfig := system.DefaultMaintenanceConfig()
+	maintenanceConfig.QuietHours, err = system.ParseQuietHours(cfg.System.QuietHours)
+	if err != nil {
+		return nil, fmt.Errorf("invalid maintenance quiet hours: %w", err)
+	}
+	maintenanceConfig.QuietHoursLocation, err = time.LoadLocation(cfg.System.QuietHoursTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid maintenance quiet hours time zone %q: %w", cfg.System.QuietHoursTimezone, err)
+	}
+	maintenanceConfig.HistoryArchived = cfg.System.HistoryArchiveDir != "" && mongoDB != nil
+	maintenanceService := system.NewMaintenanceService(
+		maintenanceConfig,
+		mongoDB,
+		redisClient,
+		roomRepo,
+		historyRepo,
+		mediaRepo,
+		playlistRepo,
+		userRepo,
+		logger,
+	)
+
+	// Link copies of the same song from different providers
+	maintenanceService.RegisterTask("media_dedupe", system.TaskClassBackfill, 24*time.Hour, func(ctx context.Context) error {
+		_, err := mediaResolver.DedupeMedia(ctx)
+		return err
+	})
+
+	// Measure the loudness of media whose provider doesn't expose it
+	if cfg.Media.LoudnessAnalyzerURL != "" {
+		analyzer := media.NewHTTPLoudnessAnalyzer(cfg.Media.LoudnessAnalyzerURL)
+		loudnessWorker := media.NewLoudnessWorker(mediaRepo, mediaResolver, analyzer, cfg.Media.LoudnessAnalysisBatch, logger)
+		maintenanceService.RegisterTask("loudness_analysis", system.TaskClassBackfill, cfg.Media.LoudnessAnalysisInterval, loudnessWorker.AnalyzeBatch)
+	}
+
+	// Attach lyrics to media, so clients can offer a lyrics view during playback
+	var lyricsService *media.LyricsService
+	if cfg.Media.LyricsProviderURL != "" {
+		lyricsProvider := media.NewLRCLibProvider(cfg.Media.LyricsProviderURL)
+		lyricsService = media.NewLyricsService(mediaRepo, redisClient, lyricsProvider, cfg.Media.LyricsCacheTTL, cfg.Media.LyricsLookupBatch, logger)
+		maintenanceService.RegisterTask("lyrics_lookup", system.TaskClassBackfill, cfg.Media.LyricsLookupInterval, lyricsService.LookupBatch)
+	}
+
+	if maintenanceConfig.HistoryArchived {
+		maintenanceService.RegisterTask("history_archive", system.TaskClassCleanup, 24*time.Hour, historyArchiveService.ArchiveHistory)
+	}
+
+	// Remove purged accounts from the archive files, which may predate turning archiving off
+	maintenanceService.RegisterTask("history_archive_redaction", system.TaskClassCleanup, maintenanceConfig.MaintenanceInterval, historyArchiveService.RedactArchives)
+
+	// Drop webhook delivery attempts developers no longer need to see
+	maintenanceService.RegisterTask("webhook_delivery_prune", system.TaskClassCleanup, 24*time.Hour, func(ctx context.Context) error {
+		return developerAppService.PruneDeliveries(ctx, cfg.Developer.DeliveryLogRetention)
+	})
+
+	// Drop scrobble log entries users no longer need to see
+	maintenanceService.RegisterTask("scrobble_log_prune", system.TaskClassCleanup, 24*time.Hour, func(ctx context.Context) error {
+		return scrobbleService.PruneScrobbles(ctx, cfg.Scrobbling.LogRetention)
+	})
+
+	// Purge deleted accounts whose grace period ended
+	maintenanceService.RegisterTask("account_purge", system.TaskClassCleanup, maintenanceConfig.MaintenanceInterval, accountDeletionService.PurgeDueAccounts)
+
+	// Initialize RPC router for WebSocket
+	rpcRouter := rpc.NewRouter(logger)
+
+	// Initialize RPC server
+	rpcServer := rpc.NewServer(
+		rpcRouter,
+		authProvider,
+		*sessionMgr,
+		*presenceMgr,
+		geoDatabase,
+		rpc.BroadcastPolicy{
+			Shards:  cfg.WebSocket.BroadcastShards,
+			Backlog: cfg.WebSocket.BroadcastBacklog,
+		},
+		logger,
+	)
+
+	// Reach the clients connected to the other nodes, and register this node's connections in Redis
+	rpcCluster := rpc.NewCluster(rpcServer, pubSubManager, redisClient, rpc.ClusterPolicy{
+		NodeID:    cfg.Server.NodeID,
+		Heartbeat: cfg.Server.NodeHeartbeat,
+		Region:    cfg.Server.Region,
+		Endpoint:  cfg.Server.RegionEndpoint,
+	}, logger)
+
+	// Let clients that lose their connection resume their session on any node, with what they missed replayed
+	rpc.NewResumer(rpcServer, redisClient, roomManager, rpc.ResumePolicy{
+		Window: cfg.WebSocket.ResumeWindow,
+		Buffer: cfg.WebSocket.ResumeBuffer,
+	}, logger)
+
+	// Spread out clients reconnecting all at once, such as after a deploy, and their reconnects once disconnected
+	rpcServer.SetAdmissionPolicy(rpc.AdmissionPolicy{
+		Rate:            cfg.WebSocket.AdmissionRate,
+		Burst:           cfg.WebSocket.AdmissionBurst,
+		QueueSize:       cfg.WebSocket.AdmissionQueue,
+		QueueTimeout:    cfg.WebSocket.AdmissionQueueTimeout,
+		ReconnectJitter: cfg.WebSocket.ReconnectJitter,
+	})
+
+	// Coalesce the membership and queue notifications of oversized rooms
+	rpcServer.SetThrottlePolicy(rpc.ThrottlePolicy{
+		Threshold: cfg.Room.LargeRoomThreshold,
+		Interval:  cfg.Room.LargeRoomEventInterval,
+	}, roomStateMgr)
+
+	// Keep room memberships in agreement across MongoDB, Redis and live connections
+	membershipReconciler := room.NewMembershipReconciler(
+		roomManager,
+		roomRepo,
+		roomStateMgr,
+		presenceMgr,
+		redisClient,
+		rpcServer,
+		room.MembershipPolicy{
+			Interval: cfg.Room.MembershipReconcileInterval,
+			Workers:  cfg.Room.MembershipReconcileWorkers,
+			Grace:    cfg.Room.MembershipGrace,
+		},
+		logger,
+	)
+	if cfg.Room.MembershipReconcileInterval > 0 {
+		maintenanceService.RegisterTask("membership_reconcile", system.TaskClassLight, cfg.Room.MembershipReconcileInterval, membershipReconciler.Reconcile)
+	}
+
+	// Reduce room capacities and queue joins while this node is under load, to keep playback in sync
+	admissionController := room.NewAdmissionController(rpcServer, redisClient, room.AdmissionPolicy{
+		Interval:            cfg.Room.AdmissionInterval,
+		MaxConnections:      cfg.Room.AdmissionMaxConnections,
+		MaxBroadcastLatency: cfg.Room.AdmissionMaxBroadcastLatency,
+		CapacityFactor:      cfg.Room.AdmissionCapacityFactor,
+		QueueTimeout:        cfg.Room.AdmissionQueueTimeout,
+	}, logger)
+	roomManager.SetAdmissionController(admissionController)
+	roomManager.SetRejoinStateTTL(cfg.WebSocket.RejoinStateTTL)
+
+	// Refresh the Redis state of rooms with live connections to this node
+	stateHeartbeat := room.NewStateHeartbeat(roomStateMgr, rpcServer, cfg.Room.StateHeartbeatInterval, logger)
+
+	// Tell rooms with live connections to this node where their current media is, so clients can correct drift
+	playbackSyncer := room.NewPlaybackSyncer(roomStateMgr, rpcServer, cfg.Room.PlaybackSyncInterval, logger)
+
+	// Initialize metrics history for capacity planning
+	metricsHistoryService := system.NewMetricsHistoryService(
+		mongoDB,
+		redisClient,
+		roomRepo,
+		rpcServer,
+		rpcServer,
+		rpcRouter,
+		admissionController,
+		transitionMonitor,
+		cfg.System.MetricsHistoryInterval,
+		cfg.System.MetricsHistoryRetention,
+		logger,
+	)
+
+	// Keep Redis under its memory limit before it starts evicting sessions
+	redisMemoryService := system.NewRedisMemoryService(redisClient, system.RedisMemoryPolicy{
+		Interval:         cfg.System.RedisMemoryInterval,
+		TrimThreshold:    cfg.System.RedisMemoryTrimThreshold,
+		SampleSize:       cfg.System.RedisMemorySampleSize,
+		VoteMediaPerRoom: cfg.System.RedisVoteMediaPerRoom,
+	}, logger)
+
+	// Initialize calendar service for room event feeds
+	calendarService := room.NewCalendarService(
+		roomManager,
+		userRepo,
+		eventRepo,
+		redisClient,
+		cfg.Auth.JWTSecret,
+		cfg.Room.CalendarCacheTTL,
+		logger,
+	)
+
+	// Remind rooms of their scheduled events and activate them when the events start
+	eventScheduler := room.NewEventScheduler(roomManager, eventRepo, pubSubManager, redisClient, room.EventPolicy{
+		ReminderBefore: cfg.Room.EventReminderBefore,
+		CheckInterval:  cfg.Room.EventCheckInterval,
+	}, logger)
+
+	// Initialize API router
+	router := api.NewRouter(
+		authProvider,
+		*sessionMgr,
+		userManager,
+		guestService,
+		oauthService,
+		trustService,
+		socialService,
+		statsService,
+		apiKeyService,
+		recoveryService,
+		pubSubManager,
+		playlistManager,
+		roomManager,
+		calendarService,
+		reportService,
+		verificationService,
+		takedownService,
+		imageReviewService,
+		membershipReconciler,
+		analyticsExporter,
+		developerAppService,
+		historyImporter,
+		historyExportService,
+		captchaService,
+		accountDeletionService,
+		dataExportService,
+		mediaResolver,
+		previewService,
+		healthService,
+		statusService,
+		snapshotService,
+		metricsHistoryService,
+		transitionMonitor,
+		historyArchiveService,
+		redisMemoryService,
+		templateService,
+		traceLogs,
+		cfg,
+		logger,
+	)
+
+	// Tell DJs when their turn is skipped because they have nothing to play
+	queueManager.AddTurnSkipHandler(func(ctx context.Context, roomID, userID bson.ObjectID, reason error) {
+		rpcServer.NotifyUser(userID.Hex(), "queue.turnSkipped", map[string]any{
+			"roomId": roomID.Hex(),
+			"reason": reason.Error(),
+		})
+	})
+
+	// Tell overflow listeners when they become full participants
+	roomManager.AddPromotionHandler(func(ctx context.Context, roomID, userID bson.ObjectID) {
+		chatShard := roomManager.ChatShardOf(ctx, roomID, userID)
+		rpcServer.SetChatShard(userID.Hex(), roomID.Hex(), chatShard)
+		rpcServer.NotifyUser(userID.Hex(), "room.listenerPromoted", map[string]any{
+			"roomId":       roomID.Hex(),
+			"listenerOnly": false,
+			"chatShard":    chatShard,
+		})
+	})
+
+	// Tell reporters what came of their room reports
+	reportService.AddResolutionHandler(func(ctx context.Context, report *models.RoomReport) {
+		rpcServer.NotifyUser(report.ReporterID.Hex(), "room.reportResolved", map[string]any{
+			"reportId": report.ID.Hex(),
+			"roomId":   report.RoomID.Hex(),
+			"status":   report.Status,
+			"action":   report.Action,
+			"note":     report.Note,
+		})
+	})
+
+	// Tell users when their avatar or playlist cover is removed
+	imageReviewService.AddRemovalHandler(func(ctx context.Context, review *models.ImageReview) {
+		rpcServer.NotifyUser(review.OwnerID.Hex(), "user.imageRemoved", map[string]any{
+			"reviewId": review.ID.Hex(),
+			"kind":     review.Kind,
+			"targetId": review.TargetID.Hex(),
+			"reason":   review.Reason,
+		})
+	})
+
+	// Tell rooms when their users level up, and users when they unlock achievements
+	gamificationService.AddLevelUpHandler(func(ctx context.Context, levelUp models.LevelUp) {
+		params := map[string]any{
+			"userId":   levelUp.UserID.Hex(),
+			"oldLevel": levelUp.OldLevel,
+			"newLevel": levelUp.NewLevel,
+		}
+		if levelUp.RoomID.IsZero() {
+			rpcServer.NotifyUser(levelUp.UserID.Hex(), "user.leveledUp", params)
+			return
+		}
+		params["roomId"] = levelUp.RoomID.Hex()
+		rpcServer.NotifyRoom(levelUp.RoomID.Hex(), "user.leveledUp", params)
+	})
+	gamificationService.AddUnlockHandler(func(ctx context.Context, unlocked *models.UserAchievement, achievement *models.Achievement) {
+		rpcServer.NotifyUser(unlocked.UserID.Hex(), "user.achievementUnlocked", map[string]any{
+			"achievement": achievement,
+			"unlockedAt":  unlocked.UnlockedAt,
+		})
+	})
+
+	// Tell claimants what came of their verification claims
+	verificationService.AddReviewHandler(func(ctx context.Context, claim *models.VerificationClaim) {
+		rpcServer.NotifyUser(claim.ClaimantID.Hex(), "verification.claimReviewed", map[string]any{
+			"claimId":    claim.ID.Hex(),
+			"targetType": claim.TargetType,
+			"targetId":   claim.TargetID.Hex(),
+			"status":     claim.Status,
+			"note":       claim.Note,
+		})
+	})
+
+	// Tell playlist owners which of their items were taken down
+	takedownService.AddOwnerHandler(func(ctx context.Context, items *models.TakenDownItems) {
+		itemIDs := make([]string, len(items.ItemIDs))
+		for i, id := range items.ItemIDs {
+			itemIDs[i] = id.Hex()
+		}
+		rpcServer.NotifyUser(items.OwnerID.Hex(), "playlist.itemsTakenDown", map[string]any{
+			"playlistId":   items.PlaylistID.Hex(),
+			"playlistName": items.PlaylistName,
+			"itemIds":      itemIDs,
+			"takedownId":   items.Takedown.ID.Hex(),
+			"title":        items.Takedown.Title,
+			"reason":       items.Takedown.Reason,
+		})
+	})
+
+	// Tell followers a user they follow started DJing
+	socialService.AddFollowedDJHandler(func(ctx context.Context, followerID, djID, roomID bson.ObjectID) {
+		rpcServer.NotifyUser(followerID.Hex(), "user.followedDJStarted", map[string]any{
+			"djId":   djID.Hex(),
+			"roomId": roomID.Hex(),
+		})
+	})
+
+	// Tell users when they are kicked or muted, and when that is undone
+	undoableModeration.AddActionHandler(func(ctx context.Context, entry *models.ModerationHistory) {
+		switch entry.Action {
+		case "kick":
+			rpcServer.UnsubscribeUser(entry.RoomID.Hex(), entry.TargetUserID.Hex())
+			rpcServer.NotifyUser(entry.TargetUserID.Hex(), "moderation.kicked", map[string]any{
+				"roomId": entry.RoomID.Hex(),
+				"reason": entry.Reason,
+			})
+		case "ban":
+			rpcServer.UnsubscribeUser(entry.RoomID.Hex(), entry.TargetUserID.Hex())
+			rpcServer.NotifyUser(entry.TargetUserID.Hex(), "moderation.banned", map[string]any{
+				"roomId": entry.RoomID.Hex(),
+				"reason": entry.Reason,
+			})
+		case "mute":
+			rpcServer.NotifyUser(entry.TargetUserID.Hex(), "moderation.muted", map[string]any{
+				"roomId":    entry.RoomID.Hex(),
+				"reason":    entry.Reason,
+				"expiresAt": entry.ExpiresAt,
+			})
+		}
+	})
+	undoableModeration.AddUndoHandler(func(ctx context.Context, entry *models.ModerationHistory, action string) {
+		if action == "delete" {
+			return
+		}
+		rpcServer.NotifyUser(entry.TargetUserID.Hex(), "moderation.undone", map[string]any{
+			"roomId": entry.RoomID.Hex(),
+			"action": action,
+		})
+	})
+
+	// Remind the hosts of scheduled events, and whoever scheduled them, wherever they are
+	eventScheduler.AddReminderHandler(func(ctx context.Context, eventRoom *models.Room, event models.RoomEvent) {
+		userIDs := append([]bson.ObjectID{event.CreatedBy}, event.Hosts...)
+		for i, userID := range userIDs {
+			if userID.IsZero() || slices.Contains(userIDs[:i], userID) {
+				continue
+			}
+			rpcServer.NotifyUser(userID.Hex(), "room.eventStarting", map[string]any{
+				"roomId":   eventRoom.ID.Hex(),
+				"roomSlug": eventRoom.Slug,
+				"event":    event,
+			})
+		}
+	})
+
+	// Let rooms follow the votes and reactions on the current media
+	roomManager.AddActivityHandler(func(ctx context.Context, activity room.RoomActivity) {
+		if activity.Type != room.ActivityVote && activity.Type != room.ActivityReaction {
+			return
+		}
+		rpcServer.NotifyRoom(activity.RoomID.Hex(), "room.votesChanged", map[string]any{
+			"roomId":  activity.RoomID.Hex(),
+			"mediaId": activity.MediaID.Hex(),
+			"votes":   activity.Votes,
+		})
+	})
+
+	// Skip media rooms vote down, and tell them
+	roomManager.AddSkipVoteHandler(queueManager.SkipVoted)
+	queueManager.AddVoteSkipHandler(func(ctx context.Context, vote room.SkipVote, state *models.RoomState) {
+		roomID := vote.RoomID.Hex()
+		queueManager.AnnounceTransition(vote.RoomID, func() {
+			rpcServer.NotifyRoom(roomID, rpc.EventTrackSkipped, map[string]any{
+				"roomId":  roomID,
+				"mediaId": vote.MediaID.Hex(),
+				"djId":    vote.DJID.Hex(),
+				"votes":   vote.Votes,
+			})
+			rpcServer.NotifyRoom(roomID, rpc.EventQueueUpdated, map[string]any{
+				"roomId":       roomID,
+				"djQueue":      state.DJQueue,
+				"currentDJ":    state.CurrentDJ,
+				"currentMedia": state.CurrentMedia,
+			})
+		})
+	})
+
+	// Let clients buffer the tracks the next DJs are expected to play
+	queueManager.AddPreloadHandler(func(ctx context.Context, roomID bson.ObjectID, preloads []room.TrackPreload) {
+		tracks := make([]map[string]any, 0, len(preloads))
+		for _, preload := range preloads {
+			track := map[string]any{
+				"djId":     preload.DJID.Hex(),
+				"position": preload.Position,
+				"media":    preload.Media,
+			}
+			if !preload.ExpectedStart.IsZero() {
+				track["expectedStart"] = preload.ExpectedStart
+			}
+			tracks = append(tracks, track)
+		}
+		rpcServer.NotifyRoom(roomID.Hex(), rpc.EventTrackPreload, map[string]any{
+			"roomId": roomID.Hex(),
+			"tracks": tracks,
+		})
+	})
+
+	// Tell rooms when a track is cut off at their maximum track length
+	queueManager.AddTruncateHandler(func(ctx context.Context, truncation room.TrackTruncation, state *models.RoomState) {
+		roomID := truncation.RoomID.Hex()
+		queueManager.AnnounceTransition(truncation.RoomID, func() {
+			rpcServer.NotifyRoom(roomID, rpc.EventTrackTruncated, map[string]any{
+				"roomId":    roomID,
+				"mediaId":   truncation.MediaID.Hex(),
+				"djId":      truncation.DJID.Hex(),
+				"duration":  truncation.Duration,
+				"maxLength": truncation.MaxLength,
+			})
+			rpcServer.NotifyRoom(roomID, rpc.EventQueueUpdated, map[string]any{
+				"roomId":       roomID,
+				"djQueue":      state.DJQueue,
+				"currentDJ":    state.CurrentDJ,
+				"currentMedia": state.CurrentMedia,
+			})
+		})
+	})
+
+	// Announce the tracks rooms replay as crowd picks
+	queueManager.AddCrowdPickHandler(func(ctx context.Context, roomID bson.ObjectID, state *models.RoomState) {
+		rpcServer.NotifyRoom(roomID.Hex(), rpc.EventCrowdPick, map[string]any{
+			"roomId":       roomID.Hex(),
+			"currentDJ":    state.CurrentDJ,
+			"currentMedia": state.CurrentMedia,
+			"crowdPick":    state.CurrentMedia.CrowdPick,
+		})
+	})
+
+	// Tell rooms about the tracks a server restart cut short, once resumed or made up for
+	queueManager.AddPlayRecoveryHandler(func(ctx context.Context, recovery room.PlayRecovery, state *models.RoomState) {
+		rpcServer.NotifyRoom(recovery.RoomID.Hex(), rpc.EventQueueUpdated, map[string]any{
+			"roomId":       recovery.RoomID.Hex(),
+			"djQueue":      state.DJQueue,
+			"currentDJ":    state.CurrentDJ,
+			"currentMedia": state.CurrentMedia,
+		})
+	})
+
+	// Show users' new usernames on their connections and in the state of the room they are in
+	userManager.AddRenamedHandler(func(ctx context.Context, rename user.Rename) {
+		userID := rename.User.ID.Hex()
+		renamed := map[string]any{
+			"userId":   userID,
+			"username": rename.User.Username,
+			"previous": rename.Previous,
+		}
+		rpcServer.RenameUser(userID, rename.User.Username)
+		rpcServer.NotifyUser(userID, rpc.EventUserRenamed, renamed)
+
+		presence, err := presenceMgr.GetPresence(ctx, rename.User.ID)
+		if err != nil || presence == nil || presence.CurrentRoomID == "" {
+			return
+		}
+		roomID, err := bson.ObjectIDFromHex(presence.CurrentRoomID)
+		if err != nil {
+			return
+		}
+
+		rpcServer.NotifyRoom(roomID.Hex(), rpc.EventUserRenamed, renamed)
+		state, err := queueManager.RenameUser(ctx, roomID, rename.User.ID, rename.User.Username)
+		if err != nil {
+			logger.Error("Failed to rename user in room state", err, "roomId", roomID.Hex(), "userId", userID)
+			return
+		}
+		if state != nil {
+			rpcServer.NotifyRoom(roomID.Hex(), rpc.EventQueueUpdated, map[string]any{
+				"roomId":       roomID.Hex(),
+				"djQueue":      state.DJQueue,
+				"currentDJ":    state.CurrentDJ,
+				"currentMedia": state.CurrentMedia,
+			})
+		}
+	})
+
+	// Apply room settings changes made on any node
+	settingsSync.AddHandler(roomManager.ApplySettingsChange)
+	settingsSync.AddHandler(chatService.ApplySettingsChange)
+	settingsSync.AddHandler(queueManager.ApplySettingsChange)
+	settingsSync.AddHandler(func(ctx context.Context, change room.RoomSettingsChange) {
+		rpcServer.NotifyLocalRoom(change.RoomID.Hex(), "room.settingsChanged", map[string]any{
+			"roomId":   change.RoomID.Hex(),
+			"settings": change.Settings,
+		})
+	})
+
+	// Each node syncs the playback of its own connections
+	playbackSyncer.AddSyncHandler(func(ctx context.Context, playback models.PlaybackSync) {
+		rpcServer.NotifyLocalRoom(playback.RoomID, rpc.EventPlaybackSync, playback)
+	})
+
+	// Register RPC methods
+	methods.RegisterAllMethods(
+		rpcRouter,
+		authProvider,
+		*sessionMgr,
+		userManager,
+		socialService,
+		statsService,
+		apiKeyService,
+		scrobbleService,
+		accountDeletionService,
+		dataExportService,
+		playlistManager,
+		mediaResolver,
+		lyricsService,
+		roomManager,
+		chatService,
+		toxicityModerator,
+		undoableModeration,
+		queueManager,
+		vibeService,
+		rotationReporter,
+		reportService,
+		captchaService,
+		calendarService,
+		listenerGeoMgr,
+		methods.JoinPolicy{
+			RosterPageSize: cfg.Room.JoinRosterPageSize,
+			ChatBacklog:    cfg.Room.JoinChatBacklog,
+		},
+		logger,
+	)
+
+	// Create HTTP server for API
+	apiAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+	httpServer := &http.Server{
+		Addr:         apiAddr,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+
+	// Create a separate HTTP server for WebSocket connections on a different port
+	// This avoids middleware that might interfere with WebSocket upgrades
+	// It also serves the Server-Sent Events fallback for clients that can't use WebSockets
+	wsPort := cfg.Server.Port + 1
+	wsAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, wsPort)
+	wsServer := &http.Server{
+		Addr:    wsAddr,
+		Handler: rpcServer.Handler(),
+	}
+
+	return &Server{
+		cfg:    cfg,
+		logger: logger,
+		ctx:    ctx,
+		cancel: cancel,
+
+		redisClient: redisClient,
+		mongoClient: mongoClient,
+		roomRepo:    roomRepo,
+		mediaRepo:   mediaRepo,
+
+		roomManager:         roomManager,
+		roomStateCache:      roomStateCache,
+		queueManager:        queueManager,
+		historyRecorder:     historyRecorder,
+		transitionMonitor:   transitionMonitor,
+		popupService:        popupService,
+		eventScheduler:      eventScheduler,
+		analyticsExporter:   analyticsExporter,
+		heatService:         heatService,
+		languageService:     languageService,
+		admissionController: admissionController,
+		stateHeartbeat:      stateHeartbeat,
+		playbackSyncer:      playbackSyncer,
+		toxicityModerator:   toxicityModerator,
+		settingsSync:        settingsSync,
+
+		webhookDispatcher:  webhookDispatcher,
+		scrobbleService:    scrobbleService,
+		imageReviewService: imageReviewService,
+
+		maintenanceService:    maintenanceService,
+		healthService:         healthService,
+		metricsHistoryService: metricsHistoryService,
+		redisMemoryService:    redisMemoryService,
+
+		rpcServer:  rpcServer,
+		rpcCluster: rpcCluster,
+		httpServer: httpServer,
+		wsServer:   wsServer,
+	}, nil
+}
+
+// Handler returns the handler of the HTTP API.
+func (s *Server) Handler() http.Handler {
+	return s.httpServer.Handler
+}
+
+// RPCHandler returns the handler of the WebSocket RPC and its Server-Sent Events fallback.
+func (s *Server) RPCHandler() http.Handler {
+	return s.wsServer.Handler
+}
+
+// Media returns the media repository of the node, for seeding the catalog without resolving media
+// from its providers.
+func (s *Server) Media() repositories.MediaRepository {
+	return s.mediaRepo
+}
+
+// Start starts the background services of the node.
+func (s *Server) Start() {
+	ctx, logger := s.ctx, s.logger
+
+	// Start maintenance service
+	if err := s.maintenanceService.Start(ctx); err != nil {
+		logger.Error("Failed to start maintenance service", err)
+	}
+
+	// Start health service
+	s.healthService.Start(ctx)
+
+	// Start pop-up room expiry
+	s.popupService.Start(ctx)
+
+	// Start room event reminders and activation
+	s.eventScheduler.Start(ctx)
+
+	// Start room analytics webhook delivery
+	s.analyticsExporter.Start(ctx)
+
+	// Start developer webhook delivery
+	s.webhookDispatcher.Start(ctx)
+
+	// Start submitting scrobbles
+	s.scrobbleService.Start(ctx)
+
+	// Start room heat scoring
+	s.heatService.Start(ctx)
+
+	// Start room language detection
+	s.languageService.Start(ctx)
+
+	// Start load-aware room admission
+	s.admissionController.Start(ctx)
+
+	// Keep the state of rooms in use from expiring
+	s.stateHeartbeat.Start(ctx)
+
+	// Start syncing the playback of rooms in use
+	s.playbackSyncer.Start(ctx)
+
+	// Start chat toxicity scoring
+	s.toxicityModerator.Start(ctx)
+
+	// Start matching changed avatars and playlist covers against known-bad images
+	s.imageReviewService.Start(ctx)
+
+	// Start fanning out notifications across the WebSocket servers
+	if err := s.rpcCluster.Start(ctx); err != nil {
+		logger.Error("Failed to join WebSocket cluster", err)
+	}
+
+	// Start room settings sync
+	if err := s.settingsSync.Start(ctx); err != nil {
+		logger.Error("Failed to start room settings sync", err)
+	}
+
+	// Start dropping cached room states written on other nodes
+	if err := s.roomStateCache.Start(); err != nil {
+		logger.Error("Failed to start room state cache", err)
+	}
+
+	// Restore play history records that failed to be written from the rooms' Redis history
+	go func() {
+		rooms, err := s.roomRepo.FindMany(ctx, bson.M{"isActive": true}, nil)
+		if err != nil {
+			logger.Error("Failed to get rooms for play history backfill", err)
+			return
+		}
+		roomIDs := make([]bson.ObjectID, len(rooms))
+		for i, activeRoom := range rooms {
+			roomIDs[i] = activeRoom.ID
+		}
+		s.historyRecorder.BackfillRooms(ctx, roomIDs)
+	}()
+
+	// Start metrics history service
+	if err := s.metricsHistoryService.Start(ctx); err != nil {
+		logger.Error("Failed to start metrics history service", err)
+	}
+
+	// Start looking for rooms that miss their track changes
+	s.transitionMonitor.Start(ctx)
+
+	// Resume or make up for the tracks that were playing when servers last restarted
+	go func() {
+		if err := s.queueManager.RecoverInFlightPlays(ctx); err != nil {
+			logger.Error("Failed to recover tracks in flight", err)
+		}
+	}()
+
+	// Start Redis memory budgeting
+	s.redisMemoryService.Start(ctx)
+}
+
+// ListenAndServe serves the HTTP API and the WebSocket RPC until the node shuts down, returning the
+// error of the first server that fails.
+func (s *Server) ListenAndServe() error {
+	errs := make(chan error, 2)
+
+	// Start HTTP server for API
+	go func() {
+		s.logger.Info("Starting HTTP server", "address", s.httpServer.Addr)
+		if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- fmt.Errorf("HTTP server error: %w", err)
+			return
+		}
+		errs <- nil
+	}()
+
+	// Start HTTP server for WebSocket
+	go func() {
+		s.logger.Info("Starting WebSocket server", "address", s.wsServer.Addr)
+		if err := s.wsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- fmt.Errorf("WebSocket server error: %w", err)
+			return
+		}
+		errs <- nil
+	}()
+
+	for range 2 {
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown shuts the node down in phases, so in-flight work finishes before what it depends on goes away.
+func (s *Server) Shutdown() {
+	cfg, logger, rpcServer := s.cfg, s.logger, s.rpcServer
+
+	var liveRooms []bson.ObjectID
+	shutdown := system.NewShutdownSequence(logger)
+
+	// Stop accepting: turn away new connections and requests, tell clients to move to other nodes before
+	// they are disconnected, and finish the HTTP requests in flight
+	shutdown.AddPhase("stop accepting", cfg.Server.ShutdownAcceptTimeout,
+		system.ShutdownStep{Name: "rpc", Run: func(ctx context.Context) error {
+			rpcServer.StopAccepting()
+			rpcServer.AnnounceShutdown(time.Now().Add(cfg.Server.ShutdownAcceptTimeout + cfg.Server.ShutdownNotifyTimeout))
+			return nil
+		}},
+		system.ShutdownStep{Name: "http", Run: s.httpServer.Shutdown},
+	)
+
+	// Notify clients: finish the RPC requests in flight, such as queue advances, then send clients to other nodes
+	shutdown.AddPhase("notify clients", cfg.Server.ShutdownNotifyTimeout,
+		system.ShutdownStep{Name: "rpc", Run: func(ctx context.Context) error {
+			for roomID := range rpcServer.RoomSubscriptions() {
+				if id, err := bson.ObjectIDFromHex(roomID); err == nil {
+					liveRooms = append(liveRooms, id)
+				}
+			}
+			if err := rpcServer.Shutdown(ctx); err != nil {
+				return err
+			}
+			if err := s.rpcCluster.Leave(ctx); err != nil {
+				logger.Warn("Failed to leave WebSocket cluster", "error", err)
+			}
+			return s.wsServer.Shutdown(ctx)
+		}},
+	)
+
+	// Flush: stop background services and deliver the work they still hold
+	shutdown.AddPhase("flush", cfg.Server.ShutdownFlushTimeout,
+		system.ShutdownStep{Name: "background services", Run: func(ctx context.Context) error {
+			s.cancel()
+			s.maintenanceService.Stop()
+			return nil
+		}},
+		system.ShutdownStep{Name: "play history", Run: func(ctx context.Context) error {
+			s.historyRecorder.BackfillRooms(ctx, liveRooms)
+			return nil
+		}},
+		system.ShutdownStep{Name: "analytics", Run: func(ctx context.Context) error {
+			s.analyticsExporter.Flush(ctx)
+			return nil
+		}},
+		system.ShutdownStep{Name: "webhooks", Run: func(ctx context.Context) error {
+			s.webhookDispatcher.Flush(ctx)
+			return nil
+		}},
+		system.ShutdownStep{Name: "scrobbles", Run: func(ctx context.Context) error {
+			s.scrobbleService.Flush(ctx)
+			return nil
+		}},
+	)
+
+	// Persist: store the state of the rooms this instance served, so it can be rebuilt if it expires
+	shutdown.AddPhase("persist room state", cfg.Server.ShutdownPersistTimeout,
+		system.ShutdownStep{Name: "rooms", Run: func(ctx context.Context) error {
+			for _, roomID := range liveRooms {
+				if err := s.roomManager.PersistRoomState(ctx, roomID); err != nil {
+					logger.Error("Failed to persist room state", err, "roomId", roomID.Hex())
+				}
+			}
+			logger.Info("Persisted room state", "rooms", len(liveRooms))
+			return nil
+		}},
+	)
+
+	// Close the database clients last, everything before may still need them
+	shutdown.AddPhase("close databases", cfg.Server.ShutdownCloseTimeout,
+		system.ShutdownStep{Name: "redis", Run: func(ctx context.Context) error {
+			return s.redisClient.Close()
+		}},
+		system.ShutdownStep{Name: "mongodb", Run: func(ctx context.Context) error {
+			if s.mongoClient == nil {
+				return nil
+			}
+			return s.mongoClient.Disconnect(ctx)
+		}},
+	)
+
+	shutdown.Run()
+}
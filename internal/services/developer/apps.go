@@ -0,0 +1,223 @@
+// Package developer provides the registry of third-party developer applications and delivers
+// platform events to their webhooks.
+package developer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// appSecretPrefix marks a string as a webhook signing secret.
+	appSecretPrefix = "whsec_"
+
+	// appSecretBytes is the number of random bytes in an application's secret.
+	appSecretBytes = 32
+)
+
+// CreatedDeveloperApp is a developer application along with its signing secret, which is never shown again.
+type CreatedDeveloperApp struct {
+	*models.DeveloperApp
+	Secret string `json:"secret"`
+}
+
+// AppService manages the developer applications users register to receive platform events.
+type AppService struct {
+	developerRepo repositories.DeveloperRepository
+	dispatcher    *WebhookDispatcher
+	maxApps       int
+	logger        *utils.Logger
+}
+
+// NewAppService creates a new developer application service. Changes to applications are
+// passed on to the dispatcher, so deliveries follow them right away on this instance.
+func NewAppService(developerRepo repositories.DeveloperRepository, dispatcher *WebhookDispatcher, maxApps int, logger *utils.Logger) *AppService {
+	return &AppService{
+		developerRepo: developerRepo,
+		dispatcher:    dispatcher,
+		maxApps:       maxApps,
+		logger:        logger.Named("developer_app_service"),
+	}
+}
+
+// CreateApp registers a developer application for a user.
+func (s *AppService) CreateApp(ctx context.Context, ownerID bson.ObjectID, request *models.DeveloperAppRequest) (*CreatedDeveloperApp, error) {
+	if err := validateAppRequest(request); err != nil {
+		return nil, err
+	}
+
+	apps, err := s.developerRepo.FindAppsByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if s.maxApps > 0 && len(apps) >= s.maxApps {
+		return nil, models.ErrTooManyDeveloperApps
+	}
+
+	secret, err := newAppSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	app := &models.DeveloperApp{
+		OwnerID:     ownerID,
+		Name:        request.Name,
+		Description: request.Description,
+		WebhookURL:  request.WebhookURL,
+		Events:      request.Events,
+		Active:      request.Active == nil || *request.Active,
+		Secret:      secret,
+	}
+	if err := s.developerRepo.CreateApp(ctx, app); err != nil {
+		return nil, err
+	}
+	s.dispatcher.invalidate()
+
+	s.logger.Info("Developer application registered", "appId", app.ID.Hex(), "ownerId", ownerID.Hex(), "events", app.Events)
+	return &CreatedDeveloperApp{DeveloperApp: app, Secret: secret}, nil
+}
+
+// ListApps lists the developer applications a user registered.
+func (s *AppService) ListApps(ctx context.Context, ownerID bson.ObjectID) ([]*models.DeveloperApp, error) {
+	return s.developerRepo.FindAppsByOwner(ctx, ownerID)
+}
+
+// GetApp gets one of a user's developer applications.
+func (s *AppService) GetApp(ctx context.Context, appID, ownerID bson.ObjectID) (*models.DeveloperApp, error) {
+	app, err := s.developerRepo.FindAppByID(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	// Other users' applications are not found rather than forbidden, so their IDs can't be probed
+	if app.OwnerID != ownerID {
+		return nil, models.ErrDeveloperAppNotFound
+	}
+	return app, nil
+}
+
+// UpdateApp changes one of a user's developer applications. The secret is kept.
+func (s *AppService) UpdateApp(ctx context.Context, appID, ownerID bson.ObjectID, request *models.DeveloperAppRequest) (*models.DeveloperApp, error) {
+	if err := validateAppRequest(request); err != nil {
+		return nil, err
+	}
+
+	app, err := s.GetApp(ctx, appID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	app.Name = request.Name
+	app.Description = request.Description
+	app.WebhookURL = request.WebhookURL
+	app.Events = request.Events
+	if request.Active != nil {
+		app.Active = *request.Active
+	}
+	if err := s.developerRepo.UpdateApp(ctx, app); err != nil {
+		return nil, err
+	}
+	s.dispatcher.invalidate()
+
+	s.logger.Info("Developer application updated", "appId", appID.Hex(), "active", app.Active, "events", app.Events)
+	return app, nil
+}
+
+// DeleteApp deletes one of a user's developer applications. Its pending deliveries are dropped.
+func (s *AppService) DeleteApp(ctx context.Context, appID, ownerID bson.ObjectID) error {
+	if _, err := s.GetApp(ctx, appID, ownerID); err != nil {
+		return err
+	}
+	if err := s.developerRepo.DeleteApp(ctx, appID); err != nil {
+		return err
+	}
+	s.dispatcher.invalidate()
+
+	s.logger.Info("Developer application deleted", "appId", appID.Hex(), "ownerId", ownerID.Hex())
+	return nil
+}
+
+// RotateSecret replaces the signing secret of one of a user's developer applications.
+// Deliveries are signed with the new secret from the next attempt on, retries included.
+func (s *AppService) RotateSecret(ctx context.Context, appID, ownerID bson.ObjectID) (*CreatedDeveloperApp, error) {
+	app, err := s.GetApp(ctx, appID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := newAppSecret()
+	if err != nil {
+		return nil, err
+	}
+	app.Secret = secret
+	if err := s.developerRepo.UpdateApp(ctx, app); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Developer application secret rotated", "appId", appID.Hex())
+	return &CreatedDeveloperApp{DeveloperApp: app, Secret: secret}, nil
+}
+
+// ListDeliveries lists the delivery log of one of a user's developer applications, most recent first,
+// along with the total number of attempts logged.
+func (s *AppService) ListDeliveries(ctx context.Context, appID, ownerID bson.ObjectID, skip, limit int) ([]*models.WebhookDelivery, int64, error) {
+	if _, err := s.GetApp(ctx, appID, ownerID); err != nil {
+		return nil, 0, err
+	}
+	return s.developerRepo.FindDeliveries(ctx, appID, skip, limit)
+}
+
+// PruneDeliveries deletes the delivery attempts logged longer ago than the retention.
+func (s *AppService) PruneDeliveries(ctx context.Context, retention time.Duration) error {
+	if retention <= 0 {
+		return nil
+	}
+
+	deleted, err := s.developerRepo.DeleteDeliveriesBefore(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		s.logger.Info("Pruned webhook delivery log", "deleted", deleted)
+	}
+	return nil
+}
+
+// validateAppRequest checks and normalizes a request to register or change an application.
+func validateAppRequest(request *models.DeveloperAppRequest) error {
+	request.Name = strings.TrimSpace(request.Name)
+	request.Description = strings.TrimSpace(request.Description)
+	if err := utils.Validate(request); err != nil {
+		return models.NewUserError(models.ErrInvalidDeveloperApp, err.Error(), http.StatusBadRequest)
+	}
+	parsed, err := url.Parse(request.WebhookURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return models.NewUserError(models.ErrInvalidDeveloperApp, "The webhook URL must be an https URL", http.StatusBadRequest)
+	}
+	// Host names are checked again on every delivery, as they can resolve to anything
+	if ip := net.ParseIP(parsed.Hostname()); (ip != nil && !utils.IsPublicIP(ip)) || parsed.Hostname() == "localhost" {
+		return models.NewUserError(models.ErrInvalidDeveloperApp, "The webhook URL must point at a public address", http.StatusBadRequest)
+	}
+	request.Events = slices.Compact(slices.Sorted(slices.Values(request.Events)))
+	return nil
+}
+
+// newAppSecret generates an application's signing secret.
+func newAppSecret() (string, error) {
+	secret := make([]byte, appSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", models.NewInternalError(err, "Failed to generate application secret")
+	}
+	return appSecretPrefix + hex.EncodeToString(secret), nil
+}
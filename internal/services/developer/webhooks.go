@@ -0,0 +1,415 @@
+package developer
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/services/room"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// webhookQueueKey is the sorted set of deliveries waiting to be attempted, scored by when they are due.
+	webhookQueueKey = "webhooks:queue"
+
+	// webhookAppsTTL is how long the applications subscribed to an event are cached.
+	// Changes made on other instances take effect after at most this long.
+	webhookAppsTTL = time.Minute
+
+	// webhookBatchSize is the largest number of due deliveries claimed at once.
+	webhookBatchSize = 100
+)
+
+// WebhookSchemaVersion is the version of the webhook payload schema. It changes when fields are
+// renamed, removed or change meaning. Fields can be added without a new version.
+const WebhookSchemaVersion = 1
+
+// WebhookPolicy controls how events are delivered to developer applications.
+type WebhookPolicy struct {
+	// PollInterval is how often due deliveries are attempted. Zero disables delivery.
+	PollInterval time.Duration
+
+	// Timeout bounds one delivery attempt.
+	Timeout time.Duration
+
+	// MaxAttempts is how many times an event is attempted before it is given up on.
+	MaxAttempts int
+
+	// RetryBackoff is the wait before the first retry. It doubles with every further attempt.
+	RetryBackoff time.Duration
+
+	// MaxBackoff caps the wait between attempts.
+	MaxBackoff time.Duration
+
+	// Workers is the number of deliveries attempted at once on each instance.
+	Workers int
+}
+
+// WebhookPayload is the body of a webhook delivery.
+type WebhookPayload struct {
+	// Schema is the payload schema version.
+	Schema int `json:"schema"`
+
+	// ID uniquely identifies the event, so receivers can drop duplicate deliveries.
+	ID string `json:"id"`
+
+	// Event is the kind of event.
+	Event models.WebhookEvent `json:"event"`
+
+	// Time is when the event happened.
+	Time time.Time `json:"time"`
+
+	// Data describes what happened, its fields depend on the event.
+	Data any `json:"data"`
+}
+
+// webhookJob is a delivery waiting in the queue.
+type webhookJob struct {
+	AppID   bson.ObjectID       `json:"appId"`
+	EventID string              `json:"eventId"`
+	Event   models.WebhookEvent `json:"event"`
+	Payload json.RawMessage     `json:"payload"`
+	Attempt int                 `json:"attempt"`
+}
+
+// cachedApps are the applications subscribed to an event, cached by the dispatcher.
+type cachedApps struct {
+	apps    []*models.DeveloperApp
+	expires time.Time
+}
+
+// WebhookDispatcher delivers platform events to the webhooks of the developer applications subscribed
+// to them. Deliveries are queued in Redis, so any instance can attempt them, and failed attempts are
+// retried with exponential backoff. Every attempt is written to the application's delivery log.
+type WebhookDispatcher struct {
+	developerRepo repositories.DeveloperRepository
+	roomManager   room.RoomManager
+	redisClient   *redis.Client
+	httpClient    *http.Client
+	policy        WebhookPolicy
+	logger        *utils.Logger
+
+	apps  map[models.WebhookEvent]cachedApps
+	mutex sync.Mutex
+}
+
+// NewWebhookDispatcher creates a new webhook dispatcher. Webhook URLs are chosen by developers, so
+// deliveries only go to public addresses and don't follow redirects.
+func NewWebhookDispatcher(developerRepo repositories.DeveloperRepository, roomManager room.RoomManager, redisClient *redis.Client, policy WebhookPolicy, logger *utils.Logger) *WebhookDispatcher {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	if policy.Workers < 1 {
+		policy.Workers = 1
+	}
+	return &WebhookDispatcher{
+		developerRepo: developerRepo,
+		roomManager:   roomManager,
+		redisClient:   redisClient,
+		httpClient:    utils.NewPublicHTTPClient(policy.Timeout, false),
+		policy:        policy,
+		logger:        logger.Named("webhook_dispatcher"),
+		apps:          make(map[models.WebhookEvent]cachedApps),
+	}
+}
+
+// UserCreated sends user.created to the subscribed applications. It is meant to be added as a user created handler.
+func (d *WebhookDispatcher) UserCreated(ctx context.Context, user *models.User) {
+	d.Publish(ctx, models.WebhookEventUserCreated, user.CreatedAt, map[string]any{
+		"id":       user.ID.Hex(),
+		"username": user.Username,
+	})
+}
+
+// RoomCreated sends room.created to the subscribed applications, unless the room is private.
+// It is meant to be added as a room created handler.
+func (d *WebhookDispatcher) RoomCreated(ctx context.Context, created *models.Room) {
+	if created.Settings.Private {
+		return
+	}
+	d.Publish(ctx, models.WebhookEventRoomCreated, created.CreatedAt, map[string]any{
+		"id":        created.ID.Hex(),
+		"name":      created.Name,
+		"slug":      created.Slug,
+		"createdBy": created.CreatedBy.Hex(),
+	})
+}
+
+// PlayRecorded sends play.recorded to the subscribed applications, unless the room is private or delisted.
+// It is meant to be added as a play history activity handler.
+func (d *WebhookDispatcher) PlayRecorded(ctx context.Context, activity room.RoomActivity) {
+	if activity.Type != room.ActivityPlay || len(d.subscribedApps(ctx, models.WebhookEventPlayRecorded)) == 0 {
+		return
+	}
+
+	playedIn, err := d.roomManager.GetRoom(ctx, activity.RoomID)
+	if err != nil {
+		d.logger.Error("Failed to get room of recorded play", err, "roomId", activity.RoomID.Hex())
+		return
+	}
+	if playedIn.Settings.Private || playedIn.Delisted {
+		return
+	}
+
+	data := map[string]any{
+		"roomId":   activity.RoomID.Hex(),
+		"djId":     activity.UserID.Hex(),
+		"audience": activity.Audience,
+	}
+	if activity.Media != nil {
+		data["media"] = map[string]any{
+			"id":       activity.Media.ID.Hex(),
+			"type":     activity.Media.Type,
+			"sourceId": activity.Media.SourceID,
+			"title":    activity.Media.Title,
+			"artist":   activity.Media.Artist,
+			"duration": activity.Media.Duration,
+		}
+	}
+	d.Publish(ctx, models.WebhookEventPlayRecorded, activity.Time, data)
+}
+
+// Publish queues an event for delivery to every active application subscribed to it.
+func (d *WebhookDispatcher) Publish(ctx context.Context, event models.WebhookEvent, at time.Time, data any) {
+	if d.policy.PollInterval <= 0 {
+		return
+	}
+
+	apps := d.subscribedApps(ctx, event)
+	if len(apps) == 0 {
+		return
+	}
+
+	eventID := bson.NewObjectID().Hex()
+	payload, err := json.Marshal(WebhookPayload{
+		Schema: WebhookSchemaVersion,
+		ID:     eventID,
+		Event:  event,
+		Time:   at.UTC(),
+		Data:   data,
+	})
+	if err != nil {
+		d.logger.Error("Failed to encode webhook payload", err, "event", event)
+		return
+	}
+
+	now := time.Now()
+	for _, app := range apps {
+		d.enqueue(ctx, webhookJob{
+			AppID:   app.ID,
+			EventID: eventID,
+			Event:   event,
+			Payload: payload,
+			Attempt: 1,
+		}, now)
+	}
+}
+
+// enqueue queues a delivery to be attempted at the given time.
+func (d *WebhookDispatcher) enqueue(ctx context.Context, job webhookJob, at time.Time) {
+	encoded, err := json.Marshal(job)
+	if err != nil {
+		d.logger.Error("Failed to encode webhook delivery", err, "appId", job.AppID.Hex())
+		return
+	}
+	if err := d.redisClient.ZAdd(ctx, webhookQueueKey, float64(at.UnixMilli()), string(encoded)); err != nil {
+		d.logger.Error("Failed to queue webhook delivery", err, "appId", job.AppID.Hex(), "event", job.Event)
+	}
+}
+
+// subscribedApps returns the active applications subscribed to an event.
+func (d *WebhookDispatcher) subscribedApps(ctx context.Context, event models.WebhookEvent) []*models.DeveloperApp {
+	d.mutex.Lock()
+	cached, ok := d.apps[event]
+	d.mutex.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.apps
+	}
+
+	apps, err := d.developerRepo.FindAppsByEvent(ctx, event)
+	if err != nil {
+		d.logger.Error("Failed to find applications subscribed to event", err, "event", event)
+		return nil
+	}
+
+	d.mutex.Lock()
+	d.apps[event] = cachedApps{apps: apps, expires: time.Now().Add(webhookAppsTTL)}
+	d.mutex.Unlock()
+	return apps
+}
+
+// invalidate drops the cached subscriptions after an application changed.
+func (d *WebhookDispatcher) invalidate() {
+	d.mutex.Lock()
+	clear(d.apps)
+	d.mutex.Unlock()
+}
+
+// Start begins attempting due deliveries.
+func (d *WebhookDispatcher) Start(ctx context.Context) {
+	if d.policy.PollInterval <= 0 {
+		d.logger.Info("Developer webhook delivery is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(d.policy.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				d.logger.Info("Stopping webhook dispatcher")
+				return
+			case <-ticker.C:
+				d.Flush(ctx)
+			}
+		}
+	}()
+
+	d.logger.Info("Webhook dispatcher started", "interval", d.policy.PollInterval, "maxAttempts", d.policy.MaxAttempts)
+}
+
+// Flush attempts the deliveries that are due. Each delivery is claimed by removing it from the queue,
+// so only one instance attempts it.
+func (d *WebhookDispatcher) Flush(ctx context.Context) {
+	due, err := d.redisClient.ZRangeByScore(ctx, webhookQueueKey, "-inf", strconv.FormatInt(time.Now().UnixMilli(), 10), webhookBatchSize)
+	if err != nil {
+		return
+	}
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, d.policy.Workers)
+	for _, member := range due {
+		claimed, err := d.redisClient.Client().ZRem(ctx, webhookQueueKey, member).Result()
+		if err != nil || claimed == 0 {
+			continue
+		}
+
+		var job webhookJob
+		if err := json.Unmarshal([]byte(member), &job); err != nil {
+			d.logger.Error("Dropping undecodable webhook delivery", err)
+			continue
+		}
+
+		workers <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			d.attempt(ctx, job)
+		}()
+	}
+	wg.Wait()
+}
+
+// attempt delivers a queued event to its application, logs the attempt and schedules a retry if it failed.
+func (d *WebhookDispatcher) attempt(ctx context.Context, job webhookJob) {
+	app, err := d.developerRepo.FindAppByID(ctx, job.AppID)
+	if err != nil {
+		if !errors.Is(err, models.ErrDeveloperAppNotFound) {
+			// Try again later, the application may well still want the event
+			d.enqueue(ctx, job, time.Now().Add(d.policy.PollInterval))
+		}
+		return
+	}
+	if !app.Active {
+		return
+	}
+
+	started := time.Now()
+	statusCode, err := d.deliver(ctx, app, job)
+
+	// The delivery was claimed, so its outcome must be kept even when shutting down interrupted it
+	ctx = context.WithoutCancel(ctx)
+	delivery := &models.WebhookDelivery{
+		AppID:      app.ID,
+		EventID:    job.EventID,
+		Event:      job.Event,
+		Attempt:    job.Attempt,
+		Success:    err == nil,
+		StatusCode: statusCode,
+		DurationMs: time.Since(started).Milliseconds(),
+		CreatedAt:  started,
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+		if job.Attempt < d.policy.MaxAttempts {
+			delivery.NextAttemptAt = time.Now().Add(d.backoff(job.Attempt))
+			retry := job
+			retry.Attempt++
+			d.enqueue(ctx, retry, delivery.NextAttemptAt)
+		} else {
+			d.logger.Warn("Giving up on webhook delivery", "appId", app.ID.Hex(), "eventId", job.EventID, "attempts", job.Attempt, "error", err)
+		}
+	}
+
+	if err := d.developerRepo.CreateDelivery(ctx, delivery); err != nil {
+		d.logger.Error("Failed to log webhook delivery", err, "appId", app.ID.Hex(), "eventId", job.EventID)
+	}
+}
+
+// backoff returns the wait before the attempt following the given one.
+func (d *WebhookDispatcher) backoff(attempt int) time.Duration {
+	wait := d.policy.RetryBackoff
+	for i := 1; i < attempt; i++ {
+		wait *= 2
+		if d.policy.MaxBackoff > 0 && wait >= d.policy.MaxBackoff {
+			return d.policy.MaxBackoff
+		}
+	}
+	return wait
+}
+
+// deliver posts an event to an application's webhook and returns the status it responded with. The body
+// is signed with the application's secret: X-Listenify-Signature is the hex HMAC-SHA256 of the
+// X-Listenify-Timestamp value, a dot and the body.
+func (d *WebhookDispatcher) deliver(ctx context.Context, app *models.DeveloperApp, job webhookJob) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(app.Secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(job.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, app.WebhookURL, bytes.NewReader(job.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Listenify-Schema", strconv.Itoa(WebhookSchemaVersion))
+	req.Header.Set("X-Listenify-Event", string(job.Event))
+	req.Header.Set("X-Listenify-Delivery", job.EventID)
+	req.Header.Set("X-Listenify-Attempt", strconv.Itoa(job.Attempt))
+	req.Header.Set("X-Listenify-Timestamp", timestamp)
+	req.Header.Set("X-Listenify-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		// The delivery log shouldn't tell what the webhook's host resolved to
+		if errors.Is(err, utils.ErrPrivateAddress) {
+			return 0, utils.ErrPrivateAddress
+		}
+		return 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,119 @@
+// Package geo provides coarse, privacy-preserving geolocation of listeners.
+package geo
+
+import (
+	"bufio"
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// Database resolves IP addresses to country codes using a local network table.
+// The table is loaded from a CSV file with one "network,country" pair per line,
+// such as "203.0.113.0/24,AU", where the country is an ISO 3166-1 alpha-2 code.
+// Networks must not overlap.
+//
+// GeoLite2 country blocks can't be loaded as they are, since they identify countries by
+// geoname ID: join them with the GeoLite2 country locations on geoname_id and keep the
+// network and country_iso_code columns.
+type Database struct {
+	networks []network
+	logger   *utils.Logger
+}
+
+// network maps an address prefix to an ISO 3166-1 alpha-2 country code.
+type network struct {
+	prefix  netip.Prefix
+	country string
+}
+
+// NewDatabase loads a GeoIP database of "network,country" pairs from the given path.
+// Lines that aren't such a pair, like a header row, are skipped.
+// An empty path yields a database that resolves every address to an unknown country.
+func NewDatabase(path string, logger *utils.Logger) (*Database, error) {
+	db := &Database{
+		logger: logger.Named("geo_database"),
+	}
+
+	if path == "" {
+		db.logger.Info("No GeoIP database configured, listener geo attribution disabled")
+		return db, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, ",")
+		if len(fields) < 2 {
+			continue
+		}
+
+		// Skip the header row and malformed networks
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(fields[0]))
+		if err != nil {
+			continue
+		}
+
+		country := strings.ToUpper(strings.TrimSpace(fields[1]))
+		if len(country) != 2 {
+			continue
+		}
+
+		db.networks = append(db.networks, network{prefix: prefix.Masked(), country: country})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read GeoIP database: %w", err)
+	}
+
+	// Sort by start address so lookups can binary search
+	sort.Slice(db.networks, func(i, j int) bool {
+		return db.networks[i].prefix.Addr().Less(db.networks[j].prefix.Addr())
+	})
+
+	if len(db.networks) == 0 {
+		db.logger.Warn("GeoIP database has no network,country pairs, listener geo attribution disabled", "path", path)
+		return db, nil
+	}
+
+	db.logger.Info("Loaded GeoIP database", "path", path, "networks", len(db.networks))
+	return db, nil
+}
+
+// LookupCountry returns the country code for an IP address, or an empty string if unknown.
+func (db *Database) LookupCountry(ip string) string {
+	if db == nil || len(db.networks) == 0 {
+		return ""
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	// Find the last network starting at or before the address
+	i := sort.Search(len(db.networks), func(i int) bool {
+		return addr.Less(db.networks[i].prefix.Addr())
+	})
+
+	if i == 0 || !db.networks[i-1].prefix.Contains(addr) {
+		return ""
+	}
+
+	return db.networks[i-1].country
+}
@@ -0,0 +1,95 @@
+// Package media provides media resolution and search functionality.
+package media
+
+import (
+	"context"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+// maxAlternatives caps the number of replacements suggested for a media item.
+const maxAlternatives = 20
+
+// FindAlternatives suggests other sources of the same song as a media item, to replace it when its
+// source is gone. Copies already linked to the song come first, followed by provider search results
+// that match the song's fingerprint and duration. The media item's own source is never suggested.
+func (r *Resolver) FindAlternatives(ctx context.Context, id bson.ObjectID, limit int) ([]models.MediaSearchResult, error) {
+	if limit <= 0 || limit > maxAlternatives {
+		limit = maxAlternatives
+	}
+
+	media, err := r.mediaRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	alternatives := make([]models.MediaSearchResult, 0, limit)
+	seen := map[string]bool{media.Type + ":" + media.SourceID: true}
+
+	variants, err := r.SongMedia(ctx, media.SongID())
+	if err != nil {
+		return nil, err
+	}
+	for _, variant := range variants {
+		key := variant.Type + ":" + variant.SourceID
+		if seen[key] || len(alternatives) == limit {
+			continue
+		}
+		seen[key] = true
+		alternatives = append(alternatives, models.MediaSearchResult{
+			Type:          variant.Type,
+			SourceID:      variant.SourceID,
+			Title:         variant.Title,
+			Artist:        variant.Artist,
+			Thumbnail:     variant.Thumbnail,
+			Duration:      variant.Duration,
+			Views:         variant.Metadata.Views,
+			PublishedAt:   variant.Metadata.PublishedAt,
+			ChannelTitle:  variant.Metadata.ChannelTitle,
+			Restricted:    variant.Metadata.Restricted,
+			AgeRestricted: variant.Metadata.AgeRestricted,
+		})
+	}
+
+	fingerprint := media.Fingerprint
+	if fingerprint == "" {
+		fingerprint = Fingerprint(media.Title, media.Artist)
+	}
+	if fingerprint == "" || len(alternatives) == limit {
+		return alternatives, nil
+	}
+
+	// Titles commonly name the artist already
+	query := media.Title
+	if !strings.Contains(query, " - ") {
+		query = strings.TrimSpace(media.Artist + " " + media.Title)
+	}
+
+	// Search every provider, re-uploads of a deleted video are often on the same provider
+	for providerType, provider := range r.providers {
+		results, _, err := provider.Search(ctx, query, limit)
+		if err != nil {
+			r.logger.Error("Error searching provider for alternatives", err, "provider", providerType, "mediaId", id.Hex())
+			continue
+		}
+
+		for _, result := range results {
+			key := result.Type + ":" + result.SourceID
+			if seen[key] || len(alternatives) == limit {
+				continue
+			}
+			if Fingerprint(result.Title, result.Artist) != fingerprint {
+				continue
+			}
+			if !sameSong(media, &models.Media{Duration: result.Duration}) {
+				continue
+			}
+			seen[key] = true
+			alternatives = append(alternatives, result)
+		}
+	}
+
+	return alternatives, nil
+}
@@ -0,0 +1,341 @@
+// Package media provides media resolution and search functionality.
+package media
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+const (
+	// durationTolerance is the maximum difference in seconds between two copies of the same song.
+	durationTolerance = 5
+
+	// dedupeBatchSize is the number of media items loaded at a time by the dedupe job.
+	dedupeBatchSize = 500
+
+	// canonicalRecentPlays is the number of recent plays included in a canonical lookup.
+	canonicalRecentPlays = 20
+)
+
+var (
+	// bracketedRegex matches parenthesized or bracketed title segments.
+	bracketedRegex = regexp.MustCompile(`\([^)]*\)|\[[^\]]*\]`)
+
+	// featuringRegex matches a featured artist clause and everything after it.
+	featuringRegex = regexp.MustCompile(`\s(feat\.?|ft\.?|featuring)\s.*$`)
+
+	// releaseNoiseWords are words that mark a bracketed segment as describing the upload rather than the song.
+	releaseNoiseWords = []string{"official", "video", "audio", "lyric", "lyrics", "hd", "hq", "4k", "visualizer", "visualiser", "mv"}
+
+	// isrcRegex matches an ISRC labelled as such in a description, with or without its dashes.
+	isrcRegex = regexp.MustCompile(`(?i)\bISRC\b\W{0,3}([A-Z]{2}-?[A-Z0-9]{3}-?[0-9]{2}-?[0-9]{5})\b`)
+)
+
+// DedupeReport summarizes a run of the media dedupe job.
+type DedupeReport struct {
+	Scanned       int   `json:"scanned"`
+	Fingerprinted int   `json:"fingerprinted"`
+	Songs         int   `json:"songs"`
+	Linked        int   `json:"linked"`
+	Plays         int64 `json:"plays"`
+}
+
+// ParseISRC finds the ISRC of a recording in a media description, such as the "ISRC: USUM71703861" line
+// labels add to their uploads. It returns the code without dashes, or an empty string if there is none.
+func ParseISRC(description string) string {
+	match := isrcRegex.FindStringSubmatch(description)
+	if match == nil {
+		return ""
+	}
+	return strings.ToUpper(strings.ReplaceAll(match[1], "-", ""))
+}
+
+// Fingerprint derives a provider-independent song identity from a media item's title and artist.
+// It returns an empty string when the media does not carry enough information to be matched.
+func Fingerprint(title, artist string) string {
+	title = strings.ToLower(title)
+	artist = strings.ToLower(artist)
+
+	// Uploader names aren't artist names
+	artist = strings.TrimSuffix(artist, " - topic")
+	artist = strings.TrimSuffix(artist, "vevo")
+
+	// Titles are commonly "Artist - Title", prefer the artist from the title when there is one
+	if left, right, found := strings.Cut(title, " - "); found {
+		artist, title = left, right
+	}
+
+	title = bracketedRegex.ReplaceAllStringFunc(title, func(segment string) string {
+		for _, word := range strings.Fields(normalizeWords(segment)) {
+			if slices.Contains(releaseNoiseWords, word) {
+				return " "
+			}
+		}
+		return segment
+	})
+	title = featuringRegex.ReplaceAllString(title, "")
+	artist = featuringRegex.ReplaceAllString(artist, "")
+
+	title, artist = normalizeWords(title), normalizeWords(artist)
+	if title == "" || artist == "" {
+		return ""
+	}
+	return artist + "|" + title
+}
+
+// normalizeWords keeps only letters and digits, separated by single spaces.
+func normalizeWords(s string) string {
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// sameSong checks whether two media items with the same fingerprint are copies of the same song.
+// Items with different ISRCs are different recordings, such as a live and a studio version.
+func sameSong(a, b *models.Media) bool {
+	if a.Metadata.ISRC != "" && b.Metadata.ISRC != "" && a.Metadata.ISRC != b.Metadata.ISRC {
+		return false
+	}
+	diff := a.Duration - b.Duration
+	return diff >= -durationTolerance && diff <= durationTolerance
+}
+
+// linkCanonical fingerprints a new media item and links it to a matching song from another provider.
+// A copy with the same ISRC is the same recording whatever its title, so it is looked for first.
+func (r *Resolver) linkCanonical(ctx context.Context, media *models.Media) {
+	media.Fingerprint = Fingerprint(media.Title, media.Artist)
+
+	if media.Metadata.ISRC != "" && r.linkMatching(ctx, media, bson.M{"metadata.isrc": media.Metadata.ISRC}, nil) {
+		return
+	}
+	if media.Fingerprint != "" {
+		r.linkMatching(ctx, media, bson.M{"fingerprint": media.Fingerprint}, sameSong)
+	}
+}
+
+// linkMatching links a new media item to the oldest media from another provider matching the filter and,
+// if given, the match function. It returns whether the media was linked.
+func (r *Resolver) linkMatching(ctx context.Context, media *models.Media, filter bson.M, match func(a, b *models.Media) bool) bool {
+	filter["type"] = bson.M{"$ne": media.Type}
+	candidates, err := r.mediaRepo.FindMany(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		r.logger.Error("Failed to find canonical media candidates", err, "filter", filter)
+		return false
+	}
+
+	for _, candidate := range candidates {
+		if match == nil || match(media, candidate) {
+			media.CanonicalID = candidate.SongID()
+			r.logger.Debug("Linked media to canonical song", "source", media.Type, "sourceID", media.SourceID, "canonicalId", media.CanonicalID.Hex())
+			return true
+		}
+	}
+	return false
+}
+
+// GetCanonical retrieves the song a media item belongs to, with every provider's copy and their combined statistics.
+func (r *Resolver) GetCanonical(ctx context.Context, id bson.ObjectID) (*models.CanonicalMedia, error) {
+	media, err := r.mediaRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	canonical := media
+	if !media.CanonicalID.IsZero() {
+		if canonical, err = r.mediaRepo.FindByID(ctx, media.CanonicalID); err != nil {
+			return nil, err
+		}
+	}
+
+	variants, err := r.SongMedia(ctx, canonical.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]bson.ObjectID, len(variants))
+	for i, variant := range variants {
+		ids[i] = variant.ID
+	}
+	plays, err := r.mediaRepo.FindPlayHistory(ctx, bson.M{"mediaId": bson.M{"$in": ids}},
+		options.Find().SetSort(bson.D{{Key: "startTime", Value: -1}}).SetLimit(canonicalRecentPlays))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CanonicalMedia{
+		Media:       canonical,
+		Variants:    variants,
+		Stats:       combineStats(variants),
+		RecentPlays: plays,
+	}, nil
+}
+
+// SongMedia retrieves every media item linked to a canonical media item, including the canonical one.
+func (r *Resolver) SongMedia(ctx context.Context, canonicalID bson.ObjectID) ([]*models.Media, error) {
+	return r.mediaRepo.FindMany(ctx, bson.M{
+		"$or": bson.A{
+			bson.M{"_id": canonicalID},
+			bson.M{"canonicalId": canonicalID},
+		},
+	}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+}
+
+// combineStats adds up the statistics of every copy of a song.
+func combineStats(variants []*models.Media) models.MediaStats {
+	var stats models.MediaStats
+	var weightedRating float64
+	for _, variant := range variants {
+		stats.PlayCount += variant.Stats.PlayCount
+		stats.WootCount += variant.Stats.WootCount
+		stats.MehCount += variant.Stats.MehCount
+		stats.GrabCount += variant.Stats.GrabCount
+		stats.SkipCount += variant.Stats.SkipCount
+		weightedRating += variant.Stats.AggregateRating * float64(variant.Stats.PlayCount)
+		if variant.Stats.LastPlayed.After(stats.LastPlayed) {
+			stats.LastPlayed = variant.Stats.LastPlayed
+		}
+		if variant.Stats.LastUpdated.After(stats.LastUpdated) {
+			stats.LastUpdated = variant.Stats.LastUpdated
+		}
+	}
+	if stats.PlayCount > 0 {
+		stats.AggregateRating = weightedRating / float64(stats.PlayCount)
+	}
+	return stats
+}
+
+// DedupeMedia fingerprints all stored media and links copies of the same song from different providers,
+// first by ISRC, then by fingerprint and duration. The oldest copy of a song becomes canonical unless the
+// song already has a canonical copy. The plays of linked copies are moved to the song, so play history and
+// top tracks count them together.
+func (r *Resolver) DedupeMedia(ctx context.Context) (*DedupeReport, error) {
+	report := &DedupeReport{}
+	recordings := make(map[string][]*models.Media)
+	songs := make(map[string][]*models.Media)
+
+	var lastID bson.ObjectID
+	for {
+		filter := bson.M{}
+		if !lastID.IsZero() {
+			filter["_id"] = bson.M{"$gt": lastID}
+		}
+		batch, err := r.mediaRepo.FindMany(ctx, filter,
+			options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(dedupeBatchSize))
+		if err != nil {
+			return report, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		lastID = batch[len(batch)-1].ID
+
+		for _, media := range batch {
+			report.Scanned++
+			if fingerprint := Fingerprint(media.Title, media.Artist); fingerprint != media.Fingerprint {
+				media.Fingerprint = fingerprint
+				if err := r.mediaRepo.Update(ctx, media); err != nil {
+					return report, err
+				}
+				report.Fingerprinted++
+			}
+			if media.Metadata.ISRC != "" {
+				recordings[media.Metadata.ISRC] = append(recordings[media.Metadata.ISRC], media)
+			}
+			if media.Fingerprint != "" {
+				songs[media.Fingerprint] = append(songs[media.Fingerprint], media)
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+	}
+
+	var clusters [][]*models.Media
+	for _, group := range recordings {
+		clusters = append(clusters, group)
+	}
+	for _, group := range songs {
+		clusters = append(clusters, clusterByDuration(group)...)
+	}
+
+	for _, cluster := range clusters {
+		if err := r.linkCluster(ctx, cluster, report); err != nil {
+			return report, err
+		}
+	}
+
+	r.logger.Info("Media dedupe completed", "scanned", report.Scanned, "fingerprinted", report.Fingerprinted, "songs", report.Songs, "linked", report.Linked, "plays", report.Plays)
+	return report, nil
+}
+
+// clusterByDuration splits media sharing a fingerprint into groups of matching duration.
+func clusterByDuration(group []*models.Media) [][]*models.Media {
+	slices.SortFunc(group, func(a, b *models.Media) int {
+		return cmp.Compare(a.Duration, b.Duration)
+	})
+
+	var clusters [][]*models.Media
+	start := 0
+	for i := 1; i <= len(group); i++ {
+		if i == len(group) || !sameSong(group[start], group[i]) {
+			clusters = append(clusters, group[start:i])
+			start = i
+		}
+	}
+	return clusters
+}
+
+// linkCluster points every copy of a song at its canonical copy and moves their plays to the song.
+// Clusters that aren't a cross-provider song are left alone.
+func (r *Resolver) linkCluster(ctx context.Context, cluster []*models.Media, report *DedupeReport) error {
+	providers := make(map[string]bool)
+	for _, media := range cluster {
+		providers[media.Type] = true
+	}
+	if len(providers) < 2 {
+		return nil
+	}
+	report.Songs++
+
+	// Keep an existing canonical copy so links made at resolve time or by ISRC stay valid
+	canonical := slices.MinFunc(cluster, func(a, b *models.Media) int {
+		return bytes.Compare(a.ID[:], b.ID[:])
+	}).ID
+	if index := slices.IndexFunc(cluster, func(m *models.Media) bool { return !m.CanonicalID.IsZero() }); index >= 0 {
+		canonical = cluster[index].CanonicalID
+	}
+
+	for _, media := range cluster {
+		target := canonical
+		if media.ID == canonical {
+			target = bson.ObjectID{}
+		}
+		if media.CanonicalID != target {
+			media.CanonicalID = target
+			if err := r.mediaRepo.Update(ctx, media); err != nil {
+				return err
+			}
+			if !target.IsZero() {
+				report.Linked++
+			}
+		}
+
+		plays, err := r.mediaRepo.SetPlaysSong(ctx, media.ID, media.SongID())
+		if err != nil {
+			return err
+		}
+		report.Plays += plays
+	}
+
+	return nil
+}
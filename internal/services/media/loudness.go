@@ -0,0 +1,163 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// LoudnessAnalyzer measures the loudness of media whose provider doesn't expose it.
+type LoudnessAnalyzer interface {
+	// Analyze measures the loudness of a media item playable from the stream URL.
+	Analyze(ctx context.Context, media *models.Media, streamURL string) (*models.MediaLoudness, error)
+}
+
+// HTTPLoudnessAnalyzer asks an external analysis service to measure media loudness.
+type HTTPLoudnessAnalyzer struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewHTTPLoudnessAnalyzer creates an analyzer for the analysis service at the given URL.
+func NewHTTPLoudnessAnalyzer(url string) *HTTPLoudnessAnalyzer {
+	return &HTTPLoudnessAnalyzer{
+		url: url,
+		httpClient: &http.Client{
+			// Analysis has to fetch and decode the whole track
+			Timeout: 2 * time.Minute,
+		},
+	}
+}
+
+// loudnessRequest is the request body sent to the analysis service.
+type loudnessRequest struct {
+	Type      string `json:"type"`
+	SourceID  string `json:"sourceId"`
+	StreamURL string `json:"streamUrl"`
+}
+
+// loudnessResponse is the response body returned by the analysis service.
+type loudnessResponse struct {
+	IntegratedLUFS float64 `json:"integratedLufs"`
+	Peak           float64 `json:"peak"`
+}
+
+// Analyze measures the loudness of a media item.
+func (a *HTTPLoudnessAnalyzer) Analyze(ctx context.Context, media *models.Media, streamURL string) (*models.MediaLoudness, error) {
+	body, err := json.Marshal(loudnessRequest{
+		Type:      media.Type,
+		SourceID:  media.SourceID,
+		StreamURL: streamURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("loudness analysis failed with status %d", resp.StatusCode)
+	}
+
+	var result loudnessResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode loudness analysis: %w", err)
+	}
+
+	return &models.MediaLoudness{
+		IntegratedLUFS: result.IntegratedLUFS,
+		Peak:           result.Peak,
+		Source:         models.LoudnessSourceAnalysis,
+		MeasuredAt:     time.Now(),
+	}, nil
+}
+
+// LoudnessWorker measures the loudness of stored media that has none, a batch at a time.
+type LoudnessWorker struct {
+	mediaRepo repositories.MediaRepository
+	resolver  *Resolver
+	analyzer  LoudnessAnalyzer
+	batchSize int
+	logger    *utils.Logger
+
+	// lastID is where the next batch starts, so media that failed analysis doesn't hold up the rest
+	lastID bson.ObjectID
+}
+
+// NewLoudnessWorker creates a new loudness worker.
+func NewLoudnessWorker(mediaRepo repositories.MediaRepository, resolver *Resolver, analyzer LoudnessAnalyzer, batchSize int, logger *utils.Logger) *LoudnessWorker {
+	return &LoudnessWorker{
+		mediaRepo: mediaRepo,
+		resolver:  resolver,
+		analyzer:  analyzer,
+		batchSize: batchSize,
+		logger:    logger.Named("loudness_worker"),
+	}
+}
+
+// AnalyzeBatch measures the loudness of the next batch of media without loudness data.
+// Once it reaches the end of the media it starts over, retrying media that failed before.
+func (w *LoudnessWorker) AnalyzeBatch(ctx context.Context) error {
+	filter := bson.M{"metadata.loudness": bson.M{"$exists": false}}
+	if !w.lastID.IsZero() {
+		filter["_id"] = bson.M{"$gt": w.lastID}
+	}
+
+	batch, err := w.mediaRepo.FindMany(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(w.batchSize)))
+	if err != nil {
+		return err
+	}
+	if len(batch) < w.batchSize {
+		w.lastID = bson.ObjectID{}
+	} else {
+		w.lastID = batch[len(batch)-1].ID
+	}
+
+	analyzed := 0
+	for _, media := range batch {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		streamURL, err := w.resolver.GetStreamURL(ctx, media.Type, media.SourceID)
+		if err != nil {
+			w.logger.Debug("Skipping loudness analysis, no stream URL", "mediaId", media.ID.Hex(), "error", err)
+			continue
+		}
+
+		loudness, err := w.analyzer.Analyze(ctx, media, streamURL)
+		if err != nil {
+			w.logger.Error("Failed to analyze media loudness", err, "mediaId", media.ID.Hex())
+			continue
+		}
+
+		media.Metadata.Loudness = loudness
+		if err := w.mediaRepo.Update(ctx, media); err != nil {
+			return err
+		}
+		analyzed++
+	}
+
+	w.logger.Info("Analyzed media loudness", "scanned", len(batch), "analyzed", analyzed)
+	return nil
+}
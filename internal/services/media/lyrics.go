@@ -0,0 +1,311 @@
+package media
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// lyricsCacheKeyPrefix prefixes the keys of the cached lyrics of media items.
+	lyricsCacheKeyPrefix = "lyrics:"
+
+	// lyricsMissing is cached for media the provider has no lyrics for, so it isn't asked again until it expires.
+	lyricsMissing = "none"
+)
+
+// lrcTimestamp matches the timestamps at the start of a line of LRC lyrics, such as [01:23.45].
+var lrcTimestamp = regexp.MustCompile(`^\[(\d+):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+
+// LyricsProvider finds the lyrics of media.
+type LyricsProvider interface {
+	// Name returns the name of the provider, credited with the lyrics.
+	Name() string
+
+	// Lyrics finds the lyrics of a media item. It returns models.ErrLyricsNotFound when it has none.
+	Lyrics(ctx context.Context, media *models.Media) (*models.MediaLyrics, error)
+}
+
+// LRCLibProvider finds lyrics with an LRCLIB compatible API, which looks up tracks by title, artist and duration.
+type LRCLibProvider struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewLRCLibProvider creates a lyrics provider for the track lookup endpoint at the given URL.
+func NewLRCLibProvider(url string) *LRCLibProvider {
+	return &LRCLibProvider{
+		url:        url,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// lrcLibResponse is the response body returned by the track lookup endpoint.
+type lrcLibResponse struct {
+	Instrumental bool   `json:"instrumental"`
+	PlainLyrics  string `json:"plainLyrics"`
+	SyncedLyrics string `json:"syncedLyrics"`
+}
+
+// Name returns the name of the provider.
+func (p *LRCLibProvider) Name() string {
+	return "lrclib"
+}
+
+// Lyrics finds the lyrics of a media item, preferring synced lyrics.
+func (p *LRCLibProvider) Lyrics(ctx context.Context, media *models.Media) (*models.MediaLyrics, error) {
+	artist := media.Artist
+	if artist == "" {
+		artist = media.Metadata.ChannelTitle
+	}
+	if media.Title == "" || artist == "" {
+		return nil, models.ErrLyricsNotFound
+	}
+
+	query := url.Values{}
+	query.Set("track_name", media.Title)
+	query.Set("artist_name", artist)
+	query.Set("duration", strconv.Itoa(media.Duration))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, models.ErrLyricsNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("lyrics lookup failed with status %d", resp.StatusCode)
+	}
+
+	var result lrcLibResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode lyrics: %w", err)
+	}
+
+	lyrics := &models.MediaLyrics{
+		MediaID:   media.ID,
+		Provider:  p.Name(),
+		FetchedAt: time.Now(),
+	}
+	switch {
+	case result.SyncedLyrics != "":
+		lyrics.Synced = true
+		lyrics.Lines = ParseLRC(result.SyncedLyrics)
+	case result.PlainLyrics != "":
+		lyrics.Lines = plainLyricsLines(result.PlainLyrics)
+	case result.Instrumental:
+		lyrics.Instrumental = true
+		lyrics.Lines = []models.LyricsLine{}
+	default:
+		return nil, models.ErrLyricsNotFound
+	}
+
+	return lyrics, nil
+}
+
+// ParseLRC parses lyrics in the LRC format into lines ordered by time. Lines sung more than once may
+// carry several timestamps, and lines without one, such as the [ar:] and [ti:] tags, are skipped.
+func ParseLRC(lrc string) []models.LyricsLine {
+	lines := []models.LyricsLine{}
+	for raw := range strings.Lines(lrc) {
+		text := strings.TrimSpace(raw)
+
+		var times []int64
+		for {
+			match := lrcTimestamp.FindStringSubmatch(text)
+			if match == nil {
+				break
+			}
+			times = append(times, lrcMillis(match[1], match[2], match[3]))
+			text = text[len(match[0]):]
+		}
+
+		text = strings.TrimSpace(text)
+		for _, t := range times {
+			lines = append(lines, models.LyricsLine{Time: t, Text: text})
+		}
+	}
+
+	slices.SortStableFunc(lines, func(a, b models.LyricsLine) int {
+		return cmp.Compare(a.Time, b.Time)
+	})
+	return lines
+}
+
+// lrcMillis converts the minutes, seconds and fraction of an LRC timestamp to milliseconds.
+func lrcMillis(minutes, seconds, fraction string) int64 {
+	m, _ := strconv.ParseInt(minutes, 10, 64)
+	s, _ := strconv.ParseInt(seconds, 10, 64)
+
+	// The fraction is in hundredths of a second usually, but some files use tenths or thousandths
+	var ms int64
+	if fraction != "" {
+		f, _ := strconv.ParseInt(fraction, 10, 64)
+		for range 3 - len(fraction) {
+			f *= 10
+		}
+		ms = f
+	}
+	return (m*60+s)*1000 + ms
+}
+
+// plainLyricsLines splits lyrics without timing into lines.
+func plainLyricsLines(plain string) []models.LyricsLine {
+	lines := []models.LyricsLine{}
+	for raw := range strings.Lines(strings.TrimSpace(plain)) {
+		lines = append(lines, models.LyricsLine{Text: strings.TrimSpace(raw)})
+	}
+	return lines
+}
+
+// LyricsService attaches lyrics to media. Lyrics are cached per media item, and whether a media item
+// has any is recorded on it so clients know to offer a lyrics view during playback.
+type LyricsService struct {
+	mediaRepo   repositories.MediaRepository
+	redisClient *redis.Client
+	provider    LyricsProvider
+	cacheTTL    time.Duration
+	batchSize   int
+	logger      *utils.Logger
+
+	// lastID is where the next lookup batch starts, so media whose lookup failed doesn't hold up the rest
+	lastID bson.ObjectID
+}
+
+// NewLyricsService creates a new lyrics service.
+func NewLyricsService(mediaRepo repositories.MediaRepository, redisClient *redis.Client, provider LyricsProvider, cacheTTL time.Duration, batchSize int, logger *utils.Logger) *LyricsService {
+	return &LyricsService{
+		mediaRepo:   mediaRepo,
+		redisClient: redisClient,
+		provider:    provider,
+		cacheTTL:    cacheTTL,
+		batchSize:   batchSize,
+		logger:      logger.Named("lyrics_service"),
+	}
+}
+
+// GetLyrics gets the lyrics of a media item, from the cache or else from the provider.
+// It returns models.ErrLyricsNotFound when the media has none.
+func (s *LyricsService) GetLyrics(ctx context.Context, mediaID bson.ObjectID) (*models.MediaLyrics, error) {
+	cached, err := s.redisClient.Get(ctx, formatLyricsKey(mediaID))
+	if err != nil {
+		s.logger.Warn("Failed to get lyrics from cache", "mediaId", mediaID.Hex(), "error", err)
+	}
+	switch cached {
+	case "":
+		// Not cached, or expired
+	case lyricsMissing:
+		return nil, models.ErrLyricsNotFound
+	default:
+		var lyrics models.MediaLyrics
+		err := json.Unmarshal([]byte(cached), &lyrics)
+		if err == nil {
+			return &lyrics, nil
+		}
+		s.logger.Error("Failed to decode cached lyrics", err, "mediaId", mediaID.Hex())
+	}
+
+	media, err := s.mediaRepo.FindByID(ctx, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	return s.fetch(ctx, media)
+}
+
+// LookupBatch asks the provider for the lyrics of the next batch of media never looked up, so their
+// hasLyrics flag is set before anyone asks for them. Once it reaches the end of the media it starts over.
+func (s *LyricsService) LookupBatch(ctx context.Context) error {
+	filter := bson.M{"metadata.lyricsCheckedAt": bson.M{"$exists": false}}
+	if !s.lastID.IsZero() {
+		filter["_id"] = bson.M{"$gt": s.lastID}
+	}
+
+	batch, err := s.mediaRepo.FindMany(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(s.batchSize)))
+	if err != nil {
+		return err
+	}
+	if len(batch) < s.batchSize {
+		s.lastID = bson.ObjectID{}
+	} else {
+		s.lastID = batch[len(batch)-1].ID
+	}
+
+	found := 0
+	for _, media := range batch {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if _, err := s.fetch(ctx, media); err == nil {
+			found++
+		}
+	}
+
+	s.logger.Info("Looked up media lyrics", "scanned", len(batch), "found", found)
+	return nil
+}
+
+// fetch gets a media item's lyrics from the provider, caches them, and records whether it has any.
+// Lookups that fail for another reason than missing lyrics are neither cached nor recorded.
+func (s *LyricsService) fetch(ctx context.Context, media *models.Media) (*models.MediaLyrics, error) {
+	lyrics, err := s.provider.Lyrics(ctx, media)
+	if err != nil && !errors.Is(err, models.ErrLyricsNotFound) {
+		s.logger.Error("Failed to look up lyrics", err, "mediaId", media.ID.Hex(), "provider", s.provider.Name())
+		return nil, err
+	}
+
+	cached := lyricsMissing
+	if lyrics != nil {
+		data, err := json.Marshal(lyrics)
+		if err != nil {
+			return nil, err
+		}
+		cached = string(data)
+	}
+	if err := s.redisClient.Set(ctx, formatLyricsKey(media.ID), cached, s.cacheTTL); err != nil {
+		s.logger.Warn("Failed to cache lyrics", "mediaId", media.ID.Hex(), "error", err)
+	}
+
+	hasLyrics := lyrics != nil
+	if media.Metadata.LyricsCheckedAt == nil || media.Metadata.HasLyrics != hasLyrics {
+		if err := s.mediaRepo.SetLyricsChecked(ctx, media.ID, hasLyrics); err != nil {
+			s.logger.Error("Failed to record media lyrics status", err, "mediaId", media.ID.Hex())
+		}
+	}
+
+	if lyrics == nil {
+		return nil, models.ErrLyricsNotFound
+	}
+	return lyrics, nil
+}
+
+// formatLyricsKey formats the key of a media item's cached lyrics.
+func formatLyricsKey(mediaID bson.ObjectID) string {
+	return lyricsCacheKeyPrefix + mediaID.Hex()
+}
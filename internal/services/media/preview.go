@@ -0,0 +1,200 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+	"norelock.dev/listenify/backend/pkg/mediaproxy"
+)
+
+const (
+	// previewRateKeyPrefix prefixes the keys counting the previews each user fetched per window.
+	previewRateKeyPrefix = "preview:rate:"
+
+	// previewContentType is the content type of previews whose stream doesn't name one.
+	previewContentType = "audio/mpeg"
+)
+
+// PreviewPolicy controls the short previews of search results.
+type PreviewPolicy struct {
+	// Enabled turns previews on
+	Enabled bool
+
+	// Providers are the provider types whose terms of service permit previewing through a proxy.
+	// Other providers are never previewed.
+	Providers []string
+
+	// Duration is how much of the start of a track a preview plays
+	Duration time.Duration
+
+	// Bitrate is the bitrate in kbps previews are sized for, as streams are cut by bytes
+	Bitrate int
+
+	// CacheTTL is how long a fetched preview is served from the cache
+	CacheTTL time.Duration
+
+	// CacheSize is the most bytes of previews cached on this node
+	CacheSize int64
+
+	// RateLimit is the number of previews a user may fetch per RateWindow. Zero means no limit.
+	RateLimit int
+
+	// RateWindow is the window previews are counted in
+	RateWindow time.Duration
+}
+
+// Preview is the start of a track, for listening before adding it to a playlist.
+type Preview struct {
+	// Content is the audio of the preview
+	Content []byte
+
+	// ContentType is the MIME type of the audio
+	ContentType string
+
+	// Cached is whether the preview was served from the cache
+	Cached bool
+}
+
+// PreviewService proxies the first seconds of tracks found by searching, so users can listen to them
+// without leaving the app. Previews are only fetched from providers whose terms permit it, never for
+// media in the takedown registry, and are cached so popular results reach the provider once.
+type PreviewService struct {
+	resolver    *Resolver
+	redisClient *redis.Client
+	cache       *mediaproxy.MemoryCache
+	policy      PreviewPolicy
+	httpClient  *http.Client
+	logger      *utils.Logger
+}
+
+// NewPreviewService creates a new preview service.
+func NewPreviewService(resolver *Resolver, redisClient *redis.Client, policy PreviewPolicy, logger *utils.Logger) *PreviewService {
+	return &PreviewService{
+		resolver:    resolver,
+		redisClient: redisClient,
+		cache:       mediaproxy.NewMemoryCache(mediaproxy.WithDefaultTTL(policy.CacheTTL), mediaproxy.WithMaxSize(policy.CacheSize)),
+		policy:      policy,
+		httpClient:  &http.Client{Timeout: 15 * time.Second},
+		logger:      logger.Named("preview_service"),
+	}
+}
+
+// Preview gets the preview of a track for a user. It returns models.ErrPreviewUnavailable when the
+// provider can't be previewed, and models.ErrTooManyRequests when the user fetched too many previews.
+func (s *PreviewService) Preview(ctx context.Context, userID string, source string, sourceID string) (*Preview, error) {
+	if !s.policy.Enabled || !slices.Contains(s.policy.Providers, source) {
+		return nil, models.ErrPreviewUnavailable
+	}
+	if _, ok := s.resolver.providers[source]; !ok {
+		return nil, models.ErrPreviewUnavailable
+	}
+
+	if err := s.resolver.checkTakedown(ctx, source, sourceID); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkRateLimit(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	cacheKey := source + ":" + sourceID
+	if entry, ok := s.cache.Get(ctx, cacheKey); ok {
+		return &Preview{Content: entry.Content, ContentType: entry.ContentType, Cached: true}, nil
+	}
+
+	preview, err := s.fetch(ctx, source, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cache.Set(ctx, cacheKey, preview.Content, preview.ContentType, s.policy.CacheTTL); err != nil {
+		s.logger.Warn("Failed to cache preview", "source", source, "sourceId", sourceID, "error", err)
+	}
+
+	s.logger.Debug("Fetched preview", "source", source, "sourceId", sourceID, "bytes", len(preview.Content))
+	return preview, nil
+}
+
+// fetch fetches the first bytes of a track's stream, as many as the preview duration takes at the
+// policy's bitrate. Streams the provider doesn't expose at an absolute URL can't be previewed.
+func (s *PreviewService) fetch(ctx context.Context, source string, sourceID string) (*Preview, error) {
+	streamURL, err := s.resolver.GetStreamURL(ctx, source, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := url.Parse(streamURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, models.ErrPreviewUnavailable
+	}
+
+	size := s.previewBytes()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", size-1))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("preview fetch failed with status %d", resp.StatusCode)
+	}
+
+	// Streams ignoring the range are cut short here
+	content, err := io.ReadAll(io.LimitReader(resp.Body, size))
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("failed to read preview: %w", err)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = previewContentType
+	}
+
+	return &Preview{Content: content, ContentType: contentType}, nil
+}
+
+// previewBytes is the number of bytes a preview takes at the policy's bitrate.
+func (s *PreviewService) previewBytes() int64 {
+	return int64(s.policy.Bitrate) * 1000 / 8 * int64(s.policy.Duration/time.Second)
+}
+
+// checkRateLimit counts a preview against the user's window.
+func (s *PreviewService) checkRateLimit(ctx context.Context, userID string) error {
+	if s.policy.RateLimit <= 0 || s.policy.RateWindow <= 0 {
+		return nil
+	}
+
+	window := time.Now().Truncate(s.policy.RateWindow).Unix()
+	key := fmt.Sprintf("%s%s:%d", previewRateKeyPrefix, userID, window)
+
+	count, err := s.redisClient.Incr(ctx, key)
+	if err != nil {
+		s.logger.Error("Failed to check preview rate limit", err, "userId", userID)
+		return nil // Fail open, previews are cached and sized small
+	}
+	if count == 1 {
+		if err := s.redisClient.Expire(ctx, key, 2*s.policy.RateWindow); err != nil {
+			s.logger.Error("Failed to set preview rate limit expiry", err, "userId", userID)
+		}
+	}
+
+	if count > int64(s.policy.RateLimit) {
+		return models.ErrTooManyRequests
+	}
+	return nil
+}
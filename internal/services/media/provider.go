@@ -13,6 +13,7 @@ type Provider interface {
 	Search(ctx context.Context, query string, limit int) ([]models.MediaSearchResult, string, error)
 
 	// GetMediaInfo retrieves information about a media item.
+	// Providers that expose loudness data fill in the media's Metadata.Loudness.
 	GetMediaInfo(ctx context.Context, sourceID string) (*models.Media, error)
 
 	// GetStreamURL retrieves the streaming URL for a media item.
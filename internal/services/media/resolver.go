@@ -3,6 +3,7 @@ package media
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -15,15 +16,20 @@ import (
 type Resolver struct {
 	providers    map[string]Provider
 	mediaRepo    repositories.MediaRepository
+	takedownRepo repositories.TakedownRepository
 	logger       *utils.Logger
 	defaultLimit int
+
+	// searchRouter routes searches by cost when set
+	searchRouter *SearchRouter
 }
 
-// NewResolver creates a new media resolver.
-func NewResolver(mediaRepo repositories.MediaRepository, logger *utils.Logger) *Resolver {
+// NewResolver creates a new media resolver. Sources in the takedown registry are never resolved.
+func NewResolver(mediaRepo repositories.MediaRepository, takedownRepo repositories.TakedownRepository, logger *utils.Logger) *Resolver {
 	r := &Resolver{
 		providers:    make(map[string]Provider),
 		mediaRepo:    mediaRepo,
+		takedownRepo: takedownRepo,
 		logger:       logger.Named("media_resolver"),
 		defaultLimit: 20,
 	}
@@ -37,6 +43,12 @@ func NewResolver(mediaRepo repositories.MediaRepository, logger *utils.Logger) *
 	return r
 }
 
+// SetSearchRouter sets the router searches go through, to serve them from the cache and within
+// the providers' budgets.
+func (r *Resolver) SetSearchRouter(router *SearchRouter) {
+	r.searchRouter = router
+}
+
 // RegisterProvider registers a media provider.
 func (r *Resolver) RegisterProvider(provider Provider) {
 	r.providers[provider.GetType()] = provider
@@ -51,6 +63,10 @@ func (r *Resolver) Search(ctx context.Context, query string, source string, limi
 		limit = r.defaultLimit
 	}
 
+	if r.searchRouter != nil {
+		return r.searchRouter.Search(ctx, r.providers, query, source, limit)
+	}
+
 	response := &models.MediaSearchResponse{
 		Query:  query,
 		Source: source,
@@ -103,41 +119,113 @@ func (r *Resolver) Search(ctx context.Context, query string, source string, limi
 	return response, nil
 }
 
+// WithoutAgeRestricted returns a copy of a search response without its age-restricted results,
+// for users who didn't attest being adults.
+func WithoutAgeRestricted(response *models.MediaSearchResponse) *models.MediaSearchResponse {
+	filtered := *response
+	filtered.Results = make([]models.MediaSearchResult, 0, len(response.Results))
+	for _, result := range response.Results {
+		if !result.AgeRestricted {
+			filtered.Results = append(filtered.Results, result)
+		}
+	}
+	filtered.TotalResults = max(response.TotalResults-(len(response.Results)-len(filtered.Results)), 0)
+	return &filtered
+}
+
 // Resolve resolves a media item by its source and ID.
 func (r *Resolver) Resolve(ctx context.Context, source string, sourceID string, userID bson.ObjectID) (*models.Media, error) {
+	media, _, err := r.ResolveCreated(ctx, source, sourceID, userID)
+	return media, err
+}
+
+// ResolveCreated resolves a media item by its source and ID like Resolve. It also returns whether the item
+// was created by this call, rather than already stored or stored concurrently by another.
+func (r *Resolver) ResolveCreated(ctx context.Context, source string, sourceID string, userID bson.ObjectID) (*models.Media, bool, error) {
 	r.logger.Debug("Resolving media", "source", source, "sourceID", sourceID)
 
 	// Check if the media is already in the database
 	media, err := r.mediaRepo.FindBySourceID(ctx, source, sourceID)
 	if err == nil {
 		// Media found in database
-		return media, nil
+		if !media.TakedownID.IsZero() {
+			return nil, false, models.ErrMediaTakenDown
+		}
+		return media, false, nil
+	}
+
+	// Taken down sources are never looked up again
+	if err := r.checkTakedown(ctx, source, sourceID); err != nil {
+		return nil, false, err
 	}
 
 	// If not found, resolve it from the provider
 	provider, ok := r.providers[source]
 	if !ok {
-		return nil, fmt.Errorf("unknown provider: %s", source)
+		return nil, false, fmt.Errorf("unknown provider: %s", source)
 	}
 
 	media, err = provider.GetMediaInfo(ctx, sourceID)
 	if err != nil {
 		r.logger.Error("Error resolving media", err, "source", source, "sourceID", sourceID)
-		return nil, err
+		return nil, false, err
 	}
 
 	// Set the user who added the media
 	media.AddedBy = userID
 	media.CreateNow()
 
-	// Save the media to the database
+	// Link the media to the same song from other providers
+	r.linkCanonical(ctx, media)
+
+	// Save the media to the database. When another request stored the source first,
+	// its item is returned instead, with its own ID.
+	id := bson.NewObjectID()
+	media.ID = id
 	err = r.mediaRepo.Create(ctx, media)
 	if err != nil {
 		r.logger.Error("Error saving media", err, "source", source, "sourceID", sourceID)
-		return nil, err
+		return nil, false, err
 	}
 
-	return media, nil
+	return media, media.ID == id, nil
+}
+
+// ImportMedia stores a media item described by another platform, such as in imported play history, without
+// looking it up on its provider. If the source's media is already stored, that item is returned instead.
+// It also returns whether the item was created.
+func (r *Resolver) ImportMedia(ctx context.Context, media *models.Media) (*models.Media, bool, error) {
+	existing, err := r.mediaRepo.FindBySourceID(ctx, media.Type, media.SourceID)
+	if err == nil {
+		return existing, false, nil
+	}
+	if !errors.Is(err, models.ErrMediaNotFound) {
+		return nil, false, err
+	}
+	if err := r.checkTakedown(ctx, media.Type, media.SourceID); err != nil {
+		return nil, false, err
+	}
+
+	media.CreateNow()
+	r.linkCanonical(ctx, media)
+
+	if err := r.mediaRepo.Create(ctx, media); err != nil {
+		r.logger.Error("Error saving imported media", err, "source", media.Type, "sourceID", media.SourceID)
+		return nil, false, err
+	}
+	return media, true, nil
+}
+
+// checkTakedown checks a media source isn't in the takedown registry.
+func (r *Resolver) checkTakedown(ctx context.Context, source string, sourceID string) error {
+	_, err := r.takedownRepo.FindTakedownBySource(ctx, source, sourceID)
+	if err == nil {
+		return models.ErrMediaTakenDown
+	}
+	if errors.Is(err, models.ErrTakedownNotFound) {
+		return nil
+	}
+	return err
 }
 
 // GetStreamURL retrieves the streaming URL for a media item.
@@ -0,0 +1,231 @@
+package media
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// searchCacheKeyPrefix prefixes the keys of cached search responses.
+	searchCacheKeyPrefix = "search:cache:"
+
+	// searchSpendKeyPrefix prefixes the keys counting what each provider's searches cost per day.
+	searchSpendKeyPrefix = "search:spend:"
+
+	// searchSpendRetention is how long daily spend counters are kept, past the end of their day.
+	searchSpendRetention = 48 * time.Hour
+)
+
+// SearchBudget is what searching a provider costs and how much may be spent on it per day.
+type SearchBudget struct {
+	// Cost is the quota cost of one search. Providers without a cost are searched first.
+	Cost int
+
+	// Daily is the quota that may be spent on searches per day. Zero means no limit.
+	Daily int
+}
+
+// SearchRoutingPolicy controls how searches are routed to the providers.
+type SearchRoutingPolicy struct {
+	// CacheTTL is how long search responses are served from the cache. Zero disables the cache.
+	CacheTTL time.Duration
+
+	// Budgets are the search budgets of the providers, by provider type
+	Budgets map[string]SearchBudget
+
+	// Location is the time zone in which the daily budgets reset
+	Location *time.Location
+}
+
+// SearchRouter routes media searches by cost: cached responses are served first, then providers
+// are searched cheapest first while their daily budgets last. A provider whose budget is spent is
+// skipped for the rest of the day, and the response is flagged partial instead of failing.
+type SearchRouter struct {
+	redisClient *redis.Client
+	policy      SearchRoutingPolicy
+	logger      *utils.Logger
+}
+
+// NewSearchRouter creates a new search router.
+func NewSearchRouter(redisClient *redis.Client, policy SearchRoutingPolicy, logger *utils.Logger) *SearchRouter {
+	if policy.Location == nil {
+		policy.Location = time.UTC
+	}
+	return &SearchRouter{
+		redisClient: redisClient,
+		policy:      policy,
+		logger:      logger.Named("search_router"),
+	}
+}
+
+// Search searches the providers for a query. The source is a provider type or "all".
+func (s *SearchRouter) Search(ctx context.Context, providers map[string]Provider, query string, source string, limit int) (*models.MediaSearchResponse, error) {
+	if source != "all" {
+		if _, ok := providers[source]; !ok {
+			return nil, fmt.Errorf("unknown provider: %s", source)
+		}
+	}
+
+	cacheKey := s.cacheKey(query, source, limit)
+	if response := s.cached(ctx, cacheKey); response != nil {
+		return response, nil
+	}
+
+	response := &models.MediaSearchResponse{
+		Query:  query,
+		Source: source,
+	}
+
+	var order []string
+	if source == "all" {
+		order = s.cheapestFirst(providers)
+	} else {
+		order = []string{source}
+	}
+
+	var lastErr error
+	for _, providerType := range order {
+		remaining := limit - len(response.Results)
+		if remaining <= 0 {
+			break
+		}
+
+		if !s.reserve(ctx, providerType) {
+			response.Partial = true
+			response.SkippedProviders = append(response.SkippedProviders, providerType)
+			continue
+		}
+
+		results, nextPageToken, err := providers[providerType].Search(ctx, query, remaining)
+		if err != nil {
+			s.logger.Error("Error searching provider", err, "provider", providerType)
+			response.Partial = true
+			response.SkippedProviders = append(response.SkippedProviders, providerType)
+			lastErr = err
+			continue
+		}
+
+		response.Results = append(response.Results, results...)
+		if response.NextPageToken == "" && nextPageToken != "" {
+			if source == "all" {
+				response.NextPageToken = fmt.Sprintf("%s:%s", providerType, nextPageToken)
+			} else {
+				response.NextPageToken = nextPageToken
+			}
+		}
+	}
+
+	// A failing provider is only an error when it was the one searched, an exhausted budget never is
+	if source != "all" && lastErr != nil {
+		return nil, lastErr
+	}
+
+	if response.Results == nil {
+		response.Results = []models.MediaSearchResult{}
+	}
+	response.TotalResults = len(response.Results)
+
+	// Partial responses would hide the skipped providers' results for as long as they are cached
+	if !response.Partial {
+		s.cache(ctx, cacheKey, response)
+	}
+
+	return response, nil
+}
+
+// cheapestFirst orders providers by search cost, cheapest first.
+func (s *SearchRouter) cheapestFirst(providers map[string]Provider) []string {
+	order := make([]string, 0, len(providers))
+	for providerType := range providers {
+		order = append(order, providerType)
+	}
+	slices.SortFunc(order, func(a, b string) int {
+		if costA, costB := s.policy.Budgets[a].Cost, s.policy.Budgets[b].Cost; costA != costB {
+			return costA - costB
+		}
+		return strings.Compare(a, b)
+	})
+	return order
+}
+
+// reserve spends a search's cost from a provider's daily budget, and reports whether it fit.
+func (s *SearchRouter) reserve(ctx context.Context, providerType string) bool {
+	budget := s.policy.Budgets[providerType]
+	if budget.Daily <= 0 || budget.Cost <= 0 {
+		return true
+	}
+
+	key := s.spendKey(providerType)
+	spent, err := s.redisClient.IncrBy(ctx, key, int64(budget.Cost))
+	if err != nil {
+		s.logger.Error("Failed to count provider search spend", err, "provider", providerType)
+		return true // Fail open, Redis hiccups shouldn't stop searches
+	}
+	if spent == int64(budget.Cost) {
+		if err := s.redisClient.Expire(ctx, key, searchSpendRetention); err != nil {
+			s.logger.Error("Failed to set provider search spend expiry", err, "provider", providerType)
+		}
+	}
+
+	if spent > int64(budget.Daily) {
+		if _, err := s.redisClient.DecrBy(ctx, key, int64(budget.Cost)); err != nil {
+			s.logger.Error("Failed to release provider search spend", err, "provider", providerType)
+		}
+		s.logger.Debug("Provider search budget exhausted", "provider", providerType, "daily", budget.Daily)
+		return false
+	}
+	return true
+}
+
+// cached gets a cached search response, or nil if there is none.
+func (s *SearchRouter) cached(ctx context.Context, key string) *models.MediaSearchResponse {
+	if s.policy.CacheTTL <= 0 {
+		return nil
+	}
+
+	data, err := s.redisClient.Get(ctx, key)
+	if err != nil || data == "" {
+		return nil
+	}
+
+	var response models.MediaSearchResponse
+	if err := json.Unmarshal([]byte(data), &response); err != nil {
+		s.logger.Error("Failed to decode cached search response", err)
+		return nil
+	}
+	response.Cached = true
+	return &response
+}
+
+// cache stores a search response.
+func (s *SearchRouter) cache(ctx context.Context, key string, response *models.MediaSearchResponse) {
+	if s.policy.CacheTTL <= 0 {
+		return
+	}
+
+	if err := s.redisClient.SetObject(ctx, key, response, s.policy.CacheTTL); err != nil {
+		s.logger.Error("Failed to cache search response", err, "query", response.Query)
+	}
+}
+
+// cacheKey gets the cache key of a search, equal for queries differing only in case and spacing.
+func (s *SearchRouter) cacheKey(query, source string, limit int) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	sum := sha1.Sum([]byte(normalized))
+	return fmt.Sprintf("%s%s:%d:%s", searchCacheKeyPrefix, source, limit, hex.EncodeToString(sum[:]))
+}
+
+// spendKey gets the key counting a provider's search spend today, in the budgets' time zone.
+func (s *SearchRouter) spendKey(providerType string) string {
+	return searchSpendKeyPrefix + providerType + ":" + time.Now().In(s.policy.Location).Format("2006-01-02")
+}
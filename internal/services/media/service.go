@@ -81,11 +81,12 @@ func (s *MediaService) SearchMedia(ctx context.Context, query string, limit int)
 			Thumbnail: result.Thumbnail,
 			Duration:  result.Duration,
 			Metadata: models.MediaMetadata{
-				Views:        result.Views,
-				PublishedAt:  result.PublishedAt,
-				Description:  result.Description,
-				ChannelTitle: result.ChannelTitle,
-				Restricted:   result.Restricted,
+				Views:         result.Views,
+				PublishedAt:   result.PublishedAt,
+				Description:   result.Description,
+				ChannelTitle:  result.ChannelTitle,
+				Restricted:    result.Restricted,
+				AgeRestricted: result.AgeRestricted,
 			},
 			// Set a placeholder ID
 			ID: bson.NewObjectID(),
@@ -109,34 +110,12 @@ func (s *MediaService) GetStreamURL(ctx context.Context, source string, sourceID
 	return s.resolver.GetStreamURL(ctx, source, sourceID)
 }
 
-// ExtractSourceInfo extracts the source and sourceID from a URL.
-func ExtractSourceInfo(url string) (string, string, error) {
-	// This is a simplified implementation
-	// In a real implementation, this would use regex or URL parsing to extract the source and sourceID
-
-	// Check for YouTube
-	if ytID, err := extractYouTubeID(url); err == nil {
-		return "youtube", ytID, nil
-	}
-
-	// Check for SoundCloud
-	if scID, err := extractSoundCloudID(url); err == nil {
-		return "soundcloud", scID, nil
+// ExtractSourceInfo extracts the source and sourceID from a URL, canonicalized by ParseSourceURL.
+// SoundCloud tracks are identified by their permalink path, such as "artist/track".
+func ExtractSourceInfo(rawURL string) (string, string, error) {
+	sourceURL, err := ParseSourceURL(rawURL)
+	if err != nil {
+		return "", "", err
 	}
-
-	return "", "", fmt.Errorf("unsupported URL format: %s", url)
-}
-
-// extractYouTubeID extracts the video ID from a YouTube URL.
-func extractYouTubeID(url string) (string, error) {
-	// This is a simplified implementation
-	// In a real implementation, this would use regex or URL parsing to extract the video ID
-	return "placeholder_youtube_id", nil
-}
-
-// extractSoundCloudID extracts the track ID from a SoundCloud URL.
-func extractSoundCloudID(url string) (string, error) {
-	// This is a simplified implementation
-	// In a real implementation, this would use regex or URL parsing to extract the track ID
-	return "placeholder_soundcloud_id", nil
+	return sourceURL.Source, sourceURL.SourceID, nil
 }
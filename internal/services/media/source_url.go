@@ -0,0 +1,183 @@
+package media
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+var (
+	// youtubeIDRegex matches a YouTube video ID.
+	youtubeIDRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{11}$`)
+
+	// soundCloudNameRegex matches a SoundCloud user or track permalink.
+	soundCloudNameRegex = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+	// youtubeVideoPaths are the path prefixes followed by a video ID on YouTube.
+	youtubeVideoPaths = []string{"embed", "v", "e", "shorts", "live"}
+
+	// soundCloudReservedPaths are the first path segments of SoundCloud pages that aren't user profiles.
+	soundCloudReservedPaths = []string{
+		"discover", "search", "stream", "you", "charts", "upload", "settings", "messages", "notifications",
+		"pages", "mobile", "tags", "popular", "stations", "terms-of-use", "pro", "imprint", "signin",
+	}
+
+	// soundCloudProfilePaths are the second path segments of SoundCloud profile pages rather than tracks.
+	soundCloudProfilePaths = []string{
+		"sets", "likes", "tracks", "albums", "reposts", "followers", "following", "popular-tracks",
+		"comments", "spotlight", "toptracks",
+	}
+
+	// soundCloudTrackViews are the third path segments of other views of a SoundCloud track's page.
+	soundCloudTrackViews = []string{"recommendations", "comments", "likes", "reposts", "sets"}
+)
+
+// sourceURLRule extracts the source ID from a URL of one provider's hosts. The host is lowercase and
+// without a "www." or "m." prefix.
+type sourceURLRule struct {
+	// source is the provider the rule is for.
+	source string
+
+	// sourceID extracts the source ID from a URL.
+	sourceID func(u *url.URL) (string, error)
+
+	// canonical formats the canonical URL of a source ID.
+	canonical func(sourceID string) string
+}
+
+// youtubeRule handles YouTube watch, embed, shorts and live URLs, and youtu.be short links. Tracking and
+// playlist parameters are dropped, playlist-context URLs keep only the video.
+var youtubeRule = &sourceURLRule{
+	source: "youtube",
+	sourceID: func(u *url.URL) (string, error) {
+		segments := pathSegments(u.Path)
+
+		var id string
+		switch {
+		case u.Host == "youtu.be" && len(segments) > 0:
+			id = segments[0]
+		case len(segments) > 0 && segments[0] == "watch":
+			id = u.Query().Get("v")
+		case len(segments) > 1 && slices.Contains(youtubeVideoPaths, segments[0]):
+			id = segments[1]
+		case len(segments) > 0 && segments[0] == "playlist":
+			return "", fmt.Errorf("%w: links to a playlist, not a video", models.ErrMediaCantBeResolved)
+		}
+
+		if !youtubeIDRegex.MatchString(id) {
+			return "", fmt.Errorf("%w: no YouTube video ID in the link", models.ErrMediaCantBeResolved)
+		}
+		return id, nil
+	},
+	canonical: func(sourceID string) string {
+		return "https://www.youtube.com/watch?v=" + sourceID
+	},
+}
+
+// soundCloudRule handles SoundCloud track pages, from the website and the mobile website. The source ID
+// is the track's permalink path, such as "artist/track".
+var soundCloudRule = &sourceURLRule{
+	source: "soundcloud",
+	sourceID: func(u *url.URL) (string, error) {
+		segments := pathSegments(strings.ToLower(u.Path))
+
+		// Other views of a track's page point at the track
+		if len(segments) == 3 && slices.Contains(soundCloudTrackViews, segments[2]) {
+			segments = segments[:2]
+		}
+		if len(segments) == 0 || slices.Contains(soundCloudReservedPaths, segments[0]) {
+			return "", fmt.Errorf("%w: not a SoundCloud track", models.ErrMediaCantBeResolved)
+		}
+		if len(segments) == 1 || slices.Contains(soundCloudProfilePaths, segments[1]) {
+			return "", fmt.Errorf("%w: links to a SoundCloud profile or playlist, not a track", models.ErrMediaCantBeResolved)
+		}
+		if len(segments) != 2 {
+			return "", fmt.Errorf("%w: not a SoundCloud track", models.ErrMediaCantBeResolved)
+		}
+		if !soundCloudNameRegex.MatchString(segments[0]) || !soundCloudNameRegex.MatchString(segments[1]) {
+			return "", fmt.Errorf("%w: not a SoundCloud track", models.ErrMediaCantBeResolved)
+		}
+		return segments[0] + "/" + segments[1], nil
+	},
+	canonical: func(sourceID string) string {
+		return "https://soundcloud.com/" + sourceID
+	},
+}
+
+// soundCloudShortLinkRule rejects the on.soundcloud.com share links. They only point at a track through a
+// redirect served by SoundCloud, which isn't followed when parsing a link.
+var soundCloudShortLinkRule = &sourceURLRule{
+	source: "soundcloud",
+	sourceID: func(u *url.URL) (string, error) {
+		return "", fmt.Errorf("%w: SoundCloud short links aren't supported, paste the link of the track's page instead", models.ErrMediaCantBeResolved)
+	},
+}
+
+// sourceURLRules are the rules for each media provider host.
+var sourceURLRules = map[string]*sourceURLRule{
+	"youtube.com":          youtubeRule,
+	"music.youtube.com":    youtubeRule,
+	"youtube-nocookie.com": youtubeRule,
+	"youtu.be":             youtubeRule,
+	"soundcloud.com":       soundCloudRule,
+	"on.soundcloud.com":    soundCloudShortLinkRule,
+}
+
+// SourceURL is a media URL normalized to the provider and source ID it points at.
+type SourceURL struct {
+	// Source is the media provider (e.g., "youtube", "soundcloud").
+	Source string `json:"source"`
+
+	// SourceID is the ID of the media on the provider.
+	SourceID string `json:"sourceId"`
+
+	// URL is the canonical URL of the media.
+	URL string `json:"url"`
+}
+
+// ParseSourceURL normalizes a pasted media URL into the provider and source ID it points at. Short
+// links, mobile links, tracking parameters and playlist context are all reduced to the same media.
+// URLs that don't point at a single track return an error wrapping models.ErrMediaCantBeResolved.
+func ParseSourceURL(rawURL string) (*SourceURL, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return nil, fmt.Errorf("%w: empty link", models.ErrMediaCantBeResolved)
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("%w: not a link", models.ErrMediaCantBeResolved)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	host = strings.TrimPrefix(host, "www.")
+	host = strings.TrimPrefix(host, "m.")
+	u.Host = host
+
+	rule := sourceURLRules[host]
+	if rule == nil {
+		return nil, fmt.Errorf("%w: not a supported media link", models.ErrMediaCantBeResolved)
+	}
+
+	sourceID, err := rule.sourceID(u)
+	if err != nil {
+		return nil, err
+	}
+	return &SourceURL{
+		Source:   rule.source,
+		SourceID: sourceID,
+		URL:      rule.canonical(sourceID),
+	}, nil
+}
+
+// pathSegments splits a URL path into its non-empty segments.
+func pathSegments(path string) []string {
+	return strings.FieldsFunc(path, func(r rune) bool { return r == '/' })
+}
@@ -0,0 +1,102 @@
+package media
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+func TestParseSourceURL(t *testing.T) {
+	const videoID = "dQw4w9WgXcQ"
+	youtube := &SourceURL{Source: "youtube", SourceID: videoID, URL: "https://www.youtube.com/watch?v=" + videoID}
+	soundCloud := &SourceURL{Source: "soundcloud", SourceID: "artist/track-name", URL: "https://soundcloud.com/artist/track-name"}
+
+	tests := []struct {
+		name string
+		url  string
+		want *SourceURL
+
+		// errContains is part of the error message expected when want is nil
+		errContains string
+	}{
+		// YouTube
+		{name: "watch", url: "https://www.youtube.com/watch?v=" + videoID, want: youtube},
+		{name: "watch without scheme", url: "youtube.com/watch?v=" + videoID, want: youtube},
+		{name: "watch with surrounding spaces", url: "  https://www.youtube.com/watch?v=" + videoID + "\n", want: youtube},
+		{name: "watch with uppercase host", url: "https://WWW.YouTube.com/watch?v=" + videoID, want: youtube},
+		{name: "watch in a playlist", url: "https://www.youtube.com/watch?v=" + videoID + "&list=PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI&index=4", want: youtube},
+		{name: "watch with share and tracking params", url: "https://www.youtube.com/watch?v=" + videoID + "&si=Xk3fG0aB1cD2eF3g&feature=share&utm_source=twitter", want: youtube},
+		{name: "watch with a start time", url: "https://www.youtube.com/watch?t=42&v=" + videoID, want: youtube},
+		{name: "short link", url: "https://youtu.be/" + videoID, want: youtube},
+		{name: "short link with share param", url: "https://youtu.be/" + videoID + "?si=Xk3fG0aB1cD2eF3g", want: youtube},
+		{name: "short link with start time", url: "youtu.be/" + videoID + "?t=42", want: youtube},
+		{name: "shorts", url: "https://www.youtube.com/shorts/" + videoID, want: youtube},
+		{name: "shorts with share param", url: "https://youtube.com/shorts/" + videoID + "?si=Xk3fG0aB1cD2eF3g", want: youtube},
+		{name: "embed", url: "https://www.youtube.com/embed/" + videoID, want: youtube},
+		{name: "privacy enhanced embed", url: "https://www.youtube-nocookie.com/embed/" + videoID + "?start=10", want: youtube},
+		{name: "live", url: "https://www.youtube.com/live/" + videoID + "?feature=share", want: youtube},
+		{name: "mobile", url: "https://m.youtube.com/watch?v=" + videoID + "&feature=youtu.be", want: youtube},
+		{name: "music", url: "https://music.youtube.com/watch?v=" + videoID + "&list=RDAMVM" + videoID, want: youtube},
+		{name: "playlist", url: "https://www.youtube.com/playlist?list=PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI", errContains: "playlist"},
+		{name: "music playlist", url: "https://music.youtube.com/playlist?list=OLAK5uy_kQ3nH8Tz1v", errContains: "playlist"},
+		{name: "watch with a playlist only", url: "https://www.youtube.com/watch?list=PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI", errContains: "no YouTube video ID"},
+		{name: "invalid video ID", url: "https://youtu.be/too-short", errContains: "no YouTube video ID"},
+		{name: "channel", url: "https://www.youtube.com/@artist", errContains: "no YouTube video ID"},
+		{name: "home page", url: "https://www.youtube.com/", errContains: "no YouTube video ID"},
+
+		// SoundCloud
+		{name: "track", url: "https://soundcloud.com/artist/track-name", want: soundCloud},
+		{name: "track without scheme", url: "soundcloud.com/artist/track-name", want: soundCloud},
+		{name: "track with share params", url: "https://soundcloud.com/artist/track-name?si=5f1c2e&utm_source=clipboard&utm_medium=text", want: soundCloud},
+		{name: "track with trailing slash", url: "https://soundcloud.com/artist/track-name/", want: soundCloud},
+		{name: "track with mixed case", url: "https://soundcloud.com/Artist/Track-Name", want: soundCloud},
+		{name: "mobile track", url: "https://m.soundcloud.com/artist/track-name", want: soundCloud},
+		{name: "www track", url: "https://www.soundcloud.com/artist/track-name", want: soundCloud},
+		{name: "track comments", url: "https://soundcloud.com/artist/track-name/comments", want: soundCloud},
+		{name: "track recommendations", url: "https://soundcloud.com/artist/track-name/recommendations", want: soundCloud},
+		{name: "profile", url: "https://soundcloud.com/artist", errContains: "profile or playlist"},
+		{name: "mobile profile", url: "https://m.soundcloud.com/artist", errContains: "profile or playlist"},
+		{name: "profile tracks", url: "https://soundcloud.com/artist/tracks", errContains: "profile or playlist"},
+		{name: "profile likes", url: "https://soundcloud.com/artist/likes", errContains: "profile or playlist"},
+		{name: "set", url: "https://soundcloud.com/artist/sets/summer-mix", errContains: "profile or playlist"},
+		{name: "mobile set", url: "https://m.soundcloud.com/artist/sets/summer-mix", errContains: "profile or playlist"},
+		{name: "reserved page", url: "https://soundcloud.com/discover/sets/weekly", errContains: "not a SoundCloud track"},
+		{name: "search", url: "https://soundcloud.com/search?q=artist", errContains: "not a SoundCloud track"},
+		{name: "home page", url: "https://soundcloud.com/", errContains: "not a SoundCloud track"},
+		{name: "short link", url: "https://on.soundcloud.com/a1B2c3D4e5F6g7H8", errContains: "short links"},
+
+		// Other links
+		{name: "empty", url: "   ", errContains: "empty link"},
+		{name: "unsupported scheme", url: "ftp://youtube.com/watch?v=" + videoID, errContains: "not a link"},
+		{name: "unsupported provider", url: "https://vimeo.com/123456789", errContains: "not a supported media link"},
+		{name: "lookalike host", url: "https://youtube.com.example.com/watch?v=" + videoID, errContains: "not a supported media link"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSourceURL(tt.url)
+
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("ParseSourceURL(%q) = %+v, want an error", tt.url, got)
+				}
+				if !errors.Is(err, models.ErrMediaCantBeResolved) {
+					t.Errorf("ParseSourceURL(%q) error = %v, want it to wrap models.ErrMediaCantBeResolved", tt.url, err)
+				}
+				if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("ParseSourceURL(%q) error = %q, want it to contain %q", tt.url, err, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseSourceURL(%q) error = %v", tt.url, err)
+			}
+			if *got != *tt.want {
+				t.Errorf("ParseSourceURL(%q) = %+v, want %+v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
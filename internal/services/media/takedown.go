@@ -0,0 +1,165 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// TakedownService takes media down after DMCA and other content complaints. Taken down sources are kept in
+// a registry so they can't be resolved again, and the playlist items holding them are flagged for their
+// owners instead of being deleted.
+type TakedownService struct {
+	takedownRepo repositories.TakedownRepository
+	mediaRepo    repositories.MediaRepository
+	playlistRepo repositories.PlaylistRepository
+	logger       *utils.Logger
+
+	// ownerHandlers are notified of the playlist items flagged by a takedown, to tell their owners
+	ownerHandlers []func(ctx context.Context, items *models.TakenDownItems)
+}
+
+// NewTakedownService creates a new takedown service.
+func NewTakedownService(takedownRepo repositories.TakedownRepository, mediaRepo repositories.MediaRepository, playlistRepo repositories.PlaylistRepository, logger *utils.Logger) *TakedownService {
+	return &TakedownService{
+		takedownRepo: takedownRepo,
+		mediaRepo:    mediaRepo,
+		playlistRepo: playlistRepo,
+		logger:       logger.Named("takedown_service"),
+	}
+}
+
+// AddOwnerHandler adds a handler called for every playlist with items flagged by a takedown.
+func (s *TakedownService) AddOwnerHandler(handler func(ctx context.Context, items *models.TakenDownItems)) {
+	s.ownerHandlers = append(s.ownerHandlers, handler)
+}
+
+// TakeDown takes a media source down, given by its stored media item or its URL. The source is added to the
+// takedown registry, its media item is marked, and the items of every playlist holding it are flagged.
+func (s *TakedownService) TakeDown(ctx context.Context, adminID bson.ObjectID, request *models.MediaTakedownRequest) (*models.MediaTakedown, error) {
+	request.URL = strings.TrimSpace(request.URL)
+	request.Complainant = strings.TrimSpace(request.Complainant)
+	request.Details = strings.TrimSpace(request.Details)
+	if err := utils.Validate(request); err != nil {
+		return nil, models.NewUserError(models.ErrInvalidTakedown, err.Error(), http.StatusBadRequest)
+	}
+	if request.MediaID.IsZero() == (request.URL == "") {
+		return nil, models.NewUserError(models.ErrInvalidTakedown, "Give either the media or its URL", http.StatusBadRequest)
+	}
+
+	takedown := &models.MediaTakedown{
+		Reason:      request.Reason,
+		Complainant: request.Complainant,
+		Details:     request.Details,
+		TakenDownBy: adminID,
+	}
+
+	media, err := s.findMedia(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if media != nil {
+		if !media.TakedownID.IsZero() {
+			return nil, models.ErrAlreadyTakenDown
+		}
+		takedown.MediaID = media.ID
+		takedown.Type = media.Type
+		takedown.SourceID = media.SourceID
+		takedown.Title = media.Title
+	} else {
+		takedown.Type, takedown.SourceID, _ = ExtractSourceInfo(request.URL)
+	}
+
+	if err := s.takedownRepo.CreateTakedown(ctx, takedown); err != nil {
+		return nil, err
+	}
+
+	if media != nil {
+		if err := s.mediaRepo.SetTakedown(ctx, media.ID, takedown.ID); err != nil {
+			return nil, err
+		}
+		takedown.Playlists, takedown.Items = s.flagPlaylists(ctx, takedown)
+
+		if err := s.takedownRepo.SetTakedownCounts(ctx, takedown.ID, takedown.Playlists, takedown.Items); err != nil {
+			s.logger.Error("Failed to record takedown counts", err, "takedownId", takedown.ID.Hex())
+		}
+	}
+
+	s.logger.Info("Media taken down", "takedownId", takedown.ID.Hex(), "type", takedown.Type, "sourceId", takedown.SourceID,
+		"reason", takedown.Reason, "playlists", takedown.Playlists, "items", takedown.Items, "adminId", adminID.Hex())
+	return takedown, nil
+}
+
+// findMedia finds the stored media item of a takedown request, nil when its URL's source was never stored.
+func (s *TakedownService) findMedia(ctx context.Context, request *models.MediaTakedownRequest) (*models.Media, error) {
+	if !request.MediaID.IsZero() {
+		return s.mediaRepo.FindByID(ctx, request.MediaID)
+	}
+
+	source, sourceID, err := ExtractSourceInfo(request.URL)
+	if err != nil {
+		return nil, models.NewUserError(models.ErrInvalidTakedown, "Unsupported media URL", http.StatusBadRequest)
+	}
+
+	media, err := s.mediaRepo.FindBySourceID(ctx, source, sourceID)
+	if err != nil {
+		if errors.Is(err, models.ErrMediaNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return media, nil
+}
+
+// flagPlaylists flags the items of every playlist holding taken down media and tells their owners. It
+// returns how many playlists and items were flagged. Playlists that fail to be flagged are logged and skipped.
+func (s *TakedownService) flagPlaylists(ctx context.Context, takedown *models.MediaTakedown) (playlists, items int) {
+	holding, err := s.playlistRepo.FindMany(ctx, bson.M{"items.mediaId": takedown.MediaID}, nil)
+	if err != nil {
+		s.logger.Error("Failed to find playlists holding taken down media", err, "takedownId", takedown.ID.Hex())
+		return 0, 0
+	}
+
+	for _, playlist := range holding {
+		itemIDs, err := s.playlistRepo.FlagTakenDownItems(ctx, playlist.ID, takedown.MediaID, takedown.ID)
+		if err != nil {
+			s.logger.Error("Failed to flag taken down playlist items", err, "takedownId", takedown.ID.Hex(), "playlistId", playlist.ID.Hex())
+			continue
+		}
+		if len(itemIDs) == 0 {
+			continue
+		}
+
+		playlists++
+		items += len(itemIDs)
+
+		flagged := &models.TakenDownItems{
+			OwnerID:      playlist.Owner,
+			PlaylistID:   playlist.ID,
+			PlaylistName: playlist.Name,
+			ItemIDs:      itemIDs,
+			Takedown:     takedown,
+		}
+		for _, handler := range s.ownerHandlers {
+			handler(ctx, flagged)
+		}
+	}
+
+	return playlists, items
+}
+
+// ListTakedowns lists the takedown registry, newest first, along with the total number matching.
+func (s *TakedownService) ListTakedowns(ctx context.Context, filter models.MediaTakedownFilter, skip, limit int) ([]*models.MediaTakedown, int64, error) {
+	return s.takedownRepo.FindTakedowns(ctx, filter, skip, limit)
+}
+
+// GetTakedown gets a takedown by ID.
+func (s *TakedownService) GetTakedown(ctx context.Context, takedownID bson.ObjectID) (*models.MediaTakedown, error) {
+	return s.takedownRepo.FindTakedownByID(ctx, takedownID)
+}
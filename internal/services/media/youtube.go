@@ -94,16 +94,17 @@ func (p *YouTubeProvider) Search(ctx context.Context, query string, limit int) (
 
 		// Create search result
 		result := models.MediaSearchResult{
-			SourceID:     item.Id.VideoId,
-			Type:         "youtube",
-			Title:        item.Snippet.Title,
-			Artist:       item.Snippet.ChannelTitle,
-			Thumbnail:    getBestThumbnail(item.Snippet.Thumbnails),
-			Duration:     duration,
-			Views:        viewCount,
-			PublishedAt:  publishedAt,
-			Description:  item.Snippet.Description,
-			ChannelTitle: item.Snippet.ChannelTitle,
+			SourceID:      item.Id.VideoId,
+			Type:          "youtube",
+			Title:         item.Snippet.Title,
+			Artist:        item.Snippet.ChannelTitle,
+			Thumbnail:     getBestThumbnail(item.Snippet.Thumbnails),
+			Duration:      duration,
+			Views:         viewCount,
+			PublishedAt:   publishedAt,
+			Description:   item.Snippet.Description,
+			ChannelTitle:  item.Snippet.ChannelTitle,
+			AgeRestricted: isAgeRestricted(videoDetails.ContentDetails),
 		}
 
 		results = append(results, result)
@@ -160,11 +161,14 @@ func (p *YouTubeProvider) GetMediaInfo(ctx context.Context, sourceID string) (*m
 		Thumbnail: getBestThumbnail(video.Snippet.Thumbnails),
 		Duration:  duration,
 		Metadata: models.MediaMetadata{
-			Views:       viewCount,
-			Likes:       likeCount,
-			PublishedAt: parseTime(video.Snippet.PublishedAt),
-			ChannelID:   video.Snippet.ChannelId,
-			Categories:  []string{video.Snippet.CategoryId},
+			Views:         viewCount,
+			Likes:         likeCount,
+			PublishedAt:   parseTime(video.Snippet.PublishedAt),
+			ChannelID:     video.Snippet.ChannelId,
+			Categories:    []string{video.Snippet.CategoryId},
+			ContentRating: youTubeRating(video.ContentDetails),
+			AgeRestricted: isAgeRestricted(video.ContentDetails),
+			ISRC:          ParseISRC(video.Snippet.Description),
 		},
 		Stats: models.MediaStats{
 			PlayCount: 0,
@@ -177,6 +181,22 @@ func (p *YouTubeProvider) GetMediaInfo(ctx context.Context, sourceID string) (*m
 	return media, nil
 }
 
+// youTubeAgeRestricted is the YouTube rating of videos only signed-in adults can watch.
+const youTubeAgeRestricted = "ytAgeRestricted"
+
+// youTubeRating returns YouTube's own rating of a video, empty when it has none.
+func youTubeRating(details *youtube.VideoContentDetails) string {
+	if details == nil || details.ContentRating == nil {
+		return ""
+	}
+	return details.ContentRating.YtRating
+}
+
+// isAgeRestricted reports whether YouTube restricts a video to adults.
+func isAgeRestricted(details *youtube.VideoContentDetails) bool {
+	return youTubeRating(details) == youTubeAgeRestricted
+}
+
 // parseTime parses a time string into a time.Time object.
 func parseTime(timeStr string) time.Time {
 	t, err := time.Parse(time.RFC3339, timeStr)
@@ -0,0 +1,211 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// Email is an outbound email.
+type Email struct {
+	// To is the recipient's address.
+	To string
+
+	// Subject is the subject line.
+	Subject string
+
+	// Text is the plain text body.
+	Text string
+
+	// HTML is the HTML body, empty to send plain text only.
+	HTML string
+}
+
+// Sender delivers outbound emails.
+type Sender interface {
+	// Send delivers an email.
+	Send(ctx context.Context, email *Email) error
+}
+
+// SMTPSender delivers emails through an SMTP server, upgrading the connection with STARTTLS when the
+// server offers it.
+type SMTPSender struct {
+	host string
+	addr string
+	from mail.Address
+	auth smtp.Auth
+}
+
+// NewSMTPSender creates a sender for the SMTP server at the given host and port. An empty username
+// sends without authenticating.
+func NewSMTPSender(host string, port int, username, password, from string) (*SMTPSender, error) {
+	address, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address: %w", err)
+	}
+
+	sender := &SMTPSender{
+		host: host,
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		from: *address,
+	}
+	if username != "" {
+		sender.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return sender, nil
+}
+
+// Send delivers an email, giving up when the context is done.
+func (s *SMTPSender) Send(ctx context.Context, email *Email) error {
+	to, err := mail.ParseAddress(email.To)
+	if err != nil {
+		return models.NewUserError(models.ErrInvalidInput, "Invalid email address", http.StatusBadRequest)
+	}
+
+	message, err := s.format(to, email)
+	if err != nil {
+		return err
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if s.auth != nil {
+		if err := client.Auth(s.auth); err != nil {
+			return fmt.Errorf("failed to authenticate with SMTP server: %w", err)
+		}
+	}
+
+	if err := client.Mail(s.from.Address); err != nil {
+		return fmt.Errorf("SMTP server refused the sender: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("SMTP server refused the recipient: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// format writes an email as a MIME message, with the plain text and HTML bodies as alternatives
+// when it has both.
+func (s *SMTPSender) format(to *mail.Address, email *Email) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+
+	header("From", s.from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", email.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+
+	if email.HTML == "" {
+		header("Content-Type", `text/plain; charset="utf-8"`)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, email.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	header("Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": parts.Boundary()}))
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, content string }{
+		{`text/plain; charset="utf-8"`, email.Text},
+		{`text/html; charset="utf-8"`, email.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+// writeQuotedPrintable writes content in the quoted-printable encoding.
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// LogSender logs emails instead of delivering them, for when no SMTP server is configured.
+type LogSender struct {
+	logger *utils.Logger
+}
+
+// NewLogSender creates a sender that only logs emails.
+func NewLogSender(logger *utils.Logger) *LogSender {
+	return &LogSender{logger: logger.Named("email_log_sender")}
+}
+
+// Send logs an email without delivering it.
+func (s *LogSender) Send(ctx context.Context, email *Email) error {
+	if _, err := mail.ParseAddress(email.To); err != nil {
+		return models.NewUserError(models.ErrInvalidInput, "Invalid email address", http.StatusBadRequest)
+	}
+
+	s.logger.Info("Email not sent, no SMTP server configured", "to", email.To, "subject", email.Subject)
+	return nil
+}
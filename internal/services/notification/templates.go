@@ -0,0 +1,406 @@
+// Package notification renders and sends outbound emails and long-form notifications.
+package notification
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// templateName matches the names of message templates. Names are used as file names on disk, so they
+// can't contain path separators.
+var templateName = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// localeTag matches a locale as a language with an optional region, such as "en" or "pt-BR".
+var localeTag = regexp.MustCompile(`^([a-zA-Z]{2,3})(?:[-_]([a-zA-Z]{2}|[0-9]{3}))?$`)
+
+// compiledTemplate is a message template parsed and ready to execute.
+type compiledTemplate struct {
+	template *models.MessageTemplate
+	subject  *texttemplate.Template
+	text     *texttemplate.Template
+	html     *htmltemplate.Template
+}
+
+// cachedTemplate is a compiled template cached for a name and requested locale.
+type cachedTemplate struct {
+	compiled  *compiledTemplate
+	expiresAt time.Time
+}
+
+// TemplateService renders outbound emails and long-form notifications from versioned, localized templates.
+// The active version of a template in MongoDB takes precedence over the one shipped on disk, and a locale
+// without either falls back to its base language and then the default locale. Compiled templates are cached,
+// so a newly activated version reaches other instances once their cache expires.
+type TemplateService struct {
+	templateRepo  repositories.TemplateRepository
+	sender        Sender
+	dir           string
+	defaultLocale string
+	cacheTTL      time.Duration
+	logger        *utils.Logger
+
+	// cache holds the compiled templates by name and requested locale
+	cache map[string]cachedTemplate
+	mutex sync.RWMutex
+}
+
+// NewTemplateService creates a new template service. Templates on disk are read from
+// <dir>/<locale>/<name>.subject.tmpl, <name>.txt.tmpl and the optional <name>.html.tmpl.
+func NewTemplateService(templateRepo repositories.TemplateRepository, sender Sender, dir, defaultLocale string, cacheTTL time.Duration, logger *utils.Logger) *TemplateService {
+	if locale, ok := normalizeLocale(defaultLocale); ok {
+		defaultLocale = locale
+	}
+
+	return &TemplateService{
+		templateRepo:  templateRepo,
+		sender:        sender,
+		dir:           dir,
+		defaultLocale: defaultLocale,
+		cacheTTL:      cacheTTL,
+		logger:        logger.Named("template_service"),
+		cache:         make(map[string]cachedTemplate),
+	}
+}
+
+// Render renders a message template in the locale closest to the one asked for.
+func (s *TemplateService) Render(ctx context.Context, name, locale string, data any) (*models.RenderedMessage, error) {
+	compiled, err := s.resolve(ctx, name, locale)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.execute(data)
+}
+
+// Send renders a message template in the locale closest to the one asked for and emails it.
+func (s *TemplateService) Send(ctx context.Context, name, locale, to string, data any) error {
+	message, err := s.Render(ctx, name, locale, data)
+	if err != nil {
+		return err
+	}
+
+	return s.send(ctx, to, message)
+}
+
+// ListTemplates lists the versions of the message templates stored in MongoDB.
+func (s *TemplateService) ListTemplates(ctx context.Context, filter models.MessageTemplateFilter) ([]*models.MessageTemplate, error) {
+	if filter.Locale != "" {
+		locale, ok := normalizeLocale(filter.Locale)
+		if !ok {
+			return nil, models.NewUserError(models.ErrInvalidTemplate, "Invalid locale", http.StatusBadRequest)
+		}
+		filter.Locale = locale
+	}
+
+	return s.templateRepo.FindTemplates(ctx, filter)
+}
+
+// CreateVersion stores a new version of a message template, which must parse, and activates it if asked to.
+func (s *TemplateService) CreateVersion(ctx context.Context, name string, adminID bson.ObjectID, request *models.MessageTemplateRequest) (*models.MessageTemplate, error) {
+	if err := utils.Validate(request); err != nil {
+		return nil, models.NewUserError(models.ErrInvalidTemplate, err.Error(), http.StatusBadRequest)
+	}
+	if !templateName.MatchString(name) {
+		return nil, models.NewUserError(models.ErrInvalidTemplate, "Invalid template name", http.StatusBadRequest)
+	}
+	locale, ok := normalizeLocale(request.Locale)
+	if !ok {
+		return nil, models.NewUserError(models.ErrInvalidTemplate, "Invalid locale", http.StatusBadRequest)
+	}
+
+	template := &models.MessageTemplate{
+		Name:      name,
+		Locale:    locale,
+		Subject:   request.Subject,
+		Text:      request.Text,
+		HTML:      request.HTML,
+		CreatedBy: adminID,
+	}
+	if _, err := compile(template); err != nil {
+		return nil, models.NewUserError(models.ErrInvalidTemplate, err.Error(), http.StatusBadRequest)
+	}
+
+	if err := s.templateRepo.CreateVersion(ctx, template); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Message template version created", "name", name, "locale", locale, "version", template.Version, "by", adminID.Hex())
+
+	if !request.Activate {
+		return template, nil
+	}
+	return s.ActivateVersion(ctx, name, locale, template.Version)
+}
+
+// ActivateVersion makes a version of a message template the one sent in its locale.
+func (s *TemplateService) ActivateVersion(ctx context.Context, name, locale string, version int) (*models.MessageTemplate, error) {
+	locale, ok := normalizeLocale(locale)
+	if !ok {
+		return nil, models.NewUserError(models.ErrInvalidTemplate, "Invalid locale", http.StatusBadRequest)
+	}
+
+	template, err := s.templateRepo.ActivateVersion(ctx, name, locale, version)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(name)
+
+	s.logger.Info("Message template version activated", "name", name, "locale", locale, "version", version)
+	return template, nil
+}
+
+// Preview renders a message template with sample data. Version 0 renders the template that would be sent,
+// falling back across locales; other versions are rendered in exactly the locale asked for.
+func (s *TemplateService) Preview(ctx context.Context, name string, preview *models.MessageTemplatePreview) (*models.RenderedMessage, error) {
+	if err := utils.Validate(preview); err != nil {
+		return nil, models.NewUserError(models.ErrInvalidTemplate, err.Error(), http.StatusBadRequest)
+	}
+
+	if preview.Version == 0 {
+		return s.Render(ctx, name, preview.Locale, preview.Data)
+	}
+
+	locale, ok := normalizeLocale(preview.Locale)
+	if !ok {
+		return nil, models.NewUserError(models.ErrInvalidTemplate, "Invalid locale", http.StatusBadRequest)
+	}
+	template, err := s.templateRepo.FindVersion(ctx, name, locale, preview.Version)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := compile(template)
+	if err != nil {
+		return nil, models.NewUserError(models.ErrInvalidTemplate, err.Error(), http.StatusBadRequest)
+	}
+	return compiled.execute(preview.Data)
+}
+
+// TestSend renders a message template like Preview does and emails it to the address asked for,
+// marking the subject as a test.
+func (s *TemplateService) TestSend(ctx context.Context, name string, preview *models.MessageTemplatePreview) (*models.RenderedMessage, error) {
+	if preview.To == "" {
+		return nil, models.NewUserError(models.ErrInvalidTemplate, "Choose the address to send the test to", http.StatusBadRequest)
+	}
+
+	message, err := s.Preview(ctx, name, preview)
+	if err != nil {
+		return nil, err
+	}
+
+	test := *message
+	test.Subject = "[Test] " + message.Subject
+	if err := s.send(ctx, preview.To, &test); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Message template test sent", "name", name, "locale", message.Locale, "version", message.Version)
+	return &test, nil
+}
+
+// send emails a rendered message.
+func (s *TemplateService) send(ctx context.Context, to string, message *models.RenderedMessage) error {
+	err := s.sender.Send(ctx, &Email{
+		To:      to,
+		Subject: message.Subject,
+		Text:    message.Text,
+		HTML:    message.HTML,
+	})
+	if err != nil {
+		s.logger.Error("Failed to send email", err, "template", message.Name, "locale", message.Locale)
+		return err
+	}
+	return nil
+}
+
+// resolve finds the compiled template a message in a locale is rendered with, from the cache or else
+// by trying the locale's fallbacks in turn.
+func (s *TemplateService) resolve(ctx context.Context, name, locale string) (*compiledTemplate, error) {
+	if !templateName.MatchString(name) {
+		return nil, models.ErrTemplateNotFound
+	}
+
+	key := name + "|" + locale
+	if s.cacheTTL > 0 {
+		s.mutex.RLock()
+		cached, ok := s.cache[key]
+		s.mutex.RUnlock()
+		if ok && time.Now().Before(cached.expiresAt) {
+			return cached.compiled, nil
+		}
+	}
+
+	for _, candidate := range s.localeFallbacks(locale) {
+		template, err := s.load(ctx, name, candidate)
+		if errors.Is(err, models.ErrTemplateNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		compiled, err := compile(template)
+		if err != nil {
+			s.logger.Error("Failed to parse message template", err, "name", name, "locale", candidate, "version", template.Version)
+			return nil, models.NewInternalError(err, "Failed to parse message template")
+		}
+
+		if s.cacheTTL > 0 {
+			s.mutex.Lock()
+			s.cache[key] = cachedTemplate{compiled: compiled, expiresAt: time.Now().Add(s.cacheTTL)}
+			s.mutex.Unlock()
+		}
+		return compiled, nil
+	}
+
+	return nil, models.ErrTemplateNotFound
+}
+
+// load finds the template of a message in exactly one locale, the active version in MongoDB or else the one on disk.
+func (s *TemplateService) load(ctx context.Context, name, locale string) (*models.MessageTemplate, error) {
+	template, err := s.templateRepo.FindActive(ctx, name, locale)
+	if !errors.Is(err, models.ErrTemplateNotFound) {
+		return template, err
+	}
+
+	if s.dir == "" {
+		return nil, models.ErrTemplateNotFound
+	}
+
+	base := filepath.Join(s.dir, locale, name)
+	subject, err := os.ReadFile(base + ".subject.tmpl")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, models.ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, models.NewInternalError(err, "Failed to read message template")
+	}
+	text, err := os.ReadFile(base + ".txt.tmpl")
+	if err != nil {
+		return nil, models.NewInternalError(err, "Failed to read message template")
+	}
+	html, err := os.ReadFile(base + ".html.tmpl")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, models.NewInternalError(err, "Failed to read message template")
+	}
+
+	return &models.MessageTemplate{
+		Name:    name,
+		Locale:  locale,
+		Subject: string(subject),
+		Text:    string(text),
+		HTML:    string(html),
+		Active:  true,
+	}, nil
+}
+
+// invalidate drops a template's compiled versions from the cache, in every locale.
+func (s *TemplateService) invalidate(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for key := range s.cache {
+		if strings.HasPrefix(key, name+"|") {
+			delete(s.cache, key)
+		}
+	}
+}
+
+// localeFallbacks lists the locales a message in a locale is looked up in, in order: the locale itself,
+// its base language, and the default locale.
+func (s *TemplateService) localeFallbacks(locale string) []string {
+	var fallbacks []string
+	add := func(locale string) {
+		for _, existing := range fallbacks {
+			if existing == locale {
+				return
+			}
+		}
+		fallbacks = append(fallbacks, locale)
+	}
+
+	if normalized, ok := normalizeLocale(locale); ok {
+		add(normalized)
+		if language, _, found := strings.Cut(normalized, "-"); found {
+			add(language)
+		}
+	}
+	add(s.defaultLocale)
+	return fallbacks
+}
+
+// normalizeLocale writes a locale the way templates are stored, with a lowercase language and an
+// uppercase region, such as "pt-BR". It reports whether the locale is valid.
+func normalizeLocale(locale string) (string, bool) {
+	match := localeTag.FindStringSubmatch(strings.TrimSpace(locale))
+	if match == nil {
+		return "", false
+	}
+
+	normalized := strings.ToLower(match[1])
+	if match[2] != "" {
+		normalized += "-" + strings.ToUpper(match[2])
+	}
+	return normalized, true
+}
+
+// compile parses the subject and bodies of a message template. Executing a template with data missing
+// a key it uses fails, rather than sending a message with gaps in it.
+func compile(template *models.MessageTemplate) (*compiledTemplate, error) {
+	subject, err := texttemplate.New("subject").Option("missingkey=error").Parse(template.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject: %w", err)
+	}
+	text, err := texttemplate.New("text").Option("missingkey=error").Parse(template.Text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid text body: %w", err)
+	}
+
+	compiled := &compiledTemplate{template: template, subject: subject, text: text}
+	if template.HTML != "" {
+		compiled.html, err = htmltemplate.New("html").Option("missingkey=error").Parse(template.HTML)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HTML body: %w", err)
+		}
+	}
+	return compiled, nil
+}
+
+// execute renders the template with the data of a message.
+func (c *compiledTemplate) execute(data any) (*models.RenderedMessage, error) {
+	var subject, text, html bytes.Buffer
+	if err := c.subject.Execute(&subject, data); err != nil {
+		return nil, models.NewUserError(models.ErrInvalidTemplate, err.Error(), http.StatusBadRequest)
+	}
+	if err := c.text.Execute(&text, data); err != nil {
+		return nil, models.NewUserError(models.ErrInvalidTemplate, err.Error(), http.StatusBadRequest)
+	}
+	if c.html != nil {
+		if err := c.html.Execute(&html, data); err != nil {
+			return nil, models.NewUserError(models.ErrInvalidTemplate, err.Error(), http.StatusBadRequest)
+		}
+	}
+
+	return &models.RenderedMessage{
+		Name:    c.template.Name,
+		Locale:  c.template.Locale,
+		Version: c.template.Version,
+		// Subjects are a single header line, whatever line breaks the template put in them
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
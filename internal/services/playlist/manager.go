@@ -6,25 +6,43 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
 	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/services/media"
 	"norelock.dev/listenify/backend/internal/utils"
 )
 
+// maxSyncChanges is the most changes sent to bring a client's copy of a playlist up to date. Clients
+// further behind get the whole playlist instead.
+const maxSyncChanges = 500
+
 // Manager handles playlist operations.
 type Manager struct {
-	playlistRepo repositories.PlaylistRepository
-	logger       *utils.Logger
+	playlistRepo  repositories.PlaylistRepository
+	mediaRepo     repositories.MediaRepository
+	mediaResolver *media.Resolver
+	logger        *utils.Logger
+
+	// coverHandlers are notified when a playlist gets a new cover image
+	coverHandlers []func(ctx context.Context, playlist *models.Playlist)
 }
 
 // NewManager creates a new playlist manager.
-func NewManager(playlistRepo repositories.PlaylistRepository, logger *utils.Logger) *Manager {
+func NewManager(playlistRepo repositories.PlaylistRepository, mediaRepo repositories.MediaRepository, mediaResolver *media.Resolver, logger *utils.Logger) *Manager {
 	return &Manager{
-		playlistRepo: playlistRepo,
-		logger:       logger.Named("playlist_manager"),
+		playlistRepo:  playlistRepo,
+		mediaRepo:     mediaRepo,
+		mediaResolver: mediaResolver,
+		logger:        logger.Named("playlist_manager"),
 	}
 }
 
+// AddCoverChangedHandler adds a handler called when a playlist is created with a cover image or its cover image changes.
+func (m *Manager) AddCoverChangedHandler(handler func(ctx context.Context, playlist *models.Playlist)) {
+	m.coverHandlers = append(m.coverHandlers, handler)
+}
+
 // CreatePlaylist creates a new playlist.
 func (m *Manager) CreatePlaylist(ctx context.Context, playlist *models.Playlist) (*models.Playlist, error) {
 	m.logger.Debug("Creating playlist", "name", playlist.Name, "owner", playlist.Owner.Hex())
@@ -34,15 +52,60 @@ func (m *Manager) CreatePlaylist(ctx context.Context, playlist *models.Playlist)
 		return nil, err
 	}
 
+	if playlist.CoverImage != "" {
+		m.coverChanged(ctx, playlist)
+	}
+
 	return playlist, nil
 }
 
+// coverChanged tells the cover handlers a playlist got a new cover image.
+func (m *Manager) coverChanged(ctx context.Context, playlist *models.Playlist) {
+	for _, handler := range m.coverHandlers {
+		handler(ctx, playlist)
+	}
+}
+
 // GetPlaylist gets a playlist by ID.
 func (m *Manager) GetPlaylist(ctx context.Context, id bson.ObjectID) (*models.Playlist, error) {
 	m.logger.Debug("Getting playlist", "id", id.Hex())
 	return m.playlistRepo.FindByID(ctx, id)
 }
 
+// GetChangesSince gets the changes made to a playlist's items since a revision a client has, so it can
+// bring its copy up to date without downloading the whole playlist again. The client gets every item
+// instead when changes can't bring it up to date, such as when some expired from the change log or its
+// revision is ahead of the playlist's.
+func (m *Manager) GetChangesSince(ctx context.Context, playlist *models.Playlist, revision int64) (*models.PlaylistChanges, error) {
+	m.logger.Debug("Getting playlist changes", "id", playlist.ID.Hex(), "revision", revision)
+
+	result := &models.PlaylistChanges{
+		PlaylistID: playlist.ID,
+		Revision:   playlist.Revision,
+		Changes:    []*models.PlaylistChange{},
+	}
+
+	behind := playlist.Revision - revision
+	if behind >= 0 && behind <= maxSyncChanges {
+		changes, err := m.playlistRepo.FindChanges(ctx, playlist.ID, revision, playlist.Revision)
+		if err != nil {
+			return nil, err
+		}
+
+		// A change missing from the log leaves a gap the client can't cross
+		if int64(len(changes)) == behind {
+			if len(changes) > 0 {
+				result.Changes = changes
+			}
+			return result, nil
+		}
+	}
+
+	result.Reset = true
+	result.Items = playlist.Items
+	return result, nil
+}
+
 // GetUserPlaylists gets all playlists for a user.
 func (m *Manager) GetUserPlaylists(ctx context.Context, userID bson.ObjectID) ([]*models.Playlist, error) {
 	m.logger.Debug("Getting user playlists", "userID", userID.Hex())
@@ -53,11 +116,25 @@ func (m *Manager) GetUserPlaylists(ctx context.Context, userID bson.ObjectID) ([
 func (m *Manager) UpdatePlaylist(ctx context.Context, playlist *models.Playlist) (*models.Playlist, error) {
 	m.logger.Debug("Updating playlist", "id", playlist.ID.Hex(), "name", playlist.Name)
 
+	// Only a cover image that changed is reviewed again
+	previousCover := ""
+	if playlist.CoverImage != "" {
+		stored, err := m.playlistRepo.FindByID(ctx, playlist.ID)
+		if err != nil {
+			return nil, err
+		}
+		previousCover = stored.CoverImage
+	}
+
 	err := m.playlistRepo.Update(ctx, playlist)
 	if err != nil {
 		return nil, err
 	}
 
+	if playlist.CoverImage != previousCover {
+		m.coverChanged(ctx, playlist)
+	}
+
 	return playlist, nil
 }
 
@@ -71,7 +148,49 @@ func (m *Manager) DeletePlaylist(ctx context.Context, id bson.ObjectID) error {
 func (m *Manager) AddPlaylistItem(ctx context.Context, playlistID, mediaID bson.ObjectID, position int) (*models.Playlist, error) {
 	m.logger.Debug("Adding item to playlist", "playlistID", playlistID.Hex(), "mediaID", mediaID.Hex(), "position", position)
 
-	err := m.playlistRepo.AddItem(ctx, playlistID, mediaID, position)
+	// Taken down media can't be added back
+	item, err := m.mediaRepo.FindByID(ctx, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	if !item.TakedownID.IsZero() {
+		return nil, models.ErrMediaTakenDown
+	}
+
+	err = m.playlistRepo.AddItem(ctx, playlistID, mediaID, position)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the updated playlist
+	return m.playlistRepo.FindByID(ctx, playlistID)
+}
+
+// AddPlaylistItems appends several media items to a playlist at once. The media is looked up together,
+// and none of it is added if any is missing or was taken down.
+func (m *Manager) AddPlaylistItems(ctx context.Context, playlistID bson.ObjectID, mediaIDs []bson.ObjectID) (*models.Playlist, error) {
+	m.logger.Debug("Adding items to playlist", "playlistID", playlistID.Hex(), "count", len(mediaIDs))
+
+	items, err := m.mediaRepo.FindMany(ctx, bson.M{"_id": bson.M{"$in": mediaIDs}}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[bson.ObjectID]bool, len(items))
+	for _, item := range items {
+		// Taken down media can't be added back
+		if !item.TakedownID.IsZero() {
+			return nil, models.ErrMediaTakenDown
+		}
+		found[item.ID] = true
+	}
+	for _, mediaID := range mediaIDs {
+		if !found[mediaID] {
+			return nil, models.ErrMediaNotFound
+		}
+	}
+
+	err = m.playlistRepo.AddItems(ctx, playlistID, mediaIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -80,6 +199,57 @@ func (m *Manager) AddPlaylistItem(ctx context.Context, playlistID, mediaID bson.
 	return m.playlistRepo.FindByID(ctx, playlistID)
 }
 
+// AddPlaylistItemByURL resolves the media at a URL and adds it to a playlist, returning the updated playlist
+// and the media. Media created for the URL is deleted again when adding it fails, so failed adds leave no
+// orphan media behind. The provider lookup can't be part of a database transaction, and not every
+// deployment supports them, so the create is compensated instead of rolled back.
+func (m *Manager) AddPlaylistItemByURL(ctx context.Context, playlistID, userID bson.ObjectID, rawURL string, position int) (*models.Playlist, *models.Media, error) {
+	m.logger.Debug("Adding item to playlist by URL", "playlistID", playlistID.Hex(), "url", rawURL, "position", position)
+
+	source, sourceID, err := media.ExtractSourceInfo(rawURL)
+	if err != nil {
+		return nil, nil, models.ErrMediaCantBeResolved
+	}
+
+	item, created, err := m.mediaResolver.ResolveCreated(ctx, source, sourceID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = m.playlistRepo.AddItem(ctx, playlistID, item.ID, position)
+	if err != nil {
+		if created {
+			m.discardMedia(context.WithoutCancel(ctx), item)
+		}
+		return nil, nil, err
+	}
+
+	playlist, err := m.playlistRepo.FindByID(ctx, playlistID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return playlist, item, nil
+}
+
+// discardMedia deletes media created for a playlist add that failed. Media another playlist picked up
+// in the meantime is kept.
+func (m *Manager) discardMedia(ctx context.Context, item *models.Media) {
+	referenced, err := m.playlistRepo.FindMany(ctx, bson.M{"items.mediaId": item.ID}, options.Find().SetLimit(1))
+	if err != nil {
+		m.logger.Error("Failed to check media references, keeping it", err, "mediaID", item.ID.Hex())
+		return
+	}
+	if len(referenced) > 0 {
+		return
+	}
+
+	if err := m.mediaRepo.Delete(ctx, item.ID); err != nil {
+		m.logger.Error("Failed to delete media of a failed playlist add", err, "mediaID", item.ID.Hex())
+		return
+	}
+	m.logger.Info("Deleted media of a failed playlist add", "mediaID", item.ID.Hex(), "source", item.Type, "sourceID", item.SourceID)
+}
+
 // RemovePlaylistItem removes an item from a playlist.
 func (m *Manager) RemovePlaylistItem(ctx context.Context, playlistID, itemID bson.ObjectID) (*models.Playlist, error) {
 	m.logger.Debug("Removing item from playlist", "playlistID", playlistID.Hex(), "itemID", itemID.Hex())
@@ -93,6 +263,47 @@ func (m *Manager) RemovePlaylistItem(ctx context.Context, playlistID, itemID bso
 	return m.playlistRepo.FindByID(ctx, playlistID)
 }
 
+// RemovePlaylistItems removes several items from a playlist at once. None are removed if any of them
+// isn't in the playlist.
+func (m *Manager) RemovePlaylistItems(ctx context.Context, playlistID bson.ObjectID, itemIDs []bson.ObjectID) (*models.Playlist, error) {
+	m.logger.Debug("Removing items from playlist", "playlistID", playlistID.Hex(), "count", len(itemIDs))
+
+	err := m.playlistRepo.RemoveItems(ctx, playlistID, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the updated playlist
+	return m.playlistRepo.FindByID(ctx, playlistID)
+}
+
+// ReorderPlaylist puts the items of a playlist in the given order, which must list each of them once.
+func (m *Manager) ReorderPlaylist(ctx context.Context, playlistID bson.ObjectID, itemIDs []bson.ObjectID) (*models.Playlist, error) {
+	m.logger.Debug("Reordering playlist", "playlistID", playlistID.Hex(), "count", len(itemIDs))
+
+	err := m.playlistRepo.ReorderItems(ctx, playlistID, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the updated playlist
+	return m.playlistRepo.FindByID(ctx, playlistID)
+}
+
+// RelinkPlaylistItem points a playlist item at another source, typically because its source was deleted.
+// The item keeps its position and play statistics.
+func (m *Manager) RelinkPlaylistItem(ctx context.Context, playlistID, itemID, mediaID bson.ObjectID) (*models.Playlist, error) {
+	m.logger.Debug("Relinking playlist item", "playlistID", playlistID.Hex(), "itemID", itemID.Hex(), "mediaID", mediaID.Hex())
+
+	err := m.playlistRepo.RelinkItem(ctx, playlistID, itemID, mediaID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the updated playlist
+	return m.playlistRepo.FindByID(ctx, playlistID)
+}
+
 // ImportPlaylist imports a playlist from an external source.
 func (m *Manager) ImportPlaylist(ctx context.Context, ownerID bson.ObjectID, source string, externalID string) (*models.Playlist, error) {
 	m.logger.Debug("Importing playlist", "ownerID", ownerID.Hex(), "source", source, "externalID", externalID)
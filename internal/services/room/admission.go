@@ -0,0 +1,405 @@
+package room
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// admissionKeyPrefix prefixes the keys of the join queues of rooms.
+	admissionKeyPrefix = "admission"
+
+	// admissionReleaseRatio is the share of the thresholds the load has to fall below before joins are
+	// no longer constrained, so capacities don't flap while the load hovers around a threshold.
+	admissionReleaseRatio = 0.8
+)
+
+// Reasons for constraining joins.
+const (
+	AdmissionReasonConnections      = "connections"
+	AdmissionReasonBroadcastLatency = "broadcast_latency"
+)
+
+// NodeLoad reports the load of this server node.
+type NodeLoad interface {
+	GetClientCount() int
+	BroadcastLatency() time.Duration
+}
+
+// AdmissionPolicy controls when joins are constrained to protect playback sync under load.
+type AdmissionPolicy struct {
+	// Interval is how often the load of this node is evaluated. Zero disables load-aware admission.
+	Interval time.Duration
+
+	// MaxConnections is the number of connections to this node above which joins are constrained. Zero ignores connections.
+	MaxConnections int
+
+	// MaxBroadcastLatency is the average room broadcast latency above which joins are constrained. Zero ignores latency.
+	MaxBroadcastLatency time.Duration
+
+	// CapacityFactor is the share of a room's capacity and listener overflow open to joins while constrained.
+	CapacityFactor float64
+
+	// QueueTimeout is how long a queued user keeps their place without trying again.
+	QueueTimeout time.Duration
+}
+
+// AdmissionStatus is the load-aware admission state of this node.
+type AdmissionStatus struct {
+	Constrained        bool      `json:"constrained"`
+	Reason             string    `json:"reason,omitempty"`
+	Since              time.Time `json:"since,omitzero"`
+	Connections        int       `json:"connections"`
+	BroadcastLatencyMs float64   `json:"broadcastLatencyMs"`
+	CapacityFactor     float64   `json:"capacityFactor"`
+}
+
+// JoinQueuedError is returned when a join is queued because this node is constrained.
+type JoinQueuedError struct {
+	// Position is the user's place in the room's join queue, starting at 1.
+	Position int
+
+	// RetryAfter is how long the user should wait before trying again.
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *JoinQueuedError) Error() string {
+	return fmt.Sprintf("%s (position %d)", models.ErrJoinQueued, e.Position)
+}
+
+// Unwrap makes the error match models.ErrJoinQueued.
+func (e *JoinQueuedError) Unwrap() error {
+	return models.ErrJoinQueued
+}
+
+// AdmissionController reduces the effective capacity of rooms while this node is under load, and queues
+// the joins that don't fit in fair order. Queued users keep their place as long as they keep trying.
+type AdmissionController struct {
+	load        NodeLoad
+	redisClient *redis.Client
+	policy      AdmissionPolicy
+	logger      *utils.Logger
+
+	mu     sync.RWMutex
+	status AdmissionStatus
+}
+
+// NewAdmissionController creates a new admission controller.
+func NewAdmissionController(load NodeLoad, redisClient *redis.Client, policy AdmissionPolicy, logger *utils.Logger) *AdmissionController {
+	return &AdmissionController{
+		load:        load,
+		redisClient: redisClient,
+		policy:      policy,
+		logger:      logger.Named("admission_controller"),
+		status:      AdmissionStatus{CapacityFactor: 1},
+	}
+}
+
+// Start begins evaluating the load of this node.
+func (c *AdmissionController) Start(ctx context.Context) {
+	if c.policy.Interval <= 0 {
+		c.logger.Info("Load-aware room admission is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(c.policy.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				c.logger.Info("Stopping admission controller")
+				return
+			case <-ticker.C:
+				c.Evaluate()
+			}
+		}
+	}()
+
+	c.logger.Info("Admission controller started",
+		"interval", c.policy.Interval,
+		"maxConnections", c.policy.MaxConnections,
+		"maxBroadcastLatency", c.policy.MaxBroadcastLatency,
+		"capacityFactor", c.policy.CapacityFactor,
+	)
+}
+
+// Evaluate measures the load of this node and constrains or releases joins accordingly.
+func (c *AdmissionController) Evaluate() AdmissionStatus {
+	connections := c.load.GetClientCount()
+	latency := c.load.BroadcastLatency()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Constrained nodes only release once the load is clearly below the thresholds
+	ratio := 1.0
+	if c.status.Constrained {
+		ratio = admissionReleaseRatio
+	}
+	reason := ""
+	switch {
+	case c.policy.MaxConnections > 0 && float64(connections) > float64(c.policy.MaxConnections)*ratio:
+		reason = AdmissionReasonConnections
+	case c.policy.MaxBroadcastLatency > 0 && float64(latency) > float64(c.policy.MaxBroadcastLatency)*ratio:
+		reason = AdmissionReasonBroadcastLatency
+	}
+
+	wasConstrained := c.status.Constrained
+	c.status.Constrained = reason != ""
+	c.status.Reason = reason
+	c.status.Connections = connections
+	c.status.BroadcastLatencyMs = float64(latency) / float64(time.Millisecond)
+	c.status.CapacityFactor = 1
+
+	switch {
+	case c.status.Constrained && !wasConstrained:
+		c.status.Since = time.Now()
+		c.logger.Warn("Constraining room joins under load", "reason", reason, "connections", connections, "broadcastLatency", latency)
+	case !c.status.Constrained && wasConstrained:
+		c.status.Since = time.Time{}
+		c.logger.Info("Room joins are no longer constrained", "connections", connections, "broadcastLatency", latency)
+	}
+	if c.status.Constrained {
+		c.status.CapacityFactor = c.policy.CapacityFactor
+	}
+
+	return c.status
+}
+
+// Status gets the admission state of this node as of the last evaluation.
+func (c *AdmissionController) Status() AdmissionStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.status
+}
+
+// Constrained checks whether joins on this node are constrained.
+func (c *AdmissionController) Constrained() bool {
+	return c.Status().Constrained
+}
+
+// BroadcastLatency gets the room broadcast latency measured in the last evaluation.
+func (c *AdmissionController) BroadcastLatency() time.Duration {
+	return time.Duration(c.Status().BroadcastLatencyMs * float64(time.Millisecond))
+}
+
+// effectiveLimit scales a room's capacity or listener overflow to the share open to joins while constrained.
+// A room keeps at least one place as long as it has any.
+func (c *AdmissionController) effectiveLimit(limit int) int {
+	status := c.Status()
+	if !status.Constrained || limit <= 0 {
+		return limit
+	}
+	return max(int(math.Floor(float64(limit)*status.CapacityFactor)), 1)
+}
+
+// retryAfter is how long queued users are told to wait before trying again.
+func (c *AdmissionController) retryAfter() time.Duration {
+	return max(c.policy.Interval, time.Second)
+}
+
+// admit lets a user join a constrained room once their place in its join queue is among the free places,
+// and queues them otherwise. Users who join or stop trying leave the queue.
+func (c *AdmissionController) admit(ctx context.Context, roomID, userID string, free int) error {
+	position, err := c.queuePosition(ctx, roomID, userID, true)
+	if err != nil {
+		return err
+	}
+	if position > free {
+		return &JoinQueuedError{Position: position, RetryAfter: c.retryAfter()}
+	}
+
+	if err := c.redisClient.ZRem(ctx, formatAdmissionQueueKey(roomID), userID); err != nil {
+		return err
+	}
+	if err := c.redisClient.ZRem(ctx, formatAdmissionSeenKey(roomID), userID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// queuePosition gets a user's place in a room's join queue, starting at 1, after dropping the users who stopped trying.
+// Users who aren't queued get the place they would take, and take it if enqueue is set.
+func (c *AdmissionController) queuePosition(ctx context.Context, roomID, userID string, enqueue bool) (int, error) {
+	queueKey := formatAdmissionQueueKey(roomID)
+	seenKey := formatAdmissionSeenKey(roomID)
+	now := time.Now()
+
+	stale, err := c.redisClient.ZRangeByScore(ctx, seenKey, "-inf", strconv.FormatInt(now.Add(-c.policy.QueueTimeout).UnixMilli(), 10), 0)
+	if err != nil {
+		return 0, err
+	}
+	if len(stale) > 0 {
+		members := make([]any, len(stale))
+		for i, member := range stale {
+			members[i] = member
+		}
+		if err := c.redisClient.ZRem(ctx, queueKey, members...); err != nil {
+			return 0, err
+		}
+		if err := c.redisClient.ZRem(ctx, seenKey, members...); err != nil {
+			return 0, err
+		}
+	}
+
+	rank, err := c.redisClient.ZRank(ctx, queueKey, userID)
+	if err != nil {
+		return 0, err
+	}
+	if rank < 0 && !enqueue {
+		queued, err := c.redisClient.ZCard(ctx, queueKey)
+		if err != nil {
+			return 0, err
+		}
+		return int(queued) + 1, nil
+	}
+	if !enqueue {
+		return int(rank) + 1, nil
+	}
+
+	if rank < 0 {
+		if err := c.redisClient.ZAdd(ctx, queueKey, float64(now.UnixMilli()), userID); err != nil {
+			return 0, err
+		}
+		if rank, err = c.redisClient.ZRank(ctx, queueKey, userID); err != nil {
+			return 0, err
+		}
+	}
+	if err := c.redisClient.ZAdd(ctx, seenKey, float64(now.UnixMilli()), userID); err != nil {
+		return 0, err
+	}
+
+	// The queue goes away once everyone stopped trying
+	for _, key := range []string{queueKey, seenKey} {
+		if err := c.redisClient.Expire(ctx, key, c.policy.QueueTimeout); err != nil {
+			c.logger.Error("Failed to set join queue expiry", err, "key", key)
+		}
+	}
+
+	return int(rank) + 1, nil
+}
+
+// joinLimits gets the participant capacity and listener overflow open to a user joining a room,
+// and whether they are reduced by load. Room staff always get the full limits.
+func (m *Manager) joinLimits(room *models.Room, userID bson.ObjectID) (capacity, overflow int, constrained bool) {
+	capacity, overflow = room.Settings.Capacity, room.Settings.ListenerOverflow
+	if m.admission == nil || !m.admission.Constrained() || isStaffRole(roomRole(room, userID)) {
+		return capacity, overflow, false
+	}
+	return m.admission.effectiveLimit(capacity), m.admission.effectiveLimit(overflow), true
+}
+
+// admitsAge checks whether a user is old enough for a room. Only 18+ rooms check, and admit only
+// users who attested being adults.
+func (m *Manager) admitsAge(ctx context.Context, room *models.Room, userID bson.ObjectID) (bool, error) {
+	if !room.Settings.AllowAgeRestricted {
+		return true, nil
+	}
+	user, err := m.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.IsAdult(time.Now()), nil
+}
+
+// CanJoin reports whether a user can join a room right now without joining it, along with the capacity that applies.
+func (m *Manager) CanJoin(ctx context.Context, roomID, userID bson.ObjectID) (*models.JoinAvailability, error) {
+	room, err := m.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The participant count comes from the cached state rather than listing the room's users
+	participants := 0
+	state, err := m.stateReader.GetRoomState(ctx, roomID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if state != nil {
+		participants = state.ActiveUsers
+	}
+	listeners, err := m.stateManager.CountRoomListeners(ctx, roomID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	capacity, overflow, constrained := m.joinLimits(room, userID)
+	availability := &models.JoinAvailability{
+		Constrained:               m.admission != nil && m.admission.Constrained(),
+		Users:                     participants,
+		Listeners:                 listeners,
+		Capacity:                  room.Settings.Capacity,
+		EffectiveCapacity:         capacity,
+		ListenerOverflow:          room.Settings.ListenerOverflow,
+		EffectiveListenerOverflow: overflow,
+	}
+
+	inRoom, err := m.stateManager.IsUserInRoom(ctx, roomID.Hex(), userID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	isListener, err := m.stateManager.IsListenerInRoom(ctx, roomID.Hex(), userID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	adult, err := m.admitsAge(ctx, room, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case inRoom || isListener:
+		availability.CanJoin = true
+	case !room.IsActive:
+		availability.Reason = "closed"
+	case room.Quarantined && !isStaffRole(roomRole(room, userID)):
+		availability.Reason = "quarantined"
+	case !adult:
+		availability.Reason = "adults_only"
+	case slices.Contains(room.BannedUsers, userID):
+		availability.Reason = "banned"
+	case participants >= room.Settings.Capacity && listeners >= room.Settings.ListenerOverflow:
+		availability.Reason = "room_full"
+	case constrained:
+		free := max(capacity-participants, 0) + max(overflow-listeners, 0)
+		position, err := m.admission.queuePosition(ctx, roomID.Hex(), userID.Hex(), false)
+		if err != nil {
+			return nil, err
+		}
+		if position <= free {
+			availability.CanJoin = true
+			break
+		}
+		availability.Reason = "queued"
+		availability.QueuePosition = position
+		availability.RetryAfter = int(m.admission.retryAfter().Seconds())
+	default:
+		availability.CanJoin = true
+	}
+
+	return availability, nil
+}
+
+// formatAdmissionQueueKey formats the key of a room's join queue, scored by when users first tried to join.
+func formatAdmissionQueueKey(roomID string) string {
+	return admissionKeyPrefix + ":queue:" + roomID
+}
+
+// formatAdmissionSeenKey formats the key of when the users in a room's join queue last tried to join.
+func formatAdmissionSeenKey(roomID string) string {
+	return admissionKeyPrefix + ":seen:" + roomID
+}
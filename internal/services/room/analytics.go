@@ -0,0 +1,528 @@
+package room
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// Room activity types, reported to activity handlers and exported to room owners.
+const (
+	ActivityPlay     = "play"
+	ActivityJoin     = "join"
+	ActivityLeave    = "leave"
+	ActivityVote     = "vote"
+	ActivityReaction = "reaction"
+)
+
+// RoomActivity is something that happened in a room.
+type RoomActivity struct {
+	// Type is the kind of activity, one of the Activity constants.
+	Type string
+
+	// RoomID is the room the activity happened in.
+	RoomID bson.ObjectID
+
+	// UserID is the user who joined, left, voted or reacted, or the DJ of a play.
+	UserID bson.ObjectID
+
+	// MediaID is the media played, voted or reacted on.
+	MediaID bson.ObjectID
+
+	// Media describes the media of a play.
+	Media *models.MediaInfo
+
+	// Vote is the vote cast.
+	Vote string
+
+	// Reaction is the reaction added.
+	Reaction string
+
+	// Votes are the vote and reaction counts of the media after a vote or reaction.
+	Votes map[string]int
+
+	// Audience is the number of users in the room when a play started.
+	Audience int
+
+	// Time is when the activity happened.
+	Time time.Time
+}
+
+// AnalyticsSchemaVersion is the version of the exported analytics event schema. It changes when fields
+// are renamed, removed or change meaning. Fields can be added without a new version.
+const AnalyticsSchemaVersion = 1
+
+const (
+	// analyticsKeyPrefix prefixes the daily event lists and the delivery keys of the analytics export.
+	analyticsKeyPrefix = "analytics"
+
+	// analyticsPendingKey is the set of rooms with events waiting to be delivered to their webhook.
+	analyticsPendingKey = analyticsKeyPrefix + ":pending"
+
+	// analyticsConfigTTL is how long a room's export configuration is cached.
+	// Changes made on other instances take effect after at most this long.
+	analyticsConfigTTL = time.Minute
+
+	// maxPendingAnalyticsEvents caps the events kept for a webhook that doesn't accept them, the oldest are dropped.
+	maxPendingAnalyticsEvents = 10000
+
+	// analyticsBatchSize is the largest number of events delivered to a webhook in one request.
+	analyticsBatchSize = 500
+
+	// analyticsUserIDLength is the length of the pseudonymous user IDs in exported events.
+	analyticsUserIDLength = 16
+)
+
+// AnalyticsEvent is an event in a room's analytics export. It carries no usernames or addresses,
+// users are identified by an ID that is stable within the room but can't be linked to their account.
+type AnalyticsEvent struct {
+	// Schema is the event schema version.
+	Schema int `json:"schema"`
+
+	// ID uniquely identifies the event, so receivers can drop duplicate deliveries.
+	ID string `json:"id"`
+
+	// Type is the kind of event: play, join, leave, vote or reaction.
+	Type string `json:"type"`
+
+	// Room is the ID of the room.
+	Room string `json:"room"`
+
+	// Time is when the event happened.
+	Time time.Time `json:"time"`
+
+	// User is the pseudonymous ID of the user who joined, left, voted or reacted, or the DJ of a play.
+	User string `json:"user,omitempty"`
+
+	// Media is the media played, voted or reacted on.
+	Media *AnalyticsMedia `json:"media,omitempty"`
+
+	// Vote is the vote cast, always one of woot, meh or grab whatever the room calls them.
+	Vote string `json:"vote,omitempty"`
+
+	// Reaction is the reaction added.
+	Reaction string `json:"reaction,omitempty"`
+
+	// Audience is the number of users in the room when a play started.
+	Audience int `json:"audience,omitempty"`
+}
+
+// AnalyticsMedia describes the media of an analytics event.
+type AnalyticsMedia struct {
+	ID       string `json:"id"`
+	Type     string `json:"type,omitempty"`
+	SourceID string `json:"sourceId,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Artist   string `json:"artist,omitempty"`
+	Duration int    `json:"duration,omitempty"`
+}
+
+// AnalyticsPolicy controls the analytics export.
+type AnalyticsPolicy struct {
+	// FlushInterval is how often pending events are delivered to webhooks. Zero disables webhook delivery.
+	FlushInterval time.Duration
+
+	// Retention is how long daily event files are kept for download.
+	Retention time.Duration
+}
+
+// cachedAnalytics is a room's export configuration cached by the exporter.
+type cachedAnalytics struct {
+	config  *models.RoomAnalytics
+	expires time.Time
+}
+
+// AnalyticsExporter exports the activity of rooms whose owners opted in, as daily files
+// they can download and as batches delivered to their webhook.
+type AnalyticsExporter struct {
+	roomManager RoomManager
+	redisClient *redis.Client
+	httpClient  *http.Client
+	policy      AnalyticsPolicy
+	logger      *utils.Logger
+
+	configs map[bson.ObjectID]cachedAnalytics
+	mutex   sync.Mutex
+}
+
+// NewAnalyticsExporter creates a new analytics exporter. Webhook URLs are chosen by room owners, so
+// deliveries only go to public addresses and don't follow redirects.
+func NewAnalyticsExporter(roomManager RoomManager, redisClient *redis.Client, policy AnalyticsPolicy, logger *utils.Logger) *AnalyticsExporter {
+	return &AnalyticsExporter{
+		roomManager: roomManager,
+		redisClient: redisClient,
+		httpClient:  utils.NewPublicHTTPClient(10*time.Second, false),
+		policy:      policy,
+		logger:      logger.Named("analytics_exporter"),
+		configs:     make(map[bson.ObjectID]cachedAnalytics),
+	}
+}
+
+// Record exports a room activity if the room's owner opted in to its type.
+// It is meant to be added as an activity handler.
+func (e *AnalyticsExporter) Record(ctx context.Context, activity RoomActivity) {
+	config := e.roomConfig(ctx, activity.RoomID)
+	if config == nil || !config.Enabled || config.Secret == "" {
+		return
+	}
+	if len(config.Events) > 0 && !slices.Contains(config.Events, activity.Type) {
+		return
+	}
+
+	event, err := json.Marshal(newAnalyticsEvent(activity, config.Secret))
+	if err != nil {
+		e.logger.Error("Failed to encode analytics event", err, "roomId", activity.RoomID.Hex())
+		return
+	}
+
+	pipe := e.redisClient.Pipeline()
+	dailyKey := formatAnalyticsDayKey(activity.RoomID, activity.Time)
+	pipe.RPush(ctx, dailyKey, event)
+	if e.policy.Retention > 0 {
+		pipe.Expire(ctx, dailyKey, e.policy.Retention)
+	}
+	if config.WebhookURL != "" && e.policy.FlushInterval > 0 {
+		pendingKey := formatAnalyticsPendingKey(activity.RoomID)
+		pipe.RPush(ctx, pendingKey, event)
+		pipe.LTrim(ctx, pendingKey, -maxPendingAnalyticsEvents, -1)
+		pipe.SAdd(ctx, analyticsPendingKey, activity.RoomID.Hex())
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		e.logger.Error("Failed to record analytics event", err, "roomId", activity.RoomID.Hex(), "type", activity.Type)
+	}
+}
+
+// newAnalyticsEvent converts a room activity to an exported event, pseudonymizing the user with the room's secret.
+func newAnalyticsEvent(activity RoomActivity, secret string) AnalyticsEvent {
+	event := AnalyticsEvent{
+		Schema:   AnalyticsSchemaVersion,
+		ID:       bson.NewObjectID().Hex(),
+		Type:     activity.Type,
+		Room:     activity.RoomID.Hex(),
+		Time:     activity.Time.UTC(),
+		Vote:     activity.Vote,
+		Reaction: activity.Reaction,
+		Audience: activity.Audience,
+	}
+	if !activity.UserID.IsZero() {
+		event.User = pseudonymize(secret, activity.UserID)
+	}
+	if activity.Media != nil {
+		event.Media = &AnalyticsMedia{
+			ID:       activity.Media.ID.Hex(),
+			Type:     activity.Media.Type,
+			SourceID: activity.Media.SourceID,
+			Title:    activity.Media.Title,
+			Artist:   activity.Media.Artist,
+			Duration: activity.Media.Duration,
+		}
+	} else if !activity.MediaID.IsZero() {
+		event.Media = &AnalyticsMedia{ID: activity.MediaID.Hex()}
+	}
+	return event
+}
+
+// pseudonymize derives a user's pseudonymous ID in a room's export.
+func pseudonymize(secret string, userID bson.ObjectID) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("user:" + userID.Hex()))
+	return hex.EncodeToString(mac.Sum(nil))[:analyticsUserIDLength]
+}
+
+// roomConfig returns a room's export configuration, nil if the room has none.
+func (e *AnalyticsExporter) roomConfig(ctx context.Context, roomID bson.ObjectID) *models.RoomAnalytics {
+	e.mutex.Lock()
+	cached, ok := e.configs[roomID]
+	e.mutex.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.config
+	}
+
+	room, err := e.roomManager.GetRoom(ctx, roomID)
+	if err != nil {
+		e.logger.Error("Failed to get room for analytics export", err, "roomId", roomID.Hex())
+		return nil
+	}
+
+	e.mutex.Lock()
+	e.configs[roomID] = cachedAnalytics{config: room.Analytics, expires: time.Now().Add(analyticsConfigTTL)}
+	e.mutex.Unlock()
+	return room.Analytics
+}
+
+// GetConfig returns a room's export configuration. Only the room's owner can see it.
+func (e *AnalyticsExporter) GetConfig(ctx context.Context, roomID, userID bson.ObjectID) (*models.RoomAnalytics, error) {
+	room, err := e.ownedRoom(ctx, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if room.Analytics == nil {
+		return &models.RoomAnalytics{}, nil
+	}
+	return room.Analytics, nil
+}
+
+// UpdateConfig changes a room's export configuration. Only the room's owner can change it.
+// The secret is generated when the export is first enabled and kept afterwards, so pseudonymous
+// user IDs stay the same when the export is turned off and on again.
+func (e *AnalyticsExporter) UpdateConfig(ctx context.Context, roomID, userID bson.ObjectID, config *models.RoomAnalytics) (*models.RoomAnalytics, error) {
+	if err := utils.Validate(config); err != nil {
+		return nil, models.NewRoomError(models.ErrInvalidAnalytics, err.Error(), http.StatusBadRequest)
+	}
+	if config.WebhookURL != "" {
+		parsed, err := url.Parse(config.WebhookURL)
+		if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			return nil, models.NewRoomError(models.ErrInvalidAnalytics, "The webhook URL must be an https URL", http.StatusBadRequest)
+		}
+		// Host names are checked again on every delivery, as they can resolve to anything
+		if ip := net.ParseIP(parsed.Hostname()); (ip != nil && !utils.IsPublicIP(ip)) || parsed.Hostname() == "localhost" {
+			return nil, models.NewRoomError(models.ErrInvalidAnalytics, "The webhook URL must point at a public address", http.StatusBadRequest)
+		}
+	}
+
+	room, err := e.ownedRoom(ctx, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := &models.RoomAnalytics{
+		Enabled:    config.Enabled,
+		WebhookURL: config.WebhookURL,
+		Events:     config.Events,
+	}
+	if room.Analytics != nil {
+		updated.Secret = room.Analytics.Secret
+	}
+	if updated.Enabled && updated.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, models.NewInternalError(err, "Failed to generate analytics secret")
+		}
+		updated.Secret = hex.EncodeToString(secret)
+	}
+
+	room.Analytics = updated
+	if _, err := e.roomManager.UpdateRoom(ctx, room); err != nil {
+		e.logger.Error("Failed to save analytics export configuration", err, "roomId", roomID.Hex())
+		return nil, err
+	}
+
+	e.mutex.Lock()
+	delete(e.configs, roomID)
+	e.mutex.Unlock()
+
+	e.logger.Info("Analytics export configured", "roomId", roomID.Hex(), "enabled", updated.Enabled, "webhook", updated.WebhookURL != "")
+	return updated, nil
+}
+
+// Export returns a room's events of one day as newline-delimited JSON. Only the room's owner can download them.
+func (e *AnalyticsExporter) Export(ctx context.Context, roomID, userID bson.ObjectID, day time.Time) ([]byte, error) {
+	if _, err := e.ownedRoom(ctx, roomID, userID); err != nil {
+		return nil, err
+	}
+
+	events, err := e.redisClient.LRange(ctx, formatAnalyticsDayKey(roomID, day), 0, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, event := range events {
+		buf.WriteString(event)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// ownedRoom gets a room if the user owns it.
+func (e *AnalyticsExporter) ownedRoom(ctx context.Context, roomID, userID bson.ObjectID) (*models.Room, error) {
+	room, err := e.roomManager.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if room.CreatedBy != userID {
+		return nil, ErrNotAuthorized
+	}
+	return room, nil
+}
+
+// Start begins delivering pending events to the webhooks of their rooms.
+func (e *AnalyticsExporter) Start(ctx context.Context) {
+	if e.policy.FlushInterval <= 0 {
+		e.logger.Info("Analytics webhook delivery is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(e.policy.FlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				e.logger.Info("Stopping analytics exporter")
+				return
+			case <-ticker.C:
+				e.Flush(ctx)
+			}
+		}
+	}()
+
+	e.logger.Info("Analytics exporter started", "interval", e.policy.FlushInterval)
+}
+
+// Flush delivers the pending events of every room to its webhook. Events a webhook doesn't accept
+// are kept and delivered again on the next flush.
+func (e *AnalyticsExporter) Flush(ctx context.Context) {
+	e.pruneConfigs()
+
+	roomIDs, err := e.redisClient.SMembers(ctx, analyticsPendingKey)
+	if err != nil {
+		return
+	}
+
+	for _, id := range roomIDs {
+		roomID, err := bson.ObjectIDFromHex(id)
+		if err != nil {
+			e.redisClient.SRem(ctx, analyticsPendingKey, id)
+			continue
+		}
+
+		// Only one instance delivers a room's events at a time, so they aren't sent twice
+		claimed, err := e.redisClient.Client().SetNX(ctx, formatAnalyticsLockKey(roomID), "1", e.policy.FlushInterval).Result()
+		if err != nil || !claimed {
+			continue
+		}
+
+		if err := e.flushRoom(ctx, roomID); err != nil {
+			e.logger.Warn("Failed to deliver analytics events", "roomId", id, "error", err)
+		}
+		e.redisClient.Del(ctx, formatAnalyticsLockKey(roomID))
+	}
+}
+
+// flushRoom delivers a room's pending events to its webhook in batches.
+func (e *AnalyticsExporter) flushRoom(ctx context.Context, roomID bson.ObjectID) error {
+	pendingKey := formatAnalyticsPendingKey(roomID)
+
+	config := e.roomConfig(ctx, roomID)
+	if config == nil || !config.Enabled || config.WebhookURL == "" {
+		// The owner turned the webhook off, the events remain in the daily files
+		e.redisClient.Del(ctx, pendingKey)
+		e.redisClient.SRem(ctx, analyticsPendingKey, roomID.Hex())
+		return nil
+	}
+
+	for {
+		events, err := e.redisClient.LRange(ctx, pendingKey, 0, analyticsBatchSize-1)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			e.redisClient.SRem(ctx, analyticsPendingKey, roomID.Hex())
+			return nil
+		}
+
+		if err := e.deliver(ctx, roomID, config, events); err != nil {
+			return err
+		}
+		if err := e.redisClient.Client().LTrim(ctx, pendingKey, int64(len(events)), -1).Err(); err != nil {
+			return err
+		}
+		if len(events) < analyticsBatchSize {
+			return nil
+		}
+	}
+}
+
+// deliver posts a batch of events to a room's webhook. The body is signed with the room's secret:
+// X-Listenify-Signature is the hex HMAC-SHA256 of the X-Listenify-Timestamp value, a dot and the body.
+func (e *AnalyticsExporter) deliver(ctx context.Context, roomID bson.ObjectID, config *models.RoomAnalytics, events []string) error {
+	batch := struct {
+		Schema int               `json:"schema"`
+		Room   string            `json:"room"`
+		Events []json.RawMessage `json:"events"`
+	}{
+		Schema: AnalyticsSchemaVersion,
+		Room:   roomID.Hex(),
+		Events: make([]json.RawMessage, len(events)),
+	}
+	for i, event := range events {
+		batch.Events[i] = json.RawMessage(event)
+	}
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(config.Secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Listenify-Schema", strconv.Itoa(AnalyticsSchemaVersion))
+	req.Header.Set("X-Listenify-Timestamp", timestamp)
+	req.Header.Set("X-Listenify-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// pruneConfigs drops expired cached configurations.
+func (e *AnalyticsExporter) pruneConfigs() {
+	now := time.Now()
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for roomID, cached := range e.configs {
+		if now.After(cached.expires) {
+			delete(e.configs, roomID)
+		}
+	}
+}
+
+// formatAnalyticsDayKey formats the key of a room's event list for the UTC day of the given time.
+func formatAnalyticsDayKey(roomID bson.ObjectID, day time.Time) string {
+	return fmt.Sprintf("%s:%s:%s", analyticsKeyPrefix, roomID.Hex(), day.UTC().Format("20060102"))
+}
+
+// formatAnalyticsPendingKey formats the key of a room's events waiting for webhook delivery.
+func formatAnalyticsPendingKey(roomID bson.ObjectID) string {
+	return fmt.Sprintf("%s:pending:%s", analyticsKeyPrefix, roomID.Hex())
+}
+
+// formatAnalyticsLockKey formats the key claiming the delivery of a room's events.
+func formatAnalyticsLockKey(roomID bson.ObjectID) string {
+	return fmt.Sprintf("%s:lock:%s", analyticsKeyPrefix, roomID.Hex())
+}
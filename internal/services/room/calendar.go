@@ -0,0 +1,367 @@
+package room
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// roomCalendarKeyPrefix is the cache key prefix for per-room ICS feeds.
+	roomCalendarKeyPrefix = "calendar:room:"
+
+	// userCalendarKeyPrefix is the cache key prefix for per-user ICS feeds.
+	userCalendarKeyPrefix = "calendar:user:"
+
+	// maxRoomEvents is the maximum number of events a room can have scheduled.
+	maxRoomEvents = 50
+
+	// maxEventHosts is the maximum number of hosts lined up for a single event.
+	maxEventHosts = 10
+
+	// eventFeedHistory is how long finished events remain in feeds.
+	eventFeedHistory = 30 * 24 * time.Hour
+
+	// icsTimeFormat is the UTC date-time format used by iCalendar.
+	icsTimeFormat = "20060102T150405Z"
+
+	// icsLineLimit is the maximum line length in octets before folding.
+	icsLineLimit = 75
+)
+
+// CalendarService manages scheduled room events and renders them as iCalendar feeds.
+type CalendarService struct {
+	roomManager RoomManager
+	userRepo    repositories.UserRepository
+	eventRepo   repositories.EventRepository
+	redisClient *redis.Client
+	feedSecret  []byte
+	cacheTTL    time.Duration
+	logger      *utils.Logger
+}
+
+// NewCalendarService creates a new calendar service.
+// The feed secret signs the private per-user feed URLs.
+func NewCalendarService(
+	roomManager RoomManager,
+	userRepo repositories.UserRepository,
+	eventRepo repositories.EventRepository,
+	redisClient *redis.Client,
+	feedSecret string,
+	cacheTTL time.Duration,
+	logger *utils.Logger,
+) *CalendarService {
+	return &CalendarService{
+		roomManager: roomManager,
+		userRepo:    userRepo,
+		eventRepo:   eventRepo,
+		redisClient: redisClient,
+		feedSecret:  []byte(feedSecret),
+		cacheTTL:    cacheTTL,
+		logger:      logger.Named("calendar_service"),
+	}
+}
+
+// GetEvents gets a room's events that have not finished yet, soonest first.
+// Private rooms are not found by users who can't see their events.
+func (s *CalendarService) GetEvents(ctx context.Context, roomID, viewerID bson.ObjectID) ([]*models.RoomEvent, error) {
+	room, err := s.roomManager.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	visible, err := s.canViewEvents(ctx, room, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, models.ErrRoomNotFound
+	}
+
+	return s.eventRepo.FindRoomEvents(ctx, []bson.ObjectID{room.ID}, time.Now())
+}
+
+// AddEvent schedules an event in a room. Only the room owner and moderators can schedule events.
+// The hosts lined up for the event must be existing users.
+func (s *CalendarService) AddEvent(ctx context.Context, roomID, userID bson.ObjectID, event *models.RoomEvent) (*models.RoomEvent, error) {
+	event.Title = strings.TrimSpace(event.Title)
+	event.Description = strings.TrimSpace(event.Description)
+	event.Theme = strings.TrimSpace(event.Theme)
+	if len(event.Title) < 2 || len(event.Title) > 100 || len(event.Description) > 1000 || len(event.Theme) > 100 ||
+		event.StartTime.IsZero() || !event.EndTime.After(event.StartTime) || event.EndTime.Before(time.Now()) {
+		return nil, models.ErrInvalidRoomEvent
+	}
+
+	hosts := make([]bson.ObjectID, 0, len(event.Hosts))
+	for _, hostID := range event.Hosts {
+		if !hostID.IsZero() && !slices.Contains(hosts, hostID) {
+			hosts = append(hosts, hostID)
+		}
+	}
+	event.Hosts = hosts
+	if len(event.Hosts) > maxEventHosts {
+		return nil, models.NewRoomError(models.ErrInvalidRoomEvent, fmt.Sprintf("events can have at most %d hosts", maxEventHosts), http.StatusBadRequest)
+	}
+	for _, hostID := range event.Hosts {
+		if _, err := s.userRepo.FindByID(ctx, hostID); err != nil {
+			if errors.Is(err, models.ErrUserNotFound) {
+				return nil, models.NewRoomError(models.ErrInvalidRoomEvent, "event hosts must be existing users", http.StatusBadRequest)
+			}
+			return nil, err
+		}
+	}
+
+	room, err := s.roomManager.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if !canManageEvents(room, userID) {
+		return nil, ErrNotAuthorized
+	}
+
+	scheduled, err := s.eventRepo.CountRoomEvents(ctx, room.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if scheduled >= maxRoomEvents {
+		return nil, models.NewRoomError(models.ErrInvalidRoomEvent, fmt.Sprintf("rooms can have at most %d scheduled events", maxRoomEvents), http.StatusBadRequest)
+	}
+
+	event.ID = bson.NewObjectID()
+	event.RoomID = room.ID
+	event.StartTime = event.StartTime.UTC()
+	event.EndTime = event.EndTime.UTC()
+	event.CreatedBy = userID
+	event.CreatedAt = time.Now()
+
+	if err := s.eventRepo.CreateEvent(ctx, event); err != nil {
+		s.logger.Error("Failed to save room event", err, "roomId", roomID.Hex())
+		return nil, err
+	}
+	s.invalidateRoomFeed(ctx, room.ID)
+
+	s.logger.Info("Room event scheduled", "roomId", roomID.Hex(), "eventId", event.ID.Hex(), "userId", userID.Hex())
+	return event, nil
+}
+
+// RemoveEvent cancels a scheduled event. Only the room owner and moderators can cancel events.
+func (s *CalendarService) RemoveEvent(ctx context.Context, roomID, eventID, userID bson.ObjectID) error {
+	room, err := s.roomManager.GetRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if !canManageEvents(room, userID) {
+		return ErrNotAuthorized
+	}
+
+	if err := s.eventRepo.DeleteEvent(ctx, room.ID, eventID); err != nil {
+		if !errors.Is(err, models.ErrRoomEventNotFound) {
+			s.logger.Error("Failed to remove room event", err, "roomId", roomID.Hex(), "eventId", eventID.Hex())
+		}
+		return err
+	}
+	s.invalidateRoomFeed(ctx, room.ID)
+
+	s.logger.Info("Room event cancelled", "roomId", roomID.Hex(), "eventId", eventID.Hex(), "userId", userID.Hex())
+	return nil
+}
+
+// RoomFeed renders the ICS feed for a public room.
+func (s *CalendarService) RoomFeed(ctx context.Context, slug string) ([]byte, error) {
+	room, err := s.roomManager.GetRoomBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	if room.Settings.Private {
+		return nil, models.ErrRoomNotFound
+	}
+
+	return s.cached(ctx, roomCalendarKeyPrefix+room.ID.Hex(), func() ([]byte, error) {
+		return s.renderRooms(ctx, room.Name, []*models.Room{room})
+	})
+}
+
+// UserFeed renders the ICS feed combining the events of every public room a user has favorited.
+// Per-user feeds are not invalidated on changes and rely on the cache TTL instead.
+func (s *CalendarService) UserFeed(ctx context.Context, userID bson.ObjectID) ([]byte, error) {
+	return s.cached(ctx, userCalendarKeyPrefix+userID.Hex(), func() ([]byte, error) {
+		user, err := s.userRepo.FindByID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		rooms := make([]*models.Room, 0, len(user.Connections.Favorites))
+		for _, roomID := range user.Connections.Favorites {
+			room, err := s.roomManager.GetRoom(ctx, roomID)
+			if err != nil {
+				if !errors.Is(err, models.ErrRoomNotFound) {
+					s.logger.Error("Failed to get favorited room", err, "roomId", roomID.Hex(), "userId", userID.Hex())
+				}
+				continue
+			}
+			if room.Settings.Private {
+				continue
+			}
+			rooms = append(rooms, room)
+		}
+
+		return s.renderRooms(ctx, user.Username+"'s rooms", rooms)
+	})
+}
+
+// renderRooms renders the events of the given rooms that finished recently or have yet to.
+func (s *CalendarService) renderRooms(ctx context.Context, name string, rooms []*models.Room) ([]byte, error) {
+	roomIDs := make([]bson.ObjectID, len(rooms))
+	for i, room := range rooms {
+		roomIDs[i] = room.ID
+	}
+
+	events, err := s.eventRepo.FindRoomEvents(ctx, roomIDs, time.Now().Add(-eventFeedHistory))
+	if err != nil {
+		return nil, err
+	}
+
+	return renderCalendar(name, rooms, events), nil
+}
+
+// UserFeedToken returns the token authorizing access to a user's feed.
+// Calendar apps cannot send auth headers, so the token is embedded in the subscription URL.
+func (s *CalendarService) UserFeedToken(userID bson.ObjectID) string {
+	mac := hmac.New(sha256.New, s.feedSecret)
+	mac.Write([]byte("calendar:" + userID.Hex()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyUserFeedToken checks a user feed token in constant time.
+func (s *CalendarService) VerifyUserFeedToken(userID bson.ObjectID, token string) bool {
+	return hmac.Equal([]byte(token), []byte(s.UserFeedToken(userID)))
+}
+
+// cached serves a feed from Redis, rendering and storing it on a miss.
+func (s *CalendarService) cached(ctx context.Context, key string, render func() ([]byte, error)) ([]byte, error) {
+	if s.cacheTTL > 0 {
+		if feed, err := s.redisClient.Get(ctx, key); err == nil && feed != "" {
+			return []byte(feed), nil
+		}
+	}
+
+	feed, err := render()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.cacheTTL > 0 {
+		if err := s.redisClient.Set(ctx, key, string(feed), s.cacheTTL); err != nil {
+			s.logger.Warn("Failed to cache calendar feed", "key", key, "error", err)
+		}
+	}
+
+	return feed, nil
+}
+
+// invalidateRoomFeed drops a room's cached feed after its events change.
+func (s *CalendarService) invalidateRoomFeed(ctx context.Context, roomID bson.ObjectID) {
+	if err := s.redisClient.Del(ctx, roomCalendarKeyPrefix+roomID.Hex()); err != nil {
+		s.logger.Warn("Failed to invalidate room calendar feed", "roomId", roomID.Hex(), "error", err)
+	}
+}
+
+// canViewEvents checks whether a user can see a room's events. Like their feed, the events of a private
+// room are hidden from everyone but the users holding a role in it and the users in it.
+func (s *CalendarService) canViewEvents(ctx context.Context, room *models.Room, userID bson.ObjectID) (bool, error) {
+	if !room.Settings.Private || roomRole(room, userID) != models.RoomRoleUser {
+		return true, nil
+	}
+	return s.roomManager.IsUserInRoom(ctx, room.ID, userID)
+}
+
+// canManageEvents checks whether a user is the room owner or one of its co-hosts.
+func canManageEvents(room *models.Room, userID bson.ObjectID) bool {
+	return isStaffRole(roomRole(room, userID))
+}
+
+// renderCalendar renders events held in the given rooms as an RFC 5545 calendar.
+func renderCalendar(name string, rooms []*models.Room, events []*models.RoomEvent) []byte {
+	var buf bytes.Buffer
+	now := time.Now().UTC()
+
+	roomNames := make(map[bson.ObjectID]string, len(rooms))
+	for _, room := range rooms {
+		roomNames[room.ID] = room.Name
+	}
+
+	writeICSLine(&buf, "BEGIN:VCALENDAR")
+	writeICSLine(&buf, "VERSION:2.0")
+	writeICSLine(&buf, "PRODID:-//Listenify//Room Events//EN")
+	writeICSLine(&buf, "CALSCALE:GREGORIAN")
+	writeICSLine(&buf, "METHOD:PUBLISH")
+	writeICSLine(&buf, "X-WR-CALNAME:"+escapeICSText(name))
+
+	for _, event := range events {
+		writeICSLine(&buf, "BEGIN:VEVENT")
+		writeICSLine(&buf, "UID:"+event.ID.Hex()+"@listenify")
+		writeICSLine(&buf, "DTSTAMP:"+now.Format(icsTimeFormat))
+		writeICSLine(&buf, "CREATED:"+event.CreatedAt.UTC().Format(icsTimeFormat))
+		writeICSLine(&buf, "DTSTART:"+event.StartTime.UTC().Format(icsTimeFormat))
+		writeICSLine(&buf, "DTEND:"+event.EndTime.UTC().Format(icsTimeFormat))
+		writeICSLine(&buf, "SUMMARY:"+escapeICSText(event.Title))
+		if event.Description != "" {
+			writeICSLine(&buf, "DESCRIPTION:"+escapeICSText(event.Description))
+		}
+		if event.Theme != "" {
+			writeICSLine(&buf, "CATEGORIES:"+escapeICSText(event.Theme))
+		}
+		writeICSLine(&buf, "LOCATION:"+escapeICSText(roomNames[event.RoomID]))
+		writeICSLine(&buf, "END:VEVENT")
+	}
+
+	writeICSLine(&buf, "END:VCALENDAR")
+	return buf.Bytes()
+}
+
+// writeICSLine writes a content line, folding it at the octet limit without splitting UTF-8 sequences.
+func writeICSLine(buf *bytes.Buffer, line string) {
+	limit := icsLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+		limit = icsLineLimit - 1 // Continuation lines start with a space
+	}
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
+
+// icsTextEscaper escapes TEXT values as required by RFC 5545.
+var icsTextEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", "",
+)
+
+// escapeICSText escapes a TEXT property value.
+func escapeICSText(text string) string {
+	return icsTextEscaper.Replace(text)
+}
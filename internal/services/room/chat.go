@@ -4,12 +4,18 @@ package room
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"slices"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
 	"norelock.dev/listenify/backend/internal/db/redis/managers"
 	"norelock.dev/listenify/backend/internal/models"
 	"norelock.dev/listenify/backend/internal/utils"
@@ -21,16 +27,79 @@ var (
 	ErrNotAuthorized   = errors.New("not authorized to perform this action")
 )
 
+// linkPattern matches URLs and bare www. hosts in chat messages.
+var linkPattern = regexp.MustCompile(`(?i)\b(?:[a-z][a-z0-9+.-]*://|www\.)\S+`)
+
 // ChatService provides chat functionality for rooms.
 type ChatService interface {
 	// SendMessage sends a chat message to a room.
 	SendMessage(ctx context.Context, message models.ChatMessage) (models.ChatMessage, error)
 
-	// GetMessages retrieves chat messages for a room.
-	GetMessages(ctx context.Context, roomID string, limit int, before string) ([]models.ChatMessage, error)
+	// GetMessages retrieves the chat messages of one of a room's chat channels, the general channel if it is empty.
+	GetMessages(ctx context.Context, roomID string, channel string, limit int, before string) ([]models.ChatMessage, error)
+
+	// GetBacklog gets the most recent messages of a room's general chat channel for users joining it, most recent first.
+	GetBacklog(ctx context.Context, roomID string, limit int) ([]models.ChatMessage, error)
+
+	// InvalidateBacklog drops a room's cached backlog after its past messages changed.
+	InvalidateBacklog(ctx context.Context, roomID string)
 
 	// DeleteMessage deletes a chat message.
 	DeleteMessage(ctx context.Context, roomID string, messageID string, userID string) error
+
+	// PinMessage pins a chat message to the top of a room's chat.
+	PinMessage(ctx context.Context, roomID string, messageID string, userID string) (models.ChatMessage, error)
+
+	// UnpinMessage removes a chat message from a room's pinned messages.
+	UnpinMessage(ctx context.Context, roomID string, messageID string, userID string) error
+
+	// GetPinnedMessages retrieves a room's pinned messages, oldest pin first.
+	GetPinnedMessages(ctx context.Context, roomID string) ([]models.ChatMessage, error)
+
+	// ApplySettingsChange updates the chat behavior of a room whose settings changed.
+	ApplySettingsChange(ctx context.Context, change RoomSettingsChange)
+
+	// AddMessageHandler adds a handler called when a chat message is sent.
+	AddMessageHandler(handler func(ctx context.Context, message models.ChatMessage))
+
+	// RegisterCommand adds a chat command available in every room.
+	RegisterCommand(command Command)
+
+	// GetCommands lists the chat commands available in a room.
+	GetCommands(ctx context.Context, roomID string) ([]models.ChatCommand, error)
+
+	// SetCustomCommands replaces a room's custom chat commands.
+	SetCustomCommands(ctx context.Context, roomID string, userID string, commands []models.ChatCommandRequest) ([]models.ChatCommand, error)
+
+	// SetChatModes changes the content restrictions of a room's chat.
+	SetChatModes(ctx context.Context, roomID string, userID string, modes models.ChatModes) (models.ChatModes, error)
+
+	// BroadcastMessage posts a message from room staff to the main chat and every overflow chat shard of a room.
+	BroadcastMessage(ctx context.Context, roomID string, userID string, content string) (models.ChatMessage, error)
+
+	// GetChatChannels lists the chat channels of a room, the general channel first.
+	GetChatChannels(ctx context.Context, roomID string) ([]models.ChatChannel, error)
+
+	// CreateChatChannel adds a named channel to a room's chat.
+	CreateChatChannel(ctx context.Context, roomID string, userID string, request models.ChatChannelRequest) (models.ChatChannel, error)
+
+	// UpdateChatChannel changes a room's named chat channel.
+	UpdateChatChannel(ctx context.Context, roomID string, userID string, request models.ChatChannelRequest) (models.ChatChannel, error)
+
+	// DeleteChatChannel removes a named channel from a room's chat.
+	DeleteChatChannel(ctx context.Context, roomID string, userID string, name string) error
+
+	// GetChatFilters lists the terms and patterns blocked in a room's chat, with how often they matched.
+	GetChatFilters(ctx context.Context, roomID string, userID string) ([]models.ChatFilterStats, error)
+
+	// AddChatFilter blocks a term or pattern in a room's chat.
+	AddChatFilter(ctx context.Context, roomID string, userID string, request models.ChatFilterRequest) (models.ChatFilter, error)
+
+	// RemoveChatFilter unblocks a term or pattern in a room's chat.
+	RemoveChatFilter(ctx context.Context, roomID string, userID string, filterID string) error
+
+	// TestChatFilters checks sample messages against a room's chat filters and an optional candidate filter.
+	TestChatFilters(ctx context.Context, roomID string, userID string, messages []string, candidate *models.ChatFilterRequest) ([]models.ChatFilterTest, error)
 }
 
 // ChatRoomManager defines the minimal room management operations needed by the chat service.
@@ -38,8 +107,36 @@ type ChatRoomManager interface {
 	// GetRoom retrieves a room by ID.
 	GetRoom(ctx context.Context, roomID bson.ObjectID) (*models.Room, error)
 
+	// UpdateRoom updates a room.
+	UpdateRoom(ctx context.Context, room *models.Room) (*models.Room, error)
+
 	// IsUserInRoom checks if a user is in a room.
 	IsUserInRoom(ctx context.Context, roomID, userID bson.ObjectID) (bool, error)
+
+	// IsListenerOnly checks if a user is in a room as a listener-only overflow member.
+	IsListenerOnly(ctx context.Context, roomID, userID bson.ObjectID) (bool, error)
+
+	// GetPinnedMessages gets the IDs of a room's pinned chat messages.
+	GetPinnedMessages(ctx context.Context, roomID bson.ObjectID) ([]bson.ObjectID, error)
+
+	// PinMessage adds a chat message to a room's pinned messages unless it is already pinned, reporting
+	// whether it was pinned by this call.
+	PinMessage(ctx context.Context, roomID, messageID bson.ObjectID, limit int) (bool, error)
+
+	// UnpinMessage removes a chat message from a room's pinned messages, reporting whether it was pinned.
+	UnpinMessage(ctx context.Context, roomID, messageID bson.ObjectID) (bool, error)
+
+	// ClearPinnedMessages unpins all of a room's chat messages.
+	ClearPinnedMessages(ctx context.Context, roomID bson.ObjectID) error
+
+	// ChatAppearanceOf gets how a user's name shows in a room's chat, nil when it isn't customized.
+	ChatAppearanceOf(ctx context.Context, room *models.Room, userID bson.ObjectID) *models.ChatAppearance
+
+	// ChatShardOf gets the chat shard a user chats in in a room, 0 for the main chat.
+	ChatShardOf(ctx context.Context, roomID, userID bson.ObjectID) int
+
+	// CheckMinorMessage checks a chat message against the minor policy if its author is a minor.
+	CheckMinorMessage(ctx context.Context, userID bson.ObjectID, content string) error
 }
 
 // chatService implements the ChatService interface.
@@ -48,7 +145,21 @@ type chatService struct {
 	chatRepo    repositories.ChatRepository
 	userRepo    repositories.UserRepository
 	pubSub      *managers.PubSubManager
+	redisClient *redis.Client
+	trustPolicy TrustPolicy
+	maxPinned   int
 	logger      *utils.Logger
+
+	// commands are the chat commands available in every room
+	commands        *CommandRegistry
+	commandsEnabled bool
+
+	// nextMessage holds when each user may chat again in rooms with a chat delay, by room and user
+	nextMessage map[bson.ObjectID]map[bson.ObjectID]time.Time
+	delayMutex  sync.Mutex
+
+	// messageHandlers are notified when a chat message is sent
+	messageHandlers []func(ctx context.Context, message models.ChatMessage)
 }
 
 // NewChatService creates a new chat service.
@@ -57,15 +168,31 @@ func NewChatService(
 	chatRepo repositories.ChatRepository,
 	userRepo repositories.UserRepository,
 	pubSub *managers.PubSubManager,
+	redisClient *redis.Client,
+	trustPolicy TrustPolicy,
+	maxPinned int,
+	commandsEnabled bool,
 	logger *utils.Logger,
 ) ChatService {
-	return &chatService{
-		roomManager: roomManager,
-		chatRepo:    chatRepo,
-		userRepo:    userRepo,
-		pubSub:      pubSub,
-		logger:      logger.Named("chat_service"),
+	s := &chatService{
+		roomManager:     roomManager,
+		chatRepo:        chatRepo,
+		userRepo:        userRepo,
+		pubSub:          pubSub,
+		redisClient:     redisClient,
+		trustPolicy:     trustPolicy,
+		maxPinned:       maxPinned,
+		logger:          logger.Named("chat_service"),
+		commands:        NewCommandRegistry(),
+		commandsEnabled: commandsEnabled,
+		nextMessage:     make(map[bson.ObjectID]map[bson.ObjectID]time.Time),
 	}
+
+	s.commands.Register(meCommand())
+	s.commands.Register(rollCommand())
+	s.commands.Register(s.clearCommand())
+
+	return s
 }
 
 // SendMessage sends a chat message to a room.
@@ -104,6 +231,11 @@ func (s *chatService) SendMessage(ctx context.Context, message models.ChatMessag
 	}
 
 	if !isInRoom {
+		// Overflow listeners are present but may not chat
+		listenerOnly, err := s.roomManager.IsListenerOnly(ctx, roomID, userID)
+		if err == nil && listenerOnly {
+			return models.ChatMessage{}, models.ErrListenerOnly
+		}
 		return models.ChatMessage{}, models.ErrUserNotInRoom
 	}
 
@@ -118,45 +250,306 @@ func (s *chatService) SendMessage(ctx context.Context, message models.ChatMessag
 	}
 
 	// Check if user is muted
-	// TODO: Implement mute check
+	muted, err := s.redisClient.Exists(ctx, formatMuteKey(roomID, userID))
+	if err != nil {
+		s.logger.Warn("Failed to check if user is muted", "roomId", roomID.Hex(), "userId", userID.Hex(), "error", err)
+		// Continue anyway, a failed check shouldn't silence the room
+	} else if muted {
+		return models.ChatMessage{}, models.ErrUserMuted
+	}
 
-	// Set message ID and creation time
-	message.ID = bson.NewObjectID()
-	message.CreatedAt = time.Now()
+	// Room staff aren't held to the chat delay
+	isStaff := isStaffRole(roomRole(room, userID))
+	if !isStaff && !s.takeChatTurn(roomID, userID, room.Settings.ChatDelay) {
+		return models.ChatMessage{}, models.ErrMessageRateLimited
+	}
+
+	// Channels can be restricted to posting by some roles
+	message.Channel, err = checkChatChannel(room, message.Channel, roomRole(room, userID))
+	if err != nil {
+		return models.ChatMessage{}, err
+	}
 
-	// Get user's role in the room
-	var userRole string
-	if room.CreatedBy == userID {
-		userRole = "owner"
-	} else {
-		if slices.Contains(room.Moderators, userID) {
-			userRole = "moderator"
+	// Users past the room's chat threshold chat in their overflow shard, named channels are read by the whole room
+	if message.Channel == "" {
+		message.ChatShard = s.roomManager.ChatShardOf(ctx, roomID, userID)
+	}
+
+	// Commands run instead of being posted as they are
+	if name, text, ok := parseCommand(message.Content); ok && s.commandsEnabled {
+		return s.runCommand(ctx, room, userID, name, text, message)
+	}
+
+	// Moderators can restrict what is posted during big events
+	if !isStaff {
+		if err := checkChatModes(room.ChatModes, message.Content); err != nil {
+			return models.ChatMessage{}, err
 		}
-		if userRole == "" {
-			userRole = "user"
+		if err := s.checkChatFilters(ctx, room, message.Content); err != nil {
+			return models.ChatMessage{}, err
 		}
 	}
-	message.UserRole = userRole
 
+	// Minors chat under the minor policy, even as room staff
+	if err := s.roomManager.CheckMinorMessage(ctx, userID, message.Content); err != nil {
+		return models.ChatMessage{}, err
+	}
+
+	// Links are only allowed once the user is trusted enough
+	if linkPattern.MatchString(message.Content) {
+		if err := s.trustPolicy.CheckAbility(ctx, userID.Hex(), models.TrustAbilityPostLinks); err != nil {
+			return models.ChatMessage{}, err
+		}
+	}
+
+	// Set message ID and creation time
+	message.ID = bson.NewObjectID()
+	message.CreatedAt = time.Now()
+
+	// Get user's role in the room and how their name shows in its chat
+	message.UserRole = roomRole(room, userID)
+	message.Appearance = s.roomManager.ChatAppearanceOf(ctx, room, userID)
+
+	return s.postMessage(ctx, message)
+}
+
+// postMessage stores a message and broadcasts it to its room.
+func (s *chatService) postMessage(ctx context.Context, message models.ChatMessage) (models.ChatMessage, error) {
 	// Store message in database
-	err = s.chatRepo.SaveMessage(ctx, &message)
+	err := s.chatRepo.SaveMessage(ctx, &message)
 	if err != nil {
-		s.logger.Error("Failed to save message", err, "roomId", roomID.Hex())
+		s.logger.Error("Failed to save message", err, "roomId", message.RoomID.Hex())
 		return models.ChatMessage{}, err
 	}
-
-	// Broadcast message to room
-	err = s.broadcastMessage(ctx, room.ID.Hex(), "chat_message", message)
+	s.appendBacklog(ctx, message)
+
+	// Broadcast message to its chat shard or named channel, or to the whole room for staff broadcasts
+	switch {
+	case message.Broadcast:
+		err = s.broadcastMessage(ctx, message.RoomID.Hex(), "chat_message", message)
+	case message.Channel != "":
+		err = s.pubSub.PublishToChatChannel(ctx, message.RoomID.Hex(), message.Channel, "chat_message", message)
+	default:
+		err = s.pubSub.PublishToChatShard(ctx, message.RoomID.Hex(), message.ChatShard, models.DefaultChatChannel, "chat_message", message)
+	}
 	if err != nil {
-		s.logger.Error("Failed to broadcast message", err, "roomId", roomID.Hex())
+		s.logger.Error("Failed to broadcast message", err, "roomId", message.RoomID.Hex())
 		// Continue anyway, the message was saved
 	}
 
+	for _, handler := range s.messageHandlers {
+		handler(ctx, message)
+	}
+
 	return message, nil
 }
 
-// GetMessages retrieves chat messages for a room.
-func (s *chatService) GetMessages(ctx context.Context, roomID string, limit int, before string) ([]models.ChatMessage, error) {
+// runCommand runs a chat command and turns its response into a message. Responses for the whole room
+// are posted like other messages, private ones are only returned to the sender.
+func (s *chatService) runCommand(ctx context.Context, room *models.Room, userID bson.ObjectID, name, text string, message models.ChatMessage) (models.ChatMessage, error) {
+	role := roomRole(room, userID)
+	result, err := s.commands.Run(ctx, name, &CommandContext{
+		Room:   room,
+		UserID: userID,
+		Role:   role,
+		Args:   strings.Fields(text),
+		Text:   text,
+	})
+	if err != nil {
+		return models.ChatMessage{}, err
+	}
+
+	message.ID = bson.NewObjectID()
+	message.CreatedAt = time.Now()
+	message.UserRole = role
+	message.Type = "command"
+	message.Content = result.Content
+	message.Metadata = map[string]any{"command": result}
+	if result.Emote {
+		// Emotes are the user's own words, so the chat modes apply to them
+		if role == roleUser {
+			if err := checkChatModes(room.ChatModes, result.Content); err != nil {
+				return models.ChatMessage{}, err
+			}
+		}
+		message.Type = "emote"
+		message.Metadata = nil
+	}
+
+	if result.Visibility != models.CommandVisibilityRoom {
+		return message, nil
+	}
+	message.Appearance = s.roomManager.ChatAppearanceOf(ctx, room, userID)
+	return s.postMessage(ctx, message)
+}
+
+// RegisterCommand adds a chat command available in every room.
+func (s *chatService) RegisterCommand(command Command) {
+	s.commands.Register(command)
+}
+
+// GetCommands lists the chat commands available in a room.
+func (s *chatService) GetCommands(ctx context.Context, roomID string) ([]models.ChatCommand, error) {
+	roomObjID, err := bson.ObjectIDFromHex(roomID)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	room, err := s.roomManager.GetRoom(ctx, roomObjID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.commands.Commands(room), nil
+}
+
+// SetCustomCommands replaces a room's custom chat commands. Only the room's owner can define them,
+// and they can't take the name of a command available in every room.
+func (s *chatService) SetCustomCommands(ctx context.Context, roomID string, userID string, commands []models.ChatCommandRequest) ([]models.ChatCommand, error) {
+	roomObjID, err := bson.ObjectIDFromHex(roomID)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	userObjID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	if len(commands) > maxCustomCommands {
+		return nil, models.NewChatError(models.ErrInvalidCommand, fmt.Sprintf("Rooms can have at most %d custom commands", maxCustomCommands), http.StatusBadRequest)
+	}
+
+	custom := make([]models.ChatCommand, 0, len(commands))
+	for _, command := range commands {
+		if err := utils.Validate(command); err != nil {
+			return nil, models.NewChatError(models.ErrInvalidCommand, fmt.Sprintf("Invalid command /%s: %s", command.Name, err.Error()), http.StatusBadRequest)
+		}
+		if s.commands.IsRegistered(command.Name) {
+			return nil, models.NewChatError(models.ErrInvalidCommand, fmt.Sprintf("/%s is a built-in command", command.Name), http.StatusBadRequest)
+		}
+		if slices.ContainsFunc(custom, func(c models.ChatCommand) bool { return c.Name == command.Name }) {
+			return nil, models.NewChatError(models.ErrInvalidCommand, fmt.Sprintf("/%s is defined twice", command.Name), http.StatusBadRequest)
+		}
+
+		minimumRole := command.MinimumRole
+		if minimumRole == "" {
+			minimumRole = roleUser
+		}
+		custom = append(custom, models.ChatCommand{
+			Name:            command.Name,
+			Description:     command.Description,
+			Usage:           commandPrefix + command.Name,
+			MinimumRole:     minimumRole,
+			Enabled:         true,
+			CooldownSeconds: command.CooldownSeconds,
+			Response:        command.Response,
+		})
+	}
+
+	room, err := s.roomManager.GetRoom(ctx, roomObjID)
+	if err != nil {
+		return nil, err
+	}
+	if room.CreatedBy != userObjID {
+		return nil, ErrNotAuthorized
+	}
+
+	room.Commands = custom
+	if _, err := s.roomManager.UpdateRoom(ctx, room); err != nil {
+		s.logger.Error("Failed to save custom commands", err, "roomId", roomID)
+		return nil, err
+	}
+
+	s.logger.Info("Custom chat commands updated", "roomId", roomID, "count", len(custom))
+	return custom, nil
+}
+
+// clearCommand creates the /clear command, which lets users with the chat delete permission delete a room's chat history.
+func (s *chatService) clearCommand() Command {
+	return Command{
+		ChatCommand: models.ChatCommand{
+			Name:        "clear",
+			Description: "Clear the chat",
+			Usage:       "/clear",
+			MinimumRole: roleUser,
+			Permission:  models.RoomPermissionChatDelete,
+			Enabled:     true,
+		},
+		Run: func(ctx context.Context, cmd *CommandContext) (*models.ChatCommandResult, error) {
+			cleared, err := s.chatRepo.DeleteMessagesByRoom(ctx, cmd.Room.ID, cmd.UserID)
+			if err != nil {
+				return nil, err
+			}
+			s.InvalidateBacklog(ctx, cmd.Room.ID.Hex())
+
+			// Deleted messages can't stay pinned
+			if err := s.roomManager.ClearPinnedMessages(ctx, cmd.Room.ID); err != nil {
+				s.logger.Error("Failed to unpin cleared messages", err, "roomId", cmd.Room.ID.Hex())
+			}
+
+			err = s.broadcastMessage(ctx, cmd.Room.ID.Hex(), "chat_cleared", map[string]any{
+				"clearedBy": cmd.UserID.Hex(),
+			})
+			if err != nil {
+				s.logger.Error("Failed to broadcast chat clear", err, "roomId", cmd.Room.ID.Hex())
+				// Continue anyway, the messages were deleted
+			}
+
+			return &models.ChatCommandResult{
+				Visibility: models.CommandVisibilityRoom,
+				Content:    "cleared the chat",
+				Data: map[string]any{
+					"cleared": cleared,
+				},
+			}, nil
+		},
+	}
+}
+
+// AddMessageHandler adds a handler called when a chat message is sent.
+func (s *chatService) AddMessageHandler(handler func(ctx context.Context, message models.ChatMessage)) {
+	s.messageHandlers = append(s.messageHandlers, handler)
+}
+
+// takeChatTurn checks whether a user's chat delay has passed and starts the next one.
+func (s *chatService) takeChatTurn(roomID, userID bson.ObjectID, delay int) bool {
+	if delay <= 0 {
+		return true
+	}
+
+	s.delayMutex.Lock()
+	defer s.delayMutex.Unlock()
+
+	now := time.Now()
+	users, ok := s.nextMessage[roomID]
+	if !ok {
+		users = make(map[bson.ObjectID]time.Time)
+		s.nextMessage[roomID] = users
+	}
+	if now.Before(users[userID]) {
+		return false
+	}
+
+	users[userID] = now.Add(time.Duration(delay) * time.Second)
+	return true
+}
+
+// ApplySettingsChange resets a room's chat delays when its chat delay changes or chat is turned off,
+// so users are held to the new delay right away instead of the one they last chatted under.
+func (s *chatService) ApplySettingsChange(ctx context.Context, change RoomSettingsChange) {
+	if change.Previous.ChatDelay == change.Settings.ChatDelay && change.Settings.ChatEnabled {
+		return
+	}
+
+	s.delayMutex.Lock()
+	defer s.delayMutex.Unlock()
+
+	delete(s.nextMessage, change.RoomID)
+}
+
+// GetMessages retrieves the chat messages of one of a room's chat channels, the general channel if it is empty.
+func (s *chatService) GetMessages(ctx context.Context, roomID string, channel string, limit int, before string) ([]models.ChatMessage, error) {
 	// Validate room ID
 	roomObjID, err := bson.ObjectIDFromHex(roomID)
 	if err != nil {
@@ -164,7 +557,7 @@ func (s *chatService) GetMessages(ctx context.Context, roomID string, limit int,
 	}
 
 	// Check if room exists
-	_, err = s.roomManager.GetRoom(ctx, roomObjID)
+	room, err := s.roomManager.GetRoom(ctx, roomObjID)
 	if err != nil {
 		if errors.Is(err, models.ErrRoomNotFound) {
 			return nil, models.ErrRoomNotFound
@@ -173,6 +566,11 @@ func (s *chatService) GetMessages(ctx context.Context, roomID string, limit int,
 		return nil, err
 	}
 
+	// Check the channel exists, messages of removed channels aren't listed
+	if _, ok := findChatChannel(room, channel); !ok {
+		return nil, models.ErrChatChannelNotFound
+	}
+
 	// Parse before ID if provided
 	var beforeObjID bson.ObjectID
 	if before != "" {
@@ -183,7 +581,7 @@ func (s *chatService) GetMessages(ctx context.Context, roomID string, limit int,
 	}
 
 	// Retrieve messages from database
-	messages, err := s.chatRepo.FindMessagesByRoom(ctx, roomObjID, limit, beforeObjID)
+	messages, err := s.chatRepo.FindMessagesByRoom(ctx, roomObjID, channel, limit, beforeObjID)
 	if err != nil {
 		s.logger.Error("Failed to get messages", err, "roomId", roomID)
 		return nil, err
@@ -193,6 +591,7 @@ func (s *chatService) GetMessages(ctx context.Context, roomID string, limit int,
 	result := make([]models.ChatMessage, len(messages))
 	for i, msg := range messages {
 		result[i] = *msg
+		maskContent(&result[i])
 	}
 
 	return result, nil
@@ -249,15 +648,9 @@ func (s *chatService) DeleteMessage(ctx context.Context, roomID string, messageI
 		isAuthorized = true
 	}
 
-	// Room owner and moderators can delete any message
-	if !isAuthorized {
-		if room.CreatedBy == userObjID {
-			isAuthorized = true
-		} else {
-			if slices.Contains(room.Moderators, userObjID) {
-				isAuthorized = true
-			}
-		}
+	// Roles with the chat delete permission can delete any message
+	if !isAuthorized && room.Can(userObjID, models.RoomPermissionChatDelete) {
+		isAuthorized = true
 	}
 
 	if !isAuthorized {
@@ -270,6 +663,7 @@ func (s *chatService) DeleteMessage(ctx context.Context, roomID string, messageI
 		s.logger.Error("Failed to delete message", err, "messageId", messageID)
 		return err
 	}
+	s.InvalidateBacklog(ctx, roomID)
 
 	// Update message with deletion info
 	message.IsDeleted = true
@@ -286,9 +680,169 @@ func (s *chatService) DeleteMessage(ctx context.Context, roomID string, messageI
 		// Continue anyway, the message was deleted
 	}
 
+	// Deleted messages can't stay pinned
+	if err := s.removePin(ctx, roomObjID, messageObjID, userObjID); err != nil && !errors.Is(err, models.ErrMessageNotFound) {
+		s.logger.Error("Failed to unpin deleted message", err, "messageId", messageID)
+	}
+
 	return nil
 }
 
+// PinMessage pins a chat message to the top of a room's chat.
+// Only the room owner and moderators can pin messages.
+func (s *chatService) PinMessage(ctx context.Context, roomID string, messageID string, userID string) (models.ChatMessage, error) {
+	roomObjID, messageObjID, userObjID, err := parsePinIDs(roomID, messageID, userID)
+	if err != nil {
+		return models.ChatMessage{}, err
+	}
+
+	if err := s.checkCanPin(ctx, roomObjID, userObjID); err != nil {
+		return models.ChatMessage{}, err
+	}
+
+	// Check the message exists and belongs to the room
+	message, err := s.chatRepo.FindMessageByID(ctx, messageObjID)
+	if err != nil {
+		if errors.Is(err, models.ErrMessageNotFound) {
+			return models.ChatMessage{}, models.ErrMessageNotFound
+		}
+		s.logger.Error("Failed to get message", err, "messageId", messageID)
+		return models.ChatMessage{}, err
+	}
+	if message.RoomID != roomObjID || message.IsDeleted {
+		return models.ChatMessage{}, models.ErrMessageNotFound
+	}
+
+	pinned, err := s.roomManager.PinMessage(ctx, roomObjID, messageObjID, s.maxPinned)
+	if err != nil {
+		return models.ChatMessage{}, err
+	}
+
+	// Pinning is idempotent
+	if !pinned {
+		return *message, nil
+	}
+
+	// Broadcast pin so clients can update their pinned banner
+	err = s.broadcastMessage(ctx, roomID, "chat_message_pinned", map[string]any{
+		"message":  message,
+		"pinnedBy": userID,
+	})
+	if err != nil {
+		s.logger.Error("Failed to broadcast message pin", err, "messageId", messageID)
+		// Continue anyway, the message was pinned
+	}
+
+	return *message, nil
+}
+
+// UnpinMessage removes a chat message from a room's pinned messages.
+// Only the room owner and moderators can unpin messages.
+func (s *chatService) UnpinMessage(ctx context.Context, roomID string, messageID string, userID string) error {
+	roomObjID, messageObjID, userObjID, err := parsePinIDs(roomID, messageID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkCanPin(ctx, roomObjID, userObjID); err != nil {
+		return err
+	}
+
+	return s.removePin(ctx, roomObjID, messageObjID, userObjID)
+}
+
+// GetPinnedMessages retrieves a room's pinned messages, oldest pin first.
+func (s *chatService) GetPinnedMessages(ctx context.Context, roomID string) ([]models.ChatMessage, error) {
+	roomObjID, err := bson.ObjectIDFromHex(roomID)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	pinned, err := s.roomManager.GetPinnedMessages(ctx, roomObjID)
+	if err != nil {
+		s.logger.Error("Failed to get pinned messages", err, "roomId", roomID)
+		return nil, err
+	}
+
+	messages := make([]models.ChatMessage, 0, len(pinned))
+	for _, messageID := range pinned {
+		message, err := s.chatRepo.FindMessageByID(ctx, messageID)
+		if err != nil {
+			if !errors.Is(err, models.ErrMessageNotFound) {
+				s.logger.Error("Failed to get pinned message", err, "messageId", messageID.Hex())
+			}
+			continue
+		}
+		if message.IsDeleted {
+			continue
+		}
+		maskContent(message)
+		messages = append(messages, *message)
+	}
+
+	return messages, nil
+}
+
+// removePin unpins a message and broadcasts the change.
+func (s *chatService) removePin(ctx context.Context, roomID, messageID, userID bson.ObjectID) error {
+	unpinned, err := s.roomManager.UnpinMessage(ctx, roomID, messageID)
+	if err != nil {
+		return err
+	}
+	if !unpinned {
+		return models.ErrMessageNotFound
+	}
+
+	err = s.broadcastMessage(ctx, roomID.Hex(), "chat_message_unpinned", map[string]any{
+		"messageId":  messageID.Hex(),
+		"unpinnedBy": userID.Hex(),
+	})
+	if err != nil {
+		s.logger.Error("Failed to broadcast message unpin", err, "messageId", messageID.Hex())
+		// Continue anyway, the message was unpinned
+	}
+
+	return nil
+}
+
+// checkCanPin checks that the user is the room owner or one of its moderators.
+func (s *chatService) checkCanPin(ctx context.Context, roomID, userID bson.ObjectID) error {
+	room, err := s.roomManager.GetRoom(ctx, roomID)
+	if err != nil {
+		if errors.Is(err, models.ErrRoomNotFound) {
+			return models.ErrRoomNotFound
+		}
+		s.logger.Error("Failed to get room", err, "roomId", roomID.Hex())
+		return err
+	}
+
+	if !isStaffRole(roomRole(room, userID)) {
+		return ErrNotAuthorized
+	}
+	return nil
+}
+
+// parsePinIDs parses the IDs of a pin or unpin request.
+func parsePinIDs(roomID, messageID, userID string) (roomObjID, messageObjID, userObjID bson.ObjectID, err error) {
+	if roomObjID, err = bson.ObjectIDFromHex(roomID); err != nil {
+		return roomObjID, messageObjID, userObjID, models.ErrInvalidID
+	}
+	if messageObjID, err = bson.ObjectIDFromHex(messageID); err != nil {
+		return roomObjID, messageObjID, userObjID, models.ErrInvalidID
+	}
+	if userObjID, err = bson.ObjectIDFromHex(userID); err != nil {
+		return roomObjID, messageObjID, userObjID, models.ErrInvalidID
+	}
+	return roomObjID, messageObjID, userObjID, nil
+}
+
+// maskContent hides the content of a message masked by moderation. The room's moderators review it from the flag.
+func maskContent(message *models.ChatMessage) {
+	if message.IsMasked {
+		message.Content = ""
+	}
+}
+
 // broadcastMessage broadcasts a message to a room channel.
 func (s *chatService) broadcastMessage(ctx context.Context, roomID string, eventType string, data any) error {
 	return s.pubSub.PublishToRoom(ctx, roomID, eventType, data)
@@ -0,0 +1,143 @@
+package room
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// validateChatAppearanceSettings checks the chat colors and flairs of room settings.
+func validateChatAppearanceSettings(settings models.RoomSettings) error {
+	if err := utils.GetValidator().StructPartial(settings, "ChatColors", "ChatFlairs"); err != nil {
+		return models.NewRoomError(models.ErrInvalidChatAppearance, err.Error(), http.StatusBadRequest)
+	}
+	return nil
+}
+
+// SetChatAppearance sets how a user's name shows in a room's chat, from the colors and flairs the room
+// allows. An empty color or flair goes without one, and neither resets the user's appearance.
+func (m *Manager) SetChatAppearance(ctx context.Context, roomID, userID bson.ObjectID, appearance models.ChatAppearance) (*models.ChatAppearance, error) {
+	room, err := m.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	inRoom, err := m.IsUserInRoom(ctx, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !inRoom {
+		return nil, models.ErrUserNotInRoom
+	}
+
+	var ok bool
+	if appearance.Color, ok = allowedChatOption(room.Settings.ChatColors, appearance.Color); !ok {
+		return nil, models.NewRoomError(models.ErrInvalidChatAppearance, "Pick one of the room's chat colors", http.StatusBadRequest)
+	}
+	if appearance.Flair, ok = allowedChatOption(room.Settings.ChatFlairs, appearance.Flair); !ok {
+		return nil, models.NewRoomError(models.ErrInvalidChatAppearance, "Pick one of the room's chat flairs", http.StatusBadRequest)
+	}
+
+	if appearance == (models.ChatAppearance{}) {
+		err = m.roomRepo.SetChatAppearance(ctx, roomID, userID, nil)
+		if err != nil && !errors.Is(err, models.ErrUserNotInRoom) {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	err = m.roomRepo.SetChatAppearance(ctx, roomID, userID, &appearance)
+	if errors.Is(err, models.ErrUserNotInRoom) {
+		// The user's room record is only added once memberships are reconciled
+		err = m.roomRepo.AddUserToRoom(ctx, &models.RoomUser{
+			RoomID:         roomID,
+			UserID:         userID,
+			Role:           roomRole(room, userID),
+			ChatAppearance: &appearance,
+		})
+		if errors.Is(err, models.ErrUserAlreadyInRoom) {
+			err = m.roomRepo.SetChatAppearance(ctx, roomID, userID, &appearance)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &appearance, nil
+}
+
+// ResetChatAppearance resets how a user's name shows in a room's chat, for moderators taking down an
+// abusive flair or color. Moderators need the chat delete permission, and can only reset the appearance
+// of users whose role is below their own.
+func (m *Manager) ResetChatAppearance(ctx context.Context, roomID, actorID, targetID bson.ObjectID) error {
+	room, err := m.GetRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	if actorID != targetID {
+		if !room.Can(actorID, models.RoomPermissionChatDelete) ||
+			models.RoomRoleRanks[room.RoleOf(actorID)] <= models.RoomRoleRanks[room.RoleOf(targetID)] {
+			return ErrNotAuthorized
+		}
+	}
+
+	err = m.roomRepo.SetChatAppearance(ctx, roomID, targetID, nil)
+	if err != nil && !errors.Is(err, models.ErrUserNotInRoom) {
+		return err
+	}
+
+	m.logger.Info("Chat appearance reset", "roomId", roomID.Hex(), "userId", targetID.Hex(), "by", actorID.Hex())
+	return nil
+}
+
+// ChatAppearanceOf gets how a user's name shows in a room's chat, nil when it isn't customized.
+// Colors and flairs the room no longer allows are left out.
+func (m *Manager) ChatAppearanceOf(ctx context.Context, room *models.Room, userID bson.ObjectID) *models.ChatAppearance {
+	if len(room.Settings.ChatColors) == 0 && len(room.Settings.ChatFlairs) == 0 {
+		return nil
+	}
+
+	roomUser, err := m.roomRepo.FindRoomUser(ctx, room.ID, userID)
+	if err != nil {
+		if !errors.Is(err, models.ErrUserNotInRoom) {
+			m.logger.Warn("Failed to get chat appearance", "roomId", room.ID.Hex(), "userId", userID.Hex(), "error", err)
+		}
+		return nil
+	}
+	if roomUser.ChatAppearance == nil {
+		return nil
+	}
+
+	appearance := *roomUser.ChatAppearance
+	if _, ok := allowedChatOption(room.Settings.ChatColors, appearance.Color); !ok {
+		appearance.Color = ""
+	}
+	if _, ok := allowedChatOption(room.Settings.ChatFlairs, appearance.Flair); !ok {
+		appearance.Flair = ""
+	}
+	if appearance == (models.ChatAppearance{}) {
+		return nil
+	}
+	return &appearance
+}
+
+// allowedChatOption finds a chosen color or flair among the ones a room allows, ignoring case, and
+// returns it as the room wrote it. Choosing none is always allowed.
+func allowedChatOption(allowed []string, chosen string) (string, bool) {
+	chosen = strings.TrimSpace(chosen)
+	if chosen == "" {
+		return "", true
+	}
+	for _, option := range allowed {
+		if strings.EqualFold(option, chosen) {
+			return option, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,129 @@
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+const (
+	// chatBacklogKeyPrefix is the key prefix for the capped lists of recent chat messages of rooms.
+	chatBacklogKeyPrefix = "chat_backlog:"
+
+	// maxChatBacklog is the number of recent messages kept per room, and the deepest backlog a room can set.
+	maxChatBacklog = 200
+
+	// chatBacklogTTL is how long a room's backlog list is kept without new messages. It also bounds how long
+	// a message missed while the list was being rebuilt stays out of it.
+	chatBacklogTTL = time.Hour
+)
+
+// GetBacklog gets the most recent messages of a room's general chat channel, most recent first, for users joining it.
+// They are served from a capped list in Redis, rebuilt from MongoDB when it is missing.
+func (s *chatService) GetBacklog(ctx context.Context, roomID string, limit int) ([]models.ChatMessage, error) {
+	roomObjID, err := bson.ObjectIDFromHex(roomID)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	limit = min(limit, maxChatBacklog)
+	if limit <= 0 {
+		return []models.ChatMessage{}, nil
+	}
+
+	key := formatChatBacklogKey(roomID)
+	values, err := s.redisClient.LRange(ctx, key, 0, int64(limit-1))
+	if err != nil {
+		s.logger.Warn("Failed to get chat backlog from cache", "roomId", roomID, "error", err)
+	}
+	if len(values) > 0 {
+		messages := make([]models.ChatMessage, 0, len(values))
+		for _, value := range values {
+			var message models.ChatMessage
+			if err := json.Unmarshal([]byte(value), &message); err != nil {
+				s.logger.Error("Failed to decode cached chat message", err, "roomId", roomID)
+				continue
+			}
+			maskContent(&message)
+			messages = append(messages, message)
+		}
+		return messages, nil
+	}
+
+	// Rebuild the list with as many messages as it keeps, so deeper backlogs are served from it too
+	stored, err := s.chatRepo.FindMessagesByRoom(ctx, roomObjID, models.DefaultChatChannel, maxChatBacklog, bson.ObjectID{})
+	if err != nil {
+		s.logger.Error("Failed to get chat backlog", err, "roomId", roomID)
+		return nil, err
+	}
+	s.fillBacklog(ctx, key, stored)
+
+	messages := make([]models.ChatMessage, 0, min(len(stored), limit))
+	for _, message := range stored[:min(len(stored), limit)] {
+		messages = append(messages, *message)
+		maskContent(&messages[len(messages)-1])
+	}
+	return messages, nil
+}
+
+// InvalidateBacklog drops a room's cached backlog after its past messages changed, so it is rebuilt from MongoDB.
+func (s *chatService) InvalidateBacklog(ctx context.Context, roomID string) {
+	if err := s.redisClient.Del(ctx, formatChatBacklogKey(roomID)); err != nil {
+		s.logger.Warn("Failed to invalidate chat backlog", "roomId", roomID, "error", err)
+	}
+}
+
+// fillBacklog replaces a room's cached backlog with messages given most recent first.
+func (s *chatService) fillBacklog(ctx context.Context, key string, messages []*models.ChatMessage) {
+	if len(messages) == 0 {
+		return
+	}
+
+	values := make([]any, 0, len(messages))
+	for _, message := range messages {
+		data, err := json.Marshal(message)
+		if err != nil {
+			continue
+		}
+		values = append(values, data)
+	}
+
+	pipe := s.redisClient.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.RPush(ctx, key, values...)
+	pipe.Expire(ctx, key, chatBacklogTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.Warn("Failed to cache chat backlog", "key", key, "error", err)
+	}
+}
+
+// appendBacklog adds a new message to the front of its room's cached backlog. Rooms without a cached
+// backlog are left alone, it is rebuilt whole on the next join. The backlog only holds the general channel.
+func (s *chatService) appendBacklog(ctx context.Context, message models.ChatMessage) {
+	if message.Channel != "" {
+		return
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		s.logger.Error("Failed to encode chat message for backlog", err, "messageId", message.ID.Hex())
+		return
+	}
+
+	key := formatChatBacklogKey(message.RoomID.Hex())
+	pipe := s.redisClient.Pipeline()
+	pipe.LPushX(ctx, key, data)
+	pipe.LTrim(ctx, key, 0, maxChatBacklog-1)
+	pipe.Expire(ctx, key, chatBacklogTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.Warn("Failed to add chat message to backlog", "roomId", message.RoomID.Hex(), "error", err)
+	}
+}
+
+// formatChatBacklogKey formats the key of a room's cached backlog.
+func formatChatBacklogKey(roomID string) string {
+	return chatBacklogKeyPrefix + roomID
+}
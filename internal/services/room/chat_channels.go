@@ -0,0 +1,204 @@
+package room
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// maxChatChannels is the number of named chat channels a room can have besides the general channel.
+const maxChatChannels = 10
+
+// generalChannel is the chat channel every room has, which everyone in the room can post in.
+var generalChannel = models.ChatChannel{
+	Name:        models.DefaultChatChannel,
+	Description: "Chat with everyone in the room",
+	PostRole:    roleUser,
+}
+
+// findChatChannel finds a chat channel of a room by name. An empty name is the general channel.
+func findChatChannel(room *models.Room, name string) (models.ChatChannel, bool) {
+	if name == "" || name == models.DefaultChatChannel {
+		return generalChannel, true
+	}
+
+	index := slices.IndexFunc(room.ChatChannels, func(c models.ChatChannel) bool { return c.Name == name })
+	if index < 0 {
+		return models.ChatChannel{}, false
+	}
+	return room.ChatChannels[index], true
+}
+
+// checkChatChannel resolves the chat channel a message is posted in and checks the user's role can post in it.
+// It returns the name the message is stored under, empty for the general channel.
+func checkChatChannel(room *models.Room, name string, role string) (string, error) {
+	channel, ok := findChatChannel(room, name)
+	if !ok {
+		return "", models.ErrChatChannelNotFound
+	}
+	if roleRanks[role] < roleRanks[channel.PostRole] {
+		return "", models.ErrChatChannelRestricted
+	}
+	if channel.Name == models.DefaultChatChannel {
+		return "", nil
+	}
+	return channel.Name, nil
+}
+
+// GetChatChannels lists the chat channels of a room, the general channel first.
+func (s *chatService) GetChatChannels(ctx context.Context, roomID string) ([]models.ChatChannel, error) {
+	roomObjID, err := bson.ObjectIDFromHex(roomID)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	room, err := s.roomManager.GetRoom(ctx, roomObjID)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]models.ChatChannel{generalChannel}, room.ChatChannels...), nil
+}
+
+// CreateChatChannel adds a named channel to a room's chat and tells the room. Only the room's owner and
+// moderators can add channels.
+func (s *chatService) CreateChatChannel(ctx context.Context, roomID string, userID string, request models.ChatChannelRequest) (models.ChatChannel, error) {
+	room, userObjID, err := s.channelRoom(ctx, roomID, userID, request)
+	if err != nil {
+		return models.ChatChannel{}, err
+	}
+
+	if _, ok := findChatChannel(room, request.Name); ok {
+		return models.ChatChannel{}, models.ErrChatChannelExists
+	}
+	if len(room.ChatChannels) >= maxChatChannels {
+		return models.ChatChannel{}, models.NewChatError(models.ErrInvalidChatChannel, fmt.Sprintf("Rooms can have at most %d chat channels", maxChatChannels), http.StatusBadRequest)
+	}
+
+	channel := models.ChatChannel{
+		Name:        request.Name,
+		Description: request.Description,
+		PostRole:    channelPostRole(request.PostRole),
+		CreatedBy:   userObjID,
+		CreatedAt:   time.Now(),
+	}
+	room.ChatChannels = append(room.ChatChannels, channel)
+	if err := s.saveChatChannels(ctx, room, "chat_channel_created", userID, channel); err != nil {
+		return models.ChatChannel{}, err
+	}
+
+	s.logger.Info("Chat channel created", "roomId", roomID, "userId", userID, "channel", channel.Name, "postRole", channel.PostRole)
+	return channel, nil
+}
+
+// UpdateChatChannel changes the description and post role of a room's named chat channel and tells the room.
+// Only the room's owner and moderators can change channels, and the general channel can't be changed.
+func (s *chatService) UpdateChatChannel(ctx context.Context, roomID string, userID string, request models.ChatChannelRequest) (models.ChatChannel, error) {
+	room, _, err := s.channelRoom(ctx, roomID, userID, request)
+	if err != nil {
+		return models.ChatChannel{}, err
+	}
+
+	if request.Name == models.DefaultChatChannel {
+		return models.ChatChannel{}, models.NewChatError(models.ErrInvalidChatChannel, "The general channel can't be changed", http.StatusBadRequest)
+	}
+	index := slices.IndexFunc(room.ChatChannels, func(c models.ChatChannel) bool { return c.Name == request.Name })
+	if index < 0 {
+		return models.ChatChannel{}, models.ErrChatChannelNotFound
+	}
+
+	channel := &room.ChatChannels[index]
+	channel.Description = request.Description
+	channel.PostRole = channelPostRole(request.PostRole)
+	if err := s.saveChatChannels(ctx, room, "chat_channel_updated", userID, *channel); err != nil {
+		return models.ChatChannel{}, err
+	}
+
+	s.logger.Info("Chat channel updated", "roomId", roomID, "userId", userID, "channel", channel.Name, "postRole", channel.PostRole)
+	return *channel, nil
+}
+
+// DeleteChatChannel removes a named channel from a room's chat and tells the room. Its messages are kept
+// but can't be listed anymore. Only the room's owner and moderators can remove channels, and the general
+// channel can't be removed.
+func (s *chatService) DeleteChatChannel(ctx context.Context, roomID string, userID string, name string) error {
+	room, _, err := s.channelRoom(ctx, roomID, userID, models.ChatChannelRequest{Name: name})
+	if err != nil {
+		return err
+	}
+
+	if name == models.DefaultChatChannel {
+		return models.NewChatError(models.ErrInvalidChatChannel, "The general channel can't be removed", http.StatusBadRequest)
+	}
+	index := slices.IndexFunc(room.ChatChannels, func(c models.ChatChannel) bool { return c.Name == name })
+	if index < 0 {
+		return models.ErrChatChannelNotFound
+	}
+
+	channel := room.ChatChannels[index]
+	room.ChatChannels = slices.Delete(room.ChatChannels, index, index+1)
+	if err := s.saveChatChannels(ctx, room, "chat_channel_deleted", userID, channel); err != nil {
+		return err
+	}
+
+	s.logger.Info("Chat channel deleted", "roomId", roomID, "userId", userID, "channel", name)
+	return nil
+}
+
+// channelRoom validates a chat channel request and gets the room it is for, checking the user is its owner or a moderator.
+func (s *chatService) channelRoom(ctx context.Context, roomID string, userID string, request models.ChatChannelRequest) (*models.Room, bson.ObjectID, error) {
+	roomObjID, err := bson.ObjectIDFromHex(roomID)
+	if err != nil {
+		return nil, bson.ObjectID{}, models.ErrInvalidID
+	}
+
+	userObjID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, bson.ObjectID{}, models.ErrInvalidID
+	}
+
+	if err := utils.Validate(request); err != nil {
+		return nil, bson.ObjectID{}, models.NewChatError(models.ErrInvalidChatChannel, fmt.Sprintf("Invalid channel %s: %s", request.Name, err.Error()), http.StatusBadRequest)
+	}
+
+	room, err := s.roomManager.GetRoom(ctx, roomObjID)
+	if err != nil {
+		return nil, bson.ObjectID{}, err
+	}
+	if roleRanks[roomRole(room, userObjID)] < roleRanks[roleModerator] {
+		return nil, bson.ObjectID{}, ErrNotAuthorized
+	}
+	return room, userObjID, nil
+}
+
+// saveChatChannels stores a room's changed chat channels and tells the room about the channel that changed.
+func (s *chatService) saveChatChannels(ctx context.Context, room *models.Room, eventType string, userID string, channel models.ChatChannel) error {
+	if _, err := s.roomManager.UpdateRoom(ctx, room); err != nil {
+		s.logger.Error("Failed to save chat channels", err, "roomId", room.ID.Hex())
+		return err
+	}
+
+	err := s.broadcastMessage(ctx, room.ID.Hex(), eventType, map[string]any{
+		"channel":   channel,
+		"changedBy": userID,
+	})
+	if err != nil {
+		s.logger.Error("Failed to broadcast chat channel change", err, "roomId", room.ID.Hex(), "channel", channel.Name)
+		// Continue anyway, the channels were saved
+	}
+	return nil
+}
+
+// channelPostRole gets the role a chat channel's post role was requested as, everyone when it is empty.
+func channelPostRole(role string) string {
+	if role == "" {
+		return roleUser
+	}
+	return role
+}
@@ -0,0 +1,316 @@
+package room
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// maxChatFilters is the number of terms and patterns a room can block.
+	maxChatFilters = 50
+
+	// maxChatFilterSize is the number of instructions a compiled chat filter pattern may take, which
+	// bounds how long matching a message against it takes.
+	maxChatFilterSize = 2000
+
+	// chatFilterTimeout is how long checking a message against a room's chat filters may take. Messages
+	// taking longer are let through rather than holding up the chat.
+	chatFilterTimeout = 50 * time.Millisecond
+
+	// maxChatFilterSamples is the number of sample messages a moderator can test at once.
+	maxChatFilterSamples = 20
+
+	// chatFilterStatsKeyPrefix prefixes the keys of the match statistics of rooms' chat filters.
+	chatFilterStatsKeyPrefix = "chat_filter_stats:"
+
+	// candidateChatFilter is the match reported for a filter tested before being added.
+	candidateChatFilter = "candidate"
+)
+
+// chatFilterPatterns caches the compiled regular expressions of chat filters, by pattern.
+var chatFilterPatterns sync.Map
+
+// compileChatFilter checks a chat filter is safe to run against every message of a room, and compiles it.
+// Terms are matched as whole words. Patterns are compiled ignoring case, and refused when they are too
+// large, could match any message, or aren't valid regular expressions.
+func compileChatFilter(pattern string, regex bool) (*regexp.Regexp, error) {
+	if !regex {
+		if normalizeWords(pattern) == "" {
+			return nil, models.NewChatError(models.ErrInvalidChatFilter, "Blocked terms need at least one letter or digit", http.StatusBadRequest)
+		}
+		return nil, nil
+	}
+
+	if compiled, ok := chatFilterPatterns.Load(pattern); ok {
+		return compiled.(*regexp.Regexp), nil
+	}
+
+	parsed, err := syntax.Parse(pattern, syntax.Perl|syntax.FoldCase)
+	if err != nil {
+		return nil, models.NewChatError(models.ErrInvalidChatFilter, fmt.Sprintf("Invalid pattern: %s", err.Error()), http.StatusBadRequest)
+	}
+	program, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return nil, models.NewChatError(models.ErrInvalidChatFilter, fmt.Sprintf("Invalid pattern: %s", err.Error()), http.StatusBadRequest)
+	}
+	if len(program.Inst) > maxChatFilterSize {
+		return nil, models.NewChatError(models.ErrInvalidChatFilter, "Pattern is too complex, split it into simpler patterns", http.StatusBadRequest)
+	}
+
+	compiled, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, models.NewChatError(models.ErrInvalidChatFilter, fmt.Sprintf("Invalid pattern: %s", err.Error()), http.StatusBadRequest)
+	}
+	if compiled.MatchString("") {
+		return nil, models.NewChatError(models.ErrInvalidChatFilter, "Pattern would block every message", http.StatusBadRequest)
+	}
+
+	chatFilterPatterns.Store(pattern, compiled)
+	return compiled, nil
+}
+
+// chatFilterMatches checks a message against chat filters, returning the indexes of those matching it.
+// It gives up once the timeout passes, reporting the message as timed out. Filters that can't be
+// compiled anymore are skipped.
+func chatFilterMatches(filters []models.ChatFilter, content string) ([]int, bool) {
+	if len(filters) == 0 {
+		return nil, false
+	}
+
+	done := make(chan []int, 1)
+	go func() {
+		// Pad with spaces so terms only match whole words
+		padded := " " + normalizeWords(content) + " "
+
+		var matches []int
+		for i, filter := range filters {
+			compiled, err := compileChatFilter(filter.Pattern, filter.Regex)
+			if err != nil {
+				continue
+			}
+			if compiled != nil && compiled.MatchString(content) ||
+				compiled == nil && strings.Contains(padded, " "+normalizeWords(filter.Pattern)+" ") {
+				matches = append(matches, i)
+			}
+		}
+		done <- matches
+	}()
+
+	// Patterns are limited in size and matched in linear time, so a check left running ends shortly
+	timer := time.NewTimer(chatFilterTimeout)
+	defer timer.Stop()
+
+	select {
+	case matches := <-done:
+		return matches, false
+	case <-timer.C:
+		return nil, true
+	}
+}
+
+// checkChatFilters checks a message against a room's chat filters, counting the matches of the filters
+// refusing it.
+func (s *chatService) checkChatFilters(ctx context.Context, room *models.Room, content string) error {
+	matches, timedOut := chatFilterMatches(room.ChatFilters, content)
+	if timedOut {
+		s.logger.Warn("Chat filters took too long, letting the message through", "roomId", room.ID.Hex(), "filters", len(room.ChatFilters))
+		return nil
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+
+	key := formatChatFilterStatsKey(room.ID.Hex())
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	for _, i := range matches {
+		id := room.ChatFilters[i].ID.Hex()
+		if _, err := s.redisClient.HIncrBy(ctx, key, id, 1); err != nil {
+			continue // Statistics are best effort
+		}
+		if err := s.redisClient.HSet(ctx, key, id+":last", now); err != nil {
+			s.logger.Error("Failed to record chat filter match", err, "roomId", room.ID.Hex(), "filterId", id)
+		}
+	}
+	return models.ErrChatFiltered
+}
+
+// GetChatFilters lists the chat filters of a room with how often they matched. Only the room's owner and
+// moderators can see them, so users can't look up how to get around them.
+func (s *chatService) GetChatFilters(ctx context.Context, roomID string, userID string) ([]models.ChatFilterStats, error) {
+	room, _, err := s.filterRoom(ctx, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.redisClient.HGetAll(ctx, formatChatFilterStatsKey(roomID))
+	if err != nil {
+		stats = nil // Statistics are best effort, the filters are listed anyway
+	}
+
+	filters := make([]models.ChatFilterStats, 0, len(room.ChatFilters))
+	for _, filter := range room.ChatFilters {
+		entry := models.ChatFilterStats{ChatFilter: filter}
+		entry.Matches, _ = strconv.ParseInt(stats[filter.ID.Hex()], 10, 64)
+		if last, err := strconv.ParseInt(stats[filter.ID.Hex()+":last"], 10, 64); err == nil {
+			entry.LastMatchAt = time.Unix(last, 0)
+		}
+		filters = append(filters, entry)
+	}
+	return filters, nil
+}
+
+// AddChatFilter blocks a term or pattern in a room's chat. Only the room's owner and moderators can add filters.
+func (s *chatService) AddChatFilter(ctx context.Context, roomID string, userID string, request models.ChatFilterRequest) (models.ChatFilter, error) {
+	room, userObjID, err := s.filterRoom(ctx, roomID, userID)
+	if err != nil {
+		return models.ChatFilter{}, err
+	}
+
+	if err := validateChatFilter(request); err != nil {
+		return models.ChatFilter{}, err
+	}
+	if slices.ContainsFunc(room.ChatFilters, func(f models.ChatFilter) bool {
+		return f.Pattern == request.Pattern && f.Regex == request.Regex
+	}) {
+		return models.ChatFilter{}, models.NewChatError(models.ErrInvalidChatFilter, "This filter already exists", http.StatusConflict)
+	}
+	if len(room.ChatFilters) >= maxChatFilters {
+		return models.ChatFilter{}, models.NewChatError(models.ErrInvalidChatFilter, fmt.Sprintf("Rooms can have at most %d chat filters", maxChatFilters), http.StatusBadRequest)
+	}
+
+	filter := models.ChatFilter{
+		ID:        bson.NewObjectID(),
+		Pattern:   request.Pattern,
+		Regex:     request.Regex,
+		CreatedBy: userObjID,
+		CreatedAt: time.Now(),
+	}
+	room.ChatFilters = append(room.ChatFilters, filter)
+	if _, err := s.roomManager.UpdateRoom(ctx, room); err != nil {
+		s.logger.Error("Failed to save chat filters", err, "roomId", roomID)
+		return models.ChatFilter{}, err
+	}
+
+	s.logger.Info("Chat filter added", "roomId", roomID, "userId", userID, "filterId", filter.ID.Hex(), "regex", filter.Regex)
+	return filter, nil
+}
+
+// RemoveChatFilter unblocks a term or pattern in a room's chat, dropping its statistics. Only the room's
+// owner and moderators can remove filters.
+func (s *chatService) RemoveChatFilter(ctx context.Context, roomID string, userID string, filterID string) error {
+	room, _, err := s.filterRoom(ctx, roomID, userID)
+	if err != nil {
+		return err
+	}
+
+	index := slices.IndexFunc(room.ChatFilters, func(f models.ChatFilter) bool { return f.ID.Hex() == filterID })
+	if index < 0 {
+		return models.ErrChatFilterNotFound
+	}
+
+	room.ChatFilters = slices.Delete(room.ChatFilters, index, index+1)
+	if _, err := s.roomManager.UpdateRoom(ctx, room); err != nil {
+		s.logger.Error("Failed to save chat filters", err, "roomId", roomID)
+		return err
+	}
+
+	key := formatChatFilterStatsKey(roomID)
+	for _, field := range []string{filterID, filterID + ":last"} {
+		if err := s.redisClient.HDel(ctx, key, field); err != nil {
+			s.logger.Error("Failed to drop chat filter statistics", err, "roomId", roomID, "filterId", filterID)
+		}
+	}
+
+	s.logger.Info("Chat filter removed", "roomId", roomID, "userId", userID, "filterId", filterID)
+	return nil
+}
+
+// TestChatFilters checks sample messages against a room's chat filters, and a candidate filter if one is
+// given, without posting them or counting matches. Only the room's owner and moderators can test filters.
+func (s *chatService) TestChatFilters(ctx context.Context, roomID string, userID string, messages []string, candidate *models.ChatFilterRequest) ([]models.ChatFilterTest, error) {
+	room, _, err := s.filterRoom(ctx, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(messages) == 0 || len(messages) > maxChatFilterSamples {
+		return nil, models.NewChatError(models.ErrInvalidChatFilter, fmt.Sprintf("Test between 1 and %d messages", maxChatFilterSamples), http.StatusBadRequest)
+	}
+
+	filters := room.ChatFilters
+	if candidate != nil {
+		if err := validateChatFilter(*candidate); err != nil {
+			return nil, err
+		}
+		filters = append(slices.Clip(filters), models.ChatFilter{Pattern: candidate.Pattern, Regex: candidate.Regex})
+	}
+
+	results := make([]models.ChatFilterTest, 0, len(messages))
+	for _, message := range messages {
+		matches, timedOut := chatFilterMatches(filters, message)
+		result := models.ChatFilterTest{
+			Message:  message,
+			Blocked:  len(matches) > 0,
+			Matches:  make([]string, 0, len(matches)),
+			TimedOut: timedOut,
+		}
+		for _, i := range matches {
+			if filters[i].ID.IsZero() {
+				result.Matches = append(result.Matches, candidateChatFilter)
+			} else {
+				result.Matches = append(result.Matches, filters[i].ID.Hex())
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// validateChatFilter validates a chat filter request and checks its pattern is safe to run.
+func validateChatFilter(request models.ChatFilterRequest) error {
+	if err := utils.Validate(request); err != nil {
+		return models.NewChatError(models.ErrInvalidChatFilter, fmt.Sprintf("Invalid filter: %s", err.Error()), http.StatusBadRequest)
+	}
+	_, err := compileChatFilter(request.Pattern, request.Regex)
+	return err
+}
+
+// filterRoom gets the room a chat filter request is for, checking the user is its owner or a moderator.
+func (s *chatService) filterRoom(ctx context.Context, roomID string, userID string) (*models.Room, bson.ObjectID, error) {
+	roomObjID, err := bson.ObjectIDFromHex(roomID)
+	if err != nil {
+		return nil, bson.ObjectID{}, models.ErrInvalidID
+	}
+
+	userObjID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, bson.ObjectID{}, models.ErrInvalidID
+	}
+
+	room, err := s.roomManager.GetRoom(ctx, roomObjID)
+	if err != nil {
+		return nil, bson.ObjectID{}, err
+	}
+	if roleRanks[roomRole(room, userObjID)] < roleRanks[roleModerator] {
+		return nil, bson.ObjectID{}, ErrNotAuthorized
+	}
+	return room, userObjID, nil
+}
+
+// formatChatFilterStatsKey formats the key of the match statistics of a room's chat filters. Each filter
+// has its match count under its ID, and when it last matched under its ID with a ":last" suffix.
+func formatChatFilterStatsKey(roomID string) string {
+	return chatFilterStatsKeyPrefix + roomID
+}
@@ -0,0 +1,111 @@
+package room
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+// imageLinkPattern matches links to image files, which clients embed in the chat.
+var imageLinkPattern = regexp.MustCompile(`(?i)\.(?:png|jpe?g|gif|webp|avif|bmp|svg)(?:[?#]\S*)?$`)
+
+// checkChatModes checks a message's content against a room's chat modes.
+func checkChatModes(modes models.ChatModes, content string) error {
+	if modes.EmojiOnly && !isEmojiOnly(content) {
+		return models.ErrChatEmojiOnly
+	}
+
+	links := linkPattern.FindAllString(content, -1)
+	if len(links) == 0 {
+		return nil
+	}
+	if modes.LinksDisabled {
+		return models.ErrChatLinksDisabled
+	}
+	if modes.ImagesDisabled {
+		for _, link := range links {
+			if imageLinkPattern.MatchString(link) {
+				return models.ErrChatImagesDisabled
+			}
+		}
+	}
+	return nil
+}
+
+// isEmojiOnly checks whether a message is made of emoji and whitespace only.
+// Emoji are symbol characters, along with the joiners, variation selectors, skin tone modifiers,
+// keycaps and tags that combine them into sequences.
+func isEmojiOnly(content string) bool {
+	runes := []rune(strings.TrimSpace(content))
+	hasEmoji := false
+	for i, r := range runes {
+		switch {
+		case unicode.IsSpace(r):
+		case r == '\u200d', r >= '\ufe00' && r <= '\ufe0f', r == '\u20e3':
+			// Zero width joiner, variation selectors and the combining keycap
+		case r >= 0x1f3fb && r <= 0x1f3ff, r >= 0xe0020 && r <= 0xe007f:
+			// Skin tone modifiers and tag sequences
+		case (r == '#' || r == '*' || unicode.IsDigit(r)) && isKeycap(runes[i+1:]):
+			hasEmoji = true
+		case unicode.Is(unicode.So, r):
+			hasEmoji = true
+		default:
+			return false
+		}
+	}
+	return hasEmoji
+}
+
+// isKeycap checks whether the runes following a character turn it into a keycap emoji, like 1️⃣.
+func isKeycap(next []rune) bool {
+	if len(next) > 0 && next[0] == '\ufe0f' {
+		next = next[1:]
+	}
+	return len(next) > 0 && next[0] == '\u20e3'
+}
+
+// SetChatModes changes a room's chat modes and tells the room. Only the room's owner and moderators can change them.
+func (s *chatService) SetChatModes(ctx context.Context, roomID string, userID string, modes models.ChatModes) (models.ChatModes, error) {
+	roomObjID, err := bson.ObjectIDFromHex(roomID)
+	if err != nil {
+		return models.ChatModes{}, models.ErrInvalidID
+	}
+
+	userObjID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return models.ChatModes{}, models.ErrInvalidID
+	}
+
+	room, err := s.roomManager.GetRoom(ctx, roomObjID)
+	if err != nil {
+		return models.ChatModes{}, err
+	}
+	if roleRanks[roomRole(room, userObjID)] < roleRanks[roleModerator] {
+		return models.ChatModes{}, ErrNotAuthorized
+	}
+	if room.ChatModes == modes {
+		return modes, nil
+	}
+
+	room.ChatModes = modes
+	if _, err := s.roomManager.UpdateRoom(ctx, room); err != nil {
+		s.logger.Error("Failed to save chat modes", err, "roomId", roomID)
+		return models.ChatModes{}, err
+	}
+
+	err = s.broadcastMessage(ctx, roomID, "chat_modes_changed", map[string]any{
+		"chatModes": modes,
+		"changedBy": userID,
+	})
+	if err != nil {
+		s.logger.Error("Failed to broadcast chat modes change", err, "roomId", roomID)
+		// Continue anyway, the modes were saved
+	}
+
+	s.logger.Info("Chat modes changed", "roomId", roomID, "userId", userID, "linksDisabled", modes.LinksDisabled, "imagesDisabled", modes.ImagesDisabled, "emojiOnly", modes.EmojiOnly)
+	return modes, nil
+}
@@ -0,0 +1,85 @@
+package room
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+// assignChatShard puts a user who just became a participant of a room in its main chat or, once the
+// main chat is past the room's threshold, in an overflow chat shard. Rooms without a threshold only
+// have the main chat.
+func (m *Manager) assignChatShard(ctx context.Context, room *models.Room, userID string) int {
+	if room.Settings.ChatShardThreshold <= 0 {
+		return 0
+	}
+
+	size := room.Settings.ChatShardSize
+	if size <= 0 {
+		size = room.Settings.ChatShardThreshold
+	}
+
+	shard, err := m.stateManager.AssignChatShard(ctx, room.ID.Hex(), userID, room.Settings.ChatShardThreshold, size)
+	if err != nil {
+		m.logger.Error("Failed to assign chat shard", err, "roomId", room.ID.Hex(), "userId", userID)
+		// Continue anyway, the user chats in the main chat
+		return 0
+	}
+	if shard > 0 {
+		m.logger.Debug("Assigned overflow chat shard", "roomId", room.ID.Hex(), "userId", userID, "chatShard", shard)
+	}
+	return shard
+}
+
+// ChatShardOf gets the chat shard a user chats in in a room, 0 for the main chat.
+func (m *Manager) ChatShardOf(ctx context.Context, roomID, userID bson.ObjectID) int {
+	shard, err := m.stateManager.GetChatShard(ctx, roomID.Hex(), userID.Hex())
+	if err != nil {
+		m.logger.Warn("Failed to get chat shard", "roomId", roomID.Hex(), "userId", userID.Hex(), "error", err)
+		return 0
+	}
+	return shard
+}
+
+// BroadcastMessage posts a message from room staff to the main chat and every overflow chat shard of a room.
+func (s *chatService) BroadcastMessage(ctx context.Context, roomID string, userID string, content string) (models.ChatMessage, error) {
+	roomObjID, err := bson.ObjectIDFromHex(roomID)
+	if err != nil {
+		return models.ChatMessage{}, models.ErrInvalidID
+	}
+
+	userObjID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return models.ChatMessage{}, models.ErrInvalidID
+	}
+
+	room, err := s.roomManager.GetRoom(ctx, roomObjID)
+	if err != nil {
+		return models.ChatMessage{}, err
+	}
+	if roleRanks[roomRole(room, userObjID)] < roleRanks[roleModerator] {
+		return models.ChatMessage{}, ErrNotAuthorized
+	}
+
+	message := models.ChatMessage{
+		ID:         bson.NewObjectID(),
+		RoomID:     roomObjID,
+		UserID:     userObjID,
+		Type:       "text",
+		Content:    content,
+		Broadcast:  true,
+		CreatedAt:  time.Now(),
+		UserRole:   roomRole(room, userObjID),
+		Appearance: s.roomManager.ChatAppearanceOf(ctx, room, userObjID),
+	}
+
+	message, err = s.postMessage(ctx, message)
+	if err != nil {
+		return models.ChatMessage{}, err
+	}
+
+	s.logger.Info("Chat message broadcast to every chat shard", "roomId", roomID, "userId", userID, "messageId", message.ID.Hex())
+	return message, nil
+}
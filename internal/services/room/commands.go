@@ -0,0 +1,408 @@
+package room
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+const (
+	// commandPrefix starts a chat message that runs a command.
+	commandPrefix = "/"
+
+	// maxCustomCommands is the maximum number of custom commands a room can define.
+	maxCustomCommands = 25
+
+	// defaultRollSides is the number of sides /roll rolls without an argument.
+	defaultRollSides = 100
+
+	// maxRollSides is the largest die /roll can roll.
+	maxRollSides = 1000000
+
+	// maxCommandUses is the number of tracked command uses past which old ones are swept.
+	maxCommandUses = 10000
+)
+
+// Room roles, from least to most privileged.
+const (
+	roleUser  = models.RoomRoleUser
+	roleOwner = models.RoomRoleOwner
+
+	// roleModerator is what co-hosts were called before roles existed. Commands saved
+	// back then may still require it.
+	roleModerator = "moderator"
+)
+
+// roleRanks orders the room roles commands can require.
+var roleRanks = map[string]int{
+	roleUser:                  models.RoomRoleRanks[models.RoomRoleUser],
+	models.RoomRoleVIP:        models.RoomRoleRanks[models.RoomRoleVIP],
+	models.RoomRoleResidentDJ: models.RoomRoleRanks[models.RoomRoleResidentDJ],
+	models.RoomRoleCoHost:     models.RoomRoleRanks[models.RoomRoleCoHost],
+	roleModerator:             models.RoomRoleRanks[models.RoomRoleCoHost],
+	roleOwner:                 models.RoomRoleRanks[models.RoomRoleOwner],
+}
+
+// roomRole returns a user's role in a room.
+func roomRole(room *models.Room, userID bson.ObjectID) string {
+	return room.RoleOf(userID)
+}
+
+// isStaffRole checks whether a role is one of the room's staff, its owner and co-hosts.
+func isStaffRole(role string) bool {
+	return roleRanks[role] >= roleRanks[models.RoomRoleCoHost]
+}
+
+// CommandContext is what a chat command runs with.
+type CommandContext struct {
+	// Room is the room the command was sent in.
+	Room *models.Room
+
+	// UserID is the user who ran the command.
+	UserID bson.ObjectID
+
+	// Role is the user's role in the room.
+	Role string
+
+	// Args are the whitespace-separated arguments after the command name.
+	Args []string
+
+	// Text is everything after the command name.
+	Text string
+}
+
+// CommandHandler runs a chat command and returns its response.
+type CommandHandler func(ctx context.Context, cmd *CommandContext) (*models.ChatCommandResult, error)
+
+// Command is a chat command that can be registered with a command registry.
+type Command struct {
+	models.ChatCommand
+
+	// Run runs the command.
+	Run CommandHandler
+}
+
+// commandUse identifies a user's use of a command in a room, for cooldowns.
+type commandUse struct {
+	roomID bson.ObjectID
+	userID bson.ObjectID
+	name   string
+}
+
+// CommandRegistry holds the chat commands available in every room. Rooms add their own
+// custom commands on top, which can't replace the registered ones.
+type CommandRegistry struct {
+	commands map[string]Command
+	mutex    sync.RWMutex
+
+	// lastUse holds when users last ran commands with a cooldown
+	lastUse  map[commandUse]time.Time
+	useMutex sync.Mutex
+}
+
+// NewCommandRegistry creates an empty command registry.
+func NewCommandRegistry() *CommandRegistry {
+	return &CommandRegistry{
+		commands: make(map[string]Command),
+		lastUse:  make(map[commandUse]time.Time),
+	}
+}
+
+// Register adds a command to the registry, replacing a command with the same name.
+func (r *CommandRegistry) Register(command Command) {
+	if command.MinimumRole == "" {
+		command.MinimumRole = roleUser
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.commands[command.Name] = command
+}
+
+// IsRegistered checks whether a command name is taken by a registered command.
+func (r *CommandRegistry) IsRegistered(name string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	_, ok := r.commands[name]
+	return ok
+}
+
+// Commands lists the enabled commands available in a room, sorted by name.
+func (r *CommandRegistry) Commands(room *models.Room) []models.ChatCommand {
+	r.mutex.RLock()
+	commands := make([]models.ChatCommand, 0, len(r.commands)+len(room.Commands))
+	for _, command := range r.commands {
+		if command.Enabled {
+			commands = append(commands, command.ChatCommand)
+		}
+	}
+	r.mutex.RUnlock()
+
+	for _, command := range room.Commands {
+		if command.Enabled && !r.IsRegistered(command.Name) {
+			commands = append(commands, command)
+		}
+	}
+
+	slices.SortFunc(commands, func(a, b models.ChatCommand) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return commands
+}
+
+// Run runs a command by name, checking that it is enabled, that the user's role allows it and that its cooldown passed.
+func (r *CommandRegistry) Run(ctx context.Context, name string, cmd *CommandContext) (*models.ChatCommandResult, error) {
+	command, ok := r.lookup(cmd.Room, name)
+	if !ok {
+		return nil, models.NewChatError(models.ErrInvalidCommand, fmt.Sprintf("Unknown command /%s", name), http.StatusBadRequest)
+	}
+	if !command.Enabled {
+		return nil, models.ErrCommandDisabled
+	}
+	if !commandAllowed(command.ChatCommand, cmd) {
+		return nil, models.ErrInsufficientPermission
+	}
+	if !r.takeCooldown(cmd, command.ChatCommand) {
+		return nil, models.ErrMessageRateLimited
+	}
+
+	result, err := command.Run(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	result.Command = command.Name
+	if result.Visibility == "" {
+		result.Visibility = models.CommandVisibilityPrivate
+	}
+	return result, nil
+}
+
+// commandAllowed checks whether the user's role allows running a command. Commands that require
+// a permission follow the room's permission matrix instead of the minimum role.
+func commandAllowed(command models.ChatCommand, cmd *CommandContext) bool {
+	if command.Permission != "" {
+		return cmd.Room.Can(cmd.UserID, command.Permission)
+	}
+	return roleRanks[cmd.Role] >= roleRanks[command.MinimumRole]
+}
+
+// lookup finds a registered command, or one of the room's custom commands.
+func (r *CommandRegistry) lookup(room *models.Room, name string) (Command, bool) {
+	r.mutex.RLock()
+	command, ok := r.commands[name]
+	r.mutex.RUnlock()
+	if ok {
+		return command, true
+	}
+
+	for _, custom := range room.Commands {
+		if custom.Name == name {
+			return Command{ChatCommand: custom, Run: customCommandHandler(custom)}, true
+		}
+	}
+	return Command{}, false
+}
+
+// takeCooldown checks whether a command's cooldown passed for the user and starts the next one.
+// The room's owner isn't held to cooldowns.
+func (r *CommandRegistry) takeCooldown(cmd *CommandContext, command models.ChatCommand) bool {
+	if command.CooldownSeconds <= 0 || cmd.Role == roleOwner {
+		return true
+	}
+
+	cooldown := time.Duration(command.CooldownSeconds) * time.Second
+	use := commandUse{roomID: cmd.Room.ID, userID: cmd.UserID, name: command.Name}
+	now := time.Now()
+
+	r.useMutex.Lock()
+	defer r.useMutex.Unlock()
+
+	if last, ok := r.lastUse[use]; ok && now.Sub(last) < cooldown {
+		return false
+	}
+
+	// Cooldowns are at most an hour, older uses don't matter anymore
+	if len(r.lastUse) >= maxCommandUses {
+		for key, last := range r.lastUse {
+			if now.Sub(last) > time.Hour {
+				delete(r.lastUse, key)
+			}
+		}
+	}
+	r.lastUse[use] = now
+	return true
+}
+
+// parseCommand splits a chat message into a command name and its text, if the message is a command.
+func parseCommand(content string) (string, string, bool) {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, commandPrefix) {
+		return "", "", false
+	}
+
+	name, text, _ := strings.Cut(content[len(commandPrefix):], " ")
+	name = strings.ToLower(name)
+	if name == "" {
+		return "", "", false
+	}
+	return name, strings.TrimSpace(text), true
+}
+
+// customCommandHandler runs a room's custom command, which replies with its response.
+func customCommandHandler(command models.ChatCommand) CommandHandler {
+	return func(ctx context.Context, cmd *CommandContext) (*models.ChatCommandResult, error) {
+		return &models.ChatCommandResult{
+			Visibility: models.CommandVisibilityRoom,
+			Content:    command.Response,
+		}, nil
+	}
+}
+
+// meCommand describes the user's own action, like "/me waves".
+func meCommand() Command {
+	return Command{
+		ChatCommand: models.ChatCommand{
+			Name:        "me",
+			Description: "Describe what you are doing",
+			Usage:       "/me <action>",
+			MinimumRole: roleUser,
+			Enabled:     true,
+		},
+		Run: func(ctx context.Context, cmd *CommandContext) (*models.ChatCommandResult, error) {
+			if cmd.Text == "" {
+				return nil, models.NewChatError(models.ErrInvalidCommand, "Usage: /me <action>", http.StatusBadRequest)
+			}
+			return &models.ChatCommandResult{
+				Visibility: models.CommandVisibilityRoom,
+				Content:    cmd.Text,
+				Emote:      true,
+			}, nil
+		},
+	}
+}
+
+// rollCommand rolls a die for everyone in the room to see.
+func rollCommand() Command {
+	return Command{
+		ChatCommand: models.ChatCommand{
+			Name:            "roll",
+			Description:     "Roll a die",
+			Usage:           "/roll [sides]",
+			MinimumRole:     roleUser,
+			Enabled:         true,
+			CooldownSeconds: 5,
+		},
+		Run: func(ctx context.Context, cmd *CommandContext) (*models.ChatCommandResult, error) {
+			sides := defaultRollSides
+			if len(cmd.Args) > 0 {
+				n, err := strconv.Atoi(cmd.Args[0])
+				if err != nil || n < 2 || n > maxRollSides {
+					return nil, models.NewChatError(models.ErrInvalidCommand, fmt.Sprintf("Usage: /roll [sides], with 2 to %d sides", maxRollSides), http.StatusBadRequest)
+				}
+				sides = n
+			}
+
+			roll := rand.IntN(sides) + 1
+			return &models.ChatCommandResult{
+				Visibility: models.CommandVisibilityRoom,
+				Content:    fmt.Sprintf("rolled %d (1-%d)", roll, sides),
+				Data: map[string]any{
+					"result": roll,
+					"sides":  sides,
+				},
+			}, nil
+		},
+	}
+}
+
+// NewSkipCommand creates the /skip command, which lets users with the skip permission skip the playing track.
+func NewSkipCommand(queueManager *QueueManager) Command {
+	return Command{
+		ChatCommand: models.ChatCommand{
+			Name:        "skip",
+			Description: "Skip the playing track",
+			Usage:       "/skip [reason]",
+			MinimumRole: roleUser,
+			Permission:  models.RoomPermissionSkip,
+			Enabled:     true,
+		},
+		Run: func(ctx context.Context, cmd *CommandContext) (*models.ChatCommandResult, error) {
+			if _, err := queueManager.SkipCurrentMedia(ctx, cmd.Room.ID); err != nil {
+				return nil, err
+			}
+
+			content := "skipped the playing track"
+			if cmd.Text != "" {
+				content += ": " + cmd.Text
+			}
+			return &models.ChatCommandResult{
+				Visibility: models.CommandVisibilityRoom,
+				Content:    content,
+				Data: map[string]any{
+					"reason": cmd.Text,
+				},
+			}, nil
+		},
+	}
+}
+
+// NewBanCommand creates the /ban command, which lets users with the ban permission ban a user from the
+// room by username. Only users of lower roles can be banned. Bans are recorded in the room's moderation
+// history, and the moderator can undo them like a kick.
+func NewBanCommand(moderation *UndoableModeration, userRepo repositories.UserRepository) Command {
+	return Command{
+		ChatCommand: models.ChatCommand{
+			Name:        "ban",
+			Description: "Ban a user from the room",
+			Usage:       "/ban <username> [reason]",
+			MinimumRole: roleUser,
+			Permission:  models.RoomPermissionBan,
+			Enabled:     true,
+		},
+		Run: func(ctx context.Context, cmd *CommandContext) (*models.ChatCommandResult, error) {
+			if len(cmd.Args) == 0 {
+				return nil, models.NewChatError(models.ErrInvalidCommand, "Usage: /ban <username> [reason]", http.StatusBadRequest)
+			}
+			username := strings.TrimPrefix(cmd.Args[0], "@")
+			reason := strings.TrimSpace(strings.TrimPrefix(cmd.Text, cmd.Args[0]))
+
+			target, err := userRepo.FindByUsername(ctx, username)
+			if err != nil {
+				return nil, err
+			}
+			if target.ID == cmd.UserID || roleRanks[roomRole(cmd.Room, target.ID)] >= roleRanks[cmd.Role] {
+				return nil, models.ErrInsufficientPermission
+			}
+
+			if _, err := moderation.BanUser(ctx, cmd.Room.ID, cmd.UserID, target.ID, reason); err != nil {
+				return nil, err
+			}
+
+			content := "banned " + target.Username
+			if reason != "" {
+				content += ": " + reason
+			}
+			return &models.ChatCommandResult{
+				Visibility: models.CommandVisibilityRoom,
+				Content:    content,
+				Data: map[string]any{
+					"userId":   target.ID.Hex(),
+					"username": target.Username,
+					"reason":   reason,
+				},
+			}, nil
+		},
+	}
+}
@@ -0,0 +1,116 @@
+package room
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+const (
+	// defaultCrowdPickInterval is the number of DJ tracks between crowd picks in rooms that don't set one.
+	defaultCrowdPickInterval = 5
+
+	// crowdPickMinGrabs is the number of grabs a play needs to nominate its track as a crowd pick.
+	crowdPickMinGrabs = 2
+)
+
+// isCrowdPick checks whether media plays as a crowd pick.
+func isCrowdPick(media *models.MediaInfo) bool {
+	return media != nil && media.CrowdPick != nil
+}
+
+// AddCrowdPickHandler adds a handler called when a crowd pick starts playing in a room,
+// with the room's state playing it.
+func (m *QueueManager) AddCrowdPickHandler(handler func(ctx context.Context, roomID bson.ObjectID, state *models.RoomState)) {
+	m.crowdPickHandlers = append(m.crowdPickHandlers, handler)
+}
+
+// crowdPicked notifies the crowd pick handlers that a crowd pick started playing.
+func (m *QueueManager) crowdPicked(ctx context.Context, roomID bson.ObjectID, roomState *models.RoomState) {
+	m.logger.Info("Playing crowd pick", "roomId", roomID.Hex(), "mediaId", roomState.CurrentMedia.ID.Hex(), "grabs", roomState.CurrentMedia.CrowdPick.Grabs)
+	for _, handler := range m.crowdPickHandlers {
+		handler(ctx, roomID, roomState)
+	}
+}
+
+// nextCrowdPick picks the track to replay between DJ turns, with the DJ credited with it, when the room has
+// crowd picks and played enough DJ tracks since the last one. The pick is the track of the recent play with
+// the most grabs, at least crowdPickMinGrabs, that wasn't picked already, isn't the track that just ended,
+// and can still be played in the room. It returns nil when there is nothing to pick.
+func (m *QueueManager) nextCrowdPick(ctx context.Context, roomID bson.ObjectID, roomState *models.RoomState) (*models.MediaInfo, *models.PublicUser) {
+	settings := roomState.Settings
+	if !settings.CrowdPicks || len(roomState.DJQueue) == 0 {
+		return nil, nil
+	}
+	interval := settings.CrowdPickInterval
+	if interval == 0 {
+		interval = defaultCrowdPickInterval
+	}
+
+	entries, err := m.history.stateManager.GetHistoryEntries(ctx, roomID.Hex(), managers.RoomHistoryMaxItems)
+	if err != nil {
+		m.logger.Error("Failed to get room history for crowd pick", err, "roomId", roomID.Hex())
+		return nil, nil
+	}
+
+	// Count the DJ tracks since the last crowd pick, and note the tracks already picked
+	plays := make([]*models.PlayHistory, 0, len(entries))
+	since, counting := 0, true
+	picked := make(map[bson.ObjectID]bool)
+	var lastMediaID bson.ObjectID
+	for _, entry := range entries {
+		if entry.Play == nil {
+			continue
+		}
+		if lastMediaID.IsZero() {
+			lastMediaID = entry.Play.MediaID
+		}
+		if entry.Play.Media.CrowdPick != nil {
+			picked[entry.Play.MediaID] = true
+			counting = false
+			continue
+		}
+		if counting {
+			since++
+		}
+		plays = append(plays, entry.Play)
+	}
+	if since < interval {
+		return nil, nil
+	}
+
+	candidates := make([]*models.PlayHistory, 0)
+	for _, play := range plays {
+		if play.Votes.Grabs < crowdPickMinGrabs || picked[play.MediaID] || play.MediaID == lastMediaID || play.EndTime.IsZero() {
+			continue
+		}
+		candidates = append(candidates, play)
+	}
+
+	// Most grabs first, the most recent play breaking ties
+	slices.SortStableFunc(candidates, func(a, b *models.PlayHistory) int {
+		return cmp.Compare(b.Votes.Grabs, a.Votes.Grabs)
+	})
+
+	for _, play := range candidates {
+		media, err := m.mediaRepo.FindByID(ctx, play.MediaID)
+		if err != nil {
+			m.logger.Error("Failed to get media for crowd pick", err, "roomId", roomID.Hex(), "mediaId", play.MediaID.Hex())
+			continue
+		}
+		if err := playableInRoom(settings, media); err != nil {
+			continue
+		}
+
+		mediaInfo := media.ToMediaInfo(nil)
+		mediaInfo.CrowdPick = &models.CrowdPick{PlayID: play.ID, Grabs: play.Votes.Grabs}
+		dj := play.DJ
+		return mediaInfo, &dj
+	}
+
+	return nil, nil
+}
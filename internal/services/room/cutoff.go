@@ -0,0 +1,137 @@
+package room
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+const (
+	// transitionTriggerTruncated is the trigger of track changes forced by a room's maximum track length.
+	transitionTriggerTruncated = "truncated"
+
+	// cutoffTimeout is how long cutting off a track and advancing its room may take.
+	cutoffTimeout = 30 * time.Second
+)
+
+// playEnd is how a room's current play ends when the room advances.
+type playEnd struct {
+	// skipReason is why the play was skipped, empty if it wasn't
+	skipReason string
+
+	// truncated is set when the play was cut off at the room's maximum track length
+	truncated bool
+}
+
+// trigger gets the trigger of the track change ending the play.
+func (e playEnd) trigger() string {
+	switch {
+	case e.truncated:
+		return transitionTriggerTruncated
+	case e.skipReason != "":
+		return e.skipReason
+	}
+	return transitionTriggerAdvance
+}
+
+// TrackTruncation is a track cut off because it reached its room's maximum track length, whatever
+// length its metadata claimed.
+type TrackTruncation struct {
+	// RoomID is the room the track played in.
+	RoomID bson.ObjectID
+
+	// MediaID is the media cut off.
+	MediaID bson.ObjectID
+
+	// DJID is the DJ who played the media.
+	DJID bson.ObjectID
+
+	// Duration is the length in seconds the media's metadata claimed.
+	Duration int
+
+	// MaxLength is the room's maximum track length in seconds.
+	MaxLength int
+}
+
+// trackCutoffs are the hard cutoffs scheduled for the tracks rooms started playing on this node.
+type trackCutoffs struct {
+	mu     sync.Mutex
+	timers map[bson.ObjectID]*time.Timer
+}
+
+// AddTruncateHandler adds a handler called when a track is cut off at its room's maximum track length,
+// with the room's state after it advanced.
+func (m *QueueManager) AddTruncateHandler(handler func(ctx context.Context, truncation TrackTruncation, state *models.RoomState)) {
+	m.truncateHandlers = append(m.truncateHandlers, handler)
+}
+
+// scheduleCutoff schedules the hard cutoff of the track a room just started playing at the room's maximum
+// track length, replacing the cutoff of the room's previous track.
+func (m *QueueManager) scheduleCutoff(roomID bson.ObjectID, roomState *models.RoomState) {
+	m.cutoffs.mu.Lock()
+	defer m.cutoffs.mu.Unlock()
+
+	if timer := m.cutoffs.timers[roomID]; timer != nil {
+		timer.Stop()
+		delete(m.cutoffs.timers, roomID)
+	}
+
+	maxLength := roomState.Settings.MaxSongLength
+	if roomState.CurrentMedia == nil || roomState.CurrentDJ == nil || maxLength <= 0 {
+		return
+	}
+
+	truncation := TrackTruncation{
+		RoomID:    roomID,
+		MediaID:   roomState.CurrentMedia.ID,
+		DJID:      roomState.CurrentDJ.ID,
+		Duration:  roomState.CurrentMedia.Duration,
+		MaxLength: maxLength,
+	}
+	started := roomState.MediaStartTime
+	cutoff := started.Add(time.Duration(maxLength) * time.Second)
+
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Until(cutoff), func() {
+		m.cutoffs.mu.Lock()
+		if m.cutoffs.timers[roomID] == timer {
+			delete(m.cutoffs.timers, roomID)
+		}
+		m.cutoffs.mu.Unlock()
+
+		m.cutOff(truncation, started)
+	})
+	m.cutoffs.timers[roomID] = timer
+}
+
+// cutOff advances a room still playing a track that reached the room's maximum track length. Tracks
+// that already stopped playing, because they ended or were skipped, are left alone.
+func (m *QueueManager) cutOff(truncation TrackTruncation, started time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), cutoffTimeout)
+	defer cancel()
+
+	roomState, err := m.roomManager.GetRoomState(ctx, truncation.RoomID)
+	if err != nil {
+		m.logger.Error("Failed to get room state for track cutoff", err, "roomId", truncation.RoomID.Hex())
+		return
+	}
+	if roomState.CurrentMedia == nil || roomState.CurrentMedia.ID != truncation.MediaID ||
+		roomState.MediaStartTime.Sub(started).Abs() >= time.Second {
+		return
+	}
+
+	roomState, err = m.advance(ctx, truncation.RoomID, playEnd{truncated: true})
+	if err != nil {
+		m.logger.Error("Failed to cut off track", err, "roomId", truncation.RoomID.Hex(), "mediaId", truncation.MediaID.Hex())
+		return
+	}
+
+	m.logger.Info("Cut off track at maximum length", "roomId", truncation.RoomID.Hex(), "mediaId", truncation.MediaID.Hex(),
+		"djId", truncation.DJID.Hex(), "duration", truncation.Duration, "maxLength", truncation.MaxLength)
+	for _, handler := range m.truncateHandlers {
+		handler(ctx, truncation, roomState)
+	}
+}
@@ -0,0 +1,206 @@
+package room
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// eventKeyPrefix prefixes the keys marking the reminders and starts of room events as done, so each
+// happens once across instances.
+const eventKeyPrefix = "calendar:event:"
+
+// EventPolicy controls the reminders and starts of scheduled room events.
+type EventPolicy struct {
+	// ReminderBefore is how long before an event starts its room is reminded.
+	ReminderBefore time.Duration
+
+	// CheckInterval is how often scheduled events are checked. Zero disables reminders and starts.
+	CheckInterval time.Duration
+}
+
+// EventScheduler reminds rooms of their scheduled events shortly before they start, and activates the
+// rooms when they do so their live state is ready for the hosts. Rooms closed or quarantined by the
+// platform admins are never activated by their events.
+type EventScheduler struct {
+	roomManager *Manager
+	eventRepo   repositories.EventRepository
+	pubSub      *managers.PubSubManager
+	redisClient *redis.Client
+	policy      EventPolicy
+	logger      *utils.Logger
+
+	handlersMutex sync.RWMutex
+
+	// reminderHandlers are notified of each event about to start
+	reminderHandlers []func(ctx context.Context, room *models.Room, event models.RoomEvent)
+}
+
+// NewEventScheduler creates a new room event scheduler.
+func NewEventScheduler(roomManager *Manager, eventRepo repositories.EventRepository, pubSub *managers.PubSubManager, redisClient *redis.Client, policy EventPolicy, logger *utils.Logger) *EventScheduler {
+	return &EventScheduler{
+		roomManager: roomManager,
+		eventRepo:   eventRepo,
+		pubSub:      pubSub,
+		redisClient: redisClient,
+		policy:      policy,
+		logger:      logger.Named("event_scheduler"),
+	}
+}
+
+// AddReminderHandler adds a handler notified of each event about to start, once per event.
+func (s *EventScheduler) AddReminderHandler(handler func(ctx context.Context, room *models.Room, event models.RoomEvent)) {
+	s.handlersMutex.Lock()
+	defer s.handlersMutex.Unlock()
+	s.reminderHandlers = append(s.reminderHandlers, handler)
+}
+
+// Start begins checking scheduled events.
+func (s *EventScheduler) Start(ctx context.Context) {
+	if s.policy.CheckInterval <= 0 {
+		s.logger.Info("Room event scheduling is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.policy.CheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				s.logger.Info("Stopping room event scheduler")
+				return
+			case <-ticker.C:
+				if err := s.CheckEvents(ctx); err != nil {
+					s.logger.Error("Failed to check room events", err)
+				}
+			}
+		}
+	}()
+
+	s.logger.Info("Room event scheduler started", "interval", s.policy.CheckInterval)
+}
+
+// CheckEvents sends the reminders of events starting soon and starts the events whose time has come.
+func (s *EventScheduler) CheckEvents(ctx context.Context) error {
+	now := time.Now()
+	events, err := s.eventRepo.FindEventsStartingBefore(ctx, now.Add(s.policy.ReminderBefore), now)
+	if err != nil {
+		return err
+	}
+
+	rooms := make(map[bson.ObjectID]*models.Room)
+	for _, event := range events {
+		room, ok := rooms[event.RoomID]
+		if !ok {
+			room, err = s.roomManager.GetRoom(ctx, event.RoomID)
+			if err != nil {
+				// The events of deleted rooms are left to expire
+				if !errors.Is(err, models.ErrRoomNotFound) {
+					s.logger.Error("Failed to get room of event", err, "roomId", event.RoomID.Hex(), "eventId", event.ID.Hex())
+				}
+				continue
+			}
+			rooms[event.RoomID] = room
+		}
+
+		if err := s.checkEvent(ctx, room, *event, now); err != nil {
+			s.logger.Error("Failed to check room event", err, "roomId", room.ID.Hex(), "eventId", event.ID.Hex())
+		}
+	}
+
+	return nil
+}
+
+// checkEvent sends an event's reminder or starts it, whichever is due and not done yet. Events found
+// after their start time skip the reminder.
+func (s *EventScheduler) checkEvent(ctx context.Context, room *models.Room, event models.RoomEvent, now time.Time) error {
+	if now.Before(event.StartTime) {
+		claimed, err := s.claim(ctx, event, "reminded")
+		if err != nil || !claimed {
+			return err
+		}
+		s.remind(ctx, room, event)
+		return nil
+	}
+
+	claimed, err := s.claim(ctx, event, "started")
+	if err != nil || !claimed {
+		return err
+	}
+	return s.start(ctx, room, event)
+}
+
+// claim marks a step of an event as done, reporting whether this call was the one to do so.
+// The mark lasts until the event ends.
+func (s *EventScheduler) claim(ctx context.Context, event models.RoomEvent, step string) (bool, error) {
+	ttl := time.Until(event.EndTime)
+	if ttl < s.policy.CheckInterval {
+		ttl = s.policy.CheckInterval
+	}
+	return s.redisClient.Client().SetNX(ctx, formatEventStepKey(event.ID, step), "1", ttl).Result()
+}
+
+// remind tells a room's users and the event's handlers that an event starts soon.
+func (s *EventScheduler) remind(ctx context.Context, room *models.Room, event models.RoomEvent) {
+	err := s.pubSub.PublishToRoom(ctx, room.ID.Hex(), "room_event_starting", map[string]any{
+		"roomId": room.ID.Hex(),
+		"event":  event,
+	})
+	if err != nil {
+		s.logger.Error("Failed to broadcast room event reminder", err, "roomId", room.ID.Hex(), "eventId", event.ID.Hex())
+		// Continue anyway, the hosts are still reminded
+	}
+
+	s.handlersMutex.RLock()
+	handlers := s.reminderHandlers
+	s.handlersMutex.RUnlock()
+	for _, handler := range handlers {
+		handler(ctx, room, event)
+	}
+
+	s.logger.Info("Room event reminder sent", "roomId", room.ID.Hex(), "eventId", event.ID.Hex())
+}
+
+// start activates an event's room and tells its users the event has started.
+func (s *EventScheduler) start(ctx context.Context, room *models.Room, event models.RoomEvent) error {
+	roomID := room.ID.Hex()
+
+	// Closed rooms were closed by the platform admins, only restoring them opens them again
+	if !room.IsActive || room.Quarantined {
+		s.logger.Info("Not activating closed room for its event", "roomId", roomID, "eventId", event.ID.Hex())
+		return nil
+	}
+
+	if err := s.roomManager.stateManager.InitRoom(ctx, roomID); err != nil {
+		return err
+	}
+	s.roomManager.InvalidateLobby(ctx)
+
+	err := s.pubSub.PublishToRoom(ctx, roomID, "room_event_started", map[string]any{
+		"roomId": roomID,
+		"event":  event,
+	})
+	if err != nil {
+		s.logger.Error("Failed to broadcast room event start", err, "roomId", roomID, "eventId", event.ID.Hex())
+		// Continue anyway, the room is active
+	}
+
+	s.logger.Info("Room event started", "roomId", roomID, "eventId", event.ID.Hex())
+	return nil
+}
+
+// formatEventStepKey formats the key marking a step of an event as done.
+func formatEventStepKey(eventID bson.ObjectID, step string) string {
+	return fmt.Sprintf("%s%s:%s", eventKeyPrefix, eventID.Hex(), step)
+}
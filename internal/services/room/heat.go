@@ -0,0 +1,263 @@
+package room
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// heatKeyPrefix prefixes the keys of the heat signals and rates of rooms.
+	heatKeyPrefix = "heat"
+
+	// heatRoomsKey is the set of rooms whose heat is tracked.
+	heatRoomsKey = heatKeyPrefix + ":rooms"
+
+	// heatLockKey claims the recomputation of the heat of all rooms, so one instance does it at a time.
+	heatLockKey = heatKeyPrefix + ":lock"
+
+	// heatWindow is the time over which signals are averaged. Older activity fades out over about this long.
+	heatWindow = 10 * time.Minute
+
+	// minHeatRate is the rate below which a signal counts as gone.
+	minHeatRate = 0.01
+)
+
+// Heat signals counted between recomputations.
+const (
+	heatSignalJoins  = "joins"
+	heatSignalLeaves = "leaves"
+	heatSignalChat   = "chat"
+	heatSignalVotes  = "votes"
+)
+
+// heatWeights weigh the per-minute rate of each signal in a room's heat.
+// Joins count the most, they show the room is drawing people in right now.
+var heatWeights = map[string]float64{
+	heatSignalJoins: 3,
+	heatSignalChat:  1,
+	heatSignalVotes: 2,
+}
+
+// HeatPolicy controls the heat scoring of rooms.
+type HeatPolicy struct {
+	// Interval is how often the heat of rooms is recomputed. Zero disables heat scoring.
+	Interval time.Duration
+}
+
+// HeatService scores how lively rooms are from recent join velocity, retention, chat activity and
+// vote engagement. Activity is counted in Redis and recomputed into the rooms' heat every interval,
+// so discovery can favor lively rooms over big stagnant ones.
+type HeatService struct {
+	roomRepo     repositories.RoomRepository
+	stateManager *managers.RoomStateManager
+	redisClient  *redis.Client
+	policy       HeatPolicy
+	logger       *utils.Logger
+}
+
+// NewHeatService creates a new heat service.
+func NewHeatService(
+	roomRepo repositories.RoomRepository,
+	stateManager *managers.RoomStateManager,
+	redisClient *redis.Client,
+	policy HeatPolicy,
+	logger *utils.Logger,
+) *HeatService {
+	return &HeatService{
+		roomRepo:     roomRepo,
+		stateManager: stateManager,
+		redisClient:  redisClient,
+		policy:       policy,
+		logger:       logger.Named("heat_service"),
+	}
+}
+
+// RecordActivity counts a join, leave or vote towards a room's heat.
+// It is meant to be added as an activity handler.
+func (s *HeatService) RecordActivity(ctx context.Context, activity RoomActivity) {
+	switch activity.Type {
+	case ActivityJoin:
+		s.count(ctx, activity.RoomID, heatSignalJoins)
+	case ActivityLeave:
+		s.count(ctx, activity.RoomID, heatSignalLeaves)
+	case ActivityVote, ActivityReaction:
+		s.count(ctx, activity.RoomID, heatSignalVotes)
+	}
+}
+
+// RecordMessage counts a chat message towards its room's heat.
+// It is meant to be added as a chat message handler.
+func (s *HeatService) RecordMessage(ctx context.Context, message models.ChatMessage) {
+	s.count(ctx, message.RoomID, heatSignalChat)
+}
+
+// count increments a heat signal of a room.
+func (s *HeatService) count(ctx context.Context, roomID bson.ObjectID, signal string) {
+	if s.policy.Interval <= 0 {
+		return
+	}
+
+	pipe := s.redisClient.Pipeline()
+	pipe.HIncrBy(ctx, formatHeatSignalsKey(roomID), signal, 1)
+	pipe.SAdd(ctx, heatRoomsKey, roomID.Hex())
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.Error("Failed to count heat signal", err, "roomId", roomID.Hex(), "signal", signal)
+	}
+}
+
+// Start begins recomputing the heat of rooms.
+func (s *HeatService) Start(ctx context.Context) {
+	if s.policy.Interval <= 0 {
+		s.logger.Info("Room heat scoring is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.policy.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				s.logger.Info("Stopping heat service")
+				return
+			case <-ticker.C:
+				if err := s.Recompute(ctx); err != nil {
+					s.logger.Error("Failed to recompute room heat", err)
+				}
+			}
+		}
+	}()
+
+	s.logger.Info("Heat service started", "interval", s.policy.Interval)
+}
+
+// Recompute folds the signals counted since the last run into each tracked room's rates and
+// updates the rooms' heat. Rooms that went quiet and empty are no longer tracked, their heat drops to zero.
+func (s *HeatService) Recompute(ctx context.Context) error {
+	claimed, err := s.redisClient.Client().SetNX(ctx, heatLockKey, "1", s.policy.Interval/2).Result()
+	if err != nil || !claimed {
+		return err
+	}
+
+	roomIDs, err := s.redisClient.SMembers(ctx, heatRoomsKey)
+	if err != nil {
+		return err
+	}
+
+	// Weight of the latest interval in the moving averages
+	alpha := 1 - math.Exp(-s.policy.Interval.Seconds()/heatWindow.Seconds())
+	minutes := s.policy.Interval.Minutes()
+
+	heat := make(map[bson.ObjectID]float64, len(roomIDs))
+	for _, id := range roomIDs {
+		roomID, err := bson.ObjectIDFromHex(id)
+		if err != nil {
+			s.redisClient.SRem(ctx, heatRoomsKey, id)
+			continue
+		}
+
+		value, tracked, err := s.recomputeRoom(ctx, roomID, alpha, minutes)
+		if err != nil {
+			s.logger.Error("Failed to recompute room heat", err, "roomId", id)
+			continue
+		}
+		heat[roomID] = value
+		if !tracked {
+			s.redisClient.SRem(ctx, heatRoomsKey, id)
+		}
+	}
+
+	return s.roomRepo.UpdateHeat(ctx, heat)
+}
+
+// recomputeRoom updates a room's signal rates and returns its heat, and whether it is still worth tracking.
+func (s *HeatService) recomputeRoom(ctx context.Context, roomID bson.ObjectID, alpha, minutes float64) (float64, bool, error) {
+	signalsKey := formatHeatSignalsKey(roomID)
+	ratesKey := formatHeatRatesKey(roomID)
+
+	// Take the counted signals, new ones start counting towards the next run
+	pipe := s.redisClient.TxPipeline()
+	countsCmd := pipe.HGetAll(ctx, signalsKey)
+	pipe.Del(ctx, signalsKey)
+	ratesCmd := pipe.HGetAll(ctx, ratesKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, true, err
+	}
+
+	users, err := s.stateManager.GetRoomUsers(ctx, roomID.Hex())
+	if err != nil {
+		return 0, true, err
+	}
+
+	counts := countsCmd.Val()
+	previous := ratesCmd.Val()
+	rates := make(map[string]float64, 4)
+	active := false
+	for _, signal := range []string{heatSignalJoins, heatSignalLeaves, heatSignalChat, heatSignalVotes} {
+		count, _ := strconv.ParseFloat(counts[signal], 64)
+		rate, _ := strconv.ParseFloat(previous[signal], 64)
+		rate += alpha * (count/minutes - rate)
+		if rate < minHeatRate {
+			rate = 0
+		}
+		rates[signal] = rate
+		active = active || rate > 0
+	}
+
+	if !active && len(users) == 0 {
+		s.redisClient.Del(ctx, ratesKey)
+		return 0, false, nil
+	}
+
+	fields := make(map[string]any, len(rates))
+	for signal, rate := range rates {
+		fields[signal] = strconv.FormatFloat(rate, 'f', 4, 64)
+	}
+	pipe = s.redisClient.Pipeline()
+	pipe.HSet(ctx, ratesKey, fields)
+	pipe.Expire(ctx, ratesKey, 2*heatWindow)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, true, err
+	}
+
+	return roomHeat(rates, len(users)), true, nil
+}
+
+// roomHeat scores a room from its signal rates and number of users. Engagement is scaled down by how
+// many people left recently compared to those who stayed, and the room's size only adds a little,
+// so a crowd that doesn't do anything scores below a smaller room that is lively.
+func roomHeat(rates map[string]float64, users int) float64 {
+	engagement := 0.0
+	for signal, weight := range heatWeights {
+		engagement += weight * rates[signal]
+	}
+
+	retention := 1.0
+	if left := rates[heatSignalLeaves] * heatWindow.Minutes(); left > 0 {
+		retention = float64(users) / (float64(users) + left)
+	}
+
+	heat := engagement*(0.5+0.5*retention) + math.Log1p(float64(users))
+	return math.Round(heat*100) / 100
+}
+
+// formatHeatSignalsKey formats the key of the signals counted for a room since the last recomputation.
+func formatHeatSignalsKey(roomID bson.ObjectID) string {
+	return fmt.Sprintf("%s:signals:%s", heatKeyPrefix, roomID.Hex())
+}
+
+// formatHeatRatesKey formats the key of a room's per-minute signal rates.
+func formatHeatRatesKey(roomID bson.ObjectID) string {
+	return fmt.Sprintf("%s:rates:%s", heatKeyPrefix, roomID.Hex())
+}
@@ -0,0 +1,375 @@
+package room
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// HistoryRecorder records the plays in a room. Every play is written both to the room's recent
+// history list in Redis and to the play history collection, with the Redis entry linked to the
+// record's ID so votes, skips and recovery all find the same play.
+type HistoryRecorder struct {
+	historyRepo  repositories.HistoryRepository
+	stateManager *managers.RoomStateManager
+	logger       *utils.Logger
+
+	// activityHandlers are notified when media starts playing
+	activityHandlers []func(ctx context.Context, activity RoomActivity)
+
+	// endHandlers are notified when a play ends, with its duration and votes
+	endHandlers []func(ctx context.Context, play *models.PlayHistory)
+}
+
+// NewHistoryRecorder creates a new play history recorder.
+func NewHistoryRecorder(historyRepo repositories.HistoryRepository, stateManager *managers.RoomStateManager, logger *utils.Logger) *HistoryRecorder {
+	return &HistoryRecorder{
+		historyRepo:  historyRepo,
+		stateManager: stateManager,
+		logger:       logger.Named("history_recorder"),
+	}
+}
+
+// Start records media starting to play in a room, ending the room's previous play if it is still open.
+// The play is kept in Redis even if its record can't be created, so it can be backfilled later.
+func (h *HistoryRecorder) Start(ctx context.Context, roomID bson.ObjectID, media models.MediaInfo, dj models.PublicUser, userCount int) error {
+	if err := h.End(ctx, roomID, false, ""); err != nil {
+		h.logger.Error("Failed to end previous play", err, "roomId", roomID.Hex())
+	}
+
+	play := &models.PlayHistory{
+		ID:        bson.NewObjectID(),
+		RoomID:    roomID,
+		MediaID:   media.ID,
+		SongID:    media.SongID(),
+		DjID:      dj.ID,
+		Media:     media,
+		DJ:        dj,
+		StartTime: time.Now(),
+		Votes:     models.MediaVotes{Voters: make(map[string]string)},
+		UserCount: userCount,
+	}
+
+	stateErr := h.stateManager.AddToHistory(ctx, roomID.Hex(), historyEntry(play))
+
+	// The record is created from a copy, the Redis entry is what it is restored from
+	record := *play
+	err := h.historyRepo.CreatePlayHistory(ctx, &record)
+	if err != nil {
+		if stateErr != nil {
+			return err
+		}
+		h.logger.Error("Failed to create play history, it will be backfilled from the room history", err, "roomId", roomID.Hex(), "playId", play.ID.Hex())
+	}
+	if stateErr != nil {
+		h.logger.Error("Failed to add play to room history", stateErr, "roomId", roomID.Hex(), "playId", play.ID.Hex())
+	}
+
+	for _, handler := range h.activityHandlers {
+		handler(ctx, RoomActivity{
+			Type:     ActivityPlay,
+			RoomID:   roomID,
+			UserID:   dj.ID,
+			MediaID:  media.ID,
+			Media:    &play.Media,
+			Audience: userCount,
+			Time:     play.StartTime,
+		})
+	}
+
+	return nil
+}
+
+// AddActivityHandler adds a handler called when media starts playing in a room.
+func (h *HistoryRecorder) AddActivityHandler(handler func(ctx context.Context, activity RoomActivity)) {
+	h.activityHandlers = append(h.activityHandlers, handler)
+}
+
+// AddEndHandler adds a handler called when a play ends in a room, with its duration and votes.
+func (h *HistoryRecorder) AddEndHandler(handler func(ctx context.Context, play *models.PlayHistory)) {
+	h.endHandlers = append(h.endHandlers, handler)
+}
+
+// End records the end of the room's current play. It does nothing if the latest play already ended.
+func (h *HistoryRecorder) End(ctx context.Context, roomID bson.ObjectID, skipped bool, skipReason string) error {
+	entry, err := h.openEntry(ctx, roomID)
+	if err != nil || entry == nil {
+		return err
+	}
+
+	entry.Play.Skipped = skipped
+	entry.Play.SkipReason = skipReason
+	return h.close(ctx, roomID, entry, time.Now())
+}
+
+// Truncate records the end of the room's current play, cut off at the room's maximum track length.
+// It does nothing if the latest play already ended.
+func (h *HistoryRecorder) Truncate(ctx context.Context, roomID bson.ObjectID) error {
+	entry, err := h.openEntry(ctx, roomID)
+	if err != nil || entry == nil {
+		return err
+	}
+
+	entry.Play.Truncated = true
+	return h.close(ctx, roomID, entry, time.Now())
+}
+
+// Interrupt records the end of a room's play that a server restart cut short. The play is marked as
+// interrupted and counts as played until the restart or its expected end, whichever came first.
+func (h *HistoryRecorder) Interrupt(ctx context.Context, roomID bson.ObjectID, inFlight *models.InFlightPlay) error {
+	end := time.Now()
+	if inFlight.ExpectedEnd.Before(end) {
+		end = inFlight.ExpectedEnd
+	}
+
+	entry, err := h.openEntry(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if entry != nil && entry.Play.MediaID == inFlight.Media.ID {
+		entry.Play.Interrupted = true
+		return h.close(ctx, roomID, entry, end)
+	}
+
+	// Redis lost the play, its record is all that is left
+	play, err := h.openRecord(ctx, roomID, inFlight.Media.ID)
+	if err != nil || play == nil {
+		return err
+	}
+	play.EndTime = end
+	play.Duration = max(int(end.Sub(play.StartTime).Seconds()), 0)
+	play.Interrupted = true
+	if err := h.historyRepo.UpdatePlayHistory(ctx, play); err != nil {
+		return err
+	}
+
+	h.ended(ctx, play)
+	return nil
+}
+
+// Resume links the record of a play resumed after a server restart back to the room's recent history
+// in Redis, if Redis lost it, so the play is ended and voted on as usual.
+func (h *HistoryRecorder) Resume(ctx context.Context, roomID bson.ObjectID, inFlight *models.InFlightPlay) error {
+	entry, err := h.openEntry(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if entry != nil && entry.Play.MediaID == inFlight.Media.ID {
+		return nil
+	}
+
+	play, err := h.openRecord(ctx, roomID, inFlight.Media.ID)
+	if err != nil || play == nil {
+		return err
+	}
+	return h.stateManager.AddToHistory(ctx, roomID.Hex(), historyEntry(play))
+}
+
+// openEntry gets the room's latest play from its recent history in Redis, or nil if it already ended.
+func (h *HistoryRecorder) openEntry(ctx context.Context, roomID bson.ObjectID) (*managers.HistoryEntry, error) {
+	entries, err := h.stateManager.GetHistoryEntries(ctx, roomID.Hex(), 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 || !entries[0].EndTime.IsZero() || entries[0].Play == nil {
+		return nil, nil
+	}
+	return &entries[0], nil
+}
+
+// openRecord gets the record of the room's latest play if it is of the given media and hasn't ended.
+func (h *HistoryRecorder) openRecord(ctx context.Context, roomID, mediaID bson.ObjectID) (*models.PlayHistory, error) {
+	plays, err := h.historyRepo.FindPlayHistoryByRoom(ctx, roomID, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(plays) == 0 || plays[0].MediaID != mediaID || !plays[0].EndTime.IsZero() {
+		return nil, nil
+	}
+	return plays[0], nil
+}
+
+// close ends a play from the room's recent history at the given time, in Redis and in its record.
+func (h *HistoryRecorder) close(ctx context.Context, roomID bson.ObjectID, entry *managers.HistoryEntry, end time.Time) error {
+	play := entry.Play
+	entry.EndTime = end
+	play.EndTime = end
+	play.Duration = max(int(end.Sub(play.StartTime).Seconds()), 0)
+
+	// Keep the votes with the play, the counts in Redis don't outlive the day
+	votes, err := h.stateManager.GetVotes(ctx, roomID.Hex(), play.MediaID.Hex(), models.ReactionTypes)
+	if err != nil {
+		h.logger.Error("Failed to get play votes", err, "roomId", roomID.Hex(), "playId", play.ID.Hex())
+	} else {
+		applyVotes(&play.Votes, votes)
+	}
+
+	if err := h.stateManager.UpdateHistoryEntry(ctx, roomID.Hex(), *entry); err != nil {
+		return err
+	}
+
+	err = h.historyRepo.UpdatePlayHistory(ctx, play)
+	if errors.Is(err, models.ErrPlayHistoryNotFound) {
+		record := *play
+		err = h.historyRepo.CreatePlayHistory(ctx, &record)
+	}
+	if err != nil {
+		return err
+	}
+
+	h.ended(ctx, play)
+	return nil
+}
+
+// ended tells the end handlers about a play that ended.
+func (h *HistoryRecorder) ended(ctx context.Context, play *models.PlayHistory) {
+	for _, handler := range h.endHandlers {
+		handler(ctx, play)
+	}
+}
+
+// StartTurn records a DJ's turn starting in a room, after waiting in the queue for the given time.
+func (h *HistoryRecorder) StartTurn(ctx context.Context, roomID, djID bson.ObjectID, waited time.Duration) error {
+	return h.historyRepo.CreateDJHistory(ctx, &models.DJHistory{
+		UserID:    djID,
+		RoomID:    roomID,
+		StartTime: time.Now(),
+		WaitTime:  int(waited.Seconds()),
+	})
+}
+
+// EndTurn records the end of the room's current DJ turn. It does nothing if the latest turn already ended.
+func (h *HistoryRecorder) EndTurn(ctx context.Context, roomID bson.ObjectID, reason string) error {
+	turns, err := h.historyRepo.FindDJHistoryByRoom(ctx, roomID, 0, 1)
+	if err != nil {
+		return err
+	}
+	if len(turns) == 0 || !turns[0].EndTime.IsZero() {
+		return nil
+	}
+	return h.historyRepo.UpdateDJHistoryEndTime(ctx, turns[0].ID, time.Now(), reason)
+}
+
+// Backfill creates the play history records missing for the plays in a room's Redis history.
+// It returns the number of records created.
+func (h *HistoryRecorder) Backfill(ctx context.Context, roomID bson.ObjectID) (int, error) {
+	entries, err := h.stateManager.GetHistoryEntries(ctx, roomID.Hex(), managers.RoomHistoryMaxItems)
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, entry := range entries {
+		if entry.Play == nil {
+			continue // Recorded before plays were linked to their records
+		}
+
+		_, err := h.historyRepo.FindPlayHistoryByID(ctx, entry.Play.ID)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, models.ErrPlayHistoryNotFound) {
+			return created, err
+		}
+
+		if err := h.historyRepo.CreatePlayHistory(ctx, entry.Play); err != nil {
+			return created, err
+		}
+		created++
+	}
+
+	return created, nil
+}
+
+// BackfillRooms backfills the play history records of several rooms, logging rooms that fail.
+func (h *HistoryRecorder) BackfillRooms(ctx context.Context, roomIDs []bson.ObjectID) {
+	total := 0
+	for _, roomID := range roomIDs {
+		created, err := h.Backfill(ctx, roomID)
+		if err != nil {
+			h.logger.Error("Failed to backfill play history", err, "roomId", roomID.Hex())
+		}
+		total += created
+	}
+
+	if total > 0 {
+		h.logger.Info("Backfilled play history from room history", "rooms", len(roomIDs), "created", total)
+	}
+}
+
+// Recent gets the recent plays in a room, most recent first. Votes come from the play history
+// records, plays without a record fall back to the copy kept in Redis.
+func (h *HistoryRecorder) Recent(ctx context.Context, roomID bson.ObjectID) ([]models.PlayHistoryEntry, error) {
+	entries, err := h.stateManager.GetHistoryEntries(ctx, roomID.Hex(), managers.RoomHistoryMaxItems)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return []models.PlayHistoryEntry{}, nil
+	}
+
+	records, err := h.historyRepo.FindPlayHistoryByRoom(ctx, roomID, 0, len(entries))
+	if err != nil {
+		h.logger.Error("Failed to get play history records", err, "roomId", roomID.Hex())
+	}
+	recordsByID := make(map[bson.ObjectID]*models.PlayHistory, len(records))
+	for _, record := range records {
+		recordsByID[record.ID] = record
+	}
+
+	history := make([]models.PlayHistoryEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Play == nil {
+			continue
+		}
+
+		play := entry.Play
+		if record, ok := recordsByID[play.ID]; ok {
+			play = record
+		}
+		history = append(history, models.PlayHistoryEntry{
+			Media:     play.Media,
+			DJ:        play.DJ,
+			PlayTime:  play.StartTime,
+			Woots:     play.Votes.Woots,
+			Mehs:      play.Votes.Mehs,
+			Grabs:     play.Votes.Grabs,
+			Reactions: play.Votes.Reactions,
+		})
+	}
+
+	return history, nil
+}
+
+// applyVotes sets the vote counts of a play, keeping only the reactions it received.
+func applyVotes(playVotes *models.MediaVotes, votes map[string]int) {
+	playVotes.Woots = votes[models.VoteWoot]
+	playVotes.Mehs = votes[models.VoteMeh]
+	playVotes.Grabs = votes[models.VoteGrab]
+	for _, reaction := range models.ReactionTypes {
+		if count := votes[reaction]; count > 0 {
+			if playVotes.Reactions == nil {
+				playVotes.Reactions = make(map[string]int)
+			}
+			playVotes.Reactions[reaction] = count
+		}
+	}
+}
+
+// historyEntry creates the Redis history entry for a play.
+func historyEntry(play *models.PlayHistory) managers.HistoryEntry {
+	return managers.HistoryEntry{
+		HistoryID: play.ID.Hex(),
+		MediaID:   play.MediaID.Hex(),
+		DJID:      play.DjID.Hex(),
+		Time:      play.StartTime,
+		Duration:  play.Media.Duration,
+		Play:      play,
+	}
+}
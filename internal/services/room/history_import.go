@@ -0,0 +1,376 @@
+package room
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/services/media"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// importJobKeyPrefix is the Redis key prefix for play history import jobs.
+	importJobKeyPrefix = "history_import:"
+
+	// importJobTTL is how long an import job's report is kept.
+	importJobTTL = 7 * 24 * time.Hour
+
+	// importProgressInterval is the number of lines imported between progress updates.
+	importProgressInterval = 100
+
+	// maxImportErrorsReported caps the number of failed lines detailed in an import job's report.
+	maxImportErrorsReported = 100
+
+	// maxImportLineSize is the longest line read from an import.
+	maxImportLineSize = 1024 * 1024
+)
+
+// HistoryImportStatus is the state of a play history import job.
+type HistoryImportStatus string
+
+const (
+	HistoryImportRunning   HistoryImportStatus = "running"
+	HistoryImportCompleted HistoryImportStatus = "completed"
+	HistoryImportFailed    HistoryImportStatus = "failed"
+)
+
+// HistoryImportPolicy controls bulk play history imports.
+type HistoryImportPolicy struct {
+	// MaxAge is how long ago the oldest imported play may have started. Older plays would be removed
+	// by the play history retention right away, so they are rejected. Zero accepts plays of any age.
+	MaxAge time.Duration
+}
+
+// HistoryImportError describes a line of an import that could not be imported.
+type HistoryImportError struct {
+	Line       int    `json:"line"`
+	ExternalID string `json:"externalId,omitempty"`
+	Error      string `json:"error"`
+}
+
+// HistoryImportJob is a background play history import and its progress.
+type HistoryImportJob struct {
+	ID           string               `json:"id"`
+	RoomID       bson.ObjectID        `json:"roomId"`
+	AdminID      string               `json:"adminId"`
+	Status       HistoryImportStatus  `json:"status"`
+	Total        int                  `json:"total"`        // Plays in the import
+	Processed    int                  `json:"processed"`    // Plays handled so far
+	Created      int                  `json:"created"`      // Plays imported for the first time
+	Updated      int                  `json:"updated"`      // Plays imported before and replaced
+	Failed       int                  `json:"failed"`       // Plays that could not be imported
+	MediaCreated int                  `json:"mediaCreated"` // Media items added for the plays
+	Errors       []HistoryImportError `json:"errors,omitempty"`
+	Error        string               `json:"error,omitempty"`
+	StartedAt    time.Time            `json:"startedAt"`
+	FinishedAt   time.Time            `json:"finishedAt,omitzero"`
+}
+
+// HistoryImporter imports the play history of communities moving from other platforms, so their rooms'
+// statistics and top tracks carry over. Plays are keyed by their ID on the other platform, so an import
+// can be repeated or resumed without duplicating plays.
+type HistoryImporter struct {
+	historyRepo repositories.HistoryRepository
+	mediaRepo   repositories.MediaRepository
+	userRepo    repositories.UserRepository
+	roomRepo    repositories.RoomRepository
+	resolver    *media.Resolver
+	redisClient *redis.Client
+	policy      HistoryImportPolicy
+	logger      *utils.Logger
+}
+
+// NewHistoryImporter creates a new play history importer.
+func NewHistoryImporter(
+	historyRepo repositories.HistoryRepository,
+	mediaRepo repositories.MediaRepository,
+	userRepo repositories.UserRepository,
+	roomRepo repositories.RoomRepository,
+	resolver *media.Resolver,
+	redisClient *redis.Client,
+	policy HistoryImportPolicy,
+	logger *utils.Logger,
+) *HistoryImporter {
+	return &HistoryImporter{
+		historyRepo: historyRepo,
+		mediaRepo:   mediaRepo,
+		userRepo:    userRepo,
+		roomRepo:    roomRepo,
+		resolver:    resolver,
+		redisClient: redisClient,
+		policy:      policy,
+		logger:      logger.Named("history_importer"),
+	}
+}
+
+// StartImport starts importing plays into a room's history in the background.
+// The data holds one models.PlayImportRecord per line.
+func (s *HistoryImporter) StartImport(ctx context.Context, roomID, adminID bson.ObjectID, data []byte) (*HistoryImportJob, error) {
+	if _, err := s.roomRepo.FindByID(ctx, roomID); err != nil {
+		return nil, err
+	}
+
+	total := 0
+	for line := range bytes.Lines(data) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			total++
+		}
+	}
+	if total == 0 {
+		return nil, models.NewUserError(models.ErrInvalidImport, "The import holds no plays", http.StatusBadRequest)
+	}
+
+	job := &HistoryImportJob{
+		ID:        bson.NewObjectID().Hex(),
+		RoomID:    roomID,
+		AdminID:   adminID.Hex(),
+		Status:    HistoryImportRunning,
+		Total:     total,
+		StartedAt: time.Now(),
+	}
+	if err := s.saveJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Play history import started", "jobId", job.ID, "roomId", roomID.Hex(), "plays", total, "adminId", adminID.Hex())
+
+	// The import outlives the admin's request
+	go s.run(context.WithoutCancel(ctx), job, adminID, data)
+
+	return job, nil
+}
+
+// GetImportJob retrieves a play history import job.
+func (s *HistoryImporter) GetImportJob(ctx context.Context, jobID string) (*HistoryImportJob, error) {
+	data, err := s.redisClient.Get(ctx, importJobKeyPrefix+jobID)
+	if err != nil {
+		return nil, err
+	}
+	if data == "" {
+		return nil, models.ErrImportJobNotFound
+	}
+
+	var job HistoryImportJob
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return nil, models.NewInternalError(err, "Failed to decode history import job")
+	}
+	return &job, nil
+}
+
+// run imports the plays of an import job line by line, saving its progress along the way.
+func (s *HistoryImporter) run(ctx context.Context, job *HistoryImportJob, adminID bson.ObjectID, data []byte) {
+	batch := &importBatch{
+		adminID: adminID,
+		media:   make(map[string]*models.Media),
+		djs:     make(map[string]*models.PublicUser),
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), maxImportLineSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		var record models.PlayImportRecord
+		err := json.Unmarshal(raw, &record)
+		if err == nil {
+			err = s.importPlay(ctx, job, batch, &record)
+		}
+		job.Processed++
+		if err != nil {
+			job.Failed++
+			if len(job.Errors) < maxImportErrorsReported {
+				job.Errors = append(job.Errors, HistoryImportError{Line: line, ExternalID: record.ExternalID, Error: err.Error()})
+			}
+		}
+
+		if job.Processed%importProgressInterval == 0 {
+			if err := s.saveJob(ctx, job); err != nil {
+				s.logger.Error("Failed to save history import progress", err, "jobId", job.ID)
+			}
+		}
+	}
+
+	job.FinishedAt = time.Now()
+	job.Status = HistoryImportCompleted
+	if err := scanner.Err(); err != nil {
+		job.Status = HistoryImportFailed
+		job.Error = fmt.Sprintf("Failed to read line %d: %v", line+1, err)
+		s.logger.Error("Play history import failed", err, "jobId", job.ID)
+	} else {
+		s.logger.Info("Play history import completed", "jobId", job.ID, "created", job.Created, "updated", job.Updated, "failed", job.Failed)
+	}
+
+	if err := s.saveJob(ctx, job); err != nil {
+		s.logger.Error("Failed to save history import job", err, "jobId", job.ID)
+	}
+}
+
+// importBatch holds what an import job looked up, so repeated media and DJs are looked up once.
+type importBatch struct {
+	adminID bson.ObjectID
+	media   map[string]*models.Media
+	djs     map[string]*models.PublicUser
+}
+
+// importPlay validates a play and creates or replaces it in the room's history.
+// The media's statistics only count plays imported for the first time.
+func (s *HistoryImporter) importPlay(ctx context.Context, job *HistoryImportJob, batch *importBatch, record *models.PlayImportRecord) error {
+	if err := utils.Validate(record); err != nil {
+		return err
+	}
+	if record.PlayedAt.IsZero() {
+		return errors.New("playedAt is required")
+	}
+	if record.PlayedAt.After(time.Now()) {
+		return errors.New("playedAt is in the future")
+	}
+	if s.policy.MaxAge > 0 && time.Since(record.PlayedAt) > s.policy.MaxAge {
+		return errors.New("playedAt is older than the play history retention")
+	}
+	if !record.EndedAt.IsZero() && record.EndedAt.Before(record.PlayedAt) {
+		return errors.New("endedAt is before playedAt")
+	}
+
+	item, err := s.resolveMedia(ctx, job, batch, record)
+	if err != nil {
+		return fmt.Errorf("media could not be resolved: %w", err)
+	}
+
+	dj, err := s.resolveDJ(ctx, batch, record.DJUsername)
+	if err != nil {
+		return err
+	}
+
+	endTime := record.EndedAt
+	if endTime.IsZero() {
+		endTime = record.PlayedAt.Add(time.Duration(item.Duration) * time.Second)
+	}
+	play := &models.PlayHistory{
+		RoomID:    job.RoomID,
+		MediaID:   item.ID,
+		SongID:    item.SongID(),
+		DjID:      dj.ID,
+		Media:     *item.ToMediaInfo(nil),
+		DJ:        *dj,
+		StartTime: record.PlayedAt,
+		EndTime:   endTime,
+		Duration:  int(endTime.Sub(record.PlayedAt).Seconds()),
+		Skipped:   record.Skipped,
+		Votes: models.MediaVotes{
+			Woots:  record.Woots,
+			Mehs:   record.Mehs,
+			Grabs:  record.Grabs,
+			Voters: make(map[string]string),
+		},
+		UserCount:  record.UserCount,
+		ExternalID: record.ExternalID,
+	}
+
+	created, err := s.historyRepo.UpsertImportedPlay(ctx, play)
+	if err != nil {
+		return err
+	}
+	if !created {
+		job.Updated++
+		return nil
+	}
+	job.Created++
+
+	stats := bson.M{
+		"playCount": 1,
+		"wootCount": record.Woots,
+		"mehCount":  record.Mehs,
+		"grabCount": record.Grabs,
+	}
+	if record.Skipped {
+		stats["skipCount"] = 1
+	}
+	if err := s.mediaRepo.UpdateStats(ctx, item.ID, stats); err != nil {
+		s.logger.Error("Failed to update media stats for imported play", err, "mediaId", item.ID.Hex(), "jobId", job.ID)
+		// Continue anyway, the play was imported
+	}
+	return nil
+}
+
+// resolveMedia finds the media of a play, adding it if it isn't known yet. Plays that describe their media
+// are added as described, the others are looked up on the media's provider.
+func (s *HistoryImporter) resolveMedia(ctx context.Context, job *HistoryImportJob, batch *importBatch, record *models.PlayImportRecord) (*models.Media, error) {
+	key := record.Source + ":" + record.SourceID
+	if item, ok := batch.media[key]; ok {
+		return item, nil
+	}
+
+	item, err := s.mediaRepo.FindBySourceID(ctx, record.Source, record.SourceID)
+	switch {
+	case err == nil:
+	case !errors.Is(err, models.ErrMediaNotFound):
+		return nil, err
+	case record.Title != "" && record.Duration > 0:
+		var created bool
+		item, created, err = s.resolver.ImportMedia(ctx, &models.Media{
+			Type:     record.Source,
+			SourceID: record.SourceID,
+			Title:    record.Title,
+			Artist:   record.Artist,
+			Duration: record.Duration,
+			AddedBy:  batch.adminID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			job.MediaCreated++
+		}
+	default:
+		if item, err = s.resolver.Resolve(ctx, record.Source, record.SourceID, batch.adminID); err != nil {
+			return nil, err
+		}
+		job.MediaCreated++
+	}
+
+	batch.media[key] = item
+	return item, nil
+}
+
+// resolveDJ finds the account of a play's DJ by username. DJs without an account keep just their name.
+func (s *HistoryImporter) resolveDJ(ctx context.Context, batch *importBatch, username string) (*models.PublicUser, error) {
+	if dj, ok := batch.djs[username]; ok {
+		return dj, nil
+	}
+
+	dj := &models.PublicUser{BaseUser: models.BaseUser{Username: username}}
+	if username != "" {
+		user, err := s.userRepo.FindByUsername(ctx, username)
+		switch {
+		case err == nil:
+			public := user.ToPublicUser()
+			public.Online = false
+			dj = &public
+		case !errors.Is(err, models.ErrUserNotFound):
+			return nil, err
+		}
+	}
+
+	batch.djs[username] = dj
+	return dj, nil
+}
+
+// saveJob stores an import job's progress.
+func (s *HistoryImporter) saveJob(ctx context.Context, job *HistoryImportJob) error {
+	return s.redisClient.SetObject(ctx, importJobKeyPrefix+job.ID, job, importJobTTL)
+}
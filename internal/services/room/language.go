@@ -0,0 +1,386 @@
+package room
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// languageKeyPrefix prefixes the keys of the chat language counts and scores of rooms.
+	languageKeyPrefix = "language"
+
+	// languageRoomsKey is the set of rooms whose chat language is tracked.
+	languageRoomsKey = languageKeyPrefix + ":rooms"
+
+	// languageLockKey claims the detection of the language of all rooms, so one instance does it at a time.
+	languageLockKey = languageKeyPrefix + ":lock"
+
+	// languageWindow is the time over which chat messages are weighed. Older messages fade out over about this long,
+	// so a room's language follows its community as it changes.
+	languageWindow = 24 * time.Hour
+
+	// languageMinShare is the share of recent messages a language needs to be a room's language.
+	languageMinShare = 0.5
+
+	// minLanguageScore is the score below which a language counts as gone from a room's chat.
+	minLanguageScore = 0.5
+
+	// minLanguageHits is the number of common words a message needs to be told apart as a Latin-script language.
+	minLanguageHits = 2
+)
+
+// scriptLanguages are the languages told apart by their writing system alone.
+var scriptLanguages = []struct {
+	code  string
+	table *unicode.RangeTable
+}{
+	{"ko", unicode.Hangul},
+	{"ja", unicode.Hiragana},
+	{"ja", unicode.Katakana},
+	{"zh", unicode.Han},
+	{"ar", unicode.Arabic},
+	{"he", unicode.Hebrew},
+	{"el", unicode.Greek},
+	{"th", unicode.Thai},
+	{"hi", unicode.Devanagari},
+	{"ru", unicode.Cyrillic},
+}
+
+// languageWords are common words of the languages written in the Latin script, which tell them apart.
+var languageWords = map[string][]string{
+	"en": {"the", "and", "is", "are", "you", "this", "that", "what", "with", "have", "it's", "i'm", "was", "for", "not", "song", "love", "just", "like", "good"},
+	"es": {"el", "los", "las", "que", "y", "es", "una", "por", "pero", "muy", "está", "esta", "canción", "qué", "bueno", "gracias", "hola", "también", "como", "para"},
+	"pt": {"o", "os", "que", "é", "não", "uma", "com", "muito", "você", "isso", "está", "música", "obrigado", "olá", "também", "mas", "boa", "meu", "para", "eu"},
+	"fr": {"le", "les", "et", "est", "je", "tu", "une", "pas", "c'est", "très", "merci", "bonjour", "avec", "mais", "pour", "qui", "oui", "chanson", "vous", "nous"},
+	"de": {"der", "die", "das", "und", "ist", "ich", "nicht", "ein", "eine", "mit", "auch", "sehr", "danke", "hallo", "gut", "aber", "du", "wir", "lied", "schön"},
+	"it": {"il", "gli", "che", "è", "non", "una", "per", "sono", "molto", "grazie", "ciao", "anche", "questa", "questo", "canzone", "bello", "bella", "ma", "io", "della"},
+	"nl": {"de", "het", "een", "en", "is", "niet", "ik", "je", "dat", "met", "ook", "heel", "bedankt", "hallo", "goed", "maar", "wij", "nummer", "mooi", "van"},
+	"pl": {"i", "jest", "nie", "się", "to", "na", "że", "bardzo", "dzięki", "cześć", "jak", "ale", "tak", "fajne", "piosenka", "co", "mam", "dobre", "też", "czy"},
+	"tr": {"ve", "bir", "bu", "çok", "ne", "değil", "için", "ama", "teşekkürler", "merhaba", "güzel", "şarkı", "ben", "sen", "evet", "hayır", "da", "de", "mi", "iyi"},
+	"sv": {"och", "är", "jag", "inte", "det", "en", "ett", "med", "också", "mycket", "tack", "hej", "bra", "men", "vi", "låt", "som", "har", "du", "fin"},
+	"id": {"dan", "yang", "ini", "itu", "tidak", "aku", "kamu", "dengan", "juga", "sangat", "terima", "kasih", "halo", "bagus", "tapi", "lagu", "saya", "ada", "untuk", "apa"},
+}
+
+// languageLookup maps the common words of Latin-script languages to the languages using them.
+var languageLookup = func() map[string][]string {
+	lookup := make(map[string][]string)
+	for code, words := range languageWords {
+		for _, word := range words {
+			lookup[word] = append(lookup[word], code)
+		}
+	}
+	return lookup
+}()
+
+// LanguagePolicy controls the detection of the chat language of rooms.
+type LanguagePolicy struct {
+	// Interval is how often the language of rooms is detected. Zero disables language detection.
+	Interval time.Duration
+
+	// MinMessages is the weight of recent messages a room needs before its language is detected,
+	// so a few messages can't decide it.
+	MinMessages int
+}
+
+// LanguageService detects the dominant language of each room's chat over time and stores it on the room,
+// so users can find rooms in their language. Messages are counted per language in Redis and folded into
+// decaying scores every interval. Rooms whose owner set their language are left alone.
+type LanguageService struct {
+	roomRepo    repositories.RoomRepository
+	redisClient *redis.Client
+	policy      LanguagePolicy
+	logger      *utils.Logger
+}
+
+// NewLanguageService creates a new language service.
+func NewLanguageService(
+	roomRepo repositories.RoomRepository,
+	redisClient *redis.Client,
+	policy LanguagePolicy,
+	logger *utils.Logger,
+) *LanguageService {
+	return &LanguageService{
+		roomRepo:    roomRepo,
+		redisClient: redisClient,
+		policy:      policy,
+		logger:      logger.Named("language_service"),
+	}
+}
+
+// RecordMessage counts a chat message towards its room's language, if its language can be told.
+// It is meant to be added as a chat message handler.
+func (s *LanguageService) RecordMessage(ctx context.Context, message models.ChatMessage) {
+	if s.policy.Interval <= 0 || (message.Type != "text" && message.Type != "emote") {
+		return
+	}
+
+	code, ok := detectLanguage(message.Content)
+	if !ok {
+		return
+	}
+
+	pipe := s.redisClient.Pipeline()
+	pipe.HIncrBy(ctx, formatLanguageCountsKey(message.RoomID), code, 1)
+	pipe.SAdd(ctx, languageRoomsKey, message.RoomID.Hex())
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.Error("Failed to count chat language", err, "roomId", message.RoomID.Hex(), "language", code)
+	}
+}
+
+// Start begins detecting the language of rooms.
+func (s *LanguageService) Start(ctx context.Context) {
+	if s.policy.Interval <= 0 {
+		s.logger.Info("Room language detection is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.policy.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				s.logger.Info("Stopping language service")
+				return
+			case <-ticker.C:
+				if err := s.Detect(ctx); err != nil {
+					s.logger.Error("Failed to detect room languages", err)
+				}
+			}
+		}
+	}()
+
+	s.logger.Info("Language service started", "interval", s.policy.Interval)
+}
+
+// Detect folds the messages counted since the last run into each tracked room's language scores and
+// updates the language of the rooms where one language is dominant. Rooms whose chat went quiet are no
+// longer tracked, but keep their language.
+func (s *LanguageService) Detect(ctx context.Context) error {
+	claimed, err := s.redisClient.Client().SetNX(ctx, languageLockKey, "1", s.policy.Interval/2).Result()
+	if err != nil || !claimed {
+		return err
+	}
+
+	roomIDs, err := s.redisClient.SMembers(ctx, languageRoomsKey)
+	if err != nil {
+		return err
+	}
+
+	// Weight kept by the scores from one run to the next
+	decay := math.Exp(-s.policy.Interval.Seconds() / languageWindow.Seconds())
+
+	languages := make(map[bson.ObjectID]models.RoomLanguage, len(roomIDs))
+	for _, id := range roomIDs {
+		roomID, err := bson.ObjectIDFromHex(id)
+		if err != nil {
+			s.redisClient.SRem(ctx, languageRoomsKey, id)
+			continue
+		}
+
+		scores, err := s.detectRoom(ctx, roomID, decay)
+		if err != nil {
+			s.logger.Error("Failed to detect room language", err, "roomId", id)
+			continue
+		}
+		if len(scores) == 0 {
+			s.redisClient.SRem(ctx, languageRoomsKey, id)
+			continue
+		}
+		if language, ok := dominantLanguage(scores, s.policy.MinMessages); ok {
+			languages[roomID] = language
+		}
+	}
+
+	return s.roomRepo.UpdateDetectedLanguages(ctx, languages)
+}
+
+// detectRoom updates a room's language scores and returns them. No scores are left once the room's chat went quiet.
+func (s *LanguageService) detectRoom(ctx context.Context, roomID bson.ObjectID, decay float64) (map[string]float64, error) {
+	countsKey := formatLanguageCountsKey(roomID)
+	scoresKey := formatLanguageScoresKey(roomID)
+
+	// Take the counted messages, new ones start counting towards the next run
+	pipe := s.redisClient.TxPipeline()
+	countsCmd := pipe.HGetAll(ctx, countsKey)
+	pipe.Del(ctx, countsKey)
+	scoresCmd := pipe.HGetAll(ctx, scoresKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	scores := make(map[string]float64)
+	for code, value := range scoresCmd.Val() {
+		score, _ := strconv.ParseFloat(value, 64)
+		scores[code] = score * decay
+	}
+	for code, value := range countsCmd.Val() {
+		count, _ := strconv.ParseFloat(value, 64)
+		scores[code] += count
+	}
+
+	fields := make(map[string]any, len(scores))
+	for code, score := range scores {
+		if score < minLanguageScore {
+			delete(scores, code)
+			continue
+		}
+		fields[code] = strconv.FormatFloat(score, 'f', 4, 64)
+	}
+
+	pipe = s.redisClient.Pipeline()
+	pipe.Del(ctx, scoresKey)
+	if len(fields) > 0 {
+		pipe.HSet(ctx, scoresKey, fields)
+		pipe.Expire(ctx, scoresKey, 2*languageWindow)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return scores, nil
+}
+
+// dominantLanguage picks the language of a room from its language scores, if one has enough of its messages.
+func dominantLanguage(scores map[string]float64, minMessages int) (models.RoomLanguage, bool) {
+	var best string
+	var total float64
+	for code, score := range scores {
+		total += score
+		if best == "" || score > scores[best] || (score == scores[best] && code < best) {
+			best = code
+		}
+	}
+
+	if total < float64(minMessages) || scores[best]/total < languageMinShare {
+		return models.RoomLanguage{}, false
+	}
+
+	return models.RoomLanguage{
+		Code:      best,
+		Share:     math.Round(scores[best]/total*100) / 100,
+		UpdatedAt: time.Now(),
+	}, true
+}
+
+// detectLanguage tells the language of a chat message, by its writing system or by the common words it uses.
+// Messages too short or too mixed to tell are left undetected.
+func detectLanguage(text string) (string, bool) {
+	letters := 0
+	scripts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, script := range scriptLanguages {
+			if unicode.Is(script.table, r) {
+				scripts[script.code]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return "", false
+	}
+
+	// Japanese mixes kanji with kana, any kana tells it apart from Chinese
+	if scripts["ja"] > 0 {
+		scripts["ja"] += scripts["zh"]
+		delete(scripts, "zh")
+	}
+	for code, count := range scripts {
+		if count*2 > letters {
+			return code, true
+		}
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	hits := make(map[string]int)
+	for _, word := range words {
+		for _, code := range languageLookup[word] {
+			hits[code]++
+		}
+	}
+
+	// The language using the most of the words wins, a tie leaves the message undetected
+	best, tied := "", false
+	for code, count := range hits {
+		switch {
+		case count > hits[best]:
+			best, tied = code, false
+		case count == hits[best]:
+			tied = true
+		}
+	}
+	if best == "" || tied || hits[best] < minLanguageHits {
+		return "", false
+	}
+	return best, true
+}
+
+// validLanguageCode checks that a code looks like an ISO 639-1 language code.
+func validLanguageCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// SetLanguage sets the language of a room, overriding the language detected from its chat. An empty code
+// goes back to detecting it. Only the room's owner can set it.
+func (m *Manager) SetLanguage(ctx context.Context, roomID, userID bson.ObjectID, code string) (*models.Room, error) {
+	room, err := m.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if roomRole(room, userID) != roleOwner {
+		return nil, ErrNotAuthorized
+	}
+
+	code = strings.ToLower(strings.TrimSpace(code))
+	var language *models.RoomLanguage
+	if code != "" {
+		if !validLanguageCode(code) {
+			return nil, models.ErrInvalidLanguage
+		}
+		language = &models.RoomLanguage{Code: code, Manual: true, UpdatedAt: time.Now()}
+	}
+
+	if err := m.roomRepo.SetLanguage(ctx, roomID, language); err != nil {
+		return nil, err
+	}
+	m.lobby.Invalidate(ctx)
+
+	room.Language = language
+	return room, nil
+}
+
+// formatLanguageCountsKey formats the key of the messages counted per language for a room since the last run.
+func formatLanguageCountsKey(roomID bson.ObjectID) string {
+	return fmt.Sprintf("%s:counts:%s", languageKeyPrefix, roomID.Hex())
+}
+
+// formatLanguageScoresKey formats the key of a room's decaying language scores.
+func formatLanguageScoresKey(roomID bson.ObjectID) string {
+	return fmt.Sprintf("%s:scores:%s", languageKeyPrefix, roomID.Hex())
+}
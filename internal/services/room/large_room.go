@@ -0,0 +1,170 @@
+package room
+
+import (
+	"bytes"
+	"context"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+// LargeRoomPolicy controls how state is broadcast for rooms above a configurable size.
+type LargeRoomPolicy struct {
+	// Threshold is the number of users above which a room's state is summarized. Zero disables summarizing.
+	Threshold int
+
+	// RosterSize is the number of users listed in a summarized state.
+	RosterSize int
+}
+
+// IsLarge checks whether a room with the given number of users gets summarized state.
+func (p LargeRoomPolicy) IsLarge(users int) bool {
+	return p.Threshold > 0 && users > p.Threshold
+}
+
+// fillRoster lists the room's users on its state, keeping only the top of the roster for large rooms.
+func (m *Manager) fillRoster(ctx context.Context, state *models.RoomState) {
+	state.StateMode = models.RoomStateModeFull
+	limit := 0
+	if m.largeRooms.IsLarge(state.ActiveUsers) {
+		state.StateMode = models.RoomStateModeSummary
+		limit = m.largeRooms.RosterSize
+	}
+
+	ids, err := m.stateManager.GetRoomUsers(ctx, state.ID.Hex())
+	if err != nil {
+		m.logger.Error("Failed to get room users for roster", err, "roomId", state.ID.Hex())
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	room, err := m.GetRoom(ctx, state.ID)
+	if err != nil {
+		m.logger.Error("Failed to get room for roster", err, "roomId", state.ID.Hex())
+		return
+	}
+
+	userIDs := rosterOrder(room, ids)
+	if limit > 0 && len(userIDs) > limit {
+		userIDs = userIDs[:limit]
+	}
+
+	users, err := m.rosterUsers(ctx, userIDs)
+	if err != nil {
+		m.logger.Error("Failed to get roster users", err, "roomId", state.ID.Hex())
+		return
+	}
+	state.Users = users
+}
+
+// GetJoinState gets the state of a room sent to a user joining it. With progressive set, large rooms
+// only get their core state, without the roster, so the joining client can start playing right away
+// and fetch the rest of the room in pages.
+func (m *Manager) GetJoinState(ctx context.Context, roomID bson.ObjectID, progressive bool) (*models.RoomState, error) {
+	state, err := m.loadRoomState(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	if progressive && m.largeRooms.IsLarge(state.ActiveUsers) {
+		state.StateMode = models.RoomStateModeProgressive
+	} else {
+		m.fillRoster(ctx, state)
+	}
+	m.fillExpiry(ctx, state)
+	return state, nil
+}
+
+// GetRosterPage gets a page of a room's users in roster order, along with the number of users in the room.
+func (m *Manager) GetRosterPage(ctx context.Context, roomID bson.ObjectID, offset, limit int) ([]models.PublicUser, int, error) {
+	ids, err := m.stateManager.GetRoomUsers(ctx, roomID.Hex())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	room, err := m.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	userIDs := rosterOrder(room, ids)
+	total := len(userIDs)
+	if offset >= total {
+		return []models.PublicUser{}, total, nil
+	}
+	userIDs = userIDs[offset:min(offset+limit, total)]
+
+	users, err := m.rosterUsers(ctx, userIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// rosterUsers gets the public profiles of users, in the order given, marking the online ones.
+func (m *Manager) rosterUsers(ctx context.Context, userIDs []bson.ObjectID) ([]models.PublicUser, error) {
+	users, err := m.userRepo.FindMany(ctx, bson.M{"_id": bson.M{"$in": userIDs}}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// One presence lookup for the whole roster, users without presence show as offline
+	presences, err := m.presenceManager.GetPresenceBulk(ctx, userIDs)
+	if err != nil {
+		m.logger.Error("Failed to get roster presence", err, "count", len(userIDs))
+	}
+
+	// Keep the roster order, the query returns users in storage order
+	byID := make(map[bson.ObjectID]*models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	roster := make([]models.PublicUser, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if user, ok := byID[userID]; ok {
+			publicUser := user.ToPublicUser()
+			if presences != nil {
+				publicUser.Online = presences[userID] != nil
+			}
+			roster = append(roster, publicUser)
+		}
+	}
+	return roster, nil
+}
+
+// rosterOrder ranks the users in a room: the owner, co-hosts and current DJ first, then everyone else
+// by role and ID.
+func rosterOrder(room *models.Room, ids []string) []bson.ObjectID {
+	rank := func(userID bson.ObjectID) int {
+		role := roomRole(room, userID)
+		switch {
+		case isStaffRole(role):
+			return roleRanks[roleOwner] - roleRanks[role]
+		case userID == room.CurrentDJ:
+			return 2
+		default:
+			return 2 + roleRanks[models.RoomRoleCoHost] - roleRanks[role]
+		}
+	}
+
+	userIDs := make([]bson.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		userID, err := bson.ObjectIDFromHex(id)
+		if err != nil {
+			continue
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	slices.SortFunc(userIDs, func(a, b bson.ObjectID) int {
+		if ra, rb := rank(a), rank(b); ra != rb {
+			return ra - rb
+		}
+		return bytes.Compare(a[:], b[:])
+	})
+
+	return userIDs
+}
@@ -0,0 +1,169 @@
+package room
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// lobbyCacheKeyPrefix is the cache key prefix for lobby listings.
+	lobbyCacheKeyPrefix = "lobby:"
+
+	// lobbyInvalidatedKey holds when the lobby listings were last invalidated.
+	lobbyInvalidatedKey = "lobby:invalidated"
+
+	// lobbyRefreshTimeout bounds a background refresh of a stale listing.
+	lobbyRefreshTimeout = 10 * time.Second
+)
+
+// LobbyCachePolicy controls how long lobby listings are served from the cache.
+type LobbyCachePolicy struct {
+	// FreshFor is how long a cached listing is served without being refreshed. Zero disables the cache.
+	FreshFor time.Duration
+
+	// StaleFor is how long past FreshFor a listing is still served while it is refreshed in the background.
+	StaleFor time.Duration
+}
+
+// lobbyListing is a cached lobby listing.
+type lobbyListing struct {
+	Rooms    []*models.Room `json:"rooms"`
+	Total    int64          `json:"total"`
+	CachedAt time.Time      `json:"cachedAt"`
+}
+
+// LobbyCache caches the room listings shown in the lobby in Redis, so lobby refreshes don't query
+// the database for every user. Stale listings are served while they are refreshed in the background,
+// and room activity marks every listing stale instead of dropping it.
+type LobbyCache struct {
+	redisClient *redis.Client
+	policy      LobbyCachePolicy
+	logger      *utils.Logger
+
+	// refreshing holds the keys being refreshed on this node, so each is refreshed once at a time
+	refreshing map[string]bool
+	mutex      sync.Mutex
+}
+
+// NewLobbyCache creates a new lobby cache.
+func NewLobbyCache(redisClient *redis.Client, policy LobbyCachePolicy, logger *utils.Logger) *LobbyCache {
+	return &LobbyCache{
+		redisClient: redisClient,
+		policy:      policy,
+		logger:      logger.Named("lobby_cache"),
+		refreshing:  make(map[string]bool),
+	}
+}
+
+// Invalidate marks every cached listing stale. Listings keep being served until they are refreshed.
+func (c *LobbyCache) Invalidate(ctx context.Context) {
+	if c.policy.FreshFor <= 0 {
+		return
+	}
+
+	now := strconv.FormatInt(time.Now().UnixNano(), 10)
+	if err := c.redisClient.Set(ctx, lobbyInvalidatedKey, now, c.policy.FreshFor+c.policy.StaleFor); err != nil {
+		c.logger.Warn("Failed to invalidate lobby cache", "error", err)
+	}
+}
+
+// get serves a listing from the cache, loading it on a miss and refreshing it in the background once stale.
+func (c *LobbyCache) get(ctx context.Context, key string, load func(ctx context.Context) ([]*models.Room, int64, error)) ([]*models.Room, int64, error) {
+	if c.policy.FreshFor <= 0 {
+		return load(ctx)
+	}
+
+	key = lobbyCacheKeyPrefix + key
+	var listing lobbyListing
+	if err := c.redisClient.GetObject(ctx, key, &listing); err == nil {
+		if c.isStale(ctx, listing) {
+			c.refresh(ctx, key, load)
+		}
+		return listing.Rooms, listing.Total, nil
+	}
+
+	rooms, total, err := load(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	c.store(ctx, key, rooms, total)
+	return rooms, total, nil
+}
+
+// isStale checks whether a cached listing is past its fresh period or older than the last invalidation.
+func (c *LobbyCache) isStale(ctx context.Context, listing lobbyListing) bool {
+	if time.Since(listing.CachedAt) > c.policy.FreshFor {
+		return true
+	}
+
+	invalidated, err := c.redisClient.Get(ctx, lobbyInvalidatedKey)
+	if err != nil || invalidated == "" {
+		return false
+	}
+	nanos, err := strconv.ParseInt(invalidated, 10, 64)
+	return err == nil && listing.CachedAt.Before(time.Unix(0, nanos))
+}
+
+// refresh reloads a stale listing in the background, unless it is already being refreshed on this node.
+func (c *LobbyCache) refresh(ctx context.Context, key string, load func(ctx context.Context) ([]*models.Room, int64, error)) {
+	c.mutex.Lock()
+	if c.refreshing[key] {
+		c.mutex.Unlock()
+		return
+	}
+	c.refreshing[key] = true
+	c.mutex.Unlock()
+
+	go func() {
+		defer func() {
+			c.mutex.Lock()
+			delete(c.refreshing, key)
+			c.mutex.Unlock()
+		}()
+
+		// The refresh outlives the request that noticed the listing was stale
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lobbyRefreshTimeout)
+		defer cancel()
+
+		rooms, total, err := load(ctx)
+		if err != nil {
+			c.logger.Error("Failed to refresh lobby listing", err, "key", key)
+			return
+		}
+		c.store(ctx, key, rooms, total)
+	}()
+}
+
+// store caches a listing until it is too stale to be served.
+func (c *LobbyCache) store(ctx context.Context, key string, rooms []*models.Room, total int64) {
+	listing := lobbyListing{
+		Rooms:    rooms,
+		Total:    total,
+		CachedAt: time.Now(),
+	}
+	if err := c.redisClient.SetObject(ctx, key, listing, c.policy.FreshFor+c.policy.StaleFor); err != nil {
+		c.logger.Warn("Failed to cache lobby listing", "key", key, "error", err)
+	}
+}
+
+// searchKey returns the cache key of a room search.
+func searchKey(criteria models.RoomSearchCriteria) string {
+	data, _ := json.Marshal(criteria) // Plain struct, never fails
+	sum := sha1.Sum(data)
+	return "search:" + hex.EncodeToString(sum[:])
+}
+
+// limitKey returns the cache key of a listing limited to a number of rooms.
+func limitKey(listing string, limit int) string {
+	return fmt.Sprintf("%s:%d", listing, limit)
+}
@@ -3,6 +3,7 @@ package room
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"sync"
 	"time"
@@ -27,18 +28,57 @@ type RoomManager interface {
 
 	// Room state operations
 	GetRoomState(ctx context.Context, roomID bson.ObjectID) (*models.RoomState, error)
+	GetJoinState(ctx context.Context, roomID bson.ObjectID, progressive bool) (*models.RoomState, error)
+	GetRejoinState(ctx context.Context, roomID bson.ObjectID, progressive bool) (*models.RoomState, error)
 	UpdateRoomState(ctx context.Context, roomID bson.ObjectID, state *models.RoomState) error
 
+	// Tracks in flight, recovered after a server restart
+	RecordInFlightPlay(ctx context.Context, roomID bson.ObjectID, state *models.RoomState) error
+	InFlightPlays(ctx context.Context) ([]*models.Room, error)
+	TakeInFlightPlay(ctx context.Context, roomID, playID bson.ObjectID) (bool, error)
+
 	// Room user operations
 	JoinRoom(ctx context.Context, roomID, userID bson.ObjectID) error
 	LeaveRoom(ctx context.Context, roomID, userID bson.ObjectID) error
 	IsUserInRoom(ctx context.Context, roomID, userID bson.ObjectID) (bool, error)
+	IsListenerOnly(ctx context.Context, roomID, userID bson.ObjectID) (bool, error)
 	GetRoomUsers(ctx context.Context, roomID bson.ObjectID) ([]models.PublicUser, error)
+	GetRosterPage(ctx context.Context, roomID bson.ObjectID, offset, limit int) ([]models.PublicUser, int, error)
+	CanJoin(ctx context.Context, roomID, userID bson.ObjectID) (*models.JoinAvailability, error)
+
+	// Voting and reacting on the current media
+	Vote(ctx context.Context, roomID, userID bson.ObjectID, voteType string) (map[string]int, error)
+	React(ctx context.Context, roomID, userID bson.ObjectID, reaction string) (map[string]int, error)
 
 	// Room search and discovery
 	SearchRooms(ctx context.Context, criteria models.RoomSearchCriteria) ([]*models.Room, int64, error)
 	GetActiveRooms(ctx context.Context, limit int) ([]*models.Room, error)
 	GetPopularRooms(ctx context.Context, limit int) ([]*models.Room, error)
+	VisibleRooms(ctx context.Context, viewerID string, rooms []*models.Room) []*models.Room
+	SetLanguage(ctx context.Context, roomID, userID bson.ObjectID, code string) (*models.Room, error)
+
+	// Room roles and their permissions
+	SetRole(ctx context.Context, roomID, actorID, targetID bson.ObjectID, role string) (*models.Room, error)
+	GetRoles(ctx context.Context, roomID bson.ObjectID) (*models.RoomRoles, error)
+	SetRolePermissions(ctx context.Context, roomID, userID bson.ObjectID, permissions map[string][]string) (*models.Room, error)
+	SetQueueLocked(ctx context.Context, roomID, userID bson.ObjectID, locked bool) (*models.Room, error)
+	SetQueueOptions(ctx context.Context, roomID, userID bson.ObjectID, options models.QueueOptions) (*models.Room, error)
+
+	// How users' names show in a room's chat
+	SetChatAppearance(ctx context.Context, roomID, userID bson.ObjectID, appearance models.ChatAppearance) (*models.ChatAppearance, error)
+	ResetChatAppearance(ctx context.Context, roomID, actorID, targetID bson.ObjectID) error
+
+	// Overflow chat shards of rooms past their chat threshold
+	ChatShardOf(ctx context.Context, roomID, userID bson.ObjectID) int
+}
+
+// TrustPolicy checks whether a user's trust level unlocks a gated ability.
+type TrustPolicy interface {
+	// CheckAbility returns models.ErrTrustLevelTooLow if the user has not unlocked the ability.
+	CheckAbility(ctx context.Context, userID string, ability models.TrustAbility) error
+
+	// IsLongTrack checks whether a track of the given duration in seconds counts as long.
+	IsLongTrack(duration int) bool
 }
 
 // Manager implements the RoomManager interface.
@@ -46,9 +86,32 @@ type Manager struct {
 	roomRepo        repositories.RoomRepository
 	userRepo        repositories.UserRepository
 	stateManager    managers.RoomStateManager
+	stateReader     managers.RoomStateReader
 	presenceManager managers.PresenceManager
+	trustPolicy     TrustPolicy
+	largeRooms      LargeRoomPolicy
+	popups          PopupPolicy
+	minors          MinorPolicy
+	lobby           *LobbyCache
+	admission       *AdmissionController
+	rejoinStates    *rejoinStates
 	logger          *utils.Logger
 	mutex           sync.RWMutex
+
+	// promotionHandlers are notified when an overflow listener becomes a full participant
+	promotionHandlers []func(ctx context.Context, roomID, userID bson.ObjectID)
+
+	// settingsHandlers are notified when a room's settings change
+	settingsHandlers []func(ctx context.Context, change RoomSettingsChange)
+
+	// activityHandlers are notified when users join, leave or vote in a room
+	activityHandlers []func(ctx context.Context, activity RoomActivity)
+
+	// skipVoteHandlers are notified when the mehs on a room's current media pass its skip threshold
+	skipVoteHandlers []func(ctx context.Context, vote SkipVote)
+
+	// createdHandlers are notified when a room is created
+	createdHandlers []func(ctx context.Context, room *models.Room)
 }
 
 // NewManager creates a new room manager.
@@ -56,25 +119,64 @@ func NewManager(
 	roomRepo repositories.RoomRepository,
 	userRepo repositories.UserRepository,
 	stateManager managers.RoomStateManager,
+	stateReader managers.RoomStateReader,
 	presenceManager managers.PresenceManager,
+	trustPolicy TrustPolicy,
+	largeRooms LargeRoomPolicy,
+	popups PopupPolicy,
+	minors MinorPolicy,
+	lobby *LobbyCache,
 	logger *utils.Logger,
 ) *Manager {
 	return &Manager{
 		roomRepo:        roomRepo,
 		userRepo:        userRepo,
 		stateManager:    stateManager,
+		stateReader:     stateReader,
 		presenceManager: presenceManager,
+		trustPolicy:     trustPolicy,
+		largeRooms:      largeRooms,
+		popups:          popups,
+		minors:          minors,
+		lobby:           lobby,
 		logger:          logger,
 	}
 }
 
+// SetAdmissionController makes joins follow the load-aware effective capacity of rooms.
+// It is set after construction since the controller measures the RPC server, which needs the manager first.
+func (m *Manager) SetAdmissionController(admission *AdmissionController) {
+	m.admission = admission
+}
+
 // CreateRoom creates a new room.
 func (m *Manager) CreateRoom(ctx context.Context, room *models.Room) (*models.Room, error) {
+	// Check the creator is trusted enough to open rooms
+	if !room.CreatedBy.IsZero() {
+		if err := m.trustPolicy.CheckAbility(ctx, room.CreatedBy.Hex(), models.TrustAbilityCreateRoom); err != nil {
+			return nil, err
+		}
+	}
+
 	// Set creation time
 	now := time.Now()
 	room.TimeCreate(now)
 	room.LastActivity = now
 
+	if err := validateVoteSettings(room.Settings); err != nil {
+		return nil, err
+	}
+	if err := validateChatAppearanceSettings(room.Settings); err != nil {
+		return nil, err
+	}
+
+	// Pop-up rooms get their deadlines from the creation time
+	if room.Expiry != nil {
+		if err := m.popups.initExpiry(room.Expiry, now); err != nil {
+			return nil, err
+		}
+	}
+
 	// Initialize stats
 	room.Stats = models.RoomStats{
 		LastStatsReset: now,
@@ -98,9 +200,20 @@ func (m *Manager) CreateRoom(ctx context.Context, room *models.Room) (*models.Ro
 		// Continue anyway, the room was created successfully
 	}
 
+	m.lobby.Invalidate(ctx)
+
+	for _, handler := range m.createdHandlers {
+		handler(ctx, room)
+	}
+
 	return room, nil
 }
 
+// AddCreatedHandler adds a handler called when a room is created.
+func (m *Manager) AddCreatedHandler(handler func(ctx context.Context, room *models.Room)) {
+	m.createdHandlers = append(m.createdHandlers, handler)
+}
+
 // GetRoom gets a room by ID.
 func (m *Manager) GetRoom(ctx context.Context, roomID bson.ObjectID) (*models.Room, error) {
 	return m.roomRepo.FindByID(ctx, roomID)
@@ -113,11 +226,42 @@ func (m *Manager) GetRoomBySlug(ctx context.Context, slug string) (*models.Room,
 
 // UpdateRoom updates a room.
 func (m *Manager) UpdateRoom(ctx context.Context, room *models.Room) (*models.Room, error) {
+	if err := validateVoteSettings(room.Settings); err != nil {
+		return nil, err
+	}
+	if err := validateChatAppearanceSettings(room.Settings); err != nil {
+		return nil, err
+	}
+
+	// Keep the previous settings to tell whether they changed
+	previous, err := m.roomRepo.FindByID(ctx, room.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only platform admins verify rooms, by approving verification claims
+	room.Verified = previous.Verified
+
+	// The language is detected from the chat, or set by the owner through SetLanguage
+	room.Language = previous.Language
+
+	// Roles and the queue options are changed through their own methods, which check the permissions
+	room.Moderators = previous.Moderators
+	room.Roles = previous.Roles
+	room.RolePermissions = previous.RolePermissions
+	room.QueueLocked = previous.QueueLocked
+	room.QueueCycleDisabled = previous.QueueCycleDisabled
+	room.MaxConsecutivePlays = previous.MaxConsecutivePlays
+
+	// The stored DJ queue and track in flight are written through from the room state
+	room.DJQueue = previous.DJQueue
+	room.InFlightPlay = previous.InFlightPlay
+
 	// Update timestamp
 	room.UpdateNow()
 
 	// Update room in database
-	err := m.roomRepo.Update(ctx, room)
+	err = m.roomRepo.Update(ctx, room)
 	if err != nil {
 		return nil, err
 	}
@@ -141,9 +285,58 @@ func (m *Manager) UpdateRoom(ctx context.Context, room *models.Room) (*models.Ro
 		}
 	}
 
+	// A raised capacity may open slots for overflow listeners
+	m.mutex.Lock()
+	m.promoteListeners(ctx, room)
+	m.mutex.Unlock()
+
+	m.lobby.Invalidate(ctx)
+
+	if settingsChanged(previous.Settings, room.Settings) {
+		change := RoomSettingsChange{
+			RoomID:    room.ID,
+			Previous:  previous.Settings,
+			Settings:  room.Settings,
+			ChangedAt: room.UpdatedAt,
+		}
+		for _, handler := range m.settingsHandlers {
+			handler(ctx, change)
+		}
+	}
+
 	return room, nil
 }
 
+// AddSettingsChangeHandler adds a handler called when a room's settings are changed through this manager.
+func (m *Manager) AddSettingsChangeHandler(handler func(ctx context.Context, change RoomSettingsChange)) {
+	m.settingsHandlers = append(m.settingsHandlers, handler)
+}
+
+// ApplySettingsChange refreshes the settings cached in a room's state.
+// State writes started before the change carry the old settings, so this runs on every node once they are done.
+func (m *Manager) ApplySettingsChange(ctx context.Context, change RoomSettingsChange) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	managerState, err := m.stateManager.GetRoomState(ctx, change.RoomID.Hex())
+	if err != nil {
+		m.logger.Error("Failed to get room state", err, "roomId", change.RoomID.Hex())
+		return
+	}
+	if managerState == nil {
+		return
+	}
+
+	if managerState.Data == nil {
+		managerState.Data = make(map[string]any)
+	}
+	managerState.Data["settings"] = change.Settings
+
+	if err := m.stateManager.UpdateRoomState(ctx, managerState); err != nil {
+		m.logger.Error("Failed to update room state", err, "roomId", change.RoomID.Hex())
+	}
+}
+
 // DeleteRoom deletes a room.
 func (m *Manager) DeleteRoom(ctx context.Context, roomID bson.ObjectID) error {
 	// Delete room from database
@@ -159,13 +352,42 @@ func (m *Manager) DeleteRoom(ctx context.Context, roomID bson.ObjectID) error {
 		// Continue anyway, the room was deleted successfully
 	}
 
+	m.lobby.Invalidate(ctx)
+	return nil
+}
+
+// SetRoomActive opens or closes a room. Closed rooms can't be joined and aren't listed.
+func (m *Manager) SetRoomActive(ctx context.Context, roomID bson.ObjectID, active bool) error {
+	if err := m.roomRepo.SetActive(ctx, roomID, active); err != nil {
+		return err
+	}
+
+	if err := m.stateManager.SetRoomActive(ctx, roomID.Hex(), active); err != nil {
+		m.logger.Error("Failed to update room state activity", err, "roomId", roomID.Hex(), "active", active)
+		// Continue anyway, the room itself was updated
+	}
+
+	m.lobby.Invalidate(ctx)
 	return nil
 }
 
 // GetRoomState gets the current state of a room.
+// Rooms above the large room threshold get a summarized roster instead of the full user list.
 func (m *Manager) GetRoomState(ctx context.Context, roomID bson.ObjectID) (*models.RoomState, error) {
-	// Get room state from state manager
-	managerState, err := m.stateManager.GetRoomState(ctx, roomID.Hex())
+	state, err := m.loadRoomState(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.fillRoster(ctx, state)
+	m.fillExpiry(ctx, state)
+	return state, nil
+}
+
+// loadRoomState gets the current state of a room without its roster.
+func (m *Manager) loadRoomState(ctx context.Context, roomID bson.ObjectID) (*models.RoomState, error) {
+	// Get room state, possibly cached
+	managerState, err := m.stateReader.GetRoomState(ctx, roomID.Hex())
 	if err != nil {
 		return nil, err
 	}
@@ -190,7 +412,7 @@ func (m *Manager) GetRoomState(ctx context.Context, roomID bson.ObjectID) (*mode
 			ID:          room.ID,
 			Name:        room.Name,
 			Settings:    room.Settings,
-			DJQueue:     []models.QueueEntry{},
+			DJQueue:     m.loadDJQueue(ctx, roomID),
 			ActiveUsers: 0,
 			Users:       []models.PublicUser{},
 			PlayHistory: []models.PlayHistoryEntry{},
@@ -203,24 +425,200 @@ func (m *Manager) GetRoomState(ctx context.Context, roomID bson.ObjectID) (*mode
 	modelState := &models.RoomState{
 		ID:          roomID,
 		ActiveUsers: managerState.ActiveUsers,
-		DJQueue:     []models.QueueEntry{},
+		DJQueue:     m.loadDJQueue(ctx, roomID),
 		Users:       []models.PublicUser{},
 		PlayHistory: []models.PlayHistoryEntry{},
 	}
 
+	overflowListeners, err := m.stateManager.CountRoomListeners(ctx, roomID.Hex())
+	if err != nil {
+		m.logger.Error("Failed to count overflow listeners", err, "roomId", roomID.Hex())
+		// Continue anyway, the count is informational
+	}
+	modelState.OverflowListeners = overflowListeners
+
 	// Extract name and settings from Data map if available
+	settingsStored := false
 	if managerState.Data != nil {
 		if name, ok := managerState.Data["name"].(string); ok {
 			modelState.Name = name
 		}
-		if settings, ok := managerState.Data["settings"].(models.RoomSettings); ok {
+		if settings, ok := decodeSettings(managerState.Data["settings"]); ok {
 			modelState.Settings = settings
+			settingsStored = true
+		}
+	}
+
+	// States initialized when the first user joined don't store the room's name and settings until
+	// they are saved, take them from the room rather than leave the room with zero settings
+	if !settingsStored {
+		room, err := m.GetRoom(ctx, roomID)
+		if err != nil {
+			return nil, err
+		}
+		modelState.Name = room.Name
+		modelState.Settings = room.Settings
+	}
+
+	// Restore what is playing. States rebuilt from the stored room only know the IDs.
+	if managerState.CurrentDJ != "" {
+		dj, ok := decodeStateData[models.PublicUser](managerState.Data["currentDj"])
+		if !ok {
+			dj.ID, _ = bson.ObjectIDFromHex(managerState.CurrentDJ)
+		}
+		modelState.CurrentDJ = &dj
+	}
+	if managerState.CurrentMedia != "" {
+		media, ok := decodeStateData[models.MediaInfo](managerState.Data["currentMedia"])
+		if !ok {
+			media.ID, _ = bson.ObjectIDFromHex(managerState.CurrentMedia)
 		}
+		modelState.CurrentMedia = &media
+	}
+	modelState.MediaStartTime = managerState.MediaStartTime
+	modelState.MediaEndTime = managerState.MediaEndTime
+	if !modelState.MediaStartTime.IsZero() {
+		modelState.MediaProgress = max(int(time.Since(modelState.MediaStartTime).Seconds()), 0)
 	}
 
 	return modelState, nil
 }
 
+// loadDJQueue gets a room's DJ queue from Redis, or an empty queue if it can't be read.
+func (m *Manager) loadDJQueue(ctx context.Context, roomID bson.ObjectID) []models.QueueEntry {
+	entries, err := m.stateManager.GetQueueEntries(ctx, roomID.Hex())
+	if err != nil {
+		m.logger.Error("Failed to get DJ queue", err, "roomId", roomID.Hex())
+		return []models.QueueEntry{}
+	}
+
+	queue := make([]models.QueueEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.User == nil {
+			continue
+		}
+		queue = append(queue, models.QueueEntry{
+			User:         *entry.User,
+			Position:     entry.Position,
+			JoinTime:     entry.JoinTime,
+			PlayCount:    entry.PlayCount,
+			JoinedAt:     entry.JoinedAt,
+			WaitingSince: entry.WaitingSince,
+			TurnPlays:    entry.TurnPlays,
+		})
+	}
+	return queue
+}
+
+// saveDJQueue writes a room's DJ queue to Redis, and through to the stored room so it can be rebuilt
+// if Redis loses it. A queue that didn't change isn't written.
+func (m *Manager) saveDJQueue(ctx context.Context, roomID bson.ObjectID, queue []models.QueueEntry) error {
+	current := m.loadDJQueue(ctx, roomID)
+	if slices.EqualFunc(current, queue, sameQueueEntry) {
+		return nil
+	}
+
+	entries := make([]managers.QueueEntry, len(queue))
+	for i, entry := range queue {
+		entries[i] = managers.QueueEntry{
+			UserID:       entry.User.ID.Hex(),
+			Position:     entry.Position,
+			JoinTime:     entry.JoinTime,
+			PlayCount:    entry.PlayCount,
+			User:         &entry.User,
+			JoinedAt:     entry.JoinedAt,
+			WaitingSince: entry.WaitingSince,
+			TurnPlays:    entry.TurnPlays,
+		}
+	}
+	if err := m.stateManager.SetQueueEntries(ctx, roomID.Hex(), entries); err != nil {
+		return err
+	}
+
+	if err := m.roomRepo.UpdateDJQueue(ctx, roomID, queue); err != nil {
+		m.logger.Error("Failed to store DJ queue", err, "roomId", roomID.Hex())
+		// Continue anyway, the queue in Redis is the one in use
+	}
+	return nil
+}
+
+// sameQueueEntry checks if two DJ queue entries hold the same place in the queue, under the same name.
+func sameQueueEntry(a, b models.QueueEntry) bool {
+	return a.User.ID == b.User.ID &&
+		a.User.Username == b.User.Username &&
+		a.Position == b.Position &&
+		a.PlayCount == b.PlayCount &&
+		a.TurnPlays == b.TurnPlays &&
+		a.JoinTime.Equal(b.JoinTime) &&
+		a.JoinedAt.Equal(b.JoinedAt) &&
+		a.WaitingSince.Equal(b.WaitingSince)
+}
+
+// LoadDJQueue loads the DJ queue stored with a room, to rebuild the queue after Redis lost it.
+// Users who no longer exist are left out.
+func (m *Manager) LoadDJQueue(ctx context.Context, roomID string) ([]managers.QueueEntry, error) {
+	roomObjID, err := bson.ObjectIDFromHex(roomID)
+	if err != nil {
+		return nil, nil
+	}
+
+	room, err := m.roomRepo.FindByID(ctx, roomObjID)
+	if err != nil {
+		if errors.Is(err, models.ErrRoomNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	entries := make([]managers.QueueEntry, 0, len(room.DJQueue))
+	for _, stored := range room.DJQueue {
+		user, err := m.userRepo.FindByID(ctx, stored.UserID)
+		if err != nil {
+			if errors.Is(err, models.ErrUserNotFound) {
+				continue
+			}
+			return nil, err
+		}
+
+		publicUser := user.ToPublicUser()
+		entries = append(entries, managers.QueueEntry{
+			UserID:       stored.UserID.Hex(),
+			Position:     len(entries),
+			JoinTime:     stored.JoinTime,
+			PlayCount:    stored.PlayCount,
+			User:         &publicUser,
+			JoinedAt:     stored.JoinedAt,
+			WaitingSince: stored.WaitingSince,
+		})
+	}
+	return entries, nil
+}
+
+// decodeSettings reads room settings stored in a room state's Data map.
+func decodeSettings(value any) (models.RoomSettings, bool) {
+	return decodeStateData[models.RoomSettings](value)
+}
+
+// decodeStateData reads a value stored in a room state's Data map.
+// Values read back from Redis are decoded JSON rather than the type stored.
+func decodeStateData[T any](value any) (T, bool) {
+	var decoded T
+	switch v := value.(type) {
+	case T:
+		return v, true
+	case map[string]any:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return decoded, false
+		}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return decoded, false
+		}
+		return decoded, true
+	}
+	return decoded, false
+}
+
 // UpdateRoomState updates the state of a room.
 func (m *Manager) UpdateRoomState(ctx context.Context, roomID bson.ObjectID, state *models.RoomState) error {
 	// Convert models.RoomState to managers.RoomState
@@ -232,12 +630,42 @@ func (m *Manager) UpdateRoomState(ctx context.Context, roomID bson.ObjectID, sta
 		Data:         make(map[string]any),
 	}
 
-	// Store room name and settings in the Data map
+	// Keep the state that is not part of the model, such as playback
+	existing, err := m.stateManager.GetRoomState(ctx, roomID.Hex())
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.Data != nil {
+		managerState.Data = existing.Data
+	}
+
+	// Store room name and settings in the Data map.
+	// Settings already stored are kept, a state read before a settings change mustn't undo it.
 	managerState.Data["name"] = state.Name
-	managerState.Data["settings"] = state.Settings
+	if _, ok := managerState.Data["settings"]; !ok {
+		managerState.Data["settings"] = state.Settings
+	}
+
+	// Store what is playing, votes are checked against it
+	delete(managerState.Data, "currentDj")
+	delete(managerState.Data, "currentMedia")
+	if state.CurrentDJ != nil {
+		managerState.CurrentDJ = state.CurrentDJ.ID.Hex()
+		managerState.Data["currentDj"] = *state.CurrentDJ
+	}
+	if state.CurrentMedia != nil {
+		managerState.CurrentMedia = state.CurrentMedia.ID.Hex()
+		managerState.Data["currentMedia"] = *state.CurrentMedia
+	}
+	managerState.MediaStartTime = state.MediaStartTime
+	managerState.MediaEndTime = state.MediaEndTime
 
 	// Update room state in state manager
-	return m.stateManager.UpdateRoomState(ctx, managerState)
+	if err := m.stateManager.UpdateRoomState(ctx, managerState); err != nil {
+		return err
+	}
+
+	return m.saveDJQueue(ctx, roomID, state.DJQueue)
 }
 
 // JoinRoom adds a user to a room.
@@ -256,14 +684,24 @@ func (m *Manager) JoinRoom(ctx context.Context, roomID, userID bson.ObjectID) er
 		return errors.New("room is not active")
 	}
 
+	// Quarantined rooms are only open to their own staff
+	if room.Quarantined && !isStaffRole(roomRole(room, userID)) {
+		return models.ErrRoomQuarantined
+	}
+
 	// Get user
 	user, err := m.userRepo.FindByID(ctx, userID)
 	if err != nil {
 		return err
 	}
 
+	// 18+ rooms are only open to users who attested being adults
+	if room.Settings.AllowAgeRestricted && !user.IsAdult(time.Now()) {
+		return models.ErrAdultsOnly
+	}
+
 	// Get room state
-	state, err := m.GetRoomState(ctx, roomID)
+	state, err := m.loadRoomState(ctx, roomID)
 	if err != nil {
 		return err
 	}
@@ -275,9 +713,16 @@ func (m *Manager) JoinRoom(ctx context.Context, roomID, userID bson.ObjectID) er
 		}
 	}
 
-	// Check if room is at capacity
-	if len(state.Users) >= room.Settings.Capacity {
-		return models.ErrRoomFull
+	inRoom, err := m.stateManager.IsUserInRoom(ctx, roomID.Hex(), userID.Hex())
+	if err != nil {
+		return err
+	}
+	isListener, err := m.stateManager.IsListenerInRoom(ctx, roomID.Hex(), userID.Hex())
+	if err != nil {
+		return err
+	}
+	if inRoom || isListener {
+		return nil // User is already in room
 	}
 
 	// Check if user is banned
@@ -285,6 +730,43 @@ func (m *Manager) JoinRoom(ctx context.Context, roomID, userID bson.ObjectID) er
 		return errors.New("user is banned from this room")
 	}
 
+	// Check if room is at capacity
+	participants, err := m.stateManager.GetRoomUsers(ctx, roomID.Hex())
+	if err != nil {
+		return err
+	}
+	listeners, err := m.stateManager.CountRoomListeners(ctx, roomID.Hex())
+	if err != nil {
+		return err
+	}
+	if len(participants) >= room.Settings.Capacity && listeners >= room.Settings.ListenerOverflow {
+		return models.ErrRoomFull
+	}
+
+	// Under load only part of the room is open, and the joins that don't fit wait their turn
+	capacity, overflow, constrained := m.joinLimits(room, userID)
+	if constrained {
+		free := max(capacity-len(participants), 0) + max(overflow-listeners, 0)
+		if err := m.admission.admit(ctx, roomID.Hex(), userID.Hex(), free); err != nil {
+			return err
+		}
+	}
+
+	if len(participants) >= capacity {
+		// Admit the user as a listener while the overflow has room
+		if listeners >= overflow {
+			return models.ErrRoomFull
+		}
+
+		err = m.stateManager.AddListenerToRoom(ctx, roomID.Hex(), userID.Hex())
+		if err != nil {
+			return err
+		}
+
+		m.markJoined(ctx, room, user)
+		return nil
+	}
+
 	// Add user to room
 	publicUser := user.ToPublicUser()
 	state.Users = append(state.Users, publicUser)
@@ -296,17 +778,29 @@ func (m *Manager) JoinRoom(ctx context.Context, roomID, userID bson.ObjectID) er
 		return err
 	}
 
+	err = m.stateManager.AddUserToRoom(ctx, roomID.Hex(), userID.Hex())
+	if err != nil {
+		return err
+	}
+	m.assignChatShard(ctx, room, userID.Hex())
+
+	m.markJoined(ctx, room, user)
+	return nil
+}
+
+// markJoined updates presence and room activity after a user joined a room.
+func (m *Manager) markJoined(ctx context.Context, room *models.Room, user *models.User) {
 	// Update presence
-	err = m.presenceManager.UpdatePresence(ctx, userID, user.Username, "online")
+	err := m.presenceManager.UpdatePresence(ctx, user.ID, user.Username, "online")
 	if err != nil {
-		m.logger.Error("Failed to update user presence", err, "userId", userID.Hex())
+		m.logger.Error("Failed to update user presence", err, "userId", user.ID.Hex())
 		// Continue anyway, the user was added to the room successfully
 	}
 
 	// Set user's current room
-	err = m.presenceManager.SetUserRoom(ctx, userID, roomID.Hex())
+	err = m.presenceManager.SetUserRoom(ctx, user.ID, room.ID.Hex())
 	if err != nil {
-		m.logger.Error("Failed to set user room", err, "userId", userID.Hex(), "roomId", roomID.Hex())
+		m.logger.Error("Failed to set user room", err, "userId", user.ID.Hex(), "roomId", room.ID.Hex())
 		// Continue anyway, the user was added to the room successfully
 	}
 
@@ -314,11 +808,12 @@ func (m *Manager) JoinRoom(ctx context.Context, roomID, userID bson.ObjectID) er
 	room.LastActivity = time.Now()
 	err = m.roomRepo.Update(ctx, room)
 	if err != nil {
-		m.logger.Error("Failed to update room last activity", err, "roomId", roomID.Hex())
+		m.logger.Error("Failed to update room last activity", err, "roomId", room.ID.Hex())
 		// Continue anyway, the user was added to the room successfully
 	}
 
-	return nil
+	m.lobby.Invalidate(ctx)
+	m.emitActivity(ctx, RoomActivity{Type: ActivityJoin, RoomID: room.ID, UserID: user.ID})
 }
 
 // LeaveRoom removes a user from a room.
@@ -326,8 +821,23 @@ func (m *Manager) LeaveRoom(ctx context.Context, roomID, userID bson.ObjectID) e
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
+	// Listeners in the overflow only need to give up their place
+	isListener, err := m.stateManager.IsListenerInRoom(ctx, roomID.Hex(), userID.Hex())
+	if err != nil {
+		return err
+	}
+	if isListener {
+		err = m.stateManager.RemoveListenerFromRoom(ctx, roomID.Hex(), userID.Hex())
+		if err != nil {
+			return err
+		}
+
+		m.clearUserRoom(ctx, roomID, userID)
+		return nil
+	}
+
 	// Get room state
-	state, err := m.GetRoomState(ctx, roomID)
+	state, err := m.loadRoomState(ctx, roomID)
 	if err != nil {
 		return err
 	}
@@ -341,29 +851,205 @@ func (m *Manager) LeaveRoom(ctx context.Context, roomID, userID bson.ObjectID) e
 		}
 	}
 
+	inRoom, err := m.stateManager.IsUserInRoom(ctx, roomID.Hex(), userID.Hex())
+	if err != nil {
+		return err
+	}
+
 	// If user is not in room, return
-	if index == -1 {
+	if index == -1 && !inRoom {
 		return nil
 	}
 
 	// Remove user from room
-	state.Users = slices.Delete(state.Users, index, index+1)
-	state.ActiveUsers = len(state.Users)
+	if index != -1 {
+		state.Users = slices.Delete(state.Users, index, index+1)
+		state.ActiveUsers = len(state.Users)
 
-	// Update room state
-	err = m.UpdateRoomState(ctx, roomID, state)
+		// Update room state
+		err = m.UpdateRoomState(ctx, roomID, state)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = m.stateManager.RemoveUserFromRoom(ctx, roomID.Hex(), userID.Hex())
+	if err != nil {
+		return err
+	}
+
+	m.clearUserRoom(ctx, roomID, userID)
+	m.lobby.Invalidate(ctx)
+
+	// Hand the freed slot to the longest-waiting listener
+	room, err := m.GetRoom(ctx, roomID)
+	if err != nil {
+		m.logger.Error("Failed to get room for listener promotion", err, "roomId", roomID.Hex())
+		return nil
+	}
+	m.promoteListeners(ctx, room)
+
+	return nil
+}
+
+// TransferMember hands a user's place in their current room to another user, who takes over their
+// spot in the room or its overflow, in the DJ queue and their vote on the playing media.
+func (m *Manager) TransferMember(ctx context.Context, fromUserID, toUserID bson.ObjectID) error {
+	presence, err := m.presenceManager.GetPresence(ctx, fromUserID)
+	if err != nil {
+		return err
+	}
+	if presence == nil || presence.CurrentRoomID == "" {
+		return nil
+	}
+	roomID, err := bson.ObjectIDFromHex(presence.CurrentRoomID)
 	if err != nil {
+		return models.ErrInvalidID
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	moved, err := m.stateManager.TransferUser(ctx, roomID.Hex(), fromUserID.Hex(), toUserID.Hex())
+	if err != nil || !moved {
 		return err
 	}
 
-	// Update presence by removing room
-	err = m.presenceManager.SetUserRoom(ctx, userID, "")
+	room, err := m.GetRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	user, err := m.userRepo.FindByID(ctx, toUserID)
+	if err != nil {
+		return err
+	}
+
+	m.clearUserRoom(ctx, roomID, fromUserID)
+	m.markJoined(ctx, room, user)
+	return nil
+}
+
+// BanUser bans a user from a room and removes them from it if they are there.
+func (m *Manager) BanUser(ctx context.Context, roomID, userID bson.ObjectID) error {
+	if err := m.roomRepo.BanUser(ctx, roomID, userID); err != nil {
+		return err
+	}
+
+	if err := m.LeaveRoom(ctx, roomID, userID); err != nil {
+		m.logger.Error("Failed to remove banned user from room", err, "roomId", roomID.Hex(), "userId", userID.Hex())
+		// Continue anyway, the user was banned successfully
+	}
+
+	m.logger.Info("User banned from room", "roomId", roomID.Hex(), "userId", userID.Hex())
+	return nil
+}
+
+// UnbanUser lifts a user's ban from a room.
+func (m *Manager) UnbanUser(ctx context.Context, roomID, userID bson.ObjectID) error {
+	if err := m.roomRepo.UnbanUser(ctx, roomID, userID); err != nil {
+		return err
+	}
+
+	m.logger.Info("User unbanned from room", "roomId", roomID.Hex(), "userId", userID.Hex())
+	return nil
+}
+
+// clearUserRoom removes the room from a user's presence.
+func (m *Manager) clearUserRoom(ctx context.Context, roomID, userID bson.ObjectID) {
+	err := m.presenceManager.SetUserRoom(ctx, userID, "")
 	if err != nil {
 		m.logger.Error("Failed to clear user room", err, "userId", userID.Hex(), "roomId", roomID.Hex())
 		// Continue anyway, the user was removed from the room successfully
 	}
 
-	return nil
+	m.emitActivity(ctx, RoomActivity{Type: ActivityLeave, RoomID: roomID, UserID: userID})
+}
+
+// promoteListeners moves overflow listeners into open participant slots, oldest first.
+// The caller must hold the manager's lock.
+func (m *Manager) promoteListeners(ctx context.Context, room *models.Room) {
+	roomID := room.ID.Hex()
+
+	participants, err := m.stateManager.GetRoomUsers(ctx, roomID)
+	if err != nil {
+		m.logger.Error("Failed to get room users for listener promotion", err, "roomId", roomID)
+		return
+	}
+
+	openSlots := room.Settings.Capacity - len(participants)
+	if openSlots <= 0 {
+		return
+	}
+
+	listeners, err := m.stateManager.GetRoomListeners(ctx, roomID)
+	if err != nil {
+		m.logger.Error("Failed to get overflow listeners", err, "roomId", roomID)
+		return
+	}
+	if len(listeners) == 0 {
+		return
+	}
+
+	state, err := m.loadRoomState(ctx, room.ID)
+	if err != nil {
+		m.logger.Error("Failed to get room state for listener promotion", err, "roomId", roomID)
+		return
+	}
+
+	for _, listener := range listeners[:min(openSlots, len(listeners))] {
+		userID, err := bson.ObjectIDFromHex(listener)
+		if err != nil {
+			continue
+		}
+		user, err := m.userRepo.FindByID(ctx, userID)
+		if err != nil {
+			m.logger.Error("Failed to get overflow listener", err, "roomId", roomID, "userId", listener)
+			continue
+		}
+
+		if err := m.stateManager.RemoveListenerFromRoom(ctx, roomID, listener); err != nil {
+			continue
+		}
+
+		// Promoted listeners join the room's users like any participant joining
+		state.Users = append(state.Users, user.ToPublicUser())
+		state.ActiveUsers = len(state.Users)
+		if err := m.UpdateRoomState(ctx, room.ID, state); err != nil {
+			m.logger.Error("Failed to update room state for promoted listener", err, "roomId", roomID, "userId", listener)
+			state.Users = state.Users[:len(state.Users)-1]
+			state.ActiveUsers = len(state.Users)
+			continue
+		}
+		if err := m.stateManager.AddUserToRoom(ctx, roomID, listener); err != nil {
+			m.logger.Error("Failed to promote overflow listener", err, "roomId", roomID, "userId", listener)
+			continue
+		}
+		m.assignChatShard(ctx, room, listener)
+
+		m.logger.Info("Promoted overflow listener", "roomId", roomID, "userId", listener)
+		m.emitActivity(ctx, RoomActivity{Type: ActivityJoin, RoomID: room.ID, UserID: userID})
+		for _, handler := range m.promotionHandlers {
+			handler(ctx, room.ID, userID)
+		}
+	}
+}
+
+// AddPromotionHandler adds a handler called when an overflow listener is promoted to a full participant.
+func (m *Manager) AddPromotionHandler(handler func(ctx context.Context, roomID, userID bson.ObjectID)) {
+	m.promotionHandlers = append(m.promotionHandlers, handler)
+}
+
+// AddActivityHandler adds a handler called when a user joins, leaves or votes in a room.
+func (m *Manager) AddActivityHandler(handler func(ctx context.Context, activity RoomActivity)) {
+	m.activityHandlers = append(m.activityHandlers, handler)
+}
+
+// emitActivity notifies the activity handlers of something that happened in a room.
+func (m *Manager) emitActivity(ctx context.Context, activity RoomActivity) {
+	activity.Time = time.Now()
+	for _, handler := range m.activityHandlers {
+		handler(ctx, activity)
+	}
 }
 
 // IsUserInRoom checks if a user is in a room.
@@ -384,7 +1070,142 @@ func (m *Manager) IsUserInRoom(ctx context.Context, roomID, userID bson.ObjectID
 		}
 	}
 
-	return false, nil
+	return m.stateManager.IsUserInRoom(ctx, roomID.Hex(), userID.Hex())
+}
+
+// CanFollowRoom checks whether a user may still follow a room's events without joining it again, as when
+// resuming a session: the user is still in the room and isn't banned from it, and the room didn't turn private
+// for users who aren't its staff.
+func (m *Manager) CanFollowRoom(ctx context.Context, roomID, userID string) (bool, error) {
+	roomObjID, err := bson.ObjectIDFromHex(roomID)
+	if err != nil {
+		return false, nil
+	}
+	userObjID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, nil
+	}
+
+	room, err := m.GetRoom(ctx, roomObjID)
+	if errors.Is(err, models.ErrRoomNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !room.IsActive || slices.Contains(room.BannedUsers, userObjID) {
+		return false, nil
+	}
+	if room.Settings.Private && !isStaffRole(roomRole(room, userObjID)) {
+		return false, nil
+	}
+
+	inRoom, err := m.stateManager.IsUserInRoom(ctx, roomID, userID)
+	if err != nil || inRoom {
+		return inRoom, err
+	}
+	return m.stateManager.IsListenerInRoom(ctx, roomID, userID)
+}
+
+// IsListenerOnly checks if a user is in a room as a listener-only overflow member.
+func (m *Manager) IsListenerOnly(ctx context.Context, roomID, userID bson.ObjectID) (bool, error) {
+	return m.stateManager.IsListenerInRoom(ctx, roomID.Hex(), userID.Hex())
+}
+
+// GetPinnedMessages gets the IDs of a room's pinned chat messages, oldest pin first.
+func (m *Manager) GetPinnedMessages(ctx context.Context, roomID bson.ObjectID) ([]bson.ObjectID, error) {
+	ids, err := m.stateManager.GetPinnedMessages(ctx, roomID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	messageIDs := make([]bson.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		messageID, err := bson.ObjectIDFromHex(id)
+		if err != nil {
+			continue
+		}
+		messageIDs = append(messageIDs, messageID)
+	}
+	return messageIDs, nil
+}
+
+// PinMessage adds a chat message to a room's pinned messages unless it is already pinned, failing with
+// models.ErrPinLimitReached once limit messages are pinned. It reports whether the message was pinned by this call.
+func (m *Manager) PinMessage(ctx context.Context, roomID, messageID bson.ObjectID, limit int) (bool, error) {
+	return m.stateManager.PinMessage(ctx, roomID.Hex(), messageID.Hex(), limit)
+}
+
+// UnpinMessage removes a chat message from a room's pinned messages, reporting whether it was pinned.
+func (m *Manager) UnpinMessage(ctx context.Context, roomID, messageID bson.ObjectID) (bool, error) {
+	return m.stateManager.UnpinMessage(ctx, roomID.Hex(), messageID.Hex())
+}
+
+// ClearPinnedMessages unpins all of a room's chat messages.
+func (m *Manager) ClearPinnedMessages(ctx context.Context, roomID bson.ObjectID) error {
+	return m.stateManager.ClearPinnedMessages(ctx, roomID.Hex())
+}
+
+// PersistRoomState stores the current DJ and media of a room from its Redis state on the room, so
+// RebuildRoomState can restore them. It is used when the instance serving the room shuts down.
+func (m *Manager) PersistRoomState(ctx context.Context, roomID bson.ObjectID) error {
+	state, err := m.stateManager.GetRoomState(ctx, roomID.Hex())
+	if err != nil || state == nil {
+		return err
+	}
+
+	currentDJ, _ := bson.ObjectIDFromHex(state.CurrentDJ)
+	currentMedia, _ := bson.ObjectIDFromHex(state.CurrentMedia)
+
+	err = m.roomRepo.SetCurrentDJ(ctx, roomID, currentDJ)
+	if errors.Is(err, models.ErrUserNotInRoom) {
+		// The DJ left while the state still had them playing
+		currentDJ, currentMedia = bson.NilObjectID, bson.NilObjectID
+		err = m.roomRepo.SetCurrentDJ(ctx, roomID, currentDJ)
+	}
+	if err != nil {
+		return err
+	}
+
+	if currentDJ.IsZero() {
+		return nil
+	}
+	return m.roomRepo.SetCurrentMedia(ctx, roomID, currentMedia)
+}
+
+// RebuildRoomState rebuilds the Redis state of a room whose state expired while it was still in use, from the stored room.
+// Pins and the timing of the current media only live in Redis and start over. Rooms that are gone or closed
+// get no state.
+func (m *Manager) RebuildRoomState(ctx context.Context, roomID string) (*managers.RoomState, error) {
+	roomObjID, err := bson.ObjectIDFromHex(roomID)
+	if err != nil {
+		return nil, nil
+	}
+
+	room, err := m.roomRepo.FindByID(ctx, roomObjID)
+	if err != nil {
+		if errors.Is(err, models.ErrRoomNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !room.IsActive {
+		return nil, nil
+	}
+
+	state := &managers.RoomState{
+		RoomID:   roomID,
+		IsActive: true,
+		Data:     make(map[string]any),
+	}
+	if !room.CurrentDJ.IsZero() {
+		state.CurrentDJ = room.CurrentDJ.Hex()
+	}
+	if !room.CurrentMedia.IsZero() {
+		state.CurrentMedia = room.CurrentMedia.Hex()
+	}
+
+	return state, nil
 }
 
 // GetRoomUsers gets all users in a room.
@@ -401,17 +1222,32 @@ func (m *Manager) GetRoomUsers(ctx context.Context, roomID bson.ObjectID) ([]mod
 	return state.Users, nil
 }
 
-// SearchRooms searches for rooms based on criteria.
+// SearchRooms searches for rooms based on criteria. Results are served from the lobby cache.
 func (m *Manager) SearchRooms(ctx context.Context, criteria models.RoomSearchCriteria) ([]*models.Room, int64, error) {
-	return m.roomRepo.SearchRooms(ctx, criteria)
+	return m.lobby.get(ctx, searchKey(criteria), func(ctx context.Context) ([]*models.Room, int64, error) {
+		return m.roomRepo.SearchRooms(ctx, criteria)
+	})
 }
 
 // GetActiveRooms gets a list of active rooms.
 func (m *Manager) GetActiveRooms(ctx context.Context, limit int) ([]*models.Room, error) {
-	return m.roomRepo.FindRecentRooms(ctx, limit)
+	rooms, _, err := m.lobby.get(ctx, limitKey("recent", limit), func(ctx context.Context) ([]*models.Room, int64, error) {
+		rooms, err := m.roomRepo.FindRecentRooms(ctx, limit)
+		return rooms, int64(len(rooms)), err
+	})
+	return rooms, err
 }
 
-// GetPopularRooms gets a list of popular rooms.
+// GetPopularRooms gets a list of popular rooms. Pop-up rooms are left out.
 func (m *Manager) GetPopularRooms(ctx context.Context, limit int) ([]*models.Room, error) {
-	return m.roomRepo.FindPopularRooms(ctx, limit)
+	rooms, _, err := m.lobby.get(ctx, limitKey("popular", limit), func(ctx context.Context) ([]*models.Room, int64, error) {
+		rooms, err := m.roomRepo.FindPopularRooms(ctx, limit)
+		return rooms, int64(len(rooms)), err
+	})
+	return rooms, err
+}
+
+// InvalidateLobby marks the cached lobby listings stale after a room changed outside the manager.
+func (m *Manager) InvalidateLobby(ctx context.Context) {
+	m.lobby.Invalidate(ctx)
 }
@@ -0,0 +1,571 @@
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// membershipKeyPrefix prefixes the keys used to reconcile room memberships.
+	membershipKeyPrefix = "membership"
+
+	// membershipLockKey claims the reconciliation of all rooms, so one instance does it at a time.
+	membershipLockKey = membershipKeyPrefix + ":lock"
+
+	// membershipReportKey holds the report of the last reconciliation.
+	membershipReportKey = membershipKeyPrefix + ":report"
+)
+
+// LiveSubscriptions are the room subscriptions of the WebSocket connections to this instance.
+type LiveSubscriptions interface {
+	// RoomSubscriptions lists the users following each room's events, by room ID.
+	RoomSubscriptions() map[string][]string
+
+	// UnsubscribeUser stops a user's connections from following a room's events.
+	UnsubscribeUser(roomID, userID string)
+}
+
+// MembershipPolicy controls the reconciliation of room memberships.
+type MembershipPolicy struct {
+	// Interval is how often memberships are reconciled. Zero disables reconciliation.
+	Interval time.Duration
+
+	// Workers is the number of rooms reconciled in parallel.
+	Workers int
+
+	// Grace is how long a member without a live connection is kept before being removed.
+	// It is at least two intervals, so every instance gets to report its connections in between.
+	Grace time.Duration
+}
+
+// MembershipDrift counts the discrepancies found in one source of room memberships.
+type MembershipDrift struct {
+	// Ghosts are entries of users who are no longer in the room.
+	Ghosts int `json:"ghosts"`
+
+	// Missing are users in the room without an entry.
+	Missing int `json:"missing"`
+
+	// Fixed is how many of the discrepancies were repaired.
+	Fixed int `json:"fixed"`
+}
+
+// add adds another drift count to this one.
+func (d *MembershipDrift) add(other MembershipDrift) {
+	d.Ghosts += other.Ghosts
+	d.Missing += other.Missing
+	d.Fixed += other.Fixed
+}
+
+// MembershipReport summarizes a reconciliation of room memberships.
+type MembershipReport struct {
+	// StartedAt is when the reconciliation started.
+	StartedAt time.Time `json:"startedAt"`
+
+	// DurationMs is how long the reconciliation took, in milliseconds.
+	DurationMs int64 `json:"durationMs"`
+
+	// Rooms is the number of active rooms reconciled.
+	Rooms int `json:"rooms"`
+
+	// DriftedRooms is the number of rooms where any source had drifted.
+	DriftedRooms int `json:"driftedRooms"`
+
+	// FailedRooms is the number of rooms that couldn't be reconciled.
+	FailedRooms int `json:"failedRooms"`
+
+	// State is the drift of the Redis room user sets and room state.
+	State MembershipDrift `json:"state"`
+
+	// Records is the drift of the room users stored in MongoDB.
+	Records MembershipDrift `json:"records"`
+
+	// Subscriptions is the drift of the live subscriptions of the instance that ran the reconciliation.
+	Subscriptions MembershipDrift `json:"subscriptions"`
+}
+
+// MembershipReconciler keeps the three sources of room membership in agreement: the room users stored
+// in MongoDB, the room user sets in Redis and the room subscriptions of live WebSocket connections.
+// Redis is what the rooms run on; live connections tell who is really there, and MongoDB follows Redis.
+//
+// Every instance reports its live connections in Redis and drops subscriptions to rooms their users
+// have left. One instance at a time then reconciles the rooms in parallel: members without a live
+// connection anywhere are removed once the grace period is over, and connected users missing from
+// a room they are in are put back.
+type MembershipReconciler struct {
+	roomManager  *Manager
+	roomRepo     repositories.RoomRepository
+	stateManager *managers.RoomStateManager
+	presenceMgr  *managers.PresenceManager
+	redisClient  *redis.Client
+	live         LiveSubscriptions
+	policy       MembershipPolicy
+	logger       *utils.Logger
+}
+
+// NewMembershipReconciler creates a new membership reconciler.
+func NewMembershipReconciler(
+	roomManager *Manager,
+	roomRepo repositories.RoomRepository,
+	stateManager *managers.RoomStateManager,
+	presenceMgr *managers.PresenceManager,
+	redisClient *redis.Client,
+	live LiveSubscriptions,
+	policy MembershipPolicy,
+	logger *utils.Logger,
+) *MembershipReconciler {
+	if policy.Workers < 1 {
+		policy.Workers = 1
+	}
+	policy.Grace = max(policy.Grace, 2*policy.Interval)
+
+	return &MembershipReconciler{
+		roomManager:  roomManager,
+		roomRepo:     roomRepo,
+		stateManager: stateManager,
+		presenceMgr:  presenceMgr,
+		redisClient:  redisClient,
+		live:         live,
+		policy:       policy,
+		logger:       logger.Named("membership_reconciler"),
+	}
+}
+
+// Reconcile reconciles the memberships of all active rooms. It is meant to be run as a maintenance
+// task on every instance, only one of which reconciles the rooms each interval.
+func (s *MembershipReconciler) Reconcile(ctx context.Context) error {
+	startedAt := time.Now()
+
+	subscriptions := s.syncSubscriptions(ctx, startedAt)
+
+	claimed, err := s.redisClient.Client().SetNX(ctx, membershipLockKey, "1", s.policy.Interval/2).Result()
+	if err != nil || !claimed {
+		return err
+	}
+
+	rooms, err := s.roomRepo.FindMany(ctx, bson.M{"isActive": true}, nil)
+	if err != nil {
+		return err
+	}
+
+	report := &MembershipReport{
+		StartedAt:     startedAt,
+		Rooms:         len(rooms),
+		Subscriptions: subscriptions,
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan *models.Room)
+	for range min(s.policy.Workers, len(rooms)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for room := range queue {
+				state, records, err := s.reconcileRoom(ctx, room, startedAt)
+
+				mu.Lock()
+				if err != nil {
+					s.logger.Error("Failed to reconcile room membership", err, "roomId", room.ID.Hex())
+					report.FailedRooms++
+				}
+				if state != (MembershipDrift{}) || records != (MembershipDrift{}) {
+					report.DriftedRooms++
+				}
+				report.State.add(state)
+				report.Records.add(records)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, room := range rooms {
+		select {
+		case queue <- room:
+		case <-ctx.Done():
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	report.DurationMs = time.Since(startedAt).Milliseconds()
+	if err := s.redisClient.SetObject(ctx, membershipReportKey, report, 0); err != nil {
+		s.logger.Error("Failed to store membership report", err)
+	}
+
+	s.logger.Info("Reconciled room memberships",
+		"rooms", report.Rooms,
+		"driftedRooms", report.DriftedRooms,
+		"failedRooms", report.FailedRooms,
+		"stateGhosts", report.State.Ghosts,
+		"stateMissing", report.State.Missing,
+		"recordGhosts", report.Records.Ghosts,
+		"recordMissing", report.Records.Missing,
+		"subscriptionGhosts", report.Subscriptions.Ghosts,
+		"durationMs", report.DurationMs,
+	)
+	return ctx.Err()
+}
+
+// GetReport gets the report of the last reconciliation, nil if there was none yet.
+func (s *MembershipReconciler) GetReport(ctx context.Context) (*MembershipReport, error) {
+	data, err := s.redisClient.Get(ctx, membershipReportKey)
+	if err != nil || data == "" {
+		return nil, err
+	}
+
+	var report MembershipReport
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// syncSubscriptions drops this instance's subscriptions to rooms their users have left, and reports
+// the remaining ones as live connections.
+func (s *MembershipReconciler) syncSubscriptions(ctx context.Context, now time.Time) MembershipDrift {
+	var drift MembershipDrift
+
+	pipe := s.redisClient.Pipeline()
+	for roomID, userIDs := range s.live.RoomSubscriptions() {
+		connected := make(map[string]any, len(userIDs))
+		for _, userID := range userIDs {
+			member, err := s.isMember(ctx, roomID, userID)
+			if err != nil {
+				s.logger.Error("Failed to check room membership", err, "roomId", roomID, "userId", userID)
+				continue
+			}
+			if !member && !s.presentIn(ctx, roomID, userID) {
+				// Removed from the room without the connection being told, for example by a moderator
+				drift.Ghosts++
+				drift.Fixed++
+				s.live.UnsubscribeUser(roomID, userID)
+				continue
+			}
+			connected[userID] = now.Unix()
+		}
+
+		if len(connected) > 0 {
+			liveKey := formatMembershipLiveKey(roomID)
+			pipe.HSet(ctx, liveKey, connected)
+			pipe.Expire(ctx, liveKey, s.policy.Grace)
+		}
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.Error("Failed to report live room connections", err)
+	}
+	return drift
+}
+
+// reconcileRoom reconciles a room's members with its live connections, then its MongoDB records with its members.
+func (s *MembershipReconciler) reconcileRoom(ctx context.Context, room *models.Room, now time.Time) (MembershipDrift, MembershipDrift, error) {
+	var state, records MembershipDrift
+	roomID := room.ID.Hex()
+
+	live, err := s.liveUsers(ctx, roomID, now)
+	if err != nil {
+		return state, records, err
+	}
+
+	participants, err := s.stateManager.GetRoomUsers(ctx, roomID)
+	if err != nil {
+		return state, records, err
+	}
+	listeners, err := s.stateManager.GetRoomListeners(ctx, roomID)
+	if err != nil {
+		return state, records, err
+	}
+	roomState, err := s.roomManager.loadRoomState(ctx, room.ID)
+	if err != nil {
+		return state, records, err
+	}
+
+	inSet := make(map[string]bool, len(participants)+len(listeners))
+	for _, userID := range participants {
+		inSet[userID] = true
+	}
+	for _, userID := range listeners {
+		inSet[userID] = true
+	}
+	inState := make(map[string]bool, len(roomState.Users))
+	for _, user := range roomState.Users {
+		inState[user.ID.Hex()] = true
+	}
+
+	// Members without a live connection are ghosts once they have been gone for the grace period
+	suspects, err := s.redisClient.HGetAll(ctx, formatMembershipSuspectsKey(roomID))
+	if err != nil {
+		return state, records, err
+	}
+	stillSuspected := make(map[string]any)
+	for userID := range union(inSet, inState) {
+		if live[userID] {
+			continue
+		}
+
+		since, err := strconv.ParseInt(suspects[userID], 10, 64)
+		if err != nil {
+			stillSuspected[userID] = now.Unix()
+			continue
+		}
+		if now.Sub(time.Unix(since, 0)) < s.policy.Grace {
+			stillSuspected[userID] = since
+			continue
+		}
+
+		state.Ghosts++
+		if err := s.removeGhost(ctx, room.ID, userID); err != nil {
+			s.logger.Error("Failed to remove ghost room member", err, "roomId", roomID, "userId", userID)
+			stillSuspected[userID] = since
+			continue
+		}
+		state.Fixed++
+		delete(inSet, userID)
+		delete(inState, userID)
+	}
+	s.storeSuspects(ctx, roomID, stillSuspected)
+
+	// Connected users who are in the room by their presence but missing from its set or state are put back
+	for userID := range live {
+		if inSet[userID] && inState[userID] {
+			continue
+		}
+		if !s.presentIn(ctx, roomID, userID) {
+			continue
+		}
+
+		state.Missing++
+		if err := s.restoreMember(ctx, room.ID, userID); err != nil {
+			s.logger.Error("Failed to restore room member", err, "roomId", roomID, "userId", userID)
+			continue
+		}
+		state.Fixed++
+	}
+
+	records, err = s.reconcileRecords(ctx, room)
+	return state, records, err
+}
+
+// reconcileRecords makes a room's users stored in MongoDB match its participants in Redis.
+// Overflow listeners are not stored, they don't take a place in the room.
+func (s *MembershipReconciler) reconcileRecords(ctx context.Context, room *models.Room) (MembershipDrift, error) {
+	var drift MembershipDrift
+
+	participants, err := s.stateManager.GetRoomUsers(ctx, room.ID.Hex())
+	if err != nil {
+		return drift, err
+	}
+	stored, err := s.roomRepo.FindRoomUsers(ctx, room.ID)
+	if err != nil {
+		return drift, err
+	}
+
+	inRoom := make(map[string]bool, len(participants))
+	for _, userID := range participants {
+		inRoom[userID] = true
+	}
+	recorded := make(map[string]bool, len(stored))
+	for _, roomUser := range stored {
+		recorded[roomUser.UserID.Hex()] = true
+		if inRoom[roomUser.UserID.Hex()] {
+			continue
+		}
+
+		drift.Ghosts++
+		err := s.roomRepo.RemoveUserFromRoom(ctx, room.ID, roomUser.UserID)
+		if err != nil && !errors.Is(err, models.ErrUserNotInRoom) {
+			s.logger.Error("Failed to remove ghost room user record", err, "roomId", room.ID.Hex(), "userId", roomUser.UserID.Hex())
+			continue
+		}
+		drift.Fixed++
+	}
+
+	for _, id := range participants {
+		if recorded[id] {
+			continue
+		}
+		userID, err := bson.ObjectIDFromHex(id)
+		if err != nil {
+			continue
+		}
+
+		drift.Missing++
+		err = s.roomRepo.AddUserToRoom(ctx, &models.RoomUser{
+			RoomID: room.ID,
+			UserID: userID,
+			Role:   roomRole(room, userID),
+		})
+		if err != nil && !errors.Is(err, models.ErrUserAlreadyInRoom) {
+			// The record can't be added when it would exceed the room's capacity, for one
+			s.logger.Debug("Failed to add missing room user record", "roomId", room.ID.Hex(), "userId", id, "error", err)
+			continue
+		}
+		drift.Fixed++
+	}
+
+	return drift, nil
+}
+
+// liveUsers gets the users of a room with a live connection to any instance, dropping stale reports.
+func (s *MembershipReconciler) liveUsers(ctx context.Context, roomID string, now time.Time) (map[string]bool, error) {
+	liveKey := formatMembershipLiveKey(roomID)
+	reported, err := s.redisClient.HGetAll(ctx, liveKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Instances report their connections every interval, a report older than two is gone
+	cutoff := now.Add(-2 * s.policy.Interval).Unix()
+	live := make(map[string]bool, len(reported))
+	for userID, value := range reported {
+		seen, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || seen < cutoff {
+			s.redisClient.HDel(ctx, liveKey, userID)
+			continue
+		}
+		live[userID] = true
+	}
+	return live, nil
+}
+
+// storeSuspects replaces the members of a room suspected to be ghosts, with when they were first missed.
+func (s *MembershipReconciler) storeSuspects(ctx context.Context, roomID string, suspects map[string]any) {
+	suspectsKey := formatMembershipSuspectsKey(roomID)
+
+	pipe := s.redisClient.TxPipeline()
+	pipe.Del(ctx, suspectsKey)
+	if len(suspects) > 0 {
+		pipe.HSet(ctx, suspectsKey, suspects)
+		pipe.Expire(ctx, suspectsKey, 2*s.policy.Grace)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.Error("Failed to store suspected ghost members", err, "roomId", roomID)
+	}
+}
+
+// removeGhost removes a member without a live connection from a room.
+func (s *MembershipReconciler) removeGhost(ctx context.Context, roomID bson.ObjectID, id string) error {
+	userID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		// Not a user, only the Redis set can hold it
+		return s.stateManager.RemoveUserFromRoom(ctx, roomID.Hex(), id)
+	}
+	return s.roomManager.LeaveRoom(ctx, roomID, userID)
+}
+
+// restoreMember puts a connected user back in a room they are missing from.
+func (s *MembershipReconciler) restoreMember(ctx context.Context, roomID bson.ObjectID, id string) error {
+	userID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return models.ErrInvalidID
+	}
+	return s.roomManager.restoreMember(ctx, roomID, userID)
+}
+
+// isMember checks whether a user is a participant or overflow listener of a room.
+func (s *MembershipReconciler) isMember(ctx context.Context, roomID, userID string) (bool, error) {
+	inRoom, err := s.stateManager.IsUserInRoom(ctx, roomID, userID)
+	if err != nil || inRoom {
+		return inRoom, err
+	}
+	return s.stateManager.IsListenerInRoom(ctx, roomID, userID)
+}
+
+// presentIn checks whether a user's presence places them in a room. Leaving a room clears the
+// user's presence in it, whoever made them leave.
+func (s *MembershipReconciler) presentIn(ctx context.Context, roomID, id string) bool {
+	userID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return false
+	}
+
+	presence, err := s.presenceMgr.GetPresence(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to get presence", err, "userId", id)
+		return false
+	}
+	return presence != nil && presence.CurrentRoomID == roomID
+}
+
+// restoreMember puts a user back in a room's user set and state, wherever they are missing.
+// Unlike joining, it doesn't check whether there is room for the user, they are already there.
+func (m *Manager) restoreMember(ctx context.Context, roomID, userID bson.ObjectID) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	isListener, err := m.stateManager.IsListenerInRoom(ctx, roomID.Hex(), userID.Hex())
+	if err != nil || isListener {
+		return err
+	}
+
+	inRoom, err := m.stateManager.IsUserInRoom(ctx, roomID.Hex(), userID.Hex())
+	if err != nil {
+		return err
+	}
+	if !inRoom {
+		if err := m.stateManager.AddUserToRoom(ctx, roomID.Hex(), userID.Hex()); err != nil {
+			return err
+		}
+		if room, err := m.GetRoom(ctx, roomID); err == nil {
+			m.assignChatShard(ctx, room, userID.Hex())
+		}
+	}
+
+	state, err := m.loadRoomState(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	for _, user := range state.Users {
+		if user.ID == userID {
+			return nil
+		}
+	}
+
+	user, err := m.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	state.Users = append(state.Users, user.ToPublicUser())
+	state.ActiveUsers = len(state.Users)
+	if err := m.UpdateRoomState(ctx, roomID, state); err != nil {
+		return err
+	}
+
+	m.lobby.Invalidate(ctx)
+	return nil
+}
+
+// union gets the keys in either of two sets.
+func union(a, b map[string]bool) map[string]bool {
+	all := make(map[string]bool, len(a)+len(b))
+	for key := range a {
+		all[key] = true
+	}
+	for key := range b {
+		all[key] = true
+	}
+	return all
+}
+
+// formatMembershipLiveKey formats the key of the users reported connected to a room, with when they last were.
+func formatMembershipLiveKey(roomID string) string {
+	return fmt.Sprintf("%s:live:%s", membershipKeyPrefix, roomID)
+}
+
+// formatMembershipSuspectsKey formats the key of a room's members suspected to be ghosts.
+func formatMembershipSuspectsKey(roomID string) string {
+	return fmt.Sprintf("%s:suspects:%s", membershipKeyPrefix, roomID)
+}
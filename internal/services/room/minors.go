@@ -0,0 +1,104 @@
+package room
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+// MinorPolicy controls the restricted experience of minors, the users who attested being younger than
+// models.AdultAge. Users who never attested their age get the regular experience, though rooms open to
+// age-restricted media still turn them away.
+type MinorPolicy struct {
+	// Enabled turns the restricted experience on.
+	Enabled bool
+
+	// FilterChat refuses chat messages from minors containing blocked terms.
+	FilterChat bool
+
+	// BlockedTerms are the terms minors can't post. Terms may be several words long.
+	BlockedTerms []string
+
+	// DisableLinks refuses links in chat messages from minors, whatever their trust level.
+	DisableLinks bool
+
+	// HideRestrictedRooms leaves the rooms open to age-restricted media out of minors' room listings and searches.
+	HideRestrictedRooms bool
+}
+
+// checkMessage checks the content of a minor's chat message against the policy.
+func (p MinorPolicy) checkMessage(content string) error {
+	if p.DisableLinks && linkPattern.MatchString(content) {
+		return models.NewChatError(models.ErrMinorRestricted, "Links are turned off for users under 18", http.StatusForbidden)
+	}
+
+	if p.FilterChat {
+		// Pad with spaces so terms only match whole words
+		padded := " " + normalizeWords(content) + " "
+		for _, term := range p.BlockedTerms {
+			if term = normalizeWords(term); term != "" && strings.Contains(padded, " "+term+" ") {
+				return models.ErrChatFiltered
+			}
+		}
+	}
+	return nil
+}
+
+// isMinor checks whether a user gets the restricted experience of minors.
+func (m *Manager) isMinor(ctx context.Context, userID bson.ObjectID) (bool, error) {
+	if !m.minors.Enabled {
+		return false, nil
+	}
+
+	user, err := m.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.IsMinor(time.Now()), nil
+}
+
+// CheckMinorMessage checks a chat message against the minor policy if its author is a minor.
+func (m *Manager) CheckMinorMessage(ctx context.Context, userID bson.ObjectID, content string) error {
+	if !m.minors.FilterChat && !m.minors.DisableLinks {
+		return nil
+	}
+
+	minor, err := m.isMinor(ctx, userID)
+	if err != nil || !minor {
+		return err
+	}
+	return m.minors.checkMessage(content)
+}
+
+// VisibleRooms leaves the rooms open to age-restricted media out of a room listing shown to a minor.
+// When the viewer can't be looked up the rooms are left out too. An empty viewer ID is an anonymous
+// visitor, who sees every room.
+func (m *Manager) VisibleRooms(ctx context.Context, viewerID string, rooms []*models.Room) []*models.Room {
+	if !m.minors.Enabled || !m.minors.HideRestrictedRooms || viewerID == "" {
+		return rooms
+	}
+
+	hide := true
+	if objectID, err := bson.ObjectIDFromHex(viewerID); err == nil {
+		minor, err := m.isMinor(ctx, objectID)
+		if err != nil {
+			m.logger.Error("Failed to check viewer age for room listing", err, "userId", viewerID)
+		}
+		hide = err != nil || minor
+	}
+	if !hide {
+		return rooms
+	}
+
+	visible := make([]*models.Room, 0, len(rooms))
+	for _, room := range rooms {
+		if !room.Settings.AllowAgeRestricted {
+			visible = append(visible, room)
+		}
+	}
+	return visible
+}
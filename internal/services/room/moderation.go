@@ -106,6 +106,12 @@ type ModerationLog struct {
 	Details     string           `bson:"details,omitempty" json:"details,omitempty"`
 }
 
+// StrikeRecorder records moderation actions against a user's trust record.
+type StrikeRecorder interface {
+	// AddStrike records a moderation action against a user.
+	AddStrike(ctx context.Context, userID string) error
+}
+
 // ModerationService provides moderation functionality for rooms.
 type ModerationService struct {
 	db             *mongo.Database
@@ -113,10 +119,13 @@ type ModerationService struct {
 	userRepo       repositories.UserRepository
 	roomState      *managers.RoomStateManager
 	pubsub         *managers.PubSubManager
+	strikes        StrikeRecorder
 	logger         *utils.Logger
 	activeBans     map[string]map[string]*UserBan // roomID -> userID -> ban
 	bansMutex      sync.RWMutex
 	reportHandlers []func(context.Context, *UserReport) error
+	banHandlers    []func(context.Context, *UserBan)
+	kickHandlers   []func(ctx context.Context, userID, roomID, reason string)
 }
 
 // NewModerationService creates a new moderation service.
@@ -126,6 +135,7 @@ func NewModerationService(
 	userRepo repositories.UserRepository,
 	roomState *managers.RoomStateManager,
 	pubsub *managers.PubSubManager,
+	strikes StrikeRecorder,
 	logger *utils.Logger,
 ) *ModerationService {
 	return &ModerationService{
@@ -134,6 +144,7 @@ func NewModerationService(
 		userRepo:   userRepo,
 		roomState:  roomState,
 		pubsub:     pubsub,
+		strikes:    strikes,
 		logger:     logger.Named("moderation_service"),
 		activeBans: make(map[string]map[string]*UserBan),
 	}
@@ -154,29 +165,28 @@ func (s *ModerationService) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to subscribe to moderation events: %w", err)
 	}
 
-	// Add handler for moderation events
-	s.pubsub.AddHandler("moderation:*", func(channel string, payload []byte) {
+	// Add handler for moderation events, events that fail are dead-lettered for replay
+	s.pubsub.AddFailableHandler("moderation:*", "moderation", func(channel string, payload []byte) error {
 		var event map[string]any
 		if err := json.Unmarshal(payload, &event); err != nil {
-			s.logger.Error("Failed to unmarshal moderation event", err)
-			return
+			return fmt.Errorf("failed to unmarshal moderation event: %w", err)
 		}
 
 		// Handle different event types
 		eventType, ok := event["type"].(string)
 		if !ok {
-			s.logger.Error("Invalid moderation event type", fmt.Errorf("invalid event type"))
-			return
+			return fmt.Errorf("invalid moderation event type")
 		}
 
 		switch eventType {
 		case "ban_user":
-			s.handleBanUserEvent(ctx, event)
+			return s.handleBanUserEvent(ctx, event)
 		case "unban_user":
-			s.handleUnbanUserEvent(ctx, event)
+			return s.handleUnbanUserEvent(ctx, event)
 		case "report_user":
-			s.handleReportUserEvent(ctx, event)
+			return s.handleReportUserEvent(ctx, event)
 		}
+		return nil
 	})
 
 	return nil
@@ -235,18 +245,16 @@ func (s *ModerationService) loadActiveBans(ctx context.Context) error {
 }
 
 // handleBanUserEvent handles a ban user event.
-func (s *ModerationService) handleBanUserEvent(ctx context.Context, event map[string]any) {
+func (s *ModerationService) handleBanUserEvent(ctx context.Context, event map[string]any) error {
 	// Extract event data
 	userID, ok := event["user_id"].(string)
 	if !ok {
-		s.logger.Error("Invalid user ID in ban event", fmt.Errorf("invalid user ID"))
-		return
+		return fmt.Errorf("invalid user ID in ban event")
 	}
 
 	moderatorID, ok := event["moderator_id"].(string)
 	if !ok {
-		s.logger.Error("Invalid moderator ID in ban event", fmt.Errorf("invalid moderator ID"))
-		return
+		return fmt.Errorf("invalid moderator ID in ban event")
 	}
 
 	roomID, _ := event["room_id"].(string)
@@ -260,50 +268,46 @@ func (s *ModerationService) handleBanUserEvent(ctx context.Context, event map[st
 	}
 
 	// Ban the user
-	_, err := s.BanUser(ctx, userID, roomID, moderatorID, reason, duration)
-	if err != nil {
-		s.logger.Error("Failed to ban user from event", err)
+	if _, err := s.BanUser(ctx, userID, roomID, moderatorID, reason, duration); err != nil {
+		return fmt.Errorf("failed to ban user from event: %w", err)
 	}
+	return nil
 }
 
 // handleUnbanUserEvent handles an unban user event.
-func (s *ModerationService) handleUnbanUserEvent(ctx context.Context, event map[string]any) {
+func (s *ModerationService) handleUnbanUserEvent(ctx context.Context, event map[string]any) error {
 	// Extract event data
 	userID, ok := event["user_id"].(string)
 	if !ok {
-		s.logger.Error("Invalid user ID in unban event", fmt.Errorf("invalid user ID"))
-		return
+		return fmt.Errorf("invalid user ID in unban event")
 	}
 
 	moderatorID, ok := event["moderator_id"].(string)
 	if !ok {
-		s.logger.Error("Invalid moderator ID in unban event", fmt.Errorf("invalid moderator ID"))
-		return
+		return fmt.Errorf("invalid moderator ID in unban event")
 	}
 
 	roomID, _ := event["room_id"].(string)
 	reason, _ := event["reason"].(string)
 
 	// Unban the user
-	err := s.UnbanUser(ctx, userID, roomID, moderatorID, reason)
-	if err != nil {
-		s.logger.Error("Failed to unban user from event", err)
+	if err := s.UnbanUser(ctx, userID, roomID, moderatorID, reason); err != nil {
+		return fmt.Errorf("failed to unban user from event: %w", err)
 	}
+	return nil
 }
 
 // handleReportUserEvent handles a report user event.
-func (s *ModerationService) handleReportUserEvent(ctx context.Context, event map[string]any) {
+func (s *ModerationService) handleReportUserEvent(ctx context.Context, event map[string]any) error {
 	// Extract event data
 	reporterID, ok := event["reporter_id"].(string)
 	if !ok {
-		s.logger.Error("Invalid reporter ID in report event", fmt.Errorf("invalid reporter ID"))
-		return
+		return fmt.Errorf("invalid reporter ID in report event")
 	}
 
 	reportedID, ok := event["reported_id"].(string)
 	if !ok {
-		s.logger.Error("Invalid reported ID in report event", fmt.Errorf("invalid reported ID"))
-		return
+		return fmt.Errorf("invalid reported ID in report event")
 	}
 
 	roomID, _ := event["room_id"].(string)
@@ -317,10 +321,10 @@ func (s *ModerationService) handleReportUserEvent(ctx context.Context, event map
 	}
 
 	// Create the report
-	_, err := s.ReportUser(ctx, reporterID, reportedID, roomID, reason, description)
-	if err != nil {
-		s.logger.Error("Failed to create report from event", err)
+	if _, err := s.ReportUser(ctx, reporterID, reportedID, roomID, reason, description); err != nil {
+		return fmt.Errorf("failed to create report from event: %w", err)
 	}
+	return nil
 }
 
 // ReportUser creates a new user report.
@@ -374,6 +378,16 @@ func (s *ModerationService) AddReportHandler(handler func(context.Context, *User
 	s.reportHandlers = append(s.reportHandlers, handler)
 }
 
+// AddBanHandler adds a handler called after a user is banned, for example to disconnect them.
+func (s *ModerationService) AddBanHandler(handler func(context.Context, *UserBan)) {
+	s.banHandlers = append(s.banHandlers, handler)
+}
+
+// AddKickHandler adds a handler called after a user is kicked from a room.
+func (s *ModerationService) AddKickHandler(handler func(ctx context.Context, userID, roomID, reason string)) {
+	s.kickHandlers = append(s.kickHandlers, handler)
+}
+
 // GetReports retrieves reports based on the provided filter.
 func (s *ModerationService) GetReports(
 	ctx context.Context,
@@ -571,6 +585,11 @@ func (s *ModerationService) BanUser(
 	// Log moderation action
 	s.logModerationAction(ctx, ModerationActionBan, userID, moderatorID, roomID, reason, "")
 
+	// Notify ban handlers
+	for _, handler := range s.banHandlers {
+		handler(ctx, ban)
+	}
+
 	s.logger.Info("Banned user", "id", ban.ID, "user", userID, "room", roomID, "duration", duration)
 	return ban, nil
 }
@@ -966,6 +985,11 @@ func (s *ModerationService) KickUser(
 	// Log moderation action
 	s.logModerationAction(ctx, ModerationActionKick, userID, moderatorID, roomID, reason, "")
 
+	// Notify kick handlers
+	for _, handler := range s.kickHandlers {
+		handler(ctx, userID, roomID, reason)
+	}
+
 	// Notify room of kick
 	event := map[string]any{
 		"type":         "user_kicked",
@@ -1060,6 +1084,14 @@ func (s *ModerationService) logModerationAction(
 	if err != nil {
 		s.logger.Error("Failed to log moderation action", err)
 	}
+
+	// Punitive actions count against the user's trust level
+	switch action {
+	case ModerationActionWarn, ModerationActionMute, ModerationActionKick, ModerationActionBan:
+		if err := s.strikes.AddStrike(ctx, userID); err != nil {
+			s.logger.Error("Failed to record moderation strike", err, "userId", userID)
+		}
+	}
 }
 
 // GetModerationLogs retrieves moderation logs based on the provided filter.
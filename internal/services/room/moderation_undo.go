@@ -0,0 +1,370 @@
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	r "github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// muteKeyPrefix prefixes the keys of users muted in a room, expiring with the mute.
+	muteKeyPrefix = "room:mute:"
+
+	// undoKeyPrefix prefixes the keys of the last undoable action of each moderator in a room.
+	undoKeyPrefix = "moderation:undo:"
+
+	// MaxMuteDuration is the longest a user can be muted for.
+	MaxMuteDuration = 24 * time.Hour
+)
+
+// undoableAction is the last action a moderator took in a room, kept for the undo window.
+type undoableAction struct {
+	EntryID      bson.ObjectID `json:"entryId"`
+	Action       string        `json:"action"`
+	TargetUserID bson.ObjectID `json:"targetUserId"`
+	MessageID    bson.ObjectID `json:"messageId,omitempty"`
+}
+
+// UndoableModeration kicks, mutes, bans and deletes messages for room moderators. Actions take effect right
+// away, and each moderator can undo their last one in a room for a short window after taking it, for
+// the times the wrong user or message was picked. Both the action and its undo are kept in the room's
+// moderation history.
+type UndoableModeration struct {
+	roomManager *Manager
+	chat        ChatService
+	chatRepo    repositories.ChatRepository
+	historyRepo repositories.HistoryRepository
+	pubSub      *managers.PubSubManager
+	redisClient *redis.Client
+	undoWindow  time.Duration
+	logger      *utils.Logger
+
+	handlersMutex sync.RWMutex
+
+	// actionHandlers are notified of each action taken
+	actionHandlers []func(ctx context.Context, entry *models.ModerationHistory)
+
+	// undoHandlers are notified of each action undone, with the action it was
+	undoHandlers []func(ctx context.Context, entry *models.ModerationHistory, action string)
+}
+
+// NewUndoableModeration creates a new undoable moderation service. Moderators can undo their last
+// action for undoWindow after taking it.
+func NewUndoableModeration(
+	roomManager *Manager,
+	chat ChatService,
+	chatRepo repositories.ChatRepository,
+	historyRepo repositories.HistoryRepository,
+	pubSub *managers.PubSubManager,
+	redisClient *redis.Client,
+	undoWindow time.Duration,
+	logger *utils.Logger,
+) *UndoableModeration {
+	return &UndoableModeration{
+		roomManager: roomManager,
+		chat:        chat,
+		chatRepo:    chatRepo,
+		historyRepo: historyRepo,
+		pubSub:      pubSub,
+		redisClient: redisClient,
+		undoWindow:  undoWindow,
+		logger:      logger.Named("undoable_moderation"),
+	}
+}
+
+// AddActionHandler adds a handler notified of each kick, mute, ban and message deletion taken.
+func (s *UndoableModeration) AddActionHandler(handler func(ctx context.Context, entry *models.ModerationHistory)) {
+	s.handlersMutex.Lock()
+	defer s.handlersMutex.Unlock()
+	s.actionHandlers = append(s.actionHandlers, handler)
+}
+
+// AddUndoHandler adds a handler notified of each action undone, with the action it was.
+func (s *UndoableModeration) AddUndoHandler(handler func(ctx context.Context, entry *models.ModerationHistory, action string)) {
+	s.handlersMutex.Lock()
+	defer s.handlersMutex.Unlock()
+	s.undoHandlers = append(s.undoHandlers, handler)
+}
+
+// KickUser removes a user from a room. They can join again right away. Moderators need the ban
+// permission, and can only kick users whose role is below their own.
+func (s *UndoableModeration) KickUser(ctx context.Context, roomID, moderatorID, targetID bson.ObjectID, reason string) (*models.ModerationHistory, error) {
+	if _, err := s.checkModerator(ctx, roomID, moderatorID, targetID, models.RoomPermissionBan); err != nil {
+		return nil, err
+	}
+
+	inRoom, err := s.roomManager.IsUserInRoom(ctx, roomID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if !inRoom {
+		return nil, models.ErrUserNotInRoom
+	}
+
+	if err := s.roomManager.LeaveRoom(ctx, roomID, targetID); err != nil {
+		return nil, err
+	}
+
+	entry := &models.ModerationHistory{
+		RoomID:       roomID,
+		ModeratorID:  moderatorID,
+		TargetUserID: targetID,
+		Action:       "kick",
+		Reason:       reason,
+	}
+	s.record(ctx, entry)
+
+	s.logger.Info("User kicked from room", "roomId", roomID.Hex(), "userId", targetID.Hex(), "by", moderatorID.Hex())
+	return entry, nil
+}
+
+// MuteUser keeps a user from chatting in a room for a while, up to MaxMuteDuration. Moderators need
+// the chat delete permission, and can only mute users whose role is below their own.
+func (s *UndoableModeration) MuteUser(ctx context.Context, roomID, moderatorID, targetID bson.ObjectID, duration time.Duration, reason string) (*models.ModerationHistory, error) {
+	if duration <= 0 || duration > MaxMuteDuration {
+		return nil, models.ErrInvalidInput
+	}
+
+	if _, err := s.checkModerator(ctx, roomID, moderatorID, targetID, models.RoomPermissionChatDelete); err != nil {
+		return nil, err
+	}
+
+	if err := s.redisClient.Set(ctx, formatMuteKey(roomID, targetID), moderatorID.Hex(), duration); err != nil {
+		return nil, err
+	}
+
+	entry := &models.ModerationHistory{
+		RoomID:       roomID,
+		ModeratorID:  moderatorID,
+		TargetUserID: targetID,
+		Action:       "mute",
+		Reason:       reason,
+		Duration:     int(duration / time.Minute),
+		ExpiresAt:    time.Now().Add(duration),
+	}
+	s.record(ctx, entry)
+
+	s.logger.Info("User muted in room", "roomId", roomID.Hex(), "userId", targetID.Hex(), "by", moderatorID.Hex(), "duration", duration)
+	return entry, nil
+}
+
+// BanUser bans a user from a room, removing them from it if they are there. Moderators need the ban
+// permission, and can only ban users whose role is below their own.
+func (s *UndoableModeration) BanUser(ctx context.Context, roomID, moderatorID, targetID bson.ObjectID, reason string) (*models.ModerationHistory, error) {
+	if _, err := s.checkModerator(ctx, roomID, moderatorID, targetID, models.RoomPermissionBan); err != nil {
+		return nil, err
+	}
+
+	if err := s.roomManager.BanUser(ctx, roomID, targetID); err != nil {
+		return nil, err
+	}
+
+	entry := &models.ModerationHistory{
+		RoomID:       roomID,
+		ModeratorID:  moderatorID,
+		TargetUserID: targetID,
+		Action:       "ban",
+		Reason:       reason,
+	}
+	s.record(ctx, entry)
+
+	s.logger.Info("User banned from room by moderator", "roomId", roomID.Hex(), "userId", targetID.Hex(), "by", moderatorID.Hex())
+	return entry, nil
+}
+
+// UnmuteUser lets a muted user chat in a room again before their mute ends.
+func (s *UndoableModeration) UnmuteUser(ctx context.Context, roomID, moderatorID, targetID bson.ObjectID) error {
+	if _, err := s.checkModerator(ctx, roomID, moderatorID, targetID, models.RoomPermissionChatDelete); err != nil {
+		return err
+	}
+
+	if err := s.redisClient.Del(ctx, formatMuteKey(roomID, targetID)); err != nil {
+		return err
+	}
+
+	s.audit(ctx, &models.ModerationHistory{
+		RoomID:       roomID,
+		ModeratorID:  moderatorID,
+		TargetUserID: targetID,
+		Action:       "unmute",
+	})
+
+	s.logger.Info("User unmuted in room", "roomId", roomID.Hex(), "userId", targetID.Hex(), "by", moderatorID.Hex())
+	return nil
+}
+
+// DeleteMessage deletes a chat message. Deleting someone else's message is a moderation action
+// the moderator can undo, deleting their own message is not.
+func (s *UndoableModeration) DeleteMessage(ctx context.Context, roomID, messageID, userID string) error {
+	messageObjID, err := bson.ObjectIDFromHex(messageID)
+	if err != nil {
+		return models.ErrInvalidID
+	}
+
+	message, err := s.chatRepo.FindMessageByID(ctx, messageObjID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.chat.DeleteMessage(ctx, roomID, messageID, userID); err != nil {
+		return err
+	}
+
+	if message.UserID.Hex() == userID {
+		return nil
+	}
+
+	moderatorID, _ := bson.ObjectIDFromHex(userID)
+	s.record(ctx, &models.ModerationHistory{
+		RoomID:         message.RoomID,
+		ModeratorID:    moderatorID,
+		TargetUserID:   message.UserID,
+		Action:         "delete",
+		MessageID:      message.ID,
+		MessageContent: message.Content,
+	})
+	return nil
+}
+
+// UndoLast undoes the last kick, mute, ban or message deletion a moderator took in a room, if it is still
+// within the undo window. Deleted messages are restored and mutes and bans lifted, while kicked users are
+// told they can join again. It returns models.ErrNothingToUndo when there is no action left to undo.
+func (s *UndoableModeration) UndoLast(ctx context.Context, roomID, moderatorID bson.ObjectID) (*models.ModerationHistory, error) {
+	// Taking the action out first keeps it from being undone twice
+	data, err := s.redisClient.Client().GetDel(ctx, formatUndoKey(roomID, moderatorID)).Bytes()
+	if errors.Is(err, r.Nil) {
+		return nil, models.ErrNothingToUndo
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var last undoableAction
+	if err := json.Unmarshal(data, &last); err != nil {
+		return nil, err
+	}
+
+	switch last.Action {
+	case "delete":
+		if err := s.restoreMessage(ctx, roomID, last.MessageID); err != nil {
+			return nil, err
+		}
+	case "mute":
+		if err := s.redisClient.Del(ctx, formatMuteKey(roomID, last.TargetUserID)); err != nil {
+			return nil, err
+		}
+	case "ban":
+		if err := s.roomManager.UnbanUser(ctx, roomID, last.TargetUserID); err != nil {
+			return nil, err
+		}
+	}
+
+	entry := &models.ModerationHistory{
+		RoomID:       roomID,
+		ModeratorID:  moderatorID,
+		TargetUserID: last.TargetUserID,
+		Action:       "undo",
+		MessageID:    last.MessageID,
+		UndoneID:     last.EntryID,
+	}
+	s.audit(ctx, entry)
+
+	s.handlersMutex.RLock()
+	handlers := s.undoHandlers
+	s.handlersMutex.RUnlock()
+	for _, handler := range handlers {
+		handler(ctx, entry, last.Action)
+	}
+
+	s.logger.Info("Moderation action undone", "roomId", roomID.Hex(), "action", last.Action, "userId", last.TargetUserID.Hex(), "by", moderatorID.Hex())
+	return entry, nil
+}
+
+// restoreMessage restores a deleted chat message and shows it in its room again.
+func (s *UndoableModeration) restoreMessage(ctx context.Context, roomID, messageID bson.ObjectID) error {
+	if err := s.chatRepo.RestoreMessage(ctx, messageID); err != nil {
+		return err
+	}
+	s.chat.InvalidateBacklog(ctx, roomID.Hex())
+
+	message, err := s.chatRepo.FindMessageByID(ctx, messageID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.pubSub.PublishToRoom(ctx, roomID.Hex(), "chat_message_restored", message); err != nil {
+		s.logger.Error("Failed to broadcast restored message", err, "messageId", messageID.Hex())
+	}
+	return nil
+}
+
+// checkModerator gets a room, checking that the moderator has a permission in it and ranks above the
+// user they act on.
+func (s *UndoableModeration) checkModerator(ctx context.Context, roomID, moderatorID, targetID bson.ObjectID, permission string) (*models.Room, error) {
+	if moderatorID == targetID {
+		return nil, ErrNotAuthorized
+	}
+
+	room, err := s.roomManager.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !room.Can(moderatorID, permission) ||
+		models.RoomRoleRanks[room.RoleOf(moderatorID)] <= models.RoomRoleRanks[room.RoleOf(targetID)] {
+		return nil, ErrNotAuthorized
+	}
+	return room, nil
+}
+
+// record records an action in the room's moderation history, keeps it as the moderator's last action
+// for the undo window and notifies the action handlers.
+func (s *UndoableModeration) record(ctx context.Context, entry *models.ModerationHistory) {
+	s.audit(ctx, entry)
+
+	last := undoableAction{
+		EntryID:      entry.ID,
+		Action:       entry.Action,
+		TargetUserID: entry.TargetUserID,
+		MessageID:    entry.MessageID,
+	}
+	if err := s.redisClient.SetObject(ctx, formatUndoKey(entry.RoomID, entry.ModeratorID), last, s.undoWindow); err != nil {
+		s.logger.Error("Failed to keep moderation action for undo", err, "roomId", entry.RoomID.Hex(), "action", entry.Action)
+		// Continue anyway, the action was taken
+	}
+
+	s.handlersMutex.RLock()
+	handlers := s.actionHandlers
+	s.handlersMutex.RUnlock()
+	for _, handler := range handlers {
+		handler(ctx, entry)
+	}
+}
+
+// audit records a moderation action in the room's moderation history.
+func (s *UndoableModeration) audit(ctx context.Context, entry *models.ModerationHistory) {
+	if err := s.historyRepo.CreateModerationHistory(ctx, entry); err != nil {
+		s.logger.Error("Failed to record moderation history", err, "roomId", entry.RoomID.Hex(), "action", entry.Action)
+	}
+}
+
+// formatMuteKey formats the key of a user muted in a room.
+func formatMuteKey(roomID, userID bson.ObjectID) string {
+	return fmt.Sprintf("%s%s:%s", muteKeyPrefix, roomID.Hex(), userID.Hex())
+}
+
+// formatUndoKey formats the key of a moderator's last undoable action in a room.
+func formatUndoKey(roomID, moderatorID bson.ObjectID) string {
+	return fmt.Sprintf("%s%s:%s", undoKeyPrefix, roomID.Hex(), moderatorID.Hex())
+}
@@ -0,0 +1,226 @@
+package room
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+const (
+	// inFlightGrace is how long past its expected end a track still in a room's state may go on before
+	// the room counts as stuck on it, leaving time for the room's clients to advance it.
+	inFlightGrace = 30 * time.Second
+
+	// minResumeRemaining is the least time a lost track must have left to be resumed. Tracks nearly over
+	// are made up for instead.
+	minResumeRemaining = 15 * time.Second
+
+	// interruptedTurnReason is the reason recorded for DJ turns a server restart cut short.
+	interruptedTurnReason = "interrupted"
+)
+
+// PlayRecovery is what became of a track in flight when a server restart lost it.
+type PlayRecovery struct {
+	// RoomID is the ID of the room the track was playing in.
+	RoomID bson.ObjectID
+
+	// DJID is the ID of the user who was playing the track.
+	DJID bson.ObjectID
+
+	// MediaID is the ID of the track.
+	MediaID bson.ObjectID
+
+	// Resumed is whether the track resumed where it would have been. Otherwise its play was recorded
+	// as interrupted and the DJ was put first in the queue.
+	Resumed bool
+}
+
+// RecordInFlightPlay stores the track a room's state is playing on the room, or clears it when the state
+// plays nothing or plays until it is replaced.
+func (m *Manager) RecordInFlightPlay(ctx context.Context, roomID bson.ObjectID, state *models.RoomState) error {
+	var play *models.InFlightPlay
+	if state.CurrentDJ != nil && state.CurrentMedia != nil && !state.MediaEndTime.IsZero() {
+		play = &models.InFlightPlay{
+			ID:          bson.NewObjectID(),
+			DJ:          *state.CurrentDJ,
+			Media:       *state.CurrentMedia,
+			StartTime:   state.MediaStartTime,
+			ExpectedEnd: state.MediaEndTime,
+		}
+	}
+	return m.roomRepo.SetInFlightPlay(ctx, roomID, play)
+}
+
+// InFlightPlays gets the open rooms with a track in flight. Their state and DJ queue are loaded into
+// Redis first, rebuilt from the stored rooms if Redis lost them.
+func (m *Manager) InFlightPlays(ctx context.Context) ([]*models.Room, error) {
+	rooms, err := m.roomRepo.FindMany(ctx, bson.M{"inFlightPlay": bson.M{"$exists": true}, "isActive": true}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, room := range rooms {
+		if err := m.stateManager.InitRoom(ctx, room.ID.Hex()); err != nil {
+			m.logger.Error("Failed to initialize room state for play recovery", err, "roomId", room.ID.Hex())
+		}
+	}
+	return rooms, nil
+}
+
+// TakeInFlightPlay clears a room's track in flight if it is still the given play, reporting whether it
+// was, so only one instance recovers it.
+func (m *Manager) TakeInFlightPlay(ctx context.Context, roomID, playID bson.ObjectID) (bool, error) {
+	return m.roomRepo.TakeInFlightPlay(ctx, roomID, playID)
+}
+
+// AddPlayRecoveryHandler adds a handler called when a track lost to a server restart is recovered, with
+// the room's state after it.
+func (m *QueueManager) AddPlayRecoveryHandler(handler func(ctx context.Context, recovery PlayRecovery, state *models.RoomState)) {
+	m.playRecoveryHandlers = append(m.playRecoveryHandlers, handler)
+}
+
+// recordInFlight stores the track a room's state is playing, so it can be recovered if a server restart
+// loses it. Playback goes on even if it can't be stored.
+func (m *QueueManager) recordInFlight(ctx context.Context, roomID bson.ObjectID, state *models.RoomState) {
+	if err := m.roomManager.RecordInFlightPlay(ctx, roomID, state); err != nil {
+		m.logger.Error("Failed to store track in flight", err, "roomId", roomID.Hex())
+	}
+}
+
+// RecoverInFlightPlays recovers the tracks that were playing when servers restarted. A track the room's
+// state lost is resumed at the offset it would have reached if enough of it is left. Otherwise, and for
+// rooms that stayed stuck on a track past its end, the play is recorded as interrupted and the DJ is put
+// first in the queue to play again. Tracks still playing are left alone.
+func (m *QueueManager) RecoverInFlightPlays(ctx context.Context) error {
+	rooms, err := m.roomManager.InFlightPlays(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, room := range rooms {
+		if room.InFlightPlay == nil {
+			continue
+		}
+		if err := m.recoverPlay(ctx, room.ID, room.InFlightPlay); err != nil {
+			m.logger.Error("Failed to recover track in flight", err, "roomId", room.ID.Hex(), "mediaId", room.InFlightPlay.Media.ID.Hex())
+		}
+	}
+
+	return nil
+}
+
+// recoverPlay resumes or makes up for a room's track in flight if the room lost it.
+func (m *QueueManager) recoverPlay(ctx context.Context, roomID bson.ObjectID, play *models.InFlightPlay) error {
+	recovery, state, err := m.recoverPlayState(ctx, roomID, play)
+	if err != nil || state == nil {
+		return err
+	}
+
+	// The DJ's make-up turn starts right away
+	if !recovery.Resumed && state.CurrentDJ == nil && len(state.DJQueue) > 0 {
+		if state, err = m.AdvanceQueue(ctx, roomID); err != nil {
+			return err
+		}
+	}
+
+	m.logger.Info("Recovered track in flight", "roomId", roomID.Hex(), "djId", recovery.DJID.Hex(),
+		"mediaId", recovery.MediaID.Hex(), "resumed", recovery.Resumed)
+	for _, handler := range m.playRecoveryHandlers {
+		handler(ctx, recovery, state)
+	}
+	return nil
+}
+
+// recoverPlayState restores a room's lost track in its state, or ends it and puts its DJ first in the
+// queue. It returns a nil state when the room didn't lose the track or another instance recovered it.
+func (m *QueueManager) recoverPlayState(ctx context.Context, roomID bson.ObjectID, play *models.InFlightPlay) (PlayRecovery, *models.RoomState, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	recovery := PlayRecovery{RoomID: roomID, DJID: play.DJ.ID, MediaID: play.Media.ID}
+
+	state, err := m.roomManager.GetRoomState(ctx, roomID)
+	if err != nil {
+		return recovery, nil, err
+	}
+
+	now := time.Now()
+	kept := state.CurrentMedia != nil && state.CurrentMedia.ID == play.Media.ID &&
+		state.MediaStartTime.Sub(play.StartTime).Abs() < time.Second
+	if kept && now.Before(play.ExpectedEnd.Add(inFlightGrace)) {
+		return recovery, nil, nil
+	}
+
+	taken, err := m.roomManager.TakeInFlightPlay(ctx, roomID, play.ID)
+	if err != nil || !taken {
+		return recovery, nil, err
+	}
+
+	if !kept && play.ExpectedEnd.Sub(now) > minResumeRemaining && m.resumable(ctx, play) {
+		state.CurrentDJ = &play.DJ
+		state.CurrentMedia = &play.Media
+		state.MediaStartTime = play.StartTime
+		state.MediaEndTime = play.ExpectedEnd
+		state.MediaProgress = int(now.Sub(play.StartTime).Seconds())
+
+		if err := m.roomManager.UpdateRoomState(ctx, roomID, state); err != nil {
+			return recovery, nil, err
+		}
+		m.transitions.expect(roomID, state.MediaEndTime)
+		m.scheduleCutoff(roomID, state)
+		m.recordInFlight(ctx, roomID, state)
+		if err := m.history.Resume(ctx, roomID, play); err != nil {
+			m.logger.Error("Failed to relink resumed play", err, "roomId", roomID.Hex())
+		}
+
+		recovery.Resumed = true
+		return recovery, state, nil
+	}
+
+	if err := m.history.Interrupt(ctx, roomID, play); err != nil {
+		m.logger.Error("Failed to record interrupted play", err, "roomId", roomID.Hex())
+	}
+	if err := m.history.EndTurn(ctx, roomID, interruptedTurnReason); err != nil {
+		m.logger.Error("Failed to record end of interrupted DJ turn", err, "roomId", roomID.Hex())
+	}
+
+	// The DJ plays again first, unless they left the queue or were playing a crowd pick
+	index := slices.IndexFunc(state.DJQueue, func(entry models.QueueEntry) bool {
+		return entry.User.ID == play.DJ.ID
+	})
+	if index >= 0 && !isCrowdPick(&play.Media) {
+		entry := state.DJQueue[index]
+		entry.TurnPlays = 0
+		entry.WaitingSince = now
+		state.DJQueue = slices.Insert(slices.Delete(state.DJQueue, index, index+1), 0, entry)
+		for i := range state.DJQueue {
+			state.DJQueue[i].Position = i
+		}
+	}
+
+	state.CurrentDJ = nil
+	state.CurrentMedia = nil
+	state.MediaStartTime = time.Time{}
+	state.MediaProgress = 0
+	state.MediaEndTime = time.Time{}
+
+	if err := m.roomManager.UpdateRoomState(ctx, roomID, state); err != nil {
+		return recovery, nil, err
+	}
+	m.transitions.expect(roomID, time.Time{})
+
+	return recovery, state, nil
+}
+
+// resumable checks that a lost track can still be played.
+func (m *QueueManager) resumable(ctx context.Context, play *models.InFlightPlay) bool {
+	takenDown, err := m.isTakenDown(ctx, &play.Media)
+	if err != nil {
+		m.logger.Error("Failed to check takedown of lost track", err, "mediaId", play.Media.ID.Hex())
+		return false
+	}
+	return !takenDown
+}
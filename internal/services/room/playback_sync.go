@@ -0,0 +1,100 @@
+package room
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// PlaybackSyncer periodically tells the rooms followed by live connections to this instance where their
+// current media is by the server's clock, so clients whose playback drifted can catch up. Each instance
+// only syncs the rooms its own connections follow.
+type PlaybackSyncer struct {
+	stateManager *managers.RoomStateManager
+	live         RoomHeartbeatSource
+	interval     time.Duration
+	logger       *utils.Logger
+
+	handlersMutex sync.RWMutex
+
+	// syncHandlers are given the playback sync of each room playing media
+	syncHandlers []func(ctx context.Context, playback models.PlaybackSync)
+}
+
+// NewPlaybackSyncer creates a new playback syncer, syncing every interval.
+func NewPlaybackSyncer(stateManager *managers.RoomStateManager, live RoomHeartbeatSource, interval time.Duration, logger *utils.Logger) *PlaybackSyncer {
+	return &PlaybackSyncer{
+		stateManager: stateManager,
+		live:         live,
+		interval:     interval,
+		logger:       logger.Named("playback_syncer"),
+	}
+}
+
+// AddSyncHandler adds a handler given the playback sync of each room playing media, on every sync.
+func (s *PlaybackSyncer) AddSyncHandler(handler func(ctx context.Context, playback models.PlaybackSync)) {
+	s.handlersMutex.Lock()
+	defer s.handlersMutex.Unlock()
+	s.syncHandlers = append(s.syncHandlers, handler)
+}
+
+// Start starts syncing the playback of the rooms in use.
+func (s *PlaybackSyncer) Start(ctx context.Context) {
+	if s.interval <= 0 {
+		s.logger.Info("Playback sync is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				s.logger.Info("Stopping playback syncer")
+				return
+			case <-ticker.C:
+				s.Sync(ctx)
+			}
+		}
+	}()
+
+	s.logger.Info("Playback syncer started", "interval", s.interval)
+}
+
+// Sync hands the playback sync of every room playing media and followed by a live connection to this
+// instance to the sync handlers.
+func (s *PlaybackSyncer) Sync(ctx context.Context) {
+	s.handlersMutex.RLock()
+	handlers := s.syncHandlers
+	s.handlersMutex.RUnlock()
+
+	synced := 0
+	for roomID := range s.live.RoomSubscriptions() {
+		if err := ctx.Err(); err != nil {
+			return
+		}
+
+		mediaID, startTime, endTime, err := s.stateManager.GetCurrentMedia(ctx, roomID)
+		if err != nil {
+			s.logger.Error("Failed to get current media for playback sync", err, "roomId", roomID)
+			continue
+		}
+		if mediaID == "" {
+			continue
+		}
+
+		playback := models.NewPlaybackSync(roomID, mediaID, startTime, endTime, time.Now())
+		for _, handler := range handlers {
+			handler(ctx, playback)
+		}
+		synced++
+	}
+
+	s.logger.Debug("Synced room playback", "rooms", synced)
+}
@@ -0,0 +1,224 @@
+package room
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// PopupPolicy controls the lifetime of pop-up rooms.
+type PopupPolicy struct {
+	// MaxLifetime is the longest lifetime a pop-up room can be created with.
+	MaxLifetime time.Duration
+
+	// ReminderBefore is how long before a pop-up room expires its users are reminded.
+	ReminderBefore time.Duration
+
+	// CheckInterval is how often pop-up rooms are checked for expiry.
+	CheckInterval time.Duration
+}
+
+// initExpiry validates a new pop-up room's expiry configuration and sets its deadlines.
+func (p PopupPolicy) initExpiry(expiry *models.RoomExpiry, now time.Time) error {
+	if expiry.LifetimeHours < 0 || expiry.EmptyMinutes < 0 {
+		return models.NewRoomError(models.ErrInvalidRoomExpiry, "Expiry times cannot be negative", http.StatusBadRequest)
+	}
+	if expiry.LifetimeHours == 0 && expiry.EmptyMinutes == 0 {
+		return models.NewRoomError(models.ErrInvalidRoomExpiry, "A pop-up room needs a lifetime or an empty timeout", http.StatusBadRequest)
+	}
+
+	lifetime := time.Duration(expiry.LifetimeHours) * time.Hour
+	if p.MaxLifetime > 0 && lifetime > p.MaxLifetime {
+		return models.NewRoomError(models.ErrInvalidRoomExpiry, "Pop-up room lifetime is too long", http.StatusBadRequest)
+	}
+
+	expiry.ExpiresAt = time.Time{}
+	if lifetime > 0 {
+		expiry.ExpiresAt = now.Add(lifetime)
+	}
+	// The room starts empty, the creator joining clears this on the next check
+	expiry.EmptySince = now
+	expiry.RemindedFor = time.Time{}
+	return nil
+}
+
+// fillExpiry adds a pop-up room's expiry to its state.
+func (m *Manager) fillExpiry(ctx context.Context, state *models.RoomState) {
+	room, err := m.GetRoom(ctx, state.ID)
+	if err != nil {
+		m.logger.Error("Failed to get room for expiry", err, "roomId", state.ID.Hex())
+		return
+	}
+	if room.Expiry == nil {
+		return
+	}
+
+	state.Expiry = room.Expiry
+	state.ExpiresAt = room.Expiry.Deadline()
+}
+
+// PopupService deletes pop-up rooms once they expire and reminds their users beforehand.
+type PopupService struct {
+	roomManager *Manager
+	pubSub      *managers.PubSubManager
+	policy      PopupPolicy
+	logger      *utils.Logger
+}
+
+// NewPopupService creates a new pop-up room service.
+func NewPopupService(roomManager *Manager, pubSub *managers.PubSubManager, policy PopupPolicy, logger *utils.Logger) *PopupService {
+	return &PopupService{
+		roomManager: roomManager,
+		pubSub:      pubSub,
+		policy:      policy,
+		logger:      logger.Named("popup_service"),
+	}
+}
+
+// Start begins checking pop-up rooms for expiry.
+func (s *PopupService) Start(ctx context.Context) {
+	if s.policy.CheckInterval <= 0 {
+		s.logger.Info("Pop-up room expiry is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.policy.CheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				s.logger.Info("Stopping pop-up room service")
+				return
+			case <-ticker.C:
+				if err := s.CheckRooms(ctx); err != nil {
+					s.logger.Error("Failed to check pop-up rooms", err)
+				}
+			}
+		}
+	}()
+
+	s.logger.Info("Pop-up room service started", "interval", s.policy.CheckInterval)
+}
+
+// CheckRooms updates every pop-up room's empty timer, sends due reminders and deletes expired rooms.
+func (s *PopupService) CheckRooms(ctx context.Context) error {
+	rooms, err := s.roomManager.roomRepo.FindMany(ctx, bson.M{"expiry": bson.M{"$exists": true}}, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, room := range rooms {
+		if room.Expiry == nil {
+			continue
+		}
+		if err := s.checkRoom(ctx, room); err != nil {
+			s.logger.Error("Failed to check pop-up room", err, "roomId", room.ID.Hex())
+		}
+	}
+
+	return nil
+}
+
+// checkRoom handles the expiry of a single pop-up room.
+func (s *PopupService) checkRoom(ctx context.Context, room *models.Room) error {
+	roomID := room.ID.Hex()
+	expiry := room.Expiry
+	now := time.Now()
+	changed := false
+
+	if expiry.EmptyMinutes > 0 {
+		empty, err := s.isEmpty(ctx, roomID)
+		if err != nil {
+			return err
+		}
+		switch {
+		case empty && expiry.EmptySince.IsZero():
+			expiry.EmptySince = now
+			changed = true
+		case !empty && !expiry.EmptySince.IsZero():
+			expiry.EmptySince = time.Time{}
+			changed = true
+		}
+	}
+
+	deadline := expiry.Deadline()
+	if deadline.IsZero() {
+		return s.save(ctx, room, changed)
+	}
+
+	if !now.Before(deadline) {
+		return s.expire(ctx, room)
+	}
+
+	// Remind once per deadline, a room that filled up again gets a fresh reminder when it next runs low
+	if deadline.Sub(now) <= s.policy.ReminderBefore && !expiry.RemindedFor.Equal(deadline) {
+		err := s.pubSub.PublishToRoom(ctx, roomID, "room_expiring", map[string]any{
+			"roomId":    roomID,
+			"expiresAt": deadline,
+		})
+		if err != nil {
+			s.logger.Error("Failed to broadcast pop-up room reminder", err, "roomId", roomID)
+		} else {
+			expiry.RemindedFor = deadline
+			changed = true
+		}
+	}
+
+	return s.save(ctx, room, changed)
+}
+
+// isEmpty checks whether a room has neither participants nor overflow listeners.
+func (s *PopupService) isEmpty(ctx context.Context, roomID string) (bool, error) {
+	users, err := s.roomManager.stateManager.GetRoomUsers(ctx, roomID)
+	if err != nil {
+		return false, err
+	}
+	if len(users) > 0 {
+		return false, nil
+	}
+
+	listeners, err := s.roomManager.stateManager.CountRoomListeners(ctx, roomID)
+	if err != nil {
+		return false, err
+	}
+	return listeners == 0, nil
+}
+
+// save stores a pop-up room's updated expiry.
+func (s *PopupService) save(ctx context.Context, room *models.Room, changed bool) error {
+	if !changed {
+		return nil
+	}
+	if err := s.roomManager.roomRepo.Update(ctx, room); err != nil {
+		return err
+	}
+	s.roomManager.InvalidateLobby(ctx)
+	return nil
+}
+
+// expire tells a pop-up room's users it has closed and deletes it.
+func (s *PopupService) expire(ctx context.Context, room *models.Room) error {
+	roomID := room.ID.Hex()
+
+	err := s.pubSub.PublishToRoom(ctx, roomID, "room_expired", map[string]any{
+		"roomId": roomID,
+	})
+	if err != nil {
+		s.logger.Error("Failed to broadcast pop-up room expiry", err, "roomId", roomID)
+		// Continue anyway, the room still has to go
+	}
+
+	if err := s.roomManager.DeleteRoom(ctx, room.ID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Pop-up room expired", "roomId", roomID, "slug", room.Slug)
+	return nil
+}
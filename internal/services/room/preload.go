@@ -0,0 +1,186 @@
+package room
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+const (
+	// preloadPositions is how many DJs at the front of a queue get their next track hinted.
+	preloadPositions = 2
+
+	// preloadTimeout is how long resolving the tracks hinted to a room may take.
+	preloadTimeout = 10 * time.Second
+)
+
+// TrackPreload is the track a DJ about to play is expected to play, hinted to their room's clients so
+// they can buffer it ahead and the room isn't silent between tracks. The hint is the DJ's next planned
+// track that can be played, or else the first track of their active playlist that can; a DJ picking
+// another track when their turn comes makes the hint moot.
+type TrackPreload struct {
+	// DJID is the DJ expected to play the track.
+	DJID bson.ObjectID
+
+	// Position is how many DJs play before this one, 0 for the next DJ.
+	Position int
+
+	// Media is the track with the stream metadata clients buffer it with.
+	Media *models.MediaInfo
+
+	// ExpectedStart is when the track is expected to start, zero if it isn't known.
+	ExpectedStart time.Time
+}
+
+// trackPreloads are the tracks last hinted to each room, so unchanged hints aren't sent again.
+type trackPreloads struct {
+	mu     sync.Mutex
+	hinted map[bson.ObjectID]string
+}
+
+// changed records the tracks hinted to a room and checks whether they differ from the ones hinted before.
+func (p *trackPreloads) changed(roomID bson.ObjectID, preloads []TrackPreload) bool {
+	var hints strings.Builder
+	for _, preload := range preloads {
+		fmt.Fprintf(&hints, "%s:%s:%d;", preload.DJID.Hex(), preload.Media.ID.Hex(), preload.ExpectedStart.Unix())
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	previous, ok := p.hinted[roomID]
+	if (!ok && len(preloads) == 0) || (ok && previous == hints.String()) {
+		return false
+	}
+	if len(preloads) == 0 {
+		delete(p.hinted, roomID)
+	} else {
+		p.hinted[roomID] = hints.String()
+	}
+	return true
+}
+
+// AddPreloadHandler adds a handler called with the tracks the DJs at the front of a room's queue are
+// expected to play next, whenever they change. An empty list withdraws the previous hints.
+func (m *QueueManager) AddPreloadHandler(handler func(ctx context.Context, roomID bson.ObjectID, preloads []TrackPreload)) {
+	m.preloadHandlers = append(m.preloadHandlers, handler)
+}
+
+// hintPreloads hints the next tracks of the DJs at the front of a room's queue to the preload handlers.
+// The tracks are resolved in the background, so the queue change or track start isn't held up.
+func (m *QueueManager) hintPreloads(roomID bson.ObjectID, roomState *models.RoomState) {
+	if len(m.preloadHandlers) == 0 {
+		return
+	}
+
+	upcoming := make([]bson.ObjectID, 0, preloadPositions)
+	for _, entry := range roomState.DJQueue {
+		if len(upcoming) == preloadPositions {
+			break
+		}
+		if roomState.CurrentDJ != nil && entry.User.ID == roomState.CurrentDJ.ID {
+			continue
+		}
+		upcoming = append(upcoming, entry.User.ID)
+	}
+	settings := roomState.Settings
+	expectedStart := roomState.MediaEndTime
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), preloadTimeout)
+		defer cancel()
+
+		preloads := make([]TrackPreload, 0, len(upcoming))
+		for position, djID := range upcoming {
+			media, err := m.nextTrack(ctx, roomID, settings, djID)
+			if err != nil {
+				m.logger.Error("Failed to resolve track to preload", err, "roomId", roomID.Hex(), "userId", djID.Hex())
+				continue
+			}
+			if media == nil {
+				continue
+			}
+
+			preload := TrackPreload{DJID: djID, Position: position, Media: media}
+			if position == 0 {
+				preload.ExpectedStart = expectedStart
+			}
+			preloads = append(preloads, preload)
+		}
+
+		if !m.preloads.changed(roomID, preloads) {
+			return
+		}
+		for _, handler := range m.preloadHandlers {
+			handler(ctx, roomID, preloads)
+		}
+	}()
+}
+
+// nextTrack resolves the track a DJ is expected to play next in a room: their first planned track that
+// can still be played, or else the first track of their active playlist that can. It returns nil when
+// they have nothing to play.
+func (m *QueueManager) nextTrack(ctx context.Context, roomID bson.ObjectID, settings models.RoomSettings, djID bson.ObjectID) (*models.MediaInfo, error) {
+	playlist, err := m.playlists.GetActivePlaylist(ctx, djID)
+	if err != nil {
+		if errors.Is(err, models.ErrPlaylistNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	planned, err := m.planner.GetUpNext(ctx, roomID, djID)
+	if err != nil {
+		return nil, err
+	}
+	for _, mediaID := range planned {
+		// Planned tracks that can no longer be played are dropped when the turn comes
+		if media, err := m.checkPlannedMedia(ctx, settings, playlist, djID, mediaID); err == nil {
+			return m.preloadInfo(ctx, settings, media), nil
+		}
+	}
+
+	if len(playlist.Items) == 0 {
+		return nil, nil
+	}
+	mediaIDs := make([]bson.ObjectID, len(playlist.Items))
+	for i, item := range playlist.Items {
+		mediaIDs[i] = item.MediaID
+	}
+	found, err := m.mediaRepo.FindMany(ctx, bson.M{"_id": bson.M{"$in": mediaIDs}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	media := make(map[bson.ObjectID]*models.Media, len(found))
+	for _, item := range found {
+		media[item.ID] = item
+	}
+
+	for _, item := range playlist.Items {
+		candidate := media[item.MediaID]
+		if candidate == nil || !item.TakedownID.IsZero() || playableInRoom(settings, candidate) != nil {
+			continue
+		}
+		if m.trustPolicy.IsLongTrack(candidate.Duration) &&
+			m.trustPolicy.CheckAbility(ctx, djID.Hex(), models.TrustAbilityQueueLongTracks) != nil {
+			continue
+		}
+		return m.preloadInfo(ctx, settings, candidate), nil
+	}
+	return nil, nil
+}
+
+// preloadInfo gets the stream metadata of a track to preload, with the volume adjustment it will play with.
+func (m *QueueManager) preloadInfo(ctx context.Context, settings models.RoomSettings, media *models.Media) *models.MediaInfo {
+	info := media.ToMediaInfo(nil)
+	if settings.NormalizeVolume && media.Metadata.Loudness != nil {
+		info.Normalization = m.normalization.hint(media.Metadata.Loudness)
+	}
+	return info
+}
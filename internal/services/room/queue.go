@@ -11,23 +11,153 @@ import (
 	"slices"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
 	"norelock.dev/listenify/backend/internal/models"
 	"norelock.dev/listenify/backend/internal/utils"
 )
 
+// PlaylistSource provides the active playlists DJs play from.
+type PlaylistSource interface {
+	// GetActivePlaylist gets the user's active playlist.
+	GetActivePlaylist(ctx context.Context, userID bson.ObjectID) (*models.Playlist, error)
+}
+
+// NormalizationPolicy controls the volume normalization hints added to now-playing media.
+type NormalizationPolicy struct {
+	// TargetLUFS is the loudness the suggested gain brings tracks to.
+	TargetLUFS float64
+
+	// MaxGain is the largest gain in dB suggested in either direction. Zero means no limit.
+	MaxGain float64
+}
+
+// hint returns the normalization hint for media with known loudness.
+func (p NormalizationPolicy) hint(loudness *models.MediaLoudness) *models.NormalizationHint {
+	return &models.NormalizationHint{
+		GainDB:       loudness.SuggestedGain(p.TargetLUFS, p.MaxGain),
+		LoudnessLUFS: loudness.IntegratedLUFS,
+		TargetLUFS:   p.TargetLUFS,
+	}
+}
+
 // QueueManager handles DJ queue operations for a room.
 type QueueManager struct {
-	roomManager RoomManager
-	logger      *utils.Logger
-	mutex       sync.RWMutex
+	roomManager   RoomManager
+	playlists     PlaylistSource
+	mediaRepo     repositories.MediaRepository
+	trustPolicy   TrustPolicy
+	normalization NormalizationPolicy
+	history       *HistoryRecorder
+	planner       *SetPlanner
+	transitions   *TransitionMonitor
+	logger        *utils.Logger
+	mutex         sync.RWMutex
+
+	// turnSkipHandlers are notified when a DJ loses their turn because they can no longer play
+	turnSkipHandlers []func(ctx context.Context, roomID, userID bson.ObjectID, reason error)
+
+	// voteSkipHandlers are notified when media is skipped because its room voted it down
+	voteSkipHandlers []func(ctx context.Context, vote SkipVote, state *models.RoomState)
+
+	// crowdPickHandlers are notified when a crowd pick starts playing
+	crowdPickHandlers []func(ctx context.Context, roomID bson.ObjectID, state *models.RoomState)
+
+	// playRecoveryHandlers are notified when a track lost to a server restart is recovered
+	playRecoveryHandlers []func(ctx context.Context, recovery PlayRecovery, state *models.RoomState)
+
+	// truncateHandlers are notified when a track is cut off at its room's maximum track length
+	truncateHandlers []func(ctx context.Context, truncation TrackTruncation, state *models.RoomState)
+
+	// preloadHandlers are notified of the tracks the DJs at the front of a queue are expected to play next
+	preloadHandlers []func(ctx context.Context, roomID bson.ObjectID, preloads []TrackPreload)
+
+	// cutoffs end the tracks that play past their room's maximum track length
+	cutoffs trackCutoffs
+
+	// preloads are the tracks last hinted to each room
+	preloads trackPreloads
 }
 
 // NewQueueManager creates a new QueueManager.
-func NewQueueManager(roomManager RoomManager, logger *utils.Logger) *QueueManager {
+func NewQueueManager(
+	roomManager RoomManager,
+	playlists PlaylistSource,
+	mediaRepo repositories.MediaRepository,
+	trustPolicy TrustPolicy,
+	normalization NormalizationPolicy,
+	history *HistoryRecorder,
+	planner *SetPlanner,
+	transitions *TransitionMonitor,
+	logger *utils.Logger,
+) *QueueManager {
 	return &QueueManager{
-		roomManager: roomManager,
-		logger:      logger,
+		roomManager:   roomManager,
+		playlists:     playlists,
+		mediaRepo:     mediaRepo,
+		trustPolicy:   trustPolicy,
+		normalization: normalization,
+		history:       history,
+		planner:       planner,
+		transitions:   transitions,
+		logger:        logger,
+		cutoffs:       trackCutoffs{timers: make(map[bson.ObjectID]*time.Timer)},
+		preloads:      trackPreloads{hinted: make(map[bson.ObjectID]string)},
+	}
+}
+
+// AddTurnSkipHandler registers a handler called when a DJ's turn is skipped because they failed validation.
+func (m *QueueManager) AddTurnSkipHandler(handler func(ctx context.Context, roomID, userID bson.ObjectID, reason error)) {
+	m.turnSkipHandlers = append(m.turnSkipHandlers, handler)
+}
+
+// ValidateDJ checks that a user has an active playlist with at least one item playable in the room.
+// It returns models.ErrNoActivePlaylist, models.ErrPlaylistEmpty, models.ErrNoPlayableItems
+// or models.ErrAllItemsTooLong describing why the user cannot DJ.
+func (m *QueueManager) ValidateDJ(ctx context.Context, settings models.RoomSettings, userID bson.ObjectID) error {
+	playlist, err := m.playlists.GetActivePlaylist(ctx, userID)
+	if err != nil {
+		if errors.Is(err, models.ErrPlaylistNotFound) {
+			return models.ErrNoActivePlaylist
+		}
+		return err
+	}
+
+	if len(playlist.Items) == 0 {
+		return models.ErrPlaylistEmpty
+	}
+
+	mediaIDs := make([]bson.ObjectID, len(playlist.Items))
+	for i, item := range playlist.Items {
+		mediaIDs[i] = item.MediaID
+	}
+
+	media, err := m.mediaRepo.FindMany(ctx, bson.M{"_id": bson.M{"$in": mediaIDs}}, nil)
+	if err != nil {
+		return err
+	}
+
+	tooLong := 0
+	for _, item := range media {
+		if !item.TakedownID.IsZero() {
+			continue
+		}
+		if len(settings.AllowedSources) > 0 && !slices.Contains(settings.AllowedSources, item.Type) {
+			continue
+		}
+		if item.Metadata.AgeRestricted && !settings.AllowAgeRestricted {
+			continue
+		}
+		if settings.MaxSongLength > 0 && item.Duration > settings.MaxSongLength {
+			tooLong++
+			continue
+		}
+		return nil // Found a playable item
+	}
+
+	if tooLong > 0 {
+		return models.ErrAllItemsTooLong
 	}
+	return models.ErrNoPlayableItems
 }
 
 // AddToQueue adds a user to the DJ queue.
@@ -35,6 +165,24 @@ func (m *QueueManager) AddToQueue(ctx context.Context, roomID, userID bson.Objec
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
+	// Overflow listeners cannot DJ until they are promoted
+	listenerOnly, err := m.roomManager.IsListenerOnly(ctx, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if listenerOnly {
+		return nil, models.ErrListenerOnly
+	}
+
+	// Locked queues are only open to roles with the queue lock permission
+	room, err := m.roomManager.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if room.QueueLocked && !room.Can(userID, models.RoomPermissionQueueLock) {
+		return nil, models.ErrQueueLocked
+	}
+
 	// Get room state
 	roomState, err := m.roomManager.GetRoomState(ctx, roomID)
 	if err != nil {
@@ -53,6 +201,11 @@ func (m *QueueManager) AddToQueue(ctx context.Context, roomID, userID bson.Objec
 		return nil, errors.New("queue is full")
 	}
 
+	// Check the user has something to play
+	if err := m.ValidateDJ(ctx, roomState.Settings, userID); err != nil {
+		return nil, err
+	}
+
 	// Check if user is in the room
 	var user *models.PublicUser
 	for i, u := range roomState.Users {
@@ -65,16 +218,24 @@ func (m *QueueManager) AddToQueue(ctx context.Context, roomID, userID bson.Objec
 		return nil, errors.New("user is not in the room")
 	}
 
-	// Add user to queue
+	// Add user to queue. The DJ playing went to the back of the queue when their turn began, users
+	// joining during it wait in front of them.
 	position := len(roomState.DJQueue)
+	if last := position - 1; last >= 0 && roomState.CurrentDJ != nil && roomState.DJQueue[last].User.ID == roomState.CurrentDJ.ID {
+		position = last
+	}
 	entry := models.QueueEntry{
-		User:      *user,
-		Position:  position,
-		JoinTime:  time.Now(),
-		PlayCount: 0,
-		JoinedAt:  time.Now(),
+		User:         *user,
+		Position:     position,
+		JoinTime:     time.Now(),
+		PlayCount:    0,
+		JoinedAt:     time.Now(),
+		WaitingSince: time.Now(),
+	}
+	roomState.DJQueue = slices.Insert(roomState.DJQueue, position, entry)
+	for i := position; i < len(roomState.DJQueue); i++ {
+		roomState.DJQueue[i].Position = i
 	}
-	roomState.DJQueue = append(roomState.DJQueue, entry)
 
 	// Update room state
 	err = m.roomManager.UpdateRoomState(ctx, roomID, roomState)
@@ -84,9 +245,10 @@ func (m *QueueManager) AddToQueue(ctx context.Context, roomID, userID bson.Objec
 
 	// If there's no current DJ and this is the first person in the queue, make them the DJ
 	if roomState.CurrentDJ == nil && len(roomState.DJQueue) == 1 {
-		return m.AdvanceQueue(ctx, roomID)
+		return m.advanceLocked(ctx, roomID, playEnd{})
 	}
 
+	m.hintPreloads(roomID, roomState)
 	return roomState, nil
 }
 
@@ -113,8 +275,11 @@ func (m *QueueManager) RemoveFromQueue(ctx context.Context, roomID, userID bson.
 		return nil, errors.New("user is not in the queue")
 	}
 
-	// Remove user from queue
+	// Remove user from queue, their plan goes with them
 	roomState.DJQueue = slices.Delete(roomState.DJQueue, index, index+1)
+	if err := m.planner.Clear(ctx, roomID, userID); err != nil {
+		m.logger.Error("Failed to clear planned tracks", err, "roomId", roomID.Hex(), "userId", userID.Hex())
+	}
 
 	// Update positions for remaining users
 	for i := index; i < len(roomState.DJQueue); i++ {
@@ -129,12 +294,50 @@ func (m *QueueManager) RemoveFromQueue(ctx context.Context, roomID, userID bson.
 
 	// If the current DJ was removed, advance to the next DJ
 	if roomState.CurrentDJ != nil && roomState.CurrentDJ.ID == userID {
-		return m.AdvanceQueue(ctx, roomID)
+		return m.advanceLocked(ctx, roomID, playEnd{})
 	}
 
+	m.hintPreloads(roomID, roomState)
 	return roomState, nil
 }
 
+// SetUpNext replaces the tracks a user plans to play next when their turn comes, in order.
+// The tracks must be in the user's active playlist; an empty list clears the plan.
+func (m *QueueManager) SetUpNext(ctx context.Context, roomID, userID bson.ObjectID, mediaIDs []bson.ObjectID) ([]bson.ObjectID, error) {
+	inRoom, err := m.roomManager.IsUserInRoom(ctx, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !inRoom {
+		return nil, models.ErrUserNotInRoom
+	}
+
+	// Overflow listeners cannot DJ until they are promoted
+	listenerOnly, err := m.roomManager.IsListenerOnly(ctx, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if listenerOnly {
+		return nil, models.ErrListenerOnly
+	}
+
+	planned, err := m.planner.SetUpNext(ctx, roomID, userID, mediaIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// The DJ's next track changed if they are about to play
+	if roomState, err := m.roomManager.GetRoomState(ctx, roomID); err == nil {
+		m.hintPreloads(roomID, roomState)
+	}
+	return planned, nil
+}
+
+// GetUpNext gets the tracks a user plans to play next when their turn comes, in order.
+func (m *QueueManager) GetUpNext(ctx context.Context, roomID, userID bson.ObjectID) ([]bson.ObjectID, error) {
+	return m.planner.GetUpNext(ctx, roomID, userID)
+}
+
 // MoveInQueue moves a user to a new position in the DJ queue.
 func (m *QueueManager) MoveInQueue(ctx context.Context, roomID, userID bson.ObjectID, newPosition int) (*models.RoomState, error) {
 	m.mutex.Lock()
@@ -181,6 +384,7 @@ func (m *QueueManager) MoveInQueue(ctx context.Context, roomID, userID bson.Obje
 		return nil, err
 	}
 
+	m.hintPreloads(roomID, roomState)
 	return roomState, nil
 }
 
@@ -195,9 +399,28 @@ func (m *QueueManager) GetQueue(ctx context.Context, roomID bson.ObjectID) ([]mo
 		return nil, err
 	}
 
+	m.fillPlannedCounts(ctx, roomID, roomState.DJQueue)
 	return roomState.DJQueue, nil
 }
 
+// fillPlannedCounts sets how many tracks each DJ in a queue planned to play next.
+// Only the counts are shown, the tracks themselves are private to each DJ.
+func (m *QueueManager) fillPlannedCounts(ctx context.Context, roomID bson.ObjectID, queue []models.QueueEntry) {
+	userIDs := make([]bson.ObjectID, len(queue))
+	for i, entry := range queue {
+		userIDs[i] = entry.User.ID
+	}
+
+	counts, err := m.planner.CountUpNext(ctx, roomID, userIDs)
+	if err != nil {
+		m.logger.Error("Failed to count planned tracks", err, "roomId", roomID.Hex())
+		return
+	}
+	for i := range queue {
+		queue[i].PlannedCount = counts[queue[i].User.ID]
+	}
+}
+
 // GetCurrentDJ gets the current DJ for a room.
 func (m *QueueManager) GetCurrentDJ(ctx context.Context, roomID bson.ObjectID) (*models.PublicUser, error) {
 	m.mutex.RLock()
@@ -228,33 +451,118 @@ func (m *QueueManager) GetCurrentMedia(ctx context.Context, roomID bson.ObjectID
 
 // AdvanceQueue advances to the next DJ in the queue.
 func (m *QueueManager) AdvanceQueue(ctx context.Context, roomID bson.ObjectID) (*models.RoomState, error) {
+	return m.advance(ctx, roomID, playEnd{})
+}
+
+// advance ends the current play, recording why it was skipped or cut off if it was, and advances to the next
+// DJ in the queue.
+func (m *QueueManager) advance(ctx context.Context, roomID bson.ObjectID, end playEnd) (*models.RoomState, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
+	return m.advanceLocked(ctx, roomID, end)
+}
+
+// advanceLocked advances like advance for callers already holding the mutex, which isn't reentrant.
+// The stages of the track change are timed until it is announced.
+func (m *QueueManager) advanceLocked(ctx context.Context, roomID bson.ObjectID, end playEnd) (_ *models.RoomState, err error) {
+	t := m.transitions.begin(roomID, end.trigger())
+	defer func() { m.transitions.resolved(t, err) }()
+
 	// Get room state
 	roomState, err := m.roomManager.GetRoomState(ctx, roomID)
 	if err != nil {
 		return nil, err
 	}
+	t.mediaEnd = roomState.MediaEndTime
+
+	room, err := m.roomManager.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	options := room.QueueOptions()
+
+	// Record the end of the current play
+	skipReason := end.skipReason
+	skipped := skipReason != ""
+	var endErr error
+	if end.truncated {
+		endErr = m.history.Truncate(ctx, roomID)
+	} else {
+		endErr = m.history.End(ctx, roomID, skipped, skipReason)
+	}
+	if endErr != nil {
+		m.logger.Error("Failed to record end of play", endErr, "roomId", roomID.Hex())
+	}
+
+	// DJs with plays left in their turn play their next track
+	if current := m.continuingDJ(ctx, roomState, options, skipped); current != nil {
+		current.PlayCount++
+		current.TurnPlays++
+		t.stage(models.TransitionStageVoteClose)
+		t.stage(models.TransitionStageAdvanceDecision)
+		return m.playTurn(ctx, roomID, roomState, current.User.ID, t)
+	}
+
+	// The DJ whose turn ended starts waiting for their next one, or leaves the queue if it doesn't cycle
+	turnEnd := "finished"
+	if skipped {
+		turnEnd = skipReason
+	}
+	if err := m.history.EndTurn(ctx, roomID, turnEnd); err != nil {
+		m.logger.Error("Failed to record end of DJ turn", err, "roomId", roomID.Hex())
+	}
+	if roomState.CurrentDJ != nil && !isCrowdPick(roomState.CurrentMedia) {
+		if options.Cycle {
+			for i := range roomState.DJQueue {
+				if roomState.DJQueue[i].User.ID == roomState.CurrentDJ.ID {
+					roomState.DJQueue[i].WaitingSince = time.Now()
+				}
+			}
+		} else {
+			m.dropFinishedDJ(ctx, roomID, roomState, roomState.CurrentDJ.ID)
+		}
+	}
+	t.stage(models.TransitionStageVoteClose)
+
+	// Every few DJ tracks, rooms with crowd picks replay the recent track they grabbed most
+	if media, dj := m.nextCrowdPick(ctx, roomID, roomState); media != nil {
+		roomState.CurrentDJ = dj
+		t.stage(models.TransitionStageAdvanceDecision)
+		if err := m.startMedia(ctx, roomID, roomState, media); err != nil {
+			return nil, err
+		}
+		t.stage(models.TransitionStageMediaResolve)
+		m.crowdPicked(ctx, roomID, roomState)
+		m.fillPlannedCounts(ctx, roomID, roomState.DJQueue)
+		return roomState, nil
+	}
 
-	// If there's a current DJ and media, add to play history
-	if roomState.CurrentDJ != nil && roomState.CurrentMedia != nil {
-		historyEntry := models.PlayHistoryEntry{
-			Media:    *roomState.CurrentMedia,
-			DJ:       *roomState.CurrentDJ,
-			PlayTime: roomState.MediaStartTime,
-			Woots:    0, // These would be populated from actual vote data
-			Mehs:     0,
-			Grabs:    0,
+	// Get next DJ from queue, dropping DJs who can no longer play
+	var nextDJ *models.QueueEntry
+	for len(roomState.DJQueue) > 0 {
+		candidate := roomState.DJQueue[0]
+		err := m.ValidateDJ(ctx, roomState.Settings, candidate.User.ID)
+		if err != nil && !isDJValidationError(err) {
+			// Don't punish the DJ for our own failure to check their playlist
+			m.logger.Error("Failed to validate DJ", err, "roomId", roomID.Hex(), "userId", candidate.User.ID.Hex())
+			err = nil
 		}
-		roomState.PlayHistory = append([]models.PlayHistoryEntry{historyEntry}, roomState.PlayHistory...)
-		if len(roomState.PlayHistory) > 50 {
-			roomState.PlayHistory = roomState.PlayHistory[:50]
+		if err == nil {
+			nextDJ = &candidate
+			break
+		}
+
+		roomState.DJQueue = roomState.DJQueue[1:]
+		m.logger.Info("Skipped DJ turn", "roomId", roomID.Hex(), "userId", candidate.User.ID.Hex(), "reason", err.Error())
+		for _, handler := range m.turnSkipHandlers {
+			handler(ctx, roomID, candidate.User.ID, err)
 		}
 	}
 
-	// If queue is empty, clear current DJ and media
-	if len(roomState.DJQueue) == 0 {
+	// If no one in the queue can play, clear current DJ and media
+	if nextDJ == nil {
+		t.stage(models.TransitionStageAdvanceDecision)
 		roomState.CurrentDJ = nil
 		roomState.CurrentMedia = nil
 		roomState.MediaStartTime = time.Time{}
@@ -266,18 +574,29 @@ func (m *QueueManager) AdvanceQueue(ctx context.Context, roomID bson.ObjectID) (
 		if err != nil {
 			return nil, err
 		}
+		m.transitions.expect(roomID, time.Time{})
+		m.recordInFlight(ctx, roomID, roomState)
+		m.hintPreloads(roomID, roomState)
+		t.stage(models.TransitionStageMediaResolve)
 
 		return roomState, nil
 	}
 
-	// Get next DJ from queue
-	nextDJ := roomState.DJQueue[0]
+	// Record the turn along with how long the DJ waited for it
+	waitingSince := nextDJ.WaitingSince
+	if waitingSince.IsZero() {
+		waitingSince = nextDJ.JoinTime
+	}
+	if err := m.history.StartTurn(ctx, roomID, nextDJ.User.ID, time.Since(waitingSince)); err != nil {
+		m.logger.Error("Failed to record DJ turn", err, "roomId", roomID.Hex(), "userId", nextDJ.User.ID.Hex())
+	}
 
-	// Update play count for the DJ
+	// Update play counts for the DJ
 	nextDJ.PlayCount++
+	nextDJ.TurnPlays = 1
 
 	// Move DJ to end of queue
-	roomState.DJQueue = append(roomState.DJQueue[1:], nextDJ)
+	roomState.DJQueue = append(roomState.DJQueue[1:], *nextDJ)
 
 	// Update positions
 	for i := range roomState.DJQueue {
@@ -286,6 +605,67 @@ func (m *QueueManager) AdvanceQueue(ctx context.Context, roomID bson.ObjectID) (
 
 	// Set current DJ
 	roomState.CurrentDJ = &nextDJ.User
+	t.stage(models.TransitionStageAdvanceDecision)
+
+	return m.playTurn(ctx, roomID, roomState, nextDJ.User.ID, t)
+}
+
+// continuingDJ gets the queue entry of the current DJ when their turn goes on for another track: they
+// played fewer tracks in a row than the room allows, weren't skipped and can still play.
+func (m *QueueManager) continuingDJ(ctx context.Context, roomState *models.RoomState, options models.QueueOptions, skipped bool) *models.QueueEntry {
+	if skipped || options.MaxConsecutivePlays <= 1 || roomState.CurrentDJ == nil || isCrowdPick(roomState.CurrentMedia) {
+		return nil
+	}
+
+	for i := range roomState.DJQueue {
+		entry := &roomState.DJQueue[i]
+		if entry.User.ID != roomState.CurrentDJ.ID {
+			continue
+		}
+		if entry.TurnPlays >= options.MaxConsecutivePlays {
+			return nil
+		}
+		if err := m.ValidateDJ(ctx, roomState.Settings, entry.User.ID); err != nil && isDJValidationError(err) {
+			return nil
+		}
+		return entry
+	}
+	return nil
+}
+
+// dropFinishedDJ takes a DJ whose turn ended out of the queue of a room that doesn't cycle. Their plan
+// goes with them.
+func (m *QueueManager) dropFinishedDJ(ctx context.Context, roomID bson.ObjectID, roomState *models.RoomState, userID bson.ObjectID) {
+	index := slices.IndexFunc(roomState.DJQueue, func(entry models.QueueEntry) bool {
+		return entry.User.ID == userID
+	})
+	if index == -1 {
+		return
+	}
+
+	roomState.DJQueue = slices.Delete(roomState.DJQueue, index, index+1)
+	for i := range roomState.DJQueue {
+		roomState.DJQueue[i].Position = i
+	}
+	if err := m.planner.Clear(ctx, roomID, userID); err != nil {
+		m.logger.Error("Failed to clear planned tracks", err, "roomId", roomID.Hex(), "userId", userID.Hex())
+	}
+
+	m.logger.Debug("DJ left the queue after their turn", "roomId", roomID.Hex(), "userId", userID.Hex())
+}
+
+// playTurn plays the next track of the current DJ of a room: their next planned track if they planned
+// one that can still be played, or nothing until they pick one.
+func (m *QueueManager) playTurn(ctx context.Context, roomID bson.ObjectID, roomState *models.RoomState, djID bson.ObjectID, t *transition) (*models.RoomState, error) {
+	// Play the DJ's next planned track, if they planned one that can still be played
+	if mediaInfo := m.nextPlannedMedia(ctx, roomID, roomState.Settings, djID); mediaInfo != nil {
+		if err := m.startMedia(ctx, roomID, roomState, mediaInfo); err != nil {
+			return nil, err
+		}
+		t.stage(models.TransitionStageMediaResolve)
+		m.fillPlannedCounts(ctx, roomID, roomState.DJQueue)
+		return roomState, nil
+	}
 
 	// Clear current media (would be set by the DJ playing a track)
 	roomState.CurrentMedia = nil
@@ -294,14 +674,85 @@ func (m *QueueManager) AdvanceQueue(ctx context.Context, roomID bson.ObjectID) (
 	roomState.MediaEndTime = time.Time{}
 
 	// Update room state
-	err = m.roomManager.UpdateRoomState(ctx, roomID, roomState)
-	if err != nil {
+	if err := m.roomManager.UpdateRoomState(ctx, roomID, roomState); err != nil {
 		return nil, err
 	}
+	m.transitions.expect(roomID, time.Time{})
+	m.recordInFlight(ctx, roomID, roomState)
+	m.hintPreloads(roomID, roomState)
+	t.stage(models.TransitionStageMediaResolve)
 
+	m.fillPlannedCounts(ctx, roomID, roomState.DJQueue)
 	return roomState, nil
 }
 
+// nextPlannedMedia takes the first track a DJ planned that can still be played in the room.
+// Planned tracks that can't, because they left the playlist or the room settings changed, are dropped.
+func (m *QueueManager) nextPlannedMedia(ctx context.Context, roomID bson.ObjectID, settings models.RoomSettings, userID bson.ObjectID) *models.MediaInfo {
+	var playlist *models.Playlist
+	for {
+		mediaID, ok, err := m.planner.Next(ctx, roomID, userID)
+		if err != nil {
+			m.logger.Error("Failed to get planned track", err, "roomId", roomID.Hex(), "userId", userID.Hex())
+			return nil
+		}
+		if !ok {
+			return nil
+		}
+
+		if playlist == nil {
+			if playlist, err = m.playlists.GetActivePlaylist(ctx, userID); err != nil {
+				m.logger.Error("Failed to get playlist for planned track", err, "roomId", roomID.Hex(), "userId", userID.Hex())
+				return nil
+			}
+		}
+
+		media, err := m.checkPlannedMedia(ctx, settings, playlist, userID, mediaID)
+		if err == nil {
+			return media.ToMediaInfo(nil)
+		}
+		m.logger.Info("Dropped planned track", "roomId", roomID.Hex(), "userId", userID.Hex(), "mediaId", mediaID.Hex(), "reason", err.Error())
+	}
+}
+
+// checkPlannedMedia checks that a planned track is still in the DJ's playlist and can be played in the room.
+func (m *QueueManager) checkPlannedMedia(ctx context.Context, settings models.RoomSettings, playlist *models.Playlist, userID, mediaID bson.ObjectID) (*models.Media, error) {
+	if !playlistContains(playlist, mediaID) {
+		return nil, models.ErrMediaNotInPlaylist
+	}
+
+	media, err := m.mediaRepo.FindByID(ctx, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	if err := playableInRoom(settings, media); err != nil {
+		return nil, err
+	}
+	if m.trustPolicy.IsLongTrack(media.Duration) {
+		if err := m.trustPolicy.CheckAbility(ctx, userID.Hex(), models.TrustAbilityQueueLongTracks); err != nil {
+			return nil, err
+		}
+	}
+	return media, nil
+}
+
+// playableInRoom checks that media can be played under a room's settings.
+func playableInRoom(settings models.RoomSettings, media *models.Media) error {
+	if !media.TakedownID.IsZero() {
+		return models.ErrMediaTakenDown
+	}
+	if len(settings.AllowedSources) > 0 && !slices.Contains(settings.AllowedSources, media.Type) {
+		return models.ErrInvalidMediaType
+	}
+	if media.Metadata.AgeRestricted && !settings.AllowAgeRestricted {
+		return models.ErrMediaRestricted
+	}
+	if settings.MaxSongLength > 0 && media.Duration > settings.MaxSongLength {
+		return models.ErrMediaTooLong
+	}
+	return nil
+}
+
 // PlayMedia sets the currently playing media for a room.
 func (m *QueueManager) PlayMedia(ctx context.Context, roomID bson.ObjectID, mediaInfo *models.MediaInfo) (*models.RoomState, error) {
 	m.mutex.Lock()
@@ -318,29 +769,173 @@ func (m *QueueManager) PlayMedia(ctx context.Context, roomID bson.ObjectID, medi
 		return nil, errors.New("no current DJ")
 	}
 
+	// Long tracks are reserved for DJs with enough trust
+	if mediaInfo != nil && m.trustPolicy.IsLongTrack(mediaInfo.Duration) {
+		err = m.trustPolicy.CheckAbility(ctx, roomState.CurrentDJ.ID.Hex(), models.TrustAbilityQueueLongTracks)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Taken down media is never played
+	if mediaInfo != nil {
+		takenDown, err := m.isTakenDown(ctx, mediaInfo)
+		if err != nil {
+			return nil, err
+		}
+		if takenDown {
+			return nil, models.ErrMediaTakenDown
+		}
+	}
+
+	// Age-restricted media is only played in 18+ rooms
+	if mediaInfo != nil && !roomState.Settings.AllowAgeRestricted {
+		restricted, err := m.isAgeRestricted(ctx, mediaInfo)
+		if err != nil {
+			return nil, err
+		}
+		if restricted {
+			return nil, models.ErrMediaRestricted
+		}
+	}
+
+	if err := m.startMedia(ctx, roomID, roomState, mediaInfo); err != nil {
+		return nil, err
+	}
+	return roomState, nil
+}
+
+// isAgeRestricted checks whether media is age-restricted, trusting the stored media over the client's copy.
+func (m *QueueManager) isAgeRestricted(ctx context.Context, mediaInfo *models.MediaInfo) (bool, error) {
+	if mediaInfo.AgeRestricted {
+		return true, nil
+	}
+	media, err := m.mediaRepo.FindByID(ctx, mediaInfo.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrMediaNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return media.Metadata.AgeRestricted, nil
+}
+
+// isTakenDown checks whether stored media was taken down after a content complaint.
+func (m *QueueManager) isTakenDown(ctx context.Context, mediaInfo *models.MediaInfo) (bool, error) {
+	media, err := m.mediaRepo.FindByID(ctx, mediaInfo.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrMediaNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return !media.TakedownID.IsZero(), nil
+}
+
+// startMedia starts playing media in a room, or stops playback when mediaInfo is nil, and records the play.
+func (m *QueueManager) startMedia(ctx context.Context, roomID bson.ObjectID, roomState *models.RoomState, mediaInfo *models.MediaInfo) error {
+	// Suggest a volume adjustment from the stored loudness, never the client's
+	if mediaInfo != nil {
+		mediaInfo.Normalization = nil
+		if roomState.Settings.NormalizeVolume {
+			m.addNormalization(ctx, mediaInfo)
+		}
+	}
+
 	// Set current media
 	roomState.CurrentMedia = mediaInfo
 	roomState.MediaStartTime = time.Now()
 	roomState.MediaProgress = 0
 	if mediaInfo != nil {
-		roomState.MediaEndTime = roomState.MediaStartTime.Add(time.Duration(mediaInfo.Duration) * time.Second)
+		// Media claiming to be longer than the room allows is cut off at the room's maximum track length
+		duration := mediaInfo.Duration
+		if maxLength := roomState.Settings.MaxSongLength; maxLength > 0 && duration > maxLength {
+			duration = maxLength
+		}
+		roomState.MediaEndTime = roomState.MediaStartTime.Add(time.Duration(duration) * time.Second)
 	} else {
 		roomState.MediaEndTime = time.Time{}
 	}
 
 	// Update room state
-	err = m.roomManager.UpdateRoomState(ctx, roomID, roomState)
+	if err := m.roomManager.UpdateRoomState(ctx, roomID, roomState); err != nil {
+		return err
+	}
+	m.transitions.expect(roomID, roomState.MediaEndTime)
+	m.scheduleCutoff(roomID, roomState)
+	m.recordInFlight(ctx, roomID, roomState)
+	m.hintPreloads(roomID, roomState)
+
+	// Record the play, playback goes on even if it can't be recorded
+	var err error
+	if mediaInfo != nil {
+		err = m.history.Start(ctx, roomID, *mediaInfo, *roomState.CurrentDJ, roomState.ActiveUsers)
+	} else {
+		err = m.history.End(ctx, roomID, false, "")
+	}
 	if err != nil {
-		return nil, err
+		m.logger.Error("Failed to record play", err, "roomId", roomID.Hex())
 	}
+	return nil
+}
 
-	return roomState, nil
+// addNormalization adds a volume normalization hint to media whose loudness is known.
+func (m *QueueManager) addNormalization(ctx context.Context, mediaInfo *models.MediaInfo) {
+	media, err := m.mediaRepo.FindByID(ctx, mediaInfo.ID)
+	if err != nil {
+		if !errors.Is(err, models.ErrMediaNotFound) {
+			m.logger.Error("Failed to get media for normalization", err, "mediaId", mediaInfo.ID.Hex())
+		}
+		return
+	}
+	if media.Metadata.Loudness == nil {
+		return
+	}
+
+	mediaInfo.Normalization = m.normalization.hint(media.Metadata.Loudness)
+}
+
+// AnnounceTransition tells a room's clients about its latest track change with notify, so the time
+// it takes counts toward the track change.
+func (m *QueueManager) AnnounceTransition(roomID bson.ObjectID, notify func()) {
+	m.transitions.Announce(roomID, notify)
 }
 
 // SkipCurrentMedia skips the currently playing media.
 func (m *QueueManager) SkipCurrentMedia(ctx context.Context, roomID bson.ObjectID) (*models.RoomState, error) {
-	// Simply advance to the next DJ
-	return m.AdvanceQueue(ctx, roomID)
+	return m.advance(ctx, roomID, playEnd{skipReason: skipReasonSkipped})
+}
+
+// SkipCurrentMediaAs skips the currently playing media on behalf of a user. The current DJ can skip
+// their own track, anyone else needs the skip permission.
+func (m *QueueManager) SkipCurrentMediaAs(ctx context.Context, roomID, userID bson.ObjectID) (*models.RoomState, error) {
+	room, err := m.roomManager.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !room.Can(userID, models.RoomPermissionSkip) {
+		roomState, err := m.roomManager.GetRoomState(ctx, roomID)
+		if err != nil {
+			return nil, err
+		}
+		// The DJ credited with a crowd pick didn't choose to play it
+		if roomState.CurrentDJ == nil || roomState.CurrentDJ.ID != userID || isCrowdPick(roomState.CurrentMedia) {
+			return nil, ErrNotAuthorized
+		}
+	}
+
+	return m.SkipCurrentMedia(ctx, roomID)
+}
+
+// SetQueueLocked locks or unlocks the DJ queue of a room, for users with the queue lock permission.
+func (m *QueueManager) SetQueueLocked(ctx context.Context, roomID, userID bson.ObjectID, locked bool) (*models.Room, error) {
+	return m.roomManager.SetQueueLocked(ctx, roomID, userID, locked)
+}
+
+// SetQueueOptions sets the options of the DJ queue of a room, for users with the queue lock permission.
+func (m *QueueManager) SetQueueOptions(ctx context.Context, roomID, userID bson.ObjectID, options models.QueueOptions) (*models.Room, error) {
+	return m.roomManager.SetQueueOptions(ctx, roomID, userID, options)
 }
 
 // ClearQueue clears the DJ queue for a room.
@@ -363,6 +958,7 @@ func (m *QueueManager) ClearQueue(ctx context.Context, roomID bson.ObjectID) (*m
 		return nil, err
 	}
 
+	m.hintPreloads(roomID, roomState)
 	return roomState, nil
 }
 
@@ -395,6 +991,7 @@ func (m *QueueManager) ShuffleQueue(ctx context.Context, roomID bson.ObjectID) (
 		return nil, err
 	}
 
+	m.hintPreloads(roomID, roomState)
 	return roomState, nil
 }
 
@@ -460,11 +1057,95 @@ func (m *QueueManager) GetPlayHistory(ctx context.Context, roomID bson.ObjectID)
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 
-	// Get room state
-	roomState, err := m.roomManager.GetRoomState(ctx, roomID)
+	return m.history.Recent(ctx, roomID)
+}
+
+// ApplySettingsChange drops queued DJs who can no longer play once a room restricts its sources or song length,
+// instead of waiting for their turn to come up.
+func (m *QueueManager) ApplySettingsChange(ctx context.Context, change RoomSettingsChange) {
+	if !restrictsPlayback(change.Previous, change.Settings) {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	roomState, err := m.roomManager.GetRoomState(ctx, change.RoomID)
 	if err != nil {
-		return nil, err
+		m.logger.Error("Failed to get room state", err, "roomId", change.RoomID.Hex())
+		return
+	}
+
+	queue := make([]models.QueueEntry, 0, len(roomState.DJQueue))
+	var skipped []models.QueueEntry
+	var reasons []error
+	for _, entry := range roomState.DJQueue {
+		// The current DJ keeps their turn, the new settings apply from their next one
+		if roomState.CurrentDJ != nil && roomState.CurrentDJ.ID == entry.User.ID {
+			queue = append(queue, entry)
+			continue
+		}
+
+		err := m.ValidateDJ(ctx, change.Settings, entry.User.ID)
+		if err != nil && isDJValidationError(err) {
+			skipped = append(skipped, entry)
+			reasons = append(reasons, err)
+			continue
+		}
+		if err != nil {
+			m.logger.Error("Failed to validate DJ", err, "roomId", change.RoomID.Hex(), "userId", entry.User.ID.Hex())
+		}
+		queue = append(queue, entry)
+	}
+	if len(skipped) == 0 {
+		return
 	}
 
-	return roomState.PlayHistory, nil
+	for i := range queue {
+		queue[i].Position = i
+	}
+	roomState.DJQueue = queue
+
+	if err := m.roomManager.UpdateRoomState(ctx, change.RoomID, roomState); err != nil {
+		m.logger.Error("Failed to update room state", err, "roomId", change.RoomID.Hex())
+		return
+	}
+
+	for i, entry := range skipped {
+		m.logger.Info("Removed DJ after room settings change", "roomId", change.RoomID.Hex(), "userId", entry.User.ID.Hex(), "reason", reasons[i].Error())
+		for _, handler := range m.turnSkipHandlers {
+			handler(ctx, change.RoomID, entry.User.ID, reasons[i])
+		}
+	}
+}
+
+// restrictsPlayback checks whether new room settings allow fewer tracks than the previous ones.
+func restrictsPlayback(previous, current models.RoomSettings) bool {
+	if previous.AllowAgeRestricted && !current.AllowAgeRestricted {
+		return true
+	}
+	if current.MaxSongLength > 0 && (previous.MaxSongLength == 0 || current.MaxSongLength < previous.MaxSongLength) {
+		return true
+	}
+	if len(current.AllowedSources) == 0 {
+		return false
+	}
+	if len(previous.AllowedSources) == 0 {
+		return true
+	}
+	for _, source := range previous.AllowedSources {
+		if !slices.Contains(current.AllowedSources, source) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDJValidationError checks whether an error from ValidateDJ describes the DJ's playlist
+// rather than a failure to check it.
+func isDJValidationError(err error) bool {
+	return errors.Is(err, models.ErrNoActivePlaylist) ||
+		errors.Is(err, models.ErrPlaylistEmpty) ||
+		errors.Is(err, models.ErrNoPlayableItems) ||
+		errors.Is(err, models.ErrAllItemsTooLong)
 }
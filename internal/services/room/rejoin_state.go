@@ -0,0 +1,91 @@
+package room
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+// rejoinStateKey identifies a join state shared between rejoining clients.
+type rejoinStateKey struct {
+	roomID      bson.ObjectID
+	progressive bool
+}
+
+// rejoinState is a room's join state loaded for a rejoining client. Its mutex is held while it loads,
+// so clients rejoining the room meanwhile wait for it instead of loading it too.
+type rejoinState struct {
+	mu       sync.Mutex
+	state    *models.RoomState
+	loadedAt time.Time
+}
+
+// rejoinStates shares the join state of rooms between the clients rejoining them after reconnecting.
+type rejoinStates struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[rejoinStateKey]*rejoinState
+}
+
+// SetRejoinStateTTL makes the clients rejoining a room after reconnecting share its join state for a
+// short while, so a reconnect storm loads each room once instead of once per client. Zero disables sharing.
+func (m *Manager) SetRejoinStateTTL(ttl time.Duration) {
+	m.rejoinStates = &rejoinStates{
+		ttl:     ttl,
+		entries: make(map[rejoinStateKey]*rejoinState),
+	}
+}
+
+// GetRejoinState gets the state of a room sent to a client rejoining it after reconnecting. The state is
+// shared with the clients rejoining the room within a short while, so it may miss the latest changes,
+// which reach the client as notifications anyway. The state returned is the client's own copy.
+func (m *Manager) GetRejoinState(ctx context.Context, roomID bson.ObjectID, progressive bool) (*models.RoomState, error) {
+	if m.rejoinStates == nil || m.rejoinStates.ttl <= 0 {
+		return m.GetJoinState(ctx, roomID, progressive)
+	}
+
+	entry := m.rejoinStates.entry(rejoinStateKey{roomID: roomID, progressive: progressive})
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.state == nil || time.Since(entry.loadedAt) >= m.rejoinStates.ttl {
+		state, err := m.GetJoinState(ctx, roomID, progressive)
+		if err != nil {
+			return nil, err
+		}
+		entry.state, entry.loadedAt = state, time.Now()
+	}
+
+	// The fields of the joining user are set on the copy, the lists are replaced rather than changed
+	state := *entry.state
+	return &state, nil
+}
+
+// entry gets the shared join state of a room, dropping the expired ones of other rooms.
+func (c *rejoinStates) entry(key rejoinStateKey) *rejoinState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if ok {
+		return entry
+	}
+
+	for other, state := range c.entries {
+		// Entries being loaded are locked and kept
+		if state.mu.TryLock() {
+			if time.Since(state.loadedAt) >= c.ttl {
+				delete(c.entries, other)
+			}
+			state.mu.Unlock()
+		}
+	}
+
+	entry = &rejoinState{}
+	c.entries[key] = entry
+	return entry
+}
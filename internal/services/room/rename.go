@@ -0,0 +1,41 @@
+package room
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+// RenameUser shows a user's new username in a room's state, where they are its current DJ or wait in
+// its DJ queue. It returns the state after the rename, or nil when the state doesn't show the user.
+// Rosters are read from the stored users, so they show the new username already.
+func (m *QueueManager) RenameUser(ctx context.Context, roomID, userID bson.ObjectID, username string) (*models.RoomState, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	state, err := m.roomManager.GetRoomState(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	renamed := false
+	if state.CurrentDJ != nil && state.CurrentDJ.ID == userID {
+		state.CurrentDJ.Username = username
+		renamed = true
+	}
+	for i := range state.DJQueue {
+		if state.DJQueue[i].User.ID == userID {
+			state.DJQueue[i].User.Username = username
+			renamed = true
+		}
+	}
+	if !renamed {
+		return nil, nil
+	}
+
+	if err := m.roomManager.UpdateRoomState(ctx, roomID, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
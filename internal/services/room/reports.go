@@ -0,0 +1,224 @@
+package room
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// RoomReportService takes users' reports of whole rooms to platform admins, and applies the admins'
+// decisions to the rooms. Unlike reports of users, room reports never reach the room's own moderators.
+type RoomReportService struct {
+	roomManager *Manager
+	reportRepo  repositories.ReportRepository
+	pubSub      *managers.PubSubManager
+	logger      *utils.Logger
+
+	// resolutionHandlers are notified of every report resolved, to give reporters feedback
+	resolutionHandlers []func(ctx context.Context, report *models.RoomReport)
+}
+
+// NewRoomReportService creates a new room report service.
+func NewRoomReportService(roomManager *Manager, reportRepo repositories.ReportRepository, pubSub *managers.PubSubManager, logger *utils.Logger) *RoomReportService {
+	return &RoomReportService{
+		roomManager: roomManager,
+		reportRepo:  reportRepo,
+		pubSub:      pubSub,
+		logger:      logger.Named("room_report_service"),
+	}
+}
+
+// AddResolutionHandler adds a handler called for every report resolved, including the other
+// pending reports of a room resolved along with the one an admin decided on.
+func (s *RoomReportService) AddResolutionHandler(handler func(ctx context.Context, report *models.RoomReport)) {
+	s.resolutionHandlers = append(s.resolutionHandlers, handler)
+}
+
+// ReportRoom files a user's report of a room. A user can only have one pending report per room.
+func (s *RoomReportService) ReportRoom(ctx context.Context, roomID, reporterID bson.ObjectID, request *models.RoomReportRequest) (*models.RoomReport, error) {
+	request.Description = strings.TrimSpace(request.Description)
+	if err := utils.Validate(request); err != nil {
+		return nil, models.NewRoomError(models.ErrInvalidRoomReport, err.Error(), http.StatusBadRequest)
+	}
+	if request.Reason == models.RoomReportOther && request.Description == "" {
+		return nil, models.NewRoomError(models.ErrInvalidRoomReport, "Describe the problem when reporting a room for another reason", http.StatusBadRequest)
+	}
+
+	if _, err := s.roomManager.GetRoom(ctx, roomID); err != nil {
+		return nil, err
+	}
+
+	_, pending, err := s.reportRepo.FindRoomReports(ctx, models.RoomReportFilter{
+		RoomID:     roomID,
+		ReporterID: reporterID,
+		Status:     models.RoomReportPending,
+	}, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if pending > 0 {
+		return nil, models.ErrRoomAlreadyReported
+	}
+
+	report := &models.RoomReport{
+		RoomID:      roomID,
+		ReporterID:  reporterID,
+		Reason:      request.Reason,
+		Description: request.Description,
+		Status:      models.RoomReportPending,
+	}
+	if err := s.reportRepo.CreateRoomReport(ctx, report); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Room reported", "reportId", report.ID.Hex(), "roomId", roomID.Hex(), "reporterId", reporterID.Hex(), "reason", report.Reason)
+	return report, nil
+}
+
+// ListReports lists room reports for triage, oldest first, along with the total number matching.
+func (s *RoomReportService) ListReports(ctx context.Context, filter models.RoomReportFilter, skip, limit int) ([]*models.RoomReport, int64, error) {
+	return s.reportRepo.FindRoomReports(ctx, filter, skip, limit)
+}
+
+// GetReport gets a room report by ID.
+func (s *RoomReportService) GetReport(ctx context.Context, reportID bson.ObjectID) (*models.RoomReport, error) {
+	return s.reportRepo.FindRoomReportByID(ctx, reportID)
+}
+
+// ResolveReport applies an admin's decision on a report to the reported room. Every other pending
+// report of the room is resolved with the same decision, since it was made about the room as a whole.
+func (s *RoomReportService) ResolveReport(ctx context.Context, reportID, adminID bson.ObjectID, resolution *models.RoomReportResolution) (*models.RoomReport, error) {
+	resolution.Note = strings.TrimSpace(resolution.Note)
+	if err := utils.Validate(resolution); err != nil {
+		return nil, models.NewRoomError(models.ErrInvalidRoomReport, err.Error(), http.StatusBadRequest)
+	}
+
+	report, err := s.reportRepo.FindRoomReportByID(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+	if report.Status != models.RoomReportPending {
+		return nil, models.ErrRoomReportResolved
+	}
+
+	if err := s.enforce(ctx, report.RoomID, resolution.Action); err != nil {
+		return nil, err
+	}
+
+	status := models.RoomReportActioned
+	if resolution.Action == models.RoomEnforcementNone {
+		status = models.RoomReportDismissed
+	}
+
+	resolved, err := s.reportRepo.ResolveRoomReports(ctx, report.RoomID, status, resolution.Action, resolution.Note, adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, resolvedReport := range resolved {
+		for _, handler := range s.resolutionHandlers {
+			handler(ctx, resolvedReport)
+		}
+		if resolvedReport.ID == report.ID {
+			report = resolvedReport
+		}
+	}
+
+	s.logger.Info("Room report resolved", "reportId", reportID.Hex(), "roomId", report.RoomID.Hex(), "adminId", adminID.Hex(), "action", resolution.Action, "reports", len(resolved))
+	return report, nil
+}
+
+// enforce applies an enforcement action to a room.
+func (s *RoomReportService) enforce(ctx context.Context, roomID bson.ObjectID, action models.RoomEnforcement) error {
+	if action == models.RoomEnforcementNone {
+		return nil
+	}
+
+	room, err := s.roomManager.GetRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case models.RoomEnforcementDelist:
+		room.Delisted = true
+		_, err = s.roomManager.UpdateRoom(ctx, room)
+		return err
+
+	case models.RoomEnforcementQuarantine:
+		room.Delisted = true
+		room.Quarantined = true
+		if _, err := s.roomManager.UpdateRoom(ctx, room); err != nil {
+			return err
+		}
+		s.publish(ctx, roomID, "room_quarantined")
+		s.removeRegularUsers(ctx, room)
+		return nil
+
+	case models.RoomEnforcementClose:
+		s.publish(ctx, roomID, "room_closed")
+		return s.roomManager.SetRoomActive(ctx, roomID, false)
+	}
+
+	return nil
+}
+
+// removeRegularUsers sends everyone but the owner and moderators out of a quarantined room.
+func (s *RoomReportService) removeRegularUsers(ctx context.Context, room *models.Room) {
+	users, err := s.roomManager.GetRoomUsers(ctx, room.ID)
+	if err != nil {
+		s.logger.Error("Failed to get users of quarantined room", err, "roomId", room.ID.Hex())
+		return
+	}
+
+	for _, user := range users {
+		if isStaffRole(roomRole(room, user.ID)) {
+			continue
+		}
+		if err := s.roomManager.LeaveRoom(ctx, room.ID, user.ID); err != nil {
+			s.logger.Error("Failed to remove user from quarantined room", err, "roomId", room.ID.Hex(), "userId", user.ID.Hex())
+		}
+	}
+}
+
+// publish tells a room's users about an enforcement action.
+func (s *RoomReportService) publish(ctx context.Context, roomID bson.ObjectID, event string) {
+	err := s.pubSub.PublishToRoom(ctx, roomID.Hex(), event, map[string]any{
+		"roomId": roomID.Hex(),
+	})
+	if err != nil {
+		s.logger.Error("Failed to broadcast room enforcement", err, "roomId", roomID.Hex(), "event", event)
+		// Continue anyway, the action still applies
+	}
+}
+
+// RestoreRoom lifts every enforcement action from a room: it is listed in discovery, open to everyone and active again.
+func (s *RoomReportService) RestoreRoom(ctx context.Context, roomID, adminID bson.ObjectID) (*models.Room, error) {
+	room, err := s.roomManager.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	if room.Delisted || room.Quarantined {
+		room.Delisted = false
+		room.Quarantined = false
+		if room, err = s.roomManager.UpdateRoom(ctx, room); err != nil {
+			return nil, err
+		}
+	}
+	if !room.IsActive {
+		if err := s.roomManager.SetRoomActive(ctx, roomID, true); err != nil {
+			return nil, err
+		}
+		room.IsActive = true
+	}
+
+	s.logger.Info("Room restored", "roomId", roomID.Hex(), "adminId", adminID.Hex())
+	return room, nil
+}
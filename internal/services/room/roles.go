@@ -0,0 +1,152 @@
+package room
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+// SetRole gives a user a role in a room. Users can only give roles below their own, to users whose
+// role is below their own, so co-hosts manage resident DJs and VIPs while only the owner manages co-hosts.
+// Giving the user role takes the user's role away.
+func (m *Manager) SetRole(ctx context.Context, roomID, actorID, targetID bson.ObjectID, role string) (*models.Room, error) {
+	if _, ok := models.RoomRoleRanks[role]; !ok || role == models.RoomRoleOwner {
+		return nil, models.ErrInvalidRoomRole
+	}
+
+	room, err := m.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if targetID == room.CreatedBy {
+		return nil, models.ErrInvalidRoomRole
+	}
+
+	actorRank := models.RoomRoleRanks[room.RoleOf(actorID)]
+	if actorRank <= models.RoomRoleRanks[room.RoleOf(targetID)] || actorRank <= models.RoomRoleRanks[role] {
+		return nil, ErrNotAuthorized
+	}
+
+	if role == models.RoomRoleUser {
+		err = m.roomRepo.RemoveRole(ctx, roomID, targetID)
+	} else {
+		err = m.roomRepo.SetRole(ctx, roomID, models.RoomRoleAssignment{
+			UserID:     targetID,
+			Role:       role,
+			AssignedBy: actorID,
+			AssignedAt: time.Now(),
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Room role set", "roomId", roomID.Hex(), "userId", targetID.Hex(), "role", role, "by", actorID.Hex())
+	return m.GetRoom(ctx, roomID)
+}
+
+// GetRoles gets the roles given in a room and the permissions of each role.
+func (m *Manager) GetRoles(ctx context.Context, roomID bson.ObjectID) (*models.RoomRoles, error) {
+	room, err := m.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments := slices.Clone(room.Roles)
+	if assignments == nil {
+		assignments = []models.RoomRoleAssignment{}
+	}
+
+	// Moderators added before roles existed are listed as the co-hosts they are
+	for _, userID := range room.Moderators {
+		if userID != room.CreatedBy && !slices.ContainsFunc(assignments, func(a models.RoomRoleAssignment) bool { return a.UserID == userID }) {
+			assignments = append(assignments, models.RoomRoleAssignment{UserID: userID, Role: models.RoomRoleCoHost})
+		}
+	}
+
+	return &models.RoomRoles{
+		OwnerID:     room.CreatedBy,
+		Assignments: assignments,
+		Permissions: room.PermissionMatrix(),
+	}, nil
+}
+
+// SetRolePermissions changes what the roles of a room are allowed to do. Only the owner can change them,
+// and their own permissions can't be changed. Roles left out keep their default permissions, and no
+// permissions at all restores the defaults.
+func (m *Manager) SetRolePermissions(ctx context.Context, roomID, userID bson.ObjectID, permissions map[string][]string) (*models.Room, error) {
+	room, err := m.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if roomRole(room, userID) != roleOwner {
+		return nil, ErrNotAuthorized
+	}
+
+	for role, granted := range permissions {
+		if _, ok := models.RoomRoleRanks[role]; !ok || role == models.RoomRoleOwner {
+			return nil, models.ErrInvalidRoomRole
+		}
+		for _, permission := range granted {
+			if !slices.Contains(models.RoomPermissions, permission) {
+				return nil, models.ErrInvalidRoomRole
+			}
+		}
+		slices.Sort(granted)
+		permissions[role] = slices.Compact(granted)
+	}
+
+	if err := m.roomRepo.SetRolePermissions(ctx, roomID, permissions); err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Room role permissions set", "roomId", roomID.Hex(), "by", userID.Hex())
+	return m.GetRoom(ctx, roomID)
+}
+
+// SetQueueLocked locks or unlocks a room's DJ queue, for users with the queue lock permission.
+// Users already in the queue stay in it.
+func (m *Manager) SetQueueLocked(ctx context.Context, roomID, userID bson.ObjectID, locked bool) (*models.Room, error) {
+	room, err := m.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if !room.Can(userID, models.RoomPermissionQueueLock) {
+		return nil, ErrNotAuthorized
+	}
+
+	if err := m.roomRepo.SetQueueLocked(ctx, roomID, locked); err != nil {
+		return nil, err
+	}
+
+	room.QueueLocked = locked
+	return room, nil
+}
+
+// SetQueueOptions sets whether a room's DJ queue is locked, whether DJs stay in it once their turn ends
+// and how many tracks they play in a row, for users with the queue lock permission. The options apply
+// from the next track change.
+func (m *Manager) SetQueueOptions(ctx context.Context, roomID, userID bson.ObjectID, options models.QueueOptions) (*models.Room, error) {
+	room, err := m.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if !room.Can(userID, models.RoomPermissionQueueLock) {
+		return nil, ErrNotAuthorized
+	}
+
+	if err := m.roomRepo.SetQueueOptions(ctx, roomID, options); err != nil {
+		return nil, err
+	}
+
+	room.QueueLocked = options.Locked
+	room.QueueCycleDisabled = !options.Cycle
+	room.MaxConsecutivePlays = options.MaxConsecutivePlays
+
+	m.logger.Info("Room queue options set", "roomId", roomID.Hex(), "locked", options.Locked, "cycle", options.Cycle,
+		"maxConsecutivePlays", options.MaxConsecutivePlays, "by", userID.Hex())
+	return room, nil
+}
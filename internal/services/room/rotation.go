@@ -0,0 +1,131 @@
+package room
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// rotationReportKeyPrefix is the cache key prefix for rotation reports.
+	rotationReportKeyPrefix = "rotation:"
+
+	// defaultRotationWindow is the window of rotation reports when none is given, in hours.
+	defaultRotationWindow = 24
+
+	// maxRotationWindow is the longest window a rotation report can cover, in hours.
+	maxRotationWindow = 30 * 24
+)
+
+// RotationReporter breaks a room's DJ rotation down per DJ, so moderators can look into fairness complaints.
+type RotationReporter struct {
+	roomManager RoomManager
+	historyRepo repositories.HistoryRepository
+	redisClient *redis.Client
+	cacheTTL    time.Duration
+	logger      *utils.Logger
+}
+
+// NewRotationReporter creates a new rotation reporter.
+func NewRotationReporter(
+	roomManager RoomManager,
+	historyRepo repositories.HistoryRepository,
+	redisClient *redis.Client,
+	cacheTTL time.Duration,
+	logger *utils.Logger,
+) *RotationReporter {
+	return &RotationReporter{
+		roomManager: roomManager,
+		historyRepo: historyRepo,
+		redisClient: redisClient,
+		cacheTTL:    cacheTTL,
+		logger:      logger.Named("rotation_reporter"),
+	}
+}
+
+// GetReport gets the rotation report of a room over the given number of past hours: the turns each DJ
+// received, how long they waited for them, and how their plays went. Only the room's owner and moderators
+// can get it. Reports are cached, so the latest turns may take a while to show up.
+func (r *RotationReporter) GetReport(ctx context.Context, roomID, userID bson.ObjectID, hours int) (*models.RotationReport, error) {
+	room, err := r.roomManager.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if !isStaffRole(roomRole(room, userID)) {
+		return nil, ErrNotAuthorized
+	}
+
+	if hours <= 0 {
+		hours = defaultRotationWindow
+	}
+	hours = min(hours, maxRotationWindow)
+
+	key := fmt.Sprintf("%s%s:%d", rotationReportKeyPrefix, roomID.Hex(), hours)
+	if r.cacheTTL > 0 {
+		if cached, err := r.redisClient.Get(ctx, key); err == nil && cached != "" {
+			var report models.RotationReport
+			if err := json.Unmarshal([]byte(cached), &report); err == nil {
+				return &report, nil
+			}
+		}
+	}
+
+	until := time.Now()
+	since := until.Add(-time.Duration(hours) * time.Hour)
+	stats, err := r.historyRepo.GetRotationStats(ctx, roomID, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.RotationReport{
+		RoomID:      roomID,
+		Since:       since,
+		Until:       until,
+		DJs:         stats,
+		GeneratedAt: until,
+	}
+
+	if r.cacheTTL > 0 {
+		if data, err := json.Marshal(report); err == nil {
+			if err := r.redisClient.Set(ctx, key, string(data), r.cacheTTL); err != nil {
+				r.logger.Warn("Failed to cache rotation report", "key", key, "error", err)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// RotationReportCSV renders a rotation report as CSV, one row per DJ.
+func RotationReportCSV(report *models.RotationReport) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	rows := [][]string{{"user_id", "username", "turns", "average_wait_seconds", "longest_wait_seconds", "plays", "skips"}}
+	for _, dj := range report.DJs {
+		rows = append(rows, []string{
+			dj.UserID.Hex(),
+			dj.Username,
+			strconv.Itoa(dj.Turns),
+			strconv.FormatFloat(dj.AverageWait, 'f', 1, 64),
+			strconv.Itoa(dj.LongestWait),
+			strconv.Itoa(dj.Plays),
+			strconv.Itoa(dj.Skips),
+		})
+	}
+
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,158 @@
+package room
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// setPlanKeyPrefix prefixes the keys of the tracks DJs planned to play next, one list per room and DJ.
+const setPlanKeyPrefix = "room:plan"
+
+// SetPlanPolicy controls how many tracks DJs can plan ahead and for how long plans are kept.
+type SetPlanPolicy struct {
+	// MaxTracks is the number of tracks a DJ can plan ahead. Zero disables set planning.
+	MaxTracks int
+
+	// TTL is how long a plan is kept after it was last changed.
+	TTL time.Duration
+}
+
+// SetPlanner keeps the tracks DJs arranged to play next from their active playlist. Plans are private
+// to the DJ, others only see how many tracks are planned. Planned tracks are checked again when they
+// are played, since the playlist or the room settings may have changed in the meantime.
+type SetPlanner struct {
+	redisClient *redis.Client
+	playlists   PlaylistSource
+	policy      SetPlanPolicy
+	logger      *utils.Logger
+}
+
+// NewSetPlanner creates a new set planner.
+func NewSetPlanner(redisClient *redis.Client, playlists PlaylistSource, policy SetPlanPolicy, logger *utils.Logger) *SetPlanner {
+	return &SetPlanner{
+		redisClient: redisClient,
+		playlists:   playlists,
+		policy:      policy,
+		logger:      logger.Named("set_planner"),
+	}
+}
+
+// SetUpNext replaces the tracks a DJ plans to play next in a room, in order. The tracks must be in the
+// DJ's active playlist; an empty list clears the plan. It returns models.ErrSetPlanTooLong when more
+// tracks are given than can be planned and models.ErrMediaNotInPlaylist for tracks not in the playlist.
+func (p *SetPlanner) SetUpNext(ctx context.Context, roomID, userID bson.ObjectID, mediaIDs []bson.ObjectID) ([]bson.ObjectID, error) {
+	key := formatSetPlanKey(roomID, userID)
+	if len(mediaIDs) == 0 {
+		return []bson.ObjectID{}, p.redisClient.Del(ctx, key)
+	}
+
+	if p.policy.MaxTracks <= 0 {
+		return nil, models.ErrSetPlanningDisabled
+	}
+	if len(mediaIDs) > p.policy.MaxTracks {
+		return nil, models.ErrSetPlanTooLong
+	}
+
+	playlist, err := p.playlists.GetActivePlaylist(ctx, userID)
+	if err != nil {
+		if errors.Is(err, models.ErrPlaylistNotFound) {
+			return nil, models.ErrNoActivePlaylist
+		}
+		return nil, err
+	}
+	for _, mediaID := range mediaIDs {
+		if !playlistContains(playlist, mediaID) {
+			return nil, models.ErrMediaNotInPlaylist
+		}
+	}
+
+	pipe := p.redisClient.Pipeline()
+	pipe.Del(ctx, key)
+	for _, mediaID := range mediaIDs {
+		pipe.RPush(ctx, key, mediaID.Hex())
+	}
+	pipe.Expire(ctx, key, p.policy.TTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return mediaIDs, nil
+}
+
+// GetUpNext gets the tracks a DJ plans to play next in a room, in order.
+func (p *SetPlanner) GetUpNext(ctx context.Context, roomID, userID bson.ObjectID) ([]bson.ObjectID, error) {
+	values, err := p.redisClient.LRange(ctx, formatSetPlanKey(roomID, userID), 0, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaIDs := make([]bson.ObjectID, 0, len(values))
+	for _, value := range values {
+		if mediaID, err := bson.ObjectIDFromHex(value); err == nil {
+			mediaIDs = append(mediaIDs, mediaID)
+		}
+	}
+	return mediaIDs, nil
+}
+
+// CountUpNext counts the tracks each of the given DJs plans to play next in a room.
+func (p *SetPlanner) CountUpNext(ctx context.Context, roomID bson.ObjectID, userIDs []bson.ObjectID) (map[bson.ObjectID]int, error) {
+	counts := make(map[bson.ObjectID]int, len(userIDs))
+	if len(userIDs) == 0 || p.policy.MaxTracks <= 0 {
+		return counts, nil
+	}
+
+	pipe := p.redisClient.Pipeline()
+	lengths := make([]interface{ Val() int64 }, len(userIDs))
+	for i, userID := range userIDs {
+		lengths[i] = pipe.LLen(ctx, formatSetPlanKey(roomID, userID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	for i, userID := range userIDs {
+		if length := lengths[i].Val(); length > 0 {
+			counts[userID] = int(length)
+		}
+	}
+	return counts, nil
+}
+
+// Next takes the next planned track of a DJ. It returns false when nothing is planned.
+func (p *SetPlanner) Next(ctx context.Context, roomID, userID bson.ObjectID) (bson.ObjectID, bool, error) {
+	value, err := p.redisClient.LPop(ctx, formatSetPlanKey(roomID, userID))
+	if err != nil || value == "" {
+		return bson.NilObjectID, false, err
+	}
+
+	mediaID, err := bson.ObjectIDFromHex(value)
+	if err != nil {
+		return bson.NilObjectID, false, err
+	}
+	return mediaID, true, nil
+}
+
+// Clear drops the plan of a DJ, for when they leave the queue.
+func (p *SetPlanner) Clear(ctx context.Context, roomID, userID bson.ObjectID) error {
+	return p.redisClient.Del(ctx, formatSetPlanKey(roomID, userID))
+}
+
+// playlistContains checks whether a media is in a playlist.
+func playlistContains(playlist *models.Playlist, mediaID bson.ObjectID) bool {
+	return slices.ContainsFunc(playlist.Items, func(item models.PlaylistItem) bool {
+		return item.MediaID == mediaID
+	})
+}
+
+// formatSetPlanKey formats the key of the tracks a DJ planned in a room.
+func formatSetPlanKey(roomID, userID bson.ObjectID) string {
+	return redis.FormatKey(setPlanKeyPrefix, roomID.Hex()+":"+userID.Hex())
+}
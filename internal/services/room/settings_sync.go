@@ -0,0 +1,103 @@
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// settingsChannel is the PubSub channel room settings changes are published on.
+var settingsChannel = managers.FormatGlobalChannel("room_settings_changed")
+
+// RoomSettingsChange describes a change to a room's settings.
+type RoomSettingsChange struct {
+	// RoomID is the room whose settings changed.
+	RoomID bson.ObjectID `json:"roomId"`
+
+	// Previous are the settings before the change.
+	Previous models.RoomSettings `json:"previous"`
+
+	// Settings are the settings after the change.
+	Settings models.RoomSettings `json:"settings"`
+
+	// ChangedAt is when the change was made.
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+// settingsChanged checks whether two versions of a room's settings differ.
+func settingsChanged(previous, current models.RoomSettings) bool {
+	return !reflect.DeepEqual(previous, current)
+}
+
+// SettingsSync propagates room settings changes to every node, so the services caching
+// settings in memory or in Redis pick them up without users having to rejoin.
+type SettingsSync struct {
+	pubSub   *managers.PubSubManager
+	logger   *utils.Logger
+	handlers []func(ctx context.Context, change RoomSettingsChange)
+	mutex    sync.RWMutex
+}
+
+// NewSettingsSync creates a new room settings sync.
+func NewSettingsSync(pubSub *managers.PubSubManager, logger *utils.Logger) *SettingsSync {
+	return &SettingsSync{
+		pubSub: pubSub,
+		logger: logger.Named("settings_sync"),
+	}
+}
+
+// AddHandler registers a handler called on this node for every room settings change, including changes made on other nodes.
+func (s *SettingsSync) AddHandler(handler func(ctx context.Context, change RoomSettingsChange)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.handlers = append(s.handlers, handler)
+}
+
+// Start begins receiving room settings changes.
+func (s *SettingsSync) Start(ctx context.Context) error {
+	if err := s.pubSub.Subscribe(settingsChannel); err != nil {
+		return fmt.Errorf("failed to subscribe to room settings channel: %w", err)
+	}
+
+	s.pubSub.AddFailableHandler(settingsChannel, "room_settings", func(channel string, payload []byte) error {
+		var change RoomSettingsChange
+		if err := json.Unmarshal(payload, &change); err != nil {
+			return fmt.Errorf("failed to unmarshal room settings change: %w", err)
+		}
+		s.dispatch(ctx, change)
+		return nil
+	})
+
+	s.logger.Info("Room settings sync started")
+	return nil
+}
+
+// Publish sends a room settings change to every node.
+// If it cannot be published the change is still applied on this node.
+func (s *SettingsSync) Publish(ctx context.Context, change RoomSettingsChange) {
+	if err := s.pubSub.Publish(ctx, settingsChannel, change); err != nil {
+		s.logger.Error("Failed to publish room settings change", err, "roomId", change.RoomID.Hex())
+		s.dispatch(ctx, change)
+	}
+}
+
+// dispatch calls the handlers for a room settings change.
+func (s *SettingsSync) dispatch(ctx context.Context, change RoomSettingsChange) {
+	s.mutex.RLock()
+	handlers := s.handlers
+	s.mutex.RUnlock()
+
+	s.logger.Debug("Applying room settings change", "roomId", change.RoomID.Hex())
+	for _, handler := range handlers {
+		handler(ctx, change)
+	}
+}
@@ -0,0 +1,73 @@
+package room
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+const (
+	// skipReasonSkipped is the skip reason of media skipped by its DJ or staff.
+	skipReasonSkipped = "skipped"
+
+	// skipReasonVoted is the skip reason of media skipped because the room voted it down.
+	skipReasonVoted = "voted"
+)
+
+// SkipVote is the meh vote that passed a room's skip threshold, meaning its current media should be skipped.
+type SkipVote struct {
+	// RoomID is the room the media plays in.
+	RoomID bson.ObjectID
+
+	// MediaID is the media voted down.
+	MediaID bson.ObjectID
+
+	// DJID is the DJ playing the media.
+	DJID bson.ObjectID
+
+	// Votes are the vote and reaction counts of the media after the vote.
+	Votes map[string]int
+}
+
+// AddSkipVoteHandler adds a handler called when the mehs on a room's current media pass its skip threshold.
+func (m *Manager) AddSkipVoteHandler(handler func(ctx context.Context, vote SkipVote)) {
+	m.skipVoteHandlers = append(m.skipVoteHandlers, handler)
+}
+
+// skipVoted notifies the skip vote handlers that a room's current media was voted down.
+func (m *Manager) skipVoted(ctx context.Context, vote SkipVote) {
+	for _, handler := range m.skipVoteHandlers {
+		handler(ctx, vote)
+	}
+}
+
+// AddVoteSkipHandler adds a handler called when media is skipped because its room voted it down,
+// with the room's state after the skip.
+func (m *QueueManager) AddVoteSkipHandler(handler func(ctx context.Context, vote SkipVote, state *models.RoomState)) {
+	m.voteSkipHandlers = append(m.voteSkipHandlers, handler)
+}
+
+// SkipVoted skips media its room voted down and advances to the next DJ. Media that already
+// stopped playing, because it ended or was skipped otherwise, is left alone.
+func (m *QueueManager) SkipVoted(ctx context.Context, vote SkipVote) {
+	roomState, err := m.roomManager.GetRoomState(ctx, vote.RoomID)
+	if err != nil {
+		m.logger.Error("Failed to get room state for vote skip", err, "roomId", vote.RoomID.Hex())
+		return
+	}
+	if roomState.CurrentMedia == nil || roomState.CurrentMedia.ID != vote.MediaID {
+		return
+	}
+
+	roomState, err = m.advance(ctx, vote.RoomID, playEnd{skipReason: skipReasonVoted})
+	if err != nil {
+		m.logger.Error("Failed to skip voted down media", err, "roomId", vote.RoomID.Hex(), "mediaId", vote.MediaID.Hex())
+		return
+	}
+
+	m.logger.Info("Skipped voted down media", "roomId", vote.RoomID.Hex(), "mediaId", vote.MediaID.Hex(), "djId", vote.DJID.Hex(), "mehs", vote.Votes[models.VoteMeh])
+	for _, handler := range m.voteSkipHandlers {
+		handler(ctx, vote, roomState)
+	}
+}
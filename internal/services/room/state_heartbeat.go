@@ -0,0 +1,78 @@
+package room
+
+import (
+	"context"
+	"time"
+
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// RoomHeartbeatSource lists the rooms followed by live connections to this instance.
+// Connections that stop answering pings are dropped, so the rooms it lists are in use.
+type RoomHeartbeatSource interface {
+	// RoomSubscriptions lists the users following each room's events, by room ID.
+	RoomSubscriptions() map[string][]string
+}
+
+// StateHeartbeat keeps the Redis state of rooms in use from expiring. State expiry is otherwise only reset
+// by updates, so a room quietly playing a long set could lose its state.
+type StateHeartbeat struct {
+	stateManager *managers.RoomStateManager
+	live         RoomHeartbeatSource
+	interval     time.Duration
+	logger       *utils.Logger
+}
+
+// NewStateHeartbeat creates a new room state heartbeat, beating every interval.
+func NewStateHeartbeat(stateManager *managers.RoomStateManager, live RoomHeartbeatSource, interval time.Duration, logger *utils.Logger) *StateHeartbeat {
+	return &StateHeartbeat{
+		stateManager: stateManager,
+		live:         live,
+		interval:     interval,
+		logger:       logger.Named("state_heartbeat"),
+	}
+}
+
+// Start starts refreshing the state of the rooms in use.
+func (h *StateHeartbeat) Start(ctx context.Context) {
+	if h.interval <= 0 {
+		h.logger.Info("Room state heartbeat is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				h.logger.Info("Stopping room state heartbeat")
+				return
+			case <-ticker.C:
+				h.Beat(ctx)
+			}
+		}
+	}()
+
+	h.logger.Info("Room state heartbeat started", "interval", h.interval)
+}
+
+// Beat refreshes the state expiry of every room followed by a live connection to this instance,
+// rebuilding states that already expired.
+func (h *StateHeartbeat) Beat(ctx context.Context) {
+	refreshed := 0
+	for roomID := range h.live.RoomSubscriptions() {
+		if err := ctx.Err(); err != nil {
+			return
+		}
+		if err := h.stateManager.Heartbeat(ctx, roomID); err != nil {
+			h.logger.Error("Failed to refresh room state", err, "roomId", roomID)
+			continue
+		}
+		refreshed++
+	}
+
+	h.logger.Debug("Refreshed room states", "rooms", refreshed)
+}
@@ -74,6 +74,7 @@ type SyncMessage struct {
 type SyncService struct {
 	pubsub      *managers.PubSubManager
 	roomState   *managers.RoomStateManager
+	largeRooms  LargeRoomPolicy
 	logger      *utils.Logger
 	roomStates  map[string]*PlaybackState
 	stateMutex  sync.RWMutex
@@ -85,11 +86,13 @@ type SyncService struct {
 func NewSyncService(
 	pubsub *managers.PubSubManager,
 	roomState *managers.RoomStateManager,
+	largeRooms LargeRoomPolicy,
 	logger *utils.Logger,
 ) *SyncService {
 	return &SyncService{
 		pubsub:      pubsub,
 		roomState:   roomState,
+		largeRooms:  largeRooms,
 		logger:      logger.Named("sync_service"),
 		roomStates:  make(map[string]*PlaybackState),
 		subscribers: make(map[string][]chan *SyncMessage),
@@ -246,17 +249,7 @@ func (s *SyncService) UpdatePlaybackState(ctx context.Context, roomID, userID st
 		PlaybackState: state,
 	}
 
-	// Publish to Redis
-	msgJSON, err := json.Marshal(syncMsg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal sync message: %w", err)
-	}
-
-	if err := s.pubsub.Publish(ctx, fmt.Sprintf("room:sync:%s", roomID), msgJSON); err != nil {
-		return fmt.Errorf("failed to publish sync message: %w", err)
-	}
-
-	return nil
+	return s.broadcast(ctx, syncMsg)
 }
 
 // SubscribeToRoom subscribes to synchronization events for a room.
@@ -354,17 +347,7 @@ func (s *SyncService) SyncRoomState(ctx context.Context, roomID, userID string)
 		},
 	}
 
-	// Publish to Redis
-	msgJSON, err := json.Marshal(syncMsg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal sync message: %w", err)
-	}
-
-	if err := s.pubsub.Publish(ctx, fmt.Sprintf("room:sync:%s", roomID), msgJSON); err != nil {
-		return fmt.Errorf("failed to publish sync message: %w", err)
-	}
-
-	return nil
+	return s.broadcast(ctx, syncMsg)
 }
 
 // BroadcastRoomEvent broadcasts a room event to all users in a room.
@@ -384,13 +367,39 @@ func (s *SyncService) BroadcastRoomEvent(ctx context.Context, roomID, userID str
 		Data:          data,
 	}
 
-	// Publish to Redis
-	msgJSON, err := json.Marshal(syncMsg)
+	return s.broadcast(ctx, syncMsg)
+}
+
+// broadcast publishes a sync message, summarizing it when the room is large.
+func (s *SyncService) broadcast(ctx context.Context, msg *SyncMessage) error {
+	state, err := s.roomState.GetRoomState(ctx, msg.RoomID)
+	if err != nil {
+		s.logger.Error("Failed to get room size for broadcast", err, "roomID", msg.RoomID)
+		// Continue anyway, broadcast as a small room
+	}
+
+	if state == nil || !s.largeRooms.IsLarge(state.ActiveUsers) {
+		return s.publish(ctx, msg)
+	}
+
+	// Clients in large rooms rely on counts rather than per-user updates
+	if msg.Data == nil {
+		msg.Data = make(map[string]any)
+	}
+	msg.Data["active_users"] = state.ActiveUsers
+	msg.Data["state_mode"] = models.RoomStateModeSummary
+
+	return s.publish(ctx, msg)
+}
+
+// publish publishes a sync message to the room's sync channel.
+func (s *SyncService) publish(ctx context.Context, msg *SyncMessage) error {
+	msgJSON, err := json.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal sync message: %w", err)
 	}
 
-	if err := s.pubsub.Publish(ctx, fmt.Sprintf("room:sync:%s", roomID), msgJSON); err != nil {
+	if err := s.pubsub.Publish(ctx, fmt.Sprintf("room:sync:%s", msg.RoomID), msgJSON); err != nil {
 		return fmt.Errorf("failed to publish sync message: %w", err)
 	}
 
@@ -0,0 +1,494 @@
+package room
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// Room chat sensitivities, how readily automated moderation acts on a room's chat.
+const (
+	// ChatSensitivityOff turns automated moderation off for the room.
+	ChatSensitivityOff = "off"
+	// ChatSensitivityLow only acts on clearly toxic messages.
+	ChatSensitivityLow = "low"
+	// ChatSensitivityMedium uses the configured thresholds.
+	ChatSensitivityMedium = "medium"
+	// ChatSensitivityHigh also acts on borderline messages.
+	ChatSensitivityHigh = "high"
+)
+
+// sensitivityShift is how far a room's sensitivity moves the configured thresholds.
+const sensitivityShift = 0.15
+
+// classifyTimeout bounds how long scoring a single message may take.
+const classifyTimeout = 5 * time.Second
+
+// ToxicityScore is a classifier's assessment of a chat message.
+type ToxicityScore struct {
+	// Score is how toxic the message is, from 0 to 1.
+	Score float64
+
+	// Categories are the scores of the kinds of toxicity detected, for classifiers that report them.
+	Categories map[string]float64
+}
+
+// ToxicityClassifier scores how toxic chat messages are.
+type ToxicityClassifier interface {
+	// Name identifies the classifier in flags and audit records.
+	Name() string
+
+	// Classify scores a chat message's text.
+	Classify(ctx context.Context, text string) (*ToxicityScore, error)
+}
+
+// WordListClassifier is a local classifier scoring messages by the blocked terms they contain.
+// One term scores 0.7 and every further term brings the score closer to 1.
+type WordListClassifier struct {
+	terms []string
+}
+
+// NewWordListClassifier creates a classifier for the given blocked terms. Terms may be several words long.
+func NewWordListClassifier(terms []string) *WordListClassifier {
+	normalized := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if term = normalizeWords(term); term != "" {
+			normalized = append(normalized, term)
+		}
+	}
+	return &WordListClassifier{terms: normalized}
+}
+
+// Name identifies the classifier.
+func (c *WordListClassifier) Name() string {
+	return "wordlist"
+}
+
+// Classify scores a message by the blocked terms it contains.
+func (c *WordListClassifier) Classify(ctx context.Context, text string) (*ToxicityScore, error) {
+	// Pad with spaces so terms only match whole words
+	padded := " " + normalizeWords(text) + " "
+
+	hits := 0
+	for _, term := range c.terms {
+		hits += strings.Count(padded, " "+term+" ")
+	}
+
+	return &ToxicityScore{Score: 1 - math.Pow(0.3, float64(hits))}, nil
+}
+
+// normalizeWords lowercases text and reduces it to its words separated by single spaces.
+func normalizeWords(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// HTTPToxicityClassifier asks an external classification service to score messages.
+type HTTPToxicityClassifier struct {
+	url        string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewHTTPToxicityClassifier creates a classifier for the classification service at the given URL.
+// A non-empty API key is sent as a bearer token.
+func NewHTTPToxicityClassifier(url, apiKey string) *HTTPToxicityClassifier {
+	return &HTTPToxicityClassifier{
+		url:    url,
+		apiKey: apiKey,
+		httpClient: &http.Client{
+			Timeout: classifyTimeout,
+		},
+	}
+}
+
+// toxicityRequest is the request body sent to the classification service.
+type toxicityRequest struct {
+	Text string `json:"text"`
+}
+
+// toxicityResponse is the response body returned by the classification service.
+type toxicityResponse struct {
+	Score      float64            `json:"score"`
+	Categories map[string]float64 `json:"categories"`
+}
+
+// Name identifies the classifier.
+func (c *HTTPToxicityClassifier) Name() string {
+	return "http"
+}
+
+// Classify scores a message with the classification service.
+func (c *HTTPToxicityClassifier) Classify(ctx context.Context, text string) (*ToxicityScore, error) {
+	body, err := json.Marshal(toxicityRequest{Text: text})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("toxicity classification failed with status %d", resp.StatusCode)
+	}
+
+	var result toxicityResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode toxicity classification: %w", err)
+	}
+
+	return &ToxicityScore{
+		Score:      min(max(result.Score, 0), 1),
+		Categories: result.Categories,
+	}, nil
+}
+
+// ToxicityPolicy configures automated chat moderation.
+type ToxicityPolicy struct {
+	// FlagThreshold is the score from which messages are flagged to the room's moderation queue.
+	FlagThreshold float64
+
+	// MaskThreshold is the score from which flagged messages are also masked until reviewed.
+	MaskThreshold float64
+
+	// Workers is the number of messages scored at once.
+	Workers int
+}
+
+// toxicityJob is a chat message waiting to be scored.
+type toxicityJob struct {
+	ctx     context.Context
+	message models.ChatMessage
+}
+
+// ToxicityModerator scores chat messages in the background, flags toxic ones to their room's moderation queue
+// and masks the worst until a moderator reviews them. Every automated action is recorded in the room's moderation history.
+type ToxicityModerator struct {
+	rooms       ChatRoomManager
+	chat        ChatService
+	chatRepo    repositories.ChatRepository
+	historyRepo repositories.HistoryRepository
+	pubSub      *managers.PubSubManager
+	classifier  ToxicityClassifier
+	policy      ToxicityPolicy
+	logger      *utils.Logger
+
+	// jobs holds messages waiting for a worker, messages are dropped rather than slowing chat down when it is full
+	jobs chan toxicityJob
+}
+
+// NewToxicityModerator creates a new toxicity moderator. A nil classifier disables scoring,
+// while flags already queued can still be reviewed.
+func NewToxicityModerator(
+	rooms ChatRoomManager,
+	chat ChatService,
+	chatRepo repositories.ChatRepository,
+	historyRepo repositories.HistoryRepository,
+	pubSub *managers.PubSubManager,
+	classifier ToxicityClassifier,
+	policy ToxicityPolicy,
+	logger *utils.Logger,
+) *ToxicityModerator {
+	policy.Workers = max(policy.Workers, 1)
+
+	return &ToxicityModerator{
+		rooms:       rooms,
+		chat:        chat,
+		chatRepo:    chatRepo,
+		historyRepo: historyRepo,
+		pubSub:      pubSub,
+		classifier:  classifier,
+		policy:      policy,
+		logger:      logger.Named("toxicity_moderator"),
+		jobs:        make(chan toxicityJob, policy.Workers*100),
+	}
+}
+
+// Start starts the workers scoring chat messages.
+func (m *ToxicityModerator) Start(ctx context.Context) {
+	if m.classifier == nil {
+		m.logger.Info("Chat toxicity scoring is disabled")
+		return
+	}
+
+	for range m.policy.Workers {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-m.jobs:
+					m.score(job.ctx, job.message)
+				}
+			}
+		}()
+	}
+
+	m.logger.Info("Toxicity moderator started", "classifier", m.classifier.Name(), "workers", m.policy.Workers)
+}
+
+// ScoreMessage queues a sent chat message for scoring. It is a chat message handler and never blocks.
+func (m *ToxicityModerator) ScoreMessage(ctx context.Context, message models.ChatMessage) {
+	if m.classifier == nil {
+		return
+	}
+
+	// Only what users write is scored, room staff are trusted with their own chat
+	if message.Type != "text" && message.Type != "emote" {
+		return
+	}
+	if isStaffRole(message.UserRole) {
+		return
+	}
+
+	select {
+	case m.jobs <- toxicityJob{ctx: context.WithoutCancel(ctx), message: message}:
+	default:
+		m.logger.Warn("Toxicity scoring queue is full, skipping message", "roomId", message.RoomID.Hex(), "messageId", message.ID.Hex())
+	}
+}
+
+// thresholds gets the flag and mask thresholds for a room's chat sensitivity. ok is false when the room turned scoring off.
+func (m *ToxicityModerator) thresholds(sensitivity string) (flag, mask float64, ok bool) {
+	flag, mask = m.policy.FlagThreshold, m.policy.MaskThreshold
+
+	switch sensitivity {
+	case ChatSensitivityOff:
+		return 0, 0, false
+	case ChatSensitivityLow:
+		return flag + sensitivityShift, mask + sensitivityShift, true
+	case ChatSensitivityHigh:
+		return flag - sensitivityShift, mask - sensitivityShift, true
+	default:
+		return flag, mask, true
+	}
+}
+
+// score scores a message and acts on the result.
+func (m *ToxicityModerator) score(ctx context.Context, message models.ChatMessage) {
+	room, err := m.rooms.GetRoom(ctx, message.RoomID)
+	if err != nil {
+		m.logger.Error("Failed to get room for toxicity scoring", err, "roomId", message.RoomID.Hex())
+		return
+	}
+
+	flagThreshold, maskThreshold, ok := m.thresholds(room.Settings.ChatSensitivity)
+	if !ok {
+		return
+	}
+
+	classifyCtx, cancel := context.WithTimeout(ctx, classifyTimeout)
+	result, err := m.classifier.Classify(classifyCtx, message.Content)
+	cancel()
+	if err != nil {
+		m.logger.Error("Failed to score chat message", err, "roomId", message.RoomID.Hex(), "messageId", message.ID.Hex())
+		return
+	}
+	if result.Score < flagThreshold {
+		return
+	}
+
+	flag := &models.ChatFlag{
+		RoomID:     message.RoomID,
+		MessageID:  message.ID,
+		UserID:     message.UserID,
+		Content:    message.Content,
+		Score:      result.Score,
+		Categories: result.Categories,
+		Classifier: m.classifier.Name(),
+		Status:     models.ChatFlagPending,
+	}
+
+	if result.Score >= maskThreshold {
+		if err := m.chatRepo.SetMessageMasked(ctx, message.ID, true); err != nil {
+			m.logger.Error("Failed to mask chat message", err, "messageId", message.ID.Hex())
+		} else {
+			flag.Masked = true
+		}
+	}
+
+	// A masked message stays hidden only once its flag is queued, so a moderator can review and restore it
+	if err := m.chatRepo.CreateFlag(ctx, flag); err != nil {
+		m.logger.Error("Failed to flag chat message", err, "roomId", message.RoomID.Hex(), "messageId", message.ID.Hex())
+		if flag.Masked {
+			if err := m.chatRepo.SetMessageMasked(ctx, message.ID, false); err != nil {
+				m.logger.Error("Failed to unmask unflagged chat message", err, "messageId", message.ID.Hex())
+			}
+		}
+		return
+	}
+
+	action := "flag"
+	if flag.Masked {
+		action = "mask"
+		m.chat.InvalidateBacklog(ctx, message.RoomID.Hex())
+		if err := m.pubSub.PublishToRoom(ctx, message.RoomID.Hex(), "chat_message_masked", map[string]any{
+			"messageId": message.ID.Hex(),
+		}); err != nil {
+			m.logger.Error("Failed to broadcast masked message", err, "messageId", message.ID.Hex())
+		}
+	}
+
+	m.audit(ctx, &models.ModerationHistory{
+		RoomID:         message.RoomID,
+		TargetUserID:   message.UserID,
+		Action:         action,
+		Reason:         fmt.Sprintf("Scored %.2f by the %s classifier", result.Score, flag.Classifier),
+		Automated:      true,
+		Score:          result.Score,
+		MessageID:      message.ID,
+		MessageContent: message.Content,
+	})
+
+	// The queue is only for the room's staff
+	for _, userID := range room.Staff() {
+		if err := m.pubSub.PublishToUser(ctx, userID.Hex(), "chat_message_flagged", flag); err != nil {
+			m.logger.Error("Failed to notify moderator of flagged message", err, "userId", userID.Hex())
+		}
+	}
+
+	m.logger.Info("Flagged chat message", "roomId", message.RoomID.Hex(), "messageId", message.ID.Hex(), "score", result.Score, "masked", flag.Masked)
+}
+
+// GetFlags lists a room's moderation queue, oldest first, along with the total number matching (room staff only).
+// An empty status lists flags in every status.
+func (m *ToxicityModerator) GetFlags(ctx context.Context, roomID, userID bson.ObjectID, status models.ChatFlagStatus, skip, limit int) ([]*models.ChatFlag, int64, error) {
+	if _, err := m.checkStaff(ctx, roomID, userID); err != nil {
+		return nil, 0, err
+	}
+
+	return m.chatRepo.FindFlagsByRoom(ctx, roomID, status, skip, limit)
+}
+
+// ReviewFlag applies a moderator's decision on a flagged message (room staff only). Dismissing shows a masked
+// message again, removing deletes it.
+func (m *ToxicityModerator) ReviewFlag(ctx context.Context, roomID, flagID, userID bson.ObjectID, review *models.ChatFlagReview) (*models.ChatFlag, error) {
+	if err := utils.Validate(review); err != nil {
+		return nil, models.NewUserError(models.ErrInvalidInput, err.Error(), http.StatusBadRequest)
+	}
+
+	if _, err := m.checkStaff(ctx, roomID, userID); err != nil {
+		return nil, err
+	}
+
+	flag, err := m.chatRepo.FindFlagByID(ctx, flagID)
+	if err != nil {
+		return nil, err
+	}
+	if flag.RoomID != roomID {
+		return nil, models.ErrChatFlagNotFound
+	}
+
+	status := models.ChatFlagDismissed
+	if review.Action == "remove" {
+		status = models.ChatFlagRemoved
+	}
+
+	flag, err = m.chatRepo.ReviewFlag(ctx, flagID, status, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &models.ModerationHistory{
+		RoomID:         roomID,
+		ModeratorID:    userID,
+		TargetUserID:   flag.UserID,
+		MessageID:      flag.MessageID,
+		MessageContent: flag.Content,
+	}
+
+	switch {
+	case status == models.ChatFlagRemoved:
+		if err := m.chat.DeleteMessage(ctx, roomID.Hex(), flag.MessageID.Hex(), userID.Hex()); err != nil {
+			return nil, err
+		}
+		entry.Action = "delete"
+		entry.Reason = "Removed after review of an automated flag"
+		m.audit(ctx, entry)
+
+	case flag.Masked:
+		if err := m.unmask(ctx, flag); err != nil {
+			return nil, err
+		}
+		entry.Action = "unmask"
+		entry.Reason = "Dismissed after review of an automated flag"
+		m.audit(ctx, entry)
+	}
+
+	return flag, nil
+}
+
+// GetModerationLog lists a room's moderation history, most recent first, automated actions included (room staff only).
+func (m *ToxicityModerator) GetModerationLog(ctx context.Context, roomID, userID bson.ObjectID, skip, limit int) ([]*models.ModerationHistory, error) {
+	if _, err := m.checkStaff(ctx, roomID, userID); err != nil {
+		return nil, err
+	}
+
+	return m.historyRepo.FindModerationHistoryByRoom(ctx, roomID, skip, limit)
+}
+
+// unmask shows a masked message again, sending it to the room with its content.
+func (m *ToxicityModerator) unmask(ctx context.Context, flag *models.ChatFlag) error {
+	if err := m.chatRepo.SetMessageMasked(ctx, flag.MessageID, false); err != nil {
+		return err
+	}
+	m.chat.InvalidateBacklog(ctx, flag.RoomID.Hex())
+
+	message, err := m.chatRepo.FindMessageByID(ctx, flag.MessageID)
+	if err != nil {
+		return err
+	}
+	if message.IsDeleted {
+		return nil
+	}
+
+	if err := m.pubSub.PublishToRoom(ctx, flag.RoomID.Hex(), "chat_message_unmasked", message); err != nil {
+		m.logger.Error("Failed to broadcast unmasked message", err, "messageId", flag.MessageID.Hex())
+	}
+	return nil
+}
+
+// checkStaff gets a room, checking that the user is its owner or one of its co-hosts.
+func (m *ToxicityModerator) checkStaff(ctx context.Context, roomID, userID bson.ObjectID) (*models.Room, error) {
+	room, err := m.rooms.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if !isStaffRole(roomRole(room, userID)) {
+		return nil, ErrNotAuthorized
+	}
+	return room, nil
+}
+
+// audit records a moderation action in the room's moderation history.
+func (m *ToxicityModerator) audit(ctx context.Context, entry *models.ModerationHistory) {
+	if err := m.historyRepo.CreateModerationHistory(ctx, entry); err != nil {
+		m.logger.Error("Failed to record moderation history", err, "roomId", entry.RoomID.Hex(), "action", entry.Action)
+	}
+}
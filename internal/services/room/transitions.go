@@ -0,0 +1,342 @@
+package room
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// transitionSweepInterval is how often overdue tracks and unannounced track changes are looked for.
+	transitionSweepInterval = 5 * time.Second
+
+	// transitionSamplesPerRoom is the number of recent track changes kept per room.
+	transitionSamplesPerRoom = 100
+
+	// transitionStatsRetention is how long the track changes of a room are kept after its last one.
+	transitionStatsRetention = time.Hour
+
+	// transitionTriggerAdvance is the trigger of track changes asked for when a track ends.
+	transitionTriggerAdvance = "advance"
+)
+
+// transition is a track change being timed, stage by stage.
+type transition struct {
+	roomID   bson.ObjectID
+	trigger  string
+	started  time.Time
+	mark     time.Time
+	mediaEnd time.Time
+	stages   map[string]time.Duration
+}
+
+// stage ends a stage of the transition, timing it from the end of the previous one.
+func (t *transition) stage(name string) {
+	now := time.Now()
+	t.stages[name] += now.Sub(t.mark)
+	t.mark = now
+}
+
+// roomTransitions are the recent track changes of a room.
+type roomTransitions struct {
+	durations []time.Duration
+	stages    map[string]time.Duration
+	count     int
+	late      int
+	missed    int
+	last      time.Time
+}
+
+// TransitionMonitor times the track changes of rooms on this node, from closing the ending play to
+// telling the room's clients, and looks out for rooms that move on late or not at all.
+type TransitionMonitor struct {
+	roomManager RoomManager
+	slowAfter   time.Duration
+	lateAfter   time.Duration
+	missedAfter time.Duration
+	logger      *utils.Logger
+
+	mu       sync.Mutex
+	pending  map[bson.ObjectID]*transition
+	expected map[bson.ObjectID]time.Time
+	rooms    map[bson.ObjectID]*roomTransitions
+	samples  []time.Duration
+	late     int
+	missed   int
+}
+
+// NewTransitionMonitor creates a new transition monitor. Track changes taking longer than slowAfter to run
+// are logged with their stage timings. Those coming more than lateAfter past the track's expected end are
+// late, and rooms still on a track missedAfter past its end missed their track change.
+func NewTransitionMonitor(roomManager RoomManager, slowAfter, lateAfter, missedAfter time.Duration, logger *utils.Logger) *TransitionMonitor {
+	return &TransitionMonitor{
+		roomManager: roomManager,
+		slowAfter:   slowAfter,
+		lateAfter:   lateAfter,
+		missedAfter: missedAfter,
+		logger:      logger.Named("transition_monitor"),
+		pending:     make(map[bson.ObjectID]*transition),
+		expected:    make(map[bson.ObjectID]time.Time),
+		rooms:       make(map[bson.ObjectID]*roomTransitions),
+	}
+}
+
+// Start starts looking for missed track changes.
+func (m *TransitionMonitor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(transitionSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				m.logger.Info("Stopping transition monitor")
+				return
+			case <-ticker.C:
+				m.sweep(ctx)
+			}
+		}
+	}()
+
+	m.logger.Info("Transition monitor started", "lateAfter", m.lateAfter, "missedAfter", m.missedAfter)
+}
+
+// begin starts timing a track change of a room.
+func (m *TransitionMonitor) begin(roomID bson.ObjectID, trigger string) *transition {
+	now := time.Now()
+	return &transition{
+		roomID:  roomID,
+		trigger: trigger,
+		started: now,
+		mark:    now,
+		stages:  make(map[string]time.Duration, len(models.TransitionStages)),
+	}
+}
+
+// resolved ends the server side of a track change, which then waits for its room to be told. Failed
+// track changes are only logged.
+func (m *TransitionMonitor) resolved(t *transition, err error) {
+	if err != nil {
+		m.logger.Warn("Queue transition failed", append(m.fields(t), "error", err)...)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// A track change no one announced is done once the next one starts
+	if previous := m.pending[t.roomID]; previous != nil {
+		m.finish(previous)
+	}
+	m.pending[t.roomID] = t
+}
+
+// expect records when the track a room just started playing should end, zero when it plays nothing
+// or plays until it is replaced.
+func (m *TransitionMonitor) expect(roomID bson.ObjectID, end time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if end.IsZero() {
+		delete(m.expected, roomID)
+		return
+	}
+	m.expected[roomID] = end
+}
+
+// Announce tells a room's clients about its latest track change with notify, timing it as the change's
+// broadcast stage.
+func (m *TransitionMonitor) Announce(roomID bson.ObjectID, notify func()) {
+	notify()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.pending[roomID]
+	if t == nil {
+		return
+	}
+	delete(m.pending, roomID)
+
+	t.stage(models.TransitionStageBroadcast)
+	m.finish(t)
+}
+
+// finish records a timed track change and logs it. The caller must hold the lock.
+func (m *TransitionMonitor) finish(t *transition) {
+	total := t.mark.Sub(t.started)
+	lateBy := t.started.Sub(t.mediaEnd)
+	late := !t.mediaEnd.IsZero() && lateBy > m.lateAfter
+
+	stats := m.roomStats(t.roomID)
+	stats.durations = append(stats.durations, total)
+	if len(stats.durations) > transitionSamplesPerRoom {
+		stats.durations = slices.Delete(stats.durations, 0, len(stats.durations)-transitionSamplesPerRoom)
+	}
+	for stage, took := range t.stages {
+		stats.stages[stage] += took
+	}
+	stats.count++
+	stats.last = t.mark
+
+	m.samples = append(m.samples, total)
+	if late {
+		stats.late++
+		m.late++
+	}
+
+	switch {
+	case late:
+		m.logger.Warn("Late queue transition", append(m.fields(t), "lateByMs", lateBy.Milliseconds())...)
+	case total > m.slowAfter:
+		m.logger.Warn("Slow queue transition", m.fields(t)...)
+	default:
+		m.logger.Debug("Queue transition", m.fields(t)...)
+	}
+}
+
+// fields builds the log fields of a track change, with the time each stage took.
+func (m *TransitionMonitor) fields(t *transition) []any {
+	stages := make(map[string]int64, len(t.stages))
+	for stage, took := range t.stages {
+		stages[stage] = took.Milliseconds()
+	}
+	return []any{"roomId", t.roomID.Hex(), "trigger", t.trigger, "totalMs", t.mark.Sub(t.started).Milliseconds(), "stagesMs", stages}
+}
+
+// roomStats gets the recent track changes of a room. The caller must hold the lock.
+func (m *TransitionMonitor) roomStats(roomID bson.ObjectID) *roomTransitions {
+	stats := m.rooms[roomID]
+	if stats == nil {
+		stats = &roomTransitions{stages: make(map[string]time.Duration, len(models.TransitionStages))}
+		m.rooms[roomID] = stats
+	}
+	return stats
+}
+
+// sweep counts the rooms still on a track well past its expected end as missed track changes, finishes
+// track changes no one announced, and forgets rooms that stopped changing tracks.
+func (m *TransitionMonitor) sweep(ctx context.Context) {
+	now := time.Now()
+	overdue := make(map[bson.ObjectID]time.Time)
+
+	m.mu.Lock()
+	for roomID, end := range m.expected {
+		if now.Sub(end) > m.missedAfter {
+			overdue[roomID] = end
+		}
+	}
+	for roomID, t := range m.pending {
+		if now.Sub(t.mark) > m.missedAfter {
+			delete(m.pending, roomID)
+			m.finish(t)
+		}
+	}
+	for roomID, stats := range m.rooms {
+		if now.Sub(stats.last) > transitionStatsRetention {
+			delete(m.rooms, roomID)
+		}
+	}
+	m.mu.Unlock()
+
+	for roomID, end := range overdue {
+		if err := ctx.Err(); err != nil {
+			return
+		}
+
+		// The room may have moved on through another node
+		state, err := m.roomManager.GetRoomState(ctx, roomID)
+		if err != nil {
+			m.logger.Error("Failed to get room state for overdue track", err, "roomId", roomID.Hex())
+			continue
+		}
+		missed := state.CurrentMedia != nil && state.MediaEndTime.Equal(end)
+
+		m.mu.Lock()
+		if current, ok := m.expected[roomID]; !ok || !current.Equal(end) {
+			m.mu.Unlock()
+			continue
+		}
+		delete(m.expected, roomID)
+		if missed {
+			stats := m.roomStats(roomID)
+			stats.missed++
+			stats.last = now
+			m.missed++
+		}
+		m.mu.Unlock()
+
+		if missed {
+			m.logger.Warn("Missed queue transition", "roomId", roomID.Hex(), "mediaId", state.CurrentMedia.ID.Hex(),
+				"overdueMs", now.Sub(end).Milliseconds())
+		}
+	}
+}
+
+// Slowest summarizes the recent track changes of the rooms on this node, slowest first.
+func (m *TransitionMonitor) Slowest(limit int) []models.QueueTransitionStats {
+	m.mu.Lock()
+	rooms := make([]models.QueueTransitionStats, 0, len(m.rooms))
+	for roomID, stats := range m.rooms {
+		summary := models.QueueTransitionStats{
+			RoomID:         roomID,
+			Transitions:    stats.count,
+			Stages:         make(map[string]float64, len(stats.stages)),
+			Late:           stats.late,
+			Missed:         stats.missed,
+			LastTransition: stats.last,
+		}
+		if len(stats.durations) > 0 {
+			sorted := slices.Clone(stats.durations)
+			slices.Sort(sorted)
+			summary.P50 = durationPercentile(sorted, 0.50)
+			summary.P95 = durationPercentile(sorted, 0.95)
+			summary.Max = milliseconds(sorted[len(sorted)-1])
+		}
+		for stage, took := range stats.stages {
+			summary.Stages[stage] = milliseconds(took) / float64(max(stats.count, 1))
+		}
+		rooms = append(rooms, summary)
+	}
+	m.mu.Unlock()
+
+	slices.SortFunc(rooms, func(a, b models.QueueTransitionStats) int {
+		if c := cmp.Compare(b.P95, a.P95); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.Late+b.Missed, a.Late+a.Missed)
+	})
+	if limit > 0 && len(rooms) > limit {
+		rooms = rooms[:limit]
+	}
+	return rooms
+}
+
+// TakeTransitionTimings returns how long each track change took since the last call, and how many
+// were late or missed, for the metrics history.
+func (m *TransitionMonitor) TakeTransitionTimings() (durations []time.Duration, late, missed int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	durations, late, missed = m.samples, m.late, m.missed
+	m.samples, m.late, m.missed = nil, 0, 0
+	return durations, late, missed
+}
+
+// durationPercentile gets a percentile of sorted durations in milliseconds, using the nearest-rank method.
+func durationPercentile(sorted []time.Duration, p float64) float64 {
+	index := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return milliseconds(sorted[max(0, min(index, len(sorted)-1))])
+}
+
+// milliseconds converts a duration to fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,229 @@
+package room
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// VerificationService takes artists' and labels' claims of rooms and media to platform admins, and gives
+// verified badges for the claims they approve. Badges are only ever set here: the owners of rooms, users
+// and media can't set or change them through their own updates.
+type VerificationService struct {
+	roomManager *Manager
+	claimRepo   repositories.VerificationRepository
+	mediaRepo   repositories.MediaRepository
+	userRepo    repositories.UserRepository
+	logger      *utils.Logger
+
+	// reviewHandlers are notified of every claim reviewed, to give claimants feedback
+	reviewHandlers []func(ctx context.Context, claim *models.VerificationClaim)
+}
+
+// NewVerificationService creates a new verification service.
+func NewVerificationService(roomManager *Manager, claimRepo repositories.VerificationRepository, mediaRepo repositories.MediaRepository, userRepo repositories.UserRepository, logger *utils.Logger) *VerificationService {
+	return &VerificationService{
+		roomManager: roomManager,
+		claimRepo:   claimRepo,
+		mediaRepo:   mediaRepo,
+		userRepo:    userRepo,
+		logger:      logger.Named("verification_service"),
+	}
+}
+
+// AddReviewHandler adds a handler called for every claim reviewed.
+func (s *VerificationService) AddReviewHandler(handler func(ctx context.Context, claim *models.VerificationClaim)) {
+	s.reviewHandlers = append(s.reviewHandlers, handler)
+}
+
+// SubmitClaim files a user's claim of a room or media item for review. Rooms can only be claimed by their
+// owner, and a user can only have one pending claim per room or media item.
+func (s *VerificationService) SubmitClaim(ctx context.Context, claimantID bson.ObjectID, request *models.VerificationClaimRequest) (*models.VerificationClaim, error) {
+	request.Name = strings.TrimSpace(request.Name)
+	request.Message = strings.TrimSpace(request.Message)
+	if err := utils.Validate(request); err != nil {
+		return nil, models.NewUserError(models.ErrInvalidClaim, err.Error(), http.StatusBadRequest)
+	}
+	if request.TargetID.IsZero() {
+		return nil, models.NewUserError(models.ErrInvalidClaim, "Choose the room or media to claim", http.StatusBadRequest)
+	}
+
+	if err := s.checkClaimable(ctx, claimantID, request.TargetType, request.TargetID); err != nil {
+		return nil, err
+	}
+
+	_, pending, err := s.claimRepo.FindClaims(ctx, models.VerificationClaimFilter{
+		ClaimantID: claimantID,
+		TargetID:   request.TargetID,
+		Status:     models.ClaimPending,
+	}, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if pending > 0 {
+		return nil, models.ErrClaimPending
+	}
+
+	claim := &models.VerificationClaim{
+		ClaimantID: claimantID,
+		TargetType: request.TargetType,
+		TargetID:   request.TargetID,
+		Kind:       request.Kind,
+		Name:       request.Name,
+		Evidence:   request.Evidence,
+		Message:    request.Message,
+		Status:     models.ClaimPending,
+	}
+	if err := s.claimRepo.CreateClaim(ctx, claim); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Verification claim submitted", "claimId", claim.ID.Hex(), "targetType", claim.TargetType, "targetId", claim.TargetID.Hex(), "claimantId", claimantID.Hex())
+	return claim, nil
+}
+
+// checkClaimable checks a room or media item exists, isn't verified yet, and can be claimed by the user.
+func (s *VerificationService) checkClaimable(ctx context.Context, claimantID bson.ObjectID, targetType models.ClaimTarget, targetID bson.ObjectID) error {
+	switch targetType {
+	case models.ClaimTargetRoom:
+		room, err := s.roomManager.GetRoom(ctx, targetID)
+		if err != nil {
+			return err
+		}
+		if room.CreatedBy != claimantID {
+			return models.NewUserError(models.ErrUnauthorizedAction, "Only the owner of a room can claim it", http.StatusForbidden)
+		}
+		if room.Verified != nil {
+			return models.ErrAlreadyVerified
+		}
+
+	case models.ClaimTargetMedia:
+		media, err := s.mediaRepo.FindByID(ctx, targetID)
+		if err != nil {
+			return err
+		}
+		if media.Verified != nil {
+			return models.ErrAlreadyVerified
+		}
+	}
+
+	return nil
+}
+
+// ListClaims lists verification claims for review, oldest first, along with the total number matching.
+func (s *VerificationService) ListClaims(ctx context.Context, filter models.VerificationClaimFilter, skip, limit int) ([]*models.VerificationClaim, int64, error) {
+	return s.claimRepo.FindClaims(ctx, filter, skip, limit)
+}
+
+// GetClaim gets a verification claim by ID.
+func (s *VerificationService) GetClaim(ctx context.Context, claimID bson.ObjectID) (*models.VerificationClaim, error) {
+	return s.claimRepo.FindClaimByID(ctx, claimID)
+}
+
+// ReviewClaim records an admin's decision on a claim. Approving it gives the claimed room or media item
+// and the claimant the verified badge of the artist or label they speak for.
+func (s *VerificationService) ReviewClaim(ctx context.Context, claimID, adminID bson.ObjectID, review *models.VerificationClaimReview) (*models.VerificationClaim, error) {
+	review.Note = strings.TrimSpace(review.Note)
+	if err := utils.Validate(review); err != nil {
+		return nil, models.NewUserError(models.ErrInvalidClaim, err.Error(), http.StatusBadRequest)
+	}
+
+	status := models.ClaimRejected
+	if review.Approve {
+		status = models.ClaimApproved
+	}
+
+	// The review is recorded first, so a claim another admin just rejected is never approved
+	claim, err := s.claimRepo.ReviewClaim(ctx, claimID, status, review.Note, adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	if claim.Status == models.ClaimApproved {
+		badge := &models.VerifiedBadge{
+			Kind:       claim.Kind,
+			Name:       claim.Name,
+			ClaimID:    claim.ID,
+			VerifiedAt: claim.ReviewedAt,
+		}
+		if err := s.setBadge(ctx, claim.TargetType, claim.TargetID, badge); err != nil {
+			return nil, err
+		}
+		if err := s.userRepo.SetVerifiedBadge(ctx, claim.ClaimantID, badge); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, handler := range s.reviewHandlers {
+		handler(ctx, claim)
+	}
+
+	s.logger.Info("Verification claim reviewed", "claimId", claimID.Hex(), "targetType", claim.TargetType, "targetId", claim.TargetID.Hex(), "adminId", adminID.Hex(), "status", claim.Status)
+	return claim, nil
+}
+
+// RevokeBadge takes the verified badge away from a room, user or media item.
+func (s *VerificationService) RevokeBadge(ctx context.Context, targetType models.ClaimTarget, targetID, adminID bson.ObjectID) error {
+	var verified bool
+	switch targetType {
+	case models.ClaimTargetRoom:
+		room, err := s.roomManager.GetRoom(ctx, targetID)
+		if err != nil {
+			return err
+		}
+		verified = room.Verified != nil
+
+	case models.ClaimTargetMedia:
+		media, err := s.mediaRepo.FindByID(ctx, targetID)
+		if err != nil {
+			return err
+		}
+		verified = media.Verified != nil
+
+	case models.ClaimTargetUser:
+		user, err := s.userRepo.FindByID(ctx, targetID)
+		if err != nil {
+			return err
+		}
+		verified = user.Verified != nil
+
+	default:
+		return models.NewUserError(models.ErrInvalidClaim, "Unknown verification target", http.StatusBadRequest)
+	}
+	if !verified {
+		return models.ErrNotVerified
+	}
+
+	if err := s.setBadge(ctx, targetType, targetID, nil); err != nil {
+		return err
+	}
+
+	s.logger.Info("Verified badge revoked", "targetType", targetType, "targetId", targetID.Hex(), "adminId", adminID.Hex())
+	return nil
+}
+
+// setBadge sets the verified badge of a room, user or media item, or takes it away when badge is nil.
+func (s *VerificationService) setBadge(ctx context.Context, targetType models.ClaimTarget, targetID bson.ObjectID, badge *models.VerifiedBadge) error {
+	switch targetType {
+	case models.ClaimTargetRoom:
+		if err := s.roomManager.roomRepo.SetVerifiedBadge(ctx, targetID, badge); err != nil {
+			return err
+		}
+		// Rooms listed in the lobby show their badge
+		s.roomManager.lobby.Invalidate(ctx)
+		return nil
+
+	case models.ClaimTargetMedia:
+		return s.mediaRepo.SetVerifiedBadge(ctx, targetID, badge)
+
+	case models.ClaimTargetUser:
+		return s.userRepo.SetVerifiedBadge(ctx, targetID, badge)
+	}
+
+	return nil
+}
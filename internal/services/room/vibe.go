@@ -0,0 +1,244 @@
+package room
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"math"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// vibeRecentPlays is the number of recent plays a room's vibe is made of.
+	vibeRecentPlays = 10
+
+	// vibeDecay is the weight of each play in a room's vibe relative to the play after it.
+	vibeDecay = 0.8
+
+	// vibeEnergyWeight is the share of energy in how well media matches a vibe, moods make up the rest.
+	vibeEnergyWeight = 0.6
+
+	// vibeEnergyRange is the difference between the lowest and the highest energy.
+	vibeEnergyRange = 4
+)
+
+// VibeService aggregates the energy and mood tags users give media into per-track profiles, follows the
+// rolling vibe of rooms from their recent plays, and suggests playlist items matching it, so DJs can keep
+// the floor moving.
+type VibeService struct {
+	mediaRepo repositories.MediaRepository
+	playlists PlaylistSource
+	history   *HistoryRecorder
+	logger    *utils.Logger
+}
+
+// NewVibeService creates a new vibe service.
+func NewVibeService(mediaRepo repositories.MediaRepository, playlists PlaylistSource, history *HistoryRecorder, logger *utils.Logger) *VibeService {
+	return &VibeService{
+		mediaRepo: mediaRepo,
+		playlists: playlists,
+		history:   history,
+		logger:    logger.Named("vibe_service"),
+	}
+}
+
+// TagMedia sets how a user tags the energy and mood of a media item, replacing their previous tag,
+// and returns the media's updated profile.
+func (s *VibeService) TagMedia(ctx context.Context, mediaID, userID bson.ObjectID, request *models.MediaTagRequest) (*models.MediaVibe, error) {
+	if _, err := s.mediaRepo.FindByID(ctx, mediaID); err != nil {
+		return nil, err
+	}
+
+	moods := slices.Clone(request.Moods)
+	slices.Sort(moods)
+	tag := &models.MediaTag{
+		MediaID: mediaID,
+		UserID:  userID,
+		Energy:  request.Energy,
+		Moods:   slices.Compact(moods),
+	}
+	if err := s.mediaRepo.SaveTag(ctx, tag); err != nil {
+		return nil, err
+	}
+
+	// The profile is rebuilt from every tag rather than adjusted, so changed tags can't skew it
+	tags, err := s.mediaRepo.FindTags(ctx, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	vibe := aggregateVibe(tags)
+	if err := s.mediaRepo.SetVibe(ctx, mediaID, vibe); err != nil {
+		return nil, err
+	}
+
+	return vibe, nil
+}
+
+// GetRoomVibe gets the vibe of a room from the tagged media among its recent plays,
+// the latest plays weighing the most.
+func (s *VibeService) GetRoomVibe(ctx context.Context, roomID bson.ObjectID) (*models.RoomVibe, error) {
+	vibe, _, err := s.roomVibe(ctx, roomID)
+	return vibe, err
+}
+
+// MatchVibe suggests the items of a user's active playlist that best match a room's vibe, best first.
+// Untagged media and media the room just played are left out. Nothing is suggested while the room has no vibe.
+func (s *VibeService) MatchVibe(ctx context.Context, roomID, userID bson.ObjectID, limit int) (*models.RoomVibe, []*models.VibeMatch, error) {
+	roomVibe, played, err := s.roomVibe(ctx, roomID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if roomVibe.Plays == 0 {
+		return roomVibe, []*models.VibeMatch{}, nil
+	}
+
+	playlist, err := s.playlists.GetActivePlaylist(ctx, userID)
+	if err != nil {
+		if errors.Is(err, models.ErrPlaylistNotFound) {
+			return nil, nil, models.ErrNoActivePlaylist
+		}
+		return nil, nil, err
+	}
+
+	mediaIDs := make([]bson.ObjectID, 0, len(playlist.Items))
+	for _, item := range playlist.Items {
+		if !slices.Contains(played, item.MediaID) {
+			mediaIDs = append(mediaIDs, item.MediaID)
+		}
+	}
+	if len(mediaIDs) == 0 {
+		return roomVibe, []*models.VibeMatch{}, nil
+	}
+
+	media, err := s.mediaRepo.FindMany(ctx, bson.M{"_id": bson.M{"$in": mediaIDs}, "vibe": bson.M{"$exists": true}}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	matches := make([]*models.VibeMatch, 0, len(media))
+	for _, item := range media {
+		if item.Vibe == nil || item.Vibe.Tags == 0 {
+			continue
+		}
+		matches = append(matches, &models.VibeMatch{
+			Media: item.ToMediaInfo(nil),
+			Vibe:  item.Vibe,
+			Score: vibeScore(roomVibe, item.Vibe),
+		})
+	}
+	slices.SortStableFunc(matches, func(a, b *models.VibeMatch) int { return cmp.Compare(b.Score, a.Score) })
+
+	return roomVibe, matches[:min(limit, len(matches))], nil
+}
+
+// roomVibe computes the vibe of a room, along with the media it recently played.
+func (s *VibeService) roomVibe(ctx context.Context, roomID bson.ObjectID) (*models.RoomVibe, []bson.ObjectID, error) {
+	plays, err := s.history.Recent(ctx, roomID)
+	if err != nil {
+		return nil, nil, err
+	}
+	plays = plays[:min(vibeRecentPlays, len(plays))]
+
+	played := make([]bson.ObjectID, len(plays))
+	for i, play := range plays {
+		played[i] = play.Media.ID
+	}
+
+	vibe := &models.RoomVibe{Moods: map[string]float64{}}
+	if len(played) == 0 {
+		return vibe, played, nil
+	}
+
+	media, err := s.mediaRepo.FindMany(ctx, bson.M{"_id": bson.M{"$in": played}}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	vibes := make(map[bson.ObjectID]*models.MediaVibe, len(media))
+	for _, item := range media {
+		if item.Vibe != nil && item.Vibe.Tags > 0 {
+			vibes[item.ID] = item.Vibe
+		}
+	}
+
+	var totalWeight float64
+	weight := 1.0
+	for _, mediaID := range played {
+		if mediaVibe, ok := vibes[mediaID]; ok {
+			vibe.Energy += weight * mediaVibe.Energy
+			for mood, share := range moodShares(mediaVibe) {
+				vibe.Moods[mood] += weight * share
+			}
+			totalWeight += weight
+			vibe.Plays++
+		}
+		weight *= vibeDecay
+	}
+
+	if totalWeight > 0 {
+		vibe.Energy = roundScore(vibe.Energy / totalWeight)
+		for mood := range vibe.Moods {
+			vibe.Moods[mood] = roundScore(vibe.Moods[mood] / totalWeight)
+		}
+	}
+	return vibe, played, nil
+}
+
+// aggregateVibe builds the profile of a media item from its tags.
+func aggregateVibe(tags []*models.MediaTag) *models.MediaVibe {
+	vibe := &models.MediaVibe{Moods: map[string]int{}, Tags: len(tags)}
+	if len(tags) == 0 {
+		return vibe
+	}
+
+	energy := 0
+	for _, tag := range tags {
+		energy += tag.Energy
+		for _, mood := range tag.Moods {
+			vibe.Moods[mood]++
+		}
+	}
+	vibe.Energy = roundScore(float64(energy) / float64(len(tags)))
+	return vibe
+}
+
+// moodShares gets the share of the users who tagged a media item with each mood.
+func moodShares(vibe *models.MediaVibe) map[string]float64 {
+	shares := make(map[string]float64, len(vibe.Moods))
+	for mood, count := range vibe.Moods {
+		shares[mood] = float64(count) / float64(vibe.Tags)
+	}
+	return shares
+}
+
+// vibeScore scores how well a media item matches a room's vibe, from 0 to 1. Energy counts for most
+// of the score; moods count as far as the media shares them with the room, when both have any.
+func vibeScore(room *models.RoomVibe, media *models.MediaVibe) float64 {
+	energy := 1 - math.Abs(media.Energy-room.Energy)/vibeEnergyRange
+
+	shares := moodShares(media)
+	if len(shares) == 0 || len(room.Moods) == 0 {
+		return roundScore(energy)
+	}
+
+	var overlap, roomTotal, mediaTotal float64
+	for mood, share := range room.Moods {
+		overlap += min(share, shares[mood])
+		roomTotal += share
+	}
+	for _, share := range shares {
+		mediaTotal += share
+	}
+	mood := overlap / max(roomTotal, mediaTotal)
+
+	return roundScore(vibeEnergyWeight*energy + (1-vibeEnergyWeight)*mood)
+}
+
+// roundScore rounds a score to three decimals.
+func roundScore(score float64) float64 {
+	return math.Round(score*1000) / 1000
+}
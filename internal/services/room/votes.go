@@ -0,0 +1,119 @@
+package room
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// defaultVoteGrace is the vote grace period of rooms that don't set one, covering clock and network delays.
+const defaultVoteGrace = 5 * time.Second
+
+// voteRules returns the vote rules set by a room's settings.
+func voteRules(settings models.RoomSettings) managers.VoteRules {
+	rules := managers.VoteRules{
+		Grace:          time.Duration(settings.VoteGracePeriod) * time.Second,
+		SkipVoteCutoff: float64(settings.SkipVoteCutoff) / 100,
+		SkipThreshold:  float64(settings.SkipThreshold) / 100,
+	}
+	if rules.Grace == 0 {
+		rules.Grace = defaultVoteGrace
+	}
+	return rules
+}
+
+// validateVoteSettings checks the vote labels and reactions of room settings.
+func validateVoteSettings(settings models.RoomSettings) error {
+	if err := utils.GetValidator().StructPartial(settings, "VoteLabels", "Reactions"); err != nil {
+		return models.NewRoomError(models.ErrInvalidVoteSettings, err.Error(), http.StatusBadRequest)
+	}
+	return nil
+}
+
+// Vote records a user's vote on the media playing in a room and returns the updated vote and reaction counts.
+// Votes outside the media's play time are rejected with models.ErrVoteWindowClosed. The meh that passes
+// the room's skip threshold has the media skipped.
+func (m *Manager) Vote(ctx context.Context, roomID, userID bson.ObjectID, voteType string) (map[string]int, error) {
+	state, settings, err := m.voteState(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	skip, err := m.stateManager.RecordVote(ctx, roomID.Hex(), userID.Hex(), state.CurrentMedia, voteType, voteRules(settings))
+	if err != nil {
+		return nil, err
+	}
+
+	votes, err := m.stateManager.GetVotes(ctx, roomID.Hex(), state.CurrentMedia, settings.Reactions)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaID, _ := bson.ObjectIDFromHex(state.CurrentMedia)
+	m.emitActivity(ctx, RoomActivity{Type: ActivityVote, RoomID: roomID, UserID: userID, MediaID: mediaID, Vote: voteType, Votes: votes})
+
+	if skip {
+		djID, _ := bson.ObjectIDFromHex(state.CurrentDJ)
+		m.skipVoted(ctx, SkipVote{RoomID: roomID, MediaID: mediaID, DJID: djID, Votes: votes})
+	}
+
+	return votes, nil
+}
+
+// React adds a user's reaction to the media playing in a room, or takes it back if they already added it,
+// and returns the updated vote and reaction counts. Reactions the room didn't enable are rejected with
+// models.ErrReactionDisabled.
+func (m *Manager) React(ctx context.Context, roomID, userID bson.ObjectID, reaction string) (map[string]int, error) {
+	state, settings, err := m.voteState(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(settings.Reactions, reaction) {
+		return nil, models.ErrReactionDisabled
+	}
+
+	added, err := m.stateManager.ToggleReaction(ctx, roomID.Hex(), userID.Hex(), state.CurrentMedia, reaction, voteRules(settings))
+	if err != nil {
+		return nil, err
+	}
+
+	votes, err := m.stateManager.GetVotes(ctx, roomID.Hex(), state.CurrentMedia, settings.Reactions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only added reactions are activity, taking one back is not
+	mediaID, _ := bson.ObjectIDFromHex(state.CurrentMedia)
+	if added {
+		m.emitActivity(ctx, RoomActivity{Type: ActivityReaction, RoomID: roomID, UserID: userID, MediaID: mediaID, Reaction: reaction, Votes: votes})
+	}
+
+	return votes, nil
+}
+
+// voteState gets the state of a room with media playing, and the settings its votes follow.
+func (m *Manager) voteState(ctx context.Context, roomID bson.ObjectID) (*managers.RoomState, models.RoomSettings, error) {
+	state, err := m.stateReader.GetRoomState(ctx, roomID.Hex())
+	if err != nil {
+		return nil, models.RoomSettings{}, err
+	}
+	if state == nil || state.CurrentMedia == "" {
+		return nil, models.RoomSettings{}, models.ErrVoteWindowClosed
+	}
+
+	settings, ok := decodeSettings(state.Data["settings"])
+	if !ok {
+		room, err := m.GetRoom(ctx, roomID)
+		if err != nil {
+			return nil, models.RoomSettings{}, err
+		}
+		settings = room.Settings
+	}
+	return state, settings, nil
+}
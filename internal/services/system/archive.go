@@ -0,0 +1,619 @@
+// Package system provides system-level services for monitoring and maintenance.
+package system
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// historyArchivesCollection is the manifest collection listing the archive files.
+	historyArchivesCollection = "history_archives"
+
+	// historyRedactionsCollection queues the purged users whose records are still to be removed from the archive files.
+	historyRedactionsCollection = "history_redactions"
+
+	// archiveIDField marks history records restored from an archive, so they aren't archived again.
+	archiveIDField = "archiveId"
+
+	// maxArchiveLineSize is the largest history record read back from an archive file.
+	maxArchiveLineSize = 16 * 1024 * 1024
+
+	// restoreChunkSize is the number of records inserted at a time when restoring an archive.
+	restoreChunkSize = 1000
+)
+
+// archivedHistoryCollections are the history collections moved to the archive, with the field holding each record's time.
+var archivedHistoryCollections = map[string]string{
+	"play_history":    "startTime",
+	"user_history":    "timestamp",
+	"session_history": "startTime",
+}
+
+// Archive errors
+var (
+	ErrArchiveCorrupt         = errors.New("history archive file does not match its checksum")
+	ErrArchiveRequiresMongoDB = errors.New("history archiving requires MongoDB")
+)
+
+// ArchiveStore stores history archive files, typically in object storage.
+type ArchiveStore interface {
+	// Put stores a file under a key, replacing any file with the same key.
+	Put(ctx context.Context, key string, data io.Reader) error
+
+	// Get opens the file stored under a key.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the file stored under a key, if any.
+	Delete(ctx context.Context, key string) error
+}
+
+// FileArchiveStore stores archive files in a directory, such as a mounted object storage bucket.
+type FileArchiveStore struct {
+	dir string
+}
+
+// NewFileArchiveStore creates an archive store writing to a directory.
+func NewFileArchiveStore(dir string) *FileArchiveStore {
+	return &FileArchiveStore{dir: dir}
+}
+
+// Put writes a file, going through a temporary file so a partly written file is never visible under its key.
+func (s *FileArchiveStore) Put(ctx context.Context, key string, data io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".archive-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get opens a file.
+func (s *FileArchiveStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Delete removes a file, succeeding when there is none.
+func (s *FileArchiveStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path returns the path of a key, refusing keys that would leave the store's directory.
+func (s *FileArchiveStore) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid archive key %q", key)
+	}
+	return filepath.Join(s.dir, clean), nil
+}
+
+// HistoryArchive is a manifest entry describing an archive file.
+type HistoryArchive struct {
+	ID         bson.ObjectID `json:"id" bson:"_id"`
+	Collection string        `json:"collection" bson:"collection"`
+	Key        string        `json:"key" bson:"key"`
+	From       time.Time     `json:"from" bson:"from"`   // Time of the oldest record in the file
+	To         time.Time     `json:"to" bson:"to"`       // Time of the newest record in the file
+	Count      int           `json:"count" bson:"count"` // Number of records in the file
+	Size       int64         `json:"size" bson:"size"`   // Compressed size in bytes
+	Checksum   string        `json:"checksum" bson:"checksum"`
+	CreatedAt  time.Time     `json:"createdAt" bson:"createdAt"`
+	RestoredAt *time.Time    `json:"restoredAt,omitempty" bson:"restoredAt,omitempty"`
+	RedactedAt *time.Time    `json:"redactedAt,omitempty" bson:"redactedAt,omitempty"` // Last time records of purged users were removed
+}
+
+// HistoryArchiveConfig controls which history is archived.
+type HistoryArchiveConfig struct {
+	// After is the hot retention window; older history records are moved to the archive.
+	After time.Duration
+
+	// BatchSize is the number of records per archive file.
+	BatchSize int
+}
+
+// HistoryArchiveService moves old play, user and session history out of MongoDB into compressed
+// JSON Lines files, one record per line in relaxed extended JSON, and lists them in a manifest
+// collection. Archived records can be restored for analysis and released again afterwards.
+type HistoryArchiveService struct {
+	mongoDB *mongo.Database
+	store   ArchiveStore
+	config  HistoryArchiveConfig
+	logger  *utils.Logger
+}
+
+// NewHistoryArchiveService creates a new history archive service. A nil database disables archiving.
+func NewHistoryArchiveService(mongoDB *mongo.Database, store ArchiveStore, config HistoryArchiveConfig, logger *utils.Logger) *HistoryArchiveService {
+	return &HistoryArchiveService{
+		mongoDB: mongoDB,
+		store:   store,
+		config:  config,
+		logger:  logger.Named("history_archive_service"),
+	}
+}
+
+// ArchiveHistory moves history records older than the hot retention window to the archive.
+func (s *HistoryArchiveService) ArchiveHistory(ctx context.Context) error {
+	if s.mongoDB == nil {
+		return nil
+	}
+
+	cutoff := time.Now().Add(-s.config.After)
+	total := 0
+	for collection, timeField := range archivedHistoryCollections {
+		archived, err := s.archiveCollection(ctx, collection, timeField, cutoff)
+		total += archived
+		if err != nil {
+			return fmt.Errorf("failed to archive %s: %w", collection, err)
+		}
+	}
+
+	s.logger.Info("History archiving completed", "archivedCount", total, "cutoff", cutoff)
+	return nil
+}
+
+// archiveCollection archives the old records of a history collection a file at a time.
+func (s *HistoryArchiveService) archiveCollection(ctx context.Context, collection, timeField string, cutoff time.Time) (int, error) {
+	coll := s.mongoDB.Collection(collection)
+	filter := bson.M{
+		timeField:      bson.M{"$lt": cutoff},
+		archiveIDField: bson.M{"$exists": false},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: timeField, Value: 1}}).SetLimit(int64(s.config.BatchSize))
+
+	archived := 0
+	for {
+		cursor, err := coll.Find(ctx, filter, opts)
+		if err != nil {
+			return archived, err
+		}
+		var records []bson.Raw
+		if err := cursor.All(ctx, &records); err != nil {
+			return archived, err
+		}
+		if len(records) == 0 {
+			return archived, nil
+		}
+
+		if err := s.archiveBatch(ctx, coll, timeField, records); err != nil {
+			return archived, err
+		}
+		archived += len(records)
+
+		if len(records) < s.config.BatchSize {
+			return archived, nil
+		}
+	}
+}
+
+// archiveBatch writes records to an archive file, lists it in the manifest and only then deletes the records.
+// A failure part way leaves the records in place, at worst archived twice.
+func (s *HistoryArchiveService) archiveBatch(ctx context.Context, coll *mongo.Collection, timeField string, records []bson.Raw) error {
+	lines := make([]any, 0, len(records))
+	ids := make([]any, 0, len(records))
+	for _, record := range records {
+		lines = append(lines, record)
+		ids = append(ids, record.Lookup("_id"))
+	}
+	buf, checksum, err := encodeArchive(lines)
+	if err != nil {
+		return err
+	}
+
+	archive := &HistoryArchive{
+		ID:         bson.NewObjectID(),
+		Collection: coll.Name(),
+		From:       records[0].Lookup(timeField).Time(),
+		To:         records[len(records)-1].Lookup(timeField).Time(),
+		Count:      len(records),
+		Size:       int64(buf.Len()),
+		Checksum:   checksum,
+		CreatedAt:  time.Now(),
+	}
+	archive.Key = fmt.Sprintf("history/%s/%s-%s.jsonl.gz", archive.Collection, archive.From.UTC().Format("20060102T150405Z"), archive.ID.Hex())
+
+	if err := s.store.Put(ctx, archive.Key, buf); err != nil {
+		return fmt.Errorf("failed to store archive file: %w", err)
+	}
+	if _, err := s.mongoDB.Collection(historyArchivesCollection).InsertOne(ctx, archive); err != nil {
+		return fmt.Errorf("failed to record archive in manifest: %w", err)
+	}
+	if _, err := coll.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+		return fmt.Errorf("failed to delete archived records: %w", err)
+	}
+
+	s.logger.Debug("Archived history records", "collection", archive.Collection, "key", archive.Key, "count", archive.Count)
+	return nil
+}
+
+// ListArchives lists the archive files of a history collection, or of all of them, newest first.
+func (s *HistoryArchiveService) ListArchives(ctx context.Context, collection string, limit int) ([]*HistoryArchive, error) {
+	if s.mongoDB == nil {
+		return nil, ErrArchiveRequiresMongoDB
+	}
+
+	filter := bson.M{}
+	if collection != "" {
+		filter["collection"] = collection
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "from", Value: -1}}).SetLimit(int64(limit))
+
+	cursor, err := s.mongoDB.Collection(historyArchivesCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	archives := []*HistoryArchive{}
+	if err := cursor.All(ctx, &archives); err != nil {
+		return nil, err
+	}
+	return archives, nil
+}
+
+// RestoreArchive copies the records of an archive file back into their collection, tagged with the
+// archive's ID so they aren't archived again. It returns the number of records restored.
+func (s *HistoryArchiveService) RestoreArchive(ctx context.Context, archiveID bson.ObjectID) (int, error) {
+	archive, err := s.findArchive(ctx, archiveID)
+	if err != nil {
+		return 0, err
+	}
+	if archive.RestoredAt != nil {
+		return 0, models.ErrArchiveRestored
+	}
+
+	records, err := s.readArchive(ctx, archive)
+	if err != nil {
+		return 0, err
+	}
+
+	coll := s.mongoDB.Collection(archive.Collection)
+	restored := 0
+	for start := 0; start < len(records); start += restoreChunkSize {
+		chunk := records[start:min(start+restoreChunkSize, len(records))]
+		result, err := coll.InsertMany(ctx, chunk, options.InsertMany().SetOrdered(false))
+		if result != nil {
+			restored += len(result.InsertedIDs)
+		}
+		// Records that are still in the collection, from an interrupted archive run, are left as they are
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return restored, err
+		}
+	}
+
+	now := time.Now()
+	_, err = s.mongoDB.Collection(historyArchivesCollection).UpdateByID(ctx, archive.ID, bson.M{"$set": bson.M{"restoredAt": now}})
+	if err != nil {
+		return restored, err
+	}
+
+	s.logger.Info("Restored history archive", "archiveId", archive.ID.Hex(), "collection", archive.Collection, "restoredCount", restored)
+	return restored, nil
+}
+
+// ReleaseArchive deletes the records restored from an archive once they are no longer needed.
+// The archive file itself is kept. It returns the number of records deleted.
+func (s *HistoryArchiveService) ReleaseArchive(ctx context.Context, archiveID bson.ObjectID) (int64, error) {
+	archive, err := s.findArchive(ctx, archiveID)
+	if err != nil {
+		return 0, err
+	}
+	if archive.RestoredAt == nil {
+		return 0, models.ErrArchiveNotRestored
+	}
+
+	result, err := s.mongoDB.Collection(archive.Collection).DeleteMany(ctx, bson.M{archiveIDField: archive.ID})
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = s.mongoDB.Collection(historyArchivesCollection).UpdateByID(ctx, archive.ID, bson.M{"$unset": bson.M{"restoredAt": ""}})
+	if err != nil {
+		return result.DeletedCount, err
+	}
+
+	s.logger.Info("Released restored history archive", "archiveId", archive.ID.Hex(), "collection", archive.Collection, "deletedCount", result.DeletedCount)
+	return result.DeletedCount, nil
+}
+
+// RedactUser queues a purged user's records for removal from the archive files. The files are rewritten
+// by RedactArchives, so purging an account doesn't wait on reading the whole archive back.
+func (s *HistoryArchiveService) RedactUser(ctx context.Context, userID bson.ObjectID) error {
+	if s.mongoDB == nil {
+		return nil
+	}
+
+	_, err := s.mongoDB.Collection(historyRedactionsCollection).UpdateByID(ctx, userID,
+		bson.M{"$setOnInsert": bson.M{"requestedAt": time.Now()}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	return err
+}
+
+// RedactArchives rewrites the archive files holding records of the users queued by RedactUser, the way
+// purging an account treats the history still in MongoDB: their own user and session history is dropped
+// and the plays they DJed or skipped are detached from them. It runs as a maintenance task.
+func (s *HistoryArchiveService) RedactArchives(ctx context.Context) error {
+	if s.mongoDB == nil {
+		return nil
+	}
+
+	cursor, err := s.mongoDB.Collection(historyRedactionsCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	var queued []struct {
+		ID bson.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &queued); err != nil {
+		return err
+	}
+	if len(queued) == 0 {
+		return nil
+	}
+
+	users := make(map[bson.ObjectID]bool, len(queued))
+	ids := make([]bson.ObjectID, 0, len(queued))
+	for _, entry := range queued {
+		users[entry.ID] = true
+		ids = append(ids, entry.ID)
+	}
+
+	cursor, err = s.mongoDB.Collection(historyArchivesCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	var archives []*HistoryArchive
+	if err := cursor.All(ctx, &archives); err != nil {
+		return err
+	}
+
+	// Users stay queued until every file is rewritten, so a failed run is picked up again by the next one
+	redacted := 0
+	for _, archive := range archives {
+		count, err := s.redactArchive(ctx, archive, users)
+		if err != nil {
+			return fmt.Errorf("failed to redact archive %s: %w", archive.ID.Hex(), err)
+		}
+		redacted += count
+	}
+
+	if _, err := s.mongoDB.Collection(historyRedactionsCollection).DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+		return err
+	}
+
+	s.logger.Info("History archive redaction completed", "users", len(ids), "redactedCount", redacted)
+	return nil
+}
+
+// redactArchive rewrites an archive file without the records of the given users, under a new key. It
+// returns the number of records removed or detached, zero when the file holds none of them.
+func (s *HistoryArchiveService) redactArchive(ctx context.Context, archive *HistoryArchive, users map[bson.ObjectID]bool) (int, error) {
+	records, err := s.decodeArchive(ctx, archive)
+	if err != nil {
+		return 0, err
+	}
+
+	kept := make([]any, 0, len(records))
+	redacted := 0
+	for _, record := range records {
+		if archive.Collection == "play_history" {
+			if redactPlay(&record, users) {
+				redacted++
+			}
+			kept = append(kept, record)
+			continue
+		}
+		if userID, ok := recordUser(record, "userId"); ok && users[userID] {
+			redacted++
+			continue
+		}
+		kept = append(kept, record)
+	}
+	if redacted == 0 {
+		return 0, nil
+	}
+
+	buf, checksum, err := encodeArchive(kept)
+	if err != nil {
+		return 0, err
+	}
+
+	// Write the new file before pointing the manifest at it, so the manifest never lists a missing file
+	oldKey := archive.Key
+	key := fmt.Sprintf("history/%s/%s-%s-%s.jsonl.gz", archive.Collection, archive.From.UTC().Format("20060102T150405Z"), archive.ID.Hex(), bson.NewObjectID().Hex())
+	size := int64(buf.Len())
+	if err := s.store.Put(ctx, key, buf); err != nil {
+		return 0, fmt.Errorf("failed to store archive file: %w", err)
+	}
+	_, err = s.mongoDB.Collection(historyArchivesCollection).UpdateByID(ctx, archive.ID, bson.M{"$set": bson.M{
+		"key":        key,
+		"count":      len(kept),
+		"size":       size,
+		"checksum":   checksum,
+		"redactedAt": time.Now(),
+	}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to record redacted archive in manifest: %w", err)
+	}
+	if err := s.store.Delete(ctx, oldKey); err != nil {
+		s.logger.Error("Failed to delete archive file replaced by redaction", err, "key", oldKey)
+		// Continue anyway, the manifest no longer lists it
+	}
+
+	s.logger.Debug("Redacted history archive", "archiveId", archive.ID.Hex(), "key", key, "redactedCount", redacted)
+	return redacted, nil
+}
+
+// redactPlay detaches an archived play from the given users, as its DJ or the user who skipped it.
+// It returns whether the play changed.
+func redactPlay(record *bson.D, users map[bson.ObjectID]bool) bool {
+	detachedDJ, detachedSkip := false, false
+	if userID, ok := recordUser(*record, "djId"); ok && users[userID] {
+		detachedDJ = true
+	}
+	if userID, ok := recordUser(*record, "skippedBy"); ok && users[userID] {
+		detachedSkip = true
+	}
+	if !detachedDJ && !detachedSkip {
+		return false
+	}
+
+	play := make(bson.D, 0, len(*record))
+	for _, field := range *record {
+		switch {
+		case field.Key == "djId" && detachedDJ:
+			field.Value = bson.NilObjectID
+		case field.Key == "dj" && detachedDJ:
+			// The DJ's embedded profile goes with their ID
+			field.Value = models.PublicUser{BaseUser: models.BaseUser{Username: models.DeletedUsername}}
+		case field.Key == "skippedBy" && detachedSkip:
+			continue
+		}
+		play = append(play, field)
+	}
+	*record = play
+	return true
+}
+
+// recordUser gets a user ID field of an archived record.
+func recordUser(record bson.D, key string) (bson.ObjectID, bool) {
+	for _, field := range record {
+		if field.Key == key {
+			userID, ok := field.Value.(bson.ObjectID)
+			return userID, ok
+		}
+	}
+	return bson.NilObjectID, false
+}
+
+// findArchive finds an archive in the manifest.
+func (s *HistoryArchiveService) findArchive(ctx context.Context, archiveID bson.ObjectID) (*HistoryArchive, error) {
+	if s.mongoDB == nil {
+		return nil, ErrArchiveRequiresMongoDB
+	}
+
+	var archive HistoryArchive
+	err := s.mongoDB.Collection(historyArchivesCollection).FindOne(ctx, bson.M{"_id": archiveID}).Decode(&archive)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrArchiveNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &archive, nil
+}
+
+// readArchive reads the records of an archive file to restore, tagged with the archive's ID.
+func (s *HistoryArchiveService) readArchive(ctx context.Context, archive *HistoryArchive) ([]any, error) {
+	decoded, err := s.decodeArchive(ctx, archive)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]any, 0, len(decoded))
+	for _, record := range decoded {
+		records = append(records, append(record, bson.E{Key: archiveIDField, Value: archive.ID}))
+	}
+	return records, nil
+}
+
+// decodeArchive reads the records of an archive file, checking it against the checksum in the manifest.
+func (s *HistoryArchiveService) decodeArchive(ctx context.Context, archive *HistoryArchive) ([]bson.D, error) {
+	file, err := s.store.Get(ctx, archive.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive file: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive file: %w", err)
+	}
+	checksum := sha256.Sum256(data)
+	if hex.EncodeToString(checksum[:]) != archive.Checksum {
+		return nil, ErrArchiveCorrupt
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	records := make([]bson.D, 0, archive.Count)
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), maxArchiveLineSize)
+	for scanner.Scan() {
+		var record bson.D
+		if err := bson.UnmarshalExtJSON(scanner.Bytes(), false, &record); err != nil {
+			return nil, fmt.Errorf("failed to decode archived record: %w", err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// encodeArchive writes records as a compressed JSON Lines file, and returns it along with its checksum.
+func encodeArchive(records []any) (*bytes.Buffer, string, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	for _, record := range records {
+		line, err := bson.MarshalExtJSON(record, false, false)
+		if err != nil {
+			return nil, "", err
+		}
+		gz.Write(line)
+		gz.Write([]byte{'\n'})
+	}
+	if err := gz.Close(); err != nil {
+		return nil, "", err
+	}
+
+	checksum := sha256.Sum256(buf.Bytes())
+	return &buf, hex.EncodeToString(checksum[:]), nil
+}
@@ -3,7 +3,9 @@ package system
 
 import (
 	"context"
+	"maps"
 	"runtime"
+	"slices"
 	"sync"
 	"time"
 
@@ -67,6 +69,11 @@ type HealthService struct {
 	componentCache map[string]ComponentHealth
 	cacheMutex     sync.RWMutex
 	checkInterval  time.Duration
+
+	handlersMutex sync.RWMutex
+
+	// checkHandlers are notified of the result of each component check
+	checkHandlers []func(ctx context.Context, component ComponentHealth)
 }
 
 // HealthServiceConfig contains configuration for the health service.
@@ -94,6 +101,13 @@ func NewHealthService(
 	}
 }
 
+// AddCheckHandler adds a handler notified of the result of each component check.
+func (s *HealthService) AddCheckHandler(handler func(ctx context.Context, component ComponentHealth)) {
+	s.handlersMutex.Lock()
+	defer s.handlersMutex.Unlock()
+	s.checkHandlers = append(s.checkHandlers, handler)
+}
+
 // Start begins periodic health checks.
 func (s *HealthService) Start(ctx context.Context) {
 	s.logger.Info("Starting health service")
@@ -122,11 +136,26 @@ func (s *HealthService) Start(ctx context.Context) {
 func (s *HealthService) CheckHealth(ctx context.Context) {
 	s.logger.Debug("Performing health check")
 
-	// Check MongoDB
-	s.checkMongoDB(ctx)
+	// Check MongoDB, unless the server runs on in-memory repositories
+	if s.mongoClient != nil {
+		s.checkMongoDB(ctx)
+	}
 
 	// Check Redis
 	s.checkRedis(ctx)
+
+	s.cacheMutex.RLock()
+	components := slices.Collect(maps.Values(s.componentCache))
+	s.cacheMutex.RUnlock()
+
+	s.handlersMutex.RLock()
+	handlers := s.checkHandlers
+	s.handlersMutex.RUnlock()
+	for _, component := range components {
+		for _, handler := range handlers {
+			handler(ctx, component)
+		}
+	}
 }
 
 // GetHealth returns the current health status of the system.
@@ -19,9 +19,13 @@ import (
 // MaintenanceTask represents a maintenance task to be executed.
 type MaintenanceTask struct {
 	Name     string
+	Class    TaskClass
 	Interval time.Duration
 	LastRun  time.Time
 	Fn       func(context.Context) error
+
+	// deferredUntil is the end of the quiet window the task is currently waiting out
+	deferredUntil time.Time
 }
 
 // MaintenanceConfig contains configuration for the maintenance service.
@@ -36,6 +40,8 @@ type MaintenanceConfig struct {
 	LogMaxAge time.Duration
 	// Maximum age of history records before cleanup
 	HistoryMaxAge time.Duration
+	// Whether old play, user and session history is archived instead of cleaned up
+	HistoryArchived bool
 	// Maximum age of inactive rooms before cleanup
 	InactiveRoomMaxAge time.Duration
 	// Interval for running maintenance tasks
@@ -44,6 +50,10 @@ type MaintenanceConfig struct {
 	MaxConcurrentTasks int
 	// Timeout for individual maintenance tasks
 	TaskTimeout time.Duration
+	// Daily windows per task class during which those tasks are deferred
+	QuietHours map[TaskClass][]QuietWindow
+	// Time zone the quiet windows are given in, UTC if nil
+	QuietHoursLocation *time.Location
 }
 
 // DefaultMaintenanceConfig returns the default maintenance configuration.
@@ -105,29 +115,33 @@ func NewMaintenanceService(
 	}
 
 	// Register default maintenance tasks
-	s.RegisterTask("temp_file_cleanup", config.MaintenanceInterval, s.CleanupTempFiles)
-	s.RegisterTask("inactive_room_cleanup", config.MaintenanceInterval, s.CleanupInactiveRooms)
-	s.RegisterTask("history_cleanup", config.MaintenanceInterval, s.CleanupHistory)
-	s.RegisterTask("database_optimization", 24*time.Hour, s.OptimizeDatabase)
-	s.RegisterTask("cache_cleanup", config.MaintenanceInterval, s.CleanupCache)
+	s.RegisterTask("temp_file_cleanup", TaskClassLight, config.MaintenanceInterval, s.CleanupTempFiles)
+	if mongoDB != nil {
+		// These tasks operate on MongoDB collections directly and are skipped with in-memory repositories
+		s.RegisterTask("inactive_room_cleanup", TaskClassCleanup, config.MaintenanceInterval, s.CleanupInactiveRooms)
+		s.RegisterTask("history_cleanup", TaskClassCleanup, config.MaintenanceInterval, s.CleanupHistory)
+		s.RegisterTask("database_optimization", TaskClassOptimization, 24*time.Hour, s.OptimizeDatabase)
+	}
+	s.RegisterTask("cache_cleanup", TaskClassLight, config.MaintenanceInterval, s.CleanupCache)
 
 	return s
 }
 
-// RegisterTask registers a new maintenance task.
-func (s *MaintenanceService) RegisterTask(name string, interval time.Duration, fn func(context.Context) error) {
+// RegisterTask registers a new maintenance task. The task's class decides which quiet hours apply to it.
+func (s *MaintenanceService) RegisterTask(name string, class TaskClass, interval time.Duration, fn func(context.Context) error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	task := &MaintenanceTask{
 		Name:     name,
+		Class:    class,
 		Interval: interval,
 		LastRun:  time.Now().Add(-interval), // Schedule to run immediately
 		Fn:       fn,
 	}
 
 	s.tasks = append(s.tasks, task)
-	s.logger.Info("Registered maintenance task", "name", name, "interval", interval)
+	s.logger.Info("Registered maintenance task", "name", name, "class", class, "interval", interval)
 }
 
 // Start starts the maintenance service.
@@ -139,12 +153,13 @@ func (s *MaintenanceService) Start(ctx context.Context) error {
 
 	s.logger.Info("Starting maintenance service")
 
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
-
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
+
+		ticker := time.NewTicker(1 * time.Minute)
+		defer ticker.Stop()
+
 		defer func() {
 			if r := recover(); r != nil {
 				s.logger.Error("Panic in maintenance service", fmt.Errorf("%v", r))
@@ -244,9 +259,20 @@ func (s *MaintenanceService) runDueTasks(ctx context.Context) {
 	now := time.Now()
 
 	for _, task := range s.tasks {
-		if now.Sub(task.LastRun) >= task.Interval {
-			dueTasks = append(dueTasks, task)
+		if now.Sub(task.LastRun) < task.Interval {
+			continue
 		}
+
+		// Heavy tasks wait out their quiet hours, the skipped window is logged once
+		if until, window := s.config.quietUntil(task.Class, now); !until.IsZero() {
+			if !task.deferredUntil.Equal(until) {
+				task.deferredUntil = until
+				s.logger.Info("Deferring maintenance task during quiet hours", "name", task.Name, "class", task.Class, "window", window.String(), "resumesAt", until)
+			}
+			continue
+		}
+
+		dueTasks = append(dueTasks, task)
 	}
 	s.mu.Unlock()
 
@@ -389,6 +415,9 @@ func (s *MaintenanceService) CleanupHistory(ctx context.Context) error {
 
 	var totalDeleted int64
 	for _, collName := range historyCollections {
+		if _, archived := archivedHistoryCollections[collName]; archived && s.config.HistoryArchived {
+			continue
+		}
 		collection := s.mongoDB.Collection(collName)
 		result, err := collection.DeleteMany(ctx, filter)
 		if err != nil {
@@ -0,0 +1,394 @@
+// Package system provides system-level services for monitoring and maintenance.
+package system
+
+import (
+	"context"
+	"errors"
+	"math"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// metricsHistoryCollection is the time-series collection holding metrics snapshots.
+	metricsHistoryCollection = "metrics_history"
+
+	// pingSamplesPerInterval is how many Redis and MongoDB pings are taken per recording interval.
+	pingSamplesPerInterval = 12
+
+	// mongoNamespaceExists is the MongoDB error code for creating a collection that already exists.
+	mongoNamespaceExists = 48
+)
+
+// ConnectionCounter reports the number of live WebSocket connections.
+type ConnectionCounter interface {
+	GetClientCount() int
+}
+
+// BroadcastReporter reports, per shard of the WebSocket server, the room broadcasts waiting for
+// delivery and the total dropped because their room was over its backlog.
+type BroadcastReporter interface {
+	BroadcastBacklog() (waiting []int, dropped []uint64)
+}
+
+// RequestCounter reports the cumulative number of RPC requests handled.
+type RequestCounter interface {
+	RequestCount() uint64
+}
+
+// AdmissionReporter reports whether joins on this node are constrained by its load.
+type AdmissionReporter interface {
+	Constrained() bool
+	BroadcastLatency() time.Duration
+}
+
+// TransitionReporter reports how long each room track change took since the last report, and how
+// many were late or missed.
+type TransitionReporter interface {
+	TakeTransitionTimings() (durations []time.Duration, late, missed int)
+}
+
+// LatencyPercentiles summarizes sampled durations in milliseconds.
+type LatencyPercentiles struct {
+	P50     float64 `json:"p50_ms" bson:"p50"`
+	P95     float64 `json:"p95_ms" bson:"p95"`
+	P99     float64 `json:"p99_ms" bson:"p99"`
+	Samples int     `json:"samples" bson:"samples"`
+}
+
+// MetricsSnapshot is a single point in the metrics history.
+type MetricsSnapshot struct {
+	Timestamp        time.Time           `json:"timestamp" bson:"timestamp"`
+	Connections      int                 `json:"connections" bson:"connections"`
+	ActiveRooms      int64               `json:"active_rooms" bson:"activeRooms"`
+	RPCRequests      int64               `json:"rpc_requests" bson:"rpcRequests"`     // Requests handled during the interval
+	RPCThroughput    float64             `json:"rpc_throughput" bson:"rpcThroughput"` // Requests per second during the interval
+	GoRoutines       int                 `json:"go_routines" bson:"goRoutines"`
+	HeapAlloc        uint64              `json:"heap_alloc_bytes" bson:"heapAlloc"`
+	RedisPingRTT     LatencyPercentiles  `json:"redis_ping_rtt" bson:"redisPingRtt"`                     // Round trips of Redis PINGs, not of the commands the node runs
+	MongoPingRTT     *LatencyPercentiles `json:"mongo_ping_rtt,omitempty" bson:"mongoPingRtt,omitempty"` // Round trips of MongoDB pings, not of its queries
+	BroadcastLatency float64             `json:"broadcast_latency_ms" bson:"broadcastLatency"`
+	JoinsConstrained bool                `json:"joins_constrained" bson:"joinsConstrained"` // Whether room capacities were reduced under load
+	BroadcastShards  []BroadcastShard    `json:"broadcast_shards,omitempty" bson:"broadcastShards,omitempty"`
+	QueueTransitions *QueueTransitions   `json:"queue_transitions,omitempty" bson:"queueTransitions,omitempty"`
+}
+
+// QueueTransitions summarizes the track changes of rooms during an interval.
+type QueueTransitions struct {
+	Duration LatencyPercentiles `json:"duration" bson:"duration"` // Time each track change took, sampled once per change
+	Late     int                `json:"late" bson:"late"`         // Track changes long after the track's expected end
+	Missed   int                `json:"missed" bson:"missed"`     // Tracks rooms didn't move on from
+}
+
+// BroadcastShard is the room broadcast delivery of a shard of the WebSocket server.
+type BroadcastShard struct {
+	Waiting int   `json:"waiting" bson:"waiting"` // Messages waiting for delivery
+	Dropped int64 `json:"dropped" bson:"dropped"` // Messages dropped during the interval
+}
+
+// MetricsHistoryService periodically records operational gauges for capacity planning.
+// Snapshots go to a MongoDB time-series collection, or are kept in memory when MongoDB is not used.
+type MetricsHistoryService struct {
+	mongoDB     *mongo.Database
+	redisClient *redis.Client
+	roomRepo    repositories.RoomRepository
+	connections ConnectionCounter
+	broadcasts  BroadcastReporter
+	requests    RequestCounter
+	admission   AdmissionReporter
+	transitions TransitionReporter
+	interval    time.Duration
+	retention   time.Duration
+	logger      *utils.Logger
+
+	mu           sync.Mutex
+	redisPings   []time.Duration
+	mongoPings   []time.Duration
+	lastRequests uint64
+	lastDropped  []uint64
+	lastRecord   time.Time
+	memory       []MetricsSnapshot
+}
+
+// NewMetricsHistoryService creates a new metrics history service.
+func NewMetricsHistoryService(
+	mongoDB *mongo.Database,
+	redisClient *redis.Client,
+	roomRepo repositories.RoomRepository,
+	connections ConnectionCounter,
+	broadcasts BroadcastReporter,
+	requests RequestCounter,
+	admission AdmissionReporter,
+	transitions TransitionReporter,
+	interval time.Duration,
+	retention time.Duration,
+	logger *utils.Logger,
+) *MetricsHistoryService {
+	return &MetricsHistoryService{
+		mongoDB:     mongoDB,
+		redisClient: redisClient,
+		roomRepo:    roomRepo,
+		connections: connections,
+		broadcasts:  broadcasts,
+		requests:    requests,
+		admission:   admission,
+		transitions: transitions,
+		interval:    interval,
+		retention:   retention,
+		logger:      logger.Named("metrics_history_service"),
+	}
+}
+
+// Start prepares the history collection and begins recording snapshots.
+func (s *MetricsHistoryService) Start(ctx context.Context) error {
+	if s.interval <= 0 {
+		s.logger.Info("Metrics history is disabled")
+		return nil
+	}
+
+	if s.mongoDB != nil {
+		if err := s.ensureCollection(ctx); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.lastRequests = s.requests.RequestCount()
+	s.lastRecord = time.Now()
+	s.mu.Unlock()
+
+	go func() {
+		sampleTicker := time.NewTicker(max(s.interval/pingSamplesPerInterval, time.Second))
+		defer sampleTicker.Stop()
+		recordTicker := time.NewTicker(s.interval)
+		defer recordTicker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				s.logger.Info("Stopping metrics history service")
+				return
+			case <-sampleTicker.C:
+				s.samplePings(ctx)
+			case <-recordTicker.C:
+				if _, err := s.Record(ctx); err != nil {
+					s.logger.Error("Failed to record metrics snapshot", err)
+				}
+			}
+		}
+	}()
+
+	s.logger.Info("Metrics history service started", "interval", s.interval, "retention", s.retention)
+	return nil
+}
+
+// ensureCollection creates the time-series collection with its retention if it does not exist yet.
+func (s *MetricsHistoryService) ensureCollection(ctx context.Context) error {
+	opts := options.CreateCollection().
+		SetTimeSeriesOptions(options.TimeSeries().SetTimeField("timestamp").SetGranularity("minutes"))
+	if s.retention > 0 {
+		opts.SetExpireAfterSeconds(int64(s.retention.Seconds()))
+	}
+
+	err := s.mongoDB.CreateCollection(ctx, metricsHistoryCollection, opts)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == mongoNamespaceExists {
+		return nil
+	}
+	return err
+}
+
+// samplePings pings Redis and MongoDB once and keeps the round-trip times for the current interval.
+// They measure the connection to each store, not how long the node's own commands take.
+func (s *MetricsHistoryService) samplePings(ctx context.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	redisErr := s.redisClient.Ping(pingCtx)
+	redisRTT := time.Since(start)
+
+	var mongoRTT time.Duration
+	var mongoErr error
+	if s.mongoDB != nil {
+		start = time.Now()
+		mongoErr = s.mongoDB.Client().Ping(pingCtx, nil)
+		mongoRTT = time.Since(start)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Failed pings are left out so outages don't masquerade as slow round trips
+	if redisErr == nil {
+		s.redisPings = append(s.redisPings, redisRTT)
+	}
+	if s.mongoDB != nil && mongoErr == nil {
+		s.mongoPings = append(s.mongoPings, mongoRTT)
+	}
+}
+
+// Record captures a snapshot of the current gauges and stores it.
+func (s *MetricsHistoryService) Record(ctx context.Context) (*MetricsSnapshot, error) {
+	activeRooms, err := s.roomRepo.CountRooms(ctx, bson.M{"isActive": true})
+	if err != nil {
+		s.logger.Error("Failed to count active rooms", err)
+		// Continue anyway, the other gauges are still useful
+	}
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	s.mu.Lock()
+	now := time.Now()
+	requests := s.requests.RequestCount()
+	elapsed := now.Sub(s.lastRecord).Seconds()
+
+	snapshot := &MetricsSnapshot{
+		Timestamp:    now.UTC(),
+		Connections:  s.connections.GetClientCount(),
+		ActiveRooms:  activeRooms,
+		RPCRequests:  int64(requests - s.lastRequests),
+		GoRoutines:   runtime.NumGoroutine(),
+		HeapAlloc:    memStats.HeapAlloc,
+		RedisPingRTT: percentiles(s.redisPings),
+	}
+	if s.admission != nil {
+		snapshot.BroadcastLatency = float64(s.admission.BroadcastLatency()) / float64(time.Millisecond)
+		snapshot.JoinsConstrained = s.admission.Constrained()
+	}
+	if s.broadcasts != nil {
+		waiting, dropped := s.broadcasts.BroadcastBacklog()
+		snapshot.BroadcastShards = make([]BroadcastShard, len(waiting))
+		for i := range waiting {
+			snapshot.BroadcastShards[i].Waiting = waiting[i]
+			if i < len(dropped) {
+				var last uint64
+				if i < len(s.lastDropped) {
+					last = s.lastDropped[i]
+				}
+				snapshot.BroadcastShards[i].Dropped = int64(dropped[i] - last)
+			}
+		}
+		s.lastDropped = dropped
+	}
+	if s.transitions != nil {
+		durations, late, missed := s.transitions.TakeTransitionTimings()
+		snapshot.QueueTransitions = &QueueTransitions{
+			Duration: percentiles(durations),
+			Late:     late,
+			Missed:   missed,
+		}
+	}
+	if elapsed > 0 {
+		snapshot.RPCThroughput = float64(snapshot.RPCRequests) / elapsed
+	}
+	if s.mongoDB != nil {
+		mongoRTT := percentiles(s.mongoPings)
+		snapshot.MongoPingRTT = &mongoRTT
+	}
+
+	s.lastRequests = requests
+	s.lastRecord = now
+	s.redisPings = s.redisPings[:0]
+	s.mongoPings = s.mongoPings[:0]
+
+	if s.mongoDB == nil {
+		s.memory = append(s.memory, *snapshot)
+		s.pruneMemory(now)
+		s.mu.Unlock()
+		return snapshot, nil
+	}
+	s.mu.Unlock()
+
+	if _, err := s.mongoDB.Collection(metricsHistoryCollection).InsertOne(ctx, snapshot); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// pruneMemory drops in-memory snapshots older than the retention. The caller must hold the lock.
+func (s *MetricsHistoryService) pruneMemory(now time.Time) {
+	if s.retention <= 0 {
+		return
+	}
+
+	cutoff := now.Add(-s.retention)
+	keep := 0
+	for keep < len(s.memory) && s.memory[keep].Timestamp.Before(cutoff) {
+		keep++
+	}
+	s.memory = slices.Delete(s.memory, 0, keep)
+}
+
+// GetRange returns the snapshots recorded between from and to, oldest first.
+func (s *MetricsHistoryService) GetRange(ctx context.Context, from, to time.Time, limit int) ([]MetricsSnapshot, error) {
+	if s.mongoDB == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		snapshots := make([]MetricsSnapshot, 0)
+		for _, snapshot := range s.memory {
+			if snapshot.Timestamp.Before(from) || snapshot.Timestamp.After(to) {
+				continue
+			}
+			if limit > 0 && len(snapshots) >= limit {
+				break
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+		return snapshots, nil
+	}
+
+	filter := bson.M{"timestamp": bson.M{"$gte": from.UTC(), "$lte": to.UTC()}}
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	cursor, err := s.mongoDB.Collection(metricsHistoryCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	snapshots := make([]MetricsSnapshot, 0)
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+// percentiles summarizes latency samples using the nearest-rank method.
+func percentiles(samples []time.Duration) LatencyPercentiles {
+	if len(samples) == 0 {
+		return LatencyPercentiles{}
+	}
+
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+
+	rank := func(p float64) float64 {
+		index := int(math.Ceil(p*float64(len(sorted)))) - 1
+		index = max(0, min(index, len(sorted)-1))
+		return float64(sorted[index].Microseconds()) / 1000
+	}
+
+	return LatencyPercentiles{
+		P50:     rank(0.50),
+		P95:     rank(0.95),
+		P99:     rank(0.99),
+		Samples: len(sorted),
+	}
+}
@@ -0,0 +1,129 @@
+// Package system provides system-level services for monitoring and maintenance.
+package system
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TaskClass groups maintenance tasks by how much load they put on the system.
+type TaskClass string
+
+const (
+	// TaskClassLight is for cheap tasks that are always allowed to run.
+	TaskClassLight TaskClass = "light"
+
+	// TaskClassCleanup is for tasks that delete stale records in bulk.
+	TaskClassCleanup TaskClass = "cleanup"
+
+	// TaskClassOptimization is for database compaction and index maintenance.
+	TaskClassOptimization TaskClass = "optimization"
+
+	// TaskClassBackfill is for tasks that rewrite existing records, such as deduplication.
+	TaskClassBackfill TaskClass = "backfill"
+)
+
+// QuietWindow is a daily time range during which a class of maintenance tasks must not run.
+// A window whose end is before its start wraps past midnight.
+type QuietWindow struct {
+	// Start is the offset from midnight at which the window opens.
+	Start time.Duration
+
+	// End is the offset from midnight at which the window closes.
+	End time.Duration
+}
+
+// ParseQuietWindow parses a window written as "HH:MM-HH:MM".
+func ParseQuietWindow(spec string) (QuietWindow, error) {
+	startStr, endStr, found := strings.Cut(spec, "-")
+	if !found {
+		return QuietWindow{}, fmt.Errorf("quiet window %q must be written as HH:MM-HH:MM", spec)
+	}
+
+	start, err := parseClock(strings.TrimSpace(startStr))
+	if err != nil {
+		return QuietWindow{}, fmt.Errorf("quiet window %q: %w", spec, err)
+	}
+	end, err := parseClock(strings.TrimSpace(endStr))
+	if err != nil {
+		return QuietWindow{}, fmt.Errorf("quiet window %q: %w", spec, err)
+	}
+	if start == end {
+		return QuietWindow{}, fmt.Errorf("quiet window %q is empty", spec)
+	}
+
+	return QuietWindow{Start: start, End: end}, nil
+}
+
+// ParseQuietHours parses quiet windows configured per task class.
+func ParseQuietHours(spec map[string][]string) (map[TaskClass][]QuietWindow, error) {
+	quietHours := make(map[TaskClass][]QuietWindow, len(spec))
+	for class, windows := range spec {
+		switch TaskClass(class) {
+		case TaskClassLight, TaskClassCleanup, TaskClassOptimization, TaskClassBackfill:
+		default:
+			return nil, fmt.Errorf("unknown maintenance task class %q", class)
+		}
+
+		for _, windowSpec := range windows {
+			window, err := ParseQuietWindow(windowSpec)
+			if err != nil {
+				return nil, err
+			}
+			quietHours[TaskClass(class)] = append(quietHours[TaskClass(class)], window)
+		}
+	}
+	return quietHours, nil
+}
+
+// parseClock parses a time of day written as "HH:MM" into an offset from midnight.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Until returns when the window containing t closes, or the zero time if t is outside the window.
+func (w QuietWindow) Until(t time.Time) time.Time {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+
+	switch {
+	case w.Start < w.End && offset >= w.Start && offset < w.End:
+		return midnight.Add(w.End)
+	case w.Start > w.End && offset >= w.Start:
+		// Opened today, closes tomorrow
+		return midnight.AddDate(0, 0, 1).Add(w.End)
+	case w.Start > w.End && offset < w.End:
+		// Opened yesterday, closes today
+		return midnight.Add(w.End)
+	default:
+		return time.Time{}
+	}
+}
+
+// String formats the window as "HH:MM-HH:MM".
+func (w QuietWindow) String() string {
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return clock(w.Start) + "-" + clock(w.End)
+}
+
+// quietUntil returns when the quiet hours keeping a class of tasks from running at t end,
+// or the zero time if the class may run.
+func (c MaintenanceConfig) quietUntil(class TaskClass, t time.Time) (time.Time, QuietWindow) {
+	if c.QuietHoursLocation != nil {
+		t = t.In(c.QuietHoursLocation)
+	}
+
+	for _, window := range c.QuietHours[class] {
+		if until := window.Until(t); !until.IsZero() {
+			return until, window
+		}
+	}
+	return time.Time{}, QuietWindow{}
+}
@@ -0,0 +1,516 @@
+// Package system provides system-level services for monitoring and maintenance.
+package system
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	r "github.com/go-redis/redis/v8"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// redisMemoryLockKey makes sure a single instance measures and trims Redis memory at a time.
+	redisMemoryLockKey = "redis_memory:lock"
+
+	// redisMemoryReportKey holds the report of the last measurement.
+	redisMemoryReportKey = "redis_memory:report"
+
+	// redisScanCount is the number of keys asked for per SCAN call.
+	redisScanCount = 1000
+
+	// redisTrimBatch is the number of keys deleted at once while trimming.
+	redisTrimBatch = 500
+)
+
+// errBudgetMet stops trimming once Redis is back under its budget.
+var errBudgetMet = errors.New("redis memory budget met")
+
+// redisKeyFamily groups the Redis keys of one kind of data.
+type redisKeyFamily struct {
+	name      string
+	patterns  []string
+	trimmable bool     // Whether the keys can be deleted under memory pressure, only true for caches
+	keep      []string // Keys of the family that are never trimmed
+}
+
+// redisKeyFamilies lists the key families memory is broken down by. Keys matching none of them are
+// counted as unattributed, together with the overhead of Redis itself.
+var redisKeyFamilies = []redisKeyFamily{
+	{
+		name:     "sessions",
+		patterns: []string{managers.SessionKeyPrefix + ":*", managers.TokenKeyPrefix + ":*"},
+	},
+	{
+		name: "room_state",
+		patterns: []string{
+			managers.RoomStateKeyPrefix + ":*",
+			managers.RoomUsersKeyPrefix + ":*",
+			managers.RoomJoinsKeyPrefix + ":*",
+			managers.RoomListenersKeyPrefix + ":*",
+			managers.RoomChatShardsKeyPrefix + ":*",
+			managers.RoomChatShardSizesKeyPrefix + ":*",
+			managers.RoomQueueKeyPrefix + ":*",
+			managers.RoomMediaKeyPrefix + ":*",
+			managers.RoomHistoryKeyPrefix + ":*",
+			managers.RoomGeoKeyPrefix + ":*",
+			"room:plan:*",
+		},
+	},
+	{
+		name:     "votes",
+		patterns: []string{managers.RoomVotesKeyPrefix + ":*"},
+	},
+	{
+		name:     "presence",
+		patterns: []string{managers.PresenceKeyPrefix + ":*", managers.OnlineUsersKey},
+	},
+	{
+		name:     "rate_limits",
+		patterns: []string{redis.RateLimitKeyPrefix + ":*", "apikey:rate:*"},
+	},
+	{
+		name:      "caches",
+		patterns:  []string{"lobby:*", "search:cache:*", "calendar:*"},
+		trimmable: true,
+		keep:      []string{"lobby:invalidated"}, // Losing it would serve listings invalidated since they were cached
+	},
+}
+
+// RedisMemoryPolicy controls how Redis memory is measured and kept under its limit.
+type RedisMemoryPolicy struct {
+	// Interval is how often memory is measured, 0 disables measuring and trimming.
+	Interval time.Duration
+
+	// TrimThreshold is the share of maxmemory above which caches are trimmed, ahead of Redis evicting keys itself.
+	TrimThreshold float64
+
+	// SampleSize is the number of keys per family whose memory usage is measured, the rest is extrapolated.
+	SampleSize int
+
+	// VoteMediaPerRoom is the number of media per room whose votes are kept, 0 for no limit.
+	VoteMediaPerRoom int
+}
+
+// RedisFamilyUsage is the estimated memory usage of a key family.
+type RedisFamilyUsage struct {
+	Name           string `json:"name"`
+	Keys           int64  `json:"keys"`
+	SampledKeys    int    `json:"sampled_keys"`
+	EstimatedBytes int64  `json:"estimated_bytes"`
+	Trimmable      bool   `json:"trimmable"`
+}
+
+// RedisMemoryReport is the outcome of a measurement of Redis memory.
+type RedisMemoryReport struct {
+	MeasuredAt      time.Time          `json:"measured_at"`
+	UsedMemory      int64              `json:"used_memory_bytes"`
+	MaxMemory       int64              `json:"max_memory_bytes"` // 0 when Redis has no limit
+	EvictionPolicy  string             `json:"eviction_policy"`
+	SessionsAtRisk  bool               `json:"sessions_at_risk"` // Whether Redis may evict sessions when it reaches maxmemory
+	Families        []RedisFamilyUsage `json:"families"`
+	Unattributed    int64              `json:"unattributed_bytes"`
+	TrimmedKeys     int64              `json:"trimmed_keys"`
+	TrimmedVoteKeys int64              `json:"trimmed_vote_keys"`
+	DurationMs      int64              `json:"duration_ms"`
+}
+
+// RedisMemoryService breaks Redis memory usage down by key family, caps the votes kept per room and
+// trims caches before Redis reaches maxmemory, so it doesn't start evicting sessions and room state.
+type RedisMemoryService struct {
+	redisClient *redis.Client
+	policy      RedisMemoryPolicy
+	logger      *utils.Logger
+}
+
+// NewRedisMemoryService creates a new Redis memory service.
+func NewRedisMemoryService(redisClient *redis.Client, policy RedisMemoryPolicy, logger *utils.Logger) *RedisMemoryService {
+	if policy.SampleSize < 1 {
+		policy.SampleSize = 1
+	}
+
+	return &RedisMemoryService{
+		redisClient: redisClient,
+		policy:      policy,
+		logger:      logger.Named("redis_memory_service"),
+	}
+}
+
+// Start begins measuring and trimming Redis memory. It runs on its own ticker rather than as a
+// maintenance task, since quiet hours must not hold back trimming.
+func (s *RedisMemoryService) Start(ctx context.Context) {
+	if s.policy.Interval <= 0 {
+		s.logger.Info("Redis memory budgeting is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.policy.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				s.logger.Info("Stopping Redis memory service")
+				return
+			case <-ticker.C:
+				if err := s.Enforce(ctx); err != nil {
+					s.logger.Error("Failed to enforce Redis memory budget", err)
+				}
+			}
+		}
+	}()
+
+	s.logger.Info("Redis memory service started", "interval", s.policy.Interval, "trimThreshold", s.policy.TrimThreshold)
+}
+
+// Enforce caps the votes kept per room, measures memory and trims caches when memory is above the
+// trim threshold. Only one instance enforces the budget each interval.
+func (s *RedisMemoryService) Enforce(ctx context.Context) error {
+	startedAt := time.Now()
+
+	claimed, err := s.redisClient.Client().SetNX(ctx, redisMemoryLockKey, "1", s.policy.Interval/2).Result()
+	if err != nil || !claimed {
+		return err
+	}
+
+	trimmedVotes, err := s.capVotes(ctx)
+	if err != nil {
+		s.logger.Error("Failed to cap room votes", err)
+	}
+
+	report, err := s.Measure(ctx)
+	if err != nil {
+		return err
+	}
+	report.TrimmedVoteKeys = trimmedVotes
+
+	if budget := s.budget(report); budget > 0 && report.UsedMemory > budget {
+		trimmed, err := s.trimCaches(ctx, budget)
+		if err != nil {
+			s.logger.Error("Failed to trim caches", err)
+		}
+		report.TrimmedKeys = trimmed
+
+		if used, _, _, err := s.memoryInfo(ctx); err == nil {
+			report.UsedMemory = used
+		}
+		s.logger.Warn("Trimmed caches ahead of Redis eviction",
+			"trimmedKeys", trimmed,
+			"usedMemory", report.UsedMemory,
+			"budget", budget,
+			"maxMemory", report.MaxMemory,
+		)
+	}
+
+	if report.SessionsAtRisk && report.MaxMemory > 0 && float64(report.UsedMemory) > s.policy.TrimThreshold*float64(report.MaxMemory) {
+		s.logger.Warn("Redis is close to evicting sessions, caches alone can't keep it under its limit",
+			"usedMemory", report.UsedMemory,
+			"maxMemory", report.MaxMemory,
+			"evictionPolicy", report.EvictionPolicy,
+		)
+	}
+
+	report.DurationMs = time.Since(startedAt).Milliseconds()
+	if err := s.redisClient.SetObject(ctx, redisMemoryReportKey, report, 0); err != nil {
+		s.logger.Error("Failed to store Redis memory report", err)
+	}
+
+	s.logger.Debug("Measured Redis memory",
+		"usedMemory", report.UsedMemory,
+		"maxMemory", report.MaxMemory,
+		"trimmedVoteKeys", report.TrimmedVoteKeys,
+		"durationMs", report.DurationMs,
+	)
+	return ctx.Err()
+}
+
+// Measure breaks the current Redis memory usage down by key family.
+func (s *RedisMemoryService) Measure(ctx context.Context) (*RedisMemoryReport, error) {
+	used, maxMemory, policy, err := s.memoryInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RedisMemoryReport{
+		MeasuredAt:     time.Now(),
+		UsedMemory:     used,
+		MaxMemory:      maxMemory,
+		EvictionPolicy: policy,
+		SessionsAtRisk: strings.HasPrefix(policy, "allkeys-"),
+		Unattributed:   used,
+	}
+
+	for _, family := range redisKeyFamilies {
+		usage, err := s.measureFamily(ctx, family)
+		if err != nil {
+			return nil, err
+		}
+		report.Families = append(report.Families, usage)
+		report.Unattributed -= usage.EstimatedBytes
+	}
+	report.Unattributed = max(report.Unattributed, 0)
+
+	return report, nil
+}
+
+// GetReport gets the report of the last measurement, nil if there was none yet.
+func (s *RedisMemoryService) GetReport(ctx context.Context) (*RedisMemoryReport, error) {
+	data, err := s.redisClient.Get(ctx, redisMemoryReportKey)
+	if err != nil || data == "" {
+		return nil, err
+	}
+
+	var report RedisMemoryReport
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// budget gets the memory Redis may use before caches are trimmed, 0 when Redis has no limit.
+func (s *RedisMemoryService) budget(report *RedisMemoryReport) int64 {
+	if report.MaxMemory <= 0 || s.policy.TrimThreshold <= 0 {
+		return 0
+	}
+	return int64(s.policy.TrimThreshold * float64(report.MaxMemory))
+}
+
+// memoryInfo reads the memory used by Redis, its limit and its eviction policy.
+func (s *RedisMemoryService) memoryInfo(ctx context.Context) (used, maxMemory int64, policy string, err error) {
+	info, err := s.redisClient.Client().Info(ctx, "memory").Result()
+	if err != nil {
+		return 0, 0, "", err
+	}
+
+	for line := range strings.Lines(info) {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		switch name {
+		case "used_memory":
+			used, _ = strconv.ParseInt(value, 10, 64)
+		case "maxmemory":
+			maxMemory, _ = strconv.ParseInt(value, 10, 64)
+		case "maxmemory_policy":
+			policy = value
+		}
+	}
+	return used, maxMemory, policy, nil
+}
+
+// measureFamily counts the keys of a family and estimates their memory from a sample of them.
+func (s *RedisMemoryService) measureFamily(ctx context.Context, family redisKeyFamily) (RedisFamilyUsage, error) {
+	usage := RedisFamilyUsage{Name: family.name, Trimmable: family.trimmable}
+
+	var sample []string
+	err := s.scanFamily(ctx, family, func(keys []string) error {
+		usage.Keys += int64(len(keys))
+		if room := s.policy.SampleSize - len(sample); room > 0 {
+			sample = append(sample, keys[:min(room, len(keys))]...)
+		}
+		return nil
+	})
+	if err != nil || len(sample) == 0 {
+		return usage, err
+	}
+
+	pipe := s.redisClient.Pipeline()
+	cmds := make([]*r.IntCmd, len(sample))
+	for i, key := range sample {
+		cmds[i] = pipe.MemoryUsage(ctx, key)
+	}
+	// Keys that expired since they were scanned fail with a nil reply and are left out
+	_, _ = pipe.Exec(ctx)
+
+	var sampledBytes int64
+	for _, cmd := range cmds {
+		if bytes := cmd.Val(); bytes > 0 {
+			sampledBytes += bytes
+			usage.SampledKeys++
+		}
+	}
+	if usage.SampledKeys > 0 {
+		usage.EstimatedBytes = sampledBytes * usage.Keys / int64(usage.SampledKeys)
+	}
+	return usage, nil
+}
+
+// scanFamily scans the keys of a family, handing them over a batch at a time.
+func (s *RedisMemoryService) scanFamily(ctx context.Context, family redisKeyFamily, fn func(keys []string) error) error {
+	for _, pattern := range family.patterns {
+		var cursor uint64
+		for {
+			keys, next, err := s.redisClient.Client().Scan(ctx, cursor, pattern, redisScanCount).Result()
+			if err != nil {
+				return err
+			}
+
+			keys = slices.DeleteFunc(keys, func(key string) bool { return slices.Contains(family.keep, key) })
+			if len(keys) > 0 {
+				if err := fn(keys); err != nil {
+					return err
+				}
+			}
+
+			cursor = next
+			if cursor == 0 {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// trimCaches deletes cached entries until Redis uses less memory than the budget or no caches are left.
+func (s *RedisMemoryService) trimCaches(ctx context.Context, budget int64) (int64, error) {
+	var trimmed int64
+	var batch []string
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		deleted, err := s.redisClient.Client().Unlink(ctx, batch...).Result()
+		trimmed += deleted
+		batch = batch[:0]
+		if err != nil {
+			return err
+		}
+
+		used, _, _, err := s.memoryInfo(ctx)
+		if err != nil {
+			return err
+		}
+		if used <= budget {
+			return errBudgetMet
+		}
+		return nil
+	}
+
+	for _, family := range redisKeyFamilies {
+		if !family.trimmable {
+			continue
+		}
+
+		err := s.scanFamily(ctx, family, func(keys []string) error {
+			for _, key := range keys {
+				batch = append(batch, key)
+				if len(batch) >= redisTrimBatch {
+					if err := flush(); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err == nil {
+			err = flush()
+		}
+		if errors.Is(err, errBudgetMet) {
+			return trimmed, nil
+		}
+		if err != nil {
+			return trimmed, err
+		}
+	}
+	return trimmed, nil
+}
+
+// capVotes deletes the votes of all but the most recently voted media of each room. Vote counts
+// never expire on their own, so without a cap they pile up for every media a room ever played.
+func (s *RedisMemoryService) capVotes(ctx context.Context) (int64, error) {
+	if s.policy.VoteMediaPerRoom <= 0 {
+		return 0, nil
+	}
+
+	prefix := managers.RoomVotesKeyPrefix + ":"
+	rooms := make(map[string]map[string][]string)
+	err := s.scanFamily(ctx, redisKeyFamily{patterns: []string{prefix + "*"}}, func(keys []string) error {
+		for _, key := range keys {
+			parts := strings.SplitN(strings.TrimPrefix(key, prefix), ":", 3)
+			if len(parts) < 2 {
+				continue
+			}
+			media, ok := rooms[parts[0]]
+			if !ok {
+				media = make(map[string][]string)
+				rooms[parts[0]] = media
+			}
+			media[parts[1]] = append(media[parts[1]], key)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var trimmed int64
+	for roomID, media := range rooms {
+		if len(media) <= s.policy.VoteMediaPerRoom {
+			continue
+		}
+
+		deleted, err := s.capRoomVotes(ctx, media)
+		trimmed += deleted
+		if err != nil {
+			s.logger.Error("Failed to cap room votes", err, "roomId", roomID)
+		}
+		if ctx.Err() != nil {
+			return trimmed, ctx.Err()
+		}
+	}
+	return trimmed, nil
+}
+
+// capRoomVotes deletes the votes of all but the most recently voted media of a room. Votes on a media
+// are kept for a day from the last one cast, so the media whose votes expire last were voted on last.
+func (s *RedisMemoryService) capRoomVotes(ctx context.Context, media map[string][]string) (int64, error) {
+	pipe := s.redisClient.Pipeline()
+	ttls := make(map[string][]*r.DurationCmd, len(media))
+	for mediaID, keys := range media {
+		for _, key := range keys {
+			ttls[mediaID] = append(ttls[mediaID], pipe.TTL(ctx, key))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	// Counts don't expire, so media with only counts left rank last
+	expiries := make(map[string]time.Duration, len(media))
+	mediaIDs := make([]string, 0, len(media))
+	for mediaID, cmds := range ttls {
+		expiry := time.Duration(-1)
+		for _, cmd := range cmds {
+			expiry = max(expiry, cmd.Val())
+		}
+		expiries[mediaID] = expiry
+		mediaIDs = append(mediaIDs, mediaID)
+	}
+	slices.SortFunc(mediaIDs, func(a, b string) int { return cmp.Compare(expiries[b], expiries[a]) })
+
+	var stale []string
+	for _, mediaID := range mediaIDs[s.policy.VoteMediaPerRoom:] {
+		stale = append(stale, media[mediaID]...)
+	}
+
+	var trimmed int64
+	for batch := range slices.Chunk(stale, redisTrimBatch) {
+		deleted, err := s.redisClient.Client().Unlink(ctx, batch...).Result()
+		trimmed += deleted
+		if err != nil {
+			return trimmed, err
+		}
+	}
+	return trimmed, nil
+}
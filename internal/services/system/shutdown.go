@@ -0,0 +1,101 @@
+package system
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// ShutdownStep is a piece of work done while the server shuts down.
+type ShutdownStep struct {
+	// Name identifies the step in the logs.
+	Name string
+
+	// Run does the work, giving up once the context of its phase ends.
+	Run func(ctx context.Context) error
+}
+
+// shutdownPhase is a group of steps that run together and must finish before the next phase starts.
+type shutdownPhase struct {
+	name    string
+	timeout time.Duration
+	steps   []ShutdownStep
+}
+
+// ShutdownSequence shuts the server down in ordered phases, each with its own timeout, so a slow
+// phase can't eat the time of the phases after it. The steps of a phase run concurrently. A phase
+// that runs out of time is abandoned and the sequence moves on, leaving its unfinished steps running.
+type ShutdownSequence struct {
+	phases []shutdownPhase
+	logger *utils.Logger
+}
+
+// NewShutdownSequence creates a new, empty shutdown sequence.
+func NewShutdownSequence(logger *utils.Logger) *ShutdownSequence {
+	return &ShutdownSequence{
+		logger: logger.Named("shutdown"),
+	}
+}
+
+// AddPhase adds a phase after the phases added before it.
+func (s *ShutdownSequence) AddPhase(name string, timeout time.Duration, steps ...ShutdownStep) {
+	s.phases = append(s.phases, shutdownPhase{
+		name:    name,
+		timeout: timeout,
+		steps:   steps,
+	})
+}
+
+// Run runs the phases in order, logging their progress.
+func (s *ShutdownSequence) Run() {
+	started := time.Now()
+	for i, phase := range s.phases {
+		s.logger.Info("Starting shutdown phase", "phase", phase.name, "number", i+1, "phases", len(s.phases), "timeout", phase.timeout)
+		phaseStarted := time.Now()
+
+		if s.runPhase(phase) {
+			s.logger.Info("Finished shutdown phase", "phase", phase.name, "duration", time.Since(phaseStarted))
+		} else {
+			s.logger.Warn("Shutdown phase timed out, moving on", "phase", phase.name, "timeout", phase.timeout)
+		}
+	}
+
+	s.logger.Info("Shutdown sequence complete", "duration", time.Since(started))
+}
+
+// runPhase runs the steps of a phase and waits for them until the phase times out.
+// It returns whether every step finished in time.
+func (s *ShutdownSequence) runPhase(phase shutdownPhase) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), phase.timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, step := range phase.steps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			stepStarted := time.Now()
+			if err := step.Run(ctx); err != nil {
+				s.logger.Error("Shutdown step failed", err, "phase", phase.name, "step", step.Name)
+				return
+			}
+			s.logger.Debug("Finished shutdown step", "phase", phase.name, "step", step.Name, "duration", time.Since(stepStarted))
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,257 @@
+package system
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// snapshotRateKeyPrefix prefixes the per-minute request counters of the snapshot service tokens.
+const snapshotRateKeyPrefix = "snapshot:rate:"
+
+// SnapshotPolicy controls the platform state snapshot read by the analytics pipeline.
+type SnapshotPolicy struct {
+	// Tokens are the service tokens internal callers authenticate with. No tokens disables the snapshot.
+	Tokens []string
+
+	// RateLimit is the number of snapshots a token may request per minute.
+	RateLimit int
+
+	// CacheTTL is how long a generated snapshot is served before another is generated.
+	CacheTTL time.Duration
+}
+
+// SnapshotQueueEntry is a DJ waiting in a room's queue.
+type SnapshotQueueEntry struct {
+	UserID    string    `json:"userId"`
+	Position  int       `json:"position"`
+	JoinTime  time.Time `json:"joinTime"`
+	PlayCount int       `json:"playCount"`
+}
+
+// RoomSnapshot is the state of an active room in a platform state snapshot.
+type RoomSnapshot struct {
+	ID             string               `json:"id"`
+	Slug           string               `json:"slug"`
+	Name           string               `json:"name"`
+	OwnerID        string               `json:"ownerId"`
+	Users          int64                `json:"users"`
+	Listeners      int64                `json:"listeners"` // Listener-only users in the room's overflow
+	CurrentDJ      string               `json:"currentDj,omitempty"`
+	CurrentMedia   string               `json:"currentMedia,omitempty"`
+	MediaStartTime time.Time            `json:"mediaStartTime,omitzero"`
+	MediaEndTime   time.Time            `json:"mediaEndTime,omitzero"`
+	Queue          []SnapshotQueueEntry `json:"queue"`
+}
+
+// SnapshotTotals sums the rooms of a platform state snapshot.
+type SnapshotTotals struct {
+	Rooms     int   `json:"rooms"`
+	Users     int64 `json:"users"`
+	Listeners int64 `json:"listeners"`
+	QueuedDJs int   `json:"queuedDjs"`
+	Playing   int   `json:"playing"` // Rooms playing media
+}
+
+// PlatformSnapshot is the current state of the platform's active rooms as one document. The real-time
+// state of every room is read at the same point in time.
+type PlatformSnapshot struct {
+	GeneratedAt time.Time      `json:"generatedAt"`
+	Totals      SnapshotTotals `json:"totals"`
+	Rooms       []RoomSnapshot `json:"rooms"` // Most users first
+}
+
+// SnapshotService generates platform state snapshots for the analytics pipeline. Snapshots are read
+// by internal callers authenticated with a service token, rate limited per token, and generated at
+// most once per cache period however many callers ask for them.
+type SnapshotService struct {
+	roomRepo     repositories.RoomRepository
+	stateManager *managers.RoomStateManager
+	redisClient  *redis.Client
+	policy       SnapshotPolicy
+	logger       *utils.Logger
+
+	// tokenHashes are the SHA-256 hashes of the service tokens, compared in constant time
+	tokenHashes [][]byte
+
+	// buildMu lets a single snapshot be generated at a time, callers arriving meanwhile get it too
+	buildMu      sync.Mutex
+	mu           sync.Mutex
+	snapshot     *PlatformSnapshot
+	snapshotTime time.Time
+}
+
+// NewSnapshotService creates a new platform state snapshot service.
+func NewSnapshotService(roomRepo repositories.RoomRepository, stateManager *managers.RoomStateManager, redisClient *redis.Client, policy SnapshotPolicy, logger *utils.Logger) *SnapshotService {
+	tokenHashes := make([][]byte, 0, len(policy.Tokens))
+	for _, token := range policy.Tokens {
+		hash := sha256.Sum256([]byte(token))
+		tokenHashes = append(tokenHashes, hash[:])
+	}
+
+	return &SnapshotService{
+		roomRepo:     roomRepo,
+		stateManager: stateManager,
+		redisClient:  redisClient,
+		policy:       policy,
+		logger:       logger.Named("snapshot_service"),
+		tokenHashes:  tokenHashes,
+	}
+}
+
+// Enabled reports whether any service token can read snapshots.
+func (s *SnapshotService) Enabled() bool {
+	return len(s.tokenHashes) > 0
+}
+
+// AuthenticateServiceToken checks a service token and counts the request against the token's per-minute window.
+func (s *SnapshotService) AuthenticateServiceToken(ctx context.Context, token string) error {
+	hash := sha256.Sum256([]byte(token))
+	if token == "" || !slices.ContainsFunc(s.tokenHashes, func(known []byte) bool {
+		return subtle.ConstantTimeCompare(known, hash[:]) == 1
+	}) {
+		return models.ErrInvalidToken
+	}
+
+	// Tokens are counted by hash, so they never end up in Redis
+	window := time.Now().Truncate(time.Minute).Unix()
+	key := fmt.Sprintf("%s%s:%d", snapshotRateKeyPrefix, hex.EncodeToString(hash[:8]), window)
+
+	count, err := s.redisClient.Incr(ctx, key)
+	if err != nil {
+		s.logger.Error("Failed to check snapshot rate limit", err)
+		return nil // Fail open, snapshots are cached and callers are internal
+	}
+	if count == 1 {
+		if err := s.redisClient.Expire(ctx, key, 2*time.Minute); err != nil {
+			s.logger.Error("Failed to set snapshot rate limit expiry", err)
+		}
+	}
+
+	if count > int64(s.policy.RateLimit) {
+		return models.ErrTooManyRequests
+	}
+	return nil
+}
+
+// GetSnapshot gets the current platform state snapshot. It is generated at most once per cache period.
+func (s *SnapshotService) GetSnapshot(ctx context.Context) (*PlatformSnapshot, error) {
+	if snapshot := s.cached(); snapshot != nil {
+		return snapshot, nil
+	}
+
+	s.buildMu.Lock()
+	defer s.buildMu.Unlock()
+
+	// Another caller may have generated it while this one waited
+	if snapshot := s.cached(); snapshot != nil {
+		return snapshot, nil
+	}
+
+	snapshot, err := s.buildSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.snapshot, s.snapshotTime = snapshot, snapshot.GeneratedAt
+	s.mu.Unlock()
+	return snapshot, nil
+}
+
+// cached gets the last generated snapshot while it is fresh.
+func (s *SnapshotService) cached() *PlatformSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.snapshot != nil && time.Since(s.snapshotTime) < s.policy.CacheTTL {
+		return s.snapshot
+	}
+	return nil
+}
+
+// buildSnapshot generates a snapshot of the active rooms. Their real-time state is read from Redis in
+// a single transaction, their names and owners come from the stored rooms.
+func (s *SnapshotService) buildSnapshot(ctx context.Context) (*PlatformSnapshot, error) {
+	rooms, err := s.roomRepo.FindMany(ctx, bson.M{"isActive": true}, nil)
+	if err != nil {
+		s.logger.Error("Failed to find active rooms for snapshot", err)
+		return nil, err
+	}
+
+	roomIDs := make([]string, 0, len(rooms))
+	for _, room := range rooms {
+		roomIDs = append(roomIDs, room.ID.Hex())
+	}
+
+	states, err := s.stateManager.SnapshotRooms(ctx, roomIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &PlatformSnapshot{
+		GeneratedAt: time.Now(),
+		Rooms:       make([]RoomSnapshot, 0, len(rooms)),
+	}
+	for _, room := range rooms {
+		roomSnapshot := RoomSnapshot{
+			ID:      room.ID.Hex(),
+			Slug:    room.Slug,
+			Name:    room.Name,
+			OwnerID: room.CreatedBy.Hex(),
+			Queue:   []SnapshotQueueEntry{},
+		}
+
+		if state := states[roomSnapshot.ID]; state != nil {
+			roomSnapshot.Users = state.Users
+			roomSnapshot.Listeners = state.Listeners
+			if state.State != nil {
+				roomSnapshot.CurrentDJ = state.State.CurrentDJ
+				roomSnapshot.CurrentMedia = state.State.CurrentMedia
+				roomSnapshot.MediaStartTime = state.State.MediaStartTime
+				roomSnapshot.MediaEndTime = state.State.MediaEndTime
+			}
+			for _, entry := range state.Queue {
+				roomSnapshot.Queue = append(roomSnapshot.Queue, SnapshotQueueEntry{
+					UserID:    entry.UserID,
+					Position:  entry.Position,
+					JoinTime:  entry.JoinTime,
+					PlayCount: entry.PlayCount,
+				})
+			}
+		}
+
+		snapshot.Totals.Users += roomSnapshot.Users
+		snapshot.Totals.Listeners += roomSnapshot.Listeners
+		snapshot.Totals.QueuedDJs += len(roomSnapshot.Queue)
+		if roomSnapshot.CurrentMedia != "" {
+			snapshot.Totals.Playing++
+		}
+		snapshot.Rooms = append(snapshot.Rooms, roomSnapshot)
+	}
+	snapshot.Totals.Rooms = len(snapshot.Rooms)
+
+	slices.SortFunc(snapshot.Rooms, func(a, b RoomSnapshot) int {
+		if a.Users != b.Users {
+			return cmp.Compare(b.Users, a.Users)
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	s.logger.Debug("Generated platform state snapshot", "rooms", snapshot.Totals.Rooms, "users", snapshot.Totals.Users)
+	return snapshot, nil
+}
@@ -0,0 +1,467 @@
+// Package system provides system-level services for monitoring and maintenance.
+package system
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	r "github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// uptimeKeyPrefix prefixes the daily counters of each component's health checks.
+	uptimeKeyPrefix = "status:uptime:"
+
+	// incidentLockPrefix prefixes the locks keeping instances from opening the same automatic incident.
+	incidentLockPrefix = "status:incident:"
+
+	// incidentLockTTL is how long an instance holds the lock while opening an automatic incident.
+	incidentLockTTL = time.Minute
+)
+
+// uptimeWindows are the spans, in days, uptime percentages are reported for.
+var uptimeWindows = []int{1, 7, 30, 90}
+
+// StatusPolicy controls the public status page feed.
+type StatusPolicy struct {
+	// IncidentAfterFailures is the number of consecutive failed health checks of a component after
+	// which an incident is opened for it. Zero disables automatic incidents.
+	IncidentAfterFailures int
+
+	// UptimeDays is how many days of health checks are kept for uptime percentages.
+	UptimeDays int
+
+	// CacheTTL is how long the status page feed is served from memory before it is built again.
+	CacheTTL time.Duration
+}
+
+// UptimeWindow is the share of a component's health checks that passed over a number of days.
+type UptimeWindow struct {
+	Days       int      `json:"days"`
+	Percentage *float64 `json:"percentage"` // Nil when the component wasn't checked during the window
+}
+
+// ComponentStatus is a component as shown on the status page.
+type ComponentStatus struct {
+	Name   string         `json:"name"`
+	Status HealthStatus   `json:"status"`
+	Uptime []UptimeWindow `json:"uptime"`
+}
+
+// StatusPage is the data feed backing the public status page.
+type StatusPage struct {
+	Status     HealthStatus       `json:"status"`
+	Components []ComponentStatus  `json:"components"`
+	Incidents  []*models.Incident `json:"incidents"` // Active incidents, latest started first
+	UpdatedAt  time.Time          `json:"updatedAt"`
+}
+
+// failureStreak counts the consecutive failed health checks of a component on this instance.
+type failureStreak struct {
+	count  int
+	opened bool
+}
+
+// StatusService feeds the public status page with component health, active incidents and recent uptime.
+// Incidents are managed by the platform admins, and opened automatically for components failing their
+// health checks repeatedly. Automatic incidents are resolved once their component passes again.
+type StatusService struct {
+	healthSvc    *HealthService
+	incidentRepo repositories.IncidentRepository
+	redisClient  *redis.Client
+	policy       StatusPolicy
+	logger       *utils.Logger
+
+	mu       sync.Mutex
+	streaks  map[string]*failureStreak
+	page     *StatusPage
+	pageTime time.Time
+}
+
+// NewStatusService creates a new status page service.
+func NewStatusService(healthSvc *HealthService, incidentRepo repositories.IncidentRepository, redisClient *redis.Client, policy StatusPolicy, logger *utils.Logger) *StatusService {
+	return &StatusService{
+		healthSvc:    healthSvc,
+		incidentRepo: incidentRepo,
+		redisClient:  redisClient,
+		policy:       policy,
+		logger:       logger.Named("status_service"),
+		streaks:      make(map[string]*failureStreak),
+	}
+}
+
+// RecordCheck counts a component's health check towards its uptime, and opens or resolves its automatic
+// incident when the check starts or ends a streak of failures.
+func (s *StatusService) RecordCheck(ctx context.Context, component ComponentHealth) {
+	up := component.Status != StatusDown
+	if err := s.recordUptime(ctx, component.Name, up, component.LastChecked); err != nil {
+		s.logger.Error("Failed to record component uptime", err, "component", component.Name)
+	}
+
+	if s.policy.IncidentAfterFailures <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	streak, ok := s.streaks[component.Name]
+	if up {
+		delete(s.streaks, component.Name)
+	} else {
+		if !ok {
+			streak = &failureStreak{}
+			s.streaks[component.Name] = streak
+		}
+		streak.count++
+	}
+	count, opened := 0, false
+	if streak != nil {
+		count, opened = streak.count, streak.opened
+	}
+	s.mu.Unlock()
+
+	switch {
+	case up && count >= s.policy.IncidentAfterFailures:
+		if err := s.resolveAutomaticIncident(ctx, component.Name); err != nil {
+			s.logger.Error("Failed to resolve automatic incident", err, "component", component.Name)
+		}
+	case !up && count >= s.policy.IncidentAfterFailures && !opened:
+		// The incident is tried again on the next failed check if it can't be stored
+		if err := s.openAutomaticIncident(ctx, component.Name, count); err != nil {
+			s.logger.Error("Failed to open automatic incident", err, "component", component.Name)
+			return
+		}
+		s.mu.Lock()
+		streak.opened = true
+		s.mu.Unlock()
+	}
+}
+
+// recordUptime counts a health check in its component's counters for the day.
+func (s *StatusService) recordUptime(ctx context.Context, name string, up bool, checked time.Time) error {
+	key := formatUptimeKey(name, checked)
+
+	pipe := s.redisClient.Pipeline()
+	pipe.HIncrBy(ctx, key, "checks", 1)
+	if up {
+		pipe.HIncrBy(ctx, key, "up", 1)
+	}
+	pipe.Expire(ctx, key, time.Duration(s.policy.UptimeDays+1)*24*time.Hour)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// openAutomaticIncident opens an incident for a component failing its health checks, unless one is
+// open already.
+func (s *StatusService) openAutomaticIncident(ctx context.Context, name string, failures int) error {
+	locked, err := s.redisClient.Client().SetNX(ctx, incidentLockPrefix+name, "1", incidentLockTTL).Result()
+	if err != nil {
+		return err
+	}
+	if !locked {
+		// Another instance is opening it
+		return nil
+	}
+	defer s.redisClient.Del(ctx, incidentLockPrefix+name)
+
+	_, err = s.incidentRepo.FindActiveAutomaticIncident(ctx, name)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, models.ErrIncidentNotFound) {
+		return err
+	}
+
+	incident := &models.Incident{
+		Title:      fmt.Sprintf("%s is unavailable", name),
+		Message:    fmt.Sprintf("Opened automatically after %d failed health checks. We are investigating.", failures),
+		Status:     models.IncidentInvestigating,
+		Impact:     models.IncidentMajor,
+		Components: []string{name},
+		Automatic:  true,
+		StartedAt:  time.Now(),
+	}
+	if err := s.incidentRepo.CreateIncident(ctx, incident); err != nil {
+		return err
+	}
+	s.invalidate()
+
+	s.logger.Warn("Opened automatic incident", "component", name, "incidentId", incident.ID.Hex(), "failures", failures)
+	return nil
+}
+
+// resolveAutomaticIncident resolves the automatic incident of a component passing its health checks again.
+func (s *StatusService) resolveAutomaticIncident(ctx context.Context, name string) error {
+	incident, err := s.incidentRepo.FindActiveAutomaticIncident(ctx, name)
+	if err != nil {
+		if errors.Is(err, models.ErrIncidentNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	incident.Status = models.IncidentResolved
+	incident.Message = "Resolved automatically once health checks passed again."
+	incident.ResolvedAt = time.Now()
+	if err := s.incidentRepo.UpdateIncident(ctx, incident); err != nil {
+		return err
+	}
+	s.invalidate()
+
+	s.logger.Info("Resolved automatic incident", "component", name, "incidentId", incident.ID.Hex())
+	return nil
+}
+
+// GetStatus gets the status page feed. It is built at most once per cache period.
+func (s *StatusService) GetStatus(ctx context.Context) (*StatusPage, error) {
+	s.mu.Lock()
+	page, pageTime := s.page, s.pageTime
+	s.mu.Unlock()
+	if page != nil && time.Since(pageTime) < s.policy.CacheTTL {
+		return page, nil
+	}
+
+	page, err := s.buildStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.page, s.pageTime = page, time.Now()
+	s.mu.Unlock()
+	return page, nil
+}
+
+// CacheTTL is how long the status page feed may be served from a cache.
+func (s *StatusService) CacheTTL() time.Duration {
+	return s.policy.CacheTTL
+}
+
+// buildStatus builds the status page feed from the latest health checks, the stored incidents and the
+// uptime counters.
+func (s *StatusService) buildStatus(ctx context.Context) (*StatusPage, error) {
+	health := s.healthSvc.GetHealth(ctx)
+
+	incidents, _, err := s.incidentRepo.FindIncidents(ctx, models.IncidentFilter{Active: true}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(health.Components, func(a, b ComponentHealth) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	names := make([]string, 0, len(health.Components))
+	for _, component := range health.Components {
+		names = append(names, component.Name)
+	}
+
+	uptime, err := s.uptime(ctx, names, time.Now())
+	if err != nil {
+		// The page is still worth serving without uptime
+		s.logger.Error("Failed to read component uptime", err)
+	}
+
+	components := make([]ComponentStatus, 0, len(health.Components))
+	for _, component := range health.Components {
+		components = append(components, ComponentStatus{
+			Name:   component.Name,
+			Status: component.Status,
+			Uptime: uptime[component.Name],
+		})
+	}
+
+	status := health.Status
+	if status == StatusUp && len(incidents) > 0 {
+		status = StatusDegraded
+	}
+
+	return &StatusPage{
+		Status:     status,
+		Components: components,
+		Incidents:  incidents,
+		UpdatedAt:  time.Now(),
+	}, nil
+}
+
+// uptime computes the uptime percentages of components from their daily counters. Days are calendar
+// days in UTC, today included.
+func (s *StatusService) uptime(ctx context.Context, names []string, now time.Time) (map[string][]UptimeWindow, error) {
+	days := s.policy.UptimeDays
+	windows := slices.DeleteFunc(slices.Clone(uptimeWindows), func(window int) bool {
+		return window > days
+	})
+
+	pipe := s.redisClient.Pipeline()
+	cmds := make(map[string][]*r.SliceCmd, len(names))
+	for _, name := range names {
+		for day := range days {
+			cmds[name] = append(cmds[name], pipe.HMGet(ctx, formatUptimeKey(name, now.AddDate(0, 0, -day)), "checks", "up"))
+		}
+	}
+	if len(names) > 0 && days > 0 {
+		if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, r.Nil) {
+			return nil, err
+		}
+	}
+
+	uptime := make(map[string][]UptimeWindow, len(names))
+	for _, name := range names {
+		var checks, up int64
+		result := make([]UptimeWindow, 0, len(windows))
+		for day, cmd := range cmds[name] {
+			values := cmd.Val()
+			checks += counterValue(values, 0)
+			up += counterValue(values, 1)
+
+			if slices.Contains(windows, day+1) {
+				window := UptimeWindow{Days: day + 1}
+				if checks > 0 {
+					percentage := math.Round(float64(up)/float64(checks)*10000) / 100
+					window.Percentage = &percentage
+				}
+				result = append(result, window)
+			}
+		}
+		uptime[name] = result
+	}
+
+	return uptime, nil
+}
+
+// CreateIncident opens an incident on the status page for an admin.
+func (s *StatusService) CreateIncident(ctx context.Context, adminID bson.ObjectID, request *models.IncidentRequest) (*models.Incident, error) {
+	if err := validateIncidentRequest(request); err != nil {
+		return nil, err
+	}
+
+	incident := &models.Incident{CreatedBy: adminID}
+	applyIncidentRequest(incident, request)
+	if incident.StartedAt.IsZero() {
+		incident.StartedAt = time.Now()
+	}
+
+	if err := s.incidentRepo.CreateIncident(ctx, incident); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+
+	s.logger.Info("Incident opened", "incidentId", incident.ID.Hex(), "adminId", adminID.Hex(), "impact", incident.Impact)
+	return incident, nil
+}
+
+// UpdateIncident updates an incident, such as to post news or resolve it. Resolving an incident records
+// when it was resolved, and setting it back to an active status reopens it.
+func (s *StatusService) UpdateIncident(ctx context.Context, id bson.ObjectID, request *models.IncidentRequest) (*models.Incident, error) {
+	if err := validateIncidentRequest(request); err != nil {
+		return nil, err
+	}
+
+	incident, err := s.incidentRepo.FindIncidentByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	applyIncidentRequest(incident, request)
+	if err := s.incidentRepo.UpdateIncident(ctx, incident); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+
+	s.logger.Info("Incident updated", "incidentId", id.Hex(), "status", incident.Status)
+	return incident, nil
+}
+
+// DeleteIncident deletes an incident, such as one opened by mistake.
+func (s *StatusService) DeleteIncident(ctx context.Context, id bson.ObjectID) error {
+	if err := s.incidentRepo.DeleteIncident(ctx, id); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+// GetIncident gets an incident.
+func (s *StatusService) GetIncident(ctx context.Context, id bson.ObjectID) (*models.Incident, error) {
+	return s.incidentRepo.FindIncidentByID(ctx, id)
+}
+
+// ListIncidents lists incidents, latest started first, along with the total number matching.
+func (s *StatusService) ListIncidents(ctx context.Context, filter models.IncidentFilter, offset, limit int) ([]*models.Incident, int64, error) {
+	return s.incidentRepo.FindIncidents(ctx, filter, offset, limit)
+}
+
+// invalidate drops the cached status page feed, so incident changes show right away.
+func (s *StatusService) invalidate() {
+	s.mu.Lock()
+	s.page = nil
+	s.mu.Unlock()
+}
+
+// validateIncidentRequest trims and validates an admin's incident.
+func validateIncidentRequest(request *models.IncidentRequest) error {
+	request.Title = strings.TrimSpace(request.Title)
+	request.Message = strings.TrimSpace(request.Message)
+	if err := utils.Validate(request); err != nil {
+		return models.NewUserError(models.ErrInvalidIncident, err.Error(), http.StatusBadRequest)
+	}
+	if request.StartedAt.After(time.Now()) {
+		return models.NewUserError(models.ErrInvalidIncident, "Incidents can't start in the future", http.StatusBadRequest)
+	}
+	return nil
+}
+
+// applyIncidentRequest copies an admin's incident onto a stored one.
+func applyIncidentRequest(incident *models.Incident, request *models.IncidentRequest) {
+	incident.Title = request.Title
+	incident.Message = request.Message
+	incident.Impact = request.Impact
+	incident.Components = request.Components
+	if !request.StartedAt.IsZero() {
+		incident.StartedAt = request.StartedAt
+	}
+
+	status := request.Status
+	if status == "" {
+		status = incident.Status
+	}
+	if status == "" {
+		status = models.IncidentInvestigating
+	}
+	switch {
+	case status == models.IncidentResolved && incident.IsActive():
+		incident.ResolvedAt = time.Now()
+	case status != models.IncidentResolved:
+		incident.ResolvedAt = time.Time{}
+	}
+	incident.Status = status
+}
+
+// counterValue reads a counter out of an HMGET result, zero when it is missing.
+func counterValue(values []any, index int) int64 {
+	if index >= len(values) {
+		return 0
+	}
+	value, ok := values[index].(string)
+	if !ok {
+		return 0
+	}
+	count, _ := strconv.ParseInt(value, 10, 64)
+	return count
+}
+
+// formatUptimeKey formats the key of a component's health check counters for the day of a time.
+func formatUptimeKey(name string, t time.Time) string {
+	return uptimeKeyPrefix + name + ":" + t.UTC().Format(time.DateOnly)
+}
@@ -0,0 +1,51 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+// maxAttestedAge is the oldest age a user can attest, to catch mistyped years.
+const maxAttestedAge = 120
+
+// AttestAge records the date of birth a user attests, as YYYY-MM-DD. Attestations can't be changed,
+// so users can't lie their way past age restrictions after being shown they are too young.
+func (m *Manager) AttestAge(ctx context.Context, userID string, birthDate string) (*models.AgeAttestation, error) {
+	objectID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	date, err := time.Parse(time.DateOnly, birthDate)
+	if err != nil {
+		return nil, models.ErrInvalidBirthDate
+	}
+
+	now := time.Now().UTC()
+	if date.After(now) || date.AddDate(maxAttestedAge, 0, 0).Before(now) {
+		return nil, models.ErrInvalidBirthDate
+	}
+
+	attestation := models.AgeAttestation{
+		BirthDate:  date,
+		AttestedAt: now,
+	}
+	if err := m.userRepo.SetAgeAttestation(ctx, objectID, attestation); err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("User attested age", "userId", userID)
+	return &attestation, nil
+}
+
+// IsAdult reports whether a user attested being an adult. Users who haven't attested their age are not.
+func (m *Manager) IsAdult(ctx context.Context, userID string) (bool, error) {
+	user, err := m.GetUserByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.IsAdult(time.Now()), nil
+}
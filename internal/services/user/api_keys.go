@@ -0,0 +1,248 @@
+// Package user provides services for user management and operations.
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// apiKeyPrefix marks a string as a Listenify personal API key.
+	apiKeyPrefix = "lk_"
+
+	// apiKeySecretBytes is the number of random bytes in a key's secret.
+	apiKeySecretBytes = 32
+
+	// apiKeyHintLength is the number of trailing key characters kept to identify a key.
+	apiKeyHintLength = 4
+
+	// apiKeyUsageInterval is how often a key's last-used time is written back.
+	apiKeyUsageInterval = time.Minute
+)
+
+// APIKeyService manages personal API keys and authenticates requests made with them.
+type APIKeyService struct {
+	userManager      *Manager
+	redisClient      *redis.Client
+	maxKeys          int
+	defaultRateLimit int
+	logger           *utils.Logger
+}
+
+// CreatedAPIKey is a newly created API key along with its secret, which is never shown again.
+type CreatedAPIKey struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+// NewAPIKeyService creates a new API key service.
+func NewAPIKeyService(userManager *Manager, redisClient *redis.Client, maxKeys, defaultRateLimit int, logger *utils.Logger) *APIKeyService {
+	return &APIKeyService{
+		userManager:      userManager,
+		redisClient:      redisClient,
+		maxKeys:          maxKeys,
+		defaultRateLimit: defaultRateLimit,
+		logger:           logger.Named("api_key_service"),
+	}
+}
+
+// CreateAPIKey creates a personal API key with the given scopes.
+// A rate limit of zero or above the configured default uses the default.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, userID, name string, scopes []models.APIKeyScope, rateLimit int) (*CreatedAPIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 50 {
+		return nil, models.NewValidationError(models.ErrInvalidInput, "API key name must be between 1 and 50 characters")
+	}
+	if len(scopes) == 0 {
+		return nil, models.NewValidationError(models.ErrInvalidInput, "At least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(models.APIKeyScopes, scope) {
+			return nil, models.NewValidationError(models.ErrInvalidInput, fmt.Sprintf("Unknown scope %q", scope))
+		}
+	}
+	if rateLimit <= 0 || rateLimit > s.defaultRateLimit {
+		rateLimit = s.defaultRateLimit
+	}
+
+	user, err := s.userManager.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if s.maxKeys > 0 && len(user.APIKeys) >= s.maxKeys {
+		return nil, models.ErrTooManyAPIKeys
+	}
+
+	secret := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, models.NewInternalError(err, "Failed to generate API key")
+	}
+
+	apiKey := models.APIKey{
+		ID:        bson.NewObjectID(),
+		Name:      name,
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(scopes))),
+		RateLimit: rateLimit,
+		CreatedAt: time.Now(),
+	}
+	key := apiKeyPrefix + user.ID.Hex() + apiKey.ID.Hex() + hex.EncodeToString(secret)
+	apiKey.Hash = hashAPIKey(key)
+	apiKey.Hint = key[len(key)-apiKeyHintLength:]
+
+	if err := s.userManager.userRepo.AddAPIKey(ctx, user.ID, apiKey); err != nil {
+		s.logger.Error("Failed to save API key", err, "userId", userID)
+		return nil, err
+	}
+
+	s.logger.Info("API key created", "userId", userID, "keyId", apiKey.ID.Hex(), "scopes", apiKey.Scopes)
+	return &CreatedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+// ListAPIKeys lists a user's API keys without their secrets.
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	user, err := s.userManager.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.APIKeys == nil {
+		return []models.APIKey{}, nil
+	}
+	return user.APIKeys, nil
+}
+
+// RevokeAPIKey deletes one of a user's API keys, immediately invalidating it.
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, userID, keyID string) error {
+	id, err := bson.ObjectIDFromHex(keyID)
+	if err != nil {
+		return models.ErrInvalidID
+	}
+
+	user, err := s.userManager.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userManager.userRepo.RemoveAPIKey(ctx, user.ID, id); err != nil {
+		if !errors.Is(err, models.ErrAPIKeyNotFound) {
+			s.logger.Error("Failed to revoke API key", err, "userId", userID, "keyId", keyID)
+		}
+		return err
+	}
+
+	s.logger.Info("API key revoked", "userId", userID, "keyId", keyID)
+	return nil
+}
+
+// Authenticate resolves an API key to its owner and checks that it grants the scope and is within its rate limit.
+func (s *APIKeyService) Authenticate(ctx context.Context, key string, scope models.APIKeyScope) (*models.User, *models.APIKey, error) {
+	userID, keyID, ok := parseAPIKey(key)
+	if !ok {
+		return nil, nil, models.ErrInvalidAPIKey
+	}
+
+	user, err := s.userManager.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return nil, nil, models.ErrInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+	if !user.IsActive {
+		return nil, nil, models.ErrInvalidAPIKey
+	}
+	if user.Recovery.PasswordResetRequired {
+		return nil, nil, models.ErrPasswordResetRequired
+	}
+
+	index := slices.IndexFunc(user.APIKeys, func(k models.APIKey) bool { return k.ID == keyID })
+	if index < 0 {
+		return nil, nil, models.ErrInvalidAPIKey
+	}
+	apiKey := &user.APIKeys[index]
+	if subtle.ConstantTimeCompare([]byte(apiKey.Hash), []byte(hashAPIKey(key))) != 1 {
+		return nil, nil, models.ErrInvalidAPIKey
+	}
+
+	if !apiKey.HasScope(scope) {
+		return nil, nil, models.ErrAPIKeyScope
+	}
+
+	if err := s.checkRateLimit(ctx, apiKey); err != nil {
+		return nil, nil, err
+	}
+
+	if time.Since(apiKey.LastUsedAt) >= apiKeyUsageInterval {
+		apiKey.LastUsedAt = time.Now()
+		if err := s.userManager.userRepo.TouchAPIKey(ctx, user.ID, apiKey.ID, apiKey.LastUsedAt); err != nil {
+			s.logger.Error("Failed to record API key usage", err, "userId", userID.Hex(), "keyId", keyID.Hex())
+			// Continue anyway, usage tracking is best effort
+		}
+	}
+
+	return user, apiKey, nil
+}
+
+// checkRateLimit counts a request against the key's per-minute window.
+func (s *APIKeyService) checkRateLimit(ctx context.Context, apiKey *models.APIKey) error {
+	if apiKey.RateLimit <= 0 {
+		return nil
+	}
+
+	window := time.Now().Truncate(time.Minute).Unix()
+	key := fmt.Sprintf("apikey:rate:%s:%d", apiKey.ID.Hex(), window)
+
+	count, err := s.redisClient.Incr(ctx, key)
+	if err != nil {
+		s.logger.Error("Failed to check API key rate limit", err, "keyId", apiKey.ID.Hex())
+		return nil // Fail open, Redis hiccups shouldn't lock out integrations
+	}
+	if count == 1 {
+		if err := s.redisClient.Expire(ctx, key, 2*time.Minute); err != nil {
+			s.logger.Error("Failed to set API key rate limit expiry", err, "keyId", apiKey.ID.Hex())
+		}
+	}
+
+	if count > int64(apiKey.RateLimit) {
+		return models.ErrTooManyRequests
+	}
+	return nil
+}
+
+// parseAPIKey extracts the owner and key IDs embedded in an API key.
+func parseAPIKey(key string) (userID, keyID bson.ObjectID, ok bool) {
+	body, found := strings.CutPrefix(key, apiKeyPrefix)
+	if !found || len(body) != 48+apiKeySecretBytes*2 {
+		return userID, keyID, false
+	}
+
+	userID, err := bson.ObjectIDFromHex(body[:24])
+	if err != nil {
+		return userID, keyID, false
+	}
+	keyID, err = bson.ObjectIDFromHex(body[24:48])
+	if err != nil {
+		return userID, keyID, false
+	}
+
+	return userID, keyID, true
+}
+
+// hashAPIKey hashes an API key for storage.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,308 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// captchaAttemptsKey prefixes the number of recent attempts at an action from an IP address.
+	captchaAttemptsKey = "captcha:attempts:"
+
+	// captchaResponseLimit caps the size of a captcha provider response read.
+	captchaResponseLimit = 64 << 10
+)
+
+// CaptchaAction is a sensitive action a captcha may be required for.
+type CaptchaAction string
+
+const (
+	// CaptchaActionSignup is registering an account.
+	CaptchaActionSignup CaptchaAction = "signup"
+	// CaptchaActionPasswordReset is setting a new password with a reset token.
+	CaptchaActionPasswordReset CaptchaAction = "password_reset"
+	// CaptchaActionReport is reporting a room.
+	CaptchaActionReport CaptchaAction = "report"
+)
+
+// CaptchaMode is when an action requires a captcha.
+type CaptchaMode string
+
+const (
+	// CaptchaModeOff never requires a captcha.
+	CaptchaModeOff CaptchaMode = "off"
+	// CaptchaModeRisky requires a captcha when the attempt looks risky.
+	CaptchaModeRisky CaptchaMode = "risky"
+	// CaptchaModeAlways requires a captcha on every attempt.
+	CaptchaModeAlways CaptchaMode = "always"
+)
+
+// CaptchaActionPolicy configures when an action requires a captcha.
+type CaptchaActionPolicy struct {
+	// Mode is when the action requires a captcha.
+	Mode CaptchaMode
+
+	// RiskyAfter is the number of attempts from an IP address within the attempt window after which
+	// further attempts are risky.
+	RiskyAfter int
+
+	// FailOpen lets the action through without a captcha while the provider can't be reached, rather
+	// than refusing it until the provider is back.
+	FailOpen bool
+}
+
+// CaptchaPolicy configures the captcha verification of sensitive actions.
+type CaptchaPolicy struct {
+	// Provider is the captcha provider clients render the captcha of.
+	Provider string
+
+	// SiteKey is the application's public site key with the provider.
+	SiteKey string
+
+	// AttemptWindow is the window over which attempts from an IP address are counted.
+	AttemptWindow time.Duration
+
+	// Actions configures each action. Actions left out never require a captcha.
+	Actions map[CaptchaAction]CaptchaActionPolicy
+}
+
+// CaptchaConfig is what clients need to render the captcha and know which actions may require it.
+type CaptchaConfig struct {
+	// Enabled is whether any action may require a captcha.
+	Enabled bool `json:"enabled"`
+
+	// Provider is the captcha provider.
+	Provider string `json:"provider,omitempty"`
+
+	// SiteKey is the site key the captcha is rendered with.
+	SiteKey string `json:"siteKey,omitempty"`
+
+	// Actions are the actions that may require a captcha, with when they do.
+	Actions map[CaptchaAction]CaptchaMode `json:"actions,omitempty"`
+}
+
+// CaptchaRequest is an attempt at a sensitive action to check for a captcha.
+type CaptchaRequest struct {
+	// Action is the action attempted.
+	Action CaptchaAction
+
+	// Token is the captcha the client solved, empty if it didn't solve one.
+	Token string
+
+	// RemoteIP is the IP address the attempt comes from.
+	RemoteIP string
+
+	// UserID is the user attempting the action, empty for anonymous actions.
+	UserID string
+}
+
+// CaptchaVerifier verifies captcha tokens with a captcha provider.
+type CaptchaVerifier interface {
+	// Verify checks a token solved by a client at an IP address. It returns false if the provider
+	// rejected the token, and an error if the provider couldn't verify it.
+	Verify(ctx context.Context, token, remoteIP string) (bool, error)
+}
+
+// siteVerifyCaptcha verifies tokens with a provider's siteverify endpoint, which hCaptcha and Turnstile
+// share.
+type siteVerifyCaptcha struct {
+	verifyURL  string
+	secret     string
+	siteKey    string
+	httpClient *http.Client
+}
+
+// captchaConfigErrors are the error codes of a siteverify endpoint blaming the application's own
+// credentials rather than the token.
+var captchaConfigErrors = []string{
+	"missing-input-secret", "invalid-input-secret", "sitekey-secret-mismatch", "invalid-or-already-seen-sitekey",
+}
+
+// NewCaptchaVerifier creates the verifier of a captcha provider: "hcaptcha" or "turnstile". An empty
+// verify URL uses the provider's endpoint. It returns nil when no provider is set.
+func NewCaptchaVerifier(provider, secret, siteKey, verifyURL string, timeout time.Duration) (CaptchaVerifier, error) {
+	verifier := &siteVerifyCaptcha{
+		verifyURL:  verifyURL,
+		secret:     secret,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+
+	switch provider {
+	case "":
+		return nil, nil
+	case "hcaptcha":
+		// hCaptcha checks the token was solved for the site key
+		verifier.siteKey = siteKey
+		if verifier.verifyURL == "" {
+			verifier.verifyURL = "https://api.hcaptcha.com/siteverify"
+		}
+	case "turnstile":
+		if verifier.verifyURL == "" {
+			verifier.verifyURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+		}
+	default:
+		return nil, fmt.Errorf("unknown captcha provider: %s", provider)
+	}
+	return verifier, nil
+}
+
+// Verify implements CaptchaVerifier.
+func (v *siteVerifyCaptcha) Verify(ctx context.Context, token, remoteIP string) (bool, error) {
+	form := url.Values{
+		"secret":   {v.secret},
+		"response": {token},
+	}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	if v.siteKey != "" {
+		form.Set("sitekey", v.siteKey)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return false, fmt.Errorf("captcha provider responded with status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, captchaResponseLimit)).Decode(&result); err != nil {
+		return false, fmt.Errorf("invalid captcha provider response: %w", err)
+	}
+	for _, code := range result.ErrorCodes {
+		if slices.Contains(captchaConfigErrors, code) {
+			return false, fmt.Errorf("captcha provider rejected the application's credentials: %s", code)
+		}
+	}
+	return result.Success, nil
+}
+
+// CaptchaService decides when sensitive actions require a captcha and verifies the captchas solved. An
+// action configured as risky requires one from new accounts and from IP addresses attempting it more
+// often than usual. When the provider can't be reached, actions configured to fail open go through
+// without a captcha and the others are refused until it is back.
+type CaptchaService struct {
+	verifier     CaptchaVerifier
+	userManager  *Manager
+	trustService *TrustService
+	redisClient  *redis.Client
+	policy       CaptchaPolicy
+	logger       *utils.Logger
+}
+
+// NewCaptchaService creates a new captcha service. A nil verifier disables captchas.
+func NewCaptchaService(verifier CaptchaVerifier, userManager *Manager, trustService *TrustService, redisClient *redis.Client, policy CaptchaPolicy, logger *utils.Logger) *CaptchaService {
+	return &CaptchaService{
+		verifier:     verifier,
+		userManager:  userManager,
+		trustService: trustService,
+		redisClient:  redisClient,
+		policy:       policy,
+		logger:       logger.Named("captcha_service"),
+	}
+}
+
+// Config returns what clients need to render the captcha.
+func (s *CaptchaService) Config() *CaptchaConfig {
+	config := &CaptchaConfig{}
+	if s.verifier == nil {
+		return config
+	}
+
+	config.Actions = make(map[CaptchaAction]CaptchaMode)
+	for action, policy := range s.policy.Actions {
+		if policy.Mode != CaptchaModeOff {
+			config.Actions[action] = policy.Mode
+		}
+	}
+	if len(config.Actions) > 0 {
+		config.Enabled = true
+		config.Provider = s.policy.Provider
+		config.SiteKey = s.policy.SiteKey
+	}
+	return config
+}
+
+// Check checks an attempt at a sensitive action. It returns models.ErrCaptchaRequired when the attempt
+// requires a captcha the client didn't solve, models.ErrCaptchaFailed when the provider rejected the
+// captcha and models.ErrCaptchaUnavailable when the provider couldn't verify it and the action doesn't
+// fail open.
+func (s *CaptchaService) Check(ctx context.Context, request CaptchaRequest) error {
+	policy, ok := s.policy.Actions[request.Action]
+	if s.verifier == nil || !ok || policy.Mode == CaptchaModeOff {
+		return nil
+	}
+	if policy.Mode == CaptchaModeRisky && !s.risky(ctx, request, policy) {
+		return nil
+	}
+
+	if request.Token == "" {
+		return models.ErrCaptchaRequired
+	}
+
+	valid, err := s.verifier.Verify(ctx, request.Token, request.RemoteIP)
+	if err != nil {
+		if policy.FailOpen {
+			s.logger.Warn("Captcha provider unavailable, letting the action through", "action", request.Action, "error", err)
+			return nil
+		}
+		s.logger.Error("Captcha provider unavailable", err, "action", request.Action)
+		return models.ErrCaptchaUnavailable
+	}
+	if !valid {
+		return models.ErrCaptchaFailed
+	}
+	return nil
+}
+
+// risky scores an attempt: it is risky when it comes from a new account or from an IP address that
+// attempted the action more often than allowed within the attempt window. Attempts that can't be scored
+// are treated as risky.
+func (s *CaptchaService) risky(ctx context.Context, request CaptchaRequest, policy CaptchaActionPolicy) bool {
+	if request.UserID != "" {
+		user, err := s.userManager.GetUserByID(ctx, request.UserID)
+		if err != nil || s.trustService.EffectiveTrustLevel(user) == models.TrustLevelNew {
+			return true
+		}
+	}
+
+	if request.RemoteIP == "" {
+		return true
+	}
+	key := captchaAttemptsKey + string(request.Action) + ":" + request.RemoteIP
+	attempts, err := s.redisClient.Incr(ctx, key)
+	if err != nil {
+		s.logger.Error("Failed to count captcha attempts", err, "action", request.Action)
+		return true
+	}
+	if attempts == 1 {
+		if err := s.redisClient.Expire(ctx, key, s.policy.AttemptWindow); err != nil {
+			s.logger.Error("Failed to expire captcha attempts", err, "action", request.Action)
+		}
+	}
+	return attempts > int64(policy.RiskyAfter)
+}
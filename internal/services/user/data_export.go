@@ -0,0 +1,272 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"time"
+
+	r "github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// dataExportLinkKeyPrefix prefixes the hash of a data export link's token, holding the ID of the user it downloads.
+	dataExportLinkKeyPrefix = "data_export:link:"
+
+	// dataExportTokenLength is the number of hex characters in a data export link's token.
+	dataExportTokenLength = 64
+)
+
+// DataExportLink is a single-use link downloading a user's data archive.
+type DataExportLink struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// archivedSession is a session in a data archive. Unlike elsewhere, the archive shows the user where they
+// signed in from.
+type archivedSession struct {
+	*models.SessionHistory
+	IP        string `json:"ip"`
+	UserAgent string `json:"userAgent"`
+}
+
+// DataExportService exports everything kept about a user as a JSON archive: their profile, playlists, play
+// history as DJ and sessions. The archive is streamed straight from the database as it is downloaded, through
+// a single-use link handed out to the signed-in user, and counts against the same per-user limit as history
+// exports.
+type DataExportService struct {
+	userManager  *Manager
+	playlistRepo repositories.PlaylistRepository
+	historyRepo  repositories.HistoryRepository
+	redisClient  *redis.Client
+	policy       HistoryExportPolicy
+	linkExpiry   time.Duration
+	logger       *utils.Logger
+}
+
+// NewDataExportService creates a new data export service. Links expire after linkExpiry.
+func NewDataExportService(
+	userManager *Manager,
+	playlistRepo repositories.PlaylistRepository,
+	historyRepo repositories.HistoryRepository,
+	redisClient *redis.Client,
+	policy HistoryExportPolicy,
+	linkExpiry time.Duration,
+	logger *utils.Logger,
+) *DataExportService {
+	return &DataExportService{
+		userManager:  userManager,
+		playlistRepo: playlistRepo,
+		historyRepo:  historyRepo,
+		redisClient:  redisClient,
+		policy:       policy,
+		linkExpiry:   linkExpiry,
+		logger:       logger.Named("data_export_service"),
+	}
+}
+
+// CreateLink creates a link downloading a user's data archive once.
+func (s *DataExportService) CreateLink(ctx context.Context, userID string) (*DataExportLink, error) {
+	objectID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	token, err := utils.GenerateRandomHex(dataExportTokenLength)
+	if err != nil {
+		return nil, models.NewInternalError(err, "Failed to generate data export link")
+	}
+	if err := s.redisClient.Set(ctx, dataExportLinkKeyPrefix+hashToken(token), objectID.Hex(), s.linkExpiry); err != nil {
+		s.logger.Error("Failed to store data export link", err, "userId", userID)
+		return nil, models.NewInternalError(err, "Failed to create data export link")
+	}
+
+	link := url.URL{
+		Path:     "/users/" + objectID.Hex() + "/export.json",
+		RawQuery: url.Values{"token": {token}}.Encode(),
+	}
+	return &DataExportLink{
+		URL:       link.String(),
+		ExpiresAt: time.Now().Add(s.linkExpiry),
+	}, nil
+}
+
+// RedeemLink checks the token of a link downloading a user's data archive and uses it up. It returns
+// models.ErrDataExportNotFound if the link is invalid, expired or already used.
+func (s *DataExportService) RedeemLink(ctx context.Context, userID, token string) error {
+	if token == "" {
+		return models.ErrDataExportNotFound
+	}
+
+	// Links are used once, so a leaked link can't download the archive again
+	owner, err := s.redisClient.Client().GetDel(ctx, dataExportLinkKeyPrefix+hashToken(token)).Result()
+	if err != nil && !errors.Is(err, r.Nil) {
+		s.logger.Error("Failed to get data export link", err, "userId", userID)
+		return models.NewInternalError(err, "Failed to check data export link")
+	}
+	if owner == "" || owner != userID {
+		return models.ErrDataExportNotFound
+	}
+	return nil
+}
+
+// WriteArchive writes a user's data archive to w as a single JSON object. Plays and sessions are read from
+// the database as w takes them. An archive still running after the maximum export duration is cut off: its
+// lists end where they got to, and its truncated field tells it is incomplete.
+func (s *DataExportService) WriteArchive(ctx context.Context, userID string, w io.Writer) error {
+	user, err := s.userManager.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	release, err := acquireExportSlot(ctx, s.redisClient, s.policy, userID, s.logger)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	playlists, err := s.playlistRepo.FindUserPlaylists(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	session, _, err := s.userManager.sessionMgr.GetUserSession(ctx, user.ID)
+	if err != nil {
+		// The user may have no session left, the archive goes without it
+		s.logger.Debug("No session to export", "userId", userID, "error", err)
+		session = nil
+	}
+
+	deadline := time.Now().Add(s.policy.MaxDuration)
+	archive := &archiveWriter{w: w}
+	archive.field("exportedAt", time.Now())
+	archive.field("profile", user.ToPersonalUser())
+	archive.field("activeSession", session)
+	archive.field("playlists", playlists)
+	archive.list("plays", func(item func(any) error) error {
+		before := bson.NewObjectIDFromTimestamp(time.Now().Add(time.Second))
+		return s.historyRepo.StreamPlayHistoryByDJ(ctx, user.ID, bson.ObjectID{}, before, s.policy.BatchSize, func(play *models.PlayHistory) error {
+			if time.Now().After(deadline) {
+				return errExportCutOff
+			}
+			return item(play)
+		})
+	})
+	archive.list("sessions", func(item func(any) error) error {
+		for skip := 0; ; skip += s.policy.BatchSize {
+			sessions, err := s.historyRepo.FindSessionHistoryByUser(ctx, user.ID, skip, s.policy.BatchSize)
+			if err != nil {
+				return err
+			}
+			for _, session := range sessions {
+				if time.Now().After(deadline) {
+					return errExportCutOff
+				}
+				if err := item(archivedSession{SessionHistory: session, IP: session.IP, UserAgent: session.UserAgent}); err != nil {
+					return err
+				}
+			}
+			if len(sessions) < s.policy.BatchSize {
+				return nil
+			}
+		}
+	})
+	if archive.truncated {
+		archive.field("truncated", true)
+		s.logger.Info("Data archive cut off", "userId", userID)
+	}
+
+	if err := archive.close(); err != nil {
+		return err
+	}
+	s.logger.Info("Data archive exported", "userId", userID)
+	return nil
+}
+
+// archiveWriter writes a JSON object field by field, so lists can be streamed into it. The first error
+// stops all further writes and is returned by close. A list cut off by errExportCutOff is ended and marks
+// the object truncated instead, so it stays valid JSON.
+type archiveWriter struct {
+	w         io.Writer
+	fields    int
+	truncated bool
+	err       error
+}
+
+// field writes a field with its value.
+func (a *archiveWriter) field(name string, value any) {
+	a.key(name)
+	a.value(value)
+}
+
+// list writes a field holding a list, passing each the function writing an item of the list.
+func (a *archiveWriter) list(name string, each func(item func(any) error) error) {
+	a.key(name)
+	a.write("[")
+	items := 0
+	err := each(func(value any) error {
+		if items > 0 {
+			a.write(",")
+		}
+		items++
+		a.value(value)
+		return a.err
+	})
+	if errors.Is(err, errExportCutOff) {
+		a.truncated = true
+		err = nil
+	}
+	if a.err == nil {
+		a.err = err
+	}
+	a.write("]")
+}
+
+// close ends the object.
+func (a *archiveWriter) close() error {
+	if a.fields == 0 {
+		a.write("{")
+	}
+	a.write("}\n")
+	return a.err
+}
+
+// key starts a field.
+func (a *archiveWriter) key(name string) {
+	separator := ","
+	if a.fields == 0 {
+		separator = "{"
+	}
+	a.fields++
+	a.write(separator)
+	a.value(name)
+	a.write(":")
+}
+
+// value writes a JSON value.
+func (a *archiveWriter) value(value any) {
+	if a.err != nil {
+		return
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		a.err = err
+		return
+	}
+	_, a.err = a.w.Write(data)
+}
+
+// write writes raw JSON.
+func (a *archiveWriter) write(s string) {
+	if a.err != nil {
+		return
+	}
+	_, a.err = io.WriteString(a.w, s)
+}
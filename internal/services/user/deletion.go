@@ -0,0 +1,276 @@
+package user
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// AccountDeletionPolicy configures how deleted accounts are purged.
+type AccountDeletionPolicy struct {
+	// GracePeriod is how long a deleted account can be restored by signing in before it is purged.
+	GracePeriod time.Duration
+
+	// BatchSize is the number of accounts purged per maintenance run.
+	BatchSize int
+}
+
+// HistoryRedactor removes a purged user's records from the history archived out of the database.
+type HistoryRedactor interface {
+	RedactUser(ctx context.Context, userID bson.ObjectID) error
+}
+
+// AccountDeletionService deletes user accounts on their request. A deleted account is signed out everywhere
+// and kept for a grace period, during which signing in restores it. Once the grace period ends a maintenance
+// task purges it: its playlists, developer applications, scrobbles, achievements, room memberships and roles,
+// image reviews, verification claims and personal history are removed, and the play history and chat it leaves
+// in rooms are detached from it. The rooms it created go to their highest-ranked member, or are deactivated
+// when nobody else has a role in them. Archived history is rewritten the same way, shortly after the purge.
+type AccountDeletionService struct {
+	userManager     *Manager
+	playlistRepo    repositories.PlaylistRepository
+	historyRepo     repositories.HistoryRepository
+	chatRepo        repositories.ChatRepository
+	scrobbleRepo    repositories.ScrobbleRepository
+	devRepo         repositories.DeveloperRepository
+	achievementRepo repositories.AchievementRepository
+	roomRepo        repositories.RoomRepository
+	imageReviewRepo repositories.ImageReviewRepository
+	claimRepo       repositories.VerificationRepository
+	historyRedactor HistoryRedactor
+	policy          AccountDeletionPolicy
+	logger          *utils.Logger
+}
+
+// NewAccountDeletionService creates a new account deletion service.
+func NewAccountDeletionService(
+	userManager *Manager,
+	playlistRepo repositories.PlaylistRepository,
+	historyRepo repositories.HistoryRepository,
+	chatRepo repositories.ChatRepository,
+	scrobbleRepo repositories.ScrobbleRepository,
+	devRepo repositories.DeveloperRepository,
+	achievementRepo repositories.AchievementRepository,
+	roomRepo repositories.RoomRepository,
+	imageReviewRepo repositories.ImageReviewRepository,
+	claimRepo repositories.VerificationRepository,
+	historyRedactor HistoryRedactor,
+	policy AccountDeletionPolicy,
+	logger *utils.Logger,
+) *AccountDeletionService {
+	return &AccountDeletionService{
+		userManager:     userManager,
+		playlistRepo:    playlistRepo,
+		historyRepo:     historyRepo,
+		chatRepo:        chatRepo,
+		scrobbleRepo:    scrobbleRepo,
+		devRepo:         devRepo,
+		achievementRepo: achievementRepo,
+		roomRepo:        roomRepo,
+		imageReviewRepo: imageReviewRepo,
+		claimRepo:       claimRepo,
+		historyRedactor: historyRedactor,
+		policy:          policy,
+		logger:          logger.Named("account_deletion_service"),
+	}
+}
+
+// RequestDeletion schedules a user's account for deletion once the grace period ends and signs them out.
+// The password confirms the request for accounts that have one. Asking again keeps the first schedule.
+func (s *AccountDeletionService) RequestDeletion(ctx context.Context, userID, password string) (*models.AccountDeletion, error) {
+	user, err := s.userManager.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Deletion != nil {
+		return user.Deletion, nil
+	}
+
+	if user.Password != "" && !s.userManager.authProvider.VerifyPassword(password, user.Password) {
+		return nil, models.ErrInvalidCredentials
+	}
+
+	now := time.Now()
+	deletion := &models.AccountDeletion{
+		RequestedAt: now,
+		PurgeAt:     now.Add(s.policy.GracePeriod),
+	}
+	if err := s.userManager.userRepo.SetDeletion(ctx, user.ID, deletion); err != nil {
+		s.logger.Error("Failed to schedule account deletion", err, "userId", userID)
+		return nil, err
+	}
+
+	// Invalidate all sessions
+	if err := s.userManager.sessionMgr.DestroyUserSessions(ctx, user.ID); err != nil {
+		s.logger.Error("Failed to invalidate sessions after deletion request", err, "userId", userID)
+		// Continue anyway, not critical
+	}
+
+	// Set user as offline
+	if err := s.userManager.presenceMgr.RemovePresence(ctx, user.ID); err != nil {
+		s.logger.Error("Failed to set user offline", err, "userId", userID)
+		// Continue anyway, not critical
+	}
+
+	s.logger.Info("Account deletion requested", "userId", userID, "purgeAt", deletion.PurgeAt)
+	return deletion, nil
+}
+
+// PurgeAccount removes a user's account and personal data right away. The account itself goes last, so a
+// purge that fails partway is picked up again by the next maintenance run.
+func (s *AccountDeletionService) PurgeAccount(ctx context.Context, userID bson.ObjectID) error {
+	playlists, err := s.playlistRepo.FindUserPlaylists(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, playlist := range playlists {
+		if err := s.playlistRepo.Delete(ctx, playlist.ID); err != nil && !errors.Is(err, models.ErrPlaylistNotFound) {
+			return err
+		}
+	}
+
+	// Applications go first, so their webhooks stop firing for an owner who is gone
+	apps, err := s.devRepo.FindAppsByOwner(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, app := range apps {
+		if err := s.devRepo.DeleteApp(ctx, app.ID); err != nil && !errors.Is(err, models.ErrDeveloperAppNotFound) {
+			return err
+		}
+	}
+
+	history, err := s.historyRepo.AnonymizeUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.historyRedactor.RedactUser(ctx, userID); err != nil {
+		return err
+	}
+	messages, err := s.chatRepo.AnonymizeUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.scrobbleRepo.DeleteAccount(ctx, userID); err != nil && !errors.Is(err, models.ErrScrobbleAccountNotFound) {
+		return err
+	}
+	if _, err := s.scrobbleRepo.DeleteUserScrobbles(ctx, userID); err != nil {
+		return err
+	}
+
+	if _, err := s.achievementRepo.DeleteUserAchievements(ctx, userID); err != nil {
+		return err
+	}
+	transferred, deactivated, err := s.handOverRooms(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.roomRepo.RemoveUserRoles(ctx, userID); err != nil {
+		return err
+	}
+	if _, err := s.roomRepo.RemoveUserFromRooms(ctx, userID); err != nil {
+		return err
+	}
+	if _, err := s.imageReviewRepo.DeleteOwnerImageReviews(ctx, userID); err != nil {
+		return err
+	}
+	if _, err := s.claimRepo.DeleteClaimantClaims(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.userManager.userRepo.RemoveConnections(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.userManager.DeleteAccount(ctx, userID.Hex()); err != nil {
+		return err
+	}
+
+	s.logger.Info("Account purged", "userId", userID.Hex(), "playlists", len(playlists), "apps", len(apps), "historyRecords", history, "chatMessages", messages, "roomsTransferred", transferred, "roomsDeactivated", deactivated)
+	return nil
+}
+
+// handOverRooms hands each room a user created over to its highest-ranked member, so the room keeps an
+// owner once the user is gone, and deactivates the rooms nobody else has a role in. It returns how many
+// rooms it handed over and how many it deactivated.
+func (s *AccountDeletionService) handOverRooms(ctx context.Context, userID bson.ObjectID) (int, int, error) {
+	rooms, err := s.roomRepo.FindMany(ctx, bson.M{"createdBy": userID}, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	transferred, deactivated := 0, 0
+	for _, room := range rooms {
+		if successor, ok := roomSuccessor(room); ok {
+			if err := s.roomRepo.TransferOwnership(ctx, room.ID, userID, successor); err != nil && !errors.Is(err, models.ErrRoomNotFound) {
+				return transferred, deactivated, err
+			}
+			s.logger.Info("Room handed over from purged owner", "roomId", room.ID.Hex(), "userId", userID.Hex(), "ownerId", successor.Hex())
+			transferred++
+			continue
+		}
+
+		if err := s.roomRepo.SetActive(ctx, room.ID, false); err != nil && !errors.Is(err, models.ErrRoomNotFound) {
+			return transferred, deactivated, err
+		}
+		deactivated++
+	}
+	return transferred, deactivated, nil
+}
+
+// roomSuccessor picks who takes over a room from its owner: the member with the highest role, the one who
+// had it longest among equals. Moderators added before roles existed are co-hosts who had it first.
+func roomSuccessor(room *models.Room) (bson.ObjectID, bool) {
+	candidates := make([]models.RoomRoleAssignment, 0, len(room.Roles)+len(room.Moderators))
+	for _, assignment := range room.Roles {
+		if assignment.UserID != room.CreatedBy {
+			candidates = append(candidates, assignment)
+		}
+	}
+	for _, moderatorID := range room.Moderators {
+		assigned := slices.ContainsFunc(candidates, func(a models.RoomRoleAssignment) bool { return a.UserID == moderatorID })
+		if moderatorID != room.CreatedBy && !assigned {
+			candidates = append(candidates, models.RoomRoleAssignment{UserID: moderatorID, Role: models.RoomRoleCoHost})
+		}
+	}
+	if len(candidates) == 0 {
+		return bson.ObjectID{}, false
+	}
+
+	successor := slices.MinFunc(candidates, func(a, b models.RoomRoleAssignment) int {
+		if rank := cmp.Compare(models.RoomRoleRanks[b.Role], models.RoomRoleRanks[a.Role]); rank != 0 {
+			return rank
+		}
+		return a.AssignedAt.Compare(b.AssignedAt)
+	})
+	return successor.UserID, true
+}
+
+// PurgeDueAccounts purges the accounts whose grace period ended. It runs as a maintenance task.
+func (s *AccountDeletionService) PurgeDueAccounts(ctx context.Context) error {
+	users, err := s.userManager.userRepo.FindDueDeletions(ctx, time.Now(), s.policy.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, user := range users {
+		if err := s.PurgeAccount(ctx, user.ID); err != nil {
+			s.logger.Error("Failed to purge account", err, "userId", user.ID.Hex())
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to purge %d of %d accounts", failed, len(users))
+	}
+	return nil
+}
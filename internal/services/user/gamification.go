@@ -0,0 +1,290 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// achievementsTTL is how long the achievement definitions are cached, so definitions changed in the
+// database are picked up without a restart.
+const achievementsTTL = 5 * time.Minute
+
+// defaultAchievements are the achievements created when they are missing from the database. Definitions
+// already in the database are left as they are.
+var defaultAchievements = []models.Achievement{
+	{Key: "first_play", Name: "First Spin", Description: "Play your first track as DJ", Icon: "disc", Metric: models.AchievementMetricPlays, Threshold: 1},
+	{Key: "plays_100", Name: "Crate Digger", Description: "Play 100 tracks as DJ", Icon: "crate", Metric: models.AchievementMetricPlays, Threshold: 100},
+	{Key: "plays_1000", Name: "Resident DJ", Description: "Play 1,000 tracks as DJ", Icon: "turntable", Metric: models.AchievementMetricPlays, Threshold: 1000},
+	{Key: "woots_100", Name: "Crowd Pleaser", Description: "Receive 100 woots for your plays", Icon: "heart", Metric: models.AchievementMetricWoots, Threshold: 100},
+	{Key: "woots_1000", Name: "Dancefloor Hero", Description: "Receive 1,000 woots for your plays", Icon: "fire", Metric: models.AchievementMetricWoots, Threshold: 1000},
+	{Key: "dj_hours_10", Name: "Marathon", Description: "Spend 10 hours DJing", Icon: "clock", Metric: models.AchievementMetricDJMinutes, Threshold: 600},
+	{Key: "chat_1000", Name: "Chatterbox", Description: "Send 1,000 chat messages", Icon: "speech", Metric: models.AchievementMetricChatMessages, Threshold: 1000},
+	{Key: "level_10", Name: "Regular", Description: "Reach level 10", Icon: "star", Metric: models.AchievementMetricLevel, Threshold: 10},
+	{Key: "level_25", Name: "Veteran", Description: "Reach level 25", Icon: "crown", Metric: models.AchievementMetricLevel, Threshold: 25},
+}
+
+// ExperienceEvent is experience a user earned, recorded together with the statistics that earned it.
+type ExperienceEvent struct {
+	// UserID is the user who earned the experience.
+	UserID bson.ObjectID
+
+	// RoomID is the room the experience was earned in, zero if it wasn't earned in a room.
+	RoomID bson.ObjectID
+
+	// Experience is the number of experience points earned.
+	Experience int
+
+	// Stats are the increments of the user's statistics recorded with the experience.
+	Stats bson.M
+}
+
+// GamificationService awards users experience, raises their level as it crosses the level thresholds,
+// and unlocks the achievements whose statistic they reached. Achievement definitions are kept in the
+// database, so they can be added or tuned without a release.
+type GamificationService struct {
+	userManager     *Manager
+	achievementRepo repositories.AchievementRepository
+	logger          *utils.Logger
+
+	mu           sync.Mutex
+	achievements []*models.Achievement
+	loadedAt     time.Time
+
+	// levelUpHandlers are notified of every user reaching a higher level
+	levelUpHandlers []func(ctx context.Context, levelUp models.LevelUp)
+
+	// unlockHandlers are notified of every achievement a user unlocks
+	unlockHandlers []func(ctx context.Context, unlocked *models.UserAchievement, achievement *models.Achievement)
+}
+
+// NewGamificationService creates a new gamification service.
+func NewGamificationService(userManager *Manager, achievementRepo repositories.AchievementRepository, logger *utils.Logger) *GamificationService {
+	return &GamificationService{
+		userManager:     userManager,
+		achievementRepo: achievementRepo,
+		logger:          logger.Named("gamification_service"),
+	}
+}
+
+// AddLevelUpHandler adds a handler called for every user reaching a higher level.
+func (s *GamificationService) AddLevelUpHandler(handler func(ctx context.Context, levelUp models.LevelUp)) {
+	s.levelUpHandlers = append(s.levelUpHandlers, handler)
+}
+
+// AddUnlockHandler adds a handler called for every achievement a user unlocks.
+func (s *GamificationService) AddUnlockHandler(handler func(ctx context.Context, unlocked *models.UserAchievement, achievement *models.Achievement)) {
+	s.unlockHandlers = append(s.unlockHandlers, handler)
+}
+
+// SeedAchievements creates the default achievements missing from the database.
+func (s *GamificationService) SeedAchievements(ctx context.Context) error {
+	created := 0
+	for _, achievement := range defaultAchievements {
+		err := s.achievementRepo.CreateAchievement(ctx, &achievement)
+		if errors.Is(err, models.ErrAchievementExists) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		created++
+	}
+
+	if created > 0 {
+		s.logger.Info("Created default achievements", "count", created)
+	}
+	return nil
+}
+
+// Record records experience a user earned along with the statistics that earned it, levels the user up
+// if they crossed a level threshold, and unlocks the achievements they reached.
+func (s *GamificationService) Record(ctx context.Context, event ExperienceEvent) error {
+	updates := bson.M{}
+	maps.Copy(updates, event.Stats)
+	if event.Experience > 0 {
+		updates["experience"] = event.Experience
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	if err := s.userManager.userRepo.UpdateStats(ctx, event.UserID, updates); err != nil {
+		return err
+	}
+
+	user, err := s.userManager.userRepo.FindByID(ctx, event.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.levelUp(ctx, event, &user.Stats); err != nil {
+		s.logger.Error("Failed to level up user", err, "userId", event.UserID.Hex())
+	}
+	return s.unlockAchievements(ctx, event.UserID, &user.Stats)
+}
+
+// GetAchievements gets a user's progress towards every achievement.
+func (s *GamificationService) GetAchievements(ctx context.Context, userID string) ([]*models.AchievementProgress, error) {
+	user, err := s.userManager.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	achievements, err := s.definitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	unlocked, err := s.achievementRepo.FindUserAchievements(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	unlockedAt := make(map[string]time.Time, len(unlocked))
+	for _, achievement := range unlocked {
+		unlockedAt[achievement.Key] = achievement.UnlockedAt
+	}
+
+	progress := make([]*models.AchievementProgress, 0, len(achievements))
+	for _, achievement := range achievements {
+		at, ok := unlockedAt[achievement.Key]
+		progress = append(progress, &models.AchievementProgress{
+			Achievement: achievement,
+			Progress:    min(metricValue(&user.Stats, achievement.Metric), achievement.Threshold),
+			Unlocked:    ok,
+			UnlockedAt:  at,
+		})
+	}
+	return progress, nil
+}
+
+// levelUp raises a user's level if their experience crossed a level threshold.
+func (s *GamificationService) levelUp(ctx context.Context, event ExperienceEvent, stats *models.UserStats) error {
+	level := calculateLevel(stats.Experience)
+	if level <= stats.Level {
+		return nil
+	}
+
+	set, err := s.userManager.userRepo.SetLevel(ctx, event.UserID, stats.Level, level)
+	if err != nil || !set {
+		// Experience recorded meanwhile already raised the level
+		return err
+	}
+
+	levelUp := models.LevelUp{
+		UserID:   event.UserID,
+		RoomID:   event.RoomID,
+		OldLevel: stats.Level,
+		NewLevel: level,
+	}
+	stats.Level = level
+	s.logger.Info("User leveled up", "userId", event.UserID.Hex(), "oldLevel", levelUp.OldLevel, "newLevel", levelUp.NewLevel)
+
+	// Users stored without a level only get the level they should have had
+	if levelUp.OldLevel < 1 {
+		return nil
+	}
+	for _, handler := range s.levelUpHandlers {
+		handler(ctx, levelUp)
+	}
+	return nil
+}
+
+// unlockAchievements unlocks the achievements a user reached and hasn't unlocked yet.
+func (s *GamificationService) unlockAchievements(ctx context.Context, userID bson.ObjectID, stats *models.UserStats) error {
+	achievements, err := s.definitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	var reached []*models.Achievement
+	for _, achievement := range achievements {
+		if metricValue(stats, achievement.Metric) >= achievement.Threshold {
+			reached = append(reached, achievement)
+		}
+	}
+	if len(reached) == 0 {
+		return nil
+	}
+
+	unlocked, err := s.achievementRepo.FindUserAchievements(ctx, userID)
+	if err != nil {
+		return err
+	}
+	keys := make(map[string]bool, len(unlocked))
+	for _, achievement := range unlocked {
+		keys[achievement.Key] = true
+	}
+
+	for _, achievement := range reached {
+		if keys[achievement.Key] {
+			continue
+		}
+
+		userAchievement := &models.UserAchievement{
+			UserID:     userID,
+			Key:        achievement.Key,
+			UnlockedAt: time.Now(),
+		}
+		err := s.achievementRepo.UnlockAchievement(ctx, userAchievement)
+		if errors.Is(err, models.ErrAchievementUnlocked) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		s.logger.Info("Achievement unlocked", "userId", userID.Hex(), "key", achievement.Key)
+		for _, handler := range s.unlockHandlers {
+			handler(ctx, userAchievement, achievement)
+		}
+	}
+	return nil
+}
+
+// definitions gets the achievement definitions, loading them from the database when the cached ones expired.
+func (s *GamificationService) definitions(ctx context.Context) ([]*models.Achievement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.achievements != nil && time.Since(s.loadedAt) < achievementsTTL {
+		return s.achievements, nil
+	}
+
+	achievements, err := s.achievementRepo.FindAchievements(ctx)
+	if err != nil {
+		if s.achievements != nil {
+			// Keep going with the definitions loaded before
+			s.logger.Error("Failed to reload achievements", err)
+			return s.achievements, nil
+		}
+		return nil, err
+	}
+
+	s.achievements, s.loadedAt = achievements, time.Now()
+	return achievements, nil
+}
+
+// metricValue gets a user's value of the statistic an achievement is unlocked by.
+func metricValue(stats *models.UserStats, metric models.AchievementMetric) int64 {
+	switch metric {
+	case models.AchievementMetricPlays:
+		return int64(stats.PlayCount)
+	case models.AchievementMetricWoots:
+		return int64(stats.Woots)
+	case models.AchievementMetricDJMinutes:
+		return stats.DJTime / 60
+	case models.AchievementMetricChatMessages:
+		return int64(stats.ChatMessages)
+	case models.AchievementMetricLevel:
+		return int64(stats.Level)
+	}
+	return 0
+}
@@ -0,0 +1,275 @@
+// Package user provides services for user management and operations.
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// guestRole is the role of guest accounts.
+	guestRole = "guest"
+
+	// guestEmailDomain is the reserved domain of the placeholder addresses guest accounts are stored with.
+	guestEmailDomain = "@guest.invalid"
+
+	// guestClaimTimeout is how long a registration can hold a guest before another registration can take it over.
+	guestClaimTimeout = time.Minute
+
+	// guestMoveAttempts is the number of times a move of a guest to their new account is tried.
+	guestMoveAttempts = 3
+
+	// guestMoveBackoff is the delay before retrying a move, growing with each attempt.
+	guestMoveBackoff = 100 * time.Millisecond
+)
+
+// GuestPolicy controls guest access.
+type GuestPolicy struct {
+	// Enabled allows listening as a guest without registering.
+	Enabled bool
+
+	// HistoryTransfer allows guests to keep their listening time and history when they register.
+	HistoryTransfer bool
+}
+
+// GuestService lets people listen as guests and links their guest session to the account they register,
+// so they keep their place in their room and, if they ask to, what they listened to.
+type GuestService struct {
+	userManager *Manager
+	historyRepo repositories.HistoryRepository
+	policy      GuestPolicy
+	logger      *utils.Logger
+
+	// linkHandlers move the guest's state held by other services to the new account
+	linkHandlers []func(ctx context.Context, guest, user *models.User) error
+}
+
+// NewGuestService creates a new guest service.
+func NewGuestService(userManager *Manager, historyRepo repositories.HistoryRepository, policy GuestPolicy, logger *utils.Logger) *GuestService {
+	return &GuestService{
+		userManager: userManager,
+		historyRepo: historyRepo,
+		policy:      policy,
+		logger:      logger.Named("guest_service"),
+	}
+}
+
+// AddLinkHandler adds a handler called when a guest registers, before the guest account is closed.
+func (s *GuestService) AddLinkHandler(handler func(ctx context.Context, guest, user *models.User) error) {
+	s.linkHandlers = append(s.linkHandlers, handler)
+}
+
+// CreateGuest creates a guest account and returns it with its token.
+// Guest accounts have no email address or password, so they can't be signed in to again.
+func (s *GuestService) CreateGuest(ctx context.Context) (*models.User, string, error) {
+	if !s.policy.Enabled {
+		return nil, "", models.ErrGuestsDisabled
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, "", models.NewInternalError(err, "Failed to generate guest name")
+	}
+
+	guest := s.userManager.newUser("guest-"+hex.EncodeToString(suffix), "", "")
+	guest.Email = guest.ID.Hex() + guestEmailDomain // Keeps the unique email index satisfied
+	guest.Roles = []string{guestRole}
+	guest.Guest = &models.GuestAccount{}
+
+	if err := s.userManager.userRepo.Create(ctx, guest); err != nil {
+		s.logger.Error("Failed to create guest", err)
+		return nil, "", err
+	}
+
+	token, err := s.userManager.startSession(ctx, guest)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return guest, token, nil
+}
+
+// Register creates a user account for a guest and links the guest session to it. The guest's place
+// in their room, DJ queue spot and vote on the playing track move to the new account, and the guest
+// account is closed. With KeepGuestHistory, the guest's listening time and history move along too.
+// The guest is claimed before the account is created, so it can only be linked once, and a move that
+// keeps failing undoes the others and removes the account, leaving the guest as it was.
+func (s *GuestService) Register(ctx context.Context, req models.UserRegisterRequest) (*models.User, string, error) {
+	guest, err := s.findGuest(ctx, req.GuestToken)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.userManager.userRepo.ClaimGuest(ctx, guest.ID, time.Now().Add(-guestClaimTimeout)); err != nil {
+		return nil, "", err
+	}
+
+	user, token, err := s.userManager.Register(ctx, req)
+	if err != nil {
+		s.releaseGuest(ctx, guest)
+		return nil, "", err
+	}
+
+	keepHistory := req.KeepGuestHistory && s.policy.HistoryTransfer
+	if err := s.moveGuest(ctx, guest, user, keepHistory); err != nil {
+		s.logger.Error("Failed to move guest to new account", err, "guestId", guest.ID.Hex(), "userId", user.ID.Hex())
+		s.discardAccount(ctx, user)
+		s.releaseGuest(ctx, guest)
+		return nil, "", models.NewInternalError(err, "Failed to move guest to new account")
+	}
+
+	if err := s.userManager.userRepo.LinkGuest(ctx, guest.ID, user.ID, time.Now()); err != nil {
+		s.logger.Error("Failed to mark guest as linked", err, "guestId", guest.ID.Hex())
+		// Continue anyway, the guest stays claimed so it can't register again
+	}
+	if err := s.userManager.DeactivateAccount(ctx, guest.ID.Hex()); err != nil {
+		s.logger.Error("Failed to close guest account", err, "guestId", guest.ID.Hex())
+	}
+
+	s.logger.Info("Linked guest to new account", "guestId", guest.ID.Hex(), "userId", user.ID.Hex(), "keptHistory", keepHistory)
+	return user, token, nil
+}
+
+// guestMove is a step of moving a guest to their new account, along with the step undoing it.
+type guestMove struct {
+	name string
+	move func(ctx context.Context) error
+	undo func(ctx context.Context) error
+}
+
+// moveGuest moves a guest's state, and with keepHistory their history, to their new account. Each move is
+// retried, and one that still fails undoes the moves made so far, itself included, in reverse.
+func (s *GuestService) moveGuest(ctx context.Context, guest, user *models.User, keepHistory bool) error {
+	var moves []guestMove
+	for _, handler := range s.linkHandlers {
+		moves = append(moves, guestMove{
+			name: "state",
+			move: func(ctx context.Context) error { return handler(ctx, guest, user) },
+			undo: func(ctx context.Context) error { return handler(ctx, user, guest) },
+		})
+	}
+	if keepHistory {
+		moves = append(moves, s.historyMoves(guest, user)...)
+	}
+
+	for i, move := range moves {
+		err := retryGuestMove(ctx, move.move)
+		if err == nil {
+			continue
+		}
+
+		for j := i; j >= 0; j-- {
+			if err := retryGuestMove(ctx, moves[j].undo); err != nil {
+				s.logger.Error("Failed to undo guest move", err, "move", moves[j].name, "guestId", guest.ID.Hex(), "userId", user.ID.Hex())
+			}
+		}
+		return fmt.Errorf("failed to move guest %s: %w", move.name, err)
+	}
+	return nil
+}
+
+// historyMoves are the moves attributing a guest's history records and listening time to the new account.
+// Listening time is moved with increments, so the moves don't overwrite anything else written to either account.
+func (s *GuestService) historyMoves(guest, user *models.User) []guestMove {
+	userRepo := s.userManager.userRepo
+	seconds := guest.Stats.AudienceTime
+
+	return []guestMove{
+		{
+			name: "history",
+			move: func(ctx context.Context) error {
+				_, err := s.historyRepo.ReassignUser(ctx, guest.ID, user.ID)
+				return err
+			},
+			undo: func(ctx context.Context) error {
+				_, err := s.historyRepo.ReassignUser(ctx, user.ID, guest.ID)
+				return err
+			},
+		},
+		{
+			name: "listening time",
+			move: func(ctx context.Context) error {
+				return userRepo.AddAudienceTime(ctx, []bson.ObjectID{user.ID}, seconds)
+			},
+			undo: func(ctx context.Context) error {
+				return userRepo.AddAudienceTime(ctx, []bson.ObjectID{user.ID}, -seconds)
+			},
+		},
+		{
+			name: "guest listening time",
+			move: func(ctx context.Context) error {
+				return userRepo.AddAudienceTime(ctx, []bson.ObjectID{guest.ID}, -seconds)
+			},
+			undo: func(ctx context.Context) error {
+				return userRepo.AddAudienceTime(ctx, []bson.ObjectID{guest.ID}, seconds)
+			},
+		},
+	}
+}
+
+// retryGuestMove runs a move until it succeeds, up to guestMoveAttempts times.
+func retryGuestMove(ctx context.Context, move func(ctx context.Context) error) error {
+	var err error
+	for attempt := 1; attempt <= guestMoveAttempts; attempt++ {
+		if err = move(ctx); err == nil {
+			return nil
+		}
+		if attempt == guestMoveAttempts {
+			break
+		}
+
+		select {
+		case <-time.After(time.Duration(attempt) * guestMoveBackoff):
+		case <-ctx.Done():
+			return err
+		}
+	}
+	return err
+}
+
+// discardAccount removes an account created for a guest whose move failed.
+func (s *GuestService) discardAccount(ctx context.Context, user *models.User) {
+	if err := s.userManager.DeleteAccount(ctx, user.ID.Hex()); err != nil {
+		s.logger.Error("Failed to remove account of failed guest registration", err, "userId", user.ID.Hex())
+	}
+}
+
+// releaseGuest drops the claim on a guest whose registration failed, so it can register again.
+func (s *GuestService) releaseGuest(ctx context.Context, guest *models.User) {
+	if err := s.userManager.userRepo.ReleaseGuest(ctx, guest.ID); err != nil {
+		s.logger.Error("Failed to release guest", err, "guestId", guest.ID.Hex())
+		// Continue anyway, the claim goes stale after guestClaimTimeout
+	}
+}
+
+// findGuest finds the guest account a guest token belongs to, if it isn't linked yet.
+func (s *GuestService) findGuest(ctx context.Context, token string) (*models.User, error) {
+	claims, err := s.userManager.authProvider.ValidateToken(token)
+	if err != nil {
+		return nil, models.ErrInvalidToken
+	}
+
+	guest, err := s.userManager.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return nil, models.ErrNotGuest
+		}
+		return nil, err
+	}
+	if guest.Guest == nil {
+		return nil, models.ErrNotGuest
+	}
+	if !guest.Guest.LinkedTo.IsZero() || !guest.IsActive {
+		return nil, models.ErrGuestAlreadyLinked
+	}
+
+	return guest, nil
+}
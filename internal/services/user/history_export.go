@@ -0,0 +1,148 @@
+package user
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// historyExportSlotsKey prefixes the number of history exports and data archives a user is downloading.
+const historyExportSlotsKey = "history_export:active:"
+
+// errExportCutOff stops an export that ran for its maximum duration.
+var errExportCutOff = errors.New("history export ran for its maximum duration")
+
+// HistoryExportPolicy configures how play history exports are read and limited.
+type HistoryExportPolicy struct {
+	// MaxConcurrent is the number of exports a user may download at once, across all instances.
+	MaxConcurrent int
+
+	// BatchSize is the number of plays read from the database at a time.
+	BatchSize int
+
+	// MaxDuration is the longest a single export download may run. Longer exports are resumed with a
+	// range token.
+	MaxDuration time.Duration
+}
+
+// HistoryExportService streams users' play history as DJ out of the database as it is downloaded. Plays
+// are read from a cursor only as fast as the download takes them, and every play comes with a range
+// token resuming the export after it, so an interrupted download picks up where it stopped. Resumed
+// exports stop at the same point as the first download, so they add up to one consistent export.
+type HistoryExportService struct {
+	historyRepo repositories.HistoryRepository
+	redisClient *redis.Client
+	policy      HistoryExportPolicy
+	logger      *utils.Logger
+}
+
+// NewHistoryExportService creates a new history export service.
+func NewHistoryExportService(historyRepo repositories.HistoryRepository, redisClient *redis.Client, policy HistoryExportPolicy, logger *utils.Logger) *HistoryExportService {
+	return &HistoryExportService{
+		historyRepo: historyRepo,
+		redisClient: redisClient,
+		policy:      policy,
+		logger:      logger.Named("history_export_service"),
+	}
+}
+
+// ExportPlays passes the plays a user played as DJ to write, oldest first, each with the range token
+// resuming the export after it. An empty token starts a new export. It reports whether the export is
+// complete, or was cut off after running for the maximum duration and has to be resumed. An error from
+// write stops the export and is returned as is.
+func (s *HistoryExportService) ExportPlays(ctx context.Context, userID, token string, write func(play *models.PlayHistory, next string) error) (bool, error) {
+	objectID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, models.ErrInvalidID
+	}
+
+	after, before, err := parseExportToken(token)
+	if err != nil {
+		return false, err
+	}
+
+	release, err := acquireExportSlot(ctx, s.redisClient, s.policy, userID, s.logger)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	deadline := time.Now().Add(s.policy.MaxDuration)
+	err = s.historyRepo.StreamPlayHistoryByDJ(ctx, objectID, after, before, s.policy.BatchSize, func(play *models.PlayHistory) error {
+		if time.Now().After(deadline) {
+			return errExportCutOff
+		}
+		return write(play, exportToken(play.ID, before))
+	})
+	if errors.Is(err, errExportCutOff) {
+		s.logger.Info("History export cut off", "userId", userID)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// acquireExportSlot takes one of a user's export slots, shared by history exports and data archives, and
+// returns the function giving it back. The slot count expires once no export could still be running, so
+// slots of an instance that died aren't lost.
+func acquireExportSlot(ctx context.Context, redisClient *redis.Client, policy HistoryExportPolicy, userID string, logger *utils.Logger) (func(), error) {
+	key := historyExportSlotsKey + userID
+
+	active, err := redisClient.Incr(ctx, key)
+	if err != nil {
+		logger.Error("Failed to take export slot", err, "userId", userID)
+		return nil, models.NewInternalError(err, "Failed to start export")
+	}
+	release := func() {
+		if _, err := redisClient.Decr(context.WithoutCancel(ctx), key); err != nil {
+			logger.Error("Failed to release export slot", err, "userId", userID)
+		}
+	}
+
+	if err := redisClient.Expire(ctx, key, policy.MaxDuration+time.Minute); err != nil {
+		release()
+		return nil, models.NewInternalError(err, "Failed to start export")
+	}
+	if active > int64(policy.MaxConcurrent) {
+		release()
+		return nil, models.ErrTooManyExports
+	}
+	return release, nil
+}
+
+// exportToken encodes the range token resuming an export after a play: the play's ID and the ID the
+// export stops before.
+func exportToken(after, before bson.ObjectID) string {
+	raw := make([]byte, 0, 2*len(after))
+	raw = append(raw, after[:]...)
+	raw = append(raw, before[:]...)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// parseExportToken decodes a range token into the play an export resumes after and the ID it stops
+// before. An empty token starts at the first play and stops after the plays recorded so far.
+func parseExportToken(token string) (after, before bson.ObjectID, err error) {
+	if token == "" {
+		return bson.ObjectID{}, bson.NewObjectIDFromTimestamp(time.Now().Add(time.Second)), nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != 2*len(after) {
+		return after, before, models.ErrInvalidExportToken
+	}
+	copy(after[:], raw[:len(after)])
+	copy(before[:], raw[len(after):])
+	if after.Timestamp().After(before.Timestamp()) {
+		return after, before, models.ErrInvalidExportToken
+	}
+	return after, before, nil
+}
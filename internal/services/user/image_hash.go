@@ -0,0 +1,122 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // Register GIF decoding for avatars and covers
+	_ "image/jpeg" // Register JPEG decoding for avatars and covers
+	_ "image/png"  // Register PNG decoding for avatars and covers
+	"io"
+	"math/bits"
+	"net/http"
+	"strconv"
+	"time"
+
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// imageFetchTimeout is how long downloading an image to hash it may take.
+	imageFetchTimeout = 15 * time.Second
+
+	// hashSamples is the most pixels sampled along each side of a hash cell, so huge images hash quickly.
+	hashSamples = 16
+)
+
+// newImageClient creates the HTTP client downloading users' images. It refuses to connect to addresses
+// that aren't publicly routable, so image URLs can't be used to reach internal services. Redirects are
+// followed, they are held to the same addresses.
+func newImageClient() *http.Client {
+	return utils.NewPublicHTTPClient(imageFetchTimeout, true)
+}
+
+// fetchImageHash downloads an image and computes its perceptual hash. Images larger than maxSize bytes
+// can't be read.
+func fetchImageHash(ctx context.Context, client *http.Client, url string, maxSize int64) (uint64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("image download returned status %d", resp.StatusCode)
+	}
+
+	img, _, err := image.Decode(io.LimitReader(resp.Body, maxSize))
+	if err != nil {
+		return 0, err
+	}
+	if img.Bounds().Dx() < 9 || img.Bounds().Dy() < 8 {
+		return 0, errors.New("image is too small to hash")
+	}
+	return differenceHash(img), nil
+}
+
+// differenceHash computes the 64-bit difference hash of an image: the image is reduced to a 9x8 grid of
+// brightness, and each bit tells whether a cell is darker than the cell to its right. Resized, recompressed
+// and slightly edited copies of an image get hashes only a few bits apart. Images must be at least 9x8 pixels.
+func differenceHash(img image.Image) uint64 {
+	bounds := img.Bounds()
+
+	var grid [8][9]float64
+	for row := range 8 {
+		y0 := bounds.Min.Y + row*bounds.Dy()/8
+		y1 := bounds.Min.Y + (row+1)*bounds.Dy()/8
+		for col := range 9 {
+			x0 := bounds.Min.X + col*bounds.Dx()/9
+			x1 := bounds.Min.X + (col+1)*bounds.Dx()/9
+			grid[row][col] = cellBrightness(img, x0, y0, x1, y1)
+		}
+	}
+
+	var hash uint64
+	for row := range 8 {
+		for col := range 8 {
+			hash <<= 1
+			if grid[row][col] < grid[row][col+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// cellBrightness averages the brightness of a sample of the pixels in a rectangle of an image.
+func cellBrightness(img image.Image, x0, y0, x1, y1 int) float64 {
+	stepX := max(1, (x1-x0)/hashSamples)
+	stepY := max(1, (y1-y0)/hashSamples)
+
+	var sum float64
+	var count int
+	for y := y0; y < y1; y += stepY {
+		for x := x0; x < x1; x += stepX {
+			r, g, b, _ := img.At(x, y).RGBA()
+			sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			count++
+		}
+	}
+	return sum / float64(count)
+}
+
+// hashDistance counts the bits two perceptual hashes differ by.
+func hashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// formatImageHash formats a perceptual hash as 16 hexadecimal digits.
+func formatImageHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// parseImageHash parses a perceptual hash formatted by formatImageHash.
+func parseImageHash(hash string) (uint64, error) {
+	return strconv.ParseUint(hash, 16, 64)
+}
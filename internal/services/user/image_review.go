@@ -0,0 +1,345 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// blockedImageReason is the removal reason of images that matched a blocked image.
+const blockedImageReason = "This image isn't allowed"
+
+// ImageReviewPolicy controls how changed avatars and playlist covers are matched against the blocked images.
+type ImageReviewPolicy struct {
+	// CheckInterval is how often changed images are matched. Zero disables automatic matching.
+	CheckInterval time.Duration
+
+	// CheckBatch is the most changed images matched per check.
+	CheckBatch int
+
+	// MatchDistance is the most bits a perceptual hash may differ from a blocked image's to match it.
+	MatchDistance int
+
+	// MaxImageSize is the largest image in bytes that can be downloaded to hash it.
+	MaxImageSize int64
+}
+
+// ImageReviewService queues the avatars and playlist covers users set for review by platform admins.
+// Images matching a known-bad image are removed right away. Removed images are taken off the avatar
+// or playlist, which goes back to its default, and their owner is told.
+type ImageReviewService struct {
+	userManager  *Manager
+	reviewRepo   repositories.ImageReviewRepository
+	playlistRepo repositories.PlaylistRepository
+	policy       ImageReviewPolicy
+	httpClient   *http.Client
+	logger       *utils.Logger
+
+	// removalHandlers are notified of every image removed, to tell its owner
+	removalHandlers []func(ctx context.Context, review *models.ImageReview)
+}
+
+// NewImageReviewService creates a new image review service.
+func NewImageReviewService(userManager *Manager, reviewRepo repositories.ImageReviewRepository, playlistRepo repositories.PlaylistRepository, policy ImageReviewPolicy, logger *utils.Logger) *ImageReviewService {
+	return &ImageReviewService{
+		userManager:  userManager,
+		reviewRepo:   reviewRepo,
+		playlistRepo: playlistRepo,
+		policy:       policy,
+		httpClient:   newImageClient(),
+		logger:       logger.Named("image_review_service"),
+	}
+}
+
+// AddRemovalHandler adds a handler called for every image removed, by an admin or automatically.
+func (s *ImageReviewService) AddRemovalHandler(handler func(ctx context.Context, review *models.ImageReview)) {
+	s.removalHandlers = append(s.removalHandlers, handler)
+}
+
+// Submit queues an image a user set for review.
+func (s *ImageReviewService) Submit(ctx context.Context, kind models.ImageKind, ownerID, targetID bson.ObjectID, url string) error {
+	review := &models.ImageReview{
+		Kind:     kind,
+		OwnerID:  ownerID,
+		TargetID: targetID,
+		URL:      url,
+		Status:   models.ImageReviewPending,
+	}
+	if err := s.reviewRepo.CreateImageReview(ctx, review); err != nil {
+		return err
+	}
+
+	s.logger.Debug("Image queued for review", "reviewId", review.ID.Hex(), "kind", kind, "targetId", targetID.Hex())
+	return nil
+}
+
+// Start begins matching changed images against the blocked images.
+func (s *ImageReviewService) Start(ctx context.Context) {
+	if s.policy.CheckInterval <= 0 {
+		s.logger.Info("Automatic image matching is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.policy.CheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				s.logger.Info("Stopping image matching")
+				return
+			case <-ticker.C:
+				if err := s.CheckImages(ctx); err != nil {
+					s.logger.Error("Failed to check changed images", err)
+				}
+			}
+		}
+	}()
+
+	s.logger.Info("Image matching started", "interval", s.policy.CheckInterval)
+}
+
+// CheckImages hashes the changed images not checked yet and removes those matching a blocked image.
+// Images that can't be downloaded or read stay in the queue for the admins.
+func (s *ImageReviewService) CheckImages(ctx context.Context) error {
+	reviews, err := s.reviewRepo.FindUncheckedImageReviews(ctx, s.policy.CheckBatch)
+	if err != nil || len(reviews) == 0 {
+		return err
+	}
+
+	blocked, err := s.reviewRepo.FindBlockedImages(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, review := range reviews {
+		hash, err := fetchImageHash(ctx, s.httpClient, review.URL, s.policy.MaxImageSize)
+		if err != nil {
+			s.logger.Warn("Failed to hash changed image", "reviewId", review.ID.Hex(), "url", review.URL, "error", err)
+			if err := s.reviewRepo.SetImageReviewHash(ctx, review.ID, ""); err != nil {
+				return err
+			}
+			continue
+		}
+
+		review.Hash = formatImageHash(hash)
+		if err := s.reviewRepo.SetImageReviewHash(ctx, review.ID, review.Hash); err != nil {
+			return err
+		}
+
+		if match := s.match(hash, blocked); match != nil {
+			if _, err := s.remove(ctx, review, blockedImageReason, bson.NilObjectID, match.ID); err != nil && !errors.Is(err, models.ErrImageReviewed) {
+				s.logger.Error("Failed to remove blocked image", err, "reviewId", review.ID.Hex())
+			}
+		}
+	}
+
+	return nil
+}
+
+// match finds the blocked image a perceptual hash matches, nil if it matches none.
+func (s *ImageReviewService) match(hash uint64, blocked []*models.BlockedImage) *models.BlockedImage {
+	for _, image := range blocked {
+		blockedHash, err := parseImageHash(image.Hash)
+		if err != nil {
+			continue
+		}
+		if hashDistance(hash, blockedHash) <= s.policy.MatchDistance {
+			return image
+		}
+	}
+	return nil
+}
+
+// ListReviews lists image reviews, most recently changed first, along with the total number matching.
+func (s *ImageReviewService) ListReviews(ctx context.Context, filter models.ImageReviewFilter, skip, limit int) ([]*models.ImageReview, int64, error) {
+	return s.reviewRepo.FindImageReviews(ctx, filter, skip, limit)
+}
+
+// GetReview gets an image review by ID.
+func (s *ImageReviewService) GetReview(ctx context.Context, reviewID bson.ObjectID) (*models.ImageReview, error) {
+	return s.reviewRepo.FindImageReviewByID(ctx, reviewID)
+}
+
+// ResolveReviews applies an admin's decision to pending image reviews. Reviews the decision can't be
+// applied to, such as those another admin resolved first, are reported without stopping the others.
+func (s *ImageReviewService) ResolveReviews(ctx context.Context, adminID bson.ObjectID, decision *models.ImageReviewDecision) (*models.ImageReviewResults, error) {
+	decision.Reason = strings.TrimSpace(decision.Reason)
+	if err := utils.Validate(decision); err != nil {
+		return nil, models.NewUserError(models.ErrInvalidImage, err.Error(), http.StatusBadRequest)
+	}
+
+	results := &models.ImageReviewResults{
+		Resolved: []*models.ImageReview{},
+		Failed:   map[string]string{},
+	}
+	for _, reviewID := range decision.ReviewIDs {
+		review, err := s.resolve(ctx, reviewID, adminID, decision)
+		if err != nil {
+			if models.MapErrorToHTTPStatus(err) == http.StatusInternalServerError {
+				s.logger.Error("Failed to resolve image review", err, "reviewId", reviewID.Hex())
+			}
+			results.Failed[reviewID.Hex()] = err.Error()
+			continue
+		}
+		results.Resolved = append(results.Resolved, review)
+	}
+
+	s.logger.Info("Image reviews resolved", "adminId", adminID.Hex(), "status", decision.Status,
+		"resolved", len(results.Resolved), "failed", len(results.Failed))
+	return results, nil
+}
+
+// resolve applies an admin's decision to a pending image review.
+func (s *ImageReviewService) resolve(ctx context.Context, reviewID, adminID bson.ObjectID, decision *models.ImageReviewDecision) (*models.ImageReview, error) {
+	review, err := s.reviewRepo.FindImageReviewByID(ctx, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if review.Status != models.ImageReviewPending {
+		return nil, models.ErrImageReviewed
+	}
+
+	if decision.Status == models.ImageReviewApproved {
+		return s.reviewRepo.ResolveImageReview(ctx, reviewID, models.ImageReviewApproved, "", adminID, bson.NilObjectID)
+	}
+
+	resolved, err := s.remove(ctx, review, decision.Reason, adminID, bson.NilObjectID)
+	if err != nil {
+		return nil, err
+	}
+	if decision.Block {
+		s.blockReviewed(ctx, resolved, adminID)
+	}
+	return resolved, nil
+}
+
+// remove resolves a review as removed, takes the image off its avatar or playlist, and tells its owner.
+// The matched image is zero unless a blocked image removed the image.
+func (s *ImageReviewService) remove(ctx context.Context, review *models.ImageReview, reason string, adminID, matchedImageID bson.ObjectID) (*models.ImageReview, error) {
+	resolved, err := s.reviewRepo.ResolveImageReview(ctx, review.ID, models.ImageReviewRemoved, reason, adminID, matchedImageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.revert(ctx, resolved); err != nil {
+		s.logger.Error("Failed to revert removed image", err, "reviewId", resolved.ID.Hex(), "kind", resolved.Kind, "targetId", resolved.TargetID.Hex())
+	}
+
+	for _, handler := range s.removalHandlers {
+		handler(ctx, resolved)
+	}
+
+	s.logger.Info("Image removed", "reviewId", resolved.ID.Hex(), "kind", resolved.Kind, "targetId", resolved.TargetID.Hex(),
+		"automatic", !matchedImageID.IsZero())
+	return resolved, nil
+}
+
+// revert takes a removed image off the avatar or playlist it was set on, unless it was replaced since.
+// Avatars go back to a default avatar and playlists to having no cover.
+func (s *ImageReviewService) revert(ctx context.Context, review *models.ImageReview) error {
+	switch review.Kind {
+	case models.ImageKindAvatar:
+		return s.userManager.resetAvatar(ctx, review.TargetID, review.URL)
+
+	case models.ImageKindPlaylistCover:
+		playlist, err := s.playlistRepo.FindByID(ctx, review.TargetID)
+		if err != nil {
+			if errors.Is(err, models.ErrPlaylistNotFound) {
+				return nil
+			}
+			return err
+		}
+		if playlist.CoverImage != review.URL {
+			return nil
+		}
+		playlist.CoverImage = ""
+		playlist.UpdateNow()
+		return s.playlistRepo.Update(ctx, playlist)
+	}
+
+	return nil
+}
+
+// blockReviewed adds a removed image to the blocked images, hashing it first if it wasn't yet.
+func (s *ImageReviewService) blockReviewed(ctx context.Context, review *models.ImageReview, adminID bson.ObjectID) {
+	hash := review.Hash
+	if hash == "" {
+		computed, err := fetchImageHash(ctx, s.httpClient, review.URL, s.policy.MaxImageSize)
+		if err != nil {
+			s.logger.Warn("Failed to hash removed image to block it", "reviewId", review.ID.Hex(), "error", err)
+			return
+		}
+		hash = formatImageHash(computed)
+	}
+
+	image := &models.BlockedImage{
+		Hash:     hash,
+		Note:     review.Reason,
+		ReviewID: review.ID,
+		AddedBy:  adminID,
+	}
+	if err := s.reviewRepo.CreateBlockedImage(ctx, image); err != nil && !errors.Is(err, models.ErrImageAlreadyBlocked) {
+		s.logger.Error("Failed to block removed image", err, "reviewId", review.ID.Hex())
+	}
+}
+
+// ListBlockedImages lists the blocked images, most recently blocked first.
+func (s *ImageReviewService) ListBlockedImages(ctx context.Context) ([]*models.BlockedImage, error) {
+	return s.reviewRepo.FindBlockedImages(ctx)
+}
+
+// BlockImage adds an image to the blocked images, given by its perceptual hash or its URL. Images set
+// from then on are removed automatically if they match it.
+func (s *ImageReviewService) BlockImage(ctx context.Context, adminID bson.ObjectID, request *models.BlockedImageRequest) (*models.BlockedImage, error) {
+	request.Hash = strings.ToLower(strings.TrimSpace(request.Hash))
+	request.URL = strings.TrimSpace(request.URL)
+	request.Note = strings.TrimSpace(request.Note)
+	if err := utils.Validate(request); err != nil {
+		return nil, models.NewUserError(models.ErrInvalidImage, err.Error(), http.StatusBadRequest)
+	}
+	if (request.Hash == "") == (request.URL == "") {
+		return nil, models.NewUserError(models.ErrInvalidImage, "Give either the image's hash or its URL", http.StatusBadRequest)
+	}
+
+	hash, err := parseImageHash(request.Hash)
+	if request.URL != "" {
+		hash, err = fetchImageHash(ctx, s.httpClient, request.URL, s.policy.MaxImageSize)
+		if err != nil {
+			s.logger.Warn("Failed to hash image to block", "url", request.URL, "error", err)
+			return nil, models.NewUserError(models.ErrInvalidImage, "The image couldn't be downloaded or read", http.StatusBadRequest)
+		}
+	} else if err != nil {
+		return nil, models.NewUserError(models.ErrInvalidImage, "Image hashes are 16 hexadecimal digits", http.StatusBadRequest)
+	}
+
+	image := &models.BlockedImage{
+		Hash:    formatImageHash(hash),
+		Note:    request.Note,
+		AddedBy: adminID,
+	}
+	if err := s.reviewRepo.CreateBlockedImage(ctx, image); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Image blocked", "blockedImageId", image.ID.Hex(), "hash", image.Hash, "adminId", adminID.Hex())
+	return image, nil
+}
+
+// UnblockImage removes an image from the blocked images. Images it removed stay removed.
+func (s *ImageReviewService) UnblockImage(ctx context.Context, imageID, adminID bson.ObjectID) error {
+	if err := s.reviewRepo.DeleteBlockedImage(ctx, imageID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Image unblocked", "blockedImageId", imageID.Hex(), "adminId", adminID.Hex())
+	return nil
+}
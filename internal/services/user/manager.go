@@ -22,6 +22,18 @@ type Manager struct {
 	authProvider auth.Provider
 	logger       *utils.Logger
 	avatarSvc    *AvatarService
+
+	// usernamePolicy controls the usernames users choose
+	usernamePolicy UsernamePolicy
+
+	// createdHandlers are notified when a user registers
+	createdHandlers []func(ctx context.Context, user *models.User)
+
+	// renamedHandlers are notified when a user changes their username
+	renamedHandlers []func(ctx context.Context, rename Rename)
+
+	// avatarHandlers are notified when a user uploads a new avatar image
+	avatarHandlers []func(ctx context.Context, user *models.User)
 }
 
 // NewManager creates a new user manager.
@@ -30,20 +42,26 @@ func NewManager(
 	sessionMgr managers.SessionManager,
 	presenceMgr managers.PresenceManager,
 	authProvider auth.Provider,
+	usernamePolicy UsernamePolicy,
 	logger *utils.Logger,
 ) *Manager {
 	return &Manager{
-		userRepo:     userRepo,
-		sessionMgr:   sessionMgr,
-		presenceMgr:  presenceMgr,
-		authProvider: authProvider,
-		logger:       logger.Named("user_manager"),
-		avatarSvc:    NewAvatarService(logger),
+		userRepo:       userRepo,
+		sessionMgr:     sessionMgr,
+		presenceMgr:    presenceMgr,
+		authProvider:   authProvider,
+		usernamePolicy: usernamePolicy,
+		logger:         logger.Named("user_manager"),
+		avatarSvc:      NewAvatarService(logger),
 	}
 }
 
 // Register creates a new user account.
 func (m *Manager) Register(ctx context.Context, req models.UserRegisterRequest) (*models.User, string, error) {
+	if err := m.usernamePolicy.Validate(req.Username); err != nil {
+		return nil, "", err
+	}
+
 	// Check if email already exists
 	_, err := m.userRepo.FindByEmail(ctx, req.Email)
 	if err == nil {
@@ -70,10 +88,43 @@ func (m *Manager) Register(ctx context.Context, req models.UserRegisterRequest)
 	}
 
 	// Create user
+	user := m.newUser(req.Username, req.Email, hashedPassword)
+
+	// Save user to database
+	if err := m.userRepo.Create(ctx, user); err != nil {
+		m.logger.Error("Failed to create user", err, "email", req.Email)
+		return nil, "", err
+	}
+
+	// Generate JWT token and session
+	token, err := m.startSession(ctx, user)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, handler := range m.createdHandlers {
+		handler(ctx, user)
+	}
+
+	return user, token, nil
+}
+
+// AddCreatedHandler adds a handler called when a user registers, including guests registering an account.
+func (m *Manager) AddCreatedHandler(handler func(ctx context.Context, user *models.User)) {
+	m.createdHandlers = append(m.createdHandlers, handler)
+}
+
+// AddAvatarChangedHandler adds a handler called when a user sets an uploaded avatar image they didn't have.
+func (m *Manager) AddAvatarChangedHandler(handler func(ctx context.Context, user *models.User)) {
+	m.avatarHandlers = append(m.avatarHandlers, handler)
+}
+
+// newUser creates a user account with the default profile and settings, without saving it.
+func (m *Manager) newUser(username, email, password string) *models.User {
 	now := time.Now()
 	baseUser := models.BaseUser{
 		ID:           bson.NewObjectID(),
-		Username:     req.Username,
+		Username:     username,
 		AvatarConfig: m.avatarSvc.GenerateDefaultAvatar(),
 		Profile: models.UserProfile{
 			JoinDate: now,
@@ -92,8 +143,8 @@ func (m *Manager) Register(ctx context.Context, req models.UserRegisterRequest)
 	}
 	user := &models.User{
 		BaseUser:    baseUser,
-		Email:       req.Email,
-		Password:    hashedPassword,
+		Email:       email,
+		Password:    password,
 		IsActive:    true,
 		IsVerified:  false, // Requires email verification
 		LastLogin:   now,
@@ -114,27 +165,24 @@ func (m *Manager) Register(ctx context.Context, req models.UserRegisterRequest)
 		},
 	}
 
-	// Save user to database
-	if err := m.userRepo.Create(ctx, user); err != nil {
-		m.logger.Error("Failed to create user", err, "email", req.Email)
-		return nil, "", err
-	}
+	return user
+}
 
-	// Generate JWT token
+// startSession generates a JWT token for a user and creates their session.
+func (m *Manager) startSession(ctx context.Context, user *models.User) (string, error) {
 	token, err := m.authProvider.GenerateToken(user.ID.Hex(), user.Username, user.Roles)
 	if err != nil {
 		m.logger.Error("Failed to generate token", err, "userId", user.ID.Hex())
-		return nil, "", models.NewInternalError(err, "Failed to generate authentication token")
+		return "", models.NewInternalError(err, "Failed to generate authentication token")
 	}
 
-	// Create session
 	_, err = m.sessionMgr.CreateSession(ctx, user, token, "unknown", "unknown") // IP and user agent not available here
 	if err != nil {
 		m.logger.Error("Failed to create session", err, "userId", user.ID.Hex())
 		// Continue anyway, user can log in again
 	}
 
-	return user, token, nil
+	return token, nil
 }
 
 // Login authenticates a user and returns a JWT token.
@@ -159,24 +207,41 @@ func (m *Manager) Login(ctx context.Context, req models.UserLoginRequest) (*mode
 		return nil, "", models.ErrInvalidCredentials
 	}
 
+	token, err := m.signIn(ctx, user)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, token, nil
+}
+
+// signIn starts the session of a user who proved who they are, and marks them online. It cancels the
+// deletion of their account if they asked for it.
+func (m *Manager) signIn(ctx context.Context, user *models.User) (string, error) {
+	// Support may require a new password before the account can be used again, however the user signs in
+	if user.Recovery.PasswordResetRequired {
+		return "", models.ErrPasswordResetRequired
+	}
+
+	// Signing in during the deletion grace period keeps the account
+	if user.Deletion != nil {
+		if err := m.userRepo.SetDeletion(ctx, user.ID, nil); err != nil {
+			m.logger.Error("Failed to cancel account deletion", err, "userId", user.ID.Hex())
+			return "", err
+		}
+		user.Deletion = nil
+		m.logger.Info("Account deletion cancelled by signing in", "userId", user.ID.Hex())
+	}
+
 	// Update last login
 	if err := m.userRepo.UpdateLastLogin(ctx, user.ID); err != nil {
 		m.logger.Error("Failed to update last login", err, "userId", user.ID.Hex())
 		// Continue anyway, not critical
 	}
 
-	// Generate JWT token
-	token, err := m.authProvider.GenerateToken(user.ID.Hex(), user.Username, user.Roles)
+	// Generate JWT token and session
+	token, err := m.startSession(ctx, user)
 	if err != nil {
-		m.logger.Error("Failed to generate token", err, "userId", user.ID.Hex())
-		return nil, "", models.NewInternalError(err, "Failed to generate authentication token")
-	}
-
-	// Create session
-	_, err = m.sessionMgr.CreateSession(ctx, user, token, "unknown", "unknown") // IP and user agent not available here
-	if err != nil {
-		m.logger.Error("Failed to create session", err, "userId", user.ID.Hex())
-		// Continue anyway, user can log in again
+		return "", err
 	}
 
 	// Set user as online
@@ -185,7 +250,7 @@ func (m *Manager) Login(ctx context.Context, req models.UserLoginRequest) (*mode
 		// Continue anyway, not critical
 	}
 
-	return user, token, nil
+	return token, nil
 }
 
 // Logout invalidates a user's session.
@@ -317,21 +382,15 @@ func (m *Manager) UpdateUser(ctx context.Context, userID string, req models.User
 		return nil, models.NewInternalError(err, "Failed to retrieve user")
 	}
 
-	// Update username if provided
+	// Update username if provided, as a rename so it follows the same rules
 	if req.Username != "" && req.Username != user.Username {
-		// Check if username already exists
-		_, err = m.userRepo.FindByUsername(ctx, req.Username)
-		if err == nil {
-			return nil, models.ErrUsernameAlreadyExists
-		} else if !errors.Is(err, models.ErrUserNotFound) {
-			m.logger.Error("Error checking username existence", err, "username", req.Username)
+		if user, err = m.RenameUser(ctx, userID, req.Username); err != nil {
 			return nil, err
 		}
-
-		user.Username = req.Username
 	}
 
 	// Update avatar if provided
+	previousImage := user.AvatarConfig.CustomImage
 	if req.AvatarConfig != nil {
 		user.AvatarConfig = *req.AvatarConfig
 	}
@@ -355,9 +414,31 @@ func (m *Manager) UpdateUser(ctx context.Context, userID string, req models.User
 		return nil, err
 	}
 
+	if user.AvatarConfig.Type == "uploaded" && user.AvatarConfig.CustomImage != previousImage {
+		for _, handler := range m.avatarHandlers {
+			handler(ctx, user)
+		}
+	}
+
 	return user, nil
 }
 
+// resetAvatar gives a user a default avatar in place of an uploaded image, unless they replaced the image since.
+func (m *Manager) resetAvatar(ctx context.Context, userID bson.ObjectID, image string) error {
+	user, err := m.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.AvatarConfig.Type != "uploaded" || user.AvatarConfig.CustomImage != image {
+		return nil
+	}
+
+	return m.userRepo.UpdateAvatar(ctx, userID, m.avatarSvc.GenerateDefaultAvatar())
+}
+
 // ChangePassword changes a user's password.
 func (m *Manager) ChangePassword(ctx context.Context, userID string, req models.UserPasswordChangeRequest) error {
 	objectID, err := bson.ObjectIDFromHex(userID)
@@ -498,23 +579,7 @@ func (m *Manager) SearchUsers(ctx context.Context, query string, skip, limit int
 	}
 
 	// Convert to public users
-	publicUsers := make([]*models.PublicUser, 0, len(users))
-	for _, user := range users {
-		publicUser := user.ToPublicUser()
-
-		// Check if user is online
-		isOnline, err := m.presenceMgr.IsUserOnline(ctx, user.ID)
-		if err != nil {
-			m.logger.Error("Failed to check if user is online", err, "userId", user.ID.Hex())
-			// Continue anyway, default to false
-		} else {
-			publicUser.Online = isOnline
-		}
-
-		publicUsers = append(publicUsers, &publicUser)
-	}
-
-	return publicUsers, nil
+	return m.toPublicUsers(ctx, users), nil
 }
 
 // GetOnlineUsers gets a list of currently online users.
@@ -588,6 +653,68 @@ func (m *Manager) IsUserOnline(ctx context.Context, userID string) (bool, error)
 	return m.presenceMgr.IsUserOnline(ctx, objectID)
 }
 
+// GetOnlineStatuses checks which of the given users are online, with a single presence lookup.
+func (m *Manager) GetOnlineStatuses(ctx context.Context, userIDs []bson.ObjectID) (map[bson.ObjectID]bool, error) {
+	presences, err := m.presenceMgr.GetPresenceBulk(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	online := make(map[bson.ObjectID]bool, len(presences))
+	for userID := range presences {
+		online[userID] = true
+	}
+	return online, nil
+}
+
+// getUsersByIDs gets users by their IDs in a single query, in the order given. Missing users are skipped.
+func (m *Manager) getUsersByIDs(ctx context.Context, userIDs []bson.ObjectID) ([]*models.User, error) {
+	if len(userIDs) == 0 {
+		return []*models.User{}, nil
+	}
+
+	users, err := m.userRepo.FindMany(ctx, bson.M{"_id": bson.M{"$in": userIDs}}, nil)
+	if err != nil {
+		m.logger.Error("Failed to get users by ID", err, "count", len(userIDs))
+		return nil, models.NewInternalError(err, "Failed to retrieve users")
+	}
+
+	// Keep the order given, the query returns users in storage order
+	byID := make(map[bson.ObjectID]*models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	ordered := make([]*models.User, 0, len(users))
+	for _, userID := range userIDs {
+		if user, ok := byID[userID]; ok {
+			ordered = append(ordered, user)
+		}
+	}
+	return ordered, nil
+}
+
+// toPublicUsers converts users to public users, marking the online ones with a single presence lookup.
+func (m *Manager) toPublicUsers(ctx context.Context, users []*models.User) []*models.PublicUser {
+	userIDs := make([]bson.ObjectID, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
+	}
+
+	online, err := m.GetOnlineStatuses(ctx, userIDs)
+	if err != nil {
+		m.logger.Error("Failed to check which users are online", err, "count", len(users))
+		// Continue anyway, default to offline
+	}
+
+	publicUsers := make([]*models.PublicUser, 0, len(users))
+	for _, user := range users {
+		publicUser := user.ToPublicUser()
+		publicUser.Online = online[user.ID]
+		publicUsers = append(publicUsers, &publicUser)
+	}
+	return publicUsers
+}
+
 // GetUserCount gets the total number of users.
 func (m *Manager) GetUserCount(ctx context.Context) (int64, error) {
 	return m.userRepo.CountUsers(ctx, bson.M{"isActive": true})
@@ -0,0 +1,226 @@
+// Package user provides services for user management and operations.
+package user
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	r "github.com/go-redis/redis/v8"
+	"norelock.dev/listenify/backend/internal/auth"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// oauthStateKeyPrefix prefixes the keys of the OAuth sign-ins waiting on the provider's callback.
+	oauthStateKeyPrefix = "oauth_state:"
+
+	// oauthUsernameAttempts is how many usernames are tried for a new account before giving up.
+	oauthUsernameAttempts = 5
+
+	// oauthUsernameMaxLength is the longest username derived from a third-party account, leaving room for a suffix.
+	oauthUsernameMaxLength = 24
+)
+
+// oauthUsernameInvalid matches the characters not allowed in usernames.
+var oauthUsernameInvalid = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// OAuthService signs users in with their Google or Discord accounts. Third-party accounts are linked
+// to the user with the same verified email address, or to a new account when there is none. An account
+// whose address was never verified is not linked, as whoever registered it may not own the address.
+type OAuthService struct {
+	userManager   *Manager
+	oauthProvider *auth.OAuthProvider
+	redisClient   *redis.Client
+	stateTTL      time.Duration
+	logger        *utils.Logger
+}
+
+// NewOAuthService creates a new OAuth sign-in service. Sign-ins must be completed within the state TTL.
+func NewOAuthService(userManager *Manager, oauthProvider *auth.OAuthProvider, redisClient *redis.Client, stateTTL time.Duration, logger *utils.Logger) *OAuthService {
+	return &OAuthService{
+		userManager:   userManager,
+		oauthProvider: oauthProvider,
+		redisClient:   redisClient,
+		stateTTL:      stateTTL,
+		logger:        logger.Named("oauth_service"),
+	}
+}
+
+// StateTTL returns how long a sign-in can take to complete.
+func (s *OAuthService) StateTTL() time.Duration {
+	return s.stateTTL
+}
+
+// Start begins signing in with a provider and returns the URL of its consent page along with the state
+// sent to it. The state protects the callback from forged requests, and must also be kept by the browser
+// signing in so the sign-in can't be completed in another browser.
+func (s *OAuthService) Start(ctx context.Context, provider string) (string, string, error) {
+	state, err := utils.GenerateRandomHex(16)
+	if err != nil {
+		return "", "", models.NewInternalError(err, "Failed to generate OAuth state")
+	}
+
+	url, err := s.oauthProvider.AuthCodeURL(provider, state)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := s.redisClient.Set(ctx, oauthStateKeyPrefix+state, provider, s.stateTTL); err != nil {
+		s.logger.Error("Failed to store OAuth state", err, "provider", provider)
+		return "", "", models.NewInternalError(err, "Failed to start OAuth sign-in")
+	}
+
+	return url, state, nil
+}
+
+// Complete finishes signing in with a provider from its callback, and returns the user with their token.
+// The browser state is the state kept by the browser the callback came to, which must be the one the
+// sign-in started in.
+func (s *OAuthService) Complete(ctx context.Context, provider, code, state, browserState string) (*models.User, string, error) {
+	if code == "" || state == "" {
+		return nil, "", models.ErrInvalidOAuthState
+	}
+
+	// A callback from a sign-in started elsewhere would sign this browser in to someone else's account
+	if subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		return nil, "", models.ErrInvalidOAuthState
+	}
+
+	// States are used once, so a leaked callback URL can't be replayed
+	started, err := s.redisClient.Client().GetDel(ctx, oauthStateKeyPrefix+state).Result()
+	if err != nil && !errors.Is(err, r.Nil) {
+		s.logger.Error("Failed to get OAuth state", err, "provider", provider)
+		return nil, "", models.NewInternalError(err, "Failed to complete OAuth sign-in")
+	}
+	if started != provider {
+		return nil, "", models.ErrInvalidOAuthState
+	}
+
+	identity, err := s.oauthProvider.Exchange(ctx, provider, code)
+	if err != nil {
+		return nil, "", err
+	}
+
+	user, err := s.findOrCreateUser(ctx, identity)
+	if err != nil {
+		return nil, "", err
+	}
+	if !user.IsActive {
+		return nil, "", models.ErrAccountDisabled
+	}
+
+	token, err := s.userManager.signIn(ctx, user)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.logger.Info("User signed in with OAuth", "userId", user.ID.Hex(), "provider", provider)
+	return user, token, nil
+}
+
+// findOrCreateUser finds the user a third-party account is linked to, links it to the user with the same
+// verified email address, or creates an account for it.
+func (s *OAuthService) findOrCreateUser(ctx context.Context, identity *auth.OAuthIdentity) (*models.User, error) {
+	userRepo := s.userManager.userRepo
+
+	user, err := userRepo.FindByOAuthIdentity(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, models.ErrUserNotFound) {
+		return nil, err
+	}
+
+	// Only addresses the provider verified are trusted, anyone can claim an address they don't own
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, models.ErrEmailNotVerified
+	}
+
+	linked := models.OAuthIdentity{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+		LinkedAt: time.Now(),
+	}
+
+	user, err = userRepo.FindByEmail(ctx, identity.Email)
+	if err == nil {
+		// Whoever registered an unverified address may not own it, and would share the account once linked
+		if !user.IsVerified {
+			s.logger.Info("Refused to link OAuth account to user with unverified email", "userId", user.ID.Hex(), "provider", identity.Provider)
+			return nil, models.ErrOAuthEmailUnverified
+		}
+		if err := userRepo.LinkOAuthIdentity(ctx, user.ID, linked); err != nil {
+			return nil, err
+		}
+		user.OAuthIdentities = append(user.OAuthIdentities, linked)
+		s.logger.Info("Linked OAuth account to user", "userId", user.ID.Hex(), "provider", identity.Provider)
+		return user, nil
+	}
+	if !errors.Is(err, models.ErrUserNotFound) {
+		return nil, err
+	}
+
+	return s.createUser(ctx, identity, linked)
+}
+
+// createUser creates an account for a third-party account, named after it. The account has no password,
+// the user signs in with the third-party account until they set one.
+func (s *OAuthService) createUser(ctx context.Context, identity *auth.OAuthIdentity, linked models.OAuthIdentity) (*models.User, error) {
+	base := oauthUsername(identity)
+
+	for attempt := range oauthUsernameAttempts {
+		username := base
+		if attempt > 0 {
+			suffix, err := utils.GenerateRandomHex(2)
+			if err != nil {
+				return nil, models.NewInternalError(err, "Failed to generate username")
+			}
+			username = base + "-" + suffix
+		}
+
+		user := s.userManager.newUser(username, identity.Email, "")
+		user.IsVerified = true
+		user.OAuthIdentities = []models.OAuthIdentity{linked}
+
+		err := s.userManager.userRepo.Create(ctx, user)
+		if errors.Is(err, models.ErrUsernameAlreadyExists) {
+			continue
+		}
+		if err != nil {
+			s.logger.Error("Failed to create user for OAuth account", err, "provider", identity.Provider)
+			return nil, err
+		}
+
+		for _, handler := range s.userManager.createdHandlers {
+			handler(ctx, user)
+		}
+		s.logger.Info("Created user for OAuth account", "userId", user.ID.Hex(), "provider", identity.Provider)
+		return user, nil
+	}
+
+	return nil, models.ErrUsernameAlreadyExists
+}
+
+// oauthUsername derives a username from a third-party account's name or email address.
+func oauthUsername(identity *auth.OAuthIdentity) string {
+	name := identity.Name
+	if name == "" {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	username := strings.Trim(oauthUsernameInvalid.ReplaceAllString(name, "_"), "_-")
+	if len(username) > oauthUsernameMaxLength {
+		username = username[:oauthUsernameMaxLength]
+	}
+	if len(username) < 3 {
+		username = "listener"
+	}
+	return username
+}
@@ -0,0 +1,502 @@
+// Package user provides services for user management and operations.
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/services/notification"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// recoveryTokenBytes is the number of random bytes in a recovery token's secret.
+	recoveryTokenBytes = 32
+
+	// mergeJobKeyPrefix is the Redis key prefix for account merge jobs.
+	mergeJobKeyPrefix = "account_merge:"
+
+	// mergeJobTTL is how long a finished merge job's report is kept.
+	mergeJobTTL = 7 * 24 * time.Hour
+)
+
+// MergeJobStatus is the state of an account merge job.
+type MergeJobStatus string
+
+const (
+	MergeJobRunning   MergeJobStatus = "running"
+	MergeJobCompleted MergeJobStatus = "completed"
+	MergeJobFailed    MergeJobStatus = "failed"
+)
+
+// RecoveryToken is a single-use token handed to a user by support to complete a recovery action.
+type RecoveryToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// MergeReport counts what merging one account into another moves.
+type MergeReport struct {
+	SourceID       bson.ObjectID `json:"sourceId"`
+	TargetID       bson.ObjectID `json:"targetId"`
+	Playlists      int           `json:"playlists"`
+	HistoryRecords int64         `json:"historyRecords"`
+	Following      int           `json:"following"`
+	Followers      int           `json:"followers"`
+	Friends        int           `json:"friends"`
+	Favorites      int           `json:"favorites"`
+}
+
+// MergeJob is a background account merge and its outcome.
+type MergeJob struct {
+	ID         string         `json:"id"`
+	Status     MergeJobStatus `json:"status"`
+	AdminID    string         `json:"adminId"`
+	Plan       MergeReport    `json:"plan"`
+	Result     *MergeReport   `json:"result,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	StartedAt  time.Time      `json:"startedAt"`
+	FinishedAt time.Time      `json:"finishedAt,omitzero"`
+}
+
+// RecoveryService provides support tools for recovering and consolidating user accounts.
+type RecoveryService struct {
+	userManager  *Manager
+	playlistRepo repositories.PlaylistRepository
+	historyRepo  repositories.HistoryRepository
+	redisClient  *redis.Client
+	emailSender  notification.Sender
+	tokenExpiry  time.Duration
+	logger       *utils.Logger
+}
+
+// NewRecoveryService creates a new account recovery service.
+func NewRecoveryService(
+	userManager *Manager,
+	playlistRepo repositories.PlaylistRepository,
+	historyRepo repositories.HistoryRepository,
+	redisClient *redis.Client,
+	emailSender notification.Sender,
+	tokenExpiry time.Duration,
+	logger *utils.Logger,
+) *RecoveryService {
+	return &RecoveryService{
+		userManager:  userManager,
+		playlistRepo: playlistRepo,
+		historyRepo:  historyRepo,
+		redisClient:  redisClient,
+		emailSender:  emailSender,
+		tokenExpiry:  tokenExpiry,
+		logger:       logger.Named("recovery_service"),
+	}
+}
+
+// ResetEmail starts changing a user's email address. The verification token is emailed to the new
+// address, which only replaces the old one once verified with it, so only the owner of the new address
+// can complete the change. It returns when the token expires.
+func (s *RecoveryService) ResetEmail(ctx context.Context, userID, email, adminID string) (time.Time, error) {
+	email = strings.TrimSpace(email)
+
+	user, err := s.userManager.GetUserByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := s.checkEmailAvailable(ctx, user.ID, email); err != nil {
+		return time.Time{}, err
+	}
+
+	token, hash, err := newRecoveryToken(user.ID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	user.Recovery.PendingEmail = email
+	user.Recovery.EmailTokenHash = hash
+	user.Recovery.EmailTokenExpiry = time.Now().Add(s.tokenExpiry)
+	user.UpdateNow()
+
+	if err := s.userManager.userRepo.Update(ctx, user); err != nil {
+		s.logger.Error("Failed to save pending email", err, "userId", userID)
+		return time.Time{}, err
+	}
+
+	if err := s.emailSender.Send(ctx, &notification.Email{
+		To:      email,
+		Subject: "Verify your new email address",
+		Text: "Support started changing the email address of your account " + user.Username + " to this address.\n\n" +
+			"Verify it with this token to complete the change:\n\n" + token + "\n\n" +
+			"The token expires on " + user.Recovery.EmailTokenExpiry.UTC().Format(time.RFC1123) + ". " +
+			"If you didn't ask for this change, ignore this email.",
+	}); err != nil {
+		s.logger.Error("Failed to send email verification", err, "userId", userID)
+		return time.Time{}, models.NewInternalError(err, "Failed to send verification email")
+	}
+
+	s.logger.Info("Email reset started", "userId", userID, "adminId", adminID)
+	return user.Recovery.EmailTokenExpiry, nil
+}
+
+// ConfirmEmail verifies a pending email address and makes it the user's email.
+func (s *RecoveryService) ConfirmEmail(ctx context.Context, token string) error {
+	user, err := s.findByToken(ctx, token, func(r *models.UserRecovery) (string, time.Time) {
+		return r.EmailTokenHash, r.EmailTokenExpiry
+	})
+	if err != nil {
+		return err
+	}
+	if user.Recovery.PendingEmail == "" {
+		return models.ErrInvalidRecoveryToken
+	}
+
+	// The address may have been registered since the reset started
+	if err := s.checkEmailAvailable(ctx, user.ID, user.Recovery.PendingEmail); err != nil {
+		return err
+	}
+
+	user.Email = user.Recovery.PendingEmail
+	user.IsVerified = true
+	user.Recovery.PendingEmail = ""
+	user.Recovery.EmailTokenHash = ""
+	user.Recovery.EmailTokenExpiry = time.Time{}
+	user.UpdateNow()
+
+	if err := s.userManager.userRepo.Update(ctx, user); err != nil {
+		s.logger.Error("Failed to confirm email", err, "userId", user.ID.Hex())
+		return err
+	}
+
+	s.logger.Info("Email reset confirmed", "userId", user.ID.Hex())
+	return nil
+}
+
+// ForcePasswordReset signs a user out everywhere and blocks their logins until they set a new password
+// with the returned token.
+func (s *RecoveryService) ForcePasswordReset(ctx context.Context, userID, adminID string) (*RecoveryToken, error) {
+	user, err := s.userManager.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, hash, err := newRecoveryToken(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Recovery.PasswordResetRequired = true
+	user.Recovery.ResetTokenHash = hash
+	user.Recovery.ResetTokenExpiry = time.Now().Add(s.tokenExpiry)
+	user.UpdateNow()
+
+	if err := s.userManager.userRepo.Update(ctx, user); err != nil {
+		s.logger.Error("Failed to force password reset", err, "userId", userID)
+		return nil, err
+	}
+
+	if err := s.userManager.sessionMgr.DestroyUserSessions(ctx, user.ID); err != nil {
+		s.logger.Error("Failed to invalidate sessions after forced password reset", err, "userId", userID)
+		// Continue anyway, logins are blocked until the reset
+	}
+
+	s.logger.Info("Password reset forced", "userId", userID, "adminId", adminID)
+	return &RecoveryToken{Token: token, ExpiresAt: user.Recovery.ResetTokenExpiry}, nil
+}
+
+// ResetPassword sets a new password using a token from a forced password reset.
+func (s *RecoveryService) ResetPassword(ctx context.Context, req models.UserPasswordResetConfirmRequest) error {
+	user, err := s.findByToken(ctx, req.Token, func(r *models.UserRecovery) (string, time.Time) {
+		return r.ResetTokenHash, r.ResetTokenExpiry
+	})
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.userManager.authProvider.HashPassword(req.Password)
+	if err != nil {
+		s.logger.Error("Failed to hash reset password", err, "userId", user.ID.Hex())
+		return models.NewInternalError(err, "Failed to process password")
+	}
+
+	user.Password = hashedPassword
+	user.Recovery.PasswordResetRequired = false
+	user.Recovery.ResetTokenHash = ""
+	user.Recovery.ResetTokenExpiry = time.Time{}
+	user.UpdateNow()
+
+	if err := s.userManager.userRepo.Update(ctx, user); err != nil {
+		s.logger.Error("Failed to reset password", err, "userId", user.ID.Hex())
+		return err
+	}
+
+	if err := s.userManager.sessionMgr.DestroyUserSessions(ctx, user.ID); err != nil {
+		s.logger.Error("Failed to invalidate sessions after password reset", err, "userId", user.ID.Hex())
+		// Continue anyway, not critical
+	}
+
+	s.logger.Info("Password reset completed", "userId", user.ID.Hex())
+	return nil
+}
+
+// PlanMerge reports what merging the source account into the target account would move, without changing anything.
+func (s *RecoveryService) PlanMerge(ctx context.Context, sourceID, targetID string) (*MergeReport, error) {
+	source, target, err := s.mergeAccounts(ctx, sourceID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	playlists, err := s.playlistRepo.CountUserPlaylists(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.historyRepo.CountUserRecords(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := source.Connections, target.Connections
+	return &MergeReport{
+		SourceID:       source.ID,
+		TargetID:       target.ID,
+		Playlists:      int(playlists),
+		HistoryRecords: history,
+		Following:      countNew(to.Following, from.Following, source.ID, target.ID),
+		Followers:      countNew(to.Followers, from.Followers, source.ID, target.ID),
+		Friends:        countNew(to.Friends, from.Friends, source.ID, target.ID),
+		Favorites:      countNew(to.Favorites, from.Favorites, source.ID, target.ID),
+	}, nil
+}
+
+// StartMerge starts merging the source account into the target account in the background.
+// The source account's playlists, history and connections move to the target and the source is deactivated.
+func (s *RecoveryService) StartMerge(ctx context.Context, sourceID, targetID, adminID string) (*MergeJob, error) {
+	plan, err := s.PlanMerge(ctx, sourceID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &MergeJob{
+		ID:        bson.NewObjectID().Hex(),
+		Status:    MergeJobRunning,
+		AdminID:   adminID,
+		Plan:      *plan,
+		StartedAt: time.Now(),
+	}
+	if err := s.saveMergeJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Account merge started", "jobId", job.ID, "sourceId", sourceID, "targetId", targetID, "adminId", adminID)
+
+	// The merge outlives the admin's request
+	go s.runMerge(context.WithoutCancel(ctx), job)
+
+	return job, nil
+}
+
+// GetMergeJob retrieves an account merge job.
+func (s *RecoveryService) GetMergeJob(ctx context.Context, jobID string) (*MergeJob, error) {
+	data, err := s.redisClient.Get(ctx, mergeJobKeyPrefix+jobID)
+	if err != nil {
+		return nil, err
+	}
+	if data == "" {
+		return nil, models.ErrMergeJobNotFound
+	}
+
+	var job MergeJob
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return nil, models.NewInternalError(err, "Failed to decode account merge job")
+	}
+	return &job, nil
+}
+
+// runMerge performs a merge job and records its outcome.
+func (s *RecoveryService) runMerge(ctx context.Context, job *MergeJob) {
+	result, err := s.merge(ctx, job.Plan.SourceID, job.Plan.TargetID)
+	job.Result = result
+	job.FinishedAt = time.Now()
+	job.Status = MergeJobCompleted
+	if err != nil {
+		job.Status = MergeJobFailed
+		job.Error = err.Error()
+		s.logger.Error("Account merge failed", err, "jobId", job.ID)
+	} else {
+		s.logger.Info("Account merge completed", "jobId", job.ID, "playlists", result.Playlists, "historyRecords", result.HistoryRecords)
+	}
+
+	if err := s.saveMergeJob(ctx, job); err != nil {
+		s.logger.Error("Failed to save account merge job", err, "jobId", job.ID)
+	}
+}
+
+// merge moves everything the source account owns to the target account and deactivates the source.
+// It returns what was moved so far, even when it fails partway.
+func (s *RecoveryService) merge(ctx context.Context, sourceID, targetID bson.ObjectID) (*MergeReport, error) {
+	report := &MergeReport{SourceID: sourceID, TargetID: targetID}
+
+	playlists, err := s.playlistRepo.FindUserPlaylists(ctx, sourceID)
+	if err != nil {
+		return report, err
+	}
+	for _, playlist := range playlists {
+		// The target keeps its own active playlist
+		playlist.Owner = targetID
+		playlist.IsActive = false
+		playlist.UpdateNow()
+		if err := s.playlistRepo.Update(ctx, playlist); err != nil {
+			return report, err
+		}
+		report.Playlists++
+	}
+
+	if report.HistoryRecords, err = s.historyRepo.ReassignUser(ctx, sourceID, targetID); err != nil {
+		return report, err
+	}
+
+	source, target, err := s.mergeAccounts(ctx, sourceID.Hex(), targetID.Hex())
+	if err != nil {
+		return report, err
+	}
+
+	// The connections are merged in place, so follows made while the merge runs aren't lost
+	from, to := &source.Connections, &target.Connections
+	added := models.UserConnections{
+		Following: newIDs(to.Following, from.Following, sourceID, targetID),
+		Followers: newIDs(to.Followers, from.Followers, sourceID, targetID),
+		Friends:   newIDs(to.Friends, from.Friends, sourceID, targetID),
+		Blocked:   newIDs(to.Blocked, from.Blocked, sourceID, targetID),
+		Favorites: newIDs(to.Favorites, from.Favorites, sourceID, targetID),
+	}
+	if err := s.userManager.userRepo.AddConnections(ctx, targetID, added); err != nil {
+		return report, err
+	}
+	report.Following = len(added.Following)
+	report.Followers = len(added.Followers)
+	report.Friends = len(added.Friends)
+	report.Favorites = len(added.Favorites)
+
+	// Point everyone connected to the source at the target instead
+	if err := s.userManager.userRepo.ReplaceConnection(ctx, sourceID, targetID); err != nil {
+		return report, err
+	}
+
+	if err := s.userManager.userRepo.ClearConnections(ctx, sourceID); err != nil {
+		return report, err
+	}
+
+	return report, s.userManager.DeactivateAccount(ctx, sourceID.Hex())
+}
+
+// mergeAccounts loads and checks the two accounts of a merge.
+func (s *RecoveryService) mergeAccounts(ctx context.Context, sourceID, targetID string) (*models.User, *models.User, error) {
+	if sourceID == targetID {
+		return nil, nil, models.ErrMergeSameAccount
+	}
+
+	source, err := s.userManager.GetUserByID(ctx, sourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err := s.userManager.GetUserByID(ctx, targetID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return source, target, nil
+}
+
+// saveMergeJob stores a merge job for status lookups.
+func (s *RecoveryService) saveMergeJob(ctx context.Context, job *MergeJob) error {
+	return s.redisClient.SetObject(ctx, mergeJobKeyPrefix+job.ID, job, mergeJobTTL)
+}
+
+// checkEmailAvailable checks that no other account uses an email address.
+func (s *RecoveryService) checkEmailAvailable(ctx context.Context, userID bson.ObjectID, email string) error {
+	existing, err := s.userManager.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if existing.ID != userID {
+		return models.ErrEmailAlreadyExists
+	}
+	return nil
+}
+
+// findByToken resolves a recovery token to its user, checking it against the hash and expiry picked from the user's recovery state.
+func (s *RecoveryService) findByToken(ctx context.Context, token string, pick func(*models.UserRecovery) (string, time.Time)) (*models.User, error) {
+	encodedID, _, found := strings.Cut(token, ".")
+	if !found {
+		return nil, models.ErrInvalidRecoveryToken
+	}
+	userID, err := bson.ObjectIDFromHex(encodedID)
+	if err != nil {
+		return nil, models.ErrInvalidRecoveryToken
+	}
+
+	user, err := s.userManager.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return nil, models.ErrInvalidRecoveryToken
+		}
+		return nil, err
+	}
+
+	hash, expiry := pick(&user.Recovery)
+	if hash == "" || subtle.ConstantTimeCompare([]byte(hash), []byte(hashToken(token))) != 1 {
+		return nil, models.ErrInvalidRecoveryToken
+	}
+	if time.Now().After(expiry) {
+		return nil, models.ErrInvalidRecoveryToken
+	}
+
+	return user, nil
+}
+
+// newRecoveryToken generates a recovery token for a user along with the hash to store.
+func newRecoveryToken(userID bson.ObjectID) (token, hash string, err error) {
+	secret := make([]byte, recoveryTokenBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", models.NewInternalError(err, "Failed to generate recovery token")
+	}
+
+	token = userID.Hex() + "." + hex.EncodeToString(secret)
+	return token, hashToken(token), nil
+}
+
+// hashToken hashes a secret token for storage.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// countNew counts the IDs in src that merging would add to dst.
+func countNew(dst, src []bson.ObjectID, sourceID, targetID bson.ObjectID) int {
+	return len(newIDs(dst, src, sourceID, targetID))
+}
+
+// newIDs returns the IDs in src missing from dst, leaving out both merged accounts so the target
+// doesn't end up connected to itself or the account being retired.
+func newIDs(dst, src []bson.ObjectID, sourceID, targetID bson.ObjectID) []bson.ObjectID {
+	var ids []bson.ObjectID
+	for _, id := range src {
+		if id == sourceID || id == targetID || slices.Contains(dst, id) || slices.Contains(ids, id) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
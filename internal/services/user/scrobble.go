@@ -0,0 +1,564 @@
+package user
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+const (
+	// scrobbleQueueKey is the sorted set of scrobble IDs waiting to be submitted, scored by when they are due.
+	scrobbleQueueKey = "scrobbles:queue"
+
+	// scrobbleBatchSize is the largest number of due scrobbles claimed at once.
+	scrobbleBatchSize = 100
+
+	// scrobbleMinDuration is the length of the shortest track scrobbling services accept, in seconds.
+	scrobbleMinDuration = 30
+
+	// scrobbleResponseLimit caps the size of a scrobbling service response read.
+	scrobbleResponseLimit = 1 << 20
+)
+
+// ScrobblePolicy configures the scrobbling service plays are submitted to and how submissions are retried.
+type ScrobblePolicy struct {
+	// APIURL is the Last.fm compatible API endpoint.
+	APIURL string
+
+	// AuthURL is the page users authorize the application on.
+	AuthURL string
+
+	// APIKey is the application's API key. Empty disables scrobbling.
+	APIKey string
+
+	// APISecret is the application's shared secret requests are signed with.
+	APISecret string
+
+	// CallbackURL is where users are sent back to with their authorization token.
+	CallbackURL string
+
+	// PollInterval is how often due scrobbles are submitted. Zero disables submitting them.
+	PollInterval time.Duration
+
+	// Timeout bounds one submission attempt.
+	Timeout time.Duration
+
+	// MaxAttempts is how many times a scrobble is submitted before it is given up on.
+	MaxAttempts int
+
+	// RetryBackoff is the wait before the first retry. It doubles with every further attempt.
+	RetryBackoff time.Duration
+
+	// MaxBackoff caps the wait between attempts.
+	MaxBackoff time.Duration
+
+	// Workers is the number of scrobbles submitted at once on each instance.
+	Workers int
+}
+
+// ScrobbleLog is a user's scrobbling account along with a page of the plays scrobbled for them.
+type ScrobbleLog struct {
+	// Available is whether scrobbling is set up on this server.
+	Available bool `json:"available"`
+
+	// Account is the user's linked account, nil if they didn't link one.
+	Account *models.ScrobbleAccount `json:"account"`
+
+	// Scrobbles are the user's scrobbles, most recently played first.
+	Scrobbles []*models.Scrobble `json:"scrobbles"`
+
+	// Total is the number of scrobbles in the user's log.
+	Total int64 `json:"total"`
+}
+
+// scrobbleAPIError is an error returned by the scrobbling service.
+type scrobbleAPIError struct {
+	Code    int    `json:"error"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface.
+func (e *scrobbleAPIError) Error() string {
+	return fmt.Sprintf("scrobbling service error %d: %s", e.Code, e.Message)
+}
+
+// temporary checks whether the request may succeed when it is repeated later: the service was offline,
+// failed temporarily or rate limited the application.
+func (e *scrobbleAPIError) temporary() bool {
+	return e.Code == 11 || e.Code == 16 || e.Code == 29
+}
+
+// invalidToken checks whether the error rejected an authorization token as invalid, unauthorized or expired.
+func (e *scrobbleAPIError) invalidToken() bool {
+	return e.Code == 4 || e.Code == 14 || e.Code == 15
+}
+
+// ScrobbleService submits the plays users listened to to their linked Last.fm compatible accounts.
+// A play counts once a user listened to at least half of it. Scrobbles are logged for the user and queued
+// in Redis, so any instance can submit them, and failed submissions are retried with exponential backoff.
+type ScrobbleService struct {
+	scrobbleRepo repositories.ScrobbleRepository
+	stateManager *managers.RoomStateManager
+	redisClient  *redis.Client
+	httpClient   *http.Client
+	policy       ScrobblePolicy
+	logger       *utils.Logger
+}
+
+// NewScrobbleService creates a new scrobbling service.
+func NewScrobbleService(scrobbleRepo repositories.ScrobbleRepository, stateManager *managers.RoomStateManager, redisClient *redis.Client, policy ScrobblePolicy, logger *utils.Logger) *ScrobbleService {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	if policy.Workers < 1 {
+		policy.Workers = 1
+	}
+	return &ScrobbleService{
+		scrobbleRepo: scrobbleRepo,
+		stateManager: stateManager,
+		redisClient:  redisClient,
+		httpClient: &http.Client{
+			Timeout: policy.Timeout,
+		},
+		policy: policy,
+		logger: logger.Named("scrobble_service"),
+	}
+}
+
+// available checks whether scrobbling is set up.
+func (s *ScrobbleService) available() bool {
+	return s.policy.APIKey != ""
+}
+
+// AuthURL returns the URL of the page users authorize scrobbling their plays on. The scrobbling service
+// sends them back to the callback URL with a token to link their account with.
+func (s *ScrobbleService) AuthURL() (string, error) {
+	if !s.available() {
+		return "", models.ErrScrobblingDisabled
+	}
+
+	query := url.Values{"api_key": {s.policy.APIKey}}
+	if s.policy.CallbackURL != "" {
+		query.Set("cb", s.policy.CallbackURL)
+	}
+	return s.policy.AuthURL + "?" + query.Encode(), nil
+}
+
+// Link links the account a user authorized with a token to them and enables scrobbling their plays.
+// An account they linked before is replaced.
+func (s *ScrobbleService) Link(ctx context.Context, userID, token string) (*models.ScrobbleAccount, error) {
+	if !s.available() {
+		return nil, models.ErrScrobblingDisabled
+	}
+	objectID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+	if token == "" {
+		return nil, models.ErrInvalidScrobbleAuthToken
+	}
+
+	var result struct {
+		Session struct {
+			Name string `json:"name"`
+			Key  string `json:"key"`
+		} `json:"session"`
+	}
+	err = s.call(ctx, "auth.getSession", url.Values{"token": {token}}, &result)
+	var apiErr *scrobbleAPIError
+	if errors.As(err, &apiErr) && apiErr.invalidToken() {
+		return nil, models.ErrInvalidScrobbleAuthToken
+	}
+	if err != nil {
+		s.logger.Error("Failed to get scrobbling session", err, "userId", userID)
+		return nil, models.NewInternalError(err, "Failed to link scrobbling account")
+	}
+	if result.Session.Key == "" {
+		return nil, models.ErrInvalidScrobbleAuthToken
+	}
+
+	account := &models.ScrobbleAccount{
+		UserID:     objectID,
+		Username:   result.Session.Name,
+		SessionKey: result.Session.Key,
+		Enabled:    true,
+	}
+	if err := s.scrobbleRepo.SaveAccount(ctx, account); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Scrobbling account linked", "userId", userID, "username", account.Username)
+	return account, nil
+}
+
+// Unlink unlinks a user's scrobbling account. Scrobbles still queued for it are given up on.
+func (s *ScrobbleService) Unlink(ctx context.Context, userID string) error {
+	objectID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return models.ErrInvalidID
+	}
+	return s.scrobbleRepo.DeleteAccount(ctx, objectID)
+}
+
+// SetEnabled enables or disables scrobbling a user's plays, and returns their account.
+func (s *ScrobbleService) SetEnabled(ctx context.Context, userID string, enabled bool) (*models.ScrobbleAccount, error) {
+	objectID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+	if err := s.scrobbleRepo.SetAccountEnabled(ctx, objectID, enabled); err != nil {
+		return nil, err
+	}
+	return s.scrobbleRepo.FindAccount(ctx, objectID)
+}
+
+// GetScrobbles gets a user's scrobbling account along with a page of their scrobble log.
+func (s *ScrobbleService) GetScrobbles(ctx context.Context, userID string, skip, limit int) (*ScrobbleLog, error) {
+	objectID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	account, err := s.scrobbleRepo.FindAccount(ctx, objectID)
+	if err != nil && !errors.Is(err, models.ErrScrobbleAccountNotFound) {
+		return nil, err
+	}
+
+	scrobbles, total, err := s.scrobbleRepo.FindScrobbles(ctx, objectID, skip, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScrobbleLog{
+		Available: s.available(),
+		Account:   account,
+		Scrobbles: scrobbles,
+		Total:     total,
+	}, nil
+}
+
+// PlayEnded queues a scrobble of an ended play for every user in its room who listened to at least half of
+// it and has scrobbling enabled. It is meant to be added as a play history end handler.
+func (s *ScrobbleService) PlayEnded(ctx context.Context, play *models.PlayHistory) {
+	if !s.available() || s.policy.PollInterval <= 0 {
+		return
+	}
+	if play.Media.Duration < scrobbleMinDuration || play.Media.Artist == "" || play.Media.Title == "" {
+		return
+	}
+
+	roomID := play.RoomID.Hex()
+	users, err := s.stateManager.GetRoomUsers(ctx, roomID)
+	if err != nil {
+		s.logger.Error("Failed to get room users for scrobbling", err, "roomId", roomID)
+		return
+	}
+	if len(users) == 0 {
+		return
+	}
+	joinTimes, err := s.stateManager.GetRoomJoinTimes(ctx, roomID)
+	if err != nil {
+		s.logger.Error("Failed to get room join times for scrobbling", err, "roomId", roomID)
+		return
+	}
+
+	required := time.Duration(play.Media.Duration) * time.Second / 2
+	listeners := make([]bson.ObjectID, 0, len(users))
+	for _, userID := range users {
+		start := play.StartTime
+		if joined, ok := joinTimes[userID]; ok && joined.After(start) {
+			start = joined
+		}
+		if play.EndTime.Sub(start) < required {
+			continue
+		}
+		if objectID, err := bson.ObjectIDFromHex(userID); err == nil {
+			listeners = append(listeners, objectID)
+		}
+	}
+	if len(listeners) == 0 {
+		return
+	}
+
+	accounts, err := s.scrobbleRepo.FindEnabledAccounts(ctx, listeners)
+	if err != nil {
+		s.logger.Error("Failed to find scrobbling accounts", err, "roomId", roomID)
+		return
+	}
+
+	now := time.Now()
+	for _, account := range accounts {
+		scrobble := &models.Scrobble{
+			UserID:   account.UserID,
+			RoomID:   play.RoomID,
+			PlayID:   play.ID,
+			MediaID:  play.MediaID,
+			Artist:   play.Media.Artist,
+			Track:    play.Media.Title,
+			Duration: play.Media.Duration,
+			PlayedAt: play.StartTime,
+			Status:   models.ScrobbleStatusPending,
+		}
+		if err := s.scrobbleRepo.CreateScrobble(ctx, scrobble); err != nil {
+			s.logger.Error("Failed to create scrobble", err, "roomId", roomID, "userId", account.UserID.Hex())
+			continue
+		}
+		s.enqueue(ctx, scrobble.ID, now)
+	}
+}
+
+// enqueue queues a scrobble to be submitted at the given time.
+func (s *ScrobbleService) enqueue(ctx context.Context, scrobbleID bson.ObjectID, at time.Time) {
+	if err := s.redisClient.ZAdd(ctx, scrobbleQueueKey, float64(at.UnixMilli()), scrobbleID.Hex()); err != nil {
+		s.logger.Error("Failed to queue scrobble", err, "scrobbleId", scrobbleID.Hex())
+	}
+}
+
+// Start begins submitting due scrobbles.
+func (s *ScrobbleService) Start(ctx context.Context) {
+	if !s.available() || s.policy.PollInterval <= 0 {
+		s.logger.Info("Scrobbling is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.policy.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				s.logger.Info("Stopping scrobble submission")
+				return
+			case <-ticker.C:
+				s.Flush(ctx)
+			}
+		}
+	}()
+
+	s.logger.Info("Scrobble submission started", "interval", s.policy.PollInterval, "maxAttempts", s.policy.MaxAttempts)
+}
+
+// Flush submits the scrobbles that are due. Each scrobble is claimed by removing it from the queue,
+// so only one instance submits it.
+func (s *ScrobbleService) Flush(ctx context.Context) {
+	due, err := s.redisClient.ZRangeByScore(ctx, scrobbleQueueKey, "-inf", strconv.FormatInt(time.Now().UnixMilli(), 10), scrobbleBatchSize)
+	if err != nil {
+		return
+	}
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, s.policy.Workers)
+	for _, member := range due {
+		claimed, err := s.redisClient.Client().ZRem(ctx, scrobbleQueueKey, member).Result()
+		if err != nil || claimed == 0 {
+			continue
+		}
+
+		scrobbleID, err := bson.ObjectIDFromHex(member)
+		if err != nil {
+			s.logger.Warn("Dropping invalid queued scrobble", "member", member)
+			continue
+		}
+
+		workers <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			s.attempt(ctx, scrobbleID)
+		}()
+	}
+	wg.Wait()
+}
+
+// attempt submits a queued scrobble, records the outcome in the user's scrobble log and schedules a retry
+// if it failed.
+func (s *ScrobbleService) attempt(ctx context.Context, scrobbleID bson.ObjectID) {
+	scrobble, err := s.scrobbleRepo.FindScrobbleByID(ctx, scrobbleID)
+	if err != nil {
+		if !errors.Is(err, models.ErrScrobbleNotFound) {
+			s.enqueue(ctx, scrobbleID, time.Now().Add(s.policy.PollInterval))
+		}
+		return
+	}
+	if scrobble.Status != models.ScrobbleStatusPending {
+		return
+	}
+
+	account, err := s.scrobbleRepo.FindAccount(ctx, scrobble.UserID)
+	if err != nil && !errors.Is(err, models.ErrScrobbleAccountNotFound) {
+		s.enqueue(ctx, scrobbleID, time.Now().Add(s.policy.PollInterval))
+		return
+	}
+
+	var ignored string
+	switch {
+	case account == nil:
+		err = errors.New("scrobbling account was unlinked")
+	case !account.Enabled:
+		err = errors.New("scrobbling was disabled")
+	default:
+		scrobble.Attempts++
+		ignored, err = s.submit(ctx, account, scrobble)
+	}
+
+	// The scrobble was claimed, so its outcome must be kept even when shutting down interrupted it
+	ctx = context.WithoutCancel(ctx)
+	now := time.Now()
+	switch {
+	case err == nil && ignored == "":
+		scrobble.Status, scrobble.Error, scrobble.SubmittedAt = models.ScrobbleStatusSubmitted, "", now
+	case err == nil:
+		scrobble.Status, scrobble.Error, scrobble.SubmittedAt = models.ScrobbleStatusIgnored, ignored, now
+	default:
+		scrobble.Error = err.Error()
+		var apiErr *scrobbleAPIError
+		retry := account != nil && account.Enabled && (!errors.As(err, &apiErr) || apiErr.temporary())
+		if retry && scrobble.Attempts < s.policy.MaxAttempts {
+			s.enqueue(ctx, scrobbleID, now.Add(s.backoff(scrobble.Attempts)))
+		} else {
+			scrobble.Status = models.ScrobbleStatusFailed
+			s.logger.Warn("Giving up on scrobble", "scrobbleId", scrobbleID.Hex(), "userId", scrobble.UserID.Hex(), "attempts", scrobble.Attempts, "error", err)
+		}
+	}
+
+	if err := s.scrobbleRepo.UpdateScrobble(ctx, scrobble); err != nil {
+		s.logger.Error("Failed to log scrobble", err, "scrobbleId", scrobbleID.Hex())
+	}
+}
+
+// backoff returns the wait before the attempt following the given one.
+func (s *ScrobbleService) backoff(attempt int) time.Duration {
+	wait := s.policy.RetryBackoff
+	for i := 1; i < attempt; i++ {
+		wait *= 2
+		if s.policy.MaxBackoff > 0 && wait >= s.policy.MaxBackoff {
+			return s.policy.MaxBackoff
+		}
+	}
+	return wait
+}
+
+// submit scrobbles a play to a user's account. It returns why the scrobbling service ignored the scrobble,
+// empty if it was accepted.
+func (s *ScrobbleService) submit(ctx context.Context, account *models.ScrobbleAccount, scrobble *models.Scrobble) (string, error) {
+	params := url.Values{
+		"sk":        {account.SessionKey},
+		"artist":    {scrobble.Artist},
+		"track":     {scrobble.Track},
+		"timestamp": {strconv.FormatInt(scrobble.PlayedAt.Unix(), 10)},
+		"duration":  {strconv.Itoa(scrobble.Duration)},
+	}
+
+	var result struct {
+		Scrobbles struct {
+			Attr struct {
+				Ignored int `json:"ignored"`
+			} `json:"@attr"`
+			Scrobble struct {
+				IgnoredMessage struct {
+					Code string `json:"code"`
+					Text string `json:"#text"`
+				} `json:"ignoredMessage"`
+			} `json:"scrobble"`
+		} `json:"scrobbles"`
+	}
+	if err := s.call(ctx, "track.scrobble", params, &result); err != nil {
+		return "", err
+	}
+
+	if result.Scrobbles.Attr.Ignored > 0 {
+		message := result.Scrobbles.Scrobble.IgnoredMessage
+		if message.Text != "" {
+			return message.Text, nil
+		}
+		return "ignored with code " + message.Code, nil
+	}
+	return "", nil
+}
+
+// call posts a signed request for an API method to the scrobbling service and decodes its response.
+func (s *ScrobbleService) call(ctx context.Context, method string, params url.Values, result any) error {
+	params.Set("method", method)
+	params.Set("api_key", s.policy.APIKey)
+	params.Set("api_sig", s.sign(params))
+	params.Set("format", "json")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.policy.APIURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, scrobbleResponseLimit))
+	if err != nil {
+		return err
+	}
+
+	var apiErr scrobbleAPIError
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Code != 0 {
+		return &apiErr
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("scrobbling service responded with status %d", resp.StatusCode)
+	}
+	return json.Unmarshal(body, result)
+}
+
+// sign signs request parameters: the MD5 of every parameter name and value, sorted by name, followed by
+// the application's secret.
+func (s *ScrobbleService) sign(params url.Values) string {
+	var signed strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(params)) {
+		signed.WriteString(name)
+		signed.WriteString(params.Get(name))
+	}
+	signed.WriteString(s.policy.APISecret)
+
+	sum := md5.Sum([]byte(signed.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// PruneScrobbles deletes the scrobbles logged longer ago than the retention.
+func (s *ScrobbleService) PruneScrobbles(ctx context.Context, retention time.Duration) error {
+	if retention <= 0 {
+		return nil
+	}
+
+	deleted, err := s.scrobbleRepo.DeleteScrobblesBefore(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		s.logger.Info("Pruned scrobble log", "deleted", deleted)
+	}
+	return nil
+}
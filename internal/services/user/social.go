@@ -4,12 +4,20 @@ package user
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis"
 	"norelock.dev/listenify/backend/internal/models"
 	"norelock.dev/listenify/backend/internal/utils"
 )
 
+// djNoticeCooldown is how long followers aren't told again about a user DJing in the same room, so
+// they hear about a DJ set once rather than every turn of it.
+const djNoticeCooldown = 2 * time.Hour
+
 // SocialSummary represents a summary of a user's social connections.
 type SocialSummary struct {
 	FollowersCount int `json:"followersCount"`
@@ -17,20 +25,40 @@ type SocialSummary struct {
 	FriendsCount   int `json:"friendsCount"`
 }
 
+// FriendPresence is a friend who is online, and where they are.
+type FriendPresence struct {
+	User          *models.PublicUser `json:"user"`
+	Status        string             `json:"status"`
+	CurrentRoomID string             `json:"currentRoomId,omitempty"`
+	LastActivity  time.Time          `json:"lastActivity"`
+}
+
 // SocialService provides functionality for managing user social connections.
 type SocialService struct {
-	userManager *Manager
-	logger      *utils.Logger
+	userManager      *Manager
+	relationshipRepo repositories.RelationshipRepository
+	redisClient      *redis.Client
+	logger           *utils.Logger
+
+	// djHandlers are notified for each follower of a user who starts DJing in a room
+	djHandlers []func(ctx context.Context, followerID, djID, roomID bson.ObjectID)
 }
 
 // NewSocialService creates a new social service.
-func NewSocialService(userManager *Manager, logger *utils.Logger) *SocialService {
+func NewSocialService(userManager *Manager, relationshipRepo repositories.RelationshipRepository, redisClient *redis.Client, logger *utils.Logger) *SocialService {
 	return &SocialService{
-		userManager: userManager,
-		logger:      logger.Named("social_service"),
+		userManager:      userManager,
+		relationshipRepo: relationshipRepo,
+		redisClient:      redisClient,
+		logger:           logger.Named("social_service"),
 	}
 }
 
+// AddFollowedDJHandler adds a handler called for each follower of a user who starts DJing in a room.
+func (s *SocialService) AddFollowedDJHandler(handler func(ctx context.Context, followerID, djID, roomID bson.ObjectID)) {
+	s.djHandlers = append(s.djHandlers, handler)
+}
+
 // FollowUser makes a user follow another user.
 func (s *SocialService) FollowUser(ctx context.Context, userID, targetID string) error {
 	// Convert string IDs to ObjectIDs
@@ -56,7 +84,7 @@ func (s *SocialService) FollowUser(ctx context.Context, userID, targetID string)
 	}
 
 	// Follow user
-	if err := s.userManager.userRepo.Follow(ctx, userObjectID, targetObjectID); err != nil {
+	if err := s.relationshipRepo.Follow(ctx, userObjectID, targetObjectID); err != nil {
 		s.logger.Error("Failed to follow user", err, "userId", userID, "targetId", targetID)
 		return err
 	}
@@ -83,7 +111,7 @@ func (s *SocialService) UnfollowUser(ctx context.Context, userID, targetID strin
 	}
 
 	// Unfollow user
-	if err := s.userManager.userRepo.Unfollow(ctx, userObjectID, targetObjectID); err != nil {
+	if err := s.relationshipRepo.Unfollow(ctx, userObjectID, targetObjectID); err != nil {
 		s.logger.Error("Failed to unfollow user", err, "userId", userID, "targetId", targetID)
 		return err
 	}
@@ -105,7 +133,7 @@ func (s *SocialService) IsFollowing(ctx context.Context, userID, targetID string
 	}
 
 	// Check if user is following target
-	isFollowing, err := s.userManager.userRepo.IsFollowing(ctx, userObjectID, targetObjectID)
+	isFollowing, err := s.relationshipRepo.IsFollowing(ctx, userObjectID, targetObjectID)
 	if err != nil {
 		s.logger.Error("Failed to check if user is following", err, "userId", userID, "targetId", targetID)
 		return false, err
@@ -123,30 +151,14 @@ func (s *SocialService) GetFollowers(ctx context.Context, userID string, skip, l
 	}
 
 	// Get followers
-	followers, err := s.userManager.userRepo.FindFollowers(ctx, userObjectID, skip, limit)
+	followers, err := s.relationshipRepo.FindFollowers(ctx, userObjectID, skip, limit)
 	if err != nil {
 		s.logger.Error("Failed to get followers", err, "userId", userID)
 		return nil, err
 	}
 
 	// Convert to public users
-	publicUsers := make([]*models.PublicUser, 0, len(followers))
-	for _, user := range followers {
-		publicUser := user.ToPublicUser()
-
-		// Check if user is online
-		isOnline, err := s.userManager.IsUserOnline(ctx, user.ID.Hex())
-		if err != nil {
-			s.logger.Error("Failed to check if user is online", err, "userId", user.ID.Hex())
-			// Continue anyway, default to false
-		} else {
-			publicUser.Online = isOnline
-		}
-
-		publicUsers = append(publicUsers, &publicUser)
-	}
-
-	return publicUsers, nil
+	return s.userManager.toPublicUsers(ctx, followers), nil
 }
 
 // GetFollowing gets a list of users that the specified user follows.
@@ -158,30 +170,14 @@ func (s *SocialService) GetFollowing(ctx context.Context, userID string, skip, l
 	}
 
 	// Get following
-	following, err := s.userManager.userRepo.FindFollowing(ctx, userObjectID, skip, limit)
+	following, err := s.relationshipRepo.FindFollowing(ctx, userObjectID, skip, limit)
 	if err != nil {
 		s.logger.Error("Failed to get following", err, "userId", userID)
 		return nil, err
 	}
 
 	// Convert to public users
-	publicUsers := make([]*models.PublicUser, 0, len(following))
-	for _, user := range following {
-		publicUser := user.ToPublicUser()
-
-		// Check if user is online
-		isOnline, err := s.userManager.IsUserOnline(ctx, user.ID.Hex())
-		if err != nil {
-			s.logger.Error("Failed to check if user is online", err, "userId", user.ID.Hex())
-			// Continue anyway, default to false
-		} else {
-			publicUser.Online = isOnline
-		}
-
-		publicUsers = append(publicUsers, &publicUser)
-	}
-
-	return publicUsers, nil
+	return s.userManager.toPublicUsers(ctx, following), nil
 }
 
 // GetFriends gets a list of users who are mutual followers (friends) with the specified user.
@@ -197,29 +193,13 @@ func (s *SocialService) GetFriends(ctx context.Context, userID string) ([]*model
 	}
 
 	// Get friends
-	friendUsers := make([]*models.PublicUser, 0, len(user.Connections.Friends))
-	for _, friendID := range user.Connections.Friends {
-		friend, err := s.userManager.GetUserByID(ctx, friendID.Hex())
-		if err != nil {
-			s.logger.Error("Failed to get friend", err, "userId", userID, "friendId", friendID.Hex())
-			continue // Skip this friend and continue
-		}
-
-		publicUser := friend.ToPublicUser()
-
-		// Check if user is online
-		isOnline, err := s.userManager.IsUserOnline(ctx, friend.ID.Hex())
-		if err != nil {
-			s.logger.Error("Failed to check if user is online", err, "userId", friend.ID.Hex())
-			// Continue anyway, default to false
-		} else {
-			publicUser.Online = isOnline
-		}
-
-		friendUsers = append(friendUsers, &publicUser)
+	friends, err := s.userManager.getUsersByIDs(ctx, user.Connections.Friends)
+	if err != nil {
+		s.logger.Error("Failed to get friends", err, "userId", userID)
+		return nil, err
 	}
 
-	return friendUsers, nil
+	return s.userManager.toPublicUsers(ctx, friends), nil
 }
 
 // GetFollowersCount gets the number of followers for a user.
@@ -304,29 +284,13 @@ func (s *SocialService) GetMutualFollowers(ctx context.Context, userID, targetID
 	}
 
 	// Get mutual followers
-	mutualFollowers := make([]*models.PublicUser, 0, len(mutualFollowerIDs))
-	for _, followerID := range mutualFollowerIDs {
-		follower, err := s.userManager.GetUserByID(ctx, followerID.Hex())
-		if err != nil {
-			s.logger.Error("Failed to get mutual follower", err, "userId", userID, "targetId", targetID, "followerId", followerID.Hex())
-			continue // Skip this follower and continue
-		}
-
-		publicUser := follower.ToPublicUser()
-
-		// Check if user is online
-		isOnline, err := s.userManager.IsUserOnline(ctx, follower.ID.Hex())
-		if err != nil {
-			s.logger.Error("Failed to check if user is online", err, "userId", follower.ID.Hex())
-			// Continue anyway, default to false
-		} else {
-			publicUser.Online = isOnline
-		}
-
-		mutualFollowers = append(mutualFollowers, &publicUser)
+	mutualFollowers, err := s.userManager.getUsersByIDs(ctx, mutualFollowerIDs)
+	if err != nil {
+		s.logger.Error("Failed to get mutual followers", err, "userId", userID, "targetId", targetID)
+		return nil, err
 	}
 
-	return mutualFollowers, nil
+	return s.userManager.toPublicUsers(ctx, mutualFollowers), nil
 }
 
 // GetSuggestedUsers gets a list of suggested users to follow based on mutual connections.
@@ -380,27 +344,102 @@ func (s *SocialService) GetSuggestedUsers(ctx context.Context, userID string, li
 	}
 
 	// Get suggested users
-	suggestedUsers := make([]*models.PublicUser, 0, len(suggestedUserIDs))
+	objectIDs := make([]bson.ObjectID, 0, len(suggestedUserIDs))
 	for _, id := range suggestedUserIDs {
-		suggestedUser, err := s.userManager.GetUserByID(ctx, id)
+		objectID, err := bson.ObjectIDFromHex(id)
 		if err != nil {
-			s.logger.Error("Failed to get suggested user", err, "userId", userID, "suggestedId", id)
-			continue // Skip this suggested user and continue
+			continue
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+	suggestedUsers, err := s.userManager.getUsersByIDs(ctx, objectIDs)
+	if err != nil {
+		s.logger.Error("Failed to get suggested users", err, "userId", userID)
+		return nil, err
+	}
+
+	return s.userManager.toPublicUsers(ctx, suggestedUsers), nil
+}
+
+// GetFriendsOnline gets the friends of a user who are online, with their status and the room they are in.
+func (s *SocialService) GetFriendsOnline(ctx context.Context, userID string) ([]*FriendPresence, error) {
+	user, err := s.userManager.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(user.Connections.Friends) == 0 {
+		return []*FriendPresence{}, nil
+	}
+
+	presences, err := s.userManager.presenceMgr.GetPresenceBulk(ctx, user.Connections.Friends)
+	if err != nil {
+		s.logger.Error("Failed to get friends presence", err, "userId", userID)
+		return nil, models.NewInternalError(err, "Failed to get friends presence")
+	}
+
+	onlineIDs := make([]bson.ObjectID, 0, len(presences))
+	for _, friendID := range user.Connections.Friends {
+		if _, ok := presences[friendID]; ok {
+			onlineIDs = append(onlineIDs, friendID)
 		}
+	}
 
-		publicUser := suggestedUser.ToPublicUser()
+	friends, err := s.userManager.getUsersByIDs(ctx, onlineIDs)
+	if err != nil {
+		return nil, err
+	}
 
-		// Check if user is online
-		isOnline, err := s.userManager.IsUserOnline(ctx, suggestedUser.ID.Hex())
-		if err != nil {
-			s.logger.Error("Failed to check if user is online", err, "userId", suggestedUser.ID.Hex())
-			// Continue anyway, default to false
-		} else {
-			publicUser.Online = isOnline
+	online := make([]*FriendPresence, 0, len(friends))
+	for _, friend := range friends {
+		presence := presences[friend.ID]
+		publicUser := friend.ToPublicUser()
+		publicUser.Online = true
+		online = append(online, &FriendPresence{
+			User:          &publicUser,
+			Status:        presence.Status,
+			CurrentRoomID: presence.CurrentRoomID,
+			LastActivity:  presence.LastActivity,
+		})
+	}
+
+	return online, nil
+}
+
+// DJStarted tells the followers of a user that the user started DJing in a room. Followers are told once
+// per room within djNoticeCooldown, however many turns the user plays there.
+func (s *SocialService) DJStarted(ctx context.Context, djID, roomID bson.ObjectID) {
+	if len(s.djHandlers) == 0 {
+		return
+	}
+
+	key := fmt.Sprintf("social:dj:%s:%s", djID.Hex(), roomID.Hex())
+	claimed, err := s.redisClient.Client().SetNX(ctx, key, "1", djNoticeCooldown).Result()
+	if err != nil {
+		s.logger.Error("Failed to claim DJ notice", err, "userId", djID.Hex(), "roomId", roomID.Hex())
+		return
+	}
+	if !claimed {
+		// Extend the set, followers were already told about it
+		if err := s.redisClient.Expire(ctx, key, djNoticeCooldown); err != nil {
+			s.logger.Warn("Failed to extend DJ notice", "userId", djID.Hex(), "roomId", roomID.Hex(), "error", err)
 		}
+		return
+	}
 
-		suggestedUsers = append(suggestedUsers, &publicUser)
+	followerIDs, err := s.relationshipRepo.FindFollowerIDs(ctx, djID)
+	if err != nil {
+		s.logger.Error("Failed to get followers of DJ", err, "userId", djID.Hex())
+		return
 	}
 
-	return suggestedUsers, nil
+	for _, followerID := range followerIDs {
+		for _, handler := range s.djHandlers {
+			handler(ctx, followerID, djID, roomID)
+		}
+	}
+
+	if len(followerIDs) > 0 {
+		s.logger.Debug("Told followers about DJ", "userId", djID.Hex(), "roomId", roomID.Hex(), "followers", len(followerIDs))
+	}
 }
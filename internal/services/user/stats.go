@@ -4,25 +4,56 @@ package user
 import (
 	"context"
 	"math"
+	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"norelock.dev/listenify/backend/internal/db/mongo/repositories"
+	"norelock.dev/listenify/backend/internal/db/redis/managers"
 	"norelock.dev/listenify/backend/internal/models"
 	"norelock.dev/listenify/backend/internal/utils"
 )
 
+// Experience awarded for room activity
+const (
+	// playExperience is awarded to a DJ for every track they play.
+	playExperience = 10
+	// wootExperience is awarded to a DJ for every woot their play receives.
+	wootExperience = 5
+	// djMinuteExperience is awarded to a DJ for every minute they play.
+	djMinuteExperience = 2
+	// chatExperience is awarded for chatting, at most once per chatExperienceInterval.
+	chatExperience = 1
+	// chatExperienceInterval keeps users from earning experience by flooding the chat.
+	chatExperienceInterval = time.Minute
+	// maxChatExperienceUsers is how many users' last chat award are kept before the expired ones are dropped.
+	maxChatExperienceUsers = 10000
+)
+
 // StatsService provides functionality for tracking and managing user statistics.
 type StatsService struct {
-	userManager *Manager
-	logger      *utils.Logger
+	userManager  *Manager
+	historyRepo  repositories.HistoryRepository
+	gamification *GamificationService
+	stateManager *managers.RoomStateManager
+	logger       *utils.Logger
+
+	// chatAwards are when users last earned experience for chatting
+	chatMu     sync.Mutex
+	chatAwards map[bson.ObjectID]time.Time
 }
 
-// NewStatsService creates a new stats service.
-func NewStatsService(userManager *Manager, logger *utils.Logger) *StatsService {
+// NewStatsService creates a new stats service. Experience is awarded through the gamification service, and
+// listening time is credited to the users the state manager lists in a room.
+func NewStatsService(userManager *Manager, historyRepo repositories.HistoryRepository, gamification *GamificationService, stateManager *managers.RoomStateManager, logger *utils.Logger) *StatsService {
 	return &StatsService{
-		userManager: userManager,
-		logger:      logger.Named("stats_service"),
+		userManager:  userManager,
+		historyRepo:  historyRepo,
+		gamification: gamification,
+		stateManager: stateManager,
+		logger:       logger.Named("stats_service"),
+		chatAwards:   make(map[bson.ObjectID]time.Time),
 	}
 }
 
@@ -35,46 +66,152 @@ func (s *StatsService) GetUserStats(ctx context.Context, userID string) (*models
 	return &user.Stats, nil
 }
 
+// GetAchievements retrieves a user's progress towards every achievement.
+func (s *StatsService) GetAchievements(ctx context.Context, userID string) ([]*models.AchievementProgress, error) {
+	return s.gamification.GetAchievements(ctx, userID)
+}
+
+// GetDJHistory retrieves the DJ sets a user has played, most recent first.
+func (s *StatsService) GetDJHistory(ctx context.Context, userID string, skip, limit int) ([]*models.DJHistory, error) {
+	objectID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	history, err := s.historyRepo.FindDJHistoryByUser(ctx, objectID, skip, limit)
+	if err != nil {
+		s.logger.Error("Failed to get DJ history", err, "userId", userID)
+		return nil, err
+	}
+
+	return history, nil
+}
+
 // AddExperience adds experience points to a user's stats and updates their level if necessary.
 func (s *StatsService) AddExperience(ctx context.Context, userID string, amount int) error {
-	// Get current user
-	user, err := s.userManager.GetUserByID(ctx, userID)
+	objectID, err := bson.ObjectIDFromHex(userID)
 	if err != nil {
+		return models.ErrInvalidID
+	}
+
+	if err := s.gamification.Record(ctx, ExperienceEvent{UserID: objectID, Experience: amount}); err != nil {
+		s.logger.Error("Failed to update user stats", err, "userId", userID)
 		return err
 	}
 
-	// Update experience
-	user.Stats.Experience += amount
-	user.Stats.LastUpdated = time.Now()
+	return nil
+}
 
-	// Check if user should level up
-	newLevel := s.calculateLevel(user.Stats.Experience)
-	if newLevel > user.Stats.Level {
-		// Level up!
-		oldLevel := user.Stats.Level
-		user.Stats.Level = newLevel
-		s.logger.Info("User leveled up", "userId", userID, "oldLevel", oldLevel, "newLevel", newLevel)
+// TrackPlay tracks a play ending in a room, awarding its DJ experience for playing, for the time they
+// played and for every woot the play received.
+func (s *StatsService) TrackPlay(ctx context.Context, play *models.PlayHistory) {
+	if play.DjID.IsZero() {
+		return
 	}
 
-	// Update user stats with $inc operator
-	objectID, err := bson.ObjectIDFromHex(userID)
+	experience := playExperience + play.Votes.Woots*wootExperience + play.Duration/60*djMinuteExperience
+	err := s.gamification.Record(ctx, ExperienceEvent{
+		UserID:     play.DjID,
+		RoomID:     play.RoomID,
+		Experience: experience,
+		Stats: bson.M{
+			"playCount": 1,
+			"woots":     play.Votes.Woots,
+			"mehs":      play.Votes.Mehs,
+			"djTime":    int64(play.Duration),
+		},
+	})
 	if err != nil {
-		return models.ErrInvalidID
+		s.logger.Error("Failed to track play", err, "userId", play.DjID.Hex(), "playId", play.ID.Hex())
 	}
+}
 
-	// Create updates map for experience and level if needed
-	updates := bson.M{"experience": amount}
-	if newLevel > user.Stats.Level {
-		// Also update the level
-		updates["level"] = newLevel - user.Stats.Level // Increment by the difference
+// TrackListeners credits the users in a room with the time they listened to a play that ended. Listening
+// time feeds their trust level. Users who joined during the play are credited from when they joined, and the
+// DJ is left out, their time counts as DJ time.
+func (s *StatsService) TrackListeners(ctx context.Context, play *models.PlayHistory) {
+	if play.Duration <= 0 {
+		return
 	}
 
-	if err := s.userManager.userRepo.UpdateStats(ctx, objectID, updates); err != nil {
-		s.logger.Error("Failed to update user stats", err, "userId", userID)
-		return err
+	roomID := play.RoomID.Hex()
+	users, err := s.stateManager.GetRoomUsers(ctx, roomID)
+	if err != nil {
+		s.logger.Error("Failed to get room users to credit listening time", err, "roomId", roomID)
+		return
+	}
+	joinTimes, err := s.stateManager.GetRoomJoinTimes(ctx, roomID)
+	if err != nil {
+		s.logger.Error("Failed to get join times to credit listening time", err, "roomId", roomID)
+		return
 	}
 
-	return nil
+	// Most listeners heard the whole play, so listeners are credited in groups of the same time
+	played := time.Duration(play.Duration) * time.Second
+	listeners := make(map[int64][]bson.ObjectID)
+	for _, userID := range users {
+		objectID, err := bson.ObjectIDFromHex(userID)
+		if err != nil || objectID == play.DjID {
+			continue
+		}
+		listened := played
+		if joined, ok := joinTimes[userID]; ok && joined.After(play.StartTime) {
+			listened = min(play.EndTime.Sub(joined), played)
+		}
+		if seconds := int64(listened / time.Second); seconds > 0 {
+			listeners[seconds] = append(listeners[seconds], objectID)
+		}
+	}
+
+	for seconds, userIDs := range listeners {
+		if err := s.userManager.userRepo.AddAudienceTime(ctx, userIDs, seconds); err != nil {
+			s.logger.Error("Failed to credit listening time", err, "roomId", roomID, "playId", play.ID.Hex())
+		}
+	}
+}
+
+// TrackChatMessage tracks a chat message a user sent in a room. Chatting earns experience at most once
+// per interval, the other messages are only counted.
+func (s *StatsService) TrackChatMessage(ctx context.Context, message models.ChatMessage) {
+	if message.UserID.IsZero() {
+		return
+	}
+
+	var err error
+	if s.claimChatExperience(message.UserID) {
+		err = s.gamification.Record(ctx, ExperienceEvent{
+			UserID:     message.UserID,
+			RoomID:     message.RoomID,
+			Experience: chatExperience,
+			Stats:      bson.M{"chatMessages": 1},
+		})
+	} else {
+		err = s.userManager.userRepo.UpdateStats(ctx, message.UserID, bson.M{"chatMessages": 1})
+	}
+	if err != nil {
+		s.logger.Error("Failed to track chat message", err, "userId", message.UserID.Hex(), "roomId", message.RoomID.Hex())
+	}
+}
+
+// claimChatExperience reports whether a user can earn experience for chatting, and if so starts their next interval.
+func (s *StatsService) claimChatExperience(userID bson.ObjectID) bool {
+	s.chatMu.Lock()
+	defer s.chatMu.Unlock()
+
+	now := time.Now()
+	if last, ok := s.chatAwards[userID]; ok && now.Sub(last) < chatExperienceInterval {
+		return false
+	}
+
+	if len(s.chatAwards) >= maxChatExperienceUsers {
+		for id, last := range s.chatAwards {
+			if now.Sub(last) >= chatExperienceInterval {
+				delete(s.chatAwards, id)
+			}
+		}
+	}
+	s.chatAwards[userID] = now
+	return true
 }
 
 // AddPoints adds points to a user's stats.
@@ -215,23 +352,7 @@ func (s *StatsService) GetTopUsers(ctx context.Context, limit int) ([]*models.Pu
 	}
 
 	// Convert to public users
-	publicUsers := make([]*models.PublicUser, 0, len(users))
-	for _, user := range users {
-		publicUser := user.ToPublicUser()
-
-		// Check if user is online
-		isOnline, err := s.userManager.IsUserOnline(ctx, user.ID.Hex())
-		if err != nil {
-			s.logger.Error("Failed to check if user is online", err, "userId", user.ID.Hex())
-			// Continue anyway, default to false
-		} else {
-			publicUser.Online = isOnline
-		}
-
-		publicUsers = append(publicUsers, &publicUser)
-	}
-
-	return publicUsers, nil
+	return s.userManager.toPublicUsers(ctx, users), nil
 }
 
 // GetUserRank gets a user's rank based on experience points.
@@ -270,7 +391,7 @@ func (s *StatsService) GetExperienceForNextLevel(ctx context.Context, userID str
 	nextLevel := currentLevel + 1
 
 	// Calculate experience required for next level
-	expForNextLevel := s.calculateExperienceForLevel(nextLevel)
+	expForNextLevel := calculateExperienceForLevel(nextLevel)
 
 	return expForNextLevel, nil
 }
@@ -287,8 +408,8 @@ func (s *StatsService) GetExperienceProgress(ctx context.Context, userID string)
 	currentExp := user.Stats.Experience
 
 	// Calculate experience required for current and next level
-	expForCurrentLevel := s.calculateExperienceForLevel(currentLevel)
-	expForNextLevel := s.calculateExperienceForLevel(currentLevel + 1)
+	expForCurrentLevel := calculateExperienceForLevel(currentLevel)
+	expForNextLevel := calculateExperienceForLevel(currentLevel + 1)
 
 	// Calculate progress
 	expInCurrentLevel := currentExp - expForCurrentLevel
@@ -308,7 +429,7 @@ func (s *StatsService) GetExperienceProgress(ctx context.Context, userID string)
 
 // calculateLevel calculates the level based on experience points.
 // The formula is: level = 1 + floor(sqrt(experience / 100))
-func (s *StatsService) calculateLevel(experience int) int {
+func calculateLevel(experience int) int {
 	if experience < 0 {
 		return 1
 	}
@@ -319,7 +440,7 @@ func (s *StatsService) calculateLevel(experience int) int {
 
 // calculateExperienceForLevel calculates the minimum experience required for a given level.
 // The formula is: experience = 100 * (level - 1)^2
-func (s *StatsService) calculateExperienceForLevel(level int) int {
+func calculateExperienceForLevel(level int) int {
 	if level <= 1 {
 		return 0
 	}
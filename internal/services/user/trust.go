@@ -0,0 +1,192 @@
+// Package user provides services for user management and operations.
+package user
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/config"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// TrustService computes user trust levels and gates abilities behind them.
+type TrustService struct {
+	userManager       *Manager
+	thresholds        map[models.TrustLevel]config.TrustThreshold
+	abilities         map[models.TrustAbility]models.TrustLevel
+	longTrackDuration int
+	logger            *utils.Logger
+}
+
+// TrustStatus describes a user's current trust level and where it comes from.
+type TrustStatus struct {
+	// Level is the effective trust level.
+	Level models.TrustLevel `json:"level"`
+
+	// Computed is the level derived from account age, listening time and moderation record.
+	Computed models.TrustLevel `json:"computed"`
+
+	// Overridden indicates whether an admin override is in effect.
+	Overridden bool `json:"overridden"`
+
+	// Strikes is the number of moderation strikes on the user's record.
+	Strikes int `json:"strikes"`
+
+	// Abilities lists the gated abilities and whether the user has unlocked them.
+	Abilities map[models.TrustAbility]bool `json:"abilities"`
+}
+
+// NewTrustService creates a new trust service.
+// The configured level names must already have been validated.
+func NewTrustService(cfg *config.Config, userManager *Manager, logger *utils.Logger) *TrustService {
+	parse := func(name string) models.TrustLevel {
+		level, _ := models.ParseTrustLevel(name)
+		return level
+	}
+
+	return &TrustService{
+		userManager: userManager,
+		thresholds: map[models.TrustLevel]config.TrustThreshold{
+			models.TrustLevelBasic:   cfg.Trust.Basic,
+			models.TrustLevelMember:  cfg.Trust.Member,
+			models.TrustLevelRegular: cfg.Trust.Regular,
+		},
+		abilities: map[models.TrustAbility]models.TrustLevel{
+			models.TrustAbilityPostLinks:       parse(cfg.Trust.PostLinksLevel),
+			models.TrustAbilityCreateRoom:      parse(cfg.Trust.CreateRoomLevel),
+			models.TrustAbilityQueueLongTracks: parse(cfg.Trust.LongTrackLevel),
+		},
+		longTrackDuration: cfg.Trust.LongTrackDuration,
+		logger:            logger.Named("trust_service"),
+	}
+}
+
+// ComputeTrustLevel derives a user's trust level from their account, ignoring overrides.
+// Levels are cumulative: a user must meet every lower level's requirements to reach a higher one.
+func (s *TrustService) ComputeTrustLevel(user *models.User) models.TrustLevel {
+	joined := user.Profile.JoinDate
+	if joined.IsZero() {
+		joined = user.CreatedAt
+	}
+	accountAge := time.Since(joined)
+	listeningTime := time.Duration(user.Stats.AudienceTime) * time.Second
+
+	level := models.TrustLevelNew
+	for _, next := range []models.TrustLevel{models.TrustLevelBasic, models.TrustLevelMember, models.TrustLevelRegular} {
+		threshold := s.thresholds[next]
+		if accountAge < threshold.MinAccountAge ||
+			listeningTime < threshold.MinListeningTime ||
+			user.Trust.Strikes > threshold.MaxStrikes {
+			break
+		}
+		level = next
+	}
+
+	return level
+}
+
+// EffectiveTrustLevel returns a user's trust level, honoring any admin override.
+func (s *TrustService) EffectiveTrustLevel(user *models.User) models.TrustLevel {
+	if user.Trust.Override != nil {
+		return *user.Trust.Override
+	}
+	return s.ComputeTrustLevel(user)
+}
+
+// GetTrustStatus gets a user's trust level along with the abilities it unlocks.
+func (s *TrustService) GetTrustStatus(ctx context.Context, userID string) (*TrustStatus, error) {
+	user, err := s.userManager.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	level := s.EffectiveTrustLevel(user)
+	status := &TrustStatus{
+		Level:      level,
+		Computed:   s.ComputeTrustLevel(user),
+		Overridden: user.Trust.Override != nil,
+		Strikes:    user.Trust.Strikes,
+		Abilities:  make(map[models.TrustAbility]bool, len(s.abilities)),
+	}
+	for ability, required := range s.abilities {
+		status.Abilities[ability] = level >= required
+	}
+
+	return status, nil
+}
+
+// CheckAbility returns models.ErrTrustLevelTooLow if the user has not unlocked the ability.
+func (s *TrustService) CheckAbility(ctx context.Context, userID string, ability models.TrustAbility) error {
+	required, ok := s.abilities[ability]
+	if !ok {
+		return nil // Ungated ability
+	}
+
+	user, err := s.userManager.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// Admins are never gated
+	for _, role := range user.Roles {
+		if role == "admin" {
+			return nil
+		}
+	}
+
+	if level := s.EffectiveTrustLevel(user); level < required {
+		s.logger.Debug("Ability denied by trust level", "userId", userID, "ability", ability, "level", level, "required", required)
+		return models.ErrTrustLevelTooLow
+	}
+
+	return nil
+}
+
+// IsLongTrack checks whether a track of the given duration in seconds counts as long.
+func (s *TrustService) IsLongTrack(duration int) bool {
+	return s.longTrackDuration > 0 && duration > s.longTrackDuration
+}
+
+// SetTrustOverride pins a user's trust level, or clears the override when level is nil.
+func (s *TrustService) SetTrustOverride(ctx context.Context, userID string, level *models.TrustLevel, adminID string) error {
+	objectID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return models.ErrInvalidID
+	}
+
+	var overriddenBy bson.ObjectID
+	if level != nil {
+		if overriddenBy, err = bson.ObjectIDFromHex(adminID); err != nil {
+			return models.ErrInvalidID
+		}
+	}
+
+	if err := s.userManager.userRepo.SetTrustOverride(ctx, objectID, level, overriddenBy); err != nil {
+		s.logger.Error("Failed to update trust override", err, "userId", userID)
+		return err
+	}
+
+	if level != nil {
+		s.logger.Info("Trust level overridden", "userId", userID, "level", *level, "adminId", adminID)
+	} else {
+		s.logger.Info("Trust level override cleared", "userId", userID, "adminId", adminID)
+	}
+	return nil
+}
+
+// AddStrike records a moderation action against a user, which may lower their trust level.
+func (s *TrustService) AddStrike(ctx context.Context, userID string) error {
+	objectID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return models.ErrInvalidID
+	}
+
+	if err := s.userManager.userRepo.AddStrike(ctx, objectID, time.Now()); err != nil {
+		s.logger.Error("Failed to record moderation strike", err, "userId", userID)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"norelock.dev/listenify/backend/internal/models"
+)
+
+// guestUsernamePrefix starts the names given to guest accounts, which users can't choose themselves.
+const guestUsernamePrefix = "guest-"
+
+// usernameCharset matches usernames made of letters, digits, underscores and hyphens, starting with a
+// letter or digit.
+var usernameCharset = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+// leetspeak maps the digits standing in for letters to the letters, so blocked terms can't be spelled
+// around.
+var leetspeak = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b")
+
+// UsernamePolicy controls which usernames users can choose, at signup and when renaming, and how often
+// they can rename.
+type UsernamePolicy struct {
+	// MinLength is the minimum username length.
+	MinLength int
+
+	// MaxLength is the maximum username length.
+	MaxLength int
+
+	// Reserved are the usernames nobody can take. They match regardless of case, underscores and hyphens.
+	Reserved []string
+
+	// BlockedTerms are the terms usernames can't contain, however they are spelled. Empty disables the
+	// profanity check.
+	BlockedTerms []string
+
+	// RenameCooldown is how long users wait between renames.
+	RenameCooldown time.Duration
+
+	// HistorySize is the number of previous usernames kept on each user.
+	HistorySize int
+}
+
+// Rename is a user changing their username.
+type Rename struct {
+	// User is the user, with their new username.
+	User *models.User
+
+	// Previous is the username the user had.
+	Previous string
+}
+
+// Validate checks that a username can be chosen. The error explains why it can't.
+func (p UsernamePolicy) Validate(username string) error {
+	if len(username) < p.MinLength || len(username) > p.MaxLength {
+		return invalidUsername(fmt.Sprintf("Usernames must be between %d and %d characters", p.MinLength, p.MaxLength))
+	}
+	if !usernameCharset.MatchString(username) {
+		return invalidUsername("Usernames can only contain letters, digits, underscores and hyphens, and must start with a letter or digit")
+	}
+	if strings.HasPrefix(strings.ToLower(username), guestUsernamePrefix) {
+		return invalidUsername("Usernames starting with guest- are kept for guests")
+	}
+
+	squashed := squashUsername(username)
+	for _, reserved := range p.Reserved {
+		if squashed == squashUsername(reserved) {
+			return invalidUsername("This username is reserved")
+		}
+	}
+
+	despelled := leetspeak.Replace(squashed)
+	for _, term := range p.BlockedTerms {
+		term = squashUsername(term)
+		if term != "" && (strings.Contains(squashed, term) || strings.Contains(despelled, term)) {
+			return invalidUsername("This username isn't allowed")
+		}
+	}
+
+	return nil
+}
+
+// squashUsername lowercases a username and drops its underscores, hyphens and spaces, so lookalike usernames
+// compare equal.
+func squashUsername(username string) string {
+	return strings.NewReplacer("_", "", "-", "", " ", "").Replace(strings.ToLower(username))
+}
+
+// invalidUsername creates the error for a username that can't be chosen.
+func invalidUsername(message string) error {
+	return models.NewUserError(models.ErrInvalidUsername, message, http.StatusBadRequest)
+}
+
+// AddRenamedHandler adds a handler called when a user changes their username.
+func (m *Manager) AddRenamedHandler(handler func(ctx context.Context, rename Rename)) {
+	m.renamedHandlers = append(m.renamedHandlers, handler)
+}
+
+// RenameUser changes a user's username, once the rename cooldown since their last rename has passed.
+// The username they had is kept in their username history.
+func (m *Manager) RenameUser(ctx context.Context, userID string, username string) (*models.User, error) {
+	objectID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	user, err := m.userRepo.FindByID(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Guest != nil {
+		return nil, models.NewUserError(models.ErrUnauthorizedAction, "Guests choose a username when they register", http.StatusForbidden)
+	}
+	if username == user.Username {
+		return user, nil
+	}
+
+	if err := m.usernamePolicy.Validate(username); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if next := user.LastRenamed().Add(m.usernamePolicy.RenameCooldown); now.Before(next) {
+		return nil, models.NewUserError(models.ErrRenameCooldown,
+			fmt.Sprintf("You can change your username again on %s", next.UTC().Format(time.DateOnly)), http.StatusTooManyRequests)
+	}
+
+	// Changing the case of one's own username is the only way to take a taken one
+	existing, err := m.userRepo.FindByUsername(ctx, username)
+	if err == nil && existing.ID != user.ID {
+		return nil, models.ErrUsernameAlreadyExists
+	} else if err != nil && !errors.Is(err, models.ErrUserNotFound) {
+		m.logger.Error("Error checking username existence", err, "username", username)
+		return nil, err
+	}
+
+	change := models.UsernameChange{Username: user.Username, ChangedAt: now}
+	if err := m.userRepo.RenameUser(ctx, user.ID, username, change, m.usernamePolicy.HistorySize); err != nil {
+		return nil, err
+	}
+
+	user.Username = username
+	user.UsernameHistory = append(user.UsernameHistory, change)
+	user.UsernameHistory = user.UsernameHistory[max(len(user.UsernameHistory)-m.usernamePolicy.HistorySize, 0):]
+	m.renameSessions(ctx, user)
+
+	m.logger.Info("User renamed", "userId", userID, "from", change.Username, "to", username)
+	for _, handler := range m.renamedHandlers {
+		handler(ctx, Rename{User: user, Previous: change.Username})
+	}
+
+	return user, nil
+}
+
+// renameSessions shows a renamed user's new username in their session and presence.
+func (m *Manager) renameSessions(ctx context.Context, user *models.User) {
+	session, token, err := m.sessionMgr.GetUserSession(ctx, user.ID)
+	if err != nil {
+		m.logger.Error("Failed to get session of renamed user", err, "userId", user.ID.Hex())
+	} else if session != nil {
+		session.Username = user.Username
+		if err := m.sessionMgr.UpdateSession(ctx, token, session); err != nil {
+			m.logger.Error("Failed to rename user in session", err, "userId", user.ID.Hex())
+		}
+	}
+
+	if err := m.presenceMgr.SetUsername(ctx, user.ID, user.Username); err != nil {
+		m.logger.Error("Failed to rename user in presence", err, "userId", user.ID.Hex())
+	}
+}
@@ -0,0 +1,198 @@
+// Package harness boots a Listenify node in process for tests, on the in-memory repositories and the
+// in-memory Redis, and provides typed clients to its HTTP API and its WebSocket RPC.
+//
+// The node never runs against real MongoDB and Redis servers: there are no dockerized dependencies,
+// and the module doesn't depend on testcontainers. Behavior that only the real stores have, such as
+// transactions, TTL indexes or key expiry, is not covered.
+package harness
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap/zapcore"
+	"norelock.dev/listenify/backend/internal/config"
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/server"
+	"norelock.dev/listenify/backend/internal/utils"
+)
+
+// Password is the password of the users the harness registers.
+const Password = "Harness-Passw0rd"
+
+// configYAML is the configuration of the node, on the in-memory databases and letting new accounts
+// do what tests need them to.
+const configYAML = `
+database:
+  use_in_memory: true
+
+auth:
+  jwt_secret: "harness-secret-not-for-production"
+
+trust:
+  create_room_level: "new"
+  post_links_level: "new"
+
+features:
+  enable_soundcloud: false
+`
+
+// Option changes the configuration of the node before it boots.
+type Option func(cfg *config.Config)
+
+// Harness is a Listenify node running in process, serving its HTTP API and its WebSocket RPC on
+// loopback listeners.
+type Harness struct {
+	t testing.TB
+
+	// Server is the node under test.
+	Server *server.Server
+
+	// Config is the configuration the node booted with.
+	Config *config.Config
+
+	api *httptest.Server
+	rpc *httptest.Server
+
+	// users numbers the users registered, so their usernames and emails don't collide
+	users atomic.Int64
+}
+
+// New boots a node for a test with the in-memory databases, applying the options to its configuration.
+// The node shuts down when the test ends.
+func New(t testing.TB, opts ...Option) *Harness {
+	t.Helper()
+
+	// Load the configuration from a file of the harness's own, so the node needs nothing outside the
+	// process and no configuration lying around the working directory changes it
+	configFile := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(configFile, []byte(configYAML), 0o600); err != nil {
+		t.Fatalf("harness: write config: %v", err)
+	}
+	t.Setenv("CONFIG_FILE", configFile)
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("harness: load config: %v", err)
+	}
+
+	// Shutdown only waits on the node's own work, the defaults are sized for real clients
+	cfg.Server.ShutdownAcceptTimeout = time.Second
+	cfg.Server.ShutdownNotifyTimeout = time.Second
+	cfg.Server.ShutdownFlushTimeout = 2 * time.Second
+	cfg.Server.ShutdownPersistTimeout = 2 * time.Second
+	cfg.Server.ShutdownCloseTimeout = time.Second
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	// Tests provoke errors on purpose, the node only logs them in verbose runs
+	level := zapcore.FatalLevel
+	if testing.Verbose() {
+		level = zapcore.ErrorLevel
+	}
+	logger := utils.NewLogger(utils.LoggerOptions{
+		Level:            level,
+		OutputPaths:      []string{"stderr"},
+		ErrorOutputPaths: []string{"stderr"},
+	})
+
+	srv, err := server.New(cfg, logger, nil)
+	if err != nil {
+		t.Fatalf("harness: create server: %v", err)
+	}
+	srv.Start()
+
+	h := &Harness{
+		t:      t,
+		Server: srv,
+		Config: cfg,
+		api:    httptest.NewServer(srv.Handler()),
+		rpc:    httptest.NewServer(srv.RPCHandler()),
+	}
+	t.Cleanup(h.close)
+
+	return h
+}
+
+// close shuts the node down, closing the listeners once it no longer serves connections.
+func (h *Harness) close() {
+	h.Server.Shutdown()
+	h.rpc.CloseClientConnections()
+	h.rpc.Close()
+	h.api.Close()
+}
+
+// APIURL returns the base URL of the HTTP API.
+func (h *Harness) APIURL() string {
+	return h.api.URL
+}
+
+// RPCURL returns the URL of the WebSocket RPC.
+func (h *Harness) RPCURL() string {
+	return "ws" + strings.TrimPrefix(h.rpc.URL, "http") + "/"
+}
+
+// User is a user registered through the HTTP API, along with the access token it got.
+type User struct {
+	ID       string
+	Username string
+	Email    string
+	Token    string
+}
+
+// Register registers a user with a unique username starting with the prefix, failing the test if it can't.
+func (h *Harness) Register(prefix string) *User {
+	h.t.Helper()
+
+	n := h.users.Add(1)
+	username := fmt.Sprintf("%s%d", prefix, n)
+	email := fmt.Sprintf("%s@example.com", strings.ToLower(username))
+
+	var resp struct {
+		User  models.PersonalUser `json:"user"`
+		Token string              `json:"token"`
+	}
+	err := h.HTTP(nil).Post("/auth/register", models.UserRegisterRequest{
+		Username: username,
+		Email:    email,
+		Password: Password,
+	}, &resp)
+	if err != nil {
+		h.t.Fatalf("harness: register %s: %v", username, err)
+	}
+
+	return &User{
+		ID:       resp.User.ID.Hex(),
+		Username: resp.User.Username,
+		Email:    email,
+		Token:    resp.Token,
+	}
+}
+
+// SeedMedia adds a media item to the catalog without resolving it from its provider, returning its ID.
+func (h *Harness) SeedMedia(title string, duration time.Duration) string {
+	h.t.Helper()
+
+	media := &models.Media{
+		Type:      "youtube",
+		SourceID:  bson.NewObjectID().Hex()[:11],
+		Title:     title,
+		Artist:    "Harness",
+		Thumbnail: "https://i.ytimg.com/vi/harness/default.jpg",
+		Duration:  int(duration.Seconds()),
+	}
+	if err := h.Server.Media().Create(context.Background(), media); err != nil {
+		h.t.Fatalf("harness: seed media %q: %v", title, err)
+	}
+	return media.ID.Hex()
+}
@@ -0,0 +1,109 @@
+package harness
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// APIError is an error response of the HTTP API.
+type APIError struct {
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+
+	// Message is the error message of the response, or its body when it isn't an error response.
+	Message string
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+// HTTPClient calls the HTTP API of the node as a user, or anonymously.
+type HTTPClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// HTTP returns a client of the HTTP API authenticated as the user, or anonymous if the user is nil.
+func (h *Harness) HTTP(user *User) *HTTPClient {
+	c := &HTTPClient{
+		baseURL: h.APIURL(),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+	if user != nil {
+		c.token = user.Token
+	}
+	return c
+}
+
+// Get gets a path of the API, decoding the response into result unless it is nil.
+func (c *HTTPClient) Get(path string, result any) error {
+	return c.Do(http.MethodGet, path, nil, result)
+}
+
+// Post posts a body encoded as JSON to a path of the API, decoding the response into result unless it is nil.
+func (c *HTTPClient) Post(path string, body, result any) error {
+	return c.Do(http.MethodPost, path, body, result)
+}
+
+// Do sends a request to a path of the API with a body encoded as JSON unless it is nil, and decodes the
+// response into result unless it is nil. Error responses are returned as an *APIError.
+func (c *HTTPClient) Do(method, path string, body, result any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: string(data)}
+		var errorResponse struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &errorResponse) == nil && errorResponse.Error.Message != "" {
+			apiErr.Message = errorResponse.Error.Message
+		}
+		return apiErr
+	}
+
+	if result == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
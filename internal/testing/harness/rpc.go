@@ -0,0 +1,275 @@
+package harness
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"norelock.dev/listenify/backend/internal/rpc"
+)
+
+// DefaultTimeout is how long clients wait for a response or a notification before failing the test.
+const DefaultTimeout = 5 * time.Second
+
+// Notification is a notification the node sent to an RPC client.
+type Notification struct {
+	Method string
+	Params json.RawMessage
+}
+
+// RoomEvent is the payload of the room.event notification, an event published to a room's followers.
+type RoomEvent struct {
+	Type   string          `json:"type"`
+	RoomID string          `json:"roomId"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// message is any message the node sends over the WebSocket: a response or a notification.
+type message struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *rpc.Error      `json:"error,omitempty"`
+}
+
+// RPCClient is a WebSocket RPC connection of a user to the node. It keeps the notifications it receives
+// until a test waits for them.
+type RPCClient struct {
+	t    testing.TB
+	User *User
+
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+	nextID  atomic.Int64
+
+	mu            sync.Mutex
+	pending       map[int64]chan *message
+	notifications []Notification
+	// received is closed, then replaced, whenever a notification arrives
+	received chan struct{}
+	closed   bool
+
+	done chan struct{}
+}
+
+// Connect opens a WebSocket RPC connection as the user, waiting for the node to negotiate its protocol.
+// The connection closes when the test ends.
+func (h *Harness) Connect(user *User) *RPCClient {
+	h.t.Helper()
+
+	query := url.Values{}
+	query.Set("token", user.Token)
+	query.Set("protocol", strconv.Itoa(rpc.ProtocolVersion))
+
+	conn, _, err := websocket.DefaultDialer.Dial(h.RPCURL()+"?"+query.Encode(), nil)
+	if err != nil {
+		h.t.Fatalf("harness: connect %s: %v", user.Username, err)
+	}
+
+	c := &RPCClient{
+		t:        h.t,
+		User:     user,
+		conn:     conn,
+		pending:  make(map[int64]chan *message),
+		received: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go c.readLoop()
+	h.t.Cleanup(c.Close)
+
+	c.WaitFor("connection.negotiated", nil)
+	return c
+}
+
+// readLoop dispatches the messages of the connection until it closes.
+func (c *RPCClient) readLoop() {
+	defer close(c.done)
+
+	for {
+		_, frame, err := c.conn.ReadMessage()
+		if err != nil {
+			c.mu.Lock()
+			c.closed = true
+			for id, ch := range c.pending {
+				close(ch)
+				delete(c.pending, id)
+			}
+			close(c.received)
+			c.mu.Unlock()
+			return
+		}
+
+		// A frame carries queued messages separated by newlines
+		for _, data := range bytes.Split(frame, []byte{'\n'}) {
+			if len(bytes.TrimSpace(data)) == 0 {
+				continue
+			}
+			var msg message
+			if err := json.Unmarshal(data, &msg); err != nil {
+				c.t.Errorf("harness: %s received a malformed message %q: %v", c.User.Username, data, err)
+				continue
+			}
+			c.dispatch(&msg)
+		}
+	}
+}
+
+// dispatch hands a response to its call, or keeps a notification.
+func (c *RPCClient) dispatch(msg *message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if msg.Method != "" && len(msg.ID) == 0 {
+		c.notifications = append(c.notifications, Notification{Method: msg.Method, Params: msg.Params})
+		close(c.received)
+		c.received = make(chan struct{})
+		return
+	}
+
+	id, err := strconv.ParseInt(string(msg.ID), 10, 64)
+	if err != nil {
+		return
+	}
+	if ch, ok := c.pending[id]; ok {
+		ch <- msg
+		delete(c.pending, id)
+	}
+}
+
+// Call calls a method, decoding its result into result unless it is nil. Error responses are returned
+// as an *rpc.Error.
+func (c *RPCClient) Call(method string, params, result any) error {
+	id := c.nextID.Add(1)
+	ch := make(chan *message, 1)
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return fmt.Errorf("call %s: connection closed", method)
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("call %s: encode params: %w", method, err)
+	}
+	data, err := json.Marshal(rpc.Request{JSONRPC: "2.0", Method: method, Params: raw, ID: id})
+	if err != nil {
+		return fmt.Errorf("call %s: encode request: %w", method, err)
+	}
+
+	c.writeMu.Lock()
+	err = c.conn.WriteMessage(websocket.TextMessage, data)
+	c.writeMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("call %s: %w", method, err)
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return fmt.Errorf("call %s: connection closed", method)
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("call %s: decode result: %w", method, err)
+		}
+		return nil
+	case <-time.After(DefaultTimeout):
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return fmt.Errorf("call %s: no response after %s", method, DefaultTimeout)
+	}
+}
+
+// MustCall calls a method like Call, failing the test if the call fails.
+func (c *RPCClient) MustCall(method string, params, result any) {
+	c.t.Helper()
+	if err := c.Call(method, params, result); err != nil {
+		c.t.Fatalf("%s: %s: %v", c.User.Username, method, err)
+	}
+}
+
+// WaitFor waits for a notification of a method matching the filter, any of them if it is nil, failing
+// the test if none arrives in time. The notification is consumed, so waiting again waits for the next one.
+func (c *RPCClient) WaitFor(method string, match func(Notification) bool) Notification {
+	c.t.Helper()
+
+	deadline := time.After(DefaultTimeout)
+	for {
+		c.mu.Lock()
+		for i, n := range c.notifications {
+			if n.Method == method && (match == nil || match(n)) {
+				c.notifications = append(c.notifications[:i], c.notifications[i+1:]...)
+				c.mu.Unlock()
+				return n
+			}
+		}
+		received, closed := c.received, c.closed
+		c.mu.Unlock()
+
+		if closed {
+			c.t.Fatalf("%s: connection closed waiting for %s", c.User.Username, method)
+		}
+		select {
+		case <-received:
+		case <-deadline:
+			c.t.Fatalf("%s: no %s notification after %s", c.User.Username, method, DefaultTimeout)
+		}
+	}
+}
+
+// Notifications returns the notifications of a method received and not waited for yet, without
+// consuming them.
+func (c *RPCClient) Notifications(method string) []Notification {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var notifications []Notification
+	for _, n := range c.notifications {
+		if n.Method == method {
+			notifications = append(notifications, n)
+		}
+	}
+	return notifications
+}
+
+// WaitForRoomEvent waits for an event of a type published to a room, failing the test if none arrives in time.
+func (c *RPCClient) WaitForRoomEvent(roomID, eventType string) RoomEvent {
+	c.t.Helper()
+
+	var event RoomEvent
+	c.WaitFor(rpc.EventRoomEvent, func(n Notification) bool {
+		var e RoomEvent
+		if json.Unmarshal(n.Params, &e) != nil || e.RoomID != roomID || e.Type != eventType {
+			return false
+		}
+		event = e
+		return true
+	})
+	return event
+}
+
+// Close closes the connection, waiting for its reads to stop.
+func (c *RPCClient) Close() {
+	c.writeMu.Lock()
+	_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	c.writeMu.Unlock()
+	_ = c.conn.Close()
+	<-c.done
+}
@@ -0,0 +1,117 @@
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/rpc"
+	"norelock.dev/listenify/backend/internal/testing/harness"
+)
+
+// sendMessage sends a chat message to a room as the client's user.
+func sendMessage(t *testing.T, c *harness.RPCClient, roomID, content string) models.ChatMessage {
+	t.Helper()
+
+	var result struct {
+		Message models.ChatMessage `json:"message"`
+	}
+	c.MustCall("chat.sendMessage", map[string]any{"roomId": roomID, "content": content}, &result)
+	return result.Message
+}
+
+// eventMessageID decodes the ID of the message a chat event is about, carried as the message itself,
+// under its message field, or by its ID alone.
+func eventMessageID(t *testing.T, event harness.RoomEvent) string {
+	t.Helper()
+
+	var data struct {
+		ID        string `json:"id"`
+		MessageID string `json:"messageId"`
+		Message   struct {
+			ID string `json:"id"`
+		} `json:"message"`
+	}
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		t.Fatalf("decode %s event: %v", event.Type, err)
+	}
+	switch {
+	case data.MessageID != "":
+		return data.MessageID
+	case data.Message.ID != "":
+		return data.Message.ID
+	}
+	return data.ID
+}
+
+func TestChatMessagesReachTheRoom(t *testing.T) {
+	h := harness.New(t)
+
+	owner := h.Connect(h.Register("owner"))
+	listener := h.Connect(h.Register("listener"))
+
+	roomID := createRoom(t, owner, "chat").ID.Hex()
+	joinRoom(t, listener, roomID)
+
+	sent := sendMessage(t, owner, roomID, "hello room")
+	if sent.Content != "hello room" || sent.UserID.Hex() != owner.User.ID {
+		t.Fatalf("sent message = %q by %s, want %q by %s", sent.Content, sent.UserID.Hex(), "hello room", owner.User.ID)
+	}
+
+	// Users in the room get the message as it is sent
+	event := listener.WaitForRoomEvent(roomID, "chat_message")
+	if id := eventMessageID(t, event); id != sent.ID.Hex() {
+		t.Errorf("chat_message event for message %s, want %s", id, sent.ID.Hex())
+	}
+
+	// Users joining later find it in the room's history
+	var history struct {
+		Messages []models.ChatMessage `json:"messages"`
+	}
+	listener.MustCall("chat.getMessages", map[string]any{"roomId": roomID}, &history)
+	found := false
+	for _, m := range history.Messages {
+		found = found || m.ID == sent.ID
+	}
+	if !found {
+		t.Errorf("chat.getMessages = %d messages without the one sent", len(history.Messages))
+	}
+}
+
+func TestChatPinAndDelete(t *testing.T) {
+	h := harness.New(t)
+
+	owner := h.Connect(h.Register("owner"))
+	listener := h.Connect(h.Register("listener"))
+
+	roomID := createRoom(t, owner, "pins").ID.Hex()
+	joinRoom(t, listener, roomID)
+
+	message := sendMessage(t, listener, roomID, "pin me")
+	messageID := message.ID.Hex()
+
+	// Only moderators pin messages
+	err := listener.Call("chat.pinMessage", map[string]any{"roomId": roomID, "messageId": messageID}, nil)
+	var rpcErr *rpc.Error
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("chat.pinMessage by a listener = %v, want an RPC error", err)
+	}
+
+	owner.MustCall("chat.pinMessage", map[string]any{"roomId": roomID, "messageId": messageID}, nil)
+	event := listener.WaitForRoomEvent(roomID, "chat_message_pinned")
+	if id := eventMessageID(t, event); id != messageID {
+		t.Errorf("chat_message_pinned event for message %s, want %s", id, messageID)
+	}
+
+	// Deleting a pinned message takes it out of the room's chat, and unpins it
+	owner.MustCall("chat.deleteMessage", map[string]any{"roomId": roomID, "messageId": messageID}, nil)
+	event = listener.WaitForRoomEvent(roomID, "chat_message_deleted")
+	if id := eventMessageID(t, event); id != messageID {
+		t.Errorf("chat_message_deleted event for message %s, want %s", id, messageID)
+	}
+	event = listener.WaitForRoomEvent(roomID, "chat_message_unpinned")
+	if id := eventMessageID(t, event); id != messageID {
+		t.Errorf("chat_message_unpinned event for message %s, want %s", id, messageID)
+	}
+}
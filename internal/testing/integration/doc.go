@@ -0,0 +1,3 @@
+// Package integration tests flows across the services of a Listenify node, booting it in process
+// with the harness and driving it through its HTTP API and its WebSocket RPC.
+package integration
@@ -0,0 +1,120 @@
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"norelock.dev/listenify/backend/internal/rpc"
+	"norelock.dev/listenify/backend/internal/testing/harness"
+)
+
+// moderationParams decodes the params of a moderation notification.
+func moderationParams(t *testing.T, n harness.Notification) (roomID, reason string) {
+	t.Helper()
+
+	var params struct {
+		RoomID string `json:"roomId"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal(n.Params, &params); err != nil {
+		t.Fatalf("decode %s params: %v", n.Method, err)
+	}
+	return params.RoomID, params.Reason
+}
+
+func TestModeratorKicksUser(t *testing.T) {
+	h := harness.New(t)
+
+	owner := h.Connect(h.Register("owner"))
+	troll := h.Connect(h.Register("troll"))
+
+	roomID := createRoom(t, owner, "kick").ID.Hex()
+	joinRoom(t, troll, roomID)
+
+	// Users without the permission can't kick
+	err := troll.Call("moderation.kick", map[string]any{"roomId": roomID, "userId": owner.User.ID}, nil)
+	var rpcErr *rpc.Error
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("moderation.kick by a listener = %v, want an RPC error", err)
+	}
+
+	owner.MustCall("moderation.kick", map[string]any{"roomId": roomID, "userId": troll.User.ID, "reason": "spam"}, nil)
+
+	n := troll.WaitFor("moderation.kicked", nil)
+	if gotRoom, reason := moderationParams(t, n); gotRoom != roomID || reason != "spam" {
+		t.Errorf("moderation.kicked for room %s with reason %q, want room %s with reason %q", gotRoom, reason, roomID, "spam")
+	}
+	if isUserInRoom(t, owner, roomID, troll.User.ID) {
+		t.Errorf("kicked user is still in the room")
+	}
+
+	// Kicked users no longer get the room's chat
+	sendMessage(t, owner, roomID, "after the kick")
+	owner.WaitForRoomEvent(roomID, "chat_message")
+	troll.MustCall("room.get", map[string]any{"roomId": roomID}, nil)
+	for _, n := range troll.Notifications(rpc.EventRoomEvent) {
+		var event harness.RoomEvent
+		if json.Unmarshal(n.Params, &event) == nil && event.RoomID == roomID && event.Type == "chat_message" {
+			t.Errorf("kicked user got the room's chat")
+		}
+	}
+}
+
+func TestModeratorMutesUser(t *testing.T) {
+	h := harness.New(t)
+
+	owner := h.Connect(h.Register("owner"))
+	loud := h.Connect(h.Register("loud"))
+
+	roomID := createRoom(t, owner, "mute").ID.Hex()
+	joinRoom(t, loud, roomID)
+
+	owner.MustCall("moderation.mute", map[string]any{"roomId": roomID, "userId": loud.User.ID, "duration": 5, "reason": "caps"}, nil)
+
+	n := loud.WaitFor("moderation.muted", nil)
+	if gotRoom, reason := moderationParams(t, n); gotRoom != roomID || reason != "caps" {
+		t.Errorf("moderation.muted for room %s with reason %q, want room %s with reason %q", gotRoom, reason, roomID, "caps")
+	}
+
+	// Muted users stay in the room but can't chat
+	if !isUserInRoom(t, owner, roomID, loud.User.ID) {
+		t.Errorf("muted user is no longer in the room")
+	}
+	if err := loud.Call("chat.sendMessage", map[string]any{"roomId": roomID, "content": "HELLO"}, nil); err == nil {
+		t.Errorf("muted user sent a chat message")
+	}
+
+	// Undoing the mute lets them chat again
+	owner.MustCall("moderation.undoLast", map[string]any{"roomId": roomID}, nil)
+	loud.WaitFor("moderation.undone", nil)
+	sendMessage(t, loud, roomID, "hello")
+}
+
+func TestBanCommandIsUndoable(t *testing.T) {
+	h := harness.New(t)
+
+	owner := h.Connect(h.Register("owner"))
+	troll := h.Connect(h.Register("troll"))
+
+	roomID := createRoom(t, owner, "ban").ID.Hex()
+	joinRoom(t, troll, roomID)
+
+	owner.MustCall("chat.sendMessage", map[string]any{"roomId": roomID, "content": "/ban " + troll.User.Username + " spam"}, nil)
+
+	n := troll.WaitFor("moderation.banned", nil)
+	if gotRoom, reason := moderationParams(t, n); gotRoom != roomID || reason != "spam" {
+		t.Errorf("moderation.banned for room %s with reason %q, want room %s with reason %q", gotRoom, reason, roomID, "spam")
+	}
+	if isUserInRoom(t, owner, roomID, troll.User.ID) {
+		t.Errorf("banned user is still in the room")
+	}
+	if err := troll.Call("room.join", map[string]any{"roomId": roomID}, nil); err == nil {
+		t.Fatalf("banned user joined the room again")
+	}
+
+	// The ban is the moderator's last action, so undoing it lifts it
+	owner.MustCall("moderation.undoLast", map[string]any{"roomId": roomID}, nil)
+	troll.WaitFor("moderation.undone", nil)
+	joinRoom(t, troll, roomID)
+}
@@ -0,0 +1,108 @@
+package integration
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/rpc"
+	"norelock.dev/listenify/backend/internal/testing/harness"
+)
+
+// activatePlaylist gives the client's user an active playlist of seeded media, so they can DJ.
+func activatePlaylist(t *testing.T, h *harness.Harness, c *harness.RPCClient, tracks ...string) {
+	t.Helper()
+
+	var created struct {
+		Playlist models.PlaylistInfo `json:"playlist"`
+	}
+	c.MustCall("playlist.create", map[string]any{"name": c.User.Username + " picks"}, &created)
+	playlistID := created.Playlist.ID.Hex()
+
+	for _, title := range tracks {
+		mediaID := h.SeedMedia(title, 3*time.Minute)
+		c.MustCall("playlist.addItem", map[string]any{"playlistId": playlistID, "mediaId": mediaID}, nil)
+	}
+	c.MustCall("playlist.setActive", map[string]any{"playlistId": playlistID}, nil)
+}
+
+// currentDJ returns the ID of a room's current DJ, empty if nobody is playing.
+func currentDJ(t *testing.T, state *models.RoomState) string {
+	t.Helper()
+
+	if state.CurrentDJ == nil {
+		return ""
+	}
+	return state.CurrentDJ.ID.Hex()
+}
+
+// queueOrder returns the IDs of the users in a room's DJ queue, in order.
+func queueOrder(state *models.RoomState) []string {
+	ids := make([]string, len(state.DJQueue))
+	for i, entry := range state.DJQueue {
+		ids[i] = entry.User.ID.Hex()
+	}
+	return ids
+}
+
+func TestQueueRotation(t *testing.T) {
+	h := harness.New(t)
+
+	owner := h.Connect(h.Register("owner"))
+	first := h.Connect(h.Register("first"))
+	second := h.Connect(h.Register("second"))
+
+	roomID := createRoom(t, owner, "rotation").ID.Hex()
+	joinRoom(t, first, roomID)
+	joinRoom(t, second, roomID)
+
+	// Users need something to play to join the queue
+	err := first.Call("queue.join", map[string]any{"roomId": roomID}, nil)
+	var rpcErr *rpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != rpc.ErrInvalidParams {
+		t.Fatalf("queue.join without an active playlist = %v, want an invalid params error", err)
+	}
+
+	activatePlaylist(t, h, first, "First song", "First encore")
+	activatePlaylist(t, h, second, "Second song")
+
+	// The first DJ to join an empty booth starts their turn right away
+	var state models.RoomState
+	first.MustCall("queue.join", map[string]any{"roomId": roomID}, &state)
+	if dj := currentDJ(t, &state); dj != first.User.ID {
+		t.Fatalf("current DJ after the first join = %q, want %s", dj, first.User.ID)
+	}
+	owner.WaitFor(rpc.EventQueueUpdated, nil)
+
+	// DJs joining during a turn wait in front of the DJ playing
+	second.MustCall("queue.join", map[string]any{"roomId": roomID}, &state)
+	if got, want := queueOrder(&state), []string{second.User.ID, first.User.ID}; !slices.Equal(got, want) {
+		t.Errorf("queue after the second join = %v, want %v", got, want)
+	}
+	owner.WaitFor(rpc.EventQueueUpdated, nil)
+
+	// Turns rotate through the queue, DJs cycling back to its end
+	for _, want := range []*harness.RPCClient{second, first, second} {
+		owner.MustCall("queue.advance", map[string]any{"roomId": roomID}, &state)
+		if dj := currentDJ(t, &state); dj != want.User.ID {
+			t.Fatalf("current DJ after advancing = %q, want %s (%s)", dj, want.User.ID, want.User.Username)
+		}
+	}
+
+	// The DJ playing leaving the queue hands the turn to the next one
+	second.MustCall("queue.leave", map[string]any{"roomId": roomID}, &state)
+	if dj := currentDJ(t, &state); dj != first.User.ID {
+		t.Errorf("current DJ after the DJ playing left = %q, want %s", dj, first.User.ID)
+	}
+	if got, want := queueOrder(&state), []string{first.User.ID}; !slices.Equal(got, want) {
+		t.Errorf("queue after the DJ playing left = %v, want %v", got, want)
+	}
+
+	var queue []models.QueueEntry
+	owner.MustCall("queue.get", map[string]any{"roomId": roomID}, &queue)
+	if len(queue) != 1 || queue[0].User.ID.Hex() != first.User.ID {
+		t.Errorf("queue.get = %d entries, want only %s", len(queue), first.User.ID)
+	}
+}
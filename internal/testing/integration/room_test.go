@@ -0,0 +1,171 @@
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"norelock.dev/listenify/backend/internal/models"
+	"norelock.dev/listenify/backend/internal/rpc"
+	"norelock.dev/listenify/backend/internal/testing/harness"
+)
+
+// roomSettings are the settings of the rooms the tests create.
+var roomSettings = models.RoomSettings{
+	Capacity:       50,
+	WaitlistMax:    10,
+	AllowedSources: []string{"youtube"},
+	ChatEnabled:    true,
+	ChatBacklog:    20,
+}
+
+// createRoom creates a room as the client's user, who joins it as its moderator.
+func createRoom(t *testing.T, c *harness.RPCClient, slug string) *models.Room {
+	t.Helper()
+
+	var room models.Room
+	c.MustCall("room.create", map[string]any{
+		"name":     "Room " + slug,
+		"slug":     slug,
+		"settings": roomSettings,
+	}, &room)
+	return &room
+}
+
+// joinRoom joins a room as the client's user.
+func joinRoom(t *testing.T, c *harness.RPCClient, roomID string) {
+	t.Helper()
+	c.MustCall("room.join", map[string]any{"roomId": roomID}, nil)
+}
+
+// isUserInRoom checks whether a user is in a room.
+func isUserInRoom(t *testing.T, c *harness.RPCClient, roomID, userID string) bool {
+	t.Helper()
+
+	var inRoom bool
+	c.MustCall("room.isUserInRoom", map[string]any{"roomId": roomID, "userId": userID}, &inRoom)
+	return inRoom
+}
+
+// userParams decodes the params of a notification about a user, such as room.userJoined.
+func userParams(t *testing.T, n harness.Notification) (roomID, userID string) {
+	t.Helper()
+
+	var params struct {
+		RoomID string `json:"roomId"`
+		UserID string `json:"userId"`
+	}
+	if err := json.Unmarshal(n.Params, &params); err != nil {
+		t.Fatalf("decode %s params: %v", n.Method, err)
+	}
+	return params.RoomID, params.UserID
+}
+
+func TestRoomLifecycle(t *testing.T) {
+	h := harness.New(t)
+
+	owner := h.Connect(h.Register("owner"))
+	listener := h.Connect(h.Register("listener"))
+
+	room := createRoom(t, owner, "lifecycle")
+	roomID := room.ID.Hex()
+	if room.CreatedBy.Hex() != owner.User.ID {
+		t.Errorf("room created by %s, want %s", room.CreatedBy.Hex(), owner.User.ID)
+	}
+	if !isUserInRoom(t, owner, roomID, owner.User.ID) {
+		t.Fatalf("owner is not in the room they created")
+	}
+
+	// The room is found by ID and through the HTTP API
+	var got models.Room
+	owner.MustCall("room.get", map[string]any{"roomId": roomID}, &got)
+	if got.Slug != "lifecycle" {
+		t.Errorf("room.get slug = %q, want %q", got.Slug, "lifecycle")
+	}
+
+	// Joining tells the users already in the room
+	joinRoom(t, listener, roomID)
+	n := owner.WaitFor(rpc.EventUserJoinedRoom, nil)
+	if gotRoom, gotUser := userParams(t, n); gotRoom != roomID || gotUser != listener.User.ID {
+		t.Errorf("%s for room %s and user %s, want room %s and user %s", n.Method, gotRoom, gotUser, roomID, listener.User.ID)
+	}
+	if !isUserInRoom(t, owner, roomID, listener.User.ID) {
+		t.Errorf("listener is not in the room after joining it")
+	}
+
+	// Leaving tells the users left in the room
+	listener.MustCall("room.leave", map[string]any{"roomId": roomID}, nil)
+	n = owner.WaitFor(rpc.EventUserLeftRoom, nil)
+	if gotRoom, gotUser := userParams(t, n); gotRoom != roomID || gotUser != listener.User.ID {
+		t.Errorf("%s for room %s and user %s, want room %s and user %s", n.Method, gotRoom, gotUser, roomID, listener.User.ID)
+	}
+	if isUserInRoom(t, owner, roomID, listener.User.ID) {
+		t.Errorf("listener is still in the room after leaving it")
+	}
+}
+
+func TestRoomSlugCollision(t *testing.T) {
+	h := harness.New(t)
+
+	first := createRoom(t, h.Connect(h.Register("owner")), "taken")
+	second := createRoom(t, h.Connect(h.Register("other")), "taken")
+
+	if first.Slug != "taken" {
+		t.Errorf("first room slug = %q, want %q", first.Slug, "taken")
+	}
+	if second.Slug == first.Slug {
+		t.Errorf("second room got the taken slug %q", second.Slug)
+	}
+	if second.ID == first.ID {
+		t.Errorf("second room has the ID of the first")
+	}
+}
+
+func TestJoinUnknownRoom(t *testing.T) {
+	h := harness.New(t)
+
+	c := h.Connect(h.Register("lost"))
+	err := c.Call("room.join", map[string]any{"roomId": "65f0c0ffee0ddba11d0ca5e5"}, nil)
+
+	var rpcErr *rpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != rpc.ErrRoomNotFound {
+		t.Fatalf("room.join of an unknown room = %v, want a room not found error", err)
+	}
+}
+
+func TestOverflowListenerPromoted(t *testing.T) {
+	h := harness.New(t)
+
+	owner := h.Connect(h.Register("owner"))
+	first := h.Connect(h.Register("first"))
+	waiting := h.Connect(h.Register("waiting"))
+
+	settings := roomSettings
+	settings.Capacity = 2
+	settings.ListenerOverflow = 5
+	var room models.Room
+	owner.MustCall("room.create", map[string]any{"name": "Room overflow", "slug": "overflow", "settings": settings}, &room)
+	roomID := room.ID.Hex()
+
+	joinRoom(t, first, roomID)
+	joinRoom(t, waiting, roomID)
+	if isUserInRoom(t, owner, roomID, waiting.User.ID) {
+		t.Fatalf("user joining a full room is a participant, want an overflow listener")
+	}
+
+	// A participant leaving hands their slot to the listener waiting longest
+	first.MustCall("room.leave", map[string]any{"roomId": roomID}, nil)
+	if !isUserInRoom(t, owner, roomID, waiting.User.ID) {
+		t.Fatalf("overflow listener is not a participant after a slot opened")
+	}
+
+	var state models.RoomState
+	owner.MustCall("room.getState", map[string]any{"roomId": roomID}, &state)
+	found := false
+	for _, u := range state.Users {
+		found = found || u.ID.Hex() == waiting.User.ID
+	}
+	if !found || state.ActiveUsers != len(state.Users) {
+		t.Errorf("room state has %d users, %d active, promoted listener found: %v", len(state.Users), state.ActiveUsers, found)
+	}
+}
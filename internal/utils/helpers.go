@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"net"
 	"net/http"
 	"regexp"
 	"slices"
@@ -254,23 +255,67 @@ func ErrorJSON(w http.ResponseWriter, statusCode int, err error) {
 	JSONResponse(w, statusCode, response)
 }
 
-// GetRequestIP gets the client IP address from the request
+// trustedProxies are the networks of the reverse proxies in front of the server.
+var trustedProxies []*net.IPNet
+
+// SetTrustedProxies sets the reverse proxies in front of the server, as IP addresses or CIDR networks.
+// It must be called before the server handles requests.
+func SetTrustedProxies(proxies []string) error {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("invalid trusted proxy: %s", proxy)
+			}
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy: %s", proxy)
+		}
+		networks = append(networks, network)
+	}
+
+	trustedProxies = networks
+	return nil
+}
+
+// isTrustedProxy checks whether an IP address belongs to a trusted proxy.
+func isTrustedProxy(address string) bool {
+	ip := net.ParseIP(address)
+	return ip != nil && slices.ContainsFunc(trustedProxies, func(network *net.IPNet) bool {
+		return network.Contains(ip)
+	})
+}
+
+// GetRequestIP gets the client IP address of a request. Clients can send anything in X-Forwarded-For,
+// so it is only read from trusted proxies: the client is the last address in it that isn't a trusted
+// proxy, as each proxy appends the address it got the request from.
 func GetRequestIP(r *http.Request) string {
-	// Check X-Forwarded-For header first (for proxies)
-	ip := r.Header.Get("X-Forwarded-For")
-	if ip == "" {
-		// If no proxy, get the remote address
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
 		ip = r.RemoteAddr
 	}
 
-	// If there are multiple IPs in X-Forwarded-For, get the first one
-	if strings.Contains(ip, ",") {
-		ip = strings.TrimSpace(strings.Split(ip, ",")[0])
+	if !isTrustedProxy(ip) {
+		return ip
 	}
-
-	// Remove port number if present
-	if strings.Contains(ip, ":") {
-		ip = strings.Split(ip, ":")[0]
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+		if !isTrustedProxy(hop) {
+			break
+		}
 	}
 
 	return ip
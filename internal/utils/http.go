@@ -3,13 +3,23 @@ package utils
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 )
 
+// ErrPrivateAddress is returned when a URL a user provided points at an address that isn't publicly routable.
+var ErrPrivateAddress = errors.New("URL points at a private address")
+
+// sharedAddressSpace is the carrier-grade NAT range, which net.IP doesn't count as private.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
 // APIResponse represents a standard API response.
 type APIResponse struct {
 	Success bool `json:"success"`
@@ -39,12 +49,18 @@ func RespondWithJSON(w http.ResponseWriter, statusCode int, data any) {
 }
 
 // RespondWithError sends an error response with the given status code and message.
+// The request's trace ID is included so users can quote it when reporting the error.
 func RespondWithError(w http.ResponseWriter, statusCode int, message string) {
+	errorBody := map[string]string{
+		"message": message,
+	}
+	if traceID := w.Header().Get(TraceIDHeader); traceID != "" {
+		errorBody["traceId"] = traceID
+	}
+
 	response := APIResponse{
 		Success: false,
-		Error: map[string]string{
-			"message": message,
-		},
+		Error:   errorBody,
 	}
 	RespondWithJSON(w, statusCode, response)
 }
@@ -121,3 +137,42 @@ func ExtractBearerToken(r *http.Request) (string, error) {
 
 	return tokenParts[1], nil
 }
+
+// IsPublicIP reports whether an IP address is publicly routable, rather than a loopback, private,
+// link-local, multicast or unspecified address.
+func IsPublicIP(ip net.IP) bool {
+	return ip != nil && !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() && !sharedAddressSpace.Contains(ip)
+}
+
+// NewPublicHTTPClient creates an HTTP client for URLs users provide. It refuses to connect to addresses
+// that aren't publicly routable, whatever the URL's host name resolves to, so those URLs can't be used to
+// reach internal services. Unless followRedirects is set, redirects aren't followed and the redirect
+// response is returned as it is.
+func NewPublicHTTPClient(timeout time.Duration, followRedirects bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, conn syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !IsPublicIP(net.ParseIP(host)) {
+				return ErrPrivateAddress
+			}
+			return nil
+		},
+	}
+
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+	}
+	if !followRedirects {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	return client
+}
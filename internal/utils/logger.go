@@ -23,6 +23,8 @@ type LoggerOptions struct {
 	OutputPaths []string
 	// ErrorOutputPaths defines where errors are written
 	ErrorOutputPaths []string
+	// TraceBuffer, if set, also keeps traced log lines for searching by trace ID
+	TraceBuffer *TraceLogBuffer
 }
 
 // DefaultLoggerOptions returns the default logger configuration.
@@ -79,10 +81,16 @@ func NewLogger(opts ...LoggerOptions) *Logger {
 	}
 
 	// Build the logger
-	logger, err := config.Build(
+	buildOptions := []zap.Option{
 		zap.AddCallerSkip(1),
 		zap.AddStacktrace(zapcore.ErrorLevel),
-	)
+	}
+	if options.TraceBuffer != nil {
+		buildOptions = append(buildOptions, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(core, options.TraceBuffer.core(config.Level))
+		}))
+	}
+	logger, err := config.Build(buildOptions...)
 	if err != nil {
 		// If we can't create the logger, use a simple fallback and log the error
 		fallback := zap.NewExample()
@@ -0,0 +1,184 @@
+// Package utils provides utility functions used throughout the application.
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// TraceIDHeader is the HTTP header carrying a request's trace ID back to the client.
+	TraceIDHeader = "X-Trace-ID"
+
+	// traceIDKey is the context key and log field holding a request's trace ID.
+	traceIDKey = "traceId"
+)
+
+// NewTraceID generates a trace ID for a request.
+func NewTraceID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b) // Never fails, see crypto/rand
+	return hex.EncodeToString(b)
+}
+
+// WithTraceID returns a context carrying a trace ID.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey, traceID)
+}
+
+// TraceIDFromContext returns the trace ID carried by a context, or an empty string.
+func TraceIDFromContext(ctx context.Context) string {
+	traceID, _ := ctx.Value(traceIDKey).(string)
+	return traceID
+}
+
+// WithTrace creates a new Logger that tags its lines with the context's trace ID, if it has one.
+func (l *Logger) WithTrace(ctx context.Context) *Logger {
+	traceID := TraceIDFromContext(ctx)
+	if traceID == "" {
+		return l
+	}
+	return l.With(traceIDKey, traceID)
+}
+
+// LogEntry is a log line captured by a TraceLogBuffer.
+type LogEntry struct {
+	Time    time.Time      `json:"time"`
+	Level   string         `json:"level"`
+	Logger  string         `json:"logger"`
+	Message string         `json:"message"`
+	TraceID string         `json:"traceId"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+// TraceLogBuffer keeps the most recent traced log lines in memory so they can be searched by trace ID.
+// Lines without a trace ID aren't kept.
+type TraceLogBuffer struct {
+	entries []LogEntry
+	next    int
+	full    bool
+	mutex   sync.RWMutex
+}
+
+// NewTraceLogBuffer creates a buffer holding up to size log lines.
+func NewTraceLogBuffer(size int) *TraceLogBuffer {
+	return &TraceLogBuffer{
+		entries: make([]LogEntry, size),
+	}
+}
+
+// Search returns the buffered log lines with a trace ID, oldest first.
+func (b *TraceLogBuffer) Search(traceID string) []LogEntry {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	results := []LogEntry{}
+	start, count := 0, b.next
+	if b.full {
+		start, count = b.next, len(b.entries)
+	}
+	for i := range count {
+		entry := b.entries[(start+i)%len(b.entries)]
+		if entry.TraceID == traceID {
+			results = append(results, entry)
+		}
+	}
+	return results
+}
+
+// add stores a log line, overwriting the oldest when the buffer is full.
+func (b *TraceLogBuffer) add(entry LogEntry) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// core returns a zap core that writes traced log lines at the enabled levels to the buffer.
+func (b *TraceLogBuffer) core(level zapcore.LevelEnabler) zapcore.Core {
+	return &traceCore{LevelEnabler: level, buffer: b}
+}
+
+// traceCore is a zap core that captures log lines tagged with a trace ID.
+type traceCore struct {
+	zapcore.LevelEnabler
+	buffer  *TraceLogBuffer
+	traceID string
+	fields  []zapcore.Field
+}
+
+// With adds fields to the core, picking up a trace ID among them.
+func (c *traceCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &traceCore{
+		LevelEnabler: c.LevelEnabler,
+		buffer:       c.buffer,
+		traceID:      c.traceID,
+		fields:       append(append([]zapcore.Field{}, c.fields...), fields...),
+	}
+	if traceID := findTraceID(fields); traceID != "" {
+		clone.traceID = traceID
+	}
+	return clone
+}
+
+// Check adds the core to the entry if its level is enabled.
+func (c *traceCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write stores a log line if it belongs to a trace.
+func (c *traceCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	traceID := c.traceID
+	if id := findTraceID(fields); id != "" {
+		traceID = id
+	}
+	if traceID == "" {
+		return nil
+	}
+
+	encoder := zapcore.NewMapObjectEncoder()
+	for _, field := range c.fields {
+		field.AddTo(encoder)
+	}
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+	delete(encoder.Fields, traceIDKey)
+
+	c.buffer.add(LogEntry{
+		Time:    entry.Time,
+		Level:   entry.Level.String(),
+		Logger:  entry.LoggerName,
+		Message: entry.Message,
+		TraceID: traceID,
+		Fields:  encoder.Fields,
+	})
+	return nil
+}
+
+// Sync has nothing to flush.
+func (c *traceCore) Sync() error {
+	return nil
+}
+
+// findTraceID finds the trace ID field among log fields.
+func findTraceID(fields []zapcore.Field) string {
+	for _, field := range fields {
+		if field.Key == traceIDKey && field.Type == zapcore.StringType {
+			return field.String
+		}
+	}
+	return ""
+}